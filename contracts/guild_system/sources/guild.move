@@ -98,6 +98,13 @@ module mmo_game::guild {
         guild_id: ID,
         withdrawer: address,
         amount: u64,
+    }
+
+    // Recorded by the game server, which grants the zone or schedules a siege
+    public struct TerritoryClaimed has copy, drop {
+        guild_id: ID,
+        zone_id: String,
+        claimant: address,
     }    // --- Initialization ---
 
     // Initialize the guild registry (call once during deployment)
@@ -469,6 +476,24 @@ module mmo_game::guild {
         };
     }
 
+    // Claim a world zone for the guild (leader or officers). Ownership is
+    // decided off-chain: the server grants unclaimed zones and schedules a
+    // siege when the zone is held by another guild.
+    public entry fun claim_territory(
+        guild: &Guild,
+        zone_id: vector<u8>,
+        ctx: &mut TxContext
+    ) {
+        let sender = tx_context::sender(ctx);
+        assert!(can_manage_guild(guild, sender), E_INSUFFICIENT_PERMISSIONS);
+
+        event::emit(TerritoryClaimed {
+            guild_id: object::uid_to_inner(&guild.id),
+            zone_id: string::utf8(zone_id),
+            claimant: sender,
+        });
+    }
+
     // --- Helper Functions ---
 
     // Check if member can manage other members
@@ -0,0 +1,218 @@
+// Escrow for high-value trades between two players.
+// The game server creates an escrow with the agreed terms and acts as its
+// arbiter. Each player deposits their side: specific item NFTs and an amount of
+// SUI. The arbiter then releases both sides to the other player in one
+// transaction, or refunds them. After the deadline, either player can also
+// claim a refund without the server.
+
+#[allow(duplicate_alias, unused_use)]
+module mmo_game::escrow {
+    use std::string::{Self, String};
+    use std::option::{Self, Option};
+    use std::vector;
+    use sui::object::{Self, UID, ID};
+    use sui::transfer;
+    use sui::tx_context::{Self, TxContext};
+    use sui::event;
+    use sui::clock::{Self, Clock};
+    use sui::coin::{Self, Coin};
+    use sui::sui::SUI;
+    use sui::balance::{Self, Balance};
+
+    // Error codes
+    const E_NOT_AUTHORIZED: u64 = 1;
+    const E_NOT_A_PARTY: u64 = 2;
+    const E_ITEM_NOT_IN_TERMS: u64 = 3;
+    const E_TOO_MANY_COINS: u64 = 4;
+    const E_INCOMPLETE: u64 = 5;
+    const E_NOT_EXPIRED: u64 = 6;
+    const E_INVALID_TERMS: u64 = 7;
+
+    /// A trade in escrow. T is the item NFT type both players trade.
+    public struct Escrow<T: key + store> has key {
+        id: UID,
+        trade_id: String,
+        arbiter: address,
+        party_a: address,
+        party_b: address,
+        expected_items_a: vector<ID>, // Items party_a gives
+        expected_items_b: vector<ID>,
+        expected_coins_a: u64, // MIST party_a gives
+        expected_coins_b: u64,
+        items_a: vector<T>,
+        items_b: vector<T>,
+        coins_a: Balance<SUI>,
+        coins_b: Balance<SUI>,
+        expires_at_ms: u64,
+    }
+
+    // Events
+    public struct EscrowCreated has copy, drop {
+        escrow_id: ID,
+        trade_id: String,
+        party_a: address,
+        party_b: address,
+        expires_at_ms: u64,
+    }
+
+    public struct EscrowDeposited has copy, drop {
+        escrow_id: ID,
+        depositor: address,
+        item_id: Option<ID>,
+        coins: u64,
+    }
+
+    public struct EscrowReleased has copy, drop {
+        escrow_id: ID,
+        trade_id: String,
+    }
+
+    public struct EscrowRefunded has copy, drop {
+        escrow_id: ID,
+        trade_id: String,
+        by: address,
+    }
+
+    /// Create and share an escrow. The sender becomes the arbiter.
+    public entry fun create<T: key + store>(
+        trade_id: vector<u8>,
+        party_a: address,
+        party_b: address,
+        expected_items_a: vector<ID>,
+        expected_items_b: vector<ID>,
+        expected_coins_a: u64,
+        expected_coins_b: u64,
+        expires_at_ms: u64,
+        ctx: &mut TxContext
+    ) {
+        assert!(party_a != party_b, E_INVALID_TERMS);
+        let escrow = Escrow<T> {
+            id: object::new(ctx),
+            trade_id: string::utf8(trade_id),
+            arbiter: tx_context::sender(ctx),
+            party_a,
+            party_b,
+            expected_items_a,
+            expected_items_b,
+            expected_coins_a,
+            expected_coins_b,
+            items_a: vector::empty(),
+            items_b: vector::empty(),
+            coins_a: balance::zero(),
+            coins_b: balance::zero(),
+            expires_at_ms,
+        };
+        event::emit(EscrowCreated {
+            escrow_id: object::id(&escrow),
+            trade_id: escrow.trade_id,
+            party_a,
+            party_b,
+            expires_at_ms,
+        });
+        transfer::share_object(escrow);
+    }
+
+    /// Deposit one of the items the sender agreed to give.
+    public entry fun deposit_item<T: key + store>(escrow: &mut Escrow<T>, item: T, ctx: &mut TxContext) {
+        let sender = tx_context::sender(ctx);
+        let item_id = object::id(&item);
+        if (sender == escrow.party_a) {
+            assert!(vector::contains(&escrow.expected_items_a, &item_id), E_ITEM_NOT_IN_TERMS);
+            vector::push_back(&mut escrow.items_a, item);
+        } else {
+            assert!(sender == escrow.party_b, E_NOT_A_PARTY);
+            assert!(vector::contains(&escrow.expected_items_b, &item_id), E_ITEM_NOT_IN_TERMS);
+            vector::push_back(&mut escrow.items_b, item);
+        };
+        event::emit(EscrowDeposited {
+            escrow_id: object::id(escrow),
+            depositor: sender,
+            item_id: option::some(item_id),
+            coins: 0,
+        });
+    }
+
+    /// Deposit SUI towards the amount the sender agreed to give.
+    public entry fun deposit_coins<T: key + store>(escrow: &mut Escrow<T>, payment: Coin<SUI>, ctx: &mut TxContext) {
+        let sender = tx_context::sender(ctx);
+        let amount = coin::value(&payment);
+        if (sender == escrow.party_a) {
+            assert!(balance::value(&escrow.coins_a) + amount <= escrow.expected_coins_a, E_TOO_MANY_COINS);
+            balance::join(&mut escrow.coins_a, coin::into_balance(payment));
+        } else {
+            assert!(sender == escrow.party_b, E_NOT_A_PARTY);
+            assert!(balance::value(&escrow.coins_b) + amount <= escrow.expected_coins_b, E_TOO_MANY_COINS);
+            balance::join(&mut escrow.coins_b, coin::into_balance(payment));
+        };
+        event::emit(EscrowDeposited {
+            escrow_id: object::id(escrow),
+            depositor: sender,
+            item_id: option::none(),
+            coins: amount,
+        });
+    }
+
+    /// Release both sides to the other player. Only the arbiter can release,
+    /// and only once everything agreed has been deposited.
+    public entry fun release<T: key + store>(escrow: Escrow<T>, ctx: &mut TxContext) {
+        assert!(tx_context::sender(ctx) == escrow.arbiter, E_NOT_AUTHORIZED);
+        assert!(is_complete(&escrow), E_INCOMPLETE);
+        let escrow_id = object::id(&escrow);
+        let Escrow {
+            id, trade_id, arbiter: _, party_a, party_b,
+            expected_items_a: _, expected_items_b: _, expected_coins_a: _, expected_coins_b: _,
+            items_a, items_b, coins_a, coins_b, expires_at_ms: _,
+        } = escrow;
+        send_items(items_a, party_b);
+        send_items(items_b, party_a);
+        send_coins(coins_a, party_b, ctx);
+        send_coins(coins_b, party_a, ctx);
+        object::delete(id);
+        event::emit(EscrowReleased { escrow_id, trade_id });
+    }
+
+    /// Return every deposit to its depositor. The arbiter can refund at any
+    /// time; either player once the escrow has expired.
+    public entry fun refund<T: key + store>(escrow: Escrow<T>, clock: &Clock, ctx: &mut TxContext) {
+        let sender = tx_context::sender(ctx);
+        if (sender != escrow.arbiter) {
+            assert!(sender == escrow.party_a || sender == escrow.party_b, E_NOT_AUTHORIZED);
+            assert!(clock::timestamp_ms(clock) >= escrow.expires_at_ms, E_NOT_EXPIRED);
+        };
+        let escrow_id = object::id(&escrow);
+        let Escrow {
+            id, trade_id, arbiter: _, party_a, party_b,
+            expected_items_a: _, expected_items_b: _, expected_coins_a: _, expected_coins_b: _,
+            items_a, items_b, coins_a, coins_b, expires_at_ms: _,
+        } = escrow;
+        send_items(items_a, party_a);
+        send_items(items_b, party_b);
+        send_coins(coins_a, party_a, ctx);
+        send_coins(coins_b, party_b, ctx);
+        object::delete(id);
+        event::emit(EscrowRefunded { escrow_id, trade_id, by: sender });
+    }
+
+    /// Whether both players have deposited everything agreed.
+    public fun is_complete<T: key + store>(escrow: &Escrow<T>): bool {
+        vector::length(&escrow.items_a) == vector::length(&escrow.expected_items_a) &&
+        vector::length(&escrow.items_b) == vector::length(&escrow.expected_items_b) &&
+        balance::value(&escrow.coins_a) == escrow.expected_coins_a &&
+        balance::value(&escrow.coins_b) == escrow.expected_coins_b
+    }
+
+    fun send_items<T: key + store>(mut items: vector<T>, recipient: address) {
+        while (!vector::is_empty(&items)) {
+            transfer::public_transfer(vector::pop_back(&mut items), recipient);
+        };
+        vector::destroy_empty(items);
+    }
+
+    fun send_coins(coins: Balance<SUI>, recipient: address, ctx: &mut TxContext) {
+        if (balance::value(&coins) == 0) {
+            balance::destroy_zero(coins);
+        } else {
+            transfer::public_transfer(coin::from_balance(coins, ctx), recipient);
+        }
+    }
+}
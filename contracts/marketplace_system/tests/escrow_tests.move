@@ -0,0 +1,124 @@
+#[test_only]
+module mmo_game::escrow_tests {
+    use std::string;
+    use sui::test_scenario::{Self as test, next_tx, ctx};
+    use sui::coin::{Self, Coin};
+    use sui::sui::SUI;
+    use sui::object::{Self, UID, ID};
+    use sui::clock;
+
+    use mmo_game::escrow::{Self, Escrow};
+
+    public struct TestNFT has key, store {
+        id: UID,
+        name: string::String,
+    }
+
+    const ARBITER: address = @0xAD;
+    const PLAYER_A: address = @0x1;
+    const PLAYER_B: address = @0x2;
+
+    fun create_test_nft(ctx: &mut sui::tx_context::TxContext): TestNFT {
+        TestNFT { id: object::new(ctx), name: string::utf8(b"Sword") }
+    }
+
+    // Player A trades a sword for 5000 MIST from player B.
+    fun setup(scenario: &mut test::Scenario): ID {
+        next_tx(scenario, PLAYER_A);
+        let nft = create_test_nft(ctx(scenario));
+        let nft_id = object::id(&nft);
+        sui::transfer::public_transfer(nft, PLAYER_A);
+
+        next_tx(scenario, ARBITER);
+        escrow::create<TestNFT>(b"trade-1", PLAYER_A, PLAYER_B, vector[nft_id], vector[], 0, 5000, 1000, ctx(scenario));
+        nft_id
+    }
+
+    #[test]
+    fun test_release_swaps_both_sides() {
+        let mut scenario = test::begin(ARBITER);
+        let nft_id = setup(&mut scenario);
+
+        next_tx(&mut scenario, PLAYER_A);
+        {
+            let mut trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            let nft = test::take_from_sender_by_id<TestNFT>(&scenario, nft_id);
+            escrow::deposit_item(&mut trade, nft, ctx(&mut scenario));
+            assert!(!escrow::is_complete(&trade), 0);
+            test::return_shared(trade);
+        };
+
+        next_tx(&mut scenario, PLAYER_B);
+        {
+            let mut trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            let payment = coin::mint_for_testing<SUI>(5000, ctx(&mut scenario));
+            escrow::deposit_coins(&mut trade, payment, ctx(&mut scenario));
+            assert!(escrow::is_complete(&trade), 1);
+            test::return_shared(trade);
+        };
+
+        next_tx(&mut scenario, ARBITER);
+        {
+            let trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            escrow::release(trade, ctx(&mut scenario));
+        };
+
+        next_tx(&mut scenario, PLAYER_B);
+        {
+            let nft = test::take_from_sender_by_id<TestNFT>(&scenario, nft_id);
+            test::return_to_sender(&scenario, nft);
+        };
+        next_tx(&mut scenario, PLAYER_A);
+        {
+            let payment = test::take_from_sender<Coin<SUI>>(&scenario);
+            assert!(coin::value(&payment) == 5000, 2);
+            test::return_to_sender(&scenario, payment);
+        };
+        test::end(scenario);
+    }
+
+    #[test]
+    #[expected_failure(abort_code = escrow::E_INCOMPLETE)]
+    fun test_release_requires_all_deposits() {
+        let mut scenario = test::begin(ARBITER);
+        setup(&mut scenario);
+
+        next_tx(&mut scenario, ARBITER);
+        {
+            let trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            escrow::release(trade, ctx(&mut scenario));
+        };
+        test::end(scenario);
+    }
+
+    #[test]
+    fun test_player_refund_after_expiry() {
+        let mut scenario = test::begin(ARBITER);
+        setup(&mut scenario);
+
+        next_tx(&mut scenario, PLAYER_B);
+        {
+            let mut trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            let payment = coin::mint_for_testing<SUI>(5000, ctx(&mut scenario));
+            escrow::deposit_coins(&mut trade, payment, ctx(&mut scenario));
+            test::return_shared(trade);
+        };
+
+        next_tx(&mut scenario, PLAYER_B);
+        {
+            let trade = test::take_shared<Escrow<TestNFT>>(&scenario);
+            let mut clock = clock::create_for_testing(ctx(&mut scenario));
+            clock::set_for_testing(&mut clock, 1000);
+            escrow::refund(trade, &clock, ctx(&mut scenario));
+            clock::destroy_for_testing(clock);
+        };
+
+        next_tx(&mut scenario, PLAYER_B);
+        {
+            let payment = test::take_from_sender<Coin<SUI>>(&scenario);
+            assert!(coin::value(&payment) == 5000, 0);
+            test::return_to_sender(&scenario, payment);
+        };
+        test::end(scenario);
+    }
+}
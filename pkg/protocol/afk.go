@@ -0,0 +1,15 @@
+package protocol
+
+// AFK status. The server marks a player AFK after a stretch without gameplay
+// actions; chat and pings do not count. Room members are told when it changes.
+
+// PlayerAFKPayload is for "PLAYER_AFK".
+type PlayerAFKPayload struct {
+	PlayerID string `json:"playerId"`
+	AFK      bool   `json:"afk"`
+	MovedTo  string `json:"movedTo,omitempty"` // Lobby room the player is being moved to, if any
+}
+
+const (
+	MsgTypePlayerAFK = "PLAYER_AFK"
+)
@@ -0,0 +1,13 @@
+package protocol
+
+// Announcements. Operators broadcast a message, such as a maintenance warning
+// or the start of a live event, and the server sends it to every online
+// player as ANNOUNCEMENT.
+
+// AnnouncementPayload is for "ANNOUNCEMENT".
+type AnnouncementPayload struct {
+	Text   string `json:"text"`
+	SentAt int64  `json:"sentAt"` // Unix milliseconds
+}
+
+const MsgTypeAnnouncement = "ANNOUNCEMENT"
@@ -0,0 +1,25 @@
+package protocol
+
+// Appearance. Players wear cosmetics, one per slot, chosen from the item NFTs
+// their primary linked address owns. APPEARANCE_EQUIP puts one on, or takes
+// off what is worn in the slot when cosmeticId is empty. The server sends the
+// player's own APPEARANCE after authentication and after each change, and
+// other players' APPEARANCE to room members: for everyone already there when
+// joining, and whenever a member joins or changes what they wear.
+
+// AppearanceEquipRequestPayload is for "APPEARANCE_EQUIP".
+type AppearanceEquipRequestPayload struct {
+	Slot       string `json:"slot"`
+	CosmeticID string `json:"cosmeticId,omitempty"` // Item type of an owned NFT; empty takes the slot off
+}
+
+// AppearancePayload is for "APPEARANCE".
+type AppearancePayload struct {
+	PlayerID string            `json:"playerId"`
+	Equipped map[string]string `json:"equipped"` // Slot -> cosmetic ID
+}
+
+const (
+	MsgTypeAppearanceEquip = "APPEARANCE_EQUIP"
+	MsgTypeAppearance      = "APPEARANCE"
+)
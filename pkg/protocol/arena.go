@@ -0,0 +1,58 @@
+package protocol
+
+// Ranked PvP arena. Players queue for a mode, are matched by rating, latency
+// and region, and get the match result with their rating change when one
+// team is defeated.
+
+// Arena modes.
+const (
+	ArenaMode1v1 = "1v1"
+	ArenaMode2v2 = "2v2"
+	ArenaMode3v3 = "3v3"
+)
+
+// ArenaQueueRequestPayload is for "ARENA_QUEUE".
+type ArenaQueueRequestPayload struct {
+	Mode  string `json:"mode,omitempty"`  // Required to join
+	Leave bool   `json:"leave,omitempty"` // Leave the queue instead; leaving a running match forfeits it
+	// Regions the player accepts, preferred first. Defaults to the region of
+	// the player's world; any region if that has none.
+	Regions []string `json:"regions,omitempty"`
+}
+
+// ArenaQueueStatusPayload is for "ARENA_QUEUE_STATUS".
+type ArenaQueueStatusPayload struct {
+	Mode    string `json:"mode,omitempty"`
+	Queued  bool   `json:"queued"`
+	Rating  int    `json:"rating,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ArenaMatchFoundPayload is for "ARENA_MATCH_FOUND".
+type ArenaMatchFoundPayload struct {
+	MatchID string   `json:"matchId"`
+	Mode    string   `json:"mode"`
+	TeamA   []string `json:"teamA"`
+	TeamB   []string `json:"teamB"`
+	Region  string   `json:"region,omitempty"`
+	// ExpectedLatencyMs is the highest measured round trip among the players,
+	// and LatencyMs the receiving player's own; absent if not measured.
+	ExpectedLatencyMs int64 `json:"expectedLatencyMs,omitempty"`
+	LatencyMs         int64 `json:"latencyMs,omitempty"`
+}
+
+// ArenaMatchResultPayload is for "ARENA_MATCH_RESULT".
+type ArenaMatchResultPayload struct {
+	MatchID      string `json:"matchId"`
+	Won          bool   `json:"won"`
+	RatingBefore int    `json:"ratingBefore"`
+	RatingAfter  int    `json:"ratingAfter"`
+}
+
+const (
+	MsgTypeArenaQueue       = "ARENA_QUEUE"
+	MsgTypeArenaQueueStatus = "ARENA_QUEUE_STATUS"
+	MsgTypeArenaMatchFound  = "ARENA_MATCH_FOUND"
+	MsgTypeArenaMatchResult = "ARENA_MATCH_RESULT"
+)
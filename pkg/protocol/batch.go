@@ -0,0 +1,49 @@
+package protocol
+
+// Batched player actions. A client submits an ordered list of PLAYER_ACTION
+// payloads in one message and gets one result per step back.
+
+// MaxBatchActions bounds the number of actions in one BATCH_ACTION.
+const MaxBatchActions = 20
+
+// Batch modes.
+const (
+	// BatchModeAtomic validates every step before running any; if one is invalid, none run.
+	BatchModeAtomic = "atomic"
+	// BatchModeUntilFailure runs steps in order and stops at the first failure. This is the default.
+	BatchModeUntilFailure = "until_failure"
+)
+
+// BatchStepSkipped is the status of a step that did not run because another step failed.
+const BatchStepSkipped = "SKIPPED"
+
+// BatchActionRequestPayload is for "BATCH_ACTION".
+type BatchActionRequestPayload struct {
+	BatchID string                `json:"batchId,omitempty"` // Echoed back to correlate the result
+	Mode    string                `json:"mode,omitempty"`    // BatchModeAtomic or BatchModeUntilFailure
+	Actions []PlayerActionPayload `json:"actions"`
+}
+
+// BatchActionStepResult is the outcome of one step of a batch.
+type BatchActionStepResult struct {
+	Index      int                    `json:"index"`
+	ActionType string                 `json:"actionType"`
+	Executed   bool                   `json:"executed"`
+	Status     string                 `json:"status"` // The PLAYER_ACTION_RESPONSE status, a validation error, or SKIPPED
+	Message    string                 `json:"message,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// BatchActionResultPayload is for "BATCH_ACTION_RESULT".
+type BatchActionResultPayload struct {
+	BatchID   string                  `json:"batchId,omitempty"`
+	Mode      string                  `json:"mode"`
+	Success   bool                    `json:"success"`   // Every step ran
+	Completed int                     `json:"completed"` // Number of steps that ran
+	Results   []BatchActionStepResult `json:"results"`
+}
+
+const (
+	MsgTypeBatchAction       = "BATCH_ACTION"
+	MsgTypeBatchActionResult = "BATCH_ACTION_RESULT"
+)
@@ -0,0 +1,64 @@
+package protocol
+
+// Battle pass. Players earn season XP from play and reach tiers, each with a
+// free reward and a premium one for players who unlocked the premium pass.
+// BATTLEPASS_REQUEST returns BATTLEPASS, which the server also pushes when the
+// player gains XP or a new season starts. BATTLEPASS_CLAIM claims the reward
+// of a reached tier and BATTLEPASS_UNLOCK unlocks the premium pass with an
+// on-chain payment; both are answered with BATTLEPASS.
+
+// BattlePassRequestPayload is for "BATTLEPASS_REQUEST".
+type BattlePassRequestPayload struct{}
+
+// BattlePassClaimRequestPayload is for "BATTLEPASS_CLAIM".
+type BattlePassClaimRequestPayload struct {
+	Tier  int    `json:"tier"`  // From 1
+	Track string `json:"track"` // free or premium
+}
+
+// BattlePassUnlockRequestPayload is for "BATTLEPASS_UNLOCK". The payment of
+// the season's premiumPrice must come from the player's primary linked
+// address.
+type BattlePassUnlockRequestPayload struct {
+	PaymentTxDigest string `json:"paymentTxDigest"`
+}
+
+// BattlePassRewardPayload is one reward of a tier.
+type BattlePassRewardPayload struct {
+	Coins    int64  `json:"coins,omitempty"`
+	ItemID   string `json:"itemId,omitempty"`
+	Quantity int    `json:"quantity,omitempty"`
+	Cosmetic string `json:"cosmetic,omitempty"` // Item NFT type, minted to the player's linked address
+	Claimed  bool   `json:"claimed"`
+}
+
+// BattlePassTierPayload is one tier of the season's track.
+type BattlePassTierPayload struct {
+	Tier    int                      `json:"tier"`
+	XP      int                      `json:"xp"` // Season XP that reaches the tier
+	Free    *BattlePassRewardPayload `json:"free,omitempty"`
+	Premium *BattlePassRewardPayload `json:"premium,omitempty"`
+}
+
+// BattlePassPayload is for "BATTLEPASS". Active is false between seasons,
+// when the other fields are empty.
+type BattlePassPayload struct {
+	Active          bool                    `json:"active"`
+	SeasonID        string                  `json:"seasonId,omitempty"`
+	Name            string                  `json:"name,omitempty"`
+	EndsAt          int64                   `json:"endsAt,omitempty"` // Unix milliseconds
+	XP              int                     `json:"xp"`
+	Tier            int                     `json:"tier"` // Highest tier reached
+	Premium         bool                    `json:"premium"`
+	PremiumPrice    uint64                  `json:"premiumPrice,omitempty"` // Absent if the premium pass is not for sale
+	PremiumCoinType string                  `json:"premiumCoinType,omitempty"`
+	Tiers           []BattlePassTierPayload `json:"tiers,omitempty"`
+	ServerTime      int64                   `json:"serverTime,omitempty"` // Server clock when sent, to count down to endsAt
+}
+
+const (
+	MsgTypeBattlePassRequest = "BATTLEPASS_REQUEST"
+	MsgTypeBattlePass        = "BATTLEPASS"
+	MsgTypeBattlePassClaim   = "BATTLEPASS_CLAIM"
+	MsgTypeBattlePassUnlock  = "BATTLEPASS_UNLOCK"
+)
@@ -0,0 +1,60 @@
+package protocol
+
+// Chat channels. Besides room chat and whispers, every world has named
+// channels: "global", "trade", "zone" (players in the same zone) and "guild"
+// (members of the same guild). A player is in at most one channel of each
+// kind, so channels are named by kind alone. Some are joined automatically on
+// login, zone entry or from the guild roster; CHANNEL_JOIN and CHANNEL_LEAVE
+// change that and are answered with CHANNEL_LIST. A message that cannot be
+// sent is answered with an ERROR with code CHANNEL_UNKNOWN,
+// CHANNEL_UNAVAILABLE, NOT_IN_CHANNEL or CHANNEL_THROTTLED.
+
+// ChannelRequestPayload is for "CHANNEL_JOIN" and "CHANNEL_LEAVE".
+type ChannelRequestPayload struct {
+	Channel string `json:"channel"` // "global", "trade", "zone" or "guild"
+}
+
+// ChannelSendPayload is for "CHANNEL_SEND".
+type ChannelSendPayload struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text" text:"500,multiline"`
+}
+
+// ChannelMessagePayload is for "CHANNEL_MESSAGE". It is sent to every member
+// of the channel, the sender included.
+type ChannelMessagePayload struct {
+	Channel  string `json:"channel"`
+	Scope    string `json:"scope,omitempty"` // Zone or guild ID of zone and guild channels
+	SenderID string `json:"senderId"`
+	Text     string `json:"text"`
+	SentAt   int64  `json:"sentAt"` // Unix milliseconds
+}
+
+// ChannelListRequestPayload is for "CHANNEL_LIST_REQUEST". It has no fields.
+type ChannelListRequestPayload struct{}
+
+// ChannelInfo is one channel in "CHANNEL_LIST".
+type ChannelInfo struct {
+	Channel           string `json:"channel"`
+	Scope             string `json:"scope,omitempty"`
+	Joined            bool   `json:"joined"`
+	Members           int    `json:"members"`
+	AutoJoin          bool   `json:"autoJoin,omitempty"`
+	MessagesPerMinute int    `json:"messagesPerMinute,omitempty"` // Per player; 0 is unlimited
+}
+
+// ChannelListPayload is for "CHANNEL_LIST". It lists the channels available
+// to the player; zone and guild are missing outside a zone or guild.
+type ChannelListPayload struct {
+	Channels []ChannelInfo `json:"channels"`
+}
+
+// Chat channel message types.
+const (
+	MsgTypeChannelJoin        = "CHANNEL_JOIN"
+	MsgTypeChannelLeave       = "CHANNEL_LEAVE"
+	MsgTypeChannelSend        = "CHANNEL_SEND"
+	MsgTypeChannelMessage     = "CHANNEL_MESSAGE"
+	MsgTypeChannelListRequest = "CHANNEL_LIST_REQUEST"
+	MsgTypeChannelList        = "CHANNEL_LIST"
+)
@@ -0,0 +1,50 @@
+package protocol
+
+// Whispers and chat history. Whispers are private messages between two players
+// in the same world. Recent room and whisper chat is kept so clients can show
+// it after joining a room or reconnecting.
+
+// WhisperRequestPayload is for "SEND_WHISPER".
+type WhisperRequestPayload struct {
+	ToPlayerID string `json:"toPlayerId"`
+	Text       string `json:"text" text:"500,multiline"`
+}
+
+// WhisperPayload is for "NEW_WHISPER". It is sent to the recipient, and to the
+// sender as confirmation of delivery.
+type WhisperPayload struct {
+	FromPlayerID string `json:"fromPlayerId"`
+	ToPlayerID   string `json:"toPlayerId"`
+	Text         string `json:"text"`
+	SentAt       int64  `json:"sentAt"` // Unix milliseconds
+}
+
+// ChatHistoryRequestPayload is for "CHAT_HISTORY_REQUEST". Set RoomID for the
+// room the player is in, or WithPlayerID for a whisper conversation.
+type ChatHistoryRequestPayload struct {
+	RoomID       string `json:"roomId,omitempty"`
+	WithPlayerID string `json:"withPlayerId,omitempty"`
+	Limit        int    `json:"limit,omitempty"` // Newest messages to return; the server caps it
+}
+
+// ChatHistoryEntry is one message in "CHAT_HISTORY".
+type ChatHistoryEntry struct {
+	SenderID   string `json:"senderId"`
+	SenderName string `json:"senderName,omitempty"`
+	Text       string `json:"text"`
+	SentAt     int64  `json:"sentAt"` // Unix milliseconds
+}
+
+// ChatHistoryPayload is for "CHAT_HISTORY". Messages are oldest first.
+type ChatHistoryPayload struct {
+	RoomID       string             `json:"roomId,omitempty"`
+	WithPlayerID string             `json:"withPlayerId,omitempty"`
+	Messages     []ChatHistoryEntry `json:"messages"`
+}
+
+const (
+	MsgTypeSendWhisper        = "SEND_WHISPER"
+	MsgTypeNewWhisper         = "NEW_WHISPER"
+	MsgTypeChatHistoryRequest = "CHAT_HISTORY_REQUEST"
+	MsgTypeChatHistory        = "CHAT_HISTORY"
+)
@@ -0,0 +1,74 @@
+package protocol
+
+// Turn-based combat. The server prompts each player on their turn, applies a
+// default action if they do not answer in time, and broadcasts the state after
+// every turn.
+
+// Combat actions.
+const (
+	CombatActionAttack  = "attack"
+	CombatActionDefend  = "defend"  // Doubles defense until the player's next turn
+	CombatActionFlee    = "flee"    // Leaves the fight if it succeeds; faster players flee more easily
+	CombatActionForfeit = "forfeit" // Concedes immediately; allowed at any time
+)
+
+// CombatantPayload is one combatant in combat messages.
+type CombatantPayload struct {
+	ID        string `json:"id"`
+	Team      int    `json:"team"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+	Defending bool   `json:"defending,omitempty"`
+	Fled      bool   `json:"fled,omitempty"`
+	Defeated  bool   `json:"defeated,omitempty"`
+}
+
+// CombatTurnPayload is for "COMBAT_TURN".
+type CombatTurnPayload struct {
+	CombatID     string   `json:"combatId"`
+	Round        int      `json:"round"`
+	Turn         int      `json:"turn"`
+	Deadline     int64    `json:"deadline"` // Unix milliseconds
+	ValidTargets []string `json:"validTargets"`
+	ServerTime   int64    `json:"serverTime,omitempty"` // Server clock when sent, to count down to deadline
+}
+
+// CombatActionPayload is for "COMBAT_ACTION".
+type CombatActionPayload struct {
+	CombatID string `json:"combatId"`
+	Action   string `json:"action"`
+	TargetID string `json:"targetId,omitempty"` // Required for attack
+}
+
+// CombatStatePayload is for "COMBAT_STATE". It is sent when a fight starts and after every turn.
+type CombatStatePayload struct {
+	CombatID    string             `json:"combatId"`
+	Round       int                `json:"round"`
+	Turn        int                `json:"turn"`
+	ActorID     string             `json:"actorId,omitempty"`
+	Action      string             `json:"action,omitempty"`
+	TargetID    string             `json:"targetId,omitempty"`
+	Damage      int                `json:"damage,omitempty"`
+	Critical    bool               `json:"critical,omitempty"`
+	Evaded      bool               `json:"evaded,omitempty"`
+	TimedOut    bool               `json:"timedOut,omitempty"`
+	Log         []string           `json:"log,omitempty"`
+	Combatants  []CombatantPayload `json:"combatants"`
+	TurnOrder   []string           `json:"turnOrder,omitempty"` // Only when the fight starts
+	NextActorID string             `json:"nextActorId,omitempty"`
+	ServerTime  int64              `json:"serverTime,omitempty"` // Server clock when sent, Unix milliseconds
+}
+
+// CombatEndedPayload is for "COMBAT_ENDED".
+type CombatEndedPayload struct {
+	CombatID   string `json:"combatId"`
+	WinnerTeam int    `json:"winnerTeam"` // -1 for a draw
+	Reason     string `json:"reason"`
+}
+
+const (
+	MsgTypeCombatTurn   = "COMBAT_TURN"
+	MsgTypeCombatAction = "COMBAT_ACTION"
+	MsgTypeCombatState  = "COMBAT_STATE"
+	MsgTypeCombatEnded  = "COMBAT_ENDED"
+)
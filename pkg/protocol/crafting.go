@@ -0,0 +1,51 @@
+package protocol
+
+// Crafting queues. CRAFT_START pays for a craft of a recipe and adds it to the
+// end of the player's queue, where crafts run one after another, online or
+// not; the server answers with CRAFT_QUEUE. CRAFT_QUEUE_REQUEST returns the
+// queue and the recipes. When a craft finishes the player gets mail and, if
+// connected, CRAFT_COMPLETED; its item NFT is then minted to them.
+
+// CraftStartRequestPayload is for "CRAFT_START".
+type CraftStartRequestPayload struct {
+	RecipeID string `json:"recipeId"`
+}
+
+// CraftQueueRequestPayload is for "CRAFT_QUEUE_REQUEST".
+type CraftQueueRequestPayload struct{}
+
+// CraftPayload is one craft, and the payload of "CRAFT_COMPLETED".
+type CraftPayload struct {
+	CraftID     string `json:"craftId"`
+	RecipeID    string `json:"recipeId"`
+	Name        string `json:"name"`
+	ItemType    string `json:"itemType"`
+	Stage       string `json:"stage"`       // queued, crafting or ready
+	StartsAt    int64  `json:"startsAt"`    // Unix milliseconds
+	CompletesAt int64  `json:"completesAt"` // Unix milliseconds
+}
+
+// RecipePayload is one recipe in "CRAFT_QUEUE".
+type RecipePayload struct {
+	RecipeID        string         `json:"recipeId"`
+	Name            string         `json:"name"`
+	ItemType        string         `json:"itemType"`
+	DurationSeconds int            `json:"durationSeconds"`
+	Coins           int64          `json:"coins,omitempty"`
+	Materials       map[string]int `json:"materials,omitempty"` // Inventory items used up, by item ID
+}
+
+// CraftQueuePayload is for "CRAFT_QUEUE". Crafts are in queue order.
+type CraftQueuePayload struct {
+	Crafts     []CraftPayload  `json:"crafts"`
+	Recipes    []RecipePayload `json:"recipes"`
+	MaxQueued  int             `json:"maxQueued"`
+	ServerTime int64           `json:"serverTime,omitempty"` // Server clock when sent, to count down to completesAt
+}
+
+const (
+	MsgTypeCraftStart        = "CRAFT_START"
+	MsgTypeCraftQueueRequest = "CRAFT_QUEUE_REQUEST"
+	MsgTypeCraftQueue        = "CRAFT_QUEUE"
+	MsgTypeCraftCompleted    = "CRAFT_COMPLETED"
+)
@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Errors of DecodeClientMessage. Text that fails sanitizing is reported as a
+// *TextError.
+var (
+	ErrUnknownTypeID = errors.New("unknown message type ID")
+	ErrInvalidUTF8   = errors.New("message is not valid UTF-8")
+	ErrInvalidJSON   = errors.New("message is not valid JSON")
+)
+
+// DecodeClientMessage turns the body of a client frame into a message with a
+// sanitized payload (see SanitizePayload). Version 2 frames with a type ID
+// carry only the payload; other frames carry an envelope.
+//
+// The payload is decoded once, into its registered type, and kept with the
+// message so DecodePayload can hand it out without decoding again. The
+// returned payload may share memory with body.
+func DecodeClientMessage(frameVersion byte, typeID uint16, body []byte) (ClientServerMessage, error) {
+	var msg ClientServerMessage
+	if frameVersion == FrameVersion2 && typeID != EnvelopeTypeID {
+		spec, ok := LookupMessageID(typeID)
+		if !ok {
+			return msg, fmt.Errorf("%w: %d", ErrUnknownTypeID, typeID)
+		}
+		msg = ClientServerMessage{Type: spec.Type, Payload: body}
+	} else if !utf8.Valid(body) {
+		// Checked before decoding, which would silently replace the bad bytes.
+		return msg, ErrInvalidUTF8
+	} else if err := json.Unmarshal(body, &msg); err != nil {
+		return msg, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if msg.Payload == nil {
+		return msg, nil
+	}
+	payload, decoded, err := sanitizePayload(msg.Type, msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload, msg.decoded = payload, decoded
+	return msg, nil
+}
+
+// AppendEnvelope appends the JSON envelope of a message whose payload is
+// already encoded, without re-encoding the payload. A nil payload is
+// written as null.
+func AppendEnvelope(dst []byte, msgType string, payload json.RawMessage, seq uint64) []byte {
+	dst = append(dst, `{"type":`...)
+	dst = appendJSONString(dst, msgType)
+	dst = append(dst, `,"payload":`...)
+	if len(payload) == 0 {
+		dst = append(dst, "null"...)
+	} else {
+		dst = append(dst, payload...)
+	}
+	if seq != 0 {
+		dst = append(dst, `,"seq":`...)
+		dst = strconv.AppendUint(dst, seq, 10)
+	}
+	return append(dst, '}')
+}
+
+// appendJSONString appends s as a JSON string. Message types are plain ASCII,
+// so anything else takes the slow path.
+func appendJSONString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, _ := json.Marshal(s)
+			return append(dst, quoted...)
+		}
+	}
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"')
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecodeClientMessage(t *testing.T) {
+	body := []byte(`{"type":"SEND_CHAT","payload":{"text":"hi\u0007 there"}}`)
+	msg, err := DecodeClientMessage(FrameVersionLegacy, EnvelopeTypeID, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chat ChatMessagePayload
+	if err := msg.DecodePayload(&chat); err != nil || chat.Text != "hi there" {
+		t.Fatalf("chat = %+v, %v", chat, err)
+	}
+	// The payload is re-encoded only because the text was cleaned.
+	if string(msg.Payload) != `{"text":"hi there"}` {
+		t.Fatalf("payload = %s", msg.Payload)
+	}
+
+	// Version 2 frames carry only the payload, which is kept as sent when clean.
+	move := []byte(`{"x":3,"y":4}`)
+	spec, _ := LookupMessage(MsgTypeMove)
+	if msg, err = DecodeClientMessage(FrameVersion2, spec.ID, move); err != nil || msg.Type != MsgTypeMove || &msg.Payload[0] != &move[0] {
+		t.Fatalf("move = %+v, %v", msg, err)
+	}
+	var decoded MovePayload
+	if err := msg.DecodePayload(&decoded); err != nil || decoded.X != 3 || decoded.Y != 4 {
+		t.Fatalf("decoded move = %+v, %v", decoded, err)
+	}
+
+	for _, tc := range []struct {
+		version byte
+		typeID  uint16
+		body    string
+		want    error
+	}{
+		{FrameVersion2, 9999, `{}`, ErrUnknownTypeID},
+		{FrameVersionLegacy, 0, "{\"type\":\"SEND_CHAT\",\"payload\":{\"text\":\"\xff\"}}", ErrInvalidUTF8},
+		{FrameVersionLegacy, 0, `{"type":`, ErrInvalidJSON},
+	} {
+		if _, err := DecodeClientMessage(tc.version, tc.typeID, []byte(tc.body)); !errors.Is(err, tc.want) {
+			t.Errorf("%q: err = %v, want %v", tc.body, err, tc.want)
+		}
+	}
+}
+
+func TestAppendEnvelopeMatchesMarshal(t *testing.T) {
+	payload := json.RawMessage(`{"text":"hi"}`)
+	want, _ := json.Marshal(ClientServerMessage{Type: MsgTypeNewChatMessage, Payload: payload, Seq: 7})
+	if got := AppendEnvelope(nil, MsgTypeNewChatMessage, payload, 7); string(got) != string(want) {
+		t.Fatalf("envelope = %s, want %s", got, want)
+	}
+	want, _ = json.Marshal(ClientServerMessage{Type: "<odd>"})
+	if got := AppendEnvelope(nil, "<odd>", nil, 0); string(got) != string(want) {
+		t.Fatalf("envelope = %s, want %s", got, want)
+	}
+}
+
+// decodeReencoded is how the session decoded client messages before
+// DecodeClientMessage: the payload was decoded generically, re-encoded for
+// sanitizing, decoded into a map for the tutorial gate, and re-encoded again
+// by the handler before its typed decode.
+func decodeReencoded(body []byte, v interface{}) error {
+	var msg struct {
+		Type    string      `json:"type"`
+		Payload interface{} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return err
+	}
+	if payload, err = SanitizePayload(msg.Type, payload); err != nil {
+		return err
+	}
+	var payloadMap map[string]interface{}
+	if err := json.Unmarshal(payload, &payloadMap); err != nil {
+		return err
+	}
+	payload, _ = json.Marshal(json.RawMessage(payload))
+	return json.Unmarshal(payload, v)
+}
+
+// BenchmarkDecodeClientMessage compares the old and new decoding of a chat
+// message and a move, from envelope to typed payload. Run with -benchmem to
+// see the allocations per message.
+func BenchmarkDecodeClientMessage(b *testing.B) {
+	chat := []byte(`{"type":"SEND_CHAT","payload":{"text":"anyone up for the arena tonight?"}}`)
+	move := []byte(`{"type":"MOVE","payload":{"x":120.5,"y":-33.25}}`)
+	cases := []struct {
+		name   string
+		body   []byte
+		target func() interface{}
+	}{
+		{"chat", chat, func() interface{} { return new(ChatMessagePayload) }},
+		{"move", move, func() interface{} { return new(MovePayload) }},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name+"/reencoded", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := decodeReencoded(tc.body, tc.target()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(tc.name+"/direct", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				msg, err := DecodeClientMessage(FrameVersionLegacy, EnvelopeTypeID, tc.body)
+				if err == nil {
+					err = msg.DecodePayload(tc.target())
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package protocol
+
+// Deep links. Partner sites embed signed links the server minted, each leading
+// to a room, a marketplace listing or a guild page. A client opened with one
+// sends its token in DEEP_LINK_OPEN once authenticated; the server checks it,
+// records who promoted the visit and answers DEEP_LINK_ROUTE with where to go.
+// For a room it also joins the player, as JOIN_ROOM would; the usual
+// JOIN_ROOM_RESPONSE follows. Listings and guild pages are for the client to
+// show.
+
+// DeepLinkOpenPayload is for "DEEP_LINK_OPEN".
+type DeepLinkOpenPayload struct {
+	Token string `json:"token" text:"1024,verbatim"`
+}
+
+// DeepLinkRoutePayload is for "DEEP_LINK_ROUTE".
+type DeepLinkRoutePayload struct {
+	LinkID string `json:"linkId"`
+	Kind   string `json:"kind"`   // room, listing or guild
+	Target string `json:"target"` // The room, listing or guild ID
+}
+
+const (
+	MsgTypeDeepLinkOpen  = "DEEP_LINK_OPEN"
+	MsgTypeDeepLinkRoute = "DEEP_LINK_ROUTE"
+)
@@ -0,0 +1,43 @@
+package protocol
+
+// Reliable delivery. A client that sets reliable in AUTH gets the important
+// server messages listed by IsReliable as envelopes carrying a seq number,
+// counting up from 1 per player. The client sends ACK with the highest seq it
+// has processed now and then; the server keeps the unacknowledged messages.
+// After a dropped connection, the client authenticates again with reliable and
+// lastSeq set, and the server re-sends everything after lastSeq that it still
+// has, before any new message. Seq numbers a client has seen may repeat in a
+// replay, so it should skip messages with a seq it has already processed.
+// AUTH_RESPONSE reports a gap when the server no longer has every message
+// after lastSeq, for instance after a long disconnect; the client then
+// refetches its state and counts seq numbers afresh, as they may restart at 1.
+//
+// Version 2 clients receive sequenced messages in envelope frames (type ID 0),
+// since the seq travels in the envelope.
+
+// AckPayload is for "ACK".
+type AckPayload struct {
+	Seq uint64 `json:"seq"` // Highest seq processed; acknowledges it and every seq before it
+}
+
+const (
+	MsgTypeAck = "ACK"
+)
+
+// reliableMessageTypes are the messages worth replaying: trade and gift
+// confirmations and rewards a player must not miss.
+var reliableMessageTypes = map[string]bool{
+	MsgTypeTradeUpdate:           true,
+	MsgTypeGiftUpdate:            true,
+	MsgTypeShopTransactionResult: true,
+	MsgTypeArenaMatchResult:      true,
+	MsgTypeCombatEnded:           true,
+	MsgTypeClaimZoneResponse:     true,
+	MsgTypeCraftCompleted:        true,
+}
+
+// IsReliable reports whether messages of msgType are sequenced and replayed
+// for clients that ask for reliable delivery.
+func IsReliable(msgType string) bool {
+	return reliableMessageTypes[msgType]
+}
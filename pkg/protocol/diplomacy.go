@@ -0,0 +1,76 @@
+package protocol
+
+// Guild diplomacy. A guild leader offers another guild an alliance with
+// ALLIANCE_PROPOSE; that guild's leader accepts or declines it with
+// ALLIANCE_RESPOND. A guild is in at most one alliance: a guild that accepts
+// the proposal of an alliance member joins that alliance, otherwise the two
+// form a new one. ALLIANCE_LEAVE takes the leader's guild out of its
+// alliance. RIVALRY_DECLARE and RIVALRY_END declare and withdraw a rivalry;
+// rivals cannot ally until it is withdrawn.
+//
+// Each of these, and DIPLOMACY_REQUEST, is answered with DIPLOMACY. Members
+// of allied guilds share the "alliance" chat channel and the guild events
+// opened to the alliance, and cannot besiege each other's zones.
+
+// AllianceProposeRequestPayload is for "ALLIANCE_PROPOSE".
+type AllianceProposeRequestPayload struct {
+	GuildID string `json:"guildId"`
+	Name    string `json:"name,omitempty" text:"32"` // Name of a new alliance; the server makes one up if empty
+}
+
+// AllianceRespondRequestPayload is for "ALLIANCE_RESPOND".
+type AllianceRespondRequestPayload struct {
+	GuildID string `json:"guildId"` // Guild that made the proposal
+	Accept  bool   `json:"accept"`
+}
+
+// AllianceLeaveRequestPayload is for "ALLIANCE_LEAVE".
+type AllianceLeaveRequestPayload struct{}
+
+// RivalryRequestPayload is for "RIVALRY_DECLARE" and "RIVALRY_END".
+type RivalryRequestPayload struct {
+	GuildID string `json:"guildId"`
+}
+
+// DiplomacyRequestPayload is for "DIPLOMACY_REQUEST".
+type DiplomacyRequestPayload struct {
+	GuildID string `json:"guildId,omitempty"` // The player's own guild if empty
+}
+
+// AlliancePayload is one alliance.
+type AlliancePayload struct {
+	AllianceID string   `json:"allianceId"`
+	Name       string   `json:"name"`
+	GuildIDs   []string `json:"guildIds"` // In the order they joined
+	FormedAt   int64    `json:"formedAt"` // Unix milliseconds
+}
+
+// AllianceProposalPayload is one open alliance proposal.
+type AllianceProposalPayload struct {
+	FromGuildID string `json:"fromGuildId"`
+	ToGuildID   string `json:"toGuildId"`
+	ProposedBy  string `json:"proposedBy"`
+	Name        string `json:"name"`      // Alliance the guild would join or form
+	ExpiresAt   int64  `json:"expiresAt"` // Unix milliseconds
+}
+
+// DiplomacyPayload is for "DIPLOMACY": a guild's alliance and rivalries, and
+// for the player's own guild its open proposals.
+type DiplomacyPayload struct {
+	GuildID  string                    `json:"guildId"`
+	Alliance *AlliancePayload          `json:"alliance,omitempty"`
+	Rivals   []string                  `json:"rivals"`  // Guilds it declared rivals
+	RivalOf  []string                  `json:"rivalOf"` // Guilds that declared it their rival
+	Incoming []AllianceProposalPayload `json:"incoming,omitempty"`
+	Outgoing []AllianceProposalPayload `json:"outgoing,omitempty"`
+}
+
+const (
+	MsgTypeDiplomacyRequest = "DIPLOMACY_REQUEST"
+	MsgTypeAlliancePropose  = "ALLIANCE_PROPOSE"
+	MsgTypeAllianceRespond  = "ALLIANCE_RESPOND"
+	MsgTypeAllianceLeave    = "ALLIANCE_LEAVE"
+	MsgTypeRivalryDeclare   = "RIVALRY_DECLARE"
+	MsgTypeRivalryEnd       = "RIVALRY_END"
+	MsgTypeDiplomacy        = "DIPLOMACY"
+)
@@ -0,0 +1,28 @@
+package protocol
+
+// When the server ends a session, its last message is DISCONNECT, sent just
+// before the connection is closed. It says why, and whether and when the
+// client should reconnect, so clients need not guess from how the socket
+// closed. A client that loses its connection without one should treat it
+// as a network failure and reconnect.
+
+// Disconnect codes. Parental controls end sessions with CURFEW or
+// PLAYTIME_LIMIT_REACHED.
+const (
+	DisconnectTimeout           = "TIMEOUT"            // No message, or no AUTH, in time
+	DisconnectMaintenance       = "MAINTENANCE"        // The server is shutting down
+	DisconnectKicked            = "KICKED"             // Removed by an operator
+	DisconnectAFK               = "AFK_KICK"           // Idle while the server was busy
+	DisconnectCheatDetected     = ErrCodeCheatDetected // Repeated rule violations
+	DisconnectProtocolViolation = "PROTOCOL_VIOLATION" // The client sent something the server cannot read, such as a frame over the size limit
+)
+
+// DisconnectPayload is the payload of DISCONNECT.
+type DisconnectPayload struct {
+	Code       string `json:"code"`
+	Reason     string `json:"reason"`               // For the player
+	Retryable  bool   `json:"retryable"`            // Reconnecting right away is expected to work; otherwise wait for the player
+	RetryAfter int    `json:"retryAfter,omitempty"` // Seconds to wait before reconnecting, when the server knows
+}
+
+const MsgTypeDisconnect = "DISCONNECT"
@@ -0,0 +1,25 @@
+package protocol
+
+// Energy. Some actions, such as CRAFT_START and joining the arena queue, spend
+// energy, which comes back one point every regenSeconds, online or not. The
+// server sends ENERGY after authentication, whenever the player's energy
+// changes while they are online, and in answer to ENERGY_REQUEST. An action
+// the player lacks the energy for is refused with NOT_ENOUGH_ENERGY.
+
+// EnergyRequestPayload is for "ENERGY_REQUEST".
+type EnergyRequestPayload struct{}
+
+// EnergyPayload is for "ENERGY".
+type EnergyPayload struct {
+	Energy       int            `json:"energy"`
+	Max          int            `json:"max"`
+	RegenSeconds int            `json:"regenSeconds"`          // Time to regain one point
+	NextRegenAt  int64          `json:"nextRegenAt,omitempty"` // Unix milliseconds; absent when full
+	Costs        map[string]int `json:"costs,omitempty"`       // Action -> energy it spends, e.g. "craft"
+	ServerTime   int64          `json:"serverTime,omitempty"`  // Server clock when sent, to count down to nextRegenAt
+}
+
+const (
+	MsgTypeEnergyRequest = "ENERGY_REQUEST"
+	MsgTypeEnergy        = "ENERGY"
+)
@@ -0,0 +1,44 @@
+package protocol
+
+// Verifiable loot. High-stakes loot tables are rolled from a random seed the
+// server commits to before the roll: LOOT_COMMITMENT carries the SHA-256 of
+// the seed of the player's next such roll, sent at login and after each roll.
+// LOOT_REVEAL follows the roll with the seed and the table, so the client can
+// recompute the loot. Draw i of a roll is the first 8 bytes of
+// HMAC-SHA256(key: seed, message: i as a big-endian uint64), read as a
+// big-endian uint64, shifted right by 11 and divided by 2^53. For each drop in
+// order, one draw below chance*dropRateMultiplier drops it, and a second
+// picks min + floor(draw*(max-min+1)) units, capped at max.
+
+// LootCommitmentPayload is for "LOOT_COMMITMENT".
+type LootCommitmentPayload struct {
+	CommitmentID string `json:"commitmentId"`
+	Hash         string `json:"hash"` // Hex SHA-256 of the seed
+	At           int64  `json:"at"`   // Unix milliseconds
+}
+
+// LootDropPayload is one entry of a rolled loot table.
+type LootDropPayload struct {
+	ItemID string  `json:"itemId"`
+	Chance float64 `json:"chance"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+}
+
+// LootRevealPayload is for "LOOT_REVEAL".
+type LootRevealPayload struct {
+	CommitmentID       string            `json:"commitmentId"`
+	Hash               string            `json:"hash"`
+	Seed               string            `json:"seed"` // Hex; its SHA-256 is hash
+	Table              string            `json:"table"`
+	Drops              []LootDropPayload `json:"drops"`
+	DropRateMultiplier float64           `json:"dropRateMultiplier"`
+	Draws              int               `json:"draws"`
+	Loot               map[string]int    `json:"loot"` // Item ID -> quantity
+	At                 int64             `json:"at"`   // Unix milliseconds
+}
+
+const (
+	MsgTypeLootCommitment = "LOOT_COMMITMENT"
+	MsgTypeLootReveal     = "LOOT_REVEAL"
+)
@@ -0,0 +1,224 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Framing
+//
+// Version 1 (legacy) frames are a 4-byte big-endian length followed by a JSON
+// ClientServerMessage envelope. Version 2 frames have an 8-byte header:
+//
+//	byte 0    version (2)
+//	byte 1    flags (FrameFlag*)
+//	bytes 2-3 message type ID, big-endian (see MessageSpec.ID)
+//	bytes 4-7 body length, big-endian
+//
+// and a body holding only the JSON payload, so the receiver can route on the
+// type ID without parsing an envelope. Type ID 0 means the body is a full
+// envelope, for message types without an ID.
+//
+// The two versions are told apart by the first byte: a legacy length prefix
+// starts with 0 because frames are far smaller than 16 MiB. A server answers
+// in the version the client first sent, so legacy clients are unaffected.
+
+// Frame versions.
+const (
+	FrameVersionLegacy byte = 1
+	FrameVersion2      byte = 2
+)
+
+// Frame header sizes in bytes.
+const (
+	LegacyFrameHeaderSize = 4
+	FrameHeaderSize       = 8
+)
+
+// FrameFlags are the bits of a version 2 frame's flags byte.
+type FrameFlags byte
+
+const (
+	FrameFlagCompressed FrameFlags = 1 << 0 // Body is DEFLATE-compressed (RFC 1951)
+	FrameFlagEncrypted  FrameFlags = 1 << 1 // Reserved for transport encryption; not accepted yet
+	FrameFlagKeyframe   FrameFlags = 1 << 2 // Body is a full state snapshot rather than a delta
+)
+
+// EnvelopeTypeID marks a version 2 frame whose body is a full ClientServerMessage envelope.
+const EnvelopeTypeID uint16 = 0
+
+// ErrFrameTooLarge is returned when a frame's declared length exceeds the limit.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// Frame is one decoded frame. Body is decompressed, and for version 2 frames
+// with a type ID it holds only the payload JSON.
+type Frame struct {
+	Version byte
+	Flags   FrameFlags
+	TypeID  uint16
+	Body    []byte
+	Size    int // Bytes read from the wire: header plus body before decompression
+}
+
+// ReadFrame reads one frame of either version from r. Bodies larger than
+// maxSize, before or after decompression, are rejected with ErrFrameTooLarge.
+func ReadFrame(r io.Reader, maxSize uint32) (Frame, error) {
+	var header [FrameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:1]); err != nil {
+		return Frame{}, err
+	}
+
+	frame := Frame{Version: FrameVersionLegacy}
+	var length uint32
+	switch header[0] {
+	case 0:
+		if _, err := io.ReadFull(r, header[1:LegacyFrameHeaderSize]); err != nil {
+			return Frame{}, err
+		}
+		length = binary.BigEndian.Uint32(header[:LegacyFrameHeaderSize])
+		frame.Size = LegacyFrameHeaderSize
+	case FrameVersion2:
+		if _, err := io.ReadFull(r, header[1:]); err != nil {
+			return Frame{}, err
+		}
+		frame.Version = FrameVersion2
+		frame.Flags = FrameFlags(header[1])
+		frame.TypeID = binary.BigEndian.Uint16(header[2:4])
+		length = binary.BigEndian.Uint32(header[4:8])
+		frame.Size = FrameHeaderSize
+	default:
+		return Frame{}, fmt.Errorf("unsupported frame version %d", header[0])
+	}
+	if length > maxSize {
+		return Frame{}, fmt.Errorf("%w: %d bytes exceeds %d", ErrFrameTooLarge, length, maxSize)
+	}
+
+	frame.Size += int(length)
+	if frame.Flags&FrameFlagEncrypted != 0 {
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return Frame{}, err
+		}
+		return Frame{}, errors.New("encrypted frames are not supported")
+	}
+	if frame.Flags&FrameFlagCompressed != 0 {
+		body, err := readCompressed(r, length, maxSize)
+		if err != nil {
+			return Frame{}, err
+		}
+		frame.Body = body
+		return frame, nil
+	}
+	body, err := readBody(r, length)
+	if err != nil {
+		return Frame{}, err
+	}
+	frame.Body = body
+	return frame, nil
+}
+
+// maxPooledBuffer bounds the compressed-body buffers kept for reuse, so one
+// large frame does not pin its buffer. It is also the most memory reserved
+// for a body before its bytes arrive.
+const maxPooledBuffer = 64 << 10
+
+// readBody reads a body of length bytes from r. Large bodies grow as their
+// bytes arrive rather than being allocated from the declared length, so a
+// header alone cannot make the server reserve maxSize. The body is handed to
+// the caller, so it cannot come from a pool.
+func readBody(r io.Reader, length uint32) ([]byte, error) {
+	if length <= maxPooledBuffer {
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+	var body bytes.Buffer
+	body.Grow(maxPooledBuffer)
+	if _, err := io.CopyN(&body, r, int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// Compressed bodies are read into pooled buffers and inflated by pooled
+// readers; only the decompressed body is allocated per frame.
+var (
+	compressedBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+	inflaters         sync.Pool // of io.ReadCloser implementing flate.Resetter
+)
+
+// readCompressed reads a compressed body of length bytes from r and returns
+// it decompressed.
+func readCompressed(r io.Reader, length, maxSize uint32) ([]byte, error) {
+	compressed := compressedBuffers.Get().(*bytes.Buffer)
+	compressed.Reset()
+	if length <= maxPooledBuffer {
+		compressed.Grow(int(length))
+	}
+	defer func() {
+		if compressed.Cap() <= maxPooledBuffer {
+			compressedBuffers.Put(compressed)
+		}
+	}()
+	if _, err := io.CopyN(compressed, r, int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	source := bytes.NewReader(compressed.Bytes())
+	inflater, _ := inflaters.Get().(io.ReadCloser)
+	if inflater == nil {
+		inflater = flate.NewReader(source)
+	} else if err := inflater.(flate.Resetter).Reset(source, nil); err != nil {
+		return nil, fmt.Errorf("decompress frame: %w", err)
+	}
+	defer inflaters.Put(inflater)
+	decompressed, err := io.ReadAll(io.LimitReader(inflater, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress frame: %w", err)
+	}
+	if uint32(len(decompressed)) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed body exceeds %d", ErrFrameTooLarge, maxSize)
+	}
+	return decompressed, nil
+}
+
+// EncodeFrame builds a frame. Legacy frames ignore flags and typeID; for
+// version 2, FrameFlagCompressed compresses body.
+func EncodeFrame(version byte, flags FrameFlags, typeID uint16, body []byte) ([]byte, error) {
+	if version != FrameVersion2 {
+		frame := make([]byte, LegacyFrameHeaderSize+len(body))
+		binary.BigEndian.PutUint32(frame, uint32(len(body)))
+		copy(frame[LegacyFrameHeaderSize:], body)
+		return frame, nil
+	}
+	if flags&FrameFlagCompressed != 0 {
+		var compressed bytes.Buffer
+		writer, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		body = compressed.Bytes()
+	}
+	frame := make([]byte, FrameHeaderSize+len(body))
+	frame[0] = FrameVersion2
+	frame[1] = byte(flags)
+	binary.BigEndian.PutUint16(frame[2:4], typeID)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	copy(frame[FrameHeaderSize:], body)
+	return frame, nil
+}
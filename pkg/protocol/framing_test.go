@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	body := []byte(`{"text":"` + strings.Repeat("hello ", 200) + `"}`)
+	cases := []struct {
+		name    string
+		version byte
+		flags   FrameFlags
+		typeID  uint16
+	}{
+		{"legacy", FrameVersionLegacy, 0, 0},
+		{"v2", FrameVersion2, FrameFlagKeyframe, 7},
+		{"v2 compressed", FrameVersion2, FrameFlagCompressed, 7},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := EncodeFrame(tc.version, tc.flags, tc.typeID, body)
+			if err != nil {
+				t.Fatalf("EncodeFrame: %v", err)
+			}
+			if tc.flags&FrameFlagCompressed != 0 && len(encoded) >= len(body) {
+				t.Errorf("compressed frame is %d bytes for a %d byte body", len(encoded), len(body))
+			}
+			frame, err := ReadFrame(bytes.NewReader(encoded), 1<<20)
+			if err != nil {
+				t.Fatalf("ReadFrame: %v", err)
+			}
+			if frame.Version != tc.version || frame.TypeID != tc.typeID || frame.Flags != tc.flags || !bytes.Equal(frame.Body, body) {
+				t.Fatalf("got version=%d flags=%d type=%d body=%d bytes", frame.Version, frame.Flags, frame.TypeID, len(frame.Body))
+			}
+			if frame.Size != len(encoded) {
+				t.Errorf("frame size = %d, want %d", frame.Size, len(encoded))
+			}
+		})
+	}
+}
+
+func TestReadFrameRejectsOversizedAndUnknownFrames(t *testing.T) {
+	encoded, _ := EncodeFrame(FrameVersion2, 0, 1, make([]byte, 100))
+	if _, err := ReadFrame(bytes.NewReader(encoded), 50); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("oversized frame: err = %v, want ErrFrameTooLarge", err)
+	}
+	// A small compressed body that inflates past the limit.
+	bomb, _ := EncodeFrame(FrameVersion2, FrameFlagCompressed, 1, make([]byte, 10000))
+	if _, err := ReadFrame(bytes.NewReader(bomb), 1000); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("compressed frame: err = %v, want ErrFrameTooLarge", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{9, 0, 0, 0, 0, 0, 0, 0}), 50); err == nil {
+		t.Error("expected an error for an unknown frame version")
+	}
+}
+
+func TestMessageIDsAreUnique(t *testing.T) {
+	seen := map[uint16]string{}
+	for _, spec := range Messages() {
+		if spec.ID == EnvelopeTypeID {
+			t.Errorf("message %s has no ID", spec.Type)
+		}
+		if other, dup := seen[spec.ID]; dup {
+			t.Errorf("messages %s and %s share ID %d", other, spec.Type, spec.ID)
+		}
+		seen[spec.ID] = spec.Type
+		if found, ok := LookupMessageID(spec.ID); !ok || found.Type != spec.Type {
+			t.Errorf("LookupMessageID(%d) = %v, %t", spec.ID, found.Type, ok)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+	"unicode/utf8"
+)
+
+// The fuzz targets feed malformed client input to the framing decoder and the
+// payload parsers. Without -fuzz they only run their seeds, as regular tests.
+// To fuzz one:
+//
+//	go test ./pkg/protocol -run '^$' -fuzz FuzzReadFrame -fuzztime 60s
+//
+// Inputs that fail are saved under testdata/fuzz and replayed by go test from
+// then on.
+
+// fuzzMaxFrame is the frame limit of the fuzz targets, small enough that an
+// input can reach it.
+const fuzzMaxFrame = 4 << 10
+
+// payloadSeeds returns the JSON of a zero payload of every client message,
+// with the message's type ID.
+func payloadSeeds() map[uint16][]byte {
+	seeds := make(map[uint16][]byte)
+	for _, spec := range Messages() {
+		if spec.Payload == nil || spec.Direction == DirectionServerToClient {
+			continue
+		}
+		body, err := json.Marshal(spec.Payload)
+		if err != nil {
+			panic(err)
+		}
+		seeds[spec.ID] = body
+	}
+	return seeds
+}
+
+func FuzzReadFrame(f *testing.F) {
+	for id, body := range payloadSeeds() {
+		for _, flags := range []FrameFlags{0, FrameFlagCompressed, FrameFlagKeyframe} {
+			frame, _ := EncodeFrame(FrameVersion2, flags, id, body)
+			f.Add(frame)
+		}
+	}
+	legacy, _ := EncodeFrame(FrameVersionLegacy, 0, 0, []byte(`{"type":"PING","payload":{}}`))
+	f.Add(legacy)
+	f.Add([]byte{FrameVersion2, byte(FrameFlagEncrypted), 0, 1, 0, 0, 0, 2, '{', '}'})
+	f.Add([]byte{0, 0, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frame, err := ReadFrame(bytes.NewReader(data), fuzzMaxFrame)
+		if err != nil {
+			return
+		}
+		if len(frame.Body) > fuzzMaxFrame {
+			t.Fatalf("%d byte body passed a limit of %d", len(frame.Body), fuzzMaxFrame)
+		}
+		if frame.Size > len(data) {
+			t.Fatalf("frame of %d bytes read from %d", frame.Size, len(data))
+		}
+		if frame.Flags&FrameFlagCompressed != 0 {
+			return // DEFLATE has many encodings of one body
+		}
+		encoded, err := EncodeFrame(frame.Version, frame.Flags, frame.TypeID, frame.Body)
+		if err != nil || !bytes.Equal(encoded, data[:frame.Size]) {
+			t.Fatalf("re-encoded frame %x differs from the %x read, %v", encoded, data[:frame.Size], err)
+		}
+	})
+}
+
+func FuzzDecodeClientMessage(f *testing.F) {
+	for id, body := range payloadSeeds() {
+		f.Add(FrameVersion2, id, body)
+		spec, _ := LookupMessageID(id)
+		f.Add(FrameVersionLegacy, EnvelopeTypeID, AppendEnvelope(nil, spec.Type, body, 0))
+	}
+	chat, _ := LookupMessage(MsgTypeSendChat)
+	f.Add(FrameVersion2, chat.ID, []byte(`{"text":"e\u0007́ ‮\n�"}`))
+	action, _ := LookupMessage(MsgTypePlayerAction)
+	f.Add(FrameVersion2, action.ID, []byte(`{"actionType":"x","data":{"a":[{"b":{"c":"\u0000"}}],"\u0085k":1}}`))
+	f.Add(FrameVersionLegacy, EnvelopeTypeID, []byte(`{"type":"SEND_CHAT","payload":"\xff"}`))
+
+	f.Fuzz(func(t *testing.T, version byte, typeID uint16, body []byte) {
+		msg, err := DecodeClientMessage(version, typeID, body)
+		if err != nil {
+			return
+		}
+		if msg.Payload == nil {
+			return
+		}
+		if !utf8.Valid(msg.Payload) {
+			t.Fatalf("accepted a payload that is not UTF-8: %q", msg.Payload)
+		}
+		// Cleaned text stays clean: the server may sanitize a payload again,
+		// e.g. when it forwards one, and must not see it change.
+		again, err := SanitizePayload(msg.Type, msg.Payload)
+		if err != nil || !bytes.Equal(again, msg.Payload) {
+			t.Fatalf("sanitizing %s again gave %s, %v", msg.Payload, again, err)
+		}
+		spec, ok := LookupMessage(msg.Type)
+		if !ok || spec.Payload == nil {
+			return
+		}
+		decoded := reflect.New(reflect.TypeOf(spec.Payload))
+		if err := msg.DecodePayload(decoded.Interface()); err != nil && msg.decoded.IsValid() {
+			t.Fatalf("a payload that decoded once failed to decode: %v", err)
+		}
+	})
+}
+
+// TestReadFrameAllocatesAsTheBodyArrives checks that a header declaring a
+// large body does not make ReadFrame reserve the whole of it up front.
+func TestReadFrameAllocatesAsTheBodyArrives(t *testing.T) {
+	const declared = 64 << 20
+	for _, flags := range []FrameFlags{0, FrameFlagCompressed} {
+		header := []byte{FrameVersion2, byte(flags), 0, 1, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[4:], declared)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := ReadFrame(io.MultiReader(bytes.NewReader(header), bytes.NewReader([]byte("{}"))), declared)
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("flags %d: err = %v, want io.ErrUnexpectedEOF", flags, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > declared/16 {
+			t.Errorf("flags %d: allocated %d bytes for a 2 byte body declared as %d", flags, allocated, declared)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package protocol
+
+// Player-to-player gifts of item NFTs. A player offers an item they own with
+// GIFT_SEND; both players then get GIFT_UPDATE whenever the gift changes.
+//
+// When a transfer is due from the sender, their GIFT_UPDATE carries txBytes:
+// an unsigned transfer of the item to sign and execute, after which they send
+// GIFT_SENT with its digest. Gifts worth more than the server's acceptance
+// threshold wait for the recipient to answer with GIFT_RESPOND. If the server
+// holds such gifts in custody, the sender transfers the item to the custody
+// address first; the server releases it to the recipient on acceptance and
+// returns it to the sender if the gift is declined, cancelled or expires.
+// Otherwise the sender's transfer to the recipient follows the acceptance.
+// GIFT_HISTORY_REQUEST returns the player's finished gifts.
+
+// Gift respond actions.
+const (
+	GiftActionAccept  = "accept"  // The recipient takes the gift
+	GiftActionDecline = "decline" // The recipient refuses it
+	GiftActionCancel  = "cancel"  // The sender withdraws it
+)
+
+// GiftSendRequestPayload is for "GIFT_SEND".
+type GiftSendRequestPayload struct {
+	RecipientID string `json:"recipientId"`
+	ItemID      string `json:"itemId"` // Item NFT object ID
+	Note        string `json:"note,omitempty"`
+}
+
+// GiftRespondRequestPayload is for "GIFT_RESPOND".
+type GiftRespondRequestPayload struct {
+	GiftID string `json:"giftId"`
+	Action string `json:"action"` // GiftActionAccept, GiftActionDecline or GiftActionCancel
+}
+
+// GiftSentRequestPayload is for "GIFT_SENT", sent after the player's transfer executed.
+type GiftSentRequestPayload struct {
+	GiftID   string `json:"giftId"`
+	TxDigest string `json:"txDigest"`
+}
+
+// GiftPayload is one gift, and the payload of "GIFT_UPDATE".
+type GiftPayload struct {
+	GiftID          string `json:"giftId"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	ItemID          string `json:"itemId"`
+	ItemType        string `json:"itemType"`
+	Note            string `json:"note,omitempty"`
+	Value           uint64 `json:"value"` // Estimated MIST value
+	NeedsAcceptance bool   `json:"needsAcceptance"`
+	Custody         bool   `json:"custody"`            // Held by the server until accepted
+	Status          string `json:"status"`             // awaiting_deposit, pending, awaiting_transfer, releasing, returning, completed, declined, cancelled, expired or returned
+	TxBytes         string `json:"txBytes,omitempty"`  // Unsigned transfer for the sender to sign
+	TxDigest        string `json:"txDigest,omitempty"` // Transaction that delivered or returned the item
+	CreatedAt       int64  `json:"createdAt"`          // Unix milliseconds
+	UpdatedAt       int64  `json:"updatedAt"`          // Unix milliseconds
+	Deadline        int64  `json:"deadline,omitempty"` // Unix milliseconds; for the transfer or the answer
+}
+
+// GiftHistoryRequestPayload is for "GIFT_HISTORY_REQUEST".
+type GiftHistoryRequestPayload struct{}
+
+// GiftHistoryPayload is for "GIFT_HISTORY". Gifts are newest first.
+type GiftHistoryPayload struct {
+	Gifts []GiftPayload `json:"gifts"`
+}
+
+const (
+	MsgTypeGiftSend           = "GIFT_SEND"
+	MsgTypeGiftRespond        = "GIFT_RESPOND"
+	MsgTypeGiftSent           = "GIFT_SENT"
+	MsgTypeGiftUpdate         = "GIFT_UPDATE"
+	MsgTypeGiftHistoryRequest = "GIFT_HISTORY_REQUEST"
+	MsgTypeGiftHistory        = "GIFT_HISTORY"
+)
@@ -0,0 +1,68 @@
+package protocol
+
+// Guild event calendar. Guild members whose rank has the "events" permission,
+// officers by default, schedule raids and meetings with GUILD_EVENT_CREATE;
+// members sign up or back out with GUILD_EVENT_RSVP, and those ranks call
+// events off with GUILD_EVENT_CANCEL. Each of these is answered with the
+// event as GUILD_EVENT; GUILD_EVENTS_REQUEST returns the guild's calendar as
+// GUILD_EVENTS. Attendees are mailed a reminder before the start.
+// At the start the server opens a private room only the attendees may join
+// and sends the online ones GUILD_EVENT_STARTED with its roomId; offline
+// attendees find it in their mail. Events created with "alliance" set are on
+// the calendars of the guild's allies too, whose members may RSVP.
+
+// GuildEventCreateRequestPayload is for "GUILD_EVENT_CREATE".
+type GuildEventCreateRequestPayload struct {
+	Kind        string `json:"kind"` // "raid" or "meeting"
+	Title       string `json:"title" text:"64"`
+	Description string `json:"description,omitempty" text:"500,multiline"`
+	StartsAt    int64  `json:"startsAt"`           // Unix milliseconds
+	Capacity    int    `json:"capacity,omitempty"` // Most attendees; the server's maximum if 0
+	Alliance    bool   `json:"alliance,omitempty"` // Open to the members of allied guilds
+}
+
+// GuildEventRSVPRequestPayload is for "GUILD_EVENT_RSVP".
+type GuildEventRSVPRequestPayload struct {
+	EventID string `json:"eventId"`
+	Going   bool   `json:"going"`
+}
+
+// GuildEventCancelRequestPayload is for "GUILD_EVENT_CANCEL".
+type GuildEventCancelRequestPayload struct {
+	EventID string `json:"eventId"`
+}
+
+// GuildEventsRequestPayload is for "GUILD_EVENTS_REQUEST".
+type GuildEventsRequestPayload struct{}
+
+// GuildEventPayload is one event, and the payload of "GUILD_EVENT" and
+// "GUILD_EVENT_STARTED".
+type GuildEventPayload struct {
+	EventID     string   `json:"eventId"`
+	GuildID     string   `json:"guildId,omitempty"` // Guild that scheduled it; the player's own if absent
+	Kind        string   `json:"kind"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	CreatedBy   string   `json:"createdBy"`
+	StartsAt    int64    `json:"startsAt"` // Unix milliseconds
+	Capacity    int      `json:"capacity"`
+	Attendees   []string `json:"attendees"`
+	Status      string   `json:"status"`           // scheduled, started or cancelled
+	RoomID      string   `json:"roomId,omitempty"` // Room of the attendees, once started
+	Alliance    bool     `json:"alliance,omitempty"`
+}
+
+// GuildEventsPayload is for "GUILD_EVENTS". Events are by start time.
+type GuildEventsPayload struct {
+	Events []GuildEventPayload `json:"events"`
+}
+
+const (
+	MsgTypeGuildEventCreate   = "GUILD_EVENT_CREATE"
+	MsgTypeGuildEventRSVP     = "GUILD_EVENT_RSVP"
+	MsgTypeGuildEventCancel   = "GUILD_EVENT_CANCEL"
+	MsgTypeGuildEventsRequest = "GUILD_EVENTS_REQUEST"
+	MsgTypeGuildEvent         = "GUILD_EVENT"
+	MsgTypeGuildEvents        = "GUILD_EVENTS"
+	MsgTypeGuildEventStarted  = "GUILD_EVENT_STARTED"
+)
@@ -0,0 +1,81 @@
+package protocol
+
+// Guild ranks and the permission matrix. Every guild has a leader, an
+// officer and a member rank; the leader redefines the last two and adds
+// custom ranks with GUILD_RANK_DEFINE, removes custom ranks with
+// GUILD_RANK_DELETE and moves members between ranks with GUILD_RANK_ASSIGN.
+// Permissions are "invite", "kick", "bankWithdraw" (up to withdrawPerDay game
+// coin per UTC day, unlimited if 0) and "events" (schedule and cancel guild
+// events). GUILD_RANKS_REQUEST and the define and delete requests are
+// answered with GUILD_RANKS.
+//
+// GUILD_INVITE, GUILD_KICK, GUILD_BANK_WITHDRAW and GUILD_RANK_ASSIGN are
+// answered with GUILD_ACTION once the player's rank allows them. For on-chain
+// guilds it carries txBytes: the unsigned guild contract call for the player
+// to sign and execute. Each rank maps to the contract's "officer" or "member"
+// rank; assigning one that maps differently from the member's last rank
+// comes with the promotion or demotion.
+
+// GuildRankPayload is one rank, and the payload of "GUILD_RANK_DEFINE".
+type GuildRankPayload struct {
+	Name           string   `json:"name" text:"24"`
+	Permissions    []string `json:"permissions"`
+	WithdrawPerDay uint64   `json:"withdrawPerDay,omitempty"`
+	OnChainRank    string   `json:"onChainRank,omitempty"` // "officer" or "member"; "member" if empty, except for the officer rank
+}
+
+// GuildRanksRequestPayload is for "GUILD_RANKS_REQUEST".
+type GuildRanksRequestPayload struct{}
+
+// GuildRankDeleteRequestPayload is for "GUILD_RANK_DELETE". Deleting the
+// officer or member rank restores the server's definition.
+type GuildRankDeleteRequestPayload struct {
+	Name string `json:"name" text:"24"`
+}
+
+// GuildRankAssignRequestPayload is for "GUILD_RANK_ASSIGN".
+type GuildRankAssignRequestPayload struct {
+	PlayerID string `json:"playerId"`
+	Rank     string `json:"rank" text:"24"`
+}
+
+// GuildMemberRequestPayload is for "GUILD_INVITE" and "GUILD_KICK".
+type GuildMemberRequestPayload struct {
+	PlayerID string `json:"playerId"`
+}
+
+// GuildBankWithdrawRequestPayload is for "GUILD_BANK_WITHDRAW".
+type GuildBankWithdrawRequestPayload struct {
+	Amount uint64 `json:"amount"` // Game coin
+}
+
+// GuildRanksPayload is for "GUILD_RANKS". Ranks start with leader, officer
+// and member.
+type GuildRanksPayload struct {
+	GuildID string             `json:"guildId"`
+	Ranks   []GuildRankPayload `json:"ranks"`
+	Members map[string]string  `json:"members"` // Player ID -> rank name
+}
+
+// GuildActionPayload is for "GUILD_ACTION".
+type GuildActionPayload struct {
+	Action    string `json:"action"` // "invite", "kick", "withdraw" or "rank"
+	GuildID   string `json:"guildId"`
+	PlayerID  string `json:"playerId,omitempty"` // Player invited, kicked or ranked
+	Rank      string `json:"rank,omitempty"`
+	Amount    uint64 `json:"amount,omitempty"`
+	Remaining uint64 `json:"remaining,omitempty"` // Game coin the rank may still withdraw today, under a limit
+	TxBytes   string `json:"txBytes,omitempty"`   // Unsigned guild contract call to sign
+}
+
+const (
+	MsgTypeGuildRanksRequest = "GUILD_RANKS_REQUEST"
+	MsgTypeGuildRankDefine   = "GUILD_RANK_DEFINE"
+	MsgTypeGuildRankDelete   = "GUILD_RANK_DELETE"
+	MsgTypeGuildRankAssign   = "GUILD_RANK_ASSIGN"
+	MsgTypeGuildInvite       = "GUILD_INVITE"
+	MsgTypeGuildKick         = "GUILD_KICK"
+	MsgTypeGuildBankWithdraw = "GUILD_BANK_WITHDRAW"
+	MsgTypeGuildRanks        = "GUILD_RANKS"
+	MsgTypeGuildAction       = "GUILD_ACTION"
+)
@@ -0,0 +1,25 @@
+package protocol
+
+// Login prefetch. When a player with a primary linked address logs in, the
+// server reads their coin balances, profile object and item NFTs from the
+// chain at once and waits a moment for them. AUTH_RESPONSE carries what was
+// read as ready, so the client can draw its first screens without asking.
+// Parts listed as pending are still being read; the client asks for them the
+// usual way when it needs them.
+
+// LoginReadyPayload is "ready" in "AUTH_RESPONSE".
+type LoginReadyPayload struct {
+	Address   string        `json:"address,omitempty"` // Primary linked address; empty if the player has none and nothing was read
+	Ready     []string      `json:"ready,omitempty"`   // Parts read in time: "balances", "profile", "items"
+	Pending   []string      `json:"pending,omitempty"` // Parts still being read
+	Failed    []string      `json:"failed,omitempty"`  // Parts the chain could not be read for
+	Balances  []CoinBalance `json:"balances,omitempty"`
+	ProfileID string        `json:"profileId,omitempty"` // Object ID of the player's profile object
+	Items     []string      `json:"items,omitempty"`     // Item types of the item NFTs the address owns
+}
+
+// CoinBalance is the balance of one coin type.
+type CoinBalance struct {
+	CoinType string `json:"coinType"`
+	Total    string `json:"total"` // Decimal, in the coin's smallest unit
+}
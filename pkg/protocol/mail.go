@@ -0,0 +1,42 @@
+package protocol
+
+// Mail. The server leaves players messages that wait until read, such as a
+// notice that a marketplace listing expired. A player asks for their mailbox
+// with MAIL_LIST_REQUEST and gets MAIL_LIST; MAIL_READ and MAIL_DELETE change
+// it and are answered with a fresh MAIL_LIST. Mail arriving while the player
+// is online is pushed as MAIL_NEW.
+
+// MailListRequestPayload is for "MAIL_LIST_REQUEST".
+type MailListRequestPayload struct{}
+
+// MailMessagePayload is one message, and the payload of "MAIL_NEW".
+type MailMessagePayload struct {
+	ID      string `json:"id"`
+	From    string `json:"from"` // "system" or a player ID
+	Kind    string `json:"kind"` // e.g. "market.listing_expired"
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Ref     string `json:"ref,omitempty"` // ID of the object the mail is about
+	SentAt  int64  `json:"sentAt"`        // Unix milliseconds
+	Read    bool   `json:"read"`
+}
+
+// MailListPayload is for "MAIL_LIST". Messages are newest first.
+type MailListPayload struct {
+	Messages []MailMessagePayload `json:"messages"`
+	Unread   int                  `json:"unread"`
+}
+
+// MailIDsPayload is for "MAIL_READ" and "MAIL_DELETE".
+type MailIDsPayload struct {
+	IDs []string `json:"ids"`
+}
+
+// Mail message types.
+const (
+	MsgTypeMailListRequest = "MAIL_LIST_REQUEST"
+	MsgTypeMailList        = "MAIL_LIST"
+	MsgTypeMailRead        = "MAIL_READ"
+	MsgTypeMailDelete      = "MAIL_DELETE"
+	MsgTypeMailNew         = "MAIL_NEW"
+)
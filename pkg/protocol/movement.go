@@ -0,0 +1,50 @@
+package protocol
+
+// Position sync. Clients report their own movement with MOVE; the room checks
+// it against the map's collision data and sends POSITION_UPDATE to the other
+// members, or back to the mover with corrected set if the move was refused.
+// Movement abilities (USE_MOVEMENT_ABILITY) are answered with
+// MOVEMENT_ABILITY_RESULT and broadcast as POSITION_UPDATE with the ability.
+// Players entering a room receive a POSITION_UPDATE for every member and NPC.
+// NPCs' moves are broadcast as POSITION_UPDATE with npc set.
+
+// MovePayload is for "MOVE".
+type MovePayload struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// UseMovementAbilityPayload is for "USE_MOVEMENT_ABILITY".
+type UseMovementAbilityPayload struct {
+	AbilityID string  `json:"abilityId"` // From the server's skill data, e.g. "dash"
+	X         float64 `json:"x"`         // Target point
+	Y         float64 `json:"y"`
+}
+
+// MovementAbilityResultPayload is for "MOVEMENT_ABILITY_RESULT".
+type MovementAbilityResultPayload struct {
+	AbilityID  string  `json:"abilityId"`
+	Success    bool    `json:"success"`
+	Message    string  `json:"message,omitempty"`
+	X          float64 `json:"x"` // Where the player is now; a dash may stop short of the target
+	Y          float64 `json:"y"`
+	CooldownMs int64   `json:"cooldownMs,omitempty"` // Until the ability can be used again
+}
+
+// PositionUpdatePayload is for "POSITION_UPDATE".
+type PositionUpdatePayload struct {
+	PlayerID  string  `json:"playerId"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	AbilityID string  `json:"abilityId,omitempty"` // The movement ability that moved the player, if any
+	Corrected bool    `json:"corrected,omitempty"` // The server refused the client's move; snap back here
+	NPC       bool    `json:"npc,omitempty"`       // playerId is an NPC of the room
+}
+
+// Movement message types.
+const (
+	MsgTypeMove                  = "MOVE"
+	MsgTypeUseMovementAbility    = "USE_MOVEMENT_ABILITY"
+	MsgTypeMovementAbilityResult = "MOVEMENT_ABILITY_RESULT"
+	MsgTypePositionUpdate        = "POSITION_UPDATE"
+)
@@ -0,0 +1,103 @@
+package protocol
+
+// The order book for the game's fungible token, traded for SUI. Prices are
+// MIST per token unit, quantities token units. A player places a limit order
+// with ORDER_PLACE and gets ORDER_UPDATE with txBytes: an unsigned deposit
+// into the server's custody, the tokens to sell or the MIST to buy with, to
+// sign and execute. ORDER_FUNDED with its digest puts the order on the book.
+//
+// Orders match by best price, then the oldest, at the resting order's price,
+// and may fill in parts. Each fill reaches both players in ORDER_UPDATE, and
+// again with its txDigest once custody has paid the tokens to the buyer and
+// the MIST to the seller. ORDER_CANCEL takes an order off the book. What an
+// order's deposit has left when it is filled, cancelled or expires is
+// refunded. ORDER_BOOK_REQUEST returns the depth of the book and the player's
+// own orders.
+
+// Order sides.
+const (
+	OrderSideBuy  = "buy"
+	OrderSideSell = "sell"
+)
+
+// OrderPlaceRequestPayload is for "ORDER_PLACE".
+type OrderPlaceRequestPayload struct {
+	Side       string `json:"side"`                 // OrderSideBuy or OrderSideSell
+	Price      uint64 `json:"price"`                // MIST per token
+	Quantity   uint64 `json:"quantity"`             // Tokens
+	TTLSeconds int    `json:"ttlSeconds,omitempty"` // From placement; the server's default if 0
+}
+
+// OrderFundedRequestPayload is for "ORDER_FUNDED", sent after the player's deposit executed.
+type OrderFundedRequestPayload struct {
+	OrderID  string `json:"orderId"`
+	TxDigest string `json:"txDigest"`
+}
+
+// OrderCancelRequestPayload is for "ORDER_CANCEL".
+type OrderCancelRequestPayload struct {
+	OrderID string `json:"orderId"`
+}
+
+// OrderBookRequestPayload is for "ORDER_BOOK_REQUEST".
+type OrderBookRequestPayload struct {
+	Levels int `json:"levels,omitempty"` // Price levels per side; the server's depth if 0
+}
+
+// OrderPayload is one of the player's orders.
+type OrderPayload struct {
+	OrderID   string `json:"orderId"`
+	Side      string `json:"side"`
+	Price     uint64 `json:"price"`
+	Quantity  uint64 `json:"quantity"`
+	Filled    uint64 `json:"filled"`
+	CoinType  string `json:"coinType"` // What the deposit is paid in
+	Deposit   uint64 `json:"deposit"`
+	Refunded  uint64 `json:"refunded,omitempty"`
+	Status    string `json:"status"`             // awaiting_deposit, open, filled, cancelled or expired
+	TxBytes   string `json:"txBytes,omitempty"`  // Unsigned deposit to sign, while one is due
+	RefundTx  string `json:"refundTx,omitempty"` // Transaction returning the unspent deposit
+	PlacedAt  int64  `json:"placedAt"`           // Unix milliseconds
+	Deadline  int64  `json:"deadline,omitempty"` // Unix milliseconds; for the deposit
+	ExpiresAt int64  `json:"expiresAt"`          // Unix milliseconds
+}
+
+// OrderFillPayload is one fill of an order.
+type OrderFillPayload struct {
+	FillID   string `json:"fillId"`
+	Price    uint64 `json:"price"`
+	Quantity uint64 `json:"quantity"`
+	At       int64  `json:"at"`                 // Unix milliseconds
+	TxDigest string `json:"txDigest,omitempty"` // Settlement transaction, once executed
+}
+
+// OrderUpdatePayload is for "ORDER_UPDATE".
+type OrderUpdatePayload struct {
+	Order OrderPayload      `json:"order"`
+	Fill  *OrderFillPayload `json:"fill,omitempty"` // Set for a fill and for its settlement
+}
+
+// OrderBookLevelPayload is the open quantity at one price.
+type OrderBookLevelPayload struct {
+	Price    uint64 `json:"price"`
+	Quantity uint64 `json:"quantity"`
+	Orders   int    `json:"orders"`
+}
+
+// OrderBookPayload is for "ORDER_BOOK".
+type OrderBookPayload struct {
+	TokenType string                  `json:"tokenType"`
+	Bids      []OrderBookLevelPayload `json:"bids"`      // Highest first
+	Asks      []OrderBookLevelPayload `json:"asks"`      // Lowest first
+	LastPrice uint64                  `json:"lastPrice"` // Of the latest fill; 0 before any
+	Orders    []OrderPayload          `json:"orders"`    // The player's unfinished orders
+}
+
+const (
+	MsgTypeOrderPlace       = "ORDER_PLACE"
+	MsgTypeOrderFunded      = "ORDER_FUNDED"
+	MsgTypeOrderCancel      = "ORDER_CANCEL"
+	MsgTypeOrderBookRequest = "ORDER_BOOK_REQUEST"
+	MsgTypeOrderUpdate      = "ORDER_UPDATE"
+	MsgTypeOrderBook        = "ORDER_BOOK"
+)
@@ -0,0 +1,43 @@
+package protocol
+
+// Parental controls. Operators can restrict an account with a daily playtime
+// limit, a nightly curfew, a restricted chat mode and a daily cap on coins
+// spent in shops. PARENTAL_STATUS_REQUEST is answered with PARENTAL_STATUS,
+// which restricted players also get right after AUTH_RESPONSE and, with
+// warning set, as the time they may keep playing runs low. When it runs out
+// the session ends with PLAYTIME_LIMIT_REACHED or CURFEW; AUTH is refused
+// with the same codes until the player may play again.
+
+// Limits named by ParentalStatusPayload.Warning.
+const (
+	ParentalLimitPlaytime = "playtime"
+	ParentalLimitCurfew   = "curfew"
+)
+
+// ParentalStatusRequestPayload is for "PARENTAL_STATUS_REQUEST".
+type ParentalStatusRequestPayload struct{}
+
+// ParentalCurfewPayload is a daily window without play, in TimeZone.
+type ParentalCurfewPayload struct {
+	Start string `json:"start"` // 15:04
+	End   string `json:"end"`
+}
+
+// ParentalStatusPayload is for "PARENTAL_STATUS". Negative values mean no limit.
+type ParentalStatusPayload struct {
+	Restricted     bool                   `json:"restricted"`
+	PlaytimeLeft   int64                  `json:"playtimeLeft"` // Seconds of play left today
+	Curfew         *ParentalCurfewPayload `json:"curfew,omitempty"`
+	CurfewStartsIn int64                  `json:"curfewStartsIn"` // Seconds
+	TimeZone       string                 `json:"timeZone,omitempty"`
+	ChatRestricted bool                   `json:"chatRestricted,omitempty"`
+	SpendLeft      int64                  `json:"spendLeft"`          // Coins the player may still spend in shops today
+	Warning        string                 `json:"warning,omitempty"`  // ParentalLimitPlaytime or ParentalLimitCurfew, when sent as a warning
+	TimeLeft       int64                  `json:"timeLeft,omitempty"` // Seconds until Warning's limit ends the session
+	ServerTime     int64                  `json:"serverTime,omitempty"`
+}
+
+const (
+	MsgTypeParentalStatusRequest = "PARENTAL_STATUS_REQUEST"
+	MsgTypeParentalStatus        = "PARENTAL_STATUS"
+)
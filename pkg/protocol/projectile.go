@@ -0,0 +1,64 @@
+package protocol
+
+// Projectiles. A player fires with FIRE_PROJECTILE; the room simulates the
+// flight and broadcasts PROJECTILE_SPAWNED, which carries everything needed to
+// draw the path, and then PROJECTILE_HIT or PROJECTILE_EXPIRED once it ends.
+// A refused shot is answered with an ERROR with code PROJECTILE_REFUSED.
+// Height is measured from the ground; the path of a projectile t seconds after
+// it was fired is (x, y) + (dirX, dirY)·speed·t at height
+// launchHeight + lift·t - gravity·t²/2.
+
+// FireProjectilePayload is for "FIRE_PROJECTILE".
+type FireProjectilePayload struct {
+	ProjectileID string  `json:"projectileId"` // From the server's skill data, e.g. "arrow"
+	X            float64 `json:"x"`            // Aimed point
+	Y            float64 `json:"y"`
+}
+
+// ProjectileSpawnedPayload is for "PROJECTILE_SPAWNED".
+type ProjectileSpawnedPayload struct {
+	ID           string  `json:"id"` // Instance, named again by the hit or expiry
+	ProjectileID string  `json:"projectileId"`
+	OwnerID      string  `json:"ownerId"`
+	X            float64 `json:"x"` // Where it was fired from
+	Y            float64 `json:"y"`
+	DirX         float64 `json:"dirX"`
+	DirY         float64 `json:"dirY"`
+	Speed        float64 `json:"speed"`
+	Range        float64 `json:"range"`
+	LaunchHeight float64 `json:"launchHeight"`
+	Lift         float64 `json:"lift,omitempty"`
+	Gravity      float64 `json:"gravity,omitempty"`
+}
+
+// ProjectileHitPayload is for "PROJECTILE_HIT".
+type ProjectileHitPayload struct {
+	ID       string         `json:"id"`
+	TargetID string         `json:"targetId"`
+	NPC      bool           `json:"npc,omitempty"` // targetId is an NPC of the room
+	X        float64        `json:"x"`
+	Y        float64        `json:"y"`
+	Damage   int            `json:"damage"`
+	Critical bool           `json:"critical,omitempty"`
+	Evaded   bool           `json:"evaded,omitempty"`
+	Health   int            `json:"health"`             // The target's health afterwards
+	Defeated bool           `json:"defeated,omitempty"` // Defeated NPCs leave the room; defeated players respawn, unless the room is hardcore
+	XP       int            `json:"xp,omitempty"`       // Earned by the shooter for a defeat, after the room's XP multiplier
+	Loot     map[string]int `json:"loot,omitempty"`     // Dropped by a defeat: item ID -> quantity; none in no-loot rooms
+}
+
+// ProjectileExpiredPayload is for "PROJECTILE_EXPIRED".
+type ProjectileExpiredPayload struct {
+	ID     string  `json:"id"`
+	Reason string  `json:"reason"` // "range", "wall" or "ground"
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+}
+
+// Projectile message types.
+const (
+	MsgTypeFireProjectile    = "FIRE_PROJECTILE"
+	MsgTypeProjectileSpawned = "PROJECTILE_SPAWNED"
+	MsgTypeProjectileHit     = "PROJECTILE_HIT"
+	MsgTypeProjectileExpired = "PROJECTILE_EXPIRED"
+)
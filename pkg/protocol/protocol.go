@@ -0,0 +1,170 @@
+// Package protocol defines the wire protocol shared by the game server and its
+// clients. It has no dependencies outside the standard library so that external
+// client teams (Unity, Unreal, tooling) can import the message types directly.
+//
+// Every message type must be registered in registry.go; the exported JSON schema
+// (schema.json) is generated from that registry by server/cmd/schemagen.
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// ClientServerMessage defines the standard structure for messages exchanged
+// between client and server. The payload is kept as JSON; build messages with
+// NewMessage and read payloads with DecodePayload.
+type ClientServerMessage struct {
+	Type    string          `json:"type"`          // Defines the kind of message, e.g., "AUTH", "PLAYER_ACTION"
+	Payload json.RawMessage `json:"payload"`       // Data specific to the message type
+	Seq     uint64          `json:"seq,omitempty"` // Sequence number of a reliably delivered server message
+	// Chosen by the client for a mutating command, such as SHOP_TRANSACTION,
+	// so that a retry with the same key is answered with the original result
+	// instead of running again. At most MaxIdempotencyKeyLength bytes.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	decoded reflect.Value // Payload already decoded into its registered type, by DecodeClientMessage
+}
+
+// MaxIdempotencyKeyLength is the longest idempotency key the server accepts.
+const MaxIdempotencyKeyLength = 128
+
+// NewMessage builds a message carrying payload encoded as JSON.
+func NewMessage(msgType string, payload interface{}) (ClientServerMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return ClientServerMessage{}, err
+	}
+	return ClientServerMessage{Type: msgType, Payload: body}, nil
+}
+
+// DecodePayload decodes the payload into v, a pointer. A missing or null
+// payload leaves v unchanged. When DecodeClientMessage has already decoded the
+// payload into v's type, that value is copied instead of decoding again.
+func (m ClientServerMessage) DecodePayload(v interface{}) error {
+	if m.decoded.IsValid() {
+		if target := reflect.ValueOf(v); target.Kind() == reflect.Ptr && !target.IsNil() && target.Elem().Type() == m.decoded.Type() {
+			target.Elem().Set(m.decoded)
+			return nil
+		}
+	}
+	if len(m.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Payload, v)
+}
+
+// AuthRequestPayload is the payload for an "AUTH" request from the client.
+type AuthRequestPayload struct {
+	Token    string `json:"token" text:"4096,verbatim"`
+	WorldID  string `json:"worldId,omitempty"`  // Game world to enter; the server's default world if empty
+	Reliable bool   `json:"reliable,omitempty"` // Sequence and replay important messages; see ACK
+	LastSeq  uint64 `json:"lastSeq,omitempty"`  // When resuming, the highest seq processed; later messages are re-sent
+	// Build of the client, e.g. "1.4.2". Servers may refuse versions they no
+	// longer or do not yet support with ErrCodeUpgradeRequired or
+	// ErrCodeVersionUnsupported.
+	ClientVersion string `json:"clientVersion,omitempty" text:"32"`
+	// Resume token from an earlier AUTH_RESPONSE, sent instead of token to
+	// reconnect without credentials; see LOGOUT.
+	ResumeToken string `json:"resumeToken,omitempty" text:"64,verbatim"`
+	DeviceID    string `json:"deviceId,omitempty" text:"128"` // Stable ID of the device; resume tokens only work on the device they were issued to
+}
+
+// AuthResponsePayload is the payload for an "AUTH_SUCCESS" or "AUTH_FAILURE" response.
+type AuthResponsePayload struct {
+	PlayerID string             `json:"playerId,omitempty"` // Included on success
+	Success  bool               `json:"success"`
+	Message  string             `json:"message"`            // e.g., "Authentication successful" or error message
+	WorldID  string             `json:"worldId,omitempty"`  // World the player entered, on success
+	Reliable bool               `json:"reliable,omitempty"` // Reliable delivery is on
+	Replayed int                `json:"replayed,omitempty"` // Messages after lastSeq re-sent right after this response
+	Gap      bool               `json:"gap,omitempty"`      // Some messages after lastSeq were no longer kept; refetch state
+	Ready    *LoginReadyPayload `json:"ready,omitempty"`    // What was read from the chain at login; absent if prefetch is off
+	// Token to reconnect with, on success; absent if resumption is off. It
+	// replaces the one this AUTH was sent with.
+	ResumeToken    string `json:"resumeToken,omitempty"`
+	ResumeWindow   int    `json:"resumeWindow,omitempty"`   // Seconds the token stays valid after a disconnect
+	ResumeNotAfter int64  `json:"resumeNotAfter,omitempty"` // Unix milliseconds; sign in with credentials after
+	ServerTime     int64  `json:"serverTime,omitempty"`     // Server clock when sent, Unix milliseconds; see TIME_SYNC
+}
+
+// ErrorResponsePayload is a generic payload for error messages.
+type ErrorResponsePayload struct {
+	Code       string `json:"code"` // e.g., "INVALID_COMMAND", "NOT_AUTHENTICATED"
+	Message    string `json:"message"`
+	UpgradeURL string `json:"upgradeUrl,omitempty"` // Where to get a supported client, with ErrCodeUpgradeRequired
+}
+
+// Error codes of an AUTH refused for its ClientVersion.
+const (
+	ErrCodeUpgradeRequired    = "UPGRADE_REQUIRED"           // The client is too old; see UpgradeURL
+	ErrCodeVersionUnsupported = "CLIENT_VERSION_UNSUPPORTED" // The client is newer than the server supports yet
+)
+
+// ErrCodeCheatDetected ends a session closed for repeated rule violations,
+// such as moving too fast.
+const ErrCodeCheatDetected = "CHEAT_DETECTED"
+
+// SimpleMessagePayload is for simple text messages to the client (e.g., welcome, usage)
+type SimpleMessagePayload struct {
+	Message string `json:"message"`
+}
+
+// JoinRoomRequestPayload is for "JOIN_ROOM" request from client
+type JoinRoomRequestPayload struct {
+	Criteria    string `json:"criteria"`              // e.g., room ID or type
+	Password    string `json:"password,omitempty"`    // For password-protected rooms
+	InviteToken string `json:"inviteToken,omitempty"` // For invite-only rooms, see CREATE_ROOM_INVITE
+}
+
+// JoinRoomResponsePayload is for "ROOM_JOINED" or "JOIN_ROOM_FAILED"
+type JoinRoomResponsePayload struct {
+	Success    bool              `json:"success"`
+	RoomID     string            `json:"roomId,omitempty"`
+	Message    string            `json:"message"`
+	ServerTime int64             `json:"serverTime,omitempty"` // Server clock when sent, Unix milliseconds
+	State      *RoomStatePayload `json:"state,omitempty"`      // The room as the player arrives; on success
+}
+
+// ChatMessagePayload is for "SEND_CHAT" from client or "NEW_CHAT_MESSAGE" to client
+type ChatMessagePayload struct {
+	SenderName string `json:"senderName,omitempty"` // Server populates this for NEW_CHAT_MESSAGE
+	Text       string `json:"text" text:"500,multiline"`
+}
+
+// PingPongPayload can be empty or contain a timestamp, used for "PING" and "PONG"
+type PingPongPayload struct {
+	Timestamp int64 `json:"timestamp,omitempty"`
+	HintTime  int64 `json:"hintTime,omitempty"` // serverTime of the CLOCK_HINT this PING answers; the server measures the round trip from it
+}
+
+// PlayerActionPayload is a generic payload for player actions.
+// The specific content of "Data" would vary based on the action.
+type PlayerActionPayload struct {
+	ActionType string                 `json:"actionType"` // e.g., "MOVE", "USE_ITEM"
+	Data       map[string]interface{} `json:"data"`       // Action-specific data
+}
+
+// PlayerActionResponsePayload is for acknowledging player actions.
+type PlayerActionResponsePayload struct {
+	ActionType string                 `json:"actionType"`
+	Status     string                 `json:"status"` // e.g., "SUCCESS", "FAILURE", "PENDING"
+	Message    string                 `json:"message,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"` // For returning data, e.g., from GET_PLAYER_PROFILE
+}
+
+// Constants for message types
+const (
+	MsgTypeError                = "ERROR"
+	MsgTypeSimpleMessage        = "SIMPLE_MESSAGE"
+	MsgTypeAuthRequest          = "AUTH"
+	MsgTypeAuthResponse         = "AUTH_RESPONSE"
+	MsgTypeJoinRoomRequest      = "JOIN_ROOM"
+	MsgTypeJoinRoomResponse     = "JOIN_ROOM_RESPONSE"
+	MsgTypeSendChat             = "SEND_CHAT"
+	MsgTypeNewChatMessage       = "NEW_CHAT_MESSAGE"
+	MsgTypePing                 = "PING"
+	MsgTypePong                 = "PONG"
+	MsgTypePlayerAction         = "PLAYER_ACTION"
+	MsgTypePlayerActionResponse = "PLAYER_ACTION_RESPONSE"
+)
@@ -0,0 +1,114 @@
+package protocol
+
+// Guild recruitment. Guild officers, or the ranks allowed to invite when a
+// guild has custom ranks, put up a listing with GUILD_RECRUITMENT_POST and
+// take it down with GUILD_RECRUITMENT_CLOSE. GUILD_RECRUITMENT_REQUEST shows
+// them the listing and the applications waiting for review, and
+// GUILD_APPLICATION_REVIEW accepts or rejects one; each is answered with
+// GUILD_RECRUITMENT. An accepted application is also answered with
+// GUILD_ACTION carrying the guild invite to sign, when guild ranks are on.
+//
+// Players find guilds with GUILD_RECRUITMENT_SEARCH, answered with
+// GUILD_RECRUITMENT_LISTINGS, and apply with GUILD_APPLY. GUILD_APPLY,
+// GUILD_APPLICATION_WITHDRAW and GUILD_APPLICATIONS_REQUEST are answered with
+// GUILD_APPLICATIONS. Recruiters are mailed each application, and applicants
+// the decision.
+
+// GuildRecruitmentPostRequestPayload is for "GUILD_RECRUITMENT_POST". It
+// replaces the guild's listing, if it has one.
+type GuildRecruitmentPostRequestPayload struct {
+	Description  string   `json:"description" text:"500"`
+	Requirements string   `json:"requirements,omitempty" text:"200"`
+	Tags         []string `json:"tags,omitempty"` // Up to 5 single words, e.g. "pvp", "eu"
+}
+
+// GuildRecruitmentRequestPayload is for "GUILD_RECRUITMENT_REQUEST" and
+// "GUILD_RECRUITMENT_CLOSE".
+type GuildRecruitmentRequestPayload struct{}
+
+// GuildApplicationReviewRequestPayload is for "GUILD_APPLICATION_REVIEW".
+type GuildApplicationReviewRequestPayload struct {
+	ApplicationID string `json:"applicationId"`
+	Accept        bool   `json:"accept"`
+	Note          string `json:"note,omitempty" text:"200"` // Mailed to the applicant
+}
+
+// GuildRecruitmentSearchRequestPayload is for "GUILD_RECRUITMENT_SEARCH".
+type GuildRecruitmentSearchRequestPayload struct {
+	Query string `json:"query,omitempty"` // Matched against guild names, descriptions and requirements
+	Tag   string `json:"tag,omitempty"`
+	Limit int    `json:"limit,omitempty"` // 20 if 0, at most 50
+}
+
+// GuildApplyRequestPayload is for "GUILD_APPLY".
+type GuildApplyRequestPayload struct {
+	GuildID string `json:"guildId"`
+	Message string `json:"message,omitempty" text:"300"`
+}
+
+// GuildApplicationWithdrawRequestPayload is for "GUILD_APPLICATION_WITHDRAW".
+type GuildApplicationWithdrawRequestPayload struct {
+	ApplicationID string `json:"applicationId"`
+}
+
+// GuildApplicationsRequestPayload is for "GUILD_APPLICATIONS_REQUEST".
+type GuildApplicationsRequestPayload struct{}
+
+// RecruitmentListingPayload is one guild's listing.
+type RecruitmentListingPayload struct {
+	GuildID      string   `json:"guildId"`
+	GuildName    string   `json:"guildName"`
+	Members      int      `json:"members"`
+	Description  string   `json:"description"`
+	Requirements string   `json:"requirements,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	UpdatedAt    int64    `json:"updatedAt"` // Unix milliseconds
+	ExpiresAt    int64    `json:"expiresAt"` // Unix milliseconds
+}
+
+// GuildApplicationPayload is one application.
+type GuildApplicationPayload struct {
+	ApplicationID string `json:"applicationId"`
+	GuildID       string `json:"guildId"`
+	PlayerID      string `json:"playerId"`
+	Message       string `json:"message,omitempty"`
+	Status        string `json:"status"`    // "pending", "accepted" or "rejected"
+	AppliedAt     int64  `json:"appliedAt"` // Unix milliseconds
+	DecidedBy     string `json:"decidedBy,omitempty"`
+	Note          string `json:"note,omitempty"`
+	ExpiresAt     int64  `json:"expiresAt"` // Unix milliseconds; pending applications lapse then
+}
+
+// GuildRecruitmentPayload is for "GUILD_RECRUITMENT": the recruiter's guild
+// listing, if it has one, and its pending applications, oldest first.
+type GuildRecruitmentPayload struct {
+	GuildID      string                     `json:"guildId"`
+	Listing      *RecruitmentListingPayload `json:"listing,omitempty"`
+	Applications []GuildApplicationPayload  `json:"applications"`
+}
+
+// GuildRecruitmentListingsPayload is for "GUILD_RECRUITMENT_LISTINGS", most
+// recently posted first.
+type GuildRecruitmentListingsPayload struct {
+	Listings []RecruitmentListingPayload `json:"listings"`
+}
+
+// GuildApplicationsPayload is for "GUILD_APPLICATIONS": the player's
+// applications, newest first.
+type GuildApplicationsPayload struct {
+	Applications []GuildApplicationPayload `json:"applications"`
+}
+
+const (
+	MsgTypeGuildRecruitmentPost     = "GUILD_RECRUITMENT_POST"
+	MsgTypeGuildRecruitmentClose    = "GUILD_RECRUITMENT_CLOSE"
+	MsgTypeGuildRecruitmentRequest  = "GUILD_RECRUITMENT_REQUEST"
+	MsgTypeGuildApplicationReview   = "GUILD_APPLICATION_REVIEW"
+	MsgTypeGuildRecruitmentSearch   = "GUILD_RECRUITMENT_SEARCH"
+	MsgTypeGuildApply               = "GUILD_APPLY"
+	MsgTypeGuildApplicationWithdraw = "GUILD_APPLICATION_WITHDRAW"
+	MsgTypeGuildApplicationsRequest = "GUILD_APPLICATIONS_REQUEST"
+	MsgTypeGuildRecruitment         = "GUILD_RECRUITMENT"
+	MsgTypeGuildRecruitmentListings = "GUILD_RECRUITMENT_LISTINGS"
+	MsgTypeGuildApplications        = "GUILD_APPLICATIONS"
+)
@@ -0,0 +1,207 @@
+package protocol
+
+// ProtocolVersion is bumped whenever the wire protocol changes in a way that
+// clients need to know about. It is embedded in the exported schema.
+const ProtocolVersion = 1
+
+// Message directions used in the exported schema.
+const (
+	DirectionClientToServer = "client_to_server"
+	DirectionServerToClient = "server_to_client"
+	DirectionBoth           = "both"
+)
+
+// MessageSpec describes a single message type on the wire: its type string,
+// its numeric ID for version 2 frames, which side sends it, and a zero value of
+// its payload struct. IDs are permanent: never reuse or renumber one.
+type MessageSpec struct {
+	ID        uint16
+	Type      string
+	Direction string
+	Payload   interface{}
+}
+
+// messageRegistry lists every message type the server understands or emits.
+// New message types must be added here, with the next free ID, so they appear
+// in the exported schema.
+var messageRegistry = []MessageSpec{
+	{ID: 1, Type: MsgTypeError, Direction: DirectionServerToClient, Payload: ErrorResponsePayload{}},
+	{ID: 2, Type: MsgTypeSimpleMessage, Direction: DirectionServerToClient, Payload: SimpleMessagePayload{}},
+	{ID: 3, Type: MsgTypeAuthRequest, Direction: DirectionClientToServer, Payload: AuthRequestPayload{}},
+	{ID: 4, Type: MsgTypeAuthResponse, Direction: DirectionServerToClient, Payload: AuthResponsePayload{}},
+	{ID: 5, Type: MsgTypeJoinRoomRequest, Direction: DirectionClientToServer, Payload: JoinRoomRequestPayload{}},
+	{ID: 6, Type: MsgTypeJoinRoomResponse, Direction: DirectionServerToClient, Payload: JoinRoomResponsePayload{}},
+	{ID: 7, Type: MsgTypeSendChat, Direction: DirectionClientToServer, Payload: ChatMessagePayload{}},
+	{ID: 8, Type: MsgTypeNewChatMessage, Direction: DirectionServerToClient, Payload: ChatMessagePayload{}},
+	{ID: 9, Type: MsgTypePing, Direction: DirectionClientToServer, Payload: PingPongPayload{}},
+	{ID: 10, Type: MsgTypePong, Direction: DirectionServerToClient, Payload: PingPongPayload{}},
+	{ID: 11, Type: MsgTypePlayerAction, Direction: DirectionClientToServer, Payload: PlayerActionPayload{}},
+	{ID: 12, Type: MsgTypePlayerActionResponse, Direction: DirectionServerToClient, Payload: PlayerActionResponsePayload{}},
+	{ID: 13, Type: MsgTypeCreateRoomRequest, Direction: DirectionClientToServer, Payload: CreateRoomRequestPayload{}},
+	{ID: 14, Type: MsgTypeCreateRoomResponse, Direction: DirectionServerToClient, Payload: CreateRoomResponsePayload{}},
+	{ID: 15, Type: MsgTypeListRoomsRequest, Direction: DirectionClientToServer, Payload: ListRoomsRequestPayload{}},
+	{ID: 16, Type: MsgTypeRoomList, Direction: DirectionServerToClient, Payload: RoomListPayload{}},
+	{ID: 17, Type: MsgTypeCreateRoomInviteRequest, Direction: DirectionClientToServer, Payload: CreateRoomInviteRequestPayload{}},
+	{ID: 18, Type: MsgTypeCreateRoomInviteResponse, Direction: DirectionServerToClient, Payload: RoomInvitePayload{}},
+	{ID: 19, Type: MsgTypeVoiceOffer, Direction: DirectionBoth, Payload: VoiceSessionDescriptionPayload{}},
+	{ID: 20, Type: MsgTypeVoiceAnswer, Direction: DirectionBoth, Payload: VoiceSessionDescriptionPayload{}},
+	{ID: 21, Type: MsgTypeVoiceICECandidate, Direction: DirectionBoth, Payload: VoiceICECandidatePayload{}},
+	{ID: 22, Type: MsgTypeVoiceMute, Direction: DirectionClientToServer, Payload: VoiceMuteRequestPayload{}},
+	{ID: 23, Type: MsgTypeVoiceMuteState, Direction: DirectionServerToClient, Payload: VoiceMuteStatePayload{}},
+	{ID: 24, Type: MsgTypeTutorialStep, Direction: DirectionServerToClient, Payload: TutorialStepPayload{}},
+	{ID: 25, Type: MsgTypeBatchAction, Direction: DirectionClientToServer, Payload: BatchActionRequestPayload{}},
+	{ID: 26, Type: MsgTypeBatchActionResult, Direction: DirectionServerToClient, Payload: BatchActionResultPayload{}},
+	{ID: 27, Type: MsgTypeClaimZone, Direction: DirectionClientToServer, Payload: ClaimZoneRequestPayload{}},
+	{ID: 28, Type: MsgTypeClaimZoneResponse, Direction: DirectionServerToClient, Payload: ClaimZoneResponsePayload{}},
+	{ID: 29, Type: MsgTypeArenaQueue, Direction: DirectionClientToServer, Payload: ArenaQueueRequestPayload{}},
+	{ID: 30, Type: MsgTypeArenaQueueStatus, Direction: DirectionServerToClient, Payload: ArenaQueueStatusPayload{}},
+	{ID: 31, Type: MsgTypeArenaMatchFound, Direction: DirectionServerToClient, Payload: ArenaMatchFoundPayload{}},
+	{ID: 32, Type: MsgTypeArenaMatchResult, Direction: DirectionServerToClient, Payload: ArenaMatchResultPayload{}},
+	{ID: 33, Type: MsgTypeCombatTurn, Direction: DirectionServerToClient, Payload: CombatTurnPayload{}},
+	{ID: 34, Type: MsgTypeCombatAction, Direction: DirectionClientToServer, Payload: CombatActionPayload{}},
+	{ID: 35, Type: MsgTypeCombatState, Direction: DirectionServerToClient, Payload: CombatStatePayload{}},
+	{ID: 36, Type: MsgTypeCombatEnded, Direction: DirectionServerToClient, Payload: CombatEndedPayload{}},
+	{ID: 37, Type: MsgTypeShopBrowse, Direction: DirectionClientToServer, Payload: ShopBrowseRequestPayload{}},
+	{ID: 38, Type: MsgTypeShopInventory, Direction: DirectionServerToClient, Payload: ShopInventoryPayload{}},
+	{ID: 39, Type: MsgTypeShopTransaction, Direction: DirectionClientToServer, Payload: ShopTransactionRequestPayload{}},
+	{ID: 40, Type: MsgTypeShopTransactionResult, Direction: DirectionServerToClient, Payload: ShopTransactionResultPayload{}},
+	{ID: 41, Type: MsgTypePlayerAFK, Direction: DirectionServerToClient, Payload: PlayerAFKPayload{}},
+	{ID: 42, Type: MsgTypeSendWhisper, Direction: DirectionClientToServer, Payload: WhisperRequestPayload{}},
+	{ID: 43, Type: MsgTypeNewWhisper, Direction: DirectionServerToClient, Payload: WhisperPayload{}},
+	{ID: 44, Type: MsgTypeChatHistoryRequest, Direction: DirectionClientToServer, Payload: ChatHistoryRequestPayload{}},
+	{ID: 45, Type: MsgTypeChatHistory, Direction: DirectionServerToClient, Payload: ChatHistoryPayload{}},
+	{ID: 46, Type: MsgTypeWalletLinkChallengeRequest, Direction: DirectionClientToServer, Payload: WalletLinkChallengeRequestPayload{}},
+	{ID: 47, Type: MsgTypeWalletLinkChallenge, Direction: DirectionServerToClient, Payload: WalletLinkChallengePayload{}},
+	{ID: 48, Type: MsgTypeLinkWallet, Direction: DirectionClientToServer, Payload: LinkWalletRequestPayload{}},
+	{ID: 49, Type: MsgTypeUnlinkWallet, Direction: DirectionClientToServer, Payload: UnlinkWalletRequestPayload{}},
+	{ID: 50, Type: MsgTypeWalletLinksRequest, Direction: DirectionClientToServer, Payload: WalletLinksRequestPayload{}},
+	{ID: 51, Type: MsgTypeWalletLinks, Direction: DirectionServerToClient, Payload: WalletLinksPayload{}},
+	{ID: 52, Type: MsgTypeTradePropose, Direction: DirectionClientToServer, Payload: TradeProposeRequestPayload{}},
+	{ID: 53, Type: MsgTypeTradeRespond, Direction: DirectionClientToServer, Payload: TradeRespondRequestPayload{}},
+	{ID: 54, Type: MsgTypeTradeDeposited, Direction: DirectionClientToServer, Payload: TradeDepositedRequestPayload{}},
+	{ID: 55, Type: MsgTypeTradeUpdate, Direction: DirectionServerToClient, Payload: TradeUpdatePayload{}},
+	{ID: 56, Type: MsgTypeAck, Direction: DirectionClientToServer, Payload: AckPayload{}},
+	{ID: 57, Type: MsgTypeMove, Direction: DirectionClientToServer, Payload: MovePayload{}},
+	{ID: 58, Type: MsgTypeUseMovementAbility, Direction: DirectionClientToServer, Payload: UseMovementAbilityPayload{}},
+	{ID: 59, Type: MsgTypeMovementAbilityResult, Direction: DirectionServerToClient, Payload: MovementAbilityResultPayload{}},
+	{ID: 60, Type: MsgTypePositionUpdate, Direction: DirectionServerToClient, Payload: PositionUpdatePayload{}},
+	{ID: 61, Type: MsgTypeFireProjectile, Direction: DirectionClientToServer, Payload: FireProjectilePayload{}},
+	{ID: 62, Type: MsgTypeProjectileSpawned, Direction: DirectionServerToClient, Payload: ProjectileSpawnedPayload{}},
+	{ID: 63, Type: MsgTypeProjectileHit, Direction: DirectionServerToClient, Payload: ProjectileHitPayload{}},
+	{ID: 64, Type: MsgTypeProjectileExpired, Direction: DirectionServerToClient, Payload: ProjectileExpiredPayload{}},
+	{ID: 65, Type: MsgTypeChannelJoin, Direction: DirectionClientToServer, Payload: ChannelRequestPayload{}},
+	{ID: 66, Type: MsgTypeChannelLeave, Direction: DirectionClientToServer, Payload: ChannelRequestPayload{}},
+	{ID: 67, Type: MsgTypeChannelSend, Direction: DirectionClientToServer, Payload: ChannelSendPayload{}},
+	{ID: 68, Type: MsgTypeChannelMessage, Direction: DirectionServerToClient, Payload: ChannelMessagePayload{}},
+	{ID: 69, Type: MsgTypeChannelListRequest, Direction: DirectionClientToServer, Payload: ChannelListRequestPayload{}},
+	{ID: 70, Type: MsgTypeChannelList, Direction: DirectionServerToClient, Payload: ChannelListPayload{}},
+	{ID: 71, Type: MsgTypeSocialAction, Direction: DirectionClientToServer, Payload: SocialActionPayload{}},
+	{ID: 72, Type: MsgTypeSocial, Direction: DirectionServerToClient, Payload: SocialPayload{}},
+	{ID: 73, Type: MsgTypeMailListRequest, Direction: DirectionClientToServer, Payload: MailListRequestPayload{}},
+	{ID: 74, Type: MsgTypeMailList, Direction: DirectionServerToClient, Payload: MailListPayload{}},
+	{ID: 75, Type: MsgTypeMailRead, Direction: DirectionClientToServer, Payload: MailIDsPayload{}},
+	{ID: 76, Type: MsgTypeMailDelete, Direction: DirectionClientToServer, Payload: MailIDsPayload{}},
+	{ID: 77, Type: MsgTypeMailNew, Direction: DirectionServerToClient, Payload: MailMessagePayload{}},
+	{ID: 78, Type: MsgTypeGiftSend, Direction: DirectionClientToServer, Payload: GiftSendRequestPayload{}},
+	{ID: 79, Type: MsgTypeGiftRespond, Direction: DirectionClientToServer, Payload: GiftRespondRequestPayload{}},
+	{ID: 80, Type: MsgTypeGiftSent, Direction: DirectionClientToServer, Payload: GiftSentRequestPayload{}},
+	{ID: 81, Type: MsgTypeGiftUpdate, Direction: DirectionServerToClient, Payload: GiftPayload{}},
+	{ID: 82, Type: MsgTypeGiftHistoryRequest, Direction: DirectionClientToServer, Payload: GiftHistoryRequestPayload{}},
+	{ID: 83, Type: MsgTypeGiftHistory, Direction: DirectionServerToClient, Payload: GiftHistoryPayload{}},
+	{ID: 84, Type: MsgTypeCraftStart, Direction: DirectionClientToServer, Payload: CraftStartRequestPayload{}},
+	{ID: 85, Type: MsgTypeCraftQueueRequest, Direction: DirectionClientToServer, Payload: CraftQueueRequestPayload{}},
+	{ID: 86, Type: MsgTypeCraftQueue, Direction: DirectionServerToClient, Payload: CraftQueuePayload{}},
+	{ID: 87, Type: MsgTypeCraftCompleted, Direction: DirectionServerToClient, Payload: CraftPayload{}},
+	{ID: 88, Type: MsgTypeEnergyRequest, Direction: DirectionClientToServer, Payload: EnergyRequestPayload{}},
+	{ID: 89, Type: MsgTypeEnergy, Direction: DirectionServerToClient, Payload: EnergyPayload{}},
+	{ID: 90, Type: MsgTypeBattlePassRequest, Direction: DirectionClientToServer, Payload: BattlePassRequestPayload{}},
+	{ID: 91, Type: MsgTypeBattlePass, Direction: DirectionServerToClient, Payload: BattlePassPayload{}},
+	{ID: 92, Type: MsgTypeBattlePassClaim, Direction: DirectionClientToServer, Payload: BattlePassClaimRequestPayload{}},
+	{ID: 93, Type: MsgTypeBattlePassUnlock, Direction: DirectionClientToServer, Payload: BattlePassUnlockRequestPayload{}},
+	{ID: 94, Type: MsgTypeAppearanceEquip, Direction: DirectionClientToServer, Payload: AppearanceEquipRequestPayload{}},
+	{ID: 95, Type: MsgTypeAppearance, Direction: DirectionServerToClient, Payload: AppearancePayload{}},
+	{ID: 96, Type: MsgTypeAnnouncement, Direction: DirectionServerToClient, Payload: AnnouncementPayload{}},
+	{ID: 97, Type: MsgTypeTxReceipt, Direction: DirectionServerToClient, Payload: TxReceiptPayload{}},
+	{ID: 98, Type: MsgTypeStatDelta, Direction: DirectionServerToClient, Payload: StatDeltaPayload{}},
+	{ID: 99, Type: MsgTypeLogout, Direction: DirectionClientToServer, Payload: LogoutPayload{}},
+	{ID: 100, Type: MsgTypeGuildEventCreate, Direction: DirectionClientToServer, Payload: GuildEventCreateRequestPayload{}},
+	{ID: 101, Type: MsgTypeGuildEventRSVP, Direction: DirectionClientToServer, Payload: GuildEventRSVPRequestPayload{}},
+	{ID: 102, Type: MsgTypeGuildEventCancel, Direction: DirectionClientToServer, Payload: GuildEventCancelRequestPayload{}},
+	{ID: 103, Type: MsgTypeGuildEventsRequest, Direction: DirectionClientToServer, Payload: GuildEventsRequestPayload{}},
+	{ID: 104, Type: MsgTypeGuildEvent, Direction: DirectionServerToClient, Payload: GuildEventPayload{}},
+	{ID: 105, Type: MsgTypeGuildEvents, Direction: DirectionServerToClient, Payload: GuildEventsPayload{}},
+	{ID: 106, Type: MsgTypeGuildEventStarted, Direction: DirectionServerToClient, Payload: GuildEventPayload{}},
+	{ID: 107, Type: MsgTypeDeepLinkOpen, Direction: DirectionClientToServer, Payload: DeepLinkOpenPayload{}},
+	{ID: 108, Type: MsgTypeDeepLinkRoute, Direction: DirectionServerToClient, Payload: DeepLinkRoutePayload{}},
+	{ID: 109, Type: MsgTypeTimeSync, Direction: DirectionClientToServer, Payload: TimeSyncRequestPayload{}},
+	{ID: 110, Type: MsgTypeTimeSyncResponse, Direction: DirectionServerToClient, Payload: TimeSyncResponsePayload{}},
+	{ID: 111, Type: MsgTypeClockHint, Direction: DirectionServerToClient, Payload: ClockHintPayload{}},
+	{ID: 112, Type: MsgTypeRoomTransfer, Direction: DirectionServerToClient, Payload: RoomTransferPayload{}},
+	{ID: 113, Type: MsgTypeLootCommitment, Direction: DirectionServerToClient, Payload: LootCommitmentPayload{}},
+	{ID: 114, Type: MsgTypeLootReveal, Direction: DirectionServerToClient, Payload: LootRevealPayload{}},
+	{ID: 115, Type: MsgTypeGuildRanksRequest, Direction: DirectionClientToServer, Payload: GuildRanksRequestPayload{}},
+	{ID: 116, Type: MsgTypeGuildRankDefine, Direction: DirectionClientToServer, Payload: GuildRankPayload{}},
+	{ID: 117, Type: MsgTypeGuildRankDelete, Direction: DirectionClientToServer, Payload: GuildRankDeleteRequestPayload{}},
+	{ID: 118, Type: MsgTypeGuildRankAssign, Direction: DirectionClientToServer, Payload: GuildRankAssignRequestPayload{}},
+	{ID: 119, Type: MsgTypeGuildInvite, Direction: DirectionClientToServer, Payload: GuildMemberRequestPayload{}},
+	{ID: 120, Type: MsgTypeGuildKick, Direction: DirectionClientToServer, Payload: GuildMemberRequestPayload{}},
+	{ID: 121, Type: MsgTypeGuildBankWithdraw, Direction: DirectionClientToServer, Payload: GuildBankWithdrawRequestPayload{}},
+	{ID: 122, Type: MsgTypeGuildRanks, Direction: DirectionServerToClient, Payload: GuildRanksPayload{}},
+	{ID: 123, Type: MsgTypeGuildAction, Direction: DirectionServerToClient, Payload: GuildActionPayload{}},
+	{ID: 124, Type: MsgTypeRoomStateRequest, Direction: DirectionClientToServer, Payload: RoomStateRequestPayload{}},
+	{ID: 125, Type: MsgTypeRoomState, Direction: DirectionServerToClient, Payload: RoomStatePayload{}},
+	{ID: 126, Type: MsgTypeParentalStatusRequest, Direction: DirectionClientToServer, Payload: ParentalStatusRequestPayload{}},
+	{ID: 127, Type: MsgTypeParentalStatus, Direction: DirectionServerToClient, Payload: ParentalStatusPayload{}},
+	{ID: 128, Type: MsgTypeDiplomacyRequest, Direction: DirectionClientToServer, Payload: DiplomacyRequestPayload{}},
+	{ID: 129, Type: MsgTypeAlliancePropose, Direction: DirectionClientToServer, Payload: AllianceProposeRequestPayload{}},
+	{ID: 130, Type: MsgTypeAllianceRespond, Direction: DirectionClientToServer, Payload: AllianceRespondRequestPayload{}},
+	{ID: 131, Type: MsgTypeAllianceLeave, Direction: DirectionClientToServer, Payload: AllianceLeaveRequestPayload{}},
+	{ID: 132, Type: MsgTypeRivalryDeclare, Direction: DirectionClientToServer, Payload: RivalryRequestPayload{}},
+	{ID: 133, Type: MsgTypeRivalryEnd, Direction: DirectionClientToServer, Payload: RivalryRequestPayload{}},
+	{ID: 134, Type: MsgTypeDiplomacy, Direction: DirectionServerToClient, Payload: DiplomacyPayload{}},
+	{ID: 135, Type: MsgTypeOrderPlace, Direction: DirectionClientToServer, Payload: OrderPlaceRequestPayload{}},
+	{ID: 136, Type: MsgTypeOrderFunded, Direction: DirectionClientToServer, Payload: OrderFundedRequestPayload{}},
+	{ID: 137, Type: MsgTypeOrderCancel, Direction: DirectionClientToServer, Payload: OrderCancelRequestPayload{}},
+	{ID: 138, Type: MsgTypeOrderBookRequest, Direction: DirectionClientToServer, Payload: OrderBookRequestPayload{}},
+	{ID: 139, Type: MsgTypeOrderUpdate, Direction: DirectionServerToClient, Payload: OrderUpdatePayload{}},
+	{ID: 140, Type: MsgTypeOrderBook, Direction: DirectionServerToClient, Payload: OrderBookPayload{}},
+	{ID: 141, Type: MsgTypeDisconnect, Direction: DirectionServerToClient, Payload: DisconnectPayload{}},
+	{ID: 142, Type: MsgTypeGuildRecruitmentPost, Direction: DirectionClientToServer, Payload: GuildRecruitmentPostRequestPayload{}},
+	{ID: 143, Type: MsgTypeGuildRecruitmentClose, Direction: DirectionClientToServer, Payload: GuildRecruitmentRequestPayload{}},
+	{ID: 144, Type: MsgTypeGuildRecruitmentRequest, Direction: DirectionClientToServer, Payload: GuildRecruitmentRequestPayload{}},
+	{ID: 145, Type: MsgTypeGuildApplicationReview, Direction: DirectionClientToServer, Payload: GuildApplicationReviewRequestPayload{}},
+	{ID: 146, Type: MsgTypeGuildRecruitmentSearch, Direction: DirectionClientToServer, Payload: GuildRecruitmentSearchRequestPayload{}},
+	{ID: 147, Type: MsgTypeGuildApply, Direction: DirectionClientToServer, Payload: GuildApplyRequestPayload{}},
+	{ID: 148, Type: MsgTypeGuildApplicationWithdraw, Direction: DirectionClientToServer, Payload: GuildApplicationWithdrawRequestPayload{}},
+	{ID: 149, Type: MsgTypeGuildApplicationsRequest, Direction: DirectionClientToServer, Payload: GuildApplicationsRequestPayload{}},
+	{ID: 150, Type: MsgTypeGuildRecruitment, Direction: DirectionServerToClient, Payload: GuildRecruitmentPayload{}},
+	{ID: 151, Type: MsgTypeGuildRecruitmentListings, Direction: DirectionServerToClient, Payload: GuildRecruitmentListingsPayload{}},
+	{ID: 152, Type: MsgTypeGuildApplications, Direction: DirectionServerToClient, Payload: GuildApplicationsPayload{}},
+}
+
+// Messages returns a copy of the registered message specs.
+func Messages() []MessageSpec {
+	specs := make([]MessageSpec, len(messageRegistry))
+	copy(specs, messageRegistry)
+	return specs
+}
+
+// LookupMessage returns the spec registered for msgType, if any.
+func LookupMessage(msgType string) (MessageSpec, bool) {
+	for _, spec := range messageRegistry {
+		if spec.Type == msgType {
+			return spec, true
+		}
+	}
+	return MessageSpec{}, false
+}
+
+// LookupMessageID returns the spec registered with the version 2 frame type ID id.
+func LookupMessageID(id uint16) (MessageSpec, bool) {
+	for _, spec := range messageRegistry {
+		if spec.ID == id && id != EnvelopeTypeID {
+			return spec, true
+		}
+	}
+	return MessageSpec{}, false
+}
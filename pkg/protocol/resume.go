@@ -0,0 +1,16 @@
+package protocol
+
+// Session resumption. When the server has it on, AUTH_RESPONSE carries a
+// resume token. After a dropped connection the client sends AUTH with
+// resumeToken instead of token, and the same deviceId, to get back in without
+// its credentials; combine it with reliable and lastSeq to get the messages it
+// missed. Each token works once: the AUTH_RESPONSE of the resumed session has
+// the next one, and a token used twice revokes all of the account's tokens.
+// Tokens expire resumeWindow seconds after their session drops and at
+// resumeNotAfter whatever happens. LOGOUT ends the session and revokes its
+// token.
+
+// LogoutPayload is for "LOGOUT". The server closes the connection.
+type LogoutPayload struct{}
+
+const MsgTypeLogout = "LOGOUT"
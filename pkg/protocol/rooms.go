@@ -0,0 +1,152 @@
+package protocol
+
+// Room visibility values used in CreateRoomRequestPayload.
+const (
+	RoomVisibilityPublic     = "public"      // Listed and available to matchmaking
+	RoomVisibilityPrivate    = "private"     // Unlisted; joinable by room ID (with the password, if set) or invite
+	RoomVisibilityInviteOnly = "invite_only" // Unlisted; joinable only with an invite from a current member
+)
+
+// CreateRoomRequestPayload is for a "CREATE_ROOM" request. The creator joins the room automatically.
+type CreateRoomRequestPayload struct {
+	Name       string            `json:"name,omitempty" text:"64"`
+	MaxPlayers int               `json:"maxPlayers,omitempty"`
+	Visibility string            `json:"visibility,omitempty"` // Defaults to "public"
+	Password   string            `json:"password,omitempty"`   // Optional; only a hash is kept on the server
+	MapID      string            `json:"mapId,omitempty"`      // Map the room is played on, for movement checks; open ground if empty
+	Rules      *RoomRulesPayload `json:"rules,omitempty"`      // Modifiers; the defaults if absent
+}
+
+// RoomRulesPayload is a room's modifiers. In CREATE_ROOM, fields left out
+// keep their defaults: friendly fire on, an XP multiplier of 1, loot on and
+// not hardcore. Rules the server does not allow, such as too large an XP
+// multiplier, fail the CREATE_ROOM. ROOM_LIST always has every field.
+type RoomRulesPayload struct {
+	FriendlyFire *bool    `json:"friendlyFire,omitempty"` // Members' projectiles hit other members; otherwise only NPCs
+	XPMultiplier *float64 `json:"xpMultiplier,omitempty"` // Scales the XP of defeats
+	NoLoot       bool     `json:"noLoot,omitempty"`       // Defeats drop nothing
+	Hardcore     bool     `json:"hardcore,omitempty"`     // Defeated members are out, and cannot fire or be hit, until they leave the room
+}
+
+// CreateRoomResponsePayload is for "CREATE_ROOM_RESPONSE".
+type CreateRoomResponsePayload struct {
+	Success bool   `json:"success"`
+	RoomID  string `json:"roomId,omitempty"`
+	Message string `json:"message"`
+}
+
+// ListRoomsRequestPayload is for a "LIST_ROOMS" request. It has no fields.
+type ListRoomsRequestPayload struct{}
+
+// RoomSummaryPayload describes one room in a "ROOM_LIST" response.
+type RoomSummaryPayload struct {
+	RoomID           string            `json:"roomId"`
+	Name             string            `json:"name"`
+	CurrentPlayers   int               `json:"currentPlayers"`
+	MaxPlayers       int               `json:"maxPlayers"`
+	PasswordRequired bool              `json:"passwordRequired"`
+	Rules            *RoomRulesPayload `json:"rules,omitempty"` // Active modifiers; absent from servers without room rules
+}
+
+// RoomListPayload is for "ROOM_LIST". Private and invite-only rooms are never listed.
+type RoomListPayload struct {
+	Rooms []RoomSummaryPayload `json:"rooms"`
+}
+
+// CreateRoomInviteRequestPayload is for a "CREATE_ROOM_INVITE" request, sent by a
+// member of the room the player is currently in.
+type CreateRoomInviteRequestPayload struct {
+	InviteePlayerID string `json:"inviteePlayerId,omitempty"` // Restricts the invite to one player; empty allows anyone
+}
+
+// RoomInvitePayload is for "CREATE_ROOM_INVITE_RESPONSE". The token is passed as
+// JoinRoomRequestPayload.InviteToken and can be used once.
+type RoomInvitePayload struct {
+	Success   bool   `json:"success"`
+	RoomID    string `json:"roomId,omitempty"`
+	Token     string `json:"token,omitempty"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"` // Unix seconds
+	Message   string `json:"message,omitempty"`
+}
+
+// RoomTransferPayload is for "ROOM_TRANSFER", sent when the server moves the
+// player to another room, e.g. through a portal or after a dungeon. The player
+// is already in the new room and no longer in the old one; their position and
+// those of the other members follow as POSITION messages, as after JOIN_ROOM.
+type RoomTransferPayload struct {
+	FromRoomID string            `json:"fromRoomId,omitempty"` // Empty if the player was not in a room
+	RoomID     string            `json:"roomId"`
+	RoomName   string            `json:"roomName,omitempty"`
+	MapID      string            `json:"mapId,omitempty"`
+	Reason     string            `json:"reason,omitempty"` // e.g. "portal", "dungeon_complete", "admin"
+	Players    []string          `json:"players"`          // Members of the new room, the player included
+	ServerTime int64             `json:"serverTime"`       // Server clock when the move completed, Unix milliseconds
+	State      *RoomStatePayload `json:"state,omitempty"`  // The new room as the player arrives
+}
+
+// RoomStateRequestPayload is for "ROOM_STATE_REQUEST", answered with
+// "ROOM_STATE" for the room the player is in.
+type RoomStateRequestPayload struct{}
+
+// RoomStatePayload is a snapshot of a room, for "ROOM_STATE" and the state
+// of JOIN_ROOM_RESPONSE and ROOM_TRANSFER, so that players who arrive late
+// see the room as the others do. Changes after it follow as the usual
+// POSITION, APPEARANCE, VOICE_MUTE_STATE and chat messages.
+type RoomStatePayload struct {
+	RoomID     string              `json:"roomId"`
+	Members    []RoomMemberPayload `json:"members"` // By player ID
+	Chat       []RoomChatPayload   `json:"chat"`    // The latest room chat, oldest first
+	NPCs       []RoomNPCPayload    `json:"npcs"`    // By ID
+	Tick       RoomTickPayload     `json:"tick"`
+	Rules      RoomRulesPayload    `json:"rules"` // Active modifiers
+	ServerTime int64               `json:"serverTime"`
+}
+
+// RoomMemberPayload is one member in a RoomStatePayload.
+type RoomMemberPayload struct {
+	PlayerID   string            `json:"playerId"`
+	X          float64           `json:"x"`
+	Y          float64           `json:"y"`
+	Health     int               `json:"health"`
+	Eliminated bool              `json:"eliminated,omitempty"` // Out of a hardcore room until they leave
+	VoiceMuted bool              `json:"voiceMuted,omitempty"`
+	Appearance map[string]string `json:"appearance,omitempty"` // Equipped cosmetics by slot
+}
+
+// RoomChatPayload is one line of room chat in a RoomStatePayload.
+type RoomChatPayload struct {
+	SenderID   string `json:"senderId"`
+	SenderName string `json:"senderName,omitempty"`
+	Text       string `json:"text"`
+	At         int64  `json:"at"` // Unix milliseconds
+}
+
+// RoomNPCPayload is one NPC in a RoomStatePayload.
+type RoomNPCPayload struct {
+	ID        string  `json:"id"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Health    int     `json:"health,omitempty"` // 0 if it cannot be damaged
+	MaxHealth int     `json:"maxHealth,omitempty"`
+}
+
+// RoomTickPayload is where a room's simulation stands.
+type RoomTickPayload struct {
+	Running     bool   `json:"running"`     // The room ticks while it has players and NPCs or projectiles in flight
+	IntervalMs  int64  `json:"intervalMs"`  // Time between ticks
+	Count       uint64 `json:"count"`       // Ticks run since the room started
+	Projectiles int    `json:"projectiles"` // Projectiles in flight
+}
+
+// Room management message types.
+const (
+	MsgTypeCreateRoomRequest        = "CREATE_ROOM"
+	MsgTypeCreateRoomResponse       = "CREATE_ROOM_RESPONSE"
+	MsgTypeListRoomsRequest         = "LIST_ROOMS"
+	MsgTypeRoomList                 = "ROOM_LIST"
+	MsgTypeCreateRoomInviteRequest  = "CREATE_ROOM_INVITE"
+	MsgTypeCreateRoomInviteResponse = "CREATE_ROOM_INVITE_RESPONSE"
+	MsgTypeRoomTransfer             = "ROOM_TRANSFER"
+	MsgTypeRoomStateRequest         = "ROOM_STATE_REQUEST"
+	MsgTypeRoomState                = "ROOM_STATE"
+)
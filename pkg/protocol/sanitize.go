@@ -0,0 +1,209 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxTextLength is the limit, in characters, for text fields without a
+// `text` tag, including strings inside free-form data maps.
+const DefaultMaxTextLength = 256
+
+// TextError reports a text field that was rejected by SanitizePayload.
+type TextError struct {
+	Field  string // JSON path of the field, e.g. "actions[1].data.note"
+	Reason string
+}
+
+func (e *TextError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// textRule is how one text field is cleaned. Payload fields set it with a
+// `text` struct tag: a maximum length, then optional flags.
+//
+//	Text string `json:"text" text:"500,multiline"` // Keeps newlines and tabs
+//	SDP  string `json:"sdp" text:"16384,verbatim"`  // Only UTF-8 and length are checked
+type textRule struct {
+	maxLength int
+	multiline bool
+	verbatim  bool
+}
+
+var defaultTextRule = textRule{maxLength: DefaultMaxTextLength}
+
+func parseTextRule(tag string) textRule {
+	rule := defaultTextRule
+	if tag == "" {
+		return rule
+	}
+	parts := strings.Split(tag, ",")
+	if n, err := strconv.Atoi(parts[0]); err == nil && n > 0 {
+		rule.maxLength = n
+	}
+	for _, flag := range parts[1:] {
+		switch flag {
+		case "multiline":
+			rule.multiline = true
+		case "verbatim":
+			rule.verbatim = true
+		}
+	}
+	return rule
+}
+
+// SanitizePayload checks and cleans every text field of a client payload before
+// the server acts on it. The payload must be valid UTF-8. Text is normalized to
+// NFC, control characters (and newlines, unless the field is multiline) are
+// removed, and text longer than its limit is rejected with a *TextError. The
+// cleaned payload is returned re-encoded, or as given if nothing needed
+// cleaning. Payloads of unregistered message types, or that do not decode as
+// their registered type, are returned unchanged for their handler to reject.
+func SanitizePayload(msgType string, payload json.RawMessage) (json.RawMessage, error) {
+	clean, _, err := sanitizePayload(msgType, payload)
+	return clean, err
+}
+
+// sanitizePayload is SanitizePayload that also returns the cleaned payload
+// decoded into its registered type, or an invalid Value if it was not.
+func sanitizePayload(msgType string, payload json.RawMessage) (json.RawMessage, reflect.Value, error) {
+	if !utf8.Valid(payload) {
+		return nil, reflect.Value{}, &TextError{Field: "payload", Reason: "malformed UTF-8"}
+	}
+	spec, ok := LookupMessage(msgType)
+	if !ok || spec.Payload == nil || len(payload) == 0 || string(payload) == "null" {
+		return payload, reflect.Value{}, nil
+	}
+	value := reflect.New(reflect.TypeOf(spec.Payload))
+	if err := json.Unmarshal(payload, value.Interface()); err != nil {
+		return payload, reflect.Value{}, nil
+	}
+	changed := false
+	if err := sanitizeValue(value.Elem(), "", defaultTextRule, &changed); err != nil {
+		return nil, reflect.Value{}, err
+	}
+	if !changed {
+		return payload, value.Elem(), nil
+	}
+	clean, err := json.Marshal(value.Interface())
+	return clean, value.Elem(), err
+}
+
+// sanitizeValue cleans the text in v, setting *changed if any of it changed.
+func sanitizeValue(v reflect.Value, path string, rule textRule, changed *bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		clean, err := sanitizeString(path, v.String(), rule)
+		if err != nil {
+			return err
+		}
+		if clean != v.String() {
+			v.SetString(clean)
+			*changed = true
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _ := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+			if err := sanitizeValue(v.Field(i), joinPath(path, name), parseTextRule(field.Tag.Get("text")), changed); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := sanitizeValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), rule, changed); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return sanitizeValue(v.Elem(), path, rule, changed)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		// Keys and values are rebuilt, since map entries are not addressable.
+		iter := v.MapRange()
+		cleaned := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter.Next() {
+			key, err := sanitizeString(joinPath(path, iter.Key().String()), iter.Key().String(), defaultTextRule)
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if key != iter.Key().String() {
+				*changed = true
+			}
+			if err := sanitizeValue(elem, joinPath(path, key), rule, changed); err != nil {
+				return err
+			}
+			cleaned.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(cleaned)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// Free-form JSON: copy the dynamic value out, clean it and put it back.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := sanitizeValue(elem, path, rule, changed); err != nil {
+			return err
+		}
+		v.Set(elem)
+	}
+	return nil
+}
+
+func sanitizeString(path, s string, rule textRule) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", &TextError{Field: path, Reason: "malformed UTF-8"}
+	}
+	if !rule.verbatim {
+		// Controls are removed before normalizing: removing one between a
+		// letter and a combining mark afterwards would leave text that is not
+		// NFC, and cleaning it again would change it.
+		s = norm.NFC.String(strings.Map(func(r rune) rune {
+			if (r == '\n' || r == '\t') && rule.multiline {
+				return r
+			}
+			if unicode.Is(unicode.Cc, r) || isBidiControl(r) {
+				return -1
+			}
+			return r
+		}, s))
+	}
+	if n := utf8.RuneCountInString(s); n > rule.maxLength {
+		return "", &TextError{Field: path, Reason: fmt.Sprintf("too long (%d characters, at most %d)", n, rule.maxLength)}
+	}
+	return s, nil
+}
+
+// isBidiControl reports the explicit direction overrides and isolates, which
+// can make displayed text read differently from what was sent.
+func isBidiControl(r rune) bool {
+	return (r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069')
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
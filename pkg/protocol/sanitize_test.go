@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizePayloadCleansText(t *testing.T) {
+	raw := json.RawMessage("{\"text\":\"he\\u0301llo\\u0007\\n\\u202Eworld\"}")
+	clean, err := SanitizePayload(MsgTypeSendChat, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chat ChatMessagePayload
+	if err := json.Unmarshal(clean, &chat); err != nil {
+		t.Fatal(err)
+	}
+	// NFC composes e + U+0301; the bell and the override are dropped; chat keeps newlines.
+	if want := "héllo\nworld"; chat.Text != want {
+		t.Fatalf("text = %q, want %q", chat.Text, want)
+	}
+
+	clean, err = SanitizePayload(MsgTypeCreateRoomRequest, json.RawMessage(`{"name":"lobby\none"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var room CreateRoomRequestPayload
+	json.Unmarshal(clean, &room)
+	if room.Name != "lobbyone" {
+		t.Fatalf("room name = %q, want newline removed", room.Name)
+	}
+}
+
+func TestSanitizePayloadNestedData(t *testing.T) {
+	raw := json.RawMessage(`{"actionType":"USE_ITEM","data":{"note":"a\u0000b","tags":["x\u001by"],"count":3}}`)
+	clean, err := SanitizePayload(MsgTypePlayerAction, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var action PlayerActionPayload
+	json.Unmarshal(clean, &action)
+	if action.Data["note"] != "ab" || action.Data["tags"].([]interface{})[0] != "xy" || action.Data["count"] != float64(3) {
+		t.Fatalf("data = %v", action.Data)
+	}
+}
+
+func TestSanitizePayloadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType string
+		payload string
+	}{
+		{"malformed UTF-8", MsgTypeSendChat, "{\"text\":\"bad \xff\"}"},
+		{"long chat", MsgTypeSendChat, `{"text":"` + strings.Repeat("a", 501) + `"}`},
+		{"long room name", MsgTypeCreateRoomRequest, `{"name":"` + strings.Repeat("é", 65) + `"}`},
+		{"long data value", MsgTypePlayerAction, `{"actionType":"MOVE","data":{"x":"` + strings.Repeat("a", DefaultMaxTextLength+1) + `"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SanitizePayload(tt.msgType, json.RawMessage(tt.payload))
+			var textErr *TextError
+			if !errors.As(err, &textErr) {
+				t.Fatalf("got %v, want a *TextError", err)
+			}
+		})
+	}
+
+	// Verbatim fields keep their line breaks.
+	sdp := "v=0\r\no=- 1 2 IN IP4 127.0.0.1\r\n"
+	payload, _ := json.Marshal(VoiceSessionDescriptionPayload{ToPlayerID: "p2", SDP: sdp})
+	clean, err := SanitizePayload(MsgTypeVoiceOffer, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offer VoiceSessionDescriptionPayload
+	json.Unmarshal(clean, &offer)
+	if offer.SDP != sdp {
+		t.Fatalf("sdp = %q, want it unchanged", offer.SDP)
+	}
+}
@@ -0,0 +1,255 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaDraft is the JSON Schema dialect used for the exported schema.
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the exported description of the wire protocol. It is a JSON Schema
+// document with an extra "messages" section mapping message types to payloads.
+type Schema struct {
+	Schema      string                   `json:"$schema"`
+	Title       string                   `json:"title"`
+	Version     int                      `json:"version"`
+	Envelope    *TypeSchema              `json:"envelope"`
+	Messages    map[string]MessageSchema `json:"messages"`
+	Definitions map[string]*TypeSchema   `json:"definitions"`
+}
+
+// MessageSchema describes one message type in the exported schema.
+type MessageSchema struct {
+	TypeID    uint16      `json:"typeId,omitempty"` // Version 2 frame type ID
+	Direction string      `json:"direction"`
+	Payload   *TypeSchema `json:"payload"`
+}
+
+// TypeSchema is the subset of JSON Schema needed to describe payload structs.
+type TypeSchema struct {
+	Ref        string                 `json:"$ref,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Properties map[string]*TypeSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Items      *TypeSchema            `json:"items,omitempty"`
+	MaxLength  int                    `json:"maxLength,omitempty"` // From a field's `text` tag, see SanitizePayload
+}
+
+const definitionsPrefix = "#/definitions/"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// GenerateSchema builds the protocol schema from the message registry.
+func GenerateSchema() *Schema {
+	s := &Schema{
+		Schema:      SchemaDraft,
+		Title:       "suigserver wire protocol",
+		Version:     ProtocolVersion,
+		Messages:    make(map[string]MessageSchema, len(messageRegistry)),
+		Definitions: make(map[string]*TypeSchema),
+	}
+	s.Envelope = s.typeSchema(reflect.TypeOf(ClientServerMessage{}))
+	for _, spec := range messageRegistry {
+		s.Messages[spec.Type] = MessageSchema{
+			TypeID:    spec.ID,
+			Direction: spec.Direction,
+			Payload:   s.typeSchema(reflect.TypeOf(spec.Payload)),
+		}
+	}
+	return s
+}
+
+// MarshalSchema renders the schema as indented JSON with a trailing newline,
+// the format written to schema.json.
+func MarshalSchema(s *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ParseSchema decodes a schema previously produced by MarshalSchema.
+func ParseSchema(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid protocol schema: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *Schema) typeSchema(t reflect.Type) *TypeSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		return &TypeSchema{} // Any JSON
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &TypeSchema{Type: "string"}
+	case reflect.Bool:
+		return &TypeSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &TypeSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &TypeSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &TypeSchema{Type: "string"} // []byte is base64 in encoding/json
+		}
+		return &TypeSchema{Type: "array", Items: s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &TypeSchema{Type: "object"}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := s.Definitions[name]; !ok {
+			def := &TypeSchema{Type: "object", Properties: make(map[string]*TypeSchema)}
+			s.Definitions[name] = def // registered before recursing to allow self references
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				if field.PkgPath != "" {
+					continue // unexported
+				}
+				jsonName, omitEmpty := jsonFieldName(field)
+				if jsonName == "" {
+					continue
+				}
+				def.Properties[jsonName] = s.typeSchema(field.Type)
+				if tag := field.Tag.Get("text"); tag != "" && field.Type.Kind() == reflect.String {
+					def.Properties[jsonName].MaxLength = parseTextRule(tag).maxLength
+				}
+				if !omitEmpty {
+					def.Required = append(def.Required, jsonName)
+				}
+			}
+			sort.Strings(def.Required)
+		}
+		return &TypeSchema{Ref: definitionsPrefix + name}
+	default:
+		// interface{} and anything else is left unconstrained.
+		return &TypeSchema{}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+// CheckCompatibility reports every change between oldSchema and newSchema that
+// would break a client built against oldSchema: removed message types, changed
+// directions, removed or retyped fields, and newly required fields. Purely
+// additive changes are allowed. An empty result means the schemas are compatible.
+func CheckCompatibility(oldSchema, newSchema *Schema) []string {
+	var problems []string
+	msgTypes := make([]string, 0, len(oldSchema.Messages))
+	for msgType := range oldSchema.Messages {
+		msgTypes = append(msgTypes, msgType)
+	}
+	sort.Strings(msgTypes)
+
+	for _, msgType := range msgTypes {
+		oldMsg := oldSchema.Messages[msgType]
+		newMsg, ok := newSchema.Messages[msgType]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("message %s was removed", msgType))
+			continue
+		}
+		if oldMsg.TypeID != 0 && oldMsg.TypeID != newMsg.TypeID {
+			problems = append(problems, fmt.Sprintf("message %s changed type ID from %d to %d", msgType, oldMsg.TypeID, newMsg.TypeID))
+		}
+		if oldMsg.Direction != newMsg.Direction {
+			problems = append(problems, fmt.Sprintf("message %s changed direction from %s to %s", msgType, oldMsg.Direction, newMsg.Direction))
+		}
+		problems = append(problems, compareTypes("message "+msgType, oldMsg.Payload, newMsg.Payload, oldSchema, newSchema, map[string]bool{})...)
+	}
+	if oldSchema.Envelope != nil {
+		problems = append(problems, compareTypes("envelope", oldSchema.Envelope, newSchema.Envelope, oldSchema, newSchema, map[string]bool{})...)
+	}
+	return problems
+}
+
+func compareTypes(path string, oldType, newType *TypeSchema, oldSchema, newSchema *Schema, seen map[string]bool) []string {
+	if oldType == nil {
+		return nil
+	}
+	if newType == nil {
+		return []string{fmt.Sprintf("%s was removed", path)}
+	}
+	oldResolved, oldName := resolve(oldType, oldSchema)
+	newResolved, _ := resolve(newType, newSchema)
+	if oldResolved == nil {
+		return nil
+	}
+	if newResolved == nil {
+		return []string{fmt.Sprintf("%s references missing definition %s", path, newType.Ref)}
+	}
+	if oldResolved.Type != newResolved.Type {
+		return []string{fmt.Sprintf("%s changed type from %q to %q", path, oldResolved.Type, newResolved.Type)}
+	}
+	if oldName != "" {
+		if seen[oldName] {
+			return nil
+		}
+		seen[oldName] = true
+	}
+
+	var problems []string
+	if oldResolved.Items != nil {
+		problems = append(problems, compareTypes(path+"[]", oldResolved.Items, newResolved.Items, oldSchema, newSchema, seen)...)
+	}
+
+	fields := make([]string, 0, len(oldResolved.Properties))
+	for field := range oldResolved.Properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		newField, ok := newResolved.Properties[field]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s field %q was removed", path, field))
+			continue
+		}
+		problems = append(problems, compareTypes(path+"."+field, oldResolved.Properties[field], newField, oldSchema, newSchema, seen)...)
+	}
+
+	oldRequired := make(map[string]bool, len(oldResolved.Required))
+	for _, field := range oldResolved.Required {
+		oldRequired[field] = true
+	}
+	for _, field := range newResolved.Required {
+		if !oldRequired[field] {
+			problems = append(problems, fmt.Sprintf("%s field %q is now required", path, field))
+		}
+	}
+	return problems
+}
+
+// resolve follows a $ref into the schema's definitions. It returns the
+// definition name when t was a reference.
+func resolve(t *TypeSchema, s *Schema) (*TypeSchema, string) {
+	if t.Ref == "" {
+		return t, ""
+	}
+	name := strings.TrimPrefix(t.Ref, definitionsPrefix)
+	return s.Definitions[name], name
+}
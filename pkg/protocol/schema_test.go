@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestSchemaCompatibility fails the build when a protocol change breaks clients
+// generated from the committed schema.json, or when schema.json is stale.
+func TestSchemaCompatibility(t *testing.T) {
+	committedData, err := os.ReadFile("schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read committed schema: %v", err)
+	}
+	committed, err := ParseSchema(committedData)
+	if err != nil {
+		t.Fatalf("Failed to parse committed schema: %v", err)
+	}
+
+	generated := GenerateSchema()
+	for _, problem := range CheckCompatibility(committed, generated) {
+		t.Errorf("Breaking protocol change: %s", problem)
+	}
+
+	generatedData, err := MarshalSchema(generated)
+	if err != nil {
+		t.Fatalf("Failed to marshal generated schema: %v", err)
+	}
+	if !bytes.Equal(committedData, generatedData) {
+		t.Errorf("schema.json is out of date; run 'go run ./server/cmd/schemagen' from the repository root")
+	}
+}
+
+func TestCheckCompatibilityDetectsBreakingChanges(t *testing.T) {
+	oldSchema := GenerateSchema()
+
+	newSchema := GenerateSchema()
+	delete(newSchema.Messages, MsgTypePing)
+	chat := *newSchema.Definitions["ChatMessagePayload"]
+	chat.Properties = map[string]*TypeSchema{"text": {Type: "integer"}}
+	chat.Required = []string{"senderName", "text"}
+	newSchema.Definitions["ChatMessagePayload"] = &chat
+
+	problems := CheckCompatibility(oldSchema, newSchema)
+	expected := []string{
+		"message PING was removed",
+		"changed type",
+		"field \"senderName\" was removed",
+		"field \"senderName\" is now required",
+	}
+	for _, want := range expected {
+		found := false
+		for _, problem := range problems {
+			if strings.Contains(problem, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected a problem containing %q, got %v", want, problems)
+		}
+	}
+
+	if problems := CheckCompatibility(oldSchema, GenerateSchema()); len(problems) != 0 {
+		t.Errorf("Expected identical schemas to be compatible, got %v", problems)
+	}
+}
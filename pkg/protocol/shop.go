@@ -0,0 +1,75 @@
+package protocol
+
+// NPC shops. Items are bought and sold for soft currency, except premium
+// items, which the player pays for on-chain: they transfer the premium price to
+// the shop's premiumRecipient, then send SHOP_TRANSACTION with the transaction
+// digest, and the item is minted to them as an NFT.
+
+// Shop transaction actions.
+const (
+	ShopActionBuy  = "buy"
+	ShopActionSell = "sell"
+)
+
+// ShopBrowseRequestPayload is for "SHOP_BROWSE".
+type ShopBrowseRequestPayload struct {
+	ShopID string `json:"shopId"`
+}
+
+// ShopPremiumPricePayload is the on-chain price of a premium item.
+type ShopPremiumPricePayload struct {
+	Price    uint64 `json:"price"` // In the coin's smallest unit, e.g. MIST for SUI
+	CoinType string `json:"coinType"`
+}
+
+// ShopItemPayload is one item in "SHOP_INVENTORY".
+type ShopItemPayload struct {
+	ItemID        string                   `json:"itemId"`
+	Name          string                   `json:"name"`
+	BuyPrice      int64                    `json:"buyPrice,omitempty"`      // 0 if not sold for coins
+	SellPrice     int64                    `json:"sellPrice,omitempty"`     // 0 if the shop does not buy it
+	Stock         int                      `json:"stock"`                   // -1 for unlimited
+	NextRestock   int64                    `json:"nextRestock,omitempty"`   // Unix milliseconds
+	PurchasesLeft int                      `json:"purchasesLeft"`           // -1 for no limit
+	LimitResetsAt int64                    `json:"limitResetsAt,omitempty"` // Unix milliseconds
+	Premium       *ShopPremiumPricePayload `json:"premium,omitempty"`
+}
+
+// ShopInventoryPayload is for "SHOP_INVENTORY".
+type ShopInventoryPayload struct {
+	ShopID           string            `json:"shopId"`
+	Name             string            `json:"name"`
+	Coins            int64             `json:"coins"` // The player's balance
+	Items            []ShopItemPayload `json:"items"`
+	PremiumRecipient string            `json:"premiumRecipient,omitempty"` // Address premium payments go to
+	ServerTime       int64             `json:"serverTime,omitempty"`       // Server clock when sent, to count down to the restock
+}
+
+// ShopTransactionRequestPayload is for "SHOP_TRANSACTION".
+type ShopTransactionRequestPayload struct {
+	ShopID          string `json:"shopId"`
+	ItemID          string `json:"itemId"`
+	Action          string `json:"action"`                    // ShopActionBuy or ShopActionSell
+	Quantity        int    `json:"quantity,omitempty"`        // Defaults to 1
+	PaymentTxDigest string `json:"paymentTxDigest,omitempty"` // Required to buy a premium item
+}
+
+// ShopTransactionResultPayload is for "SHOP_TRANSACTION_RESULT".
+type ShopTransactionResultPayload struct {
+	ShopID   string `json:"shopId"`
+	ItemID   string `json:"itemId"`
+	Action   string `json:"action"`
+	Success  bool   `json:"success"`
+	Quantity int    `json:"quantity,omitempty"`
+	Price    int64  `json:"price,omitempty"`   // Coins paid or received
+	Coins    int64  `json:"coins,omitempty"`   // Balance afterwards
+	Premium  bool   `json:"premium,omitempty"` // The item is being minted on-chain
+	Message  string `json:"message,omitempty"`
+}
+
+const (
+	MsgTypeShopBrowse            = "SHOP_BROWSE"
+	MsgTypeShopInventory         = "SHOP_INVENTORY"
+	MsgTypeShopTransaction       = "SHOP_TRANSACTION"
+	MsgTypeShopTransactionResult = "SHOP_TRANSACTION_RESULT"
+)
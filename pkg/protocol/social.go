@@ -0,0 +1,40 @@
+package protocol
+
+// Social actions: emotes, pings on the map and quick replies. They are meant
+// for the many small gestures players make, so they are tiny, allowed much
+// more often than chat, and do not go through chat filtering or history. The
+// server only checks the name's form; what "wave" or "danger" looks like and
+// how a quick reply reads in the player's language is up to the client.
+// A player sends SOCIAL_ACTION and everyone in their room, the player included,
+// gets SOCIAL. Sending too fast is answered with an ERROR with code
+// SOCIAL_THROTTLED.
+
+// Social action kinds.
+const (
+	SocialEmote      = "emote" // An animation, e.g. "wave"
+	SocialPing       = "ping"  // A marker on the map at x, y, e.g. "danger"
+	SocialQuickReply = "quick" // A canned line, e.g. "thanks"
+)
+
+// SocialActionPayload is for "SOCIAL_ACTION".
+type SocialActionPayload struct {
+	Kind string  `json:"kind"`
+	Name string  `json:"name" text:"32"` // Lowercase letters, digits and underscores
+	X    float64 `json:"x,omitempty"`    // Pings only
+	Y    float64 `json:"y,omitempty"`
+}
+
+// SocialPayload is for "SOCIAL".
+type SocialPayload struct {
+	PlayerID string  `json:"playerId"`
+	Kind     string  `json:"kind"`
+	Name     string  `json:"name"`
+	X        float64 `json:"x,omitempty"`
+	Y        float64 `json:"y,omitempty"`
+}
+
+// Social message types.
+const (
+	MsgTypeSocialAction = "SOCIAL_ACTION"
+	MsgTypeSocial       = "SOCIAL"
+)
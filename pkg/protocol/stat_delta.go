@@ -0,0 +1,19 @@
+package protocol
+
+// Stat changes. When the server changes a player's health, level, XP or
+// coins, it sends STAT_DELTA with the new values. Changes are batched: one
+// message per tick carries every stat that changed since the last, the
+// player's own and, for health, those of the members of their room.
+
+// StatDeltaPayload is for "STAT_DELTA".
+type StatDeltaPayload struct {
+	Changes []StatChangePayload `json:"changes"`
+}
+
+// StatChangePayload is the new values of one player's changed stats.
+type StatChangePayload struct {
+	PlayerID string           `json:"playerId"`
+	Stats    map[string]int64 `json:"stats"` // "hp", "level", "xp" or "coins" -> new value
+}
+
+const MsgTypeStatDelta = "STAT_DELTA"
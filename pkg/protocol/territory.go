@@ -0,0 +1,27 @@
+package protocol
+
+// Guild territory. A guild leader or officer executes the claim_territory Move
+// call, then reports the transaction digest so the server can grant the zone
+// or schedule a siege against its current holder.
+
+// ClaimZoneRequestPayload is for "CLAIM_ZONE".
+type ClaimZoneRequestPayload struct {
+	ZoneID   string `json:"zoneId"`
+	TxDigest string `json:"txDigest"` // Executed claim_territory transaction
+}
+
+// ClaimZoneResponsePayload is for "CLAIM_ZONE_RESPONSE".
+type ClaimZoneResponsePayload struct {
+	ZoneID        string `json:"zoneId"`
+	GuildID       string `json:"guildId,omitempty"`
+	Success       bool   `json:"success"`
+	Granted       bool   `json:"granted"`                 // False with Success means a siege was scheduled
+	SiegeStartsAt int64  `json:"siegeStartsAt,omitempty"` // Unix milliseconds
+	SiegeEndsAt   int64  `json:"siegeEndsAt,omitempty"`   // Unix milliseconds
+	Message       string `json:"message,omitempty"`
+}
+
+const (
+	MsgTypeClaimZone         = "CLAIM_ZONE"
+	MsgTypeClaimZoneResponse = "CLAIM_ZONE_RESPONSE"
+)
@@ -0,0 +1,44 @@
+package protocol
+
+// Clock synchronization, for cooldowns and event timers that clients count
+// down on their own clock. The client sends TIME_SYNC with t0, its clock when
+// sending. TIME_SYNC_RESPONSE echoes t0 with receivedAt and sentAt, the
+// server's clock when the request arrived and when the answer left. With t3,
+// its clock when the answer arrived, the client estimates
+//
+//	roundTrip = (t3 - t0) - (sentAt - receivedAt)
+//	offset    = ((receivedAt - t0) + (sentAt - t3)) / 2
+//
+// and adds offset to its clock to read the server's. A few samples a few
+// hundred milliseconds apart, keeping the one with the shortest round trip,
+// give a good estimate. Clocks drift, so the server also pushes CLOCK_HINT
+// with its time every syncInterval seconds; a client whose estimate is off by
+// more than tolerance plus half its round trip should sync again.
+// Payloads with deadlines, such as COMBAT_TURN, ENERGY and CRAFT_QUEUE, and
+// AUTH_RESPONSE and JOIN_ROOM_RESPONSE carry serverTime too.
+// All times are Unix milliseconds.
+
+// TimeSyncRequestPayload is for "TIME_SYNC".
+type TimeSyncRequestPayload struct {
+	T0 int64 `json:"t0"` // The client's clock; echoed back as is
+}
+
+// TimeSyncResponsePayload is for "TIME_SYNC_RESPONSE".
+type TimeSyncResponsePayload struct {
+	T0           int64 `json:"t0"`
+	ReceivedAt   int64 `json:"receivedAt"`
+	SentAt       int64 `json:"sentAt"`
+	SyncInterval int   `json:"syncInterval,omitempty"` // Seconds between CLOCK_HINTs; absent if the server sends none
+}
+
+// ClockHintPayload is for "CLOCK_HINT".
+type ClockHintPayload struct {
+	ServerTime int64 `json:"serverTime"`
+	Tolerance  int   `json:"tolerance"` // Milliseconds of drift worth a new TIME_SYNC
+}
+
+const (
+	MsgTypeTimeSync         = "TIME_SYNC"
+	MsgTypeTimeSyncResponse = "TIME_SYNC_RESPONSE"
+	MsgTypeClockHint        = "CLOCK_HINT"
+)
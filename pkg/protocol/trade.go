@@ -0,0 +1,71 @@
+package protocol
+
+// Player-to-player trades of item NFTs and SUI. A player proposes a trade with
+// TRADE_PROPOSE; both players then get TRADE_UPDATE whenever it changes.
+//
+// Trades worth more than the server's escrow threshold go through an on-chain
+// escrow: once accepted, the server creates it and reports its escrowId. Each
+// player deposits their side into it with the escrow module's deposit_item and
+// deposit_coins calls and then sends TRADE_DEPOSITED. When both sides are in,
+// the server releases both at once; if the trade is cancelled or the deposit
+// deadline passes, every deposit is refunded. Smaller trades are settled by the
+// players' own transfers once accepted.
+//
+// The server may charge each player a fee, shown in their TRADE_UPDATE. In an
+// escrowed trade it is MIST deposited on top of the player's side and paid to
+// the treasury on release; in a direct trade it is coins taken from the
+// player's wallet on acceptance, which fails with TRADE_FEE_UNPAID if either
+// player is short.
+
+// Trade respond actions.
+const (
+	TradeActionAccept  = "accept"
+	TradeActionDecline = "decline" // Also withdraws the player's own proposal or calls off an escrow awaiting deposits
+)
+
+// TradeOfferPayload is what one side of a trade gives.
+type TradeOfferPayload struct {
+	ItemIDs []string `json:"itemIds,omitempty"` // Item NFT object IDs
+	Coins   uint64   `json:"coins,omitempty"`   // MIST
+}
+
+// TradeProposeRequestPayload is for "TRADE_PROPOSE".
+type TradeProposeRequestPayload struct {
+	PlayerID string            `json:"playerId"` // The other player
+	Give     TradeOfferPayload `json:"give"`
+	Want     TradeOfferPayload `json:"want"`
+}
+
+// TradeRespondRequestPayload is for "TRADE_RESPOND".
+type TradeRespondRequestPayload struct {
+	TradeID string `json:"tradeId"`
+	Action  string `json:"action"` // TradeActionAccept or TradeActionDecline
+}
+
+// TradeDepositedRequestPayload is for "TRADE_DEPOSITED", sent after the
+// player's deposit transactions executed.
+type TradeDepositedRequestPayload struct {
+	TradeID string `json:"tradeId"`
+}
+
+// TradeUpdatePayload is for "TRADE_UPDATE". Give is what the proposer gives.
+type TradeUpdatePayload struct {
+	TradeID      string            `json:"tradeId"`
+	Proposer     string            `json:"proposer"`
+	Counterparty string            `json:"counterparty"`
+	Give         TradeOfferPayload `json:"give"`
+	Want         TradeOfferPayload `json:"want"`
+	Value        uint64            `json:"value"` // Estimated MIST value
+	Escrowed     bool              `json:"escrowed"`
+	Status       string            `json:"status"`             // proposed, accepted, declined, cancelled, expired, escrow_creating, awaiting_deposits, releasing, completed, refunding or refunded
+	EscrowID     string            `json:"escrowId,omitempty"` // Shared escrow object to deposit into
+	Deadline     int64             `json:"deadline,omitempty"` // Unix milliseconds; for the answer, or for the deposits
+	Fee          uint64            `json:"fee,omitempty"`      // What this player pays: MIST if escrowed, coins otherwise
+}
+
+const (
+	MsgTypeTradePropose   = "TRADE_PROPOSE"
+	MsgTypeTradeRespond   = "TRADE_RESPOND"
+	MsgTypeTradeDeposited = "TRADE_DEPOSITED"
+	MsgTypeTradeUpdate    = "TRADE_UPDATE"
+)
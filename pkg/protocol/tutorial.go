@@ -0,0 +1,19 @@
+package protocol
+
+// Server-driven tutorial. The server pushes the player's current step whenever
+// it changes; clients only render the prompt.
+
+// TutorialStepPayload is for "TUTORIAL_STEP".
+type TutorialStepPayload struct {
+	StepID           string   `json:"stepId,omitempty"` // Empty once the tutorial is finished
+	Prompt           string   `json:"prompt,omitempty"`
+	Hint             string   `json:"hint,omitempty"`
+	StepIndex        int      `json:"stepIndex"` // Zero-based
+	TotalSteps       int      `json:"totalSteps"`
+	CompletedStepIDs []string `json:"completedStepIds,omitempty"` // Steps just completed, if any
+	Finished         bool     `json:"finished"`
+}
+
+const (
+	MsgTypeTutorialStep = "TUTORIAL_STEP"
+)
@@ -0,0 +1,17 @@
+package protocol
+
+// Transaction receipts. When a transaction the server executed for the player
+// completes, such as the mint of a crafted item or the settlement of an
+// escrowed trade, the server sends TX_RECEIPT. Offline players get the
+// receipt as mail of kind "chain.tx_receipt" instead.
+
+// TxReceiptPayload is for "TX_RECEIPT".
+type TxReceiptPayload struct {
+	Kind    string `json:"kind"`             // What the transaction did, e.g. "crafting", "battlepass", "shop", "arena" or "trade"
+	Digest  string `json:"digest,omitempty"` // Empty if the server gave up before a transaction executed
+	Status  string `json:"status"`           // "success" or "failure"
+	Summary string `json:"summary"`
+	At      int64  `json:"at"` // Unix milliseconds
+}
+
+const MsgTypeTxReceipt = "TX_RECEIPT"
@@ -0,0 +1,51 @@
+package protocol
+
+// Voice chat signaling. The server only relays these messages between members
+// of the same room so clients can set up peer-to-peer (or SFU) WebRTC sessions;
+// it never handles audio.
+
+// MaxVoiceSDPLength bounds the size of an SDP offer or answer.
+const MaxVoiceSDPLength = 16 * 1024
+
+// MaxVoiceCandidateLength bounds the size of an ICE candidate line.
+const MaxVoiceCandidateLength = 1024
+
+// VoiceSessionDescriptionPayload is for "VOICE_OFFER" and "VOICE_ANSWER".
+type VoiceSessionDescriptionPayload struct {
+	FromPlayerID string `json:"fromPlayerId,omitempty"` // Set by the server when relaying
+	ToPlayerID   string `json:"toPlayerId"`
+	SDP          string `json:"sdp" text:"16384,verbatim"`
+}
+
+// VoiceICECandidatePayload is for "VOICE_ICE_CANDIDATE".
+type VoiceICECandidatePayload struct {
+	FromPlayerID  string `json:"fromPlayerId,omitempty"` // Set by the server when relaying
+	ToPlayerID    string `json:"toPlayerId"`
+	Candidate     string `json:"candidate" text:"1024,verbatim"`
+	SDPMid        string `json:"sdpMid,omitempty" text:"1024,verbatim"`
+	SDPMLineIndex int    `json:"sdpMLineIndex,omitempty"`
+}
+
+// VoiceMuteRequestPayload is for "VOICE_MUTE". Without a target it changes the
+// sender's own mute state; the room owner may also mute or unmute other members.
+type VoiceMuteRequestPayload struct {
+	TargetPlayerID string `json:"targetPlayerId,omitempty"`
+	Muted          bool   `json:"muted"`
+}
+
+// VoiceMuteStatePayload is for "VOICE_MUTE_STATE", broadcast to the room whenever
+// a member's mute state changes, and sent to new members for everyone muted.
+type VoiceMuteStatePayload struct {
+	PlayerID    string `json:"playerId"`
+	Muted       bool   `json:"muted"`
+	ByModerator bool   `json:"byModerator,omitempty"` // Muted by the room owner; the player cannot unmute
+}
+
+// Voice signaling message types.
+const (
+	MsgTypeVoiceOffer        = "VOICE_OFFER"
+	MsgTypeVoiceAnswer       = "VOICE_ANSWER"
+	MsgTypeVoiceICECandidate = "VOICE_ICE_CANDIDATE"
+	MsgTypeVoiceMute         = "VOICE_MUTE"
+	MsgTypeVoiceMuteState    = "VOICE_MUTE_STATE"
+)
@@ -0,0 +1,56 @@
+package protocol
+
+// Wallet links. A player links a Sui address by signing a server-issued
+// message with that address's wallet. On-chain actions that target the
+// player, such as premium shop items and arena trophies, use their primary
+// linked address.
+
+// WalletLinkChallengeRequestPayload is for "WALLET_LINK_CHALLENGE_REQUEST".
+type WalletLinkChallengeRequestPayload struct {
+	Address string `json:"address"`
+}
+
+// WalletLinkChallengePayload is for "WALLET_LINK_CHALLENGE". The wallet signs
+// Message as a personal message before ExpiresAt.
+type WalletLinkChallengePayload struct {
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	ExpiresAt int64  `json:"expiresAt"` // Unix milliseconds
+}
+
+// LinkWalletRequestPayload is for "LINK_WALLET".
+type LinkWalletRequestPayload struct {
+	Address   string `json:"address"`
+	Signature string `json:"signature" text:"512,verbatim"` // Serialized Sui signature of the challenge message, base64
+	Primary   bool   `json:"primary,omitempty"`             // Make this the address on-chain actions target
+}
+
+// UnlinkWalletRequestPayload is for "UNLINK_WALLET".
+type UnlinkWalletRequestPayload struct {
+	Address string `json:"address"`
+}
+
+// WalletLinksRequestPayload is for "WALLET_LINKS_REQUEST".
+type WalletLinksRequestPayload struct{}
+
+// WalletLink is one linked address in "WALLET_LINKS".
+type WalletLink struct {
+	Address  string `json:"address"`
+	Primary  bool   `json:"primary"`
+	LinkedAt int64  `json:"linkedAt"` // Unix milliseconds
+}
+
+// WalletLinksPayload is for "WALLET_LINKS", the answer to a link, unlink or
+// links request.
+type WalletLinksPayload struct {
+	Links []WalletLink `json:"links"`
+}
+
+const (
+	MsgTypeWalletLinkChallengeRequest = "WALLET_LINK_CHALLENGE_REQUEST"
+	MsgTypeWalletLinkChallenge        = "WALLET_LINK_CHALLENGE"
+	MsgTypeLinkWallet                 = "LINK_WALLET"
+	MsgTypeUnlinkWallet               = "UNLINK_WALLET"
+	MsgTypeWalletLinksRequest         = "WALLET_LINKS_REQUEST"
+	MsgTypeWalletLinks                = "WALLET_LINKS"
+)
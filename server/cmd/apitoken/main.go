@@ -0,0 +1,82 @@
+// Command apitoken manages the API tokens of the HTTP gateway, stored hashed
+// in the file named by apiTokens.file. A running server picks up changes
+// within a second.
+//
+//	go run ./server/cmd/apitoken -file api-tokens.json create -name launcher -scopes read:status,read:market
+//	go run ./server/cmd/apitoken -file api-tokens.json list
+//	go run ./server/cmd/apitoken -file api-tokens.json revoke -id 3f2a9c01b4de -reason "leaked"
+//
+// create prints the token once; only its hash is kept.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phuhao00/suigserver/server/internal/apitoken"
+)
+
+func main() {
+	file := flag.String("file", "api-tokens.json", "Path of the token file (apiTokens.file)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: apitoken [-file path] create|list|revoke [flags]\n\nScopes: %s\n", strings.Join(apitoken.Scopes, ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	store := apitoken.FileStore{Path: *file}
+
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "create":
+		create := flag.NewFlagSet("create", flag.ExitOnError)
+		name := create.String("name", "", "Who or what uses the token, e.g. launcher")
+		scopes := create.String("scopes", "", "Comma-separated scopes")
+		perMinute := create.Int("per-minute", 0, "Requests allowed per minute (0 uses the server default)")
+		burst := create.Int("burst", 0, "Requests allowed at once (0 uses the server default)")
+		create.Parse(args)
+		token, value, err := store.Create(*name, strings.Split(*scopes, ","), *perMinute, *burst)
+		if err != nil {
+			log.Fatalf("Failed to create the token: %v", err)
+		}
+		log.Printf("Created token %s for %s with scopes %s. It is shown only once:", token.ID, token.Name, strings.Join(token.Scopes, ", "))
+		fmt.Println(value)
+	case "list":
+		tokens, err := store.Load()
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *file, err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPES\tLIMIT\tCREATED\tREVOKED")
+		for _, token := range tokens {
+			limit := "default"
+			if token.PerMinute > 0 {
+				limit = fmt.Sprintf("%d/min", token.PerMinute)
+			}
+			revoked := "-"
+			if token.Revoked() {
+				revoked = token.RevokedAt.Format("2006-01-02") + " " + token.RevokedReason
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", token.ID, token.Name, strings.Join(token.Scopes, ","), limit, token.CreatedAt.Format("2006-01-02"), revoked)
+		}
+		w.Flush()
+	case "revoke":
+		revoke := flag.NewFlagSet("revoke", flag.ExitOnError)
+		id := revoke.String("id", "", "ID of the token to revoke")
+		reason := revoke.String("reason", "", "Why it is revoked")
+		revoke.Parse(args)
+		if err := store.Revoke(*id, *reason); err != nil {
+			log.Fatalf("Failed to revoke the token: %v", err)
+		}
+		log.Printf("Revoked token %s.", *id)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
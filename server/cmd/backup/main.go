@@ -0,0 +1,216 @@
+// Command backup writes and restores bundles of the game server's state: a
+// pg_dump of the database, the Redis keys, the state files named in the config
+// and the chain's epoch and checkpoint. See the "Backup and Restore" section of
+// the README for the recovery steps.
+//
+//	go run ./server/cmd/backup -config config.json create -dir backups/2026-10-16
+//	go run ./server/cmd/backup verify -dir backups/2026-10-16
+//	go run ./server/cmd/backup -config config.json restore -dir backups/2026-10-16 -yes
+//
+// restore replaces the database, every Redis key and the state files, so the
+// game server must be stopped first. Without -yes it only verifies the bundle
+// and prints what it would replace.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/backup"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+)
+
+func main() {
+	configPath := flag.String("config", "config.json", "Path of the game server's configuration file")
+	pgDump := flag.String("pg-dump", "pg_dump", "pg_dump binary, of the database server's major version or newer")
+	pgRestore := flag.String("pg-restore", "pg_restore", "pg_restore binary")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: backup [-config path] create|verify|restore -dir bundle [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "create":
+		create := flag.NewFlagSet("create", flag.ExitOnError)
+		dir := create.String("dir", "", "Directory to write the bundle to; must not exist or be empty")
+		create.Parse(args)
+		cfg, fingerprint := loadConfig(*configPath)
+		db := connect(cfg)
+		src := backup.Sources{ConfigFingerprint: fingerprint, Files: stateFiles(cfg), Chain: chainWatermarks(cfg)}
+		if db != nil {
+			src.DB, src.Dump, src.Redis = db.DB(), backup.PGDump(*pgDump, cfg.Database.PostgresURL), db.RedisClient()
+			defer db.Stop()
+		}
+		manifest, err := backup.Create(ctx, requireDir(*dir), src)
+		if err != nil {
+			log.Fatalf("Backup failed: %v. %s is incomplete; delete it.", err, *dir)
+		}
+		log.Printf("Wrote the bundle to %s.", *dir)
+		printManifest(manifest)
+	case "verify":
+		verify := flag.NewFlagSet("verify", flag.ExitOnError)
+		dir := verify.String("dir", "", "Bundle directory")
+		verify.Parse(args)
+		manifest, err := backup.Verify(requireDir(*dir))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("The bundle in %s is complete.", *dir)
+		printManifest(manifest)
+	case "restore":
+		restore := flag.NewFlagSet("restore", flag.ExitOnError)
+		dir := restore.String("dir", "", "Bundle directory")
+		yes := restore.Bool("yes", false, "Replace the current state; without it the bundle is only checked")
+		allowConfigChange := restore.Bool("allow-config-change", false, "Restore a bundle taken with a different config")
+		restore.Parse(args)
+		cfg, fingerprint := loadConfig(*configPath)
+		manifest, err := backup.Verify(requireDir(*dir))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if manifest.ConfigFingerprint != fingerprint {
+			log.Printf("Warning: the bundle was taken with a different config (fingerprint %.12s, now %.12s).", manifest.ConfigFingerprint, fingerprint)
+		}
+		printManifest(manifest)
+		if !*yes {
+			log.Printf("Nothing was changed. Stop the game server and run again with -yes to restore.")
+			return
+		}
+		to := backup.Targets{ConfigFingerprint: fingerprint}
+		if db := connect(cfg); db != nil {
+			to.Restore, to.Redis = backup.PGRestore(*pgRestore, cfg.Database.PostgresURL), db.RedisClient()
+			defer db.Stop()
+		}
+		if _, err := backup.Restore(ctx, *dir, to, backup.RestoreOptions{AllowConfigChange: *allowConfigChange}); err != nil {
+			if errors.Is(err, backup.ErrConfigChanged) {
+				log.Fatalf("%v. Check the config, then run again with -allow-config-change.", err)
+			}
+			log.Fatalf("Restore failed: %v", err)
+		}
+		log.Printf("Restored the bundle from %s.", *dir)
+		if manifest.Chain != nil {
+			log.Printf("Chain events after checkpoint %d (epoch %d) are not in the bundle. See the README for reconciling them.", manifest.Chain.Checkpoint, manifest.Chain.Epoch)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func requireDir(dir string) string {
+	if dir == "" {
+		log.Fatal("-dir is required")
+	}
+	return dir
+}
+
+func loadConfig(path string) (*configs.Config, string) {
+	cfg, err := configs.LoadConfig(path)
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", path, err)
+	}
+	fingerprint, err := backup.Fingerprint(cfg)
+	if err != nil {
+		log.Fatalf("Failed to fingerprint the config: %v", err)
+	}
+	return cfg, fingerprint
+}
+
+// connect opens the database and Redis. It returns nil unless both are
+// configured, as the game server then uses neither.
+func connect(cfg *configs.Config) *game.DBCacheLayer {
+	if cfg.Database.PostgresURL == "" || (cfg.Redis.Address == "" && len(cfg.Redis.Addresses) == 0) {
+		return nil
+	}
+	redisCfg := game.RedisConfig{
+		Mode:             cfg.Redis.Mode,
+		Addr:             cfg.Redis.Address,
+		Addrs:            cfg.Redis.Addresses,
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
+	}
+	db, err := game.NewDBCacheLayerFromURL(cfg.Database.PostgresURL, redisCfg)
+	if err == nil {
+		err = db.Start()
+	}
+	if err != nil {
+		log.Fatalf("Failed to connect to the database and Redis: %v", err)
+	}
+	return db
+}
+
+// stateFiles are the files the game server keeps state in. Static game data,
+// such as recipes and maps, belongs in version control and is left out, as
+// are key files.
+func stateFiles(cfg *configs.Config) []string {
+	var files []string
+	for _, path := range []string{
+		cfg.Outbox.Path,
+		cfg.Balance.File,
+		cfg.Balance.HistoryFile,
+		cfg.Treasury.LedgerFile,
+		cfg.LiveOps.StateFile,
+		cfg.Mail.StateFile,
+		cfg.Arena.StateFile,
+		cfg.Shop.StateFile,
+		cfg.BattlePass.StateFile,
+		cfg.Trade.StateFile,
+		cfg.Airdrops.StateFile,
+		cfg.Gift.StateFile,
+		cfg.Features.OverridesFile,
+		cfg.APITokens.File,
+		cfg.Audit.Path,
+		cfg.Admin.AuditLogPath,
+	} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// chainWatermarks reads the chain's current epoch and checkpoint.
+func chainWatermarks(cfg *configs.Config) func(ctx context.Context) (backup.Watermarks, error) {
+	return func(ctx context.Context) (backup.Watermarks, error) {
+		tracker := sui.NewEpochTracker(sui.NewSuiClientWithFallbacks(cfg.Sui.RPCURL, cfg.Sui.FallbackRPCURLs), events.NewBus(1), 0)
+		if err := tracker.Poll(ctx); err != nil {
+			log.Printf("Warning: could not read the chain's checkpoint: %v. The bundle will not record it.", err)
+			return backup.Watermarks{}, err
+		}
+		epoch, _ := tracker.Current()
+		return backup.Watermarks{Epoch: epoch.Number, Checkpoint: epoch.Checkpoint}, nil
+	}
+}
+
+func printManifest(m *backup.Manifest) {
+	fmt.Printf("Taken:    %s (format %d, config %.12s)\n", m.CreatedAt.Format("2006-01-02 15:04:05 MST"), m.Version, m.ConfigFingerprint)
+	if m.Chain != nil {
+		fmt.Printf("Chain:    epoch %d, checkpoint %d\n", m.Chain.Epoch, m.Chain.Checkpoint)
+	}
+	if m.Postgres != nil {
+		fmt.Printf("Postgres: %s, %d bytes, WAL %s\n", m.Postgres.File, m.Postgres.Size, m.Postgres.WALPosition)
+	}
+	if m.Redis != nil {
+		fmt.Printf("Redis:    %s, %d keys\n", m.Redis.File, m.Redis.Keys)
+	}
+	for _, f := range m.Files {
+		fmt.Printf("File:     %s -> %s, %d bytes\n", f.File, f.Path, f.Size)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/airdrop"
+	"github.com/phuhao00/suigserver/server/internal/apitoken"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/chathistory"
+	"github.com/phuhao00/suigserver/server/internal/deeplink"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/gamedata"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/hotzone"
+	"github.com/phuhao00/suigserver/server/internal/liveops"
+	"github.com/phuhao00/suigserver/server/internal/metrichistory"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/privacy"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/resume"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+	"github.com/phuhao00/suigserver/server/internal/webhooks"
+	"github.com/phuhao00/suigserver/server/internal/worlds"
+)
+
+// adminServices are the services behind the admin endpoints, and the /debug/
+// endpoints. Optional ones are nil when off, and their endpoints are not served.
+type adminServices struct {
+	AuditLog     *audit.Log
+	DBCacheLayer *game.DBCacheLayer
+	Balance      *balance.Service
+	GameData     *gamedata.Watcher
+	Worlds       *worlds.Directory
+	ActorSystem  *actor.ActorSystem
+	ChatHistory  *chathistory.Service
+	AccountLinks *accountlink.Service
+	Trade        *trade.Service
+	Gift         *gift.Service
+	Airdrops     *airdrop.Service
+	Webhooks     *webhooks.Service
+	Quarantine   *quarantine.Service
+	Chaos        *chaos.Service
+	Features     *features.Registry
+	LiveOps      *liveops.Service
+	Metrics      *metrichistory.Recorder
+	Treasury     *treasury.Ledger
+	Resume       *resume.Service
+	DeepLinks    *deeplink.Service
+	HotZones     *hotzone.Monitor
+	Plugins      *plugins.Manager
+	Sui          *sui.SuiClient
+	Debug        http.Handler // The /debug/ endpoints
+}
+
+// registerAdminHandlers adds the admin endpoints of s, and its debug endpoints
+// behind the same check, when an admin token or API tokens are configured.
+// Otherwise neither is served. Admin commands are recorded in s.AuditLog. The
+// returned function closes the privacy audit log.
+func registerAdminHandlers(mux *http.ServeMux, cfg *configs.Config, apiTokens *apitoken.Registry, s adminServices) (closeAdmin func()) {
+	adminToken := ""
+	if cfg.Admin.TokenEnvVar != "" {
+		adminToken = os.Getenv(cfg.Admin.TokenEnvVar)
+	}
+	if adminToken == "" && apiTokens == nil {
+		utils.LogInfo("No admin token configured. Admin and debug endpoints are disabled.")
+		return func() {}
+	}
+	if adminToken == "" {
+		// Only API tokens reach the admin endpoints. The handlers check the
+		// admin token, so the gateway hands them a random one no client knows.
+		var err error
+		if adminToken, err = apitoken.RandomSecret(); err != nil {
+			utils.LogFatalf("Failed to generate the internal admin token: %v", err)
+		}
+	}
+	privacyLog, err := privacy.OpenFileAuditLog(cfg.Admin.AuditLogPath)
+	if err != nil {
+		utils.LogFatalf("Failed to open admin audit log: %v", err)
+	}
+	privacyService := newPrivacyService(privacyLog, s)
+	// Every admin endpoint lives under /admin/, so one middleware sees every command.
+	adminMux := http.NewServeMux()
+	privacyService.RegisterHandlers(adminMux, adminToken)
+	s.Balance.RegisterHandlers(adminMux, adminToken)
+	s.GameData.RegisterHandlers(adminMux, adminToken)
+	s.Worlds.RegisterHandlers(adminMux, s.ActorSystem.Root, adminToken)
+	if s.Webhooks != nil {
+		s.Webhooks.RegisterHandlers(adminMux, adminToken)
+	}
+	s.Quarantine.RegisterHandlers(adminMux, adminToken)
+	if s.Chaos != nil {
+		s.Chaos.RegisterHandlers(adminMux, adminToken)
+	}
+	s.Features.RegisterHandlers(adminMux, adminToken)
+	s.LiveOps.RegisterHandlers(adminMux, adminToken)
+	if s.Metrics != nil {
+		s.Metrics.RegisterHandlers(adminMux, adminToken)
+	}
+	s.Treasury.RegisterHandlers(adminMux, adminToken)
+	if s.Airdrops != nil {
+		s.Airdrops.RegisterHandlers(adminMux, adminToken)
+	}
+	if s.Resume != nil {
+		s.Resume.RegisterHandlers(adminMux, adminToken)
+	}
+	if s.DeepLinks != nil {
+		s.DeepLinks.RegisterHandlers(adminMux, adminToken, cfg.DeepLinks.BaseURL)
+	}
+	if s.HotZones != nil {
+		s.HotZones.RegisterHandlers(adminMux, adminToken)
+	}
+	s.Plugins.RegisterHandlers(adminMux, adminToken)
+	newTransferService(cfg, s.DBCacheLayer, s.AccountLinks, s.Trade, s.Gift, s.Worlds, s.ActorSystem.Root, s.Sui).RegisterHandlers(adminMux, adminToken)
+	if s.AuditLog != nil {
+		s.AuditLog.RegisterHandlers(adminMux, adminToken)
+	}
+	var admin http.Handler = adminMux
+	if apiTokens != nil {
+		admin = apitoken.Gateway{Tokens: apiTokens, Scope: adminScope, Anonymous: true, AdminToken: adminToken}.Wrap(admin)
+	}
+	mux.Handle("/admin/", s.AuditLog.AdminMiddleware(admin))
+	mux.Handle("/debug/", guardDebug(s.Debug, adminToken, apiTokens))
+	utils.LogInfof("Admin endpoints enabled. Audit log: %s", cfg.Admin.AuditLogPath)
+	return func() { privacyLog.Close() }
+}
+
+// guardDebug returns the debug endpoints behind the admin check: they report
+// flagged players, signer addresses and internal state. Like an admin
+// endpoint, a request needs the admin token and an X-Admin-User header, or an
+// API token with the admin:ops scope.
+func guardDebug(debug http.Handler, adminToken string, apiTokens *apitoken.Registry) http.Handler {
+	var guarded http.Handler = adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		debug.ServeHTTP(w, r)
+	})
+	if apiTokens != nil {
+		guarded = apitoken.Gateway{Tokens: apiTokens, Scope: adminScope, Anonymous: true, AdminToken: adminToken}.Wrap(guarded)
+	}
+	return guarded
+}
+
+// newPrivacyService sets up player data export and deletion over the stores
+// of s. Open trades and gifts block a deletion.
+func newPrivacyService(privacyLog privacy.AuditLog, s adminServices) *privacy.Service {
+	privacyService := privacy.NewService(privacyLog)
+	if s.DBCacheLayer != nil {
+		privacyService.AddSource(game.PlayerDataPrivacySource{DB: s.DBCacheLayer})
+	} else {
+		utils.LogWarn("No DB cache layer. Privacy export and deletion will not cover player data.")
+	}
+	if s.ChatHistory != nil {
+		privacyService.AddSource(chathistory.PrivacySource{Store: s.ChatHistory.Store()})
+	}
+	privacyService.AddSource(accountlink.PrivacySource{Store: s.AccountLinks.Store()})
+	if s.Trade != nil {
+		privacyService.AddGuard(s.Trade)
+	}
+	if s.Gift != nil {
+		privacyService.AddGuard(s.Gift)
+	}
+	return privacyService
+}
+
+// adminScope is the API token scope an admin or debug endpoint needs.
+func adminScope(r *http.Request) string {
+	for _, prefix := range []string{"/admin/privacy/", "/admin/transfer/", "/admin/players/", "/admin/audit"} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return apitoken.ScopeAdminPlayers
+		}
+	}
+	for _, prefix := range []string{"/admin/balance", "/admin/treasury", "/admin/airdrops"} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return apitoken.ScopeAdminEconomy
+		}
+	}
+	return apitoken.ScopeAdminOps
+}
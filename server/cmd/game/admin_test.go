@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/apitoken"
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+)
+
+func TestDebugEndpointsNeedAdminCredentials(t *testing.T) {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/anticheat", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"flagged": ["p1"]}`))
+	})
+	get := func(mux *http.ServeMux, token, operator string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/anticheat", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if operator != "" {
+			req.Header.Set("X-Admin-User", operator)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Without an admin token or API tokens, debug endpoints are not served.
+	off := http.NewServeMux()
+	registerAdminHandlers(off, &configs.Config{}, nil, adminServices{Debug: debug})
+	if code := get(off, "", ""); code != http.StatusNotFound {
+		t.Fatalf("debug endpoints with admin off: status %d", code)
+	}
+
+	store := apitoken.FileStore{Path: filepath.Join(t.TempDir(), "tokens.json")}
+	_, reader, err := store.Create("launcher", []string{apitoken.ScopeReadStatus}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ops, err := store.Create("dashboard", []string{apitoken.ScopeAdminOps}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := apitoken.NewRegistry(store, ratelimit.PerMinute(60, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/debug/", guardDebug(debug, "secret", tokens))
+	for _, tc := range []struct {
+		name, token, operator string
+		want                  int
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized},
+		{"anonymous operator", "", "ops", http.StatusUnauthorized},
+		{"wrong admin token", "guess", "ops", http.StatusUnauthorized},
+		{"admin token without operator", "secret", "", http.StatusBadRequest},
+		{"admin token", "secret", "ops", http.StatusOK},
+		{"read token", reader, "", http.StatusForbidden},
+		{"admin:ops token", ops, "", http.StatusOK},
+	} {
+		if code := get(mux, tc.token, tc.operator); code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, code, tc.want)
+		}
+	}
+}
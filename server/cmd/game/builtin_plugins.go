@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/idempotency"
+	"github.com/phuhao00/suigserver/server/internal/keys"
+	"github.com/phuhao00/suigserver/server/internal/leader"
+	"github.com/phuhao00/suigserver/server/internal/orderbook"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// builtins holds the subsystems that the server sets up as built-in plugins,
+// and what they need beyond plugins.Host. A subsystem is nil when it is off,
+// including when its plugin is listed in plugins.disabled.
+type builtins struct {
+	// Set by main before the plugins are initialized
+	keys         *keys.Manager
+	db           *game.DBCacheLayer
+	accountLinks *accountlink.Service
+	reservations *reservation.Registry
+	auditLog     *audit.Log
+	balance      *balance.Service
+	wallets      stats.Wallets
+	treasury     *treasury.Ledger
+
+	// Set by the plugins' Init
+	elector       *leader.Elector
+	playerArchive *game.PlayerArchive
+	parental      *parental.Service
+	idempotency   *idempotency.Cache
+	trades        *trade.Service
+	orderBook     *orderbook.Service
+}
+
+// plugins returns the built-in plugins. Leader election comes before the
+// workers it elects, and parental controls before the services they limit.
+func (b *builtins) plugins() []plugins.Plugin {
+	return []plugins.Plugin{
+		{Name: "leader", Init: b.initLeader, Start: b.startLeader, Stop: func() { b.elector.Stop() }},
+		{Name: "playerArchive", After: []string{"leader"}, Init: b.initPlayerArchive, Stop: b.stopPlayerArchive},
+		{Name: "parental", Init: b.initParental},
+		{Name: "idempotency", Init: b.initIdempotency},
+		{Name: "trade", After: []string{"parental"}, Init: b.initTrade, Start: b.startTrade, Stop: b.stopTrade},
+		{Name: "orderBook", After: []string{"parental"}, Init: b.initOrderBook, Start: b.startOrderBook, Stop: b.stopOrderBook},
+	}
+}
+
+// initLeader campaigns for the singleton workers' roles. Singleton workers
+// run only on the instance leading their role; without the plugin, every
+// instance runs every worker.
+func (b *builtins) initLeader(h *plugins.Host) error {
+	b.elector = newLeaderElector(h.Config, b.db)
+	h.Jobs.UseLeader(workerRole(h.Config, b.elector, "outbox").IsLeader)
+	if b.elector != nil {
+		b.elector.SetHealthMonitor(h.Health)
+		h.HandleDebug("", jsonHandler(b.elector.Status))
+	}
+	return nil
+}
+
+func (b *builtins) startLeader() error {
+	if b.elector != nil {
+		b.elector.Start()
+	}
+	return nil
+}
+
+// initPlayerArchive moves inactive players' records out of the hot table and
+// the cache until they return.
+func (b *builtins) initPlayerArchive(h *plugins.Host) error {
+	b.playerArchive = newPlayerArchive(h.Config, b.db, workerRole(h.Config, b.elector, "playerArchive").IsLeader)
+	if b.playerArchive == nil {
+		return nil
+	}
+	events.On(h.Events, events.TopicPlayerLogin, "player-archive", func(login events.PlayerLogin) {
+		if err := b.playerArchive.RecordLogin(context.Background(), login.PlayerID, time.Now()); err != nil {
+			utils.LogErrorf("Failed to record the login of %s: %v", login.PlayerID, err)
+		}
+	})
+	h.HandleDebug("", jsonHandler(b.playerArchive.Stats))
+	return nil
+}
+
+func (b *builtins) stopPlayerArchive() {
+	if b.playerArchive != nil {
+		b.playerArchive.Stop()
+	}
+}
+
+// initParental opens the accounts' parental controls, managed through the
+// admin API.
+func (b *builtins) initParental(h *plugins.Host) error {
+	b.parental = newParentalControls(h.Config)
+	if b.parental != nil {
+		h.HandleAdminRoutes(b.parental.RegisterHandlers)
+	}
+	return nil
+}
+
+// initIdempotency keeps the results of commands sent with idempotency keys,
+// so a client retrying one gets the first result rather than a second run.
+func (b *builtins) initIdempotency(h *plugins.Host) error {
+	b.idempotency = idempotency.NewCache(idempotency.Options{
+		TTL:        time.Duration(h.Config.Idempotency.TTLSeconds) * time.Second,
+		PendingTTL: time.Duration(h.Config.Idempotency.PendingSeconds) * time.Second,
+		MaxKeys:    h.Config.Idempotency.MaxKeysPerPlayer,
+	})
+	return nil
+}
+
+// initTrade sets up player trades. They reserve the items they use, check
+// that the parties own them, pay fees to the treasury and are limited by
+// parental controls.
+func (b *builtins) initTrade(h *plugins.Host) error {
+	cfg := h.Config
+	b.trades = newTradeService(cfg, h.Sui, h.Jobs, b.keys, b.accountLinks)
+	if b.trades == nil {
+		return nil
+	}
+	b.trades.UseReservations(b.reservations)
+	b.trades.UseAuditLog(b.auditLog)
+	b.trades.UseEvents(h.Events)
+	b.trades.UseOwnershipCheck(func(ctx context.Context, owner string, itemIDs []string) error {
+		claims := make([]sui.ObjectClaim, len(itemIDs))
+		for i, id := range itemIDs {
+			claims[i] = sui.ObjectClaim{ObjectID: id, Owner: owner, Type: cfg.Trade.ItemType, Role: "item"}
+		}
+		return h.Sui.VerifyObjects(ctx, claims...)
+	})
+	b.trades.UseFees(tradeFeeSchedule(cfg, b.balance), b.wallets, b.treasury)
+	b.trades.SetEscrowPaused(!h.Features.Enabled(features.EscrowTrades))
+	h.Features.OnChange(features.EscrowTrades, func(enabled bool) { b.trades.SetEscrowPaused(!enabled) })
+	if b.parental != nil {
+		b.trades.UseSpendLimit(b.parental)
+	}
+	return nil
+}
+
+func (b *builtins) startTrade() error {
+	if b.trades != nil {
+		b.trades.Start()
+	}
+	return nil
+}
+
+func (b *builtins) stopTrade() {
+	if b.trades != nil {
+		b.trades.Stop()
+	}
+}
+
+// initOrderBook sets up the order book for the game token, limited by
+// parental controls.
+func (b *builtins) initOrderBook(h *plugins.Host) error {
+	b.orderBook = newOrderBook(h.Config, h.Sui, h.Jobs, b.keys, b.accountLinks)
+	if b.orderBook != nil && b.parental != nil {
+		b.orderBook.UseSpendLimit(b.parental)
+	}
+	return nil
+}
+
+func (b *builtins) startOrderBook() error {
+	if b.orderBook != nil {
+		b.orderBook.Start()
+	}
+	return nil
+}
+
+func (b *builtins) stopOrderBook() {
+	if b.orderBook != nil {
+		b.orderBook.Stop()
+	}
+}
+
+// jsonHandler serves what stats returns as JSON.
+func jsonHandler[T any](stats func() T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats())
+	}
+}
+
+// newPlayerArchive starts archiving inactive players, or returns nil when
+// archiving is off or there is no database. Only the leader of the
+// playerArchive role archives; every instance restores.
+func newPlayerArchive(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer, leader func() bool) *game.PlayerArchive {
+	if cfg.Database.ArchiveInactiveDays <= 0 || dbCacheLayer == nil {
+		return nil
+	}
+	archive := &game.PlayerArchive{
+		DB:          dbCacheLayer,
+		InactiveFor: time.Duration(cfg.Database.ArchiveInactiveDays) * 24 * time.Hour,
+		BatchSize:   cfg.Database.ArchiveBatchSize,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := archive.EnsureSchema(ctx); err != nil {
+		utils.LogErrorf("Player archive table unavailable: %v. Inactive players are not archived.", err)
+		return nil
+	}
+	archive.Start(time.Duration(cfg.Database.ArchiveIntervalMinutes)*time.Minute, leader)
+	utils.LogInfof("Archiving players inactive for %d days.", cfg.Database.ArchiveInactiveDays)
+	return archive
+}
+
+// newLeaderElector campaigns for the singleton workers' roles in the
+// configured lease store, or returns nil when every instance runs every worker.
+func newLeaderElector(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer) *leader.Elector {
+	var lease leader.Lease
+	switch cfg.LeaderElection.Backend {
+	case "":
+		return nil
+	case "memory":
+		lease = leader.NewMemoryLease()
+	case "redis", "postgres":
+		if dbCacheLayer == nil {
+			utils.LogFatalf("Leader election backend %q needs the database and Redis to be configured.", cfg.LeaderElection.Backend)
+		}
+		if cfg.LeaderElection.Backend == "redis" {
+			lease = leader.RedisLease{Client: dbCacheLayer.RedisClient()}
+		} else {
+			lease = leader.NewPostgresLease(dbCacheLayer.DB())
+		}
+	default:
+		utils.LogFatalf("Unknown leader election backend %q.", cfg.LeaderElection.Backend)
+	}
+	elector := leader.New(lease, leader.Options{LeaseTTL: time.Duration(cfg.LeaderElection.LeaseSeconds) * time.Second})
+	for _, worker := range cfg.LeaderElection.Workers {
+		elector.Role(worker)
+	}
+	utils.LogInfof("Leader election on %s as %s for %v.", cfg.LeaderElection.Backend, elector.Holder(), cfg.LeaderElection.Workers)
+	return elector
+}
+
+// workerRole returns the role of a singleton worker, or nil, which always
+// leads, if the worker is not elected.
+func workerRole(cfg *configs.Config, elector *leader.Elector, worker string) *leader.Role {
+	for _, name := range cfg.LeaderElection.Workers {
+		if name == worker {
+			return elector.Role(worker)
+		}
+	}
+	return nil
+}
+
+// newParentalControls opens the accounts' parental controls. Nobody is
+// restricted (nil) if they are disabled or cannot be loaded.
+func newParentalControls(cfg *configs.Config) *parental.Service {
+	if !cfg.Parental.Enabled {
+		return nil
+	}
+	var store parental.Store = &parental.MemoryStore{}
+	if cfg.Parental.StateFile != "" {
+		store = parental.FileStore{Path: cfg.Parental.StateFile}
+	}
+	warnBefore := make([]time.Duration, 0, len(cfg.Parental.WarnMinutes))
+	for _, minutes := range cfg.Parental.WarnMinutes {
+		warnBefore = append(warnBefore, time.Duration(minutes)*time.Minute)
+	}
+	service, err := parental.NewService(store, parental.Options{
+		CheckInterval: time.Duration(cfg.Parental.CheckIntervalSeconds) * time.Second,
+		WarnBefore:    warnBefore,
+	})
+	if err != nil {
+		utils.LogErrorf("Failed to load parental controls: %v. Parental controls are disabled.", err)
+		return nil
+	}
+	return service
+}
+
+// newTradeService sets up player trades; the trade plugin starts them. Trades
+// above the escrow threshold need the escrow package and an arbiter address;
+// without them only smaller trades are possible.
+func newTradeService(cfg *configs.Config, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, accountLinks *accountlink.Service) *trade.Service {
+	if !cfg.Trade.Enabled {
+		return nil
+	}
+	tradeService, err := trade.NewServiceFromConfig(cfg.Trade, accountLinks)
+	if err != nil {
+		utils.LogErrorf("Failed to set up trades: %v. Trading is disabled.", err)
+		return nil
+	}
+	if cfg.Trade.EscrowPackageID != "" && cfg.Trade.ItemType != "" && cfg.Trade.ArbiterAddress != "" && cfg.Trade.ArbiterGasObjectID != "" {
+		escrow := sui.NewEscrowSuiService(suiClient, cfg.Trade.EscrowPackageID, cfg.Trade.EscrowModule, cfg.Trade.ItemType,
+			cfg.Trade.ArbiterAddress, cfg.Trade.ArbiterGasObjectID, cfg.Sui.GasBudget)
+		tradeService.EnableEscrow(suiEscrow{escrow: escrow, keys: keyManager, treasury: cfg.Treasury.Address}, box)
+		utils.LogInfof("Trades enabled. Trades worth more than %d MIST go through escrow.", cfg.Trade.EscrowThresholdMist)
+	} else {
+		utils.LogWarnf("trade.escrowPackageId, itemType, arbiterAddress or arbiterGasObjectId is not set. Trades worth more than %d MIST are refused.", cfg.Trade.EscrowThresholdMist)
+	}
+	return tradeService
+}
+
+// suiEscrow adapts sui.EscrowSuiService to trade.Escrow, signing with the server key.
+type suiEscrow struct {
+	escrow   *sui.EscrowSuiService
+	keys     *keys.Manager
+	treasury string // Receives the trade fees
+}
+
+func (e suiEscrow) Create(ctx context.Context, t trade.Trade, expiresAt time.Time) (string, error) {
+	privateKey, err := e.keys.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return e.escrow.CreateEscrow(sui.EscrowTerms{
+		TradeID:   t.ID,
+		PartyA:    t.ProposerAddress,
+		PartyB:    t.CounterpartyAddress,
+		ItemsA:    t.Give.ItemIDs,
+		ItemsB:    t.Want.ItemIDs,
+		CoinsA:    t.Give.Coins,
+		CoinsB:    t.Want.Coins,
+		FeeA:      t.ProposerFee,
+		FeeB:      t.CounterpartyFee,
+		Treasury:  e.treasury,
+		ExpiresAt: expiresAt,
+	}, privateKey)
+}
+
+func (e suiEscrow) Complete(ctx context.Context, escrowID string) (bool, error) {
+	info, err := e.escrow.GetEscrow(escrowID)
+	return info.Complete(), err
+}
+
+func (e suiEscrow) Release(ctx context.Context, escrowID string) (string, error) {
+	privateKey, err := e.keys.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return e.escrow.ReleaseEscrow(escrowID, privateKey)
+}
+
+func (e suiEscrow) Refund(ctx context.Context, escrowID string) (string, error) {
+	privateKey, err := e.keys.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return e.escrow.RefundEscrow(escrowID, privateKey)
+}
+
+// newOrderBook sets up the order book for the game token, whose deposits,
+// settlements and refunds go through the custody address; the orderBook
+// plugin starts it. It returns nil if orderBook.tokenType or
+// orderBook.custodyAddress is not set.
+func newOrderBook(cfg *configs.Config, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, accountLinks *accountlink.Service) *orderbook.Service {
+	if cfg.OrderBook.TokenType == "" || cfg.OrderBook.CustodyAddress == "" {
+		utils.LogInfo("orderBook.tokenType or orderBook.custodyAddress is not set. The order book is off.")
+		return nil
+	}
+	chain := suiOrderBook{client: suiClient, keys: keyManager, custody: cfg.OrderBook.CustodyAddress, gasObjectID: cfg.OrderBook.CustodyGasObjectID, gasBudget: cfg.Sui.GasBudget}
+	book, err := orderbook.NewServiceFromConfig(cfg.OrderBook, chain, accountLinks, box)
+	if err != nil {
+		utils.LogErrorf("Failed to set up the order book: %v. The order book is off.", err)
+		return nil
+	}
+	utils.LogInfof("Order book enabled for %s. Deposits are held by %s.", cfg.OrderBook.TokenType, cfg.OrderBook.CustodyAddress)
+	return book
+}
+
+// suiOrderBook adapts sui.SuiClient to orderbook.Chain, signing custody payouts with the server key.
+type suiOrderBook struct {
+	client      *sui.SuiClient
+	keys        *keys.Manager
+	custody     string // Address of the server key holding deposits
+	gasObjectID string // Custody's gas coin
+	gasBudget   uint64
+}
+
+func (b suiOrderBook) BuildDeposit(ctx context.Context, sender, coinType string, amount uint64) (string, error) {
+	tx, err := b.client.BuildCoinPayment(sender, coinType, amount, b.custody, "", b.gasBudget)
+	return tx.TxBytes, err
+}
+
+func (b suiOrderBook) VerifyDeposit(ctx context.Context, txDigest, sender, coinType string, amount uint64, since time.Time) error {
+	return b.client.VerifyPaymentSince(txDigest, sender, b.custody, coinType, amount, since)
+}
+
+func (b suiOrderBook) SignPayout(ctx context.Context, payouts []orderbook.Payout) (orderbook.SignedPayout, error) {
+	privateKey, err := b.keys.PrivateKey()
+	if err != nil {
+		return orderbook.SignedPayout{}, err
+	}
+	payments := make([]sui.CoinPayment, len(payouts))
+	for i, p := range payouts {
+		payments[i] = sui.CoinPayment{Recipient: p.Recipient, CoinType: p.CoinType, Amount: p.Amount}
+	}
+	tx, err := b.client.SignCoinPayments(b.custody, payments, b.gasObjectID, b.gasBudget, privateKey)
+	return orderbook.SignedPayout(tx), err
+}
+
+func (b suiOrderBook) SubmitPayout(ctx context.Context, payout orderbook.SignedPayout) error {
+	_, err := b.client.SubmitSigned(sui.SignedTransaction(payout))
+	if errors.Is(err, sui.ErrTransactionDropped) {
+		return fmt.Errorf("%w: %v", orderbook.ErrPayoutDropped, err)
+	}
+	return err
+}
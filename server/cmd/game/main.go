@@ -2,27 +2,102 @@ package main
 
 import (
 	// For SUI client health check
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
 	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
 	internalActor "github.com/phuhao00/suigserver/server/internal/actor" // Renamed to avoid conflict with protoactor's actor package
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/afk"
+	"github.com/phuhao00/suigserver/server/internal/airdrop"
+	"github.com/phuhao00/suigserver/server/internal/anticheat"
+	"github.com/phuhao00/suigserver/server/internal/apitoken"
+	"github.com/phuhao00/suigserver/server/internal/arena"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/autoscale"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/battlepass"
+	"github.com/phuhao00/suigserver/server/internal/calendar"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/chatchannels"
+	"github.com/phuhao00/suigserver/server/internal/chathistory"
+	"github.com/phuhao00/suigserver/server/internal/clientversion"
+	"github.com/phuhao00/suigserver/server/internal/cosmetics"
+	"github.com/phuhao00/suigserver/server/internal/crafting"
+	"github.com/phuhao00/suigserver/server/internal/deeplink"
+	"github.com/phuhao00/suigserver/server/internal/delivery"
+	"github.com/phuhao00/suigserver/server/internal/diplomacy"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/fairroll"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/gamedata"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/health"
+	"github.com/phuhao00/suigserver/server/internal/hotzone"
+	"github.com/phuhao00/suigserver/server/internal/keys"
+	"github.com/phuhao00/suigserver/server/internal/liveops"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/metrichistory"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
 	"github.com/phuhao00/suigserver/server/internal/network"
-	"github.com/phuhao00/suigserver/server/internal/sui"   // Import for SUI client
+	"github.com/phuhao00/suigserver/server/internal/onboarding"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/prefetch"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+	"github.com/phuhao00/suigserver/server/internal/receipts"
+	"github.com/phuhao00/suigserver/server/internal/recruitment"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/resume"
+	"github.com/phuhao00/suigserver/server/internal/ruleset"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/status"
+	"github.com/phuhao00/suigserver/server/internal/sui" // Import for SUI client
+	"github.com/phuhao00/suigserver/server/internal/territory"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
 	"github.com/phuhao00/suigserver/server/internal/utils" // Import for logger
+	"github.com/phuhao00/suigserver/server/internal/webhooks"
+	"github.com/phuhao00/suigserver/server/internal/worlds"
 	// Other direct service initializations if any (e.g., DB connection pools)
 )
 
+// version is reported on /status. Release builds set it with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
 func main() {
+	configPath := flag.String("config", "config.json", "Path of the configuration file")
+	validate := flag.Bool("validate", false, "Check the configuration and its dependencies, print a report and exit (status 1 if a check fails)")
+	reportFormat := flag.String("format", "text", "Report format for -validate: text or json")
+	flag.Parse()
+	if *validate {
+		os.Exit(runValidate(*configPath, *reportFormat))
+	}
+
 	// --- Configuration Loading ---
 	// Create an example config if it doesn't exist.
 	// In production, ensure 'config.json' is present and properly configured.
-	configs.CreateExampleConfigFile("config.json") // This might log using standard logger before ours is set
-	cfg, err := configs.LoadConfig("config.json")
+	configs.CreateExampleConfigFile(*configPath) // This might log using standard logger before ours is set
+	cfg, err := configs.LoadConfig(*configPath)
 	if err != nil {
 		// Use standard log here as our logger might not be initialized or config not loaded
 		log.Fatalf("Failed to load configuration: %v", err)
@@ -33,6 +108,16 @@ func main() {
 	utils.LogInfo("Starting MMO Game Server with Actor Model...")
 	utils.LogInfof("Configuration loaded. Server TCP Port: %d, Sui RPC: %s, LogLevel: %s", cfg.Server.TCPPort, cfg.Sui.RPCURL, cfg.Server.LogLevel)
 
+	// --- Self-Check ---
+	// Placeholder package IDs, missing keys and unreachable dependencies are
+	// reported before anything starts; server.strictStartup makes failures fatal.
+	runStartupChecks(cfg)
+
+	// --- Feature Flags ---
+	// Risky or environment-specific subsystems check these at startup; runtime
+	// flags can be overridden through the admin API.
+	featureFlags := newFeatureFlags(cfg)
+
 	// --- Initialize Actor System ---
 	// Note: Proto.Actor logging configuration methods may vary by version
 	// Commenting out potentially outdated logging setup
@@ -40,49 +125,320 @@ func main() {
 
 	actorSystem := actor.NewActorSystem()
 	utils.LogInfo("Actor system initialized.")
+	// Dead letters, handler panics and unhandled messages are kept for inspection and replay.
+	messageQuarantine := quarantine.New(actorSystem, quarantine.Options{
+		MaxEntries:       cfg.Quarantine.MaxEntries,
+		FailureThreshold: cfg.Quarantine.FailureThreshold,
+		FailureWindow:    time.Duration(cfg.Quarantine.FailureWindowSeconds) * time.Second,
+	})
+	// While the actorMessageAudit flag is on, every message sent between actors is
+	// checked against the message registry and its size limit.
+	messageAudit := msgaudit.New(actorSystem, internalActor.MessageRegistry(), msgaudit.Options{MaxBytes: cfg.ActorAudit.MaxMessageBytes})
+	actorSystem.Root.WithSenderMiddleware(msgaudit.Sender)
+	messageAudit.SetEnabled(featureFlags.Enabled(features.ActorMessageAudit))
+	featureFlags.OnChange(features.ActorMessageAudit, messageAudit.SetEnabled)
+	// Every actor message handler is timed, client messages by protocol type.
+	handlerMetrics := handlermetrics.New(actorSystem, handlermetrics.Options{SlowThreshold: time.Duration(cfg.HandlerMetrics.SlowThresholdMs) * time.Millisecond})
+	// Fault injection exists only in builds with the chaos tag; elsewhere this is nil.
+	chaosService := chaos.New(actorSystem)
+
+	// --- Event Bus ---
+	// Cross-module notifications (player.login, combat.finished, ...). Achievements,
+	// quests and analytics subscribe here instead of being called from gameplay code.
+	eventBus := events.NewBus(events.DefaultSubscriberBuffer)
+	eventBus.Subscribe("*", "event-log", func(e events.Event) {
+		utils.LogDebugf("Event %s: %+v", e.Topic, e.Payload)
+	})
+	// Logged errors become server.error events (at most one per 10s), e.g. for webhooks.
+	utils.SetErrorHook(events.ErrorReporter(eventBus, 10*time.Second))
 
 	// --- Spawn Top-Level Actors ---
-	// RoomManagerActor
-	roomManagerProps := internalActor.PropsForRoomManager(actorSystem)
-	roomManagerPID, err := actorSystem.Root.SpawnNamed(roomManagerProps, "room-manager")
-	if err != nil {
-		utils.LogFatalf("Failed to spawn RoomManagerActor: %v", err)
+	// TODO: Spawn other top-level actors as needed (e.g., PlayerDataManagerActor, GameEventManagerActor)
+	utils.LogInfo("Placeholder: Additional top-level actors (PlayerDataManager, GameEventManager) would be spawned here if defined.")
+
+	// --- Audit Log ---
+	var auditLog *audit.Log
+	if cfg.Audit.Path != "" {
+		auditLog, err = audit.Open(cfg.Audit.Path)
+		if err != nil {
+			utils.LogFatalf("Failed to open audit log: %v", err)
+		}
+		seq, hash := auditLog.Head()
+		utils.LogInfof("Audit log %s opened at entry %d (head %.16s).", cfg.Audit.Path, seq, hash)
+	}
+
+	// --- Initialize SUI Client ---
+	suiClient := sui.NewSuiClientWithFallbacks(cfg.Sui.RPCURL, cfg.Sui.FallbackRPCURLs) // Using the modern SuiClient
+	suiClient.SetAuditLog(auditLog)
+	if chaosService != nil {
+		suiClient.SetFaultInjector(chaosService)
 	}
-	utils.LogInfof("RoomManagerActor spawned with PID: %s", roomManagerPID.String())
+	utils.LogInfof("SUI client initialized for RPC URL: %s (fallbacks: %v)", cfg.Sui.RPCURL, cfg.Sui.FallbackRPCURLs)
 
-	// Spawn WorldManagerActor
-	worldManagerProps := internalActor.PropsForWorldManager(actorSystem)
-	worldManagerPID, err := actorSystem.Root.SpawnNamed(worldManagerProps, "world-manager")
+	// --- Game Balance ---
+	// Tunables for the combat engine (CombatEngine.UseBalance) and loot, reloaded from the balance file while running.
+	balanceService, err := balance.Open(cfg.Balance.File, balance.FileHistory{Path: cfg.Balance.HistoryFile}, game.ValidateBalance)
 	if err != nil {
-		utils.LogFatalf("Failed to spawn WorldManagerActor: %v", err)
+		utils.LogFatalf("Failed to load balance values: %v", err)
 	}
-	utils.LogInfof("WorldManagerActor spawned with PID: %s", worldManagerPID.String())
+	if cfg.Balance.ReloadIntervalSeconds > 0 {
+		balanceService.StartWatching(time.Duration(cfg.Balance.ReloadIntervalSeconds) * time.Second)
+	}
+	utils.LogInfof("Balance values at version %d.", balanceService.Current().Version)
 
-	// TODO: Spawn other top-level actors as needed (e.g., PlayerDataManagerActor, GameEventManagerActor)
-	utils.LogInfo("Placeholder: Additional top-level actors (PlayerDataManager, GameEventManager) would be spawned here if defined.")
+	// --- Game Data ---
+	// Maps, abilities, NPCs and projectiles, reloaded while running; rooms keep the version they were created with.
+	gameData := newGameData(cfg, balanceService)
 
-	// --- Initialize SUI Client ---
-	suiClient := sui.NewSuiClient(cfg.Sui.RPCURL) // Using the modern SuiClient
-	utils.LogInfof("SUI client initialized for RPC URL: %s", cfg.Sui.RPCURL)
-	if cfg.Sui.PrivateKey != "" && cfg.Sui.PrivateKey != "YOUR_SUI_PRIVATE_KEY_HEX_HERE" {
+	// Spawn a RoomManagerActor and WorldManagerActor per world (after the SUI
+	// client, which verifies territory claims). Sessions start on the default world.
+	// Health, level, XP and coin changes, pushed to sessions in batches.
+	statsService := stats.NewService(stats.Options{TickInterval: time.Duration(cfg.Stats.TickIntervalMs) * time.Millisecond})
+	statsService.Start()
+	// High-stakes loot rolled from committed seeds that players can check.
+	fairRolls := newFairRolls(cfg)
+	// Load signals for autoscaling, from the players online, the CPU they use and
+	// how much of their tick budget the rooms take; reported on /healthz and /readyz.
+	var worldDirectory *worlds.Directory
+	loadTracker := autoscale.NewTracker(autoscale.Options{
+		PlayersPerCore:     cfg.Autoscaling.PlayersPerCore,
+		TargetCPU:          cfg.Autoscaling.TargetCPU,
+		MaxTickUtilization: cfg.Autoscaling.MaxTickUtilization,
+		SampleInterval:     time.Duration(cfg.Autoscaling.SampleIntervalSeconds) * time.Second,
+		Players:            func() int { return worldDirectory.Sessions() },
+	})
+	roomServices := newRoomServices(cfg, eventBus, balanceService, gameData, statsService, fairRolls)
+	roomServices.Load = loadTracker
+	// Crowded maps get more NPCs and overflow instances, as balance versions operators can roll back.
+	hotZones := newHotZones(cfg, balanceService)
+	roomServices.Density = hotZones
+	guildRoster := newGuildRoster(cfg)
+	// Alliances between guilds, behind the alliance chat channels and friendly territory.
+	guildDiplomacy := newDiplomacy(cfg, guildRoster)
+	worldDirectory = spawnWorlds(actorSystem, cfg, suiClient, eventBus, roomServices, guildRoster, guildDiplomacy)
+	if guildDiplomacy != nil {
+		guildDiplomacy.OnChange(moveAllianceChannels(actorSystem, worldDirectory))
+	}
+	loadTracker.Start()
+	if hotZones != nil {
+		hotZones.Start()
+	}
+	defaultWorld, _ := worldDirectory.Lookup("")
+	roomManagerPID, worldManagerPID := defaultWorld.RoomManagerPID, defaultWorld.WorldManagerPID
+	afkPolicy := newAFKPolicy(actorSystem, cfg, worldDirectory)
+
+	// --- Load Signing Key ---
+	keyProvider, err := keys.NewProviderFromConfig(cfg.Sui.KeySource, cfg.Sui.PrivateKey)
+	if err != nil {
+		utils.LogFatalf("Invalid SUI key source configuration: %v", err)
+	}
+	keyManager := keys.NewManager(keyProvider)
+	if err := keyManager.Reload(); err != nil {
+		utils.LogWarn("SUI private key is not available. Server-side SUI transactions requiring this key will not be possible.")
+	} else {
 		utils.LogInfo("SUI private key loaded and available for server-side transaction signing.")
+	}
+	keyManager.StartAutoReload(time.Duration(cfg.Sui.KeySource.ReloadIntervalSeconds) * time.Second)
+	// Optional pool of extra signer addresses for parallel server transactions
+	signerPool, signerKeyManagers, err := newSignerPool(cfg.Sui.SignerPool)
+	if err != nil {
+		utils.LogFatalf("Invalid SUI signer pool configuration: %v", err)
+	}
+	if signerPool == nil {
+		utils.LogInfo("No signer pool configured. Server transactions are signed by the single server key.")
 	} else {
-		utils.LogWarn("SUI private key is not configured or is using the default placeholder. Server-side SUI transactions requiring this key will not be possible.")
+		suiClient.UseSignerPool(signerPool)
 	}
-	// Perform SUI client health check
+	// SIGHUP reloads the signing key on demand, e.g. right after rotating it in the backend.
+	reloadKey := make(chan os.Signal, 1)
+	signal.Notify(reloadKey, syscall.SIGHUP)
 	go func() {
-		time.Sleep(2 * time.Second) // Brief delay to allow server to fully start before check
-		// Test SUI client connectivity by querying an object (using a simple call that should exist)
-		// Using a simple get object call with a known invalid ID to test connectivity
-		_, err := suiClient.GetObject("0x1")
-		if err != nil {
-			// Even if the object doesn't exist, if we get a response from the network, it means connectivity is working
-			// We expect this to fail with "object not found" rather than a network error
-			utils.LogInfo("SUI client health check completed. Network connectivity appears to be working.")
-		} else {
-			utils.LogInfo("SUI client health check successful. Connected to Sui network.")
+		for range reloadKey {
+			utils.LogInfo("SIGHUP received. Reloading SUI signing keys...")
+			keyManager.Reload()
+			for _, manager := range signerKeyManagers {
+				manager.Reload()
+			}
 		}
 	}()
+	// Probe the RPC nodes in the background and fail over between them as needed
+	suiClient.StartHealthMonitor(time.Duration(cfg.Sui.HealthCheckIntervalSeconds) * time.Second)
+	// Follow the Sui epoch for mechanics counted in epochs; changes go out on the event bus
+	var epochTracker *sui.EpochTracker
+	if cfg.Sui.EpochPollSeconds > 0 {
+		epochTracker = sui.NewEpochTracker(suiClient, eventBus, time.Duration(cfg.Sui.EpochPollSeconds)*time.Second)
+		epochTracker.Start()
+	}
+
+	// --- Initialize DB/Cache Layer ---
+	// Connections are lazy; reachability is reported through the readiness endpoint.
+	var dbCacheLayer *game.DBCacheLayer
+	if cfg.Database.PostgresURL != "" && redisConfigured(cfg) {
+		dbCacheLayer, err = game.NewDBCacheLayerFromURL(cfg.Database.PostgresURL, redisConfig(cfg))
+		if err != nil {
+			utils.LogWarnf("Failed to initialize DB cache layer: %v. Readiness will not include DB/Redis checks.", err)
+			dbCacheLayer = nil
+		} else {
+			configureDatabase(cfg, dbCacheLayer)
+		}
+	} else {
+		utils.LogWarn("Database or Redis address not configured. Readiness will not include DB/Redis checks.")
+	}
+
+	// --- Outbox ---
+	// On-chain side effects that must not be lost, such as season trophy mints.
+	outboxStore, err := outbox.OpenFileStore(cfg.Outbox.Path)
+	if err != nil {
+		utils.LogFatalf("Failed to open outbox %s: %v", cfg.Outbox.Path, err)
+	}
+	sideEffects := outbox.New(outboxStore, outbox.Options{})
+
+	// --- Webhooks ---
+	// Selected events posted to Discord, Slack or other endpoints, delivered through the outbox.
+	webhookService, err := webhooks.NewServiceFromConfig(cfg.Webhooks, sideEffects)
+	if err != nil {
+		utils.LogFatalf("Failed to set up webhooks: %v", err)
+	}
+	if webhookService != nil {
+		webhookService.Subscribe(eventBus)
+		utils.LogInfof("Webhooks enabled for %d endpoint(s).", len(cfg.Webhooks.Endpoints))
+	}
+
+	accountLinks := newAccountLinkService(cfg, dbCacheLayer)
+
+	// --- Arena ---
+	var arenaService *arena.Service
+	if cfg.Arena.Enabled {
+		arenaService, err = arena.NewServiceFromConfig(cfg.Arena, arenaRegions(cfg, worldDirectory), sideEffects)
+		if err != nil {
+			utils.LogFatalf("Failed to set up the arena: %v", err)
+		}
+		arenaService.Subscribe(eventBus)
+		arenaService.Start()
+		registerTrophyMinter(sideEffects, cfg, suiClient, keyManager, eventBus, accountLinks)
+		utils.LogInfof("Arena enabled. Season %d ends %s.", arenaService.Season().Number, arenaService.Season().EndsAt.Format(time.RFC3339))
+	}
+	// Shops and direct trade fees spend the same soft currency.
+	wallets := stats.Wallets{WalletStore: newWalletStore(cfg, dbCacheLayer), Stats: statsService}
+	statsService.UseProgress(newProgressStore(dbCacheLayer), func() balance.XPValues { return balanceService.Values().XP })
+	shopService := newShopService(cfg, wallets, suiClient, sideEffects, keyManager, eventBus)
+	chatHistory := newChatHistoryService(cfg, dbCacheLayer)
+	treasuryLedger := newTreasuryLedger(cfg)
+	mailService := newMailService(cfg)
+	// Receipts of the transactions the server executes for players, sent to their
+	// sessions or mailed.
+	txReceipts := receipts.NewService(mailService)
+	txReceipts.Subscribe(eventBus)
+	registerLootCommitmentLogger(sideEffects, cfg, suiClient, keyManager, fairRolls)
+	guildRanks := newGuildRanks(cfg, guildRoster, suiClient, accountLinks)
+	guildCalendar := newGuildCalendar(cfg, guildRoster)
+	guildRecruitment := newRecruitment(cfg, guildRoster)
+	if guildRecruitment != nil {
+		guildRecruitment.UseRanks(guildRanks)
+		guildRecruitment.UseMail(mailService)
+	}
+	if guildDiplomacy != nil {
+		guildDiplomacy.UseMail(mailService)
+	}
+	if guildCalendar != nil {
+		guildCalendar.UseRanks(guildRanks)
+		if guildDiplomacy != nil {
+			guildCalendar.UseAlliances(guildDiplomacy)
+		}
+		guildCalendar.UseMail(mailService)
+		guildCalendar.UseRooms(internalActor.GuildEventRooms(actorSystem, roomManagerPID))
+		guildCalendar.Start()
+	}
+	// Trades and marketplace transactions reserve the items they use, so one
+	// item cannot be traded and listed at the same time.
+	itemReservations := reservation.NewRegistry()
+
+	// --- Plugins ---
+	// Leader election, the player archive, parental controls, idempotency
+	// keys, trades and the order book are built-in plugins (builtin_plugins.go);
+	// optional ones such as analytics are compiled in through plugins.go.
+	healthMonitor := health.NewMonitor()
+	builtin := &builtins{
+		keys:         keyManager,
+		db:           dbCacheLayer,
+		accountLinks: accountLinks,
+		reservations: itemReservations,
+		auditLog:     auditLog,
+		balance:      balanceService,
+		wallets:      wallets,
+		treasury:     treasuryLedger,
+	}
+	pluginManager := newPlugins(builtin.plugins(), plugins.Host{Config: cfg, Events: eventBus, Jobs: sideEffects, Features: featureFlags, Sui: suiClient, Health: healthMonitor})
+	parentalControls := builtin.parental
+	giftService := newGiftService(cfg, suiClient, sideEffects, keyManager, accountLinks)
+	if giftService != nil {
+		giftService.UseReservations(itemReservations)
+		giftService.UseAuditLog(auditLog)
+		giftService.UseMail(mailService)
+		if parentalControls != nil {
+			giftService.UseSpendLimit(parentalControls)
+		}
+	}
+	energyService := newEnergyService(dbCacheLayer, balanceService)
+	energyService.Start()
+	craftingService := newCraftingService(cfg, dbCacheLayer, wallets, suiClient, sideEffects, keyManager, eventBus, accountLinks)
+	if craftingService != nil {
+		craftingService.UseMail(mailService)
+		craftingService.UseLeader(workerRole(cfg, builtin.elector, "crafting").IsLeader)
+		craftingService.Start()
+	}
+	battlePassService := newBattlePassService(cfg, wallets, suiClient, sideEffects, keyManager, eventBus, accountLinks)
+	if battlePassService != nil {
+		battlePassService.UseMail(mailService)
+		battlePassService.Subscribe(eventBus)
+		battlePassService.Start()
+	}
+	cosmeticsService := newCosmeticsService(cfg, dbCacheLayer, suiClient, accountLinks)
+	if cosmeticsService != nil {
+		cosmeticsService.Subscribe(eventBus)
+	}
+	loginPrefetch := newLoginPrefetch(cfg, suiClient, accountLinks, cosmeticsService)
+	if loginPrefetch != nil {
+		loginPrefetch.Subscribe(eventBus)
+	}
+	airdrops := newAirdropService(cfg, suiClient, sideEffects, keyManager, eventBus)
+	marketplace := newMarketplaceGate(cfg.Features.MarketplaceConfigFile, featureFlags, itemReservations)
+	marketplace.UseFees(func() balance.FeeRate { return balanceService.Values().Fees.In("").Marketplace }, cfg.Treasury.Address, treasuryLedger)
+	marketplace.UseEvents(eventBus)
+	marketplace.UseExpiry(epochTracker, keyManager.PrivateKey, workerRole(cfg, builtin.elector, "listingExpiry").IsLeader, func(expired sui.ExpiredListing) {
+		notifyListingExpired(mailService, accountLinks.Store(), expired)
+	})
+	sideEffects.Start()
+	liveOps := newLiveOps(cfg, worldDirectory, actorSystem.Root, sideEffects, featureFlags)
+	liveOps.Subscribe(eventBus)
+	liveOps.Start()
+	metricHistory := newMetricHistory(cfg, dbCacheLayer, worldDirectory, actorSystem.Root, signerPool, sideEffects, handlerMetrics, suiClient)
+	if metricHistory != nil {
+		metricHistory.Start()
+	}
+	if err := pluginManager.Start(); err != nil {
+		utils.LogFatalf("Failed to start plugins: %v", err)
+	}
+
+	// --- Health Monitoring ---
+	healthMonitor.ExpectHeartbeat(actorSystemHeartbeat, 30*time.Second)
+	healthMonitor.AddReadinessCheck("sui-rpc", 15*time.Second, func(ctx context.Context) error {
+		_, err := suiClient.GetLatestCheckpointSequenceNumber(ctx)
+		return err
+	})
+	if dbCacheLayer != nil {
+		healthMonitor.AddReadinessCheck("postgres", 10*time.Second, dbCacheLayer.PingDB)
+		healthMonitor.AddReadinessCheck("redis", 10*time.Second, dbCacheLayer.PingRedis)
+		if dbCacheLayer.HasReplica() {
+			healthMonitor.AddReadinessCheck("postgres-replica", 10*time.Second, dbCacheLayer.PingReplica)
+		}
+	}
+	healthMonitor.SetLoad(func() interface{} { return loadTracker.Signals() })
+	if cfg.Autoscaling.GateReadiness {
+		healthMonitor.AddReadinessCheck("accepting-players", time.Second, loadTracker.CheckAccepting)
+	}
+	stopActorProbe := make(chan struct{})
+	go probeActorSystem(actorSystem, worldManagerPID, healthMonitor, stopActorProbe)
 
 	// --- Initialize Network Server ---
 	// TCPServer now also needs WorldManagerPID, suiClient, and Auth configs to pass to PlayerSessionActors
@@ -96,10 +452,169 @@ func main() {
 		cfg.Auth.DummyToken,
 		cfg.Auth.DummyPlayerID,
 	)
+	clientVersions, err := clientversion.New(clientversion.Options{
+		Min:            cfg.ClientVersions.Min,
+		Max:            cfg.ClientVersions.Max,
+		UpgradeURL:     cfg.ClientVersions.UpgradeURL,
+		RequireVersion: cfg.ClientVersions.RequireVersion,
+	})
+	if err != nil {
+		utils.LogFatalf("Invalid client version config: %v", err)
+	}
+	var resumeTokens *resume.Service
+	if cfg.Resume.Enabled {
+		resumeTokens = resume.NewService(resume.Options{
+			Window: time.Duration(cfg.Resume.WindowSeconds) * time.Second,
+			MaxAge: time.Duration(cfg.Resume.MaxAgeHours) * time.Hour,
+			BindIP: cfg.Resume.BindIP,
+		})
+	}
+	deepLinks := newDeepLinks(cfg)
+	clockHints := internalActor.ClockHints{
+		Interval:  time.Duration(cfg.ClockSync.HintIntervalSeconds) * time.Second,
+		Tolerance: time.Duration(cfg.ClockSync.ToleranceMs) * time.Millisecond,
+	}
+	tcpServer.SetHealthMonitor(healthMonitor)
+	tcpServer.SetChaos(chaosService)
+	tcpServer.SetShutdownRetryAfter(time.Duration(cfg.Server.ShutdownRetryAfterSeconds) * time.Second)
+	tcpServer.SetSessionServices(internalActor.SessionServices{
+		Onboarding:  newOnboardingService(cfg.Onboarding.TutorialFile, dbCacheLayer),
+		Events:      eventBus,
+		Arena:       arenaService,
+		Shop:        shopService,
+		Worlds:      worldDirectory,
+		AFK:         afkPolicy,
+		ChatHistory: chatHistory,
+		Accounts:    accountLinks,
+		Trades:      builtin.trades,
+		Gifts:       giftService,
+		OrderBook:   builtin.orderBook,
+		Audit:       auditLog,
+		Delivery:    delivery.NewStore(cfg.Delivery.Capacity, time.Duration(cfg.Delivery.RetentionSeconds)*time.Second),
+		Social:      ratelimit.Limit{PerSecond: cfg.Social.ActionsPerSecond, Burst: cfg.Social.Burst},
+		ClockHints:  clockHints,
+		Mail:        mailService,
+		Versions:    clientVersions,
+		Crafting:    craftingService,
+		Energy:      energyService,
+		BattlePass:  battlePassService,
+		Cosmetics:   cosmeticsService,
+		Receipts:    txReceipts,
+		FairRolls:   fairRolls,
+		Prefetch:    loginPrefetch,
+		Stats:       statsService,
+		Idempotency: builtin.idempotency,
+		Resume:      resumeTokens,
+		Calendar:    guildCalendar,
+		GuildRanks:  guildRanks,
+		DeepLinks:   deepLinks,
+		Parental:    parentalControls,
+		Diplomacy:   guildDiplomacy,
+		Plugins:     pluginManager,
+
+		Recruitment: guildRecruitment,
+	})
 	if err := tcpServer.Start(); err != nil {
 		log.Fatalf("Failed to start TCP server: %v", err)
 	}
 
+	// --- Initialize HTTP Server (health endpoints) ---
+	httpMux := http.NewServeMux()
+	healthMonitor.RegisterHandlers(httpMux)
+	// Debug endpoints are served behind the admin check; see registerAdminHandlers.
+	debugMux := http.NewServeMux()
+	if signerPool != nil {
+		debugMux.HandleFunc("/debug/signers", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(signerPool.Stats())
+		})
+	}
+	debugMux.HandleFunc("/debug/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(eventBus.Stats())
+	})
+	debugMux.HandleFunc("/debug/epoch", func(w http.ResponseWriter, r *http.Request) {
+		epoch, known := epochTracker.Current()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"known": known, "epoch": epoch, "estimatedEnd": epoch.EstimatedEnd()})
+	})
+	apiTokens := newAPITokens(cfg)
+	closeAdmin := registerAdminHandlers(httpMux, cfg, apiTokens, adminServices{
+		AuditLog:     auditLog,
+		DBCacheLayer: dbCacheLayer,
+		Balance:      balanceService,
+		GameData:     gameData,
+		Worlds:       worldDirectory,
+		ActorSystem:  actorSystem,
+		ChatHistory:  chatHistory,
+		AccountLinks: accountLinks,
+		Trade:        builtin.trades,
+		Gift:         giftService,
+		Airdrops:     airdrops,
+		Webhooks:     webhookService,
+		Quarantine:   messageQuarantine,
+		Chaos:        chaosService,
+		Features:     featureFlags,
+		LiveOps:      liveOps,
+		Metrics:      metricHistory,
+		Treasury:     treasuryLedger,
+		Resume:       resumeTokens,
+		DeepLinks:    deepLinks,
+		HotZones:     hotZones,
+		Plugins:      pluginManager,
+		Sui:          suiClient,
+		Debug:        debugMux,
+	})
+	readMux := registerReadGateway(httpMux, cfg, apiTokens)
+	marketplace.RegisterHandlers(readMux)
+	debugMux.HandleFunc("/debug/outbox", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sideEffects.Stats())
+	})
+	debugMux.HandleFunc("/debug/actor-messages", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(messageAudit.Stats())
+	})
+	debugMux.HandleFunc("/debug/anticheat", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(roomServices.Anticheat.Stats(time.Now()))
+	})
+	debugMux.HandleFunc("/debug/clientVersions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clientVersions.Stats())
+	})
+	debugMux.HandleFunc("/debug/handlers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(handlerMetrics.Stats())
+	})
+	if dbCacheLayer != nil {
+		debugMux.HandleFunc("/debug/database", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(dbCacheLayer.Stats())
+		})
+	}
+	if arenaService != nil {
+		readMux.HandleFunc("/arena/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"season":    arenaService.Season(),
+				"standings": arenaService.Leaderboard(100),
+			})
+		})
+	}
+	newStatusService(cfg, worldDirectory, arenaService).RegisterHandlers(readMux)
+	pluginManager.RegisterDebugHandlers(debugMux)
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort),
+		Handler: httpMux,
+	}
+	go func() {
+		utils.LogInfof("HTTP server listening on %s (/healthz, /readyz, /status)", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			utils.LogErrorf("HTTP server error: %v", err)
+		}
+	}()
+
 	log.Println("MMO Game Server successfully initialized and running.")
 	log.Println("Press Ctrl+C to shut down.")
 
@@ -110,25 +625,73 @@ func main() {
 
 	log.Println("Shutting down MMO Game Server...")
 
+	// Fail readiness first so orchestrators stop routing new players here.
+	healthMonitor.SetDraining(true)
+
 	// Stop TCPServer first to prevent new connections and allow existing handlers to finish
 	tcpServer.Stop() // This should handle its goroutines
+	close(stopActorProbe)
+	suiClient.StopHealthMonitor()
+	if epochTracker != nil {
+		epochTracker.Stop()
+	}
+	keyManager.Stop()
+	for _, manager := range signerKeyManagers {
+		manager.Stop()
+	}
+	signal.Stop(reloadKey)
+
+	if arenaService != nil {
+		arenaService.Stop()
+	}
+	if giftService != nil {
+		giftService.Stop()
+	}
+	if craftingService != nil {
+		craftingService.Stop()
+	}
+	energyService.Stop()
+	statsService.Stop()
+	loadTracker.Stop()
+	if hotZones != nil {
+		hotZones.Stop()
+	}
+	if guildCalendar != nil {
+		guildCalendar.Stop()
+	}
+	if battlePassService != nil {
+		battlePassService.Stop()
+	}
+	liveOps.Stop()
+	if metricHistory != nil {
+		metricHistory.Stop()
+	}
+	marketplace.Close()
+	sideEffects.Stop()
+	gameData.Stop()
+	balanceService.Stop()
+	if chatHistory != nil {
+		chatHistory.Stop()
+	}
 
 	// Stop top-level actors
 	// Order might matter if actors message each other during shutdown.
 	// Proto.Actor's Stop will send a Stopping message, then wait for the actor to process it and stop.
-	log.Printf("Stopping RoomManagerActor %s...", roomManagerPID.String())
-	if err := actorSystem.Root.StopFuture(roomManagerPID).Wait(); err != nil {
-		log.Printf("Error stopping RoomManagerActor: %v", err)
-	} else {
-		log.Println("RoomManagerActor stopped.")
-	}
+	for _, world := range worldDirectory.Worlds() {
+		log.Printf("Stopping RoomManagerActor %s...", world.RoomManagerPID.String())
+		if err := actorSystem.Root.StopFuture(world.RoomManagerPID).Wait(); err != nil {
+			log.Printf("Error stopping RoomManagerActor: %v", err)
+		} else {
+			log.Println("RoomManagerActor stopped.")
+		}
 
-	// Stop WorldManagerActor
-	log.Printf("Stopping WorldManagerActor %s...", worldManagerPID.String())
-	if err := actorSystem.Root.StopFuture(worldManagerPID).Wait(); err != nil {
-		log.Printf("Error stopping WorldManagerActor: %v", err)
-	} else {
-		log.Println("WorldManagerActor stopped.")
+		// Stop WorldManagerActor
+		log.Printf("Stopping WorldManagerActor %s...", world.WorldManagerPID.String())
+		if err := actorSystem.Root.StopFuture(world.WorldManagerPID).Wait(); err != nil {
+			log.Printf("Error stopping WorldManagerActor: %v", err)
+		} else {
+			log.Println("WorldManagerActor stopped.")
+		}
 	}
 
 	// TODO: Stop other top-level actors (e.g., PlayerDataManagerActor) in appropriate order
@@ -138,6 +701,14 @@ func main() {
 	// This will wait for all actors to stop.
 	log.Println("Shutting down actor system...")
 	actorSystem.Shutdown() // Waits for all actors to stop
+	messageQuarantine.Close()
+	chaosService.Close()
+	utils.SetErrorHook(nil)
+	eventBus.Close() // Delivers events published during shutdown
+	// After the event bus, so plugins get the events published during shutdown,
+	// and after the workers, so a follower takes over their leases only once
+	// they are done.
+	pluginManager.Stop()
 	// It's good practice to use actorSystem.ProcessRegistry.AddutdownHook if you need complex shutdown sequences or timeouts.
 	// For example:
 	// done := make(chan bool)
@@ -152,9 +723,1357 @@ func main() {
 	// 	log.Println("Actor system shutdown timed out.")
 	// }
 
-	// dbCacheLayer.Stop() // Stop any other services
+	// Stop the HTTP server and any other services
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+	cancelShutdown()
+	closeAdmin()
+	auditLog.Close()
+	if dbCacheLayer != nil {
+		dbCacheLayer.Stop()
+	}
 
 	// A small delay to allow logs to flush, if necessary.
 	time.Sleep(1 * time.Second)
 	log.Println("MMO Game Server shut down gracefully.")
 }
+
+// newPlugins initializes the built-in plugins and those compiled in, except
+// those listed in plugins.disabled. A plugin that fails to initialize stops
+// the server.
+func newPlugins(builtin []plugins.Plugin, host plugins.Host) *plugins.Manager {
+	manager := plugins.New(host.Config.Plugins, append(plugins.Registered(), builtin...))
+	err := manager.Init(host)
+	if err != nil {
+		utils.LogFatalf("Failed to set up plugins: %v", err)
+	}
+	utils.LogInfof("Plugins enabled: %v.", manager.Names())
+	return manager
+}
+
+// actorSystemHeartbeat is the health heartbeat fed by probeActorSystem.
+const actorSystemHeartbeat = "actor-system"
+
+// probeActorSystem periodically round-trips a Ping through the WorldManagerActor
+// and reports a heartbeat on success, so a stalled actor system fails liveness.
+func probeActorSystem(system *actor.ActorSystem, worldManagerPID *actor.PID, monitor *health.Monitor, stop <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			result, err := system.Root.RequestFuture(worldManagerPID, &messages.Ping{Timestamp: time.Now().UnixMilli()}, 2*time.Second).Result()
+			if err != nil {
+				utils.LogWarnf("Health: actor system probe failed: %v", err)
+				continue
+			}
+			if _, ok := result.(*messages.Pong); ok {
+				monitor.Beat(actorSystemHeartbeat)
+			}
+		}
+	}
+}
+
+// newSignerPool builds the signer pool from configuration, loading each signer's key.
+// It returns a nil pool when no signers are configured.
+func newSignerPool(cfg configs.SignerPoolConfig) (*sui.SignerPool, []*keys.Manager, error) {
+	if len(cfg.Signers) == 0 {
+		return nil, nil, nil
+	}
+	var managers []*keys.Manager
+	signers := make([]sui.PoolSignerConfig, 0, len(cfg.Signers))
+	for _, signerCfg := range cfg.Signers {
+		if signerCfg.KeySource.Type == "" || signerCfg.KeySource.Type == "config" {
+			return nil, nil, fmt.Errorf("signer %s needs an explicit keySource", signerCfg.Address)
+		}
+		provider, err := keys.NewProviderFromConfig(signerCfg.KeySource, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("signer %s: %w", signerCfg.Address, err)
+		}
+		manager := keys.NewManager(provider)
+		if err := manager.Reload(); err != nil {
+			utils.LogWarnf("SignerPool: Key for signer %s is not available yet; its transactions will fail until it loads.", signerCfg.Address)
+		}
+		manager.StartAutoReload(time.Duration(signerCfg.KeySource.ReloadIntervalSeconds) * time.Second)
+		managers = append(managers, manager)
+		signers = append(signers, sui.PoolSignerConfig{
+			Address:    signerCfg.Address,
+			GasCoinIDs: signerCfg.GasCoinIDs,
+			Keys:       manager,
+		})
+	}
+	pool, err := sui.NewSignerPool(sui.SignerSelection(cfg.Selection), signers)
+	if err != nil {
+		for _, manager := range managers {
+			manager.Stop()
+		}
+		return nil, nil, err
+	}
+	return pool, managers, nil
+}
+
+// newOnboardingService loads the tutorial definition. Onboarding is disabled
+// (nil) when the file does not exist or is invalid.
+func newOnboardingService(tutorialFile string, dbCacheLayer *game.DBCacheLayer) *onboarding.Service {
+	if tutorialFile == "" {
+		return nil
+	}
+	def, err := onboarding.LoadDefinition(tutorialFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No tutorial definition at %s. Onboarding is disabled.", tutorialFile)
+		} else {
+			utils.LogErrorf("Failed to load tutorial definition: %v. Onboarding is disabled.", err)
+		}
+		return nil
+	}
+	var store onboarding.Store = onboarding.NewMemoryStore()
+	if dbCacheLayer != nil {
+		store = dbCacheLayer
+	} else {
+		utils.LogWarn("No DB cache layer. Tutorial progress will not survive a restart.")
+	}
+	utils.LogInfof("Onboarding enabled with %d tutorial steps from %s.", len(def.Steps), tutorialFile)
+	return onboarding.NewService(def, store)
+}
+
+// newRoomServices builds the services rooms share. The maps, movement
+// abilities, NPCs and projectiles come from gameData, so rooms created after
+// a reload use the new data. Without maps every room is open ground.
+func newRoomServices(cfg *configs.Config, eventBus *events.Bus, balanceService *balance.Service, gameData *gamedata.Watcher, statsService *stats.Service, fairRolls *fairroll.Service) internalActor.RoomServices {
+	// Projectile hits are resolved off-chain; defeats still reach the event bus.
+	combatEngine := game.NewCombatEngine(nil)
+	combatEngine.SetEventBus(eventBus)
+	combatEngine.UseBalance(balanceService.Values)
+	if fairRolls != nil {
+		combatEngine.RollLootWith(fairRolls)
+	}
+	return internalActor.RoomServices{
+		PathBudget: cfg.NPCs.PathBudget,
+		Tick:       time.Duration(cfg.NPCs.TickIntervalMs) * time.Millisecond,
+		Combat:     combatEngine,
+		PlayerStats: game.CombatantStats{
+			Health:    cfg.Projectiles.PlayerHealth,
+			MaxHealth: cfg.Projectiles.PlayerHealth,
+			Defense:   cfg.Projectiles.PlayerDefense,
+		},
+		Data:       gameData,
+		Anticheat:  newAnticheat(cfg, eventBus),
+		RuleLimits: ruleset.Limits{MaxXPMultiplier: cfg.RoomRules.MaxXPMultiplier},
+		Stats:      statsService,
+	}
+}
+
+// newHotZones creates the monitor of crowded maps, or nil if it is disabled.
+func newHotZones(cfg *configs.Config, balanceService *balance.Service) *hotzone.Monitor {
+	if !cfg.HotZones.Enabled {
+		return nil
+	}
+	return hotzone.NewMonitor(balanceService, hotzone.Options{
+		CellSize:      cfg.HotZones.CellSize,
+		HotPlayers:    cfg.HotZones.HotPlayers,
+		CoolPlayers:   cfg.HotZones.CoolPlayers,
+		Sustain:       cfg.HotZones.SustainChecks,
+		NPCStep:       cfg.HotZones.NPCStep,
+		MaxNPCDensity: cfg.HotZones.MaxNPCDensity,
+		Interval:      time.Duration(cfg.HotZones.IntervalSeconds) * time.Second,
+	})
+}
+
+// newAnticheat creates the monitor checking player movement. Players it
+// reports are published on the event bus, where webhooks can pick them up.
+func newAnticheat(cfg *configs.Config, eventBus *events.Bus) *anticheat.Monitor {
+	monitor := anticheat.New(anticheat.Options{
+		MaxSpeed:        cfg.Anticheat.MaxSpeed,
+		SpeedWindow:     time.Duration(cfg.Anticheat.SpeedWindowMs) * time.Millisecond,
+		Tolerance:       cfg.Anticheat.SpeedTolerance,
+		FlagAfter:       cfg.Anticheat.FlagAfter,
+		KickAfter:       cfg.Anticheat.KickAfter,
+		ViolationWindow: time.Duration(cfg.Anticheat.ViolationWindowSeconds) * time.Second,
+	})
+	monitor.UseEvents(eventBus)
+	if monitor.Enabled() {
+		utils.LogInfof("Speed checks enabled: at most %.1f units/s over %dms, reported after %d and disconnected after %d violations.",
+			cfg.Anticheat.MaxSpeed, cfg.Anticheat.SpeedWindowMs, cfg.Anticheat.FlagAfter, cfg.Anticheat.KickAfter)
+	}
+	return monitor
+}
+
+// newGameData loads the maps, the skills file and the NPC file, and watches
+// them for edits. Invalid files are logged and leave rooms without that data
+// until they are fixed and reloaded.
+func newGameData(cfg *configs.Config, balanceService *balance.Service) *gamedata.Watcher {
+	gameData, report := gamedata.NewWatcher(gamedata.Sources{
+		MapsDir:     cfg.Movement.MapsDir,
+		SkillsFile:  cfg.Movement.SkillsFile,
+		NPCFile:     cfg.NPCs.File,
+		NavCellSize: cfg.NPCs.NavCellSize,
+	})
+	gameData.UseLoot(balanceService)
+	if err := report.Err(); err != nil {
+		utils.LogErrorf("Failed to load game data: %v. Rooms have no maps, abilities, NPCs or projectiles until it is fixed.", err)
+	}
+	utils.LogInfof("Game data at version %d: %d maps, %d movement abilities, %d projectiles and %d NPCs.",
+		report.Version, report.Maps, report.Abilities, report.Projectiles, report.NPCs)
+	if cfg.Movement.ReloadIntervalSeconds > 0 {
+		gameData.StartWatching(time.Duration(cfg.Movement.ReloadIntervalSeconds) * time.Second)
+	}
+	return gameData
+}
+
+// arenaRegions lists the regions of the configured worlds and of the server
+// itself, which are the regions arena players may choose.
+func arenaRegions(cfg *configs.Config, worldDirectory *worlds.Directory) []string {
+	var regions []string
+	seen := make(map[string]bool)
+	add := func(region string) {
+		if region != "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	add(cfg.Status.Region)
+	for _, world := range worldDirectory.Worlds() {
+		add(world.Region)
+	}
+	return regions
+}
+
+// spawnWorlds spawns the room and world managers of every configured world. The
+// default world keeps the plain actor names; others get their ID as a suffix.
+func spawnWorlds(actorSystem *actor.ActorSystem, cfg *configs.Config, suiClient *sui.SuiClient, eventBus *events.Bus, roomServices internalActor.RoomServices, guildRoster *guilds.Roster, guildDiplomacy *diplomacy.Service) *worlds.Directory {
+	directory := worlds.NewDirectory()
+	for _, worldCfg := range cfg.WorldList() {
+		suffix := ""
+		if worldCfg.ID != worlds.DefaultID {
+			suffix = "-" + worldCfg.ID
+		}
+		roomManagerPID, err := actorSystem.Root.SpawnNamed(internalActor.PropsForRoomManagerWithServices(actorSystem, roomServices), "room-manager"+suffix)
+		if err != nil {
+			utils.LogFatalf("Failed to spawn RoomManagerActor for world %s: %v", worldCfg.ID, err)
+		}
+		worldManagerProps := internalActor.PropsForWorldManagerWithServices(actorSystem, newWorldServices(cfg, worldCfg, suiClient, eventBus, guildRoster, guildDiplomacy))
+		worldManagerPID, err := actorSystem.Root.SpawnNamed(worldManagerProps, "world-manager"+suffix)
+		if err != nil {
+			utils.LogFatalf("Failed to spawn WorldManagerActor for world %s: %v", worldCfg.ID, err)
+		}
+		err = directory.Add(&worlds.World{
+			ID:              worldCfg.ID,
+			Name:            worldCfg.Name,
+			Region:          worldCfg.Region,
+			RoomManagerPID:  roomManagerPID,
+			WorldManagerPID: worldManagerPID,
+		})
+		if err != nil {
+			utils.LogFatalf("Invalid world config: %v", err)
+		}
+		utils.LogInfof("World %s (%s) spawned: RoomManager %s, WorldManager %s", worldCfg.ID, worldCfg.Name, roomManagerPID.String(), worldManagerPID.String())
+	}
+	return directory
+}
+
+// newAFKPolicy builds the AFK policy, or returns nil if AFK detection is off. The
+// lobby AFK players are moved to is created as an unlisted room in every world.
+func newAFKPolicy(actorSystem *actor.ActorSystem, cfg *configs.Config, worldDirectory *worlds.Directory) *afk.Policy {
+	if cfg.AFK.AfterSeconds <= 0 {
+		utils.LogInfo("AFK detection is disabled.")
+		return nil
+	}
+	policy := &afk.Policy{
+		After:           time.Duration(cfg.AFK.AfterSeconds) * time.Second,
+		KickAfter:       time.Duration(cfg.AFK.KickAfterSeconds) * time.Second,
+		KickMinSessions: cfg.AFK.KickMinSessions,
+		LobbyRoomID:     cfg.AFK.LobbyRoomID,
+		CheckInterval:   time.Duration(cfg.AFK.CheckIntervalSeconds) * time.Second,
+		Sessions:        worldDirectory.Sessions,
+	}
+	if policy.LobbyRoomID != "" {
+		for _, world := range worldDirectory.Worlds() {
+			actorSystem.Root.Send(world.RoomManagerPID, &messages.CreateRoomRequest{
+				RoomID:     policy.LobbyRoomID,
+				RoomName:   "AFK Lobby",
+				MaxPlayers: 10000,
+				Visibility: messages.RoomVisibilityPrivate,
+			})
+		}
+	}
+	utils.LogInfof("AFK detection enabled: AFK after %s, kicked after %s with %d+ players online.", policy.After, policy.KickAfter, policy.KickMinSessions)
+	return policy
+}
+
+// redisConfigured reports whether the redis section names a server.
+func redisConfigured(cfg *configs.Config) bool {
+	return cfg.Redis.Address != "" || len(cfg.Redis.Addresses) > 0
+}
+
+// redisConfig is the redis config section for the DB cache layer.
+func redisConfig(cfg *configs.Config) game.RedisConfig {
+	return game.RedisConfig{
+		Mode:             cfg.Redis.Mode,
+		Addr:             cfg.Redis.Address,
+		Addrs:            cfg.Redis.Addresses,
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
+	}
+}
+
+// configureDatabase applies the pool, replica and timeout settings of the
+// database config section, and the Redis fallback policies.
+func configureDatabase(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer) {
+	dbCacheLayer.ConfigurePool(game.PoolOptions{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetimeSeconds) * time.Second,
+		ConnMaxIdleTime: time.Duration(cfg.Database.ConnMaxIdleTimeSeconds) * time.Second,
+	})
+	dbCacheLayer.SetQueryTimeout(time.Duration(cfg.Database.QueryTimeoutSeconds) * time.Second)
+	if cfg.Database.ReplicaURL != "" {
+		if err := dbCacheLayer.UseReadReplica(cfg.Database.ReplicaURL); err != nil {
+			utils.LogWarnf("Read replica unavailable: %v. Reads go to the primary.", err)
+		}
+	}
+	policies := make(map[string]game.CachePolicy)
+	for useCase, name := range cfg.Redis.Fallback.Policies {
+		policy, err := game.ParseCachePolicy(name)
+		if err != nil {
+			utils.LogWarnf("redis.fallback.policies.%s: %v. Keeping its default.", useCase, err)
+			continue
+		}
+		policies[useCase] = policy
+	}
+	dbCacheLayer.UseCacheOptions(game.CacheOptions{
+		Policies:        policies,
+		BreakerFailures: cfg.Redis.Fallback.BreakerFailures,
+		BreakerCooldown: time.Duration(cfg.Redis.Fallback.BreakerCooldownSeconds) * time.Second,
+		StaleEntries:    cfg.Redis.Fallback.StaleEntries,
+	})
+}
+
+// newChatHistoryService stores chat in PostgreSQL, or in memory when there is no
+// database. It returns nil if chat history is disabled.
+func newChatHistoryService(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer) *chathistory.Service {
+	if !cfg.ChatHistory.Enabled {
+		return nil
+	}
+	var store chathistory.Store = chathistory.NewMemoryStore()
+	if dbCacheLayer != nil {
+		pgStore := &chathistory.PostgresStore{DB: dbCacheLayer.DB(), ReadDB: dbCacheLayer.ReadDB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Chat history table unavailable: %v. Keeping chat history in memory.", err)
+		} else {
+			store = pgStore
+		}
+	} else {
+		utils.LogWarn("No database configured. Chat history is kept in memory and lost on restart.")
+	}
+	return chathistory.NewService(store, chathistory.Options{
+		MaxPerChannel: cfg.ChatHistory.MaxPerChannel,
+		MaxAge:        time.Duration(cfg.ChatHistory.RetentionDays) * 24 * time.Hour,
+		MaxFetch:      cfg.ChatHistory.MaxFetch,
+	})
+}
+
+// newGuildRoster loads the off-chain guild memberships behind guild chat and
+// guild events.
+func newGuildRoster(cfg *configs.Config) *guilds.Roster {
+	roster, err := guilds.LoadRoster(cfg.Guilds.RosterFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No guild roster at %s. Guild chat channels and events are disabled.", cfg.Guilds.RosterFile)
+		} else {
+			utils.LogErrorf("Failed to load the guild roster: %v. Guild chat channels and events are disabled.", err)
+		}
+		return nil
+	}
+	utils.LogInfof("Loaded %d guilds from %s.", roster.Len(), cfg.Guilds.RosterFile)
+	return roster
+}
+
+// chatChannelPolicies converts the chat channel config.
+func chatChannelPolicies(cfg *configs.Config) chatchannels.Policies {
+	policy := func(c configs.ChatChannelConfig) chatchannels.Policy {
+		return chatchannels.Policy{AutoJoin: c.AutoJoin, MessagesPerMinute: c.MessagesPerMinute, Burst: c.Burst}
+	}
+	return chatchannels.Policies{
+		chatchannels.Global: policy(cfg.ChatChannels.Global),
+		chatchannels.Trade:  policy(cfg.ChatChannels.Trade),
+		chatchannels.Zone:   policy(cfg.ChatChannels.Zone),
+		chatchannels.Guild:  policy(cfg.ChatChannels.Guild),
+
+		chatchannels.Alliance: policy(cfg.ChatChannels.Alliance),
+	}
+}
+
+// newWorldServices sets up the optional world systems for one world. Guild
+// territory needs a zone file and the guild package ID to verify claims
+// against; allied guilds do not contest each other's zones.
+func newWorldServices(cfg *configs.Config, worldCfg configs.WorldConfig, suiClient *sui.SuiClient, eventBus *events.Bus, guildRoster *guilds.Roster, guildDiplomacy *diplomacy.Service) internalActor.WorldServices {
+	services := internalActor.WorldServices{Events: eventBus, Guilds: guildRoster, ChatChannels: chatChannelPolicies(cfg), Diplomacy: guildDiplomacy}
+	if worldCfg.ZonesFile == "" {
+		return services
+	}
+	zones, err := territory.LoadZones(worldCfg.ZonesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No zone file at %s. Guild territory is disabled in world %s.", worldCfg.ZonesFile, worldCfg.ID)
+		} else {
+			utils.LogErrorf("Failed to load zones for world %s: %v. Guild territory is disabled there.", worldCfg.ID, err)
+		}
+		return services
+	}
+	services.Territory = territory.NewRegistry(zones,
+		time.Duration(cfg.Territory.SiegeDelaySeconds)*time.Second,
+		time.Duration(cfg.Territory.SiegeDurationSeconds)*time.Second)
+	if guildDiplomacy != nil {
+		services.Territory.UseAlliances(guildDiplomacy)
+	}
+	if worldCfg.GuildPackageID != "" {
+		services.ClaimVerifier = sui.NewGuildSystemSuiService(suiClient, worldCfg.GuildPackageID, worldCfg.GuildModule)
+	} else {
+		utils.LogWarnf("No guild package ID for world %s. Territory claims cannot be verified and will be refused.", worldCfg.ID)
+	}
+	utils.LogInfof("Guild territory enabled in world %s with %d zones from %s.", worldCfg.ID, len(zones), worldCfg.ZonesFile)
+	return services
+}
+
+// newAccountLinkService keeps linked Sui addresses in PostgreSQL, or in memory
+// when there is no database.
+func newAccountLinkService(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer) *accountlink.Service {
+	var store accountlink.Store = accountlink.NewMemoryStore()
+	if dbCacheLayer != nil {
+		pgStore := &accountlink.PostgresStore{DB: dbCacheLayer.DB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Account link table unavailable: %v. Keeping linked addresses in memory.", err)
+		} else {
+			store = pgStore
+		}
+	} else {
+		utils.LogWarn("No database configured. Linked Sui addresses are kept in memory and lost on restart.")
+	}
+	return accountlink.NewService(store, accountlink.Options{
+		MaxAddresses: cfg.AccountLinks.MaxAddresses,
+		ChallengeTTL: time.Duration(cfg.AccountLinks.ChallengeTTLSeconds) * time.Second,
+		GameName:     cfg.AccountLinks.GameName,
+	})
+}
+
+// newShopService loads the shop catalog. Shops are disabled (nil) when the file
+// does not exist or is invalid. Premium items need a payment recipient, and are
+// minted from the outbox once a minter address is configured.
+func newShopService(cfg *configs.Config, wallets shop.WalletStore, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, eventBus *events.Bus) *shop.Service {
+	if cfg.Shop.CatalogFile == "" {
+		return nil
+	}
+	catalog, err := shop.LoadCatalog(cfg.Shop.CatalogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No shop catalog at %s. Shops are disabled.", cfg.Shop.CatalogFile)
+		} else {
+			utils.LogErrorf("Failed to load shop catalog: %v. Shops are disabled.", err)
+		}
+		return nil
+	}
+	shopService, err := shop.NewService(catalog, wallets, shop.FileStore{Path: cfg.Shop.StateFile})
+	if err != nil {
+		utils.LogErrorf("Failed to set up shops: %v. Shops are disabled.", err)
+		return nil
+	}
+	if cfg.Shop.PremiumRecipient != "" {
+		shopService.EnablePremium(suiClient, box, cfg.Shop.PremiumRecipient)
+		registerPremiumMinter(box, cfg, suiClient, keyManager, eventBus)
+	}
+	utils.LogInfof("Shops enabled with %d shops from %s.", len(catalog.Shops), cfg.Shop.CatalogFile)
+	return shopService
+}
+
+// newCraftingService loads the recipes and sets up the crafting queues, kept
+// in the database when there is one so crafts finish across restarts.
+// Finished crafts are minted from the outbox to each player's primary linked
+// address; without a minter address the mints stay queued.
+func newCraftingService(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer, wallets shop.WalletStore, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, eventBus *events.Bus, accountLinks *accountlink.Service) *crafting.Service {
+	if cfg.Crafting.RecipesFile == "" {
+		return nil
+	}
+	recipes, err := crafting.LoadRecipes(cfg.Crafting.RecipesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No recipes at %s. Crafting is disabled.", cfg.Crafting.RecipesFile)
+		} else {
+			utils.LogErrorf("Failed to load recipes: %v. Crafting is disabled.", err)
+		}
+		return nil
+	}
+	var store crafting.Store = crafting.NewMemoryStore()
+	if dbCacheLayer != nil {
+		pgStore := &crafting.PostgresStore{DB: dbCacheLayer.DB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Crafts table unavailable: %v. Crafting is disabled.", err)
+			return nil
+		}
+		store = pgStore
+	} else {
+		utils.LogWarn("No database configured. Crafting queues are kept in memory and lost on restart.")
+	}
+	service := crafting.NewService(recipes, store, wallets, crafting.Options{
+		MaxQueued:    cfg.Crafting.MaxQueued,
+		TickInterval: time.Duration(cfg.Crafting.TickIntervalSeconds) * time.Second,
+	})
+	if cfg.Crafting.MinterAddress == "" || cfg.Crafting.MinterGasObjectID == "" {
+		utils.LogWarn("crafting.minterAddress or crafting.minterGasObjectId is not set. Crafted items will stay queued in the outbox.")
+		service.UseMinting(box, nil)
+	} else {
+		items := sui.NewItemNFTService(suiClient, cfg.Sui.ItemSystemPackageID, cfg.Crafting.ItemModule, cfg.Crafting.MinterAddress, cfg.Crafting.MinterGasObjectID)
+		service.UseMinting(box, func(ctx context.Context, craft crafting.Craft) (string, error) {
+			privateKey, err := keyManager.PrivateKey()
+			if err != nil {
+				return "", err
+			}
+			recipient, err := accountLinks.Address(ctx, craft.PlayerID)
+			if err != nil {
+				return "", fmt.Errorf("crafted item for %s: %w", craft.PlayerID, err)
+			}
+			metadata := map[string]interface{}{"recipe": craft.RecipeID, "craft": craft.ID}
+			resp, err := items.MintItemNFTAndExecute(craft.ItemType, metadata, recipient, cfg.Sui.GasBudget, privateKey)
+			if err != nil {
+				return "", err
+			}
+			eventBus.Publish(events.TopicItemMinted, events.ItemMinted{ItemType: craft.ItemType, Owner: recipient, Source: "crafting", TxDigest: resp.Digest})
+			mintReceipt(eventBus, "crafting", craft.PlayerID, craft.Name, resp.Digest)
+			return resp.Digest, nil
+		})
+		reportMintFailures(box, eventBus, crafting.MintKind, "crafting", func(craft crafting.Craft) (string, string) { return craft.PlayerID, craft.Name })
+	}
+	utils.LogInfof("Crafting enabled with %d recipes from %s.", len(recipes), cfg.Crafting.RecipesFile)
+	return service
+}
+
+// newEnergyService keeps the players' energy, in the database when there is
+// one. Its costs and regeneration come from the balance values.
+func newEnergyService(dbCacheLayer *game.DBCacheLayer, balanceService *balance.Service) *energy.Service {
+	var store energy.Store = energy.NewMemoryStore()
+	if dbCacheLayer != nil {
+		pgStore := &energy.PostgresStore{DB: dbCacheLayer.DB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Energy table unavailable: %v. Keeping energy in memory.", err)
+		} else {
+			store = pgStore
+		}
+	} else {
+		utils.LogWarn("No database configured. Energy is kept in memory and refilled on restart.")
+	}
+	return energy.NewService(store, func() balance.EnergyValues { return balanceService.Values().Energy }, energy.Options{})
+}
+
+// newBattlePassService loads the battle pass seasons. The battle pass is
+// disabled (nil) when the file does not exist or is invalid. The premium pass
+// is sold only with a payment recipient; cosmetic rewards are minted from the
+// outbox to each player's primary linked address once a minter address is
+// configured.
+func newBattlePassService(cfg *configs.Config, wallets shop.WalletStore, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, eventBus *events.Bus, accountLinks *accountlink.Service) *battlepass.Service {
+	if cfg.BattlePass.File == "" {
+		return nil
+	}
+	def, err := battlepass.LoadDefinition(cfg.BattlePass.File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No battle pass at %s. The battle pass is disabled.", cfg.BattlePass.File)
+		} else {
+			utils.LogErrorf("Failed to load the battle pass: %v. The battle pass is disabled.", err)
+		}
+		return nil
+	}
+	service, err := battlepass.NewService(def, battlepass.FileStore{Path: cfg.BattlePass.StateFile}, wallets, battlepass.Options{})
+	if err != nil {
+		utils.LogErrorf("Failed to set up the battle pass: %v. The battle pass is disabled.", err)
+		return nil
+	}
+	service.UseMinting(box)
+	if cfg.BattlePass.PremiumRecipient != "" {
+		service.UsePremium(suiClient, cfg.BattlePass.PremiumRecipient)
+	}
+	registerBattlePassMinter(box, cfg, suiClient, keyManager, eventBus, accountLinks)
+	utils.LogInfof("Battle pass enabled with %d seasons from %s.", len(def.Seasons), cfg.BattlePass.File)
+	return service
+}
+
+// registerBattlePassMinter mints battle pass cosmetics from the outbox like
+// registerTrophyMinter mints arena trophies.
+func registerBattlePassMinter(box *outbox.Outbox, cfg *configs.Config, suiClient *sui.SuiClient, keyManager *keys.Manager, eventBus *events.Bus, accountLinks *accountlink.Service) {
+	if cfg.BattlePass.MinterAddress == "" || cfg.BattlePass.MinterGasObjectID == "" {
+		utils.LogWarn("battlePass.minterAddress or battlePass.minterGasObjectId is not set. Battle pass cosmetics will stay queued in the outbox.")
+		return
+	}
+	items := sui.NewItemNFTService(suiClient, cfg.Sui.ItemSystemPackageID, cfg.BattlePass.ItemModule, cfg.BattlePass.MinterAddress, cfg.BattlePass.MinterGasObjectID)
+	box.Handle(battlepass.MintKind, func(ctx context.Context, payload json.RawMessage) error {
+		var grant battlepass.Grant
+		if err := json.Unmarshal(payload, &grant); err != nil {
+			return err
+		}
+		privateKey, err := keyManager.PrivateKey()
+		if err != nil {
+			return err
+		}
+		recipient, err := accountLinks.Address(ctx, grant.PlayerID)
+		if err != nil {
+			return fmt.Errorf("battle pass cosmetic for %s: %w", grant.PlayerID, err)
+		}
+		metadata := map[string]interface{}{"season": grant.SeasonID, "tier": grant.Tier, "track": grant.Track}
+		resp, err := items.MintItemNFTAndExecute(grant.ItemType, metadata, recipient, cfg.Sui.GasBudget, privateKey)
+		if err != nil {
+			return err
+		}
+		eventBus.Publish(events.TopicItemMinted, events.ItemMinted{ItemType: grant.ItemType, Owner: recipient, Source: "battlepass", TxDigest: resp.Digest})
+		mintReceipt(eventBus, "battlepass", grant.PlayerID, grant.ItemType, resp.Digest)
+		return nil
+	})
+	reportMintFailures(box, eventBus, battlepass.MintKind, "battlepass", func(grant battlepass.Grant) (string, string) { return grant.PlayerID, grant.ItemType })
+}
+
+// newCosmeticsService loads the cosmetic slots and sets up the appearances,
+// kept in the database when there is one. Cosmetics are disabled (nil) when
+// the file does not exist or is invalid, or when the Move type of item NFTs
+// is not configured.
+func newCosmeticsService(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer, suiClient *sui.SuiClient, accountLinks *accountlink.Service) *cosmetics.Service {
+	if cfg.Cosmetics.File == "" {
+		return nil
+	}
+	catalog, err := cosmetics.LoadCatalog(cfg.Cosmetics.File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			utils.LogInfof("No cosmetics at %s. Cosmetics are disabled.", cfg.Cosmetics.File)
+		} else {
+			utils.LogErrorf("Failed to load cosmetics: %v. Cosmetics are disabled.", err)
+		}
+		return nil
+	}
+	itemType := itemObjectType(cfg)
+	if itemType == "" {
+		utils.LogWarn("Neither cosmetics.itemObjectType nor trade.itemType is set. Cosmetics are disabled.")
+		return nil
+	}
+	var store cosmetics.Store = cosmetics.NewMemoryStore()
+	if dbCacheLayer != nil {
+		pgStore := &cosmetics.PostgresStore{DB: dbCacheLayer.DB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Cosmetics table unavailable: %v. Cosmetics are disabled.", err)
+			return nil
+		}
+		store = pgStore
+	} else {
+		utils.LogWarn("No database configured. Appearances are kept in memory and lost on restart.")
+	}
+	ownedBy := func(ctx context.Context, address string) ([]string, error) {
+		return suiClient.OwnedItemNames(ctx, address, itemType)
+	}
+	service := cosmetics.NewService(catalog, store, accountLinks.Address, ownedBy, cosmetics.Options{
+		OwnershipTTL: time.Duration(cfg.Cosmetics.OwnershipCacheSeconds) * time.Second,
+	})
+	utils.LogInfof("Cosmetics enabled with %d cosmetics in %d slots from %s.", len(catalog.Cosmetics), len(catalog.Slots), cfg.Cosmetics.File)
+	return service
+}
+
+// itemObjectType is the Move type of item NFTs, or "" if it is not configured.
+func itemObjectType(cfg *configs.Config) string {
+	if cfg.Cosmetics.ItemObjectType != "" {
+		return cfg.Cosmetics.ItemObjectType
+	}
+	return cfg.Trade.ItemType
+}
+
+// newLoginPrefetch sets up the chain reads at login: balances, the profile
+// object if its type is configured and the item NFTs if theirs is. The item
+// NFTs it reads prime the cosmetics' ownership cache. It returns nil if the
+// prefetch is disabled.
+func newLoginPrefetch(cfg *configs.Config, suiClient *sui.SuiClient, accountLinks *accountlink.Service, cosmeticsService *cosmetics.Service) *prefetch.Service {
+	if !cfg.LoginPrefetch.Enabled {
+		return nil
+	}
+	fetch := prefetch.Fetchers{
+		Address: accountLinks.Address,
+		Balances: func(ctx context.Context, address string) ([]prefetch.Balance, error) {
+			all, err := suiClient.AllBalances(ctx, address)
+			balances := make([]prefetch.Balance, 0, len(all))
+			for _, balance := range all {
+				balances = append(balances, prefetch.Balance{CoinType: balance.CoinType, Total: balance.TotalBalance})
+			}
+			return balances, err
+		},
+	}
+	if profileType := cfg.LoginPrefetch.ProfileObjectType; profileType != "" {
+		fetch.Profile = func(ctx context.Context, address string) (*prefetch.Profile, error) {
+			object, err := suiClient.FirstOwnedObject(ctx, address, profileType)
+			if err != nil || object == nil {
+				return nil, err
+			}
+			profile := &prefetch.Profile{ObjectID: object.ObjectId, Version: object.Version}
+			if object.Content != nil {
+				profile.Fields = object.Content.Fields
+			}
+			return profile, nil
+		}
+	}
+	if itemType := itemObjectType(cfg); itemType != "" {
+		fetch.Items = func(ctx context.Context, address string) ([]string, error) {
+			return suiClient.OwnedItemNames(ctx, address, itemType)
+		}
+	}
+	service := prefetch.NewService(fetch, prefetch.Options{
+		Wait:     time.Duration(cfg.LoginPrefetch.WaitMs) * time.Millisecond,
+		CacheTTL: time.Duration(cfg.LoginPrefetch.CacheSeconds) * time.Second,
+	})
+	if cosmeticsService != nil && fetch.Items != nil {
+		service.OnFetched(func(snapshot prefetch.Snapshot) {
+			if !snapshot.HasFailed(prefetch.PartItems) {
+				cosmeticsService.Prime(snapshot.Address, snapshot.Items, snapshot.FetchedAt)
+			}
+		})
+	}
+	utils.LogInfof("Login prefetch enabled; logins wait up to %dms for the player's chain state.", cfg.LoginPrefetch.WaitMs)
+	return service
+}
+
+// newProgressStore returns the players' experience and levels, kept with
+// their player data when there is a database.
+func newProgressStore(dbCacheLayer *game.DBCacheLayer) stats.ProgressStore {
+	if dbCacheLayer == nil {
+		utils.LogWarn("No DB cache layer. Experience will not survive a restart.")
+		return &stats.MemoryProgress{}
+	}
+	return game.PlayerProgress{DB: dbCacheLayer}
+}
+
+// newWalletStore returns the players' soft currency wallets, kept with their
+// player data when there is a database.
+func newWalletStore(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer) shop.WalletStore {
+	if dbCacheLayer == nil {
+		utils.LogWarn("No DB cache layer. Wallets will not survive a restart.")
+		return &shop.MemoryWallets{StartingCoins: cfg.Shop.StartingCoins}
+	}
+	return game.ShopWallets{DB: dbCacheLayer, StartingCoins: cfg.Shop.StartingCoins}
+}
+
+// newLiveOps creates the live-ops dashboard over the worlds, the outbox of
+// on-chain side effects and the feature flags.
+func newLiveOps(cfg *configs.Config, worldDirectory *worlds.Directory, root *actor.RootContext, sideEffects *outbox.Outbox, featureFlags *features.Registry) *liveops.Service {
+	var store liveops.Store = &liveops.MemoryStore{}
+	if cfg.LiveOps.StateFile != "" {
+		store = liveops.FileStore{Path: cfg.LiveOps.StateFile}
+	}
+	service, err := liveops.New(store, liveops.Options{
+		Worlds:   func() []worlds.Stats { return worldDirectory.Stats(root, 2*time.Second) },
+		Outbox:   sideEffects.Stats,
+		Flags:    featureFlags,
+		Announce: func(text string) int { return worldDirectory.Announce(root, text) },
+	})
+	if err != nil {
+		utils.LogFatalf("Failed to load the live-ops state: %v", err)
+	}
+	return service
+}
+
+// newMetricHistory records the players online, transaction and RPC rates and
+// latencies for the admin trend endpoint, in PostgreSQL when there is a
+// database. It returns nil if metric history is disabled.
+func newMetricHistory(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer, worldDirectory *worlds.Directory, root *actor.RootContext, signerPool *sui.SignerPool, sideEffects *outbox.Outbox, handlerMetrics *handlermetrics.Service, suiClient *sui.SuiClient) *metrichistory.Recorder {
+	if !cfg.MetricHistory.Enabled {
+		return nil
+	}
+	var store metrichistory.Store = &metrichistory.MemoryStore{}
+	if dbCacheLayer != nil {
+		pgStore := &metrichistory.PostgresStore{DB: dbCacheLayer.DB(), Timeout: dbCacheLayer.QueryTimeout()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgStore.EnsureSchema(ctx); err != nil {
+			utils.LogErrorf("Metric samples table unavailable: %v. Metric history is disabled.", err)
+			return nil
+		}
+		store = pgStore
+	} else {
+		utils.LogWarn("No database configured. Metric history is kept in memory and lost on restart.")
+	}
+	recorder := metrichistory.NewRecorder(store, metrichistory.Options{
+		Instance:  cfg.MetricHistory.Instance,
+		Interval:  time.Duration(cfg.MetricHistory.IntervalSeconds) * time.Second,
+		Retention: time.Duration(cfg.MetricHistory.RetentionDays) * 24 * time.Hour,
+	})
+	worldTotals := func(field func(worlds.Stats) int) func() float64 {
+		return func() float64 {
+			total := 0
+			for _, stats := range worldDirectory.Stats(root, 2*time.Second) {
+				total += field(stats)
+			}
+			return float64(total)
+		}
+	}
+	recorder.Gauge("players.online", "Connected sessions in every world", func() float64 { return float64(worldDirectory.Sessions()) })
+	recorder.Gauge("players.in_rooms", "Players in a room", worldTotals(func(s worlds.Stats) int { return s.PlayersInRoom }))
+	recorder.Gauge("rooms.open", "Open rooms", worldTotals(func(s worlds.Stats) int { return s.Rooms }))
+	recorder.Mean("handler.mean_ms", "Mean time actors took to handle a message", func() (uint64, float64) {
+		var count uint64
+		var totalMs float64
+		for _, h := range handlerMetrics.Stats().Handlers {
+			count += h.Count
+			totalMs += float64(h.Count) * h.MeanMs
+		}
+		return count, totalMs
+	})
+	recorder.Counter("outbox.delivered_per_second", "On-chain side effects delivered", func() uint64 { return sideEffects.Stats().Delivered })
+	recorder.Gauge("outbox.pending", "On-chain side effects waiting", func() float64 { return float64(sideEffects.Stats().Pending) })
+	recorder.Gauge("outbox.dead", "On-chain side effects given up on", func() float64 { return float64(sideEffects.Stats().Dead) })
+	if signerPool != nil {
+		recorder.Counter("sui.tx_signed_per_second", "Transactions signed by the signer pool", func() uint64 {
+			var signed uint64
+			for _, signer := range signerPool.Stats() {
+				signed += signer.Signed
+			}
+			return signed
+		})
+	}
+	rpcTotals := func(field func(sui.EndpointStatus) uint64) func() uint64 {
+		return func() uint64 {
+			var total uint64
+			for _, endpoint := range suiClient.EndpointStatuses() {
+				total += field(endpoint)
+			}
+			return total
+		}
+	}
+	recorder.Counter("sui.rpc_requests_per_second", "Sui RPC requests", rpcTotals(func(e sui.EndpointStatus) uint64 { return e.TotalRequests }))
+	recorder.Counter("sui.rpc_errors_per_second", "Failed Sui RPC requests", rpcTotals(func(e sui.EndpointStatus) uint64 { return e.TotalErrors }))
+	recorder.Gauge("sui.rpc_latency_ms", "Average latency of the active Sui RPC endpoint", func() float64 {
+		for _, endpoint := range suiClient.EndpointStatuses() {
+			if endpoint.Active {
+				return float64(endpoint.AvgLatency) / float64(time.Millisecond)
+			}
+		}
+		return 0
+	})
+	if dbCacheLayer != nil {
+		recorder.Gauge("db.connections_in_use", "Primary database connections in use", func() float64 { return float64(dbCacheLayer.Stats().Primary.InUse) })
+	}
+	return recorder
+}
+
+// newTreasuryLedger opens the ledger of collected fees. Without it, fees are
+// still charged but left out of the treasury report.
+func newTreasuryLedger(cfg *configs.Config) *treasury.Ledger {
+	var store treasury.Store = &treasury.MemoryStore{}
+	if cfg.Treasury.LedgerFile != "" {
+		store = treasury.FileStore{Path: cfg.Treasury.LedgerFile}
+	}
+	ledger, err := treasury.Open(store, cfg.Treasury.RecentEntries)
+	if err != nil {
+		utils.LogErrorf("Failed to open the treasury ledger: %v. Fees will not be recorded.", err)
+		return nil
+	}
+	return ledger
+}
+
+// newMailService opens the players' mailboxes. Mail is off (nil) if they
+// cannot be loaded.
+func newMailService(cfg *configs.Config) *mail.Service {
+	var store mail.Store = &mail.MemoryStore{}
+	if cfg.Mail.StateFile != "" {
+		store = mail.FileStore{Path: cfg.Mail.StateFile}
+	}
+	mailService, err := mail.NewService(store, mail.Options{MaxPerPlayer: cfg.Mail.MaxPerPlayer})
+	if err != nil {
+		utils.LogErrorf("Failed to open mailboxes: %v. Mail is disabled.", err)
+		return nil
+	}
+	return mailService
+}
+
+// newGuildCalendar opens the guilds' event calendars. Guild events are off
+// (nil) without a guild roster or if the calendars cannot be loaded.
+func newGuildCalendar(cfg *configs.Config, roster *guilds.Roster) *calendar.Service {
+	if roster == nil {
+		return nil
+	}
+	var store calendar.Store = &calendar.MemoryStore{}
+	if cfg.GuildEvents.StateFile != "" {
+		store = calendar.FileStore{Path: cfg.GuildEvents.StateFile}
+	}
+	service, err := calendar.NewService(store, roster, calendar.Options{
+		ReminderLead: time.Duration(cfg.GuildEvents.ReminderMinutes) * time.Minute,
+		MaxCapacity:  cfg.GuildEvents.MaxCapacity,
+		MaxAhead:     time.Duration(cfg.GuildEvents.MaxDaysAhead) * 24 * time.Hour,
+		MaxScheduled: cfg.GuildEvents.MaxScheduledPerGuild,
+	})
+	if err != nil {
+		utils.LogErrorf("Failed to open guild calendars: %v. Guild events are disabled.", err)
+		return nil
+	}
+	return service
+}
+
+// newDiplomacy opens the alliances and rivalries between guilds. Diplomacy is
+// off (nil) without a guild roster or if its state cannot be loaded.
+func newDiplomacy(cfg *configs.Config, roster *guilds.Roster) *diplomacy.Service {
+	if roster == nil || !cfg.Diplomacy.Enabled {
+		return nil
+	}
+	var store diplomacy.Store = &diplomacy.MemoryStore{}
+	if cfg.Diplomacy.StateFile != "" {
+		store = diplomacy.FileStore{Path: cfg.Diplomacy.StateFile}
+	}
+	service, err := diplomacy.NewService(store, roster, diplomacy.Options{
+		MaxGuilds:   cfg.Diplomacy.MaxAllianceGuilds,
+		ProposalTTL: time.Duration(cfg.Diplomacy.ProposalHours) * time.Hour,
+	})
+	if err != nil {
+		utils.LogErrorf("Failed to load guild diplomacy: %v. Alliances are disabled.", err)
+		return nil
+	}
+	return service
+}
+
+// newRecruitment sets up the guild recruitment board, on which the guilds of
+// roster find members.
+func newRecruitment(cfg *configs.Config, roster *guilds.Roster) *recruitment.Service {
+	if roster == nil || !cfg.Recruitment.Enabled {
+		return nil
+	}
+	var store recruitment.Store = &recruitment.MemoryStore{}
+	if cfg.Recruitment.StateFile != "" {
+		store = recruitment.FileStore{Path: cfg.Recruitment.StateFile}
+	}
+	service, err := recruitment.NewService(store, roster, recruitment.Options{
+		ListingTTL:          time.Duration(cfg.Recruitment.ListingDays) * 24 * time.Hour,
+		ApplicationTTL:      time.Duration(cfg.Recruitment.ApplicationDays) * 24 * time.Hour,
+		MaxOpenApplications: cfg.Recruitment.MaxOpenApplications,
+		MaxPendingPerGuild:  cfg.Recruitment.MaxPendingPerGuild,
+	})
+	if err != nil {
+		utils.LogErrorf("Failed to load guild recruitment: %v. Recruitment is disabled.", err)
+		return nil
+	}
+	return service
+}
+
+// moveAllianceChannels has every world move the online members of guilds that
+// joined or left an alliance to their new alliance channel.
+func moveAllianceChannels(actorSystem *actor.ActorSystem, directory *worlds.Directory) func(guildIDs []string) {
+	return func(guildIDs []string) {
+		for _, world := range directory.Worlds() {
+			actorSystem.Root.Send(world.WorldManagerPID, &messages.AllianceChanged{GuildIDs: guildIDs})
+		}
+	}
+}
+
+// newGuildRanks sets up the guilds' permission matrices. Guild invites,
+// kicks, bank withdrawals and rank changes come with the guild contract call
+// to sign when the guild package is configured.
+func newGuildRanks(cfg *configs.Config, roster *guilds.Roster, suiClient *sui.SuiClient, accountLinks *accountlink.Service) *guildranks.Service {
+	if roster == nil || !cfg.GuildRanks.Enabled {
+		return nil
+	}
+	var store guildranks.Store = &guildranks.MemoryStore{}
+	if cfg.GuildRanks.StateFile != "" {
+		store = guildranks.FileStore{Path: cfg.GuildRanks.StateFile}
+	}
+	service, err := guildranks.NewService(store, roster, guildranks.Options{
+		MaxCustomRanks:        cfg.GuildRanks.MaxCustomRanks,
+		OfficerWithdrawPerDay: cfg.GuildRanks.OfficerWithdrawPerDay,
+	})
+	if err != nil {
+		utils.LogErrorf("Failed to load guild ranks: %v. Guild ranks are disabled.", err)
+		return nil
+	}
+	if cfg.Sui.GuildPackageID != "" {
+		service.UseChain(suiGuildRanks{guilds: sui.NewGuildSystemSuiService(suiClient, cfg.Sui.GuildPackageID, cfg.Sui.GuildModule), gasBudget: cfg.Sui.GasBudget}, accountLinks)
+	} else {
+		utils.LogWarnf("No guild package ID. Guild invites, kicks, withdrawals and rank changes are checked but not made on chain.")
+	}
+	return service
+}
+
+// suiGuildRanks adapts sui.GuildSystemSuiService to guildranks.Chain. The
+// acting player pays the gas of the transactions they sign.
+type suiGuildRanks struct {
+	guilds    *sui.GuildSystemSuiService
+	gasBudget uint64
+}
+
+func (g suiGuildRanks) BuildAddMember(ctx context.Context, signer, guildObjectID, player string) (string, error) {
+	tx, err := g.guilds.AddMember(signer, guildObjectID, player, "", g.gasBudget)
+	return tx.TxBytes, err
+}
+
+func (g suiGuildRanks) BuildRemoveMember(ctx context.Context, signer, guildObjectID, player string) (string, error) {
+	tx, err := g.guilds.RemoveMember(signer, guildObjectID, player, "", g.gasBudget)
+	return tx.TxBytes, err
+}
+
+func (g suiGuildRanks) BuildSetRank(ctx context.Context, signer, guildObjectID, member, onChainRank string) (string, error) {
+	change := g.guilds.DemoteMember
+	if onChainRank == guildranks.ChainOfficer {
+		change = g.guilds.PromoteMember
+	}
+	tx, err := change(signer, guildObjectID, member, onChainRank, "", g.gasBudget)
+	return tx.TxBytes, err
+}
+
+func (g suiGuildRanks) BuildWithdraw(ctx context.Context, signer, guildObjectID string, amount uint64) (string, error) {
+	tx, err := g.guilds.ManageGuildBank(signer, guildObjectID, "", amount, "withdraw_game_coin_from_bank", "", g.gasBudget)
+	return tx.TxBytes, err
+}
+
+func newDeepLinks(cfg *configs.Config) *deeplink.Service {
+	secret := ""
+	if cfg.DeepLinks.SecretEnvVar != "" {
+		secret = os.Getenv(cfg.DeepLinks.SecretEnvVar)
+	}
+	if secret == "" {
+		utils.LogInfo("No deep link secret configured. Deep links are disabled.")
+		return nil
+	}
+	service, err := deeplink.NewService([]byte(secret), deeplink.Options{
+		TTL:    time.Duration(cfg.DeepLinks.TTLMinutes) * time.Minute,
+		MaxTTL: time.Duration(cfg.DeepLinks.MaxTTLHours) * time.Hour,
+	})
+	if err != nil {
+		utils.LogFatalf("Invalid deep link config: %v", err)
+	}
+	return service
+}
+
+// tradeFeeSchedule reads the trade fees of a region from the balance values.
+// Escrowed trades pay their fees on-chain, so they are free without a
+// treasury address to pay them to.
+func tradeFeeSchedule(cfg *configs.Config, balanceService *balance.Service) trade.FeeSchedule {
+	if cfg.Treasury.Address == "" {
+		utils.LogWarn("treasury.address is not set. Escrowed trades and marketplace purchases are free of fees.")
+	}
+	return func(region string) balance.Fees {
+		fees := balanceService.Values().Fees.In(region)
+		if cfg.Treasury.Address == "" {
+			fees.Trade = balance.FeeRate{}
+		}
+		return fees
+	}
+}
+
+// registerPremiumMinter mints premium shop items from the outbox and publishes
+// item.minted. Without a minter address the purchases stay queued.
+func registerPremiumMinter(box *outbox.Outbox, cfg *configs.Config, suiClient *sui.SuiClient, keyManager *keys.Manager, eventBus *events.Bus) {
+	if cfg.Shop.MinterAddress == "" || cfg.Shop.MinterGasObjectID == "" {
+		utils.LogWarn("shop.minterAddress or shop.minterGasObjectId is not set. Premium purchases will stay queued in the outbox.")
+		return
+	}
+	items := sui.NewItemNFTService(suiClient, cfg.Sui.ItemSystemPackageID, cfg.Shop.ItemModule, cfg.Shop.MinterAddress, cfg.Shop.MinterGasObjectID)
+	box.Handle(shop.PremiumMintKind, func(ctx context.Context, payload json.RawMessage) error {
+		var purchase shop.PremiumPurchase
+		if err := json.Unmarshal(payload, &purchase); err != nil {
+			return err
+		}
+		privateKey, err := keyManager.PrivateKey()
+		if err != nil {
+			return err
+		}
+		recipient := purchase.Address
+		if recipient == "" {
+			recipient = purchase.PlayerID // Queued before account linking, when player IDs were addresses
+		}
+		metadata := map[string]interface{}{"name": purchase.Name, "shop": purchase.ShopID, "paymentTx": purchase.TxDigest}
+		resp, err := items.MintItemNFTAndExecute(purchase.ItemID, metadata, recipient, cfg.Sui.GasBudget, privateKey)
+		if err != nil {
+			return err
+		}
+		eventBus.Publish(events.TopicItemMinted, events.ItemMinted{ItemType: purchase.ItemID, Owner: recipient, Source: "shop", TxDigest: resp.Digest})
+		mintReceipt(eventBus, "shop", purchase.PlayerID, purchase.Name, resp.Digest)
+		return nil
+	})
+	reportMintFailures(box, eventBus, shop.PremiumMintKind, "shop", func(purchase shop.PremiumPurchase) (string, string) { return purchase.PlayerID, purchase.Name })
+}
+
+// registerTrophyMinter mints arena season trophies from the outbox with the
+// item NFT service to each player's primary linked address and publishes
+// item.minted. Without a minter address the trophies stay queued; a trophy of
+// a player without a linked address is retried until they link one or the
+// outbox gives up.
+func registerTrophyMinter(box *outbox.Outbox, cfg *configs.Config, suiClient *sui.SuiClient, keyManager *keys.Manager, eventBus *events.Bus, accountLinks *accountlink.Service) {
+	if cfg.Arena.MinterAddress == "" || cfg.Arena.MinterGasObjectID == "" {
+		utils.LogWarn("arena.minterAddress or arena.minterGasObjectId is not set. Season trophies will stay queued in the outbox.")
+		return
+	}
+	items := sui.NewItemNFTService(suiClient, cfg.Sui.ItemSystemPackageID, cfg.Arena.ItemModule, cfg.Arena.MinterAddress, cfg.Arena.MinterGasObjectID)
+	box.Handle(arena.TrophyMintKind, func(ctx context.Context, payload json.RawMessage) error {
+		var reward arena.TrophyReward
+		if err := json.Unmarshal(payload, &reward); err != nil {
+			return err
+		}
+		privateKey, err := keyManager.PrivateKey()
+		if err != nil {
+			return err
+		}
+		recipient, err := accountLinks.Address(ctx, reward.PlayerID)
+		if err != nil {
+			return fmt.Errorf("trophy for %s: %w", reward.PlayerID, err)
+		}
+		metadata := map[string]interface{}{"season": reward.Season, "rank": reward.Rank, "rating": reward.Rating}
+		resp, err := items.MintItemNFTAndExecute(reward.Trophy, metadata, recipient, cfg.Sui.GasBudget, privateKey)
+		if err != nil {
+			return err
+		}
+		eventBus.Publish(events.TopicItemMinted, events.ItemMinted{ItemType: reward.Trophy, Owner: recipient, Source: "arena", TxDigest: resp.Digest})
+		mintReceipt(eventBus, "arena", reward.PlayerID, reward.Trophy, resp.Digest)
+		return nil
+	})
+	reportMintFailures(box, eventBus, arena.TrophyMintKind, "arena", func(reward arena.TrophyReward) (string, string) { return reward.PlayerID, reward.Trophy })
+}
+
+// newFairRolls builds the roller of the high-stakes loot tables in
+// fairLoot.tables, or returns nil if fairLoot is off.
+func newFairRolls(cfg *configs.Config) *fairroll.Service {
+	if !cfg.FairLoot.Enabled {
+		return nil
+	}
+	utils.LogInfof("Fair loot enabled. Tables %v are rolled from committed seeds.", cfg.FairLoot.Tables)
+	return fairroll.NewService(cfg.FairLoot.Tables)
+}
+
+// registerLootCommitmentLogger logs every loot commitment on chain through
+// the outbox when fairLoot.eventLogPackageId is set. Commitments are queued
+// before their seed is used; the transaction may land after the roll, but its
+// hash still has to match the seed revealed to the player.
+func registerLootCommitmentLogger(box *outbox.Outbox, cfg *configs.Config, suiClient *sui.SuiClient, keyManager *keys.Manager, fairRolls *fairroll.Service) {
+	if fairRolls == nil || cfg.FairLoot.EventLogPackageID == "" {
+		return
+	}
+	if cfg.FairLoot.SenderAddress == "" || cfg.FairLoot.SenderGasObjectID == "" {
+		utils.LogWarn("fairLoot.senderAddress or fairLoot.senderGasObjectId is not set. Loot commitments are sent to players only.")
+		return
+	}
+	eventLog := sui.NewEventLogSuiService(suiClient, cfg.FairLoot.EventLogPackageID, cfg.FairLoot.EventLogModule, cfg.FairLoot.SenderAddress, cfg.FairLoot.SenderGasObjectID)
+	box.Handle(fairroll.CommitmentKind, func(ctx context.Context, payload json.RawMessage) error {
+		var c fairroll.Commitment
+		if err := json.Unmarshal(payload, &c); err != nil {
+			return err
+		}
+		privateKey, err := keyManager.PrivateKey()
+		if err != nil {
+			return err
+		}
+		_, err = eventLog.LogGameEventAndExecute(sui.GameEventData{
+			EventType:      fairroll.CommitmentKind,
+			Timestamp:      c.At.Unix(),
+			EventCreator:   cfg.FairLoot.SenderAddress,
+			RelatedObjects: []string{c.PlayerID},
+			Payload:        map[string]interface{}{"commitmentId": c.ID, "hash": c.Hash},
+		}, cfg.Sui.GasBudget, privateKey)
+		return err
+	})
+	fairRolls.UsePublisher(outboxCommitments{box: box})
+}
+
+// outboxCommitments queues loot commitments for registerLootCommitmentLogger.
+type outboxCommitments struct {
+	box *outbox.Outbox
+}
+
+func (p outboxCommitments) PublishCommitment(c fairroll.Commitment) error {
+	_, err := p.box.Enqueue(c.ID, fairroll.CommitmentKind, c)
+	return err
+}
+
+// mintReceipt publishes chain.tx_completed for a mint of item for playerID,
+// so they get a TX_RECEIPT or a mail.
+func mintReceipt(bus *events.Bus, source, playerID, item, digest string) {
+	bus.Publish(events.TopicTxCompleted, events.TxCompleted{PlayerIDs: []string{playerID}, Kind: source, Digest: digest, Status: events.TxSuccess, Summary: item + " minted"})
+}
+
+// reportMintFailures publishes a failed chain.tx_completed when box gives up
+// on a mint of kind. describe reads the player and item from the payload.
+func reportMintFailures[T any](box *outbox.Outbox, bus *events.Bus, kind, source string, describe func(T) (playerID, item string)) {
+	box.OnGiveUp(kind, func(payload json.RawMessage, err error) {
+		var mint T
+		if json.Unmarshal(payload, &mint) != nil {
+			return
+		}
+		playerID, item := describe(mint)
+		bus.Publish(events.TopicTxCompleted, events.TxCompleted{PlayerIDs: []string{playerID}, Kind: source, Status: events.TxFailure, Summary: item + " could not be minted"})
+	})
+}
+
+// newFeatureFlags loads the feature flags and their admin overrides. Flags for
+// subsystems this build does not have stay off.
+// newStatusService builds the public /status report from the status config,
+// the worlds' session counts and the arena queues.
+func newStatusService(cfg *configs.Config, worldDirectory *worlds.Directory, arenaService *arena.Service) *status.Service {
+	maintenance := make([]status.Window, 0, len(cfg.Status.Maintenance))
+	for _, w := range cfg.Status.Maintenance {
+		maintenance = append(maintenance, status.Window{Start: w.Start, End: w.End, Message: w.Message})
+	}
+	opts := status.Options{
+		Name:        cfg.Status.Name,
+		Version:     version,
+		Region:      cfg.Status.Region,
+		MaxAge:      time.Duration(cfg.Status.MaxAgeSeconds) * time.Second,
+		Maintenance: maintenance,
+		Players:     worldDirectory.Sessions,
+		Worlds: func() []status.World {
+			var list []status.World
+			for _, world := range worldDirectory.Worlds() {
+				list = append(list, status.World{ID: world.ID, Name: world.Name, Region: world.Region, Players: world.Sessions()})
+			}
+			return list
+		},
+	}
+	if arenaService != nil {
+		opts.Queue = arenaService.Queued
+	}
+	return status.New(opts)
+}
+
+// newAPITokens loads the API tokens of the HTTP gateway, or returns nil if
+// they are off.
+func newAPITokens(cfg *configs.Config) *apitoken.Registry {
+	if cfg.APITokens.File == "" {
+		return nil
+	}
+	registry, err := apitoken.NewRegistry(apitoken.FileStore{Path: cfg.APITokens.File}, ratelimit.PerMinute(cfg.APITokens.DefaultPerMinute, cfg.APITokens.DefaultBurst))
+	if err != nil {
+		utils.LogFatalf("Failed to load API tokens: %v", err)
+	}
+	utils.LogInfof("API tokens enabled: %d in %s.", len(registry.Tokens()), cfg.APITokens.File)
+	return registry
+}
+
+// registerReadGateway returns the mux for the public read endpoints, served
+// on mux behind the API token gateway when tokens are on.
+func registerReadGateway(mux *http.ServeMux, cfg *configs.Config, apiTokens *apitoken.Registry) *http.ServeMux {
+	if apiTokens == nil {
+		return mux
+	}
+	readMux := http.NewServeMux()
+	gateway := apitoken.Gateway{Tokens: apiTokens, Scope: readScope, Anonymous: !cfg.APITokens.RequireForReads}.Wrap(readMux)
+	for _, pattern := range []string{"/status", "/arena/", "/marketplace/"} {
+		mux.Handle(pattern, gateway)
+	}
+	return readMux
+}
+
+// readScope is the API token scope a public read endpoint needs.
+func readScope(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/marketplace/") {
+		return apitoken.ScopeReadMarket
+	}
+	return apitoken.ScopeReadStatus
+}
+
+func newFeatureFlags(cfg *configs.Config) *features.Registry {
+	flags, err := features.New(cfg.Features.Flags, features.FileStore{Path: cfg.Features.OverridesFile})
+	if err != nil {
+		utils.LogFatalf("Failed to load feature flags: %v", err)
+	}
+	for _, state := range flags.List() {
+		if state.Configured && !state.Available {
+			utils.LogWarnf("Feature %s is enabled in the config but not available in this build. It stays off.", state.Name)
+		}
+	}
+	return flags
+}
+
+// newAirdropService sets up bulk item mints for the admin API, minted by
+// the airdrop minter in chunks of one programmable transaction each. Every
+// minted item is published as item.minted. It returns nil without a minter.
+func newAirdropService(cfg *configs.Config, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, eventBus *events.Bus) *airdrop.Service {
+	if cfg.Airdrops.MinterAddress == "" || cfg.Airdrops.MinterGasObjectID == "" {
+		utils.LogInfo("airdrops.minterAddress or airdrops.minterGasObjectId is not set. Airdrops are off.")
+		return nil
+	}
+	items := sui.NewItemNFTService(suiClient, cfg.Sui.ItemSystemPackageID, cfg.Airdrops.ItemModule, cfg.Airdrops.MinterAddress, cfg.Airdrops.MinterGasObjectID)
+	service, err := airdrop.NewServiceFromConfig(cfg.Airdrops, box, func(ctx context.Context, mints []airdrop.Mint, gasBudget uint64) (string, error) {
+		privateKey, err := keyManager.PrivateKey()
+		if err != nil {
+			return "", err
+		}
+		batch := make([]sui.ItemMint, len(mints))
+		for i, mint := range mints {
+			batch[i] = sui.ItemMint{ItemType: mint.ItemType, Metadata: mint.Metadata, Owner: mint.Recipient}
+		}
+		resp, err := items.MintItemNFTBatchAndExecute(batch, gasBudget, privateKey)
+		if err != nil {
+			return "", err
+		}
+		for _, mint := range mints {
+			eventBus.Publish(events.TopicItemMinted, events.ItemMinted{ItemType: mint.ItemType, Owner: mint.Recipient, Source: "airdrop", TxDigest: resp.Digest})
+		}
+		return resp.Digest, nil
+	})
+	if err != nil {
+		utils.LogFatalf("Failed to set up airdrops: %v", err)
+	}
+	return service
+}
+
+// newGiftService sets up player gifts. Gifts that need the recipient's
+// acceptance are held by the custody address when one is configured;
+// otherwise the sender keeps the item until the recipient accepts.
+func newGiftService(cfg *configs.Config, suiClient *sui.SuiClient, box *outbox.Outbox, keyManager *keys.Manager, accountLinks *accountlink.Service) *gift.Service {
+	if !cfg.Gift.Enabled {
+		return nil
+	}
+	chain := suiGifts{client: suiClient, keys: keyManager, custody: cfg.Gift.CustodyAddress, gasObjectID: cfg.Gift.CustodyGasObjectID, gasBudget: cfg.Sui.GasBudget}
+	giftService, err := gift.NewServiceFromConfig(cfg.Gift, chain, accountLinks)
+	if err != nil {
+		utils.LogErrorf("Failed to set up gifts: %v. Gifting is disabled.", err)
+		return nil
+	}
+	if cfg.Gift.CustodyAddress != "" && cfg.Gift.CustodyGasObjectID != "" {
+		giftService.UseCustody(cfg.Gift.CustodyAddress, box)
+		utils.LogInfof("Gifts enabled. Gifts worth more than %d MIST are held by %s until accepted.", cfg.Gift.AcceptThresholdMist, cfg.Gift.CustodyAddress)
+	} else {
+		utils.LogInfof("Gifts enabled. Gifts worth more than %d MIST stay with the sender until accepted (gift.custodyAddress is not set).", cfg.Gift.AcceptThresholdMist)
+	}
+	giftService.Start()
+	return giftService
+}
+
+// suiGifts adapts sui.SuiClient to gift.Chain, signing custody transfers with the server key.
+type suiGifts struct {
+	client      *sui.SuiClient
+	keys        *keys.Manager
+	custody     string // Address of the server key holding gifts in custody
+	gasObjectID string // Custody's gas coin
+	gasBudget   uint64
+}
+
+func (g suiGifts) Owner(ctx context.Context, objectID string) (string, string, error) {
+	return g.client.ObjectOwner(objectID)
+}
+
+func (g suiGifts) BuildTransfer(ctx context.Context, sender, objectID, recipient string) (string, error) {
+	tx, err := g.client.BuildObjectTransfer(sender, objectID, recipient, "", g.gasBudget)
+	return tx.TxBytes, err
+}
+
+func (g suiGifts) VerifyTransfer(ctx context.Context, txDigest, sender, objectID, recipient string) error {
+	return g.client.VerifyObjectTransfer(txDigest, sender, objectID, recipient)
+}
+
+func (g suiGifts) Transfer(ctx context.Context, objectID, recipient string) (string, error) {
+	privateKey, err := g.keys.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+	resp, err := g.client.TransferObjectWithServerKey(g.custody, objectID, recipient, g.gasObjectID, g.gasBudget, privateKey)
+	return resp.Digest, err
+}
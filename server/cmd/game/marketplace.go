@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// marketplaceGate serves the read-only marketplace endpoints while the
+// marketplace feature flag is on. The marketplace manager is created when the
+// flag turns on and closed when it turns off.
+type marketplaceGate struct {
+	configFile string
+	items      *reservation.Registry
+
+	mu              sync.Mutex
+	manager         *sui.MarketplaceServiceManager
+	config          *configs.MarketplaceConfig // The running manager's
+	listingEvent    string                     // Move event type of new listings
+	feeRate         func() balance.FeeRate     // Server fee on purchases; nil charges none
+	treasuryAddress string
+	ledger          *treasury.Ledger
+	bus             *events.Bus              // Receives the marketplace's sales
+	epochs          *sui.EpochTracker        // For removing expired listings on-chain
+	onExpired       func(sui.ExpiredListing) // Told when a listing expires
+	cleanupKey      func() (string, error)   // Key of the marketplace config's cleanup_address
+	expiryLeader    func() bool              // Whether this instance notifies sellers and removes expired listings
+	expiryEnabled   bool                     // UseExpiry was called
+}
+
+// newMarketplaceGate starts the marketplace if the flag is on and follows the
+// flag from then on. The marketplace reserves NFTs and listings in items.
+func newMarketplaceGate(configFile string, flags *features.Registry, items *reservation.Registry) *marketplaceGate {
+	gate := &marketplaceGate{configFile: configFile, items: items}
+	gate.setEnabled(flags.Enabled(features.Marketplace))
+	flags.OnChange(features.Marketplace, gate.setEnabled)
+	return gate
+}
+
+func (g *marketplaceGate) setEnabled(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !enabled {
+		if g.manager != nil {
+			g.manager.Close()
+			g.manager, g.config = nil, nil
+			utils.LogInfo("Marketplace disabled.")
+		}
+		return
+	}
+	if g.manager != nil {
+		return
+	}
+	config, err := loadMarketplaceConfig(g.configFile)
+	if err != nil {
+		utils.LogErrorf("Marketplace flag is on, but %v. The marketplace stays off.", err)
+		return
+	}
+	manager, err := sui.NewMarketplaceServiceManager(config)
+	if err != nil {
+		utils.LogErrorf("Failed to start the marketplace: %v. The marketplace stays off.", err)
+		return
+	}
+	manager.UseReservations(g.items)
+	if g.feeRate != nil {
+		manager.UseFees(g.feeRate, g.treasuryAddress, g.ledger)
+	}
+	manager.UseEvents(g.bus)
+	if g.expiryEnabled {
+		manager.UseExpiry(g.listingExpiry(config))
+	}
+	g.manager = manager
+	g.config = config
+	g.listingEvent = fmt.Sprintf("%s::%s::ListingCreated", config.PackageID, config.Module)
+	utils.LogInfof("Marketplace enabled for package %s.", config.PackageID)
+}
+
+// UseFees charges purchases the server fee rate returns, paid to
+// treasuryAddress and recorded in ledger. It applies to the running
+// marketplace and to any started later.
+func (g *marketplaceGate) UseFees(rate func() balance.FeeRate, treasuryAddress string, ledger *treasury.Ledger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.feeRate, g.treasuryAddress, g.ledger = rate, treasuryAddress, ledger
+	if g.manager != nil {
+		g.manager.UseFees(rate, treasuryAddress, ledger)
+	}
+}
+
+// UseEvents publishes the sales of the running marketplace, and of any
+// started later, on bus.
+func (g *marketplaceGate) UseEvents(bus *events.Bus) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.bus = bus
+	if g.manager != nil {
+		g.manager.UseEvents(bus)
+	}
+}
+
+// UseExpiry hides listings past their duration and calls onExpired for each,
+// and removes them on-chain once epochs reports the chain allows it, signed
+// with cleanupKey. Only while leader reports true does this instance call
+// onExpired and remove listings. It applies to the running marketplace and to
+// any started later.
+func (g *marketplaceGate) UseExpiry(epochs *sui.EpochTracker, cleanupKey func() (string, error), leader func() bool, onExpired func(sui.ExpiredListing)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.epochs, g.cleanupKey, g.expiryLeader, g.onExpired, g.expiryEnabled = epochs, cleanupKey, leader, onExpired, true
+	if g.manager != nil {
+		g.manager.UseExpiry(g.listingExpiry(g.config))
+	}
+}
+
+func (g *marketplaceGate) listingExpiry(config *configs.MarketplaceConfig) sui.ListingExpiry {
+	expiry := sui.ListingExpiry{Epochs: g.epochs, OnExpired: g.onExpired, Leader: g.expiryLeader}
+	if config.CleanupAddress != "" && config.CleanupGasObjectID != "" {
+		expiry.Cleanup = &sui.CleanupSigner{
+			Address:     config.CleanupAddress,
+			GasObjectID: config.CleanupGasObjectID,
+			GasBudget:   config.DefaultGasBudget,
+			PrivateKey:  g.cleanupKey,
+		}
+	} else {
+		utils.LogInfo("Marketplace: No cleanup_address. Expired listings are hidden but stay on-chain until their sellers cancel them.")
+	}
+	return expiry
+}
+
+// notifyListingExpired mails the player linked to an expired listing's seller address.
+func notifyListingExpired(mailService *mail.Service, links accountlink.Store, expired sui.ExpiredListing) {
+	if mailService == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	playerID, err := links.Owner(ctx, expired.Listing.Seller)
+	if err != nil {
+		utils.LogWarnf("Marketplace: Could not look up the player of seller %s: %v", expired.Listing.Seller, err)
+		return
+	}
+	if playerID == "" {
+		return // Not one of our players
+	}
+	mailService.Send(mail.Message{
+		To:      playerID,
+		Kind:    "market.listing_expired",
+		Subject: "Your marketplace listing expired",
+		Body: fmt.Sprintf("Your listing of NFT %s for %d expired on %s and is no longer shown to buyers. "+
+			"The NFT is returned to your wallet once the listing is removed on-chain, or you can cancel the listing yourself.",
+			expired.Listing.NFTID, expired.Listing.Price, expired.ExpiredAt.UTC().Format("2006-01-02 15:04 MST")),
+		Ref: expired.Listing.ID,
+	})
+}
+
+// Close stops the marketplace, if it is running.
+func (g *marketplaceGate) Close() {
+	g.setEnabled(false)
+}
+
+func (g *marketplaceGate) current() (*sui.MarketplaceServiceManager, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.manager, g.listingEvent
+}
+
+// RegisterHandlers adds the marketplace endpoints to mux. They answer 503
+// while the marketplace is off.
+//
+//	GET /marketplace/info                      fees and totals of the marketplace object
+//	GET /marketplace/listings?limit=&cursor=   newest listings first
+func (g *marketplaceGate) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/marketplace/info", func(w http.ResponseWriter, r *http.Request) {
+		manager, _ := g.current()
+		if manager == nil {
+			writeMarketplaceError(w, http.StatusServiceUnavailable, errors.New("the marketplace is not enabled"))
+			return
+		}
+		info, err := manager.GetMarketplaceInfo()
+		if err != nil {
+			utils.LogErrorf("Marketplace: failed to read the marketplace: %v", err)
+			writeMarketplaceError(w, http.StatusBadGateway, errors.New("could not read the marketplace"))
+			return
+		}
+		writeMarketplaceJSON(w, info)
+	})
+	mux.HandleFunc("/marketplace/listings", func(w http.ResponseWriter, r *http.Request) {
+		manager, listingEvent := g.current()
+		if manager == nil {
+			writeMarketplaceError(w, http.StatusServiceUnavailable, errors.New("the marketplace is not enabled"))
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 100 {
+			limit = 50
+		}
+		var cursor *string
+		if c := r.URL.Query().Get("cursor"); c != "" {
+			cursor = &c
+		}
+		listings, next, err := manager.GetListings(listingEvent, limit, cursor)
+		if err != nil {
+			utils.LogErrorf("Marketplace: failed to read listings: %v", err)
+			writeMarketplaceError(w, http.StatusBadGateway, errors.New("could not read listings"))
+			return
+		}
+		writeMarketplaceJSON(w, map[string]interface{}{"listings": listings, "nextCursor": next})
+	})
+}
+
+// loadMarketplaceConfig reads and validates the marketplace contract settings.
+func loadMarketplaceConfig(path string) (*configs.MarketplaceConfig, error) {
+	config, err := configs.LoadMarketplaceConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func writeMarketplaceJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMarketplaceError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
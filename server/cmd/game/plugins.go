@@ -0,0 +1,9 @@
+package main
+
+// Plugins compiled into the server (see internal/plugins). A deployment adds
+// its own plugin packages with another file of blank imports like this one,
+// and leaves a plugin out by dropping its import or listing it in
+// plugins.disabled.
+import (
+	_ "github.com/phuhao00/suigserver/server/plugins/analytics"
+)
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/apitoken"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/battlepass"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/clientversion"
+	"github.com/phuhao00/suigserver/server/internal/cosmetics"
+	"github.com/phuhao00/suigserver/server/internal/crafting"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/gamedata"
+	"github.com/phuhao00/suigserver/server/internal/keys"
+	"github.com/phuhao00/suigserver/server/internal/onboarding"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/selfcheck"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/territory"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+	"github.com/phuhao00/suigserver/server/internal/webhooks"
+)
+
+// runValidate loads the configuration, runs the self-checks and prints the
+// report in format ("text" or "json"). It returns the process exit code: 1 if
+// any check failed.
+func runValidate(configPath, format string) int {
+	var suite *selfcheck.Suite
+	cfg, err := configs.LoadConfig(configPath)
+	if cfg == nil { // Invalid values are reported by the checks below
+		suite = &selfcheck.Suite{}
+		suite.Add("config", "file", func(context.Context) (string, error) {
+			return "", fmt.Errorf("cannot load %s: %w", configPath, err)
+		})
+	} else {
+		utils.SetLogLevel("ERROR") // Keep the report readable
+		suite = newSelfChecks(cfg)
+	}
+	report := suite.Run(context.Background())
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// runStartupChecks runs the self-checks before the server starts, logs every
+// problem, and stops the server if one fails and server.strictStartup is set.
+func runStartupChecks(cfg *configs.Config) {
+	report := newSelfChecks(cfg).Run(context.Background())
+	for _, result := range report.Checks {
+		switch result.Status {
+		case selfcheck.StatusFail:
+			utils.LogErrorf("Self-check failed: %s %s: %s", result.Category, result.Name, result.Detail)
+		case selfcheck.StatusWarn:
+			utils.LogWarnf("Self-check warning: %s %s: %s", result.Category, result.Name, result.Detail)
+		}
+	}
+	if !report.OK && cfg.Server.StrictStartup {
+		utils.LogFatalf("%d self-check(s) failed and server.strictStartup is set. Run with -validate for the full report.", report.Failures)
+	}
+	utils.LogInfof("Self-check: %d checks, %d failed, %d warnings.", len(report.Checks), report.Failures, report.Warnings)
+}
+
+// newSelfChecks lists the configuration, connectivity and port checks for cfg.
+func newSelfChecks(cfg *configs.Config) *selfcheck.Suite {
+	suite := &selfcheck.Suite{}
+	mintsItems := cfg.MintsItems()
+
+	// --- Configuration ---
+	// Every invalid value is its own failed check, named after the setting.
+	var invalid *configs.ValidationError
+	if errors.As(cfg.Validate(), &invalid) {
+		for _, violation := range invalid.Violations {
+			problem := violation.Problem
+			suite.Add("config", violation.Field, func(context.Context) (string, error) {
+				return "", errors.New(problem)
+			})
+		}
+	} else {
+		suite.Add("config", "values", func(context.Context) (string, error) {
+			return "ports, URLs, addresses and bounds are valid", nil
+		})
+	}
+	// Package IDs that enabled features need are checked by Validate; the rest
+	// are only reported.
+	suite.Add("config", "sui.itemSystemPackageId", packageIDCheck(cfg.Sui.ItemSystemPackageID))
+	suite.Add("config", "sui.gameLogicPackageId", packageIDCheck(cfg.Sui.GameLogicPackageID))
+	suite.Add("config", "sui.playerRegistryPackageId", packageIDCheck(cfg.Sui.PlayerRegistryPackageID))
+	suite.Add("config", "sui.playerObjectPackageId", packageIDCheck(cfg.Sui.PlayerObjectPackageID))
+	for _, world := range cfg.WorldList() {
+		world := world
+		suite.Add("config", "worlds."+world.ID+".guildPackageId", func(context.Context) (string, error) {
+			if _, err := os.Stat(world.ZonesFile); err != nil {
+				return "territory is off in this world", nil
+			}
+			return packageIDCheck(world.GuildPackageID)(context.Background())
+		})
+	}
+	suite.Add("config", "sui.keySource", func(ctx context.Context) (string, error) {
+		provider, err := keys.NewProviderFromConfig(cfg.Sui.KeySource, cfg.Sui.PrivateKey)
+		if err != nil {
+			return "", err
+		}
+		key, err := provider.LoadKey(ctx)
+		if err != nil {
+			if mintsItems {
+				return "", fmt.Errorf("%s: %v (needed for item mints)", provider.Name(), err)
+			}
+			return "", selfcheck.Warnf("%s: %v; server-side transactions are not possible", provider.Name(), err)
+		}
+		return fmt.Sprintf("%s, fingerprint %s", provider.Name(), keys.Fingerprint(key)), nil
+	})
+	suite.Add("config", "redis.fallback.policies", func(context.Context) (string, error) {
+		for useCase, name := range cfg.Redis.Fallback.Policies {
+			if _, err := game.ParseCachePolicy(name); err != nil {
+				return "", fmt.Errorf("%s: %v", useCase, err)
+			}
+		}
+		return fmt.Sprintf("%d cache use cases", len(cfg.Redis.Fallback.Policies)), nil
+	})
+	suite.Add("config", "auth", func(context.Context) (string, error) {
+		if cfg.Auth.EnableDummyAuth {
+			return "", selfcheck.Warnf("dummy authentication is enabled")
+		}
+		return "dummy authentication is off", nil
+	})
+	suite.Add("config", "clientVersions", func(context.Context) (string, error) {
+		v := cfg.ClientVersions
+		if _, err := clientversion.New(clientversion.Options{Min: v.Min, Max: v.Max, UpgradeURL: v.UpgradeURL, RequireVersion: v.RequireVersion}); err != nil {
+			return "", err
+		}
+		if v.Min == "" && v.Max == "" {
+			return "every client version is allowed", nil
+		}
+		if v.Min != "" && v.UpgradeURL == "" {
+			return "", selfcheck.Warnf("clients older than %s are refused without an upgradeUrl", v.Min)
+		}
+		return fmt.Sprintf("client versions from %q to %q are allowed", v.Min, v.Max), nil
+	})
+	suite.Add("config", "build.chaos", func(context.Context) (string, error) {
+		if chaos.Available {
+			return "", selfcheck.Warnf("built with the chaos tag; faults can be injected through the admin API")
+		}
+		return "fault injection is not built in", nil
+	})
+	suite.Add("config", "leaderElection", func(context.Context) (string, error) {
+		switch cfg.LeaderElection.Backend {
+		case "":
+			return "off; every instance runs every worker", nil
+		case "memory", "redis", "postgres":
+		default:
+			return "", fmt.Errorf("unknown backend %q (want redis, postgres or memory)", cfg.LeaderElection.Backend)
+		}
+		for _, worker := range cfg.LeaderElection.Workers {
+			switch worker {
+			case "listingExpiry", "playerArchive":
+			case "outbox":
+				if cfg.LeaderElection.Backend != "memory" {
+					return "", selfcheck.Warnf("outbox is elected, but each instance keeps its own outbox file; followers' messages wait until they lead")
+				}
+			default:
+				return "", fmt.Errorf("unknown worker %q (want listingExpiry, outbox or playerArchive)", worker)
+			}
+		}
+		return fmt.Sprintf("%s lease for %s", cfg.LeaderElection.Backend, strings.Join(cfg.LeaderElection.Workers, ", ")), nil
+	})
+	suite.Add("config", "admin.tokenEnvVar", func(context.Context) (string, error) {
+		if cfg.Admin.TokenEnvVar == "" || os.Getenv(cfg.Admin.TokenEnvVar) == "" {
+			return "", selfcheck.Warnf("no admin token is set; admin endpoints are disabled")
+		}
+		return cfg.Admin.TokenEnvVar + " is set", nil
+	})
+	suite.Add("config", "apiTokens.file", func(context.Context) (string, error) {
+		if cfg.APITokens.File == "" {
+			return "API tokens are off", nil
+		}
+		tokens, err := apitoken.FileStore{Path: cfg.APITokens.File}.Load()
+		if err != nil {
+			return "", err
+		}
+		live := 0
+		for _, token := range tokens {
+			if err := apitoken.ValidateScopes(token.Scopes); err != nil {
+				return "", fmt.Errorf("token %s: %v", token.ID, err)
+			}
+			if !token.Revoked() {
+				live++
+			}
+		}
+		if cfg.APITokens.RequireForReads && live == 0 {
+			return "", selfcheck.Warnf("reads require an API token, but no live token exists")
+		}
+		return fmt.Sprintf("%d live token(s)", live), nil
+	})
+	suite.Add("config", "airdrops", func(context.Context) (string, error) {
+		if cfg.Airdrops.MinterAddress == "" || cfg.Airdrops.MinterGasObjectID == "" {
+			return "airdrops are off", nil
+		}
+		if cfg.Airdrops.GasPerMint > cfg.Airdrops.MaxGasBudget {
+			return "", fmt.Errorf("gasPerMint %d exceeds maxGasBudget %d", cfg.Airdrops.GasPerMint, cfg.Airdrops.MaxGasBudget)
+		}
+		perTx := cfg.Airdrops.MaxMintsPerTx
+		if cfg.Airdrops.GasPerMint > 0 && cfg.Airdrops.MaxGasBudget/cfg.Airdrops.GasPerMint < uint64(perTx) {
+			perTx = int(cfg.Airdrops.MaxGasBudget / cfg.Airdrops.GasPerMint)
+		}
+		return fmt.Sprintf("up to %d mints per transaction", perTx), nil
+	})
+	suite.Add("config", "webhooks", func(context.Context) (string, error) {
+		service, err := webhooks.NewServiceFromConfig(cfg.Webhooks, outbox.New(outbox.NewMemoryStore(), outbox.Options{}))
+		if err != nil {
+			return "", err
+		}
+		if service == nil {
+			return "none configured", nil
+		}
+		return fmt.Sprintf("%d endpoint(s)", len(cfg.Webhooks.Endpoints)), nil
+	})
+	suite.Add("config", "anticheat", func(context.Context) (string, error) {
+		ac := cfg.Anticheat
+		switch {
+		case ac.MaxSpeed <= 0:
+			return "", selfcheck.Warnf("speed checks are off; set anticheat.maxSpeed to catch speedhacks")
+		case ac.SpeedTolerance < 0:
+			return "", errors.New("anticheat.speedTolerance must not be negative")
+		case ac.KickAfter > 0 && ac.FlagAfter > ac.KickAfter:
+			return "", selfcheck.Warnf("players are disconnected after %d violations, before they are reported after %d", ac.KickAfter, ac.FlagAfter)
+		}
+		return fmt.Sprintf("at most %.1f units/s, disconnected after %d violations", ac.MaxSpeed, ac.KickAfter), nil
+	})
+	suite.Add("config", "balance.file", func(context.Context) (string, error) {
+		balanceService, err := balance.Open(cfg.Balance.File, &balance.MemoryHistory{}, game.ValidateBalance)
+		if err != nil {
+			return "", err
+		}
+		if _, statErr := os.Stat(cfg.Balance.File); statErr != nil {
+			return "", selfcheck.Warnf("%s not found; built-in defaults are used", cfg.Balance.File)
+		}
+		return fmt.Sprintf("%d loot tables", len(balanceService.Values().Loot.Tables)), nil
+	})
+	suite.Add("config", "gameData", func(context.Context) (string, error) {
+		_, report := gamedata.NewWatcher(gamedata.Sources{MapsDir: cfg.Movement.MapsDir, SkillsFile: cfg.Movement.SkillsFile, NPCFile: cfg.NPCs.File})
+		if err := report.Err(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d maps, %d movement abilities, %d projectiles, %d NPCs", report.Maps, report.Abilities, report.Projectiles, report.NPCs), nil
+	})
+	suite.Add("config", "features", func(context.Context) (string, error) {
+		flags, err := features.New(cfg.Features.Flags, features.FileStore{Path: cfg.Features.OverridesFile})
+		if err != nil {
+			return "", err
+		}
+		var enabled, problems []string
+		for _, state := range flags.List() {
+			switch {
+			case state.Enabled:
+				enabled = append(enabled, state.Name)
+			case state.Configured && !state.Available:
+				problems = append(problems, state.Name+" is not available in this build")
+			}
+		}
+		for name := range cfg.Features.Flags {
+			if !flags.Known(name) {
+				problems = append(problems, "unknown flag "+name)
+			}
+		}
+		if flags.Enabled(features.Marketplace) {
+			if _, err := loadMarketplaceConfig(cfg.Features.MarketplaceConfigFile); err != nil {
+				problems = append(problems, "marketplace: "+err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			return "", selfcheck.Warnf("%s", strings.Join(problems, "; "))
+		}
+		if len(enabled) == 0 {
+			return "no optional features enabled", nil
+		}
+		return "enabled: " + strings.Join(enabled, ", "), nil
+	})
+	suite.Add("config", "onboarding.tutorialFile", optionalFileCheck(cfg.Onboarding.TutorialFile, func(path string) (string, error) {
+		def, err := onboarding.LoadDefinition(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d steps", len(def.Steps)), nil
+	}))
+	suite.Add("config", "shop.catalogFile", optionalFileCheck(cfg.Shop.CatalogFile, func(path string) (string, error) {
+		catalog, err := shop.LoadCatalog(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d shops", len(catalog.Shops)), nil
+	}))
+	suite.Add("config", "crafting.recipesFile", optionalFileCheck(cfg.Crafting.RecipesFile, func(path string) (string, error) {
+		recipes, err := crafting.LoadRecipes(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d recipes", len(recipes)), nil
+	}))
+	suite.Add("config", "battlePass.file", optionalFileCheck(cfg.BattlePass.File, func(path string) (string, error) {
+		def, err := battlepass.LoadDefinition(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d seasons", len(def.Seasons)), nil
+	}))
+	suite.Add("config", "cosmetics.file", optionalFileCheck(cfg.Cosmetics.File, func(path string) (string, error) {
+		catalog, err := cosmetics.LoadCatalog(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d cosmetics in %d slots", len(catalog.Cosmetics), len(catalog.Slots)), nil
+	}))
+	suite.Add("config", "territory.zonesFile", optionalFileCheck(cfg.Territory.ZonesFile, func(path string) (string, error) {
+		zones, err := territory.LoadZones(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d zones", len(zones)), nil
+	}))
+
+	// --- Connectivity ---
+	suite.Add("connectivity", "postgres", dbCheck(cfg, (*game.DBCacheLayer).PingDB))
+	suite.Add("connectivity", "redis", dbCheck(cfg, (*game.DBCacheLayer).PingRedis))
+	if cfg.Database.ReplicaURL != "" {
+		suite.Add("connectivity", "postgres-replica", dbCheck(cfg, func(dbCacheLayer *game.DBCacheLayer, ctx context.Context) error {
+			if err := dbCacheLayer.UseReadReplica(cfg.Database.ReplicaURL); err != nil {
+				return err
+			}
+			return dbCacheLayer.PingReplica(ctx)
+		}))
+	}
+	for i, url := range append([]string{cfg.Sui.RPCURL}, cfg.Sui.FallbackRPCURLs...) {
+		i, url, name := i, url, "sui.rpcUrl"
+		if i > 0 {
+			name = "sui.fallbackRpcUrls[" + strconv.Itoa(i-1) + "]"
+		}
+		suite.Add("connectivity", name, func(ctx context.Context) (string, error) {
+			checkpoint, err := sui.NewSuiClient(url).GetLatestCheckpointSequenceNumber(ctx)
+			if err != nil {
+				if i > 0 {
+					return "", selfcheck.Warnf("%s: %v", url, err)
+				}
+				return "", fmt.Errorf("%s: %w", url, err)
+			}
+			return fmt.Sprintf("%s at checkpoint %d", url, checkpoint), nil
+		})
+	}
+
+	// --- Ports ---
+	suite.Add("ports", "server.tcpPort", portCheck(cfg.Server.Host, cfg.Server.TCPPort))
+	suite.Add("ports", "server.httpPort", portCheck(cfg.Server.Host, cfg.Server.HTTPPort))
+	return suite
+}
+
+// dbCheck pings PostgreSQL or Redis through a short-lived DB cache layer.
+func dbCheck(cfg *configs.Config, ping func(*game.DBCacheLayer, context.Context) error) selfcheck.CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if cfg.Database.PostgresURL == "" || !redisConfigured(cfg) {
+			return "", selfcheck.Warnf("database.postgresUrl or redis.address is not set; data is kept in memory")
+		}
+		dbCacheLayer, err := game.NewDBCacheLayerFromURL(cfg.Database.PostgresURL, redisConfig(cfg))
+		if err != nil {
+			return "", err
+		}
+		defer dbCacheLayer.Stop()
+		if err := ping(dbCacheLayer, ctx); err != nil {
+			return "", err
+		}
+		return "reachable", nil
+	}
+}
+
+// packageIDCheck warns about an unset or placeholder package ID.
+func packageIDCheck(id string) selfcheck.CheckFunc {
+	return func(context.Context) (string, error) {
+		if configs.IsSuiID(id) {
+			return id, nil
+		}
+		problem := fmt.Sprintf("%q is not a deployed package ID", id)
+		if id == "" {
+			problem = "not set"
+		} else if configs.IsPlaceholder(id) {
+			problem = "still the placeholder"
+		}
+		return "", selfcheck.Warnf("%s", problem)
+	}
+}
+
+// optionalFileCheck validates a feature file with load. A missing file turns
+// the feature off, which is reported but not a problem.
+func optionalFileCheck(path string, load func(path string) (string, error)) selfcheck.CheckFunc {
+	return func(context.Context) (string, error) {
+		if path == "" {
+			return "not set; the feature is off", nil
+		}
+		detail, err := load(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path + " not found; the feature is off", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return detail, nil
+	}
+}
+
+// portCheck verifies that nothing else is listening on host:port.
+func portCheck(host string, port int) selfcheck.CheckFunc {
+	return func(context.Context) (string, error) {
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return "", fmt.Errorf("%s is not available: %w", address, err)
+		}
+		listener.Close()
+		return address + " is free", nil
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/transfer"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+	"github.com/phuhao00/suigserver/server/internal/worlds"
+)
+
+// onlineTimeout bounds the lookup of a player's session before a transfer.
+const onlineTimeout = 2 * time.Second
+
+// newTransferService sets up player transfers between shards, covering player
+// data and linked addresses. Players cannot be moved while online or while
+// they have trades in escrow.
+func newTransferService(cfg *configs.Config, dbCacheLayer *game.DBCacheLayer, accountLinks *accountlink.Service, tradeService *trade.Service, giftService *gift.Service, worldDirectory *worlds.Directory, root *actor.RootContext, suiClient *sui.SuiClient) *transfer.Service {
+	shard := cfg.Transfer.Shard
+	if shard == "" {
+		shard = cfg.Status.Region
+	}
+	var names transfer.Names
+	var players *game.PlayerDataTransferSection
+	if dbCacheLayer != nil {
+		players = &game.PlayerDataTransferSection{DB: dbCacheLayer}
+		names = players
+	} else {
+		utils.LogWarn("No DB cache layer. Player transfers will not cover player data.")
+	}
+	service := transfer.NewService(shard, accountLinks.Store(), suiClient, names)
+	if players != nil {
+		service.AddSection(players)
+	}
+	service.AddSection(accountlink.TransferSection{Store: accountLinks.Store()})
+	service.AddGuard(onlineGuard{directory: worldDirectory, root: root})
+	if tradeService != nil {
+		service.AddGuard(tradeService)
+	}
+	if giftService != nil {
+		service.AddGuard(giftService)
+	}
+	return service
+}
+
+// onlineGuard refuses to transfer players who are online on this server.
+type onlineGuard struct {
+	directory *worlds.Directory
+	root      *actor.RootContext
+}
+
+// Name implements transfer.Guard.
+func (g onlineGuard) Name() string { return "sessions" }
+
+// CheckTransfer implements transfer.Guard.
+func (g onlineGuard) CheckTransfer(_ context.Context, playerID string) error {
+	_, err := g.directory.PlayerDiagnostics(g.root, playerID, onlineTimeout)
+	if errors.Is(err, worlds.ErrPlayerOffline) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not tell whether the player is online: %w", err)
+	}
+	return errors.New("player is online")
+}
@@ -0,0 +1,44 @@
+// Command keytool creates the encrypted key files read by the "file" key source.
+//
+//	SUI_PRIVATE_KEY=... SUI_KEY_PASSPHRASE=... go run ./server/cmd/keytool -out sui-key.json
+//
+// If the key variable is unset the key is read from stdin.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/keys"
+)
+
+func main() {
+	out := flag.String("out", "sui-key.json", "Path of the encrypted key file to write")
+	keyEnv := flag.String("key-env", "SUI_PRIVATE_KEY", "Environment variable holding the private key (stdin is used if unset)")
+	passphraseEnv := flag.String("passphrase-env", "SUI_KEY_PASSPHRASE", "Environment variable holding the encryption passphrase")
+	flag.Parse()
+
+	passphrase := os.Getenv(*passphraseEnv)
+	if passphrase == "" {
+		log.Fatalf("Passphrase environment variable %s is not set", *passphraseEnv)
+	}
+
+	privateKey := os.Getenv(*keyEnv)
+	if privateKey == "" {
+		log.Println("Reading private key from stdin...")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Failed to read private key from stdin: %v", err)
+		}
+		privateKey = line
+	}
+	privateKey = strings.TrimSpace(privateKey)
+
+	if err := keys.WriteEncryptedKeyFile(*out, privateKey, passphrase); err != nil {
+		log.Fatalf("Failed to write encrypted key file: %v", err)
+	}
+	log.Printf("Encrypted key file written to %s (fingerprint %s)", *out, keys.Fingerprint(privateKey))
+}
@@ -0,0 +1,65 @@
+// Command mapconv converts a level editor export into the map format the
+// server loads collision data from, one file per zone.
+//
+//	go run ./server/cmd/mapconv -in plaza.export.json -out configs/maps/plaza.json
+//	go run ./server/cmd/mapconv -in plaza.glb -id plaza -out configs/maps/plaza.json
+//
+// .gltf and .glb files are read as glTF scenes; anything else as the simple
+// JSON export. See the geometry package for both formats.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+func main() {
+	in := flag.String("in", "", "Path of the export to convert")
+	out := flag.String("out", "", "Path of the map file to write (stdout if unset)")
+	id := flag.String("id", "", "Map ID; defaults to the export's own ID, then to the output file name")
+	flag.Parse()
+	if *in == "" {
+		log.Fatal("-in is required")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *in, err)
+	}
+	var m *geometry.Map
+	switch strings.ToLower(filepath.Ext(*in)) {
+	case ".gltf", ".glb":
+		m, err = geometry.FromGLTF(data, filepath.Dir(*in))
+	default:
+		m, err = geometry.FromExport(data)
+	}
+	if err != nil {
+		log.Fatalf("Failed to convert %s: %v", *in, err)
+	}
+	switch {
+	case *id != "":
+		m.ID = *id
+	case m.ID == "" && *out != "":
+		m.ID = strings.TrimSuffix(filepath.Base(*out), filepath.Ext(*out))
+	}
+
+	encoded, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode map: %v", err)
+	}
+	encoded = append(encoded, '\n')
+	if *out == "" {
+		os.Stdout.Write(encoded)
+		return
+	}
+	if err := os.WriteFile(*out, encoded, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	log.Printf("Map %s written to %s (%d ground areas, %d obstacles)", m.ID, *out, len(m.Ground), len(m.Obstacles))
+}
@@ -0,0 +1,67 @@
+// Command schemagen exports the wire protocol defined in pkg/protocol as a JSON
+// schema for client teams.
+//
+//	go run ./server/cmd/schemagen                 # regenerate pkg/protocol/schema.json
+//	go run ./server/cmd/schemagen -check          # fail if the change breaks the committed schema
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+func main() {
+	out := flag.String("out", "pkg/protocol/schema.json", "Path of the schema file to write or check")
+	check := flag.Bool("check", false, "Check the current protocol against the schema file instead of writing it")
+	flag.Parse()
+
+	generated := protocol.GenerateSchema()
+	data, err := protocol.MarshalSchema(generated)
+	if err != nil {
+		log.Fatalf("Failed to marshal protocol schema: %v", err)
+	}
+
+	if !*check {
+		if err := os.WriteFile(*out, data, 0644); err != nil {
+			log.Fatalf("Failed to write protocol schema to %s: %v", *out, err)
+		}
+		log.Printf("Protocol schema v%d written to %s (%d message types)", generated.Version, *out, len(generated.Messages))
+		return
+	}
+
+	if err := checkSchema(*out, generated); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Protocol schema %s is up to date", *out)
+}
+
+// checkSchema reports whether the protocol, as generated, breaks the schema
+// at path or differs from it. pkg/protocol is its own module, so this is what
+// fails the server's own tests on a breaking change.
+func checkSchema(path string, generated *protocol.Schema) error {
+	existingData, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read protocol schema %s: %v", path, err)
+	}
+	existing, err := protocol.ParseSchema(existingData)
+	if err != nil {
+		return fmt.Errorf("failed to parse protocol schema %s: %v", path, err)
+	}
+	if problems := protocol.CheckCompatibility(existing, generated); len(problems) > 0 {
+		return fmt.Errorf("protocol changes break the exported schema in %s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	data, err := protocol.MarshalSchema(generated)
+	if err != nil {
+		return fmt.Errorf("failed to marshal protocol schema: %v", err)
+	}
+	if !bytes.Equal(existingData, data) {
+		return fmt.Errorf("protocol schema %s is out of date; run 'go run ./server/cmd/schemagen' to regenerate it", path)
+	}
+	return nil
+}
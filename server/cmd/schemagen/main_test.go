@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// TestSchemaIsCurrent fails the server's build when a protocol change breaks
+// clients generated from the committed schema.json, or leaves it stale.
+func TestSchemaIsCurrent(t *testing.T) {
+	if err := checkSchema("../../../pkg/protocol/schema.json", protocol.GenerateSchema()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -12,12 +12,18 @@ import (
 
 func main() {
 	var port = flag.Int("port", 8080, "Port to run the server on")
+	var protocolFlag = flag.String("protocol", "text", "Wire protocol: 'text' (slash commands) or 'json' (ClientServerMessage, newline or length-prefixed)")
 	flag.Parse()
 
+	protocolMode, err := simple.ParseProtocolMode(*protocolFlag)
+	if err != nil {
+		log.Fatalf("Invalid -protocol flag: %v", err)
+	}
+
 	log.Println("Starting Simple Game Server...")
 
 	// Create and start server
-	server := simple.NewSimpleServer(*port)
+	server := simple.NewSimpleServer(*port, protocolMode)
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
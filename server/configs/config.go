@@ -5,6 +5,7 @@ import (
 	"log" // Standard log for initial messages before custom logger is configured
 	"os"
 	"sync"
+	"time"
 	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
 )
 
@@ -15,19 +16,40 @@ type Config struct {
 		TCPPort int    `json:"tcpPort"`
 		HTTPPort int   `json:"httpPort"` // For potential admin/metrics endpoints
 		LogLevel string `json:"logLevel"`
+		StrictStartup bool `json:"strictStartup"` // Refuse to start when a startup self-check fails (see -validate)
+		ShutdownRetryAfterSeconds int `json:"shutdownRetryAfterSeconds"` // Sent to clients at shutdown as how long to wait before reconnecting; 0 leaves it out
 	} `json:"server"`
 	Database struct {
 		PostgresURL string `json:"postgresUrl"`
+		ReplicaURL  string `json:"replicaUrl"` // Optional read replica for read-heavy queries such as chat history
+		MaxOpenConns int `json:"maxOpenConns"` // Per pool; 0 is unlimited
+		MaxIdleConns int `json:"maxIdleConns"`
+		ConnMaxLifetimeSeconds int `json:"connMaxLifetimeSeconds"` // Connections are replaced after this long; 0 keeps them
+		ConnMaxIdleTimeSeconds int `json:"connMaxIdleTimeSeconds"` // Idle connections are closed after this long; 0 keeps them
+		QueryTimeoutSeconds int `json:"queryTimeoutSeconds"` // Bound on each query; 0 leaves it to the caller
+		ArchiveInactiveDays int `json:"archiveInactiveDays"` // Players who have not logged in for this long move to players_archive until they return; 0 never archives
+		ArchiveIntervalMinutes int `json:"archiveIntervalMinutes"` // How often inactive players are looked for
+		ArchiveBatchSize int `json:"archiveBatchSize"` // Players moved per statement
 	} `json:"database"`
 	Redis struct {
-		Address  string `json:"address"`
+		Mode     string `json:"mode"` // "single" (default), "sentinel" or "cluster"
+		Address  string `json:"address"` // Single mode
+		Addresses []string `json:"addresses"` // Sentinel or cluster node addresses
+		MasterName string `json:"masterName"` // Sentinel mode
+		SentinelPassword string `json:"sentinelPassword"`
 		Password string `json:"password"`
 		DB       int    `json:"db"`
+		Fallback RedisFallbackConfig `json:"fallback"` // What each cache use case does while Redis is down
 	} `json:"redis"`
 	Sui struct {
 		RPCURL         string `json:"rpcUrl"`
+		FallbackRPCURLs []string `json:"fallbackRpcUrls"` // Tried in order when the primary RPC node is unhealthy
+		HealthCheckIntervalSeconds int `json:"healthCheckIntervalSeconds"` // RPC endpoint probe interval
+		EpochPollSeconds int `json:"epochPollSeconds"` // How often the current epoch is checked; 0 turns epoch tracking off
 		WebsocketURL   string `json:"websocketUrl"` // For event subscriptions
-		PrivateKey     string `json:"privateKey"`   // Server's private key for transactions (handle with care!)
+		PrivateKey     string `json:"privateKey"`   // Server's private key for transactions (handle with care!). Prefer keySource.
+		KeySource      KeySourceConfig `json:"keySource"` // Where the signing key is loaded from
+		SignerPool     SignerPoolConfig `json:"signerPool"` // Extra server addresses for parallel transactions
 		GasBudget      uint64 `json:"gasBudget"`
 		// Placeholder Contract package IDs - replace with actual IDs after deployment
 		GameLogicPackageID      string `json:"gameLogicPackageId"`
@@ -35,15 +57,534 @@ type Config struct {
 		ItemSystemPackageID     string `json:"itemSystemPackageId"`
 		PlayerObjectPackageID   string `json:"playerObjectPackageId"` // For player profile/data objects
 		PlayerObjectModule      string `json:"playerObjectModule"`    // Module name for player profile/data
+		GuildPackageID          string `json:"guildPackageId"`        // Package containing the guild module; territory claims need it
+		GuildModule             string `json:"guildModule"`
 	} `json:"sui"`
 	Auth struct {
 		DummyToken      string `json:"dummyToken"`
 		DummyPlayerID   string `json:"dummyPlayerId"`
 		EnableDummyAuth bool   `json:"enableDummyAuth"` // To easily switch it off
 	} `json:"auth"`
+	ClientVersions struct {
+		Min            string `json:"min"`            // Oldest client version allowed to authenticate, e.g. "1.4.0"; older ones get UPGRADE_REQUIRED. Any if empty
+		Max            string `json:"max"`            // Newest client version allowed, for clients released ahead of the server; any if empty
+		UpgradeURL     string `json:"upgradeUrl"`     // Sent to refused old clients, e.g. the store page
+		RequireVersion bool   `json:"requireVersion"` // Clients that report no version are refused as older than min
+	} `json:"clientVersions"`
+	Onboarding struct {
+		TutorialFile string `json:"tutorialFile"` // Tutorial steps and gates; onboarding is off if the file is missing
+	} `json:"onboarding"`
+	Movement struct {
+		SkillsFile            string `json:"skillsFile"`            // Skill data with the movement abilities (dash, jump, teleport) and projectiles; none if the file is missing
+		MapsDir               string `json:"mapsDir"`               // Collision data, one JSON file per map; rooms name theirs with mapId
+		ReloadIntervalSeconds int    `json:"reloadIntervalSeconds"` // How often the maps, skills and NPC files are checked for edits; 0 reloads only on admin request
+	} `json:"movement"`
+	Anticheat struct {
+		MaxSpeed               float64 `json:"maxSpeed"`               // Fastest plain movement in map units per second; 0 turns the speed check off
+		SpeedWindowMs          int     `json:"speedWindowMs"`          // Moves are summed over this window, so brief bursts from lag are averaged out
+		SpeedTolerance         float64 `json:"speedTolerance"`         // Extra distance allowed for latency and jitter, as a fraction of maxSpeed
+		FlagAfter              int     `json:"flagAfter"`              // Violations within violationWindowSeconds that report a player on the event bus; 0 never reports
+		KickAfter              int     `json:"kickAfter"`              // Violations within violationWindowSeconds that disconnect a player; 0 never kicks
+		ViolationWindowSeconds int     `json:"violationWindowSeconds"` // How long a violation counts towards flagAfter and kickAfter
+	} `json:"anticheat"`
+	NPCs struct {
+		File           string  `json:"file"`           // NPC definitions; no NPCs if the file is missing
+		TickIntervalMs int     `json:"tickIntervalMs"` // How often rooms tick their NPCs
+		PathBudget     int     `json:"pathBudget"`     // Grid cells a room's pathfinding may expand per tick
+		NavCellSize    float64 `json:"navCellSize"`    // Width of the cells of the navigation grids NPCs path on
+	} `json:"npcs"`
+	Projectiles struct {
+		PlayerHealth  int `json:"playerHealth"`  // Health players have in a room; players cannot be hurt by projectiles if 0
+		PlayerDefense int `json:"playerDefense"` // Defense players have against projectiles
+	} `json:"projectiles"`
+	RoomRules struct {
+		MaxXPMultiplier float64 `json:"maxXpMultiplier"` // Largest XP multiplier players may create a room with
+	} `json:"roomRules"`
+	Stats struct {
+		TickIntervalMs int `json:"tickIntervalMs"` // How often players are sent their batched stat changes
+	} `json:"stats"`
+	Idempotency struct {
+		TTLSeconds       int `json:"ttlSeconds"`       // How long the result of a command sent with an idempotency key is kept
+		PendingSeconds   int `json:"pendingSeconds"`   // How long a command may run before a retry with its key runs again
+		MaxKeysPerPlayer int `json:"maxKeysPerPlayer"` // Keys kept per player; the oldest are dropped first
+	} `json:"idempotency"`
+	ClockSync struct {
+		HintIntervalSeconds int `json:"hintIntervalSeconds"` // How often sessions push CLOCK_HINT for clients to check their clock for drift; 0 sends none
+		ToleranceMs         int `json:"toleranceMs"`         // Drift clients should correct with a new TIME_SYNC
+	} `json:"clockSync"`
+	Resume struct {
+		Enabled       bool `json:"enabled"`       // Hand out resume tokens at auth, so clients reconnect without credentials
+		WindowSeconds int  `json:"windowSeconds"` // How long a token stays valid after its session drops
+		MaxAgeHours   int  `json:"maxAgeHours"`   // How long resumes may go on before the player signs in again
+		BindIP        bool `json:"bindIp"`        // Only accept a token from the IP it was issued to
+	} `json:"resume"`
+	Guilds struct {
+		RosterFile string `json:"rosterFile"` // Off-chain guild memberships, for guild chat and events; no guild channels or events if the file is missing
+	} `json:"guilds"`
+	GuildEvents struct {
+		StateFile            string `json:"stateFile"`            // Guild calendars; kept in memory if empty
+		ReminderMinutes      int    `json:"reminderMinutes"`      // How long before an event its attendees are mailed a reminder
+		MaxCapacity          int    `json:"maxCapacity"`          // Most attendees of an event
+		MaxDaysAhead         int    `json:"maxDaysAhead"`         // How far ahead events may be scheduled
+		MaxScheduledPerGuild int    `json:"maxScheduledPerGuild"` // Events a guild may have scheduled at once
+	} `json:"guildEvents"`
+	GuildRanks struct {
+		Enabled               bool   `json:"enabled"`               // Guild leaders define ranks; otherwise the roster's officers have every permission
+		StateFile             string `json:"stateFile"`             // Guild ranks and bank withdrawals; kept in memory if empty
+		MaxCustomRanks        int    `json:"maxCustomRanks"`        // Ranks a guild may add besides leader, officer and member
+		OfficerWithdrawPerDay uint64 `json:"officerWithdrawPerDay"` // Game coin officers may take from the guild bank per day until the leader redefines the rank
+	} `json:"guildRanks"`
+	Diplomacy struct {
+		Enabled           bool   `json:"enabled"`           // Guild leaders form alliances and declare rivalries
+		StateFile         string `json:"stateFile"`         // Alliances, open proposals and rivalries; kept in memory if empty
+		MaxAllianceGuilds int    `json:"maxAllianceGuilds"` // Most guilds in one alliance
+		ProposalHours     int    `json:"proposalHours"`     // How long an alliance proposal stays open
+	} `json:"diplomacy"`
+	Recruitment struct {
+		Enabled             bool   `json:"enabled"`             // Guilds post recruitment listings and review applications
+		StateFile           string `json:"stateFile"`           // Listings and applications; kept in memory if empty
+		ListingDays         int    `json:"listingDays"`         // How long a listing stays up unless posted again
+		ApplicationDays     int    `json:"applicationDays"`     // How long an application waits for a decision
+		MaxOpenApplications int    `json:"maxOpenApplications"` // Pending applications per player
+		MaxPendingPerGuild  int    `json:"maxPendingPerGuild"`  // Pending applications per guild
+	} `json:"recruitment"`
+	Parental struct {
+		Enabled              bool   `json:"enabled"`              // Operators may set playtime limits, curfews, restricted chat and spending caps on accounts
+		StateFile            string `json:"stateFile"`            // Accounts' controls and what they used of them today; kept in memory if empty
+		CheckIntervalSeconds int    `json:"checkIntervalSeconds"` // How often sessions count playtime and check the limits
+		WarnMinutes          []int  `json:"warnMinutes"`          // Players are warned as the time left falls below each
+	} `json:"parental"`
+	DeepLinks struct {
+		SecretEnvVar string `json:"secretEnvVar"` // Variable holding the key deep links are signed with, at least 32 bytes; deep links are off if it is unset
+		TTLMinutes   int    `json:"ttlMinutes"`   // Lifetime of links minted without one
+		MaxTTLHours  int    `json:"maxTtlHours"`  // Longest lifetime a link may be minted with
+		BaseURL      string `json:"baseUrl"`      // Page partner sites link to; minted links come as this URL with the token, if set
+	} `json:"deepLinks"`
+	ChatChannels ChatChannelsConfig `json:"chatChannels"`
+	Social       struct {
+		ActionsPerSecond float64 `json:"actionsPerSecond"` // Emotes, map pings and quick replies a player may send per second; 0 is unlimited
+		Burst            int     `json:"burst"`            // Sent at once before the rate applies
+	} `json:"social"`
+	Territory    struct {
+		ZonesFile            string `json:"zonesFile"`            // Claimable zones with their buffs and tax rates; territory is off if the file is missing
+		SiegeDelaySeconds    int    `json:"siegeDelaySeconds"`    // Time between a contested claim and its siege
+		SiegeDurationSeconds int    `json:"siegeDurationSeconds"` // The defender keeps the zone if no winner is reported in this time
+	} `json:"territory"`
+	Analytics  AnalyticsConfig  `json:"analytics"`
+	Arena      ArenaConfig      `json:"arena"`
+	Shop       ShopConfig       `json:"shop"`
+	Crafting   CraftingConfig   `json:"crafting"`
+	BattlePass BattlePassConfig `json:"battlePass"`
+	Cosmetics  CosmeticsConfig  `json:"cosmetics"`
+	Trade      TradeConfig      `json:"trade"`
+	Gift       GiftConfig       `json:"gift"`
+	OrderBook  OrderBookConfig  `json:"orderBook"`
+	Airdrops   AirdropConfig    `json:"airdrops"`
+	FairLoot   FairLootConfig   `json:"fairLoot"`
+	Features   FeaturesConfig   `json:"features"`
+	Worlds     []WorldConfig    `json:"worlds"` // Game worlds served by this process; one "default" world if empty
+	Status     StatusConfig     `json:"status"`
+	Webhooks   WebhooksConfig   `json:"webhooks"`
+	Plugins    PluginsConfig    `json:"plugins"`
+	Outbox    struct {
+		Path string `json:"path"` // Pending on-chain side effects (e.g. trophy mints); survives restarts
+	} `json:"outbox"`
+	Balance struct {
+		File                  string `json:"file"`                  // Live tunables (combat constants, XP curve, loot tables, fees); edits are picked up while running
+		HistoryFile           string `json:"historyFile"`           // Every version, for the admin API and rollbacks
+		ReloadIntervalSeconds int    `json:"reloadIntervalSeconds"` // How often the file is checked for edits
+	} `json:"balance"`
+	Treasury struct {
+		Address       string `json:"address"`       // Sui address on-chain fees are paid to; escrowed trades and marketplace purchases are free if empty
+		LedgerFile    string `json:"ledgerFile"`    // Fees collected, for the treasury report
+		RecentEntries int    `json:"recentEntries"` // Latest fees listed in the report
+	} `json:"treasury"`
+	LiveOps struct {
+		StateFile string `json:"stateFile"` // Running live events and today's marketplace sales, for the live-ops dashboard
+	} `json:"liveOps"`
+	MetricHistory struct {
+		Enabled         bool   `json:"enabled"`
+		Instance        string `json:"instance"`        // Names this server's samples; defaults to the hostname
+		IntervalSeconds int    `json:"intervalSeconds"` // Between samples of players online, transaction rates and latencies
+		RetentionDays   int    `json:"retentionDays"`   // Older samples are deleted
+	} `json:"metricHistory"`
+	Mail struct {
+		StateFile    string `json:"stateFile"`    // Players' mailboxes; kept in memory if empty
+		MaxPerPlayer int    `json:"maxPerPlayer"` // Messages kept per mailbox; the oldest are dropped beyond it
+	} `json:"mail"`
+	Transfer struct {
+		Shard string `json:"shard"` // Names this server in player transfer bundles; renamed players get it as a suffix. Defaults to status.region
+	} `json:"transfer"`
+	ChatHistory struct {
+		Enabled       bool `json:"enabled"`
+		MaxPerChannel int  `json:"maxPerChannel"` // Messages kept per room or whisper conversation
+		RetentionDays int  `json:"retentionDays"` // Older messages are pruned; 0 keeps them
+		MaxFetch      int  `json:"maxFetch"`      // Most messages one CHAT_HISTORY_REQUEST returns
+	} `json:"chatHistory"`
+	AccountLinks struct {
+		MaxAddresses        int    `json:"maxAddresses"`        // Sui addresses one player may link
+		ChallengeTTLSeconds int    `json:"challengeTtlSeconds"` // How long a link challenge can be signed
+		GameName            string `json:"gameName"`            // Named in the message the wallet shows
+	} `json:"accountLinks"`
+	LoginPrefetch struct {
+		Enabled           bool   `json:"enabled"`
+		WaitMs            int    `json:"waitMs"`            // How long login waits for the chain reads; parts still being read are reported as pending
+		CacheSeconds      int    `json:"cacheSeconds"`      // A player's reads are reused for this long, e.g. across a quick reconnect
+		ProfileObjectType string `json:"profileObjectType"` // Full Move type of the player profile object; not read if empty
+	} `json:"loginPrefetch"`
+	AFK struct {
+		AfterSeconds         int    `json:"afterSeconds"`         // No gameplay (chat does not count) for this long marks a player AFK; 0 turns AFK detection off
+		KickAfterSeconds     int    `json:"kickAfterSeconds"`     // AFK players idle this long are disconnected under high load; 0 never kicks
+		KickMinSessions      int    `json:"kickMinSessions"`      // High load: at least this many players online; 0 kicks regardless of load
+		LobbyRoomID          string `json:"lobbyRoomId"`          // AFK players are moved to this room, created in every world; empty leaves them in place
+		CheckIntervalSeconds int    `json:"checkIntervalSeconds"` // How often sessions are checked
+	} `json:"afk"`
+	Autoscaling struct {
+		PlayersPerCore        int     `json:"playersPerCore"`        // Players one core is sized for; the capacity ceiling is this times GOMAXPROCS
+		TargetCPU             float64 `json:"targetCpu"`             // Share of the cores players may use; the measured cost per player scales capacity to it
+		MaxTickUtilization    float64 `json:"maxTickUtilization"`    // Share of its tick budget the busiest room may use before new players are turned away
+		SampleIntervalSeconds int     `json:"sampleIntervalSeconds"` // How often the load signals are recomputed
+		GateReadiness         bool    `json:"gateReadiness"`         // /readyz fails while the server is not accepting players
+	} `json:"autoscaling"`
+	HotZones struct {
+		Enabled         bool    `json:"enabled"`         // Watch how crowded maps are and adjust their NPC density and overflow instances
+		CellSize        float64 `json:"cellSize"`        // Side of a density grid cell, in map units
+		HotPlayers      int     `json:"hotPlayers"`      // Players in one cell that make the map hot
+		CoolPlayers     int     `json:"coolPlayers"`     // The map cools down once no cell has more players than this
+		SustainChecks   int     `json:"sustainChecks"`   // Checks in a row a map must stay hot, or cool, before it is adjusted
+		IntervalSeconds int     `json:"intervalSeconds"` // How often rooms report and maps are checked
+		NPCStep         float64 `json:"npcStep"`         // NPC density added or taken back per adjustment
+		MaxNPCDensity   float64 `json:"maxNpcDensity"`   // Most NPC density a map is raised to
+	} `json:"hotZones"`
+	Quarantine struct {
+		MaxEntries           int `json:"maxEntries"`           // Undeliverable and failed actor messages kept for the admin API
+		FailureThreshold     int `json:"failureThreshold"`     // Handler panics on one message type that hold back further messages of that type; -1 never holds back
+		FailureWindowSeconds int `json:"failureWindowSeconds"` // Window the panics are counted in
+	} `json:"quarantine"`
+	ActorAudit struct {
+		MaxMessageBytes int `json:"maxMessageBytes"` // Estimated size above which the actor message audit reports a message; the audit runs while the actorMessageAudit flag is on
+	} `json:"actorAudit"`
+	HandlerMetrics struct {
+		SlowThresholdMs int `json:"slowThresholdMs"` // Actor message handlers slower than this are logged with their context; -1 never logs
+	} `json:"handlerMetrics"`
+	LeaderElection struct {
+		Backend      string   `json:"backend"`      // "redis" or "postgres" lease, shared by the instances; "memory" for one instance; empty runs every worker on every instance
+		LeaseSeconds int      `json:"leaseSeconds"` // How long a leader keeps a role after its last renewal; renewed every third of it
+		Workers      []string `json:"workers"`      // Singleton workers that run only on their leader: "listingExpiry", "outbox", "playerArchive", "crafting"
+	} `json:"leaderElection"`
+	Admin struct {
+		TokenEnvVar  string `json:"tokenEnvVar"`  // Variable holding the bearer token for /admin endpoints; they are off if it is empty
+		AuditLogPath string `json:"auditLogPath"` // Append-only log of admin privacy requests
+	} `json:"admin"`
+	APITokens struct {
+		File             string `json:"file"`             // Hashed API tokens, managed with cmd/apitoken; API tokens are off if empty
+		RequireForReads  bool   `json:"requireForReads"`  // /status, /arena/ and /marketplace/ need a token; otherwise they stay public
+		DefaultPerMinute int    `json:"defaultPerMinute"` // Rate limit of tokens without their own
+		DefaultBurst     int    `json:"defaultBurst"`
+	} `json:"apiTokens"`
+	Audit struct {
+		Path string `json:"path"` // Hash-chained log of auth attempts, admin commands, chain transactions and trades; off if empty
+	} `json:"audit"`
+	Delivery struct {
+		Capacity         int `json:"capacity"`         // Unacknowledged messages kept per player; the oldest are dropped beyond it
+		RetentionSeconds int `json:"retentionSeconds"` // How long a disconnected player's messages are kept for a reconnect
+	} `json:"delivery"`
 	// Potentially add other sections like JWT secrets, external API keys, etc.
 }
 
+// RedisFallbackConfig is what the cache does while Redis is down.
+type RedisFallbackConfig struct {
+	Policies               map[string]string `json:"policies"`               // Cache use case ("playerData", "playerNames") -> "bypass", "stale" or "fail"
+	BreakerFailures        int               `json:"breakerFailures"`        // Consecutive Redis errors before Redis is skipped
+	BreakerCooldownSeconds int               `json:"breakerCooldownSeconds"` // How long Redis is skipped before it is tried again
+	StaleEntries           int               `json:"staleEntries"`           // Values kept per use case for "stale", and writes kept for replay
+}
+
+// KeySourceConfig selects the backend the SUI signing key is loaded from.
+type KeySourceConfig struct {
+	Type                  string   `json:"type"`                  // "config" (sui.privateKey, default), "env", "file", "vault" or "command"
+	EnvVar                string   `json:"envVar"`                // type "env": variable holding the key
+	FilePath              string   `json:"filePath"`              // type "file": encrypted key file (see server/cmd/keytool)
+	PassphraseEnvVar      string   `json:"passphraseEnvVar"`      // type "file": variable holding the passphrase
+	VaultAddress          string   `json:"vaultAddress"`          // type "vault": e.g. https://vault:8200
+	VaultTokenEnvVar      string   `json:"vaultTokenEnvVar"`      // type "vault": defaults to VAULT_TOKEN
+	VaultSecretPath       string   `json:"vaultSecretPath"`       // type "vault": e.g. secret/data/suigserver
+	VaultField            string   `json:"vaultField"`            // type "vault": defaults to privateKey
+	Command               []string `json:"command"`               // type "command": e.g. a KMS/secret manager CLI invocation
+	ReloadIntervalSeconds int      `json:"reloadIntervalSeconds"` // Periodic reload for key rotation; 0 disables
+}
+
+// SignerPoolConfig lists server addresses that sign transactions in parallel.
+// Each signer needs its own gas coins; a gas coin is used by one transaction at a time.
+type SignerPoolConfig struct {
+	Selection string         `json:"selection"` // "round_robin" (default) or "least_busy"
+	Signers   []SignerConfig `json:"signers"`
+}
+
+// SignerConfig is one address in the signer pool.
+type SignerConfig struct {
+	Address    string          `json:"address"`
+	GasCoinIDs []string        `json:"gasCoinIds"`
+	KeySource  KeySourceConfig `json:"keySource"` // Type "config" is not supported here
+}
+
+// ChatChannelsConfig sets how each kind of chat channel is joined and
+// throttled.
+type ChatChannelsConfig struct {
+	Global ChatChannelConfig `json:"global"`
+	Trade  ChatChannelConfig `json:"trade"`
+	Zone   ChatChannelConfig `json:"zone"`  // Players in the same zone, i.e. rooms on the same map
+	Guild  ChatChannelConfig `json:"guild"` // Members of the same guild in guilds.rosterFile
+
+	Alliance ChatChannelConfig `json:"alliance"` // Members of the guilds of the same alliance
+}
+
+// ChatChannelConfig is the policy of one kind of chat channel.
+type ChatChannelConfig struct {
+	AutoJoin          bool `json:"autoJoin"`          // Players are put in the channel on login, zone entry or by their guild
+	MessagesPerMinute int  `json:"messagesPerMinute"` // Sustained rate per player; 0 is unlimited
+	Burst             int  `json:"burst"`             // Messages a player may send at once before the rate applies
+}
+
+// AnalyticsConfig controls the gameplay analytics pipeline.
+type AnalyticsConfig struct {
+	Enabled         bool                  `json:"enabled"`
+	BatchSize       int                   `json:"batchSize"`       // Records per sink write
+	FlushIntervalMs int                   `json:"flushIntervalMs"` // Maximum time a record waits for a batch to fill
+	QueueSize       int                   `json:"queueSize"`       // Records buffered before new ones are dropped
+	SampleRates     map[string]float64    `json:"sampleRates"`     // Record name -> fraction kept (0-1); unlisted names are always kept
+	Sinks           []AnalyticsSinkConfig `json:"sinks"`
+}
+
+// AnalyticsSinkConfig is one destination for analytics records.
+type AnalyticsSinkConfig struct {
+	Type           string            `json:"type"`           // "stdout", "file", "http" or "kafka"
+	Path           string            `json:"path"`           // type "file": JSON lines file, appended to
+	URL            string            `json:"url"`            // type "http": webhook; type "kafka": Kafka REST proxy base URL
+	Topic          string            `json:"topic"`          // type "kafka": topic to produce to
+	Headers        map[string]string `json:"headers"`        // type "http"/"kafka": extra request headers, e.g. Authorization
+	TimeoutSeconds int               `json:"timeoutSeconds"` // type "http"/"kafka": request timeout
+}
+
+// ArenaConfig controls ranked PvP.
+type ArenaConfig struct {
+	Enabled                bool              `json:"enabled"`
+	StateFile              string            `json:"stateFile"` // Current season and standings
+	SeasonDays             int               `json:"seasonDays"`
+	KFactor                float64           `json:"kFactor"`                // Elo K-factor: the most rating one match can move
+	RatingWindow           int               `json:"ratingWindow"`           // Rating spread matched immediately
+	WindowGrowthPerSecond  float64           `json:"windowGrowthPerSecond"`  // Extra spread per second in the queue
+	MaxLatencyMs           int               `json:"maxLatencyMs"`           // Players with a slower measured round trip cannot queue; 0 for no limit
+	LatencyWindowMs        int               `json:"latencyWindowMs"`        // Round-trip spread matched immediately; 0 ignores latency
+	LatencyGrowthPerSecond int               `json:"latencyGrowthPerSecond"` // Extra milliseconds of spread per second in the queue
+	MaxLatencySpreadMs     int               `json:"maxLatencySpreadMs"`     // Widest round-trip spread ever matched; 0 for no limit
+	RewardTiers            []ArenaRewardTier `json:"rewardTiers"`            // Best tier first
+	ItemModule             string            `json:"itemModule"`             // Module in sui.itemSystemPackageId that mints trophies
+	MinterAddress          string            `json:"minterAddress"`          // Server address that mints trophies; its key is sui.keySource
+	MinterGasObjectID      string            `json:"minterGasObjectId"`
+}
+
+// ShopConfig controls the NPC shops.
+type ShopConfig struct {
+	CatalogFile       string `json:"catalogFile"`      // Shops and their price tables; shops are off if the file is missing
+	StateFile         string `json:"stateFile"`        // Stock levels, purchase limits and used premium payments
+	StartingCoins     int64  `json:"startingCoins"`    // Soft currency for players without a wallet yet
+	PremiumRecipient  string `json:"premiumRecipient"` // Address premium payments go to; premium items are hidden if empty
+	ItemModule        string `json:"itemModule"`       // Module in sui.itemSystemPackageId that mints premium items
+	MinterAddress     string `json:"minterAddress"`    // Server address that mints premium items; its key is sui.keySource
+	MinterGasObjectID string `json:"minterGasObjectId"`
+}
+
+// CraftingConfig controls the crafting queues. Crafts cost soft currency and
+// items from the shop wallets and mint item NFTs when they finish.
+type CraftingConfig struct {
+	RecipesFile         string `json:"recipesFile"`         // Recipes; crafting is off if the file is missing
+	MaxQueued           int    `json:"maxQueued"`           // Unfinished crafts a player may have queued
+	TickIntervalSeconds int    `json:"tickIntervalSeconds"` // How often finished crafts are finalized, online or not
+	ItemModule          string `json:"itemModule"`          // Module in sui.itemSystemPackageId that mints crafted items
+	MinterAddress       string `json:"minterAddress"`       // Server address that mints crafted items; its key is sui.keySource
+	MinterGasObjectID   string `json:"minterGasObjectId"`
+}
+
+// BattlePassConfig controls the seasonal battle pass. Free and premium tier
+// rewards are defined per season; cosmetics are minted as item NFTs.
+type BattlePassConfig struct {
+	File              string `json:"file"`             // Seasons, tiers and XP sources; the battle pass is off if the file is missing
+	StateFile         string `json:"stateFile"`        // Player progress and used premium payments
+	PremiumRecipient  string `json:"premiumRecipient"` // Address premium pass payments go to; the premium pass is not sold if empty
+	ItemModule        string `json:"itemModule"`       // Module in sui.itemSystemPackageId that mints cosmetics
+	MinterAddress     string `json:"minterAddress"`    // Server address that mints cosmetics; its key is sui.keySource
+	MinterGasObjectID string `json:"minterGasObjectId"`
+}
+
+// CosmeticsConfig controls what players can wear. Cosmetics are item NFTs,
+// checked against the chain when equipped.
+type CosmeticsConfig struct {
+	File                  string `json:"file"`                  // Slots and wearable item types; cosmetics are off if the file is missing
+	ItemObjectType        string `json:"itemObjectType"`        // Full Move type of item NFTs; trade.itemType if empty
+	OwnershipCacheSeconds int    `json:"ownershipCacheSeconds"` // How long the cosmetics an address owns are trusted before the chain is asked again
+}
+
+// TradeConfig controls player-to-player trades and their escrow.
+type TradeConfig struct {
+	Enabled             bool   `json:"enabled"`
+	StateFile           string `json:"stateFile"`           // Open and recently finished trades
+	EscrowThresholdMist uint64 `json:"escrowThresholdMist"` // Trades worth more than this go through escrow
+	ItemValueMist       uint64 `json:"itemValueMist"`       // Estimated value of one item when valuing a trade
+	MaxItems            int    `json:"maxItems"`            // Items per side of a trade
+	ProposalTTLSeconds  int    `json:"proposalTtlSeconds"`  // How long the other player has to answer
+	DepositTTLSeconds   int    `json:"depositTtlSeconds"`   // How long both players have to deposit into escrow
+	EscrowPackageID     string `json:"escrowPackageId"`     // Package with the escrow module; escrowed trades are refused if empty
+	EscrowModule        string `json:"escrowModule"`
+	ItemType            string `json:"itemType"`       // Full Move type of tradable item NFTs
+	ArbiterAddress      string `json:"arbiterAddress"` // Server address that creates and settles escrows; its key is sui.keySource
+	ArbiterGasObjectID  string `json:"arbiterGasObjectId"`
+}
+
+// AirdropConfig controls bulk mints of item NFTs through the admin API.
+type AirdropConfig struct {
+	StateFile         string `json:"stateFile"`         // Airdrops and the digest of every item they minted
+	ItemModule        string `json:"itemModule"`        // Module in sui.itemSystemPackageId that mints the items
+	MinterAddress     string `json:"minterAddress"`     // Server address that mints; its key is sui.keySource. Airdrops are off if empty
+	MinterGasObjectID string `json:"minterGasObjectId"` // Pays for every chunk; must hold at least maxGasBudget
+	GasPerMint        uint64 `json:"gasPerMint"`        // Estimated MIST one mint costs, for sizing chunks
+	MaxGasBudget      uint64 `json:"maxGasBudget"`      // Most MIST one chunk's transaction may budget
+	MaxMintsPerTx     int    `json:"maxMintsPerTx"`     // Most items minted by one programmable transaction
+	MaxMints          int    `json:"maxMints"`          // Most items in one manifest
+}
+
+// FairLootConfig controls commit-reveal rolls of high-stakes loot tables.
+type FairLootConfig struct {
+	Enabled           bool     `json:"enabled"`
+	Tables            []string `json:"tables"`            // Loot tables in the balance file rolled with commitments
+	EventLogPackageID string   `json:"eventLogPackageId"` // Package with the event log module; commitments are also logged on chain if set
+	EventLogModule    string   `json:"eventLogModule"`
+	SenderAddress     string   `json:"senderAddress"` // Server address that logs commitments; its key is sui.keySource
+	SenderGasObjectID string   `json:"senderGasObjectId"`
+}
+
+// GiftConfig controls player-to-player gifts of item NFTs.
+type GiftConfig struct {
+	Enabled             bool              `json:"enabled"`
+	StateFile           string            `json:"stateFile"`           // Open gifts and every player's gift history
+	AcceptThresholdMist uint64            `json:"acceptThresholdMist"` // Gifts worth more than this wait for the recipient to accept
+	ItemValueMist       uint64            `json:"itemValueMist"`       // Estimated value of an item whose type is not in typeValuesMist
+	TypeValuesMist      map[string]uint64 `json:"typeValuesMist"`      // Values by Move type, or by its "module::Name" suffix
+	AcceptTTLSeconds    int               `json:"acceptTtlSeconds"`    // How long the recipient has to answer
+	TransferTTLSeconds  int               `json:"transferTtlSeconds"`  // How long the sender has to sign a transfer
+	HistoryLimit        int               `json:"historyLimit"`        // Finished gifts kept per player
+	CustodyAddress      string            `json:"custodyAddress"`      // Server address holding gifts until accepted; its key is sui.keySource. Off if empty
+	CustodyGasObjectID  string            `json:"custodyGasObjectId"`
+}
+
+// OrderBookConfig sets up the order book for the game's fungible token.
+// Players pay each order's deposit into the custody address, which settles
+// fills and refunds what orders leave unspent. The order book is off if
+// tokenType or custodyAddress is empty.
+type OrderBookConfig struct {
+	StateFile          string `json:"stateFile"`          // Open and recently finished orders, and unsettled fills
+	TokenType          string `json:"tokenType"`          // Coin type of the game token, e.g. "0xpkg::game_coin::GAME_COIN"
+	CustodyAddress     string `json:"custodyAddress"`     // Server address holding deposits; its key is sui.keySource
+	CustodyGasObjectID string `json:"custodyGasObjectId"` // Custody's gas coin; kept out of payouts
+	DepositTTLSeconds  int    `json:"depositTtlSeconds"`  // How long a player has to pay an order's deposit
+	DefaultTTLSeconds  int    `json:"defaultTtlSeconds"`  // How long an order lasts if the player names no TTL
+	MaxTTLSeconds      int    `json:"maxTtlSeconds"`
+	MaxOpenOrders      int    `json:"maxOpenOrders"` // Unfinished orders per player
+	DepthLevels        int    `json:"depthLevels"`   // Most price levels per side in ORDER_BOOK
+}
+
+// PluginsConfig controls the plugins compiled into the server (see
+// internal/plugins). Every compiled-in plugin runs unless disabled here.
+type PluginsConfig struct {
+	Disabled []string                   `json:"disabled"` // Plugin names not to run
+	Settings map[string]json.RawMessage `json:"settings"` // Plugin name -> its own settings, passed to it as they are
+}
+
+// FeaturesConfig sets the feature flags that gate risky or environment-specific
+// subsystems. See internal/features for the flag names.
+type FeaturesConfig struct {
+	Flags                 map[string]bool `json:"flags"`                 // Flags left out keep their built-in default
+	OverridesFile         string          `json:"overridesFile"`         // Admin overrides made while running; they win over flags
+	MarketplaceConfigFile string          `json:"marketplaceConfigFile"` // Marketplace contract settings, read when the marketplace flag turns on
+}
+
+// WebhooksConfig lists the outside endpoints that are notified of events.
+type WebhooksConfig struct {
+	Endpoints []WebhookEndpointConfig `json:"endpoints"`
+}
+
+// WebhookEndpointConfig is one webhook receiver, such as a Discord or Slack channel.
+type WebhookEndpointConfig struct {
+	Name           string   `json:"name"`
+	URL            string   `json:"url"`
+	URLEnvVar      string   `json:"urlEnvVar"`      // Variable holding the URL instead; Discord and Slack URLs contain a secret token
+	SecretEnvVar   string   `json:"secretEnvVar"`   // Variable holding the HMAC key for X-Webhook-Signature; unsigned if empty
+	Format         string   `json:"format"`         // "json" (default), "discord" or "slack"
+	Events         []string `json:"events"`         // Topic patterns, e.g. "market.sold", "territory.*"
+	MinSalePrice   uint64   `json:"minSalePrice"`   // market.sold: only sales at or above this price, in MIST
+	ItemTypes      []string `json:"itemTypes"`      // item.minted: only these item types; empty sends every mint
+	TimeoutSeconds int      `json:"timeoutSeconds"` // Per request; default 10
+}
+
+// WorldConfig describes one game world (shard). Each world has its own room and
+// world managers; empty fields fall back to the top-level settings.
+type WorldConfig struct {
+	ID             string `json:"id"` // Clients send it in the AUTH request as worldId
+	Name           string `json:"name"`
+	Region         string `json:"region,omitempty"`
+	ZonesFile      string `json:"zonesFile,omitempty"`      // Defaults to territory.zonesFile
+	GuildPackageID string `json:"guildPackageId,omitempty"` // Defaults to sui.guildPackageId
+	GuildModule    string `json:"guildModule,omitempty"`    // Defaults to sui.guildModule
+}
+
+// StatusConfig describes the server on the public /status endpoint that
+// launchers and the website poll.
+type StatusConfig struct {
+	Name          string                    `json:"name"`
+	Region        string                    `json:"region"`
+	MaxAgeSeconds int                       `json:"maxAgeSeconds"` // How long CDNs and browsers may cache the response
+	Maintenance   []MaintenanceWindowConfig `json:"maintenance"`   // Scheduled downtime, announced before it starts
+}
+
+// MaintenanceWindowConfig is one scheduled downtime.
+type MaintenanceWindowConfig struct {
+	Start   time.Time `json:"start"` // RFC 3339
+	End     time.Time `json:"end"`
+	Message string    `json:"message,omitempty"`
+}
+
+// WorldList returns the configured worlds with defaults filled in, or a single
+// "default" world if none are configured.
+func (c *Config) WorldList() []WorldConfig {
+	worlds := c.Worlds
+	if len(worlds) == 0 {
+		worlds = []WorldConfig{{ID: "default", Name: "Default"}}
+	}
+	list := make([]WorldConfig, len(worlds))
+	for i, world := range worlds {
+		if world.Name == "" {
+			world.Name = world.ID
+		}
+		if world.ZonesFile == "" {
+			world.ZonesFile = c.Territory.ZonesFile
+		}
+		if world.GuildPackageID == "" {
+			world.GuildPackageID = c.Sui.GuildPackageID
+		}
+		if world.GuildModule == "" {
+			world.GuildModule = c.Sui.GuildModule
+		}
+		list[i] = world
+	}
+	return list
+}
+
+// ArenaRewardTier grants a trophy to players finishing a season at MaxRank or better.
+type ArenaRewardTier struct {
+	MaxRank int    `json:"maxRank"`
+	Trophy  string `json:"trophy"` // Item type of the minted trophy NFT
+}
+
 var (
 	once   sync.Once
 	config *Config
@@ -51,7 +592,10 @@ var (
 )
 
 // LoadConfig loads the configuration from a file (e.g., config.json).
-// It's designed to be called once.
+// It's designed to be called once. ${ENV_VAR} references in string values are
+// resolved first (see interpolate). If the file parses but some values are
+// invalid, the configuration is returned along with a *ValidationError listing
+// all of them (see Config.Validate).
 func LoadConfig(filePath string) (*Config, error) {
 	once.Do(func() {
 		// Use standard log here initially, as our logger's level isn't set yet.
@@ -64,6 +608,14 @@ func LoadConfig(filePath string) (*Config, error) {
 			return
 		}
 
+		// Secrets such as database URLs and keys can be injected with ${ENV_VAR}
+		// references instead of being written into the file.
+		file, err = interpolate(file, osEnvironment)
+		if err != nil {
+			log.Printf("Error in config file %s: %v", filePath, err) // Standard log
+			return
+		}
+
 		cfg := &Config{}
 		// Set default values before unmarshalling
 		setDefaultValues(cfg)
@@ -75,6 +627,10 @@ func LoadConfig(filePath string) (*Config, error) {
 			return
 		}
 		config = cfg
+		if err = cfg.Validate(); err != nil {
+			log.Printf("Config file %s has invalid values", filePath) // Standard log
+			return
+		}
 		log.Println("Configuration loaded successfully.") // Standard log
 	})
 	return config, err
@@ -98,8 +654,24 @@ func setDefaultValues(cfg *Config) {
 	cfg.Server.TCPPort = 8080
 	cfg.Server.HTTPPort = 8081
 	cfg.Server.LogLevel = "INFO"
+	cfg.Server.ShutdownRetryAfterSeconds = 30
+	cfg.Redis.Mode = "single"
+	cfg.Redis.Fallback.Policies = map[string]string{"playerData": "bypass", "playerNames": "fail"}
+	cfg.Redis.Fallback.BreakerFailures = 5
+	cfg.Redis.Fallback.BreakerCooldownSeconds = 10
+	cfg.Redis.Fallback.StaleEntries = 10000
+	cfg.Database.MaxOpenConns = 25
+	cfg.Database.MaxIdleConns = 10
+	cfg.Database.ConnMaxLifetimeSeconds = 1800
+	cfg.Database.ConnMaxIdleTimeSeconds = 300
+	cfg.Database.QueryTimeoutSeconds = 5
+	cfg.Database.ArchiveIntervalMinutes = 60
+	cfg.Database.ArchiveBatchSize = 500
 	cfg.Sui.GasBudget = 100000000 // Default gas budget (adjust as needed)
 	cfg.Sui.RPCURL = "https://fullnode.testnet.sui.io:443" // Default to Sui Testnet
+	cfg.Sui.HealthCheckIntervalSeconds = 15
+	cfg.Sui.EpochPollSeconds = 30
+	cfg.Sui.KeySource.Type = "config"
 	cfg.Sui.GameLogicPackageID = "0xYOUR_GAME_LOGIC_PACKAGE_ID_HERE"
 	cfg.Sui.PlayerRegistryPackageID = "0xYOUR_PLAYER_REGISTRY_PACKAGE_ID_HERE"
 	cfg.Sui.ItemSystemPackageID = "0xYOUR_ITEM_SYSTEM_PACKAGE_ID_HERE"
@@ -109,6 +681,187 @@ func setDefaultValues(cfg *Config) {
 	cfg.Auth.EnableDummyAuth = true
 	cfg.Auth.DummyToken = "fixed_dummy_secret_token_123"
 	cfg.Auth.DummyPlayerID = "player_associated_with_dummy_token"
+	cfg.Sui.GuildModule = "guild"
+	cfg.Onboarding.TutorialFile = "configs/tutorial.json"
+	cfg.Guilds.RosterFile = "configs/guilds.json"
+	cfg.GuildEvents.StateFile = "guild-events.json"
+	cfg.GuildEvents.ReminderMinutes = 15
+	cfg.GuildEvents.MaxCapacity = 40
+	cfg.GuildEvents.MaxDaysAhead = 30
+	cfg.GuildEvents.MaxScheduledPerGuild = 20
+	cfg.GuildRanks.Enabled = true
+	cfg.GuildRanks.StateFile = "guild-ranks.json"
+	cfg.GuildRanks.MaxCustomRanks = 8
+	cfg.GuildRanks.OfficerWithdrawPerDay = 10000
+	cfg.Diplomacy.Enabled = true
+	cfg.Diplomacy.StateFile = "diplomacy.json"
+	cfg.Diplomacy.MaxAllianceGuilds = 5
+	cfg.Diplomacy.ProposalHours = 72
+	cfg.Recruitment.Enabled = true
+	cfg.Recruitment.StateFile = "recruitment.json"
+	cfg.Recruitment.ListingDays = 30
+	cfg.Recruitment.ApplicationDays = 7
+	cfg.Recruitment.MaxOpenApplications = 5
+	cfg.Recruitment.MaxPendingPerGuild = 50
+	cfg.Parental.Enabled = true
+	cfg.Parental.StateFile = "parental.json"
+	cfg.Parental.CheckIntervalSeconds = 60
+	cfg.Parental.WarnMinutes = []int{15, 5, 1}
+	cfg.DeepLinks.SecretEnvVar = "DEEP_LINK_SECRET"
+	cfg.DeepLinks.TTLMinutes = 1440
+	cfg.DeepLinks.MaxTTLHours = 168
+	cfg.ChatChannels.Global = ChatChannelConfig{AutoJoin: true, MessagesPerMinute: 6, Burst: 2}
+	cfg.ChatChannels.Trade = ChatChannelConfig{MessagesPerMinute: 4, Burst: 2}
+	cfg.ChatChannels.Zone = ChatChannelConfig{AutoJoin: true, MessagesPerMinute: 20, Burst: 5}
+	cfg.ChatChannels.Guild = ChatChannelConfig{AutoJoin: true, MessagesPerMinute: 30, Burst: 10}
+	cfg.ChatChannels.Alliance = ChatChannelConfig{AutoJoin: true, MessagesPerMinute: 20, Burst: 5}
+	cfg.Social.ActionsPerSecond = 4
+	cfg.Social.Burst = 8
+	cfg.Territory.ZonesFile = "configs/zones.json"
+	cfg.Territory.SiegeDelaySeconds = 3600
+	cfg.Territory.SiegeDurationSeconds = 1800
+	cfg.Movement.SkillsFile = "configs/skills.json"
+	cfg.Movement.MapsDir = "configs/maps"
+	cfg.Movement.ReloadIntervalSeconds = 5
+	cfg.Anticheat.SpeedWindowMs = 1000
+	cfg.Anticheat.SpeedTolerance = 0.25
+	cfg.Anticheat.FlagAfter = 3
+	cfg.Anticheat.KickAfter = 10
+	cfg.Anticheat.ViolationWindowSeconds = 60
+	cfg.NPCs.File = "configs/npcs.json"
+	cfg.NPCs.TickIntervalMs = 100
+	cfg.NPCs.PathBudget = 2000
+	cfg.NPCs.NavCellSize = 0.5
+	cfg.Projectiles.PlayerHealth = 100
+	cfg.Projectiles.PlayerDefense = 5
+	cfg.RoomRules.MaxXPMultiplier = 2
+	cfg.Stats.TickIntervalMs = 100
+	cfg.Idempotency.TTLSeconds = 600
+	cfg.Idempotency.PendingSeconds = 60
+	cfg.Idempotency.MaxKeysPerPlayer = 64
+	cfg.ClockSync.HintIntervalSeconds = 60
+	cfg.ClockSync.ToleranceMs = 50
+	cfg.Resume.Enabled = true
+	cfg.Resume.WindowSeconds = 600
+	cfg.Resume.MaxAgeHours = 24
+	cfg.Admin.TokenEnvVar = "ADMIN_TOKEN"
+	cfg.Admin.AuditLogPath = "admin-audit.jsonl"
+	cfg.APITokens.File = "api-tokens.json"
+	cfg.APITokens.DefaultPerMinute = 120
+	cfg.APITokens.DefaultBurst = 20
+	cfg.Audit.Path = "audit.jsonl"
+	cfg.Delivery.Capacity = 256
+	cfg.Delivery.RetentionSeconds = 300
+	cfg.Status.Name = "Sui Game Server"
+	cfg.Status.MaxAgeSeconds = 15
+	cfg.Outbox.Path = "outbox.json"
+	cfg.Arena.StateFile = "arena-state.json"
+	cfg.Arena.SeasonDays = 28
+	cfg.Arena.KFactor = 32
+	cfg.Arena.RatingWindow = 100
+	cfg.Arena.WindowGrowthPerSecond = 5
+	cfg.Arena.MaxLatencyMs = 250
+	cfg.Arena.LatencyWindowMs = 40
+	cfg.Arena.LatencyGrowthPerSecond = 5
+	cfg.Arena.MaxLatencySpreadMs = 150
+	cfg.Arena.ItemModule = "item"
+	cfg.Arena.RewardTiers = []ArenaRewardTier{
+		{MaxRank: 1, Trophy: "arena_champion"},
+		{MaxRank: 10, Trophy: "arena_top10"},
+		{MaxRank: 100, Trophy: "arena_top100"},
+	}
+	cfg.Balance.File = "configs/balance.json"
+	cfg.Balance.HistoryFile = "balance-history.jsonl"
+	cfg.Balance.ReloadIntervalSeconds = 5
+	cfg.ChatHistory.Enabled = true
+	cfg.ChatHistory.MaxPerChannel = 200
+	cfg.ChatHistory.RetentionDays = 30
+	cfg.ChatHistory.MaxFetch = 100
+	cfg.AccountLinks.MaxAddresses = 5
+	cfg.AccountLinks.ChallengeTTLSeconds = 300
+	cfg.AccountLinks.GameName = "suigserver"
+	cfg.LoginPrefetch.Enabled = true
+	cfg.LoginPrefetch.WaitMs = 1000
+	cfg.LoginPrefetch.CacheSeconds = 30
+	cfg.AFK.AfterSeconds = 300
+	cfg.AFK.KickAfterSeconds = 1800
+	cfg.AFK.KickMinSessions = 500
+	cfg.AFK.CheckIntervalSeconds = 30
+	cfg.Autoscaling.PlayersPerCore = 250
+	cfg.Autoscaling.TargetCPU = 0.7
+	cfg.Autoscaling.MaxTickUtilization = 0.8
+	cfg.Autoscaling.SampleIntervalSeconds = 5
+	cfg.HotZones.Enabled = true
+	cfg.HotZones.CellSize = 10
+	cfg.HotZones.HotPlayers = 12
+	cfg.HotZones.CoolPlayers = 6
+	cfg.HotZones.SustainChecks = 3
+	cfg.HotZones.IntervalSeconds = 10
+	cfg.HotZones.NPCStep = 0.25
+	cfg.HotZones.MaxNPCDensity = 2
+	cfg.Quarantine.MaxEntries = 1000
+	cfg.Quarantine.FailureThreshold = 3
+	cfg.Quarantine.FailureWindowSeconds = 600
+	cfg.ActorAudit.MaxMessageBytes = 65536
+	cfg.HandlerMetrics.SlowThresholdMs = 50
+	cfg.LeaderElection.LeaseSeconds = 15
+	cfg.LeaderElection.Workers = []string{"listingExpiry"}
+	cfg.Shop.CatalogFile = "configs/shops.json"
+	cfg.Shop.StateFile = "shop-state.json"
+	cfg.Shop.StartingCoins = 100
+	cfg.Shop.ItemModule = "item"
+	cfg.Crafting.RecipesFile = "configs/recipes.json"
+	cfg.Crafting.MaxQueued = 5
+	cfg.Crafting.TickIntervalSeconds = 10
+	cfg.Crafting.ItemModule = "item"
+	cfg.BattlePass.File = "configs/battlepass.json"
+	cfg.BattlePass.StateFile = "battlepass-state.json"
+	cfg.BattlePass.ItemModule = "item"
+	cfg.Cosmetics.File = "configs/cosmetics.json"
+	cfg.Cosmetics.OwnershipCacheSeconds = 300
+	cfg.Airdrops.StateFile = "airdrops.json"
+	cfg.Airdrops.ItemModule = "item"
+	cfg.Airdrops.GasPerMint = 5_000_000
+	cfg.Airdrops.MaxGasBudget = 500_000_000
+	cfg.Airdrops.MaxMintsPerTx = 100
+	cfg.Airdrops.MaxMints = 10000
+	cfg.Trade.Enabled = true
+	cfg.Trade.StateFile = "trade-state.json"
+	cfg.Trade.EscrowThresholdMist = 1000000000
+	cfg.Trade.ItemValueMist = 100000000
+	cfg.Trade.MaxItems = 16
+	cfg.Trade.ProposalTTLSeconds = 120
+	cfg.Trade.DepositTTLSeconds = 900
+	cfg.Trade.EscrowModule = "escrow"
+	cfg.FairLoot.Tables = []string{"elite"}
+	cfg.FairLoot.EventLogModule = "event_log"
+	cfg.Gift.Enabled = true
+	cfg.Gift.StateFile = "gift-state.json"
+	cfg.Gift.AcceptThresholdMist = 1000000000
+	cfg.Gift.ItemValueMist = 100000000
+	cfg.Gift.AcceptTTLSeconds = 259200
+	cfg.Gift.TransferTTLSeconds = 900
+	cfg.Gift.HistoryLimit = 50
+	cfg.OrderBook.StateFile = "orderbook-state.json"
+	cfg.OrderBook.DepositTTLSeconds = 900
+	cfg.OrderBook.DefaultTTLSeconds = 86400
+	cfg.OrderBook.MaxTTLSeconds = 604800
+	cfg.OrderBook.MaxOpenOrders = 20
+	cfg.OrderBook.DepthLevels = 20
+	cfg.Treasury.LedgerFile = "treasury-ledger.json"
+	cfg.Treasury.RecentEntries = 200
+	cfg.LiveOps.StateFile = "liveops-state.json"
+	cfg.MetricHistory.Enabled = true
+	cfg.MetricHistory.IntervalSeconds = 60
+	cfg.MetricHistory.RetentionDays = 30
+	cfg.Mail.StateFile = "mail-state.json"
+	cfg.Mail.MaxPerPlayer = 100
+	cfg.Features.OverridesFile = "feature-overrides.json"
+	cfg.Features.MarketplaceConfigFile = "configs/marketplace.json"
+	cfg.Analytics.BatchSize = 100
+	cfg.Analytics.FlushIntervalMs = 5000
+	cfg.Analytics.QueueSize = 10000
+	cfg.Analytics.Sinks = []AnalyticsSinkConfig{{Type: "stdout"}}
 }
 
 // CreateExampleConfigFile creates an example config.json if it doesn't exist.
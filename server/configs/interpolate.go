@@ -0,0 +1,151 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// environment is where interpolated values come from.
+type environment struct {
+	lookupEnv func(string) (string, bool)
+	readFile  func(string) ([]byte, error)
+}
+
+var osEnvironment = environment{lookupEnv: os.LookupEnv, readFile: os.ReadFile}
+
+// interpolate replaces references in the string values of a JSON config:
+//
+//	${NAME}          the value of the environment variable NAME, or else the
+//	                 contents of the file named by NAME_FILE, as with Docker
+//	                 and Kubernetes secrets
+//	${NAME:-default} the same, or default if neither is set
+//	$$               a literal $
+//
+// Settings named *EnvVar, such as admin.tokenEnvVar, hold the name of a
+// variable that is read when the server starts, after interpolation. They are
+// not interpolated: a reference there would put the secret where its name
+// belongs, so it is reported instead. Where a setting has both forms, such as
+// a webhook's url and urlEnvVar, the *EnvVar one wins.
+//
+// Every reference that cannot be resolved is reported in one
+// *ValidationError, by the setting it is in.
+func interpolate(data []byte, env environment) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep large integers such as gas budgets exact
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	tree = interpolateValue(tree, "", env, v)
+	if len(v.violations) > 0 {
+		return nil, &ValidationError{Violations: v.violations}
+	}
+	return json.Marshal(tree)
+}
+
+func interpolateValue(value interface{}, path string, env environment, v *validator) interface{} {
+	switch value := value.(type) {
+	case string:
+		return interpolateString(value, path, env, v)
+	case []interface{}:
+		for i, element := range value {
+			value[i] = interpolateValue(element, path+"["+strconv.Itoa(i)+"]", env, v)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report problems in a stable order
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if name, ok := value[key].(string); ok && strings.HasSuffix(strings.ToLower(key), "envvar") {
+				if strings.Contains(name, "$") {
+					v.addf(field, "names an environment variable; write NAME, not ${NAME}")
+				}
+				continue
+			}
+			value[key] = interpolateValue(value[key], field, env, v)
+		}
+	}
+	return value
+}
+
+func interpolateString(s, field string, env environment, v *validator) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "$$"):
+			b.WriteByte('$')
+			s = s[2:]
+		case strings.HasPrefix(s, "${"):
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				v.addf(field, "unterminated reference %q", s)
+				return b.String()
+			}
+			value, err := resolve(s[2:end], env)
+			if err != nil {
+				v.addf(field, "%v", err)
+			}
+			b.WriteString(value)
+			s = s[end+1:]
+		default:
+			b.WriteByte('$')
+			s = s[1:]
+		}
+	}
+}
+
+// resolve returns the value of one ${...} reference.
+func resolve(reference string, env environment) (string, error) {
+	name, fallback, hasFallback := strings.Cut(reference, ":-")
+	if !validEnvName(name) {
+		return "", fmt.Errorf("${%s} does not name an environment variable", reference)
+	}
+	if value, ok := env.lookupEnv(name); ok {
+		return value, nil
+	}
+	if path, ok := env.lookupEnv(name + "_FILE"); ok {
+		data, err := env.readFile(path)
+		if err != nil {
+			return "", fmt.Errorf("${%s}: cannot read %s_FILE: %v", name, name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil // Secret files usually end with a newline
+	}
+	if hasFallback {
+		return fallback, nil
+	}
+	return "", fmt.Errorf("${%s} is not set; set %s or %s_FILE", name, name, name)
+}
+
+func validEnvName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package configs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateResolvesVariablesAndSecretFiles(t *testing.T) {
+	env := environment{
+		lookupEnv: func(name string) (string, bool) {
+			value, ok := map[string]string{
+				"DB_PASSWORD":     "hunter2",
+				"SUI_KEY_FILE":    "/run/secrets/sui_key",
+				"MISSING_FILE":    "/run/secrets/missing",
+				"EMPTY_BUT_SET":   "",
+				"REDIS_HOST_PORT": "redis:6379",
+			}[name]
+			return value, ok
+		},
+		readFile: func(path string) ([]byte, error) {
+			if path == "/run/secrets/sui_key" {
+				return []byte("suiprivkey1abc\n"), nil
+			}
+			return nil, fs.ErrNotExist
+		},
+	}
+	data := []byte(`{
+		"server": {"tcpPort": 9000},
+		"database": {"postgresUrl": "postgresql://game:${DB_PASSWORD}@db:5432/game"},
+		"redis": {"address": "${REDIS_HOST_PORT}", "password": "${EMPTY_BUT_SET:-unused}", "addresses": ["${REDIS_NODE:-localhost:6379}"]},
+		"sui": {"privateKey": "${SUI_KEY}", "gasBudget": 18446744073709551615},
+		"auth": {"dummyToken": "$$literal$"}
+	}`)
+	resolved, err := interpolate(data, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(resolved, cfg); err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case cfg.Database.PostgresURL != "postgresql://game:hunter2@db:5432/game":
+		t.Fatalf("postgresUrl = %q", cfg.Database.PostgresURL)
+	case cfg.Redis.Address != "redis:6379" || cfg.Redis.Password != "" || cfg.Redis.Addresses[0] != "localhost:6379":
+		t.Fatalf("redis = %+v", cfg.Redis)
+	case cfg.Sui.PrivateKey != "suiprivkey1abc":
+		t.Fatalf("privateKey from SUI_KEY_FILE = %q", cfg.Sui.PrivateKey)
+	case cfg.Sui.GasBudget != 18446744073709551615 || cfg.Server.TCPPort != 9000:
+		t.Fatalf("numbers changed: gasBudget %d, tcpPort %d", cfg.Sui.GasBudget, cfg.Server.TCPPort)
+	case cfg.Auth.DummyToken != "$literal$":
+		t.Fatalf("dummyToken = %q", cfg.Auth.DummyToken)
+	}
+
+	// Every unresolved reference is reported, by the setting it is in.
+	_, err = interpolate([]byte(`{"sui": {"rpcUrl": "${RPC_URL}", "fallbackRpcUrls": ["${MISSING}"]}, "redis": {"password": "${1BAD}"}}`), env)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 3 {
+		t.Fatalf("interpolate with missing variables = %v", err)
+	}
+	if v := invalid.Violations[1]; v.Field != "sui.fallbackRpcUrls[0]" || !strings.Contains(v.Problem, "cannot read MISSING_FILE") {
+		t.Fatalf("violation = %+v", v)
+	}
+	if v := invalid.Violations[2]; v.Field != "sui.rpcUrl" || v.Problem != "${RPC_URL} is not set; set RPC_URL or RPC_URL_FILE" {
+		t.Fatalf("violation = %+v", v)
+	}
+}
+
+func TestInterpolateLeavesEnvVarNamesAlone(t *testing.T) {
+	env := environment{
+		lookupEnv: func(name string) (string, bool) {
+			value, ok := map[string]string{"WEBHOOK_URL": "https://discord.example/api/webhooks/1/secret"}[name]
+			return value, ok
+		},
+		readFile: func(string) ([]byte, error) { return nil, fs.ErrNotExist },
+	}
+	// A *EnvVar setting is a variable name, read at startup, so it is kept as written.
+	resolved, err := interpolate([]byte(`{"admin": {"tokenEnvVar": "ADMIN_TOKEN"}, "sui": {"keySource": {"envVar": "SUI_KEY"}},
+		"webhooks": {"endpoints": [{"url": "${WEBHOOK_URL}", "urlEnvVar": "OPS_WEBHOOK_URL"}]}}`), env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(resolved, cfg); err != nil {
+		t.Fatal(err)
+	}
+	endpoint := cfg.Webhooks.Endpoints[0]
+	switch {
+	case cfg.Admin.TokenEnvVar != "ADMIN_TOKEN" || cfg.Sui.KeySource.EnvVar != "SUI_KEY":
+		t.Fatalf("variable names changed: %q, %q", cfg.Admin.TokenEnvVar, cfg.Sui.KeySource.EnvVar)
+	case endpoint.URL != "https://discord.example/api/webhooks/1/secret" || endpoint.URLEnvVar != "OPS_WEBHOOK_URL":
+		t.Fatalf("webhook = %+v", endpoint)
+	}
+
+	// A reference where a name belongs would look up a variable named after the secret.
+	_, err = interpolate([]byte(`{"admin": {"tokenEnvVar": "${ADMIN_TOKEN}"}, "deepLinks": {"secretEnvVar": "${DEEP_LINK_SECRET:-}"}}`), env)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 2 {
+		t.Fatalf("interpolate with references in *EnvVar settings = %v", err)
+	}
+	if v := invalid.Violations[0]; v.Field != "admin.tokenEnvVar" || v.Problem != "names an environment variable; write NAME, not ${NAME}" {
+		t.Fatalf("violation = %+v", v)
+	}
+}
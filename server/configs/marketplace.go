@@ -24,6 +24,22 @@ type MarketplaceConfig struct {
 	// Cache settings
 	EnableCaching     bool   `json:"enable_caching"`
 	CacheExpiration   int    `json:"cache_expiration_seconds"`
+
+	// Event sync: cached listings follow the marketplace's events, so they
+	// can be kept much longer than CacheExpiration
+	EventSyncEnabled      bool `json:"event_sync_enabled"`
+	EventPollInterval     int  `json:"event_poll_interval_seconds"`
+	SyncedCacheExpiration int  `json:"synced_cache_expiration_seconds"`
+
+	// How long an NFT or listing stays reserved after a transaction for it is
+	// prepared, unless the marketplace reports the outcome sooner
+	ReservationTTL int `json:"reservation_ttl_seconds"`
+
+	// Listing expiry: how often listings past their duration are looked for
+	// (0 turns it off), and the address that removes them on-chain, if any
+	ExpirySweepInterval int    `json:"expiry_sweep_interval_seconds"`
+	CleanupAddress      string `json:"cleanup_address"`
+	CleanupGasObjectID  string `json:"cleanup_gas_object_id"`
 	
 	// Rate limiting
 	RateLimitEnabled  bool   `json:"rate_limit_enabled"`
@@ -39,6 +55,11 @@ func DefaultMarketplaceConfig() *MarketplaceConfig {
 		MaxListingDuration:   168, // 7 days
 		EnableCaching:        true,
 		CacheExpiration:      300, // 5 minutes
+		EventSyncEnabled:     true,
+		EventPollInterval:    2,
+		SyncedCacheExpiration: 1800, // 30 minutes
+		ReservationTTL:       120,
+		ExpirySweepInterval:  60,
 		RateLimitEnabled:     true,
 		RateLimitPerMin:      100,
 	}
@@ -0,0 +1,365 @@
+package configs
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+// Bounds of sui.gasBudget and airdrops.maxGasBudget, in MIST. Sui refuses
+// transactions budgeting more than 50 SUI, and a budget below 0.001 SUI does
+// not cover the computation of a simple call.
+const (
+	MinGasBudget uint64 = 1_000_000
+	MaxGasBudget uint64 = 50_000_000_000
+)
+
+// suiIDPattern matches a Sui package, object or account ID.
+var suiIDPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{1,64}$`)
+
+// IsSuiID reports whether id looks like a Sui package, object or address ID,
+// as opposed to an empty value or a "0xYOUR_..." placeholder.
+func IsSuiID(id string) bool {
+	return suiIDPattern.MatchString(id)
+}
+
+// Violation is one invalid setting.
+type Violation struct {
+	Field   string // JSON path of the setting, e.g. "server.tcpPort"
+	Problem string
+}
+
+// ValidationError lists every invalid setting of a configuration, so that
+// they can all be fixed at once.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problem(s)):", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  %s: %s", v.Field, v.Problem)
+	}
+	return b.String()
+}
+
+// MintsItems reports whether any feature is set up to mint item NFTs, which
+// makes sui.itemSystemPackageId and the signing key required.
+func (c *Config) MintsItems() bool {
+	return c.Shop.PremiumRecipient != "" && c.Shop.MinterAddress != "" ||
+		c.Arena.Enabled && c.Arena.MinterAddress != "" ||
+		c.Airdrops.MinterAddress != "" ||
+		c.Crafting.MinterAddress != "" ||
+		c.BattlePass.MinterAddress != ""
+}
+
+// Validate checks the settings that can be checked without files, the network
+// or the environment: port ranges, URL formats, addresses, package IDs of
+// enabled features and numeric bounds. It returns a *ValidationError listing
+// every problem, or nil.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.tcpPort", c.Server.TCPPort)
+	v.port("server.httpPort", c.Server.HTTPPort)
+	if c.Server.TCPPort == c.Server.HTTPPort {
+		v.addf("server.httpPort", "must differ from server.tcpPort (both are %d)", c.Server.HTTPPort)
+	}
+	switch strings.ToUpper(c.Server.LogLevel) {
+	case "DEBUG", "INFO", "WARN", "WARNING", "ERROR", "FATAL":
+	default:
+		v.addf("server.logLevel", "unknown level %q (want DEBUG, INFO, WARN or ERROR)", c.Server.LogLevel)
+	}
+
+	v.url("database.postgresUrl", c.Database.PostgresURL, "postgres", "postgresql")
+	v.url("database.replicaUrl", c.Database.ReplicaURL, "postgres", "postgresql")
+	v.nonNegative("database.maxOpenConns", c.Database.MaxOpenConns)
+	v.nonNegative("database.maxIdleConns", c.Database.MaxIdleConns)
+	v.nonNegative("database.queryTimeoutSeconds", c.Database.QueryTimeoutSeconds)
+	v.nonNegative("database.archiveInactiveDays", c.Database.ArchiveInactiveDays)
+	if c.Database.ArchiveInactiveDays > 0 {
+		v.positive("database.archiveIntervalMinutes", c.Database.ArchiveIntervalMinutes)
+		v.positive("database.archiveBatchSize", c.Database.ArchiveBatchSize)
+	}
+
+	switch c.Redis.Mode {
+	case "", "single":
+		if c.Redis.Address != "" {
+			v.hostPort("redis.address", c.Redis.Address)
+		}
+	case "sentinel", "cluster":
+		if len(c.Redis.Addresses) == 0 {
+			v.addf("redis.addresses", "needs at least one node in %s mode", c.Redis.Mode)
+		}
+		for i, address := range c.Redis.Addresses {
+			v.hostPort(fmt.Sprintf("redis.addresses[%d]", i), address)
+		}
+		if c.Redis.Mode == "sentinel" && c.Redis.MasterName == "" {
+			v.addf("redis.masterName", "is required in sentinel mode")
+		}
+	default:
+		v.addf("redis.mode", "unknown mode %q (want single, sentinel or cluster)", c.Redis.Mode)
+	}
+	v.nonNegative("redis.db", c.Redis.DB)
+
+	if c.Sui.RPCURL == "" {
+		v.addf("sui.rpcUrl", "is required")
+	}
+	v.url("sui.rpcUrl", c.Sui.RPCURL, "http", "https")
+	for i, rpcURL := range c.Sui.FallbackRPCURLs {
+		v.url(fmt.Sprintf("sui.fallbackRpcUrls[%d]", i), rpcURL, "http", "https")
+	}
+	v.url("sui.websocketUrl", c.Sui.WebsocketURL, "ws", "wss")
+	v.gasBudget("sui.gasBudget", c.Sui.GasBudget)
+	if c.MintsItems() {
+		v.packageID("sui.itemSystemPackageId", c.Sui.ItemSystemPackageID, "item mints")
+	}
+	for i, signer := range c.Sui.SignerPool.Signers {
+		v.address(fmt.Sprintf("sui.signerPool.signers[%d].address", i), signer.Address)
+	}
+
+	// Addresses are optional; each one that is set turns a feature on.
+	for _, a := range []struct{ field, address string }{
+		{"treasury.address", c.Treasury.Address},
+		{"shop.premiumRecipient", c.Shop.PremiumRecipient},
+		{"shop.minterAddress", c.Shop.MinterAddress},
+		{"arena.minterAddress", c.Arena.MinterAddress},
+		{"crafting.minterAddress", c.Crafting.MinterAddress},
+		{"battlePass.premiumRecipient", c.BattlePass.PremiumRecipient},
+		{"battlePass.minterAddress", c.BattlePass.MinterAddress},
+		{"airdrops.minterAddress", c.Airdrops.MinterAddress},
+		{"trade.arbiterAddress", c.Trade.ArbiterAddress},
+		{"gift.custodyAddress", c.Gift.CustodyAddress},
+		{"orderBook.custodyAddress", c.OrderBook.CustodyAddress},
+		{"fairLoot.senderAddress", c.FairLoot.SenderAddress},
+	} {
+		if a.address != "" {
+			v.address(a.field, a.address)
+		}
+	}
+
+	if c.Trade.Enabled && c.Trade.EscrowPackageID != "" {
+		v.packageID("trade.escrowPackageId", c.Trade.EscrowPackageID, "escrowed trades")
+	}
+	if c.Trade.Enabled {
+		v.positive("trade.maxItems", c.Trade.MaxItems)
+		v.positive("trade.proposalTtlSeconds", c.Trade.ProposalTTLSeconds)
+	}
+	if c.FairLoot.Enabled && c.FairLoot.EventLogPackageID != "" {
+		v.packageID("fairLoot.eventLogPackageId", c.FairLoot.EventLogPackageID, "on-chain loot commitments")
+		if c.FairLoot.SenderAddress == "" {
+			v.addf("fairLoot.senderAddress", "is required to log loot commitments on chain")
+		}
+	}
+	if c.Airdrops.MinterAddress != "" {
+		v.gasBudget("airdrops.maxGasBudget", c.Airdrops.MaxGasBudget)
+		if c.Airdrops.GasPerMint > c.Airdrops.MaxGasBudget {
+			v.addf("airdrops.gasPerMint", "%d exceeds airdrops.maxGasBudget %d", c.Airdrops.GasPerMint, c.Airdrops.MaxGasBudget)
+		}
+	}
+	if c.OrderBook.CustodyAddress != "" {
+		if c.OrderBook.TokenType == "" {
+			v.addf("orderBook.tokenType", "is required to run the order book")
+		}
+		if c.OrderBook.CustodyGasObjectID == "" {
+			v.addf("orderBook.custodyGasObjectId", "is required to settle orders from custody")
+		}
+		v.positive("orderBook.depositTtlSeconds", c.OrderBook.DepositTTLSeconds)
+		if c.OrderBook.DefaultTTLSeconds > c.OrderBook.MaxTTLSeconds {
+			v.addf("orderBook.defaultTtlSeconds", "%d exceeds orderBook.maxTtlSeconds %d", c.OrderBook.DefaultTTLSeconds, c.OrderBook.MaxTTLSeconds)
+		}
+	}
+	if c.GuildRanks.Enabled {
+		v.positive("guildRanks.maxCustomRanks", c.GuildRanks.MaxCustomRanks)
+	}
+	if c.Diplomacy.Enabled {
+		v.positive("diplomacy.proposalHours", c.Diplomacy.ProposalHours)
+		if c.Diplomacy.MaxAllianceGuilds < 2 {
+			v.addf("diplomacy.maxAllianceGuilds", "must be at least 2, got %d", c.Diplomacy.MaxAllianceGuilds)
+		}
+	}
+	if c.Recruitment.Enabled {
+		v.positive("recruitment.listingDays", c.Recruitment.ListingDays)
+		v.positive("recruitment.applicationDays", c.Recruitment.ApplicationDays)
+		v.positive("recruitment.maxOpenApplications", c.Recruitment.MaxOpenApplications)
+		v.positive("recruitment.maxPendingPerGuild", c.Recruitment.MaxPendingPerGuild)
+	}
+	if c.Parental.Enabled {
+		v.nonNegative("parental.checkIntervalSeconds", c.Parental.CheckIntervalSeconds)
+		for i, minutes := range c.Parental.WarnMinutes {
+			v.positive(fmt.Sprintf("parental.warnMinutes[%d]", i), minutes)
+		}
+	}
+	if c.Arena.Enabled {
+		v.nonNegative("arena.maxLatencyMs", c.Arena.MaxLatencyMs)
+		v.nonNegative("arena.latencyWindowMs", c.Arena.LatencyWindowMs)
+		v.nonNegative("arena.latencyGrowthPerSecond", c.Arena.LatencyGrowthPerSecond)
+		v.nonNegative("arena.maxLatencySpreadMs", c.Arena.MaxLatencySpreadMs)
+		if c.Arena.MaxLatencySpreadMs > 0 && c.Arena.LatencyWindowMs > c.Arena.MaxLatencySpreadMs {
+			v.addf("arena.latencyWindowMs", "%d exceeds arena.maxLatencySpreadMs %d", c.Arena.LatencyWindowMs, c.Arena.MaxLatencySpreadMs)
+		}
+	}
+
+	v.url("clientVersions.upgradeUrl", c.ClientVersions.UpgradeURL, "http", "https")
+	v.url("deepLinks.baseUrl", c.DeepLinks.BaseURL, "http", "https")
+	v.nonNegative("deepLinks.ttlMinutes", c.DeepLinks.TTLMinutes)
+	v.nonNegative("deepLinks.maxTtlHours", c.DeepLinks.MaxTTLHours)
+	if c.DeepLinks.MaxTTLHours > 0 && c.DeepLinks.TTLMinutes > c.DeepLinks.MaxTTLHours*60 {
+		v.addf("deepLinks.ttlMinutes", "%d minutes is longer than deepLinks.maxTtlHours (%d hours)", c.DeepLinks.TTLMinutes, c.DeepLinks.MaxTTLHours)
+	}
+	v.nonNegative("clockSync.hintIntervalSeconds", c.ClockSync.HintIntervalSeconds)
+	v.nonNegative("clockSync.toleranceMs", c.ClockSync.ToleranceMs)
+	if c.Resume.Enabled {
+		v.positive("resume.windowSeconds", c.Resume.WindowSeconds)
+	}
+	v.nonNegative("afk.afterSeconds", c.AFK.AfterSeconds)
+	if c.AFK.AfterSeconds > 0 {
+		v.positive("afk.checkIntervalSeconds", c.AFK.CheckIntervalSeconds)
+	}
+	v.positive("autoscaling.playersPerCore", c.Autoscaling.PlayersPerCore)
+	v.positive("autoscaling.sampleIntervalSeconds", c.Autoscaling.SampleIntervalSeconds)
+	if c.Autoscaling.TargetCPU <= 0 || c.Autoscaling.TargetCPU > 1 {
+		v.addf("autoscaling.targetCpu", "must be above 0 and at most 1, got %v", c.Autoscaling.TargetCPU)
+	}
+	if c.Autoscaling.MaxTickUtilization <= 0 || c.Autoscaling.MaxTickUtilization > 1 {
+		v.addf("autoscaling.maxTickUtilization", "must be above 0 and at most 1, got %v", c.Autoscaling.MaxTickUtilization)
+	}
+	if c.HotZones.Enabled {
+		v.positive("hotZones.hotPlayers", c.HotZones.HotPlayers)
+		v.positive("hotZones.sustainChecks", c.HotZones.SustainChecks)
+		v.positive("hotZones.intervalSeconds", c.HotZones.IntervalSeconds)
+		v.nonNegative("hotZones.coolPlayers", c.HotZones.CoolPlayers)
+		if c.HotZones.CoolPlayers >= c.HotZones.HotPlayers {
+			v.addf("hotZones.coolPlayers", "must be below hotZones.hotPlayers %d, got %d", c.HotZones.HotPlayers, c.HotZones.CoolPlayers)
+		}
+		if c.HotZones.CellSize <= 0 {
+			v.addf("hotZones.cellSize", "must be above 0, got %v", c.HotZones.CellSize)
+		}
+		if c.HotZones.NPCStep <= 0 {
+			v.addf("hotZones.npcStep", "must be above 0, got %v", c.HotZones.NPCStep)
+		}
+		if c.HotZones.MaxNPCDensity < 1 || c.HotZones.MaxNPCDensity > balance.MaxNPCMultiplier {
+			v.addf("hotZones.maxNpcDensity", "must be between 1 and %d, got %v", balance.MaxNPCMultiplier, c.HotZones.MaxNPCDensity)
+		}
+	}
+
+	for i, sink := range c.Analytics.Sinks {
+		if sink.Type == "http" || sink.Type == "kafka" {
+			field := fmt.Sprintf("analytics.sinks[%d].url", i)
+			if sink.URL == "" {
+				v.addf(field, "is required for %s sinks", sink.Type)
+			}
+			v.url(field, sink.URL, "http", "https")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, world := range c.Worlds {
+		field := fmt.Sprintf("worlds[%d].id", i)
+		switch {
+		case world.ID == "":
+			v.addf(field, "is required")
+		case seen[world.ID]:
+			v.addf(field, "%q is used by another world", world.ID)
+		}
+		seen[world.ID] = true
+	}
+	for i, window := range c.Status.Maintenance {
+		if !window.End.After(window.Start) {
+			v.addf(fmt.Sprintf("status.maintenance[%d].end", i), "must be after its start")
+		}
+	}
+
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// validator collects violations.
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) port(field string, port int) {
+	if port < 1 || port > 65535 {
+		v.addf(field, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) positive(field string, n int) {
+	if n <= 0 {
+		v.addf(field, "must be positive, got %d", n)
+	}
+}
+
+func (v *validator) nonNegative(field string, n int) {
+	if n < 0 {
+		v.addf(field, "must not be negative, got %d", n)
+	}
+}
+
+// url checks that raw, if set, is an absolute URL with one of schemes.
+func (v *validator) url(field, raw string, schemes ...string) {
+	if raw == "" {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		v.addf(field, "%q is not an absolute URL", raw)
+		return
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return
+		}
+	}
+	v.addf(field, "%q must be a %s URL", raw, strings.Join(schemes, " or "))
+}
+
+func (v *validator) hostPort(field, address string) {
+	if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+		v.addf(field, "%q is not a host:port address", address)
+	}
+}
+
+func (v *validator) gasBudget(field string, budget uint64) {
+	if budget < MinGasBudget || budget > MaxGasBudget {
+		v.addf(field, "must be between %d and %d MIST, got %d", MinGasBudget, MaxGasBudget, budget)
+	}
+}
+
+func (v *validator) address(field, address string) {
+	if !IsSuiID(address) {
+		v.addf(field, "%q is not a Sui address", address)
+	}
+}
+
+// packageID checks the package ID a feature needs.
+func (v *validator) packageID(field, id, neededFor string) {
+	switch {
+	case IsSuiID(id):
+	case id == "":
+		v.addf(field, "is required for %s", neededFor)
+	case IsPlaceholder(id):
+		v.addf(field, "is still the placeholder %q; set the deployed package ID for %s", id, neededFor)
+	default:
+		v.addf(field, "%q is not a package ID; needed for %s", id, neededFor)
+	}
+}
+
+// IsPlaceholder reports whether id is one of the "0xYOUR_..._HERE" values the
+// example configuration ships with.
+func IsPlaceholder(id string) bool {
+	upper := strings.ToUpper(id)
+	return strings.Contains(upper, "YOUR_") || strings.Contains(upper, "PLACEHOLDER")
+}
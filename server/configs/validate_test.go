@@ -0,0 +1,58 @@
+package configs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExampleConfigIsValid(t *testing.T) {
+	data, err := os.ReadFile("../../config.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	setDefaultValues(cfg)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config.example.json: %v", err)
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	cfg := &Config{}
+	setDefaultValues(cfg)
+	cfg.Server.TCPPort = 70000
+	cfg.Database.PostgresURL = "mysql://localhost/game"
+	cfg.Redis.Mode = "sentinel"
+	cfg.Sui.RPCURL = "fullnode.testnet.sui.io"
+	cfg.Sui.GasBudget = 100 * MaxGasBudget
+	cfg.Crafting.MinterAddress = "0xc0ffee" // Crafted items are minted with the placeholder package ID
+
+	err := cfg.Validate()
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Validate = %v, want a *ValidationError", err)
+	}
+	var fields []string
+	for _, v := range invalid.Violations {
+		fields = append(fields, v.Field)
+	}
+	want := []string{"server.tcpPort", "database.postgresUrl", "redis.addresses", "redis.masterName", "sui.rpcUrl", "sui.gasBudget", "sui.itemSystemPackageId"}
+	if strings.Join(fields, " ") != strings.Join(want, " ") {
+		t.Fatalf("violations = %v, want %v", fields, want)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "7 problem(s)") || !strings.Contains(msg, "\n  sui.itemSystemPackageId: is still the placeholder") {
+		t.Fatalf("Error() = %q", msg)
+	}
+
+	cfg.Sui.ItemSystemPackageID = "0x2"
+	cfg.Crafting.MinterAddress = "crafter"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `crafting.minterAddress: "crafter" is not a Sui address`) {
+		t.Fatalf("Validate with a bad address = %v", err)
+	}
+}
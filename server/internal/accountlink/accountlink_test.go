@@ -0,0 +1,117 @@
+package accountlink
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"golang.org/x/crypto/blake2b"
+)
+
+type testWallet struct {
+	key     ed25519.PrivateKey
+	Address string
+}
+
+func wallet(seed byte) testWallet {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, 32))
+	return testWallet{key: key, Address: models.Ed25519PublicKeyToSuiAddress(key.Public().(ed25519.PublicKey))}
+}
+
+// sign signs message as a Sui wallet does: the BCS-encoded bytes behind the
+// personal message intent, hashed with BLAKE2b-256.
+func sign(t *testing.T, w testWallet, message string) string {
+	t.Helper()
+	if len(message) >= 1<<14 {
+		t.Fatal("message too long for a two-byte length prefix")
+	}
+	encoded := []byte{byte(len(message)&0x7f | 0x80), byte(len(message) >> 7)}
+	if len(message) < 0x80 {
+		encoded = []byte{byte(len(message))}
+	}
+	digest := blake2b.Sum256(append([]byte{3, 0, 0}, append(encoded, message...)...))
+	serialized := append([]byte{0}, ed25519.Sign(w.key, digest[:])...)
+	serialized = append(serialized, w.key.Public().(ed25519.PublicKey)...)
+	return base64.StdEncoding.EncodeToString(serialized)
+}
+
+func TestLinkRequiresSignedChallenge(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(NewMemoryStore(), Options{})
+	first, second := wallet(1), wallet(2)
+
+	if _, err := s.Link(ctx, "p1", first.Address, "", false); !errors.Is(err, ErrNoChallenge) {
+		t.Fatalf("link without challenge: %v", err)
+	}
+	challenge, err := s.Challenge("p1", first.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Link(ctx, "p1", first.Address, sign(t, second, challenge.Message), false); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("link signed by another wallet: %v", err)
+	}
+	links, err := s.Link(ctx, "p1", first.Address, sign(t, first, challenge.Message), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || !links[0].Primary {
+		t.Fatalf("links = %+v, want the first address as primary", links)
+	}
+	if _, err := s.Link(ctx, "p1", first.Address, sign(t, first, challenge.Message), false); !errors.Is(err, ErrNoChallenge) {
+		t.Fatalf("challenge reused: %v", err)
+	}
+
+	// The same address cannot be linked to a second account.
+	challenge, _ = s.Challenge("p2", first.Address)
+	if _, err := s.Link(ctx, "p2", first.Address, sign(t, first, challenge.Message), false); !errors.Is(err, ErrAddressTaken) {
+		t.Fatalf("link of a taken address: %v", err)
+	}
+
+	// A second address linked as primary takes over; unlinking it hands primary back.
+	challenge, _ = s.Challenge("p1", second.Address)
+	if _, err := s.Link(ctx, "p1", second.Address, sign(t, second, challenge.Message), true); err != nil {
+		t.Fatal(err)
+	}
+	if address, _ := s.Address(ctx, "p1"); address != second.Address {
+		t.Fatalf("primary = %s, want %s", address, second.Address)
+	}
+	if _, err := s.Unlink(ctx, "p1", second.Address); err != nil {
+		t.Fatal(err)
+	}
+	if address, _ := s.Address(ctx, "p1"); address != first.Address {
+		t.Fatalf("primary after unlink = %s, want %s", address, first.Address)
+	}
+	if _, err := s.Address(ctx, "p2"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("address of unlinked player: %v", err)
+	}
+}
+
+func TestChallengeExpires(t *testing.T) {
+	s := NewService(NewMemoryStore(), Options{ChallengeTTL: time.Minute})
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	w := wallet(3)
+	challenge, err := s.Challenge("p1", w.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := s.Link(context.Background(), "p1", w.Address, sign(t, w, challenge.Message), false); !errors.Is(err, ErrNoChallenge) {
+		t.Fatalf("link with expired challenge: %v", err)
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	address, err := NormalizeAddress(" 0xABC ")
+	if err != nil || address != "0x0000000000000000000000000000000000000000000000000000000000000abc" {
+		t.Fatalf("NormalizeAddress = %q, %v", address, err)
+	}
+	if _, err := NormalizeAddress("abc"); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("NormalizeAddress without 0x: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package accountlink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS account_links (
+	address    TEXT PRIMARY KEY,
+	player_id  TEXT NOT NULL,
+	is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+	linked_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS account_links_player_id ON account_links (player_id);
+`
+
+// PostgresStore keeps links in the account_links table. It always reads
+// from the primary: ownership checks must see the latest link.
+type PostgresStore struct {
+	DB      *sql.DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the account_links table and its index if they are missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create account_links: %w", err)
+	}
+	return nil
+}
+
+// Links implements Store.
+func (s *PostgresStore) Links(ctx context.Context, playerID string) ([]Link, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT player_id, address, is_primary, linked_at FROM account_links
+		WHERE player_id = $1 ORDER BY linked_at, address`, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query account links of %s: %w", playerID, err)
+	}
+	defer rows.Close()
+	var links []Link
+	for rows.Next() {
+		var link Link
+		if err := rows.Scan(&link.PlayerID, &link.Address, &link.Primary, &link.LinkedAt); err != nil {
+			return nil, fmt.Errorf("scan account link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Owner implements Store.
+func (s *PostgresStore) Owner(ctx context.Context, address string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var playerID string
+	err := s.DB.QueryRowContext(ctx, `SELECT player_id FROM account_links WHERE address = $1`, address).Scan(&playerID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query owner of %s: %w", address, err)
+	}
+	return playerID, nil
+}
+
+// Save implements Store.
+func (s *PostgresStore) Save(ctx context.Context, link Link) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO account_links (address, player_id, is_primary, linked_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address) DO UPDATE SET player_id = EXCLUDED.player_id, is_primary = EXCLUDED.is_primary, linked_at = EXCLUDED.linked_at`,
+		link.Address, link.PlayerID, link.Primary, link.LinkedAt)
+	if err != nil {
+		return fmt.Errorf("save account link %s: %w", link.Address, err)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *PostgresStore) Delete(ctx context.Context, playerID, address string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM account_links WHERE player_id = $1 AND address = $2`, playerID, address); err != nil {
+		return fmt.Errorf("delete account link %s: %w", address, err)
+	}
+	return nil
+}
+
+// DeletePlayer implements Store.
+func (s *PostgresStore) DeletePlayer(ctx context.Context, playerID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM account_links WHERE player_id = $1`, playerID); err != nil {
+		return fmt.Errorf("delete account links of %s: %w", playerID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
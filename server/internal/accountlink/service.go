@@ -0,0 +1,357 @@
+package accountlink
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultMaxAddresses = 5
+	DefaultChallengeTTL = 5 * time.Minute
+	DefaultGameName     = "suigserver"
+)
+
+var (
+	ErrInvalidAddress    = errors.New("not a valid Sui address")
+	ErrNoChallenge       = errors.New("no link challenge is pending for this address, or it expired")
+	ErrBadSignature      = errors.New("signature does not verify")
+	ErrAddressTaken      = errors.New("address is linked to another account")
+	ErrTooManyAddresses  = errors.New("too many linked addresses")
+	ErrNotLinked         = errors.New("no Sui address is linked to this account")
+	ErrUnknownAddress    = errors.New("address is not linked to this account")
+	suiAddressPattern    = regexp.MustCompile(`^0x[0-9a-f]{1,64}$`)
+	ed25519SignatureSize = 1 + ed25519.SignatureSize + ed25519.PublicKeySize // Flag, signature, public key
+)
+
+// Options configure a Service.
+type Options struct {
+	MaxAddresses int           // Addresses one player may link
+	ChallengeTTL time.Duration // How long a challenge can be signed
+	GameName     string        // Named in the message the wallet shows
+}
+
+// Challenge is a message the player signs with the wallet of Address.
+type Challenge struct {
+	Address   string
+	Message   string
+	ExpiresAt time.Time
+}
+
+// Service issues link challenges and keeps verified links. It is safe for
+// concurrent use by session actors.
+type Service struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]Challenge // By player; a new challenge replaces the last
+}
+
+// NewService creates a Service keeping links in store.
+func NewService(store Store, opts Options) *Service {
+	if opts.MaxAddresses <= 0 {
+		opts.MaxAddresses = DefaultMaxAddresses
+	}
+	if opts.ChallengeTTL <= 0 {
+		opts.ChallengeTTL = DefaultChallengeTTL
+	}
+	if opts.GameName == "" {
+		opts.GameName = DefaultGameName
+	}
+	return &Service{store: store, opts: opts, now: time.Now, pending: make(map[string]Challenge)}
+}
+
+// Store returns the underlying store.
+func (s *Service) Store() Store {
+	return s.store
+}
+
+// NormalizeAddress lowercases a Sui address and pads it to 64 hex digits.
+func NormalizeAddress(address string) (string, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if !suiAddressPattern.MatchString(address) {
+		return "", ErrInvalidAddress
+	}
+	return "0x" + strings.Repeat("0", 66-len(address)) + address[2:], nil
+}
+
+// Challenge issues the message playerID must sign to link address.
+func (s *Service) Challenge(playerID, address string) (Challenge, error) {
+	address, err := NormalizeAddress(address)
+	if err != nil {
+		return Challenge{}, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, fmt.Errorf("could not generate a nonce: %w", err)
+	}
+	expiresAt := s.now().Add(s.opts.ChallengeTTL).UTC().Truncate(time.Second)
+	challenge := Challenge{
+		Address:   address,
+		ExpiresAt: expiresAt,
+		Message: fmt.Sprintf("Link this Sui address to your %s account.\n\nAccount: %s\nAddress: %s\nNonce: %s\nExpires: %s",
+			s.opts.GameName, playerID, address, hex.EncodeToString(nonce), expiresAt.Format(time.RFC3339)),
+	}
+	s.mu.Lock()
+	s.pending[playerID] = challenge
+	s.mu.Unlock()
+	return challenge, nil
+}
+
+// Link verifies the wallet signature of playerID's pending challenge for
+// address and stores the link. The first address, or one linked with primary,
+// becomes the primary address. It returns the player's links.
+func (s *Service) Link(ctx context.Context, playerID, address, signature string, primary bool) ([]Link, error) {
+	address, err := NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	challenge, ok := s.pending[playerID]
+	if ok && s.now().After(challenge.ExpiresAt) {
+		delete(s.pending, playerID)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok || challenge.Address != address {
+		return nil, ErrNoChallenge
+	}
+	if err := VerifySignature(challenge.Message, signature, address); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[playerID] != challenge {
+		return nil, ErrNoChallenge // Used by a concurrent request
+	}
+	delete(s.pending, playerID)
+	owner, err := s.store.Owner(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if owner != "" && owner != playerID {
+		return nil, ErrAddressTaken
+	}
+	links, err := s.store.Links(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" && len(links) >= s.opts.MaxAddresses {
+		return nil, fmt.Errorf("%w: at most %d", ErrTooManyAddresses, s.opts.MaxAddresses)
+	}
+	link := Link{PlayerID: playerID, Address: address, LinkedAt: s.now().UTC()}
+	for _, existing := range links {
+		if existing.Address == address {
+			link = existing // Linking again only changes the primary address
+		}
+	}
+	link.Primary = link.Primary || primary || len(links) == 0
+	if link.Primary {
+		if err := s.clearPrimary(ctx, links, address); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.Save(ctx, link); err != nil {
+		return nil, err
+	}
+	utils.LogInfof("AccountLink: %s linked %s (primary: %v).", playerID, address, link.Primary)
+	return s.store.Links(ctx, playerID)
+}
+
+// Unlink removes playerID's link to address. If it was the primary address,
+// the oldest remaining link becomes primary. It returns the player's links.
+func (s *Service) Unlink(ctx context.Context, playerID, address string) ([]Link, error) {
+	address, err := NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links, err := s.store.Links(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	var removed *Link
+	var remaining []Link
+	for i := range links {
+		if links[i].Address == address {
+			removed = &links[i]
+		} else {
+			remaining = append(remaining, links[i])
+		}
+	}
+	if removed == nil {
+		return nil, ErrUnknownAddress
+	}
+	if err := s.store.Delete(ctx, playerID, address); err != nil {
+		return nil, err
+	}
+	if removed.Primary && len(remaining) > 0 {
+		remaining[0].Primary = true
+		if err := s.store.Save(ctx, remaining[0]); err != nil {
+			return nil, err
+		}
+	}
+	utils.LogInfof("AccountLink: %s unlinked %s.", playerID, address)
+	return remaining, nil
+}
+
+// Links returns playerID's links, oldest first.
+func (s *Service) Links(ctx context.Context, playerID string) ([]Link, error) {
+	return s.store.Links(ctx, playerID)
+}
+
+// Address returns playerID's primary address, the one on-chain actions that
+// target the player use, or ErrNotLinked.
+func (s *Service) Address(ctx context.Context, playerID string) (string, error) {
+	links, err := s.store.Links(ctx, playerID)
+	if err != nil {
+		return "", err
+	}
+	for _, link := range links {
+		if link.Primary {
+			return link.Address, nil
+		}
+	}
+	return "", ErrNotLinked
+}
+
+func (s *Service) clearPrimary(ctx context.Context, links []Link, except string) error {
+	for _, link := range links {
+		if link.Primary && link.Address != except {
+			link.Primary = false
+			if err := s.store.Save(ctx, link); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// VerifySignature checks that signature, a serialized Sui wallet signature, signs
+// the personal message with the key of address. Only Ed25519 keys are supported.
+func VerifySignature(message, signature, address string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: not base64", ErrBadSignature)
+	}
+	if len(raw) != ed25519SignatureSize || raw[0] != 0 {
+		return fmt.Errorf("%w: only Ed25519 wallet signatures are supported", ErrBadSignature)
+	}
+	signer, valid, err := models.VerifyPersonalMessage(message, signature)
+	if err != nil || !valid {
+		return ErrBadSignature
+	}
+	if signer != address {
+		return fmt.Errorf("%w: signed by %s", ErrBadSignature, signer)
+	}
+	return nil
+}
+
+// PrivacySource exposes linked addresses to privacy export and deletion
+// requests (it implements privacy.DataSource).
+type PrivacySource struct {
+	Store Store
+}
+
+// Name implements privacy.DataSource.
+func (p PrivacySource) Name() string { return "accountLinks" }
+
+// Export implements privacy.DataSource.
+func (p PrivacySource) Export(ctx context.Context, playerID string) (interface{}, error) {
+	links, err := p.Store.Links(ctx, playerID)
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Erase implements privacy.DataSource.
+func (p PrivacySource) Erase(ctx context.Context, playerID string) error {
+	return p.Store.DeletePlayer(ctx, playerID)
+}
+
+// TransferSection moves linked addresses between shards (it implements
+// transfer.Section). An address stays linked to one player, so an import is
+// refused if the target has it linked to someone else.
+type TransferSection struct {
+	Store Store
+}
+
+// Name implements transfer.Section.
+func (t TransferSection) Name() string { return "accountLinks" }
+
+// Export implements transfer.Section.
+func (t TransferSection) Export(ctx context.Context, playerID string) (json.RawMessage, error) {
+	links, err := t.Store.Links(ctx, playerID)
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return json.Marshal(links)
+}
+
+// Exists implements transfer.Section.
+func (t TransferSection) Exists(ctx context.Context, playerID string) (bool, error) {
+	links, err := t.Store.Links(ctx, playerID)
+	return len(links) > 0, err
+}
+
+// Check implements transfer.Section.
+func (t TransferSection) Check(ctx context.Context, playerID string, data json.RawMessage) ([]string, error) {
+	var links []Link
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("decode links: %w", err)
+	}
+	var clashes []string
+	for _, link := range links {
+		owner, err := t.Store.Owner(ctx, link.Address)
+		if err != nil {
+			return nil, err
+		}
+		if owner != "" && owner != playerID {
+			clashes = append(clashes, fmt.Sprintf("address %s is linked to player %s", link.Address, owner))
+		}
+	}
+	return clashes, nil
+}
+
+// Import implements transfer.Section. The player's links on this shard are
+// replaced by the imported ones.
+func (t TransferSection) Import(ctx context.Context, playerID, _ string, data json.RawMessage) error {
+	var links []Link
+	if err := json.Unmarshal(data, &links); err != nil {
+		return fmt.Errorf("decode links: %w", err)
+	}
+	if err := t.Store.DeletePlayer(ctx, playerID); err != nil {
+		return err
+	}
+	for _, link := range links {
+		link.PlayerID = playerID
+		if err := t.Store.Save(ctx, link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retire implements transfer.Section. The links are removed; the player's
+// addresses are linked on the shard they moved to.
+func (t TransferSection) Retire(ctx context.Context, playerID, _ string) error {
+	return t.Store.DeletePlayer(ctx, playerID)
+}
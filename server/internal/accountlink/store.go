@@ -0,0 +1,101 @@
+// Package accountlink links game accounts to the Sui addresses their players
+// control. A player proves control of an address by signing a server-issued
+// message with their wallet; on-chain actions that target the player then use
+// their primary linked address.
+package accountlink
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Link is a verified link between a player and a Sui address.
+type Link struct {
+	PlayerID string    `json:"playerId"`
+	Address  string    `json:"address"` // Normalized: 0x and 64 lowercase hex digits
+	Primary  bool      `json:"primary"` // The address on-chain actions target
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+// Store persists links. An address is linked to at most one player.
+type Store interface {
+	// Links returns the player's links, oldest first.
+	Links(ctx context.Context, playerID string) ([]Link, error)
+	// Owner returns the player an address is linked to, or "" if it is not linked.
+	Owner(ctx context.Context, address string) (string, error)
+	// Save adds or replaces the link for link.Address.
+	Save(ctx context.Context, link Link) error
+	// Delete removes the player's link to address, if there is one.
+	Delete(ctx context.Context, playerID, address string) error
+	// DeletePlayer removes every link of the player.
+	DeletePlayer(ctx context.Context, playerID string) error
+}
+
+// MemoryStore keeps links in memory. It is used when there is no database.
+type MemoryStore struct {
+	mu    sync.Mutex
+	links map[string]Link // By address
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{links: make(map[string]Link)}
+}
+
+// Links implements Store.
+func (m *MemoryStore) Links(ctx context.Context, playerID string) ([]Link, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var links []Link
+	for _, link := range m.links {
+		if link.PlayerID == playerID {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].LinkedAt.Equal(links[j].LinkedAt) {
+			return links[i].LinkedAt.Before(links[j].LinkedAt)
+		}
+		return links[i].Address < links[j].Address
+	})
+	return links, nil
+}
+
+// Owner implements Store.
+func (m *MemoryStore) Owner(ctx context.Context, address string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.links[address].PlayerID, nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(ctx context.Context, link Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links[link.Address] = link
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, playerID, address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.links[address].PlayerID == playerID {
+		delete(m.links, address)
+	}
+	return nil
+}
+
+// DeletePlayer implements Store.
+func (m *MemoryStore) DeletePlayer(ctx context.Context, playerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for address, link := range m.links {
+		if link.PlayerID == playerID {
+			delete(m.links, address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package actor
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/timers"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+const (
+	defaultCombatTurnTimeout = 30 * time.Second
+	defaultCombatMaxRounds   = 50
+	defaultCombatFleeChance  = 0.5
+)
+
+// CombatParticipant is one side's fighter in a CombatSessionActor.
+type CombatParticipant struct {
+	Stats      game.CombatantStats
+	Team       int
+	SessionPID *actor.PID // PlayerSessionActor; nil for NPCs, which always take the default action
+}
+
+// CombatSessionConfig describes a turn-based fight.
+type CombatSessionConfig struct {
+	CombatID     string
+	Participants []CombatParticipant
+	TurnTimeout  time.Duration     // Time a player has to act before the default action; default 30s
+	MaxRounds    int               // The fight is a draw after this many rounds; default 50
+	Mode         string            // Game mode, selects the damage model
+	DamageModel  string            // Explicit damage model, e.g. from the room; overrides Mode
+	FleeChance   float64           // Flee chance against an equally fast enemy; default 0.5
+	Timers       *timers.Scheduler // Turn timeouts; real time if nil
+}
+
+type combatant struct {
+	stats     game.CombatantStats
+	team      int
+	pid       *actor.PID
+	defending bool
+	fled      bool
+	defeated  bool
+}
+
+func (c *combatant) active() bool { return !c.fled && !c.defeated }
+
+// combatDefaultAction is sent to self when a player's turn times out, or right
+// away on an NPC's turn. Turn guards against a stale timer.
+type combatDefaultAction struct {
+	turn     int
+	timedOut bool
+}
+
+// CombatSessionActor runs one turn-based fight. Each round, the active
+// combatants act in order of speed. Players are prompted for an action and get
+// the default action (attack the weakest enemy) if they do not answer within
+// the turn timeout. The state is broadcast after every turn, and the actor
+// stops once one team is left.
+type CombatSessionActor struct {
+	engine     *game.CombatEngine
+	cfg        CombatSessionConfig
+	combatants []*combatant
+	order      []*combatant // This round's turn order
+	current    int          // Index into order
+	round      int
+	turn       int // Turns taken so far, across rounds
+	timer      *timers.Timer
+	endReason  string
+	ended      bool
+	rng        *rand.Rand
+}
+
+// NewCombatSessionActor creates a CombatSessionActor.
+func NewCombatSessionActor(engine *game.CombatEngine, cfg CombatSessionConfig) actor.Actor {
+	if cfg.TurnTimeout <= 0 {
+		cfg.TurnTimeout = defaultCombatTurnTimeout
+	}
+	if cfg.MaxRounds <= 0 {
+		cfg.MaxRounds = defaultCombatMaxRounds
+	}
+	if cfg.FleeChance <= 0 {
+		cfg.FleeChance = defaultCombatFleeChance
+	}
+	a := &CombatSessionActor{engine: engine, cfg: cfg, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for _, p := range cfg.Participants {
+		a.combatants = append(a.combatants, &combatant{stats: p.Stats, team: p.Team, pid: p.SessionPID})
+	}
+	return a
+}
+
+// PropsForCombatSession creates actor.Props for a CombatSessionActor.
+func PropsForCombatSession(engine *game.CombatEngine, cfg CombatSessionConfig) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor { return NewCombatSessionActor(engine, cfg) }, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// Receive is the message handling loop for the CombatSessionActor.
+func (a *CombatSessionActor) Receive(ctx actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *actor.Started:
+		utils.LogInfof("[CombatSessionActor %s] Combat %s started with %d combatants.", ctx.Self().Id, a.cfg.CombatID, len(a.combatants))
+		a.startRound(ctx)
+		a.broadcast(ctx, &messages.CombatStarted{
+			CombatID:   a.cfg.CombatID,
+			CombatPID:  ctx.Self(),
+			Combatants: a.snapshot(),
+			TurnOrder:  a.orderIDs(),
+		})
+		if a.checkEnd(ctx) {
+			return
+		}
+		a.beginTurn(ctx)
+
+	case *actor.Stopping:
+		if a.timer != nil {
+			a.timer.Stop()
+		}
+
+	case *actor.Stopped:
+		utils.LogInfof("[CombatSessionActor %s] Combat %s stopped.", ctx.Self().Id, a.cfg.CombatID)
+
+	case *messages.SubmitCombatAction:
+		a.handleSubmit(ctx, msg)
+
+	case *combatDefaultAction:
+		if a.ended || msg.turn != a.turn {
+			return // Stale timer: the player acted in time
+		}
+		c := a.order[a.current]
+		action, target := a.defaultAction(c)
+		a.resolve(ctx, c, action, target, msg.timedOut)
+
+	default:
+		utils.LogWarnf("[CombatSessionActor %s] Received unknown message: %T", ctx.Self().Id, msg)
+		quarantine.ReportUnhandled(ctx, msg)
+	}
+}
+
+func (a *CombatSessionActor) handleSubmit(ctx actor.Context, msg *messages.SubmitCombatAction) {
+	if a.ended {
+		return
+	}
+	c := a.find(msg.PlayerID)
+	if c == nil || !c.active() {
+		a.reject(ctx, c, "You are not an active combatant in this fight.")
+		return
+	}
+	if msg.Action == protocol.CombatActionForfeit {
+		c.defeated = true
+		a.endReason = "forfeit"
+		utils.LogInfof("[CombatSessionActor %s] %s forfeits combat %s.", ctx.Self().Id, c.stats.ID, a.cfg.CombatID)
+		onTurn := a.order[a.current] == c
+		a.broadcastTurn(ctx, &messages.CombatStateUpdate{ActorID: c.stats.ID, Action: protocol.CombatActionForfeit})
+		if a.checkEnd(ctx) || !onTurn {
+			return
+		}
+		a.advance(ctx)
+		return
+	}
+	if a.order[a.current] != c {
+		a.reject(ctx, c, "It is not your turn.")
+		return
+	}
+	switch msg.Action {
+	case protocol.CombatActionAttack:
+		target := a.find(msg.TargetID)
+		if target == nil || !target.active() || target.team == c.team {
+			a.reject(ctx, c, "Choose an active enemy to attack.")
+			return
+		}
+		a.resolve(ctx, c, msg.Action, target, false)
+	case protocol.CombatActionDefend, protocol.CombatActionFlee:
+		a.resolve(ctx, c, msg.Action, nil, false)
+	default:
+		a.reject(ctx, c, "Unknown combat action: "+msg.Action)
+	}
+}
+
+// resolve applies the current combatant's action, broadcasts the result and moves on.
+func (a *CombatSessionActor) resolve(ctx actor.Context, c *combatant, action string, target *combatant, timedOut bool) {
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	update := &messages.CombatStateUpdate{ActorID: c.stats.ID, Action: action, TimedOut: timedOut}
+	switch action {
+	case protocol.CombatActionAttack:
+		defender := target.stats
+		if target.defending {
+			defender.Defense *= 2
+		}
+		result := a.engine.SimulateCombatTurnWith(c.stats, defender, game.TurnOptions{Mode: a.cfg.Mode, DamageModel: a.cfg.DamageModel})
+		target.stats.Health = result.DefenderHealth
+		target.defeated = result.IsDefenderDefeated
+		update.TargetID = target.stats.ID
+		update.Damage = result.DamageDealt
+		update.Critical = result.IsCriticalHit
+		update.Evaded = result.IsEvaded
+		update.Log = result.CombatLog
+		if target.defeated {
+			a.endReason = "defeated"
+		}
+	case protocol.CombatActionDefend:
+		c.defending = true
+		update.Log = []string{c.stats.ID + " takes a defensive stance."}
+	case protocol.CombatActionFlee:
+		if a.rng.Float64() < a.fleeChance(c) {
+			c.fled = true
+			a.endReason = "fled"
+			update.Log = []string{c.stats.ID + " flees the fight!"}
+		} else {
+			update.Log = []string{c.stats.ID + " tries to flee but is cut off."}
+		}
+	}
+	a.broadcastTurn(ctx, update)
+	if a.checkEnd(ctx) {
+		return
+	}
+	a.advance(ctx)
+}
+
+// fleeChance scales the base chance by the combatant's speed relative to the fastest enemy.
+func (a *CombatSessionActor) fleeChance(c *combatant) float64 {
+	fastest := 0
+	for _, other := range a.combatants {
+		if other.active() && other.team != c.team && other.stats.Speed > fastest {
+			fastest = other.stats.Speed
+		}
+	}
+	chance := a.cfg.FleeChance
+	if fastest > 0 {
+		chance *= float64(c.stats.Speed) / float64(fastest)
+	}
+	if chance > 0.95 {
+		chance = 0.95
+	}
+	return chance
+}
+
+// defaultAction attacks the weakest active enemy.
+func (a *CombatSessionActor) defaultAction(c *combatant) (string, *combatant) {
+	var weakest *combatant
+	for _, other := range a.combatants {
+		if other.active() && other.team != c.team && (weakest == nil || other.stats.Health < weakest.stats.Health) {
+			weakest = other
+		}
+	}
+	if weakest == nil {
+		return protocol.CombatActionDefend, nil
+	}
+	return protocol.CombatActionAttack, weakest
+}
+
+// startRound orders the active combatants by speed, fastest first.
+func (a *CombatSessionActor) startRound(ctx actor.Context) {
+	a.round++
+	a.order = a.order[:0]
+	for _, c := range a.combatants {
+		if c.active() {
+			a.order = append(a.order, c)
+		}
+	}
+	sort.SliceStable(a.order, func(i, j int) bool {
+		if a.order[i].stats.Speed != a.order[j].stats.Speed {
+			return a.order[i].stats.Speed > a.order[j].stats.Speed
+		}
+		return a.order[i].stats.ID < a.order[j].stats.ID
+	})
+	a.current = 0
+}
+
+// advance moves to the next active combatant, starting a new round when needed.
+func (a *CombatSessionActor) advance(ctx actor.Context) {
+	a.current++
+	a.beginTurn(ctx)
+}
+
+// beginTurn prompts the combatant whose turn it is.
+func (a *CombatSessionActor) beginTurn(ctx actor.Context) {
+	for a.current < len(a.order) && !a.order[a.current].active() {
+		a.current++
+	}
+	if a.current >= len(a.order) {
+		if a.round >= a.cfg.MaxRounds {
+			a.end(ctx, -1, "round limit reached")
+			return
+		}
+		a.startRound(ctx)
+	}
+	c := a.order[a.current]
+	a.turn++
+	c.defending = false // A defensive stance lasts until the combatant's next turn
+	self := ctx.Self()
+	if c.pid == nil {
+		ctx.Send(self, &combatDefaultAction{turn: a.turn})
+		return
+	}
+	ctx.Send(c.pid, &messages.CombatTurnPrompt{
+		CombatID:     a.cfg.CombatID,
+		Round:        a.round,
+		Turn:         a.turn,
+		Deadline:     a.cfg.Timers.Now().Add(a.cfg.TurnTimeout),
+		ValidTargets: a.targetsFor(c),
+	})
+	a.timer = a.cfg.Timers.After(ctx.ActorSystem().Root, self, a.cfg.TurnTimeout, &combatDefaultAction{turn: a.turn, timedOut: true})
+}
+
+// checkEnd ends the fight if at most one team has active combatants.
+func (a *CombatSessionActor) checkEnd(ctx actor.Context) bool {
+	teams := make(map[int]bool)
+	for _, c := range a.combatants {
+		if c.active() {
+			teams[c.team] = true
+		}
+	}
+	if len(teams) > 1 {
+		return false
+	}
+	winner := -1
+	for team := range teams {
+		winner = team
+	}
+	reason := a.endReason
+	if reason == "" {
+		reason = "no opponents"
+	}
+	a.end(ctx, winner, reason)
+	return true
+}
+
+func (a *CombatSessionActor) end(ctx actor.Context, winner int, reason string) {
+	a.ended = true
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	utils.LogInfof("[CombatSessionActor %s] Combat %s ended after %d rounds: team %d wins (%s).", ctx.Self().Id, a.cfg.CombatID, a.round, winner, reason)
+	a.broadcast(ctx, &messages.CombatEnded{CombatID: a.cfg.CombatID, WinnerTeam: winner, Reason: reason})
+	ctx.Stop(ctx.Self())
+}
+
+// broadcastTurn fills in the shared fields of a turn update and sends it to every player.
+func (a *CombatSessionActor) broadcastTurn(ctx actor.Context, update *messages.CombatStateUpdate) {
+	update.CombatID = a.cfg.CombatID
+	update.Round = a.round
+	update.Turn = a.turn
+	update.Combatants = a.snapshot()
+	for i := a.current + 1; i < len(a.order); i++ {
+		if a.order[i].active() {
+			update.NextActorID = a.order[i].stats.ID
+			break
+		}
+	}
+	a.broadcast(ctx, update)
+}
+
+func (a *CombatSessionActor) broadcast(ctx actor.Context, msg interface{}) {
+	for _, c := range a.combatants {
+		if c.pid != nil {
+			ctx.Send(c.pid, msg)
+		}
+	}
+}
+
+func (a *CombatSessionActor) reject(ctx actor.Context, c *combatant, reason string) {
+	var pid *actor.PID
+	if c != nil {
+		pid = c.pid
+	} else {
+		pid = ctx.Sender()
+	}
+	if pid != nil {
+		ctx.Send(pid, &messages.CombatActionRejected{CombatID: a.cfg.CombatID, Reason: reason})
+	}
+}
+
+func (a *CombatSessionActor) find(id string) *combatant {
+	for _, c := range a.combatants {
+		if c.stats.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (a *CombatSessionActor) targetsFor(c *combatant) []string {
+	var targets []string
+	for _, other := range a.combatants {
+		if other.active() && other.team != c.team {
+			targets = append(targets, other.stats.ID)
+		}
+	}
+	return targets
+}
+
+func (a *CombatSessionActor) orderIDs() []string {
+	ids := make([]string, len(a.order))
+	for i, c := range a.order {
+		ids[i] = c.stats.ID
+	}
+	return ids
+}
+
+func (a *CombatSessionActor) snapshot() []messages.CombatantState {
+	states := make([]messages.CombatantState, len(a.combatants))
+	for i, c := range a.combatants {
+		states[i] = messages.CombatantState{
+			ID:        c.stats.ID,
+			Team:      c.team,
+			Health:    c.stats.Health,
+			MaxHealth: c.stats.MaxHealth,
+			Defending: c.defending,
+			Fled:      c.fled,
+			Defeated:  c.defeated,
+		}
+	}
+	return states
+}
@@ -0,0 +1,41 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/arena"
+	"github.com/phuhao00/suigserver/server/internal/calendar"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/fairroll"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/orderbook"
+	"github.com/phuhao00/suigserver/server/internal/receipts"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+)
+
+// MessageRegistry lists the messages the game's actors send each other, for
+// the actor message audit: the messages package, this package's own results
+// and timers, Proto.Actor's control messages, the notes services deliver
+// to sessions, and chaos crashes. A new message type sent between actors belongs here.
+func MessageRegistry() *msgaudit.Registry {
+	registry := msgaudit.NewRegistry()
+	registry.AllowPackageOf(&messages.Ping{}, &tradeResult{}, &actor.PoisonPill{})
+	registry.Allow(
+		&arena.MatchFound{},
+		&arena.MatchResult{},
+		&trade.Update{},
+		&gift.Update{},
+		&orderbook.Update{},
+		&mail.Notice{},
+		&calendar.Started{},
+		&receipts.Receipt{},
+		&fairroll.Commitment{},
+		&fairroll.Reveal{},
+		&stats.Delta{},
+		&chaos.Crash{},
+	)
+	return registry
+}
@@ -0,0 +1,87 @@
+package messages
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// --- Chat Channel Messages (between a PlayerSessionActor and the WorldManagerActor) ---
+
+// SetPlayerZone tells the WorldManagerActor which zone a player is in, for
+// zone chat. An empty ZoneID is no zone.
+type SetPlayerZone struct {
+	PlayerID string
+	ZoneID   string
+}
+
+// AllianceChanged tells the WorldManagerActor that guilds joined or left an
+// alliance, so that their online members move to the right alliance channel.
+type AllianceChanged struct {
+	GuildIDs []string
+}
+
+// JoinChannel asks the WorldManagerActor to put a player in a chat channel.
+// It answers with a ChannelList, or a ChannelRefused.
+type JoinChannel struct {
+	PlayerID  string
+	PlayerPID *actor.PID
+	Channel   string
+}
+
+// LeaveChannel asks the WorldManagerActor to take a player out of a chat
+// channel. It answers like JoinChannel.
+type LeaveChannel struct {
+	PlayerID  string
+	PlayerPID *actor.PID
+	Channel   string
+}
+
+// ListChannels asks the WorldManagerActor for the chat channels available to
+// a player. It answers with a ChannelList.
+type ListChannels struct {
+	PlayerID  string
+	PlayerPID *actor.PID
+}
+
+// SendChannelMessage is a chat line for a channel. The WorldManagerActor
+// delivers a ChannelMessage to every member, the sender included, or answers
+// with a ChannelRefused.
+type SendChannelMessage struct {
+	PlayerID  string
+	PlayerPID *actor.PID
+	Channel   string
+	Text      string
+	SentAt    time.Time
+}
+
+// ChannelMessage is a chat line delivered to a member of a channel.
+type ChannelMessage struct {
+	Channel  string
+	Scope    string // Zone or guild ID of zone and guild channels
+	SenderID string
+	Text     string
+	SentAt   time.Time
+}
+
+// ChannelInfo describes one chat channel to a player.
+type ChannelInfo struct {
+	Channel           string
+	Scope             string
+	Joined            bool
+	Members           int
+	AutoJoin          bool
+	MessagesPerMinute int
+}
+
+// ChannelList lists the chat channels available to a player.
+type ChannelList struct {
+	Channels []ChannelInfo
+}
+
+// ChannelRefused tells a player why a channel request failed.
+type ChannelRefused struct {
+	Channel string
+	Code    string // Error code for the client, e.g. CHANNEL_THROTTLED
+	Reason  string
+}
@@ -0,0 +1,77 @@
+package messages
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// --- Turn-Based Combat Messages (between a CombatSessionActor and PlayerSessionActors) ---
+
+// CombatantState is one combatant as shown to the players of a fight.
+type CombatantState struct {
+	ID        string
+	Team      int
+	Health    int
+	MaxHealth int
+	Defending bool
+	Fled      bool
+	Defeated  bool
+}
+
+// CombatStarted tells a player's session which fight it has joined.
+type CombatStarted struct {
+	CombatID   string
+	CombatPID  *actor.PID
+	Combatants []CombatantState
+	TurnOrder  []string
+}
+
+// CombatTurnPrompt asks a player for their action. If none arrives by
+// Deadline, the default action (attack the weakest enemy) is taken.
+type CombatTurnPrompt struct {
+	CombatID     string
+	Round        int
+	Turn         int
+	Deadline     time.Time
+	ValidTargets []string
+}
+
+// SubmitCombatAction is a player's action, sent by their session to the CombatSessionActor.
+// Forfeit is accepted at any time; other actions only on the player's turn.
+type SubmitCombatAction struct {
+	CombatID string
+	PlayerID string
+	Action   string // protocol.CombatAction*
+	TargetID string
+}
+
+// CombatActionRejected is sent back when a submitted action is not allowed.
+type CombatActionRejected struct {
+	CombatID string
+	Reason   string
+}
+
+// CombatStateUpdate is broadcast after every turn.
+type CombatStateUpdate struct {
+	CombatID    string
+	Round       int
+	Turn        int
+	ActorID     string
+	Action      string
+	TargetID    string
+	Damage      int
+	Critical    bool
+	Evaded      bool
+	TimedOut    bool // The action was the default after the turn timed out
+	Log         []string
+	Combatants  []CombatantState
+	NextActorID string
+}
+
+// CombatEnded is broadcast when one team is left standing or the round limit is reached.
+type CombatEnded struct {
+	CombatID   string
+	WinnerTeam int // -1 for a draw
+	Reason     string
+}
@@ -1,5 +1,11 @@
 package messages
 
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
 // This file can contain messages for broader game logic,
 // interactions with systems like WorldManagerActor, CombatEngineActor (if they become actors), etc.
 
@@ -53,3 +59,81 @@ type Pong struct {
 	Timestamp    int64
 	ResponseTime int64
 }
+
+// GetWorldStats asks a WorldManagerActor for its counters. The reply is a WorldStats.
+type GetWorldStats struct{}
+
+// WorldStats are a WorldManagerActor's counters.
+type WorldStats struct {
+	ActivePlayers int
+	ClaimedZones  int
+	PlayersByZone map[string]int // Online players in each zone they entered
+}
+
+// Announcement is a message from the operators. Sent to a WorldManagerActor,
+// it is passed on to every player in the world, whose sessions show it.
+type Announcement struct {
+	Text   string
+	SentAt time.Time
+}
+
+// --- Territory Messages (to the WorldManagerActor) ---
+
+// ClaimZoneRequest asks the WorldManagerActor to apply an on-chain zone claim.
+// The response is a ClaimZoneResponse sent to RequesterPID.
+type ClaimZoneRequest struct {
+	PlayerID     string
+	ZoneID       string
+	TxDigest     string // Executed claim_territory transaction
+	RequesterPID *actor.PID
+}
+
+// ClaimZoneResponse reports whether the zone was granted or a siege was scheduled.
+type ClaimZoneResponse struct {
+	ZoneID        string
+	GuildID       string
+	Granted       bool
+	SiegeStartsAt time.Time // Set when a siege was scheduled
+	SiegeEndsAt   time.Time
+	Error         string
+}
+
+// GetZoneBenefits asks the WorldManagerActor what GuildID gets from ZoneID.
+// It responds with ZoneBenefits.
+type GetZoneBenefits struct {
+	ZoneID  string
+	GuildID string
+}
+
+// ZoneBenefits are the buffs (for the owning guild) or tax (for other guilds) of a zone.
+type ZoneBenefits struct {
+	ZoneID       string
+	OwnerGuildID string
+	Buffs        map[string]float64
+	TaxRate      float64
+	Allied       bool // The owner is allied with GuildID, which pays no tax
+	Error        string
+}
+
+// ResolveSiege reports the winner of a running siege, e.g. from the combat that decided it.
+type ResolveSiege struct {
+	ZoneID        string
+	WinnerGuildID string
+}
+
+// Whisper is a private message. The sender's session sends it to the
+// WorldManagerActor, which delivers it to the recipient's session and echoes
+// it back to the sender, or answers with WhisperFailed.
+type Whisper struct {
+	FromID  string
+	FromPID *actor.PID
+	ToID    string
+	Text    string
+	SentAt  time.Time
+}
+
+// WhisperFailed tells the sender a whisper could not be delivered.
+type WhisperFailed struct {
+	ToID   string
+	Reason string
+}
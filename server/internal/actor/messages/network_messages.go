@@ -2,8 +2,10 @@ package messages
 
 import (
 	"net"
+	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
 )
 
 // ClientConnected is sent to a PlayerSessionActor when a new client connects.
@@ -18,8 +20,14 @@ type ClientDisconnected struct {
 }
 
 // ClientMessage is sent from the network layer to the PlayerSessionActor, containing raw data from the client.
+// For version 2 frames with a non-zero TypeID, Payload is only the message payload, not the JSON envelope.
 type ClientMessage struct {
-	Payload []byte
+	Payload      []byte
+	FrameVersion byte                // protocol.FrameVersionLegacy or protocol.FrameVersion2
+	TypeID       uint16              // Message type ID from a version 2 frame header
+	Flags        protocol.FrameFlags // Version 2 frame flags (already applied, e.g. decompressed)
+	WireSize     int                 // Bytes the frame took on the wire, for session metrics
+	ReceivedAt   time.Time           // When the frame was read, for TIME_SYNC
 }
 
 // ForwardToClient is sent from an actor (e.g., PlayerSessionActor) to the network layer (or a specific connection actor)
@@ -29,8 +37,12 @@ type ForwardToClient struct {
 }
 
 // TerminateSession is a message that can be sent to a PlayerSessionActor to instruct it to shut down.
+// The client is sent DISCONNECT with these fields first.
 type TerminateSession struct {
-	Reason string
+	Code       string // A protocol.Disconnect* code
+	Reason     string
+	Retryable  bool          // The client may reconnect right away
+	RetryAfter time.Duration // How long the client should wait before reconnecting; 0 if unknown
 }
 
 // SentFromNetwork indicates a message originating from the network layer to an actor.
@@ -0,0 +1,33 @@
+package messages
+
+import (
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// --- NPC Messages (between a RoomActor and its NPCActors) ---
+
+// NPCTick is sent by a room to each of its NPCs every tick.
+type NPCTick struct {
+	Players map[string]geometry.Vec // Where the room's players are; shared, do not modify
+	Delta   time.Duration           // Time since the last tick
+}
+
+// RequestNPCPath asks the room for a path.
+type RequestNPCPath struct {
+	NPCID    string
+	From, To geometry.Vec
+}
+
+// NPCPath answers RequestNPCPath.
+type NPCPath struct {
+	Path  []geometry.Vec // Waypoints after From
+	Found bool
+}
+
+// NPCMoved tells the room where an NPC has moved to.
+type NPCMoved struct {
+	NPCID string
+	X, Y  float64
+}
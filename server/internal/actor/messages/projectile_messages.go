@@ -0,0 +1,55 @@
+package messages
+
+// --- Projectile Messages (between a PlayerSessionActor and its RoomActor) ---
+
+// FireProjectile asks the room to fire a projectile for a member. The room
+// broadcasts a ProjectileSpawned, or answers the member's session with a
+// ProjectileRefused.
+type FireProjectile struct {
+	PlayerID     string
+	ProjectileID string
+	X, Y         float64 // Aimed point
+}
+
+// ProjectileRefused tells a member why their projectile was not fired.
+type ProjectileRefused struct {
+	ProjectileID string
+	Error        string
+}
+
+// ProjectileSpawned is broadcast to room members when a projectile is fired.
+type ProjectileSpawned struct {
+	ID           string
+	ProjectileID string
+	OwnerID      string
+	X, Y         float64 // Origin
+	DirX, DirY   float64
+	Speed        float64
+	Range        float64
+	Lift         float64
+	Gravity      float64
+}
+
+// ProjectileHit is broadcast to room members when a projectile hits a player
+// or NPC.
+type ProjectileHit struct {
+	ID       string
+	TargetID string
+	NPC      bool
+	X, Y     float64
+	Damage   int
+	Critical bool
+	Evaded   bool
+	Health   int // The target's health afterwards
+	Defeated bool
+	XP       int            // Earned by the shooter for a defeat
+	Loot     map[string]int // Dropped by a defeat
+}
+
+// ProjectileExpired is broadcast to room members when a projectile ends
+// without hitting anyone.
+type ProjectileExpired struct {
+	ID     string
+	Reason string
+	X, Y   float64
+}
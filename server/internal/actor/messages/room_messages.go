@@ -1,15 +1,35 @@
 package messages
 
-import "github.com/asynkron/protoactor-go/actor"
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/ruleset"
+)
 
 // --- Room Management Messages (typically to a RoomManagerActor) ---
 
+// RoomVisibility controls whether a room is listed and how it can be joined.
+type RoomVisibility string
+
+const (
+	RoomVisibilityPublic     RoomVisibility = "public"      // Listed and used by matchmaking
+	RoomVisibilityPrivate    RoomVisibility = "private"     // Unlisted; joinable by ID (password if set) or invite
+	RoomVisibilityInviteOnly RoomVisibility = "invite_only" // Unlisted; joinable only with an invite
+)
+
 // CreateRoomRequest is sent to a RoomManagerActor to request a new room.
 type CreateRoomRequest struct {
-	RoomID     string // Optional, can be auto-generated
-	RoomName   string
-	MaxPlayers int
-	// Other room parameters (e.g., map ID, game mode)
+	RoomID       string // Optional, can be auto-generated
+	RoomName     string
+	MaxPlayers   int
+	Visibility   RoomVisibility   // Defaults to public
+	PasswordHash string           // Optional, from HashRoomPassword; never the plain password
+	OwnerID      string           // Player creating the room; always admitted
+	MemberIDs    []string         // Players admitted besides the owner, such as a guild event's attendees
+	MapID        string           // Map the room is played on; open ground if empty
+	Rules        *ruleset.Ruleset // Modifiers, validated by the room manager; ruleset.Default if nil
+	// Other room parameters (e.g., game mode)
 	RequesterPID *actor.PID // PID of the actor requesting room creation (e.g. a PlayerSessionActor)
 }
 
@@ -35,23 +55,118 @@ type FindRoomResponse struct {
 	Error   string
 }
 
+// ListRoomsRequest asks the RoomManagerActor for the rooms players may browse.
+type ListRoomsRequest struct {
+	RequesterPID *actor.PID
+}
+
+// RoomSummary describes a listed room.
+type RoomSummary struct {
+	RoomID           string
+	Name             string
+	CurrentPlayers   int
+	MaxPlayers       int
+	PasswordRequired bool
+	Rules            ruleset.Ruleset
+}
+
+// ListRoomsResponse lists public rooms only.
+type ListRoomsResponse struct {
+	Rooms []RoomSummary
+}
+
+// GetRoomStats asks a RoomManagerActor for its counters. The reply is a RoomStats.
+type GetRoomStats struct{}
+
+// RoomStats are a RoomManagerActor's counters.
+type RoomStats struct {
+	Rooms   int
+	Players int // Players in rooms
+}
+
 // --- Room Interaction Messages (typically to a specific RoomActor) ---
 
 // JoinRoomRequest is sent to a RoomActor for a player to join.
 type JoinRoomRequest struct {
-	PlayerID  string
-	PlayerPID *actor.PID // PID of the PlayerSessionActor wishing to join
+	PlayerID    string
+	PlayerPID   *actor.PID        // PID of the PlayerSessionActor wishing to join
+	Password    string            // For password-protected rooms
+	InviteToken string            // For invite-only rooms
+	Appearance  map[string]string // Equipped cosmetics by slot, shown to the other members
+	Transfer    bool              // Moved by the server (see TransferSession), so the password and invites do not apply
 	// CharacterData interface{} // Potentially some character info
 }
 
+// CreateRoomInviteRequest is sent to a RoomActor by a member to invite another player.
+type CreateRoomInviteRequest struct {
+	InviterID string
+	InviteeID string // Optional; empty lets any player redeem the invite
+}
+
+// CreateRoomInviteResponse carries a single-use invite token.
+type CreateRoomInviteResponse struct {
+	RoomID    string
+	Token     string
+	ExpiresAt int64 // Unix seconds
+	Success   bool
+	Error     string
+}
+
 // JoinRoomResponse is sent by the RoomActor back to the PlayerSessionActor.
 type JoinRoomResponse struct {
 	RoomID           string
-	RoomName         string // Name of the room
+	RoomPID          *actor.PID // The RoomActor; set on success
+	RoomName         string     // Name of the room
 	Success          bool
 	Error            string
-	CurrentPlayerIDs []string // List of player IDs currently in the room
-	// Add other relevant room state if needed, e.g., map ID, game mode
+	CurrentPlayerIDs []string   // List of player IDs currently in the room
+	MapID            string     // Map the room is played on; empty for open ground
+	State            *RoomState // What the room looks like as the player arrives; set on success
+}
+
+// RoomStateRequest is sent to a RoomActor by a member's session for a
+// snapshot of the room. The RoomActor responds with a *RoomState.
+type RoomStateRequest struct {
+	PlayerID string
+}
+
+// RoomState is a snapshot of a room, so that a player who arrives late sees
+// it as the others do. Error is set instead if the player is not a member.
+type RoomState struct {
+	RoomID  string
+	Error   string
+	Members []RoomMemberState // By player ID
+	Chat    []RoomChatMessage // The latest room chat, oldest first; Timestamp is Unix milliseconds
+	NPCs    []RoomNPCState    // By ID
+	Tick    RoomTickState
+	Rules   ruleset.Ruleset
+	TakenAt time.Time
+}
+
+// RoomMemberState is one member in a RoomState.
+type RoomMemberState struct {
+	PlayerID   string
+	X, Y       float64
+	Health     int
+	Eliminated bool              // Out of a hardcore room until they leave
+	VoiceMuted bool              // Muted by themselves or the room owner
+	Appearance map[string]string // Equipped cosmetics by slot; nil if none
+}
+
+// RoomNPCState is one NPC in a RoomState.
+type RoomNPCState struct {
+	ID        string
+	X, Y      float64
+	Health    int // 0 if it cannot be damaged
+	MaxHealth int
+}
+
+// RoomTickState is where the room's simulation stands.
+type RoomTickState struct {
+	Running     bool          // The room ticks while it has players and NPCs or projectiles
+	Interval    time.Duration // Time between ticks
+	Count       uint64        // Ticks run since the room started
+	Projectiles int           // Projectiles in flight
 }
 
 // LeaveRoomRequest is sent to a RoomActor.
@@ -105,3 +220,86 @@ type PlayerActionInRoom struct {
 	Params     map[string]interface{}
 	Timestamp  int64
 }
+
+// --- Voice Chat Signaling (relayed by a RoomActor; audio never passes through the server) ---
+
+// RelayVoiceSignal asks a RoomActor to forward a WebRTC signaling message to another member.
+type RelayVoiceSignal struct {
+	MsgType      string // protocol.MsgTypeVoiceOffer, MsgTypeVoiceAnswer or MsgTypeVoiceICECandidate
+	FromPlayerID string
+	ToPlayerID   string
+	Payload      interface{} // Protocol payload, with FromPlayerID already set
+}
+
+// VoiceSignal is delivered to the target member's PlayerSessionActor.
+type VoiceSignal struct {
+	MsgType string
+	Payload interface{}
+}
+
+// VoiceSignalRejected is sent back to the sender when a signal cannot be relayed.
+type VoiceSignalRejected struct {
+	MsgType string
+	Reason  string
+}
+
+// SetVoiceMute changes a member's mute state. TargetPlayerID is empty for self-mute.
+type SetVoiceMute struct {
+	PlayerID       string
+	TargetPlayerID string
+	Muted          bool
+}
+
+// VoiceMuteChanged is broadcast to room members when a member's mute state changes.
+type VoiceMuteChanged struct {
+	PlayerID    string
+	Muted       bool
+	ByModerator bool
+}
+
+// PlayerAFKChanged is broadcast to room members when a member goes AFK or comes back.
+type PlayerAFKChanged struct {
+	PlayerID string
+	AFK      bool
+	MovedTo  string // Lobby room the player is being moved to, if any
+}
+
+// AppearanceChanged is sent to the room when a member changes what they
+// wear, and by the room to the other members.
+type AppearanceChanged struct {
+	PlayerID string
+	Equipped map[string]string // Slot -> cosmetic ID
+}
+
+// MovePlayer reports a member's own movement to the room.
+type MovePlayer struct {
+	PlayerID string
+	X, Y     float64
+}
+
+// UseMovementAbility asks the room to move a member with a movement ability.
+// The room answers the member's session with a MovementAbilityResult.
+type UseMovementAbility struct {
+	PlayerID  string
+	AbilityID string
+	X, Y      float64 // Target point
+}
+
+// MovementAbilityResult tells the caster where a movement ability took them.
+type MovementAbilityResult struct {
+	AbilityID string
+	Success   bool
+	Error     string
+	X, Y      float64       // The caster's position afterwards
+	Cooldown  time.Duration // Until the ability can be used again
+}
+
+// PositionChanged is broadcast to room members when a member moves. It is
+// sent to the mover alone, Corrected, when the room refuses a move.
+type PositionChanged struct {
+	PlayerID  string
+	X, Y      float64
+	AbilityID string // Set if a movement ability moved the player
+	Corrected bool
+	NPC       bool // PlayerID is one of the room's NPCs
+}
@@ -1,11 +1,21 @@
 package messages
 
-import "github.com/asynkron/protoactor-go/actor"
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
 
 // AuthenticatePlayer is sent to a PlayerSessionActor with credentials or a token.
 type AuthenticatePlayer struct {
 	Token    string
 	PlayerID string // Or other identifying information
+	WorldID  string // Game world to enter; empty for the default world
+	Reliable bool   // The client acknowledges sequenced messages
+	LastSeq  uint64 // Last seq the client processed in an earlier session, to replay from
+	// Resume token sent instead of Token, and the device it was issued to
+	ResumeToken string
+	DeviceID    string
 }
 
 // PlayerAuthenticated is sent back from PlayerSessionActor or an AuthActor
@@ -71,3 +81,68 @@ type PlayerLeftWorld struct {
 	PlayerID  string
 	PlayerPID *actor.PID
 }
+
+// GetPlayerSession asks a WorldManagerActor for an online player's session.
+// It responds with a PlayerSessionLocation.
+type GetPlayerSession struct {
+	PlayerID string
+}
+
+// PlayerSessionLocation answers GetPlayerSession. SessionPID is nil if the
+// player is not online in that world.
+type PlayerSessionLocation struct {
+	PlayerID   string
+	SessionPID *actor.PID
+}
+
+// TransferSession asks a PlayerSessionActor to move its player to another
+// room, e.g. through a portal, after a dungeon or on an operator's request.
+// The new room is joined before the old one is left, so a refused transfer
+// leaves the player where they were. Room passwords and invites do not apply.
+// It responds with a TransferResult when sent as a request.
+type TransferSession struct {
+	RoomID string // Room ID or criteria, as in JOIN_ROOM
+	Reason string // Shown to the client, e.g. "portal", "dungeon_complete", "admin"
+}
+
+// TransferResult answers TransferSession.
+type TransferResult struct {
+	FromRoomID string `json:"fromRoomId,omitempty"`
+	RoomID     string `json:"roomId,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// GetSessionDiagnostics asks a PlayerSessionActor for its state and traffic
+// counters. It responds with a SessionDiagnostics.
+type GetSessionDiagnostics struct{}
+
+// SessionDiagnostics describe one player session, for support tickets.
+type SessionDiagnostics struct {
+	PlayerID           string           `json:"playerId"`
+	SessionID          string           `json:"sessionId"` // Session actor ID, as it appears in the logs
+	RemoteAddr         string           `json:"remoteAddr"`
+	WorldID            string           `json:"worldId"`
+	RoomID             string           `json:"roomId,omitempty"`
+	CombatID           string           `json:"combatId,omitempty"`
+	AFK                bool             `json:"afk"`
+	ConnectedAt        time.Time        `json:"connectedAt"`
+	AuthenticatedAt    time.Time        `json:"authenticatedAt"`
+	ConnectionAge      float64          `json:"connectionAgeSeconds"`
+	LastActivity       time.Time        `json:"lastActivity"`
+	BytesIn            int64            `json:"bytesIn"` // Frames as read from the wire, before decompression
+	BytesOut           int64            `json:"bytesOut"`
+	FramesIn           int64            `json:"framesIn"`
+	FramesOut          int64            `json:"framesOut"`
+	MessagesIn         map[string]int64 `json:"messagesIn"`         // Client messages by type
+	MessagesOut        map[string]int64 `json:"messagesOut"`        // Responses and notifications by type; envelopes relayed from rooms count only in FramesOut
+	PendingSuiRequests map[string]int   `json:"pendingSuiRequests"` // On-chain checks still running, by the client message that started them
+	RecentErrors       []SessionError   `json:"recentErrors"`       // Error responses sent to the client, oldest first
+}
+
+// SessionError is an error response a session sent to its client.
+type SessionError struct {
+	At      time.Time `json:"at"`
+	Code    string    `json:"code"`
+	Message string    `json:"message"`
+}
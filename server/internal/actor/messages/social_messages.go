@@ -0,0 +1,12 @@
+package messages
+
+// --- Social Messages (between a PlayerSessionActor and its RoomActor) ---
+
+// SocialAction is an emote, map ping or quick reply of a room member. The room
+// broadcasts it to every member, the sender included.
+type SocialAction struct {
+	PlayerID string
+	Kind     string // protocol.SocialEmote, SocialPing or SocialQuickReply
+	Name     string
+	X, Y     float64 // Pings only
+}
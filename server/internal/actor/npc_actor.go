@@ -0,0 +1,54 @@
+package actor
+
+import (
+	"log"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/npc"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+)
+
+// NPCActor runs one NPC of a room. It is a child of the RoomActor, which
+// ticks it, finds its paths and shows its moves to the players.
+type NPCActor struct {
+	id    string
+	brain *npc.Brain
+}
+
+// NewNPCActor creates an NPCActor standing at the NPC's spawn point.
+func NewNPCActor(def npc.Definition) actor.Actor {
+	return &NPCActor{id: def.ID, brain: npc.NewBrain(def)}
+}
+
+// Receive is the message handling loop for the NPCActor.
+func (a *NPCActor) Receive(ctx actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *actor.Started, *actor.Stopping, *actor.Stopped:
+
+	case *messages.NPCTick:
+		goal, needPath, moved := a.brain.Tick(msg.Players, msg.Delta)
+		if needPath {
+			ctx.Send(ctx.Parent(), &messages.RequestNPCPath{NPCID: a.id, From: a.brain.Position(), To: goal})
+		}
+		if moved {
+			pos := a.brain.Position()
+			ctx.Send(ctx.Parent(), &messages.NPCMoved{NPCID: a.id, X: pos.X, Y: pos.Y})
+		}
+
+	case *messages.NPCPath:
+		a.brain.SetPath(msg.Path, msg.Found)
+
+	default:
+		log.Printf("[NPCActor %s] Received unknown message: %T", a.id, msg)
+		quarantine.ReportUnhandled(ctx, msg)
+	}
+}
+
+// PropsForNPC creates actor.Props for an NPCActor.
+func PropsForNPC(def npc.Definition) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor { return NewNPCActor(def) }, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
@@ -0,0 +1,115 @@
+package actor
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// PlayerRegistryShards is how many registry actors a world manager splits
+// its online players across.
+const PlayerRegistryShards = 16
+
+// PlayerRegistryPID returns the registry actor of world that holds playerID.
+// Whispers and GetPlayerSession go there directly, so lookups are spread over
+// the shards' mailboxes rather than queued behind everything else the world
+// manager does. The registries exist once the world manager has started;
+// lookups sent to the world manager itself are forwarded. Sessions still
+// send PlayerEnteredWorld and PlayerLeftWorld to the world manager, which
+// keeps their chat channels in order with their other messages and forwards
+// them on.
+func PlayerRegistryPID(world *actor.PID, playerID string) *actor.PID {
+	return actor.NewPID(world.Address, world.Id+"/"+registryName(registryShard(playerID)))
+}
+
+// registryShard hashes playerID to a shard with FNV-1a, computed inline so
+// routing does not allocate.
+func registryShard(playerID string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(playerID); i++ {
+		h ^= uint32(playerID[i])
+		h *= 16777619
+	}
+	return int(h % PlayerRegistryShards)
+}
+
+func registryName(shard int) string {
+	return "players-" + strconv.Itoa(shard)
+}
+
+// playerRegistryActor holds the sessions of the online players of one
+// shard. Only Receive touches its map, so it needs no lock.
+type playerRegistryActor struct {
+	players map[string]*actor.PID // PlayerID to PlayerSessionActor PID
+	online  *atomic.Int64         // Players of every shard, for world stats
+}
+
+// deliverToPlayers sends Message to those of PlayerIDs who are online in the shard.
+type deliverToPlayers struct {
+	PlayerIDs []string
+	Message   interface{}
+}
+
+func propsForPlayerRegistry(online *atomic.Int64) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor {
+		return &playerRegistryActor{players: make(map[string]*actor.PID), online: online}
+	}, actor.WithReceiverMiddleware(quarantine.Guard, handlermetrics.Receiver))
+}
+
+// Receive is the message handling loop for a playerRegistryActor.
+func (a *playerRegistryActor) Receive(ctx actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *actor.Started, *actor.Stopping, *actor.Stopped, *actor.Restarting:
+
+	case *messages.PlayerEnteredWorld:
+		if _, exists := a.players[msg.PlayerID]; exists {
+			utils.LogWarnf("[WorldManagerActor %s] Player %s (PID: %s) already marked as active. Ignoring duplicate PlayerEnteredWorld.",
+				ctx.Parent().Id, msg.PlayerID, msg.PlayerPID.Id)
+			return
+		}
+		a.players[msg.PlayerID] = msg.PlayerPID
+		a.online.Add(1)
+
+	case *messages.PlayerLeftWorld:
+		if _, exists := a.players[msg.PlayerID]; !exists {
+			utils.LogWarnf("[WorldManagerActor %s] Player %s (PID: %s) not found in active players list. Ignoring PlayerLeftWorld.",
+				ctx.Parent().Id, msg.PlayerID, msg.PlayerPID.Id)
+			return
+		}
+		delete(a.players, msg.PlayerID)
+		a.online.Add(-1)
+
+	case *messages.GetPlayerSession:
+		ctx.Respond(&messages.PlayerSessionLocation{PlayerID: msg.PlayerID, SessionPID: a.players[msg.PlayerID]})
+
+	case *messages.Whisper:
+		recipientPID, online := a.players[msg.ToID]
+		if !online {
+			ctx.Send(msg.FromPID, &messages.WhisperFailed{ToID: msg.ToID, Reason: "Player " + msg.ToID + " is not online."})
+			return
+		}
+		ctx.Send(recipientPID, msg)
+		ctx.Send(msg.FromPID, msg)
+
+	case *messages.Announcement:
+		for _, pid := range a.players {
+			ctx.Send(pid, msg)
+		}
+
+	case *deliverToPlayers:
+		for _, id := range msg.PlayerIDs {
+			if pid, ok := a.players[id]; ok {
+				ctx.Send(pid, msg.Message)
+			}
+		}
+
+	default:
+		utils.LogWarnf("[PlayerRegistryActor %s] Received unknown message: %T", ctx.Self().Id, msg)
+		quarantine.ReportUnhandled(ctx, msg)
+	}
+}
@@ -0,0 +1,81 @@
+package actor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// roomInviteTTL is how long an unused room invite stays valid.
+const roomInviteTTL = 10 * time.Minute
+
+// RoomAccess holds a room's visibility and join restrictions.
+type RoomAccess struct {
+	Visibility   messages.RoomVisibility
+	PasswordHash string   // From HashRoomPassword; empty means no password
+	OwnerID      string   // Player who created the room; always admitted
+	MemberIDs    []string // Players always admitted besides the owner
+}
+
+// Listed reports whether the room appears in room listings and matchmaking.
+func (r RoomAccess) Listed() bool {
+	return r.Visibility == "" || r.Visibility == messages.RoomVisibilityPublic
+}
+
+// ParseRoomVisibility validates a visibility string from a client. Empty means public.
+func ParseRoomVisibility(value string) (messages.RoomVisibility, error) {
+	switch visibility := messages.RoomVisibility(strings.ToLower(value)); visibility {
+	case "", messages.RoomVisibilityPublic:
+		return messages.RoomVisibilityPublic, nil
+	case messages.RoomVisibilityPrivate, messages.RoomVisibilityInviteOnly:
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("unknown room visibility %q", value)
+	}
+}
+
+// HashRoomPassword returns a salted SHA-256 hash of password in the form "sha256$<salt>$<hash>".
+func HashRoomPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate password salt: %w", err)
+	}
+	saltHex := hex.EncodeToString(salt)
+	return "sha256$" + saltHex + "$" + roomPasswordDigest(saltHex, password), nil
+}
+
+func roomPasswordDigest(saltHex, password string) string {
+	sum := sha256.Sum256([]byte(saltHex + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyRoomPassword checks password against a hash from HashRoomPassword.
+func verifyRoomPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 3 || parts[0] != "sha256" {
+		return false
+	}
+	digest := roomPasswordDigest(parts[1], password)
+	return subtle.ConstantTimeCompare([]byte(digest), []byte(parts[2])) == 1
+}
+
+// roomInvite is an outstanding single-use invite issued by a room member.
+type roomInvite struct {
+	inviterID string
+	inviteeID string // Empty if anyone may redeem it
+	expiresAt time.Time
+}
+
+func newInviteToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
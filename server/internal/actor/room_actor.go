@@ -6,6 +6,16 @@ import (
 
 	"github.com/asynkron/protoactor-go/actor"
 	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/npc"
+	"github.com/phuhao00/suigserver/server/internal/pathfinding"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/ruleset"
+	"github.com/phuhao00/suigserver/server/internal/timers"
 	// "sui-mmo-server/server/internal/models" // For Room model if needed
 )
 
@@ -15,14 +25,39 @@ type RoomActor struct {
 	roomID         string
 	roomName       string
 	maxPlayers     int
-	players        map[string]*actor.PID // Map PlayerID to PlayerSessionActor PID
-	roomManagerPID *actor.PID            // PID of the RoomManagerActor to send updates
+	players        map[string]*actor.PID        // Map PlayerID to PlayerSessionActor PID
+	roomManagerPID *actor.PID                   // PID of the RoomManagerActor to send updates
+	access         RoomAccess                   // Visibility, password and owner
+	invites        map[string]roomInvite        // Outstanding invite tokens
+	voiceMutes     map[string]voiceMuteState    // Voice mute state of muted members
+	fanOut         bool                         // Broadcasts go through the broadcaster pool; see broadcastMessage
+	services       RoomServices                 // Movement rules, NPCs, pathfinding and combat
+	terrain        *geometry.Map                // Collision data of the room's map; open ground if nil
+	positions      map[string]geometry.Vec      // Members' positions
+	npcs           map[string]*roomNPC          // NPCs of the room's map by ID
+	npcDefs        []npc.Definition             // The map's NPCs, which extra NPCs copy
+	npcExtras      int                          // Extra NPCs spawned for the map's NPC density
+	planner        *pathfinding.Planner         // Finds the NPCs' paths within a budget per tick
+	projectiles    *projectile.Simulation       // Projectiles in flight; created by the first shot
+	health         map[string]int               // Members' health once they have been hit
+	appearances    map[string]map[string]string // Members' equipped cosmetics by slot, if they wear any
+	rules          ruleset.Ruleset              // Modifiers the room was created with
+	eliminated     map[string]bool              // Members defeated in a hardcore room, out until they leave
+	tickTimer      *timers.Timer                // Moves NPCs and projectiles; runs while the room has players
+	densityTimer   *timers.Timer                // Reports members' positions to the hot zone monitor while the room has players
+	ticks          uint64                       // Ticks run since the room started
+	chatTail       []roomChatLine               // The latest chat, for players who join later
 	// other room-specific state, e.g., game state, NPCs, etc.
 }
 
-// NewRoomActor creates a new RoomActor instance.
+// NewRoomActor creates a new public RoomActor instance.
 // It now requires roomManagerPID to send updates like player count.
 func NewRoomActor(roomID, roomName string, maxPlayers int, system *actor.ActorSystem, roomManagerPID *actor.PID) actor.Actor {
+	return NewRoomActorWithAccess(roomID, roomName, maxPlayers, RoomAccess{Visibility: messages.RoomVisibilityPublic}, system, roomManagerPID)
+}
+
+// NewRoomActorWithAccess creates a RoomActor with the given visibility and join restrictions.
+func NewRoomActorWithAccess(roomID, roomName string, maxPlayers int, access RoomAccess, system *actor.ActorSystem, roomManagerPID *actor.PID) actor.Actor {
 	return &RoomActor{
 		actorSystem:    system,
 		roomID:         roomID,
@@ -30,6 +65,14 @@ func NewRoomActor(roomID, roomName string, maxPlayers int, system *actor.ActorSy
 		maxPlayers:     maxPlayers,
 		players:        make(map[string]*actor.PID),
 		roomManagerPID: roomManagerPID,
+		access:         access,
+		invites:        make(map[string]roomInvite),
+		voiceMutes:     make(map[string]voiceMuteState),
+		positions:      make(map[string]geometry.Vec),
+		health:         make(map[string]int),
+		appearances:    make(map[string]map[string]string),
+		rules:          ruleset.Default,
+		eliminated:     make(map[string]bool),
 	}
 }
 
@@ -39,9 +82,14 @@ func (a *RoomActor) Receive(ctx actor.Context) {
 	case *actor.Started:
 		log.Printf("[RoomActor %s - %s] Started. Max players: %d.", a.roomID, ctx.Self().Id, a.maxPlayers)
 		a.notifyManagerPlayerCountChanged(ctx) // Notify manager on start (0 players)
+		a.spawnNPCs(ctx)
 
 	case *actor.Stopping:
 		log.Printf("[RoomActor %s - %s] Stopping. Notifying players...", a.roomID, ctx.Self().Id)
+		a.tickTimer.Stop()
+		a.services.Load.ForgetRoom(a.loadKey(ctx))
+		a.densityTimer.Stop()
+		a.services.Density.Forget(a.loadKey(ctx))
 		// Notify all players that the room is closing
 		shutdownMsg := &messages.ForwardToClient{Payload: []byte("Room '" + a.roomName + "' is shutting down.\n")}
 		// Create a temporary list of PIDs to avoid issues if a player leaves during this broadcast
@@ -68,11 +116,51 @@ func (a *RoomActor) Receive(ctx actor.Context) {
 	case *messages.LeaveRoomRequest:
 		a.handleLeaveRoomRequest(ctx, msg)
 
+	case *messages.RoomStateRequest:
+		a.handleRoomStateRequest(ctx, msg)
+
+	case *messages.CreateRoomInviteRequest:
+		a.handleCreateRoomInviteRequest(ctx, msg)
+
+	case *messages.RelayVoiceSignal:
+		a.handleRelayVoiceSignal(ctx, msg)
+
+	case *messages.SetVoiceMute:
+		a.handleSetVoiceMute(ctx, msg)
+
 	case *messages.BroadcastToRoom:
 		a.handleBroadcastToRoom(ctx, msg)
 
+	case *messages.MovePlayer:
+		a.handleMovePlayer(ctx, msg)
+
+	case *messages.UseMovementAbility:
+		a.handleUseMovementAbility(ctx, msg)
+
+	case *messages.FireProjectile:
+		a.handleFireProjectile(ctx, msg)
+
+	case *messages.SocialAction:
+		a.handleSocialAction(ctx, msg)
+
+	case *messages.AppearanceChanged:
+		a.handleAppearanceChanged(ctx, msg)
+
+	case *densityReport:
+		a.handleDensityReport(ctx)
+
+	case *roomTick:
+		a.handleRoomTick(ctx)
+
+	case *messages.RequestNPCPath:
+		a.handleRequestNPCPath(msg)
+
+	case *messages.NPCMoved:
+		a.handleNPCMoved(ctx, msg)
+
 	default:
 		log.Printf("[RoomActor %s - %s] Received unknown message: %T %+v", a.roomID, ctx.Self().Id, msg, msg)
+		quarantine.ReportUnhandled(ctx, msg)
 	}
 }
 
@@ -99,6 +187,16 @@ func (a *RoomActor) handleJoinRoomRequest(ctx actor.Context, msg *messages.JoinR
 		return
 	}
 
+	if reason := a.checkJoinAccess(msg); reason != "" {
+		log.Printf("[RoomActor %s] Join denied for %s: %s", a.roomID, msg.PlayerID, reason)
+		ctx.Respond(&messages.JoinRoomResponse{
+			RoomID:  a.roomID,
+			Success: false,
+			Error:   reason,
+		})
+		return
+	}
+
 	a.players[msg.PlayerID] = msg.PlayerPID
 	log.Printf("[RoomActor %s] Player %s joined. Total players: %d/%d", a.roomID, msg.PlayerID, len(a.players), a.maxPlayers)
 
@@ -118,10 +216,12 @@ func (a *RoomActor) handleJoinRoomRequest(ctx actor.Context, msg *messages.JoinR
 	// Respond to the joining player
 	ctx.Respond(&messages.JoinRoomResponse{
 		RoomID:           a.roomID,
+		RoomPID:          ctx.Self(), // Respond does not pass the sender along
 		Success:          true,
 		CurrentPlayerIDs: currentPlayersInRoom, // Send current players list
 		RoomName:         a.roomName,           // Send room name
-		// Add other relevant room state if needed
+		MapID:            a.mapID(),
+		State:            a.snapshot(msg),
 	})
 
 	// Broadcast to other players in the room that a new player joined
@@ -132,6 +232,83 @@ func (a *RoomActor) handleJoinRoomRequest(ctx actor.Context, msg *messages.JoinR
 	}
 	// Send to all other players (exclude the new player from *this* specific broadcast)
 	a.broadcastMessage(ctx, msg.PlayerPID, joinBroadcast)
+	a.sendVoiceMuteStates(ctx, msg.PlayerPID)
+	a.placeMember(ctx, msg.PlayerID, msg.PlayerPID)
+	a.showAppearance(ctx, msg.PlayerID, msg.PlayerPID, msg.Appearance)
+	a.updateTick(ctx)
+	a.updateDensityReports(ctx)
+}
+
+// checkJoinAccess applies the room's visibility, password and invite rules.
+// It returns a reason for denial, or "" if the player may join. A valid invite
+// token is consumed.
+func (a *RoomActor) checkJoinAccess(msg *messages.JoinRoomRequest) string {
+	if msg.Transfer || a.access.OwnerID != "" && msg.PlayerID == a.access.OwnerID {
+		return ""
+	}
+	for _, memberID := range a.access.MemberIDs {
+		if msg.PlayerID == memberID {
+			return ""
+		}
+	}
+	if msg.InviteToken != "" {
+		invite, ok := a.invites[msg.InviteToken]
+		switch {
+		case !ok || time.Now().After(invite.expiresAt):
+			delete(a.invites, msg.InviteToken)
+			return "Invite is invalid or has expired."
+		case invite.inviteeID != "" && invite.inviteeID != msg.PlayerID:
+			return "Invite was issued to another player."
+		}
+		if _, stillMember := a.players[invite.inviterID]; !stillMember {
+			delete(a.invites, msg.InviteToken)
+			return "Invite is no longer valid because the inviting player left the room."
+		}
+		delete(a.invites, msg.InviteToken)
+		return ""
+	}
+	if a.access.Visibility == messages.RoomVisibilityInviteOnly {
+		return "This room is invite-only."
+	}
+	if a.access.PasswordHash != "" && !verifyRoomPassword(a.access.PasswordHash, msg.Password) {
+		return "Incorrect room password."
+	}
+	return ""
+}
+
+// handleCreateRoomInviteRequest issues a single-use invite token on behalf of a current member.
+func (a *RoomActor) handleCreateRoomInviteRequest(ctx actor.Context, msg *messages.CreateRoomInviteRequest) {
+	if _, isMember := a.players[msg.InviterID]; !isMember {
+		ctx.Respond(&messages.CreateRoomInviteResponse{
+			RoomID:  a.roomID,
+			Success: false,
+			Error:   "Only players in the room can create invites.",
+		})
+		return
+	}
+
+	now := time.Now()
+	for token, invite := range a.invites {
+		if now.After(invite.expiresAt) {
+			delete(a.invites, token)
+		}
+	}
+	token, err := newInviteToken()
+	if err != nil {
+		log.Printf("[RoomActor %s] Failed to create invite for %s: %v", a.roomID, msg.InviterID, err)
+		ctx.Respond(&messages.CreateRoomInviteResponse{RoomID: a.roomID, Success: false, Error: "Failed to create invite."})
+		return
+	}
+	invite := roomInvite{inviterID: msg.InviterID, inviteeID: msg.InviteeID, expiresAt: now.Add(roomInviteTTL)}
+	a.invites[token] = invite
+	log.Printf("[RoomActor %s] Player %s created an invite (invitee: %q).", a.roomID, msg.InviterID, msg.InviteeID)
+
+	ctx.Respond(&messages.CreateRoomInviteResponse{
+		RoomID:    a.roomID,
+		Token:     token,
+		ExpiresAt: invite.expiresAt.Unix(),
+		Success:   true,
+	})
 }
 
 func (a *RoomActor) handleLeaveRoomRequest(ctx actor.Context, msg *messages.LeaveRoomRequest) {
@@ -141,10 +318,17 @@ func (a *RoomActor) handleLeaveRoomRequest(ctx actor.Context, msg *messages.Leav
 		// Verify if the PID matches, for security or consistency
 		if msg.PlayerPID != nil && actualPID.Equal(msg.PlayerPID) {
 			delete(a.players, msg.PlayerID)
+			delete(a.voiceMutes, msg.PlayerID)
+			delete(a.positions, msg.PlayerID)
+			delete(a.health, msg.PlayerID)
+			delete(a.appearances, msg.PlayerID)
+			delete(a.eliminated, msg.PlayerID)
 			log.Printf("[RoomActor %s] Player %s left. Total players: %d/%d", a.roomID, msg.PlayerID, len(a.players), a.maxPlayers)
 
 			// Notify RoomManager about player count change
 			a.notifyManagerPlayerCountChanged(ctx)
+			a.updateTick(ctx)
+			a.updateDensityReports(ctx)
 
 			// Broadcast to remaining players
 			leaveBroadcast := &messages.PlayerLeftRoomBroadcast{
@@ -173,6 +357,7 @@ func (a *RoomActor) handleBroadcastToRoom(ctx actor.Context, msg *messages.Broad
 	// Example: For RoomChatMessage, log sender and message
 	if chatMsg, ok := msg.ActualMessage.(*messages.RoomChatMessage); ok {
 		log.Printf("[RoomActor %s] Broadcasting chat from %s: '%s'", a.roomID, chatMsg.SenderName, chatMsg.Message)
+		a.recordChat(chatMsg)
 	} else {
 		log.Printf("[RoomActor %s] Broadcasting generic message of type %T", a.roomID, msg.ActualMessage)
 	}
@@ -185,20 +370,40 @@ func (a *RoomActor) handleBroadcastToRoom(ctx actor.Context, msg *messages.Broad
 }
 
 // broadcastMessage sends a message to all players in the room, optionally excluding one PID.
+// Messages the sessions forward to their clients are serialized here once for
+// everyone. Once the room has grown to broadcastFanOutThreshold players its
+// broadcasts are delivered by the broadcaster pool, for the rest of its life
+// so that they keep their order.
 func (a *RoomActor) broadcastMessage(ctx actor.Context, excludePID *actor.PID, message interface{}) {
 	if len(a.players) == 0 {
 		return // No one to broadcast to
 	}
 	log.Printf("[RoomActor %s] Broadcasting message type %T to %d players (excluding: %v)",
 		a.roomID, message, len(a.players), excludePID != nil)
+	a.deliverBroadcast(ctx, excludePID, message)
+}
 
+// deliverBroadcast is broadcastMessage without the log line, for messages as
+// frequent as NPC moves.
+func (a *RoomActor) deliverBroadcast(ctx actor.Context, excludePID *actor.PID, message interface{}) {
+	recipients := make([]*actor.PID, 0, len(a.players))
 	for _, playerPID := range a.players {
 		if excludePID != nil && playerPID.Equal(excludePID) {
 			continue // Skip the excluded player
 		}
-		// The message being sent here must be something the PlayerSessionActor understands
-		// and can forward to its client. If it's already a ForwardToClient message, that's fine.
-		// If it's a structured message like RoomChatMessage, PlayerSessionActor needs a case for it.
+		recipients = append(recipients, playerPID)
+	}
+	// The message being sent here must be something the PlayerSessionActor understands
+	message = prepareBroadcast(message)
+	if len(recipients) >= broadcastFanOutThreshold && !a.fanOut {
+		log.Printf("[RoomActor %s] %d players: broadcasting through the broadcaster pool from now on.", a.roomID, len(recipients))
+		a.fanOut = true
+	}
+	if a.fanOut {
+		fanOut(ctx.ActorSystem().Root, recipients, message)
+		return
+	}
+	for _, playerPID := range recipients {
 		ctx.Send(playerPID, message)
 	}
 }
@@ -218,8 +423,31 @@ func (a *RoomActor) notifyManagerPlayerCountChanged(ctx actor.Context) {
 	log.Printf("[RoomActor %s] Notified RoomManager. Current players: %d/%d", a.roomID, len(a.players), a.maxPlayers)
 }
 
-// PropsForRoom creates actor.Props for RoomActor.
+// PropsForRoom creates actor.Props for a public RoomActor.
 // It now requires roomManagerPID.
 func PropsForRoom(roomID, roomName string, maxPlayers int, system *actor.ActorSystem, roomManagerPID *actor.PID) *actor.Props {
-	return actor.PropsFromProducer(func() actor.Actor { return NewRoomActor(roomID, roomName, maxPlayers, system, roomManagerPID) })
+	return actor.PropsFromProducer(func() actor.Actor { return NewRoomActor(roomID, roomName, maxPlayers, system, roomManagerPID) }, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// PropsForRoomWithAccess creates actor.Props for a RoomActor with join restrictions.
+func PropsForRoomWithAccess(roomID, roomName string, maxPlayers int, access RoomAccess, system *actor.ActorSystem, roomManagerPID *actor.PID) *actor.Props {
+	return PropsForRoomOnMap(roomID, roomName, maxPlayers, access, nil, ruleset.Default, RoomServices{}, system, roomManagerPID)
+}
+
+// PropsForRoomOnMap creates actor.Props for a RoomActor played on terrain,
+// which may be nil for open ground, with the given rules.
+func PropsForRoomOnMap(roomID, roomName string, maxPlayers int, access RoomAccess, terrain *geometry.Map, rules ruleset.Ruleset, services RoomServices, system *actor.ActorSystem, roomManagerPID *actor.PID) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor {
+		room := NewRoomActorWithAccess(roomID, roomName, maxPlayers, access, system, roomManagerPID).(*RoomActor)
+		room.services, room.terrain, room.rules = services, terrain, rules
+		return room
+	}, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// mapID returns the ID of the room's map, or "" for open ground.
+func (a *RoomActor) mapID() string {
+	if a.terrain == nil {
+		return ""
+	}
+	return a.terrain.ID
 }
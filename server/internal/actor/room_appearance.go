@@ -0,0 +1,34 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// showAppearance records what a player who just joined wears, tells them what
+// everyone else wears and shows them to the others.
+func (a *RoomActor) showAppearance(ctx actor.Context, playerID string, playerPID *actor.PID, equipped map[string]string) {
+	for memberID, memberEquipped := range a.appearances {
+		ctx.Send(playerPID, &messages.AppearanceChanged{PlayerID: memberID, Equipped: memberEquipped})
+	}
+	if len(equipped) == 0 {
+		return
+	}
+	a.appearances[playerID] = equipped
+	a.broadcastMessage(ctx, playerPID, &messages.AppearanceChanged{PlayerID: playerID, Equipped: equipped})
+}
+
+// handleAppearanceChanged records a member's new cosmetics and shows them to
+// the others. The member's own session has already told its client.
+func (a *RoomActor) handleAppearanceChanged(ctx actor.Context, msg *messages.AppearanceChanged) {
+	playerPID, isMember := a.players[msg.PlayerID]
+	if !isMember {
+		return
+	}
+	if len(msg.Equipped) == 0 {
+		delete(a.appearances, msg.PlayerID)
+	} else {
+		a.appearances[msg.PlayerID] = msg.Equipped
+	}
+	a.broadcastMessage(ctx, playerPID, msg)
+}
@@ -0,0 +1,206 @@
+package actor
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"runtime"
+	"sync"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+)
+
+// broadcastFanOutThreshold is the room size from which broadcasts are handed
+// to the broadcaster pool instead of being sent by the RoomActor itself.
+const broadcastFanOutThreshold = 256
+
+// broadcastQueueSize is how many batches each broadcaster may have queued
+// before the RoomActor handing it more waits.
+const broadcastQueueSize = 64
+
+// clientMessage returns the client message a session sends for a room
+// broadcast, or false if message is not sent to clients as is.
+func clientMessage(message interface{}) (string, interface{}, bool) {
+	switch msg := message.(type) {
+	case *messages.RoomChatMessage:
+		return protocol.MsgTypeNewChatMessage, protocol.ChatMessagePayload{
+			SenderName: msg.SenderName,
+			Text:       msg.Message,
+		}, true
+	case *messages.VoiceMuteChanged:
+		return protocol.MsgTypeVoiceMuteState, protocol.VoiceMuteStatePayload{
+			PlayerID:    msg.PlayerID,
+			Muted:       msg.Muted,
+			ByModerator: msg.ByModerator,
+		}, true
+	case *messages.PositionChanged:
+		return protocol.MsgTypePositionUpdate, protocol.PositionUpdatePayload{
+			PlayerID:  msg.PlayerID,
+			X:         msg.X,
+			Y:         msg.Y,
+			AbilityID: msg.AbilityID,
+			Corrected: msg.Corrected,
+			NPC:       msg.NPC,
+		}, true
+	case *messages.ProjectileSpawned:
+		return protocol.MsgTypeProjectileSpawned, protocol.ProjectileSpawnedPayload{
+			ID:           msg.ID,
+			ProjectileID: msg.ProjectileID,
+			OwnerID:      msg.OwnerID,
+			X:            msg.X,
+			Y:            msg.Y,
+			DirX:         msg.DirX,
+			DirY:         msg.DirY,
+			Speed:        msg.Speed,
+			Range:        msg.Range,
+			LaunchHeight: projectile.LaunchHeight,
+			Lift:         msg.Lift,
+			Gravity:      msg.Gravity,
+		}, true
+	case *messages.ProjectileHit:
+		return protocol.MsgTypeProjectileHit, protocol.ProjectileHitPayload{
+			ID:       msg.ID,
+			TargetID: msg.TargetID,
+			NPC:      msg.NPC,
+			X:        msg.X,
+			Y:        msg.Y,
+			Damage:   msg.Damage,
+			Critical: msg.Critical,
+			Evaded:   msg.Evaded,
+			Health:   msg.Health,
+			Defeated: msg.Defeated,
+			XP:       msg.XP,
+			Loot:     msg.Loot,
+		}, true
+	case *messages.ProjectileExpired:
+		return protocol.MsgTypeProjectileExpired, protocol.ProjectileExpiredPayload{
+			ID:     msg.ID,
+			Reason: msg.Reason,
+			X:      msg.X,
+			Y:      msg.Y,
+		}, true
+	case *messages.PlayerAFKChanged:
+		return protocol.MsgTypePlayerAFK, protocol.PlayerAFKPayload{
+			PlayerID: msg.PlayerID,
+			AFK:      msg.AFK,
+			MovedTo:  msg.MovedTo,
+		}, true
+	case *messages.AppearanceChanged:
+		return protocol.MsgTypeAppearance, protocol.AppearancePayload{
+			PlayerID: msg.PlayerID,
+			Equipped: msg.Equipped,
+		}, true
+	case *messages.SocialAction:
+		return protocol.MsgTypeSocial, protocol.SocialPayload{
+			PlayerID: msg.PlayerID,
+			Kind:     msg.Kind,
+			Name:     msg.Name,
+			X:        msg.X,
+			Y:        msg.Y,
+		}, true
+	}
+	return "", nil, false
+}
+
+// preparedBroadcast is a client message serialized once for every member of
+// a room. Sessions share it: each framing version is encoded the first time a
+// session using it asks, and the frame is then written as is by the others.
+type preparedBroadcast struct {
+	msgType string
+	body    []byte // JSON payload
+	legacy  encodedFrame
+	v2      encodedFrame
+}
+
+type encodedFrame struct {
+	once    sync.Once
+	frame   []byte
+	bodyLen int
+	err     error
+}
+
+// prepareBroadcast serializes message once if it is one sessions forward to
+// their clients. Other messages, and ones that fail to serialize, are
+// returned unchanged for the sessions to handle.
+func prepareBroadcast(message interface{}) interface{} {
+	msgType, payload, ok := clientMessage(message)
+	if !ok {
+		return message
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return message
+	}
+	return &preparedBroadcast{msgType: msgType, body: body}
+}
+
+// frame returns the message framed for version, as writeFrame would frame it.
+func (b *preparedBroadcast) frame(version byte) ([]byte, int, error) {
+	f := &b.legacy
+	if version == protocol.FrameVersion2 {
+		f = &b.v2
+	}
+	f.once.Do(func() {
+		typeID, body := protocol.EnvelopeTypeID, b.body
+		if spec, ok := protocol.LookupMessage(b.msgType); ok && version == protocol.FrameVersion2 {
+			typeID = spec.ID
+		} else {
+			body = protocol.AppendEnvelope(nil, b.msgType, b.body, 0)
+		}
+		var flags protocol.FrameFlags
+		if version == protocol.FrameVersion2 && len(body) >= compressThreshold {
+			flags |= protocol.FrameFlagCompressed
+		}
+		f.bodyLen = len(body)
+		f.frame, f.err = protocol.EncodeFrame(version, flags, typeID, body)
+	})
+	return f.frame, f.bodyLen, f.err
+}
+
+// broadcastBatch is the part of one broadcast a broadcaster delivers.
+type broadcastBatch struct {
+	root    *actor.RootContext
+	pids    []*actor.PID
+	message interface{}
+}
+
+var (
+	broadcastersOnce sync.Once
+	broadcasters     []chan broadcastBatch
+)
+
+// fanOut delivers message to pids from the broadcaster pool, a goroutine per
+// CPU started on first use. Each recipient is always served by the same
+// broadcaster, so messages fanned out to it arrive in the order sent.
+func fanOut(root *actor.RootContext, pids []*actor.PID, message interface{}) {
+	broadcastersOnce.Do(func() {
+		broadcasters = make([]chan broadcastBatch, runtime.GOMAXPROCS(0))
+		for i := range broadcasters {
+			queue := make(chan broadcastBatch, broadcastQueueSize)
+			broadcasters[i] = queue
+			go func() {
+				for batch := range queue {
+					for _, pid := range batch.pids {
+						batch.root.Send(pid, batch.message)
+					}
+				}
+			}()
+		}
+	})
+
+	batches := make([][]*actor.PID, len(broadcasters))
+	for _, pid := range pids {
+		h := fnv.New32a()
+		h.Write([]byte(pid.Address))
+		h.Write([]byte(pid.Id))
+		i := h.Sum32() % uint32(len(broadcasters))
+		batches[i] = append(batches[i], pid)
+	}
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			broadcasters[i] <- broadcastBatch{root: root, pids: batch, message: message}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package actor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// sessionFrame frames msgType and payload the way sendResponse does.
+func sessionFrame(t testing.TB, version byte, msgType string, payload interface{}) []byte {
+	typeID, body := protocol.EnvelopeTypeID, []byte(nil)
+	var err error
+	if spec, ok := protocol.LookupMessage(msgType); ok && version == protocol.FrameVersion2 {
+		typeID = spec.ID
+		body, err = json.Marshal(payload)
+	} else {
+		var msg protocol.ClientServerMessage
+		if msg, err = protocol.NewMessage(msgType, payload); err == nil {
+			body, err = json.Marshal(msg)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flags protocol.FrameFlags
+	if version == protocol.FrameVersion2 && len(body) >= compressThreshold {
+		flags |= protocol.FrameFlagCompressed
+	}
+	frame, err := protocol.EncodeFrame(version, flags, typeID, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestPreparedBroadcastMatchesSessionFraming(t *testing.T) {
+	for _, chat := range []*messages.RoomChatMessage{
+		{SenderID: "p1", SenderName: "Alice", Message: "hello"},
+		{SenderID: "p1", SenderName: "Alice", Message: string(bytes.Repeat([]byte("long "), 400))},
+	} {
+		prepared, ok := prepareBroadcast(chat).(*preparedBroadcast)
+		if !ok {
+			t.Fatalf("chat message was not prepared")
+		}
+		msgType, payload, _ := clientMessage(chat)
+		for _, version := range []byte{0, protocol.FrameVersionLegacy, protocol.FrameVersion2} {
+			got, _, err := prepared.frame(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := sessionFrame(t, version, msgType, payload); !bytes.Equal(got, want) {
+				t.Errorf("version %d, %d byte chat: prepared frame differs from the session's", version, len(chat.Message))
+			}
+		}
+	}
+
+	if _, ok := prepareBroadcast(&messages.PlayerLeftRoomBroadcast{PlayerID: "p1"}).(*messages.PlayerLeftRoomBroadcast); !ok {
+		t.Error("a message sessions do not forward to clients was prepared")
+	}
+}
+
+// BenchmarkRoomBroadcast measures one chat line reaching every member of a
+// large room, including the framing each session does before writing it.
+func BenchmarkRoomBroadcast(b *testing.B) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	for _, players := range []int{500, 1000, 2000} {
+		b.Run(fmt.Sprintf("players=%d/per-session", players), func(b *testing.B) {
+			benchmarkRoomBroadcast(b, players, func(room *RoomActor, ctx actor.Context, msg interface{}) {
+				for _, pid := range room.players {
+					ctx.Send(pid, msg)
+				}
+			})
+		})
+		b.Run(fmt.Sprintf("players=%d/prepared", players), func(b *testing.B) {
+			benchmarkRoomBroadcast(b, players, func(room *RoomActor, ctx actor.Context, msg interface{}) {
+				room.broadcastMessage(ctx, nil, msg)
+			})
+		})
+	}
+}
+
+func benchmarkRoomBroadcast(b *testing.B, players int, broadcast func(room *RoomActor, ctx actor.Context, msg interface{})) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+
+	var delivered sync.WaitGroup
+	session := actor.PropsFromFunc(func(ctx actor.Context) {
+		switch msg := ctx.Message().(type) {
+		case *messages.RoomChatMessage:
+			msgType, payload, _ := clientMessage(msg)
+			sessionFrame(b, protocol.FrameVersion2, msgType, payload)
+			delivered.Done()
+		case *preparedBroadcast:
+			if _, _, err := msg.frame(protocol.FrameVersion2); err != nil {
+				b.Error(err)
+			}
+			delivered.Done()
+		}
+	})
+	room := NewRoomActor("bench", "Bench", players, system, nil).(*RoomActor)
+	for i := 0; i < players; i++ {
+		room.players[fmt.Sprintf("p%d", i)] = system.Root.Spawn(session)
+	}
+	// broadcast runs inside an actor, as it does in the RoomActor.
+	driver := system.Root.Spawn(actor.PropsFromFunc(func(ctx actor.Context) {
+		if msg, ok := ctx.Message().(*messages.RoomChatMessage); ok {
+			broadcast(room, ctx, msg)
+		}
+	}))
+
+	chat := &messages.RoomChatMessage{SenderID: "p0", SenderName: "p0", Message: "Anyone up for the dungeon run tonight?"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		delivered.Add(players)
+		system.Root.Send(driver, chat)
+		delivered.Wait()
+	}
+}
@@ -0,0 +1,69 @@
+package actor
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/hotzone"
+)
+
+// densityReport is the room's periodic report to the hot zone monitor. Like
+// the room tick, it only runs while the room has members.
+type densityReport struct{}
+
+// updateDensityReports reports where the members of a room on a map stand
+// while it has any, and stops when it empties.
+func (a *RoomActor) updateDensityReports(ctx actor.Context) {
+	if a.services.Density == nil || a.terrain == nil {
+		return
+	}
+	running := !a.densityTimer.Stopped()
+	switch {
+	case len(a.players) > 0 && !running:
+		a.densityTimer = a.services.Timers.Every(ctx.ActorSystem().Root, ctx.Self(), a.services.Density.Interval(), 0, &densityReport{})
+	case len(a.players) == 0 && running:
+		a.densityTimer.Stop()
+		a.services.Density.Forget(a.loadKey(ctx))
+	}
+}
+
+// handleDensityReport reports the members' positions and brings the NPCs up
+// to the map's NPC density.
+func (a *RoomActor) handleDensityReport(ctx actor.Context) {
+	if a.densityTimer.Stopped() {
+		return // Stopped while the report was in flight
+	}
+	positions := make([]geometry.Vec, 0, len(a.positions))
+	for _, p := range a.positions {
+		positions = append(positions, p)
+	}
+	a.services.Density.Report(hotzone.Sample{Room: a.loadKey(ctx), Map: a.terrain.ID, Positions: positions})
+	a.spawnExtraNPCs(ctx)
+}
+
+// spawnExtraNPCs spawns copies of the map's NPCs, at their spawn points, up to
+// the map's NPC density. Copies stay until they are defeated, even once the
+// density is lowered again; defeated ones are not replaced.
+func (a *RoomActor) spawnExtraNPCs(ctx actor.Context) {
+	if len(a.npcDefs) == 0 {
+		return
+	}
+	multiplier := a.services.Density.Density().NPCMultiplier(a.terrain.ID)
+	want := int(math.Round(float64(len(a.npcDefs)) * (multiplier - 1)))
+	spawned := 0
+	for ; a.npcExtras < want; a.npcExtras++ {
+		def := a.npcDefs[a.npcExtras%len(a.npcDefs)]
+		def.ID = fmt.Sprintf("%s~%d", def.ID, a.npcExtras/len(a.npcDefs)+2)
+		a.npcs[def.ID] = &roomNPC{pid: ctx.Spawn(PropsForNPC(def)), pos: def.Spawn, health: def.Health, max: def.Health, defense: def.Defense}
+		a.deliverBroadcast(ctx, nil, &messages.PositionChanged{PlayerID: def.ID, X: def.Spawn.X, Y: def.Spawn.Y, NPC: true})
+		spawned++
+	}
+	if spawned > 0 {
+		log.Printf("[RoomActor %s] Spawned %d extra NPCs for an NPC density of %g on map %s.", a.roomID, spawned, multiplier, a.terrain.ID)
+		a.updateTick(ctx)
+	}
+}
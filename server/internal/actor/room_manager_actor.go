@@ -3,10 +3,29 @@ package actor
 import (
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
 	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/anticheat"
+	"github.com/phuhao00/suigserver/server/internal/autoscale"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/gamedata"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/hotzone"
+	"github.com/phuhao00/suigserver/server/internal/movement"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/npc"
+	"github.com/phuhao00/suigserver/server/internal/pathfinding"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/ruleset"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/timers"
 	"github.com/phuhao00/suigserver/server/internal/utils"
 )
 
@@ -17,6 +36,35 @@ type RoomManagerActor struct {
 	roomInfo    map[string]RoomInfo   // Map RoomID to RoomInfo (name, maxPlayers, currentPlayers)
 	mu          sync.RWMutex          // To protect concurrent access to the rooms map and roomInfo
 	nextRoomNum int                   // For generating unique room IDs if not provided
+	services    RoomServices          // Handed to every room
+}
+
+// RoomServices are shared services a RoomManagerActor hands to its rooms.
+type RoomServices struct {
+	Movement    *movement.Rules      // Movement abilities, cooldowns and the maps rooms are played on; plain moves on open ground if nil
+	NPCs        *npc.Roster          // NPCs spawned in rooms on each map; none if nil
+	Navigation  *pathfinding.Library // Navigation grids NPCs find paths on; straight lines if nil
+	PathBudget  int                  // Grid cells a room's pathfinding may expand per tick; pathfinding.DefaultBudget if 0
+	Tick        time.Duration        // Interval of the tick moving NPCs and projectiles; 100ms if 0
+	Timers      *timers.Scheduler    // Drives the room tick; real time if nil
+	Projectiles *projectile.Arsenal  // Projectiles members can fire, with their cooldowns; none if nil
+	Combat      *game.CombatEngine   // Works out the damage of projectile hits; hits deal none if nil
+	PlayerStats game.CombatantStats  // Health and defense members start with; members cannot be damaged without health
+	Data        *gamedata.Watcher    // Reloadable maps, abilities, NPCs and projectiles; overrides the four fields above if set
+	Anticheat   *anticheat.Monitor   // Checks plain moves for speed; unchecked if nil
+	RuleLimits  ruleset.Limits       // What players may choose in a room's rules
+	Stats       *stats.Service       // Told of members' health and the XP of their defeats; nothing is pushed if nil
+	Load        *autoscale.Tracker   // Told how much of their tick budget rooms use; untracked if nil
+	Density     *hotzone.Monitor     // Told where members stand; sets the maps' NPC density and overflow instances. Neither if nil
+}
+
+// forNewRoom returns the services of a room created now: with Data, its
+// current Set, which the room keeps even if the data is reloaded.
+func (s RoomServices) forNewRoom() RoomServices {
+	if set := s.Data.Current(); set != nil {
+		s.Movement, s.NPCs, s.Navigation, s.Projectiles = set.Movement, set.NPCs, set.Navigation, set.Projectiles
+	}
+	return s
 }
 
 // RoomInfo holds metadata about a room.
@@ -26,6 +74,16 @@ type RoomInfo struct {
 	MaxPlayers     int
 	CurrentPlayers int
 	PID            *actor.PID
+	Visibility     messages.RoomVisibility
+	PasswordHash   string // Empty if the room has no password
+	Rules          ruleset.Ruleset
+	MapID          string // Empty for open ground
+	OverflowOf     string // Room this is an overflow instance of, if it is one
+}
+
+// listed reports whether the room appears in listings and matchmaking.
+func (info RoomInfo) listed() bool {
+	return RoomAccess{Visibility: info.Visibility}.Listed()
 }
 
 // NewRoomManagerActor creates a new RoomManagerActor.
@@ -64,6 +122,9 @@ func (a *RoomManagerActor) Receive(ctx actor.Context) {
 	case *messages.FindRoomRequest:
 		a.handleFindRoomRequest(ctx, msg)
 
+	case *messages.ListRoomsRequest:
+		a.handleListRoomsRequest(ctx, msg)
+
 	case *actor.Terminated:
 		// This message is received when a child/watched actor stops.
 		a.handleRoomTerminated(ctx, msg)
@@ -71,8 +132,18 @@ func (a *RoomManagerActor) Receive(ctx actor.Context) {
 	case *messages.UpdateRoomPlayerCount:
 		a.handleUpdateRoomPlayerCount(ctx, msg)
 
+	case *messages.GetRoomStats:
+		a.mu.RLock()
+		stats := &messages.RoomStats{Rooms: len(a.roomInfo)}
+		for _, info := range a.roomInfo {
+			stats.Players += info.CurrentPlayers
+		}
+		a.mu.RUnlock()
+		ctx.Respond(stats)
+
 	default:
 		log.Printf("[RoomManagerActor %s] Received unknown message: %T %+v", ctx.Self().Id, msg, msg)
+		quarantine.ReportUnhandled(ctx, msg)
 	}
 }
 
@@ -111,6 +182,8 @@ func (a *RoomManagerActor) createDefaultRoom(ctx actor.Context) {
 		MaxPlayers:     maxPlayers,
 		CurrentPlayers: 0,
 		PID:            roomPID,
+		Visibility:     messages.RoomVisibilityPublic,
+		Rules:          ruleset.Default,
 	}
 	a.mu.Unlock()
 
@@ -158,8 +231,38 @@ func (a *RoomManagerActor) handleCreateRoomRequest(ctx actor.Context, msg *messa
 		return
 	}
 
+	visibility := msg.Visibility
+	if visibility == "" {
+		visibility = messages.RoomVisibilityPublic
+	}
+	access := RoomAccess{Visibility: visibility, PasswordHash: msg.PasswordHash, OwnerID: msg.OwnerID, MemberIDs: msg.MemberIDs}
+	services := a.services.forNewRoom()
+	var terrain *geometry.Map
+	if msg.MapID != "" {
+		var known bool
+		if terrain, known = services.Movement.Map(msg.MapID); !known {
+			utils.LogWarnf("[RoomManagerActor] Room '%s' asked for unknown map '%s'.", roomID, msg.MapID)
+			if msg.RequesterPID != nil {
+				ctx.Send(msg.RequesterPID, &messages.CreateRoomResponse{RoomID: roomID, Success: false, Error: fmt.Sprintf("Unknown map '%s'", msg.MapID)})
+			}
+			return
+		}
+	}
+
+	rules := ruleset.Default
+	if msg.Rules != nil {
+		rules = *msg.Rules
+	}
+	if err := rules.Validate(services.RuleLimits); err != nil {
+		utils.LogWarnf("[RoomManagerActor] Room '%s' asked for rules %+v: %v", roomID, rules, err)
+		if msg.RequesterPID != nil {
+			ctx.Send(msg.RequesterPID, &messages.CreateRoomResponse{RoomID: roomID, Success: false, Error: err.Error()})
+		}
+		return
+	}
+
 	// Pass RoomManager's PID (ctx.Self()) to the RoomActor so it can send updates (e.g. player count)
-	roomProps := PropsForRoom(roomID, roomName, maxPlayers, a.actorSystem, ctx.Self())
+	roomProps := PropsForRoomOnMap(roomID, roomName, maxPlayers, access, terrain, rules, services, a.actorSystem, ctx.Self())
 	roomPID, err := ctx.SpawnNamed(roomProps, "room-"+roomID) // Ensure "room-"+roomID is unique
 	if err != nil {
 		utils.LogErrorf("[RoomManagerActor] Failed to spawn room '%s': %v", roomID, err)
@@ -182,12 +285,16 @@ func (a *RoomManagerActor) handleCreateRoomRequest(ctx actor.Context, msg *messa
 		MaxPlayers:     maxPlayers,
 		CurrentPlayers: 0,
 		PID:            roomPID,
+		Visibility:     visibility,
+		PasswordHash:   msg.PasswordHash,
+		Rules:          rules,
+		MapID:          msg.MapID,
 	}
 	a.mu.Unlock()
 
 	ctx.Watch(roomPID) // Watch for termination
 
-	utils.LogInfof("[RoomManagerActor] Room '%s' (%s) created with PID: %s", roomName, roomID, roomPID.String())
+	utils.LogInfof("[RoomManagerActor] Room '%s' (%s, %s, password: %t, rules: %v) created with PID: %s", roomName, roomID, visibility, msg.PasswordHash != "", rules.Modifiers(), roomPID.String())
 
 	// Send success response to the requester
 	if msg.RequesterPID != nil {
@@ -201,6 +308,14 @@ func (a *RoomManagerActor) handleCreateRoomRequest(ctx actor.Context, msg *messa
 }
 
 func (a *RoomManagerActor) handleFindRoomRequest(ctx actor.Context, msg *messages.FindRoomRequest) {
+	if roomID, ok := msg.Criteria.(string); ok && roomID != "" {
+		if overflowID := a.overflowRoom(ctx, roomID); overflowID != "" {
+			redirected := *msg
+			redirected.Criteria = overflowID
+			msg = &redirected
+		}
+	}
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
@@ -228,8 +343,9 @@ func (a *RoomManagerActor) handleFindRoomRequest(ctx actor.Context, msg *message
 	} else {
 		// Fallback: find the first available non-full room (simple matchmaking)
 		// More sophisticated matchmaking would consider criteria like game mode, map, player rank etc.
+		// Private, invite-only and password-protected rooms are only joined by ID.
 		for _, info := range a.roomInfo {
-			if info.CurrentPlayers < info.MaxPlayers {
+			if info.CurrentPlayers < info.MaxPlayers && info.listed() && info.PasswordHash == "" {
 				foundRoom = info
 				found = true
 				break // Found a suitable room
@@ -258,6 +374,34 @@ func (a *RoomManagerActor) handleFindRoomRequest(ctx actor.Context, msg *message
 	}
 }
 
+// handleListRoomsRequest replies with the public rooms. Private and invite-only rooms are left out.
+func (a *RoomManagerActor) handleListRoomsRequest(ctx actor.Context, msg *messages.ListRoomsRequest) {
+	a.mu.RLock()
+	rooms := make([]messages.RoomSummary, 0, len(a.roomInfo))
+	for _, info := range a.roomInfo {
+		if !info.listed() {
+			continue
+		}
+		rooms = append(rooms, messages.RoomSummary{
+			RoomID:           info.ID,
+			Name:             info.Name,
+			CurrentPlayers:   info.CurrentPlayers,
+			MaxPlayers:       info.MaxPlayers,
+			PasswordRequired: info.PasswordHash != "",
+			Rules:            info.Rules,
+		})
+	}
+	a.mu.RUnlock()
+
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].RoomID < rooms[j].RoomID })
+	response := &messages.ListRoomsResponse{Rooms: rooms}
+	if msg.RequesterPID != nil {
+		ctx.Send(msg.RequesterPID, response)
+	} else {
+		ctx.Respond(response)
+	}
+}
+
 func (a *RoomManagerActor) handleRoomTerminated(ctx actor.Context, terminated *actor.Terminated) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -288,5 +432,15 @@ func (a *RoomManagerActor) handleUpdateRoomPlayerCount(ctx actor.Context, msg *m
 
 // PropsForRoomManager creates actor.Props for RoomManagerActor.
 func PropsForRoomManager(system *actor.ActorSystem) *actor.Props {
-	return actor.PropsFromProducer(func() actor.Actor { return NewRoomManagerActor(system) })
+	return actor.PropsFromProducer(func() actor.Actor { return NewRoomManagerActor(system) }, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// PropsForRoomManagerWithServices creates actor.Props for a RoomManagerActor
+// whose rooms use services.
+func PropsForRoomManagerWithServices(system *actor.ActorSystem, services RoomServices) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor {
+		manager := NewRoomManagerActor(system).(*RoomManagerActor)
+		manager.services = services
+		return manager
+	}, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
 }
@@ -0,0 +1,83 @@
+package actor
+
+import (
+	"errors"
+	"log"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/anticheat"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/movement"
+)
+
+// placeMember puts a player who just joined at the map's spawn point, tells
+// them where everyone and every NPC is and tells the others where they are.
+func (a *RoomActor) placeMember(ctx actor.Context, playerID string, playerPID *actor.PID) {
+	spawn := a.terrain.SpawnPoint()
+	a.positions[playerID] = spawn
+	for memberID, p := range a.positions {
+		ctx.Send(playerPID, &messages.PositionChanged{PlayerID: memberID, X: p.X, Y: p.Y})
+	}
+	for npcID, n := range a.npcs {
+		ctx.Send(playerPID, &messages.PositionChanged{PlayerID: npcID, X: n.pos.X, Y: n.pos.Y, NPC: true})
+	}
+	a.broadcastMessage(ctx, playerPID, &messages.PositionChanged{PlayerID: playerID, X: spawn.X, Y: spawn.Y})
+}
+
+// handleMovePlayer applies a member's reported move if the map allows it and
+// it is not too fast, and otherwise snaps them back.
+func (a *RoomActor) handleMovePlayer(ctx actor.Context, msg *messages.MovePlayer) {
+	playerPID, isMember := a.players[msg.PlayerID]
+	if !isMember {
+		return
+	}
+	from, to := a.positions[msg.PlayerID], geometry.Vec{X: msg.X, Y: msg.Y}
+	if err := movement.Move(a.terrain, from, to); err != nil {
+		log.Printf("[RoomActor %s] Move of %s from %v to %v refused: %v", a.roomID, msg.PlayerID, from, to, err)
+		ctx.Send(playerPID, &messages.PositionChanged{PlayerID: msg.PlayerID, X: from.X, Y: from.Y, Corrected: true})
+		return
+	}
+	if verdict := a.services.Anticheat.CheckMove(msg.PlayerID, from, to, a.services.Timers.Now()); verdict.Action != anticheat.Allow {
+		log.Printf("[RoomActor %s] Move of %s from %v to %v refused: %.1f units/s is too fast (%d recent violations)",
+			a.roomID, msg.PlayerID, from, to, verdict.Speed, verdict.Violations)
+		ctx.Send(playerPID, &messages.PositionChanged{PlayerID: msg.PlayerID, X: from.X, Y: from.Y, Corrected: true})
+		if verdict.Action == anticheat.Kick {
+			ctx.Send(playerPID, &messages.TerminateSession{Code: protocol.ErrCodeCheatDetected, Reason: "Disconnected for repeated movement violations."})
+		}
+		return
+	}
+	a.positions[msg.PlayerID] = to
+	a.broadcastMessage(ctx, playerPID, &messages.PositionChanged{PlayerID: msg.PlayerID, X: to.X, Y: to.Y})
+}
+
+// handleUseMovementAbility moves a member with a movement ability, answers
+// the caster and shows the others where they landed.
+func (a *RoomActor) handleUseMovementAbility(ctx actor.Context, msg *messages.UseMovementAbility) {
+	playerPID, isMember := a.players[msg.PlayerID]
+	if !isMember {
+		return
+	}
+	from := a.positions[msg.PlayerID]
+	landing, err := a.services.Movement.UseAbility(msg.PlayerID, msg.AbilityID, a.terrain, from, geometry.Vec{X: msg.X, Y: msg.Y})
+	result := &messages.MovementAbilityResult{
+		AbilityID: msg.AbilityID,
+		Success:   err == nil,
+		X:         landing.X,
+		Y:         landing.Y,
+		Cooldown:  a.services.Movement.CooldownRemaining(msg.PlayerID, msg.AbilityID),
+	}
+	if err != nil {
+		var cooldown *movement.CooldownError
+		if !errors.As(err, &cooldown) {
+			log.Printf("[RoomActor %s] %s of %s towards (%.2f, %.2f) refused: %v", a.roomID, msg.AbilityID, msg.PlayerID, msg.X, msg.Y, err)
+		}
+		result.Error = err.Error()
+		ctx.Send(playerPID, result)
+		return
+	}
+	a.positions[msg.PlayerID] = landing
+	ctx.Send(playerPID, result)
+	a.broadcastMessage(ctx, playerPID, &messages.PositionChanged{PlayerID: msg.PlayerID, X: landing.X, Y: landing.Y, AbilityID: msg.AbilityID})
+}
@@ -0,0 +1,121 @@
+package actor
+
+import (
+	"log"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/pathfinding"
+)
+
+// defaultRoomTick is the room tick interval if RoomServices does not set one.
+const defaultRoomTick = 100 * time.Millisecond
+
+// roomTick is the room's periodic tick, which moves its NPCs and projectiles.
+type roomTick struct{}
+
+// roomNPC is an NPC as its room sees it.
+type roomNPC struct {
+	pid     *actor.PID
+	pos     geometry.Vec
+	health  int // 0 if it cannot be damaged
+	max     int
+	defense int
+}
+
+// spawnNPCs starts the NPCs of the room's map.
+func (a *RoomActor) spawnNPCs(ctx actor.Context) {
+	if a.terrain == nil {
+		return
+	}
+	defs := a.services.NPCs.ForMap(a.terrain.ID)
+	if len(defs) == 0 {
+		return
+	}
+	a.npcs = make(map[string]*roomNPC, len(defs))
+	for _, def := range defs {
+		a.npcs[def.ID] = &roomNPC{pid: ctx.Spawn(PropsForNPC(def)), pos: def.Spawn, health: def.Health, max: def.Health, defense: def.Defense}
+	}
+	a.npcDefs = defs
+	a.planner = pathfinding.NewPlanner(a.services.Navigation.Grid(a.terrain), a.services.PathBudget)
+	log.Printf("[RoomActor %s] Spawned %d NPCs on map %s.", a.roomID, len(defs), a.terrain.ID)
+	a.spawnExtraNPCs(ctx)
+}
+
+// updateTick runs the room tick while there are players to see NPCs or
+// projectiles, and stops it when the room empties or has nothing to move.
+// Projectiles still in flight when the last player leaves are dropped.
+func (a *RoomActor) updateTick(ctx actor.Context) {
+	if len(a.players) == 0 && a.projectiles != nil {
+		a.projectiles.Clear()
+	}
+	running := !a.tickTimer.Stopped()
+	busy := len(a.players) > 0 && (len(a.npcs) > 0 || a.projectiles != nil && a.projectiles.Len() > 0)
+	switch {
+	case busy && !running:
+		a.tickTimer = a.services.Timers.Every(ctx.ActorSystem().Root, ctx.Self(), a.tickInterval(), 0, &roomTick{})
+	case !busy && running:
+		a.tickTimer.Stop()
+		a.services.Load.ForgetRoom(a.loadKey(ctx))
+	}
+}
+
+// loadKey names the room to the load tracker; room IDs repeat across worlds.
+func (a *RoomActor) loadKey(ctx actor.Context) string {
+	return a.roomID + "/" + ctx.Self().Id
+}
+
+func (a *RoomActor) tickInterval() time.Duration {
+	if a.services.Tick > 0 {
+		return a.services.Tick
+	}
+	return defaultRoomTick
+}
+
+// handleRoomTick hands out the paths found within this tick's budget, ticks
+// every NPC and moves the projectiles. The time it takes is reported as the
+// room's use of its tick budget.
+func (a *RoomActor) handleRoomTick(ctx actor.Context) {
+	if a.tickTimer.Stopped() {
+		return // Stopped while the tick was in flight
+	}
+	started := time.Now()
+	a.ticks++
+	if len(a.npcs) > 0 {
+		for _, result := range a.planner.Step() {
+			if n, ok := a.npcs[result.ID]; ok {
+				ctx.Send(n.pid, &messages.NPCPath{Path: result.Path, Found: result.Found})
+			}
+		}
+		players := make(map[string]geometry.Vec, len(a.positions))
+		for id, p := range a.positions {
+			players[id] = p
+		}
+		tick := &messages.NPCTick{Players: players, Delta: a.tickInterval()}
+		for _, n := range a.npcs {
+			ctx.Send(n.pid, tick)
+		}
+	}
+	a.stepProjectiles(ctx)
+	a.services.Load.ObserveTick(a.loadKey(ctx), time.Since(started), a.tickInterval())
+	a.updateTick(ctx)
+}
+
+// handleRequestNPCPath queues an NPC's path search for the next ticks.
+func (a *RoomActor) handleRequestNPCPath(msg *messages.RequestNPCPath) {
+	if _, ok := a.npcs[msg.NPCID]; ok {
+		a.planner.Request(msg.NPCID, msg.From, msg.To)
+	}
+}
+
+// handleNPCMoved shows an NPC's move to the players.
+func (a *RoomActor) handleNPCMoved(ctx actor.Context, msg *messages.NPCMoved) {
+	n, ok := a.npcs[msg.NPCID]
+	if !ok {
+		return
+	}
+	n.pos = geometry.Vec{X: msg.X, Y: msg.Y}
+	a.deliverBroadcast(ctx, nil, &messages.PositionChanged{PlayerID: msg.NPCID, X: msg.X, Y: msg.Y, NPC: true})
+}
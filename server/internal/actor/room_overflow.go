@@ -0,0 +1,78 @@
+package actor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// overflowRoom returns the overflow instance that players joining roomID go
+// to while its map overflows and the room is crowded: one with space, or else
+// a new one on the same map with the same rules and capacity. It returns ""
+// if they may join roomID itself. Only public rooms without a password
+// overflow, since an instance of a private room could not admit anyone.
+func (a *RoomManagerActor) overflowRoom(ctx actor.Context, roomID string) string {
+	density := a.services.Density.Density()
+	a.mu.RLock()
+	info, ok := a.roomInfo[roomID]
+	if !ok || info.MapID == "" || !info.listed() || info.PasswordHash != "" || !density.Overflows(info.MapID) || !crowded(info, density) {
+		a.mu.RUnlock()
+		return ""
+	}
+	origin := info
+	if info.OverflowOf != "" {
+		if original, ok := a.roomInfo[info.OverflowOf]; ok {
+			origin = original
+		} else {
+			origin.ID = info.OverflowOf
+		}
+	}
+	var instances []string
+	for id, other := range a.roomInfo {
+		if other.OverflowOf == origin.ID && id != roomID && !crowded(other, density) {
+			instances = append(instances, id)
+		}
+	}
+	n := 1
+	for ; ; n++ {
+		if _, exists := a.roomInfo[fmt.Sprintf("%s-overflow-%d", origin.ID, n)]; !exists {
+			break
+		}
+	}
+	a.mu.RUnlock()
+	if len(instances) > 0 {
+		sort.Strings(instances) // Fill the oldest instance first
+		return instances[0]
+	}
+
+	overflowID := fmt.Sprintf("%s-overflow-%d", origin.ID, n)
+	rules := info.Rules
+	a.handleCreateRoomRequest(ctx, &messages.CreateRoomRequest{
+		RoomID:     overflowID,
+		RoomName:   fmt.Sprintf("%s (overflow %d)", origin.Name, n),
+		MaxPlayers: info.MaxPlayers,
+		Visibility: info.Visibility,
+		MapID:      info.MapID,
+		Rules:      &rules,
+	})
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	overflow, ok := a.roomInfo[overflowID]
+	if !ok {
+		return "" // Could not be opened; the join goes to the room itself
+	}
+	overflow.OverflowOf = origin.ID
+	a.roomInfo[overflowID] = overflow
+	utils.LogInfof("[RoomManagerActor] Room '%s' on crowded map '%s' overflows into '%s'.", roomID, info.MapID, overflowID)
+	return overflowID
+}
+
+// crowded reports whether a room is full or, on an overflowing map, has the
+// members at which it sends new players to an overflow instance.
+func crowded(info RoomInfo, density balance.DensityValues) bool {
+	return info.CurrentPlayers >= info.MaxPlayers || density.OverflowPlayers > 0 && info.CurrentPlayers >= density.OverflowPlayers
+}
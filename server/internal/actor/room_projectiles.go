@@ -0,0 +1,183 @@
+package actor
+
+import (
+	"errors"
+	"log"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+)
+
+// handleFireProjectile fires a member's projectile from where they stand and
+// shows it to everyone, or tells the member why it was refused.
+func (a *RoomActor) handleFireProjectile(ctx actor.Context, msg *messages.FireProjectile) {
+	playerPID, isMember := a.players[msg.PlayerID]
+	if !isMember {
+		return
+	}
+	if a.eliminated[msg.PlayerID] {
+		ctx.Send(playerPID, &messages.ProjectileRefused{ProjectileID: msg.ProjectileID, Error: errEliminated.Error()})
+		return
+	}
+	def, err := a.services.Projectiles.Ready(msg.PlayerID, msg.ProjectileID)
+	var shot projectile.Projectile
+	if err == nil {
+		if a.projectiles == nil {
+			a.projectiles = projectile.NewSimulation(a.terrain)
+		}
+		shot, err = a.projectiles.Fire(msg.PlayerID, def, a.positions[msg.PlayerID], geometry.Vec{X: msg.X, Y: msg.Y})
+	}
+	if err != nil {
+		if !errors.Is(err, projectile.ErrOnCooldown) {
+			log.Printf("[RoomActor %s] %s of %s towards (%.2f, %.2f) refused: %v", a.roomID, msg.ProjectileID, msg.PlayerID, msg.X, msg.Y, err)
+		}
+		ctx.Send(playerPID, &messages.ProjectileRefused{ProjectileID: msg.ProjectileID, Error: err.Error()})
+		return
+	}
+	a.services.Projectiles.Fired(msg.PlayerID, def.ID)
+	a.broadcastMessage(ctx, nil, &messages.ProjectileSpawned{
+		ID:           shot.ID,
+		ProjectileID: def.ID,
+		OwnerID:      msg.PlayerID,
+		X:            shot.Origin.X,
+		Y:            shot.Origin.Y,
+		DirX:         shot.Dir.X,
+		DirY:         shot.Dir.Y,
+		Speed:        def.Speed,
+		Range:        def.Range,
+		Lift:         shot.Lift,
+		Gravity:      def.Gravity,
+	})
+	a.updateTick(ctx)
+}
+
+// stepProjectiles moves the projectiles in flight by one tick and resolves
+// those that ended.
+func (a *RoomActor) stepProjectiles(ctx actor.Context) {
+	if a.projectiles == nil || a.projectiles.Len() == 0 {
+		return
+	}
+	targets := make([]projectile.Target, 0, len(a.positions)+len(a.npcs))
+	for id, p := range a.positions {
+		if a.rules.FriendlyFire && !a.eliminated[id] {
+			targets = append(targets, projectile.Target{ID: id, Pos: p})
+		}
+	}
+	for id, n := range a.npcs {
+		targets = append(targets, projectile.Target{ID: id, Pos: n.pos, NPC: true})
+	}
+	for _, end := range a.projectiles.Step(a.tickInterval(), targets) {
+		if end.Reason == projectile.ReasonHit {
+			a.resolveProjectileHit(ctx, end)
+			continue
+		}
+		a.deliverBroadcast(ctx, nil, &messages.ProjectileExpired{ID: end.Projectile.ID, Reason: end.Reason, X: end.Pos.X, Y: end.Pos.Y})
+	}
+}
+
+// resolveProjectileHit has the combat engine work out the damage of a hit,
+// and the rewards of a defeat under the room's rules, and shows it to
+// everyone. Targets without health take no damage.
+func (a *RoomActor) resolveProjectileHit(ctx actor.Context, end projectile.End) {
+	shot, target := end.Projectile, end.Target
+	attacker := a.services.PlayerStats
+	attacker.ID, attacker.Health, attacker.AttackPower = shot.OwnerID, a.memberHealth(shot.OwnerID), shot.Def.AttackPower
+
+	defender := a.services.PlayerStats
+	defender.ID, defender.Health = target.ID, a.memberHealth(target.ID)
+	n := a.npcs[target.ID]
+	if target.NPC {
+		defender = game.CombatantStats{ID: target.ID, Health: n.health, MaxHealth: n.max, Defense: n.defense}
+	}
+
+	hit := &messages.ProjectileHit{ID: shot.ID, TargetID: target.ID, NPC: target.NPC, X: end.Pos.X, Y: end.Pos.Y, Health: defender.Health}
+	if defender.Health > 0 && a.services.Combat != nil {
+		result := a.services.Combat.SimulateCombatTurnWith(attacker, defender, game.TurnOptions{
+			Skill:         shot.Def.ID,
+			AttackElement: shot.Def.Element,
+			XPMultiplier:  a.rules.XPMultiplier,
+			NoLoot:        a.rules.NoLoot,
+		})
+		hit.Damage, hit.Critical, hit.Evaded = result.DamageDealt, result.IsCriticalHit, result.IsEvaded
+		hit.Health, hit.Defeated = result.DefenderHealth, result.IsDefenderDefeated
+		hit.XP, hit.Loot = result.XPAwarded, result.Loot
+	}
+	a.deliverBroadcast(ctx, nil, hit)
+	if hit.Defeated && hit.XP > 0 {
+		a.creditXP(shot.OwnerID, hit.XP)
+	}
+
+	switch {
+	case target.NPC && hit.Defeated:
+		log.Printf("[RoomActor %s] NPC %s was defeated by %s.", a.roomID, target.ID, shot.OwnerID)
+		ctx.Stop(n.pid)
+		a.planner.Cancel(target.ID)
+		delete(a.npcs, target.ID)
+	case target.NPC:
+		n.health = hit.Health
+	case hit.Defeated && a.rules.Hardcore:
+		log.Printf("[RoomActor %s] Player %s was defeated by %s and is out.", a.roomID, target.ID, shot.OwnerID)
+		delete(a.health, target.ID)
+		a.eliminated[target.ID] = true
+		a.reportHealth(target.ID, 0)
+	case hit.Defeated:
+		a.respawnMember(ctx, target.ID)
+	default:
+		a.health[target.ID] = hit.Health
+		a.reportHealth(target.ID, hit.Health)
+	}
+}
+
+// reportHealth tells the stats service a member's health, for them and the
+// rest of the room.
+func (a *RoomActor) reportHealth(playerID string, health int) {
+	if a.services.Stats == nil {
+		return
+	}
+	party := make([]string, 0, len(a.players))
+	for memberID := range a.players {
+		party = append(party, memberID)
+	}
+	a.services.Stats.Set(playerID, stats.HP, int64(health), party...)
+}
+
+// creditXP gives a member the XP of a defeat.
+func (a *RoomActor) creditXP(playerID string, xp int) {
+	if _, isMember := a.players[playerID]; !isMember || a.services.Stats == nil {
+		return
+	}
+	if _, err := a.services.Stats.AddXP(playerID, xp); err != nil && !errors.Is(err, stats.ErrNoProgress) {
+		log.Printf("[RoomActor %s] Could not credit %d XP to %s: %v", a.roomID, xp, playerID, err)
+	}
+}
+
+// errEliminated refuses the shots of members who are out of a hardcore room.
+var errEliminated = errors.New("defeated in a hardcore room; leave and rejoin to play again")
+
+// memberHealth returns a member's health; members start with full health.
+func (a *RoomActor) memberHealth(playerID string) int {
+	if health, ok := a.health[playerID]; ok {
+		return health
+	}
+	return a.services.PlayerStats.Health
+}
+
+// respawnMember puts a defeated member back at the map's spawn point with
+// full health.
+func (a *RoomActor) respawnMember(ctx actor.Context, playerID string) {
+	playerPID, isMember := a.players[playerID]
+	if !isMember {
+		return
+	}
+	log.Printf("[RoomActor %s] Player %s was defeated and respawns.", a.roomID, playerID)
+	delete(a.health, playerID)
+	a.reportHealth(playerID, a.services.PlayerStats.Health)
+	spawn := a.terrain.SpawnPoint()
+	a.positions[playerID] = spawn
+	ctx.Send(playerPID, &messages.PositionChanged{PlayerID: playerID, X: spawn.X, Y: spawn.Y, Corrected: true})
+	a.deliverBroadcast(ctx, playerPID, &messages.PositionChanged{PlayerID: playerID, X: spawn.X, Y: spawn.Y})
+}
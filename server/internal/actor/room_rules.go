@@ -0,0 +1,34 @@
+package actor
+
+import (
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/ruleset"
+)
+
+// roomRulesFromPayload applies the rules a client chose to ruleset.Default.
+// The room manager validates the result.
+func roomRulesFromPayload(payload *protocol.RoomRulesPayload) ruleset.Ruleset {
+	rules := ruleset.Default
+	if payload == nil {
+		return rules
+	}
+	if payload.FriendlyFire != nil {
+		rules.FriendlyFire = *payload.FriendlyFire
+	}
+	if payload.XPMultiplier != nil {
+		rules.XPMultiplier = *payload.XPMultiplier
+	}
+	rules.NoLoot, rules.Hardcore = payload.NoLoot, payload.Hardcore
+	return rules
+}
+
+// roomRulesPayload is rules as shown in room listings.
+func roomRulesPayload(rules ruleset.Ruleset) protocol.RoomRulesPayload {
+	friendlyFire, xpMultiplier := rules.FriendlyFire, rules.XPMultiplier
+	return protocol.RoomRulesPayload{
+		FriendlyFire: &friendlyFire,
+		XPMultiplier: &xpMultiplier,
+		NoLoot:       rules.NoLoot,
+		Hardcore:     rules.Hardcore,
+	}
+}
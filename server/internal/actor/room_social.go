@@ -0,0 +1,23 @@
+package actor
+
+import (
+	"log"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// handleSocialAction broadcasts a member's emote, map ping or quick reply.
+// Pings off the room's map are dropped.
+func (a *RoomActor) handleSocialAction(ctx actor.Context, msg *messages.SocialAction) {
+	if _, ok := a.players[msg.PlayerID]; !ok {
+		return
+	}
+	if msg.Kind == protocol.SocialPing && a.terrain != nil && !a.terrain.Bounds.Contains(geometry.Vec{X: msg.X, Y: msg.Y}) {
+		log.Printf("[RoomActor %s] Dropped ping of %s off the map at (%.1f, %.1f)", a.roomID, msg.PlayerID, msg.X, msg.Y)
+		return
+	}
+	a.deliverBroadcast(ctx, nil, msg)
+}
@@ -0,0 +1,127 @@
+package actor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// roomChatTail is how many of the latest chat messages a room keeps for
+// players who join later.
+const roomChatTail = 20
+
+// roomChatLine is a chat message the room showed its members.
+type roomChatLine struct {
+	messages.RoomChatMessage
+	at time.Time
+}
+
+// recordChat keeps msg in the room's chat tail.
+func (a *RoomActor) recordChat(msg *messages.RoomChatMessage) {
+	a.chatTail = append(a.chatTail, roomChatLine{RoomChatMessage: *msg, at: a.services.Timers.Now()})
+	if len(a.chatTail) > roomChatTail {
+		a.chatTail = append(a.chatTail[:0], a.chatTail[len(a.chatTail)-roomChatTail:]...)
+	}
+}
+
+// handleRoomStateRequest answers a member with a snapshot of the room.
+func (a *RoomActor) handleRoomStateRequest(ctx actor.Context, msg *messages.RoomStateRequest) {
+	if _, isMember := a.players[msg.PlayerID]; !isMember {
+		ctx.Respond(&messages.RoomState{RoomID: a.roomID, Error: "Not in this room."})
+		return
+	}
+	ctx.Respond(a.snapshot(nil))
+}
+
+// snapshot describes the room as its members see it. arriving is the join
+// being answered, whose member has not been placed yet; nil for members.
+func (a *RoomActor) snapshot(arriving *messages.JoinRoomRequest) *messages.RoomState {
+	state := &messages.RoomState{
+		RoomID:  a.roomID,
+		Members: make([]messages.RoomMemberState, 0, len(a.players)),
+		Chat:    make([]messages.RoomChatMessage, 0, len(a.chatTail)),
+		NPCs:    make([]messages.RoomNPCState, 0, len(a.npcs)),
+		Tick: messages.RoomTickState{
+			Running:  !a.tickTimer.Stopped(),
+			Interval: a.tickInterval(),
+			Count:    a.ticks,
+		},
+		Rules:   a.rules,
+		TakenAt: a.services.Timers.Now(),
+	}
+	if a.projectiles != nil {
+		state.Tick.Projectiles = a.projectiles.Len()
+	}
+	for playerID := range a.players {
+		pos, placed := a.positions[playerID]
+		if !placed {
+			pos = a.terrain.SpawnPoint()
+		}
+		member := messages.RoomMemberState{
+			PlayerID:   playerID,
+			X:          pos.X,
+			Y:          pos.Y,
+			Health:     a.memberHealth(playerID),
+			Eliminated: a.eliminated[playerID],
+			VoiceMuted: a.voiceMutes[playerID].muted(),
+			Appearance: a.appearances[playerID],
+		}
+		if arriving != nil && playerID == arriving.PlayerID && len(arriving.Appearance) > 0 {
+			member.Appearance = arriving.Appearance
+		}
+		state.Members = append(state.Members, member)
+	}
+	sort.Slice(state.Members, func(i, j int) bool { return state.Members[i].PlayerID < state.Members[j].PlayerID })
+	for _, line := range a.chatTail {
+		chat := line.RoomChatMessage
+		chat.Timestamp = line.at.UnixMilli()
+		state.Chat = append(state.Chat, chat)
+	}
+	for npcID, n := range a.npcs {
+		state.NPCs = append(state.NPCs, messages.RoomNPCState{ID: npcID, X: n.pos.X, Y: n.pos.Y, Health: n.health, MaxHealth: n.max})
+	}
+	sort.Slice(state.NPCs, func(i, j int) bool { return state.NPCs[i].ID < state.NPCs[j].ID })
+	return state
+}
+
+// roomStatePayload is a room snapshot as sent to clients.
+func roomStatePayload(state *messages.RoomState) *protocol.RoomStatePayload {
+	if state == nil {
+		return nil
+	}
+	payload := &protocol.RoomStatePayload{
+		RoomID:  state.RoomID,
+		Members: make([]protocol.RoomMemberPayload, 0, len(state.Members)),
+		Chat:    make([]protocol.RoomChatPayload, 0, len(state.Chat)),
+		NPCs:    make([]protocol.RoomNPCPayload, 0, len(state.NPCs)),
+		Tick: protocol.RoomTickPayload{
+			Running:     state.Tick.Running,
+			IntervalMs:  state.Tick.Interval.Milliseconds(),
+			Count:       state.Tick.Count,
+			Projectiles: state.Tick.Projectiles,
+		},
+		Rules:      roomRulesPayload(state.Rules),
+		ServerTime: state.TakenAt.UnixMilli(),
+	}
+	for _, m := range state.Members {
+		payload.Members = append(payload.Members, protocol.RoomMemberPayload{
+			PlayerID:   m.PlayerID,
+			X:          m.X,
+			Y:          m.Y,
+			Health:     m.Health,
+			Eliminated: m.Eliminated,
+			VoiceMuted: m.VoiceMuted,
+			Appearance: m.Appearance,
+		})
+	}
+	for _, c := range state.Chat {
+		payload.Chat = append(payload.Chat, protocol.RoomChatPayload{SenderID: c.SenderID, SenderName: c.SenderName, Text: c.Message, At: c.Timestamp})
+	}
+	for _, n := range state.NPCs {
+		payload.NPCs = append(payload.NPCs, protocol.RoomNPCPayload{ID: n.ID, X: n.X, Y: n.Y, Health: n.Health, MaxHealth: n.MaxHealth})
+	}
+	return payload
+}
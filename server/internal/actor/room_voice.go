@@ -0,0 +1,92 @@
+package actor
+
+import (
+	"log"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// voiceMuteState is a member's voice mute state within a room.
+type voiceMuteState struct {
+	self        bool // Muted by the player
+	byModerator bool // Muted by the room owner; only the owner can lift it
+}
+
+func (s voiceMuteState) muted() bool { return s.self || s.byModerator }
+
+// handleRelayVoiceSignal forwards a signaling message between two members of this room.
+func (a *RoomActor) handleRelayVoiceSignal(ctx actor.Context, msg *messages.RelayVoiceSignal) {
+	reject := func(reason string) {
+		log.Printf("[RoomActor %s] Voice signal %s from %s to %s rejected: %s", a.roomID, msg.MsgType, msg.FromPlayerID, msg.ToPlayerID, reason)
+		if ctx.Sender() != nil {
+			ctx.Respond(&messages.VoiceSignalRejected{MsgType: msg.MsgType, Reason: reason})
+		}
+	}
+
+	if _, isMember := a.players[msg.FromPlayerID]; !isMember {
+		reject("You are not in this room.")
+		return
+	}
+	if msg.ToPlayerID == msg.FromPlayerID {
+		reject("Cannot send voice signals to yourself.")
+		return
+	}
+	targetPID, ok := a.players[msg.ToPlayerID]
+	if !ok {
+		reject("Target player is not in this room.")
+		return
+	}
+	ctx.Send(targetPID, &messages.VoiceSignal{MsgType: msg.MsgType, Payload: msg.Payload})
+}
+
+// handleSetVoiceMute updates a member's mute state and tells the whole room.
+// Members can mute or unmute themselves; the room owner can also moderate others.
+func (a *RoomActor) handleSetVoiceMute(ctx actor.Context, msg *messages.SetVoiceMute) {
+	if _, isMember := a.players[msg.PlayerID]; !isMember {
+		return
+	}
+	target := msg.TargetPlayerID
+	byModerator := target != "" && target != msg.PlayerID
+	if !byModerator {
+		target = msg.PlayerID
+	}
+	if _, isMember := a.players[target]; !isMember {
+		ctx.Send(a.players[msg.PlayerID], &messages.VoiceSignalRejected{Reason: "Target player is not in this room."})
+		return
+	}
+	if byModerator && (a.access.OwnerID == "" || msg.PlayerID != a.access.OwnerID) {
+		ctx.Send(a.players[msg.PlayerID], &messages.VoiceSignalRejected{Reason: "Only the room owner can mute other players."})
+		return
+	}
+
+	state := a.voiceMutes[target]
+	if byModerator {
+		state.byModerator = msg.Muted
+	} else {
+		state.self = msg.Muted
+	}
+	if state.muted() {
+		a.voiceMutes[target] = state
+	} else {
+		delete(a.voiceMutes, target)
+	}
+	log.Printf("[RoomActor %s] Voice mute for %s set to %t by %s.", a.roomID, target, state.muted(), msg.PlayerID)
+
+	a.broadcastMessage(ctx, nil, &messages.VoiceMuteChanged{
+		PlayerID:    target,
+		Muted:       state.muted(),
+		ByModerator: state.byModerator,
+	})
+}
+
+// sendVoiceMuteStates tells a newly joined member who is currently muted.
+func (a *RoomActor) sendVoiceMuteStates(ctx actor.Context, playerPID *actor.PID) {
+	for playerID, state := range a.voiceMutes {
+		ctx.Send(playerPID, &messages.VoiceMuteChanged{
+			PlayerID:    playerID,
+			Muted:       state.muted(),
+			ByModerator: state.byModerator,
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package actor
 
 import (
-	"encoding/binary"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	// "log" // Replaced by utils.LogX
@@ -10,11 +12,48 @@ import (
 	"time" // For heartbeat
 
 	"github.com/asynkron/protoactor-go/actor"
-	"github.com/block-vision/sui-go-sdk/models" // For SUI SDK types
+	"github.com/phuhao00/suigserver/pkg/protocol" // For protocol definitions
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
 	"github.com/phuhao00/suigserver/server/internal/actor/messages"
-	"github.com/phuhao00/suigserver/server/internal/protocol" // For protocol definitions
-	"github.com/phuhao00/suigserver/server/internal/sui"      // For SUI client
-	"github.com/phuhao00/suigserver/server/internal/utils"    // Logger
+	"github.com/phuhao00/suigserver/server/internal/afk"
+	"github.com/phuhao00/suigserver/server/internal/arena"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/battlepass"
+	"github.com/phuhao00/suigserver/server/internal/calendar"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/chathistory"
+	"github.com/phuhao00/suigserver/server/internal/clientversion"
+	"github.com/phuhao00/suigserver/server/internal/cosmetics"
+	"github.com/phuhao00/suigserver/server/internal/crafting"
+	"github.com/phuhao00/suigserver/server/internal/deeplink"
+	"github.com/phuhao00/suigserver/server/internal/delivery"
+	"github.com/phuhao00/suigserver/server/internal/diplomacy"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/fairroll"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/idempotency"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/onboarding"
+	"github.com/phuhao00/suigserver/server/internal/orderbook"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/prefetch"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+	"github.com/phuhao00/suigserver/server/internal/receipts"
+	"github.com/phuhao00/suigserver/server/internal/recruitment"
+	"github.com/phuhao00/suigserver/server/internal/resume"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/sui" // For SUI client
+	"github.com/phuhao00/suigserver/server/internal/timers"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+	"github.com/phuhao00/suigserver/server/internal/worlds"
 )
 
 // PlayerSessionActor manages a single client's connection and game session.
@@ -23,6 +62,7 @@ type PlayerSessionActor struct {
 	actorSystem     *actor.ActorSystem // To interact with other actors
 	playerID        string             // Set after authentication
 	roomPID         *actor.PID         // PID of the room the player is currently in
+	roomID          string             // ID of that room
 	roomManagerPID  *actor.PID         // PID of the RoomManagerActor
 	worldManagerPID *actor.PID         // PID of the WorldManagerActor, to be injected or discovered
 	suiClient       *sui.SuiClient     // SUI client instance
@@ -31,9 +71,37 @@ type PlayerSessionActor struct {
 	dummyToken      string
 	dummyPlayerID   string
 	// other player-specific state
+	services    SessionServices
+	pendingJoin *messages.JoinRoomRequest // Credentials for the join in progress (password/invite), sent once the room is found
+	transfer    *roomTransfer             // Server-driven move to another room in progress
+	combatID    string                    // Turn-based fight the player is in, if any
+	combatPID   *actor.PID                // CombatSessionActor running that fight
+	world       *worlds.World             // World the player entered at auth, if the server has several
+	afk         bool                      // Marked AFK; cleared by the next gameplay message
+	afkTimer    *timers.Timer             // Periodic AFK check
+	clockTimer  *timers.Timer             // Periodic CLOCK_HINT
+	hintSentAt  time.Time                 // When the latest CLOCK_HINT left, until a PING answers it
+	rtt         time.Duration             // Smoothed round trip measured from CLOCK_HINTs; 0 until measured
+	delivery    *delivery.Buffer          // Replay buffer if the client asked for reliable delivery
+	social      *ratelimit.Bucket         // Throttles social actions; created on the first one
+	version     clientversion.Decision    // How the client version of the latest AUTH was judged
+	versionDone func()                    // Stops counting the session under its client version
+	appearance  map[string]string         // Equipped cosmetics by slot, shown in the rooms the player joins
+
+	parentalTimer  *timers.Timer // Next parental controls check
+	parentalSince  time.Time     // Playtime counted up to here; zero when parental controls are off
+	parentalWarned time.Duration // Smallest warning threshold the player was warned at
+
+	replyKey        string              // Idempotency key of the command whose reply is being sent, if any
+	awaitingReplies map[string][]string // Command type -> keys of commands waiting on their result message, oldest first
+	resumeGrant     resume.Grant        // Token the client resumes this session with
 
 	lastActivity    time.Time     // Time of last message from client or significant activity
+	lastGameplay    time.Time     // Time of last gameplay message, for AFK detection; chat does not count
+	authenticatedAt time.Time     // Start of the authenticated session, for player.logout
+	frameVersion    byte          // Framing of the client's first message; responses use the same
 	heartbeatStopCh chan struct{} // Channel to stop heartbeat goroutine (if any server-side ping)
+	metrics         sessionMetrics
 }
 
 // NewPlayerSessionActor creates a new PlayerSessionActor instance.
@@ -78,7 +146,72 @@ func PropsForPlayerSession(
 ) *actor.Props {
 	return actor.PropsFromProducer(func() actor.Actor {
 		return NewPlayerSessionActor(system, roomManagerPID, worldManagerPID, suiClient, enableDummyAuth, dummyToken, dummyPlayerID)
-	})
+	}, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// SessionServices are optional game services shared by all player sessions.
+// A nil service disables the feature it provides.
+type SessionServices struct {
+	Onboarding  *onboarding.Service  // Tutorial progress, gating and step prompts
+	Events      *events.Bus          // Receives player and room events
+	Arena       *arena.Service       // Ranked PvP queues
+	Shop        *shop.Service        // NPC vendors
+	Worlds      *worlds.Directory    // Game worlds players can pick at auth; the session's own managers if nil
+	AFK         *afk.Policy          // When idle players are marked AFK, moved and kicked
+	ChatHistory *chathistory.Service // Stores room and whisper chat for CHAT_HISTORY_REQUEST
+	Accounts    *accountlink.Service // Linked Sui addresses; without it on-chain actions target the player ID
+	Trades      *trade.Service       // Player-to-player trades, escrowed above a value threshold
+	Gifts       *gift.Service        // Player-to-player gifts of item NFTs; GIFT_* requests are refused if nil
+	Timers      *timers.Scheduler    // Periodic session checks; real time if nil
+	Auth        TokenAuthenticator   // Resolves AUTH tokens; only the dummy token is accepted if nil
+	Audit       *audit.Log           // Records auth attempts
+	Delivery    *delivery.Store      // Replay buffers for clients that ask for reliable delivery; off if nil
+	Social      ratelimit.Limit      // How fast a player may send emotes, map pings and quick replies; unlimited if zero
+	ClockHints  ClockHints           // Periodic CLOCK_HINT pushes; none if zero
+	Mail        *mail.Service        // Mailboxes of server notices; MAIL_* requests are refused if nil
+	Versions    *clientversion.Gate  // Client versions allowed to authenticate; every client if nil
+	Crafting    *crafting.Service    // Crafting queues; CRAFT_* requests are refused if nil
+	Energy      *energy.Service      // Energy spent by crafting and arena queueing; actions are free if nil
+	BattlePass  *battlepass.Service  // Seasonal battle pass; BATTLEPASS_* requests are refused if nil
+	Cosmetics   *cosmetics.Service   // What players wear; APPEARANCE_EQUIP is refused if nil
+	Receipts    *receipts.Service    // Sends TX_RECEIPT when the player's transactions complete; none if nil
+	Prefetch    *prefetch.Service    // Reads the player's chain state at login for AUTH_RESPONSE; nothing is read if nil
+	Stats       *stats.Service       // Sends STAT_DELTA when the player's stats change; none if nil
+	Idempotency *idempotency.Cache   // Results of commands sent with idempotency keys; keys are ignored if nil
+	Resume      *resume.Service      // Resume tokens handed out at auth; AUTH needs credentials if nil
+	Calendar    *calendar.Service    // Guild event calendars; GUILD_EVENT_* requests are refused if nil
+	GuildRanks  *guildranks.Service  // Guild permission matrices; GUILD_RANK*, GUILD_INVITE, GUILD_KICK and GUILD_BANK_WITHDRAW are refused if nil
+	DeepLinks   *deeplink.Service    // Checks the tokens of DEEP_LINK_OPEN; refused if nil
+	FairRolls   *fairroll.Service    // Sends LOOT_COMMITMENT and LOOT_REVEAL for high-stakes loot; none if nil
+	Parental    *parental.Service    // Playtime, curfew, chat and spending restrictions of accounts; none if nil
+	Diplomacy   *diplomacy.Service   // Guild alliances and rivalries; DIPLOMACY_REQUEST, ALLIANCE_* and RIVALRY_* are refused if nil
+	OrderBook   *orderbook.Service   // Limit orders for the game token; ORDER_* requests are refused if nil
+	Plugins     *plugins.Manager     // Handles client message types the protocol does not define
+	Recruitment *recruitment.Service // Guild recruitment board; GUILD_RECRUITMENT_* and GUILD_APPL* requests are refused if nil
+}
+
+// TokenAuthenticator resolves the token of an AUTH request to a player ID.
+// Sessions call it from their actor, so it should answer quickly.
+type TokenAuthenticator interface {
+	Authenticate(token string) (playerID string, err error)
+}
+
+// PropsForPlayerSessionWithServices is PropsForPlayerSession with shared game services attached.
+func PropsForPlayerSessionWithServices(
+	system *actor.ActorSystem,
+	roomManagerPID *actor.PID,
+	worldManagerPID *actor.PID,
+	suiClient *sui.SuiClient,
+	enableDummyAuth bool,
+	dummyToken string,
+	dummyPlayerID string,
+	services SessionServices,
+) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor {
+		session := NewPlayerSessionActor(system, roomManagerPID, worldManagerPID, suiClient, enableDummyAuth, dummyToken, dummyPlayerID).(*PlayerSessionActor)
+		session.services = services
+		return session
+	}, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
 }
 
 const (
@@ -95,9 +228,19 @@ const placeholderGameLogicPackageID = "0xPLACEHOLDER_GAME_LOGIC_PACKAGE_ID"
 const placeholderPlayerObjectPackageID = "0xPLACEHOLDER_PLAYER_OBJECT_PACKAGE_ID" // Used for GET_PLAYER_PROFILE
 const placeholderPlayerObjectModule = "player_profile"                            // Used for GET_PLAYER_PROFILE
 
+// TimesItself implements handlermetrics.SelfTimed: client messages are timed
+// by protocol type in handleClientPayload.
+func (a *PlayerSessionActor) TimesItself(message interface{}) bool {
+	_, ok := message.(*messages.ClientMessage)
+	return ok
+}
+
 // Receive is the main message handling loop for the PlayerSessionActor.
 func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 	actorID := ctx.Self().Id
+	if action, ok := resultAction(ctx.Message()); ok && a.resumeIdempotent(action) {
+		defer a.endIdempotent()
+	}
 	switch msg := ctx.Message().(type) {
 	case *actor.Started:
 		utils.LogInfof("[%s] PlayerSessionActor started.", actorID)
@@ -118,21 +261,17 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 
 	case *actor.ReceiveTimeout:
 		utils.LogWarnf("[%s] ReceiveTimeout for player %s. No client activity or authentication in time. Stopping session.", actorID, a.playerID)
-		if a.conn != nil {
-			timeoutMsg := "Timeout due to inactivity."
-			if !a.isAuthenticated() {
-				timeoutMsg = "Timeout: Authentication not completed in time."
-			}
-			utils.LogInfof("[%s] Sending timeout disconnect message to player %s: %s", actorID, a.playerID, timeoutMsg)
-			a.sendErrorResponse("TIMEOUT", timeoutMsg+" Disconnecting.")
-			a.conn.Close()
+		timeoutMsg := "Timeout due to inactivity."
+		if !a.isAuthenticated() {
+			timeoutMsg = "Timeout: Authentication not completed in time."
 		}
-		ctx.Stop(ctx.Self())
+		a.terminate(ctx, &messages.TerminateSession{Code: protocol.DisconnectTimeout, Reason: timeoutMsg, Retryable: true})
 
 	case *messages.ClientConnected:
 		utils.LogInfof("[%s] Received ClientConnected from %s", actorID, msg.Conn.RemoteAddr())
 		a.conn = msg.Conn
 		a.lastActivity = time.Now()
+		a.metrics.connectedAt, a.metrics.remoteAddr = a.lastActivity, msg.Conn.RemoteAddr().String()
 		ctx.SetReceiveTimeout(authTimeout) // Client has this much time to send auth command
 
 		// Request authentication using the new JSON protocol
@@ -141,6 +280,7 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 	case *messages.ClientMessage:
 		utils.LogDebugf("[%s] Received ClientMessage from player %s: %s", actorID, a.playerID, string(msg.Payload))
 		a.lastActivity = time.Now() // Update last activity time on any client message
+		a.metrics.frameReceived(msg.WireSize)
 
 		if !a.isAuthenticated() {
 			// If not authenticated, keep the authTimeout active.
@@ -150,11 +290,17 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 			// If authenticated, switch to clientActivityTimeout for general inactivity.
 			ctx.SetReceiveTimeout(clientActivityTimeout)
 		}
-		a.handleClientPayload(ctx, msg.Payload)
+		a.handleClientPayload(ctx, msg)
 
 	case *messages.ForwardToClient:
 		a.handleForwardToClient(msg)
 
+	case *messages.TransferSession: // From gameplay code or the admin API
+		a.handleTransferSession(ctx, msg)
+
+	case *messages.GetSessionDiagnostics: // From the admin API, via the WorldManagerActor's session lookup
+		ctx.Respond(a.diagnostics(ctx))
+
 	case *messages.ClientDisconnected:
 		utils.LogInfof("[%s] Received ClientDisconnected for player %s: %s. Cleaning up.", actorID, a.playerID, msg.Reason)
 		// If in a room, notify the room actor
@@ -173,8 +319,25 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 		// In a real app, this would involve checking against a database or auth service.
 		// The token should be securely handled.
 		success := false
+		rejection := "invalid token"
 		// PlayerID from msg.PlayerID is ignored. PlayerID is determined by the validated token.
-		if a.enableDummyAuth {
+		if msg.ResumeToken != "" {
+			if err := a.redeemResumeToken(msg); err != nil {
+				utils.LogWarnf("[%s] Resume token rejected: %v", actorID, err)
+				rejection = err.Error()
+			} else {
+				success = true
+			}
+		} else if a.services.Auth != nil {
+			playerID, err := a.services.Auth.Authenticate(msg.Token)
+			if err != nil {
+				utils.LogWarnf("[%s] Token rejected: %v", actorID, err)
+				rejection = err.Error()
+			} else if playerID != "" {
+				a.playerID = playerID
+				success = true
+			}
+		} else if a.enableDummyAuth {
 			if msg.Token == a.dummyToken {
 				a.playerID = a.dummyPlayerID // Set playerID based on the valid dummy token
 				success = true
@@ -185,11 +348,28 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 			utils.LogWarnf("[%s] Dummy authentication is disabled. Player (token: %s) authentication failed.", actorID, msg.Token)
 		}
 
+		// Parental controls may keep a valid account out at this time.
+		var parentalRefusal error
+		if success {
+			if parentalRefusal = a.admitParental(); parentalRefusal != nil {
+				utils.LogInfof("[%s] Player %s refused by parental controls: %v", actorID, a.playerID, parentalRefusal)
+				success, rejection = false, parentalRefusal.Error()
+				a.playerID, a.resumeGrant = "", resume.Grant{}
+			}
+		}
+
+		credential := msg.Token
+		if msg.ResumeToken != "" {
+			credential = msg.ResumeToken
+		}
+		a.auditAuth(success, credential, rejection)
 		if success {
 			a.lastActivity = time.Now()
+			a.authenticatedAt = a.lastActivity
 			ctx.CancelReceiveTimeout()                   // Authentication successful, cancel auth timeout
 			ctx.SetReceiveTimeout(clientActivityTimeout) // Start general client activity timeout
 			utils.LogInfof("[%s] Player %s authenticated successfully.", actorID, a.playerID)
+			a.enterWorld(ctx, msg.WorldID)
 
 			// Notify WorldManager that player has entered
 			// The WorldManagerPID should be available to the PlayerSessionActor,
@@ -218,10 +398,47 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 
 		// Send JSON response to client
 		if success {
+			replay, gap := a.attachDelivery(msg)
+			resumeToken := a.grantResumeToken(msg)
 			a.sendResponse(protocol.MsgTypeAuthResponse, protocol.AuthResponsePayload{
 				PlayerID: a.playerID, // PlayerID is now set on 'a'
 				Success:  true,
 				Message:  "Authentication successful.",
+				WorldID:  a.worldID(),
+				Reliable: a.delivery != nil,
+				Replayed: len(replay),
+				Gap:      gap,
+				Ready:    a.prefetchLogin(),
+
+				ResumeToken:    resumeToken.Token,
+				ResumeWindow:   int(resumeToken.Window / time.Second),
+				ResumeNotAfter: resumeNotAfter(resumeToken),
+				ServerTime:     serverTime(),
+			})
+			a.replayMessages(replay) // Before anything new, so the client sees them in order
+			a.services.Events.Publish(events.TopicPlayerLogin, events.PlayerLogin{PlayerID: a.playerID})
+			a.versionDone = a.services.Versions.Connected(a.version)
+			a.beginTutorial()
+			a.startAFKChecks(ctx)
+			a.startClockHints(ctx)
+			a.startParentalControls(ctx)
+			a.connectTrades(ctx)
+			a.connectGifts(ctx)
+			a.connectOrders(ctx)
+			a.connectMail(ctx)
+			a.connectCalendar(ctx)
+			a.connectReceipts(ctx)
+			a.connectFairRolls(ctx)
+			a.connectStats(ctx)
+			a.connectCrafting(ctx)
+			a.connectEnergy(ctx)
+			a.connectBattlePass(ctx)
+			a.connectCosmetics(ctx)
+		} else if parentalRefusal != nil {
+			a.sendErrorResponse(parentalErrorCode(parentalRefusal), parentalRefusal.Error()+".")
+			a.sendResponse(protocol.MsgTypeAuthResponse, protocol.AuthResponsePayload{
+				Success: false,
+				Message: "Authentication refused by parental controls.",
 			})
 		} else {
 			a.sendResponse(protocol.MsgTypeAuthResponse, protocol.AuthResponsePayload{
@@ -233,13 +450,13 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 	case *messages.FindRoomResponse: // Response from RoomManagerActor
 		utils.LogInfof("[%s] Player %s received FindRoomResponse: Found=%t, RoomID=%s, RoomPID=%s, Error=%s",
 			actorID, a.playerID, msg.Found, msg.RoomID, msg.RoomPID, msg.Error)
-		if msg.Found && msg.RoomPID != nil {
-			joinReq := &messages.JoinRoomRequest{
-				PlayerID:  a.playerID,
-				PlayerPID: ctx.Self(),
-			}
+		if a.transfer != nil {
+			a.continueTransfer(ctx, msg)
+		} else if msg.Found && msg.RoomPID != nil {
+			joinReq := a.takePendingJoin(ctx)
 			ctx.Request(msg.RoomPID, joinReq) // Request to join the actual room
 		} else {
+			a.pendingJoin = nil
 			responseMessage := "Error finding room."
 			if msg.Error != "" {
 				responseMessage = msg.Error
@@ -253,14 +470,22 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 		}
 
 	case *messages.JoinRoomResponse: // Response from a RoomActor
-		if msg.Success {
-			a.roomPID = ctx.Sender() // Assume sender is the RoomActor
+		if a.transfer != nil {
+			a.finishTransfer(ctx, msg)
+		} else if msg.Success {
+			a.roomPID = msg.RoomPID
+			a.roomID = msg.RoomID
 			utils.LogInfof("[%s] Player %s successfully joined room %s (RoomActor PID: %s)", actorID, a.playerID, msg.RoomID, a.roomPID.Id)
+			a.enterChatZone(ctx, msg)
 			a.sendResponse(protocol.MsgTypeJoinRoomResponse, protocol.JoinRoomResponsePayload{
-				Success: true,
-				RoomID:  msg.RoomID,
-				Message: "Successfully joined room: " + msg.RoomID,
+				Success:    true,
+				RoomID:     msg.RoomID,
+				Message:    "Successfully joined room: " + msg.RoomID,
+				ServerTime: serverTime(),
+				State:      roomStatePayload(msg.State),
 			})
+			a.services.Events.Publish(events.TopicRoomJoined, events.RoomJoined{PlayerID: a.playerID, RoomID: msg.RoomID})
+			a.recordTutorialEvent(onboarding.EventRoomJoined)
 		} else {
 			utils.LogWarnf("[%s] Player %s failed to join room %s: %s", actorID, a.playerID, msg.RoomID, msg.Error)
 			a.sendResponse(protocol.MsgTypeJoinRoomResponse, protocol.JoinRoomResponsePayload{
@@ -270,16 +495,247 @@ func (a *PlayerSessionActor) Receive(ctx actor.Context) {
 			})
 		}
 
-	case *messages.RoomChatMessage: // Received from a RoomActor to be forwarded to this client
-		chatPayload := protocol.ChatMessagePayload{
-			SenderName: msg.SenderName,
-			Text:       msg.Message,
+	case *messages.RoomState: // Response from a RoomActor
+		if msg.Error != "" {
+			a.sendErrorResponse("ROOM_STATE_UNAVAILABLE", msg.Error)
+			return
+		}
+		a.sendResponse(protocol.MsgTypeRoomState, roomStatePayload(msg))
+
+	case *messages.CreateRoomResponse: // Response from RoomManagerActor
+		if !msg.Success || msg.RoomPID == nil {
+			a.pendingJoin = nil
+			utils.LogWarnf("[%s] Player %s failed to create room: %s", actorID, a.playerID, msg.Error)
+			a.sendResponse(protocol.MsgTypeCreateRoomResponse, protocol.CreateRoomResponsePayload{
+				Success: false,
+				RoomID:  msg.RoomID,
+				Message: msg.Error,
+			})
+			return
 		}
-		a.sendResponse(protocol.MsgTypeNewChatMessage, chatPayload)
+		a.sendResponse(protocol.MsgTypeCreateRoomResponse, protocol.CreateRoomResponsePayload{
+			Success: true,
+			RoomID:  msg.RoomID,
+			Message: "Room created: " + msg.RoomID,
+		})
+		ctx.Request(msg.RoomPID, a.takePendingJoin(ctx))
+
+	case *messages.ListRoomsResponse: // Response from RoomManagerActor
+		rooms := make([]protocol.RoomSummaryPayload, 0, len(msg.Rooms))
+		for _, room := range msg.Rooms {
+			rules := roomRulesPayload(room.Rules)
+			rooms = append(rooms, protocol.RoomSummaryPayload{
+				RoomID:           room.RoomID,
+				Name:             room.Name,
+				CurrentPlayers:   room.CurrentPlayers,
+				MaxPlayers:       room.MaxPlayers,
+				PasswordRequired: room.PasswordRequired,
+				Rules:            &rules,
+			})
+		}
+		a.sendResponse(protocol.MsgTypeRoomList, protocol.RoomListPayload{Rooms: rooms})
+
+	case *messages.CreateRoomInviteResponse: // Response from a RoomActor
+		a.sendResponse(protocol.MsgTypeCreateRoomInviteResponse, protocol.RoomInvitePayload{
+			Success:   msg.Success,
+			RoomID:    msg.RoomID,
+			Token:     msg.Token,
+			ExpiresAt: msg.ExpiresAt,
+			Message:   msg.Error,
+		})
+
+	case *arena.MatchFound, *arena.MatchResult: // From the arena's notifier
+		a.sendArenaNotification(msg)
+
+	case *messages.CombatStarted, *messages.CombatTurnPrompt, *messages.CombatStateUpdate,
+		*messages.CombatActionRejected, *messages.CombatEnded: // From a CombatSessionActor
+		a.handleCombatMessage(ctx, msg)
+
+	case *shopPremiumResult: // From the goroutine verifying a premium payment
+		a.metrics.suiRequestFinished(protocol.MsgTypeShopTransaction)
+		a.sendShopTransactionResult(ctx, msg.request, msg.receipt, msg.err)
+
+	case *messages.ClaimZoneResponse: // Response from WorldManagerActor
+		a.sendClaimZoneResponse(msg)
+
+	case *messages.VoiceSignal: // Relayed by the RoomActor from another member
+		a.sendResponse(msg.MsgType, msg.Payload)
+
+	case *messages.VoiceSignalRejected:
+		a.sendErrorResponse("VOICE_SIGNAL_REJECTED", msg.Reason)
+
+	case *messages.VoiceMuteChanged, *messages.PlayerAFKChanged, *messages.PositionChanged,
+		*messages.ProjectileSpawned, *messages.ProjectileHit, *messages.ProjectileExpired, *messages.SocialAction, *messages.AppearanceChanged: // Broadcast by the RoomActor
+		msgType, payload, _ := clientMessage(msg)
+		a.sendResponse(msgType, payload)
+
+	case *messages.MovementAbilityResult: // From the RoomActor
+		a.sendMovementAbilityResult(msg)
+
+	case *messages.ProjectileRefused: // From the RoomActor
+		a.sendErrorResponse("PROJECTILE_REFUSED", msg.Error)
+
+	case *preparedBroadcast: // Broadcast by the RoomActor, already serialized
+		a.writePrepared(msg)
+
+	case *afkCheck:
+		a.handleAFKCheck(ctx)
+
+	case *clockHint:
+		a.sendClockHint()
+
+	case *parentalCheck:
+		a.handleParentalCheck(ctx)
+
+	case *messages.TerminateSession: // From a RoomActor for cheating, the admin API or the TCP server
+		utils.LogInfof("[%s] Terminating session of player %s: %s (%s)", actorID, a.playerID, msg.Reason, msg.Code)
+		a.terminate(ctx, msg)
+
+	case *messages.Whisper: // Delivered by the WorldManagerActor
+		a.handleWhisper(msg)
+
+	case *messages.WhisperFailed:
+		a.sendErrorResponse("WHISPER_FAILED", msg.Reason)
+
+	case *messages.ChannelMessage: // Delivered by the WorldManagerActor
+		a.handleChannelMessage(msg)
+
+	case *messages.ChannelList:
+		a.sendChannelList(msg)
+
+	case *messages.ChannelRefused:
+		a.sendErrorResponse(msg.Code, msg.Reason)
+
+	case *messages.Announcement: // From the operators, via the WorldManagerActor
+		a.sendResponse(protocol.MsgTypeAnnouncement, protocol.AnnouncementPayload{Text: msg.Text, SentAt: msg.SentAt.UnixMilli()})
+
+	case *chatHistoryResult:
+		a.handleChatHistoryResult(ctx, msg)
+
+	case *walletLinksResult:
+		a.handleWalletLinksResult(ctx, msg)
+
+	case *trade.Update: // From the trade service's notifier
+		a.sendTradeUpdate(msg.Trade)
+
+	case *gift.Update: // From the gift service's notifier
+		a.sendGiftUpdate(msg.Gift)
+
+	case *orderbook.Update: // From the order book's notifier
+		a.sendOrderUpdate(msg.Order, msg.Fill)
+
+	case *mail.Notice: // From the mail service's notifier
+		a.sendResponse(protocol.MsgTypeMailNew, mailMessagePayload(msg.Message))
+
+	case *receipts.Receipt: // From the receipts service's notifier
+		a.sendResponse(protocol.MsgTypeTxReceipt, txReceiptPayload(msg))
+
+	case *fairroll.Commitment: // From the fair roll service's notifier
+		a.sendResponse(protocol.MsgTypeLootCommitment, lootCommitmentPayload(msg))
+
+	case *fairroll.Reveal: // From the fair roll service's notifier
+		a.sendResponse(protocol.MsgTypeLootReveal, lootRevealPayload(msg))
+
+	case *stats.Delta: // From the stats service's notifier
+		a.sendResponse(protocol.MsgTypeStatDelta, statDeltaPayload(msg))
+
+	case *calendar.Started: // From the calendar service's notifier
+		a.sendResponse(protocol.MsgTypeGuildEventStarted, guildEventPayload(msg.Event))
+
+	case *crafting.Notice: // From the crafting service's notifier
+		a.sendResponse(protocol.MsgTypeCraftCompleted, craftPayload(msg.Craft, time.Now()))
+
+	case *craftingResult:
+		a.handleCraftingResult(ctx, msg)
+
+	case *energy.Update: // From the energy service's notifier
+		a.sendEnergy(msg.Status)
+
+	case *energyResult:
+		a.handleEnergyResult(ctx, msg)
+
+	case *energySpent:
+		a.handleEnergySpent(ctx, msg)
+
+	case *battlepass.Update: // From the battle pass service's notifier
+		a.sendBattlePass(msg.View)
+
+	case *battlePassResult:
+		a.handleBattlePassResult(ctx, msg)
+
+	case *appearanceResult:
+		a.handleAppearanceResult(ctx, msg)
+
+	case *tradeResult:
+		a.metrics.suiRequestFinished(msg.action)
+		a.handleTradeResult(ctx, msg)
+
+	case *giftResult:
+		a.metrics.suiRequestFinished(msg.action)
+		a.handleGiftResult(ctx, msg)
+
+	case *orderResult:
+		a.metrics.suiRequestFinished(msg.action)
+		a.handleOrderResult(ctx, msg)
+
+	case *pluginReply: // From a plugin's message handler
+		a.handlePluginReply(msg)
+
+	case *guildActionResult:
+		a.metrics.suiRequestFinished(msg.action)
+		a.handleGuildActionResult(ctx, msg)
+
+	case *messages.RoomChatMessage: // Received from a RoomActor to be forwarded to this client
+		msgType, payload, _ := clientMessage(msg)
+		a.sendResponse(msgType, payload)
 
 	default:
 		utils.LogWarnf("[%s] PlayerSessionActor %s received unknown message type %T: %+v", actorID, a.playerID, msg, msg)
+		quarantine.ReportUnhandled(ctx, msg)
+	}
+}
+
+// findAndJoinRoom asks the room manager for the room matching criteria and
+// prepares the join that follows with the given credentials.
+func (a *PlayerSessionActor) findAndJoinRoom(ctx actor.Context, criteria, password, inviteToken string) {
+	actorID := ctx.Self().Id
+	utils.LogInfof("[%s] Player %s attempting to find and join room with criteria: '%s'", actorID, a.playerID, criteria)
+	if a.roomManagerPID == nil {
+		utils.LogErrorf("[%s] Player %s: RoomManagerPID not configured. Cannot join room.", actorID, a.playerID)
+		a.sendResponse(protocol.MsgTypeJoinRoomResponse, protocol.JoinRoomResponsePayload{
+			Success: false,
+			Message: "Error: Room manager is not available.",
+		})
+		return
+	}
+	if a.transfer != nil {
+		a.sendErrorResponse("ROOM_TRANSFER_IN_PROGRESS", "You are being moved to another room.")
+		return
+	}
+
+	a.pendingJoin = &messages.JoinRoomRequest{
+		PlayerID:    a.playerID,
+		PlayerPID:   ctx.Self(),
+		Password:    password,
+		InviteToken: inviteToken,
+	}
+	ctx.Request(a.roomManagerPID, &messages.FindRoomRequest{
+		Criteria:  criteria,
+		PlayerPID: ctx.Self(),
+	})
+	a.sendSimpleMessage(fmt.Sprintf("Attempting to find and join room '%s'...", criteria))
+}
+
+// takePendingJoin returns the join request prepared when the player asked to
+// join or create a room, falling back to one without credentials.
+func (a *PlayerSessionActor) takePendingJoin(ctx actor.Context) *messages.JoinRoomRequest {
+	joinReq := a.pendingJoin
+	a.pendingJoin = nil
+	if joinReq == nil {
+		joinReq = &messages.JoinRoomRequest{PlayerID: a.playerID, PlayerPID: ctx.Self()}
 	}
+	joinReq.Appearance = a.appearance
+	return joinReq
 }
 
 // cleanupResources performs necessary cleanup when the actor is stopping.
@@ -287,6 +743,14 @@ func (a *PlayerSessionActor) cleanupResources(ctx actor.Context) {
 	actorID := ctx.Self().Id
 	utils.LogInfof("[%s] Cleaning up resources for player %s.", actorID, a.playerID)
 	ctx.CancelReceiveTimeout() // Cancel any pending receive timeout
+	a.stopAFKChecks()
+	a.stopClockHints()
+	a.stopParentalControls()
+	a.detachDelivery()
+	a.services.Resume.Release(a.resumeGrant.Token)
+	if a.versionDone != nil {
+		a.versionDone()
+	}
 
 	if a.playerID != "" {
 		if a.worldManagerPID != nil {
@@ -295,33 +759,111 @@ func (a *PlayerSessionActor) cleanupResources(ctx actor.Context) {
 		} else {
 			utils.LogWarnf("[%s] WorldManagerPID not set for player %s. Cannot notify WorldManager about leaving.", actorID, a.playerID)
 		}
+		if a.world != nil {
+			a.world.Left()
+		}
+		if a.services.Onboarding != nil {
+			a.services.Onboarding.End(a.playerID)
+		}
+		if a.services.Arena != nil {
+			a.services.Arena.Leave(a.playerID)
+		}
+		if a.services.Trades != nil {
+			a.services.Trades.Disconnect(a.playerID)
+		}
+		if a.services.Gifts != nil {
+			a.services.Gifts.Disconnect(a.playerID)
+		}
+		if a.services.OrderBook != nil {
+			a.services.OrderBook.Disconnect(a.playerID)
+		}
+		if a.services.Mail != nil {
+			a.services.Mail.Disconnect(a.playerID)
+		}
+		if a.services.Calendar != nil {
+			a.services.Calendar.Disconnect(a.playerID)
+		}
+		if a.services.Receipts != nil {
+			a.services.Receipts.Disconnect(a.playerID)
+		}
+		if a.services.FairRolls != nil {
+			a.services.FairRolls.Disconnect(a.playerID)
+		}
+		a.services.Stats.Disconnect(a.playerID)
+		if a.services.Crafting != nil {
+			a.services.Crafting.Disconnect(a.playerID)
+		}
+		a.services.Energy.Disconnect(a.playerID)
+		if a.services.BattlePass != nil {
+			a.services.BattlePass.Disconnect(a.playerID)
+		}
+		a.forfeitCombat(ctx) // Leaving mid-fight concedes it
+		if !a.authenticatedAt.IsZero() {
+			a.services.Events.Publish(events.TopicPlayerLogout, events.PlayerLogout{
+				PlayerID: a.playerID,
+				Duration: time.Since(a.authenticatedAt).Seconds(),
+			})
+		}
 		utils.LogInfof("[%s] Player %s disconnected. Placeholder: Trigger save player data mechanism.", actorID, a.playerID)
 	}
 }
 
 // handleClientPayload parses the raw payload from the client and decides what to do.
-func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload []byte) {
+func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, clientMsg *messages.ClientMessage) {
 	actorID := ctx.Self().Id
+	if a.frameVersion == 0 {
+		a.frameVersion = clientMsg.FrameVersion
+	}
+	started := time.Now()
 	var msg protocol.ClientServerMessage
-	if err := json.Unmarshal(rawPayload, &msg); err != nil {
-		utils.LogWarnf("[%s] Player %s: Error unmarshaling client message: %v. Payload: '%s'", actorID, a.playerID, err, string(rawPayload))
+	defer func() {
+		msgType := msg.Type
+		if msgType == "" {
+			msgType = "(undecoded)"
+		}
+		handlermetrics.Observe(ctx, msgType, time.Since(started), func() string {
+			return fmt.Sprintf("player %s, %d byte payload", a.playerID, len(clientMsg.Payload))
+		})
+	}()
+	// Every text field is cleaned and length-checked while decoding, before any
+	// handler broadcasts or stores it.
+	msg, err := protocol.DecodeClientMessage(clientMsg.FrameVersion, clientMsg.TypeID, clientMsg.Payload)
+	var textErr *protocol.TextError
+	switch {
+	case errors.Is(err, protocol.ErrUnknownTypeID):
+		utils.LogWarnf("[%s] Player %s: Unknown message type ID %d", actorID, a.playerID, clientMsg.TypeID)
+		a.sendErrorResponse("UNKNOWN_COMMAND", fmt.Sprintf("Unknown message type ID: %d", clientMsg.TypeID))
+		return
+	case errors.Is(err, protocol.ErrInvalidUTF8):
+		utils.LogWarnf("[%s] Player %s: Message is not valid UTF-8", actorID, a.playerID)
+		a.sendErrorResponse("INVALID_TEXT", "Message is not valid UTF-8.")
+		return
+	case errors.Is(err, protocol.ErrInvalidJSON):
+		utils.LogWarnf("[%s] Player %s: Error unmarshaling client message: %v. Payload: '%s'", actorID, a.playerID, err, string(clientMsg.Payload))
 		a.sendErrorResponse("INVALID_JSON", "Message is not valid JSON.")
 		return
+	case errors.As(err, &textErr):
+		utils.LogWarnf("[%s] Player %s: Rejected '%s' payload: %v", actorID, a.playerID, msg.Type, err)
+		a.sendErrorResponse("INVALID_TEXT", err.Error())
+		return
+	case err != nil:
+		utils.LogWarnf("[%s] Player %s: Could not decode '%s': %v", actorID, a.playerID, msg.Type, err)
+		a.sendErrorResponse("INVALID_PAYLOAD_STRUCTURE", "Cannot process payload structure.")
+		return
 	}
 
-	utils.LogDebugf("[%s] Player %s received message type '%s', Payload: %+v", actorID, a.playerID, msg.Type, msg.Payload)
+	utils.LogDebugf("[%s] Player %s received message type '%s', Payload: %s", actorID, a.playerID, msg.Type, msg.Payload)
+	a.metrics.messageReceived(msg.Type)
 
-	var payloadMap map[string]interface{}
-	if msg.Payload != nil {
-		payloadBytes, err := json.Marshal(msg.Payload)
-		if err != nil {
-			utils.LogErrorf("[%s] Player %s: Error re-marshaling payload for type '%s': %v", actorID, a.playerID, msg.Type, err)
-			a.sendErrorResponse("INVALID_PAYLOAD_STRUCTURE", "Cannot process payload structure.")
+	if a.isAuthenticated() && !a.checkTutorialGate(msg) {
+		return
+	}
+	if a.isAuthenticated() {
+		a.recordGameplay(ctx, msg.Type)
+		if !a.beginIdempotent(msg) {
 			return
 		}
-		if err := json.Unmarshal(payloadBytes, &payloadMap); err != nil {
-			utils.LogWarnf("[%s] Player %s: Payload for type '%s' is not a JSON object: %v", actorID, a.playerID, msg.Type, err)
-		}
+		defer a.endIdempotent()
 	}
 
 	switch msg.Type {
@@ -332,16 +874,38 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			return
 		}
 		var authReqPayload protocol.AuthRequestPayload
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &authReqPayload); err != nil {
+		if err := msg.DecodePayload(&authReqPayload); err != nil {
 			utils.LogWarnf("[%s] Player (no ID yet): Invalid AUTH payload structure: %v", actorID, err)
 			a.sendErrorResponse("INVALID_AUTH_PAYLOAD", "Auth payload is malformed.")
 			return
 		}
+		if a.version = a.services.Versions.Check(authReqPayload.ClientVersion); !a.version.Allowed {
+			utils.LogInfof("[%s] Player (no ID yet): Refused client version %q: %s", actorID, authReqPayload.ClientVersion, a.version.Code)
+			a.metrics.errorSent(a.version.Code, a.version.Reason)
+			a.sendResponse(protocol.MsgTypeError, protocol.ErrorResponsePayload{
+				Code:       a.version.Code,
+				Message:    a.version.Reason,
+				UpgradeURL: a.version.UpgradeURL,
+			})
+			return
+		}
+		if a.services.Worlds != nil {
+			if _, err := a.services.Worlds.Lookup(authReqPayload.WorldID); err != nil {
+				utils.LogWarnf("[%s] Player (no ID yet): AUTH for unknown world %q", actorID, authReqPayload.WorldID)
+				a.sendErrorResponse("UNKNOWN_WORLD", "No such world: "+authReqPayload.WorldID)
+				return
+			}
+		}
 		tempPlayerID := "player_awaiting_auth"
 		authInternalMsg := &messages.AuthenticatePlayer{
 			PlayerID: tempPlayerID,
 			Token:    authReqPayload.Token,
+			WorldID:  authReqPayload.WorldID,
+			Reliable: authReqPayload.Reliable,
+			LastSeq:  authReqPayload.LastSeq,
+
+			ResumeToken: authReqPayload.ResumeToken,
+			DeviceID:    authReqPayload.DeviceID,
 		}
 		ctx.Request(ctx.Self(), authInternalMsg)
 
@@ -351,8 +915,7 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			return
 		}
 		var joinReqPayload protocol.JoinRoomRequestPayload
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &joinReqPayload); err != nil {
+		if err := msg.DecodePayload(&joinReqPayload); err != nil {
 			utils.LogWarnf("[%s] Player %s: Invalid JOIN_ROOM payload: %v", actorID, a.playerID, err)
 			a.sendErrorResponse("INVALID_JOIN_PAYLOAD", "Join room payload is malformed.")
 			return
@@ -364,21 +927,93 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			return
 		}
 
-		utils.LogInfof("[%s] Player %s attempting to find and join room with criteria: '%s'", actorID, a.playerID, joinReqPayload.Criteria)
+		a.findAndJoinRoom(ctx, joinReqPayload.Criteria, joinReqPayload.Password, joinReqPayload.InviteToken)
+
+	case protocol.MsgTypeCreateRoomRequest:
+		if !a.isAuthenticated() {
+			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		var createPayload protocol.CreateRoomRequestPayload
+		if err := msg.DecodePayload(&createPayload); err != nil {
+			utils.LogWarnf("[%s] Player %s: Invalid CREATE_ROOM payload: %v", actorID, a.playerID, err)
+			a.sendErrorResponse("INVALID_CREATE_ROOM_PAYLOAD", "Create room payload is malformed.")
+			return
+		}
+		visibility, err := ParseRoomVisibility(createPayload.Visibility)
+		if err != nil {
+			a.sendErrorResponse("INVALID_ROOM_VISIBILITY", "Visibility must be public, private or invite_only.")
+			return
+		}
+		var passwordHash string
+		if createPayload.Password != "" {
+			if passwordHash, err = HashRoomPassword(createPayload.Password); err != nil {
+				utils.LogErrorf("[%s] Player %s: %v", actorID, a.playerID, err)
+				a.sendErrorResponse("INTERNAL_ERROR", "Could not create the room.")
+				return
+			}
+		}
 		if a.roomManagerPID == nil {
-			utils.LogErrorf("[%s] Player %s: RoomManagerPID not configured. Cannot join room.", actorID, a.playerID)
-			a.sendResponse(protocol.MsgTypeJoinRoomResponse, protocol.JoinRoomResponsePayload{
+			a.sendResponse(protocol.MsgTypeCreateRoomResponse, protocol.CreateRoomResponsePayload{
 				Success: false,
 				Message: "Error: Room manager is not available.",
 			})
 			return
 		}
+		if a.transfer != nil {
+			a.sendErrorResponse("ROOM_TRANSFER_IN_PROGRESS", "You are being moved to another room.")
+			return
+		}
+		rules := roomRulesFromPayload(createPayload.Rules)
+		// The creator joins as the room owner, so no password or invite is needed.
+		a.pendingJoin = &messages.JoinRoomRequest{PlayerID: a.playerID, PlayerPID: ctx.Self()}
+		ctx.Send(a.roomManagerPID, &messages.CreateRoomRequest{
+			RoomName:     createPayload.Name,
+			MaxPlayers:   createPayload.MaxPlayers,
+			Visibility:   visibility,
+			PasswordHash: passwordHash,
+			OwnerID:      a.playerID,
+			MapID:        createPayload.MapID,
+			Rules:        &rules,
+			RequesterPID: ctx.Self(),
+		})
+		a.awaitReply(protocol.MsgTypeCreateRoomRequest)
 
-		ctx.Request(a.roomManagerPID, &messages.FindRoomRequest{
-			Criteria:  joinReqPayload.Criteria,
-			PlayerPID: ctx.Self(),
+	case protocol.MsgTypeListRoomsRequest:
+		if !a.isAuthenticated() {
+			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		if a.roomManagerPID == nil {
+			a.sendErrorResponse("ROOM_MANAGER_UNAVAILABLE", "Room manager is not available.")
+			return
+		}
+		ctx.Send(a.roomManagerPID, &messages.ListRoomsRequest{RequesterPID: ctx.Self()})
+
+	case protocol.MsgTypeCreateRoomInviteRequest:
+		if !a.isAuthenticated() {
+			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		if a.roomPID == nil {
+			a.sendErrorResponse("NOT_IN_A_ROOM", "You are not in a room. Join a room first.")
+			return
+		}
+		var invitePayload protocol.CreateRoomInviteRequestPayload
+		if err := msg.DecodePayload(&invitePayload); err != nil {
+			a.sendErrorResponse("INVALID_INVITE_PAYLOAD", "Invite payload is malformed.")
+			return
+		}
+		ctx.Request(a.roomPID, &messages.CreateRoomInviteRequest{
+			InviterID: a.playerID,
+			InviteeID: invitePayload.InviteePlayerID,
 		})
-		a.sendSimpleMessage(fmt.Sprintf("Attempting to find and join room '%s'...", joinReqPayload.Criteria))
+
+	case protocol.MsgTypeVoiceOffer, protocol.MsgTypeVoiceAnswer, protocol.MsgTypeVoiceICECandidate, protocol.MsgTypeVoiceMute:
+		a.handleVoiceMessage(ctx, msg)
+
+	case protocol.MsgTypeMove, protocol.MsgTypeUseMovementAbility, protocol.MsgTypeFireProjectile:
+		a.handleMovementMessage(ctx, msg)
 
 	case protocol.MsgTypeSendChat:
 		if !a.isAuthenticated() {
@@ -390,8 +1025,7 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			return
 		}
 		var chatReqPayload protocol.ChatMessagePayload
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &chatReqPayload); err != nil {
+		if err := msg.DecodePayload(&chatReqPayload); err != nil {
 			utils.LogWarnf("[%s] Player %s: Invalid SEND_CHAT payload: %v", actorID, a.playerID, err)
 			a.sendErrorResponse("INVALID_CHAT_PAYLOAD", "Chat payload is malformed.")
 			return
@@ -401,6 +1035,9 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			a.sendErrorResponse("EMPTY_CHAT_MESSAGE", "Chat message cannot be empty.")
 			return
 		}
+		if !a.mayChat() {
+			return
+		}
 		utils.LogInfof("[%s] Player %s sends chat to room %s: %s", actorID, a.playerID, a.roomPID.Id, chatReqPayload.Text)
 		roomChatMessageInternal := &messages.RoomChatMessage{
 			SenderID:   a.playerID,
@@ -411,24 +1048,177 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 			SenderPID:     ctx.Self(),
 			ActualMessage: roomChatMessageInternal,
 		})
+		a.recordRoomChat(chatReqPayload.Text)
+
+	case protocol.MsgTypeRoomStateRequest:
+		if !a.isAuthenticated() {
+			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		if a.roomPID == nil {
+			a.sendErrorResponse("NOT_IN_A_ROOM", "You are not in a room. Join a room first.")
+			return
+		}
+		ctx.Request(a.roomPID, &messages.RoomStateRequest{PlayerID: a.playerID})
+
+	case protocol.MsgTypeSendWhisper:
+		a.handleSendWhisper(ctx, msg)
+
+	case protocol.MsgTypeParentalStatusRequest:
+		a.handleParentalStatusRequest(ctx)
+
+	case protocol.MsgTypeChatHistoryRequest:
+		a.handleChatHistoryRequest(ctx, msg)
+
+	case protocol.MsgTypeChannelJoin, protocol.MsgTypeChannelLeave, protocol.MsgTypeChannelSend, protocol.MsgTypeChannelListRequest:
+		a.handleChannelRequest(ctx, msg)
+
+	case protocol.MsgTypeSocialAction:
+		a.handleSocialAction(ctx, msg)
+
+	case protocol.MsgTypeMailListRequest, protocol.MsgTypeMailRead, protocol.MsgTypeMailDelete:
+		a.handleMailRequest(ctx, msg)
+
+	case protocol.MsgTypeGuildEventCreate, protocol.MsgTypeGuildEventRSVP, protocol.MsgTypeGuildEventCancel, protocol.MsgTypeGuildEventsRequest:
+		a.handleGuildEventRequest(ctx, msg)
+	case protocol.MsgTypeGuildRanksRequest, protocol.MsgTypeGuildRankDefine, protocol.MsgTypeGuildRankDelete:
+		a.handleGuildRankRequest(ctx, msg)
+	case protocol.MsgTypeGuildInvite, protocol.MsgTypeGuildKick, protocol.MsgTypeGuildBankWithdraw, protocol.MsgTypeGuildRankAssign:
+		a.handleGuildAction(ctx, msg)
+	case protocol.MsgTypeDiplomacyRequest, protocol.MsgTypeAlliancePropose, protocol.MsgTypeAllianceRespond, protocol.MsgTypeAllianceLeave,
+		protocol.MsgTypeRivalryDeclare, protocol.MsgTypeRivalryEnd:
+		a.handleDiplomacyRequest(ctx, msg)
+	case protocol.MsgTypeGuildRecruitmentRequest, protocol.MsgTypeGuildRecruitmentPost, protocol.MsgTypeGuildRecruitmentClose,
+		protocol.MsgTypeGuildApplicationReview:
+		a.handleRecruitmentRequest(ctx, msg)
+	case protocol.MsgTypeGuildRecruitmentSearch, protocol.MsgTypeGuildApply, protocol.MsgTypeGuildApplicationWithdraw,
+		protocol.MsgTypeGuildApplicationsRequest:
+		a.handleApplicationRequest(ctx, msg)
+	case protocol.MsgTypeDeepLinkOpen:
+		a.handleDeepLinkOpen(ctx, msg)
+
+	case protocol.MsgTypeWalletLinkChallengeRequest:
+		a.handleWalletLinkChallengeRequest(ctx, msg)
+
+	case protocol.MsgTypeLinkWallet:
+		a.handleLinkWallet(ctx, msg)
+
+	case protocol.MsgTypeUnlinkWallet:
+		a.handleUnlinkWallet(ctx, msg)
+
+	case protocol.MsgTypeWalletLinksRequest:
+		a.handleWalletLinksRequest(ctx)
+
+	case protocol.MsgTypeTradePropose:
+		a.handleTradePropose(ctx, msg)
+
+	case protocol.MsgTypeTradeRespond:
+		a.handleTradeRespond(ctx, msg)
+
+	case protocol.MsgTypeTradeDeposited:
+		a.handleTradeDeposited(ctx, msg)
+
+	case protocol.MsgTypeGiftSend:
+		a.handleGiftSend(ctx, msg)
+
+	case protocol.MsgTypeGiftRespond:
+		a.handleGiftRespond(ctx, msg)
+
+	case protocol.MsgTypeGiftSent:
+		a.handleGiftSent(ctx, msg)
+
+	case protocol.MsgTypeGiftHistoryRequest:
+		a.handleGiftHistoryRequest(ctx)
+
+	case protocol.MsgTypeOrderPlace, protocol.MsgTypeOrderFunded, protocol.MsgTypeOrderCancel, protocol.MsgTypeOrderBookRequest:
+		a.handleOrderRequest(ctx, msg)
+
+	case protocol.MsgTypeCraftStart:
+		a.handleCraftStart(ctx, msg)
+
+	case protocol.MsgTypeCraftQueueRequest:
+		a.handleCraftQueueRequest(ctx)
+
+	case protocol.MsgTypeEnergyRequest:
+		a.handleEnergyRequest(ctx)
+
+	case protocol.MsgTypeBattlePassRequest:
+		a.handleBattlePassRequest()
+
+	case protocol.MsgTypeBattlePassClaim:
+		a.handleBattlePassClaim(ctx, msg)
+
+	case protocol.MsgTypeBattlePassUnlock:
+		a.handleBattlePassUnlock(ctx, msg)
+
+	case protocol.MsgTypeAppearanceEquip:
+		a.handleAppearanceEquip(ctx, msg)
+
+	case protocol.MsgTypeArenaQueue:
+		a.handleArenaQueue(ctx, msg)
+
+	case protocol.MsgTypeCombatAction:
+		a.handleCombatAction(ctx, msg)
+
+	case protocol.MsgTypeShopBrowse:
+		a.handleShopBrowse(ctx, msg)
+
+	case protocol.MsgTypeShopTransaction:
+		a.handleShopTransaction(ctx, msg)
+
+	case protocol.MsgTypeClaimZone:
+		a.handleClaimZone(ctx, msg)
+
+	case protocol.MsgTypeAck:
+		a.handleAck(ctx, msg)
+
+	case protocol.MsgTypeLogout:
+		a.handleLogout(ctx)
+
+	case protocol.MsgTypeTimeSync:
+		a.handleTimeSync(clientMsg, msg)
 
 	case protocol.MsgTypePing:
 		utils.LogDebugf("[%s] Player %s received PING.", actorID, a.playerID)
 		var pingPayload protocol.PingPongPayload
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &pingPayload); err != nil {
+		if err := msg.DecodePayload(&pingPayload); err != nil {
 			utils.LogWarnf("[%s] Player %s: PING payload malformed: %v", actorID, a.playerID, err)
 		}
+		a.measureRoundTrip(clientMsg, pingPayload.HintTime)
 		a.sendResponse(protocol.MsgTypePong, pingPayload)
 
+	case protocol.MsgTypeBatchAction:
+		if !a.isAuthenticated() {
+			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		var batchPayload protocol.BatchActionRequestPayload
+		if err := msg.DecodePayload(&batchPayload); err != nil || len(batchPayload.Actions) == 0 {
+			a.sendErrorResponse("INVALID_BATCH_PAYLOAD", "Batch action payload needs a non-empty actions list.")
+			return
+		}
+		if len(batchPayload.Actions) > protocol.MaxBatchActions {
+			a.sendErrorResponse("BATCH_TOO_LARGE", fmt.Sprintf("A batch can hold at most %d actions.", protocol.MaxBatchActions))
+			return
+		}
+		switch batchPayload.Mode {
+		case "":
+			batchPayload.Mode = protocol.BatchModeUntilFailure
+		case protocol.BatchModeAtomic, protocol.BatchModeUntilFailure:
+		default:
+			a.sendErrorResponse("INVALID_BATCH_PAYLOAD", "Batch mode must be atomic or until_failure.")
+			return
+		}
+		utils.LogInfof("[%s] Player %s: Received BATCH_ACTION %q with %d actions (%s).", actorID, a.playerID, batchPayload.BatchID, len(batchPayload.Actions), batchPayload.Mode)
+		a.sendResponse(protocol.MsgTypeBatchActionResult, a.executeBatchAction(actorID, batchPayload))
+
 	case protocol.MsgTypePlayerAction:
 		if !a.isAuthenticated() {
 			a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
 			return
 		}
 		var actionPayload protocol.PlayerActionPayload
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &actionPayload); err != nil {
+		if err := msg.DecodePayload(&actionPayload); err != nil {
 			utils.LogWarnf("[%s] Player %s: Invalid PLAYER_ACTION payload: %v", actorID, a.playerID, err)
 			a.sendErrorResponse("INVALID_ACTION_PAYLOAD", "Player action payload is malformed.")
 			return
@@ -437,216 +1227,108 @@ func (a *PlayerSessionActor) handleClientPayload(ctx actor.Context, rawPayload [
 		utils.LogInfof("[%s] Player %s: Received PLAYER_ACTION: Type=%s, Data=%+v. SUI Client available: %t",
 			actorID, a.playerID, actionPayload.ActionType, actionPayload.Data, a.suiClient != nil)
 
-		switch actionPayload.ActionType {
-		case "GET_PLAYER_PROFILE":
-			// Using new constants for placeholder SUI object details
-			playerObjectStructName := "PlayerProfile" // Example struct name on SUI, could also be a constant or config
-
-			// Simulate deriving player's SUI object ID.
-			// This is a placeholder; actual mechanism might involve a registry contract.
-			simulatedPlayerSuiObjectID := fmt.Sprintf("0xSIMULATED_PLAYER_OBJECT_FOR_%s", a.playerID)
-			utils.LogInfof("[%s] Player %s: Action %s. Simulating SUI GetObject for object ID: %s",
-				actorID, a.playerID, actionPayload.ActionType, simulatedPlayerSuiObjectID)
-
-			// Simulate a successful SUI GetObject call and construct a mock models.SuiObjectResponse.
-			// This demonstrates how the server would interact with the SDK types.
-			mockSuiObjectData := models.SuiObjectData{
-				ObjectId: simulatedPlayerSuiObjectID,
-				Version:  "1",
-				Digest:   "SIMULATED_OBJECT_DIGEST",
-				Type:     fmt.Sprintf("%s::%s::%s", placeholderPlayerObjectPackageID, placeholderPlayerObjectModule, playerObjectStructName),
-				Owner:    &models.ObjectOwner{AddressOwner: a.playerID}, // Assuming player owns their profile object
-				Content: &models.SuiParsedData{
-					DataType: "moveObject",
-					// Note: Fields structure may vary - using a generic approach
-				},
-			}
-
-			// Simulated player data fields that would normally be in the Content.Fields
-			simulatedFields := map[string]interface{}{ // These are the SUI object's fields
-				"game_player_id": a.playerID, // Field storing the link to the game's internal player ID
-				"name":           fmt.Sprintf("Player %s", a.playerID),
-				"level":          uint64(15), // Example: SUI often uses u64 for numbers
-				"xp":             uint64(5500),
-				"health_points":  uint64(120),
-				"attack_power":   uint64(25),
-				"last_seen_tsms": uint64(time.Now().UnixMilli()), // Example timestamp
-			}
-			// In a real call: suiObjectResponse, err := a.suiClient.GetObject(context.Background(), simulatedPlayerSuiObjectID)
-			// Then check err and process suiObjectResponse.
-
-			// "Parse" the simulated SUI response to populate the data for the client.
-			var clientResponseData map[string]interface{}
-			if mockSuiObjectData.Content != nil {
-				// Use the simulated fields since actual SDK fields structure varies
-				clientResponseData = map[string]interface{}{
-					"playerId":    simulatedFields["game_player_id"],
-					"name":        simulatedFields["name"],
-					"level":       simulatedFields["level"],
-					"xp":          simulatedFields["xp"],
-					"hp":          simulatedFields["health_points"],
-					"atk":         simulatedFields["attack_power"],
-					"lastSeenMs":  simulatedFields["last_seen_tsms"],
-					"suiObjectId": mockSuiObjectData.ObjectId,
-					"suiVersion":  mockSuiObjectData.Version,
-				}
-				utils.LogInfof("[%s] Player %s: Successfully simulated parsing of SUI player profile object for GET_PLAYER_PROFILE.", actorID, a.playerID)
-			} else {
-				utils.LogWarnf("[%s] Player %s: Simulated SUI player profile object for GET_PLAYER_PROFILE was empty or malformed.", actorID, a.playerID)
-				clientResponseData = map[string]interface{}{"error": "Failed to retrieve or parse player SUI data (simulated)."}
-			}
-
-			a.sendResponse(protocol.MsgTypePlayerActionResponse, protocol.PlayerActionResponsePayload{
-				ActionType: actionPayload.ActionType,
-				Status:     "SIMULATED_SUI_GET_OBJECT_SUCCESS",
-				Message:    "Player profile data retrieved (simulated SUI GetObject).",
-				Data:       clientResponseData,
-			})
-
-		case "PERFORM_INGAME_ACTION":
-			// Define target module and function for the SUI Move call
-			targetModule := "player_actions"
-			targetFunction := "execute_game_action"
-
-			// Extract action details from payload.
-			// Expecting "action_name": string and "action_params": map[string]interface{} in actionPayload.Data
-			actionName, okActionName := actionPayload.Data["action_name"].(string)
-			actionParams, okActionParams := actionPayload.Data["action_params"].(map[string]interface{})
-
-			if !okActionName || !okActionParams {
-				utils.LogWarnf("[%s] Player %s: PERFORM_INGAME_ACTION payload malformed. Expected 'action_name' (string) and 'action_params' (map). Payload: %+v",
-					actorID, a.playerID, actionPayload.Data)
-				a.sendResponse(protocol.MsgTypePlayerActionResponse, protocol.PlayerActionResponsePayload{
-					ActionType: actionPayload.ActionType,
-					Status:     "INVALID_ACTION_DATA",
-					Message:    "Action data is malformed. Expected 'action_name' and 'action_params'.",
-				})
-				return
-			}
-
-			utils.LogInfof("[%s] Player %s: Action %s. Preparing simulated SUI MoveCall for action: %s with params: %+v",
-				actorID, a.playerID, actionPayload.ActionType, actionName, actionParams)
-
-			// Construct arguments for the SUI Move call based on actionName and actionParams.
-			// This is highly dependent on the SUI contract's function signatures.
-			// For simulation, we'll create a generic list of arguments.
-			// Example: First arg is player ID, second is action name string, third could be serialized params or specific object IDs.
-			suiCallArgs := []interface{}{
-				// In a real scenario, this might be the player's SUI address or a player capability object ID
-				// For simulation, using the game playerID string.
-				a.playerID, // This would likely be an ObjectID or address on SUI
-				actionName, // The specific action being performed
-				// More arguments could be derived from actionParams, e.g., target object IDs, amounts, etc.
-				// For example, if params included "target_object_id": "0x...", it would be added here.
-				// For now, sending the whole map as a string for simplicity in simulation, though not ideal for real contract calls.
-				fmt.Sprintf("%v", actionParams), // Simplistic representation of params
-			}
-			typeArgs := []string{} // Example: If the Move function has type arguments like T, U...
-
-			// Simulate gas details
-			gasObjectID := "0xSIMULATED_GAS_COIN_ID" // Placeholder
-			gasBudget := uint64(10000000)            // Example
-
-			utils.LogInfof(
-				"[%s] Player %s: SIMULATING SUI MoveCall: PackageID=%s, Module=%s, Function=%s, TypeArgs=%v, Args=%v, GasObj=%s, GasBudget=%d",
-				actorID, a.playerID, placeholderGameLogicPackageID, targetModule, targetFunction, typeArgs, suiCallArgs, gasObjectID, gasBudget,
-			)
-
-			// Simulate the response from suiClient.MoveCall (which prepares the transaction bytes)
-			mockTxBytes := fmt.Sprintf("SIMULATED_TX_BYTES_FOR_%s_ACTION_%s", a.playerID, actionName)
-			simulatedMoveCallResponse := models.TxnMetaData{
-				TxBytes: mockTxBytes,
-				// Other fields like GasUsed, Effects, etc., would be populated after execution.
-				// For a `SuiMoveCall` (dry run or build-only), TxBytes is the primary output.
-			}
-			utils.LogInfof("[%s] Player %s: Simulated SuiMoveCall successful. Received TxBytes: %s",
-				actorID, a.playerID, simulatedMoveCallResponse.TxBytes)
-
-			// Log next conceptual steps: signing and execution
-			utils.LogInfof("[%s] Player %s: Next conceptual step: Signing TxBytes using server's key (if applicable).",
-				actorID, a.playerID)
-			// In a real scenario, serverPrivateKeyHex would come from a secure config.
-			// For this simulation, we'll just log that it *would* be used.
-			// serverPrivateKeyHex := cfg.Sui.PrivateKey // PlayerSessionActor doesn't have cfg.
-			// conceptualSignature, signErr := sui.SignTransactionBytesWithServerKey(simulatedMoveCallResponse.TxBytes, serverPrivateKeyHex)
-			// if signErr != nil {
-			// 	 utils.LogErrorf("[%s] Player %s: Conceptual signing failed: %v", actorID, a.playerID, signErr)
-			// } else {
-			//	 utils.LogInfof("[%s] Player %s: Conceptual server-side signature obtained: %s", actorID, a.playerID, conceptualSignature)
-			// }
-			utils.LogInfo("PlayerSessionActor: (Conceptual) Call to sui.SignTransactionBytesWithServerKey would happen here if server needs to sign.")
-
-			utils.LogInfof("[%s] Player %s: Final conceptual step: ExecuteTransactionBlock with TxBytes and signature(s).",
-				actorID, a.playerID)
-
-			a.sendResponse(protocol.MsgTypePlayerActionResponse, protocol.PlayerActionResponsePayload{
-				ActionType: actionPayload.ActionType,
-				Status:     "SIMULATED_SUI_MOVE_CALL_PREPARED",
-				Message:    "In-game action prepared for SUI execution (simulated).",
-				// Optionally, could return TxBytes or a transaction digest if the simulation went further
-			})
-		default:
-			utils.LogWarnf("[%s] Player %s: Received unknown PLAYER_ACTION type: %s", actorID, a.playerID, actionPayload.ActionType)
-			a.sendResponse(protocol.MsgTypePlayerActionResponse, protocol.PlayerActionResponsePayload{
-				ActionType: actionPayload.ActionType,
-				Status:     "UNKNOWN_ACTION_TYPE",
-				Message:    "Server does not understand this player action type.",
-			})
-		}
+		a.sendResponse(protocol.MsgTypePlayerActionResponse, a.executePlayerAction(actorID, actionPayload))
 
 	default:
+		if handler, ok := a.services.Plugins.Handler(msg.Type); ok {
+			a.handlePluginMessage(ctx, handler, msg)
+			return
+		}
 		utils.LogWarnf("[%s] Player %s: Received unhandled message type '%s'", actorID, a.playerID, msg.Type)
 		a.sendErrorResponse("UNKNOWN_COMMAND", fmt.Sprintf("Unknown command type: %s", msg.Type))
 	}
 
 }
 
+// compressThreshold is the body size from which version 2 frames to the client are compressed.
+const compressThreshold = 1024
+
 // handleForwardToClient sends a message payload to the connected client.
 func (a *PlayerSessionActor) handleForwardToClient(msg *messages.ForwardToClient) {
+	a.writeFrame(protocol.EnvelopeTypeID, msg.Payload)
+}
+
+// writeFrame frames body in the client's framing version and writes it.
+// typeID is ignored for legacy frames, whose body is always a full envelope.
+func (a *PlayerSessionActor) writeFrame(typeID uint16, body []byte) {
 	if a.conn == nil {
 		utils.LogWarnf("PlayerSessionActor %s: No connection available to forward message.", a.playerID)
 		return
 	}
+	var flags protocol.FrameFlags
+	if a.frameVersion == protocol.FrameVersion2 && len(body) >= compressThreshold {
+		flags |= protocol.FrameFlagCompressed
+	}
+	frame, err := protocol.EncodeFrame(a.frameVersion, flags, typeID, body)
+	if err != nil {
+		utils.LogErrorf("PlayerSessionActor %s: Error encoding frame: %v", a.playerID, err)
+		return
+	}
+	a.writeEncodedFrame(frame, len(body))
+}
 
-	payload := msg.Payload
-	payloadLen := len(payload)
-
-	buffer := make([]byte, 4+payloadLen)
-
-	binary.BigEndian.PutUint32(buffer[0:4], uint32(payloadLen))
-
-	copy(buffer[4:], payload)
+// writePrepared writes a room broadcast, reusing the frame other sessions
+// with the same framing version already encoded.
+func (a *PlayerSessionActor) writePrepared(b *preparedBroadcast) {
+	a.metrics.messageSent(b.msgType)
+	if a.conn == nil {
+		utils.LogWarnf("PlayerSessionActor %s: No connection available to forward message.", a.playerID)
+		return
+	}
+	frame, bodyLen, err := b.frame(a.frameVersion)
+	if err != nil {
+		utils.LogErrorf("PlayerSessionActor %s: Error encoding frame: %v", a.playerID, err)
+		return
+	}
+	a.writeEncodedFrame(frame, bodyLen)
+}
 
-	if _, err := a.conn.Write(buffer); err != nil {
+func (a *PlayerSessionActor) writeEncodedFrame(frame []byte, bodyLen int) {
+	if _, err := a.conn.Write(frame); err != nil {
 		utils.LogErrorf("PlayerSessionActor %s: Error writing to client %s: %v", a.playerID, a.conn.RemoteAddr(), err)
 	} else {
-		utils.LogDebugf("PlayerSessionActor %s: Sent %d bytes (len) + %d bytes (payload) to client %s.", a.playerID, 4, payloadLen, a.conn.RemoteAddr())
+		a.metrics.frameSent(len(frame))
+		utils.LogDebugf("PlayerSessionActor %s: Sent %d byte frame (%d byte body) to client %s.", a.playerID, len(frame), bodyLen, a.conn.RemoteAddr())
 	}
 }
 
 // sendResponse constructs and sends a standard JSON message to the client.
 func (a *PlayerSessionActor) sendResponse(msgType string, payload interface{}) {
-	response := protocol.ClientServerMessage{
-		Type:    msgType,
-		Payload: payload,
+	a.metrics.messageSent(msgType)
+	if a.replyKey != "" {
+		a.recordReply(msgType, payload)
 	}
-	jsonResponse, err := json.Marshal(response)
+	if a.delivery != nil && protocol.IsReliable(msgType) {
+		if err := a.sendSequenced(msgType, payload); err == nil {
+			return
+		}
+		// Fall through: the envelope path reports the marshal error.
+	}
+	if a.frameVersion == protocol.FrameVersion2 {
+		if spec, ok := protocol.LookupMessage(msgType); ok {
+			if body, err := json.Marshal(payload); err == nil {
+				a.writeFrame(spec.ID, body)
+				return
+			}
+			// Fall through: the envelope path reports the marshal error.
+		}
+	}
+	body, err := json.Marshal(payload)
 	if err != nil {
 		utils.LogErrorf("PlayerSessionActor %s: Error marshaling response type %s: %v", a.playerID, msgType, err)
-		errorPayload := protocol.ErrorResponsePayload{
+		errorPayload, _ := json.Marshal(protocol.ErrorResponsePayload{
 			Code:    "INTERNAL_SERVER_ERROR",
 			Message: "Error creating response: " + err.Error(),
-		}
-		fallbackResponse := protocol.ClientServerMessage{Type: protocol.MsgTypeError, Payload: errorPayload}
-		jsonFallback, _ := json.Marshal(fallbackResponse)
-		a.handleForwardToClient(&messages.ForwardToClient{Payload: jsonFallback})
+		})
+		a.handleForwardToClient(&messages.ForwardToClient{Payload: protocol.AppendEnvelope(nil, protocol.MsgTypeError, errorPayload, 0)})
 		return
 	}
-	a.handleForwardToClient(&messages.ForwardToClient{Payload: jsonResponse})
+	a.handleForwardToClient(&messages.ForwardToClient{Payload: protocol.AppendEnvelope(nil, msgType, body, 0)})
 }
 
 // sendErrorResponse sends a structured error message to the client.
 func (a *PlayerSessionActor) sendErrorResponse(errCode string, errMsg string) {
+	a.metrics.errorSent(errCode, errMsg)
 	errorPayload := protocol.ErrorResponsePayload{
 		Code:    errCode,
 		Message: errMsg,
@@ -654,6 +1336,23 @@ func (a *PlayerSessionActor) sendErrorResponse(errCode string, errMsg string) {
 	a.sendResponse(protocol.MsgTypeError, errorPayload)
 }
 
+// isTransactionFailure reports whether err is a chain transaction that failed
+// or would fail, which players are told about in its PlayerMessage.
+func isTransactionFailure(err error) bool {
+	var failed *sui.TransactionFailedError
+	return errors.As(err, &failed)
+}
+
+// playerErrorMessage is the text to show a player for err. A failed
+// transaction is explained in player terms; the node's raw error is only logged.
+func playerErrorMessage(err error) string {
+	var failed *sui.TransactionFailedError
+	if errors.As(err, &failed) {
+		return failed.PlayerMessage
+	}
+	return err.Error()
+}
+
 // sendSimpleMessage sends a simple text message to the client, wrapped in standard JSON structure.
 func (a *PlayerSessionActor) sendSimpleMessage(message string) {
 	payload := protocol.SimpleMessagePayload{
@@ -665,3 +1364,17 @@ func (a *PlayerSessionActor) sendSimpleMessage(message string) {
 func (a *PlayerSessionActor) isAuthenticated() bool {
 	return a.playerID != ""
 }
+
+// auditAuth records an auth attempt. The token itself is not recorded, only a
+// fingerprint that links repeated attempts with the same token.
+func (a *PlayerSessionActor) auditAuth(success bool, token, rejection string) {
+	entry := audit.Entry{Action: audit.ActionAuth, Actor: a.metrics.remoteAddr, Result: audit.ResultOK}
+	if success {
+		entry.PlayerID = a.playerID
+	} else {
+		entry.Result, entry.Detail = audit.ResultDenied, rejection
+	}
+	sum := sha256.Sum256([]byte(token))
+	entry.Target = "token:" + hex.EncodeToString(sum[:6])
+	a.services.Audit.Record(entry)
+}
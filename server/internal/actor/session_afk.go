@@ -0,0 +1,106 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/afk"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// defaultAFKCheckInterval is used when the policy does not set one.
+const defaultAFKCheckInterval = 30 * time.Second
+
+// afkCheck is the session's periodic AFK check. It must not reset the receive
+// timeout, or an idle connection would never time out.
+type afkCheck struct{}
+
+func (*afkCheck) NotInfluenceReceiveTimeout() {}
+
+// startAFKChecks starts the AFK clock once the player has authenticated.
+func (a *PlayerSessionActor) startAFKChecks(ctx actor.Context) {
+	if !a.services.AFK.Enabled() {
+		return
+	}
+	a.lastGameplay = a.services.Timers.Now()
+	interval := a.services.AFK.CheckInterval
+	if interval <= 0 {
+		interval = defaultAFKCheckInterval
+	}
+	// Jitter spreads the checks of sessions that authenticated together.
+	a.afkTimer = a.services.Timers.Every(a.actorSystem.Root, ctx.Self(), interval, interval/10, &afkCheck{})
+}
+
+// stopAFKChecks stops the AFK timer when the session ends.
+func (a *PlayerSessionActor) stopAFKChecks() {
+	a.afkTimer.Stop()
+	a.afkTimer = nil
+}
+
+// recordGameplay resets the AFK clock for gameplay messages, bringing an AFK
+// player back.
+func (a *PlayerSessionActor) recordGameplay(ctx actor.Context, msgType string) {
+	if !a.services.AFK.Enabled() || !afk.IsGameplay(msgType) {
+		return
+	}
+	a.lastGameplay = a.services.Timers.Now()
+	if a.afk {
+		utils.LogInfof("[%s] Player %s is back from AFK", ctx.Self().Id, a.playerID)
+		a.setAFK(ctx, false, "")
+	}
+}
+
+func (a *PlayerSessionActor) handleAFKCheck(ctx actor.Context) {
+	if a.afkTimer.Stopped() {
+		return // Stopped while the check was in flight
+	}
+	idle := a.services.Timers.Now().Sub(a.lastGameplay)
+	switch a.services.AFK.Status(idle) {
+	case afk.Kick:
+		utils.LogInfof("[%s] Player %s: AFK for %s under high load, disconnecting", ctx.Self().Id, a.playerID, idle.Round(time.Second))
+		a.terminate(ctx, &messages.TerminateSession{Code: protocol.DisconnectAFK, Reason: "Disconnected for being AFK while the server is busy."})
+		return
+	case afk.Away:
+		if !a.afk {
+			utils.LogInfof("[%s] Player %s is AFK (no gameplay for %s)", ctx.Self().Id, a.playerID, idle.Round(time.Second))
+			lobby := a.services.AFK.LobbyRoomID
+			if lobby == "" || a.roomPID == nil || a.roomID == lobby || a.roomManagerPID == nil || a.transfer != nil {
+				lobby = ""
+			}
+			a.setAFK(ctx, true, lobby)
+			if lobby != "" {
+				a.moveToRoom(ctx, lobby)
+			}
+		}
+	}
+}
+
+// setAFK records the AFK state and tells the player's room, or just the
+// player when they are not in one.
+func (a *PlayerSessionActor) setAFK(ctx actor.Context, isAFK bool, movedTo string) {
+	a.afk = isAFK
+	change := &messages.PlayerAFKChanged{PlayerID: a.playerID, AFK: isAFK, MovedTo: movedTo}
+	if a.roomPID != nil {
+		ctx.Send(a.roomPID, &messages.BroadcastToRoom{SenderPID: ctx.Self(), ActualMessage: change})
+		return
+	}
+	a.sendAFKStatus(change)
+}
+
+func (a *PlayerSessionActor) sendAFKStatus(change *messages.PlayerAFKChanged) {
+	msgType, payload, _ := clientMessage(change)
+	a.sendResponse(msgType, payload)
+}
+
+// moveToRoom leaves the current room and joins roomID. The client gets the
+// usual JOIN_ROOM_RESPONSE.
+func (a *PlayerSessionActor) moveToRoom(ctx actor.Context, roomID string) {
+	if a.roomPID != nil {
+		ctx.Send(a.roomPID, &messages.LeaveRoomRequest{PlayerID: a.playerID, PlayerPID: ctx.Self()})
+		a.roomPID, a.roomID = nil, ""
+	}
+	a.pendingJoin = nil
+	ctx.Request(a.roomManagerPID, &messages.FindRoomRequest{Criteria: roomID, PlayerPID: ctx.Self()})
+}
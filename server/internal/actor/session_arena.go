@@ -0,0 +1,94 @@
+package actor
+
+import (
+	"errors"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/arena"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// maxArenaRegions bounds the regions a player may list when queueing.
+const maxArenaRegions = 8
+
+// handleArenaQueue joins or leaves a ranked arena queue. Match notifications
+// come back from the arena as messages to this actor.
+func (a *PlayerSessionActor) handleArenaQueue(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Arena == nil {
+		a.sendErrorResponse("ARENA_DISABLED", "The arena is not enabled on this server.")
+		return
+	}
+	var queuePayload protocol.ArenaQueueRequestPayload
+	if err := msg.DecodePayload(&queuePayload); err != nil || len(queuePayload.Regions) > maxArenaRegions {
+		a.sendErrorResponse("INVALID_ARENA_PAYLOAD", "Arena queue payload is malformed.")
+		return
+	}
+	season := a.services.Arena.Season().Number
+	if queuePayload.Leave {
+		a.services.Arena.Leave(a.playerID)
+		a.sendResponse(protocol.MsgTypeArenaQueueStatus, protocol.ArenaQueueStatusPayload{Queued: false, Season: season})
+		return
+	}
+	a.spendEnergy(ctx, energy.ActionArenaQueue, func() {
+		a.joinArena(ctx, queuePayload, season)
+	})
+}
+
+// joinArena queues the player once they paid the queue's energy.
+func (a *PlayerSessionActor) joinArena(ctx actor.Context, queuePayload protocol.ArenaQueueRequestPayload, season int) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	prefs := arena.Preferences{Latency: a.rtt, Regions: queuePayload.Regions}
+	if len(prefs.Regions) == 0 && a.world != nil && a.world.Region != "" {
+		prefs.Regions = []string{a.world.Region}
+	}
+	rating, err := a.services.Arena.Join(a.playerID, arena.Mode(queuePayload.Mode), prefs, func(note interface{}) {
+		root.Send(self, note)
+	})
+	if err != nil {
+		code := "ARENA_QUEUE_FAILED"
+		switch {
+		case errors.Is(err, arena.ErrUnknownMode), errors.Is(err, arena.ErrUnknownRegion):
+			code = "INVALID_ARENA_PAYLOAD"
+		case errors.Is(err, arena.ErrLatency):
+			code = "ARENA_LATENCY_TOO_HIGH"
+		}
+		a.refundEnergy(energy.ActionArenaQueue)
+		a.sendErrorResponse(code, "Could not join the arena queue: "+err.Error()+".")
+		return
+	}
+	utils.LogInfof("[%s] Player %s: Queued for %s arena at rating %d.", ctx.Self().Id, a.playerID, queuePayload.Mode, rating)
+	a.sendResponse(protocol.MsgTypeArenaQueueStatus, protocol.ArenaQueueStatusPayload{
+		Mode:   queuePayload.Mode,
+		Queued: true,
+		Rating: rating,
+		Season: season,
+	})
+}
+
+func (a *PlayerSessionActor) sendArenaNotification(note interface{}) {
+	switch note := note.(type) {
+	case *arena.MatchFound:
+		a.sendResponse(protocol.MsgTypeArenaMatchFound, protocol.ArenaMatchFoundPayload{
+			MatchID:           note.MatchID,
+			Mode:              string(note.Mode),
+			TeamA:             note.TeamA,
+			TeamB:             note.TeamB,
+			Region:            note.Region,
+			ExpectedLatencyMs: note.Latency.Milliseconds(),
+			LatencyMs:         a.rtt.Milliseconds(),
+		})
+	case *arena.MatchResult:
+		a.sendResponse(protocol.MsgTypeArenaMatchResult, protocol.ArenaMatchResultPayload{
+			MatchID:      note.MatchID,
+			Won:          note.Won,
+			RatingBefore: note.RatingBefore,
+			RatingAfter:  note.RatingAfter,
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/battlepass"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// battlePassResult carries a claim or premium unlock back from its goroutine.
+type battlePassResult struct {
+	action string // Client message type being answered
+	err    error
+}
+
+// connectBattlePass registers this session to be told about XP gains and new
+// seasons, and sends the player's pass.
+func (a *PlayerSessionActor) connectBattlePass(ctx actor.Context) {
+	if a.services.BattlePass == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.BattlePass.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+	a.sendBattlePass(a.services.BattlePass.View(a.playerID))
+}
+
+// handleBattlePassRequest answers BATTLEPASS_REQUEST with the player's pass.
+func (a *PlayerSessionActor) handleBattlePassRequest() {
+	if !a.checkBattlePass() {
+		return
+	}
+	a.sendBattlePass(a.services.BattlePass.View(a.playerID))
+}
+
+// handleBattlePassClaim claims the reward of a tier. Rewards are paid into
+// the wallet, which may be in the database, so the claim runs off the actor.
+func (a *PlayerSessionActor) handleBattlePassClaim(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkBattlePass() {
+		return
+	}
+	var claimPayload protocol.BattlePassClaimRequestPayload
+	if err := msg.DecodePayload(&claimPayload); err != nil || claimPayload.Tier < 1 {
+		a.sendErrorResponse("INVALID_BATTLEPASS_PAYLOAD", "Battle pass claim needs a tier and a track.")
+		return
+	}
+	self, root, service, playerID := ctx.Self(), a.actorSystem.Root, a.services.BattlePass, a.playerID
+	go func() {
+		_, err := service.Claim(playerID, claimPayload.Tier, claimPayload.Track)
+		root.Send(self, &battlePassResult{action: protocol.MsgTypeBattlePassClaim, err: err})
+	}()
+	a.awaitReply(protocol.MsgTypeBattlePassClaim)
+}
+
+// handleBattlePassUnlock unlocks the premium pass for an on-chain payment from
+// the player's primary linked address.
+func (a *PlayerSessionActor) handleBattlePassUnlock(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkBattlePass() {
+		return
+	}
+	var unlockPayload protocol.BattlePassUnlockRequestPayload
+	if err := msg.DecodePayload(&unlockPayload); err != nil || unlockPayload.PaymentTxDigest == "" {
+		a.sendErrorResponse("INVALID_BATTLEPASS_PAYLOAD", "Battle pass unlock needs a paymentTxDigest.")
+		return
+	}
+	self, root, service, accounts, playerID := ctx.Self(), a.actorSystem.Root, a.services.BattlePass, a.services.Accounts, a.playerID
+	a.metrics.suiRequestStarted(protocol.MsgTypeBattlePassUnlock)
+	go func() {
+		address := playerID
+		if accounts != nil {
+			queryCtx, cancel := context.WithTimeout(context.Background(), walletLinkTimeout)
+			linked, err := accounts.Address(queryCtx, playerID)
+			cancel()
+			if err != nil {
+				root.Send(self, &battlePassResult{action: protocol.MsgTypeBattlePassUnlock, err: fmt.Errorf("the premium pass needs a linked Sui address: %w", err)})
+				return
+			}
+			address = linked
+		}
+		err := service.UnlockPremium(playerID, address, unlockPayload.PaymentTxDigest)
+		root.Send(self, &battlePassResult{action: protocol.MsgTypeBattlePassUnlock, err: err})
+	}()
+	a.awaitReply(protocol.MsgTypeBattlePassUnlock)
+}
+
+func (a *PlayerSessionActor) checkBattlePass() bool {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return false
+	}
+	if a.services.BattlePass == nil {
+		a.sendErrorResponse("BATTLEPASS_DISABLED", "The battle pass is not enabled on this server.")
+		return false
+	}
+	return true
+}
+
+func (a *PlayerSessionActor) handleBattlePassResult(ctx actor.Context, result *battlePassResult) {
+	if result.action == protocol.MsgTypeBattlePassUnlock {
+		a.metrics.suiRequestFinished(result.action)
+	}
+	if result.err != nil {
+		utils.LogInfof("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		a.sendErrorResponse(battlePassErrorCode(result.err), result.err.Error())
+		return
+	}
+	a.sendBattlePass(a.services.BattlePass.View(a.playerID))
+}
+
+func battlePassErrorCode(err error) string {
+	switch {
+	case errors.Is(err, battlepass.ErrNoSeason):
+		return "NO_BATTLEPASS_SEASON"
+	case errors.Is(err, battlepass.ErrUnknownTier), errors.Is(err, battlepass.ErrUnknownTrack), errors.Is(err, battlepass.ErrNoReward):
+		return "INVALID_BATTLEPASS_PAYLOAD"
+	case errors.Is(err, battlepass.ErrTierLocked):
+		return "BATTLEPASS_TIER_LOCKED"
+	case errors.Is(err, battlepass.ErrAlreadyClaimed):
+		return "BATTLEPASS_ALREADY_CLAIMED"
+	case errors.Is(err, battlepass.ErrPremiumRequired):
+		return "BATTLEPASS_PREMIUM_REQUIRED"
+	case errors.Is(err, battlepass.ErrPremiumDisabled):
+		return "BATTLEPASS_PREMIUM_DISABLED"
+	case errors.Is(err, battlepass.ErrAlreadyPremium):
+		return "BATTLEPASS_ALREADY_PREMIUM"
+	case errors.Is(err, battlepass.ErrPaymentUsed), errors.Is(err, battlepass.ErrPaymentInvalid):
+		return "PAYMENT_INVALID"
+	}
+	return "BATTLEPASS_UNAVAILABLE"
+}
+
+func (a *PlayerSessionActor) sendBattlePass(view battlepass.View) {
+	payload := protocol.BattlePassPayload{Active: view.Active, XP: view.XP, Tier: view.Tier, Premium: view.Premium, ServerTime: serverTime()}
+	if view.Active {
+		payload.SeasonID = view.Season.ID
+		payload.Name = view.Season.Name
+		payload.EndsAt = view.Season.EndsAt.UnixMilli()
+		if view.ForSale {
+			payload.PremiumPrice = view.Season.PremiumPrice
+			payload.PremiumCoinType = view.Season.PremiumCoinType
+		}
+		for i, tier := range view.Season.Tiers {
+			n := i + 1
+			payload.Tiers = append(payload.Tiers, protocol.BattlePassTierPayload{
+				Tier:    n,
+				XP:      tier.XP,
+				Free:    battlePassRewardPayload(tier.Free, view.IsClaimed(n, battlepass.TrackFree)),
+				Premium: battlePassRewardPayload(tier.Premium, view.IsClaimed(n, battlepass.TrackPremium)),
+			})
+		}
+	}
+	a.sendResponse(protocol.MsgTypeBattlePass, payload)
+}
+
+func battlePassRewardPayload(reward *battlepass.Reward, claimed bool) *protocol.BattlePassRewardPayload {
+	if reward == nil {
+		return nil
+	}
+	return &protocol.BattlePassRewardPayload{
+		Coins:    reward.Coins,
+		ItemID:   reward.ItemID,
+		Quantity: reward.Quantity,
+		Cosmetic: reward.Cosmetic,
+		Claimed:  claimed,
+	}
+}
@@ -0,0 +1,166 @@
+package actor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/calendar"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// guildEventRoomTimeout bounds how long a starting guild event waits for its
+// room.
+const guildEventRoomTimeout = 5 * time.Second
+
+// connectCalendar registers this session to be told when guild events it
+// signed up for start.
+func (a *PlayerSessionActor) connectCalendar(ctx actor.Context) {
+	if a.services.Calendar == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Calendar.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+// handleGuildEventRequest answers GUILD_EVENT_CREATE, GUILD_EVENT_RSVP and
+// GUILD_EVENT_CANCEL with the event, and GUILD_EVENTS_REQUEST with the
+// guild's calendar.
+func (a *PlayerSessionActor) handleGuildEventRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	service := a.services.Calendar
+	if service == nil {
+		a.sendErrorResponse("GUILD_EVENTS_DISABLED", "Guild events are not enabled on this server.")
+		return
+	}
+	var event calendar.Event
+	var err error
+	switch msg.Type {
+	case protocol.MsgTypeGuildEventsRequest:
+		events, err := service.Events(a.playerID)
+		if err != nil {
+			a.sendGuildEventError(ctx, msg.Type, err)
+			return
+		}
+		payload := protocol.GuildEventsPayload{Events: make([]protocol.GuildEventPayload, 0, len(events))}
+		for _, e := range events {
+			payload.Events = append(payload.Events, guildEventPayload(e))
+		}
+		a.sendResponse(protocol.MsgTypeGuildEvents, payload)
+		return
+	case protocol.MsgTypeGuildEventCreate:
+		var createPayload protocol.GuildEventCreateRequestPayload
+		if err := msg.DecodePayload(&createPayload); err != nil {
+			a.sendErrorResponse("INVALID_GUILD_EVENT_PAYLOAD", "Guild event payload is malformed.")
+			return
+		}
+		event, err = service.Create(a.playerID, calendar.Event{
+			Kind:        calendar.Kind(createPayload.Kind),
+			Title:       createPayload.Title,
+			Description: createPayload.Description,
+			StartsAt:    time.UnixMilli(createPayload.StartsAt),
+			Capacity:    createPayload.Capacity,
+			Alliance:    createPayload.Alliance,
+		})
+	case protocol.MsgTypeGuildEventRSVP:
+		var rsvpPayload protocol.GuildEventRSVPRequestPayload
+		if err := msg.DecodePayload(&rsvpPayload); err != nil || rsvpPayload.EventID == "" {
+			a.sendErrorResponse("INVALID_GUILD_EVENT_PAYLOAD", "RSVP payload needs an eventId.")
+			return
+		}
+		event, err = service.RSVP(a.playerID, rsvpPayload.EventID, rsvpPayload.Going)
+	case protocol.MsgTypeGuildEventCancel:
+		var cancelPayload protocol.GuildEventCancelRequestPayload
+		if err := msg.DecodePayload(&cancelPayload); err != nil || cancelPayload.EventID == "" {
+			a.sendErrorResponse("INVALID_GUILD_EVENT_PAYLOAD", "Cancel payload needs an eventId.")
+			return
+		}
+		event, err = service.Cancel(a.playerID, cancelPayload.EventID)
+	}
+	if err != nil {
+		a.sendGuildEventError(ctx, msg.Type, err)
+		return
+	}
+	a.sendResponse(protocol.MsgTypeGuildEvent, guildEventPayload(event))
+}
+
+func (a *PlayerSessionActor) sendGuildEventError(ctx actor.Context, action string, err error) {
+	code := ""
+	switch {
+	case errors.Is(err, calendar.ErrNoGuild):
+		code = "NOT_IN_GUILD"
+	case errors.Is(err, calendar.ErrNotOfficer):
+		code = "NOT_GUILD_OFFICER"
+	case errors.Is(err, calendar.ErrNotFound):
+		code = "GUILD_EVENT_NOT_FOUND"
+	case errors.Is(err, calendar.ErrInvalid):
+		code = "INVALID_GUILD_EVENT"
+	case errors.Is(err, calendar.ErrTooMany):
+		code = "TOO_MANY_GUILD_EVENTS"
+	case errors.Is(err, calendar.ErrFull):
+		code = "GUILD_EVENT_FULL"
+	case errors.Is(err, calendar.ErrClosed):
+		code = "GUILD_EVENT_CLOSED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, action, err)
+		a.sendErrorResponse("GUILD_EVENTS_UNAVAILABLE", "Guild events are unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, err.Error())
+}
+
+func guildEventPayload(e calendar.Event) protocol.GuildEventPayload {
+	attendees := e.Attendees
+	if attendees == nil {
+		attendees = []string{}
+	}
+	return protocol.GuildEventPayload{
+		EventID:     e.ID,
+		GuildID:     e.GuildID,
+		Kind:        string(e.Kind),
+		Title:       e.Title,
+		Description: e.Description,
+		CreatedBy:   e.CreatedBy,
+		StartsAt:    e.StartsAt.UnixMilli(),
+		Capacity:    e.Capacity,
+		Attendees:   attendees,
+		Status:      string(e.Status),
+		RoomID:      e.RoomID,
+		Alliance:    e.Alliance,
+	}
+}
+
+// GuildEventRooms returns a calendar.RoomOpener that has roomManager open an
+// invite-only room for each guild event that starts. Only the event's
+// attendees may join it without an invite.
+func GuildEventRooms(system *actor.ActorSystem, roomManager *actor.PID) calendar.RoomOpener {
+	return func(event calendar.Event) (string, error) {
+		future := actor.NewFuture(system, guildEventRoomTimeout)
+		system.Root.Send(roomManager, &messages.CreateRoomRequest{
+			RoomName:     event.Title,
+			MaxPlayers:   event.Capacity,
+			Visibility:   messages.RoomVisibilityInviteOnly,
+			MemberIDs:    event.Attendees,
+			RequesterPID: future.PID(),
+		})
+		result, err := future.Result()
+		if err != nil {
+			return "", err
+		}
+		resp, ok := result.(*messages.CreateRoomResponse)
+		switch {
+		case !ok:
+			return "", errors.New("unexpected reply from the room manager")
+		case !resp.Success:
+			return "", errors.New(resp.Error)
+		}
+		return resp.RoomID, nil
+	}
+}
@@ -0,0 +1,90 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// handleChannelRequest forwards a chat channel request to the
+// WorldManagerActor, which keeps the channels and their members.
+func (a *PlayerSessionActor) handleChannelRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.worldManagerPID == nil {
+		a.sendErrorResponse("CHANNEL_UNAVAILABLE", "Chat channels are not available.")
+		return
+	}
+	if msg.Type == protocol.MsgTypeChannelListRequest {
+		ctx.Send(a.worldManagerPID, &messages.ListChannels{PlayerID: a.playerID, PlayerPID: ctx.Self()})
+		return
+	}
+	var channelPayload protocol.ChannelSendPayload
+	if err := msg.DecodePayload(&channelPayload); err != nil || channelPayload.Channel == "" {
+		a.sendErrorResponse("INVALID_CHANNEL_PAYLOAD", "Channel payload needs a channel.")
+		return
+	}
+	switch msg.Type {
+	case protocol.MsgTypeChannelJoin:
+		ctx.Send(a.worldManagerPID, &messages.JoinChannel{PlayerID: a.playerID, PlayerPID: ctx.Self(), Channel: channelPayload.Channel})
+	case protocol.MsgTypeChannelLeave:
+		ctx.Send(a.worldManagerPID, &messages.LeaveChannel{PlayerID: a.playerID, PlayerPID: ctx.Self(), Channel: channelPayload.Channel})
+	case protocol.MsgTypeChannelSend:
+		if channelPayload.Text == "" {
+			a.sendErrorResponse("EMPTY_CHAT_MESSAGE", "Chat message cannot be empty.")
+			return
+		}
+		if !a.mayChat() {
+			return
+		}
+		ctx.Send(a.worldManagerPID, &messages.SendChannelMessage{
+			PlayerID:  a.playerID,
+			PlayerPID: ctx.Self(),
+			Channel:   channelPayload.Channel,
+			Text:      channelPayload.Text,
+			SentAt:    time.Now(),
+		})
+	}
+}
+
+// enterChatZone moves the player's zone chat to the room they joined. Rooms
+// on the same map share a zone; a room without a map is a zone of its own.
+func (a *PlayerSessionActor) enterChatZone(ctx actor.Context, joined *messages.JoinRoomResponse) {
+	if a.worldManagerPID == nil {
+		return
+	}
+	zoneID := joined.MapID
+	if zoneID == "" {
+		zoneID = joined.RoomID
+	}
+	ctx.Send(a.worldManagerPID, &messages.SetPlayerZone{PlayerID: a.playerID, ZoneID: zoneID})
+}
+
+func (a *PlayerSessionActor) handleChannelMessage(msg *messages.ChannelMessage) {
+	a.sendResponse(protocol.MsgTypeChannelMessage, protocol.ChannelMessagePayload{
+		Channel:  msg.Channel,
+		Scope:    msg.Scope,
+		SenderID: msg.SenderID,
+		Text:     msg.Text,
+		SentAt:   msg.SentAt.UnixMilli(),
+	})
+}
+
+func (a *PlayerSessionActor) sendChannelList(list *messages.ChannelList) {
+	payload := protocol.ChannelListPayload{Channels: make([]protocol.ChannelInfo, 0, len(list.Channels))}
+	for _, c := range list.Channels {
+		payload.Channels = append(payload.Channels, protocol.ChannelInfo{
+			Channel:           c.Channel,
+			Scope:             c.Scope,
+			Joined:            c.Joined,
+			Members:           c.Members,
+			AutoJoin:          c.AutoJoin,
+			MessagesPerMinute: c.MessagesPerMinute,
+		})
+	}
+	a.sendResponse(protocol.MsgTypeChannelList, payload)
+}
@@ -0,0 +1,146 @@
+package actor
+
+import (
+	"context"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chathistory"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// chatHistoryTimeout bounds a history query.
+const chatHistoryTimeout = 5 * time.Second
+
+// chatHistoryResult carries a history query back from the goroutine that ran it.
+type chatHistoryResult struct {
+	request  protocol.ChatHistoryRequestPayload
+	messages []chathistory.Message
+	err      error
+}
+
+// recordRoomChat stores a chat line sent to the player's current room.
+func (a *PlayerSessionActor) recordRoomChat(text string) {
+	if a.services.ChatHistory == nil || a.roomID == "" {
+		return
+	}
+	a.services.ChatHistory.Record(chathistory.Message{
+		Channel:    chathistory.RoomChannel(a.worldID(), a.roomID),
+		SenderID:   a.playerID,
+		SenderName: a.playerID,
+		Text:       text,
+	})
+}
+
+// handleSendWhisper asks the WorldManagerActor to deliver a private message.
+func (a *PlayerSessionActor) handleSendWhisper(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	var whisperPayload protocol.WhisperRequestPayload
+	if err := msg.DecodePayload(&whisperPayload); err != nil || whisperPayload.ToPlayerID == "" || whisperPayload.Text == "" {
+		a.sendErrorResponse("INVALID_WHISPER_PAYLOAD", "Whisper payload needs a toPlayerId and text.")
+		return
+	}
+	if whisperPayload.ToPlayerID == a.playerID {
+		a.sendErrorResponse("INVALID_WHISPER_PAYLOAD", "You cannot whisper to yourself.")
+		return
+	}
+	if !a.mayChat() {
+		return
+	}
+	if a.worldManagerPID == nil {
+		a.sendErrorResponse("WHISPER_FAILED", "Whispers are not available.")
+		return
+	}
+	ctx.Send(PlayerRegistryPID(a.worldManagerPID, whisperPayload.ToPlayerID), &messages.Whisper{
+		FromID:  a.playerID,
+		FromPID: ctx.Self(),
+		ToID:    whisperPayload.ToPlayerID,
+		Text:    whisperPayload.Text,
+		SentAt:  time.Now(),
+	})
+}
+
+// handleWhisper forwards a delivered whisper to the client. The sender's copy
+// confirms delivery, so that is where it is recorded.
+func (a *PlayerSessionActor) handleWhisper(msg *messages.Whisper) {
+	a.sendResponse(protocol.MsgTypeNewWhisper, protocol.WhisperPayload{
+		FromPlayerID: msg.FromID,
+		ToPlayerID:   msg.ToID,
+		Text:         msg.Text,
+		SentAt:       msg.SentAt.UnixMilli(),
+	})
+	if msg.FromID == a.playerID && a.services.ChatHistory != nil {
+		a.services.ChatHistory.Record(chathistory.Message{
+			Channel:     chathistory.WhisperChannel(msg.FromID, msg.ToID),
+			SenderID:    msg.FromID,
+			SenderName:  msg.FromID,
+			RecipientID: msg.ToID,
+			Text:        msg.Text,
+			SentAt:      msg.SentAt,
+		})
+	}
+}
+
+// handleChatHistoryRequest fetches the recent messages of the player's room or
+// of one of their whisper conversations. The query runs off the actor and
+// reports back with a chatHistoryResult.
+func (a *PlayerSessionActor) handleChatHistoryRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.ChatHistory == nil {
+		a.sendErrorResponse("CHAT_HISTORY_DISABLED", "Chat history is not enabled on this server.")
+		return
+	}
+	var historyPayload protocol.ChatHistoryRequestPayload
+	if err := msg.DecodePayload(&historyPayload); err != nil || (historyPayload.RoomID == "") == (historyPayload.WithPlayerID == "") {
+		a.sendErrorResponse("INVALID_CHAT_HISTORY_PAYLOAD", "Chat history payload needs either a roomId or a withPlayerId.")
+		return
+	}
+	var channel string
+	if historyPayload.RoomID != "" {
+		// Only the room the player is in, so history cannot be read from outside.
+		if historyPayload.RoomID != a.roomID {
+			a.sendErrorResponse("NOT_IN_A_ROOM", "You can only read the history of the room you are in.")
+			return
+		}
+		channel = chathistory.RoomChannel(a.worldID(), a.roomID)
+	} else {
+		channel = chathistory.WhisperChannel(a.playerID, historyPayload.WithPlayerID)
+	}
+	self, root, history := ctx.Self(), a.actorSystem.Root, a.services.ChatHistory
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), chatHistoryTimeout)
+		defer cancel()
+		messages, err := history.History(queryCtx, channel, historyPayload.Limit)
+		root.Send(self, &chatHistoryResult{request: historyPayload, messages: messages, err: err})
+	}()
+}
+
+func (a *PlayerSessionActor) handleChatHistoryResult(ctx actor.Context, result *chatHistoryResult) {
+	if result.err != nil {
+		utils.LogErrorf("[%s] Player %s: Chat history query failed: %v", ctx.Self().Id, a.playerID, result.err)
+		a.sendErrorResponse("CHAT_HISTORY_UNAVAILABLE", "Chat history is unavailable right now.")
+		return
+	}
+	payload := protocol.ChatHistoryPayload{
+		RoomID:       result.request.RoomID,
+		WithPlayerID: result.request.WithPlayerID,
+		Messages:     make([]protocol.ChatHistoryEntry, 0, len(result.messages)),
+	}
+	for _, msg := range result.messages {
+		payload.Messages = append(payload.Messages, protocol.ChatHistoryEntry{
+			SenderID:   msg.SenderID,
+			SenderName: msg.SenderName,
+			Text:       msg.Text,
+			SentAt:     msg.SentAt.UnixMilli(),
+		})
+	}
+	a.sendResponse(protocol.MsgTypeChatHistory, payload)
+}
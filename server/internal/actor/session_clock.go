@@ -0,0 +1,109 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// defaultClockTolerance is the drift clients are told to correct when the
+// hints do not set one.
+const defaultClockTolerance = 50 * time.Millisecond
+
+// rttSmoothing is the weight of the newest sample in the round-trip moving
+// average.
+const rttSmoothing = 0.3
+
+// ClockHints configures the CLOCK_HINT messages sessions push so that clients
+// notice when their estimate of the server's clock has drifted.
+type ClockHints struct {
+	Interval  time.Duration // How often each session sends one; none if zero
+	Tolerance time.Duration // Drift worth a new TIME_SYNC
+}
+
+// clockHint is the session's periodic CLOCK_HINT. Like the AFK check, it
+// must not reset the receive timeout.
+type clockHint struct{}
+
+func (*clockHint) NotInfluenceReceiveTimeout() {}
+
+// serverTime is the server's clock as sent to clients.
+func serverTime() int64 {
+	return time.Now().UnixMilli()
+}
+
+// handleTimeSync answers TIME_SYNC with when its frame was read and when the
+// answer leaves.
+func (a *PlayerSessionActor) handleTimeSync(clientMsg *messages.ClientMessage, msg protocol.ClientServerMessage) {
+	var payload protocol.TimeSyncRequestPayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		a.sendErrorResponse("INVALID_TIME_SYNC_PAYLOAD", "Time sync payload needs a t0.")
+		return
+	}
+	receivedAt := clientMsg.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	a.sendResponse(protocol.MsgTypeTimeSyncResponse, protocol.TimeSyncResponsePayload{
+		T0:           payload.T0,
+		ReceivedAt:   receivedAt.UnixMilli(),
+		SentAt:       serverTime(),
+		SyncInterval: int(a.services.ClockHints.Interval / time.Second),
+	})
+}
+
+// startClockHints starts the CLOCK_HINTs once the player has authenticated.
+func (a *PlayerSessionActor) startClockHints(ctx actor.Context) {
+	interval := a.services.ClockHints.Interval
+	if interval <= 0 {
+		return
+	}
+	a.clockTimer = a.services.Timers.Every(a.actorSystem.Root, ctx.Self(), interval, interval/10, &clockHint{})
+}
+
+// stopClockHints stops the CLOCK_HINTs when the session ends.
+func (a *PlayerSessionActor) stopClockHints() {
+	a.clockTimer.Stop()
+	a.clockTimer = nil
+}
+
+func (a *PlayerSessionActor) sendClockHint() {
+	if a.clockTimer.Stopped() {
+		return // Stopped while the hint was in flight
+	}
+	tolerance := a.services.ClockHints.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultClockTolerance
+	}
+	sentAt := serverTime()
+	a.hintSentAt = time.UnixMilli(sentAt)
+	a.sendResponse(protocol.MsgTypeClockHint, protocol.ClockHintPayload{
+		ServerTime: sentAt,
+		Tolerance:  int(tolerance / time.Millisecond),
+	})
+}
+
+// measureRoundTrip takes a round-trip sample from a PING that answers the
+// latest CLOCK_HINT. Each hint is measured once, so a client cannot make its
+// connection look faster than it is.
+func (a *PlayerSessionActor) measureRoundTrip(clientMsg *messages.ClientMessage, hintTime int64) {
+	if hintTime == 0 || a.hintSentAt.IsZero() || hintTime != a.hintSentAt.UnixMilli() {
+		return
+	}
+	receivedAt := clientMsg.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	sample := receivedAt.Sub(a.hintSentAt)
+	a.hintSentAt = time.Time{}
+	if sample < 0 {
+		return
+	}
+	if a.rtt == 0 {
+		a.rtt = sample
+	} else {
+		a.rtt = time.Duration(rttSmoothing*float64(sample) + (1-rttSmoothing)*float64(a.rtt))
+	}
+}
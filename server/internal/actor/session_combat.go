@@ -0,0 +1,115 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleCombatAction forwards a COMBAT_ACTION to the player's CombatSessionActor,
+// which decides whether it is the player's turn.
+func (a *PlayerSessionActor) handleCombatAction(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	var actionPayload protocol.CombatActionPayload
+	if err := msg.DecodePayload(&actionPayload); err != nil {
+		a.sendErrorResponse("INVALID_COMBAT_PAYLOAD", "Combat action payload is malformed.")
+		return
+	}
+	if a.combatPID == nil || actionPayload.CombatID != a.combatID {
+		a.sendErrorResponse("NOT_IN_COMBAT", "You are not in that fight.")
+		return
+	}
+	ctx.Send(a.combatPID, &messages.SubmitCombatAction{
+		CombatID: a.combatID,
+		PlayerID: a.playerID,
+		Action:   actionPayload.Action,
+		TargetID: actionPayload.TargetID,
+	})
+}
+
+// handleCombatMessage relays messages from the player's CombatSessionActor to the client.
+func (a *PlayerSessionActor) handleCombatMessage(ctx actor.Context, msg interface{}) {
+	switch msg := msg.(type) {
+	case *messages.CombatStarted:
+		utils.LogInfof("[%s] Player %s: Entered combat %s.", ctx.Self().Id, a.playerID, msg.CombatID)
+		a.combatID, a.combatPID = msg.CombatID, msg.CombatPID
+		a.sendResponse(protocol.MsgTypeCombatState, protocol.CombatStatePayload{
+			CombatID:   msg.CombatID,
+			Round:      1,
+			Combatants: combatantPayloads(msg.Combatants),
+			TurnOrder:  msg.TurnOrder,
+			ServerTime: serverTime(),
+		})
+	case *messages.CombatTurnPrompt:
+		a.sendResponse(protocol.MsgTypeCombatTurn, protocol.CombatTurnPayload{
+			CombatID:     msg.CombatID,
+			Round:        msg.Round,
+			Turn:         msg.Turn,
+			Deadline:     msg.Deadline.UnixMilli(),
+			ValidTargets: msg.ValidTargets,
+			ServerTime:   serverTime(),
+		})
+	case *messages.CombatStateUpdate:
+		a.sendResponse(protocol.MsgTypeCombatState, protocol.CombatStatePayload{
+			CombatID:    msg.CombatID,
+			Round:       msg.Round,
+			Turn:        msg.Turn,
+			ActorID:     msg.ActorID,
+			Action:      msg.Action,
+			TargetID:    msg.TargetID,
+			Damage:      msg.Damage,
+			Critical:    msg.Critical,
+			Evaded:      msg.Evaded,
+			TimedOut:    msg.TimedOut,
+			Log:         msg.Log,
+			Combatants:  combatantPayloads(msg.Combatants),
+			NextActorID: msg.NextActorID,
+			ServerTime:  serverTime(),
+		})
+	case *messages.CombatActionRejected:
+		a.sendErrorResponse("COMBAT_ACTION_REJECTED", msg.Reason)
+	case *messages.CombatEnded:
+		utils.LogInfof("[%s] Player %s: Combat %s ended (%s).", ctx.Self().Id, a.playerID, msg.CombatID, msg.Reason)
+		if msg.CombatID == a.combatID {
+			a.combatID, a.combatPID = "", nil
+		}
+		a.sendResponse(protocol.MsgTypeCombatEnded, protocol.CombatEndedPayload{
+			CombatID:   msg.CombatID,
+			WinnerTeam: msg.WinnerTeam,
+			Reason:     msg.Reason,
+		})
+	}
+}
+
+// forfeitCombat concedes the player's current fight, e.g. when they disconnect.
+func (a *PlayerSessionActor) forfeitCombat(ctx actor.Context) {
+	if a.combatPID == nil {
+		return
+	}
+	ctx.Send(a.combatPID, &messages.SubmitCombatAction{
+		CombatID: a.combatID,
+		PlayerID: a.playerID,
+		Action:   protocol.CombatActionForfeit,
+	})
+	a.combatID, a.combatPID = "", nil
+}
+
+func combatantPayloads(states []messages.CombatantState) []protocol.CombatantPayload {
+	payloads := make([]protocol.CombatantPayload, len(states))
+	for i, s := range states {
+		payloads[i] = protocol.CombatantPayload{
+			ID:        s.ID,
+			Team:      s.Team,
+			Health:    s.Health,
+			MaxHealth: s.MaxHealth,
+			Defending: s.Defending,
+			Fled:      s.Fled,
+			Defeated:  s.Defeated,
+		}
+	}
+	return payloads
+}
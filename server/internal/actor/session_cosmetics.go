@@ -0,0 +1,95 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/cosmetics"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// appearanceTimeout bounds an appearance load or change, which may look up
+// the player's NFTs on chain.
+const appearanceTimeout = 10 * time.Second
+
+// appearanceResult carries what the player wears back from a load's or an
+// equip's goroutine.
+type appearanceResult struct {
+	equipped map[string]string
+	equip    bool // Answers APPEARANCE_EQUIP rather than the load at login
+	err      error
+}
+
+// connectCosmetics loads what the player wears, rechecked against the NFTs
+// they own, so it can be shown in the rooms they join.
+func (a *PlayerSessionActor) connectCosmetics(ctx actor.Context) {
+	if a.services.Cosmetics == nil {
+		return
+	}
+	self, root, service, playerID := ctx.Self(), a.actorSystem.Root, a.services.Cosmetics, a.playerID
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), appearanceTimeout)
+		defer cancel()
+		equipped, err := service.Load(queryCtx, playerID)
+		root.Send(self, &appearanceResult{equipped: equipped, err: err})
+	}()
+}
+
+// handleAppearanceEquip puts on or takes off a cosmetic.
+func (a *PlayerSessionActor) handleAppearanceEquip(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Cosmetics == nil {
+		a.sendErrorResponse("COSMETICS_DISABLED", "Cosmetics are not enabled on this server.")
+		return
+	}
+	var equipPayload protocol.AppearanceEquipRequestPayload
+	if err := msg.DecodePayload(&equipPayload); err != nil || equipPayload.Slot == "" {
+		a.sendErrorResponse("INVALID_APPEARANCE_PAYLOAD", "Appearance payload needs a slot.")
+		return
+	}
+	self, root, service, playerID := ctx.Self(), a.actorSystem.Root, a.services.Cosmetics, a.playerID
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), appearanceTimeout)
+		defer cancel()
+		equipped, err := service.Equip(queryCtx, playerID, equipPayload.Slot, equipPayload.CosmeticID)
+		root.Send(self, &appearanceResult{equipped: equipped, equip: true, err: err})
+	}()
+}
+
+// handleAppearanceResult keeps what the player wears, tells their client and
+// shows it to their room.
+func (a *PlayerSessionActor) handleAppearanceResult(ctx actor.Context, result *appearanceResult) {
+	if result.err != nil {
+		if !result.equip {
+			utils.LogWarnf("[%s] Player %s: Appearance load failed: %v", ctx.Self().Id, a.playerID, result.err)
+			return
+		}
+		utils.LogInfof("[%s] Player %s: Appearance change failed: %v", ctx.Self().Id, a.playerID, result.err)
+		a.sendErrorResponse(appearanceErrorCode(result.err), result.err.Error())
+		return
+	}
+	a.appearance = result.equipped
+	a.sendResponse(protocol.MsgTypeAppearance, protocol.AppearancePayload{PlayerID: a.playerID, Equipped: a.appearance})
+	if a.roomPID != nil {
+		ctx.Send(a.roomPID, &messages.AppearanceChanged{PlayerID: a.playerID, Equipped: a.appearance})
+	}
+}
+
+func appearanceErrorCode(err error) string {
+	switch {
+	case errors.Is(err, cosmetics.ErrUnknownSlot), errors.Is(err, cosmetics.ErrUnknownCosmetic), errors.Is(err, cosmetics.ErrWrongSlot):
+		return "INVALID_APPEARANCE_PAYLOAD"
+	case errors.Is(err, cosmetics.ErrNotOwned):
+		return "COSMETIC_NOT_OWNED"
+	case errors.Is(err, cosmetics.ErrOwnershipUnavailable):
+		return "OWNERSHIP_UNAVAILABLE"
+	}
+	return "APPEARANCE_UNAVAILABLE"
+}
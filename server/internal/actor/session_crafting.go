@@ -0,0 +1,152 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/crafting"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// craftingTimeout bounds the queries of a crafting request.
+const craftingTimeout = 5 * time.Second
+
+// craftingResult carries the player's queue back from a crafting request's
+// goroutine.
+type craftingResult struct {
+	action string // Client message type being answered
+	crafts []crafting.Craft
+	err    error
+}
+
+// connectCrafting registers this session to be told about finished crafts.
+func (a *PlayerSessionActor) connectCrafting(ctx actor.Context) {
+	if a.services.Crafting == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Crafting.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+// handleCraftStart queues a craft and answers with the player's queue.
+func (a *PlayerSessionActor) handleCraftStart(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkCrafting() {
+		return
+	}
+	var startPayload protocol.CraftStartRequestPayload
+	if err := msg.DecodePayload(&startPayload); err != nil || startPayload.RecipeID == "" {
+		a.sendErrorResponse("INVALID_CRAFT_PAYLOAD", "Craft payload needs a recipeId.")
+		return
+	}
+	playerID, service, energyService := a.playerID, a.services.Crafting, a.services.Energy
+	a.runCrafting(ctx, protocol.MsgTypeCraftStart, func(queryCtx context.Context) ([]crafting.Craft, error) {
+		if err := energyService.Spend(queryCtx, playerID, energy.ActionCraft); err != nil {
+			return nil, err
+		}
+		if _, err := service.Queue(queryCtx, playerID, startPayload.RecipeID); err != nil {
+			energyService.Refund(queryCtx, playerID, energy.ActionCraft)
+			return nil, err
+		}
+		return service.Crafts(queryCtx, playerID)
+	})
+}
+
+// handleCraftQueueRequest answers CRAFT_QUEUE_REQUEST with the player's queue.
+func (a *PlayerSessionActor) handleCraftQueueRequest(ctx actor.Context) {
+	if !a.checkCrafting() {
+		return
+	}
+	playerID, service := a.playerID, a.services.Crafting
+	a.runCrafting(ctx, protocol.MsgTypeCraftQueueRequest, func(queryCtx context.Context) ([]crafting.Craft, error) {
+		return service.Crafts(queryCtx, playerID)
+	})
+}
+
+func (a *PlayerSessionActor) checkCrafting() bool {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return false
+	}
+	if a.services.Crafting == nil {
+		a.sendErrorResponse("CRAFTING_DISABLED", "Crafting is not enabled on this server.")
+		return false
+	}
+	return true
+}
+
+// runCrafting runs a crafting request off the actor, since it queries the
+// database.
+func (a *PlayerSessionActor) runCrafting(ctx actor.Context, action string, op func(context.Context) ([]crafting.Craft, error)) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), craftingTimeout)
+		defer cancel()
+		crafts, err := op(queryCtx)
+		root.Send(self, &craftingResult{action: action, crafts: crafts, err: err})
+	}()
+	a.awaitReply(action)
+}
+
+func (a *PlayerSessionActor) handleCraftingResult(ctx actor.Context, result *craftingResult) {
+	if result.err != nil {
+		code := ""
+		switch {
+		case errors.Is(result.err, crafting.ErrUnknownRecipe):
+			code = "UNKNOWN_RECIPE"
+		case errors.Is(result.err, crafting.ErrQueueFull):
+			code = "CRAFT_QUEUE_FULL"
+		case errors.Is(result.err, crafting.ErrInsufficientFunds):
+			code = "INSUFFICIENT_FUNDS"
+		case errors.Is(result.err, crafting.ErrMissingMaterials):
+			code = "MISSING_MATERIALS"
+		case errors.Is(result.err, energy.ErrNotEnoughEnergy):
+			code = "NOT_ENOUGH_ENERGY"
+		default:
+			utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+			a.sendErrorResponse("CRAFTING_UNAVAILABLE", "Crafting is unavailable right now.")
+			return
+		}
+		a.sendErrorResponse(code, result.err.Error())
+		return
+	}
+	now := time.Now()
+	recipes := a.services.Crafting.Recipes()
+	payload := protocol.CraftQueuePayload{
+		Crafts:     make([]protocol.CraftPayload, 0, len(result.crafts)),
+		Recipes:    make([]protocol.RecipePayload, 0, len(recipes)),
+		MaxQueued:  a.services.Crafting.MaxQueued(),
+		ServerTime: now.UnixMilli(),
+	}
+	for _, c := range result.crafts {
+		payload.Crafts = append(payload.Crafts, craftPayload(c, now))
+	}
+	for _, r := range recipes {
+		payload.Recipes = append(payload.Recipes, protocol.RecipePayload{
+			RecipeID:        r.ID,
+			Name:            r.Name,
+			ItemType:        r.ItemType,
+			DurationSeconds: r.DurationSeconds,
+			Coins:           r.Coins,
+			Materials:       r.Materials,
+		})
+	}
+	a.sendResponse(protocol.MsgTypeCraftQueue, payload)
+}
+
+func craftPayload(c crafting.Craft, now time.Time) protocol.CraftPayload {
+	return protocol.CraftPayload{
+		CraftID:     c.ID,
+		RecipeID:    c.RecipeID,
+		Name:        c.Name,
+		ItemType:    c.ItemType,
+		Stage:       c.Stage(now),
+		StartsAt:    c.StartsAt.UnixMilli(),
+		CompletesAt: c.CompletesAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,58 @@
+package actor
+
+import (
+	"errors"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/deeplink"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleDeepLinkOpen checks the deep link the client was opened with,
+// attributes the visit and routes the player: to the linked room itself, and
+// to listings and guild pages through the client.
+func (a *PlayerSessionActor) handleDeepLinkOpen(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.DeepLinks == nil {
+		a.sendErrorResponse("DEEP_LINKS_DISABLED", "Deep links are not enabled on this server.")
+		return
+	}
+	var payload protocol.DeepLinkOpenPayload
+	if err := msg.DecodePayload(&payload); err != nil || payload.Token == "" {
+		a.sendErrorResponse("INVALID_DEEP_LINK_PAYLOAD", "Deep link payload needs a token.")
+		return
+	}
+	link, err := a.services.DeepLinks.Open(a.playerID, payload.Token)
+	if errors.Is(err, deeplink.ErrExpired) {
+		a.sendErrorResponse("DEEP_LINK_EXPIRED", "This link has expired.")
+		return
+	}
+	if err != nil {
+		utils.LogWarnf("[%s] Player %s presented an invalid deep link.", ctx.Self().Id, a.playerID)
+		a.sendErrorResponse("INVALID_DEEP_LINK", "This link is not valid.")
+		return
+	}
+	utils.LogInfof("[%s] Player %s opened deep link %s to %s %s from %s.", ctx.Self().Id, a.playerID, link.ID, link.Kind, link.Target, link.Source)
+	a.services.Events.Publish(events.TopicDeepLinkOpened, events.DeepLinkOpened{
+		PlayerID: a.playerID,
+		LinkID:   link.ID,
+		Kind:     string(link.Kind),
+		Target:   link.Target,
+		Source:   link.Source,
+		Campaign: link.Campaign,
+	})
+	a.sendResponse(protocol.MsgTypeDeepLinkRoute, protocol.DeepLinkRoutePayload{
+		LinkID: link.ID,
+		Kind:   string(link.Kind),
+		Target: link.Target,
+	})
+	if link.Kind == deeplink.KindRoom {
+		// The link gets the player to the room, not past its password or invite list.
+		a.findAndJoinRoom(ctx, link.Target, "", "")
+	}
+}
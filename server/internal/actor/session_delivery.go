@@ -0,0 +1,81 @@
+package actor
+
+import (
+	"encoding/json"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/delivery"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// attachDelivery takes the player's replay buffer when the client asked for
+// reliable delivery at auth. It returns the messages to replay after
+// AUTH_RESPONSE, and whether some the client missed are gone.
+func (a *PlayerSessionActor) attachDelivery(msg *messages.AuthenticatePlayer) (replay []delivery.Message, gap bool) {
+	if !msg.Reliable || a.services.Delivery == nil {
+		return nil, false
+	}
+	buffer, resumed := a.services.Delivery.Attach(a.playerID)
+	a.delivery = buffer
+	if msg.LastSeq == 0 && !resumed {
+		return nil, false // A fresh start
+	}
+	if !resumed || msg.LastSeq > buffer.Seq() {
+		// The buffer the client counted from expired; its seq numbers restart.
+		replay, _ = buffer.Since(0)
+		return replay, true
+	}
+	replay, complete := buffer.Since(msg.LastSeq)
+	return replay, !complete
+}
+
+// replayMessages re-sends the messages a reconnecting client missed, with
+// their original seq numbers.
+func (a *PlayerSessionActor) replayMessages(replay []delivery.Message) {
+	for _, m := range replay {
+		a.metrics.messageSent(m.Type)
+		a.writeSequenced(m)
+	}
+}
+
+// sendSequenced numbers a reliable message and keeps it until the client
+// acknowledges it.
+func (a *PlayerSessionActor) sendSequenced(msgType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	a.writeSequenced(a.delivery.Append(msgType, body))
+	return nil
+}
+
+// writeSequenced writes m as an envelope, which carries its seq in every
+// framing version.
+func (a *PlayerSessionActor) writeSequenced(m delivery.Message) {
+	a.handleForwardToClient(&messages.ForwardToClient{Payload: protocol.AppendEnvelope(nil, m.Type, m.Payload, m.Seq)})
+}
+
+func (a *PlayerSessionActor) handleAck(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if a.delivery == nil {
+		a.sendErrorResponse("NOT_RELIABLE", "Reliable delivery was not requested at auth.")
+		return
+	}
+	var ackPayload protocol.AckPayload
+	if err := msg.DecodePayload(&ackPayload); err != nil {
+		utils.LogWarnf("[%s] Player %s: ACK payload malformed: %v", ctx.Self().Id, a.playerID, err)
+		a.sendErrorResponse("INVALID_ACK_PAYLOAD", "Ack payload needs a seq.")
+		return
+	}
+	a.delivery.Ack(ackPayload.Seq)
+}
+
+// detachDelivery leaves the replay buffer to the store's retention window.
+func (a *PlayerSessionActor) detachDelivery() {
+	if a.delivery == nil {
+		return
+	}
+	a.services.Delivery.Detach(a.playerID)
+	a.delivery = nil
+}
@@ -0,0 +1,120 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// maxRecentErrors is how many error responses a session keeps for diagnostics.
+const maxRecentErrors = 20
+
+// unknownMessageType counts client messages of types the protocol does not
+// define, so that clients cannot grow the counters without bound.
+const unknownMessageType = "UNKNOWN"
+
+// sessionMetrics are a session's traffic counters for the admin diagnostics
+// query. Only the session actor touches them.
+type sessionMetrics struct {
+	connectedAt  time.Time
+	remoteAddr   string
+	bytesIn      int64
+	bytesOut     int64
+	framesIn     int64
+	framesOut    int64
+	messagesIn   map[string]int64
+	messagesOut  map[string]int64
+	pendingSui   map[string]int // Client message type -> on-chain checks still running
+	recentErrors []messages.SessionError
+}
+
+func (m *sessionMetrics) frameReceived(size int) {
+	m.framesIn++
+	m.bytesIn += int64(size)
+}
+
+func (m *sessionMetrics) frameSent(size int) {
+	m.framesOut++
+	m.bytesOut += int64(size)
+}
+
+func (m *sessionMetrics) messageReceived(msgType string) {
+	if _, ok := protocol.LookupMessage(msgType); !ok {
+		msgType = unknownMessageType
+	}
+	if m.messagesIn == nil {
+		m.messagesIn = make(map[string]int64)
+	}
+	m.messagesIn[msgType]++
+}
+
+func (m *sessionMetrics) messageSent(msgType string) {
+	if m.messagesOut == nil {
+		m.messagesOut = make(map[string]int64)
+	}
+	m.messagesOut[msgType]++
+}
+
+func (m *sessionMetrics) errorSent(code, message string) {
+	if len(m.recentErrors) == maxRecentErrors {
+		m.recentErrors = append(m.recentErrors[:0], m.recentErrors[1:]...)
+	}
+	m.recentErrors = append(m.recentErrors, messages.SessionError{At: time.Now(), Code: code, Message: message})
+}
+
+// suiRequestStarted and suiRequestFinished bracket an on-chain check run off
+// the actor for the client message msgType.
+func (m *sessionMetrics) suiRequestStarted(msgType string) {
+	if m.pendingSui == nil {
+		m.pendingSui = make(map[string]int)
+	}
+	m.pendingSui[msgType]++
+}
+
+func (m *sessionMetrics) suiRequestFinished(msgType string) {
+	if m.pendingSui[msgType] <= 1 {
+		delete(m.pendingSui, msgType)
+		return
+	}
+	m.pendingSui[msgType]--
+}
+
+// diagnostics answers GetSessionDiagnostics with a copy of the session's state.
+func (a *PlayerSessionActor) diagnostics(ctx actor.Context) *messages.SessionDiagnostics {
+	m := &a.metrics
+	d := &messages.SessionDiagnostics{
+		PlayerID:           a.playerID,
+		SessionID:          ctx.Self().Id,
+		RemoteAddr:         m.remoteAddr,
+		WorldID:            a.worldID(),
+		RoomID:             a.roomID,
+		CombatID:           a.combatID,
+		AFK:                a.afk,
+		ConnectedAt:        m.connectedAt,
+		AuthenticatedAt:    a.authenticatedAt,
+		LastActivity:       a.lastActivity,
+		BytesIn:            m.bytesIn,
+		BytesOut:           m.bytesOut,
+		FramesIn:           m.framesIn,
+		FramesOut:          m.framesOut,
+		MessagesIn:         make(map[string]int64, len(m.messagesIn)),
+		MessagesOut:        make(map[string]int64, len(m.messagesOut)),
+		PendingSuiRequests: make(map[string]int, len(m.pendingSui)),
+		RecentErrors:       append([]messages.SessionError{}, m.recentErrors...),
+	}
+	if !m.connectedAt.IsZero() {
+		d.ConnectionAge = time.Since(m.connectedAt).Seconds()
+	}
+	for msgType, n := range m.messagesIn {
+		d.MessagesIn[msgType] = n
+	}
+	for msgType, n := range m.messagesOut {
+		d.MessagesOut[msgType] = n
+	}
+	for msgType, n := range m.pendingSui {
+		d.PendingSuiRequests[msgType] = n
+	}
+	return d
+}
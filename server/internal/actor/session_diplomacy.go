@@ -0,0 +1,126 @@
+package actor
+
+import (
+	"errors"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/diplomacy"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleDiplomacyRequest answers DIPLOMACY_REQUEST, ALLIANCE_PROPOSE,
+// ALLIANCE_RESPOND, ALLIANCE_LEAVE, RIVALRY_DECLARE and RIVALRY_END with the
+// guild's standing.
+func (a *PlayerSessionActor) handleDiplomacyRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	service := a.services.Diplomacy
+	if service == nil {
+		a.sendErrorResponse("DIPLOMACY_DISABLED", "Guild diplomacy is not enabled on this server.")
+		return
+	}
+	var standing diplomacy.Standing
+	var err error
+	switch msg.Type {
+	case protocol.MsgTypeDiplomacyRequest:
+		var queryPayload protocol.DiplomacyRequestPayload
+		if err := msg.DecodePayload(&queryPayload); err != nil {
+			a.sendErrorResponse("INVALID_DIPLOMACY_PAYLOAD", "Diplomacy payload is malformed.")
+			return
+		}
+		standing, err = service.Standing(a.playerID, queryPayload.GuildID)
+	case protocol.MsgTypeAlliancePropose:
+		var proposePayload protocol.AllianceProposeRequestPayload
+		if err := msg.DecodePayload(&proposePayload); err != nil || proposePayload.GuildID == "" {
+			a.sendErrorResponse("INVALID_DIPLOMACY_PAYLOAD", "Proposal payload needs a guildId.")
+			return
+		}
+		standing, err = service.Propose(a.playerID, proposePayload.GuildID, proposePayload.Name)
+	case protocol.MsgTypeAllianceRespond:
+		var respondPayload protocol.AllianceRespondRequestPayload
+		if err := msg.DecodePayload(&respondPayload); err != nil || respondPayload.GuildID == "" {
+			a.sendErrorResponse("INVALID_DIPLOMACY_PAYLOAD", "Response payload needs a guildId.")
+			return
+		}
+		standing, err = service.Respond(a.playerID, respondPayload.GuildID, respondPayload.Accept)
+	case protocol.MsgTypeAllianceLeave:
+		standing, err = service.Leave(a.playerID)
+	case protocol.MsgTypeRivalryDeclare, protocol.MsgTypeRivalryEnd:
+		var rivalryPayload protocol.RivalryRequestPayload
+		if err := msg.DecodePayload(&rivalryPayload); err != nil || rivalryPayload.GuildID == "" {
+			a.sendErrorResponse("INVALID_DIPLOMACY_PAYLOAD", "Rivalry payload needs a guildId.")
+			return
+		}
+		if msg.Type == protocol.MsgTypeRivalryDeclare {
+			standing, err = service.DeclareRivalry(a.playerID, rivalryPayload.GuildID)
+		} else {
+			standing, err = service.EndRivalry(a.playerID, rivalryPayload.GuildID)
+		}
+	}
+	if err != nil {
+		a.sendDiplomacyError(ctx, msg.Type, err)
+		return
+	}
+	a.sendResponse(protocol.MsgTypeDiplomacy, diplomacyPayload(standing))
+}
+
+func (a *PlayerSessionActor) sendDiplomacyError(ctx actor.Context, action string, err error) {
+	code := ""
+	switch {
+	case errors.Is(err, diplomacy.ErrNoGuild):
+		code = "NOT_IN_GUILD"
+	case errors.Is(err, diplomacy.ErrNotLeader):
+		code = "NOT_GUILD_LEADER"
+	case errors.Is(err, diplomacy.ErrUnknownGuild), errors.Is(err, diplomacy.ErrSelf):
+		code = "INVALID_GUILD"
+	case errors.Is(err, diplomacy.ErrInvalid):
+		code = "INVALID_ALLIANCE"
+	case errors.Is(err, diplomacy.ErrAllied), errors.Is(err, diplomacy.ErrInAlliance), errors.Is(err, diplomacy.ErrFull):
+		code = "ALLIANCE_UNAVAILABLE"
+	case errors.Is(err, diplomacy.ErrRivals):
+		code = "GUILDS_ARE_RIVALS"
+	case errors.Is(err, diplomacy.ErrNoProposal):
+		code = "ALLIANCE_PROPOSAL_NOT_FOUND"
+	case errors.Is(err, diplomacy.ErrNotInAlliance):
+		code = "NOT_IN_ALLIANCE"
+	case errors.Is(err, diplomacy.ErrNoRivalry):
+		code = "RIVALRY_NOT_FOUND"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, action, err)
+		a.sendErrorResponse("DIPLOMACY_UNAVAILABLE", "Guild diplomacy is unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, err.Error())
+}
+
+func diplomacyPayload(s diplomacy.Standing) protocol.DiplomacyPayload {
+	payload := protocol.DiplomacyPayload{GuildID: s.GuildID, Rivals: s.Rivals, RivalOf: s.RivalOf}
+	if s.Alliance != nil {
+		payload.Alliance = &protocol.AlliancePayload{
+			AllianceID: s.Alliance.ID,
+			Name:       s.Alliance.Name,
+			GuildIDs:   s.Alliance.GuildIDs,
+			FormedAt:   s.Alliance.FormedAt.UnixMilli(),
+		}
+	}
+	for _, p := range s.Incoming {
+		payload.Incoming = append(payload.Incoming, allianceProposalPayload(p))
+	}
+	for _, p := range s.Outgoing {
+		payload.Outgoing = append(payload.Outgoing, allianceProposalPayload(p))
+	}
+	return payload
+}
+
+func allianceProposalPayload(p diplomacy.Proposal) protocol.AllianceProposalPayload {
+	return protocol.AllianceProposalPayload{
+		FromGuildID: p.FromGuildID,
+		ToGuildID:   p.ToGuildID,
+		ProposedBy:  p.ProposedBy,
+		Name:        p.Name,
+		ExpiresAt:   p.ExpiresAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,25 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// terminate ends the session. The client is sent DISCONNECT saying why
+// before its connection is closed.
+func (a *PlayerSessionActor) terminate(ctx actor.Context, msg *messages.TerminateSession) {
+	if a.conn != nil {
+		a.metrics.errorSent(msg.Code, msg.Reason)
+		a.sendResponse(protocol.MsgTypeDisconnect, protocol.DisconnectPayload{
+			Code:       msg.Code,
+			Reason:     msg.Reason,
+			Retryable:  msg.Retryable,
+			RetryAfter: int((msg.RetryAfter + time.Second - 1) / time.Second),
+		})
+		a.conn.Close()
+	}
+	ctx.Stop(ctx.Self())
+}
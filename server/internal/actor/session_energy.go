@@ -0,0 +1,134 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// energyTimeout bounds the queries of an energy lookup or spend.
+const energyTimeout = 5 * time.Second
+
+// energyResult carries the player's energy back from a lookup's goroutine.
+type energyResult struct {
+	status energy.Status
+	err    error
+}
+
+// energySpent carries the outcome of spending an action's energy back to the
+// actor, which then runs the action.
+type energySpent struct {
+	action string
+	err    error
+	then   func()
+}
+
+// connectEnergy registers this session for energy updates and sends the
+// energy the player regained while offline.
+func (a *PlayerSessionActor) connectEnergy(ctx actor.Context) {
+	if a.services.Energy == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Energy.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+	a.refreshEnergy(ctx)
+}
+
+// handleEnergyRequest answers ENERGY_REQUEST with the player's energy.
+func (a *PlayerSessionActor) handleEnergyRequest(ctx actor.Context) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Energy == nil {
+		a.sendErrorResponse("ENERGY_DISABLED", "Energy is not enabled on this server.")
+		return
+	}
+	a.refreshEnergy(ctx)
+}
+
+// refreshEnergy looks the player's energy up off the actor, since it may
+// query the database.
+func (a *PlayerSessionActor) refreshEnergy(ctx actor.Context) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	playerID, service := a.playerID, a.services.Energy
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), energyTimeout)
+		defer cancel()
+		status, err := service.Get(queryCtx, playerID)
+		root.Send(self, &energyResult{status: status, err: err})
+	}()
+}
+
+func (a *PlayerSessionActor) handleEnergyResult(ctx actor.Context, result *energyResult) {
+	if result.err != nil {
+		utils.LogErrorf("[%s] Player %s: Energy lookup failed: %v", ctx.Self().Id, a.playerID, result.err)
+		a.sendErrorResponse("ENERGY_UNAVAILABLE", "Energy is unavailable right now.")
+		return
+	}
+	a.sendEnergy(result.status)
+}
+
+func (a *PlayerSessionActor) sendEnergy(status energy.Status) {
+	payload := protocol.EnergyPayload{
+		Energy:       status.Energy,
+		Max:          status.Max,
+		RegenSeconds: int(status.RegenInterval / time.Second),
+		Costs:        a.services.Energy.Costs(),
+		ServerTime:   serverTime(),
+	}
+	if !status.NextRegenAt.IsZero() {
+		payload.NextRegenAt = status.NextRegenAt.UnixMilli()
+	}
+	a.sendResponse(protocol.MsgTypeEnergy, payload)
+}
+
+// spendEnergy spends the energy of action off the actor and then runs then on
+// it. Free actions run right away.
+func (a *PlayerSessionActor) spendEnergy(ctx actor.Context, action string, then func()) {
+	if a.services.Energy.Cost(action) == 0 {
+		then()
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	playerID, service := a.playerID, a.services.Energy
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), energyTimeout)
+		defer cancel()
+		err := service.Spend(queryCtx, playerID, action)
+		root.Send(self, &energySpent{action: action, err: err, then: then})
+	}()
+}
+
+func (a *PlayerSessionActor) handleEnergySpent(ctx actor.Context, spent *energySpent) {
+	switch {
+	case errors.Is(spent.err, energy.ErrNotEnoughEnergy):
+		a.sendErrorResponse("NOT_ENOUGH_ENERGY", spent.err.Error())
+	case spent.err != nil:
+		utils.LogErrorf("[%s] Player %s: Spending energy on %s failed: %v", ctx.Self().Id, a.playerID, spent.action, spent.err)
+		a.sendErrorResponse("ENERGY_UNAVAILABLE", "Energy is unavailable right now.")
+	default:
+		spent.then()
+	}
+}
+
+// refundEnergy gives back the energy of an action that failed after
+// spendEnergy.
+func (a *PlayerSessionActor) refundEnergy(action string) {
+	if a.services.Energy.Cost(action) == 0 {
+		return
+	}
+	playerID, service := a.playerID, a.services.Energy
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), energyTimeout)
+		defer cancel()
+		service.Refund(queryCtx, playerID, action)
+	}()
+}
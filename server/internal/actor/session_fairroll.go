@@ -0,0 +1,41 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/fairroll"
+)
+
+// connectFairRolls registers this session to be sent the commitments and
+// reveals of the player's high-stakes loot rolls.
+func (a *PlayerSessionActor) connectFairRolls(ctx actor.Context) {
+	if a.services.FairRolls == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.FairRolls.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+func lootCommitmentPayload(c *fairroll.Commitment) protocol.LootCommitmentPayload {
+	return protocol.LootCommitmentPayload{CommitmentID: c.ID, Hash: c.Hash, At: c.At.UnixMilli()}
+}
+
+func lootRevealPayload(r *fairroll.Reveal) protocol.LootRevealPayload {
+	drops := make([]protocol.LootDropPayload, len(r.Drops))
+	for i, drop := range r.Drops {
+		drops[i] = protocol.LootDropPayload{ItemID: drop.ItemID, Chance: drop.Chance, Min: drop.Min, Max: drop.Max}
+	}
+	return protocol.LootRevealPayload{
+		CommitmentID:       r.CommitmentID,
+		Hash:               r.Hash,
+		Seed:               r.Seed,
+		Table:              r.Table,
+		Drops:              drops,
+		DropRateMultiplier: r.DropRateMultiplier,
+		Draws:              r.Draws,
+		Loot:               r.Loot,
+		At:                 r.At.UnixMilli(),
+	}
+}
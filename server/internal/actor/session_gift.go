@@ -0,0 +1,203 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/gift"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// giftTimeout bounds a gift's ownership and address checks or a transfer check.
+const giftTimeout = 10 * time.Second
+
+// giftResult carries the outcome of a gift operation back from its goroutine.
+// Successes reach both players as *gift.Update.
+type giftResult struct {
+	action string // Client message type being answered
+	err    error
+}
+
+// connectGifts registers this session for gift updates and replays the
+// player's open gifts.
+func (a *PlayerSessionActor) connectGifts(ctx actor.Context) {
+	if a.services.Gifts == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Gifts.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+	for _, g := range a.services.Gifts.Gifts(a.playerID) {
+		a.sendGiftUpdate(g)
+	}
+}
+
+// handleGiftSend offers another player an item.
+func (a *PlayerSessionActor) handleGiftSend(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkGifts() {
+		return
+	}
+	var sendPayload protocol.GiftSendRequestPayload
+	if err := msg.DecodePayload(&sendPayload); err != nil || sendPayload.RecipientID == "" || sendPayload.ItemID == "" {
+		a.sendErrorResponse("INVALID_GIFT_PAYLOAD", "Gift payload needs a recipientId and an itemId.")
+		return
+	}
+	playerID, gifts := a.playerID, a.services.Gifts
+	a.runGift(ctx, protocol.MsgTypeGiftSend, func(queryCtx context.Context) error {
+		_, err := gifts.Send(queryCtx, playerID, sendPayload.RecipientID, sendPayload.ItemID, sendPayload.Note)
+		return err
+	})
+}
+
+// handleGiftRespond accepts, declines or cancels a gift.
+func (a *PlayerSessionActor) handleGiftRespond(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkGifts() {
+		return
+	}
+	var respondPayload protocol.GiftRespondRequestPayload
+	if err := msg.DecodePayload(&respondPayload); err != nil || respondPayload.GiftID == "" {
+		a.sendErrorResponse("INVALID_GIFT_PAYLOAD", "Gift respond payload needs a giftId.")
+		return
+	}
+	playerID, gifts := a.playerID, a.services.Gifts
+	switch respondPayload.Action {
+	case protocol.GiftActionAccept, protocol.GiftActionDecline:
+		accept := respondPayload.Action == protocol.GiftActionAccept
+		a.runGift(ctx, protocol.MsgTypeGiftRespond, func(queryCtx context.Context) error {
+			_, err := gifts.Respond(queryCtx, playerID, respondPayload.GiftID, accept)
+			return err
+		})
+	case protocol.GiftActionCancel:
+		if _, err := gifts.Cancel(playerID, respondPayload.GiftID); err != nil {
+			a.handleGiftResult(ctx, &giftResult{action: protocol.MsgTypeGiftRespond, err: err})
+		}
+	default:
+		a.sendErrorResponse("INVALID_GIFT_PAYLOAD", "Gift action must be accept, decline or cancel.")
+	}
+}
+
+// handleGiftSent checks the transfer the sender signed for a gift.
+func (a *PlayerSessionActor) handleGiftSent(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkGifts() {
+		return
+	}
+	var sentPayload protocol.GiftSentRequestPayload
+	if err := msg.DecodePayload(&sentPayload); err != nil || sentPayload.GiftID == "" || sentPayload.TxDigest == "" {
+		a.sendErrorResponse("INVALID_GIFT_PAYLOAD", "Gift sent payload needs a giftId and a txDigest.")
+		return
+	}
+	playerID, gifts := a.playerID, a.services.Gifts
+	a.runGift(ctx, protocol.MsgTypeGiftSent, func(queryCtx context.Context) error {
+		_, err := gifts.Sent(queryCtx, playerID, sentPayload.GiftID, sentPayload.TxDigest)
+		return err
+	})
+}
+
+// handleGiftHistoryRequest answers GIFT_HISTORY_REQUEST with the player's finished gifts.
+func (a *PlayerSessionActor) handleGiftHistoryRequest(ctx actor.Context) {
+	if !a.checkGifts() {
+		return
+	}
+	history := a.services.Gifts.History(a.playerID)
+	payload := protocol.GiftHistoryPayload{Gifts: make([]protocol.GiftPayload, 0, len(history))}
+	for _, g := range history {
+		payload.Gifts = append(payload.Gifts, a.giftPayload(g))
+	}
+	a.sendResponse(protocol.MsgTypeGiftHistory, payload)
+}
+
+func (a *PlayerSessionActor) checkGifts() bool {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return false
+	}
+	if a.services.Gifts == nil {
+		a.sendErrorResponse("GIFTS_DISABLED", "Gifting is not enabled on this server.")
+		return false
+	}
+	return true
+}
+
+// runGift runs a gift operation off the actor, since it queries the chain.
+func (a *PlayerSessionActor) runGift(ctx actor.Context, action string, op func(context.Context) error) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.metrics.suiRequestStarted(action)
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), giftTimeout)
+		defer cancel()
+		root.Send(self, &giftResult{action: action, err: op(queryCtx)})
+	}()
+	a.awaitReply(action)
+}
+
+func (a *PlayerSessionActor) handleGiftResult(ctx actor.Context, result *giftResult) {
+	if result.err == nil {
+		return // Both players get a GIFT_UPDATE
+	}
+	code := "GIFT_FAILED"
+	switch {
+	case errors.Is(result.err, gift.ErrSelfGift), errors.Is(result.err, gift.ErrNoItem):
+		code = "INVALID_GIFT_PAYLOAD"
+	case errors.Is(result.err, gift.ErrUnknownGift), errors.Is(result.err, gift.ErrNotYourGift):
+		code = "UNKNOWN_GIFT"
+	case errors.Is(result.err, gift.ErrNotOwner):
+		code = "GIFT_NOT_OWNER"
+	case errors.Is(result.err, gift.ErrNoRecipient):
+		code = "GIFT_INVALID_RECIPIENT"
+	case errors.Is(result.err, gift.ErrTransferUnverified):
+		code = "GIFT_TRANSFER_UNVERIFIED"
+	case errors.Is(result.err, accountlink.ErrNotLinked):
+		code = "WALLET_NOT_LINKED"
+	case errors.Is(result.err, reservation.ErrReserved):
+		code = "ITEM_RESERVED"
+	case errors.Is(result.err, parental.ErrSpendLimit):
+		code = "SPEND_LIMIT_REACHED"
+	case errors.Is(result.err, gift.ErrWrongState):
+	case isTransactionFailure(result.err):
+		utils.LogWarnf("[%s] Player %s: %s failed on chain: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		code = "TRANSACTION_FAILED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		a.sendErrorResponse("GIFTS_UNAVAILABLE", "Gifting is unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, playerErrorMessage(result.err))
+}
+
+func (a *PlayerSessionActor) sendGiftUpdate(g gift.Gift) {
+	a.sendResponse(protocol.MsgTypeGiftUpdate, a.giftPayload(g))
+}
+
+// giftPayload describes g to this session's player. Only the sender sees the
+// transfer they are to sign.
+func (a *PlayerSessionActor) giftPayload(g gift.Gift) protocol.GiftPayload {
+	payload := protocol.GiftPayload{
+		GiftID:          g.ID,
+		From:            g.From,
+		To:              g.To,
+		ItemID:          g.ItemID,
+		ItemType:        g.ItemType,
+		Note:            g.Note,
+		Value:           g.Value,
+		NeedsAcceptance: g.NeedsAcceptance,
+		Custody:         g.Custody,
+		Status:          string(g.Status),
+		TxDigest:        g.TxDigest,
+		CreatedAt:       g.CreatedAt.UnixMilli(),
+		UpdatedAt:       g.UpdatedAt.UnixMilli(),
+	}
+	if g.From == a.playerID {
+		payload.TxBytes = g.TxBytes
+	}
+	if !g.Status.Final() && !g.Deadline.IsZero() {
+		payload.Deadline = g.Deadline.UnixMilli()
+	}
+	return payload
+}
@@ -0,0 +1,197 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// guildActionTimeout bounds the address lookups and transaction building of
+// a guild action.
+const guildActionTimeout = 10 * time.Second
+
+// guildActionResult carries the outcome of a guild action back from its
+// goroutine.
+type guildActionResult struct {
+	action string // Client message type being answered
+	result guildranks.Action
+	err    error
+}
+
+// handleGuildRankRequest answers GUILD_RANKS_REQUEST, GUILD_RANK_DEFINE and
+// GUILD_RANK_DELETE with the guild's ranks.
+func (a *PlayerSessionActor) handleGuildRankRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	service := a.checkGuildRanks()
+	if service == nil {
+		return
+	}
+	var matrix guildranks.Matrix
+	var err error
+	switch msg.Type {
+	case protocol.MsgTypeGuildRanksRequest:
+		matrix, err = service.Matrix(a.playerID)
+	case protocol.MsgTypeGuildRankDefine:
+		var rankPayload protocol.GuildRankPayload
+		if err := msg.DecodePayload(&rankPayload); err != nil {
+			a.sendErrorResponse("INVALID_GUILD_RANK_PAYLOAD", "Guild rank payload is malformed.")
+			return
+		}
+		rank := guildranks.Rank{Name: rankPayload.Name, WithdrawPerDay: rankPayload.WithdrawPerDay, OnChainRank: rankPayload.OnChainRank}
+		for _, p := range rankPayload.Permissions {
+			rank.Permissions = append(rank.Permissions, guildranks.Permission(p))
+		}
+		matrix, err = service.DefineRank(a.playerID, rank)
+	case protocol.MsgTypeGuildRankDelete:
+		var deletePayload protocol.GuildRankDeleteRequestPayload
+		if err := msg.DecodePayload(&deletePayload); err != nil || deletePayload.Name == "" {
+			a.sendErrorResponse("INVALID_GUILD_RANK_PAYLOAD", "Delete payload needs a name.")
+			return
+		}
+		matrix, err = service.DeleteRank(a.playerID, deletePayload.Name)
+	}
+	if err != nil {
+		a.sendGuildRankError(ctx, msg.Type, err)
+		return
+	}
+	a.sendResponse(protocol.MsgTypeGuildRanks, guildRanksPayload(matrix))
+}
+
+// handleGuildAction starts GUILD_INVITE, GUILD_KICK, GUILD_BANK_WITHDRAW and
+// GUILD_RANK_ASSIGN, which are answered with GUILD_ACTION once the unsigned
+// transaction is built.
+func (a *PlayerSessionActor) handleGuildAction(ctx actor.Context, msg protocol.ClientServerMessage) {
+	service := a.checkGuildRanks()
+	if service == nil {
+		return
+	}
+	playerID := a.playerID
+	var op func(context.Context) (guildranks.Action, error)
+	switch msg.Type {
+	case protocol.MsgTypeGuildInvite, protocol.MsgTypeGuildKick:
+		var memberPayload protocol.GuildMemberRequestPayload
+		if err := msg.DecodePayload(&memberPayload); err != nil || memberPayload.PlayerID == "" {
+			a.sendErrorResponse("INVALID_GUILD_ACTION_PAYLOAD", "Guild action payload needs a playerId.")
+			return
+		}
+		if msg.Type == protocol.MsgTypeGuildInvite {
+			op = func(queryCtx context.Context) (guildranks.Action, error) {
+				return service.Invite(queryCtx, playerID, memberPayload.PlayerID)
+			}
+		} else {
+			op = func(queryCtx context.Context) (guildranks.Action, error) {
+				return service.Kick(queryCtx, playerID, memberPayload.PlayerID)
+			}
+		}
+	case protocol.MsgTypeGuildBankWithdraw:
+		var withdrawPayload protocol.GuildBankWithdrawRequestPayload
+		if err := msg.DecodePayload(&withdrawPayload); err != nil || withdrawPayload.Amount == 0 {
+			a.sendErrorResponse("INVALID_GUILD_ACTION_PAYLOAD", "Withdraw payload needs a positive amount.")
+			return
+		}
+		op = func(queryCtx context.Context) (guildranks.Action, error) {
+			return service.Withdraw(queryCtx, playerID, withdrawPayload.Amount)
+		}
+	case protocol.MsgTypeGuildRankAssign:
+		var assignPayload protocol.GuildRankAssignRequestPayload
+		if err := msg.DecodePayload(&assignPayload); err != nil || assignPayload.PlayerID == "" || assignPayload.Rank == "" {
+			a.sendErrorResponse("INVALID_GUILD_ACTION_PAYLOAD", "Assign payload needs a playerId and a rank.")
+			return
+		}
+		op = func(queryCtx context.Context) (guildranks.Action, error) {
+			return service.Assign(queryCtx, playerID, assignPayload.PlayerID, assignPayload.Rank)
+		}
+	}
+	a.runGuildAction(ctx, msg.Type, op)
+}
+
+// runGuildAction runs op for the client message action, which is answered
+// with GUILD_ACTION once the unsigned transaction is built.
+func (a *PlayerSessionActor) runGuildAction(ctx actor.Context, action string, op func(context.Context) (guildranks.Action, error)) {
+	// Building the transaction queries the chain, so it runs off the actor.
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.metrics.suiRequestStarted(action)
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), guildActionTimeout)
+		defer cancel()
+		result, err := op(queryCtx)
+		root.Send(self, &guildActionResult{action: action, result: result, err: err})
+	}()
+	a.awaitReply(action)
+}
+
+func (a *PlayerSessionActor) handleGuildActionResult(ctx actor.Context, msg *guildActionResult) {
+	if msg.err != nil {
+		a.sendGuildRankError(ctx, msg.action, msg.err)
+		return
+	}
+	r := msg.result
+	a.sendResponse(protocol.MsgTypeGuildAction, protocol.GuildActionPayload{
+		Action:    r.Kind,
+		GuildID:   r.GuildID,
+		PlayerID:  r.TargetID,
+		Rank:      r.Rank,
+		Amount:    r.Amount,
+		Remaining: r.Remaining,
+		TxBytes:   r.TxBytes,
+	})
+}
+
+// checkGuildRanks returns the rank service if the player may use it, and
+// tells them why not otherwise.
+func (a *PlayerSessionActor) checkGuildRanks() *guildranks.Service {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return nil
+	}
+	if a.services.GuildRanks == nil {
+		a.sendErrorResponse("GUILD_RANKS_DISABLED", "Guild ranks are not enabled on this server.")
+		return nil
+	}
+	return a.services.GuildRanks
+}
+
+func (a *PlayerSessionActor) sendGuildRankError(ctx actor.Context, action string, err error) {
+	code := ""
+	switch {
+	case errors.Is(err, guildranks.ErrNoGuild):
+		code = "NOT_IN_GUILD"
+	case errors.Is(err, guildranks.ErrNotLeader):
+		code = "NOT_GUILD_LEADER"
+	case errors.Is(err, guildranks.ErrNotPermitted), errors.Is(err, guildranks.ErrLeaderExempt):
+		code = "GUILD_RANK_FORBIDS"
+	case errors.Is(err, guildranks.ErrInvalid):
+		code = "INVALID_GUILD_RANK"
+	case errors.Is(err, guildranks.ErrNoRank):
+		code = "GUILD_RANK_NOT_FOUND"
+	case errors.Is(err, guildranks.ErrTooMany):
+		code = "TOO_MANY_GUILD_RANKS"
+	case errors.Is(err, guildranks.ErrNotMember), errors.Is(err, guildranks.ErrInGuild):
+		code = "INVALID_GUILD_MEMBER"
+	case errors.Is(err, guildranks.ErrOverLimit):
+		code = "GUILD_BANK_LIMIT"
+	case errors.Is(err, guildranks.ErrNoWallet):
+		code = "WALLET_NOT_LINKED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, action, err)
+		a.sendErrorResponse("GUILD_RANKS_UNAVAILABLE", "Guild ranks are unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, err.Error())
+}
+
+func guildRanksPayload(m guildranks.Matrix) protocol.GuildRanksPayload {
+	payload := protocol.GuildRanksPayload{GuildID: m.GuildID, Ranks: make([]protocol.GuildRankPayload, 0, len(m.Ranks)), Members: m.Members}
+	for _, r := range m.Ranks {
+		perms := make([]string, 0, len(r.Permissions))
+		for _, p := range r.Permissions {
+			perms = append(perms, string(p))
+		}
+		payload.Ranks = append(payload.Ranks, protocol.GuildRankPayload{Name: r.Name, Permissions: perms, WithdrawPerDay: r.WithdrawPerDay, OnChainRank: r.OnChainRank})
+	}
+	return payload
+}
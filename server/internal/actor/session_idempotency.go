@@ -0,0 +1,125 @@
+package actor
+
+import (
+	"encoding/json"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/idempotency"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// idempotentCommands are the mutating commands whose idempotency keys are
+// honoured. Keys on other messages are ignored.
+var idempotentCommands = map[string]bool{
+	protocol.MsgTypeShopTransaction:   true,
+	protocol.MsgTypeCraftStart:        true,
+	protocol.MsgTypeTradePropose:      true,
+	protocol.MsgTypeTradeRespond:      true,
+	protocol.MsgTypeTradeDeposited:    true,
+	protocol.MsgTypeGiftSend:          true,
+	protocol.MsgTypeGiftRespond:       true,
+	protocol.MsgTypeGiftSent:          true,
+	protocol.MsgTypeOrderPlace:        true,
+	protocol.MsgTypeOrderFunded:       true,
+	protocol.MsgTypeOrderCancel:       true,
+	protocol.MsgTypeBattlePassClaim:   true,
+	protocol.MsgTypeBattlePassUnlock:  true,
+	protocol.MsgTypeCreateRoomRequest: true,
+}
+
+// beginIdempotent claims the idempotency key of msg. It answers a retry
+// with the original result and returns false; otherwise the next reply the
+// session sends is recorded as the command's result.
+func (a *PlayerSessionActor) beginIdempotent(msg protocol.ClientServerMessage) bool {
+	if msg.IdempotencyKey == "" || a.services.Idempotency == nil || !idempotentCommands[msg.Type] {
+		return true
+	}
+	if len(msg.IdempotencyKey) > protocol.MaxIdempotencyKeyLength {
+		a.sendErrorResponse("INVALID_IDEMPOTENCY_KEY", "Idempotency keys are at most 128 bytes.")
+		return false
+	}
+	result, done, pending := a.services.Idempotency.Begin(a.playerID, msg.IdempotencyKey)
+	switch {
+	case done && result.Type != "":
+		utils.LogInfof("PlayerSessionActor %s: Answered a retried %s with its original %s.", a.playerID, msg.Type, result.Type)
+		a.sendResponse(result.Type, result.Payload)
+		return false
+	case done:
+		a.sendErrorResponse("DUPLICATE_REQUEST", "This request was already processed.")
+		return false
+	case pending:
+		a.sendErrorResponse("REQUEST_IN_PROGRESS", "This request is still being processed.")
+		return false
+	}
+	a.replyKey = msg.IdempotencyKey
+	return true
+}
+
+// endIdempotent finishes the synchronous handling of a keyed command. If it
+// sent no reply and started nothing that will, it was answered with nothing.
+func (a *PlayerSessionActor) endIdempotent() {
+	if a.replyKey == "" {
+		return
+	}
+	a.services.Idempotency.Complete(a.playerID, a.replyKey, idempotency.Result{})
+	a.replyKey = ""
+}
+
+// awaitReply is called by handlers that answer action asynchronously: the
+// keyed command's result is the reply sent when its result message arrives.
+func (a *PlayerSessionActor) awaitReply(action string) {
+	if a.replyKey == "" {
+		return
+	}
+	if a.awaitingReplies == nil {
+		a.awaitingReplies = make(map[string][]string)
+	}
+	a.awaitingReplies[action] = append(a.awaitingReplies[action], a.replyKey)
+	a.replyKey = ""
+}
+
+// resumeIdempotent picks up the key of the oldest keyed command waiting on
+// a result message of action, so the reply it triggers is recorded.
+func (a *PlayerSessionActor) resumeIdempotent(action string) bool {
+	keys := a.awaitingReplies[action]
+	if len(keys) == 0 {
+		return false
+	}
+	a.replyKey, a.awaitingReplies[action] = keys[0], keys[1:]
+	return true
+}
+
+// recordReply stores a reply as the result of the command being answered.
+func (a *PlayerSessionActor) recordReply(msgType string, payload interface{}) {
+	key := a.replyKey
+	a.replyKey = ""
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return // sendResponse reports it
+	}
+	a.services.Idempotency.Complete(a.playerID, key, idempotency.Result{Type: msgType, Payload: body})
+}
+
+// resultAction returns the command an asynchronous result message answers.
+func resultAction(msg interface{}) (string, bool) {
+	switch r := msg.(type) {
+	case *shopPremiumResult:
+		return protocol.MsgTypeShopTransaction, true
+	case *craftingResult:
+		return r.action, true
+	case *battlePassResult:
+		return r.action, true
+	case *tradeResult:
+		return r.action, true
+	case *giftResult:
+		return r.action, true
+	case *orderResult:
+		return r.action, true
+	case *guildActionResult:
+		return r.action, true
+	case *messages.CreateRoomResponse:
+		return protocol.MsgTypeCreateRoomRequest, true
+	}
+	return "", false
+}
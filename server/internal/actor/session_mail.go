@@ -0,0 +1,61 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+)
+
+// connectMail registers this session to be told about new mail.
+func (a *PlayerSessionActor) connectMail(ctx actor.Context) {
+	if a.services.Mail == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Mail.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+// handleMailRequest answers MAIL_LIST_REQUEST, MAIL_READ and MAIL_DELETE with the player's mailbox.
+func (a *PlayerSessionActor) handleMailRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Mail == nil {
+		a.sendErrorResponse("MAIL_DISABLED", "Mail is not enabled on this server.")
+		return
+	}
+	if msg.Type != protocol.MsgTypeMailListRequest {
+		var idsPayload protocol.MailIDsPayload
+		if err := msg.DecodePayload(&idsPayload); err != nil || len(idsPayload.IDs) == 0 {
+			a.sendErrorResponse("INVALID_MAIL_PAYLOAD", "Mail payload needs a non-empty ids list.")
+			return
+		}
+		if msg.Type == protocol.MsgTypeMailRead {
+			a.services.Mail.MarkRead(a.playerID, idsPayload.IDs)
+		} else {
+			a.services.Mail.Delete(a.playerID, idsPayload.IDs)
+		}
+	}
+	messages, unread := a.services.Mail.Inbox(a.playerID)
+	payload := protocol.MailListPayload{Messages: make([]protocol.MailMessagePayload, 0, len(messages)), Unread: unread}
+	for _, m := range messages {
+		payload.Messages = append(payload.Messages, mailMessagePayload(m))
+	}
+	a.sendResponse(protocol.MsgTypeMailList, payload)
+}
+
+func mailMessagePayload(m mail.Message) protocol.MailMessagePayload {
+	return protocol.MailMessagePayload{
+		ID:      m.ID,
+		From:    m.From,
+		Kind:    m.Kind,
+		Subject: m.Subject,
+		Body:    m.Body,
+		Ref:     m.Ref,
+		SentAt:  m.SentAt.UnixMilli(),
+		Read:    m.Read,
+	}
+}
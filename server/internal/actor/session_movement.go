@@ -0,0 +1,57 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// handleMovementMessage passes the player's moves, movement abilities and
+// projectiles to their room, which checks them against its map.
+func (a *PlayerSessionActor) handleMovementMessage(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.roomPID == nil {
+		a.sendErrorResponse("NOT_IN_A_ROOM", "Join a room before moving.")
+		return
+	}
+
+	switch msg.Type {
+	case protocol.MsgTypeMove:
+		var move protocol.MovePayload
+		if err := msg.DecodePayload(&move); err != nil {
+			a.sendErrorResponse("INVALID_MOVE_PAYLOAD", "Move payload needs x and y.")
+			return
+		}
+		ctx.Send(a.roomPID, &messages.MovePlayer{PlayerID: a.playerID, X: move.X, Y: move.Y})
+
+	case protocol.MsgTypeUseMovementAbility:
+		var use protocol.UseMovementAbilityPayload
+		if err := msg.DecodePayload(&use); err != nil || use.AbilityID == "" {
+			a.sendErrorResponse("INVALID_MOVEMENT_ABILITY_PAYLOAD", "Movement ability payload needs abilityId, x and y.")
+			return
+		}
+		ctx.Send(a.roomPID, &messages.UseMovementAbility{PlayerID: a.playerID, AbilityID: use.AbilityID, X: use.X, Y: use.Y})
+
+	case protocol.MsgTypeFireProjectile:
+		var fire protocol.FireProjectilePayload
+		if err := msg.DecodePayload(&fire); err != nil || fire.ProjectileID == "" {
+			a.sendErrorResponse("INVALID_PROJECTILE_PAYLOAD", "Projectile payload needs projectileId, x and y.")
+			return
+		}
+		ctx.Send(a.roomPID, &messages.FireProjectile{PlayerID: a.playerID, ProjectileID: fire.ProjectileID, X: fire.X, Y: fire.Y})
+	}
+}
+
+func (a *PlayerSessionActor) sendMovementAbilityResult(msg *messages.MovementAbilityResult) {
+	a.sendResponse(protocol.MsgTypeMovementAbilityResult, protocol.MovementAbilityResultPayload{
+		AbilityID:  msg.AbilityID,
+		Success:    msg.Success,
+		Message:    msg.Error,
+		X:          msg.X,
+		Y:          msg.Y,
+		CooldownMs: msg.Cooldown.Milliseconds(),
+	})
+}
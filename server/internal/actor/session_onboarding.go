@@ -0,0 +1,72 @@
+package actor
+
+import (
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/onboarding"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// beginTutorial loads the player's tutorial progress after authentication and
+// pushes the current step, if any.
+func (a *PlayerSessionActor) beginTutorial() {
+	if a.services.Onboarding == nil {
+		return
+	}
+	if prompt := a.services.Onboarding.Begin(a.playerID); prompt != nil {
+		a.sendTutorialStep(prompt, nil)
+	}
+}
+
+// checkTutorialGate rejects a client message whose tutorial prerequisites are
+// not complete. Allowed messages are recorded as tutorial events.
+func (a *PlayerSessionActor) checkTutorialGate(msg protocol.ClientServerMessage) bool {
+	if a.services.Onboarding == nil {
+		return true
+	}
+	var action protocol.PlayerActionPayload
+	if msg.Type == protocol.MsgTypePlayerAction {
+		msg.DecodePayload(&action) // Player actions are gated per action type
+	}
+	event := onboarding.ClientEvent(msg.Type, action.ActionType)
+	if allowed, required := a.services.Onboarding.Allowed(a.playerID, event); !allowed {
+		utils.LogInfof("Player %s: %s blocked until tutorial step %s is complete.", a.playerID, event, required.StepID)
+		a.sendErrorResponse("TUTORIAL_INCOMPLETE", "Complete the tutorial step \""+required.Prompt+"\" first.")
+		a.sendTutorialStep(required, nil)
+		return false
+	}
+	a.recordTutorialEvent(event)
+	return true
+}
+
+// recordTutorialEvent advances the tutorial and pushes the next step when a step completes.
+func (a *PlayerSessionActor) recordTutorialEvent(event string) {
+	if a.services.Onboarding == nil {
+		return
+	}
+	completed, next := a.services.Onboarding.Record(a.playerID, event)
+	if len(completed) == 0 {
+		return
+	}
+	for i, stepID := range completed {
+		a.services.Events.Publish(events.TopicTutorialStepCompleted, events.TutorialStepCompleted{
+			PlayerID: a.playerID,
+			StepID:   stepID,
+			Finished: next == nil && i == len(completed)-1,
+		})
+	}
+	a.sendTutorialStep(next, completed)
+}
+
+// sendTutorialStep pushes a step prompt; a nil prompt tells the client the tutorial is finished.
+func (a *PlayerSessionActor) sendTutorialStep(prompt *onboarding.Prompt, completed []string) {
+	payload := protocol.TutorialStepPayload{CompletedStepIDs: completed, Finished: prompt == nil}
+	if prompt != nil {
+		payload.StepID = prompt.StepID
+		payload.Prompt = prompt.Prompt
+		payload.Hint = prompt.Hint
+		payload.StepIndex = prompt.Index
+		payload.TotalSteps = prompt.Total
+	}
+	a.sendResponse(protocol.MsgTypeTutorialStep, payload)
+}
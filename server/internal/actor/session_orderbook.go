@@ -0,0 +1,194 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/orderbook"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// orderTimeout bounds building an order's deposit or checking it.
+const orderTimeout = 10 * time.Second
+
+// orderResult carries the outcome of an order operation back from its
+// goroutine. Successes reach the player as *orderbook.Update.
+type orderResult struct {
+	action string // Client message type being answered
+	err    error
+}
+
+// connectOrders registers this session for order updates and replays the
+// player's unfinished orders.
+func (a *PlayerSessionActor) connectOrders(ctx actor.Context) {
+	if a.services.OrderBook == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.OrderBook.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+	for _, o := range a.services.OrderBook.Orders(a.playerID) {
+		a.sendOrderUpdate(o, nil)
+	}
+}
+
+// handleOrderRequest answers ORDER_PLACE, ORDER_FUNDED, ORDER_CANCEL and
+// ORDER_BOOK_REQUEST.
+func (a *PlayerSessionActor) handleOrderRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	book := a.services.OrderBook
+	if book == nil {
+		a.sendErrorResponse("ORDER_BOOK_DISABLED", "The order book is not enabled on this server.")
+		return
+	}
+	playerID := a.playerID
+	switch msg.Type {
+	case protocol.MsgTypeOrderPlace:
+		var placePayload protocol.OrderPlaceRequestPayload
+		if err := msg.DecodePayload(&placePayload); err != nil {
+			a.sendErrorResponse("INVALID_ORDER_PAYLOAD", "Order payload is malformed.")
+			return
+		}
+		ttl := time.Duration(placePayload.TTLSeconds) * time.Second
+		a.runOrder(ctx, msg.Type, func(queryCtx context.Context) error {
+			_, err := book.Place(queryCtx, playerID, orderbook.Side(placePayload.Side), placePayload.Price, placePayload.Quantity, ttl)
+			return err
+		})
+	case protocol.MsgTypeOrderFunded:
+		var fundedPayload protocol.OrderFundedRequestPayload
+		if err := msg.DecodePayload(&fundedPayload); err != nil || fundedPayload.OrderID == "" || fundedPayload.TxDigest == "" {
+			a.sendErrorResponse("INVALID_ORDER_PAYLOAD", "Order funded payload needs an orderId and a txDigest.")
+			return
+		}
+		a.runOrder(ctx, msg.Type, func(queryCtx context.Context) error {
+			_, err := book.Funded(queryCtx, playerID, fundedPayload.OrderID, fundedPayload.TxDigest)
+			return err
+		})
+	case protocol.MsgTypeOrderCancel:
+		var cancelPayload protocol.OrderCancelRequestPayload
+		if err := msg.DecodePayload(&cancelPayload); err != nil || cancelPayload.OrderID == "" {
+			a.sendErrorResponse("INVALID_ORDER_PAYLOAD", "Order cancel payload needs an orderId.")
+			return
+		}
+		if _, err := book.Cancel(playerID, cancelPayload.OrderID); err != nil {
+			a.handleOrderResult(ctx, &orderResult{action: msg.Type, err: err})
+		}
+	case protocol.MsgTypeOrderBookRequest:
+		var bookPayload protocol.OrderBookRequestPayload
+		if err := msg.DecodePayload(&bookPayload); err != nil {
+			a.sendErrorResponse("INVALID_ORDER_PAYLOAD", "Order book payload is malformed.")
+			return
+		}
+		a.sendResponse(protocol.MsgTypeOrderBook, a.orderBookPayload(book.Depth(bookPayload.Levels), book.Orders(playerID)))
+	}
+}
+
+// runOrder runs an order operation off the actor, since it queries the chain.
+func (a *PlayerSessionActor) runOrder(ctx actor.Context, action string, op func(context.Context) error) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.metrics.suiRequestStarted(action)
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), orderTimeout)
+		defer cancel()
+		root.Send(self, &orderResult{action: action, err: op(queryCtx)})
+	}()
+	a.awaitReply(action)
+}
+
+func (a *PlayerSessionActor) handleOrderResult(ctx actor.Context, result *orderResult) {
+	if result.err == nil {
+		return // The player gets an ORDER_UPDATE
+	}
+	code := ""
+	switch {
+	case errors.Is(result.err, orderbook.ErrInvalidOrder):
+		code = "INVALID_ORDER"
+	case errors.Is(result.err, orderbook.ErrUnknownOrder), errors.Is(result.err, orderbook.ErrNotYourOrder):
+		code = "UNKNOWN_ORDER"
+	case errors.Is(result.err, orderbook.ErrTooManyOrders):
+		code = "TOO_MANY_ORDERS"
+	case errors.Is(result.err, orderbook.ErrWrongState):
+		code = "ORDER_CLOSED"
+	case errors.Is(result.err, orderbook.ErrDepositUsed), errors.Is(result.err, orderbook.ErrDepositUnverified):
+		code = "ORDER_DEPOSIT_UNVERIFIED"
+	case errors.Is(result.err, accountlink.ErrNotLinked):
+		code = "WALLET_NOT_LINKED"
+	case errors.Is(result.err, parental.ErrSpendLimit):
+		code = "SPEND_LIMIT_REACHED"
+	case isTransactionFailure(result.err):
+		utils.LogWarnf("[%s] Player %s: %s failed on chain: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		code = "TRANSACTION_FAILED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		a.sendErrorResponse("ORDER_BOOK_UNAVAILABLE", "The order book is unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, playerErrorMessage(result.err))
+}
+
+func (a *PlayerSessionActor) sendOrderUpdate(o orderbook.Order, fill *orderbook.Fill) {
+	payload := protocol.OrderUpdatePayload{Order: a.orderPayload(o)}
+	if fill != nil {
+		payload.Fill = &protocol.OrderFillPayload{
+			FillID:   fill.ID,
+			Price:    fill.Price,
+			Quantity: fill.Quantity,
+			At:       fill.At.UnixMilli(),
+			TxDigest: fill.TxDigest,
+		}
+	}
+	a.sendResponse(protocol.MsgTypeOrderUpdate, payload)
+}
+
+func (a *PlayerSessionActor) orderPayload(o orderbook.Order) protocol.OrderPayload {
+	payload := protocol.OrderPayload{
+		OrderID:   o.ID,
+		Side:      string(o.Side),
+		Price:     o.Price,
+		Quantity:  o.Quantity,
+		Filled:    o.Filled,
+		CoinType:  a.services.OrderBook.CoinType(o.Side),
+		Deposit:   o.Deposit,
+		Refunded:  o.Refunded,
+		Status:    string(o.Status),
+		TxBytes:   o.TxBytes,
+		RefundTx:  o.RefundTx,
+		PlacedAt:  o.PlacedAt.UnixMilli(),
+		ExpiresAt: o.ExpiresAt.UnixMilli(),
+	}
+	if o.Status == orderbook.StatusAwaitingDeposit {
+		payload.Deadline = o.Deadline.UnixMilli()
+	}
+	return payload
+}
+
+func (a *PlayerSessionActor) orderBookPayload(depth orderbook.Depth, orders []orderbook.Order) protocol.OrderBookPayload {
+	payload := protocol.OrderBookPayload{
+		TokenType: a.services.OrderBook.CoinType(orderbook.Sell),
+		Bids:      orderBookLevels(depth.Bids),
+		Asks:      orderBookLevels(depth.Asks),
+		LastPrice: depth.LastPrice,
+		Orders:    make([]protocol.OrderPayload, 0, len(orders)),
+	}
+	for _, o := range orders {
+		payload.Orders = append(payload.Orders, a.orderPayload(o))
+	}
+	return payload
+}
+
+func orderBookLevels(levels []orderbook.Level) []protocol.OrderBookLevelPayload {
+	payload := make([]protocol.OrderBookLevelPayload, 0, len(levels))
+	for _, level := range levels {
+		payload = append(payload, protocol.OrderBookLevelPayload{Price: level.Price, Quantity: level.Quantity, Orders: level.Orders})
+	}
+	return payload
+}
@@ -0,0 +1,163 @@
+package actor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// parentalCheck is the session's parental controls check. Like the AFK
+// check, it must not reset the receive timeout.
+type parentalCheck struct{}
+
+func (*parentalCheck) NotInfluenceReceiveTimeout() {}
+
+// admitParental checks, at sign-in, whether parental controls let the player
+// play now.
+func (a *PlayerSessionActor) admitParental() error {
+	return a.services.Parental.Admit(a.playerID)
+}
+
+// parentalErrorCode is the error code of a refusal by parental controls.
+func parentalErrorCode(err error) string {
+	switch {
+	case errors.Is(err, parental.ErrCurfew):
+		return "CURFEW"
+	case errors.Is(err, parental.ErrPlaytimeUsed):
+		return "PLAYTIME_LIMIT_REACHED"
+	case errors.Is(err, parental.ErrChatRestricted):
+		return "CHAT_RESTRICTED"
+	case errors.Is(err, parental.ErrSpendLimit):
+		return "SPEND_LIMIT_REACHED"
+	}
+	return "PARENTAL_CONTROLS"
+}
+
+// startParentalControls starts counting playtime once the player has
+// authenticated, and tells restricted players where they stand.
+func (a *PlayerSessionActor) startParentalControls(ctx actor.Context) {
+	if a.services.Parental == nil {
+		return
+	}
+	a.parentalSince = a.services.Timers.Now()
+	a.checkParental(ctx, true)
+}
+
+// stopParentalControls counts the rest of the session's playtime when it ends.
+func (a *PlayerSessionActor) stopParentalControls() {
+	if a.parentalSince.IsZero() {
+		return
+	}
+	a.countPlaytime()
+	a.parentalSince = time.Time{}
+	a.parentalTimer.Stop()
+	a.parentalTimer = nil
+}
+
+func (a *PlayerSessionActor) countPlaytime() {
+	now := a.services.Timers.Now()
+	a.services.Parental.AddPlaytime(a.playerID, now.Sub(a.parentalSince))
+	a.parentalSince = now
+}
+
+func (a *PlayerSessionActor) handleParentalCheck(ctx actor.Context) {
+	if a.parentalSince.IsZero() {
+		return // The session ended while the check was in flight
+	}
+	a.countPlaytime()
+	a.checkParental(ctx, false)
+}
+
+// checkParental ends the session once the player may not play any longer,
+// warns them as that time approaches, and schedules the next check. announce
+// sends restricted players their status even without a warning.
+func (a *PlayerSessionActor) checkParental(ctx actor.Context, announce bool) {
+	service := a.services.Parental
+	status := service.Status(a.playerID)
+	left, limit := status.TimeLeft()
+	if left == 0 {
+		err := parental.ErrPlaytimeUsed
+		if limit == parental.LimitCurfew {
+			err = parental.ErrCurfew
+		}
+		utils.LogInfof("[%s] Player %s: Ending the session for parental controls (%s).", ctx.Self().Id, a.playerID, limit)
+		a.terminate(ctx, &messages.TerminateSession{Code: parentalErrorCode(err), Reason: err.Error() + "."})
+		return
+	}
+
+	warning := ""
+	if threshold := service.Warning(left); left > 0 && threshold > 0 {
+		if a.parentalWarned == 0 || threshold < a.parentalWarned {
+			a.parentalWarned, warning = threshold, limit
+		}
+	} else {
+		a.parentalWarned = 0
+	}
+	if warning != "" || announce && status.Restricted {
+		payload := parentalStatusPayload(status)
+		if warning != "" {
+			payload.Warning, payload.TimeLeft = warning, int64(left.Seconds())
+		}
+		a.sendResponse(protocol.MsgTypeParentalStatus, payload)
+	}
+
+	next := service.CheckInterval()
+	if left > 0 && left < next {
+		next = left
+	}
+	a.parentalTimer = a.services.Timers.After(a.actorSystem.Root, ctx.Self(), next, &parentalCheck{})
+}
+
+// mayChat tells the player if parental controls restrict their chat.
+func (a *PlayerSessionActor) mayChat() bool {
+	if a.services.Parental.MayChat(a.playerID) {
+		return true
+	}
+	a.sendErrorResponse(parentalErrorCode(parental.ErrChatRestricted), "Chat is restricted by parental controls.")
+	return false
+}
+
+func (a *PlayerSessionActor) handleParentalStatusRequest(ctx actor.Context) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Parental == nil {
+		a.sendResponse(protocol.MsgTypeParentalStatus, protocol.ParentalStatusPayload{PlaytimeLeft: -1, CurfewStartsIn: -1, SpendLeft: -1, ServerTime: serverTime()})
+		return
+	}
+	a.sendResponse(protocol.MsgTypeParentalStatus, parentalStatusPayload(a.services.Parental.Status(a.playerID)))
+}
+
+func parentalStatusPayload(status parental.Status) protocol.ParentalStatusPayload {
+	c := status.Controls
+	payload := protocol.ParentalStatusPayload{
+		Restricted:     status.Restricted,
+		PlaytimeLeft:   wholeSeconds(status.PlaytimeLeft),
+		CurfewStartsIn: wholeSeconds(status.CurfewIn),
+		TimeZone:       c.TimeZone,
+		ChatRestricted: c.ChatRestricted,
+		SpendLeft:      status.SpendLeft,
+		ServerTime:     serverTime(),
+	}
+	if c.Curfew != nil {
+		payload.Curfew = &protocol.ParentalCurfewPayload{Start: c.Curfew.Start, End: c.Curfew.End}
+		if status.InCurfew {
+			payload.CurfewStartsIn = 0
+		}
+	}
+	return payload
+}
+
+// wholeSeconds is d in seconds, or -1 for no limit.
+func wholeSeconds(d time.Duration) int64 {
+	if d < 0 {
+		return -1
+	}
+	return int64(d.Seconds())
+}
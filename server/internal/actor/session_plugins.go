@@ -0,0 +1,50 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+)
+
+// pluginReply is a plugin's message to the player. It goes through the
+// session's mailbox, so plugins may answer from any goroutine.
+type pluginReply struct {
+	msgType string
+	payload interface{}
+	errCode string // Set for an error; errMsg is its message
+	errMsg  string
+}
+
+// pluginSession is the plugins.Session of a player's session actor.
+type pluginSession struct {
+	playerID string
+	root     *actor.RootContext
+	self     *actor.PID
+}
+
+func (s pluginSession) PlayerID() string { return s.playerID }
+
+func (s pluginSession) Send(msgType string, payload interface{}) {
+	s.root.Send(s.self, &pluginReply{msgType: msgType, payload: payload})
+}
+
+func (s pluginSession) SendError(code, message string) {
+	s.root.Send(s.self, &pluginReply{errCode: code, errMsg: message})
+}
+
+// handlePluginMessage passes a client message to the plugin handling its type.
+func (a *PlayerSessionActor) handlePluginMessage(ctx actor.Context, handler plugins.MessageHandler, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	handler(pluginSession{playerID: a.playerID, root: a.actorSystem.Root, self: ctx.Self()}, msg.Payload)
+}
+
+func (a *PlayerSessionActor) handlePluginReply(reply *pluginReply) {
+	if reply.errCode != "" {
+		a.sendErrorResponse(reply.errCode, reply.errMsg)
+		return
+	}
+	a.sendResponse(reply.msgType, reply.payload)
+}
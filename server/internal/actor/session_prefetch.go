@@ -0,0 +1,38 @@
+package actor
+
+import (
+	"context"
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// prefetchTimeout bounds the wait for the login prefetch, on top of the
+// service's own wait, should the service be configured to wait longer.
+const prefetchTimeout = 3 * time.Second
+
+// prefetchLogin reads the player's balances, profile object and item NFTs
+// from the chain, waiting briefly for them, and says what is ready for
+// AUTH_RESPONSE. It returns nil if the prefetch is off.
+func (a *PlayerSessionActor) prefetchLogin() *protocol.LoginReadyPayload {
+	if a.services.Prefetch == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+	defer cancel()
+	summary := a.services.Prefetch.Prefetch(ctx, a.playerID)
+	ready := &protocol.LoginReadyPayload{
+		Address: summary.Snapshot.Address,
+		Ready:   summary.Ready,
+		Pending: summary.Pending,
+		Failed:  summary.Failed,
+		Items:   summary.Snapshot.Items,
+	}
+	for _, balance := range summary.Snapshot.Balances {
+		ready.Balances = append(ready.Balances, protocol.CoinBalance{CoinType: balance.CoinType, Total: balance.Total})
+	}
+	if summary.Snapshot.Profile != nil {
+		ready.ProfileID = summary.Snapshot.Profile.ObjectID
+	}
+	return ready
+}
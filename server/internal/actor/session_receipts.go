@@ -0,0 +1,28 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/receipts"
+)
+
+// connectReceipts registers this session to be sent the player's transaction receipts.
+func (a *PlayerSessionActor) connectReceipts(ctx actor.Context) {
+	if a.services.Receipts == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Receipts.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+func txReceiptPayload(r *receipts.Receipt) protocol.TxReceiptPayload {
+	return protocol.TxReceiptPayload{
+		Kind:    r.Kind,
+		Digest:  r.Digest,
+		Status:  r.Status,
+		Summary: r.Summary,
+		At:      r.At.UnixMilli(),
+	}
+}
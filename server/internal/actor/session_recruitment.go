@@ -0,0 +1,199 @@
+package actor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/recruitment"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleRecruitmentRequest answers GUILD_RECRUITMENT_REQUEST,
+// GUILD_RECRUITMENT_POST, GUILD_RECRUITMENT_CLOSE and GUILD_APPLICATION_REVIEW
+// with the recruiter's board. Accepting an application also starts the guild
+// invite of the applicant when guild ranks are on.
+func (a *PlayerSessionActor) handleRecruitmentRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	service := a.checkRecruitment()
+	if service == nil {
+		return
+	}
+	var board recruitment.Board
+	var err error
+	invite := ""
+	switch msg.Type {
+	case protocol.MsgTypeGuildRecruitmentRequest:
+		board, err = service.Board(a.playerID)
+	case protocol.MsgTypeGuildRecruitmentPost:
+		var postPayload protocol.GuildRecruitmentPostRequestPayload
+		if err := msg.DecodePayload(&postPayload); err != nil {
+			a.sendErrorResponse("INVALID_RECRUITMENT_PAYLOAD", "Recruitment payload is malformed.")
+			return
+		}
+		board, err = service.Post(a.playerID, recruitment.Listing{
+			Description:  postPayload.Description,
+			Requirements: postPayload.Requirements,
+			Tags:         postPayload.Tags,
+		})
+	case protocol.MsgTypeGuildRecruitmentClose:
+		board, err = service.Close(a.playerID)
+	case protocol.MsgTypeGuildApplicationReview:
+		var reviewPayload protocol.GuildApplicationReviewRequestPayload
+		if err := msg.DecodePayload(&reviewPayload); err != nil || reviewPayload.ApplicationID == "" {
+			a.sendErrorResponse("INVALID_RECRUITMENT_PAYLOAD", "Review payload needs an applicationId.")
+			return
+		}
+		var decided recruitment.Application
+		board, decided, err = service.Review(a.playerID, reviewPayload.ApplicationID, reviewPayload.Accept, reviewPayload.Note)
+		if err == nil && decided.Status == recruitment.StatusAccepted {
+			invite = decided.PlayerID
+		}
+	}
+	if err != nil {
+		a.sendRecruitmentError(ctx, msg.Type, err)
+		return
+	}
+	a.sendResponse(protocol.MsgTypeGuildRecruitment, guildRecruitmentPayload(board))
+	if ranks := a.services.GuildRanks; invite != "" && ranks != nil {
+		playerID := a.playerID
+		a.runGuildAction(ctx, protocol.MsgTypeGuildInvite, func(queryCtx context.Context) (guildranks.Action, error) {
+			return ranks.Invite(queryCtx, playerID, invite)
+		})
+	}
+}
+
+// handleApplicationRequest answers GUILD_RECRUITMENT_SEARCH with the
+// listings found, and GUILD_APPLY, GUILD_APPLICATION_WITHDRAW and
+// GUILD_APPLICATIONS_REQUEST with the player's applications.
+func (a *PlayerSessionActor) handleApplicationRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	service := a.checkRecruitment()
+	if service == nil {
+		return
+	}
+	var applications []recruitment.Application
+	var err error
+	switch msg.Type {
+	case protocol.MsgTypeGuildRecruitmentSearch:
+		var searchPayload protocol.GuildRecruitmentSearchRequestPayload
+		if err := msg.DecodePayload(&searchPayload); err != nil {
+			a.sendErrorResponse("INVALID_RECRUITMENT_PAYLOAD", "Search payload is malformed.")
+			return
+		}
+		listings := service.Search(searchPayload.Query, searchPayload.Tag, searchPayload.Limit)
+		payload := protocol.GuildRecruitmentListingsPayload{Listings: make([]protocol.RecruitmentListingPayload, len(listings))}
+		for i, l := range listings {
+			payload.Listings[i] = recruitmentListingPayload(l)
+		}
+		a.sendResponse(protocol.MsgTypeGuildRecruitmentListings, payload)
+		return
+	case protocol.MsgTypeGuildApply:
+		var applyPayload protocol.GuildApplyRequestPayload
+		if err := msg.DecodePayload(&applyPayload); err != nil || applyPayload.GuildID == "" {
+			a.sendErrorResponse("INVALID_RECRUITMENT_PAYLOAD", "Application payload needs a guildId.")
+			return
+		}
+		applications, err = service.Apply(a.playerID, applyPayload.GuildID, applyPayload.Message)
+	case protocol.MsgTypeGuildApplicationWithdraw:
+		var withdrawPayload protocol.GuildApplicationWithdrawRequestPayload
+		if err := msg.DecodePayload(&withdrawPayload); err != nil || withdrawPayload.ApplicationID == "" {
+			a.sendErrorResponse("INVALID_RECRUITMENT_PAYLOAD", "Withdraw payload needs an applicationId.")
+			return
+		}
+		applications, err = service.Withdraw(a.playerID, withdrawPayload.ApplicationID)
+	case protocol.MsgTypeGuildApplicationsRequest:
+		applications = service.Applications(a.playerID)
+	}
+	if err != nil {
+		a.sendRecruitmentError(ctx, msg.Type, err)
+		return
+	}
+	payload := protocol.GuildApplicationsPayload{Applications: make([]protocol.GuildApplicationPayload, len(applications))}
+	for i, app := range applications {
+		payload.Applications[i] = guildApplicationPayload(app)
+	}
+	a.sendResponse(protocol.MsgTypeGuildApplications, payload)
+}
+
+// checkRecruitment returns the recruitment service if the player may use it,
+// and tells them why not otherwise.
+func (a *PlayerSessionActor) checkRecruitment() *recruitment.Service {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return nil
+	}
+	if a.services.Recruitment == nil {
+		a.sendErrorResponse("RECRUITMENT_DISABLED", "Guild recruitment is not enabled on this server.")
+		return nil
+	}
+	return a.services.Recruitment
+}
+
+func (a *PlayerSessionActor) sendRecruitmentError(ctx actor.Context, action string, err error) {
+	code := ""
+	switch {
+	case errors.Is(err, recruitment.ErrNoGuild):
+		code = "NOT_IN_GUILD"
+	case errors.Is(err, recruitment.ErrNotPermitted):
+		code = "GUILD_RANK_FORBIDS"
+	case errors.Is(err, recruitment.ErrInvalid):
+		code = "INVALID_RECRUITMENT"
+	case errors.Is(err, recruitment.ErrUnknownGuild):
+		code = "INVALID_GUILD"
+	case errors.Is(err, recruitment.ErrNotRecruiting):
+		code = "GUILD_NOT_RECRUITING"
+	case errors.Is(err, recruitment.ErrInGuild):
+		code = "ALREADY_IN_GUILD"
+	case errors.Is(err, recruitment.ErrApplied), errors.Is(err, recruitment.ErrTooMany):
+		code = "APPLICATION_LIMIT"
+	case errors.Is(err, recruitment.ErrNoApplication):
+		code = "APPLICATION_NOT_FOUND"
+	case errors.Is(err, recruitment.ErrDecided):
+		code = "APPLICATION_DECIDED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, action, err)
+		a.sendErrorResponse("RECRUITMENT_UNAVAILABLE", "Guild recruitment is unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, err.Error())
+}
+
+func guildRecruitmentPayload(b recruitment.Board) protocol.GuildRecruitmentPayload {
+	payload := protocol.GuildRecruitmentPayload{GuildID: b.GuildID, Applications: make([]protocol.GuildApplicationPayload, len(b.Applications))}
+	if b.Listing != nil {
+		listing := recruitmentListingPayload(*b.Listing)
+		payload.Listing = &listing
+	}
+	for i, app := range b.Applications {
+		payload.Applications[i] = guildApplicationPayload(app)
+	}
+	return payload
+}
+
+func recruitmentListingPayload(l recruitment.Listing) protocol.RecruitmentListingPayload {
+	return protocol.RecruitmentListingPayload{
+		GuildID:      l.GuildID,
+		GuildName:    l.GuildName,
+		Members:      l.Members,
+		Description:  l.Description,
+		Requirements: l.Requirements,
+		Tags:         l.Tags,
+		UpdatedAt:    l.UpdatedAt.UnixMilli(),
+		ExpiresAt:    l.ExpiresAt.UnixMilli(),
+	}
+}
+
+func guildApplicationPayload(app recruitment.Application) protocol.GuildApplicationPayload {
+	return protocol.GuildApplicationPayload{
+		ApplicationID: app.ID,
+		GuildID:       app.GuildID,
+		PlayerID:      app.PlayerID,
+		Message:       app.Message,
+		Status:        app.Status,
+		AppliedAt:     app.AppliedAt.UnixMilli(),
+		DecidedBy:     app.DecidedBy,
+		Note:          app.Note,
+		ExpiresAt:     app.ExpiresAt.UnixMilli(),
+	}
+}
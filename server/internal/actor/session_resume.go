@@ -0,0 +1,64 @@
+package actor
+
+import (
+	"errors"
+	"net"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/resume"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// redeemResumeToken authenticates msg with its resume token, which is used
+// up; the session keeps the token that replaces it.
+func (a *PlayerSessionActor) redeemResumeToken(msg *messages.AuthenticatePlayer) error {
+	if a.services.Resume == nil {
+		return errors.New("session resumption is disabled")
+	}
+	playerID, grant, err := a.services.Resume.Redeem(msg.ResumeToken, a.resumeBinding(msg))
+	if err != nil {
+		return err
+	}
+	a.playerID, a.resumeGrant = playerID, grant
+	return nil
+}
+
+// grantResumeToken returns the token the client can resume the session
+// with: the replacement of the one it resumed with, or else a new one.
+func (a *PlayerSessionActor) grantResumeToken(msg *messages.AuthenticatePlayer) resume.Grant {
+	if a.resumeGrant.Token != "" || a.services.Resume == nil {
+		return a.resumeGrant
+	}
+	grant, err := a.services.Resume.Issue(a.playerID, a.resumeBinding(msg))
+	if err != nil {
+		utils.LogErrorf("PlayerSessionActor %s: Failed to issue a resume token: %v", a.playerID, err)
+		return resume.Grant{}
+	}
+	a.resumeGrant = grant
+	return grant
+}
+
+// resumeBinding is what a resume token of this connection is tied to.
+func (a *PlayerSessionActor) resumeBinding(msg *messages.AuthenticatePlayer) resume.Binding {
+	ip, _, err := net.SplitHostPort(a.metrics.remoteAddr)
+	if err != nil {
+		ip = a.metrics.remoteAddr
+	}
+	return resume.Binding{IP: ip, Device: msg.DeviceID}
+}
+
+func resumeNotAfter(grant resume.Grant) int64 {
+	if grant.NotAfter.IsZero() {
+		return 0
+	}
+	return grant.NotAfter.UnixMilli()
+}
+
+// handleLogout revokes the session's resume token and closes the connection.
+func (a *PlayerSessionActor) handleLogout(ctx actor.Context) {
+	utils.LogInfof("[%s] Player %s logged out.", ctx.Self().Id, a.playerID)
+	a.services.Resume.Revoke(a.resumeGrant.Token)
+	a.resumeGrant = resume.Grant{}
+	ctx.Send(ctx.Self(), &messages.ClientDisconnected{Reason: "logged out"})
+}
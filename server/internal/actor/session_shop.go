@@ -0,0 +1,170 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// shopPremiumResult carries a premium purchase back from the goroutine that
+// verified its payment.
+type shopPremiumResult struct {
+	request protocol.ShopTransactionRequestPayload
+	receipt shop.Receipt
+	err     error
+}
+
+// handleShopBrowse replies with a shop's items, stock and the player's limits.
+func (a *PlayerSessionActor) handleShopBrowse(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Shop == nil {
+		a.sendErrorResponse("SHOPS_DISABLED", "Shops are not enabled on this server.")
+		return
+	}
+	var browsePayload protocol.ShopBrowseRequestPayload
+	if err := msg.DecodePayload(&browsePayload); err != nil || browsePayload.ShopID == "" {
+		a.sendErrorResponse("INVALID_SHOP_PAYLOAD", "Shop browse payload needs a shopId.")
+		return
+	}
+	view, err := a.services.Shop.Browse(a.playerID, browsePayload.ShopID)
+	if err != nil {
+		a.sendErrorResponse(shopErrorCode(err), "Could not open the shop: "+err.Error()+".")
+		return
+	}
+	payload := protocol.ShopInventoryPayload{
+		ShopID:           view.ShopID,
+		Name:             view.Name,
+		Coins:            view.Coins,
+		PremiumRecipient: view.PremiumRecipient,
+		Items:            make([]protocol.ShopItemPayload, 0, len(view.Items)),
+		ServerTime:       serverTime(),
+	}
+	for _, item := range view.Items {
+		itemPayload := protocol.ShopItemPayload{
+			ItemID:        item.ItemID,
+			Name:          item.Name,
+			BuyPrice:      item.BuyPrice,
+			SellPrice:     item.SellPrice,
+			Stock:         item.Remaining,
+			PurchasesLeft: item.PurchasesLeft,
+		}
+		if !item.NextRestock.IsZero() {
+			itemPayload.NextRestock = item.NextRestock.UnixMilli()
+		}
+		if !item.LimitResetsAt.IsZero() {
+			itemPayload.LimitResetsAt = item.LimitResetsAt.UnixMilli()
+		}
+		if item.Premium != nil {
+			itemPayload.Premium = &protocol.ShopPremiumPricePayload{Price: item.Premium.Price, CoinType: item.Premium.CoinType}
+		}
+		payload.Items = append(payload.Items, itemPayload)
+	}
+	a.sendResponse(protocol.MsgTypeShopInventory, payload)
+}
+
+// handleShopTransaction buys or sells an item. Premium purchases verify their
+// payment on-chain, so they run off the actor and report back with a shopPremiumResult.
+func (a *PlayerSessionActor) handleShopTransaction(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.services.Shop == nil {
+		a.sendErrorResponse("SHOPS_DISABLED", "Shops are not enabled on this server.")
+		return
+	}
+	var txPayload protocol.ShopTransactionRequestPayload
+	if err := msg.DecodePayload(&txPayload); err != nil || txPayload.ShopID == "" || txPayload.ItemID == "" {
+		a.sendErrorResponse("INVALID_SHOP_PAYLOAD", "Shop transaction payload needs a shopId and itemId.")
+		return
+	}
+	if txPayload.Quantity == 0 {
+		txPayload.Quantity = 1
+	}
+	var receipt shop.Receipt
+	var err error
+	switch {
+	case txPayload.Action == protocol.ShopActionBuy && txPayload.PaymentTxDigest != "":
+		self, root, shops, accounts, playerID := ctx.Self(), a.actorSystem.Root, a.services.Shop, a.services.Accounts, a.playerID
+		a.metrics.suiRequestStarted(protocol.MsgTypeShopTransaction)
+		go func() {
+			// The payment must come from, and the item is minted to, the player's
+			// primary linked address.
+			address := playerID
+			if accounts != nil {
+				queryCtx, cancel := context.WithTimeout(context.Background(), walletLinkTimeout)
+				linked, err := accounts.Address(queryCtx, playerID)
+				cancel()
+				if err != nil {
+					root.Send(self, &shopPremiumResult{request: txPayload, err: fmt.Errorf("premium items need a linked Sui address: %w", err)})
+					return
+				}
+				address = linked
+			}
+			receipt, err := shops.BuyPremium(playerID, address, txPayload.ShopID, txPayload.ItemID, txPayload.PaymentTxDigest)
+			root.Send(self, &shopPremiumResult{request: txPayload, receipt: receipt, err: err})
+		}()
+		a.awaitReply(protocol.MsgTypeShopTransaction)
+		return
+	case txPayload.Action == protocol.ShopActionBuy:
+		receipt, err = a.buyWithinSpendLimit(txPayload)
+	case txPayload.Action == protocol.ShopActionSell:
+		receipt, err = a.services.Shop.Sell(a.playerID, txPayload.ShopID, txPayload.ItemID, txPayload.Quantity)
+	default:
+		a.sendErrorResponse("INVALID_SHOP_PAYLOAD", "Shop action must be buy or sell.")
+		return
+	}
+	a.sendShopTransactionResult(ctx, txPayload, receipt, err)
+}
+
+// buyWithinSpendLimit buys for coins, holding the cost against the player's
+// daily spending limit until the purchase goes through.
+func (a *PlayerSessionActor) buyWithinSpendLimit(request protocol.ShopTransactionRequestPayload) (shop.Receipt, error) {
+	cost, err := a.services.Shop.Quote(request.ShopID, request.ItemID, request.Quantity)
+	if err == nil {
+		if err := a.services.Parental.Spend(a.playerID, cost); err != nil {
+			return shop.Receipt{}, err
+		}
+	}
+	receipt, err := a.services.Shop.Buy(a.playerID, request.ShopID, request.ItemID, request.Quantity)
+	if err != nil {
+		a.services.Parental.Release(a.playerID, cost)
+	}
+	return receipt, err
+}
+
+func (a *PlayerSessionActor) sendShopTransactionResult(ctx actor.Context, request protocol.ShopTransactionRequestPayload, receipt shop.Receipt, err error) {
+	result := protocol.ShopTransactionResultPayload{
+		ShopID:  request.ShopID,
+		ItemID:  request.ItemID,
+		Action:  request.Action,
+		Success: err == nil,
+	}
+	if err != nil {
+		utils.LogInfof("[%s] Player %s: Shop %s of %s at %s failed: %v", ctx.Self().Id, a.playerID, request.Action, request.ItemID, request.ShopID, err)
+		result.Message = playerErrorMessage(err)
+	} else {
+		result.Quantity = receipt.Quantity
+		result.Price = receipt.Price
+		result.Coins = receipt.Coins
+		result.Premium = receipt.Premium
+	}
+	a.sendResponse(protocol.MsgTypeShopTransactionResult, result)
+}
+
+func shopErrorCode(err error) string {
+	switch {
+	case errors.Is(err, shop.ErrUnknownShop):
+		return "UNKNOWN_SHOP"
+	default:
+		return "SHOP_UNAVAILABLE"
+	}
+}
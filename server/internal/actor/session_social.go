@@ -0,0 +1,58 @@
+package actor
+
+import (
+	"regexp"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+)
+
+// socialName is the form of emote, ping and quick reply names.
+var socialName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// handleSocialAction checks an emote, map ping or quick reply and hands it to
+// the player's room to broadcast.
+func (a *PlayerSessionActor) handleSocialAction(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.roomPID == nil {
+		a.sendErrorResponse("NOT_IN_A_ROOM", "You are not in a room. Join a room first.")
+		return
+	}
+	var socialPayload protocol.SocialActionPayload
+	if err := msg.DecodePayload(&socialPayload); err != nil || !socialName.MatchString(socialPayload.Name) {
+		a.sendErrorResponse("INVALID_SOCIAL_PAYLOAD", "Social action needs a kind and a name of lowercase letters, digits and underscores.")
+		return
+	}
+	switch socialPayload.Kind {
+	case protocol.SocialEmote, protocol.SocialQuickReply:
+		socialPayload.X, socialPayload.Y = 0, 0
+	case protocol.SocialPing:
+		if !(geometry.Vec{X: socialPayload.X, Y: socialPayload.Y}).Finite() {
+			a.sendErrorResponse("INVALID_SOCIAL_PAYLOAD", "A ping needs a point on the map.")
+			return
+		}
+	default:
+		a.sendErrorResponse("INVALID_SOCIAL_PAYLOAD", "Social action kind must be emote, ping or quick.")
+		return
+	}
+	if a.social == nil {
+		a.social = ratelimit.NewBucket(a.services.Social)
+	}
+	if !a.social.Allow(a.services.Timers.Now()) {
+		a.sendErrorResponse("SOCIAL_THROTTLED", "You are sending social actions too fast.")
+		return
+	}
+	ctx.Send(a.roomPID, &messages.SocialAction{
+		PlayerID: a.playerID,
+		Kind:     socialPayload.Kind,
+		Name:     socialPayload.Name,
+		X:        socialPayload.X,
+		Y:        socialPayload.Y,
+	})
+}
@@ -0,0 +1,26 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+)
+
+// connectStats registers this session to be sent the player's stat changes.
+func (a *PlayerSessionActor) connectStats(ctx actor.Context) {
+	if a.services.Stats == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.services.Stats.Connect(a.playerID, func(note interface{}) {
+		root.Send(self, note)
+	})
+}
+
+func statDeltaPayload(d *stats.Delta) protocol.StatDeltaPayload {
+	changes := make([]protocol.StatChangePayload, 0, len(d.Changes))
+	for _, change := range d.Changes {
+		changes = append(changes, protocol.StatChangePayload{PlayerID: change.PlayerID, Stats: change.Stats})
+	}
+	return protocol.StatDeltaPayload{Changes: changes}
+}
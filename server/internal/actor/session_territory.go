@@ -0,0 +1,49 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleClaimZone forwards a guild zone claim to the WorldManagerActor, which
+// verifies the claim transaction and replies with a ClaimZoneResponse.
+func (a *PlayerSessionActor) handleClaimZone(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	var claimPayload protocol.ClaimZoneRequestPayload
+	if err := msg.DecodePayload(&claimPayload); err != nil || claimPayload.ZoneID == "" || claimPayload.TxDigest == "" {
+		a.sendErrorResponse("INVALID_CLAIM_ZONE_PAYLOAD", "Claim zone payload needs zoneId and txDigest.")
+		return
+	}
+	if a.worldManagerPID == nil {
+		a.sendErrorResponse("WORLD_MANAGER_UNAVAILABLE", "World manager is not available.")
+		return
+	}
+	utils.LogInfof("[%s] Player %s: Claiming zone %s with tx %s.", ctx.Self().Id, a.playerID, claimPayload.ZoneID, claimPayload.TxDigest)
+	ctx.Send(a.worldManagerPID, &messages.ClaimZoneRequest{
+		PlayerID:     a.playerID,
+		ZoneID:       claimPayload.ZoneID,
+		TxDigest:     claimPayload.TxDigest,
+		RequesterPID: ctx.Self(),
+	})
+}
+
+func (a *PlayerSessionActor) sendClaimZoneResponse(msg *messages.ClaimZoneResponse) {
+	payload := protocol.ClaimZoneResponsePayload{
+		ZoneID:  msg.ZoneID,
+		GuildID: msg.GuildID,
+		Success: msg.Error == "",
+		Granted: msg.Granted,
+		Message: msg.Error,
+	}
+	if !msg.SiegeStartsAt.IsZero() {
+		payload.SiegeStartsAt = msg.SiegeStartsAt.UnixMilli()
+		payload.SiegeEndsAt = msg.SiegeEndsAt.UnixMilli()
+		payload.Message = "The zone is held by another guild. A siege has been scheduled."
+	}
+	a.sendResponse(protocol.MsgTypeClaimZoneResponse, payload)
+}
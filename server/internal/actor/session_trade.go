@@ -0,0 +1,187 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// tradeTimeout bounds a proposal's address lookups or a deposit check against the escrow.
+const tradeTimeout = 10 * time.Second
+
+// tradeResult carries the outcome of a proposal or deposit check back from
+// its goroutine. Successes reach both players as *trade.Update.
+type tradeResult struct {
+	action string // Client message type being answered
+	err    error
+}
+
+// connectTrades registers this session for trade updates and replays the
+// player's unfinished trades.
+func (a *PlayerSessionActor) connectTrades(ctx actor.Context) {
+	if a.services.Trades == nil {
+		return
+	}
+	self, root := ctx.Self(), a.actorSystem.Root
+	region := ""
+	if a.world != nil {
+		region = a.world.Region
+	}
+	a.services.Trades.Connect(a.playerID, region, func(note interface{}) {
+		root.Send(self, note)
+	})
+	for _, t := range a.services.Trades.Trades(a.playerID) {
+		a.sendTradeUpdate(t)
+	}
+}
+
+// handleTradePropose offers another player a trade.
+func (a *PlayerSessionActor) handleTradePropose(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkTrades() {
+		return
+	}
+	var proposePayload protocol.TradeProposeRequestPayload
+	if err := msg.DecodePayload(&proposePayload); err != nil || proposePayload.PlayerID == "" {
+		a.sendErrorResponse("INVALID_TRADE_PAYLOAD", "Trade proposal payload needs a playerId.")
+		return
+	}
+	playerID, trades := a.playerID, a.services.Trades
+	give := trade.Offer{ItemIDs: proposePayload.Give.ItemIDs, Coins: proposePayload.Give.Coins}
+	want := trade.Offer{ItemIDs: proposePayload.Want.ItemIDs, Coins: proposePayload.Want.Coins}
+	a.runTrade(ctx, protocol.MsgTypeTradePropose, func(queryCtx context.Context) error {
+		_, err := trades.Propose(queryCtx, playerID, proposePayload.PlayerID, give, want)
+		return err
+	})
+}
+
+// handleTradeRespond accepts or declines a trade.
+func (a *PlayerSessionActor) handleTradeRespond(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkTrades() {
+		return
+	}
+	var respondPayload protocol.TradeRespondRequestPayload
+	if err := msg.DecodePayload(&respondPayload); err != nil || respondPayload.TradeID == "" {
+		a.sendErrorResponse("INVALID_TRADE_PAYLOAD", "Trade respond payload needs a tradeId.")
+		return
+	}
+	var err error
+	switch respondPayload.Action {
+	case protocol.TradeActionAccept:
+		_, err = a.services.Trades.Accept(a.playerID, respondPayload.TradeID)
+	case protocol.TradeActionDecline:
+		_, err = a.services.Trades.Cancel(a.playerID, respondPayload.TradeID)
+	default:
+		a.sendErrorResponse("INVALID_TRADE_PAYLOAD", "Trade action must be accept or decline.")
+		return
+	}
+	if err != nil {
+		a.handleTradeResult(ctx, &tradeResult{action: protocol.MsgTypeTradeRespond, err: err})
+	}
+}
+
+// handleTradeDeposited checks the escrow after the player deposited their side.
+func (a *PlayerSessionActor) handleTradeDeposited(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkTrades() {
+		return
+	}
+	var depositedPayload protocol.TradeDepositedRequestPayload
+	if err := msg.DecodePayload(&depositedPayload); err != nil || depositedPayload.TradeID == "" {
+		a.sendErrorResponse("INVALID_TRADE_PAYLOAD", "Trade deposited payload needs a tradeId.")
+		return
+	}
+	playerID, trades := a.playerID, a.services.Trades
+	a.runTrade(ctx, protocol.MsgTypeTradeDeposited, func(queryCtx context.Context) error {
+		_, err := trades.Deposited(queryCtx, playerID, depositedPayload.TradeID)
+		return err
+	})
+}
+
+func (a *PlayerSessionActor) checkTrades() bool {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return false
+	}
+	if a.services.Trades == nil {
+		a.sendErrorResponse("TRADES_DISABLED", "Trading is not enabled on this server.")
+		return false
+	}
+	return true
+}
+
+// runTrade runs a trade operation off the actor, since it may query the
+// database or the chain.
+func (a *PlayerSessionActor) runTrade(ctx actor.Context, action string, op func(context.Context) error) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	a.metrics.suiRequestStarted(action)
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), tradeTimeout)
+		defer cancel()
+		root.Send(self, &tradeResult{action: action, err: op(queryCtx)})
+	}()
+	a.awaitReply(action)
+}
+
+func (a *PlayerSessionActor) handleTradeResult(ctx actor.Context, result *tradeResult) {
+	if result.err == nil {
+		return // Both players get a TRADE_UPDATE
+	}
+	code := "TRADE_FAILED"
+	var claimErr *sui.ObjectClaimError
+	switch {
+	case errors.As(result.err, &claimErr) && claimErr.Code != sui.ObjectLookupFailed:
+		code = claimErr.Code
+	case errors.Is(result.err, trade.ErrSelfTrade), errors.Is(result.err, trade.ErrEmptyTrade),
+		errors.Is(result.err, trade.ErrTooManyItems), errors.Is(result.err, trade.ErrDuplicateItem):
+		code = "INVALID_TRADE_PAYLOAD"
+	case errors.Is(result.err, trade.ErrUnknownTrade), errors.Is(result.err, trade.ErrNotYourTrade):
+		code = "UNKNOWN_TRADE"
+	case errors.Is(result.err, trade.ErrDepositsIncomplete):
+		code = "TRADE_DEPOSITS_INCOMPLETE"
+	case errors.Is(result.err, trade.ErrFeeUnpaid):
+		code = "TRADE_FEE_UNPAID"
+	case errors.Is(result.err, accountlink.ErrNotLinked):
+		code = "WALLET_NOT_LINKED"
+	case errors.Is(result.err, reservation.ErrReserved):
+		code = "ITEM_RESERVED"
+	case errors.Is(result.err, parental.ErrSpendLimit):
+		code = "SPEND_LIMIT_REACHED"
+	case errors.Is(result.err, trade.ErrWrongState), errors.Is(result.err, trade.ErrEscrowUnavailable),
+		errors.Is(result.err, trade.ErrEscrowPaused):
+	case isTransactionFailure(result.err):
+		utils.LogWarnf("[%s] Player %s: %s failed on chain: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		code = "TRANSACTION_FAILED"
+	default:
+		utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+		a.sendErrorResponse("TRADES_UNAVAILABLE", "Trading is unavailable right now.")
+		return
+	}
+	a.sendErrorResponse(code, playerErrorMessage(result.err))
+}
+
+func (a *PlayerSessionActor) sendTradeUpdate(t trade.Trade) {
+	payload := protocol.TradeUpdatePayload{
+		TradeID:      t.ID,
+		Proposer:     t.Proposer,
+		Counterparty: t.Counterparty,
+		Give:         protocol.TradeOfferPayload{ItemIDs: t.Give.ItemIDs, Coins: t.Give.Coins},
+		Want:         protocol.TradeOfferPayload{ItemIDs: t.Want.ItemIDs, Coins: t.Want.Coins},
+		Value:        t.Value,
+		Escrowed:     t.Escrowed,
+		Status:       string(t.Status),
+		EscrowID:     t.EscrowID,
+		Fee:          t.FeeOf(a.playerID),
+	}
+	if !t.Status.Final() && !t.Deadline.IsZero() {
+		payload.Deadline = t.Deadline.UnixMilli()
+	}
+	a.sendResponse(protocol.MsgTypeTradeUpdate, payload)
+}
@@ -0,0 +1,102 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// roomTransfer is a server-driven move in progress.
+type roomTransfer struct {
+	request   *messages.TransferSession
+	requester *actor.PID // Answered with a TransferResult; nil if the move was sent
+	fromID    string
+	fromPID   *actor.PID
+}
+
+// handleTransferSession starts moving the player to another room. The room
+// manager's answer and the new room's are routed to the transfer while it is
+// in progress, so client joins are refused until it ends.
+func (a *PlayerSessionActor) handleTransferSession(ctx actor.Context, msg *messages.TransferSession) {
+	refuse := func(reason string) {
+		utils.LogInfof("[%s] Transfer of player %s to room %q refused: %s", ctx.Self().Id, a.playerID, msg.RoomID, reason)
+		if ctx.Sender() != nil {
+			ctx.Respond(&messages.TransferResult{FromRoomID: a.roomID, RoomID: msg.RoomID, Error: reason})
+		}
+	}
+	switch {
+	case !a.isAuthenticated():
+		refuse("the player has not authenticated")
+	case a.roomManagerPID == nil:
+		refuse("the room manager is not available")
+	case msg.RoomID == "":
+		refuse("no room given")
+	case msg.RoomID == a.roomID:
+		refuse("the player is already in that room")
+	case a.transfer != nil || a.pendingJoin != nil:
+		refuse("the player is already joining a room")
+	default:
+		a.transfer = &roomTransfer{request: msg, requester: ctx.Sender(), fromID: a.roomID, fromPID: a.roomPID}
+		ctx.Request(a.roomManagerPID, &messages.FindRoomRequest{Criteria: msg.RoomID, PlayerPID: ctx.Self()})
+	}
+}
+
+// continueTransfer joins the room the manager found for the transfer.
+func (a *PlayerSessionActor) continueTransfer(ctx actor.Context, found *messages.FindRoomResponse) {
+	if !found.Found || found.RoomPID == nil {
+		reason := found.Error
+		if reason == "" {
+			reason = "Room not found for the given criteria."
+		}
+		a.endTransfer(ctx, &messages.TransferResult{FromRoomID: a.transfer.fromID, RoomID: a.transfer.request.RoomID, Error: reason})
+		return
+	}
+	ctx.Request(found.RoomPID, &messages.JoinRoomRequest{
+		PlayerID:   a.playerID,
+		PlayerPID:  ctx.Self(),
+		Appearance: a.appearance,
+		Transfer:   true,
+	})
+}
+
+// finishTransfer leaves the old room once the new one has taken the player,
+// and sends the client the new room's snapshot.
+func (a *PlayerSessionActor) finishTransfer(ctx actor.Context, joined *messages.JoinRoomResponse) {
+	t := a.transfer
+	if !joined.Success {
+		a.endTransfer(ctx, &messages.TransferResult{FromRoomID: t.fromID, RoomID: joined.RoomID, Error: joined.Error})
+		return
+	}
+	if t.fromPID != nil {
+		ctx.Send(t.fromPID, &messages.LeaveRoomRequest{PlayerID: a.playerID, PlayerPID: ctx.Self()})
+	}
+	a.roomPID, a.roomID = joined.RoomPID, joined.RoomID
+	utils.LogInfof("[%s] Player %s transferred from room %q to %s (%s)", ctx.Self().Id, a.playerID, t.fromID, joined.RoomID, t.request.Reason)
+	a.enterChatZone(ctx, joined)
+	a.sendResponse(protocol.MsgTypeRoomTransfer, protocol.RoomTransferPayload{
+		FromRoomID: t.fromID,
+		RoomID:     joined.RoomID,
+		RoomName:   joined.RoomName,
+		MapID:      joined.MapID,
+		Reason:     t.request.Reason,
+		Players:    joined.CurrentPlayerIDs,
+		ServerTime: serverTime(),
+		State:      roomStatePayload(joined.State),
+	})
+	a.services.Events.Publish(events.TopicRoomJoined, events.RoomJoined{PlayerID: a.playerID, RoomID: joined.RoomID})
+	a.endTransfer(ctx, &messages.TransferResult{FromRoomID: t.fromID, RoomID: joined.RoomID, Success: true})
+}
+
+func (a *PlayerSessionActor) endTransfer(ctx actor.Context, result *messages.TransferResult) {
+	t := a.transfer
+	a.transfer = nil
+	if !result.Success {
+		utils.LogInfof("[%s] Transfer of player %s to room %q failed: %s", ctx.Self().Id, a.playerID, t.request.RoomID, result.Error)
+		a.sendErrorResponse("ROOM_TRANSFER_FAILED", "Could not move you to "+t.request.RoomID+": "+result.Error)
+	}
+	if t.requester != nil {
+		ctx.Send(t.requester, result)
+	}
+}
@@ -0,0 +1,79 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// handleVoiceMessage handles voice signaling and mute requests from the client.
+// Signals are validated here and relayed by the RoomActor, which checks that
+// both players are members of the room.
+func (a *PlayerSessionActor) handleVoiceMessage(ctx actor.Context, msg protocol.ClientServerMessage) {
+	actorID := ctx.Self().Id
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return
+	}
+	if a.roomPID == nil {
+		a.sendErrorResponse("NOT_IN_A_ROOM", "Join a room before using voice chat.")
+		return
+	}
+
+	switch msg.Type {
+	case protocol.MsgTypeVoiceOffer, protocol.MsgTypeVoiceAnswer:
+		var description protocol.VoiceSessionDescriptionPayload
+		if err := msg.DecodePayload(&description); err != nil || description.ToPlayerID == "" || description.SDP == "" {
+			a.sendErrorResponse("INVALID_VOICE_PAYLOAD", msg.Type+" requires toPlayerId and sdp.")
+			return
+		}
+		if len(description.SDP) > protocol.MaxVoiceSDPLength {
+			a.sendErrorResponse("VOICE_PAYLOAD_TOO_LARGE", "SDP exceeds the maximum allowed size.")
+			return
+		}
+		if !a.mayChat() {
+			return
+		}
+		description.FromPlayerID = a.playerID
+		a.relayVoiceSignal(ctx, msg.Type, description.ToPlayerID, description)
+
+	case protocol.MsgTypeVoiceICECandidate:
+		var candidate protocol.VoiceICECandidatePayload
+		if err := msg.DecodePayload(&candidate); err != nil || candidate.ToPlayerID == "" || candidate.Candidate == "" {
+			a.sendErrorResponse("INVALID_VOICE_PAYLOAD", msg.Type+" requires toPlayerId and candidate.")
+			return
+		}
+		if len(candidate.Candidate) > protocol.MaxVoiceCandidateLength || len(candidate.SDPMid) > protocol.MaxVoiceCandidateLength {
+			a.sendErrorResponse("VOICE_PAYLOAD_TOO_LARGE", "ICE candidate exceeds the maximum allowed size.")
+			return
+		}
+		if !a.mayChat() {
+			return
+		}
+		candidate.FromPlayerID = a.playerID
+		a.relayVoiceSignal(ctx, msg.Type, candidate.ToPlayerID, candidate)
+
+	case protocol.MsgTypeVoiceMute:
+		var mute protocol.VoiceMuteRequestPayload
+		if err := msg.DecodePayload(&mute); err != nil {
+			a.sendErrorResponse("INVALID_VOICE_PAYLOAD", "Voice mute payload is malformed.")
+			return
+		}
+		utils.LogDebugf("[%s] Player %s: voice mute request (target %q, muted %t)", actorID, a.playerID, mute.TargetPlayerID, mute.Muted)
+		ctx.Send(a.roomPID, &messages.SetVoiceMute{
+			PlayerID:       a.playerID,
+			TargetPlayerID: mute.TargetPlayerID,
+			Muted:          mute.Muted,
+		})
+	}
+}
+
+func (a *PlayerSessionActor) relayVoiceSignal(ctx actor.Context, msgType, toPlayerID string, payload interface{}) {
+	ctx.Request(a.roomPID, &messages.RelayVoiceSignal{
+		MsgType:      msgType,
+		FromPlayerID: a.playerID,
+		ToPlayerID:   toPlayerID,
+		Payload:      payload,
+	})
+}
@@ -0,0 +1,140 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// walletLinkTimeout bounds a link, unlink or links query.
+const walletLinkTimeout = 5 * time.Second
+
+// walletLinksResult carries the player's links back from the goroutine that
+// changed or queried them.
+type walletLinksResult struct {
+	action string // Client message type being answered
+	links  []accountlink.Link
+	err    error
+}
+
+// handleWalletLinkChallengeRequest issues the message the player's wallet signs
+// to link an address.
+func (a *PlayerSessionActor) handleWalletLinkChallengeRequest(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkWalletLinks() {
+		return
+	}
+	var challengePayload protocol.WalletLinkChallengeRequestPayload
+	if err := msg.DecodePayload(&challengePayload); err != nil || challengePayload.Address == "" {
+		a.sendErrorResponse("INVALID_WALLET_PAYLOAD", "Wallet link challenge payload needs an address.")
+		return
+	}
+	challenge, err := a.services.Accounts.Challenge(a.playerID, challengePayload.Address)
+	if err != nil {
+		a.sendErrorResponse("INVALID_WALLET_PAYLOAD", err.Error())
+		return
+	}
+	a.sendResponse(protocol.MsgTypeWalletLinkChallenge, protocol.WalletLinkChallengePayload{
+		Address:   challenge.Address,
+		Message:   challenge.Message,
+		ExpiresAt: challenge.ExpiresAt.UnixMilli(),
+	})
+}
+
+// handleLinkWallet verifies a signed challenge and links the address. The
+// result is reported back with a walletLinksResult.
+func (a *PlayerSessionActor) handleLinkWallet(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkWalletLinks() {
+		return
+	}
+	var linkPayload protocol.LinkWalletRequestPayload
+	if err := msg.DecodePayload(&linkPayload); err != nil || linkPayload.Address == "" || linkPayload.Signature == "" {
+		a.sendErrorResponse("INVALID_WALLET_PAYLOAD", "Link wallet payload needs an address and signature.")
+		return
+	}
+	playerID, accounts := a.playerID, a.services.Accounts
+	a.runWalletLinks(ctx, protocol.MsgTypeLinkWallet, func(queryCtx context.Context) ([]accountlink.Link, error) {
+		return accounts.Link(queryCtx, playerID, linkPayload.Address, linkPayload.Signature, linkPayload.Primary)
+	})
+}
+
+// handleUnlinkWallet removes one of the player's linked addresses.
+func (a *PlayerSessionActor) handleUnlinkWallet(ctx actor.Context, msg protocol.ClientServerMessage) {
+	if !a.checkWalletLinks() {
+		return
+	}
+	var unlinkPayload protocol.UnlinkWalletRequestPayload
+	if err := msg.DecodePayload(&unlinkPayload); err != nil || unlinkPayload.Address == "" {
+		a.sendErrorResponse("INVALID_WALLET_PAYLOAD", "Unlink wallet payload needs an address.")
+		return
+	}
+	playerID, accounts := a.playerID, a.services.Accounts
+	a.runWalletLinks(ctx, protocol.MsgTypeUnlinkWallet, func(queryCtx context.Context) ([]accountlink.Link, error) {
+		return accounts.Unlink(queryCtx, playerID, unlinkPayload.Address)
+	})
+}
+
+// handleWalletLinksRequest replies with the player's linked addresses.
+func (a *PlayerSessionActor) handleWalletLinksRequest(ctx actor.Context) {
+	if !a.checkWalletLinks() {
+		return
+	}
+	playerID, accounts := a.playerID, a.services.Accounts
+	a.runWalletLinks(ctx, protocol.MsgTypeWalletLinksRequest, func(queryCtx context.Context) ([]accountlink.Link, error) {
+		return accounts.Links(queryCtx, playerID)
+	})
+}
+
+func (a *PlayerSessionActor) checkWalletLinks() bool {
+	if !a.isAuthenticated() {
+		a.sendErrorResponse("NOT_AUTHENTICATED", "Please authenticate first.")
+		return false
+	}
+	if a.services.Accounts == nil {
+		a.sendErrorResponse("WALLET_LINKS_DISABLED", "Wallet linking is not enabled on this server.")
+		return false
+	}
+	return true
+}
+
+// runWalletLinks runs a store operation off the actor, since it may query the database.
+func (a *PlayerSessionActor) runWalletLinks(ctx actor.Context, action string, op func(context.Context) ([]accountlink.Link, error)) {
+	self, root := ctx.Self(), a.actorSystem.Root
+	go func() {
+		queryCtx, cancel := context.WithTimeout(context.Background(), walletLinkTimeout)
+		defer cancel()
+		links, err := op(queryCtx)
+		root.Send(self, &walletLinksResult{action: action, links: links, err: err})
+	}()
+}
+
+func (a *PlayerSessionActor) handleWalletLinksResult(ctx actor.Context, result *walletLinksResult) {
+	if result.err != nil {
+		code := "WALLET_LINK_FAILED"
+		switch {
+		case errors.Is(result.err, accountlink.ErrInvalidAddress), errors.Is(result.err, accountlink.ErrUnknownAddress):
+			code = "INVALID_WALLET_PAYLOAD"
+		case errors.Is(result.err, accountlink.ErrNoChallenge), errors.Is(result.err, accountlink.ErrBadSignature),
+			errors.Is(result.err, accountlink.ErrAddressTaken), errors.Is(result.err, accountlink.ErrTooManyAddresses):
+		default:
+			utils.LogErrorf("[%s] Player %s: %s failed: %v", ctx.Self().Id, a.playerID, result.action, result.err)
+			a.sendErrorResponse("WALLET_LINKS_UNAVAILABLE", "Wallet links are unavailable right now.")
+			return
+		}
+		a.sendErrorResponse(code, result.err.Error())
+		return
+	}
+	payload := protocol.WalletLinksPayload{Links: make([]protocol.WalletLink, 0, len(result.links))}
+	for _, link := range result.links {
+		payload.Links = append(payload.Links, protocol.WalletLink{
+			Address:  link.Address,
+			Primary:  link.Primary,
+			LinkedAt: link.LinkedAt.UnixMilli(),
+		})
+	}
+	a.sendResponse(protocol.MsgTypeWalletLinks, payload)
+}
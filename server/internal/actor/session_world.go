@@ -0,0 +1,32 @@
+package actor
+
+import (
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/utils"
+)
+
+// enterWorld points the session at the room and world managers of the world the
+// player picked at auth. The world was checked when the AUTH request arrived.
+func (a *PlayerSessionActor) enterWorld(ctx actor.Context, worldID string) {
+	if a.services.Worlds == nil {
+		return
+	}
+	world, err := a.services.Worlds.Lookup(worldID)
+	if err != nil {
+		utils.LogWarnf("[%s] Player %s: %v; staying on the session's default managers", ctx.Self().Id, a.playerID, err)
+		return
+	}
+	a.world = world
+	a.roomManagerPID = world.RoomManagerPID
+	a.worldManagerPID = world.WorldManagerPID
+	world.Joined()
+	utils.LogInfof("[%s] Player %s entered world %s", ctx.Self().Id, a.playerID, world.ID)
+}
+
+// worldID returns the ID of the world the player is in, or "" if the server has only its default managers.
+func (a *PlayerSessionActor) worldID() string {
+	if a.world == nil {
+		return ""
+	}
+	return a.world.ID
+}
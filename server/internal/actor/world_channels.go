@@ -0,0 +1,109 @@
+package actor
+
+import (
+	"errors"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chatchannels"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// channelHub returns the world's chat channels, creating them on first use.
+func (a *WorldManagerActor) channelHub() *chatchannels.Hub {
+	if a.channels == nil {
+		a.channels = chatchannels.NewHub(a.services.ChatChannels)
+	}
+	return a.channels
+}
+
+// connectToChannels puts a player who entered the world in their auto-joined
+// channels, including their guild's from the roster and their guild's
+// alliance's.
+func (a *WorldManagerActor) connectToChannels(playerID string) {
+	guild, _ := a.services.Guilds.GuildOf(playerID)
+	a.channelHub().Connect(playerID, guild.ID)
+	if alliance, ok := a.services.Diplomacy.AllianceOf(guild.ID); ok {
+		a.channelHub().SetAlliance(playerID, alliance.ID)
+	}
+}
+
+// moveAllianceChannels moves the online members of guilds that joined or left
+// an alliance to their new alliance channel.
+func (a *WorldManagerActor) moveAllianceChannels(guildIDs []string) {
+	for _, guildID := range guildIDs {
+		alliance, _ := a.services.Diplomacy.AllianceOf(guildID)
+		a.channelHub().SetGuildAlliance(guildID, alliance.ID)
+	}
+}
+
+func (a *WorldManagerActor) handleJoinChannel(ctx actor.Context, msg *messages.JoinChannel) {
+	if err := a.channelHub().Join(msg.PlayerID, msg.Channel); err != nil {
+		a.refuseChannel(ctx, msg.PlayerPID, msg.Channel, err)
+		return
+	}
+	a.sendChannelList(ctx, msg.PlayerID, msg.PlayerPID)
+}
+
+func (a *WorldManagerActor) handleLeaveChannel(ctx actor.Context, msg *messages.LeaveChannel) {
+	if err := a.channelHub().Leave(msg.PlayerID, msg.Channel); err != nil {
+		a.refuseChannel(ctx, msg.PlayerPID, msg.Channel, err)
+		return
+	}
+	a.sendChannelList(ctx, msg.PlayerID, msg.PlayerPID)
+}
+
+// handleSendChannelMessage delivers a chat line to every online member of the
+// sender's channel.
+func (a *WorldManagerActor) handleSendChannelMessage(ctx actor.Context, msg *messages.SendChannelMessage) {
+	scope, recipients, err := a.channelHub().Send(msg.PlayerID, msg.Channel)
+	if err != nil {
+		a.refuseChannel(ctx, msg.PlayerPID, msg.Channel, err)
+		return
+	}
+	delivered := &messages.ChannelMessage{
+		Channel:  msg.Channel,
+		Scope:    scope,
+		SenderID: msg.PlayerID,
+		Text:     msg.Text,
+		SentAt:   msg.SentAt,
+	}
+	byRegistry := make(map[int][]string)
+	for _, id := range recipients {
+		shard := registryShard(id)
+		byRegistry[shard] = append(byRegistry[shard], id)
+	}
+	for shard, ids := range byRegistry {
+		ctx.Send(a.registries[shard], &deliverToPlayers{PlayerIDs: ids, Message: delivered})
+	}
+}
+
+func (a *WorldManagerActor) sendChannelList(ctx actor.Context, playerID string, pid *actor.PID) {
+	infos := a.channelHub().List(playerID)
+	list := &messages.ChannelList{Channels: make([]messages.ChannelInfo, 0, len(infos))}
+	for _, info := range infos {
+		list.Channels = append(list.Channels, messages.ChannelInfo{
+			Channel:           info.Kind,
+			Scope:             info.Scope,
+			Joined:            info.Joined,
+			Members:           info.Members,
+			AutoJoin:          info.Policy.AutoJoin,
+			MessagesPerMinute: info.Policy.MessagesPerMinute,
+		})
+	}
+	ctx.Send(pid, list)
+}
+
+func (a *WorldManagerActor) refuseChannel(ctx actor.Context, pid *actor.PID, channel string, err error) {
+	code := "CHANNEL_UNAVAILABLE"
+	switch {
+	case errors.Is(err, chatchannels.ErrUnknownChannel):
+		code = "CHANNEL_UNKNOWN"
+	case errors.Is(err, chatchannels.ErrNotJoined):
+		code = "NOT_IN_CHANNEL"
+	case errors.Is(err, chatchannels.ErrThrottled):
+		code = "CHANNEL_THROTTLED"
+	}
+	utils.LogDebugf("[WorldManagerActor %s] Channel %q request refused: %v", ctx.Self().Id, channel, err)
+	ctx.Send(pid, &messages.ChannelRefused{Channel: channel, Code: code, Reason: err.Error()})
+}
@@ -2,10 +2,21 @@ package actor
 
 import (
 	// "log" // Replaced by utils.LogX
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
 	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/chatchannels"
+	"github.com/phuhao00/suigserver/server/internal/diplomacy"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/handlermetrics"
+	"github.com/phuhao00/suigserver/server/internal/msgaudit"
+	"github.com/phuhao00/suigserver/server/internal/quarantine"
+	"github.com/phuhao00/suigserver/server/internal/territory"
+	"github.com/phuhao00/suigserver/server/internal/timers"
 	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
 )
 
@@ -13,18 +24,33 @@ import (
 // such as global events, region management, or coordinating large-scale systems.
 // It also keeps track of currently active players in the world.
 type WorldManagerActor struct {
-	actorSystem   *actor.ActorSystem
-	activePlayers map[string]*actor.PID // Map PlayerID to PlayerSessionActor PID
-	mu            sync.RWMutex          // To protect concurrent access to activePlayers
+	actorSystem *actor.ActorSystem
+	registries  []*actor.PID  // Player registry shards, spawned on start; see PlayerRegistryPID
+	online      *atomic.Int64 // Players registered in every shard
+	services    WorldServices
+	siegeTimers map[string]*timers.Timer // ZoneID -> next siege start/end
+	channels    *chatchannels.Hub        // Chat channels and their members; created on first use
 	// e.g., references to RegionActors, game event schedules, etc.
 	// regionManagerPID *actor.PID // Example: PID for a RegionManagerActor
 }
 
+// WorldServices are optional world-level systems. Nil fields disable the feature.
+type WorldServices struct {
+	Territory     *territory.Registry     // Guild zone claims, buffs/taxes and sieges
+	ClaimVerifier territory.ClaimVerifier // Checks claim_territory transactions
+	Events        *events.Bus             // Receives territory and siege events
+	Timers        *timers.Scheduler       // Siege start and end; real time if nil
+	Guilds        *guilds.Roster          // Guild memberships behind the guild chat channels; no guild channels if nil
+	ChatChannels  chatchannels.Policies   // Join and throttling policies of the chat channels; the defaults if nil
+	Diplomacy     *diplomacy.Service      // Guild alliances behind the alliance chat channels; no alliance channels if nil
+}
+
 // NewWorldManagerActor creates a new WorldManagerActor.
 func NewWorldManagerActor(system *actor.ActorSystem) actor.Actor {
 	return &WorldManagerActor{
-		actorSystem:   system,
-		activePlayers: make(map[string]*actor.PID),
+		actorSystem: system,
+		online:      new(atomic.Int64),
+		siegeTimers: make(map[string]*timers.Timer),
 		// regionManagerPID: nil, // Initialize or discover later
 	}
 }
@@ -35,6 +61,14 @@ func (a *WorldManagerActor) Receive(ctx actor.Context) {
 	switch msg := ctx.Message().(type) {
 	case *actor.Started:
 		utils.LogInfof("[WorldManagerActor %s] Started.", actorID)
+		a.registries = make([]*actor.PID, PlayerRegistryShards)
+		for i := range a.registries {
+			pid, err := ctx.SpawnNamed(propsForPlayerRegistry(a.online), registryName(i))
+			if err != nil {
+				utils.LogErrorf("[WorldManagerActor %s] Could not spawn player registry %d: %v", actorID, i, err)
+			}
+			a.registries[i] = pid
+		}
 		// Initialization logic here, e.g., load world data, spawn region actors
 		// Example: Spawn a RegionManagerActor
 		// regionManagerProps := PropsForRegionManager(a.actorSystem)
@@ -48,7 +82,10 @@ func (a *WorldManagerActor) Receive(ctx actor.Context) {
 		// if a.regionManagerPID != nil {
 		// 	ctx.Stop(a.regionManagerPID)
 		// }
-		utils.LogInfof("[WorldManagerActor %s] Currently active players at shutdown: %d", actorID, len(a.activePlayers))
+		for _, timer := range a.siegeTimers {
+			timer.Stop()
+		}
+		utils.LogInfof("[WorldManagerActor %s] Currently active players at shutdown: %d", actorID, a.online.Load())
 
 	case *actor.Stopped:
 		utils.LogInfof("[WorldManagerActor %s] Stopped.", actorID)
@@ -59,6 +96,68 @@ func (a *WorldManagerActor) Receive(ctx actor.Context) {
 	case *messages.PlayerLeftWorld:
 		a.handlePlayerLeftWorld(ctx, msg)
 
+	case *messages.GetPlayerSession:
+		// Sent here rather than to PlayerRegistryPID; the registry answers the sender.
+		ctx.Forward(a.registryOf(msg.PlayerID))
+
+	case *messages.Ping:
+		// Health probe: answering proves the actor system is still dispatching messages.
+		ctx.Respond(&messages.Pong{Timestamp: msg.Timestamp, ResponseTime: time.Now().UnixMilli()})
+
+	case *messages.GetWorldStats:
+		stats := &messages.WorldStats{ActivePlayers: int(a.online.Load())}
+		if a.services.Territory != nil {
+			stats.ClaimedZones = len(a.services.Territory.Claims())
+		}
+		if a.channels != nil {
+			stats.PlayersByZone = a.channels.Zones()
+		}
+		ctx.Respond(stats)
+
+	case *messages.Announcement:
+		for _, registry := range a.registries {
+			ctx.Send(registry, msg)
+		}
+
+	case *messages.Whisper:
+		ctx.Forward(a.registryOf(msg.ToID))
+
+	case *messages.SetPlayerZone:
+		a.channelHub().SetZone(msg.PlayerID, msg.ZoneID)
+
+	case *messages.AllianceChanged:
+		a.moveAllianceChannels(msg.GuildIDs)
+
+	case *messages.JoinChannel:
+		a.handleJoinChannel(ctx, msg)
+
+	case *messages.LeaveChannel:
+		a.handleLeaveChannel(ctx, msg)
+
+	case *messages.ListChannels:
+		a.sendChannelList(ctx, msg.PlayerID, msg.PlayerPID)
+
+	case *messages.SendChannelMessage:
+		a.handleSendChannelMessage(ctx, msg)
+
+	case *messages.ClaimZoneRequest:
+		a.handleClaimZone(ctx, msg)
+
+	case *claimVerified:
+		a.applyVerifiedClaim(ctx, msg)
+
+	case *messages.GetZoneBenefits:
+		a.handleGetZoneBenefits(ctx, msg)
+
+	case *messages.ResolveSiege:
+		a.handleResolveSiege(ctx, msg)
+
+	case *siegeStarting:
+		a.handleSiegeStarting(ctx, msg)
+
+	case *siegeEnding:
+		a.handleSiegeEnding(ctx, msg)
+
 	case *messages.UpdateWorldState:
 		utils.LogInfof("[WorldManagerActor %s] Received UpdateWorldState with data: %+v", actorID, msg.Data)
 		// TODO: Handle world state updates from game logic or other systems.
@@ -78,23 +177,21 @@ func (a *WorldManagerActor) Receive(ctx actor.Context) {
 
 	default:
 		utils.LogWarnf("[WorldManagerActor %s] Received unknown message: %T %+v", actorID, msg, msg)
+		quarantine.ReportUnhandled(ctx, msg)
 	}
 }
 
+// handlePlayerEnteredWorld connects the player to the chat channels before
+// any later message of their session, and has their registry record them.
 func (a *WorldManagerActor) handlePlayerEnteredWorld(ctx actor.Context, msg *messages.PlayerEnteredWorld) {
 	actorID := ctx.Self().Id
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	if _, exists := a.activePlayers[msg.PlayerID]; exists {
-		utils.LogWarnf("[WorldManagerActor %s] Player %s (PID: %s) already marked as active. Ignoring duplicate PlayerEnteredWorld.",
-			actorID, msg.PlayerID, msg.PlayerPID.Id)
-		return
+	ctx.Forward(a.registryOf(msg.PlayerID))
+	if a.channelHub().Online(msg.PlayerID) {
+		return // The registry warns of the duplicate
 	}
 
-	a.activePlayers[msg.PlayerID] = msg.PlayerPID
-	utils.LogInfof("[WorldManagerActor %s] Player %s (PID: %s) entered world. Total active players: %d",
-		actorID, msg.PlayerID, msg.PlayerPID.Id, len(a.activePlayers))
+	a.connectToChannels(msg.PlayerID)
+	utils.LogInfof("[WorldManagerActor %s] Player %s (PID: %s) entered world.", actorID, msg.PlayerID, msg.PlayerPID.Id)
 
 	// TODO: Further logic for when a player enters the world:
 	// 1. Assign to a default region/zone or determine based on player's last location.
@@ -108,18 +205,13 @@ func (a *WorldManagerActor) handlePlayerEnteredWorld(ctx actor.Context, msg *mes
 
 func (a *WorldManagerActor) handlePlayerLeftWorld(ctx actor.Context, msg *messages.PlayerLeftWorld) {
 	actorID := ctx.Self().Id
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	if _, exists := a.activePlayers[msg.PlayerID]; !exists {
-		utils.LogWarnf("[WorldManagerActor %s] Player %s (PID: %s) not found in active players list. Ignoring PlayerLeftWorld.",
-			actorID, msg.PlayerID, msg.PlayerPID.Id)
-		return
+	ctx.Forward(a.registryOf(msg.PlayerID))
+	if !a.channelHub().Online(msg.PlayerID) {
+		return // The registry warns of the unknown player
 	}
 
-	delete(a.activePlayers, msg.PlayerID)
-	utils.LogInfof("[WorldManagerActor %s] Player %s (PID: %s) left world. Total active players: %d",
-		actorID, msg.PlayerID, msg.PlayerPID.Id, len(a.activePlayers))
+	a.channelHub().Disconnect(msg.PlayerID)
+	utils.LogInfof("[WorldManagerActor %s] Player %s (PID: %s) left world.", actorID, msg.PlayerID, msg.PlayerPID.Id)
 
 	// TODO: Further logic for when a player leaves the world:
 	// 1. Notify the player's current region/zone actor to remove them.
@@ -131,7 +223,22 @@ func (a *WorldManagerActor) handlePlayerLeftWorld(ctx actor.Context, msg *messag
 	utils.LogInfof("[WorldManagerActor %s] Placeholder: Notify region, save player %s world data, clean up resources.", actorID, msg.PlayerID)
 }
 
+// registryOf returns the registry shard holding playerID.
+func (a *WorldManagerActor) registryOf(playerID string) *actor.PID {
+	return a.registries[registryShard(playerID)]
+}
+
 // PropsForWorldManager creates actor.Props for WorldManagerActor.
 func PropsForWorldManager(system *actor.ActorSystem) *actor.Props {
-	return actor.PropsFromProducer(func() actor.Actor { return NewWorldManagerActor(system) })
+	return actor.PropsFromProducer(func() actor.Actor { return NewWorldManagerActor(system) }, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
+}
+
+// PropsForWorldManagerWithServices creates actor.Props for a WorldManagerActor
+// with optional world services such as guild territory.
+func PropsForWorldManagerWithServices(system *actor.ActorSystem, services WorldServices) *actor.Props {
+	return actor.PropsFromProducer(func() actor.Actor {
+		manager := NewWorldManagerActor(system).(*WorldManagerActor)
+		manager.services = services
+		return manager
+	}, actor.WithReceiverMiddleware(chaos.Receiver, quarantine.Guard, handlermetrics.Receiver), actor.WithSenderMiddleware(msgaudit.Sender))
 }
@@ -0,0 +1,102 @@
+package actor
+
+import (
+	"io"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+func playerSession(t testing.TB, root *actor.RootContext, world *actor.PID, playerID string) *actor.PID {
+	t.Helper()
+	res, err := root.RequestFuture(world, &messages.GetPlayerSession{PlayerID: playerID}, time.Second).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.(*messages.PlayerSessionLocation).SessionPID
+}
+
+func TestWorldManagerTracksOnlinePlayers(t *testing.T) {
+	system := actor.NewActorSystem()
+	world := system.Root.Spawn(PropsForWorldManager(system))
+	defer system.Root.Stop(world)
+
+	alice := actor.NewPID("local", "alice")
+	system.Root.Send(world, &messages.PlayerEnteredWorld{PlayerID: "alice", PlayerPID: alice})
+	system.Root.Send(world, &messages.PlayerEnteredWorld{PlayerID: "alice", PlayerPID: actor.NewPID("local", "other")})
+	if pid := playerSession(t, system.Root, world, "alice"); pid == nil || pid.Id != "alice" {
+		t.Fatalf("alice's session = %v", pid)
+	}
+	res, err := system.Root.RequestFuture(world, &messages.GetWorldStats{}, time.Second).Result()
+	if err != nil || res.(*messages.WorldStats).ActivePlayers != 1 {
+		t.Fatalf("stats = %+v, %v", res, err)
+	}
+
+	// The registry holding alice answers lookups without the world manager.
+	if pid := playerSession(t, system.Root, PlayerRegistryPID(world, "alice"), "alice"); pid == nil || pid.Id != "alice" {
+		t.Fatalf("alice's session from her registry = %v", pid)
+	}
+
+	system.Root.Send(world, &messages.PlayerLeftWorld{PlayerID: "alice", PlayerPID: alice})
+	if pid := playerSession(t, system.Root, world, "alice"); pid != nil {
+		t.Fatalf("alice is still online at %v", pid)
+	}
+}
+
+// BenchmarkWorldManagerPlayers measures players entering, leaving and being
+// looked up (whispers, session lookups) on a world of 50,000 online players,
+// sent from many goroutines the way sessions do. One in ten operations is an
+// enter or a leave, which go through the world manager. Lookups go through
+// the world manager too in "world", as they did before the registry was
+// sharded, and straight to the player's registry in "registries".
+func BenchmarkWorldManagerPlayers(b *testing.B) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	for _, bc := range []struct {
+		name   string
+		lookup func(world *actor.PID, playerID string) *actor.PID
+	}{
+		{"world", func(world *actor.PID, playerID string) *actor.PID { return world }},
+		{"registries", PlayerRegistryPID},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			const online = 50000
+			system := actor.NewActorSystem()
+			world := system.Root.Spawn(PropsForWorldManager(system))
+			defer system.Root.Stop(world)
+			ids := make([]string, online)
+			pids := make([]*actor.PID, online)
+			for i := range ids {
+				ids[i] = "player-" + strconv.Itoa(i)
+				pids[i] = actor.NewPID("local", ids[i])
+				system.Root.Send(world, &messages.PlayerEnteredWorld{PlayerID: ids[i], PlayerPID: pids[i]})
+			}
+			playerSession(b, system.Root, world, ids[0])
+
+			var workers atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				n := workers.Add(1) * 7919 // Each goroutine walks the players from its own offset
+				for pb.Next() {
+					n++
+					i := n % online
+					switch n % 20 {
+					case 0:
+						system.Root.Send(world, &messages.PlayerLeftWorld{PlayerID: ids[i], PlayerPID: pids[i]})
+					case 10:
+						system.Root.Send(world, &messages.PlayerEnteredWorld{PlayerID: ids[i], PlayerPID: pids[i]})
+					default:
+						playerSession(b, system.Root, bc.lookup(world, ids[i]), ids[i])
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package actor
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/territory"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// claimVerified carries the result of checking a claim transaction on chain
+// back into the actor; verification runs off the actor goroutine.
+type claimVerified struct {
+	request *messages.ClaimZoneRequest
+	guildID string
+	err     error
+}
+
+// siegeStarting and siegeEnding are sent to self by the siege timers.
+type siegeStarting struct{ siege territory.Siege }
+type siegeEnding struct{ siege territory.Siege }
+
+func (a *WorldManagerActor) handleClaimZone(ctx actor.Context, msg *messages.ClaimZoneRequest) {
+	if a.services.Territory == nil || a.services.ClaimVerifier == nil {
+		a.respondClaim(ctx, msg, &messages.ClaimZoneResponse{ZoneID: msg.ZoneID, Error: "Territory claims are not enabled on this server."})
+		return
+	}
+	if msg.ZoneID == "" || msg.TxDigest == "" {
+		a.respondClaim(ctx, msg, &messages.ClaimZoneResponse{ZoneID: msg.ZoneID, Error: "A zone ID and claim transaction digest are required."})
+		return
+	}
+	utils.LogInfof("[WorldManagerActor %s] Player %s claims zone %s (tx %s). Verifying on chain...", ctx.Self().Id, msg.PlayerID, msg.ZoneID, msg.TxDigest)
+	self, root, verifier := ctx.Self(), a.actorSystem.Root, a.services.ClaimVerifier
+	go func() {
+		guildID, err := verifier.VerifyTerritoryClaim(msg.TxDigest, msg.ZoneID)
+		root.Send(self, &claimVerified{request: msg, guildID: guildID, err: err})
+	}()
+}
+
+func (a *WorldManagerActor) applyVerifiedClaim(ctx actor.Context, msg *claimVerified) {
+	actorID := ctx.Self().Id
+	req := msg.request
+	if msg.err != nil {
+		utils.LogWarnf("[WorldManagerActor %s] Claim of zone %s by player %s rejected: %v", actorID, req.ZoneID, req.PlayerID, msg.err)
+		a.respondClaim(ctx, req, &messages.ClaimZoneResponse{ZoneID: req.ZoneID, Error: "The claim transaction could not be verified."})
+		return
+	}
+	result, err := a.services.Territory.Claim(req.ZoneID, msg.guildID, req.TxDigest, time.Now())
+	if err != nil {
+		utils.LogInfof("[WorldManagerActor %s] Claim of zone %s by guild %s refused: %v", actorID, req.ZoneID, msg.guildID, err)
+		a.respondClaim(ctx, req, &messages.ClaimZoneResponse{ZoneID: req.ZoneID, GuildID: msg.guildID, Error: err.Error()})
+		return
+	}
+	if result.Granted {
+		utils.LogInfof("[WorldManagerActor %s] Zone %s granted to guild %s.", actorID, req.ZoneID, msg.guildID)
+		a.services.Events.Publish(events.TopicTerritoryClaimed, events.TerritoryClaimed{ZoneID: req.ZoneID, GuildID: msg.guildID})
+		a.respondClaim(ctx, req, &messages.ClaimZoneResponse{ZoneID: req.ZoneID, GuildID: msg.guildID, Granted: true})
+		return
+	}
+	siege := *result.Siege
+	utils.LogInfof("[WorldManagerActor %s] Zone %s contested by guild %s against %s. Siege at %s.",
+		actorID, siege.ZoneID, siege.AttackerGuildID, siege.DefenderGuildID, siege.StartsAt.Format(time.RFC3339))
+	a.scheduleSiege(ctx, time.Until(siege.StartsAt), &siegeStarting{siege: siege}, siege.ZoneID)
+	a.services.Events.Publish(events.TopicSiegeScheduled, siegeEvent(siege))
+	a.respondClaim(ctx, req, &messages.ClaimZoneResponse{
+		ZoneID:        req.ZoneID,
+		GuildID:       msg.guildID,
+		SiegeStartsAt: siege.StartsAt,
+		SiegeEndsAt:   siege.EndsAt,
+	})
+}
+
+func (a *WorldManagerActor) respondClaim(ctx actor.Context, req *messages.ClaimZoneRequest, resp *messages.ClaimZoneResponse) {
+	if req.RequesterPID != nil {
+		ctx.Send(req.RequesterPID, resp)
+	}
+}
+
+func (a *WorldManagerActor) handleGetZoneBenefits(ctx actor.Context, msg *messages.GetZoneBenefits) {
+	if a.services.Territory == nil {
+		ctx.Respond(&messages.ZoneBenefits{ZoneID: msg.ZoneID, Error: "Territory is not enabled on this server."})
+		return
+	}
+	benefits, err := a.services.Territory.Benefits(msg.ZoneID, msg.GuildID)
+	if err != nil {
+		ctx.Respond(&messages.ZoneBenefits{ZoneID: msg.ZoneID, Error: err.Error()})
+		return
+	}
+	ctx.Respond(&messages.ZoneBenefits{
+		ZoneID:       benefits.ZoneID,
+		OwnerGuildID: benefits.OwnerGuildID,
+		Buffs:        benefits.Buffs,
+		TaxRate:      benefits.TaxRate,
+		Allied:       benefits.Allied,
+	})
+}
+
+func (a *WorldManagerActor) handleSiegeStarting(ctx actor.Context, msg *siegeStarting) {
+	current, ok := a.services.Territory.Siege(msg.siege.ZoneID)
+	if !ok || current != msg.siege {
+		return // Resolved early
+	}
+	utils.LogInfof("[WorldManagerActor %s] Siege of zone %s started: guild %s attacks guild %s until %s.",
+		ctx.Self().Id, current.ZoneID, current.AttackerGuildID, current.DefenderGuildID, current.EndsAt.Format(time.RFC3339))
+	a.services.Events.Publish(events.TopicSiegeStarted, siegeEvent(current))
+	a.scheduleSiege(ctx, time.Until(current.EndsAt), &siegeEnding{siege: current}, current.ZoneID)
+}
+
+func (a *WorldManagerActor) handleSiegeEnding(ctx actor.Context, msg *siegeEnding) {
+	current, ok := a.services.Territory.Siege(msg.siege.ZoneID)
+	if !ok || current != msg.siege {
+		return
+	}
+	if _, expired := a.services.Territory.ExpireSiege(current.ZoneID, time.Now()); !expired {
+		return
+	}
+	delete(a.siegeTimers, current.ZoneID)
+	utils.LogInfof("[WorldManagerActor %s] Siege of zone %s ran out without a result. Guild %s keeps it.", ctx.Self().Id, current.ZoneID, current.DefenderGuildID)
+	a.services.Events.Publish(events.TopicSiegeEnded, events.SiegeEnded{ZoneID: current.ZoneID, WinnerGuildID: current.DefenderGuildID, Expired: true})
+}
+
+func (a *WorldManagerActor) handleResolveSiege(ctx actor.Context, msg *messages.ResolveSiege) {
+	if a.services.Territory == nil {
+		return
+	}
+	claim, err := a.services.Territory.ResolveSiege(msg.ZoneID, msg.WinnerGuildID, time.Now())
+	if err != nil {
+		utils.LogWarnf("[WorldManagerActor %s] Could not resolve siege of zone %s: %v", ctx.Self().Id, msg.ZoneID, err)
+		return
+	}
+	if timer, ok := a.siegeTimers[msg.ZoneID]; ok {
+		timer.Stop()
+		delete(a.siegeTimers, msg.ZoneID)
+	}
+	utils.LogInfof("[WorldManagerActor %s] Siege of zone %s won by guild %s.", ctx.Self().Id, msg.ZoneID, msg.WinnerGuildID)
+	a.services.Events.Publish(events.TopicSiegeEnded, events.SiegeEnded{ZoneID: msg.ZoneID, WinnerGuildID: msg.WinnerGuildID})
+	a.services.Events.Publish(events.TopicTerritoryClaimed, events.TerritoryClaimed{ZoneID: claim.ZoneID, GuildID: claim.GuildID})
+}
+
+// scheduleSiege sends msg to self after delay, replacing the zone's pending timer.
+func (a *WorldManagerActor) scheduleSiege(ctx actor.Context, delay time.Duration, msg interface{}, zoneID string) {
+	if timer, ok := a.siegeTimers[zoneID]; ok {
+		timer.Stop()
+	}
+	a.siegeTimers[zoneID] = a.services.Timers.After(a.actorSystem.Root, ctx.Self(), delay, msg)
+}
+
+func siegeEvent(siege territory.Siege) events.TerritorySiege {
+	return events.TerritorySiege{
+		ZoneID:          siege.ZoneID,
+		AttackerGuildID: siege.AttackerGuildID,
+		DefenderGuildID: siege.DefenderGuildID,
+		StartsAt:        siege.StartsAt,
+		EndsAt:          siege.EndsAt,
+	}
+}
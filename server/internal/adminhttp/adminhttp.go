@@ -0,0 +1,62 @@
+// Package adminhttp is what every admin HTTP endpoint shares: the admin token
+// check, the operator header, and JSON responses.
+package adminhttp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Handler serves an admin request that passed the admin check. operator is
+// the X-Admin-User header, which goes into the audit log.
+type Handler func(w http.ResponseWriter, r *http.Request, operator string)
+
+// Only returns handler behind the admin check. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator. An empty adminToken refuses every request.
+func Only(adminToken string, handler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			WriteError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		operator := r.Header.Get("X-Admin-User")
+		if operator == "" {
+			WriteError(w, http.StatusBadRequest, errors.New("X-Admin-User header is required"))
+			return
+		}
+		handler(w, r, operator)
+	}
+}
+
+// Method returns next, refusing requests with any other method.
+func Method(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			WriteError(w, http.StatusMethodNotAllowed, errors.New("use "+method))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// WriteJSON writes v as the JSON response. Admin responses are never cached.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.LogErrorf("Admin API: failed to write response: %v", err)
+	}
+}
+
+// WriteError writes err as {"error": "..."}.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
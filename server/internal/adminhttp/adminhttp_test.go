@@ -0,0 +1,61 @@
+package adminhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOnlyChecksTokenAndOperator(t *testing.T) {
+	handler := Method(http.MethodPost, Only("secret", func(w http.ResponseWriter, r *http.Request, operator string) {
+		WriteJSON(w, http.StatusOK, map[string]string{"operator": operator})
+	}))
+	for _, tc := range []struct {
+		name, method, token, operator string
+		want                          int
+	}{
+		{"wrong method", http.MethodGet, "secret", "ops", http.StatusMethodNotAllowed},
+		{"no token", http.MethodPost, "", "ops", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "guess", "ops", http.StatusUnauthorized},
+		{"no operator", http.MethodPost, "secret", "", http.StatusBadRequest},
+		{"admin", http.MethodPost, "secret", "ops", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/admin/thing", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		if tc.operator != "" {
+			req.Header.Set("X-Admin-User", tc.operator)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if rec.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: admin response may be cached", tc.name)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if tc.want == http.StatusOK && body["operator"] != "ops" {
+			t.Errorf("%s: body %v", tc.name, body)
+		}
+		if tc.want != http.StatusOK && body["error"] == "" {
+			t.Errorf("%s: error body %v", tc.name, body)
+		}
+	}
+
+	// An empty admin token refuses everyone, even a request with no token.
+	req := httptest.NewRequest(http.MethodGet, "/admin/thing", nil)
+	req.Header.Set("X-Admin-User", "ops")
+	rec := httptest.NewRecorder()
+	Only("", func(w http.ResponseWriter, r *http.Request, operator string) {
+		t.Error("handler ran without an admin token configured")
+	})(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("empty admin token: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+// Package afk decides when an idle player is away from keyboard. The server's
+// receive timeout only notices dead connections; a client that keeps pinging or
+// chatting can still be AFK, since only gameplay actions count as activity.
+package afk
+
+import (
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// Status is what a policy decides for a player.
+type Status int
+
+const (
+	Active Status = iota
+	Away          // AFK: shown to the room, optionally moved to the lobby
+	Kick          // AFK for too long while the server is busy: disconnect
+)
+
+// Policy holds the AFK thresholds. A nil Policy treats every player as active.
+type Policy struct {
+	After           time.Duration // No gameplay for this long marks a player AFK; 0 disables AFK detection
+	KickAfter       time.Duration // No gameplay for this long disconnects the player under high load; 0 never kicks
+	KickMinSessions int           // High load means at least this many sessions; 0 kicks regardless of load
+	LobbyRoomID     string        // AFK players are moved to this room; empty leaves them where they are
+	CheckInterval   time.Duration // How often each session checks its player
+	Sessions        func() int    // Current number of sessions, for the load check
+}
+
+// Enabled reports whether AFK detection is on.
+func (p *Policy) Enabled() bool {
+	return p != nil && p.After > 0
+}
+
+// Status returns the status of a player whose last gameplay action was idle ago.
+func (p *Policy) Status(idle time.Duration) Status {
+	if !p.Enabled() || idle < p.After {
+		return Active
+	}
+	if p.KickAfter > 0 && idle >= p.KickAfter && p.highLoad() {
+		return Kick
+	}
+	return Away
+}
+
+func (p *Policy) highLoad() bool {
+	if p.KickMinSessions <= 0 {
+		return true
+	}
+	return p.Sessions != nil && p.Sessions() >= p.KickMinSessions
+}
+
+// passiveMessages are client messages that do not count as gameplay.
+var passiveMessages = map[string]bool{
+	protocol.MsgTypeAuthRequest:        true,
+	protocol.MsgTypePing:               true,
+	protocol.MsgTypeTimeSync:           true,
+	protocol.MsgTypeSendChat:           true,
+	protocol.MsgTypeSendWhisper:        true,
+	protocol.MsgTypeChatHistoryRequest: true,
+	protocol.MsgTypeChannelJoin:        true,
+	protocol.MsgTypeChannelLeave:       true,
+	protocol.MsgTypeChannelSend:        true,
+	protocol.MsgTypeChannelListRequest: true,
+	protocol.MsgTypeSocialAction:       true,
+	protocol.MsgTypeListRoomsRequest:   true,
+	protocol.MsgTypeVoiceOffer:         true,
+	protocol.MsgTypeVoiceAnswer:        true,
+	protocol.MsgTypeVoiceICECandidate:  true,
+	protocol.MsgTypeVoiceMute:          true,
+	protocol.MsgTypeShopBrowse:         true,
+	protocol.MsgTypeMailListRequest:    true,
+	protocol.MsgTypeMailRead:           true,
+	protocol.MsgTypeMailDelete:         true,
+	protocol.MsgTypeGiftHistoryRequest: true,
+	protocol.MsgTypeCraftQueueRequest:  true,
+	protocol.MsgTypeEnergyRequest:      true,
+	protocol.MsgTypeBattlePassRequest:  true,
+}
+
+// IsGameplay reports whether a client message of msgType resets the AFK clock.
+// Chat, voice signaling, pings, clock syncs, browsing and reading mail do not.
+func IsGameplay(msgType string) bool {
+	return !passiveMessages[msgType]
+}
@@ -0,0 +1,49 @@
+package afk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+func TestPolicyStatus(t *testing.T) {
+	sessions := 10
+	policy := &Policy{
+		After:           5 * time.Minute,
+		KickAfter:       30 * time.Minute,
+		KickMinSessions: 100,
+		Sessions:        func() int { return sessions },
+	}
+	tests := []struct {
+		idle     time.Duration
+		sessions int
+		want     Status
+	}{
+		{time.Minute, 10, Active},
+		{5 * time.Minute, 10, Away},
+		{time.Hour, 10, Away}, // Quiet server: AFK players stay
+		{time.Hour, 100, Kick},
+		{10 * time.Minute, 500, Away},
+	}
+	for _, tt := range tests {
+		sessions = tt.sessions
+		if got := policy.Status(tt.idle); got != tt.want {
+			t.Errorf("Status(%v) with %d sessions = %v, want %v", tt.idle, tt.sessions, got, tt.want)
+		}
+	}
+
+	var disabled *Policy
+	if disabled.Status(24*time.Hour) != Active {
+		t.Error("nil policy should treat players as active")
+	}
+}
+
+func TestIsGameplay(t *testing.T) {
+	if IsGameplay(protocol.MsgTypeSendChat) || IsGameplay(protocol.MsgTypeChannelSend) || IsGameplay(protocol.MsgTypePing) {
+		t.Error("chat and pings must not count as gameplay")
+	}
+	if !IsGameplay(protocol.MsgTypePlayerAction) || !IsGameplay(protocol.MsgTypeCombatAction) {
+		t.Error("actions must count as gameplay")
+	}
+}
@@ -0,0 +1,461 @@
+// Package airdrop mints item NFTs in bulk, for airdrops and event rewards. An
+// operator submits a manifest of recipients and items; the items are split
+// into chunks that each fit one programmable transaction within the gas
+// limits, and every chunk is minted through the outbox, so a drop survives
+// restarts and failed chunks are retried. The job records the digest of the
+// transaction that minted each item, for a per-recipient report.
+package airdrop
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// ChunkKind is the outbox kind of one chunk's mint.
+const ChunkKind = "airdrop.mint_chunk"
+
+// Defaults for zero Options.
+const (
+	DefaultGasPerMint    = 5_000_000   // MIST
+	DefaultMaxGasBudget  = 500_000_000 // MIST
+	DefaultMaxMintsPerTx = 100
+	DefaultMaxMints      = 10_000
+)
+
+var (
+	ErrNotFound   = errors.New("no such airdrop")
+	ErrNoFailures = errors.New("the airdrop has no failed chunks")
+)
+
+// Status is where a job or a chunk is.
+type Status string
+
+const (
+	StatusPending Status = "pending" // Queued or being retried
+	StatusMinted  Status = "minted"  // Every item is on-chain
+	StatusFailed  Status = "failed"  // The outbox gave up; see Retry
+	StatusPartial Status = "partial" // A finished job with failed chunks
+)
+
+// Mint is one item of a job.
+type Mint struct {
+	Recipient string                 `json:"recipient"`
+	ItemType  string                 `json:"itemType"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Chunk     int                    `json:"chunk"`            // Index of the chunk minting it
+	Digest    string                 `json:"digest,omitempty"` // Transaction that minted it
+}
+
+// Chunk is the items minted by one transaction: Mints[From:To] of the job.
+type Chunk struct {
+	Index     int    `json:"index"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	GasBudget uint64 `json:"gasBudget"`
+	Status    Status `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Attempts  int    `json:"attempts"`
+	Retries   int    `json:"retries"` // Times an operator requeued it after the outbox gave up
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Job is one airdrop.
+type Job struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Status    Status    `json:"status"`
+	Mints     []Mint    `json:"mints"`
+	Chunks    []Chunk   `json:"chunks"`
+}
+
+// Progress counts a job's items by status.
+type Progress struct {
+	Total   int `json:"total"`
+	Minted  int `json:"minted"`
+	Pending int `json:"pending"`
+	Failed  int `json:"failed"`
+}
+
+// Summary is a job without its items, for listings and progress polling.
+type Summary struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Status    Status    `json:"status"`
+	Progress  Progress  `json:"progress"`
+	Chunks    []Chunk   `json:"chunks"`
+}
+
+// Summary returns the job without its items.
+func (j Job) Summary() Summary {
+	summary := Summary{ID: j.ID, Name: j.Name, CreatedBy: j.CreatedBy, CreatedAt: j.CreatedAt, UpdatedAt: j.UpdatedAt, Status: j.Status, Chunks: j.Chunks}
+	summary.Progress.Total = len(j.Mints)
+	for _, chunk := range j.Chunks {
+		n := chunk.To - chunk.From
+		switch chunk.Status {
+		case StatusMinted:
+			summary.Progress.Minted += n
+		case StatusFailed:
+			summary.Progress.Failed += n
+		default:
+			summary.Progress.Pending += n
+		}
+	}
+	return summary
+}
+
+// RecipientResult is what one recipient got from a job.
+type RecipientResult struct {
+	Recipient string         `json:"recipient"`
+	Items     map[string]int `json:"items"` // Count by item type
+	Status    Status         `json:"status"`
+	Digests   []string       `json:"digests"` // Transactions that minted their items
+}
+
+// Report returns the job's results by recipient, in manifest order. A
+// recipient whose items span chunks is pending or failed if any chunk is.
+func (j Job) Report() []RecipientResult {
+	var results []RecipientResult
+	index := make(map[string]int)
+	for _, mint := range j.Mints {
+		i, ok := index[mint.Recipient]
+		if !ok {
+			i = len(results)
+			index[mint.Recipient] = i
+			results = append(results, RecipientResult{Recipient: mint.Recipient, Items: make(map[string]int), Status: StatusMinted})
+		}
+		result := &results[i]
+		result.Items[mint.ItemType]++
+		if status := j.Chunks[mint.Chunk].Status; status != StatusMinted && result.Status != StatusFailed {
+			result.Status = status
+		}
+		if mint.Digest != "" && (len(result.Digests) == 0 || result.Digests[len(result.Digests)-1] != mint.Digest) {
+			result.Digests = append(result.Digests, mint.Digest)
+		}
+	}
+	return results
+}
+
+// Minter mints items in one transaction within gasBudget and returns its
+// digest. It must mint all of them or none.
+type Minter func(ctx context.Context, mints []Mint, gasBudget uint64) (digest string, err error)
+
+// Options configure a Service.
+type Options struct {
+	GasPerMint    uint64 // Estimated MIST one mint costs, for sizing chunks
+	MaxGasBudget  uint64 // Most gas one chunk's transaction may budget
+	MaxMintsPerTx int    // Most items in one transaction
+	MaxMints      int    // Most items in one job
+}
+
+// chunkSize is how many items fit one transaction.
+func (o Options) chunkSize() int {
+	size := o.MaxMintsPerTx
+	if byGas := o.MaxGasBudget / o.GasPerMint; byGas < uint64(size) {
+		size = int(byGas)
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// chunkJob is the outbox payload of one chunk.
+type chunkJob struct {
+	JobID string `json:"jobId"`
+	Chunk int    `json:"chunk"`
+}
+
+// Service runs airdrops. It is safe for concurrent use.
+type Service struct {
+	opts   Options
+	store  Store
+	jobs   *outbox.Outbox
+	minter Minter
+	now    func() time.Time
+
+	mu    sync.Mutex
+	state map[string]*Job
+}
+
+// NewService loads the jobs in store and registers the chunk handler with
+// jobs.
+func NewService(opts Options, store Store, jobs *outbox.Outbox, minter Minter) (*Service, error) {
+	state, err := store.LoadJobs()
+	if err != nil {
+		return nil, fmt.Errorf("loading airdrops: %w", err)
+	}
+	if opts.GasPerMint == 0 {
+		opts.GasPerMint = DefaultGasPerMint
+	}
+	if opts.MaxGasBudget == 0 {
+		opts.MaxGasBudget = DefaultMaxGasBudget
+	}
+	if opts.MaxMintsPerTx <= 0 {
+		opts.MaxMintsPerTx = DefaultMaxMintsPerTx
+	}
+	if opts.MaxMints <= 0 {
+		opts.MaxMints = DefaultMaxMints
+	}
+	s := &Service{opts: opts, store: store, jobs: jobs, minter: minter, now: time.Now, state: make(map[string]*Job, len(state))}
+	for i := range state {
+		s.state[state[i].ID] = &state[i]
+	}
+	jobs.Handle(ChunkKind, s.mintChunk)
+	return s, nil
+}
+
+// NewServiceFromConfig creates a Service from configuration, with a FileStore at cfg.StateFile.
+func NewServiceFromConfig(cfg configs.AirdropConfig, jobs *outbox.Outbox, minter Minter) (*Service, error) {
+	return NewService(Options{
+		GasPerMint:    cfg.GasPerMint,
+		MaxGasBudget:  cfg.MaxGasBudget,
+		MaxMintsPerTx: cfg.MaxMintsPerTx,
+		MaxMints:      cfg.MaxMints,
+	}, FileStore{Path: cfg.StateFile}, jobs, minter)
+}
+
+// Create checks manifest, splits its items into chunks and queues every
+// chunk for minting.
+func (s *Service) Create(manifest Manifest, operator string) (Job, error) {
+	mints, err := manifest.mints(s.opts.MaxMints)
+	if err != nil {
+		return Job{}, err
+	}
+	now := s.now()
+	job := &Job{ID: newJobID(), Name: manifest.Name, CreatedBy: operator, CreatedAt: now, UpdatedAt: now, Status: StatusPending, Mints: mints}
+	size := s.opts.chunkSize()
+	for from := 0; from < len(mints); from += size {
+		to := from + size
+		if to > len(mints) {
+			to = len(mints)
+		}
+		index := len(job.Chunks)
+		for i := from; i < to; i++ {
+			mints[i].Chunk = index
+		}
+		job.Chunks = append(job.Chunks, Chunk{Index: index, From: from, To: to, GasBudget: uint64(to-from) * s.opts.GasPerMint, Status: StatusPending})
+	}
+
+	s.mu.Lock()
+	s.state[job.ID] = job
+	err = s.saveLocked()
+	snapshot := copyJob(job)
+	s.mu.Unlock()
+	if err != nil {
+		return Job{}, err
+	}
+	for _, chunk := range job.Chunks {
+		if err := s.enqueue(job.ID, chunk); err != nil {
+			return snapshot, err
+		}
+	}
+	utils.LogInfof("Airdrop %s (%s): %d items for %d recipients in %d chunks, queued by %s.",
+		job.ID, job.Name, len(mints), len(snapshot.Report()), len(job.Chunks), operator)
+	return snapshot, nil
+}
+
+// Job returns the job with the given ID.
+func (s *Service) Job(id string) (Job, error) {
+	failed := s.failedChunks()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.state[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	s.markFailedLocked(job, failed)
+	return copyJob(job), nil
+}
+
+// List returns a summary of every job, newest first.
+func (s *Service) List() []Summary {
+	failed := s.failedChunks()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summaries := make([]Summary, 0, len(s.state))
+	for _, job := range s.state {
+		s.markFailedLocked(job, failed)
+		summaries = append(summaries, copyJob(job).Summary())
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].CreatedAt.After(summaries[j].CreatedAt) })
+	return summaries
+}
+
+// Retry queues the job's failed chunks again.
+func (s *Service) Retry(id string) (Job, error) {
+	failed := s.failedChunks()
+	s.mu.Lock()
+	job, ok := s.state[id]
+	if !ok {
+		s.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	s.markFailedLocked(job, failed)
+	var retry []Chunk
+	for i := range job.Chunks {
+		if chunk := &job.Chunks[i]; chunk.Status == StatusFailed {
+			chunk.Status, chunk.Attempts = StatusPending, 0
+			chunk.Retries++
+			retry = append(retry, *chunk)
+		}
+	}
+	if len(retry) == 0 {
+		s.mu.Unlock()
+		return Job{}, ErrNoFailures
+	}
+	job.Status, job.UpdatedAt = StatusPending, s.now()
+	err := s.saveLocked()
+	snapshot := copyJob(job)
+	s.mu.Unlock()
+	if err != nil {
+		return Job{}, err
+	}
+	for _, chunk := range retry {
+		if err := s.enqueue(id, chunk); err != nil {
+			return snapshot, err
+		}
+	}
+	utils.LogInfof("Airdrop %s: retrying %d failed chunk(s).", id, len(retry))
+	return snapshot, nil
+}
+
+// mintChunk is the outbox handler of ChunkKind. A chunk already minted is
+// not minted again.
+func (s *Service) mintChunk(ctx context.Context, payload json.RawMessage) error {
+	var task chunkJob
+	if err := json.Unmarshal(payload, &task); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	job, ok := s.state[task.JobID]
+	if !ok || task.Chunk < 0 || task.Chunk >= len(job.Chunks) {
+		s.mu.Unlock()
+		utils.LogWarnf("Airdrop %s has no chunk %d. Dropping it.", task.JobID, task.Chunk)
+		return nil
+	}
+	chunk := job.Chunks[task.Chunk]
+	mints := append([]Mint(nil), job.Mints[chunk.From:chunk.To]...)
+	s.mu.Unlock()
+	if chunk.Digest != "" {
+		return nil
+	}
+
+	digest, err := s.minter(ctx, mints, chunk.GasBudget)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := &job.Chunks[task.Chunk]
+	current.Attempts++
+	job.UpdatedAt = s.now()
+	if err != nil {
+		current.LastError = err.Error()
+	} else {
+		current.Status, current.Digest, current.LastError = StatusMinted, digest, ""
+		for i := chunk.From; i < chunk.To; i++ {
+			job.Mints[i].Digest = digest
+		}
+		job.Status = jobStatus(job)
+		progress := job.Summary().Progress
+		utils.LogInfof("Airdrop %s: chunk %d/%d minted in %s (%d/%d items).", job.ID, task.Chunk+1, len(job.Chunks), digest, progress.Minted, progress.Total)
+	}
+	if saveErr := s.saveLocked(); saveErr != nil {
+		// A minted chunk is kept in memory even so, so it is not minted twice
+		// by this process.
+		utils.LogErrorf("Airdrop %s: could not save chunk %d: %v", job.ID, task.Chunk, saveErr)
+	}
+	return err
+}
+
+func (s *Service) enqueue(jobID string, chunk Chunk) error {
+	id := messageID(jobID, chunk)
+	if _, err := s.jobs.Enqueue(id, ChunkKind, chunkJob{JobID: jobID, Chunk: chunk.Index}); err != nil {
+		return fmt.Errorf("queueing airdrop chunk %s: %w", id, err)
+	}
+	return nil
+}
+
+// messageID is the outbox ID of a chunk; a retried chunk gets a new one, as
+// the outbox keeps the message it gave up on.
+func messageID(jobID string, chunk Chunk) string {
+	return fmt.Sprintf("airdrop:%s:%d:%d", jobID, chunk.Index, chunk.Retries)
+}
+
+// failedChunks returns the outbox IDs of the chunks the outbox gave up on.
+func (s *Service) failedChunks() map[string]string {
+	messages, err := s.jobs.List(ChunkKind)
+	if err != nil {
+		utils.LogErrorf("Airdrops: could not read the outbox: %v", err)
+		return nil
+	}
+	failed := make(map[string]string)
+	for _, msg := range messages {
+		if msg.Dead {
+			failed[msg.ID] = msg.LastError
+		}
+	}
+	return failed
+}
+
+// markFailedLocked marks the job's chunks the outbox gave up on as failed.
+func (s *Service) markFailedLocked(job *Job, failed map[string]string) {
+	for i := range job.Chunks {
+		chunk := &job.Chunks[i]
+		if lastError, ok := failed[messageID(job.ID, *chunk)]; ok && chunk.Status == StatusPending {
+			chunk.Status, chunk.LastError = StatusFailed, lastError
+		}
+	}
+	job.Status = jobStatus(job)
+}
+
+// jobStatus is pending while any chunk is, then minted or partial.
+func jobStatus(job *Job) Status {
+	status := StatusMinted
+	for _, chunk := range job.Chunks {
+		switch chunk.Status {
+		case StatusPending:
+			return StatusPending
+		case StatusFailed:
+			status = StatusPartial
+		}
+	}
+	return status
+}
+
+func (s *Service) saveLocked() error {
+	jobs := make([]Job, 0, len(s.state))
+	for _, job := range s.state {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return s.store.SaveJobs(jobs)
+}
+
+func copyJob(job *Job) Job {
+	c := *job
+	c.Mints = append([]Mint(nil), job.Mints...)
+	c.Chunks = append([]Chunk(nil), job.Chunks...)
+	return c
+}
+
+func newJobID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "drop-" + hex.EncodeToString(b)
+}
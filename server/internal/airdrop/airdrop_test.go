@@ -0,0 +1,138 @@
+package airdrop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+)
+
+func TestParseManifests(t *testing.T) {
+	csvManifest, err := ParseCSV(strings.NewReader("recipient,itemType,quantity,rarity\n0xa1,badge,2,rare\n0xb2,badge,,\n"), "Launch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonManifest, err := ParseJSON(strings.NewReader(`[{"recipient":"0xa1","itemType":"badge","quantity":2,"metadata":{"rarity":"rare"}},{"recipient":"0xb2","itemType":"badge"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, manifest := range []Manifest{csvManifest, jsonManifest} {
+		mints, err := manifest.mints(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mints) != 3 || mints[0].Metadata["rarity"] != "rare" || mints[2].Metadata != nil || !strings.HasSuffix(mints[2].Recipient, "00b2") {
+			t.Errorf("mints = %+v", mints)
+		}
+		if _, err := manifest.mints(2); err == nil {
+			t.Error("a manifest over the item limit was accepted")
+		}
+	}
+	if _, err := ParseCSV(strings.NewReader("address,itemType\n"), ""); err == nil {
+		t.Error("a CSV manifest without a recipient column was accepted")
+	}
+	if _, err := (Manifest{Lines: []Line{{Recipient: "alice", ItemType: "badge"}}}).mints(10); err == nil {
+		t.Error("a recipient that is not an address was accepted")
+	}
+}
+
+// chainMinter mints chunks, failing the items of recipient broken.
+type chainMinter struct {
+	mu      sync.Mutex
+	broken  string
+	batches [][]Mint
+}
+
+func (c *chainMinter) mint(ctx context.Context, mints []Mint, gasBudget uint64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, mint := range mints {
+		if mint.Recipient == c.broken {
+			return "", errors.New("insufficient gas")
+		}
+	}
+	c.batches = append(c.batches, mints)
+	return fmt.Sprintf("digest%d", len(c.batches)), nil
+}
+
+func waitForJob(t *testing.T, s *Service, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := s.Job(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != StatusPending || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestAirdropMintsInChunksAndRetries(t *testing.T) {
+	box := outbox.New(outbox.NewMemoryStore(), outbox.Options{PollInterval: time.Millisecond, MaxAttempts: 2, BaseBackoff: time.Nanosecond})
+	minter := &chainMinter{broken: "0x" + strings.Repeat("0", 62) + "c3"}
+	store := FileStore{Path: filepath.Join(t.TempDir(), "airdrops.json")}
+	// Three mints fit the gas budget of one transaction.
+	s, err := NewService(Options{GasPerMint: 10, MaxGasBudget: 35, MaxMintsPerTx: 100}, store, box, minter.mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.Start()
+	defer box.Stop()
+
+	manifest := Manifest{Name: "Launch", Lines: []Line{
+		{Recipient: "0xa1", ItemType: "badge", Quantity: 4},
+		{Recipient: "0xb2", ItemType: "badge"},
+		{Recipient: "0xb2", ItemType: "cape"},
+		{Recipient: "0xc3", ItemType: "badge"},
+	}}
+	created, err := s.Create(manifest, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Chunks) != 3 || created.Chunks[0].GasBudget != 30 || created.Chunks[2].To-created.Chunks[2].From != 1 {
+		t.Fatalf("chunks = %+v", created.Chunks)
+	}
+
+	job := waitForJob(t, s, created.ID)
+	if job.Status != StatusPartial {
+		t.Fatalf("status = %s, chunks = %+v", job.Status, job.Chunks)
+	}
+	if progress := job.Summary().Progress; progress.Minted != 6 || progress.Failed != 1 {
+		t.Errorf("progress = %+v", progress)
+	}
+	report := job.Report()
+	if len(report) != 3 || report[0].Items["badge"] != 4 || len(report[0].Digests) != 2 || report[1].Items["cape"] != 1 || report[2].Status != StatusFailed {
+		t.Errorf("report = %+v", report)
+	}
+
+	// The chunk the outbox gave up on mints once the cause is fixed, and the
+	// jobs survive a restart.
+	minter.mu.Lock()
+	minter.broken = ""
+	minter.mu.Unlock()
+	if _, err := s.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job = waitForJob(t, s, job.ID); job.Status != StatusMinted || len(minter.batches) != 3 {
+		t.Fatalf("after retry: status = %s after %d batches", job.Status, len(minter.batches))
+	}
+	if _, err := s.Retry(job.ID); !errors.Is(err, ErrNoFailures) {
+		t.Errorf("retrying a finished airdrop: %v", err)
+	}
+	reloaded, err := NewService(Options{}, store, outbox.New(outbox.NewMemoryStore(), outbox.Options{}), minter.mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err := reloaded.Job(created.ID); err != nil || job.Mints[6].Digest != "digest3" {
+		t.Errorf("reloaded job = %+v, %v", job.Mints, err)
+	}
+}
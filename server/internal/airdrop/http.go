@@ -0,0 +1,96 @@
+package airdrop
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// maxManifestBytes bounds an uploaded manifest.
+const maxManifestBytes = 8 << 20
+
+// RegisterHandlers adds the airdrop endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator.
+//
+//	POST /admin/airdrops                    body: a JSON manifest, or CSV with Content-Type text/csv and ?name=
+//	GET  /admin/airdrops                    every airdrop with its progress, newest first
+//	GET  /admin/airdrops/job?id=            one airdrop's progress and chunks
+//	GET  /admin/airdrops/report?id=&format= results by recipient with digests; format=csv lists every item
+//	POST /admin/airdrops/retry?id=          queue the chunks the outbox gave up on again
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/airdrops", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		switch r.Method {
+		case http.MethodGet:
+			adminhttp.WriteJSON(w, http.StatusOK, s.List())
+		case http.MethodPost:
+			body := http.MaxBytesReader(w, r.Body, maxManifestBytes)
+			var manifest Manifest
+			var err error
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+				manifest, err = ParseCSV(body, r.URL.Query().Get("name"))
+			} else {
+				manifest, err = ParseJSON(body)
+			}
+			if err != nil {
+				adminhttp.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			job, err := s.Create(manifest, operator)
+			if err != nil {
+				adminhttp.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			adminhttp.WriteJSON(w, http.StatusAccepted, job.Summary())
+		default:
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET or POST"))
+		}
+	}))
+	mux.HandleFunc("/admin/airdrops/job", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		job, err := s.Job(r.URL.Query().Get("id"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, job.Summary())
+	}))
+	mux.HandleFunc("/admin/airdrops/report", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		job, err := s.Job(r.URL.Query().Get("id"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		if r.URL.Query().Get("format") != "csv" {
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"airdrop": job.Summary(), "recipients": job.Report()})
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+job.ID+`.csv"`)
+		out := csv.NewWriter(w)
+		out.Write([]string{"recipient", "itemType", "status", "digest"})
+		for _, mint := range job.Mints {
+			out.Write([]string{mint.Recipient, mint.ItemType, string(job.Chunks[mint.Chunk].Status), mint.Digest})
+		}
+		out.Flush()
+	}))
+	mux.HandleFunc("/admin/airdrops/retry", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		job, err := s.Retry(r.URL.Query().Get("id"))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrNoFailures):
+			adminhttp.WriteError(w, http.StatusConflict, err)
+		case err != nil:
+			adminhttp.WriteError(w, http.StatusInternalServerError, err)
+		default:
+			adminhttp.WriteJSON(w, http.StatusAccepted, job.Summary())
+		}
+	}))
+}
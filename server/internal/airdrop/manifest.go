@@ -0,0 +1,132 @@
+package airdrop
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+)
+
+// Line is one entry of a manifest: quantity items of one type for one
+// recipient.
+type Line struct {
+	Recipient string                 `json:"recipient"` // Sui address
+	ItemType  string                 `json:"itemType"`
+	Quantity  int                    `json:"quantity,omitempty"` // Default 1
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Manifest lists the items of one airdrop.
+type Manifest struct {
+	Name  string `json:"name"` // What the drop is for, e.g. "Launch week"
+	Lines []Line `json:"lines"`
+}
+
+// ParseJSON reads a manifest as JSON: either a Manifest or a bare array of
+// lines.
+func ParseJSON(r io.Reader) (Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Manifest{}, err
+	}
+	var manifest Manifest
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &manifest.Lines)
+	} else {
+		err = json.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// ParseCSV reads a manifest as CSV with a header row. The recipient and
+// itemType columns are required and quantity is optional; every other column
+// becomes metadata of the items, skipped where the cell is empty.
+//
+//	recipient,itemType,quantity,rarity
+//	0x9f3c...,launch_badge,1,rare
+func ParseCSV(r io.Reader, name string) (Manifest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: reading the header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	for _, required := range []string{"recipient", "itemType"} {
+		if _, ok := columns[required]; !ok {
+			return Manifest{}, fmt.Errorf("invalid manifest: no %s column", required)
+		}
+	}
+
+	manifest := Manifest{Name: name}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return manifest, nil
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+		}
+		line := Line{Recipient: record[columns["recipient"]], ItemType: record[columns["itemType"]]}
+		for column, i := range columns {
+			value := strings.TrimSpace(record[i])
+			switch {
+			case column == "recipient" || column == "itemType" || value == "":
+			case column == "quantity":
+				if line.Quantity, err = strconv.Atoi(value); err != nil {
+					return Manifest{}, fmt.Errorf("invalid manifest: row %d: quantity %q is not a number", row, value)
+				}
+			default:
+				if line.Metadata == nil {
+					line.Metadata = make(map[string]interface{})
+				}
+				line.Metadata[column] = value
+			}
+		}
+		manifest.Lines = append(manifest.Lines, line)
+	}
+}
+
+// mints checks the manifest and expands it into one Mint per item, with
+// recipients' addresses normalized. It refuses manifests of more than max
+// items.
+func (m Manifest) mints(max int) ([]Mint, error) {
+	if len(m.Lines) == 0 {
+		return nil, errors.New("the manifest lists no items")
+	}
+	var mints []Mint
+	for i, line := range m.Lines {
+		recipient, err := accountlink.NormalizeAddress(line.Recipient)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: recipient %q is not a Sui address", i+1, line.Recipient)
+		}
+		if strings.TrimSpace(line.ItemType) == "" {
+			return nil, fmt.Errorf("line %d: no itemType", i+1)
+		}
+		quantity := line.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 {
+			return nil, fmt.Errorf("line %d: negative quantity %d", i+1, quantity)
+		}
+		if len(mints)+quantity > max {
+			return nil, fmt.Errorf("line %d: the manifest exceeds %d items", i+1, max)
+		}
+		for n := 0; n < quantity; n++ {
+			mints = append(mints, Mint{Recipient: recipient, ItemType: strings.TrimSpace(line.ItemType), Metadata: line.Metadata})
+		}
+	}
+	return mints, nil
+}
@@ -0,0 +1,44 @@
+package airdrop
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Store persists airdrop jobs.
+type Store interface {
+	LoadJobs() ([]Job, error)
+	SaveJobs([]Job) error
+}
+
+// FileStore keeps the jobs in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+// LoadJobs implements Store. A missing file means no jobs.
+func (f FileStore) LoadJobs() ([]Job, error) {
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	err = json.Unmarshal(data, &jobs)
+	return jobs, err
+}
+
+// SaveJobs implements Store.
+func (f FileStore) SaveJobs(jobs []Job) error {
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
@@ -0,0 +1,201 @@
+package analytics
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Options controls batching and sampling.
+type Options struct {
+	BatchSize     int                // Records per sink write
+	FlushInterval time.Duration      // Maximum time a record waits for a batch to fill
+	QueueSize     int                // Records buffered before new ones are dropped
+	SampleRates   map[string]float64 // Record name -> fraction kept; unlisted names are always kept
+}
+
+// PipelineStats reports pipeline throughput.
+type PipelineStats struct {
+	Queued     int              `json:"queued"`
+	Written    uint64           `json:"written"`
+	SampledOut uint64           `json:"sampledOut"`
+	Dropped    uint64           `json:"dropped"`    // Queue full
+	SinkErrors map[string]int64 `json:"sinkErrors"` // Failed batch writes per sink
+}
+
+// Pipeline samples records, batches them and writes each batch to every sink.
+// Track never blocks; records are dropped when the queue is full.
+type Pipeline struct {
+	sinks []Sink
+	opts  Options
+	queue chan Record
+	done  chan struct{}
+
+	mu     sync.RWMutex // Guards closed against concurrent Track
+	closed bool
+
+	written    atomic.Uint64
+	sampledOut atomic.Uint64
+	dropped    atomic.Uint64
+	errMu      sync.Mutex
+	sinkErrors map[string]int64
+}
+
+// NewPipeline creates a Pipeline and starts its writer goroutine.
+func NewPipeline(sinks []Sink, opts Options) *Pipeline {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10000
+	}
+	p := &Pipeline{
+		sinks:      sinks,
+		opts:       opts,
+		queue:      make(chan Record, opts.QueueSize),
+		done:       make(chan struct{}),
+		sinkErrors: make(map[string]int64),
+	}
+	go p.run()
+	return p
+}
+
+// NewPipelineFromConfig creates a Pipeline with the sinks listed in cfg.
+func NewPipelineFromConfig(cfg configs.AnalyticsConfig) (*Pipeline, error) {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, sinkCfg := range cfg.Sinks {
+		sink, err := NewSinkFromConfig(sinkCfg)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return NewPipeline(sinks, Options{
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: time.Duration(cfg.FlushIntervalMs) * time.Millisecond,
+		QueueSize:     cfg.QueueSize,
+		SampleRates:   cfg.SampleRates,
+	}), nil
+}
+
+// Subscribe feeds the pipeline from every event on bus that analytics tracks.
+func (p *Pipeline) Subscribe(bus *events.Bus) (unsubscribe func()) {
+	return bus.Subscribe("*", "analytics", func(e events.Event) {
+		if record, ok := recordFromEvent(e); ok {
+			p.Track(record)
+		}
+	})
+}
+
+// Track queues a record, subject to sampling.
+func (p *Pipeline) Track(record Record) {
+	if rate, ok := p.opts.SampleRates[record.Name]; ok && rand.Float64() >= rate {
+		p.sampledOut.Add(1)
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- record:
+	default:
+		if p.dropped.Add(1)%1000 == 1 {
+			utils.LogWarnf("Analytics: Queue full, dropping records (%d dropped so far).", p.dropped.Load())
+		}
+	}
+}
+
+// Stats returns pipeline counters.
+func (p *Pipeline) Stats() PipelineStats {
+	p.errMu.Lock()
+	sinkErrors := make(map[string]int64, len(p.sinkErrors))
+	for name, count := range p.sinkErrors {
+		sinkErrors[name] = count
+	}
+	p.errMu.Unlock()
+	return PipelineStats{
+		Queued:     len(p.queue),
+		Written:    p.written.Load(),
+		SampledOut: p.sampledOut.Load(),
+		Dropped:    p.dropped.Load(),
+		SinkErrors: sinkErrors,
+	}
+}
+
+// Close flushes queued records and closes the sinks.
+func (p *Pipeline) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+	<-p.done
+	for _, sink := range p.sinks {
+		if err := sink.Close(); err != nil {
+			utils.LogWarnf("Analytics: Error closing sink %s: %v", sink.Name(), err)
+		}
+	}
+}
+
+func (p *Pipeline) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Record, 0, p.opts.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			p.write(batch)
+			batch = make([]Record, 0, p.opts.BatchSize)
+		}
+	}
+	for {
+		select {
+		case record, ok := <-p.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= p.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (p *Pipeline) write(batch []Record) {
+	for _, sink := range p.sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := sink.Write(ctx, batch)
+		cancel()
+		if err != nil {
+			utils.LogWarnf("Analytics: Sink %s failed to write %d records: %v", sink.Name(), len(batch), err)
+			p.errMu.Lock()
+			p.sinkErrors[sink.Name()]++
+			p.errMu.Unlock()
+		}
+	}
+	p.written.Add(uint64(len(batch)))
+}
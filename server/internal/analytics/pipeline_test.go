@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]Record
+}
+
+func (s *memorySink) Name() string { return "memory" }
+func (s *memorySink) Close() error { return nil }
+func (s *memorySink) Write(_ context.Context, batch []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]Record(nil), batch...))
+	return nil
+}
+
+func TestPipelineBatchesAndSamples(t *testing.T) {
+	sink := &memorySink{}
+	p := NewPipeline([]Sink{sink}, Options{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		SampleRates:   map[string]float64{RecordRoomJoin: 0},
+	})
+	for i := 0; i < 3; i++ {
+		p.Track(Record{Name: RecordLogin, PlayerID: "p1"})
+	}
+	p.Track(Record{Name: RecordRoomJoin, PlayerID: "p1"})
+	p.Close()
+
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 || len(sink.batches[1]) != 1 {
+		t.Fatalf("batches = %v, want sizes [2 1]", sink.batches)
+	}
+	stats := p.Stats()
+	if stats.Written != 3 || stats.SampledOut != 1 {
+		t.Fatalf("stats = %+v, want 3 written and 1 sampled out", stats)
+	}
+}
+
+func TestPipelineSubscribesToBus(t *testing.T) {
+	sink := &memorySink{}
+	p := NewPipeline([]Sink{sink}, Options{})
+	bus := events.NewBus(0)
+	p.Subscribe(bus)
+
+	bus.Publish(events.TopicPlayerLogout, events.PlayerLogout{PlayerID: "p1", Duration: 42})
+	bus.Publish(events.Topic("unknown.topic"), struct{}{})
+	bus.Close()
+	p.Close()
+
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 1 {
+		t.Fatalf("batches = %v, want one session_end record", sink.batches)
+	}
+	record := sink.batches[0][0]
+	if record.Name != RecordSessionEnd || record.PlayerID != "p1" || record.Properties["durationSeconds"] != 42.0 {
+		t.Fatalf("record = %+v", record)
+	}
+}
@@ -0,0 +1,73 @@
+// Package analytics turns gameplay events into structured analytics records
+// and ships them in batches to pluggable sinks (stdout, file, HTTP webhook,
+// Kafka). Gameplay code does not call it directly: the pipeline subscribes to
+// the event bus.
+package analytics
+
+import (
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+)
+
+// Record names.
+const (
+	RecordLogin          = "login"
+	RecordSessionEnd     = "session_end"
+	RecordRoomJoin       = "room_join"
+	RecordPurchase       = "purchase"
+	RecordCombatFinished = "combat_finished"
+	RecordTutorialStep   = "tutorial_step"
+	RecordDeepLinkOpen   = "deep_link_open"
+)
+
+// Record is one analytics data point.
+type Record struct {
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	PlayerID   string                 `json:"playerId,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// recordFromEvent maps a bus event to a record. ok is false for events
+// analytics does not track.
+func recordFromEvent(e events.Event) (Record, bool) {
+	record := Record{Time: e.Time}
+	switch p := e.Payload.(type) {
+	case events.PlayerLogin:
+		record.Name, record.PlayerID = RecordLogin, p.PlayerID
+	case events.PlayerLogout:
+		record.Name, record.PlayerID = RecordSessionEnd, p.PlayerID
+		record.Properties = map[string]interface{}{"durationSeconds": p.Duration}
+	case events.RoomJoined:
+		record.Name, record.PlayerID = RecordRoomJoin, p.PlayerID
+		record.Properties = map[string]interface{}{"roomId": p.RoomID}
+	case events.MarketSold:
+		record.Name, record.PlayerID = RecordPurchase, p.Buyer
+		record.Properties = map[string]interface{}{
+			"listingId": p.ListingID,
+			"nftId":     p.NFTID,
+			"seller":    p.Seller,
+			"price":     p.Price,
+			"txDigest":  p.TxDigest,
+		}
+	case events.CombatFinished:
+		record.Name, record.PlayerID = RecordCombatFinished, p.WinnerID
+		record.Properties = map[string]interface{}{"loserId": p.LoserID, "finalDamage": p.Damage}
+	case events.TutorialStepCompleted:
+		record.Name, record.PlayerID = RecordTutorialStep, p.PlayerID
+		record.Properties = map[string]interface{}{"stepId": p.StepID, "finished": p.Finished}
+	case events.DeepLinkOpened:
+		record.Name, record.PlayerID = RecordDeepLinkOpen, p.PlayerID
+		record.Properties = map[string]interface{}{
+			"linkId":   p.LinkID,
+			"kind":     p.Kind,
+			"target":   p.Target,
+			"source":   p.Source,
+			"campaign": p.Campaign,
+		}
+	default:
+		return Record{}, false
+	}
+	return record, true
+}
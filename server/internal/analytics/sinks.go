@@ -0,0 +1,190 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+)
+
+// Sink receives batches of records. Write is called from a single goroutine.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, batch []Record) error
+	Close() error
+}
+
+// NewSinkFromConfig creates the sink described by cfg.
+func NewSinkFromConfig(cfg configs.AnalyticsSinkConfig) (Sink, error) {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	switch strings.ToLower(cfg.Type) {
+	case "stdout":
+		return NewWriterSink("stdout", os.Stdout), nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("analytics file sink needs a path")
+		}
+		return NewFileSink(cfg.Path)
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("analytics http sink needs a url")
+		}
+		return NewHTTPSink(cfg.URL, cfg.Headers, timeout), nil
+	case "kafka":
+		if cfg.URL == "" || cfg.Topic == "" {
+			return nil, fmt.Errorf("analytics kafka sink needs a url and topic")
+		}
+		return NewKafkaRESTSink(cfg.URL, cfg.Topic, cfg.Headers, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown analytics sink type %q", cfg.Type)
+	}
+}
+
+// WriterSink writes records as JSON lines to an io.Writer.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+// NewWriterSink creates a WriterSink.
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+// Name implements Sink.
+func (s *WriterSink) Name() string { return s.name }
+
+// Write implements Sink.
+func (s *WriterSink) Write(_ context.Context, batch []Record) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range batch {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// Close implements Sink. It closes the writer if it is an io.Closer other than stdout.
+func (s *WriterSink) Close() error {
+	if closer, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewFileSink appends records as JSON lines to the file at path.
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open analytics file %s: %w", path, err)
+	}
+	return NewWriterSink("file:"+path, file), nil
+}
+
+// HTTPSink POSTs each batch as a JSON array to a webhook.
+type HTTPSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTPSink creates an HTTPSink.
+func NewHTTPSink(url string, headers map[string]string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, headers: headers, client: &http.Client{Timeout: timeout}}
+}
+
+// Name implements Sink.
+func (s *HTTPSink) Name() string { return "http:" + s.url }
+
+// Write implements Sink.
+func (s *HTTPSink) Write(ctx context.Context, batch []Record) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, "application/json", s.headers, body)
+}
+
+// Close implements Sink.
+func (s *HTTPSink) Close() error { return nil }
+
+// KafkaRESTSink produces records to a Kafka topic through a Kafka REST proxy
+// (the Confluent REST Proxy v2 API), keyed by player ID so a player's records
+// stay in order within a partition.
+type KafkaRESTSink struct {
+	url     string
+	topic   string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewKafkaRESTSink creates a KafkaRESTSink. baseURL is the REST proxy address, e.g. http://kafka-rest:8082.
+func NewKafkaRESTSink(baseURL, topic string, headers map[string]string, timeout time.Duration) *KafkaRESTSink {
+	return &KafkaRESTSink{
+		url:     strings.TrimRight(baseURL, "/") + "/topics/" + topic,
+		topic:   topic,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Sink.
+func (s *KafkaRESTSink) Name() string { return "kafka:" + s.topic }
+
+// Write implements Sink.
+func (s *KafkaRESTSink) Write(ctx context.Context, batch []Record) error {
+	type kafkaRecord struct {
+		Key   string `json:"key,omitempty"`
+		Value Record `json:"value"`
+	}
+	records := make([]kafkaRecord, len(batch))
+	for i, record := range batch {
+		records[i] = kafkaRecord{Key: record.PlayerID, Value: record}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, "application/vnd.kafka.json.v2+json", s.headers, body)
+}
+
+// Close implements Sink.
+func (s *KafkaRESTSink) Close() error { return nil }
+
+func postJSON(ctx context.Context, client *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return nil
+}
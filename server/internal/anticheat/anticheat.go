@@ -0,0 +1,262 @@
+// Package anticheat checks what clients report against what the server allows
+// and responds to players who keep breaking the rules. Movement is checked for
+// speed: the distance a player covers with plain moves is summed over a
+// sliding window, and a move that would take them faster than the limit is
+// refused. Movement abilities are not counted, since the movement rules already
+// bound them with a range and a cooldown. Violations escalate: each one is
+// corrected, repeated ones get the player reported on the event bus, and
+// persistent ones disconnect them.
+package anticheat
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// CheckSpeed names the speed check in reports.
+const CheckSpeed = "speed"
+
+// sweepThreshold is the number of tracked players above which idle ones are
+// swept.
+const sweepThreshold = 4096
+
+// Action is the response to a reported move.
+type Action int
+
+const (
+	Allow   Action = iota
+	Correct        // Refuse the move and put the player back
+	Flag           // Correct, and the player was reported
+	Kick           // Correct and disconnect the player
+)
+
+func (a Action) String() string {
+	switch a {
+	case Correct:
+		return "correct"
+	case Flag:
+		return "flag"
+	case Kick:
+		return "kick"
+	}
+	return "allow"
+}
+
+// Options configures a Monitor.
+type Options struct {
+	MaxSpeed        float64       // Fastest plain movement, in map units per second; 0 disables the speed check
+	SpeedWindow     time.Duration // Distance is summed over this window; 1s if 0
+	Tolerance       float64       // Extra distance allowed for latency and jitter, as a fraction of MaxSpeed
+	FlagAfter       int           // Violations within ViolationWindow that get a player reported; 0 never reports
+	KickAfter       int           // Violations within ViolationWindow that disconnect a player; 0 never kicks
+	ViolationWindow time.Duration // How long a violation counts towards FlagAfter and KickAfter; 1m if 0
+}
+
+// Verdict is the Monitor's answer to one move.
+type Verdict struct {
+	Action     Action
+	Speed      float64 // Average speed over the window, this move included
+	Violations int     // Violations of the player within ViolationWindow, this one included
+}
+
+// Suspect is a player with recent violations.
+type Suspect struct {
+	PlayerID      string    `json:"playerId"`
+	Violations    int       `json:"violations"`
+	LastViolation time.Time `json:"lastViolation"`
+	TopSpeed      float64   `json:"topSpeed"` // Fastest refused speed within ViolationWindow
+}
+
+// Stats are the Monitor's counters since start and its current suspects,
+// most violations first.
+type Stats struct {
+	Enabled   bool      `json:"enabled"`
+	MaxSpeed  float64   `json:"maxSpeed"`
+	Checked   uint64    `json:"checked"`
+	Corrected uint64    `json:"corrected"`
+	Flagged   uint64    `json:"flagged"`
+	Kicked    uint64    `json:"kicked"`
+	Suspects  []Suspect `json:"suspects"`
+}
+
+// Monitor tracks every player's movement. It is safe for concurrent use, so
+// rooms share one and a player's record follows them from room to room. A
+// nil *Monitor allows everything.
+type Monitor struct {
+	opts Options
+	bus  *events.Bus
+
+	mu      sync.Mutex
+	players map[string]*track
+	stats   Stats
+}
+
+// track is one player's recent moves and violations.
+type track struct {
+	moves      []sample
+	violations []violation
+}
+
+type sample struct {
+	at       time.Time
+	distance float64
+}
+
+type violation struct {
+	at    time.Time
+	speed float64
+}
+
+// New creates a Monitor.
+func New(opts Options) *Monitor {
+	if opts.SpeedWindow <= 0 {
+		opts.SpeedWindow = time.Second
+	}
+	if opts.ViolationWindow <= 0 {
+		opts.ViolationWindow = time.Minute
+	}
+	return &Monitor{opts: opts, players: make(map[string]*track)}
+}
+
+// UseEvents makes the Monitor publish events.TopicCheatSuspected when it
+// reports or disconnects a player.
+func (m *Monitor) UseEvents(bus *events.Bus) {
+	m.bus = bus
+}
+
+// Enabled reports whether the Monitor checks anything.
+func (m *Monitor) Enabled() bool {
+	return m != nil && m.opts.MaxSpeed > 0
+}
+
+// CheckMove checks a plain move of playerID from from to to at now. Allowed
+// moves count towards the player's speed; refused ones do not, as the player
+// is put back.
+func (m *Monitor) CheckMove(playerID string, from, to geometry.Vec, now time.Time) Verdict {
+	if !m.Enabled() {
+		return Verdict{Action: Allow}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Checked++
+	t := m.players[playerID]
+	if t == nil {
+		m.sweep(now)
+		t = &track{}
+		m.players[playerID] = t
+	}
+	t.prune(now, m.opts)
+
+	distance := from.Dist(to)
+	covered := distance
+	for _, s := range t.moves {
+		covered += s.distance
+	}
+	window := m.opts.SpeedWindow.Seconds()
+	speed := covered / window
+	if covered <= m.opts.MaxSpeed*window*(1+m.opts.Tolerance) {
+		t.moves = append(t.moves, sample{at: now, distance: distance})
+		return Verdict{Action: Allow, Speed: speed}
+	}
+
+	t.violations = append(t.violations, violation{at: now, speed: speed})
+	verdict := Verdict{Action: Correct, Speed: speed, Violations: len(t.violations)}
+	m.stats.Corrected++
+	switch {
+	case m.opts.KickAfter > 0 && verdict.Violations >= m.opts.KickAfter:
+		verdict.Action = Kick
+		m.stats.Kicked++
+		t.violations = nil // A player who reconnects starts over
+	case m.opts.FlagAfter > 0 && verdict.Violations == m.opts.FlagAfter:
+		verdict.Action = Flag
+		m.stats.Flagged++
+	default:
+		return verdict
+	}
+	m.report(playerID, verdict, now)
+	return verdict
+}
+
+// report logs a flagged or kicked player and publishes them on the bus.
+// Callers hold m.mu.
+func (m *Monitor) report(playerID string, verdict Verdict, now time.Time) {
+	utils.LogWarnf("Anticheat: %s player %s after %d speed violations within %s (%.1f units/s, limit %.1f)",
+		verdict.Action, playerID, verdict.Violations, m.opts.ViolationWindow, verdict.Speed, m.opts.MaxSpeed)
+	if m.bus == nil {
+		return
+	}
+	m.bus.Publish(events.TopicCheatSuspected, events.CheatSuspected{
+		PlayerID:   playerID,
+		Check:      CheckSpeed,
+		Action:     verdict.Action.String(),
+		Violations: verdict.Violations,
+		Observed:   verdict.Speed,
+		Limit:      m.opts.MaxSpeed,
+		At:         now,
+	})
+}
+
+// Stats returns the counters and the players with violations within
+// ViolationWindow of now.
+func (m *Monitor) Stats(now time.Time) Stats {
+	if m == nil {
+		return Stats{Suspects: []Suspect{}}
+	}
+	m.mu.Lock()
+	stats := m.stats
+	stats.Enabled, stats.MaxSpeed = m.Enabled(), m.opts.MaxSpeed
+	stats.Suspects = []Suspect{}
+	for playerID, t := range m.players {
+		t.prune(now, m.opts)
+		if len(t.violations) == 0 {
+			continue
+		}
+		suspect := Suspect{PlayerID: playerID, Violations: len(t.violations), LastViolation: t.violations[len(t.violations)-1].at}
+		for _, v := range t.violations {
+			suspect.TopSpeed = max(suspect.TopSpeed, v.speed)
+		}
+		stats.Suspects = append(stats.Suspects, suspect)
+	}
+	m.mu.Unlock()
+	sort.Slice(stats.Suspects, func(i, j int) bool {
+		a, b := stats.Suspects[i], stats.Suspects[j]
+		if a.Violations != b.Violations {
+			return a.Violations > b.Violations
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	return stats
+}
+
+// prune drops the moves and violations that have left their windows.
+func (t *track) prune(now time.Time, opts Options) {
+	moves := 0
+	for moves < len(t.moves) && now.Sub(t.moves[moves].at) >= opts.SpeedWindow {
+		moves++
+	}
+	t.moves = t.moves[moves:]
+	violations := 0
+	for violations < len(t.violations) && now.Sub(t.violations[violations].at) >= opts.ViolationWindow {
+		violations++
+	}
+	t.violations = t.violations[violations:]
+}
+
+// sweep forgets players with nothing left in their windows once many are
+// tracked. Callers hold m.mu.
+func (m *Monitor) sweep(now time.Time) {
+	if len(m.players) < sweepThreshold {
+		return
+	}
+	for playerID, t := range m.players {
+		t.prune(now, m.opts)
+		if len(t.moves) == 0 && len(t.violations) == 0 {
+			delete(m.players, playerID)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package anticheat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+func TestSpeedIsMeasuredOverTheWindow(t *testing.T) {
+	m := New(Options{MaxSpeed: 4, SpeedWindow: time.Second, Tolerance: 0.25})
+	start := time.Unix(1000, 0)
+	pos := geometry.Vec{}
+	step := func(dx float64, after time.Duration) Verdict {
+		next := geometry.Vec{X: pos.X + dx}
+		verdict := m.CheckMove("p1", pos, next, start.Add(after))
+		if verdict.Action == Allow {
+			pos = next
+		}
+		return verdict
+	}
+
+	// 5 units within a second is the limit with the tolerance.
+	for i := 0; i < 5; i++ {
+		if v := step(1, time.Duration(i)*100*time.Millisecond); v.Action != Allow {
+			t.Fatalf("move %d: %+v", i+1, v)
+		}
+	}
+	if v := step(1, 500*time.Millisecond); v.Action != Correct || v.Violations != 1 || v.Speed != 6 {
+		t.Fatalf("sixth unit within the second = %+v", v)
+	}
+	// Once the first moves leave the window the player may go on.
+	if v := step(1, 1050*time.Millisecond); v.Action != Allow {
+		t.Errorf("after the window = %+v", v)
+	}
+	if pos.X != 6 {
+		t.Errorf("position = %v", pos)
+	}
+	if !m.Enabled() || New(Options{}).Enabled() {
+		t.Error("only a max speed enables the monitor")
+	}
+	var nilMonitor *Monitor
+	if v := nilMonitor.CheckMove("p1", geometry.Vec{}, geometry.Vec{X: 1000}, start); v.Action != Allow {
+		t.Errorf("nil monitor = %+v", v)
+	}
+}
+
+func TestRepeatedViolationsEscalate(t *testing.T) {
+	bus := events.NewBus(0)
+	defer bus.Close()
+	reported := make(chan events.CheatSuspected, 4)
+	events.On(bus, events.TopicCheatSuspected, "test", func(e events.CheatSuspected) { reported <- e })
+	nextReport := func() events.CheatSuspected {
+		t.Helper()
+		select {
+		case e := <-reported:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("nothing was reported")
+			return events.CheatSuspected{}
+		}
+	}
+	m := New(Options{MaxSpeed: 1, FlagAfter: 2, KickAfter: 3, ViolationWindow: time.Minute})
+	m.UseEvents(bus)
+	start := time.Unix(1000, 0)
+	teleport := func(at time.Duration) Action {
+		return m.CheckMove("cheater", geometry.Vec{}, geometry.Vec{X: 50}, start.Add(at)).Action
+	}
+
+	if a := teleport(0); a != Correct {
+		t.Errorf("first violation: %v", a)
+	}
+	// Violations older than the window no longer count.
+	if a := teleport(2 * time.Minute); a != Correct {
+		t.Errorf("violation after the window: %v", a)
+	}
+	if a := teleport(2*time.Minute + time.Second); a != Flag {
+		t.Errorf("second violation in the window: %v", a)
+	}
+	if got := nextReport(); got.PlayerID != "cheater" || got.Action != "flag" || got.Violations != 2 || got.Check != CheckSpeed {
+		t.Errorf("reported %+v", got)
+	}
+	stats := m.Stats(start.Add(2*time.Minute + 2*time.Second))
+	if len(stats.Suspects) != 1 || stats.Suspects[0].Violations != 2 || stats.Suspects[0].TopSpeed != 50 {
+		t.Errorf("suspects = %+v", stats.Suspects)
+	}
+	if a := teleport(2*time.Minute + 3*time.Second); a != Kick {
+		t.Errorf("third violation in the window: %v", a)
+	}
+	if got := nextReport(); got.Action != "kick" {
+		t.Errorf("reported %+v", got)
+	}
+	stats = m.Stats(start.Add(2*time.Minute + 4*time.Second))
+	if stats.Checked != 4 || stats.Corrected != 4 || stats.Flagged != 1 || stats.Kicked != 1 || len(stats.Suspects) != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,333 @@
+// Package apitoken issues API tokens for the HTTP gateway. Each token names
+// the scopes it may use, such as reading the marketplace or administering
+// players, and has its own rate limit. Only a hash of each token is stored, in
+// a JSON file managed with cmd/apitoken; the server notices changes to the
+// file without a restart, so a revoked token stops working within seconds.
+//
+// A token reads "sgt_<id>_<secret>". The id is not secret: it names the token
+// in logs and in the audit log.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+)
+
+// Prefix starts every API token, telling it apart from the admin token.
+const Prefix = "sgt_"
+
+// Scopes.
+const (
+	ScopeReadStatus   = "read:status"   // GET /status and /arena/leaderboard
+	ScopeReadMarket   = "read:market"   // GET /marketplace/
+	ScopeAdminPlayers = "admin:players" // Privacy requests, player transfers and the audit log
+	ScopeAdminEconomy = "admin:economy" // Balance values and the treasury
+	ScopeAdminOps     = "admin:ops"     // Worlds, webhooks, quarantine, feature flags and chaos
+)
+
+// Scopes lists every scope.
+var Scopes = []string{ScopeReadStatus, ScopeReadMarket, ScopeAdminPlayers, ScopeAdminEconomy, ScopeAdminOps}
+
+// reloadInterval is how often the registry checks the token file for changes.
+const reloadInterval = time.Second
+
+var (
+	ErrInvalid = errors.New("invalid API token")
+	ErrRevoked = errors.New("API token revoked")
+)
+
+// Token is a stored API token.
+type Token struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`      // Who or what uses it, e.g. "launcher"
+	Hash          string     `json:"hash"`      // SHA-256 of the secret, hex encoded
+	Scopes        []string   `json:"scopes"`    // Scopes it may use
+	PerMinute     int        `json:"perMinute"` // Requests allowed per minute; 0 uses the server default
+	Burst         int        `json:"burst"`     // Requests allowed at once; 0 uses the server default
+	CreatedAt     time.Time  `json:"createdAt"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+	RevokedReason string     `json:"revokedReason,omitempty"`
+}
+
+// Revoked reports whether the token was revoked.
+func (t Token) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// HasScope reports whether the token may use scope.
+func (t Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor names the token in the audit log.
+func (t Token) Actor() string {
+	return fmt.Sprintf("token %s (%s)", t.ID, t.Name)
+}
+
+// ValidateScopes checks that every scope is known.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("a token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range Scopes {
+			known = known || s == scope
+		}
+		if !known {
+			return fmt.Errorf("unknown scope %q (want one of %s)", scope, strings.Join(Scopes, ", "))
+		}
+	}
+	return nil
+}
+
+// FileStore keeps tokens in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+// Load returns the stored tokens. A missing file holds none.
+func (f FileStore) Load() ([]Token, error) {
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	err = json.Unmarshal(data, &tokens)
+	return tokens, err
+}
+
+// Save replaces the stored tokens.
+func (f FileStore) Save(tokens []Token) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Create adds a token to store and returns it with its full value, which is
+// shown once and never stored.
+func (f FileStore) Create(name string, scopes []string, perMinute, burst int) (Token, string, error) {
+	if name == "" {
+		return Token{}, "", errors.New("a token needs a name")
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return Token{}, "", err
+	}
+	if perMinute < 0 || burst < 0 {
+		return Token{}, "", errors.New("rate limits cannot be negative")
+	}
+	tokens, err := f.Load()
+	if err != nil {
+		return Token{}, "", err
+	}
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Token{}, "", err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return Token{}, "", err
+	}
+	token := Token{
+		ID:        hex.EncodeToString(idBytes),
+		Name:      name,
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		PerMinute: perMinute,
+		Burst:     burst,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := f.Save(append(tokens, token)); err != nil {
+		return Token{}, "", err
+	}
+	return token, Prefix + token.ID + "_" + secret, nil
+}
+
+// Revoke marks the token id revoked, giving reason.
+func (f FileStore) Revoke(id, reason string) error {
+	tokens, err := f.Load()
+	if err != nil {
+		return err
+	}
+	for i := range tokens {
+		if tokens[i].ID != id {
+			continue
+		}
+		if tokens[i].Revoked() {
+			return fmt.Errorf("token %s is already revoked", id)
+		}
+		now := time.Now().UTC()
+		tokens[i].RevokedAt, tokens[i].RevokedReason = &now, reason
+		return f.Save(tokens)
+	}
+	return fmt.Errorf("no token %s", id)
+}
+
+// RandomSecret returns a random value fit for a bearer token.
+func RandomSecret() (string, error) {
+	return randomString(32)
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Registry checks presented tokens against the store and enforces their
+// rate limits. It is safe for concurrent use.
+type Registry struct {
+	store        FileStore
+	defaultLimit ratelimit.Limit
+	now          func() time.Time
+
+	mu        sync.Mutex
+	tokens    map[string]Token
+	modTime   time.Time
+	checkedAt time.Time
+	buckets   map[string]*ratelimit.Bucket
+}
+
+// NewRegistry loads the tokens in store. Tokens without their own limit get
+// defaultLimit.
+func NewRegistry(store FileStore, defaultLimit ratelimit.Limit) (*Registry, error) {
+	r := &Registry{
+		store:        store,
+		defaultLimit: defaultLimit,
+		now:          time.Now,
+		tokens:       make(map[string]Token),
+		buckets:      make(map[string]*ratelimit.Bucket),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the store if it changed since the last read. It must be called
+// with r.mu held, or before r is shared.
+func (r *Registry) load() error {
+	info, err := os.Stat(r.store.Path)
+	if os.IsNotExist(err) {
+		r.tokens, r.modTime = map[string]Token{}, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+	tokens, err := r.store.Load()
+	if err != nil {
+		return fmt.Errorf("loading API tokens from %s: %w", r.store.Path, err)
+	}
+	r.tokens = make(map[string]Token, len(tokens))
+	for _, token := range tokens {
+		r.tokens[token.ID] = token
+	}
+	r.modTime = info.ModTime()
+	// Limits may have changed; buckets refill from scratch.
+	r.buckets = make(map[string]*ratelimit.Bucket)
+	return nil
+}
+
+// refresh reloads the store at most once per reloadInterval. A store that
+// cannot be read keeps the tokens last loaded. It must be called with r.mu held.
+func (r *Registry) refresh(now time.Time) error {
+	if now.Sub(r.checkedAt) < reloadInterval {
+		return nil
+	}
+	r.checkedAt = now
+	return r.load()
+}
+
+// Authenticate returns the token whose full value is raw.
+func (r *Registry) Authenticate(raw string) (Token, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, Prefix), "_")
+	if !strings.HasPrefix(raw, Prefix) || !ok {
+		return Token{}, ErrInvalid
+	}
+	r.mu.Lock()
+	reloadErr := r.refresh(r.now())
+	token, found := r.tokens[id]
+	r.mu.Unlock()
+	if !found || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(token.Hash)) != 1 {
+		if reloadErr != nil {
+			return Token{}, reloadErr
+		}
+		return Token{}, ErrInvalid
+	}
+	if token.Revoked() {
+		return token, ErrRevoked
+	}
+	return token, nil
+}
+
+// Allow reports whether token may make another request now, and counts it
+// if so.
+func (r *Registry) Allow(token Token) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bucket, ok := r.buckets[token.ID]
+	if !ok {
+		limit := r.defaultLimit
+		if token.PerMinute > 0 {
+			limit = ratelimit.PerMinute(token.PerMinute, max(token.Burst, 1))
+		}
+		if token.Burst > 0 {
+			limit.Burst = token.Burst
+		}
+		bucket = ratelimit.NewBucket(limit)
+		r.buckets[token.ID] = bucket
+	}
+	return bucket.Allow(r.now())
+}
+
+// Tokens returns the stored tokens, without their hashes, ordered by creation.
+func (r *Registry) Tokens() []Token {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refresh(r.now())
+	tokens := make([]Token, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		token.Hash = ""
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens
+}
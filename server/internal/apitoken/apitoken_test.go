@@ -0,0 +1,117 @@
+package apitoken
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "tokens.json")}
+	if _, _, err := store.Create("launcher", []string{"read:everything"}, 0, 0); err == nil {
+		t.Fatal("created a token with an unknown scope")
+	}
+	token, value, err := store.Create("launcher", []string{ScopeReadStatus}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(store.Path)
+	if strings.Contains(string(data), strings.TrimPrefix(value, Prefix+token.ID+"_")) {
+		t.Fatal("the secret was stored")
+	}
+
+	registry, err := NewRegistry(store, ratelimit.PerMinute(60, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := registry.Authenticate(value)
+	if err != nil || got.ID != token.ID || !got.HasScope(ScopeReadStatus) {
+		t.Fatalf("authenticate = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"", "sgt_", Prefix + token.ID + "_wrong", value + "x"} {
+		if _, err := registry.Authenticate(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("authenticate(%q) err = %v", bad, err)
+		}
+	}
+
+	// A revoked token stops working once the registry rereads the file.
+	if err := store.Revoke(token.ID, "leaked"); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(store.Path, later, later)
+	registry.now = func() time.Time { return later }
+	if _, err := registry.Authenticate(value); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked token err = %v", err)
+	}
+}
+
+func TestGateway(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "tokens.json")}
+	_, reader, _ := store.Create("launcher", []string{ScopeReadStatus}, 60, 2)
+	_, admin, _ := store.Create("support", []string{ScopeAdminPlayers}, 0, 0)
+	registry, err := NewRegistry(store, ratelimit.PerMinute(60, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen *http.Request
+	gateway := Gateway{
+		Tokens:     registry,
+		Scope:      func(*http.Request) string { return ScopeAdminPlayers },
+		Anonymous:  true,
+		AdminToken: "internal",
+	}.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = r }))
+	call := func(bearer string) int {
+		seen = nil
+		req := httptest.NewRequest(http.MethodPost, "/admin/privacy/export", nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		gateway.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call("admin-token"); code != http.StatusOK || seen.Header.Get("Authorization") != "Bearer admin-token" {
+		t.Errorf("the admin token did not pass through unchanged: %d", code)
+	}
+	if code := call(admin); code != http.StatusOK {
+		t.Fatalf("scoped token: %d", code)
+	}
+	if seen.Header.Get("Authorization") != "Bearer internal" || !strings.HasPrefix(seen.Header.Get("X-Admin-User"), "token ") {
+		t.Errorf("handler saw %v", seen.Header)
+	}
+	if code := call(reader); code != http.StatusForbidden {
+		t.Errorf("token without the scope: %d", code)
+	}
+	if code := call(Prefix + "nope_nope"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: %d", code)
+	}
+
+	// The reader's limit allows a burst of 2. Requests refused for their scope
+	// do not count.
+	readGateway := Gateway{Tokens: registry, Scope: func(*http.Request) string { return ScopeReadStatus }}.Wrap(http.NotFoundHandler())
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Authorization", "Bearer "+reader)
+		rec := httptest.NewRecorder()
+		readGateway.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusNotFound || codes[1] != http.StatusNotFound || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v", codes)
+	}
+	rec := httptest.NewRecorder()
+	readGateway.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous read with tokens required: %d", rec.Code)
+	}
+}
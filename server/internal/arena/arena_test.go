@@ -0,0 +1,118 @@
+package arena
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+)
+
+func TestRatingChangeFavoursUpsets(t *testing.T) {
+	even := RatingChange(1500, 1500, 32)
+	upset := RatingChange(1300, 1700, 32)
+	expected := RatingChange(1700, 1300, 32)
+	if even != 16 || upset <= even || expected >= even {
+		t.Fatalf("changes even=%d upset=%d expected=%d", even, upset, expected)
+	}
+}
+
+func TestMatchmakerWidensWindowWithWait(t *testing.T) {
+	now := time.Now()
+	m := NewMatchmaker(100, 10)
+	m.Add(Mode1v1, "low", 1400, now)
+	m.Add(Mode1v1, "high", 1600, now)
+	if got := m.Match(now); len(got) != 0 {
+		t.Fatalf("matched %v before the window widened", got)
+	}
+	got := m.Match(now.Add(15 * time.Second))
+	if len(got) != 1 || got[0].TeamA[0] != "low" || got[0].TeamB[0] != "high" {
+		t.Fatalf("matches = %+v, want low vs high", got)
+	}
+}
+
+func TestMatchmakerBalancesTeams(t *testing.T) {
+	now := time.Now()
+	m := NewMatchmaker(1000, 0)
+	for _, p := range []struct {
+		id     string
+		rating int
+	}{{"a", 1000}, {"b", 1100}, {"c", 1200}, {"d", 1300}} {
+		m.Add(Mode2v2, p.id, p.rating, now)
+	}
+	got := m.Match(now)
+	if len(got) != 1 {
+		t.Fatalf("matches = %+v", got)
+	}
+	if a, b := got[0].TeamA, got[0].TeamB; a[0] != "a" || a[1] != "d" || b[0] != "b" || b[1] != "c" {
+		t.Fatalf("teams = %v vs %v, want [a d] vs [b c]", a, b)
+	}
+}
+
+func TestMatchmakerGroupsByLatencyAndRegion(t *testing.T) {
+	now := time.Now()
+	m := NewMatchmaker(1000, 0)
+	m.UseLatency(LatencyRules{Window: 30 * time.Millisecond, WidenPerSecond: 10 * time.Millisecond, MaxSpread: 60 * time.Millisecond})
+	m.Queue(Mode1v1, Entry{PlayerID: "near", Rating: 1500, Latency: 20 * time.Millisecond, Regions: []string{"eu"}}, now)
+	m.Queue(Mode1v1, Entry{PlayerID: "far", Rating: 1510, Latency: 120 * time.Millisecond, Regions: []string{"eu"}}, now)
+	m.Queue(Mode1v1, Entry{PlayerID: "us", Rating: 1520, Latency: 30 * time.Millisecond, Regions: []string{"us"}}, now)
+	m.Queue(Mode1v1, Entry{PlayerID: "close", Rating: 1530, Latency: 40 * time.Millisecond, Regions: []string{"us", "eu"}}, now)
+
+	got := m.Match(now)
+	if len(got) != 1 || got[0].TeamA[0] != "near" || got[0].TeamB[0] != "close" || got[0].Region != "eu" || got[0].Latency != 40*time.Millisecond {
+		t.Fatalf("matches = %+v, want near vs close in eu", got)
+	}
+	// The spread widens with waiting, but never past MaxSpread.
+	m.Queue(Mode1v1, Entry{PlayerID: "late", Rating: 1500, Latency: 70 * time.Millisecond, Regions: []string{"eu"}}, now)
+	if got := m.Match(now.Add(time.Minute)); len(got) != 1 || got[0].TeamA[0] != "late" || got[0].TeamB[0] != "far" {
+		t.Fatalf("matches after waiting = %+v, want late vs far", got)
+	}
+	if m.Len() != 1 {
+		t.Fatalf("queued = %d, want us left alone", m.Len())
+	}
+}
+
+func TestMatchUpdatesRatingsAndSeasonQueuesTrophies(t *testing.T) {
+	rewards := outbox.New(outbox.NewMemoryStore(), outbox.Options{})
+	s, err := NewService(Options{
+		KFactor:      32,
+		SeasonLength: time.Hour,
+		BaseWindow:   100,
+		RewardTiers:  []RewardTier{{MaxRank: 1, Trophy: "champion"}},
+	}, &MemoryStore{}, rewards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := make(map[string][]interface{})
+	for _, id := range []string{"alice", "bob"} {
+		id := id
+		if _, err := s.Join(id, Mode1v1, Preferences{}, func(note interface{}) { notes[id] = append(notes[id], note) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Join("alice", Mode1v1, Preferences{}, nil); err != ErrAlreadyQueued {
+		t.Fatalf("second join error = %v, want ErrAlreadyQueued", err)
+	}
+	s.Tick(time.Now())
+	if len(notes["alice"]) != 1 {
+		t.Fatalf("alice notifications = %v, want MatchFound", notes["alice"])
+	}
+	s.RecordDefeat("bob")
+	result, ok := notes["alice"][1].(*MatchResult)
+	if !ok || !result.Won || result.RatingAfter != DefaultRating+16 {
+		t.Fatalf("alice result = %+v", notes["alice"][1])
+	}
+	if board := s.Leaderboard(0); len(board) != 2 || board[0].PlayerID != "alice" || board[1].Rating != DefaultRating-16 {
+		t.Fatalf("leaderboard = %+v", board)
+	}
+
+	s.Tick(time.Now().Add(2 * time.Hour))
+	if season := s.Season(); season.Number != 2 {
+		t.Fatalf("season = %d, want 2", season.Number)
+	}
+	if stats := rewards.Stats(); stats.Pending != 1 {
+		t.Fatalf("queued trophies = %d, want 1 (champion)", stats.Pending)
+	}
+	if board := s.Leaderboard(0); len(board) != 0 {
+		t.Fatalf("new season leaderboard = %+v, want empty", board)
+	}
+}
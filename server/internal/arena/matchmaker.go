@@ -0,0 +1,272 @@
+package arena
+
+import (
+	"sort"
+	"time"
+)
+
+// Mode is an arena queue.
+type Mode string
+
+const (
+	Mode1v1 Mode = "1v1"
+	Mode2v2 Mode = "2v2"
+	Mode3v3 Mode = "3v3"
+)
+
+// TeamSize returns the players per team, or 0 for an unknown mode.
+func (m Mode) TeamSize() int {
+	switch m {
+	case Mode1v1:
+		return 1
+	case Mode2v2:
+		return 2
+	case Mode3v3:
+		return 3
+	}
+	return 0
+}
+
+// Pairing is a match formed by the matchmaker.
+type Pairing struct {
+	Mode    Mode
+	TeamA   []string
+	TeamB   []string
+	Region  string        // Region every player accepts; empty if none of them chose
+	Latency time.Duration // Highest measured round trip among the players; 0 if none was measured
+}
+
+// Entry is a player joining a queue.
+type Entry struct {
+	PlayerID string
+	Rating   int
+	Latency  time.Duration // Measured round trip to the server; 0 if unknown
+	Regions  []string      // Regions the player accepts, preferred first; any if empty
+}
+
+// LatencyRules keep players with very different round trips apart. The
+// accepted spread starts at Window and widens the longer a player waits, up
+// to MaxSpread. Players with no measured latency fit any match.
+type LatencyRules struct {
+	Window         time.Duration // Spread accepted immediately; latency is ignored if zero
+	WidenPerSecond time.Duration // Extra spread per second of waiting
+	MaxSpread      time.Duration // Widest spread ever accepted; no limit if zero
+}
+
+type ticket struct {
+	Entry
+	queuedAt time.Time
+}
+
+// Matchmaker groups queued players of similar rating and latency who share a
+// region. The accepted rating spread starts at baseWindow and widens the
+// longer a player waits. It is not safe for concurrent use.
+type Matchmaker struct {
+	queues      map[Mode][]ticket
+	baseWindow  int
+	widenPerSec float64
+	latency     LatencyRules
+}
+
+// NewMatchmaker creates an empty Matchmaker.
+func NewMatchmaker(baseWindow int, widenPerSecond float64) *Matchmaker {
+	return &Matchmaker{queues: make(map[Mode][]ticket), baseWindow: baseWindow, widenPerSec: widenPerSecond}
+}
+
+// UseLatency makes the matchmaker keep players apart by latency.
+func (m *Matchmaker) UseLatency(rules LatencyRules) {
+	m.latency = rules
+}
+
+// Add queues a player with no latency or region preference.
+func (m *Matchmaker) Add(mode Mode, playerID string, rating int, now time.Time) {
+	m.Queue(mode, Entry{PlayerID: playerID, Rating: rating}, now)
+}
+
+// Queue queues a player.
+func (m *Matchmaker) Queue(mode Mode, entry Entry, now time.Time) {
+	m.queues[mode] = append(m.queues[mode], ticket{Entry: entry, queuedAt: now})
+}
+
+// Remove takes a player out of whichever queue they are in.
+func (m *Matchmaker) Remove(playerID string) bool {
+	for mode, queue := range m.queues {
+		for i, t := range queue {
+			if t.PlayerID == playerID {
+				m.queues[mode] = append(queue[:i], queue[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Len returns the number of queued players.
+func (m *Matchmaker) Len() int {
+	n := 0
+	for _, queue := range m.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// Match forms as many matches as the current queues allow. Players are
+// taken in rating order; each one still free anchors a group of the next
+// players it is compatible with.
+func (m *Matchmaker) Match(now time.Time) []Pairing {
+	var pairings []Pairing
+	for mode, queue := range m.queues {
+		size := 2 * mode.TeamSize()
+		if size == 0 || len(queue) < size {
+			continue
+		}
+		sort.SliceStable(queue, func(i, j int) bool { return queue[i].Rating < queue[j].Rating })
+		matched := make([]bool, len(queue))
+		for i := range queue {
+			if matched[i] {
+				continue
+			}
+			group, members := []ticket{queue[i]}, []int{i}
+			for j := i + 1; j < len(queue) && len(group) < size; j++ {
+				if !matched[j] && m.fits(append(group, queue[j]), now) {
+					group, members = append(group, queue[j]), append(members, j)
+				}
+			}
+			if len(group) < size {
+				continue
+			}
+			for _, member := range members {
+				matched[member] = true
+			}
+			pairing := split(mode, group)
+			pairing.Region = commonRegion(group)
+			pairing.Latency = highestLatency(group)
+			pairings = append(pairings, pairing)
+		}
+		var remaining []ticket
+		for i, t := range queue {
+			if !matched[i] {
+				remaining = append(remaining, t)
+			}
+		}
+		m.queues[mode] = remaining
+	}
+	return pairings
+}
+
+// fits reports whether a rating-sorted group could play together.
+func (m *Matchmaker) fits(group []ticket, now time.Time) bool {
+	last := len(group) - 1
+	if group[last].Rating-group[0].Rating > m.window(group, now) {
+		return false
+	}
+	if m.latency.Window > 0 {
+		lowest, highest := time.Duration(0), time.Duration(0)
+		for _, t := range group {
+			if t.Latency <= 0 {
+				continue
+			}
+			if lowest == 0 || t.Latency < lowest {
+				lowest = t.Latency
+			}
+			if t.Latency > highest {
+				highest = t.Latency
+			}
+		}
+		if highest-lowest > m.latencyWindow(group, now) {
+			return false
+		}
+	}
+	return commonRegion(group) != "" || !anyRegions(group)
+}
+
+// window is the rating spread accepted for group, based on its longest wait.
+func (m *Matchmaker) window(group []ticket, now time.Time) int {
+	return m.baseWindow + int(m.widenPerSec*longestWait(group, now).Seconds())
+}
+
+// latencyWindow is the latency spread accepted for group, based on its
+// longest wait.
+func (m *Matchmaker) latencyWindow(group []ticket, now time.Time) time.Duration {
+	spread := m.latency.Window + time.Duration(float64(m.latency.WidenPerSecond)*longestWait(group, now).Seconds())
+	if m.latency.MaxSpread > 0 && spread > m.latency.MaxSpread {
+		spread = m.latency.MaxSpread
+	}
+	return spread
+}
+
+func longestWait(group []ticket, now time.Time) time.Duration {
+	oldest := group[0].queuedAt
+	for _, t := range group[1:] {
+		if t.queuedAt.Before(oldest) {
+			oldest = t.queuedAt
+		}
+	}
+	return now.Sub(oldest)
+}
+
+// commonRegion returns the region every player in group accepts, picked in
+// the order of whoever waited longest, or "" if there is none.
+func commonRegion(group []ticket) string {
+	first := group[0]
+	for _, t := range group[1:] {
+		if len(t.Regions) > 0 && (len(first.Regions) == 0 || t.queuedAt.Before(first.queuedAt)) {
+			first = t
+		}
+	}
+	for _, region := range first.Regions {
+		accepted := true
+		for _, t := range group {
+			if len(t.Regions) > 0 && !contains(t.Regions, region) {
+				accepted = false
+				break
+			}
+		}
+		if accepted {
+			return region
+		}
+	}
+	return ""
+}
+
+func anyRegions(group []ticket) bool {
+	for _, t := range group {
+		if len(t.Regions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func highestLatency(group []ticket) time.Duration {
+	var highest time.Duration
+	for _, t := range group {
+		if t.Latency > highest {
+			highest = t.Latency
+		}
+	}
+	return highest
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// split divides a rating-sorted group into teams with a snake draft
+// (A B B A A B ...) so team averages stay close.
+func split(mode Mode, group []ticket) Pairing {
+	pairing := Pairing{Mode: mode}
+	for i, t := range group {
+		if i%4 == 0 || i%4 == 3 {
+			pairing.TeamA = append(pairing.TeamA, t.PlayerID)
+		} else {
+			pairing.TeamB = append(pairing.TeamB, t.PlayerID)
+		}
+	}
+	return pairing
+}
@@ -0,0 +1,17 @@
+package arena
+
+import "math"
+
+// DefaultRating is the rating of a player's first match of a season.
+const DefaultRating = 1500
+
+// ExpectedScore is the Elo probability that a player rated rating beats one rated opponent.
+func ExpectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// RatingChange returns the points the winner gains and the loser loses. Team
+// matches use each team's average rating.
+func RatingChange(winner, loser, kFactor float64) int {
+	return int(math.Round(kFactor * (1 - ExpectedScore(winner, loser))))
+}
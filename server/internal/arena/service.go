@@ -0,0 +1,430 @@
+// Package arena runs ranked PvP: 1v1 and team queues, Elo ratings updated from
+// combat results, seasonal leaderboards, and trophy rewards at season end.
+package arena
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// TrophyMintKind is the outbox message kind for season trophies. Its payload is a TrophyReward.
+const TrophyMintKind = "arena.trophy_mint"
+
+var (
+	ErrUnknownMode   = errors.New("unknown arena mode")
+	ErrAlreadyQueued = errors.New("already in an arena queue")
+	ErrInMatch       = errors.New("already in an arena match")
+	ErrLatency       = errors.New("your connection is too slow for the arena")
+	ErrUnknownRegion = errors.New("unknown arena region")
+)
+
+// RewardTier grants Trophy to players ranked MaxRank or better. Tiers are
+// checked in order, so list the best tier first.
+type RewardTier = configs.ArenaRewardTier
+
+// TrophyReward is the payload of a TrophyMintKind outbox message.
+type TrophyReward struct {
+	Season   int    `json:"season"`
+	PlayerID string `json:"playerId"`
+	Rank     int    `json:"rank"`
+	Rating   int    `json:"rating"`
+	Trophy   string `json:"trophy"`
+}
+
+// MatchFound is sent to each player of a new match.
+type MatchFound struct {
+	MatchID string
+	Mode    Mode
+	TeamA   []string
+	TeamB   []string
+	Region  string        // Empty if no player chose one
+	Latency time.Duration // Highest measured round trip among the players; 0 if none was measured
+}
+
+// Preferences are what a player brings to the queue besides their rating.
+type Preferences struct {
+	Latency time.Duration // Measured round trip to the server; 0 if unknown
+	Regions []string      // Regions the player accepts, preferred first; any if empty
+}
+
+// MatchResult is sent to each player when their match ends.
+type MatchResult struct {
+	MatchID      string
+	Won          bool
+	RatingBefore int
+	RatingAfter  int
+}
+
+// Notifier delivers a MatchFound or MatchResult to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	KFactor        float64
+	SeasonLength   time.Duration
+	BaseWindow     int     // Rating spread accepted immediately
+	WidenPerSecond float64 // Extra spread per second of waiting
+	RewardTiers    []RewardTier
+	TickInterval   time.Duration
+	Latency        LatencyRules
+	MaxLatency     time.Duration // Players with a slower measured round trip cannot queue; no limit if zero
+	Regions        []string      // Regions players may choose; any if empty
+}
+
+type match struct {
+	id       string
+	mode     Mode
+	teams    [2][]string
+	defeated map[string]bool
+}
+
+// Service is the arena. It is safe for concurrent use by session actors.
+type Service struct {
+	opts    Options
+	store   Store
+	rewards *outbox.Outbox
+
+	mu         sync.Mutex
+	state      State
+	matchmaker *Matchmaker
+	notifiers  map[string]Notifier // Queued or matched players
+	matches    map[string]*match
+	playing    map[string]string // PlayerID -> match ID
+	nextMatch  int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService loads the arena state from store, starting season 1 if there is none.
+// Season rewards are queued in rewards; a nil outbox skips them.
+func NewService(opts Options, store Store, rewards *outbox.Outbox) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading arena state: %w", err)
+	}
+	if state.Standings == nil {
+		state.Standings = make(map[string]Standing)
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = 2 * time.Second
+	}
+	s := &Service{
+		opts:       opts,
+		store:      store,
+		rewards:    rewards,
+		state:      state,
+		matchmaker: NewMatchmaker(opts.BaseWindow, opts.WidenPerSecond),
+		notifiers:  make(map[string]Notifier),
+		matches:    make(map[string]*match),
+		playing:    make(map[string]string),
+		stop:       make(chan struct{}),
+	}
+	s.matchmaker.UseLatency(opts.Latency)
+	if state.Season.Number == 0 {
+		s.startSeasonLocked(1, time.Now())
+	}
+	return s, nil
+}
+
+// NewServiceFromConfig creates a Service from configuration, with a FileStore
+// at cfg.StateFile. regions are the regions players may choose.
+func NewServiceFromConfig(cfg configs.ArenaConfig, regions []string, rewards *outbox.Outbox) (*Service, error) {
+	return NewService(Options{
+		KFactor:        cfg.KFactor,
+		SeasonLength:   time.Duration(cfg.SeasonDays) * 24 * time.Hour,
+		BaseWindow:     cfg.RatingWindow,
+		WidenPerSecond: cfg.WindowGrowthPerSecond,
+		RewardTiers:    cfg.RewardTiers,
+		Latency: LatencyRules{
+			Window:         time.Duration(cfg.LatencyWindowMs) * time.Millisecond,
+			WidenPerSecond: time.Duration(cfg.LatencyGrowthPerSecond) * time.Millisecond,
+			MaxSpread:      time.Duration(cfg.MaxLatencySpreadMs) * time.Millisecond,
+		},
+		MaxLatency: time.Duration(cfg.MaxLatencyMs) * time.Millisecond,
+		Regions:    regions,
+	}, FileStore{Path: cfg.StateFile}, rewards)
+}
+
+// Start runs matchmaking and season rollover in the background.
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.Tick(now)
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Subscribe updates matches from combat.finished events on bus.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicCombatFinished, "arena", func(e events.CombatFinished) {
+		s.RecordDefeat(e.LoserID)
+	})
+}
+
+// Join queues a player for mode and returns their current rating. notify
+// receives the player's MatchFound and MatchResult notifications.
+func (s *Service) Join(playerID string, mode Mode, prefs Preferences, notify Notifier) (int, error) {
+	if mode.TeamSize() == 0 {
+		return 0, ErrUnknownMode
+	}
+	if s.opts.MaxLatency > 0 && prefs.Latency > s.opts.MaxLatency {
+		return 0, ErrLatency
+	}
+	if len(s.opts.Regions) > 0 {
+		for _, region := range prefs.Regions {
+			if !contains(s.opts.Regions, region) {
+				return 0, ErrUnknownRegion
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, playing := s.playing[playerID]; playing {
+		return 0, ErrInMatch
+	}
+	if _, queued := s.notifiers[playerID]; queued {
+		return 0, ErrAlreadyQueued
+	}
+	rating := s.standingLocked(playerID).Rating
+	s.notifiers[playerID] = notify
+	s.matchmaker.Queue(mode, Entry{PlayerID: playerID, Rating: rating, Latency: prefs.Latency, Regions: prefs.Regions}, time.Now())
+	return rating, nil
+}
+
+// Leave takes a player out of the queue. A player who leaves a running match
+// forfeits: they count as defeated.
+func (s *Service) Leave(playerID string) {
+	s.mu.Lock()
+	if s.matchmaker.Remove(playerID) {
+		delete(s.notifiers, playerID)
+		s.mu.Unlock()
+		return
+	}
+	deliveries := s.recordDefeatLocked(playerID)
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// Queued returns the number of players waiting for a match.
+func (s *Service) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.matchmaker.Len()
+}
+
+// RecordDefeat marks a player in a running match as defeated. The match ends
+// when every member of one team is defeated.
+func (s *Service) RecordDefeat(playerID string) {
+	s.mu.Lock()
+	deliveries := s.recordDefeatLocked(playerID)
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// Tick forms matches from the queues and ends the season if it is over.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	var deliveries []func()
+	for _, pairing := range s.matchmaker.Match(now) {
+		s.nextMatch++
+		m := &match{
+			id:       fmt.Sprintf("arena-%d-%d", s.state.Season.Number, s.nextMatch),
+			mode:     pairing.Mode,
+			teams:    [2][]string{pairing.TeamA, pairing.TeamB},
+			defeated: make(map[string]bool),
+		}
+		s.matches[m.id] = m
+		note := &MatchFound{MatchID: m.id, Mode: m.mode, TeamA: pairing.TeamA, TeamB: pairing.TeamB, Region: pairing.Region, Latency: pairing.Latency}
+		for _, team := range m.teams {
+			for _, playerID := range team {
+				s.playing[playerID] = m.id
+				if notify := s.notifiers[playerID]; notify != nil {
+					deliveries = append(deliveries, func() { notify(note) })
+				}
+			}
+		}
+		utils.LogInfof("Arena: Match %s (%s) formed: %v vs %v.", m.id, m.mode, pairing.TeamA, pairing.TeamB)
+	}
+	if !now.Before(s.state.Season.EndsAt) {
+		s.endSeasonLocked(now)
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// Season returns the current season.
+func (s *Service) Season() Season {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Season
+}
+
+// Leaderboard returns the top limit standings of the current season (all if limit <= 0).
+func (s *Service) Leaderboard(limit int) []Standing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	board := s.leaderboardLocked()
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	return board
+}
+
+func (s *Service) leaderboardLocked() []Standing {
+	board := make([]Standing, 0, len(s.state.Standings))
+	for _, standing := range s.state.Standings {
+		board = append(board, standing)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].Rating != board[j].Rating {
+			return board[i].Rating > board[j].Rating
+		}
+		return board[i].Wins > board[j].Wins
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+	}
+	return board
+}
+
+func (s *Service) standingLocked(playerID string) Standing {
+	standing, ok := s.state.Standings[playerID]
+	if !ok {
+		standing = Standing{PlayerID: playerID, Rating: DefaultRating}
+	}
+	return standing
+}
+
+// recordDefeatLocked returns the notifications to deliver once the lock is released.
+func (s *Service) recordDefeatLocked(playerID string) []func() {
+	m, ok := s.matches[s.playing[playerID]]
+	if !ok {
+		return nil
+	}
+	m.defeated[playerID] = true
+	for i, team := range m.teams {
+		if allDefeated(team, m.defeated) {
+			return s.finishMatchLocked(m, 1-i)
+		}
+	}
+	return nil
+}
+
+// finishMatchLocked applies rating changes and returns the result notifications.
+func (s *Service) finishMatchLocked(m *match, winner int) []func() {
+	loser := 1 - winner
+	change := RatingChange(s.averageRatingLocked(m.teams[winner]), s.averageRatingLocked(m.teams[loser]), s.opts.KFactor)
+	var results []func()
+	for i, team := range m.teams {
+		for _, playerID := range team {
+			standing := s.standingLocked(playerID)
+			result := &MatchResult{MatchID: m.id, Won: i == winner, RatingBefore: standing.Rating}
+			if result.Won {
+				standing.Rating += change
+				standing.Wins++
+			} else {
+				standing.Rating -= change
+				standing.Losses++
+			}
+			result.RatingAfter = standing.Rating
+			s.state.Standings[playerID] = standing
+			if notify := s.notifiers[playerID]; notify != nil {
+				results = append(results, func() { notify(result) })
+			}
+			delete(s.notifiers, playerID)
+			delete(s.playing, playerID)
+		}
+	}
+	delete(s.matches, m.id)
+	utils.LogInfof("Arena: Match %s won by %v (%+d rating).", m.id, m.teams[winner], change)
+	s.saveLocked()
+	return results
+}
+
+func (s *Service) averageRatingLocked(team []string) float64 {
+	total := 0
+	for _, playerID := range team {
+		total += s.standingLocked(playerID).Rating
+	}
+	return float64(total) / float64(len(team))
+}
+
+// endSeasonLocked queues trophies for the final leaderboard and starts the next season.
+func (s *Service) endSeasonLocked(now time.Time) {
+	season := s.state.Season.Number
+	board := s.leaderboardLocked()
+	rewarded := 0
+	for _, standing := range board {
+		trophy := s.trophyFor(standing.Rank)
+		if trophy == "" || s.rewards == nil {
+			continue
+		}
+		reward := TrophyReward{Season: season, PlayerID: standing.PlayerID, Rank: standing.Rank, Rating: standing.Rating, Trophy: trophy}
+		id := fmt.Sprintf("arena:season-%d:%s", season, standing.PlayerID)
+		if _, err := s.rewards.Enqueue(id, TrophyMintKind, reward); err != nil {
+			utils.LogErrorf("Arena: Could not queue season %d trophy for %s: %v", season, standing.PlayerID, err)
+			continue
+		}
+		rewarded++
+	}
+	utils.LogInfof("Arena: Season %d ended with %d ranked players; %d trophies queued.", season, len(board), rewarded)
+	s.startSeasonLocked(season+1, now)
+}
+
+func (s *Service) trophyFor(rank int) string {
+	for _, tier := range s.opts.RewardTiers {
+		if rank <= tier.MaxRank {
+			return tier.Trophy
+		}
+	}
+	return ""
+}
+
+func (s *Service) startSeasonLocked(number int, now time.Time) {
+	s.state.Season = Season{Number: number, StartsAt: now, EndsAt: now.Add(s.opts.SeasonLength)}
+	s.state.Standings = make(map[string]Standing)
+	utils.LogInfof("Arena: Season %d started; it ends %s.", number, s.state.Season.EndsAt.Format(time.RFC3339))
+	s.saveLocked()
+}
+
+func (s *Service) saveLocked() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Arena: Could not save arena state: %v", err)
+	}
+}
+
+func deliver(deliveries []func()) {
+	for _, d := range deliveries {
+		d()
+	}
+}
+
+func allDefeated(team []string, defeated map[string]bool) bool {
+	for _, playerID := range team {
+		if !defeated[playerID] {
+			return false
+		}
+	}
+	return true
+}
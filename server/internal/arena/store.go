@@ -0,0 +1,38 @@
+package arena
+
+import (
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/jsonstore"
+)
+
+// Season is one ranked season. Ratings reset when a new season starts.
+type Season struct {
+	Number   int       `json:"number"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+// Standing is a player's record for the current season.
+type Standing struct {
+	PlayerID string `json:"playerId"`
+	Rating   int    `json:"rating"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+	Rank     int    `json:"rank,omitempty"` // Set in leaderboards
+}
+
+// State is the persisted arena state.
+type State struct {
+	Season    Season              `json:"season"`
+	Standings map[string]Standing `json:"standings"`
+}
+
+// Store persists the arena state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the arena state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the arena state in a JSON file.
+type FileStore = jsonstore.File[State]
@@ -0,0 +1,252 @@
+// Package audit keeps an append-only log of security-relevant actions: auth
+// attempts, admin commands, on-chain transactions and trades. Each entry
+// carries the hash of the one before it, so editing, removing or reordering
+// entries breaks the chain, which Verify reports. The log backs dispute
+// resolution and incident forensics through the admin API.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Actions.
+const (
+	ActionAuth    = "auth"     // A session presented a token
+	ActionAdmin   = "admin"    // An admin API command
+	ActionChainTx = "chain_tx" // A transaction executed on Sui
+	ActionTrade   = "trade"    // A trade was proposed or changed status
+	ActionGift    = "gift"     // A gift was offered or changed status
+)
+
+// Results.
+const (
+	ResultOK     = "ok"
+	ResultDenied = "denied"
+	ResultFailed = "failed"
+)
+
+// maxLineSize bounds an entry when reading the log back.
+const maxLineSize = 1 << 20
+
+// Entry is one audited action.
+type Entry struct {
+	Seq      uint64    `json:"seq"`                // Position in the log, from 1
+	Time     time.Time `json:"time"`               // UTC
+	Action   string    `json:"action"`             // One of the Action constants
+	PlayerID string    `json:"playerId,omitempty"` // The player the action concerns
+	Peer     string    `json:"peer,omitempty"`     // The other player of a trade
+	Actor    string    `json:"actor,omitempty"`    // Who acted if not the player: admin operator, remote address or signing address
+	Target   string    `json:"target,omitempty"`   // What was acted on: admin path, transaction digest or trade ID
+	Result   string    `json:"result"`             // ResultOK, ResultDenied, ResultFailed or an action-specific status
+	Detail   string    `json:"detail,omitempty"`
+	Prev     string    `json:"prev"` // Hash of the previous entry; empty for the first
+	Hash     string    `json:"hash"` // SHA-256 of Prev and the fields above
+}
+
+// computeHash hashes the entry's fields in a fixed order, so the hash does not
+// depend on how the JSON was encoded.
+func (e Entry) computeHash() string {
+	fields := []string{
+		e.Prev,
+		strconv.FormatUint(e.Seq, 10),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Action, e.PlayerID, e.Peer, e.Actor, e.Target, e.Result, e.Detail,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainError reports where the hash chain breaks.
+type ChainError struct {
+	Line   int // 1-based line of the offending entry
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit log line %d: %s", e.Line, e.Reason)
+}
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	PlayerID string // Matches PlayerID or Peer
+	Action   string
+	Since    time.Time // Inclusive
+	Until    time.Time // Exclusive
+	Limit    int       // The most recent Limit matches; all if zero
+}
+
+func (f Filter) matches(e Entry) bool {
+	return (f.PlayerID == "" || e.PlayerID == f.PlayerID || e.Peer == f.PlayerID) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+// Log is a hash-chained audit log in a file of JSON lines. A nil *Log records
+// nothing, so services can take one optionally.
+type Log struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64 // Seq of the last entry
+	last string // Hash of the last entry
+}
+
+// Open opens (or creates) the audit log at path and continues its chain. A
+// log that fails verification is still opened, with an error logged; new
+// entries chain from its last entry, and Verify keeps reporting the break.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, now: time.Now}
+	var last Entry
+	if _, err := l.scan(func(e Entry) { last = e }); err != nil {
+		if _, isChain := err.(*ChainError); !isChain {
+			return nil, fmt.Errorf("read audit log %s: %w", path, err)
+		}
+		utils.LogErrorf("Audit: %s fails verification: %v", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log %s: %w", path, err)
+	}
+	l.file, l.seq, l.last = file, last.Seq, last.Hash
+	return l, nil
+}
+
+// Record appends entry, filling in Seq, Time, Prev and Hash, and syncs it to
+// disk. Failures are logged as well as returned, so callers that cannot act on
+// them may ignore the error.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.Seq = l.seq + 1
+	entry.Time = l.now().UTC()
+	entry.Prev = l.last
+	entry.Hash = entry.computeHash()
+	line, err := json.Marshal(entry)
+	if err == nil {
+		if _, err = l.file.Write(append(line, '\n')); err == nil {
+			err = l.file.Sync()
+		}
+	}
+	if err != nil {
+		utils.LogErrorf("Audit: could not record %s for %q: %v", entry.Action, entry.PlayerID, err)
+		return err
+	}
+	l.seq, l.last = entry.Seq, entry.Hash
+	return nil
+}
+
+// Head returns the sequence number and hash of the last entry. The chain
+// cannot show entries cut from its end, so operators may note the head
+// elsewhere to compare against later.
+func (l *Log) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.last
+}
+
+// Query returns the entries matching filter, oldest first.
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	var matches []Entry
+	_, err := l.scan(func(e Entry) {
+		if !filter.matches(e) {
+			return
+		}
+		matches = append(matches, e)
+		if filter.Limit > 0 && len(matches) > filter.Limit {
+			matches = matches[1:]
+		}
+	})
+	if _, isChain := err.(*ChainError); isChain {
+		err = nil // Entries after a break are still evidence
+	}
+	return matches, err
+}
+
+// Verify checks the whole chain and returns the number of entries in the log.
+// A break is returned as a *ChainError.
+func (l *Log) Verify() (int, error) {
+	return l.scan(func(Entry) {})
+}
+
+// scan reads the log, calling visit for every entry that parses, and checks
+// the chain as it goes. It returns the first break found.
+func (l *Log) scan(visit func(Entry)) (int, error) {
+	if l.file != nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+	}
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var (
+		count    int
+		prev     string
+		prevSeq  uint64
+		chainErr *ChainError
+	)
+	broken := func(line int, format string, args ...interface{}) {
+		if chainErr == nil {
+			chainErr = &ChainError{Line: line, Reason: fmt.Sprintf(format, args...)}
+		}
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			broken(line, "unreadable entry: %v", err)
+			continue
+		}
+		count++
+		switch {
+		case e.Seq != prevSeq+1:
+			broken(line, "sequence %d follows %d", e.Seq, prevSeq)
+		case e.Prev != prev:
+			broken(line, "entry %d does not chain to the entry before it", e.Seq)
+		case e.computeHash() != e.Hash:
+			broken(line, "entry %d does not match its hash", e.Seq)
+		}
+		prev, prevSeq = e.Hash, e.Seq
+		visit(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	if chainErr != nil {
+		return count, chainErr
+	}
+	return count, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
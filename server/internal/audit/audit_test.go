@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openLog(t *testing.T, path string, start time.Time) *Log {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := start
+	l.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestChainSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := openLog(t, path, start)
+	l.Record(Entry{Action: ActionAuth, PlayerID: "alice", Result: ResultOK})
+	l.Record(Entry{Action: ActionAuth, Actor: "10.0.0.1:5000", Result: ResultDenied})
+	l.Close()
+
+	l = openLog(t, path, start.Add(time.Hour))
+	if seq, _ := l.Head(); seq != 2 {
+		t.Fatalf("reopened at entry %d, want 2", seq)
+	}
+	l.Record(Entry{Action: ActionTrade, PlayerID: "bob", Peer: "alice", Target: "t1", Result: "proposed"})
+	count, err := l.Verify()
+	if err != nil || count != 3 {
+		t.Fatalf("Verify = %d, %v; want 3 intact entries", count, err)
+	}
+	entries, _ := l.Query(Filter{})
+	if entries[2].Seq != 3 || entries[2].Prev != entries[1].Hash || entries[0].Prev != "" {
+		t.Errorf("entries are not chained: %+v", entries)
+	}
+}
+
+func TestVerifyFindsTampering(t *testing.T) {
+	for name, tamper := range map[string]func(lines []string) []string{
+		"edited": func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"result":"denied"`, `"result":"ok"`, 1)
+			return lines
+		},
+		"removed": func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		},
+		"reordered": func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			l := openLog(t, path, time.Now())
+			for _, result := range []string{ResultOK, ResultDenied, ResultOK} {
+				l.Record(Entry{Action: ActionAuth, Result: result})
+			}
+			data, _ := os.ReadFile(path)
+			lines := tamper(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+			os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+			var chainErr *ChainError
+			if _, err := l.Verify(); !errors.As(err, &chainErr) || chainErr.Line != 2 {
+				t.Errorf("Verify = %v, want a break at line 2", err)
+			}
+			// The entries are still there to read.
+			if entries, err := l.Query(Filter{}); err != nil || len(entries) == 0 {
+				t.Errorf("Query after tampering = %d entries, %v", len(entries), err)
+			}
+		})
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := openLog(t, filepath.Join(t.TempDir(), "audit.jsonl"), start)
+	l.Record(Entry{Action: ActionAuth, PlayerID: "alice", Result: ResultOK})                       // 00:01
+	l.Record(Entry{Action: ActionTrade, PlayerID: "bob", Peer: "alice", Result: "proposed"})       // 00:02
+	l.Record(Entry{Action: ActionAuth, PlayerID: "bob", Result: ResultOK})                         // 00:03
+	l.Record(Entry{Action: ActionAdmin, PlayerID: "alice", Actor: "ops", Result: ResultOK})        // 00:04
+	l.Record(Entry{Action: ActionChainTx, Actor: "0xserver", Target: "digest", Result: "success"}) // 00:05
+
+	seqs := func(filter Filter) []uint64 {
+		entries, err := l.Query(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []uint64
+		for _, e := range entries {
+			out = append(out, e.Seq)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"player or peer", Filter{PlayerID: "alice"}, "[1 2 4]"},
+		{"action", Filter{Action: ActionAuth}, "[1 3]"},
+		{"window", Filter{Since: start.Add(2 * time.Minute), Until: start.Add(4 * time.Minute)}, "[2 3]"},
+		{"most recent", Filter{PlayerID: "alice", Limit: 2}, "[2 4]"},
+	} {
+		if got := fmt.Sprint(seqs(tc.filter)); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNilLogRecordsNothing(t *testing.T) {
+	var l *Log
+	if err := l.Record(Entry{Action: ActionAuth}); err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	l.AdminMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/x", nil))
+	if !called {
+		t.Error("a nil log's middleware did not pass the request on")
+	}
+}
+
+func TestAdminCommandsAreRecordedAndQueryable(t *testing.T) {
+	l := openLog(t, filepath.Join(t.TempDir(), "audit.jsonl"), time.Now())
+	admin := http.NewServeMux()
+	admin.HandleFunc("/admin/balance/rollback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	l.RegisterHandlers(admin, "secret")
+	handler := l.AdminMiddleware(admin)
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Admin-User", "ops")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	do(http.MethodPost, "/admin/balance/rollback?playerId=alice", "secret")
+	do(http.MethodPost, "/admin/balance/rollback", "guess")
+	do(http.MethodGet, "/admin/audit", "secret") // Reads are not commands
+
+	rec := do(http.MethodGet, "/admin/audit?action=admin", "secret")
+	var body struct{ Entries []Entry }
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/audit: %d %s", rec.Code, rec.Body)
+	}
+	if len(body.Entries) != 2 {
+		t.Fatalf("recorded %+v, want the two POSTs", body.Entries)
+	}
+	if e := body.Entries[0]; e.PlayerID != "alice" || e.Actor != "ops" || e.Result != ResultOK || e.Target != "POST /admin/balance/rollback?playerId=alice" {
+		t.Errorf("first command recorded as %+v", e)
+	}
+	if e := body.Entries[1]; e.Result != ResultDenied {
+		t.Errorf("rejected command recorded as %+v", e)
+	}
+
+	if rec := do(http.MethodGet, "/admin/audit", "guess"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("querying with a bad token: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/admin/audit?since=yesterday", "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad since: %d", rec.Code)
+	}
+	rec = do(http.MethodGet, "/admin/audit/verify", "secret")
+	var report struct {
+		Entries int
+		Intact  bool
+		HeadSeq uint64
+	}
+	json.Unmarshal(rec.Body.Bytes(), &report)
+	if !report.Intact || report.Entries != 2 || report.HeadSeq != 2 {
+		t.Errorf("verify = %s", rec.Body)
+	}
+}
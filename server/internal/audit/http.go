@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultQueryLimit is how many entries GET /admin/audit returns without a limit.
+const DefaultQueryLimit = 100
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header.
+//
+//	GET /admin/audit?playerId=ID&action=auth&since=RFC3339&until=RFC3339&limit=N   matching entries, oldest first
+//	GET /admin/audit/verify                                                        checks the hash chain and reports its head
+func (l *Log) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/audit", adminhttp.Method(http.MethodGet, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		filter, err := parseFilter(r)
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		entries, err := l.Query(filter)
+		if err != nil {
+			utils.LogErrorf("Audit: query failed: %v", err)
+			adminhttp.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+	})))
+	mux.HandleFunc("/admin/audit/verify", adminhttp.Method(http.MethodGet, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		count, err := l.Verify()
+		headSeq, headHash := l.Head()
+		report := map[string]interface{}{"entries": count, "intact": err == nil, "headSeq": headSeq, "headHash": headHash}
+		var chainErr *ChainError
+		switch {
+		case errors.As(err, &chainErr):
+			report["brokenAtLine"], report["reason"] = chainErr.Line, chainErr.Reason
+		case err != nil:
+			adminhttp.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, report)
+	})))
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filter := Filter{PlayerID: query.Get("playerId"), Action: query.Get("action"), Limit: DefaultQueryLimit}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New(name + " must be an RFC 3339 time")
+			}
+			*t = parsed
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("limit must be a positive number")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// AdminMiddleware records every admin command passing through next: requests
+// other than GET, with the operator, path and response status. Requests the
+// admin token or an API token gateway rejects are recorded as denied.
+func (l *Log) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		result := ResultOK
+		switch {
+		case recorder.status == http.StatusUnauthorized, recorder.status == http.StatusForbidden, recorder.status == http.StatusTooManyRequests:
+			result = ResultDenied
+		case recorder.status >= 400:
+			result = ResultFailed
+		}
+		target := r.URL.Path
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		l.Record(Entry{
+			Action:   ActionAdmin,
+			PlayerID: r.URL.Query().Get("playerId"),
+			Actor:    r.Header.Get("X-Admin-User"),
+			Target:   r.Method + " " + target,
+			Result:   result,
+			Detail:   "status " + strconv.Itoa(recorder.status) + " from " + r.RemoteAddr,
+		})
+	})
+}
+
+// statusRecorder captures the status a handler writes.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
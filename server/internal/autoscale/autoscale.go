@@ -0,0 +1,257 @@
+// Package autoscale works out the load signals orchestrators scale game
+// server replicas on, such as a Kubernetes HPA on an external metric: how
+// many players this instance can hold for the CPU it has, how much of their
+// tick budget the rooms use, and whether the instance should take new
+// players. Raw CPU says little about a game server; a handful of busy rooms
+// can miss their ticks long before the process looks busy.
+package autoscale
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults used when Options leaves a value unset.
+const (
+	DefaultPlayersPerCore     = 250
+	DefaultTargetCPU          = 0.7
+	DefaultMaxTickUtilization = 0.8
+	DefaultSampleInterval     = 5 * time.Second
+)
+
+const (
+	tickSmoothing  = 0.2 // Weight of each new tick against a room's running utilization
+	minMeasuredCPU = 0.2 // CPU use below which the process's own overhead would skew a per-player cost
+)
+
+// Options configure a Tracker. Players is required.
+type Options struct {
+	PlayersPerCore     int           // Players one core is sized for; the capacity ceiling is this times the cores
+	TargetCPU          float64       // CPU use, as a fraction of the cores, the capacity estimate aims at
+	MaxTickUtilization float64       // Busiest room's share of its tick budget above which no new players are taken
+	SampleInterval     time.Duration // How often the signals are recomputed
+	Cores              int           // runtime.GOMAXPROCS(0) if 0
+	Players            func() int    // Online players
+}
+
+// Signals are the load of the instance at one sample.
+type Signals struct {
+	Players            int       `json:"players"`
+	Capacity           int       `json:"capacity"`          // Players the instance can hold for its CPU
+	PlayerUtilization  float64   `json:"playerUtilization"` // Players / capacity
+	CPUUtilization     float64   `json:"cpuUtilization"`    // Share of the cores the process used since the last sample
+	Cores              int       `json:"cores"`
+	TickRooms          int       `json:"tickRooms"`          // Rooms running their tick
+	TickUtilization    float64   `json:"tickUtilization"`    // Mean share of the tick budget rooms use
+	MaxTickUtilization float64   `json:"maxTickUtilization"` // The busiest room's share
+	BusiestRoom        string    `json:"busiestRoom,omitempty"`
+	TickOverruns       uint64    `json:"tickOverruns"` // Ticks that took longer than their budget, since start
+	Load               float64   `json:"load"`         // The larger of playerUtilization and maxTickUtilization relative to its limit; scale out above 1
+	AcceptingPlayers   bool      `json:"acceptingPlayers"`
+	Reasons            []string  `json:"reasons,omitempty"` // Why new players are not accepted
+	SampledAt          time.Time `json:"sampledAt"`
+}
+
+type roomLoad struct {
+	utilization float64 // Smoothed share of the tick budget
+}
+
+// Tracker collects the rooms' tick times and samples the signals. Its methods
+// are safe for concurrent use and do nothing on a nil Tracker, so rooms can
+// report without checking whether autoscaling signals are on.
+type Tracker struct {
+	opts Options
+	cpu  func() (busy, total float64) // Cumulative CPU seconds of the process and available to it
+	now  func() time.Time
+
+	mu        sync.Mutex
+	rooms     map[string]*roomLoad
+	overruns  uint64
+	lastBusy  float64
+	lastTotal float64
+	signals   Signals
+	stop      chan struct{}
+}
+
+// NewTracker creates a Tracker for opts.
+func NewTracker(opts Options) *Tracker {
+	if opts.PlayersPerCore <= 0 {
+		opts.PlayersPerCore = DefaultPlayersPerCore
+	}
+	if opts.TargetCPU <= 0 {
+		opts.TargetCPU = DefaultTargetCPU
+	}
+	if opts.MaxTickUtilization <= 0 {
+		opts.MaxTickUtilization = DefaultMaxTickUtilization
+	}
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = DefaultSampleInterval
+	}
+	if opts.Cores <= 0 {
+		opts.Cores = runtime.GOMAXPROCS(0)
+	}
+	t := &Tracker{opts: opts, cpu: runtimeCPU, now: time.Now, rooms: make(map[string]*roomLoad)}
+	t.lastBusy, t.lastTotal = t.cpu()
+	t.signals = Signals{Cores: opts.Cores, AcceptingPlayers: true} // Until the first sample
+	return t
+}
+
+// ObserveTick records that a room's tick took spent out of a budget of one
+// tick interval.
+func (t *Tracker) ObserveTick(roomID string, spent, budget time.Duration) {
+	if t == nil || budget <= 0 {
+		return
+	}
+	share := float64(spent) / float64(budget)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if spent > budget {
+		t.overruns++
+	}
+	room, ok := t.rooms[roomID]
+	if !ok {
+		t.rooms[roomID] = &roomLoad{utilization: share}
+		return
+	}
+	room.utilization += tickSmoothing * (share - room.utilization)
+}
+
+// ForgetRoom drops a room that stopped ticking.
+func (t *Tracker) ForgetRoom(roomID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.rooms, roomID)
+}
+
+// Signals returns the latest sample.
+func (t *Tracker) Signals() Signals {
+	if t == nil {
+		return Signals{AcceptingPlayers: true}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.signals
+}
+
+// Sample recomputes the signals now. Start does so every SampleInterval.
+func (t *Tracker) Sample() Signals {
+	players := t.opts.Players()
+	busy, total := t.cpu()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := Signals{Players: players, Cores: t.opts.Cores, TickRooms: len(t.rooms), TickOverruns: t.overruns, SampledAt: t.now().UTC()}
+	if elapsed := total - t.lastTotal; elapsed > 0 {
+		s.CPUUtilization = (busy - t.lastBusy) / elapsed
+	}
+	t.lastBusy, t.lastTotal = busy, total
+
+	// The ceiling is what the cores are sized for. Once players use a
+	// measurable share of the CPU, what they cost each lowers it.
+	s.Capacity = t.opts.PlayersPerCore * t.opts.Cores
+	if players > 0 && s.CPUUtilization >= minMeasuredCPU {
+		if estimate := int(float64(players) * t.opts.TargetCPU / s.CPUUtilization); estimate < s.Capacity {
+			s.Capacity = estimate
+		}
+	}
+	if s.Capacity > 0 {
+		s.PlayerUtilization = float64(players) / float64(s.Capacity)
+	}
+
+	ids := make([]string, 0, len(t.rooms))
+	for id := range t.rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) // The same busiest room wins ties between samples
+	for _, id := range ids {
+		u := t.rooms[id].utilization
+		s.TickUtilization += u
+		if u > s.MaxTickUtilization {
+			s.MaxTickUtilization, s.BusiestRoom = u, id
+		}
+	}
+	if len(ids) > 0 {
+		s.TickUtilization /= float64(len(ids))
+	}
+
+	s.Load = s.PlayerUtilization
+	if tick := s.MaxTickUtilization / t.opts.MaxTickUtilization; tick > s.Load {
+		s.Load = tick
+	}
+	if players >= s.Capacity {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d players fill the capacity of %d", players, s.Capacity))
+	}
+	if s.MaxTickUtilization > t.opts.MaxTickUtilization {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("room %s uses %.0f%% of its tick budget", s.BusiestRoom, 100*s.MaxTickUtilization))
+	}
+	s.AcceptingPlayers = len(s.Reasons) == 0
+	t.signals = s
+	return s
+}
+
+// Start samples the signals now and every SampleInterval until Stop.
+func (t *Tracker) Start() {
+	t.mu.Lock()
+	if t.stop != nil {
+		t.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+	t.mu.Unlock()
+	t.Sample()
+	go func() {
+		ticker := time.NewTicker(t.opts.SampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.Sample()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends sampling.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+// CheckAccepting fails while the instance takes no new players. It is meant
+// as a readiness check, so that load balancers send new players elsewhere.
+func (t *Tracker) CheckAccepting(ctx context.Context) error {
+	if s := t.Signals(); !s.AcceptingPlayers {
+		return fmt.Errorf("not accepting players: %v", s.Reasons)
+	}
+	return nil
+}
+
+var cpuSamples = []metrics.Sample{{Name: "/cpu/classes/idle:cpu-seconds"}, {Name: "/cpu/classes/total:cpu-seconds"}}
+
+// runtimeCPU reads the Go runtime's estimate of the CPU seconds the process
+// was busy and had available, both cumulative.
+func runtimeCPU() (busy, total float64) {
+	samples := make([]metrics.Sample, len(cpuSamples))
+	copy(samples, cpuSamples)
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindFloat64 || samples[1].Value.Kind() != metrics.KindFloat64 {
+		return 0, 0 // Not supported by this runtime
+	}
+	idle, total := samples[0].Value.Float64(), samples[1].Value.Float64()
+	return total - idle, total
+}
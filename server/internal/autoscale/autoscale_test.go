@@ -0,0 +1,62 @@
+package autoscale
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignalsFollowPlayersCPUAndTicks(t *testing.T) {
+	players := 100
+	tracker := NewTracker(Options{PlayersPerCore: 100, Cores: 4, Players: func() int { return players }})
+	var busy, total float64
+	tracker.cpu = func() (float64, float64) { return busy, total }
+	tracker.lastBusy, tracker.lastTotal = 0, 0
+
+	// Light CPU use: the capacity is what the cores are sized for.
+	busy, total = 4, 40
+	s := tracker.Sample()
+	if s.Capacity != 400 || s.PlayerUtilization != 0.25 || !s.AcceptingPlayers || s.Load != 0.25 {
+		t.Fatalf("signals at 10%% CPU = %+v", s)
+	}
+
+	// 100 players take 35% of the CPU, so 200 fill the 70% target.
+	busy, total = 4+14, 80
+	s = tracker.Sample()
+	if s.Capacity != 200 || s.CPUUtilization != 0.35 || s.PlayerUtilization != 0.5 {
+		t.Fatalf("signals at 35%% CPU = %+v", s)
+	}
+
+	// A room that spends most of its tick stops new players before the CPU does.
+	for i := 0; i < 20; i++ {
+		tracker.ObserveTick("r-busy", 95*time.Millisecond, 100*time.Millisecond)
+		tracker.ObserveTick("r-quiet", 5*time.Millisecond, 100*time.Millisecond)
+	}
+	tracker.ObserveTick("r-busy", 150*time.Millisecond, 100*time.Millisecond)
+	busy, total = 18+14, 120
+	s = tracker.Sample()
+	if s.TickRooms != 2 || s.BusiestRoom != "r-busy" || s.MaxTickUtilization < 0.9 || s.TickOverruns != 1 {
+		t.Fatalf("tick signals = %+v", s)
+	}
+	if s.AcceptingPlayers || len(s.Reasons) != 1 || !strings.Contains(s.Reasons[0], "room r-busy") || s.Load <= 1 {
+		t.Fatalf("signals with a busy room = %+v", s)
+	}
+	if err := tracker.CheckAccepting(context.Background()); err == nil {
+		t.Error("CheckAccepting passed while a room is over its tick budget")
+	}
+
+	// Once the room stops ticking and players leave, the instance takes players again.
+	tracker.ForgetRoom("r-busy")
+	players = 10
+	busy, total = 32+1, 160
+	if s = tracker.Sample(); !s.AcceptingPlayers || s.TickRooms != 1 || s.Capacity != 400 {
+		t.Fatalf("signals after the busy room stopped = %+v", s)
+	}
+
+	var none *Tracker
+	none.ObserveTick("r", time.Second, time.Millisecond)
+	if !none.Signals().AcceptingPlayers {
+		t.Error("a nil Tracker does not accept players")
+	}
+}
@@ -0,0 +1,323 @@
+// Package backup writes and restores bundles of the game server's state, for
+// recovering from data corruption. A bundle is a directory holding:
+//
+//	manifest.json   what the bundle holds, with a SHA-256 of every part
+//	postgres.dump   pg_dump of the database, taken from an exported snapshot
+//	redis.jsonl     every Redis key as DUMPed, with its remaining TTL
+//	files/          the state files named in the config, such as the outbox
+//
+// The manifest also records a fingerprint of the config the bundle was taken
+// with and the chain's epoch and checkpoint at the time. It is written last,
+// so a bundle without one is incomplete.
+//
+// The database dump, the Redis keys and the files are read while the
+// database snapshot is held, so they are at most seconds apart. Redis and the
+// files are not read atomically; the server should be idle or stopped for a
+// bundle that is exactly consistent.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FormatVersion is the bundle format this server writes and reads.
+const FormatVersion = 1
+
+// Names of the parts in a bundle directory.
+const (
+	ManifestFile = "manifest.json"
+	postgresFile = "postgres.dump"
+	redisFile    = "redis.jsonl"
+	filesDir     = "files"
+)
+
+var (
+	// ErrInvalidBundle is returned (wrapped) when a bundle is incomplete or a
+	// part does not match its checksum.
+	ErrInvalidBundle = errors.New("invalid backup bundle")
+	// ErrConfigChanged is returned when a bundle was taken with a different
+	// config than the one it is restored with.
+	ErrConfigChanged = errors.New("config differs from the one the bundle was taken with")
+	// ErrMissingTarget is returned when a bundle holds a part the restore has
+	// nowhere to put, such as a dump without a database.
+	ErrMissingTarget = errors.New("nowhere to restore a part of the bundle")
+)
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Version           int           `json:"version"`
+	CreatedAt         time.Time     `json:"createdAt"`
+	ConfigFingerprint string        `json:"configFingerprint"`
+	Chain             *Watermarks   `json:"chain,omitempty"` // Nil if the chain could not be read
+	Postgres          *PostgresPart `json:"postgres,omitempty"`
+	Redis             *RedisPart    `json:"redis,omitempty"`
+	Files             []FilePart    `json:"files"`
+}
+
+// Part is one file of a bundle.
+type Part struct {
+	File   string `json:"file"` // Relative to the bundle directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// PostgresPart is the database dump.
+type PostgresPart struct {
+	Part
+	Snapshot    string `json:"snapshot"`    // Exported snapshot the dump was taken from
+	WALPosition string `json:"walPosition"` // WAL LSN at the snapshot, for point-in-time recovery
+}
+
+// RedisPart is the dump of the Redis keys.
+type RedisPart struct {
+	Part
+	Keys int `json:"keys"`
+}
+
+// FilePart is a copy of a state file.
+type FilePart struct {
+	Part
+	Path string `json:"path"` // Where the server reads the file
+}
+
+// Watermarks are how far the chain had got when the bundle was taken. Chain
+// events after them are not reflected in the bundle.
+type Watermarks struct {
+	Epoch      uint64 `json:"epoch"`
+	Checkpoint uint64 `json:"checkpoint"`
+}
+
+// Sources are what Create backs up. Nil fields are skipped.
+type Sources struct {
+	ConfigFingerprint string
+	DB                *sql.DB
+	Dump              DumpFunc // Required with DB
+	Redis             redis.UniversalClient
+	Files             []string // State files; missing ones are skipped
+	Chain             func(ctx context.Context) (Watermarks, error)
+}
+
+// Targets are where Restore puts a bundle back. A bundle part without a
+// target fails the restore.
+type Targets struct {
+	ConfigFingerprint string
+	Restore           RestoreFunc
+	Redis             redis.UniversalClient
+}
+
+// Fingerprint is the SHA-256 of cfg as JSON. Secrets in cfg are only hashed.
+func Fingerprint(cfg interface{}) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Create writes a bundle of src into dir, which must not exist or be empty.
+func Create(ctx context.Context, dir string, src Sources) (*Manifest, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, filesDir), 0700); err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC(), ConfigFingerprint: src.ConfigFingerprint, Files: []FilePart{}}
+	if src.Chain != nil {
+		// Read first: whatever the chain does later may or may not be in the bundle.
+		if marks, err := src.Chain(ctx); err == nil {
+			manifest.Chain = &marks
+		}
+	}
+
+	var snapshot *postgresSnapshot
+	if src.DB != nil {
+		if src.Dump == nil {
+			return nil, errors.New("a database needs a dump function")
+		}
+		var err error
+		if snapshot, err = exportSnapshot(ctx, src.DB); err != nil {
+			return nil, err
+		}
+		defer snapshot.release()
+	}
+	if src.Redis != nil {
+		part, err := dumpRedis(ctx, src.Redis, dir)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Redis = part
+	}
+	for i, path := range src.Files {
+		part, err := copyStateFile(dir, i, path)
+		if err != nil {
+			return nil, err
+		}
+		if part != nil {
+			manifest.Files = append(manifest.Files, *part)
+		}
+	}
+	if snapshot != nil {
+		if err := src.Dump(ctx, snapshot.id, filepath.Join(dir, postgresFile)); err != nil {
+			return nil, fmt.Errorf("dump the database: %w", err)
+		}
+		part, err := describe(dir, postgresFile)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Postgres = &PostgresPart{Part: part, Snapshot: snapshot.id, WALPosition: snapshot.walPosition}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return manifest, writeFile(filepath.Join(dir, ManifestFile), data)
+}
+
+// Verify reads the manifest of the bundle in dir and checks every part
+// against its size and checksum.
+func Verify(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d (this server reads up to %d)", ErrInvalidBundle, manifest.Version, FormatVersion)
+	}
+	parts := make([]Part, 0, len(manifest.Files)+2)
+	if manifest.Postgres != nil {
+		parts = append(parts, manifest.Postgres.Part)
+	}
+	if manifest.Redis != nil {
+		parts = append(parts, manifest.Redis.Part)
+	}
+	for _, file := range manifest.Files {
+		if file.Path == "" {
+			return nil, fmt.Errorf("%w: %s has no path to restore to", ErrInvalidBundle, file.File)
+		}
+		parts = append(parts, file.Part)
+	}
+	for _, want := range parts {
+		if !filepath.IsLocal(want.File) {
+			return nil, fmt.Errorf("%w: %s is outside the bundle", ErrInvalidBundle, want.File)
+		}
+		got, err := describe(dir, want.File)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if got != want {
+			return nil, fmt.Errorf("%w: %s does not match its checksum", ErrInvalidBundle, want.File)
+		}
+	}
+	return &manifest, nil
+}
+
+// RestoreOptions changes what Restore accepts.
+type RestoreOptions struct {
+	AllowConfigChange bool // Restore a bundle taken with a different config
+}
+
+// Restore verifies the bundle in dir and puts it back: the database first,
+// then Redis, whose keys are all replaced, then the state files. The game
+// server must be stopped. State files the bundle does not hold are left
+// as they are.
+func Restore(ctx context.Context, dir string, to Targets, opts RestoreOptions) (*Manifest, error) {
+	manifest, err := Verify(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.ConfigFingerprint != to.ConfigFingerprint && !opts.AllowConfigChange {
+		return manifest, ErrConfigChanged
+	}
+	if manifest.Postgres != nil && to.Restore == nil {
+		return manifest, fmt.Errorf("%w: the bundle has a database dump but no database is configured", ErrMissingTarget)
+	}
+	if manifest.Redis != nil && to.Redis == nil {
+		return manifest, fmt.Errorf("%w: the bundle has Redis keys but Redis is not configured", ErrMissingTarget)
+	}
+
+	if manifest.Postgres != nil {
+		if err := to.Restore(ctx, filepath.Join(dir, manifest.Postgres.File)); err != nil {
+			return manifest, fmt.Errorf("restore the database: %w", err)
+		}
+	}
+	if manifest.Redis != nil {
+		if err := restoreRedis(ctx, to.Redis, filepath.Join(dir, manifest.Redis.File)); err != nil {
+			return manifest, err
+		}
+	}
+	for _, file := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(dir, file.File))
+		if err != nil {
+			return manifest, err
+		}
+		if err := writeFile(file.Path, data); err != nil {
+			return manifest, fmt.Errorf("restore %s: %w", file.Path, err)
+		}
+	}
+	return manifest, nil
+}
+
+// copyStateFile copies the state file at path into the bundle. It returns
+// nil if there is no such file.
+func copyStateFile(dir string, index int, path string) (*FilePart, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Join(filesDir, fmt.Sprintf("%02d-%s", index, filepath.Base(path)))
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return nil, err
+	}
+	part, err := describe(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePart{Part: part, Path: path}, nil
+}
+
+// describe returns the size and checksum of the part named name in dir.
+func describe(dir, name string) (Part, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return Part{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return Part{}, err
+	}
+	return Part{File: filepath.ToSlash(name), Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// writeFile replaces path with data through a temporary file, so a crash
+// leaves the old or the new contents.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
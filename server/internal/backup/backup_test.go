@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	state := t.TempDir()
+	outbox, mail := filepath.Join(state, "outbox.json"), filepath.Join(state, "mail-state.json")
+	os.WriteFile(outbox, []byte(`{"pending":[1]}`), 0644)
+	os.WriteFile(mail, []byte(`{"alice":[]}`), 0644)
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	manifest, err := Create(ctx, dir, Sources{
+		ConfigFingerprint: "cfg-1",
+		Files:             []string{outbox, mail, filepath.Join(state, "never-written.json")},
+		Chain:             func(context.Context) (Watermarks, error) { return Watermarks{Epoch: 7, Checkpoint: 1234}, nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 || manifest.Chain == nil || manifest.Chain.Checkpoint != 1234 || manifest.Postgres != nil || manifest.Redis != nil {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if _, err := Create(ctx, dir, Sources{}); err == nil {
+		t.Error("a second bundle was written over the first")
+	}
+
+	// The corruption the bundle is for.
+	os.WriteFile(outbox, []byte(`garbage`), 0644)
+	os.Remove(mail)
+	if _, err := Restore(ctx, dir, Targets{ConfigFingerprint: "cfg-2"}, RestoreOptions{}); !errors.Is(err, ErrConfigChanged) {
+		t.Fatalf("restore with another config: %v", err)
+	}
+	if _, err := Restore(ctx, dir, Targets{ConfigFingerprint: "cfg-2"}, RestoreOptions{AllowConfigChange: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outbox); string(data) != `{"pending":[1]}` {
+		t.Errorf("outbox = %s", data)
+	}
+	if data, _ := os.ReadFile(mail); string(data) != `{"alice":[]}` {
+		t.Errorf("mail = %s", data)
+	}
+}
+
+func TestVerifyRejectsDamagedBundles(t *testing.T) {
+	ctx := context.Background()
+	state := filepath.Join(t.TempDir(), "trade-state.json")
+	os.WriteFile(state, []byte(`{}`), 0644)
+	dir := t.TempDir()
+	manifest, err := Create(ctx, dir, Sources{ConfigFingerprint: "cfg", Files: []string{state}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied := filepath.Join(dir, manifest.Files[0].File)
+	os.WriteFile(copied, []byte(`{"x":1}`), 0600)
+	if _, err := Verify(dir); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("changed file: %v", err)
+	}
+	if _, err := Restore(ctx, dir, Targets{ConfigFingerprint: "cfg"}, RestoreOptions{}); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("restore of a changed file: %v", err)
+	}
+	if data, _ := os.ReadFile(state); string(data) != `{}` {
+		t.Errorf("a damaged bundle was restored: %s", data)
+	}
+
+	os.Remove(filepath.Join(dir, ManifestFile))
+	if _, err := Verify(dir); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("bundle without a manifest: %v", err)
+	}
+}
+
+func TestRestoreNeedsATargetForEveryPart(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, postgresFile), []byte("PGDMP"), 0600)
+	part, _ := describe(dir, postgresFile)
+	manifest := `{"version":1,"configFingerprint":"cfg","postgres":{"file":"postgres.dump","size":5,"sha256":"` + part.SHA256 + `"},"files":[]}`
+	os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0644)
+
+	if _, err := Restore(context.Background(), dir, Targets{ConfigFingerprint: "cfg"}, RestoreOptions{}); !errors.Is(err, ErrMissingTarget) {
+		t.Errorf("restore without a database: %v", err)
+	}
+	var restored string
+	restore := func(_ context.Context, file string) error { restored = file; return nil }
+	if _, err := Restore(context.Background(), dir, Targets{ConfigFingerprint: "cfg", Restore: restore}, RestoreOptions{}); err != nil || restored != filepath.Join(dir, postgresFile) {
+		t.Errorf("restored %q: %v", restored, err)
+	}
+}
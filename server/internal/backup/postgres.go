@@ -0,0 +1,73 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DumpFunc dumps the database as of the exported snapshot into file.
+type DumpFunc func(ctx context.Context, snapshot, file string) error
+
+// RestoreFunc restores the database from a dump.
+type RestoreFunc func(ctx context.Context, file string) error
+
+// PGDump dumps the database at postgresURL with the pg_dump binary, in its
+// custom format.
+func PGDump(binary, postgresURL string) DumpFunc {
+	return func(ctx context.Context, snapshot, file string) error {
+		return run(ctx, binary, "--format=custom", "--snapshot="+snapshot, "--file="+file, "--dbname="+postgresURL)
+	}
+}
+
+// PGRestore restores a custom format dump into the database at postgresURL
+// with the pg_restore binary. Tables in the dump are dropped and recreated,
+// in one transaction.
+func PGRestore(binary, postgresURL string) RestoreFunc {
+	return func(ctx context.Context, file string) error {
+		return run(ctx, binary, "--clean", "--if-exists", "--no-owner", "--single-transaction", "--dbname="+postgresURL, file)
+	}
+}
+
+func run(ctx context.Context, binary string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", binary, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// postgresSnapshot holds a transaction open so its exported snapshot stays
+// usable by pg_dump.
+type postgresSnapshot struct {
+	tx          *sql.Tx
+	id          string
+	walPosition string
+}
+
+// exportSnapshot starts a read-only repeatable read transaction and exports
+// its snapshot.
+func exportSnapshot(ctx context.Context, db *sql.DB) (*postgresSnapshot, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("start the database snapshot: %w", err)
+	}
+	s := &postgresSnapshot{tx: tx}
+	err = tx.QueryRowContext(ctx, `
+		SELECT pg_export_snapshot(),
+		       (CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END)::text`).Scan(&s.id, &s.walPosition)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("export the database snapshot: %w", err)
+	}
+	return s, nil
+}
+
+func (s *postgresSnapshot) release() {
+	s.tx.Rollback()
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisScanCount is the SCAN batch size hint.
+const redisScanCount = 1000
+
+// redisKey is one line of redis.jsonl.
+type redisKey struct {
+	Key   string `json:"key"`
+	TTLMs int64  `json:"ttlMs,omitempty"` // 0 never expires
+	Value []byte `json:"value"`           // DUMP serialization
+}
+
+// eachRedisNode calls fn with every node holding keys: the masters of a
+// cluster, or the one server otherwise.
+func eachRedisNode(ctx context.Context, client redis.UniversalClient, fn func(ctx context.Context, node *redis.Client) error) error {
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		var mu sync.Mutex // ForEachMaster runs fn concurrently
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(ctx, node)
+		})
+	case *redis.Client:
+		return fn(ctx, c)
+	}
+	return fmt.Errorf("unsupported redis client %T", client)
+}
+
+// dumpRedis writes every key with its value and remaining TTL into the bundle
+// in dir.
+func dumpRedis(ctx context.Context, client redis.UniversalClient, dir string) (*RedisPart, error) {
+	f, err := os.OpenFile(filepath.Join(dir, redisFile), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	keys := 0
+	err = eachRedisNode(ctx, client, func(ctx context.Context, node *redis.Client) error {
+		iter := node.Scan(ctx, 0, "", redisScanCount).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			value, err := node.Dump(ctx, key).Result()
+			if err == redis.Nil {
+				continue // Expired or deleted since the scan
+			}
+			if err != nil {
+				return fmt.Errorf("dump redis key %s: %w", key, err)
+			}
+			ttl, err := node.PTTL(ctx, key).Result()
+			if err != nil {
+				return fmt.Errorf("read the ttl of redis key %s: %w", key, err)
+			}
+			entry := redisKey{Key: key, Value: []byte(value)}
+			if ttl > 0 {
+				entry.TTLMs = ttl.Milliseconds()
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+			keys++
+		}
+		return iter.Err()
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("back up redis: %w", err)
+	}
+	part, err := describe(dir, redisFile)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisPart{Part: part, Keys: keys}, nil
+}
+
+// restoreRedis deletes every key and restores those in file, each with the
+// TTL it had left when it was backed up.
+func restoreRedis(ctx context.Context, client redis.UniversalClient, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = eachRedisNode(ctx, client, func(ctx context.Context, node *redis.Client) error {
+		return node.FlushDB(ctx).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("clear redis: %w", err)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 512*1024*1024)
+	for scanner.Scan() {
+		var entry redisKey
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBundle, redisFile, err)
+		}
+		ttl := time.Duration(entry.TTLMs) * time.Millisecond
+		if err := client.RestoreReplace(ctx, entry.Key, ttl, string(entry.Value)).Err(); err != nil {
+			return fmt.Errorf("restore redis key %s: %w", entry.Key, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,153 @@
+package balance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type seqRNG []float64
+
+func (r *seqRNG) Float64() float64 {
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v
+}
+
+func TestXPCurve(t *testing.T) {
+	xp := XPValues{BaseXP: 100, Growth: 2, MaxLevel: 4}
+	if got := xp.XPToNextLevel(1); got != 100 {
+		t.Fatalf("XPToNextLevel(1) = %d, want 100", got)
+	}
+	if got := xp.XPToNextLevel(3); got != 400 {
+		t.Fatalf("XPToNextLevel(3) = %d, want 400", got)
+	}
+	if got := xp.LevelForXP(299); got != 2 {
+		t.Fatalf("LevelForXP(299) = %d, want 2", got)
+	}
+	if got := xp.LevelForXP(1_000_000); got != 4 {
+		t.Fatalf("LevelForXP past the cap = %d, want 4", got)
+	}
+}
+
+func TestLootRollAppliesMultiplier(t *testing.T) {
+	loot := LootValues{DropRateMultiplier: 2, Tables: map[string][]LootDrop{
+		"boss": {{ItemID: "gem", Chance: 0.3, Min: 1, Max: 3}, {ItemID: "crown", Chance: 0.1, Min: 1, Max: 1}},
+	}}
+	rng := seqRNG{0.5, 0.99, 0.25}
+	drops := loot.Roll("boss", &rng)
+	if drops["gem"] != 3 || drops["crown"] != 0 {
+		t.Fatalf("drops = %v, want 3 gems and no crown", drops)
+	}
+}
+
+func TestUpdateAndRollbackCreateVersions(t *testing.T) {
+	s, err := Open("", &MemoryHistory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified []int
+	s.Subscribe(func(snapshot Snapshot) { notified = append(notified, snapshot.Version) })
+	values := s.Values()
+	values.Combat.CritChance = 0.5
+	if _, err := s.Update(values, "ops", "crit event"); err != nil {
+		t.Fatal(err)
+	}
+	values.Combat.CritChance = 2
+	if _, err := s.Update(values, "ops", ""); err == nil {
+		t.Fatal("accepted a crit chance above 1")
+	}
+	snapshot, err := s.Rollback(1, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Version != 3 || snapshot.Values.Combat.CritChance != 0.10 {
+		t.Fatalf("rollback = version %d crit %v, want version 3 with the default crit", snapshot.Version, snapshot.Values.Combat.CritChance)
+	}
+	if _, err := s.Rollback(9, "ops"); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("err = %v, want ErrUnknownVersion", err)
+	}
+	if len(notified) != 2 || len(s.History()) != 3 {
+		t.Fatalf("notified %v, history %d; want 2 notifications and 3 versions", notified, len(s.History()))
+	}
+}
+
+func TestReloadPicksUpFileEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "balance.json")
+	if err := os.WriteFile(path, []byte(`{"xp": {"killXp": 250}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := FileHistory{Path: filepath.Join(dir, "history.jsonl")}
+	s, err := Open(path, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Values(); v.XP.KillXP != 250 || v.XP.BaseXP != 100 {
+		t.Fatalf("xp = %+v, want killXp from the file and other defaults", v.XP)
+	}
+
+	later := time.Now().Add(time.Second)
+	os.WriteFile(path, []byte(`{"combat": {"hitChance": 7}}`), 0644)
+	os.Chtimes(path, later, later)
+	if _, err := s.Reload(); err == nil {
+		t.Fatal("accepted an invalid file")
+	}
+	later = later.Add(time.Second)
+	os.WriteFile(path, []byte(`{"combat": {"hitChance": 0.8}}`), 0644)
+	os.Chtimes(path, later, later)
+	if changed, err := s.Reload(); err != nil || !changed {
+		t.Fatalf("Reload = %v, %v; want a change", changed, err)
+	}
+
+	reopened, err := Open(path, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current := reopened.Current(); current.Version != 2 || current.Values.Combat.HitChance != 0.8 {
+		t.Fatalf("reopened at version %d hit %v, want version 2 with hit 0.8", current.Version, current.Values.Combat.HitChance)
+	}
+}
+
+func TestFeesApplyRegionAndEventOverrides(t *testing.T) {
+	free := int64(0)
+	fees := FeeValues{
+		Trade:       FeeRate{BasisPoints: 200, Flat: 10, Max: 100},
+		DirectTrade: 5,
+		Overrides: []FeeOverride{
+			{Region: "eu", Trade: &FeeRate{BasisPoints: 100}},
+			{Event: "festival", DirectTrade: &free},
+		},
+	}
+	if got := fees.In("us").Trade.On(1000); got != 30 {
+		t.Errorf("2%% + 10 of 1000 = %d, want 30", got)
+	}
+	if got := fees.In("us").Trade.On(1_000_000); got != 100 {
+		t.Errorf("fee on 1000000 = %d, want the cap of 100", got)
+	}
+	if got := fees.In("eu").Trade.On(1000); got != 10 {
+		t.Errorf("eu fee on 1000 = %d, want 10", got)
+	}
+	if got := fees.In("eu").DirectTrade; got != 5 {
+		t.Errorf("direct trade fee without the event = %d, want 5", got)
+	}
+	fees.ActiveEvents = []string{"festival"}
+	if got := fees.In("eu").DirectTrade; got != 0 {
+		t.Errorf("direct trade fee during the event = %d, want 0", got)
+	}
+	if got := (FeeRate{BasisPoints: 10000}).On(^uint64(0)); got != ^uint64(0) {
+		t.Errorf("100%% of the largest amount = %d", got)
+	}
+
+	values := Defaults()
+	values.Fees.Overrides = []FeeOverride{{Trade: &FeeRate{BasisPoints: 1}}}
+	if err := values.Validate(); err == nil {
+		t.Error("an override without a region or event was accepted")
+	}
+	values.Fees = FeeValues{Marketplace: FeeRate{BasisPoints: 10001}}
+	if err := values.Validate(); err == nil {
+		t.Error("a fee above 100% was accepted")
+	}
+}
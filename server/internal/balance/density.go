@@ -0,0 +1,47 @@
+package balance
+
+import "fmt"
+
+// MaxNPCMultiplier is the most NPCs a map may spawn per NPC of the NPC file.
+const MaxNPCMultiplier = 10
+
+// DensityValues are how densely crowded maps are populated. The hot zone
+// monitor raises them while players crowd a map and lowers them again once
+// the crowd thins out; operators may set them by hand as well.
+type DensityValues struct {
+	NPCMultipliers  map[string]float64 `json:"npcMultipliers,omitempty"` // Map ID -> NPCs spawned per NPC of the map, at least 1; 1 if absent
+	OverflowMaps    []string           `json:"overflowMaps,omitempty"`   // Maps whose crowded public rooms send new players to an overflow instance
+	OverflowPlayers int                `json:"overflowPlayers"`          // Members at which a room on an overflow map sends new players on; 0 only once it is full
+}
+
+// NPCMultiplier returns the NPC density of the map with id.
+func (d DensityValues) NPCMultiplier(mapID string) float64 {
+	if m, ok := d.NPCMultipliers[mapID]; ok {
+		return m
+	}
+	return 1
+}
+
+// Overflows reports whether crowded rooms on the map with id open overflow
+// instances.
+func (d DensityValues) Overflows(mapID string) bool {
+	for _, id := range d.OverflowMaps {
+		if id == mapID {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every multiplier is in range.
+func (d DensityValues) Validate() error {
+	for mapID, m := range d.NPCMultipliers {
+		if m < 1 || m > MaxNPCMultiplier {
+			return fmt.Errorf("density.npcMultipliers.%s must be between 1 and %d, got %v", mapID, MaxNPCMultiplier, m)
+		}
+	}
+	if d.OverflowPlayers < 0 {
+		return fmt.Errorf("density.overflowPlayers cannot be negative")
+	}
+	return nil
+}
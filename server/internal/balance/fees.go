@@ -0,0 +1,135 @@
+package balance
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// basisPointsPerUnit is 100%: a rate of 250 basis points takes 2.5%.
+const basisPointsPerUnit = 10000
+
+// FeeValues are the fees the server charges on trades and marketplace
+// purchases. Overrides change them in a region, during an event or both: an
+// event's override applies while the event is listed in ActiveEvents, and
+// later overrides win over earlier ones.
+type FeeValues struct {
+	Trade        FeeRate       `json:"trade"`       // MIST on the value of an escrowed trade, split between the players
+	DirectTrade  int64         `json:"directTrade"` // Coins each player pays when a direct trade is accepted
+	Marketplace  FeeRate       `json:"marketplace"` // MIST on the price of a marketplace purchase, paid by the buyer
+	ActiveEvents []string      `json:"activeEvents,omitempty"`
+	Overrides    []FeeOverride `json:"overrides,omitempty"`
+}
+
+// FeeRate is a fee of BasisPoints of an amount plus Flat, capped at Max.
+type FeeRate struct {
+	BasisPoints uint64 `json:"basisPoints"` // 100 is 1%
+	Flat        uint64 `json:"flat,omitempty"`
+	Max         uint64 `json:"max,omitempty"` // 0 is no cap
+}
+
+// FeeOverride replaces the fees it sets in Region while Event is active. An
+// empty Region matches every region and an empty Event is always active.
+type FeeOverride struct {
+	Region      string   `json:"region,omitempty"`
+	Event       string   `json:"event,omitempty"`
+	Trade       *FeeRate `json:"trade,omitempty"`
+	DirectTrade *int64   `json:"directTrade,omitempty"`
+	Marketplace *FeeRate `json:"marketplace,omitempty"`
+}
+
+// Fees are the fees in effect in one region.
+type Fees struct {
+	Trade       FeeRate
+	DirectTrade int64
+	Marketplace FeeRate
+}
+
+// In returns the fees in effect in region, with the matching overrides
+// applied. Services not tied to a region pass "" and only get the overrides
+// without one.
+func (f FeeValues) In(region string) Fees {
+	fees := Fees{Trade: f.Trade, DirectTrade: f.DirectTrade, Marketplace: f.Marketplace}
+	for _, o := range f.Overrides {
+		if o.Region != "" && o.Region != region || o.Event != "" && !f.active(o.Event) {
+			continue
+		}
+		if o.Trade != nil {
+			fees.Trade = *o.Trade
+		}
+		if o.DirectTrade != nil {
+			fees.DirectTrade = *o.DirectTrade
+		}
+		if o.Marketplace != nil {
+			fees.Marketplace = *o.Marketplace
+		}
+	}
+	return fees
+}
+
+// Validate checks that every rate is at most 100% and every override names a
+// region or an event.
+func (f FeeValues) Validate() error {
+	check := func(name string, rate FeeRate) error {
+		if rate.BasisPoints > basisPointsPerUnit {
+			return fmt.Errorf("fees.%s.basisPoints must be at most %d, got %d", name, basisPointsPerUnit, rate.BasisPoints)
+		}
+		if rate.Max != 0 && rate.Max < rate.Flat {
+			return fmt.Errorf("fees.%s.max cannot be below its flat fee", name)
+		}
+		return nil
+	}
+	if f.DirectTrade < 0 {
+		return fmt.Errorf("fees.directTrade cannot be negative")
+	}
+	if err := check("trade", f.Trade); err != nil {
+		return err
+	}
+	if err := check("marketplace", f.Marketplace); err != nil {
+		return err
+	}
+	for i, o := range f.Overrides {
+		if o.Region == "" && o.Event == "" {
+			return fmt.Errorf("fees.overrides[%d] needs a region or an event", i)
+		}
+		if o.DirectTrade != nil && *o.DirectTrade < 0 {
+			return fmt.Errorf("fees.overrides[%d].directTrade cannot be negative", i)
+		}
+		for name, rate := range map[string]*FeeRate{"trade": o.Trade, "marketplace": o.Marketplace} {
+			if rate == nil {
+				continue
+			}
+			if err := check(fmt.Sprintf("overrides[%d].%s", i, name), *rate); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f FeeValues) active(event string) bool {
+	for _, e := range f.ActiveEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// On returns the fee on amount, rounded down.
+func (r FeeRate) On(amount uint64) uint64 {
+	hi, lo := bits.Mul64(amount, min(r.BasisPoints, basisPointsPerUnit))
+	share, _ := bits.Div64(hi, lo, basisPointsPerUnit) // At most amount, so it cannot overflow
+	fee := share + r.Flat
+	if fee < share {
+		fee = ^uint64(0)
+	}
+	if r.Max != 0 && fee > r.Max {
+		fee = r.Max
+	}
+	return fee
+}
+
+// Zero reports whether the rate charges nothing.
+func (r FeeRate) Zero() bool {
+	return r.BasisPoints == 0 && r.Flat == 0
+}
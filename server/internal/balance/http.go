@@ -0,0 +1,69 @@
+package balance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is recorded with each version.
+//
+//	GET  /admin/balance                    current version
+//	PUT  /admin/balance?note=...           body: values to change, merged over the current ones
+//	GET  /admin/balance/history            every version, oldest first
+//	POST /admin/balance/rollback?version=N
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/balance", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		switch r.Method {
+		case http.MethodGet:
+			adminhttp.WriteJSON(w, http.StatusOK, s.Current())
+		case http.MethodPut:
+			values := s.Values().clone()
+			if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+				adminhttp.WriteError(w, http.StatusBadRequest, errors.New("body must be JSON balance values"))
+				return
+			}
+			snapshot, err := s.Update(values, operator, r.URL.Query().Get("note"))
+			if err != nil {
+				adminhttp.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			adminhttp.WriteJSON(w, http.StatusOK, snapshot)
+		default:
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET or PUT"))
+		}
+	}))
+	mux.HandleFunc("/admin/balance/history", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.History())
+	}))
+	mux.HandleFunc("/admin/balance/rollback", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		version, err := strconv.Atoi(r.URL.Query().Get("version"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("version must be a number"))
+			return
+		}
+		snapshot, err := s.Rollback(version, operator)
+		if errors.Is(err, ErrUnknownVersion) {
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, snapshot)
+	}))
+}
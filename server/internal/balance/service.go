@@ -0,0 +1,351 @@
+package balance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Snapshot sources.
+const (
+	SourceDefaults = "defaults"
+	SourceFile     = "file"
+	SourceAdmin    = "admin"
+	SourceRollback = "rollback"
+	SourceAuto     = "auto" // Changed by the server itself, e.g. the hot zone monitor
+)
+
+// ErrUnknownVersion is returned when rolling back to a version not in the history.
+var ErrUnknownVersion = errors.New("unknown balance version")
+
+// Snapshot is one version of the values.
+type Snapshot struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	By      string    `json:"by,omitempty"` // Operator, for admin changes and rollbacks
+	Note    string    `json:"note,omitempty"`
+	Values  Values    `json:"values"`
+}
+
+// History stores every version.
+type History interface {
+	Load() ([]Snapshot, error)
+	Append(Snapshot) error
+}
+
+// MemoryHistory keeps versions in memory.
+type MemoryHistory struct {
+	mu        sync.Mutex
+	snapshots []Snapshot
+}
+
+// Load implements History.
+func (m *MemoryHistory) Load() ([]Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Snapshot(nil), m.snapshots...), nil
+}
+
+// Append implements History.
+func (m *MemoryHistory) Append(snapshot Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots = append(m.snapshots, snapshot)
+	return nil
+}
+
+// FileHistory appends versions as JSON lines to a file.
+type FileHistory struct {
+	Path string
+}
+
+// Load implements History. A missing file is an empty history.
+func (f FileHistory) Load() ([]Snapshot, error) {
+	file, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid balance history %s: %w", f.Path, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, scanner.Err()
+}
+
+// Append implements History.
+func (f FileHistory) Append(snapshot Snapshot) error {
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Validator is an extra check on new values, e.g. that a damage model exists.
+type Validator func(Values) error
+
+// Service serves the current values. It is safe for concurrent use.
+//
+// The live values are kept in a JSON file that operators may edit by hand:
+// the service notices changes while watching, and admin updates rewrite the
+// file. Every change becomes a new version in the history.
+type Service struct {
+	path       string
+	history    History
+	validators []Validator
+
+	mu          sync.RWMutex
+	current     Snapshot
+	snapshots   []Snapshot
+	modTime     time.Time
+	subscribers []func(Snapshot)
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Open loads the history and the live file at path. Fields missing from the
+// file keep their defaults. An empty path keeps the values in memory only.
+func Open(path string, history History, validators ...Validator) (*Service, error) {
+	snapshots, err := history.Load()
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{path: path, history: history, validators: validators, snapshots: snapshots, done: make(chan struct{})}
+	if len(snapshots) > 0 {
+		s.current = snapshots[len(snapshots)-1]
+	} else {
+		s.current = Snapshot{Time: time.Now(), Source: SourceDefaults, Values: Defaults()}
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	if s.current.Version == 0 {
+		s.current.Version = 1
+		if err := s.record(s.current); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Current returns the current version.
+func (s *Service) Current() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Values returns the current values.
+func (s *Service) Values() Values {
+	return s.Current().Values
+}
+
+// History returns every version, oldest first.
+func (s *Service) History() []Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Snapshot(nil), s.snapshots...)
+}
+
+// Subscribe calls fn with each new version.
+func (s *Service) Subscribe(fn func(Snapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Update replaces the values, writing them to the live file.
+func (s *Service) Update(values Values, by, note string) (Snapshot, error) {
+	return s.apply(values, SourceAdmin, by, note)
+}
+
+// Adjust applies change to a copy of the current values, writing them to the
+// live file, and records the note change returns. It is for changes the server
+// makes itself; by names the part of the server that made them.
+func (s *Service) Adjust(by string, change func(*Values) (note string)) (Snapshot, error) {
+	values := s.Values().clone()
+	note := change(&values)
+	return s.apply(values, SourceAuto, by, note)
+}
+
+// Rollback makes an earlier version's values current again, as a new version.
+func (s *Service) Rollback(version int, by string) (Snapshot, error) {
+	for _, snapshot := range s.History() {
+		if snapshot.Version == version {
+			return s.apply(snapshot.Values.clone(), SourceRollback, by, fmt.Sprintf("rollback to version %d", version))
+		}
+	}
+	return Snapshot{}, ErrUnknownVersion
+}
+
+// Reload reads the live file if it changed since it was last read. Invalid
+// files are rejected and the current values stay in effect.
+func (s *Service) Reload() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	values := Defaults()
+	values.Loot.Tables = nil // Tables in the file replace the default ones
+	if err := json.Unmarshal(data, &values); err != nil {
+		return false, fmt.Errorf("invalid balance file %s: %w", s.path, err)
+	}
+	if err := s.validate(values); err != nil {
+		return false, fmt.Errorf("invalid balance file %s: %w", s.path, err)
+	}
+	s.mu.Lock()
+	s.modTime = info.ModTime()
+	same := sameValues(values, s.current.Values)
+	s.mu.Unlock()
+	if same {
+		return false, nil
+	}
+	if _, err := s.commit(values, SourceFile, "", ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StartWatching reloads the live file every interval until Stop.
+func (s *Service) StartWatching(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				changed, err := s.Reload()
+				if err != nil {
+					utils.LogErrorf("Balance: %v. Keeping version %d.", err, s.Current().Version)
+				} else if changed {
+					utils.LogInfof("Balance: Reloaded %s as version %d.", s.path, s.Current().Version)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops watching the live file.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+// apply validates values, writes them to the live file and commits them.
+func (s *Service) apply(values Values, source, by, note string) (Snapshot, error) {
+	if err := s.validate(values); err != nil {
+		return Snapshot{}, err
+	}
+	if s.path != "" {
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return Snapshot{}, err
+		}
+		tmp := s.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return Snapshot{}, err
+		}
+		if err := os.Rename(tmp, s.path); err != nil {
+			return Snapshot{}, err
+		}
+		if info, err := os.Stat(s.path); err == nil {
+			s.mu.Lock()
+			s.modTime = info.ModTime() // Our own write is not a hand edit to reload
+			s.mu.Unlock()
+		}
+	}
+	snapshot, err := s.commit(values, source, by, note)
+	if err == nil {
+		utils.LogInfof("Balance: Version %d applied (%s by %q).", snapshot.Version, source, by)
+	}
+	return snapshot, err
+}
+
+// commit records values as the next version and notifies subscribers.
+func (s *Service) commit(values Values, source, by, note string) (Snapshot, error) {
+	s.mu.Lock()
+	snapshot := Snapshot{Version: s.current.Version + 1, Time: time.Now(), Source: source, By: by, Note: note, Values: values}
+	if err := s.record(snapshot); err != nil {
+		s.mu.Unlock()
+		return Snapshot{}, err
+	}
+	subscribers := append([]func(Snapshot){}, s.subscribers...)
+	s.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(snapshot)
+	}
+	return snapshot, nil
+}
+
+// record appends snapshot to the history and makes it current. Callers hold s.mu,
+// except while opening.
+func (s *Service) record(snapshot Snapshot) error {
+	if err := s.history.Append(snapshot); err != nil {
+		return fmt.Errorf("could not record balance version %d: %w", snapshot.Version, err)
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	s.current = snapshot
+	return nil
+}
+
+func (s *Service) validate(values Values) error {
+	if err := values.Validate(); err != nil {
+		return err
+	}
+	for _, validator := range s.validators {
+		if err := validator(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameValues(a, b Values) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return bytes.Equal(aJSON, bJSON)
+}
@@ -0,0 +1,187 @@
+// Package balance holds the game's tunable values (combat constants, the XP
+// curve, loot tables, fees, energy and map density), reloads them from a JSON
+// file while the server runs, keeps a version history, and serves them to
+// operators over an admin API.
+package balance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Values are the tunables. Consumers read them on every use so that changes
+// apply without a restart; they must treat them as read-only.
+type Values struct {
+	Combat  CombatValues  `json:"combat"`
+	XP      XPValues      `json:"xp"`
+	Loot    LootValues    `json:"loot"`
+	Fees    FeeValues     `json:"fees"`
+	Energy  EnergyValues  `json:"energy"`
+	Density DensityValues `json:"density"`
+}
+
+// CombatValues are the combat engine's chances and multipliers.
+type CombatValues struct {
+	HitChance         float64           `json:"hitChance"`
+	CritChance        float64           `json:"critChance"`
+	EvadeChance       float64           `json:"evadeChance"`
+	CritBonus         float64           `json:"critBonus"`         // Damage multiplier on a critical hit
+	MinDamageFraction float64           `json:"minDamageFraction"` // Share of attack power a hit always deals
+	DamageModel       string            `json:"damageModel"`
+	ModeDamageModels  map[string]string `json:"modeDamageModels,omitempty"` // Game mode -> damage model
+}
+
+// XPValues define the experience curve. Reaching level 2 takes BaseXP, and
+// each later level takes Growth times as much as the one before.
+type XPValues struct {
+	BaseXP   int     `json:"baseXp"`
+	Growth   float64 `json:"growth"`
+	MaxLevel int     `json:"maxLevel"`
+	KillXP   int     `json:"killXp"` // Awarded for defeating an opponent
+}
+
+// LootDrop is one possible drop: Min to Max units of ItemID with probability Chance.
+type LootDrop struct {
+	ItemID string  `json:"itemId"`
+	Chance float64 `json:"chance"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+}
+
+// LootValues are the drop tables. DropRateMultiplier scales every chance, for
+// example 2 during a double-drop event.
+type LootValues struct {
+	DropRateMultiplier float64               `json:"dropRateMultiplier"`
+	Tables             map[string][]LootDrop `json:"tables"`
+}
+
+// EnergyValues define the players' energy. Actions named in Costs spend it,
+// and it comes back one point every RegenSeconds, online or not.
+type EnergyValues struct {
+	Max          int            `json:"max"`          // Energy of a rested player; 0 turns energy off
+	RegenSeconds int            `json:"regenSeconds"` // Time to regain one point
+	Costs        map[string]int `json:"costs"`        // Action -> energy it spends, e.g. "craft"
+}
+
+// RegenInterval returns the time to regain one point.
+func (e EnergyValues) RegenInterval() time.Duration {
+	return time.Duration(e.RegenSeconds) * time.Second
+}
+
+// RNG is the randomness a loot roll needs.
+type RNG interface {
+	Float64() float64
+}
+
+// Defaults returns the values the server uses without a balance file. The
+// combat values match the engine's built-in constants.
+func Defaults() Values {
+	return Values{
+		Combat: CombatValues{
+			HitChance:         0.90,
+			CritChance:        0.10,
+			EvadeChance:       0.05,
+			CritBonus:         1.5,
+			MinDamageFraction: 0.1,
+			DamageModel:       "standard",
+		},
+		XP: XPValues{BaseXP: 100, Growth: 1.5, MaxLevel: 60, KillXP: 100},
+		Loot: LootValues{
+			DropRateMultiplier: 1,
+			Tables: map[string][]LootDrop{
+				"default": {{ItemID: "health_potion", Chance: 0.25, Min: 1, Max: 2}},
+			},
+		},
+		Energy: EnergyValues{Max: 100, RegenSeconds: 180, Costs: map[string]int{"craft": 5, "arenaQueue": 10}},
+	}
+}
+
+// Validate checks that every value is in range.
+func (v Values) Validate() error {
+	c := v.Combat
+	for name, chance := range map[string]float64{"hitChance": c.HitChance, "critChance": c.CritChance, "evadeChance": c.EvadeChance, "minDamageFraction": c.MinDamageFraction} {
+		if chance < 0 || chance > 1 {
+			return fmt.Errorf("combat.%s must be between 0 and 1, got %v", name, chance)
+		}
+	}
+	if c.CritBonus < 1 {
+		return fmt.Errorf("combat.critBonus must be at least 1, got %v", c.CritBonus)
+	}
+	if v.XP.BaseXP <= 0 || v.XP.Growth < 1 || v.XP.MaxLevel < 2 || v.XP.KillXP < 0 {
+		return fmt.Errorf("xp needs baseXp > 0, growth >= 1, maxLevel >= 2 and killXp >= 0")
+	}
+	if err := v.Fees.Validate(); err != nil {
+		return err
+	}
+	if err := v.Density.Validate(); err != nil {
+		return err
+	}
+	if v.Loot.DropRateMultiplier < 0 {
+		return fmt.Errorf("loot.dropRateMultiplier cannot be negative")
+	}
+	if v.Energy.Max < 0 || v.Energy.Max > 0 && v.Energy.RegenSeconds <= 0 {
+		return fmt.Errorf("energy needs max >= 0 and, when max is set, regenSeconds > 0")
+	}
+	for action, cost := range v.Energy.Costs {
+		if cost < 0 {
+			return fmt.Errorf("energy.costs.%s cannot be negative", action)
+		}
+	}
+	for table, drops := range v.Loot.Tables {
+		for _, drop := range drops {
+			if drop.ItemID == "" || drop.Chance < 0 || drop.Chance > 1 || drop.Min < 1 || drop.Max < drop.Min {
+				return fmt.Errorf("loot table %q has an invalid drop %+v", table, drop)
+			}
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy, so that a decoded patch cannot change shared maps.
+func (v Values) clone() Values {
+	data, _ := json.Marshal(v)
+	var copied Values
+	_ = json.Unmarshal(data, &copied)
+	return copied
+}
+
+// XPToNextLevel returns the experience needed to go from level to level+1, or
+// 0 at the level cap.
+func (x XPValues) XPToNextLevel(level int) int {
+	if level < 1 || level >= x.MaxLevel {
+		return 0
+	}
+	return int(math.Round(float64(x.BaseXP) * math.Pow(x.Growth, float64(level-1))))
+}
+
+// LevelForXP returns the level reached with totalXP experience.
+func (x XPValues) LevelForXP(totalXP int) int {
+	level := 1
+	for level < x.MaxLevel {
+		needed := x.XPToNextLevel(level)
+		if totalXP < needed {
+			break
+		}
+		totalXP -= needed
+		level++
+	}
+	return level
+}
+
+// Roll rolls a loot table and returns ItemID -> quantity. An unknown table drops nothing.
+func (l LootValues) Roll(table string, rng RNG) map[string]int {
+	drops := make(map[string]int)
+	for _, drop := range l.Tables[table] {
+		if rng.Float64() >= drop.Chance*l.DropRateMultiplier {
+			continue
+		}
+		quantity := drop.Min + int(rng.Float64()*float64(drop.Max-drop.Min+1))
+		if quantity > drop.Max {
+			quantity = drop.Max
+		}
+		drops[drop.ItemID] += quantity
+	}
+	return drops
+}
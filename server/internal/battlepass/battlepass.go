@@ -0,0 +1,612 @@
+// Package battlepass runs the seasonal battle pass. Players earn season XP from
+// gameplay events and reach tiers on a track, each with a free reward and a
+// premium one for players who paid for the premium pass on chain. Rewards are
+// claimed: coins and items go to the shop wallet, cosmetics are minted as item
+// NFTs through the outbox. When a season ends, rewards that were earned but not
+// claimed are granted, and progress starts over for the next season.
+package battlepass
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// MintKind is the outbox message kind of a cosmetic reward. Its payload is a
+// Grant.
+const MintKind = "battlepass.mint"
+
+// DefaultTickInterval is how often the season is checked for rollover when
+// Options.TickInterval is zero.
+const DefaultTickInterval = time.Minute
+
+// Tracks of a season.
+const (
+	TrackFree    = "free"
+	TrackPremium = "premium"
+)
+
+// XP sources, as named in the definition's xpSources.
+const (
+	SourceDailyLogin   = "dailyLogin"   // First login of a UTC day
+	SourceCombatWin    = "combatWin"    // Defeating an opponent
+	SourceCombatLoss   = "combatLoss"   // Being defeated
+	SourceTutorialStep = "tutorialStep" // Completing a tutorial step
+)
+
+var (
+	ErrNoSeason        = errors.New("no battle pass season is running")
+	ErrUnknownTier     = errors.New("unknown battle pass tier")
+	ErrUnknownTrack    = errors.New("battle pass track must be free or premium")
+	ErrNoReward        = errors.New("the tier has no reward on this track")
+	ErrTierLocked      = errors.New("the tier has not been reached yet")
+	ErrAlreadyClaimed  = errors.New("the reward was already claimed")
+	ErrPremiumRequired = errors.New("the reward needs the premium pass")
+	ErrPremiumDisabled = errors.New("the premium pass is not for sale")
+	ErrAlreadyPremium  = errors.New("the premium pass is already unlocked")
+	ErrPaymentUsed     = errors.New("payment already used")
+	ErrPaymentInvalid  = errors.New("payment could not be verified")
+)
+
+// Reward is what a tier grants on one track.
+type Reward struct {
+	Coins    int64  `json:"coins,omitempty"`
+	ItemID   string `json:"itemId,omitempty"`   // Inventory item
+	Quantity int    `json:"quantity,omitempty"` // Units of ItemID; 1 if 0
+	Cosmetic string `json:"cosmetic,omitempty"` // Item NFT type minted to the player
+}
+
+// Tier is one step of a season's track.
+type Tier struct {
+	XP      int     `json:"xp"` // Season XP that reaches the tier
+	Free    *Reward `json:"free,omitempty"`
+	Premium *Reward `json:"premium,omitempty"`
+}
+
+// Season is one battle pass season. Tiers are numbered from 1.
+type Season struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	StartsAt        time.Time `json:"startsAt"`
+	EndsAt          time.Time `json:"endsAt"`
+	PremiumPrice    uint64    `json:"premiumPrice"`    // In the smallest unit of premiumCoinType; the premium pass is not for sale if 0
+	PremiumCoinType string    `json:"premiumCoinType"` // "0x2::sui::SUI" if empty
+	Tiers           []Tier    `json:"tiers"`
+}
+
+// Definition is the battle pass file: the seasons and the XP each source
+// awards.
+type Definition struct {
+	Seasons   []Season       `json:"seasons"`
+	XPSources map[string]int `json:"xpSources"`
+}
+
+// LoadDefinition reads and validates a battle pass file.
+func LoadDefinition(path string) (Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Definition{}, err
+	}
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return Definition{}, fmt.Errorf("invalid battle pass %s: %w", path, err)
+	}
+	if err := def.Validate(); err != nil {
+		return Definition{}, fmt.Errorf("invalid battle pass %s: %w", path, err)
+	}
+	return def, nil
+}
+
+// Validate checks the seasons and sorts them by start.
+func (d *Definition) Validate() error {
+	sort.Slice(d.Seasons, func(i, j int) bool { return d.Seasons[i].StartsAt.Before(d.Seasons[j].StartsAt) })
+	seen := make(map[string]bool, len(d.Seasons))
+	for i, season := range d.Seasons {
+		switch {
+		case season.ID == "" || seen[season.ID]:
+			return fmt.Errorf("season %d needs a unique id", i+1)
+		case !season.EndsAt.After(season.StartsAt):
+			return fmt.Errorf("season %q must end after it starts", season.ID)
+		case i > 0 && season.StartsAt.Before(d.Seasons[i-1].EndsAt):
+			return fmt.Errorf("season %q overlaps season %q", season.ID, d.Seasons[i-1].ID)
+		}
+		seen[season.ID] = true
+		previous := 0
+		for n, tier := range season.Tiers {
+			if tier.XP <= previous {
+				return fmt.Errorf("season %q tier %d needs more xp than the tier before it", season.ID, n+1)
+			}
+			previous = tier.XP
+			for _, reward := range []*Reward{tier.Free, tier.Premium} {
+				if reward != nil && (reward.Coins < 0 || reward.Quantity < 0) {
+					return fmt.Errorf("season %q tier %d has a negative reward", season.ID, n+1)
+				}
+			}
+		}
+	}
+	for source, xp := range d.XPSources {
+		if xp < 0 {
+			return fmt.Errorf("xpSources.%s cannot be negative", source)
+		}
+	}
+	return nil
+}
+
+// at returns the season running at now.
+func (d Definition) at(now time.Time) (Season, bool) {
+	for _, season := range d.Seasons {
+		if !now.Before(season.StartsAt) && now.Before(season.EndsAt) {
+			return season, true
+		}
+	}
+	return Season{}, false
+}
+
+func (d Definition) season(id string) (Season, bool) {
+	for _, season := range d.Seasons {
+		if season.ID == id {
+			return season, true
+		}
+	}
+	return Season{}, false
+}
+
+// Grant is the payload of a MintKind outbox message.
+type Grant struct {
+	SeasonID string `json:"seasonId"`
+	PlayerID string `json:"playerId"`
+	Tier     int    `json:"tier"`
+	Track    string `json:"track"`
+	ItemType string `json:"itemType"`
+}
+
+// View is a player's battle pass in the current season.
+type View struct {
+	Season  Season
+	Active  bool // A season is running
+	XP      int
+	Tier    int // Highest tier reached; 0 before the first
+	Premium bool
+	Claimed map[string]bool
+	ForSale bool // The premium pass can be bought
+}
+
+// IsClaimed reports whether the reward of tier on track was claimed.
+func (v View) IsClaimed(tier int, track string) bool {
+	return v.Claimed[claimKey(track, tier)]
+}
+
+// Update tells a connected session that its player's battle pass changed.
+type Update struct {
+	View View
+}
+
+// Notifier delivers an *Update to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	TickInterval time.Duration // How often the season is checked for rollover
+}
+
+// Service runs the battle pass. It is safe for concurrent use.
+type Service struct {
+	def     Definition
+	store   Store
+	wallets shop.WalletStore
+	opts    Options
+	now     func() time.Time
+
+	mints     *outbox.Outbox       // Cosmetic rewards; nil skips them
+	mail      *mail.Service        // Season-end notices; nil sends none
+	verifier  shop.PaymentVerifier // Premium pass payments; premium is not for sale if nil
+	recipient string
+
+	mu        sync.Mutex
+	state     State
+	notifiers map[string]Notifier
+	pending   map[string]bool // Payments being verified
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService loads the battle pass state from store. Rewards are paid into
+// wallets. The state is rolled over to the season running now by Start.
+func NewService(def Definition, store Store, wallets shop.WalletStore, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading battle pass state: %w", err)
+	}
+	if state.Players == nil {
+		state.Players = make(map[string]Progress)
+	}
+	if state.Payments == nil {
+		state.Payments = make(map[string]string)
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	s := &Service{
+		def:       def,
+		store:     store,
+		wallets:   wallets,
+		opts:      opts,
+		now:       time.Now,
+		state:     state,
+		notifiers: make(map[string]Notifier),
+		pending:   make(map[string]bool),
+	}
+	return s, nil
+}
+
+// UseMinting queues the mints of cosmetic rewards in mints.
+func (s *Service) UseMinting(mints *outbox.Outbox) {
+	s.mints = mints
+}
+
+// UseMail mails players the rewards granted when a season ends.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// UsePremium sells the premium pass for on-chain payments to recipient,
+// checked with verifier.
+func (s *Service) UsePremium(verifier shop.PaymentVerifier, recipient string) {
+	s.verifier, s.recipient = verifier, recipient
+}
+
+// Subscribe awards XP for the gameplay events on bus.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicPlayerLogin, "battlepass", func(e events.PlayerLogin) {
+		s.dailyLogin(e.PlayerID)
+	})
+	events.On(bus, events.TopicCombatFinished, "battlepass", func(e events.CombatFinished) {
+		s.AddXP(e.WinnerID, SourceCombatWin)
+		s.AddXP(e.LoserID, SourceCombatLoss)
+	})
+	events.On(bus, events.TopicTutorialStepCompleted, "battlepass", func(e events.TutorialStepCompleted) {
+		s.AddXP(e.PlayerID, SourceTutorialStep)
+	})
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect forgets a player's notifier.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// View returns playerID's battle pass.
+func (s *Service) View(playerID string) View {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.viewLocked(playerID)
+}
+
+// AddXP awards playerID the XP of source in the running season.
+func (s *Service) AddXP(playerID, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addXPLocked(playerID, source)
+}
+
+func (s *Service) dailyLogin(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	day := s.now().UTC().Format("2006-01-02")
+	progress := s.state.Players[playerID]
+	if s.state.SeasonID == "" || progress.LoginDay == day {
+		return
+	}
+	progress.LoginDay = day
+	s.state.Players[playerID] = progress
+	if !s.addXPLocked(playerID, SourceDailyLogin) {
+		s.save()
+	}
+}
+
+// addXPLocked reports whether it awarded any XP, and saved the state.
+func (s *Service) addXPLocked(playerID, source string) bool {
+	xp := s.def.XPSources[source]
+	if playerID == "" || xp <= 0 || s.state.SeasonID == "" {
+		return false
+	}
+	progress := s.state.Players[playerID]
+	progress.XP += xp
+	s.state.Players[playerID] = progress
+	s.save()
+	if notify := s.notifiers[playerID]; notify != nil {
+		notify(&Update{View: s.viewLocked(playerID)})
+	}
+	return true
+}
+
+// Claim grants playerID the reward of tier on track and returns it.
+func (s *Service) Claim(playerID string, tier int, track string) (Reward, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	season, ok := s.def.season(s.state.SeasonID)
+	if !ok {
+		return Reward{}, ErrNoSeason
+	}
+	if tier < 1 || tier > len(season.Tiers) {
+		return Reward{}, ErrUnknownTier
+	}
+	var reward *Reward
+	switch track {
+	case TrackFree:
+		reward = season.Tiers[tier-1].Free
+	case TrackPremium:
+		reward = season.Tiers[tier-1].Premium
+	default:
+		return Reward{}, ErrUnknownTrack
+	}
+	progress := s.state.Players[playerID]
+	switch {
+	case reward == nil:
+		return Reward{}, ErrNoReward
+	case progress.XP < season.Tiers[tier-1].XP:
+		return Reward{}, ErrTierLocked
+	case progress.Claimed[claimKey(track, tier)]:
+		return Reward{}, ErrAlreadyClaimed
+	case track == TrackPremium && !progress.Premium:
+		return Reward{}, ErrPremiumRequired
+	}
+	if err := s.grant(season.ID, playerID, tier, track, *reward); err != nil {
+		return Reward{}, err
+	}
+	if progress.Claimed == nil {
+		progress.Claimed = make(map[string]bool)
+	}
+	progress.Claimed[claimKey(track, tier)] = true
+	s.state.Players[playerID] = progress
+	s.save()
+	utils.LogInfof("Battle pass: %s claimed the %s reward of tier %d in %s.", playerID, track, tier, season.ID)
+	return *reward, nil
+}
+
+// UnlockPremium unlocks the premium pass of the running season for the
+// on-chain payment txDigest, which must be sent from address. It makes RPC
+// calls, so callers should not run it on an actor's goroutine.
+func (s *Service) UnlockPremium(playerID, address, txDigest string) error {
+	s.mu.Lock()
+	season, ok := s.def.season(s.state.SeasonID)
+	var err error
+	switch {
+	case !ok:
+		err = ErrNoSeason
+	case s.verifier == nil || season.PremiumPrice == 0:
+		err = ErrPremiumDisabled
+	case s.state.Players[playerID].Premium:
+		err = ErrAlreadyPremium
+	case txDigest == "" || s.pending[txDigest] || s.state.Payments[txDigest] != "":
+		err = ErrPaymentUsed
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.pending[txDigest] = true
+	s.mu.Unlock()
+
+	coinType := season.PremiumCoinType
+	if coinType == "" {
+		coinType = "0x2::sui::SUI"
+	}
+	verifyErr := s.verifier.VerifyPayment(txDigest, address, s.recipient, coinType, season.PremiumPrice)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, txDigest)
+	if verifyErr != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentInvalid, verifyErr)
+	}
+	// The season may have rolled over while the payment was verified; the
+	// payment still unlocks the season it was made for.
+	if s.state.SeasonID == season.ID {
+		progress := s.state.Players[playerID]
+		progress.Premium = true
+		s.state.Players[playerID] = progress
+	}
+	s.state.Payments[txDigest] = playerID
+	s.save()
+	utils.LogInfof("Battle pass: %s unlocked the premium pass of %s (tx %s).", playerID, season.ID, txDigest)
+	return nil
+}
+
+// Start rolls the state over to the running season, then checks for rollover
+// in the background.
+func (s *Service) Start() {
+	s.Tick(s.now())
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Tick(s.now())
+			}
+		}
+	}()
+}
+
+// Stop stops the background rollover checks.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Tick rolls the state over when the season running at now is not the one
+// the progress belongs to. Earned rewards left unclaimed in the ended season
+// are granted and mailed, then every player starts the new season at 0 XP.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, _ := s.def.at(now)
+	if current.ID == s.state.SeasonID {
+		return
+	}
+	if ended, ok := s.def.season(s.state.SeasonID); ok {
+		for playerID, progress := range s.state.Players {
+			s.grantUnclaimed(ended, playerID, progress)
+		}
+		utils.LogInfof("Battle pass: Season %s ended for %d players.", ended.ID, len(s.state.Players))
+	}
+	s.state.SeasonID = current.ID
+	s.state.Players = make(map[string]Progress)
+	s.save()
+	if current.ID != "" {
+		utils.LogInfof("Battle pass: Season %s (%s) started, ending %s.", current.ID, current.Name, current.EndsAt.Format(time.RFC3339))
+	}
+	for playerID, notify := range s.notifiers {
+		notify(&Update{View: s.viewLocked(playerID)})
+	}
+}
+
+// grantUnclaimed grants playerID the rewards of ended they reached but did
+// not claim, and mails them what they got.
+func (s *Service) grantUnclaimed(ended Season, playerID string, progress Progress) {
+	var granted []string
+	for n, tier := range ended.Tiers {
+		if progress.XP < tier.XP {
+			break
+		}
+		for _, track := range []string{TrackFree, TrackPremium} {
+			reward := tier.Free
+			if track == TrackPremium {
+				if !progress.Premium {
+					continue
+				}
+				reward = tier.Premium
+			}
+			if reward == nil || progress.Claimed[claimKey(track, n+1)] {
+				continue
+			}
+			if err := s.grant(ended.ID, playerID, n+1, track, *reward); err != nil {
+				utils.LogErrorf("Battle pass: Could not grant %s the %s reward of tier %d in %s: %v", playerID, track, n+1, ended.ID, err)
+				continue
+			}
+			granted = append(granted, describe(*reward))
+		}
+	}
+	if len(granted) == 0 {
+		return
+	}
+	s.mail.Send(mail.Message{
+		To:      playerID,
+		Kind:    "battlepass.season_ended",
+		Subject: fmt.Sprintf("%s has ended", ended.Name),
+		Body:    fmt.Sprintf("You had unclaimed rewards, which we have granted: %s.", strings.Join(granted, ", ")),
+		Ref:     ended.ID,
+	})
+}
+
+// grant pays reward into playerID's wallet and queues its cosmetic mint.
+func (s *Service) grant(seasonID, playerID string, tier int, track string, reward Reward) error {
+	if reward.Coins > 0 || reward.ItemID != "" {
+		wallet, err := s.wallets.LoadWallet(playerID)
+		if err != nil {
+			return err
+		}
+		wallet.Coins += reward.Coins
+		if reward.ItemID != "" {
+			if wallet.Inventory == nil {
+				wallet.Inventory = make(map[string]int)
+			}
+			wallet.Inventory[reward.ItemID] += quantity(reward)
+		}
+		if err := s.wallets.SaveWallet(playerID, wallet); err != nil {
+			return err
+		}
+	}
+	if reward.Cosmetic != "" && s.mints != nil {
+		id := fmt.Sprintf("battlepass:%s:%s:%s:%d", seasonID, playerID, track, tier)
+		grant := Grant{SeasonID: seasonID, PlayerID: playerID, Tier: tier, Track: track, ItemType: reward.Cosmetic}
+		if _, err := s.mints.Enqueue(id, MintKind, grant); err != nil {
+			utils.LogErrorf("Battle pass: Could not queue %s for %s: %v", reward.Cosmetic, playerID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) viewLocked(playerID string) View {
+	season, ok := s.def.season(s.state.SeasonID)
+	progress := s.state.Players[playerID]
+	view := View{
+		Season:  season,
+		Active:  ok,
+		XP:      progress.XP,
+		Premium: progress.Premium,
+		Claimed: make(map[string]bool, len(progress.Claimed)),
+		ForSale: ok && s.verifier != nil && season.PremiumPrice > 0,
+	}
+	for key := range progress.Claimed {
+		view.Claimed[key] = true
+	}
+	for _, tier := range season.Tiers {
+		if progress.XP < tier.XP {
+			break
+		}
+		view.Tier++
+	}
+	return view
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Battle pass: Could not save state: %v", err)
+	}
+}
+
+func claimKey(track string, tier int) string {
+	return fmt.Sprintf("%s:%d", track, tier)
+}
+
+func quantity(reward Reward) int {
+	if reward.Quantity == 0 {
+		return 1
+	}
+	return reward.Quantity
+}
+
+func describe(reward Reward) string {
+	var parts []string
+	if reward.Coins > 0 {
+		parts = append(parts, fmt.Sprintf("%d coins", reward.Coins))
+	}
+	if reward.ItemID != "" {
+		parts = append(parts, fmt.Sprintf("%d x %s", quantity(reward), reward.ItemID))
+	}
+	if reward.Cosmetic != "" {
+		parts = append(parts, reward.Cosmetic)
+	}
+	return strings.Join(parts, " and ")
+}
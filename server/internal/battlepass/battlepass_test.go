@@ -0,0 +1,148 @@
+package battlepass
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+)
+
+type fakeVerifier struct {
+	paid map[string]uint64 // Digest -> amount
+}
+
+func (f fakeVerifier) VerifyPayment(txDigest, payer, recipient, coinType string, amount uint64) error {
+	if f.paid[txDigest] < amount {
+		return errors.New("underpaid")
+	}
+	return nil
+}
+
+var seasonStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testDefinition() Definition {
+	return Definition{
+		Seasons: []Season{{
+			ID:           "s1",
+			Name:         "Season 1",
+			StartsAt:     seasonStart,
+			EndsAt:       seasonStart.AddDate(0, 1, 0),
+			PremiumPrice: 1000,
+			Tiers: []Tier{
+				{XP: 100, Free: &Reward{Coins: 50}, Premium: &Reward{Cosmetic: "ember_cape"}},
+				{XP: 300, Free: &Reward{ItemID: "health_potion", Quantity: 2}},
+			},
+		}},
+		XPSources: map[string]int{SourceDailyLogin: 40, SourceCombatWin: 100},
+	}
+}
+
+func newTestService(t *testing.T, wallets shop.WalletStore) (*Service, *time.Time) {
+	t.Helper()
+	s, err := NewService(testDefinition(), &MemoryStore{}, wallets, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := seasonStart.Add(time.Hour)
+	s.now = func() time.Time { return now }
+	s.Tick(now)
+	return s, &now
+}
+
+func TestTiersAreReachedAndClaimed(t *testing.T) {
+	wallets := &shop.MemoryWallets{}
+	s, now := newTestService(t, wallets)
+	s.UsePremium(fakeVerifier{paid: map[string]uint64{"0xpaid": 1000, "0xcheap": 10}}, "0xtreasury")
+
+	s.dailyLogin("alice")
+	s.dailyLogin("alice") // Same day
+	if view := s.View("alice"); view.XP != 40 || view.Tier != 0 || !view.Active || !view.ForSale {
+		t.Fatalf("after logging in twice: %+v", view)
+	}
+	if _, err := s.Claim("alice", 1, TrackFree); !errors.Is(err, ErrTierLocked) {
+		t.Errorf("claim before reaching the tier: %v", err)
+	}
+	s.AddXP("alice", SourceCombatWin)
+	if view := s.View("alice"); view.Tier != 1 {
+		t.Fatalf("after a win: %+v", view)
+	}
+	if _, err := s.Claim("alice", 1, TrackFree); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Claim("alice", 1, TrackFree); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("second claim: %v", err)
+	}
+	if _, err := s.Claim("alice", 1, TrackPremium); !errors.Is(err, ErrPremiumRequired) {
+		t.Errorf("premium claim without the pass: %v", err)
+	}
+	if _, err := s.Claim("alice", 2, TrackPremium); !errors.Is(err, ErrNoReward) {
+		t.Errorf("tier 2 premium: %v", err)
+	}
+	if wallet, _ := wallets.LoadWallet("alice"); wallet.Coins != 50 {
+		t.Errorf("wallet = %+v", wallet)
+	}
+
+	if err := s.UnlockPremium("alice", "0xa", "0xcheap"); !errors.Is(err, ErrPaymentInvalid) {
+		t.Errorf("underpaid unlock: %v", err)
+	}
+	if err := s.UnlockPremium("alice", "0xa", "0xpaid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UnlockPremium("bob", "0xb", "0xpaid"); !errors.Is(err, ErrPaymentUsed) {
+		t.Errorf("reused payment: %v", err)
+	}
+	jobs := outbox.NewMemoryStore()
+	s.UseMinting(outbox.New(jobs, outbox.Options{}))
+	if _, err := s.Claim("alice", 1, TrackPremium); err != nil {
+		t.Fatal(err)
+	}
+	messages, _ := jobs.List()
+	if len(messages) != 1 || messages[0].Kind != MintKind {
+		t.Errorf("outbox = %+v", messages)
+	}
+	*now = now.Add(24 * time.Hour)
+	s.dailyLogin("alice")
+	if view := s.View("alice"); view.XP != 180 || !view.Premium || !view.IsClaimed(1, TrackPremium) {
+		t.Errorf("next day: %+v", view)
+	}
+}
+
+func TestSeasonEndGrantsUnclaimedRewards(t *testing.T) {
+	wallets := &shop.MemoryWallets{}
+	s, now := newTestService(t, wallets)
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UseMail(mailService)
+	var updates []View
+	s.Connect("bob", func(note interface{}) { updates = append(updates, note.(*Update).View) })
+
+	for i := 0; i < 3; i++ {
+		s.AddXP("bob", SourceCombatWin)
+	}
+	if _, err := s.Claim("bob", 1, TrackFree); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now.AddDate(0, 2, 0))
+
+	if wallet, _ := wallets.LoadWallet("bob"); wallet.Coins != 50 || wallet.Inventory["health_potion"] != 2 {
+		t.Errorf("wallet after the season = %+v", wallet)
+	}
+	if inbox, _ := mailService.Inbox("bob"); len(inbox) != 1 || inbox[0].Kind != "battlepass.season_ended" {
+		t.Errorf("inbox = %+v", inbox)
+	}
+	if view := s.View("bob"); view.Active || view.XP != 0 {
+		t.Errorf("after the season: %+v", view)
+	}
+	if len(updates) != 4 || updates[3].Active {
+		t.Errorf("updates = %+v", updates)
+	}
+	s.AddXP("bob", SourceCombatWin)
+	if view := s.View("bob"); view.XP != 0 {
+		t.Errorf("XP between seasons: %+v", view)
+	}
+}
@@ -0,0 +1,27 @@
+package battlepass
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// Progress is a player's standing in the current season.
+type Progress struct {
+	XP       int             `json:"xp"`
+	Premium  bool            `json:"premium,omitempty"`
+	Claimed  map[string]bool `json:"claimed,omitempty"`  // claimKey(track, tier) of the claimed rewards
+	LoginDay string          `json:"loginDay,omitempty"` // UTC date of the last daily login XP
+}
+
+// State is the persisted battle pass state.
+type State struct {
+	SeasonID string              `json:"seasonId"` // Season the progress belongs to; empty between seasons
+	Players  map[string]Progress `json:"players"`
+	Payments map[string]string   `json:"payments"` // Premium payment digest -> player ID, kept across seasons
+}
+
+// Store persists the battle pass state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the battle pass state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the battle pass state in a JSON file.
+type FileStore = jsonstore.File[State]
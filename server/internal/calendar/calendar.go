@@ -0,0 +1,501 @@
+// Package calendar keeps the guilds' event calendars. Guild officers, or
+// the ranks the guild's permission matrix allows to, schedule events such as raids and meetings with a start time and a
+// capacity, and members RSVP. Attendees are mailed a reminder shortly before
+// the start; at the start a private room is opened for them and they are
+// told where to go. Events opened to the alliance are on the calendars of
+// the guild's allies too, whose members RSVP like the guild's own.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultReminderLead = 15 * time.Minute
+	DefaultMaxCapacity  = 40
+	DefaultMaxAhead     = 30 * 24 * time.Hour
+	DefaultMaxScheduled = 20
+	DefaultKeepFor      = 2 * time.Hour
+	DefaultTickInterval = 30 * time.Second
+)
+
+var (
+	ErrNoGuild    = errors.New("you are not in a guild")
+	ErrNotOfficer = errors.New("your guild rank cannot schedule or cancel events")
+	ErrNotFound   = errors.New("no such guild event")
+	ErrInvalid    = errors.New("invalid guild event")
+	ErrTooMany    = errors.New("the guild has too many scheduled events")
+	ErrFull       = errors.New("the event is full")
+	ErrClosed     = errors.New("the event has already started or was cancelled")
+)
+
+// Kind is what an event is for.
+type Kind string
+
+const (
+	KindRaid    Kind = "raid"
+	KindMeeting Kind = "meeting"
+)
+
+// Status is where an event stands.
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusStarted   Status = "started"
+	StatusCancelled Status = "cancelled"
+)
+
+// Event is one entry of a guild's calendar.
+type Event struct {
+	ID          string    `json:"id"`
+	GuildID     string    `json:"guildId"`
+	Kind        Kind      `json:"kind"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"createdBy"`
+	StartsAt    time.Time `json:"startsAt"`
+	Capacity    int       `json:"capacity"`            // Most attendees
+	Attendees   []string  `json:"attendees,omitempty"` // Players who RSVPed yes, in order
+	Status      Status    `json:"status"`
+	Reminded    bool      `json:"reminded,omitempty"`
+	RoomID      string    `json:"roomId,omitempty"` // Room opened for the attendees at the start
+
+	Alliance bool `json:"alliance,omitempty"` // Open to the members of allied guilds
+}
+
+// Attending reports whether playerID RSVPed yes.
+func (e Event) Attending(playerID string) bool {
+	for _, id := range e.Attendees {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Started tells a connected attendee's session that an event started and
+// where its room is.
+type Started struct {
+	Event Event
+}
+
+// Notifier delivers a *Started to a player's session.
+type Notifier func(note interface{})
+
+// Alliances tells which guilds are allied. diplomacy.Service implements it.
+type Alliances interface {
+	Allied(guildA, guildB string) bool
+}
+
+// RoomOpener opens the private room of an event that starts, admitting its
+// attendees, and returns the room's ID.
+type RoomOpener func(event Event) (roomID string, err error)
+
+// Options configures a Service.
+type Options struct {
+	ReminderLead time.Duration // How long before the start attendees are reminded
+	MaxCapacity  int           // Largest capacity, and that of events created without one
+	MaxAhead     time.Duration // How far ahead events may be scheduled
+	MaxScheduled int           // Scheduled events per guild
+	KeepFor      time.Duration // How long started and cancelled events stay listed
+	TickInterval time.Duration // How often due reminders and starts are checked
+}
+
+// Service keeps the calendars. It is safe for concurrent use.
+type Service struct {
+	store  Store
+	roster *guilds.Roster
+	opts   Options
+	ranks  *guildranks.Service // Who may organize events; the roster's officers if nil
+	mail   *mail.Service       // Reminders and notices; nil sends none
+	rooms  RoomOpener          // Opens event rooms; nil opens none
+	allies Alliances           // Who sees the events opened to the alliance; nobody if nil
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	notifiers map[string]Notifier // Connected sessions by player ID
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates a Service for the guilds of roster and loads its state.
+func NewService(store Store, roster *guilds.Roster, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load guild calendars: %w", err)
+	}
+	if opts.ReminderLead <= 0 {
+		opts.ReminderLead = DefaultReminderLead
+	}
+	if opts.MaxCapacity <= 0 {
+		opts.MaxCapacity = DefaultMaxCapacity
+	}
+	if opts.MaxAhead <= 0 {
+		opts.MaxAhead = DefaultMaxAhead
+	}
+	if opts.MaxScheduled <= 0 {
+		opts.MaxScheduled = DefaultMaxScheduled
+	}
+	if opts.KeepFor <= 0 {
+		opts.KeepFor = DefaultKeepFor
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	return &Service{
+		store:     store,
+		roster:    roster,
+		opts:      opts,
+		now:       time.Now,
+		state:     state,
+		notifiers: make(map[string]Notifier),
+	}, nil
+}
+
+// UseMail mails attendees their reminders, and cancellations and starts they
+// were offline for.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// UseRanks lets the ranks the guild's permission matrix gives the events
+// permission schedule and cancel events, instead of the roster's officers.
+func (s *Service) UseRanks(ranks *guildranks.Service) {
+	s.ranks = ranks
+}
+
+// UseAlliances puts the events a guild opens to its alliance on the
+// calendars of its allies.
+func (s *Service) UseAlliances(alliances Alliances) {
+	s.allies = alliances
+}
+
+// UseRooms opens a room for each event that starts.
+func (s *Service) UseRooms(open RoomOpener) {
+	s.rooms = open
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect forgets a player's notifier.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Create schedules draft's kind, title, description, start, capacity and
+// openness to the alliance for the guild of playerID, whose rank must allow
+// it, who attends it.
+func (s *Service) Create(playerID string, draft Event) (Event, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return Event{}, ErrNoGuild
+	}
+	if !s.mayOrganize(guild.ID, playerID) {
+		return Event{}, ErrNotOfficer
+	}
+	now := s.now()
+	if draft.Capacity == 0 {
+		draft.Capacity = s.opts.MaxCapacity
+	}
+	switch {
+	case draft.Kind != KindRaid && draft.Kind != KindMeeting:
+		return Event{}, fmt.Errorf("%w: kind must be %q or %q", ErrInvalid, KindRaid, KindMeeting)
+	case draft.Title == "":
+		return Event{}, fmt.Errorf("%w: it needs a title", ErrInvalid)
+	case !draft.StartsAt.After(now) || draft.StartsAt.After(now.Add(s.opts.MaxAhead)):
+		return Event{}, fmt.Errorf("%w: it must start in the future and within %s", ErrInvalid, s.opts.MaxAhead)
+	case draft.Capacity < 1 || draft.Capacity > s.opts.MaxCapacity:
+		return Event{}, fmt.Errorf("%w: capacity must be between 1 and %d", ErrInvalid, s.opts.MaxCapacity)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scheduled := 0
+	for _, e := range s.state.Events {
+		if e.GuildID == guild.ID && e.Status == StatusScheduled {
+			scheduled++
+		}
+	}
+	if scheduled >= s.opts.MaxScheduled {
+		return Event{}, ErrTooMany
+	}
+	s.state.NextID++
+	event := Event{
+		ID:          "gev-" + strconv.FormatUint(s.state.NextID, 10),
+		GuildID:     guild.ID,
+		Kind:        draft.Kind,
+		Title:       draft.Title,
+		Description: draft.Description,
+		CreatedBy:   playerID,
+		StartsAt:    draft.StartsAt.UTC(),
+		Capacity:    draft.Capacity,
+		Attendees:   []string{playerID},
+		Status:      StatusScheduled,
+		Alliance:    draft.Alliance,
+	}
+	s.state.Events = append(s.state.Events, event)
+	sort.SliceStable(s.state.Events, func(i, j int) bool { return s.state.Events[i].StartsAt.Before(s.state.Events[j].StartsAt) })
+	s.save()
+	utils.LogInfof("Calendar: %s scheduled %s %s (%s) for guild %s at %s.", playerID, event.Kind, event.ID, event.Title, guild.ID, event.StartsAt.Format(time.RFC3339))
+	return event, nil
+}
+
+// Cancel calls off a scheduled event of playerID's guild. playerID's rank
+// must allow it; the other attendees are mailed.
+func (s *Service) Cancel(playerID, eventID string) (Event, error) {
+	s.mu.Lock()
+	e, err := s.find(playerID, eventID)
+	if err == nil && !s.mayOrganize(e.GuildID, playerID) {
+		err = ErrNotOfficer
+	}
+	if err == nil && e.Status != StatusScheduled {
+		err = ErrClosed
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Event{}, err
+	}
+	e.Status = StatusCancelled
+	event := *e
+	s.save()
+	s.mu.Unlock()
+
+	utils.LogInfof("Calendar: %s cancelled %s (%s) of guild %s.", playerID, event.ID, event.Title, event.GuildID)
+	for _, attendeeID := range event.Attendees {
+		if attendeeID != playerID {
+			s.mail.Send(mail.Message{
+				To:      attendeeID,
+				Kind:    "guild.event_cancelled",
+				Subject: fmt.Sprintf("%s is cancelled", event.Title),
+				Body:    fmt.Sprintf("The guild %s planned for %s was cancelled.", event.Kind, event.StartsAt.Format(time.RFC1123)),
+				Ref:     event.ID,
+			})
+		}
+	}
+	return event, nil
+}
+
+// RSVP records whether playerID attends a scheduled event of their guild, or
+// one an allied guild opened to the alliance. A player can only join an event
+// that has room.
+func (s *Service) RSVP(playerID, eventID string, going bool) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.find(playerID, eventID)
+	if err != nil {
+		return Event{}, err
+	}
+	if e.Status != StatusScheduled {
+		return Event{}, ErrClosed
+	}
+	switch attending := e.Attending(playerID); {
+	case going && !attending:
+		if len(e.Attendees) >= e.Capacity {
+			return Event{}, ErrFull
+		}
+		e.Attendees = append(e.Attendees, playerID)
+	case !going && attending:
+		kept := make([]string, 0, len(e.Attendees)-1)
+		for _, id := range e.Attendees {
+			if id != playerID {
+				kept = append(kept, id)
+			}
+		}
+		e.Attendees = kept
+	default:
+		return *e, nil
+	}
+	s.save()
+	return *e, nil
+}
+
+// Events returns the calendar of playerID's guild by start time: scheduled
+// events, and started and cancelled ones for a while, including those allied
+// guilds opened to the alliance.
+func (s *Service) Events(playerID string) ([]Event, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return nil, ErrNoGuild
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	for _, e := range s.state.Events {
+		if s.visible(e, guild.ID) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// mayOrganize reports whether playerID may schedule and cancel events of
+// guildID.
+func (s *Service) mayOrganize(guildID, playerID string) bool {
+	if s.ranks != nil {
+		return s.ranks.Can(guildID, playerID, guildranks.PermEvents)
+	}
+	return s.roster.IsOfficer(guildID, playerID)
+}
+
+// visible reports whether e is on the calendar of guildID.
+func (s *Service) visible(e Event, guildID string) bool {
+	return e.GuildID == guildID || e.Alliance && s.allies != nil && s.allies.Allied(e.GuildID, guildID)
+}
+
+// find returns the event eventID if it is on the calendar of playerID's
+// guild. Callers hold s.mu.
+func (s *Service) find(playerID, eventID string) (*Event, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return nil, ErrNoGuild
+	}
+	for i := range s.state.Events {
+		if e := &s.state.Events[i]; e.ID == eventID && s.visible(*e, guild.ID) {
+			return e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Start sends due reminders and starts due events in the background.
+func (s *Service) Start() {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			s.Tick(s.now())
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Tick reminds the attendees of events starting within the reminder lead,
+// starts the events due by now and forgets those that ended a while ago.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	var reminders, starting []Event
+	kept, changed := s.state.Events[:0], false
+	for _, e := range s.state.Events {
+		if e.Status == StatusScheduled && !e.Reminded && !now.Before(e.StartsAt.Add(-s.opts.ReminderLead)) {
+			e.Reminded, changed = true, true
+			if now.Before(e.StartsAt) {
+				reminders = append(reminders, e)
+			}
+		}
+		if e.Status == StatusScheduled && !now.Before(e.StartsAt) {
+			e.Status, changed = StatusStarted, true
+			starting = append(starting, e)
+		}
+		if e.Status != StatusScheduled && !now.Before(e.StartsAt.Add(s.opts.KeepFor)) {
+			changed = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.state.Events = kept
+	if changed {
+		s.save()
+	}
+	s.mu.Unlock()
+
+	for _, e := range reminders {
+		for _, attendeeID := range e.Attendees {
+			s.mail.Send(mail.Message{
+				To:      attendeeID,
+				Kind:    "guild.event_reminder",
+				Subject: fmt.Sprintf("%s starts soon", e.Title),
+				Body:    fmt.Sprintf("The guild %s you signed up for starts at %s.", e.Kind, e.StartsAt.Format(time.RFC1123)),
+				Ref:     e.ID,
+			})
+		}
+	}
+	for _, e := range starting {
+		s.begin(e)
+	}
+}
+
+// begin opens the room of an event that started and tells its attendees:
+// on their session if they are online, by mail otherwise.
+func (s *Service) begin(e Event) {
+	if s.rooms != nil && len(e.Attendees) > 0 {
+		roomID, err := s.rooms(e)
+		if err != nil {
+			utils.LogErrorf("Calendar: Could not open a room for %s (%s) of guild %s: %v", e.ID, e.Title, e.GuildID, err)
+		} else {
+			e.RoomID = roomID
+			s.mu.Lock()
+			for i := range s.state.Events {
+				if s.state.Events[i].ID == e.ID {
+					s.state.Events[i].RoomID = roomID
+				}
+			}
+			s.save()
+			s.mu.Unlock()
+		}
+	}
+	utils.LogInfof("Calendar: %s (%s) of guild %s started with %d attendee(s) in room %q.", e.ID, e.Title, e.GuildID, len(e.Attendees), e.RoomID)
+	for _, attendeeID := range e.Attendees {
+		s.mu.Lock()
+		notify := s.notifiers[attendeeID]
+		s.mu.Unlock()
+		if notify != nil {
+			notify(&Started{Event: e})
+			continue
+		}
+		body := fmt.Sprintf("The guild %s you signed up for has started.", e.Kind)
+		if e.RoomID != "" {
+			body += fmt.Sprintf(" Join room %s to take part.", e.RoomID)
+		}
+		s.mail.Send(mail.Message{
+			To:      attendeeID,
+			Kind:    "guild.event_started",
+			Subject: fmt.Sprintf("%s has started", e.Title),
+			Body:    body,
+			Ref:     e.ID,
+		})
+	}
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Calendar: Failed to save guild calendars: %v", err)
+	}
+}
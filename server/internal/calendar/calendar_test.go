@@ -0,0 +1,199 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/model"
+)
+
+func newTestService(t *testing.T, now *time.Time) (*Service, *mail.Service) {
+	t.Helper()
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", LeaderID: "ann", MemberIDs: []string{"ann", "bob", "cat"}, OfficerIDs: []string{"bob"}},
+		{ID: "bears", LeaderID: "dan", MemberIDs: []string{"dan"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(&MemoryStore{}, roster, Options{MaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *now }
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UseMail(mailService)
+	return s, mailService
+}
+
+func TestOfficersScheduleAndMembersRSVP(t *testing.T) {
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	s, _ := newTestService(t, &now)
+	raid := Event{Kind: KindRaid, Title: "Dragon raid", StartsAt: now.Add(time.Hour)}
+
+	if _, err := s.Create("cat", raid); !errors.Is(err, ErrNotOfficer) {
+		t.Fatalf("member Create: %v", err)
+	}
+	if _, err := s.Create("eve", raid); !errors.Is(err, ErrNoGuild) {
+		t.Fatalf("guildless Create: %v", err)
+	}
+	if _, err := s.Create("bob", Event{Kind: KindRaid, Title: "Too late", StartsAt: now.Add(-time.Minute)}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("past Create: %v", err)
+	}
+	event, err := s.Create("bob", raid)
+	if err != nil || event.Capacity != 2 || !event.Attending("bob") {
+		t.Fatalf("Create = %+v, %v", event, err)
+	}
+
+	if _, err := s.RSVP("dan", event.ID, true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RSVP from another guild: %v", err)
+	}
+	if event, err = s.RSVP("cat", event.ID, true); err != nil || len(event.Attendees) != 2 {
+		t.Fatalf("RSVP = %+v, %v", event, err)
+	}
+	if _, err := s.RSVP("ann", event.ID, true); !errors.Is(err, ErrFull) {
+		t.Fatalf("RSVP to a full event: %v", err)
+	}
+	if event, err = s.RSVP("bob", event.ID, false); err != nil || event.Attending("bob") {
+		t.Fatalf("RSVP no = %+v, %v", event, err)
+	}
+	if event, err = s.RSVP("ann", event.ID, true); err != nil || !event.Attending("ann") {
+		t.Fatalf("RSVP after a seat freed = %+v, %v", event, err)
+	}
+	if events, err := s.Events("cat"); err != nil || len(events) != 1 {
+		t.Fatalf("Events = %+v, %v", events, err)
+	}
+	if _, err := s.Cancel("cat", event.ID); !errors.Is(err, ErrNotOfficer) {
+		t.Fatalf("member Cancel: %v", err)
+	}
+	if event, err = s.Cancel("ann", event.ID); err != nil || event.Status != StatusCancelled {
+		t.Fatalf("Cancel = %+v, %v", event, err)
+	}
+	if _, err := s.RSVP("bob", event.ID, true); !errors.Is(err, ErrClosed) {
+		t.Fatalf("RSVP to a cancelled event: %v", err)
+	}
+}
+
+func TestRanksDecideWhoOrganizes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	s, _ := newTestService(t, &now)
+	ranks, err := guildranks.NewService(&guildranks.MemoryStore{}, s.roster, guildranks.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UseRanks(ranks)
+	raid := Event{Kind: KindRaid, Title: "Dragon raid", StartsAt: now.Add(time.Hour)}
+
+	// The leader takes events away from officers and gives them to a raid lead.
+	if _, err := ranks.DefineRank("ann", guildranks.Rank{Name: guildranks.Officer, Permissions: []guildranks.Permission{guildranks.PermInvite}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ranks.DefineRank("ann", guildranks.Rank{Name: "raid lead", Permissions: []guildranks.Permission{guildranks.PermEvents}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ranks.Assign(context.Background(), "ann", "cat", "raid lead"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("bob", raid); !errors.Is(err, ErrNotOfficer) {
+		t.Fatalf("officer Create without the events permission: %v", err)
+	}
+	event, err := s.Create("cat", raid)
+	if err != nil {
+		t.Fatalf("raid lead Create: %v", err)
+	}
+	if _, err := s.Cancel("bob", event.ID); !errors.Is(err, ErrNotOfficer) {
+		t.Fatalf("officer Cancel without the events permission: %v", err)
+	}
+	if _, err := s.Cancel("cat", event.ID); err != nil {
+		t.Fatalf("raid lead Cancel: %v", err)
+	}
+}
+
+func TestEventsRemindAndStartWithARoom(t *testing.T) {
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	s, mailService := newTestService(t, &now)
+	var opened []Event
+	s.UseRooms(func(e Event) (string, error) {
+		opened = append(opened, e)
+		return "room-" + e.ID, nil
+	})
+	var notices []*Started
+	s.Connect("bob", func(note interface{}) { notices = append(notices, note.(*Started)) })
+
+	event, err := s.Create("bob", Event{Kind: KindMeeting, Title: "Weekly meeting", StartsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RSVP("cat", event.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Tick(now.Add(50 * time.Minute))
+	if box, _ := mailService.Inbox("cat"); len(box) != 1 || box[0].Kind != "guild.event_reminder" {
+		t.Fatalf("cat's mail after the reminder = %+v", box)
+	}
+	s.Tick(now.Add(55 * time.Minute))
+	if box, _ := mailService.Inbox("cat"); len(box) != 1 {
+		t.Fatalf("reminded twice: %+v", box)
+	}
+
+	s.Tick(now.Add(time.Hour))
+	if len(opened) != 1 || len(opened[0].Attendees) != 2 {
+		t.Fatalf("opened rooms = %+v", opened)
+	}
+	if len(notices) != 1 || notices[0].Event.RoomID != "room-"+event.ID {
+		t.Fatalf("bob's notices = %+v", notices)
+	}
+	if box, _ := mailService.Inbox("cat"); len(box) != 2 || box[0].Kind != "guild.event_started" {
+		t.Fatalf("offline cat's mail after the start = %+v", box)
+	}
+	if events, _ := s.Events("ann"); len(events) != 1 || events[0].Status != StatusStarted || events[0].RoomID == "" {
+		t.Fatalf("Events after the start = %+v", events)
+	}
+
+	s.Tick(now.Add(time.Hour + DefaultKeepFor))
+	if events, _ := s.Events("ann"); len(events) != 0 {
+		t.Fatalf("Events long after the start = %+v", events)
+	}
+}
+
+type allies map[string]string // Guild -> alliance
+
+func (a allies) Allied(guildA, guildB string) bool {
+	return guildA != guildB && a[guildA] != "" && a[guildA] == a[guildB]
+}
+
+func TestAllianceEventsAreOnAlliedCalendars(t *testing.T) {
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	s, _ := newTestService(t, &now)
+	s.UseAlliances(allies{"wolves": "aln-1", "bears": "aln-1"})
+	own, err := s.Create("bob", Event{Kind: KindMeeting, Title: "Wolves only", StartsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	joint, err := s.Create("bob", Event{Kind: KindRaid, Title: "Joint raid", StartsAt: now.Add(2 * time.Hour), Alliance: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.Events("dan")
+	if err != nil || len(events) != 1 || events[0].ID != joint.ID {
+		t.Fatalf("the bears' calendar = %+v, %v", events, err)
+	}
+	if _, err := s.RSVP("dan", own.ID, true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RSVP to a guild-only event of an ally: %v", err)
+	}
+	if e, err := s.RSVP("dan", joint.ID, true); err != nil || !e.Attending("dan") {
+		t.Fatalf("RSVP to the joint raid = %+v, %v", e, err)
+	}
+	if _, err := s.Cancel("dan", joint.ID); !errors.Is(err, ErrNotOfficer) {
+		t.Fatalf("an ally cancelling: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package calendar
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted calendar: every guild's events, by start time.
+type State struct {
+	Events []Event `json:"events"`
+	NextID uint64  `json:"nextId"`
+}
+
+// Store persists the calendar state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the calendar state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the calendar state in a JSON file.
+type FileStore = jsonstore.File[State]
@@ -0,0 +1,6 @@
+//go:build chaos
+
+package chaos
+
+// Available reports whether fault injection is built into this server.
+const Available = true
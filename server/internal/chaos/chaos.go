@@ -0,0 +1,366 @@
+// Package chaos injects faults on purpose, to check that retries,
+// supervision and reconnection hold up: it can delay and drop client
+// messages, fail or stall Sui RPC calls, and crash or stop random actors.
+// Faults are off until an operator sets them through the admin API.
+//
+// Fault injection is compiled in only with the chaos build tag
+// (go build -tags chaos). In other builds New returns nil, Receiver passes
+// messages straight through, and a nil *Service injects nothing, so
+// production binaries cannot be made to misbehave by accident.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/extensions"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Kill modes.
+const (
+	KillCrash = "crash" // The actor panics in its next handler, and its supervisor restarts it
+	KillStop  = "stop"  // The actor is stopped, as if it had finished
+)
+
+// DefaultKillKinds are the actors killed when Config leaves KillKinds empty.
+var DefaultKillKinds = []string{"PlayerSessionActor", "RoomActor"}
+
+// ErrInjected wraps every Sui RPC error chaos injects.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// ErrNoActor is returned by Kill when no live actor matches.
+var ErrNoActor = errors.New("chaos: no live actor of that kind")
+
+var extensionID = extensions.NextExtensionID()
+
+// Config is the faults to inject. Rates are probabilities from 0 to 1; the
+// zero Config injects nothing.
+type Config struct {
+	ClientDelayRate  float64  `json:"clientDelayRate"`  // Client messages held back before reaching the session
+	ClientDelayMaxMs int      `json:"clientDelayMaxMs"` // Longest hold; each delay is random up to this
+	ClientDropRate   float64  `json:"clientDropRate"`   // Client messages discarded
+	SuiErrorRate     float64  `json:"suiErrorRate"`     // Sui RPC calls that fail at once
+	SuiTimeoutRate   float64  `json:"suiTimeoutRate"`   // Sui RPC calls that stall, then fail with a deadline error
+	SuiTimeoutMs     int      `json:"suiTimeoutMs"`     // How long a stalled call hangs
+	KillEverySeconds int      `json:"killEverySeconds"` // Interval between random actor kills; 0 kills none
+	KillKinds        []string `json:"killKinds"`        // Actor kinds that may be killed; empty means DefaultKillKinds
+	KillMode         string   `json:"killMode"`         // KillCrash (default) or KillStop
+}
+
+// Validate checks the rates and kill settings.
+func (c Config) Validate() error {
+	for name, rate := range map[string]float64{
+		"clientDelayRate": c.ClientDelayRate,
+		"clientDropRate":  c.ClientDropRate,
+		"suiErrorRate":    c.SuiErrorRate,
+		"suiTimeoutRate":  c.SuiTimeoutRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if c.ClientDelayMaxMs < 0 || c.SuiTimeoutMs < 0 || c.KillEverySeconds < 0 {
+		return errors.New("durations cannot be negative")
+	}
+	if c.ClientDelayRate > 0 && c.ClientDelayMaxMs == 0 {
+		return errors.New("clientDelayMaxMs is required with clientDelayRate")
+	}
+	if c.SuiTimeoutRate > 0 && c.SuiTimeoutMs == 0 {
+		return errors.New("suiTimeoutMs is required with suiTimeoutRate")
+	}
+	if c.KillMode != "" && c.KillMode != KillCrash && c.KillMode != KillStop {
+		return fmt.Errorf("unknown kill mode %q (want %s or %s)", c.KillMode, KillCrash, KillStop)
+	}
+	return nil
+}
+
+// Stats count the faults injected since startup.
+type Stats struct {
+	ClientDelayed uint64 `json:"clientDelayed"`
+	ClientDropped uint64 `json:"clientDropped"`
+	SuiErrors     uint64 `json:"suiErrors"`
+	SuiTimeouts   uint64 `json:"suiTimeouts"`
+	ActorsKilled  uint64 `json:"actorsKilled"`
+	LiveActors    int    `json:"liveActors"` // Actors tracked as kill candidates
+}
+
+// Crash makes the actor that receives it panic. Kill sends it.
+type Crash struct {
+	Reason string
+}
+
+// Service injects the faults of one actor system.
+type Service struct {
+	system *actor.ActorSystem
+
+	mu     sync.Mutex
+	cfg    Config
+	rand   *rand.Rand
+	stats  Stats
+	live   map[string]map[string]*actor.PID // Actor kind -> PID id -> PID
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+// New creates the fault injector for system, with every fault off, and
+// registers it so Receiver can find it. It returns nil unless the server was
+// built with the chaos tag.
+func New(system *actor.ActorSystem) *Service {
+	if !Available {
+		return nil
+	}
+	utils.LogWarnf("Chaos: This server was built with fault injection. Do not run it in production.")
+	return newService(system)
+}
+
+func newService(system *actor.ActorSystem) *Service {
+	s := &Service{
+		system: system,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		live:   make(map[string]map[string]*actor.PID),
+	}
+	system.Extensions.Register(s)
+	return s
+}
+
+// ExtensionID implements extensions.Extension.
+func (s *Service) ExtensionID() extensions.ExtensionID { return extensionID }
+
+// fromSystem returns the fault injector registered with system, if any.
+func fromSystem(system *actor.ActorSystem) *Service {
+	s, _ := system.Extensions.Get(extensionID).(*Service)
+	return s
+}
+
+// Configure replaces the faults being injected.
+func (s *Service) Configure(cfg Config) error {
+	if s == nil {
+		return errors.New("chaos: fault injection is not built into this server")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.stopKillerLocked()
+	if cfg.KillEverySeconds > 0 {
+		s.ticker = time.NewTicker(time.Duration(cfg.KillEverySeconds) * time.Second)
+		s.stop = make(chan struct{})
+		go s.killRandomly(s.ticker.C, s.stop)
+	}
+	return nil
+}
+
+// Config returns the faults being injected.
+func (s *Service) Config() Config {
+	if s == nil {
+		return Config{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Stats returns the faults injected so far.
+func (s *Service) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	for _, pids := range s.live {
+		stats.LiveActors += len(pids)
+	}
+	return stats
+}
+
+// Close stops killing actors.
+func (s *Service) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopKillerLocked()
+}
+
+func (s *Service) stopKillerLocked() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		close(s.stop)
+		s.ticker, s.stop = nil, nil
+	}
+}
+
+// chance reports true with probability rate. It must be called with s.mu held.
+func (s *Service) chance(rate float64) bool {
+	return rate > 0 && s.rand.Float64() < rate
+}
+
+// ClientMessage decides the fate of one client message: whether to drop it,
+// and otherwise how long to hold it first.
+func (s *Service) ClientMessage() (delay time.Duration, drop bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chance(s.cfg.ClientDropRate) {
+		s.stats.ClientDropped++
+		return 0, true
+	}
+	if s.chance(s.cfg.ClientDelayRate) {
+		s.stats.ClientDelayed++
+		return time.Duration(s.rand.Int63n(int64(s.cfg.ClientDelayMaxMs)*int64(time.Millisecond)) + 1), false
+	}
+	return 0, false
+}
+
+// SuiCall runs before each Sui RPC call and returns the error to fail it
+// with, if any. A stalled call returns after the configured timeout, or when
+// ctx is done.
+func (s *Service) SuiCall(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	var stall time.Duration
+	switch {
+	case s.chance(s.cfg.SuiErrorRate):
+		s.stats.SuiErrors++
+		s.mu.Unlock()
+		return fmt.Errorf("%w: Sui RPC error", ErrInjected)
+	case s.chance(s.cfg.SuiTimeoutRate):
+		s.stats.SuiTimeouts++
+		stall = time.Duration(s.cfg.SuiTimeoutMs) * time.Millisecond
+	}
+	s.mu.Unlock()
+	if stall == 0 {
+		return nil
+	}
+	timer := time.NewTimer(stall)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return fmt.Errorf("%w: Sui RPC timed out: %v", ErrInjected, context.DeadlineExceeded)
+}
+
+// Kill crashes or stops one live actor of kind, chosen at random, and returns
+// its PID. An empty kind picks among the kill kinds of the configuration.
+func (s *Service) Kill(kind, mode string) (*actor.PID, error) {
+	if s == nil {
+		return nil, errors.New("chaos: fault injection is not built into this server")
+	}
+	s.mu.Lock()
+	if mode == "" {
+		mode = s.cfg.KillMode
+	}
+	kinds := []string{kind}
+	if kind == "" {
+		kinds = s.cfg.KillKinds
+		if len(kinds) == 0 {
+			kinds = DefaultKillKinds
+		}
+	}
+	var candidates []*actor.PID
+	for _, k := range kinds {
+		for _, pid := range s.live[k] {
+			candidates = append(candidates, pid)
+		}
+	}
+	if len(candidates) == 0 {
+		s.mu.Unlock()
+		return nil, ErrNoActor
+	}
+	pid := candidates[s.rand.Intn(len(candidates))]
+	s.stats.ActorsKilled++
+	s.mu.Unlock()
+
+	if mode == KillStop {
+		utils.LogWarnf("Chaos: Stopping actor %s.", pid)
+		s.system.Root.Stop(pid)
+	} else {
+		utils.LogWarnf("Chaos: Crashing actor %s.", pid)
+		s.system.Root.Send(pid, &Crash{Reason: "chaos kill"})
+	}
+	return pid, nil
+}
+
+func (s *Service) killRandomly(tick <-chan time.Time, stop <-chan struct{}) {
+	for {
+		select {
+		case <-tick:
+			if _, err := s.Kill("", ""); err != nil && !errors.Is(err, ErrNoActor) {
+				utils.LogWarnf("Chaos: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Service) track(kind string, pid *actor.PID, alive bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pids := s.live[kind]
+	if !alive {
+		delete(pids, pid.Id)
+		return
+	}
+	if pids == nil {
+		pids = make(map[string]*actor.PID)
+		s.live[kind] = pids
+	}
+	pids[pid.Id] = pid
+}
+
+// Receiver is receiver middleware that keeps track of live actors, so Kill
+// can pick one, and panics on Crash. Add it to actor props with
+// actor.WithReceiverMiddleware, before the quarantine guard so chaos crashes
+// are not quarantined. Without the chaos build tag it adds nothing.
+func Receiver(next actor.ReceiverFunc) actor.ReceiverFunc {
+	if !Available {
+		return next
+	}
+	return receiver(next)
+}
+
+func receiver(next actor.ReceiverFunc) actor.ReceiverFunc {
+	return func(ctx actor.ReceiverContext, env *actor.MessageEnvelope) {
+		s := fromSystem(ctx.ActorSystem())
+		if s == nil {
+			next(ctx, env)
+			return
+		}
+		switch msg := env.Message.(type) {
+		case *actor.Started:
+			s.track(actorKind(ctx.Actor()), ctx.Self(), true)
+		case *actor.Stopped:
+			s.track(actorKind(ctx.Actor()), ctx.Self(), false)
+		case *Crash:
+			panic(fmt.Sprintf("chaos: %s", msg.Reason))
+		}
+		next(ctx, env)
+	}
+}
+
+// actorKind is the type name of an actor, e.g. "RoomActor".
+func actorKind(a actor.Actor) string {
+	kind := fmt.Sprintf("%T", a)
+	for i := len(kind) - 1; i >= 0; i-- {
+		if kind[i] == '.' {
+			return kind[i+1:]
+		}
+	}
+	return kind
+}
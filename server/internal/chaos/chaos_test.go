@@ -0,0 +1,132 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{ClientDropRate: 1.5},
+		{SuiErrorRate: -0.1},
+		{ClientDelayRate: 0.5},
+		{SuiTimeoutRate: 0.5},
+		{KillEverySeconds: -1},
+		{KillMode: "explode"},
+	} {
+		if cfg.Validate() == nil {
+			t.Errorf("%+v: no error", cfg)
+		}
+	}
+	if err := (Config{ClientDelayRate: 1, ClientDelayMaxMs: 10, KillMode: KillStop}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNilServiceInjectsNothing(t *testing.T) {
+	var s *Service
+	if delay, drop := s.ClientMessage(); delay != 0 || drop {
+		t.Error("a nil service touched a client message")
+	}
+	if err := s.SuiCall(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if s.Configure(Config{}) == nil {
+		t.Error("a nil service accepted faults")
+	}
+}
+
+func TestClientAndSuiFaults(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	s := newService(system)
+
+	if delay, drop := s.ClientMessage(); delay != 0 || drop {
+		t.Fatal("faults injected before any were configured")
+	}
+	if err := s.Configure(Config{ClientDropRate: 1, SuiErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, drop := s.ClientMessage(); !drop {
+		t.Error("client message not dropped")
+	}
+	if err := s.SuiCall(context.Background()); !errors.Is(err, ErrInjected) {
+		t.Errorf("Sui call err = %v", err)
+	}
+
+	if err := s.Configure(Config{ClientDelayRate: 1, ClientDelayMaxMs: 5, SuiTimeoutRate: 1, SuiTimeoutMs: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if delay, drop := s.ClientMessage(); drop || delay <= 0 || delay > 5*time.Millisecond {
+		t.Errorf("client message delay = %v, drop = %v", delay, drop)
+	}
+	started := time.Now()
+	if err := s.SuiCall(context.Background()); !errors.Is(err, ErrInjected) || time.Since(started) < 10*time.Millisecond {
+		t.Errorf("stalled Sui call returned %v after %v", err, time.Since(started))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Configure(Config{SuiTimeoutRate: 1, SuiTimeoutMs: 60000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SuiCall(ctx); !errors.Is(err, ErrInjected) {
+		t.Errorf("cancelled Sui call err = %v", err)
+	}
+
+	stats := s.Stats()
+	if stats.ClientDropped != 1 || stats.ClientDelayed != 1 || stats.SuiErrors != 1 || stats.SuiTimeouts != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+type victim struct{ restarts chan struct{} }
+
+func (v *victim) Receive(ctx actor.Context) {
+	if _, ok := ctx.Message().(*actor.Restarting); ok {
+		v.restarts <- struct{}{}
+	}
+}
+
+func TestKill(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	s := newService(system)
+	if _, err := s.Kill("", ""); !errors.Is(err, ErrNoActor) {
+		t.Fatalf("kill with no actors: err = %v", err)
+	}
+
+	restarts := make(chan struct{}, 1)
+	pid := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor { return &victim{restarts: restarts} }, actor.WithReceiverMiddleware(receiver)))
+	waitFor(t, func() bool { return s.Stats().LiveActors == 1 })
+
+	// Crashing hands the actor to its supervisor, which restarts it.
+	if killed, err := s.Kill("victim", KillCrash); err != nil || !killed.Equal(pid) {
+		t.Fatalf("crash = %v, %v", killed, err)
+	}
+	select {
+	case <-restarts:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the crashed actor was not restarted")
+	}
+
+	if _, err := s.Kill("victim", KillStop); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return s.Stats().LiveActors == 0 })
+	if stats := s.Stats(); stats.ActorsKilled != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !done(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package chaos
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is logged with every change.
+//
+//	GET  /admin/chaos        faults being injected, and counts of those injected so far
+//	POST /admin/chaos        a Config, replacing the faults being injected; {} turns them all off
+//	POST /admin/chaos/kill   {"kind": "RoomActor", "mode": "stop"} kills one random actor now
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/chaos", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method == http.MethodGet {
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"config": s.Config(), "stats": s.Stats()})
+			return
+		}
+		var cfg Config
+		if !decodePost(w, r, &cfg) {
+			return
+		}
+		if err := s.Configure(cfg); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		utils.LogWarnf("Chaos: %s set the faults to %+v.", operator, cfg)
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"config": s.Config(), "stats": s.Stats()})
+	}))
+	mux.HandleFunc("/admin/chaos/kill", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var req struct {
+			Kind string `json:"kind"`
+			Mode string `json:"mode"`
+		}
+		if !decodePost(w, r, &req) {
+			return
+		}
+		if req.Mode != "" && req.Mode != KillCrash && req.Mode != KillStop {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("mode must be crash or stop"))
+			return
+		}
+		pid, err := s.Kill(req.Kind, req.Mode)
+		if errors.Is(err, ErrNoActor) {
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		utils.LogWarnf("Chaos: %s killed %s.", operator, pid)
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]string{"pid": pid.String()})
+	}))
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+// Package chatchannels manages the named chat channels of a world: global,
+// trade, zone, guild and alliance. Each kind has its own policy for who is put
+// in it and how fast players may send. A player is in at most one channel of
+// each kind; the zone channel follows the player from zone to zone and the
+// guild and alliance channels are their guild's, so clients name channels by
+// kind alone.
+package chatchannels
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+)
+
+// Channel kinds.
+const (
+	Global = "global" // Everyone in the world
+	Trade  = "trade"  // Buying and selling
+	Zone   = "zone"   // Players in the same zone
+	Guild  = "guild"  // Members of the same guild
+
+	Alliance = "alliance" // Members of the guilds of the same alliance
+)
+
+// Kinds lists the channel kinds in the order they are listed.
+var Kinds = []string{Global, Trade, Zone, Guild, Alliance}
+
+var (
+	ErrUnknownChannel = errors.New("no such channel")
+	ErrUnavailable    = errors.New("channel is not available to you")
+	ErrNotJoined      = errors.New("you are not in the channel")
+	ErrThrottled      = errors.New("you are sending to the channel too fast")
+)
+
+// Policy is how a kind of channel is joined and throttled.
+type Policy struct {
+	AutoJoin          bool // Players are put in the channel on login, zone entry or by their guild
+	MessagesPerMinute int  // Sustained rate per player; 0 is unlimited
+	Burst             int  // Messages a player may send at once before the rate applies; at least 1
+}
+
+// Policies holds a Policy per kind. A missing kind can be joined by hand and
+// is not throttled.
+type Policies map[string]Policy
+
+// DefaultPolicies returns the policies used when none are configured.
+func DefaultPolicies() Policies {
+	return Policies{
+		Global: {AutoJoin: true, MessagesPerMinute: 6, Burst: 2},
+		Trade:  {MessagesPerMinute: 4, Burst: 2},
+		Zone:   {AutoJoin: true, MessagesPerMinute: 20, Burst: 5},
+		Guild:  {AutoJoin: true, MessagesPerMinute: 30, Burst: 10},
+
+		Alliance: {AutoJoin: true, MessagesPerMinute: 20, Burst: 5},
+	}
+}
+
+// Info describes one channel to a player.
+type Info struct {
+	Kind    string
+	Scope   string // Zone, guild or alliance ID of zone, guild and alliance channels
+	Joined  bool
+	Members int
+	Policy  Policy
+}
+
+// Hub is the channels of one world and their members. It belongs to a single
+// goroutine, such as the world actor's.
+type Hub struct {
+	policies Policies
+	now      func() time.Time
+	players  map[string]*member
+	channels map[key]map[string]bool // Member IDs
+}
+
+// key names one channel: a kind and, for zone and guild channels, a scope.
+type key struct {
+	kind  string
+	scope string
+}
+
+// member is an online player.
+type member struct {
+	zone    string
+	guild   string
+	joined  map[string]bool              // Kinds
+	optOut  map[string]bool              // Auto-joined kinds the player left; they stay out for the session
+	buckets map[string]*ratelimit.Bucket // Throttle the player per kind
+
+	alliance string // Alliance of the player's guild
+}
+
+// NewHub creates a Hub with policies, or DefaultPolicies if nil.
+func NewHub(policies Policies) *Hub {
+	if policies == nil {
+		policies = DefaultPolicies()
+	}
+	return &Hub{
+		policies: policies,
+		now:      time.Now,
+		players:  make(map[string]*member),
+		channels: make(map[key]map[string]bool),
+	}
+}
+
+// Connect brings playerID online in guildID's guild, or in none if empty, and
+// puts them in the auto-joined channels. Connecting again starts over.
+func (h *Hub) Connect(playerID, guildID string) {
+	h.Disconnect(playerID)
+	m := &member{
+		guild:   guildID,
+		joined:  make(map[string]bool),
+		optOut:  make(map[string]bool),
+		buckets: make(map[string]*ratelimit.Bucket),
+	}
+	h.players[playerID] = m
+	for _, kind := range Kinds {
+		if h.policies[kind].AutoJoin {
+			h.join(playerID, m, kind)
+		}
+	}
+}
+
+// Online reports whether playerID is connected.
+func (h *Hub) Online(playerID string) bool {
+	_, ok := h.players[playerID]
+	return ok
+}
+
+// Disconnect takes playerID out of every channel.
+func (h *Hub) Disconnect(playerID string) {
+	m, ok := h.players[playerID]
+	if !ok {
+		return
+	}
+	for kind := range m.joined {
+		h.leave(playerID, m, kind)
+	}
+	delete(h.players, playerID)
+}
+
+// SetZone moves playerID into zoneID, or out of any zone if empty, taking
+// their zone channel with them.
+func (h *Hub) SetZone(playerID, zoneID string) {
+	if m, ok := h.players[playerID]; ok {
+		h.move(playerID, m, Zone, &m.zone, zoneID)
+	}
+}
+
+// SetAlliance moves playerID into the channel of allianceID, or out of any
+// alliance if empty.
+func (h *Hub) SetAlliance(playerID, allianceID string) {
+	if m, ok := h.players[playerID]; ok {
+		h.move(playerID, m, Alliance, &m.alliance, allianceID)
+	}
+}
+
+// SetGuildAlliance moves every online member of guildID into the channel of
+// allianceID, or out of any alliance if empty, as their guild joins or leaves
+// one.
+func (h *Hub) SetGuildAlliance(guildID, allianceID string) {
+	for playerID, m := range h.players {
+		if m.guild == guildID {
+			h.move(playerID, m, Alliance, &m.alliance, allianceID)
+		}
+	}
+}
+
+// Join puts playerID in their channel of kind.
+func (h *Hub) Join(playerID, kind string) error {
+	m, err := h.member(playerID, kind)
+	if err != nil {
+		return err
+	}
+	delete(m.optOut, kind)
+	h.join(playerID, m, kind)
+	return nil
+}
+
+// Leave takes playerID out of their channel of kind.
+func (h *Hub) Leave(playerID, kind string) error {
+	m, err := h.member(playerID, kind)
+	if err != nil {
+		return err
+	}
+	if !m.joined[kind] {
+		return ErrNotJoined
+	}
+	h.leave(playerID, m, kind)
+	m.optOut[kind] = true
+	return nil
+}
+
+// Send checks that playerID may send to their channel of kind now and returns
+// the channel's scope and members, the sender included.
+func (h *Hub) Send(playerID, kind string) (string, []string, error) {
+	m, err := h.member(playerID, kind)
+	if err != nil {
+		return "", nil, err
+	}
+	if !m.joined[kind] {
+		return "", nil, ErrNotJoined
+	}
+	if !h.allow(m, kind) {
+		return "", nil, ErrThrottled
+	}
+	k := m.key(kind)
+	recipients := make([]string, 0, len(h.channels[k]))
+	for id := range h.channels[k] {
+		recipients = append(recipients, id)
+	}
+	sort.Strings(recipients)
+	return k.scope, recipients, nil
+}
+
+// List returns the channels available to playerID.
+func (h *Hub) List(playerID string) []Info {
+	m, ok := h.players[playerID]
+	if !ok {
+		return nil
+	}
+	infos := make([]Info, 0, len(Kinds))
+	for _, kind := range Kinds {
+		if !m.available(kind) {
+			continue
+		}
+		k := m.key(kind)
+		infos = append(infos, Info{
+			Kind:    kind,
+			Scope:   k.scope,
+			Joined:  m.joined[kind],
+			Members: len(h.channels[k]),
+			Policy:  h.policies[kind],
+		})
+	}
+	return infos
+}
+
+// Zones returns how many online players are in each zone. Players outside
+// any zone are not counted.
+func (h *Hub) Zones() map[string]int {
+	zones := make(map[string]int)
+	for _, m := range h.players {
+		if m.zone != "" {
+			zones[m.zone]++
+		}
+	}
+	return zones
+}
+
+// member returns playerID if they are online and have a channel of kind.
+func (h *Hub) member(playerID, kind string) (*member, error) {
+	if !known(kind) {
+		return nil, ErrUnknownChannel
+	}
+	m, ok := h.players[playerID]
+	if !ok || !m.available(kind) {
+		return nil, ErrUnavailable
+	}
+	return m, nil
+}
+
+// move changes the scope of m's channel of kind to to, taking their
+// membership along.
+func (h *Hub) move(playerID string, m *member, kind string, scope *string, to string) {
+	if *scope == to {
+		return
+	}
+	wasJoined := m.joined[kind]
+	if wasJoined {
+		h.leave(playerID, m, kind)
+	}
+	*scope = to
+	if wasJoined || h.policies[kind].AutoJoin && !m.optOut[kind] {
+		h.join(playerID, m, kind)
+	}
+}
+
+func (h *Hub) join(playerID string, m *member, kind string) {
+	if m.joined[kind] || !m.available(kind) {
+		return
+	}
+	k := m.key(kind)
+	if h.channels[k] == nil {
+		h.channels[k] = make(map[string]bool)
+	}
+	h.channels[k][playerID] = true
+	m.joined[kind] = true
+}
+
+func (h *Hub) leave(playerID string, m *member, kind string) {
+	k := m.key(kind)
+	delete(h.channels[k], playerID)
+	if len(h.channels[k]) == 0 {
+		delete(h.channels, k)
+	}
+	delete(m.joined, kind)
+}
+
+// allow reports whether m may send to their channel of kind now.
+func (h *Hub) allow(m *member, kind string) bool {
+	b, ok := m.buckets[kind]
+	if !ok {
+		policy := h.policies[kind]
+		b = ratelimit.NewBucket(ratelimit.PerMinute(policy.MessagesPerMinute, policy.Burst))
+		m.buckets[kind] = b
+	}
+	return b.Allow(h.now())
+}
+
+// available reports whether m has a channel of kind: zone channels need a
+// zone, guild channels a guild and alliance channels an alliance.
+func (m *member) available(kind string) bool {
+	switch kind {
+	case Zone:
+		return m.zone != ""
+	case Guild:
+		return m.guild != ""
+	case Alliance:
+		return m.alliance != ""
+	}
+	return true
+}
+
+func (m *member) key(kind string) key {
+	switch kind {
+	case Zone:
+		return key{kind, m.zone}
+	case Guild:
+		return key{kind, m.guild}
+	case Alliance:
+		return key{kind, m.alliance}
+	}
+	return key{kind: kind}
+}
+
+func known(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package chatchannels
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recipients returns who gets playerID's message to kind, failing on an error.
+func recipients(t *testing.T, h *Hub, playerID, kind string) []string {
+	t.Helper()
+	_, ids, err := h.Send(playerID, kind)
+	if err != nil {
+		t.Fatalf("%s sending to %s: %v", playerID, kind, err)
+	}
+	return ids
+}
+
+func TestAutoJoinedChannels(t *testing.T) {
+	h := NewHub(nil)
+	h.Connect("ann", "wolves")
+	h.Connect("bob", "wolves")
+	h.Connect("cid", "")
+	h.SetZone("ann", "plaza")
+	h.SetZone("cid", "plaza")
+	h.SetZone("bob", "harbor")
+
+	if got := recipients(t, h, "ann", Global); !reflect.DeepEqual(got, []string{"ann", "bob", "cid"}) {
+		t.Errorf("global reaches %v", got)
+	}
+	if got := recipients(t, h, "ann", Zone); !reflect.DeepEqual(got, []string{"ann", "cid"}) {
+		t.Errorf("zone reaches %v", got)
+	}
+	if got := recipients(t, h, "bob", Guild); !reflect.DeepEqual(got, []string{"ann", "bob"}) {
+		t.Errorf("guild reaches %v", got)
+	}
+	if _, _, err := h.Send("cid", Guild); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("guildless cid sending to guild: %v", err)
+	}
+	if _, _, err := h.Send("ann", Trade); !errors.Is(err, ErrNotJoined) {
+		t.Errorf("sending to trade without joining: %v", err)
+	}
+	if _, _, err := h.Send("ann", "shouting"); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("sending to an unknown channel: %v", err)
+	}
+
+	// Zone chat follows the player.
+	h.SetZone("cid", "harbor")
+	if got := recipients(t, h, "bob", Zone); !reflect.DeepEqual(got, []string{"bob", "cid"}) {
+		t.Errorf("after cid moved, harbor zone reaches %v", got)
+	}
+}
+
+func TestJoinAndLeave(t *testing.T) {
+	h := NewHub(nil)
+	h.Connect("ann", "")
+	h.Connect("bob", "")
+	if err := h.Join("ann", Trade); err != nil {
+		t.Fatal(err)
+	}
+	if got := recipients(t, h, "ann", Trade); !reflect.DeepEqual(got, []string{"ann"}) {
+		t.Errorf("trade reaches %v", got)
+	}
+
+	// A left zone channel is not joined again in the next zone.
+	h.SetZone("bob", "plaza")
+	if err := h.Leave("bob", Zone); err != nil {
+		t.Fatal(err)
+	}
+	h.SetZone("bob", "harbor")
+	if err := h.Leave("bob", Zone); !errors.Is(err, ErrNotJoined) {
+		t.Errorf("bob rejoined zone chat: %v", err)
+	}
+
+	h.Disconnect("ann")
+	h.Join("bob", Trade)
+	if got := recipients(t, h, "bob", Trade); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("after ann left, trade reaches %v", got)
+	}
+	if len(h.List("ann")) != 0 {
+		t.Error("channels listed for a player who is offline")
+	}
+}
+
+func TestChannelsAreThrottledSeparately(t *testing.T) {
+	now := time.Unix(1000, 0)
+	h := NewHub(Policies{
+		Global: {AutoJoin: true, MessagesPerMinute: 6, Burst: 2},
+		Trade:  {AutoJoin: true},
+	})
+	h.now = func() time.Time { return now }
+	h.Connect("ann", "")
+
+	for i := 0; i < 2; i++ {
+		recipients(t, h, "ann", Global)
+	}
+	if _, _, err := h.Send("ann", Global); !errors.Is(err, ErrThrottled) {
+		t.Errorf("third message in a burst of 2: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		recipients(t, h, "ann", Trade)
+	}
+	now = now.Add(10 * time.Second) // One message every 10s
+	recipients(t, h, "ann", Global)
+	if _, _, err := h.Send("ann", Global); !errors.Is(err, ErrThrottled) {
+		t.Errorf("second message after 10s: %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	h := NewHub(nil)
+	h.Connect("ann", "wolves")
+	h.Connect("bob", "wolves")
+	h.SetZone("ann", "plaza")
+
+	got := h.List("ann")
+	want := []Info{
+		{Kind: Global, Joined: true, Members: 2, Policy: DefaultPolicies()[Global]},
+		{Kind: Trade, Policy: DefaultPolicies()[Trade]},
+		{Kind: Zone, Scope: "plaza", Joined: true, Members: 1, Policy: DefaultPolicies()[Zone]},
+		{Kind: Guild, Scope: "wolves", Joined: true, Members: 2, Policy: DefaultPolicies()[Guild]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %+v\nwant %+v", got, want)
+	}
+	if got := h.List("bob"); len(got) != 3 {
+		t.Errorf("bob, without a zone, has %d channels, want 3", len(got))
+	}
+	if zones := h.Zones(); !reflect.DeepEqual(zones, map[string]int{"plaza": 1}) {
+		t.Errorf("Zones = %v", zones)
+	}
+}
+
+func TestAllianceChannelFollowsTheGuild(t *testing.T) {
+	h := NewHub(nil)
+	h.Connect("ann", "wolves")
+	h.Connect("bob", "bears")
+	h.Connect("cid", "wolves")
+	if _, _, err := h.Send("ann", Alliance); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("alliance chat outside an alliance: %v", err)
+	}
+	h.SetGuildAlliance("wolves", "aln-1")
+	h.SetAlliance("bob", "aln-1")
+	if got := recipients(t, h, "bob", Alliance); !reflect.DeepEqual(got, []string{"ann", "bob", "cid"}) {
+		t.Errorf("alliance reaches %v", got)
+	}
+	h.SetGuildAlliance("wolves", "")
+	if got := recipients(t, h, "bob", Alliance); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("after the wolves left, alliance reaches %v", got)
+	}
+}
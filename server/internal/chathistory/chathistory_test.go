@@ -0,0 +1,62 @@
+package chathistory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestServiceRetention(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewService(store, Options{MaxPerChannel: 3, MaxFetch: 2})
+	channel := RoomChannel("default", "room_1")
+	for _, text := range []string{"one", "two", "three", "four"} {
+		service.Record(Message{Channel: channel, SenderID: "p1", Text: text})
+	}
+	service.Record(Message{Channel: RoomChannel("default", "room_2"), SenderID: "p1", Text: "elsewhere"})
+	service.Stop() // Flushes the queue
+
+	all, _ := store.Recent(context.Background(), channel, 0)
+	if len(all) != 3 || all[0].Text != "two" {
+		t.Fatalf("stored %v, want the newest 3 messages", all)
+	}
+	recent, err := service.History(context.Background(), channel, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Text != "three" || recent[1].Text != "four" {
+		t.Fatalf("History = %v, want [three four] (capped at MaxFetch)", recent)
+	}
+}
+
+func TestWhisperChannelAndPlayerData(t *testing.T) {
+	if WhisperChannel("alice", "bob") != WhisperChannel("bob", "alice") {
+		t.Fatal("whisper channel depends on player order")
+	}
+	if WhisperChannel("a:b", "c") == WhisperChannel("a", "b:c") {
+		t.Fatal("whisper channels collide")
+	}
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	old := time.Now().Add(-48 * time.Hour)
+	store.Append(ctx, Message{Channel: WhisperChannel("alice", "bob"), SenderID: "bob", RecipientID: "alice", Text: "hi", SentAt: old}, 0)
+	store.Append(ctx, Message{Channel: RoomChannel("default", "r"), SenderID: "carol", Text: "hello", SentAt: time.Now()}, 0)
+
+	source := PrivacySource{Store: store}
+	exported, _ := source.Export(ctx, "alice")
+	if messages, ok := exported.([]Message); !ok || len(messages) != 1 {
+		t.Fatalf("export for alice = %v, want the whisper she received", exported)
+	}
+	if err := source.Erase(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if messages, _ := store.ForPlayer(ctx, "bob"); len(messages) != 0 {
+		t.Fatalf("whisper to an erased player was kept: %v", messages)
+	}
+
+	store.Append(ctx, Message{Channel: RoomChannel("default", "r"), SenderID: "dave", Text: "old", SentAt: old}, 0)
+	if removed, _ := store.DeleteBefore(ctx, time.Now().Add(-24*time.Hour)); removed != 1 {
+		t.Fatalf("DeleteBefore removed %d, want 1", removed)
+	}
+}
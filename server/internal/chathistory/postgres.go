@@ -0,0 +1,135 @@
+package chathistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS chat_messages (
+	id           BIGSERIAL PRIMARY KEY,
+	channel      TEXT NOT NULL,
+	sender_id    TEXT NOT NULL,
+	sender_name  TEXT NOT NULL,
+	recipient_id TEXT NOT NULL DEFAULT '',
+	text         TEXT NOT NULL,
+	sent_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS chat_messages_channel_id ON chat_messages (channel, id DESC);
+CREATE INDEX IF NOT EXISTS chat_messages_sender_id ON chat_messages (sender_id);
+CREATE INDEX IF NOT EXISTS chat_messages_recipient_id ON chat_messages (recipient_id) WHERE recipient_id <> '';
+CREATE INDEX IF NOT EXISTS chat_messages_sent_at ON chat_messages (sent_at);
+`
+
+// PostgresStore keeps messages in the chat_messages table.
+type PostgresStore struct {
+	DB      *sql.DB
+	ReadDB  *sql.DB       // Read replica for history queries; nil reads from DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the chat_messages table and its indexes if they are missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create chat_messages: %w", err)
+	}
+	return nil
+}
+
+// Append implements Store.
+func (s *PostgresStore) Append(ctx context.Context, msg Message, keep int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO chat_messages (channel, sender_id, sender_name, recipient_id, text, sent_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		msg.Channel, msg.SenderID, msg.SenderName, msg.RecipientID, msg.Text, msg.SentAt)
+	if err != nil {
+		return fmt.Errorf("insert chat message: %w", err)
+	}
+	if keep <= 0 {
+		return nil
+	}
+	_, err = s.DB.ExecContext(ctx,
+		`DELETE FROM chat_messages WHERE channel = $1 AND id <= (
+			SELECT id FROM chat_messages WHERE channel = $1 ORDER BY id DESC OFFSET $2 LIMIT 1)`,
+		msg.Channel, keep)
+	if err != nil {
+		return fmt.Errorf("trim chat channel %s: %w", msg.Channel, err)
+	}
+	return nil
+}
+
+// Recent implements Store.
+func (s *PostgresStore) Recent(ctx context.Context, channel string, limit int) ([]Message, error) {
+	messages, err := s.query(ctx,
+		`SELECT channel, sender_id, sender_name, recipient_id, text, sent_at FROM chat_messages
+		WHERE channel = $1 ORDER BY id DESC LIMIT $2`, channel, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// ForPlayer implements Store.
+func (s *PostgresStore) ForPlayer(ctx context.Context, playerID string) ([]Message, error) {
+	return s.query(ctx,
+		`SELECT channel, sender_id, sender_name, recipient_id, text, sent_at FROM chat_messages
+		WHERE sender_id = $1 OR recipient_id = $1 ORDER BY id`, playerID)
+}
+
+// DeletePlayer implements Store.
+func (s *PostgresStore) DeletePlayer(ctx context.Context, playerID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM chat_messages WHERE sender_id = $1 OR recipient_id = $1`, playerID); err != nil {
+		return fmt.Errorf("delete chat messages of %s: %w", playerID, err)
+	}
+	return nil
+}
+
+// DeleteBefore implements Store.
+func (s *PostgresStore) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM chat_messages WHERE sent_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("prune chat messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// query reads messages from the replica, if there is one.
+func (s *PostgresStore) query(ctx context.Context, query string, args ...interface{}) ([]Message, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	db := s.ReadDB
+	if db == nil {
+		db = s.DB
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query chat messages: %w", err)
+	}
+	defer rows.Close()
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.Channel, &msg.SenderID, &msg.SenderName, &msg.RecipientID, &msg.Text, &msg.SentAt); err != nil {
+			return nil, fmt.Errorf("scan chat message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
@@ -0,0 +1,159 @@
+package chathistory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultMaxFetch  = 100
+	DefaultQueueSize = 1024
+	pruneInterval    = 10 * time.Minute
+	writeTimeout     = 5 * time.Second
+)
+
+// Options are the retention limits of a Service.
+type Options struct {
+	MaxPerChannel int           // Older messages beyond this many per room or conversation are dropped; 0 keeps all
+	MaxAge        time.Duration // Messages older than this are pruned; 0 keeps them
+	MaxFetch      int           // Most messages one History call returns
+	QueueSize     int           // Messages waiting to be written; more are dropped
+}
+
+// Service records chat in the background, so a slow database never holds up
+// the sessions sending it, and serves recent history.
+type Service struct {
+	store    Store
+	opts     Options
+	queue    chan Message
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService starts a Service writing to store.
+func NewService(store Store, opts Options) *Service {
+	if opts.MaxFetch <= 0 {
+		opts.MaxFetch = DefaultMaxFetch
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	s := &Service{
+		store: store,
+		opts:  opts,
+		queue: make(chan Message, opts.QueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Store returns the underlying store.
+func (s *Service) Store() Store {
+	return s.store
+}
+
+// Record queues msg to be stored. It never blocks; if the queue is full the
+// message is dropped from history (it was still delivered).
+func (s *Service) Record(msg Message) {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now()
+	}
+	select {
+	case s.queue <- msg:
+	default:
+		utils.LogWarnf("ChatHistory: write queue full, message in %s not stored", msg.Channel)
+	}
+}
+
+// History returns up to limit of the channel's newest messages, oldest first.
+// The limit is capped at Options.MaxFetch.
+func (s *Service) History(ctx context.Context, channel string, limit int) ([]Message, error) {
+	if limit <= 0 || limit > s.opts.MaxFetch {
+		limit = s.opts.MaxFetch
+	}
+	return s.store.Recent(ctx, channel, limit)
+}
+
+// Stop writes the queued messages and stops the background worker.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+func (s *Service) run() {
+	defer close(s.done)
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+	if s.opts.MaxAge > 0 {
+		s.prune()
+	}
+	for {
+		select {
+		case msg := <-s.queue:
+			s.write(msg)
+		case <-prune.C:
+			if s.opts.MaxAge > 0 {
+				s.prune()
+			}
+		case <-s.stop:
+			for {
+				select {
+				case msg := <-s.queue:
+					s.write(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) write(msg Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := s.store.Append(ctx, msg, s.opts.MaxPerChannel); err != nil {
+		utils.LogErrorf("ChatHistory: failed to store message in %s: %v", msg.Channel, err)
+	}
+}
+
+func (s *Service) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	removed, err := s.store.DeleteBefore(ctx, time.Now().Add(-s.opts.MaxAge))
+	if err != nil {
+		utils.LogErrorf("ChatHistory: failed to prune old messages: %v", err)
+	} else if removed > 0 {
+		utils.LogInfof("ChatHistory: pruned %d messages older than %s", removed, s.opts.MaxAge)
+	}
+}
+
+// PrivacySource exposes stored chat to privacy export and deletion requests
+// (it implements privacy.DataSource).
+type PrivacySource struct {
+	Store Store
+}
+
+// Name implements privacy.DataSource.
+func (p PrivacySource) Name() string { return "chatHistory" }
+
+// Export implements privacy.DataSource.
+func (p PrivacySource) Export(ctx context.Context, playerID string) (interface{}, error) {
+	messages, err := p.Store.ForPlayer(ctx, playerID)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Erase implements privacy.DataSource. It removes the player's messages and
+// whisper conversations sent to them.
+func (p PrivacySource) Erase(ctx context.Context, playerID string) error {
+	return p.Store.DeletePlayer(ctx, playerID)
+}
@@ -0,0 +1,134 @@
+// Package chathistory keeps recent room and whisper chat so players see the
+// conversation they missed after reconnecting or joining a room.
+package chathistory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Message is one stored chat line.
+type Message struct {
+	Channel     string    `json:"channel"`
+	SenderID    string    `json:"senderId"`
+	SenderName  string    `json:"senderName"`
+	RecipientID string    `json:"recipientId,omitempty"` // Whispers only
+	Text        string    `json:"text"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+// RoomChannel names the history of a room. Room IDs are only unique within a world.
+func RoomChannel(worldID, roomID string) string {
+	return fmt.Sprintf("room:%s/%s", worldID, roomID)
+}
+
+// WhisperChannel names the whisper conversation between two players; the order
+// of the players does not matter.
+func WhisperChannel(playerA, playerB string) string {
+	if playerB < playerA {
+		playerA, playerB = playerB, playerA
+	}
+	// Length-prefixed so that IDs containing the separator cannot collide.
+	return fmt.Sprintf("whisper:%d:%s:%s", len(playerA), playerA, playerB)
+}
+
+// Store persists chat messages.
+type Store interface {
+	// Append stores msg, then drops the channel's oldest messages beyond keep (0 keeps all).
+	Append(ctx context.Context, msg Message, keep int) error
+	// Recent returns up to limit of the channel's newest messages, oldest first.
+	Recent(ctx context.Context, channel string, limit int) ([]Message, error)
+	// ForPlayer returns every message the player sent or received as a whisper.
+	ForPlayer(ctx context.Context, playerID string) ([]Message, error)
+	// DeletePlayer removes every message the player sent or received as a whisper.
+	DeletePlayer(ctx context.Context, playerID string) error
+	// DeleteBefore removes messages sent before cutoff and reports how many.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// MemoryStore keeps messages in memory. It is used when there is no database.
+type MemoryStore struct {
+	mu       sync.Mutex
+	channels map[string][]Message
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{channels: make(map[string][]Message)}
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(_ context.Context, msg Message, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := append(s.channels[msg.Channel], msg)
+	if keep > 0 && len(messages) > keep {
+		messages = append([]Message(nil), messages[len(messages)-keep:]...)
+	}
+	s.channels[msg.Channel] = messages
+	return nil
+}
+
+// Recent implements Store.
+func (s *MemoryStore) Recent(_ context.Context, channel string, limit int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.channels[channel]
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return append([]Message(nil), messages...), nil
+}
+
+// ForPlayer implements Store.
+func (s *MemoryStore) ForPlayer(_ context.Context, playerID string) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []Message
+	for _, messages := range s.channels {
+		for _, msg := range messages {
+			if msg.SenderID == playerID || msg.RecipientID == playerID {
+				found = append(found, msg)
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].SentAt.Before(found[j].SentAt) })
+	return found, nil
+}
+
+// DeletePlayer implements Store.
+func (s *MemoryStore) DeletePlayer(_ context.Context, playerID string) error {
+	s.filter(func(msg Message) bool { return msg.SenderID != playerID && msg.RecipientID != playerID })
+	return nil
+}
+
+// DeleteBefore implements Store.
+func (s *MemoryStore) DeleteBefore(_ context.Context, cutoff time.Time) (int64, error) {
+	return s.filter(func(msg Message) bool { return !msg.SentAt.Before(cutoff) }), nil
+}
+
+// filter keeps the messages for which keep returns true and reports how many were removed.
+func (s *MemoryStore) filter(keep func(Message) bool) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed int64
+	for channel, messages := range s.channels {
+		kept := messages[:0]
+		for _, msg := range messages {
+			if keep(msg) {
+				kept = append(kept, msg)
+			} else {
+				removed++
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.channels, channel)
+		} else {
+			s.channels[channel] = kept
+		}
+	}
+	return removed
+}
@@ -0,0 +1,250 @@
+// Package clientversion decides which client builds may connect. Clients
+// report their version in AUTH; a Gate refuses versions below the minimum,
+// which must upgrade, and above the maximum, which this server does not
+// support yet, and counts the versions it sees so a rollout can be followed.
+package clientversion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// Labels of the versions counted without a number of their own.
+const (
+	LabelNone    = "none"    // The client reported no version
+	LabelInvalid = "invalid" // The client reported something that is not a version
+	LabelOther   = "other"   // A version seen after MaxTracked others
+)
+
+// MaxTracked bounds the distinct versions counted, since clients choose what
+// they report.
+const MaxTracked = 100
+
+// Version is a client version, major.minor.patch. Missing parts are 0, and a
+// leading "v" and a pre-release or build suffix ("-beta.2", "+42") are
+// ignored.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// Parse reads a version such as "1.4", "v2.0.3" or "1.5.0-rc1".
+func Parse(s string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	parts := strings.Split(trimmed, ".")
+	if trimmed == "" || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid client version %q", s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid client version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{numbers[0], numbers[1], numbers[2]}, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Options configures a Gate.
+type Options struct {
+	Min            string // Oldest version allowed; any if empty
+	Max            string // Newest version allowed; any if empty
+	UpgradeURL     string // Where refused old clients are sent to upgrade
+	RequireVersion bool   // Refuse clients that report no valid version, as older than Min
+}
+
+// Decision is a Gate's answer to one AUTH.
+type Decision struct {
+	Allowed    bool
+	Code       string // protocol.ErrCodeUpgradeRequired or protocol.ErrCodeVersionUnsupported when refused
+	Reason     string
+	UpgradeURL string // Set with ErrCodeUpgradeRequired
+	Label      string // What the version is counted as
+}
+
+// Count is how often one version was seen.
+type Count struct {
+	Version string `json:"version"`
+	Allowed uint64 `json:"allowed"`
+	Refused uint64 `json:"refused"`
+	Online  int    `json:"online"` // Sessions of this version connected now
+}
+
+// Stats are the version distribution, newest version first and the labels
+// last.
+type Stats struct {
+	Min      string  `json:"min,omitempty"`
+	Max      string  `json:"max,omitempty"`
+	Versions []Count `json:"versions"`
+}
+
+// Gate checks reported versions and counts them. It is safe for concurrent
+// use. A nil *Gate allows every client and counts nothing.
+type Gate struct {
+	opts     Options
+	min, max *Version
+
+	mu     sync.Mutex
+	counts map[string]*Count
+}
+
+// New creates a Gate. It fails if Min or Max is not a version, or Min is
+// newer than Max.
+func New(opts Options) (*Gate, error) {
+	g := &Gate{opts: opts, counts: make(map[string]*Count)}
+	for _, bound := range []struct {
+		value string
+		into  **Version
+	}{{opts.Min, &g.min}, {opts.Max, &g.max}} {
+		if bound.value == "" {
+			continue
+		}
+		v, err := Parse(bound.value)
+		if err != nil {
+			return nil, err
+		}
+		*bound.into = &v
+	}
+	if g.min != nil && g.max != nil && g.min.Compare(*g.max) > 0 {
+		return nil, errors.New("minimum client version is newer than the maximum")
+	}
+	if opts.RequireVersion && g.min == nil {
+		return nil, errors.New("requiring a client version needs a minimum version")
+	}
+	return g, nil
+}
+
+// Check decides whether a client reporting version may authenticate, and
+// counts it.
+func (g *Gate) Check(version string) Decision {
+	if g == nil {
+		return Decision{Allowed: true}
+	}
+	d := g.decide(version)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	count := g.count(d.Label)
+	if d.Allowed {
+		count.Allowed++
+	} else {
+		count.Refused++
+	}
+	return d
+}
+
+// Connected counts a session of the version a Decision allowed as online,
+// until the returned function is called.
+func (g *Gate) Connected(d Decision) (disconnected func()) {
+	if g == nil || !d.Allowed {
+		return func() {}
+	}
+	g.mu.Lock()
+	g.count(d.Label).Online++
+	g.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.count(d.Label).Online--
+			g.mu.Unlock()
+		})
+	}
+}
+
+// Stats returns the versions seen since start.
+func (g *Gate) Stats() Stats {
+	if g == nil {
+		return Stats{Versions: []Count{}}
+	}
+	stats := Stats{Min: g.opts.Min, Max: g.opts.Max, Versions: []Count{}}
+	g.mu.Lock()
+	for _, count := range g.counts {
+		stats.Versions = append(stats.Versions, *count)
+	}
+	g.mu.Unlock()
+	sort.Slice(stats.Versions, func(i, j int) bool {
+		a, errA := Parse(stats.Versions[i].Version)
+		b, errB := Parse(stats.Versions[j].Version)
+		switch {
+		case errA == nil && errB == nil:
+			return a.Compare(b) > 0
+		case errA == nil || errB == nil:
+			return errA == nil
+		}
+		return stats.Versions[i].Version < stats.Versions[j].Version
+	})
+	return stats
+}
+
+func (g *Gate) decide(reported string) Decision {
+	if strings.TrimSpace(reported) == "" {
+		return g.unknown(LabelNone, "no client version reported")
+	}
+	v, err := Parse(reported)
+	if err != nil {
+		return g.unknown(LabelInvalid, err.Error())
+	}
+	d := Decision{Allowed: true, Label: v.String()}
+	switch {
+	case g.min != nil && v.Compare(*g.min) < 0:
+		d = Decision{Code: protocol.ErrCodeUpgradeRequired, UpgradeURL: g.opts.UpgradeURL, Label: d.Label,
+			Reason: fmt.Sprintf("Client version %s is no longer supported. Please upgrade to %s or later.", v, g.min)}
+	case g.max != nil && v.Compare(*g.max) > 0:
+		d = Decision{Code: protocol.ErrCodeVersionUnsupported, Label: d.Label,
+			Reason: fmt.Sprintf("Client version %s is newer than this server supports (up to %s).", v, g.max)}
+	}
+	return d
+}
+
+// unknown decides on a client without a valid version: allowed unless a
+// version is required, in which case it is treated as too old.
+func (g *Gate) unknown(label, reason string) Decision {
+	if !g.opts.RequireVersion {
+		return Decision{Allowed: true, Label: label}
+	}
+	return Decision{Code: protocol.ErrCodeUpgradeRequired, UpgradeURL: g.opts.UpgradeURL, Label: label,
+		Reason: fmt.Sprintf("This client is no longer supported (%s). Please upgrade to %s or later.", reason, g.min)}
+}
+
+// count returns the count of label, folding new versions into LabelOther once
+// MaxTracked are counted. Callers hold g.mu.
+func (g *Gate) count(label string) *Count {
+	if count, ok := g.counts[label]; ok {
+		return count
+	}
+	if len(g.counts) >= MaxTracked {
+		label = LabelOther
+		if count, ok := g.counts[label]; ok {
+			return count
+		}
+	}
+	count := &Count{Version: label}
+	g.counts[label] = count
+	return count
+}
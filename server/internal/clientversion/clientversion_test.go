@@ -0,0 +1,80 @@
+package clientversion
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]Version{"1.4": {1, 4, 0}, "v2.0.3": {2, 0, 3}, "1.5.0-rc1": {1, 5, 0}, " 3+build7 ": {3, 0, 0}} {
+		if got, err := Parse(in); err != nil || got != want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "v", "1.2.3.4", "1..2", "one", "-1", "1.-2"} {
+		if v, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v", in, v)
+		}
+	}
+}
+
+func TestGateRefusesVersionsOutsideTheRange(t *testing.T) {
+	g, err := New(Options{Min: "1.4", Max: "2.0.99", UpgradeURL: "https://example.com/get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := g.Check("1.3.9")
+	if old.Allowed || old.Code != protocol.ErrCodeUpgradeRequired || old.UpgradeURL != "https://example.com/get" {
+		t.Errorf("old client: %+v", old)
+	}
+	if d := g.Check("2.1.0"); d.Allowed || d.Code != protocol.ErrCodeVersionUnsupported || d.UpgradeURL != "" {
+		t.Errorf("new client: %+v", d)
+	}
+	current := g.Check("v1.4.0")
+	if !current.Allowed || !g.Check("").Allowed || !g.Check("nightly").Allowed {
+		t.Error("a current client, or one without a version, was refused")
+	}
+
+	done := g.Connected(current)
+	g.Connected(g.Check("2.0.1"))
+	done()
+	done()
+	stats := g.Stats()
+	want := []Count{{Version: "2.1.0", Refused: 1}, {Version: "2.0.1", Allowed: 1, Online: 1}, {Version: "1.4.0", Allowed: 1}, {Version: "1.3.9", Refused: 1}}
+	for i, count := range want {
+		if stats.Versions[i] != count {
+			t.Errorf("versions[%d] = %+v, want %+v", i, stats.Versions[i], count)
+		}
+	}
+	if len(stats.Versions) != 6 || stats.Versions[4].Version != LabelInvalid || stats.Versions[5].Version != LabelNone {
+		t.Errorf("versions = %+v", stats.Versions)
+	}
+}
+
+func TestGateRequiringAVersion(t *testing.T) {
+	if _, err := New(Options{RequireVersion: true}); err == nil {
+		t.Error("a required version without a minimum was accepted")
+	}
+	if _, err := New(Options{Min: "2", Max: "1.9"}); err == nil {
+		t.Error("a minimum above the maximum was accepted")
+	}
+	g, _ := New(Options{Min: "1.0", RequireVersion: true})
+	for _, reported := range []string{"", "latest"} {
+		if d := g.Check(reported); d.Allowed || d.Code != protocol.ErrCodeUpgradeRequired {
+			t.Errorf("Check(%q) = %+v", reported, d)
+		}
+	}
+}
+
+func TestGateBoundsTheVersionsItCounts(t *testing.T) {
+	g, _ := New(Options{})
+	for i := 0; i < MaxTracked+10; i++ {
+		g.Check(fmt.Sprintf("1.0.%d", i))
+	}
+	stats := g.Stats()
+	if len(stats.Versions) != MaxTracked+1 || stats.Versions[len(stats.Versions)-1] != (Count{Version: LabelOther, Allowed: 10}) {
+		t.Errorf("%d versions counted, the last %+v", len(stats.Versions), stats.Versions[len(stats.Versions)-1])
+	}
+}
@@ -0,0 +1,282 @@
+// Package cosmetics keeps what players wear. A player equips cosmetics, one
+// per slot, from the item NFTs their primary linked address owns; the server
+// checks the chain when a cosmetic is equipped and again, from a cache, when
+// the player logs in, so an NFT traded away does not stay on show. Rooms
+// replicate the equipped cosmetics to the other members.
+package cosmetics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultOwnershipTTL is how long the cosmetics an address owns are trusted
+// when Options.OwnershipTTL is zero.
+const DefaultOwnershipTTL = 5 * time.Minute
+
+// recheckAfter is how soon a cached lookup is repeated when a player equips
+// a cosmetic it does not list, e.g. one they were just traded.
+const recheckAfter = 10 * time.Second
+
+var (
+	ErrUnknownSlot          = errors.New("unknown cosmetic slot")
+	ErrUnknownCosmetic      = errors.New("unknown cosmetic")
+	ErrWrongSlot            = errors.New("cosmetic does not fit that slot")
+	ErrNotOwned             = errors.New("cosmetic is not owned")
+	ErrOwnershipUnavailable = errors.New("cosmetic ownership could not be checked")
+)
+
+// Cosmetic is an item type that can be worn.
+type Cosmetic struct {
+	ID   string `json:"id"` // Item type its NFTs are minted as
+	Name string `json:"name"`
+	Slot string `json:"slot"`
+}
+
+// Catalog is the slots and the cosmetics that fit them.
+type Catalog struct {
+	Slots     []string   `json:"slots"`
+	Cosmetics []Cosmetic `json:"cosmetics"`
+}
+
+// LoadCatalog reads and validates a cosmetics file.
+func LoadCatalog(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, err
+	}
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("invalid cosmetics %s: %w", path, err)
+	}
+	slots := make(map[string]bool, len(catalog.Slots))
+	for _, slot := range catalog.Slots {
+		if slot == "" || slots[slot] {
+			return Catalog{}, fmt.Errorf("invalid cosmetics %s: slot %q is empty or listed twice", path, slot)
+		}
+		slots[slot] = true
+	}
+	seen := make(map[string]bool, len(catalog.Cosmetics))
+	for i, c := range catalog.Cosmetics {
+		switch {
+		case c.ID == "":
+			return Catalog{}, fmt.Errorf("invalid cosmetics %s: cosmetic %d needs an id", path, i+1)
+		case seen[c.ID]:
+			return Catalog{}, fmt.Errorf("invalid cosmetics %s: duplicate cosmetic %q", path, c.ID)
+		case !slots[c.Slot]:
+			return Catalog{}, fmt.Errorf("invalid cosmetics %s: cosmetic %q has unknown slot %q", path, c.ID, c.Slot)
+		}
+		if c.Name == "" {
+			catalog.Cosmetics[i].Name = c.ID
+		}
+		seen[c.ID] = true
+	}
+	return catalog, nil
+}
+
+// AddressFunc returns a player's primary linked address.
+type AddressFunc func(ctx context.Context, playerID string) (string, error)
+
+// OwnedFunc returns the item types of the NFTs address owns.
+type OwnedFunc func(ctx context.Context, address string) ([]string, error)
+
+// Options configures a Service.
+type Options struct {
+	OwnershipTTL time.Duration // How long an address's cosmetics are trusted
+}
+
+// owned is the cosmetics an address was last seen to own.
+type owned struct {
+	types     map[string]bool
+	fetchedAt time.Time
+}
+
+// Service equips cosmetics and remembers what addresses own. It is safe for
+// concurrent use.
+type Service struct {
+	slots     map[string]bool
+	cosmetics map[string]Cosmetic
+	store     Store
+	address   AddressFunc
+	ownedBy   OwnedFunc
+	opts      Options
+	now       func() time.Time
+
+	mu    sync.Mutex // Guards cache and serializes loading and saving appearances
+	cache map[string]owned
+}
+
+// NewService creates a Service. Ownership is looked up with address and
+// ownedBy.
+func NewService(catalog Catalog, store Store, address AddressFunc, ownedBy OwnedFunc, opts Options) *Service {
+	if opts.OwnershipTTL <= 0 {
+		opts.OwnershipTTL = DefaultOwnershipTTL
+	}
+	s := &Service{
+		slots:     make(map[string]bool, len(catalog.Slots)),
+		cosmetics: make(map[string]Cosmetic, len(catalog.Cosmetics)),
+		store:     store,
+		address:   address,
+		ownedBy:   ownedBy,
+		opts:      opts,
+		now:       time.Now,
+		cache:     make(map[string]owned),
+	}
+	for _, slot := range catalog.Slots {
+		s.slots[slot] = true
+	}
+	for _, c := range catalog.Cosmetics {
+		s.cosmetics[c.ID] = c
+	}
+	return s
+}
+
+// Subscribe forgets the cached cosmetics of addresses that are minted an
+// item, so a reward can be worn right away.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicItemMinted, "cosmetics", func(e events.ItemMinted) {
+		s.Forget(e.Owner)
+	})
+}
+
+// Forget drops the cached cosmetics of address.
+func (s *Service) Forget(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, address)
+}
+
+// Prime caches the item types of the NFTs address was seen to own at
+// fetchedAt, e.g. by the login prefetch, so Load need not look them up.
+func (s *Service) Prime(address string, itemTypes []string, fetchedAt time.Time) {
+	s.cacheOwned(address, itemTypes, fetchedAt)
+}
+
+// Load returns what playerID wears, by slot. Cosmetics their address no
+// longer owns are taken off; if ownership cannot be checked the stored
+// appearance is returned as is.
+func (s *Service) Load(ctx context.Context, playerID string) (map[string]string, error) {
+	s.mu.Lock()
+	equipped, err := s.store.Load(ctx, playerID)
+	s.mu.Unlock()
+	if err != nil || len(equipped) == 0 {
+		return equipped, err
+	}
+	types, err := s.owned(ctx, playerID, "")
+	if err != nil {
+		utils.LogWarnf("Cosmetics: Could not recheck what %s wears: %v", playerID, err)
+		return equipped, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	equipped, err = s.store.Load(ctx, playerID) // Reloaded: the player may have changed it meanwhile
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for slot, id := range equipped {
+		if !types[id] || s.cosmetics[id].Slot != slot {
+			delete(equipped, slot)
+			changed = true
+		}
+	}
+	if changed {
+		if err := s.store.Save(ctx, playerID, equipped); err != nil {
+			return nil, err
+		}
+	}
+	return equipped, nil
+}
+
+// Equip puts cosmeticID on playerID in slot, or takes off what they wear
+// there if cosmeticID is empty, and returns what they wear now.
+func (s *Service) Equip(ctx context.Context, playerID, slot, cosmeticID string) (map[string]string, error) {
+	if !s.slots[slot] {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSlot, slot)
+	}
+	if cosmeticID != "" {
+		cosmetic, ok := s.cosmetics[cosmeticID]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%w %q", ErrUnknownCosmetic, cosmeticID)
+		case cosmetic.Slot != slot:
+			return nil, fmt.Errorf("%w: %s is worn on %s", ErrWrongSlot, cosmeticID, cosmetic.Slot)
+		}
+		types, err := s.owned(ctx, playerID, cosmeticID)
+		if err != nil {
+			return nil, err
+		}
+		if !types[cosmeticID] {
+			return nil, fmt.Errorf("%w: %s has no %s", ErrNotOwned, playerID, cosmeticID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	equipped, err := s.store.Load(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if equipped == nil {
+		equipped = make(map[string]string)
+	}
+	if equipped[slot] == cosmeticID {
+		return equipped, nil
+	}
+	if cosmeticID == "" {
+		delete(equipped, slot)
+	} else {
+		equipped[slot] = cosmeticID
+	}
+	if err := s.store.Save(ctx, playerID, equipped); err != nil {
+		return nil, err
+	}
+	return equipped, nil
+}
+
+// owned returns the cosmetics playerID's address owns, from the cache while
+// it is fresh. A cached lookup missing want is repeated once it is a few
+// seconds old.
+func (s *Service) owned(ctx context.Context, playerID, want string) (map[string]bool, error) {
+	address, err := s.address(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOwnershipUnavailable, err)
+	}
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.cache[address]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < s.opts.OwnershipTTL && (want == "" || cached.types[want] || now.Sub(cached.fetchedAt) < recheckAfter) {
+		return cached.types, nil
+	}
+
+	itemTypes, err := s.ownedBy(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOwnershipUnavailable, err)
+	}
+	return s.cacheOwned(address, itemTypes, now), nil
+}
+
+// cacheOwned remembers the cosmetics among itemTypes that address owned at
+// fetchedAt, and returns them.
+func (s *Service) cacheOwned(address string, itemTypes []string, fetchedAt time.Time) map[string]bool {
+	types := make(map[string]bool)
+	for _, itemType := range itemTypes {
+		if _, ok := s.cosmetics[itemType]; ok {
+			types[itemType] = true
+		}
+	}
+	s.mu.Lock()
+	s.cache[address] = owned{types: types, fetchedAt: fetchedAt}
+	s.mu.Unlock()
+	return types
+}
@@ -0,0 +1,101 @@
+package cosmetics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testCatalog = Catalog{
+	Slots: []string{"back", "head"},
+	Cosmetics: []Cosmetic{
+		{ID: "ember_cape", Slot: "back"},
+		{ID: "ember_crown", Slot: "head"},
+	},
+}
+
+// chain is a fake ledger of the item types each address owns.
+type chain struct {
+	items   map[string][]string
+	lookups int
+}
+
+func (c *chain) owned(ctx context.Context, address string) ([]string, error) {
+	c.lookups++
+	return c.items[address], nil
+}
+
+func newTestService(c *chain, store Store) (*Service, *time.Time) {
+	address := func(ctx context.Context, playerID string) (string, error) { return "0x" + playerID, nil }
+	s := NewService(testCatalog, store, address, c.owned, Options{})
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestEquipChecksOwnership(t *testing.T) {
+	ctx := context.Background()
+	c := &chain{items: map[string][]string{"0xalice": {"ember_cape", "sword"}}}
+	s, now := newTestService(c, NewMemoryStore())
+
+	equipped, err := s.Equip(ctx, "alice", "back", "ember_cape")
+	if err != nil || equipped["back"] != "ember_cape" {
+		t.Fatalf("equip = %v, %v", equipped, err)
+	}
+	if _, err := s.Equip(ctx, "alice", "head", "ember_cape"); !errors.Is(err, ErrWrongSlot) {
+		t.Errorf("cape on the head: %v", err)
+	}
+	if _, err := s.Equip(ctx, "alice", "feet", ""); !errors.Is(err, ErrUnknownSlot) {
+		t.Errorf("unknown slot: %v", err)
+	}
+	if _, err := s.Equip(ctx, "alice", "head", "ember_crown"); !errors.Is(err, ErrNotOwned) {
+		t.Errorf("crown alice does not own: %v", err)
+	}
+	if c.lookups != 1 {
+		t.Errorf("lookups within the recheck delay = %d", c.lookups)
+	}
+
+	// Alice is traded a crown; a failed equip looks again after a few seconds.
+	c.items["0xalice"] = append(c.items["0xalice"], "ember_crown")
+	*now = now.Add(recheckAfter)
+	if _, err := s.Equip(ctx, "alice", "head", "ember_crown"); err != nil {
+		t.Fatal(err)
+	}
+	if c.lookups != 2 {
+		t.Errorf("lookups after the recheck delay = %d", c.lookups)
+	}
+	equipped, err = s.Equip(ctx, "alice", "head", "")
+	if err != nil || len(equipped) != 1 || c.lookups != 2 {
+		t.Errorf("unequip = %v, %v after %d lookups", equipped, err, c.lookups)
+	}
+}
+
+func TestLoadTakesOffCosmeticsNoLongerOwned(t *testing.T) {
+	ctx := context.Background()
+	c := &chain{items: map[string][]string{"0xbob": {"ember_cape", "ember_crown"}}}
+	store := NewMemoryStore()
+	s, now := newTestService(c, store)
+	s.Equip(ctx, "bob", "back", "ember_cape")
+	s.Equip(ctx, "bob", "head", "ember_crown")
+
+	c.items["0xbob"] = []string{"ember_crown"} // Bob sold his cape
+	if equipped, _ := s.Load(ctx, "bob"); len(equipped) != 2 {
+		t.Errorf("load from the cache = %v", equipped)
+	}
+	*now = now.Add(DefaultOwnershipTTL)
+	equipped, err := s.Load(ctx, "bob")
+	if err != nil || len(equipped) != 1 || equipped["head"] != "ember_crown" {
+		t.Fatalf("load after the cache expired = %v, %v", equipped, err)
+	}
+	if stored, _ := store.Load(ctx, "bob"); len(stored) != 1 {
+		t.Errorf("stored = %v", stored)
+	}
+
+	s.Forget("0xbob")
+	lookups := c.lookups
+	s.Load(ctx, "bob")
+	if c.lookups != lookups+1 {
+		t.Errorf("load after Forget did not look up")
+	}
+}
@@ -0,0 +1,88 @@
+package cosmetics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS player_cosmetics (
+	player_id   TEXT NOT NULL,
+	slot        TEXT NOT NULL,
+	cosmetic_id TEXT NOT NULL,
+	PRIMARY KEY (player_id, slot)
+);
+`
+
+// PostgresStore keeps appearances in the player_cosmetics table, one row per
+// worn cosmetic.
+type PostgresStore struct {
+	DB      *sql.DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the player_cosmetics table if it is missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create player_cosmetics: %w", err)
+	}
+	return nil
+}
+
+// Load implements Store.
+func (s *PostgresStore) Load(ctx context.Context, playerID string) (map[string]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.DB.QueryContext(ctx, `SELECT slot, cosmetic_id FROM player_cosmetics WHERE player_id = $1`, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("load cosmetics of %s: %w", playerID, err)
+	}
+	defer rows.Close()
+	var equipped map[string]string
+	for rows.Next() {
+		var slot, id string
+		if err := rows.Scan(&slot, &id); err != nil {
+			return nil, fmt.Errorf("load cosmetics of %s: %w", playerID, err)
+		}
+		if equipped == nil {
+			equipped = make(map[string]string)
+		}
+		equipped[slot] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load cosmetics of %s: %w", playerID, err)
+	}
+	return equipped, nil
+}
+
+// Save implements Store. The player's rows are replaced in one transaction.
+func (s *PostgresStore) Save(ctx context.Context, playerID string, equipped map[string]string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save cosmetics of %s: %w", playerID, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM player_cosmetics WHERE player_id = $1`, playerID); err != nil {
+		return fmt.Errorf("save cosmetics of %s: %w", playerID, err)
+	}
+	for slot, id := range equipped {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO player_cosmetics (player_id, slot, cosmetic_id) VALUES ($1, $2, $3)`, playerID, slot, id); err != nil {
+			return fmt.Errorf("save cosmetics of %s: %w", playerID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save cosmetics of %s: %w", playerID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
@@ -0,0 +1,51 @@
+package cosmetics
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists what players wear.
+type Store interface {
+	// Load returns playerID's equipped cosmetics by slot; nil if none.
+	Load(ctx context.Context, playerID string) (map[string]string, error)
+	Save(ctx context.Context, playerID string, equipped map[string]string) error
+}
+
+// MemoryStore keeps appearances in memory. It is used when there is no
+// database.
+type MemoryStore struct {
+	mu       sync.Mutex
+	equipped map[string]map[string]string
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{equipped: make(map[string]map[string]string)}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load(ctx context.Context, playerID string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copyEquipped(m.equipped[playerID]), nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(ctx context.Context, playerID string, equipped map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.equipped[playerID] = copyEquipped(equipped)
+	return nil
+}
+
+func copyEquipped(equipped map[string]string) map[string]string {
+	if equipped == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(equipped))
+	for slot, id := range equipped {
+		copied[slot] = id
+	}
+	return copied
+}
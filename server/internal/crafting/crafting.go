@@ -0,0 +1,455 @@
+// Package crafting runs players' crafting queues. A recipe turns coins and
+// inventory items into an item NFT after a set time. Players queue crafts,
+// which run one after another and keep running while the player is offline:
+// each craft is stored with its completion time, and a scheduler finalizes the
+// ones that are due, queues their mints in the outbox and mails the player.
+package crafting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// MintKind is the outbox message kind of a finished craft's mint. Its payload
+// is the Craft.
+const MintKind = "crafting.mint"
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultMaxQueued    = 5
+	DefaultTickInterval = 10 * time.Second
+	DefaultBatchSize    = 50
+)
+
+var (
+	ErrUnknownRecipe     = errors.New("unknown recipe")
+	ErrQueueFull         = errors.New("the crafting queue is full")
+	ErrMissingMaterials  = errors.New("missing crafting materials")
+	ErrInsufficientFunds = shop.ErrInsufficientFunds
+)
+
+// Recipe is one entry of the recipes file.
+type Recipe struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	ItemType        string         `json:"itemType"` // Item NFT minted when the craft finishes
+	DurationSeconds int            `json:"durationSeconds"`
+	Coins           int64          `json:"coins,omitempty"`     // Soft currency the craft costs
+	Materials       map[string]int `json:"materials,omitempty"` // Inventory items used up, by item ID
+}
+
+// Duration returns how long a craft of the recipe takes.
+func (r Recipe) Duration() time.Duration {
+	return time.Duration(r.DurationSeconds) * time.Second
+}
+
+// LoadRecipes reads and validates a recipes file.
+func LoadRecipes(path string) ([]Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Recipes []Recipe `json:"recipes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid recipes %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(file.Recipes))
+	for i, r := range file.Recipes {
+		switch {
+		case r.ID == "" || r.ItemType == "":
+			return nil, fmt.Errorf("invalid recipes %s: recipe %d needs an id and an itemType", path, i+1)
+		case seen[r.ID]:
+			return nil, fmt.Errorf("invalid recipes %s: duplicate recipe %q", path, r.ID)
+		case r.DurationSeconds <= 0 || r.Coins < 0:
+			return nil, fmt.Errorf("invalid recipes %s: recipe %q needs a positive duration and no negative cost", path, r.ID)
+		}
+		for itemID, quantity := range r.Materials {
+			if quantity <= 0 {
+				return nil, fmt.Errorf("invalid recipes %s: recipe %q needs a positive quantity of %q", path, r.ID, itemID)
+			}
+		}
+		if r.Name == "" {
+			file.Recipes[i].Name = r.ID
+		}
+		seen[r.ID] = true
+	}
+	return file.Recipes, nil
+}
+
+// Status is where a craft stands in storage.
+type Status string
+
+const (
+	StatusQueued Status = "queued" // Waiting for its turn or being crafted
+	StatusReady  Status = "ready"  // Finished; its item is being minted
+)
+
+// Craft is one queued or finished craft. Minted crafts are removed.
+type Craft struct {
+	ID          string    `json:"id"`
+	PlayerID    string    `json:"playerId"`
+	RecipeID    string    `json:"recipeId"`
+	Name        string    `json:"name"`
+	ItemType    string    `json:"itemType"`
+	Status      Status    `json:"status"`
+	QueuedAt    time.Time `json:"queuedAt"`
+	StartsAt    time.Time `json:"startsAt"` // When the craft before it in the queue finishes
+	CompletesAt time.Time `json:"completesAt"`
+	FinishedAt  time.Time `json:"finishedAt,omitempty"`
+}
+
+// Stage describes the craft to its player at now: "queued", "crafting" or
+// "ready".
+func (c Craft) Stage(now time.Time) string {
+	switch {
+	case c.Status == StatusReady:
+		return "ready"
+	case now.Before(c.StartsAt):
+		return "queued"
+	}
+	return "crafting"
+}
+
+// Notice tells a connected session that one of its player's crafts finished.
+type Notice struct {
+	Craft Craft
+}
+
+// Notifier delivers a *Notice to a player's session.
+type Notifier func(note interface{})
+
+// Minter mints the item of a finished craft and returns the transaction digest.
+type Minter func(ctx context.Context, craft Craft) (txDigest string, err error)
+
+// Options configures a Service.
+type Options struct {
+	MaxQueued    int           // Unfinished crafts per player
+	TickInterval time.Duration // How often due crafts are finalized
+	BatchSize    int           // Crafts finalized per query
+}
+
+// Service runs the crafting queues. It is safe for concurrent use.
+type Service struct {
+	recipes map[string]Recipe
+	store   Store
+	wallets shop.WalletStore
+	opts    Options
+	jobs    *outbox.Outbox // Mints of finished crafts; nil mints nothing
+	mail    *mail.Service  // Tells players their crafts finished; nil sends none
+	leader  func() bool    // Whether this instance finalizes crafts; nil always does
+	now     func() time.Time
+
+	mu        sync.Mutex // Serializes queueing, so a player's crafts do not overlap
+	notifiers map[string]Notifier
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates a Service for recipes. Costs are taken from wallets.
+func NewService(recipes []Recipe, store Store, wallets shop.WalletStore, opts Options) *Service {
+	byID := make(map[string]Recipe, len(recipes))
+	for _, r := range recipes {
+		byID[r.ID] = r
+	}
+	if opts.MaxQueued <= 0 {
+		opts.MaxQueued = DefaultMaxQueued
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	return &Service{
+		recipes:   byID,
+		store:     store,
+		wallets:   wallets,
+		opts:      opts,
+		now:       time.Now,
+		notifiers: make(map[string]Notifier),
+	}
+}
+
+// UseMinting queues the mint of every finished craft in jobs. With a nil
+// mint the mints stay queued until a server with a minter delivers them.
+func (s *Service) UseMinting(jobs *outbox.Outbox, mint Minter) {
+	s.jobs = jobs
+	if mint != nil {
+		jobs.Handle(MintKind, func(ctx context.Context, payload json.RawMessage) error {
+			return s.mintCraft(ctx, payload, mint)
+		})
+	}
+}
+
+// UseMail mails players when their crafts finish.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// UseLeader makes only the instance for which leader reports true finalize
+// crafts, for a store shared by several instances.
+func (s *Service) UseLeader(leader func() bool) {
+	s.leader = leader
+}
+
+// Recipes returns the recipes, by ID.
+func (s *Service) Recipes() []Recipe {
+	recipes := make([]Recipe, 0, len(s.recipes))
+	for _, r := range s.recipes {
+		recipes = append(recipes, r)
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i].ID < recipes[j].ID })
+	return recipes
+}
+
+// MaxQueued returns how many unfinished crafts a player may have.
+func (s *Service) MaxQueued() int {
+	return s.opts.MaxQueued
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect forgets a player's notifier. Their crafts carry on.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Queue pays for a craft of recipeID and adds it to the end of playerID's
+// queue. It starts when the player's last unfinished craft completes, or now.
+func (s *Service) Queue(ctx context.Context, playerID, recipeID string) (Craft, error) {
+	recipe, ok := s.recipes[recipeID]
+	if !ok {
+		return Craft{}, fmt.Errorf("%w: %s", ErrUnknownRecipe, recipeID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crafts, err := s.store.ForPlayer(ctx, playerID)
+	if err != nil {
+		return Craft{}, err
+	}
+	now := s.now().UTC()
+	startsAt, queued := now, 0
+	for _, c := range crafts {
+		if c.Status != StatusQueued {
+			continue
+		}
+		queued++
+		if c.CompletesAt.After(startsAt) {
+			startsAt = c.CompletesAt
+		}
+	}
+	if queued >= s.opts.MaxQueued {
+		return Craft{}, ErrQueueFull
+	}
+	if err := s.pay(playerID, recipe); err != nil {
+		return Craft{}, err
+	}
+	craft := Craft{
+		ID:          newCraftID(),
+		PlayerID:    playerID,
+		RecipeID:    recipe.ID,
+		Name:        recipe.Name,
+		ItemType:    recipe.ItemType,
+		Status:      StatusQueued,
+		QueuedAt:    now,
+		StartsAt:    startsAt,
+		CompletesAt: startsAt.Add(recipe.Duration()),
+	}
+	if err := s.store.Add(ctx, craft); err != nil {
+		if refundErr := s.refund(playerID, recipe); refundErr != nil {
+			utils.LogErrorf("Crafting: Could not refund %s for craft of %s that was not stored: %v", playerID, recipe.ID, refundErr)
+		}
+		return Craft{}, err
+	}
+	utils.LogInfof("Crafting: %s queued %s (%s), completing at %s.", playerID, recipe.ID, craft.ID, craft.CompletesAt.Format(time.RFC3339))
+	return craft, nil
+}
+
+// Crafts returns playerID's queued and ready crafts, in queue order.
+func (s *Service) Crafts(ctx context.Context, playerID string) ([]Craft, error) {
+	return s.store.ForPlayer(ctx, playerID)
+}
+
+// Start finalizes due crafts in the background.
+func (s *Service) Start() {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			if s.leader == nil || s.leader() {
+				if _, err := s.Tick(context.Background(), s.now()); err != nil {
+					utils.LogErrorf("Crafting: Could not finalize crafts: %v", err)
+				}
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background finalizing after the batch in progress.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Tick finalizes the crafts that completed by now, in batches, and returns
+// how many it finalized. Each craft's mint is queued before the craft is
+// marked ready, so a failure in between only queues the same mint again.
+func (s *Service) Tick(ctx context.Context, now time.Time) (int, error) {
+	finalized := 0
+	for {
+		due, err := s.store.Due(ctx, now, s.opts.BatchSize)
+		if err != nil {
+			return finalized, err
+		}
+		for _, craft := range due {
+			if s.jobs != nil {
+				if _, err := s.jobs.Enqueue("craft:"+craft.ID, MintKind, craft); err != nil {
+					return finalized, fmt.Errorf("queue mint of craft %s: %w", craft.ID, err)
+				}
+			}
+			changed, err := s.store.Finish(ctx, craft.ID, now)
+			if err != nil {
+				return finalized, err
+			}
+			if !changed {
+				continue // Finalized by another instance
+			}
+			craft.Status, craft.FinishedAt = StatusReady, now.UTC()
+			finalized++
+			s.finished(craft)
+		}
+		if len(due) < s.opts.BatchSize {
+			return finalized, nil
+		}
+	}
+}
+
+// finished tells the player a craft finished, by mail and, when they are
+// online, on their session.
+func (s *Service) finished(craft Craft) {
+	utils.LogInfof("Crafting: %s of %s finished (%s).", craft.RecipeID, craft.PlayerID, craft.ID)
+	s.mail.Send(mail.Message{
+		To:      craft.PlayerID,
+		Kind:    "crafting.completed",
+		Subject: fmt.Sprintf("Your %s is ready", craft.Name),
+		Body:    fmt.Sprintf("Your %s finished crafting. The item will arrive in your wallet shortly.", craft.Name),
+		Ref:     craft.ID,
+	})
+	s.mu.Lock()
+	notify := s.notifiers[craft.PlayerID]
+	s.mu.Unlock()
+	if notify != nil {
+		notify(&Notice{Craft: craft})
+	}
+}
+
+// mintCraft handles MintKind. A craft no longer stored was minted already.
+func (s *Service) mintCraft(ctx context.Context, payload json.RawMessage, mint Minter) error {
+	var queued Craft
+	if err := json.Unmarshal(payload, &queued); err != nil {
+		return err
+	}
+	craft, ok, err := s.store.Get(ctx, queued.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	digest, err := mint(ctx, craft)
+	if err != nil {
+		return err
+	}
+	utils.LogInfof("Crafting: Minted %s for %s (%s) in %s.", craft.ItemType, craft.PlayerID, craft.ID, digest)
+	// The mint is done; failing here would mint the item again.
+	if err := s.store.Delete(ctx, craft.ID); err != nil {
+		utils.LogErrorf("Crafting: Minted craft %s but could not remove it: %v", craft.ID, err)
+	}
+	return nil
+}
+
+// pay takes the recipe's coins and materials from playerID's wallet.
+func (s *Service) pay(playerID string, recipe Recipe) error {
+	if recipe.Coins == 0 && len(recipe.Materials) == 0 {
+		return nil
+	}
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err != nil {
+		return err
+	}
+	if wallet.Coins < recipe.Coins {
+		return ErrInsufficientFunds
+	}
+	for itemID, quantity := range recipe.Materials {
+		if wallet.Inventory[itemID] < quantity {
+			return fmt.Errorf("%w: %d x %s", ErrMissingMaterials, quantity, itemID)
+		}
+	}
+	wallet.Coins -= recipe.Coins
+	for itemID, quantity := range recipe.Materials {
+		if wallet.Inventory[itemID] -= quantity; wallet.Inventory[itemID] == 0 {
+			delete(wallet.Inventory, itemID)
+		}
+	}
+	return s.wallets.SaveWallet(playerID, wallet)
+}
+
+// refund gives back what pay took.
+func (s *Service) refund(playerID string, recipe Recipe) error {
+	if recipe.Coins == 0 && len(recipe.Materials) == 0 {
+		return nil
+	}
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err != nil {
+		return err
+	}
+	wallet.Coins += recipe.Coins
+	if wallet.Inventory == nil {
+		wallet.Inventory = make(map[string]int)
+	}
+	for itemID, quantity := range recipe.Materials {
+		wallet.Inventory[itemID] += quantity
+	}
+	return s.wallets.SaveWallet(playerID, wallet)
+}
+
+func newCraftID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "craft-" + hex.EncodeToString(b)
+}
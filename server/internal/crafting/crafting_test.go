@@ -0,0 +1,130 @@
+package crafting
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+)
+
+var testRecipes = []Recipe{
+	{ID: "sword", Name: "Sword", ItemType: "iron_sword", DurationSeconds: 60, Coins: 20, Materials: map[string]int{"iron_ore": 2}},
+	{ID: "potion", Name: "Potion", ItemType: "potion", DurationSeconds: 30},
+}
+
+func newTestService(t *testing.T, wallets shop.WalletStore) (*Service, *MemoryStore, *time.Time) {
+	t.Helper()
+	store := NewMemoryStore()
+	s := NewService(testRecipes, store, wallets, Options{MaxQueued: 2})
+	now := time.Unix(1000, 0).UTC()
+	s.now = func() time.Time { return now }
+	return s, store, &now
+}
+
+func TestCraftsQueueBehindEachOtherAndCost(t *testing.T) {
+	ctx := context.Background()
+	wallets := &shop.MemoryWallets{StartingCoins: 30}
+	wallets.SaveWallet("alice", shop.Wallet{Coins: 30, Inventory: map[string]int{"iron_ore": 3}})
+	s, _, now := newTestService(t, wallets)
+
+	first, err := s.Queue(ctx, "alice", "sword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.StartsAt.Equal(*now) || !first.CompletesAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("first craft runs %s to %s", first.StartsAt, first.CompletesAt)
+	}
+	second, err := s.Queue(ctx, "alice", "potion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.StartsAt.Equal(first.CompletesAt) || second.Stage(*now) != "queued" || first.Stage(*now) != "crafting" {
+		t.Errorf("second craft starts %s, stages %s and %s", second.StartsAt, first.Stage(*now), second.Stage(*now))
+	}
+	if _, err := s.Queue(ctx, "alice", "potion"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("third craft: %v", err)
+	}
+	if _, err := s.Queue(ctx, "alice", "shield"); !errors.Is(err, ErrUnknownRecipe) {
+		t.Errorf("unknown recipe: %v", err)
+	}
+	wallet, _ := wallets.LoadWallet("alice")
+	if wallet.Coins != 10 || wallet.Inventory["iron_ore"] != 1 {
+		t.Errorf("wallet after crafting = %+v", wallet)
+	}
+
+	if _, err := s.Queue(ctx, "bob", "sword"); !errors.Is(err, ErrMissingMaterials) {
+		t.Errorf("bob without ore: %v", err)
+	}
+	wallets.SaveWallet("carol", shop.Wallet{Coins: 5, Inventory: map[string]int{"iron_ore": 2}})
+	if _, err := s.Queue(ctx, "carol", "sword"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("carol without coins: %v", err)
+	}
+	if crafts, _ := s.Crafts(ctx, "carol"); len(crafts) != 0 {
+		t.Errorf("refused craft was stored: %+v", crafts)
+	}
+}
+
+func TestFinishedCraftsAreMailedAndMintedOnce(t *testing.T) {
+	ctx := context.Background()
+	s, store, now := newTestService(t, &shop.MemoryWallets{})
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := outbox.NewMemoryStore()
+	minted := 0
+	mint := func(ctx context.Context, craft Craft) (string, error) {
+		minted++
+		return "0xdigest", nil
+	}
+	s.UseMail(mailService)
+	s.UseMinting(outbox.New(jobs, outbox.Options{}), mint)
+	var notices []*Notice
+	s.Connect("alice", func(note interface{}) { notices = append(notices, note.(*Notice)) })
+
+	craft, err := s.Queue(ctx, "alice", "potion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The player goes offline; the craft finishes anyway.
+	s.Disconnect("alice")
+	if n, _ := s.Tick(ctx, now.Add(29*time.Second)); n != 0 {
+		t.Errorf("finalized %d crafts early", n)
+	}
+	if n, err := s.Tick(ctx, now.Add(time.Minute)); n != 1 || err != nil {
+		t.Fatalf("Tick = %d, %v", n, err)
+	}
+	if n, _ := s.Tick(ctx, now.Add(2*time.Minute)); n != 0 {
+		t.Errorf("finalized the craft again")
+	}
+	if len(notices) != 0 {
+		t.Errorf("offline player was notified: %+v", notices)
+	}
+	if inbox, unread := mailService.Inbox("alice"); unread != 1 || inbox[0].Kind != "crafting.completed" || inbox[0].Ref != craft.ID {
+		t.Errorf("inbox = %+v", inbox)
+	}
+	if stored, _, _ := store.Get(ctx, craft.ID); stored.Status != StatusReady {
+		t.Errorf("stored craft = %+v", stored)
+	}
+
+	messages, _ := jobs.List()
+	if len(messages) != 1 || messages[0].Kind != MintKind {
+		t.Fatalf("outbox = %+v", messages)
+	}
+	// A redelivered mint does nothing once the craft was minted.
+	for i := 0; i < 2; i++ {
+		if err := s.mintCraft(ctx, messages[0].Payload, mint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if minted != 1 {
+		t.Errorf("minted %d times", minted)
+	}
+	if crafts, _ := s.Crafts(ctx, "alice"); len(crafts) != 0 {
+		t.Errorf("minted craft is still queued: %+v", crafts)
+	}
+}
@@ -0,0 +1,128 @@
+package crafting
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS crafts (
+	id           TEXT PRIMARY KEY,
+	player_id    TEXT NOT NULL,
+	recipe_id    TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	item_type    TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	queued_at    TIMESTAMPTZ NOT NULL,
+	starts_at    TIMESTAMPTZ NOT NULL,
+	completes_at TIMESTAMPTZ NOT NULL,
+	finished_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS crafts_player_id ON crafts (player_id, completes_at);
+CREATE INDEX IF NOT EXISTS crafts_due ON crafts (completes_at) WHERE status = 'queued';
+`
+
+const craftColumns = `id, player_id, recipe_id, name, item_type, status, queued_at, starts_at, completes_at, finished_at`
+
+// PostgresStore keeps crafts in the crafts table.
+type PostgresStore struct {
+	DB      *sql.DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the crafts table and its indexes if they are missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create crafts: %w", err)
+	}
+	return nil
+}
+
+// Add implements Store.
+func (s *PostgresStore) Add(ctx context.Context, craft Craft) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO crafts (`+craftColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULL)`,
+		craft.ID, craft.PlayerID, craft.RecipeID, craft.Name, craft.ItemType, string(craft.Status), craft.QueuedAt, craft.StartsAt, craft.CompletesAt)
+	if err != nil {
+		return fmt.Errorf("insert craft %s: %w", craft.ID, err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *PostgresStore) Get(ctx context.Context, id string) (Craft, bool, error) {
+	crafts, err := s.query(ctx, `SELECT `+craftColumns+` FROM crafts WHERE id = $1`, id)
+	if err != nil || len(crafts) == 0 {
+		return Craft{}, false, err
+	}
+	return crafts[0], true, nil
+}
+
+// ForPlayer implements Store.
+func (s *PostgresStore) ForPlayer(ctx context.Context, playerID string) ([]Craft, error) {
+	return s.query(ctx, `SELECT `+craftColumns+` FROM crafts WHERE player_id = $1 ORDER BY completes_at, id`, playerID)
+}
+
+// Due implements Store.
+func (s *PostgresStore) Due(ctx context.Context, now time.Time, limit int) ([]Craft, error) {
+	return s.query(ctx,
+		`SELECT `+craftColumns+` FROM crafts WHERE status = 'queued' AND completes_at <= $1
+		ORDER BY completes_at, id LIMIT $2`, now, limit)
+}
+
+// Finish implements Store.
+func (s *PostgresStore) Finish(ctx context.Context, id string, at time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.DB.ExecContext(ctx,
+		`UPDATE crafts SET status = 'ready', finished_at = $2 WHERE id = $1 AND status = 'queued'`, id, at)
+	if err != nil {
+		return false, fmt.Errorf("finish craft %s: %w", id, err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Delete implements Store.
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM crafts WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete craft %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) query(ctx context.Context, query string, args ...interface{}) ([]Craft, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query crafts: %w", err)
+	}
+	defer rows.Close()
+	var crafts []Craft
+	for rows.Next() {
+		var craft Craft
+		var status string
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&craft.ID, &craft.PlayerID, &craft.RecipeID, &craft.Name, &craft.ItemType, &status,
+			&craft.QueuedAt, &craft.StartsAt, &craft.CompletesAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("scan craft: %w", err)
+		}
+		craft.Status, craft.FinishedAt = Status(status), finishedAt.Time
+		crafts = append(crafts, craft)
+	}
+	return crafts, rows.Err()
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
@@ -0,0 +1,113 @@
+package crafting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists crafts.
+type Store interface {
+	// Add stores a new craft.
+	Add(ctx context.Context, craft Craft) error
+	// Get returns the craft with id, if it is stored.
+	Get(ctx context.Context, id string) (Craft, bool, error)
+	// ForPlayer returns the player's crafts by completion time.
+	ForPlayer(ctx context.Context, playerID string) ([]Craft, error)
+	// Due returns up to limit queued crafts completed by now, oldest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]Craft, error)
+	// Finish marks a queued craft ready; it reports whether the craft was queued.
+	Finish(ctx context.Context, id string, at time.Time) (bool, error)
+	// Delete removes a craft.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore keeps crafts in memory. It is used when there is no database;
+// queued crafts are lost on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	crafts map[string]Craft
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{crafts: make(map[string]Craft)}
+}
+
+// Add implements Store.
+func (m *MemoryStore) Add(ctx context.Context, craft Craft) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.crafts[craft.ID] = craft
+	return nil
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(ctx context.Context, id string) (Craft, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	craft, ok := m.crafts[id]
+	return craft, ok, nil
+}
+
+// ForPlayer implements Store.
+func (m *MemoryStore) ForPlayer(ctx context.Context, playerID string) ([]Craft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var crafts []Craft
+	for _, craft := range m.crafts {
+		if craft.PlayerID == playerID {
+			crafts = append(crafts, craft)
+		}
+	}
+	sortByCompletion(crafts)
+	return crafts, nil
+}
+
+// Due implements Store.
+func (m *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]Craft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []Craft
+	for _, craft := range m.crafts {
+		if craft.Status == StatusQueued && !craft.CompletesAt.After(now) {
+			due = append(due, craft)
+		}
+	}
+	sortByCompletion(due)
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Finish implements Store.
+func (m *MemoryStore) Finish(ctx context.Context, id string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	craft, ok := m.crafts[id]
+	if !ok || craft.Status != StatusQueued {
+		return false, nil
+	}
+	craft.Status, craft.FinishedAt = StatusReady, at.UTC()
+	m.crafts[id] = craft
+	return true, nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.crafts, id)
+	return nil
+}
+
+func sortByCompletion(crafts []Craft) {
+	sort.Slice(crafts, func(i, j int) bool {
+		if !crafts[i].CompletesAt.Equal(crafts[j].CompletesAt) {
+			return crafts[i].CompletesAt.Before(crafts[j].CompletesAt)
+		}
+		return crafts[i].ID < crafts[j].ID
+	})
+}
@@ -0,0 +1,221 @@
+// Package deeplink mints signed deep links into the game for cross-promotion.
+// A link names a destination (a room, a marketplace listing or a guild page)
+// and who promotes it; partner sites embed its token, and a client that was
+// opened with one presents it after auth to be routed there. Tokens are
+// signed with a server secret and expire, so they need no storage; the
+// service only counts who opened which link, for attribution.
+//
+// A token reads "<claims>.<signature>", both base64url encoded, with the
+// signature an HMAC-SHA256 of the encoded claims.
+package deeplink
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultTTL    = 24 * time.Hour
+	DefaultMaxTTL = 7 * 24 * time.Hour
+)
+
+// MinSecretLength is the shortest signing secret NewService accepts.
+const MinSecretLength = 32
+
+// maxTokenLength bounds the tokens Verify looks at.
+const maxTokenLength = 1024
+
+var (
+	ErrInvalid = errors.New("invalid deep link")
+	ErrExpired = errors.New("deep link expired")
+)
+
+// Kind is what a link leads to.
+type Kind string
+
+const (
+	KindRoom    Kind = "room"    // Target is a room ID; the player joins it
+	KindListing Kind = "listing" // Target is a marketplace listing ID
+	KindGuild   Kind = "guild"   // Target is a guild ID
+)
+
+// Link is what a token says.
+type Link struct {
+	ID        string `json:"id"`                 // Names the link in attribution reports
+	Kind      Kind   `json:"kind"`               // Where it leads
+	Target    string `json:"target"`             // The room, listing or guild ID
+	Source    string `json:"source"`             // Who embeds it, e.g. "partner-blog"
+	Campaign  string `json:"campaign,omitempty"` // What promotion it belongs to
+	ExpiresAt int64  `json:"exp"`                // Unix seconds
+}
+
+// Expires returns when the link stops working.
+func (l Link) Expires() time.Time {
+	return time.Unix(l.ExpiresAt, 0).UTC()
+}
+
+// Attribution counts the opens of one link.
+type Attribution struct {
+	Link         Link      `json:"link"`
+	Opens        int       `json:"opens"`   // Every time a player presented the token
+	Players      int       `json:"players"` // Distinct players among them
+	LastOpenedAt time.Time `json:"lastOpenedAt"`
+}
+
+type attribution struct {
+	Attribution
+	players map[string]bool
+}
+
+// Options configures a Service.
+type Options struct {
+	TTL    time.Duration // Lifetime of links minted without one
+	MaxTTL time.Duration // Longest lifetime a link may be minted with
+}
+
+// Service mints and checks deep links. It is safe for concurrent use.
+type Service struct {
+	secret []byte
+	opts   Options
+	now    func() time.Time
+
+	mu     sync.Mutex
+	opened map[string]*attribution // By link ID
+}
+
+// NewService creates a Service that signs links with secret.
+func NewService(secret []byte, opts Options) (*Service, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("the deep link secret must be at least %d bytes", MinSecretLength)
+	}
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = DefaultMaxTTL
+	}
+	if opts.TTL <= 0 || opts.TTL > opts.MaxTTL {
+		opts.TTL = min(DefaultTTL, opts.MaxTTL)
+	}
+	return &Service{secret: secret, opts: opts, now: time.Now, opened: make(map[string]*attribution)}, nil
+}
+
+// Mint signs a link to draft's kind and target for draft's source and
+// campaign. It expires after ttl, or the default lifetime if ttl is zero.
+func (s *Service) Mint(draft Link, ttl time.Duration) (token string, link Link, err error) {
+	if ttl == 0 {
+		ttl = s.opts.TTL
+	}
+	switch {
+	case draft.Kind != KindRoom && draft.Kind != KindListing && draft.Kind != KindGuild:
+		return "", Link{}, fmt.Errorf("%w: kind must be %q, %q or %q", ErrInvalid, KindRoom, KindListing, KindGuild)
+	case draft.Target == "" || draft.Source == "":
+		return "", Link{}, fmt.Errorf("%w: it needs a target and a source", ErrInvalid)
+	case ttl < 0 || ttl > s.opts.MaxTTL:
+		return "", Link{}, fmt.Errorf("%w: it may live at most %s", ErrInvalid, s.opts.MaxTTL)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", Link{}, err
+	}
+	link = Link{
+		ID:        hex.EncodeToString(id),
+		Kind:      draft.Kind,
+		Target:    draft.Target,
+		Source:    draft.Source,
+		Campaign:  draft.Campaign,
+		ExpiresAt: s.now().Add(ttl).Unix(),
+	}
+	claims, err := json.Marshal(link)
+	if err != nil {
+		return "", Link{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(claims)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)), link, nil
+}
+
+// Verify returns the link a token says, if the server signed it and it has
+// not expired.
+func (s *Service) Verify(token string) (Link, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || len(token) > maxTokenLength {
+		return Link{}, ErrInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(encoded)) {
+		return Link{}, ErrInvalid
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Link{}, ErrInvalid
+	}
+	var link Link
+	if err := json.Unmarshal(claims, &link); err != nil || link.ID == "" || link.Target == "" {
+		return Link{}, ErrInvalid
+	}
+	if !s.now().Before(link.Expires()) {
+		return Link{}, ErrExpired
+	}
+	return link, nil
+}
+
+// Open verifies a token presented by playerID and attributes the open to its
+// link.
+func (s *Service) Open(playerID, token string) (Link, error) {
+	link, err := s.Verify(token)
+	if err != nil {
+		return Link{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.opened[link.ID]
+	if a == nil {
+		a = &attribution{Attribution: Attribution{Link: link}, players: make(map[string]bool)}
+		s.opened[link.ID] = a
+	}
+	a.Opens++
+	if !a.players[playerID] {
+		a.players[playerID] = true
+		a.Players++
+	}
+	a.LastOpenedAt = s.now().UTC()
+	return link, nil
+}
+
+// Attributions returns the opens of every link opened since the server
+// started, most opened first. Links expired for longer than their maximum
+// lifetime are forgotten.
+func (s *Service) Attributions() []Attribution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forgetBefore := s.now().Add(-s.opts.MaxTTL)
+	list := make([]Attribution, 0, len(s.opened))
+	for id, a := range s.opened {
+		if a.Link.Expires().Before(forgetBefore) {
+			delete(s.opened, id)
+			continue
+		}
+		list = append(list, a.Attribution)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Opens != list[j].Opens {
+			return list[i].Opens > list[j].Opens
+		}
+		return list[i].Link.ID < list[j].Link.ID
+	})
+	return list
+}
+
+func (s *Service) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
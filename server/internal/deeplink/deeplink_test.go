@@ -0,0 +1,85 @@
+package deeplink
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T, now *time.Time) *Service {
+	t.Helper()
+	s, err := NewService([]byte(strings.Repeat("k", MinSecretLength)), Options{TTL: time.Hour, MaxTTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestLinksAreSignedAndExpire(t *testing.T) {
+	if _, err := NewService([]byte("short"), Options{}); err == nil {
+		t.Fatal("a short secret was accepted")
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(t, &now)
+
+	if _, _, err := s.Mint(Link{Kind: "shop", Target: "general", Source: "blog"}, 0); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Mint with an unknown kind: %v", err)
+	}
+	if _, _, err := s.Mint(Link{Kind: KindGuild, Target: "wolves", Source: "blog"}, 48*time.Hour); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Mint past the maximum lifetime: %v", err)
+	}
+	token, minted, err := s.Mint(Link{Kind: KindRoom, Target: "room-1", Source: "blog", Campaign: "spring"}, 0)
+	if err != nil || minted.Expires() != now.Add(time.Hour) {
+		t.Fatalf("Mint = %+v, %v", minted, err)
+	}
+	if link, err := s.Verify(token); err != nil || link != minted {
+		t.Fatalf("Verify = %+v, %v", link, err)
+	}
+
+	// Another server's links, and tampered ones, are refused.
+	other, err := NewService([]byte(strings.Repeat("x", MinSecretLength)), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Verify(token); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Verify with another secret: %v", err)
+	}
+	forged, _, _ := other.Mint(Link{Kind: KindRoom, Target: "room-2", Source: "blog"}, 0)
+	claims, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(token, ".")
+	if _, err := s.Verify(claims + "." + signature); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Verify with swapped claims: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.Verify(token); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Verify after expiry: %v", err)
+	}
+}
+
+func TestOpensAreAttributedToTheirLink(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(t, &now)
+	room, _, _ := s.Mint(Link{Kind: KindRoom, Target: "room-1", Source: "blog"}, 0)
+	guild, guildLink, _ := s.Mint(Link{Kind: KindGuild, Target: "wolves", Source: "stream", Campaign: "recruit"}, 0)
+
+	for _, open := range []struct{ player, token string }{{"ann", guild}, {"bob", guild}, {"ann", guild}, {"ann", room}} {
+		if _, err := s.Open(open.player, open.token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Open("cat", "not-a-link"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Open with garbage: %v", err)
+	}
+	got := s.Attributions()
+	if len(got) != 2 || got[0].Link != guildLink || got[0].Opens != 3 || got[0].Players != 2 || got[1].Opens != 1 {
+		t.Fatalf("Attributions = %+v", got)
+	}
+
+	now = now.Add(time.Hour + 24*time.Hour + time.Second)
+	if got := s.Attributions(); len(got) != 0 {
+		t.Fatalf("Attributions long after expiry = %+v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package deeplink
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is logged. When baseURL is set, minted links come with it
+// and the token as its "link" query parameter, ready to embed.
+//
+//	POST /admin/deeplinks               {"kind": "room", "target": "...", "source": "...", "campaign": "...", "ttlSeconds": 3600} mints a link
+//	GET  /admin/deeplinks/attribution   opens per link
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken, baseURL string) {
+	mux.HandleFunc("/admin/deeplinks", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		var req struct {
+			Kind       Kind   `json:"kind"`
+			Target     string `json:"target"`
+			Source     string `json:"source"`
+			Campaign   string `json:"campaign"`
+			TTLSeconds int    `json:"ttlSeconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+			return
+		}
+		draft := Link{Kind: req.Kind, Target: req.Target, Source: req.Source, Campaign: req.Campaign}
+		token, link, err := s.Mint(draft, time.Duration(req.TTLSeconds)*time.Second)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrInvalid) {
+				status = http.StatusBadRequest
+			}
+			adminhttp.WriteError(w, status, err)
+			return
+		}
+		utils.LogInfof("Deep links: %s minted %s to %s %s for %s (campaign %q), expiring %s.", operator, link.ID, link.Kind, link.Target, link.Source, link.Campaign, link.Expires().Format(time.RFC3339))
+		resp := struct {
+			Token string `json:"token"`
+			URL   string `json:"url,omitempty"`
+			Link  Link   `json:"link"`
+		}{Token: token, Link: link}
+		if baseURL != "" {
+			resp.URL = withToken(baseURL, token)
+		}
+		adminhttp.WriteJSON(w, http.StatusCreated, resp)
+	}))
+	mux.HandleFunc("/admin/deeplinks/attribution", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.Attributions())
+	}))
+}
+
+func withToken(baseURL, token string) string {
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return baseURL + separator + "link=" + url.QueryEscape(token)
+}
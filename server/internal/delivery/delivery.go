@@ -0,0 +1,181 @@
+// Package delivery keeps the replay buffers of reliable delivery: the
+// important messages a session sent, numbered per player, until the client
+// acknowledges them. Buffers outlive the session, so a client that reconnects
+// within the retention window gets what it missed. See pkg/protocol's ACK.
+package delivery
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often expired buffers are swept from a Store.
+const pruneInterval = time.Minute
+
+// Defaults for NewStore arguments left at zero.
+const (
+	DefaultCapacity  = 256
+	DefaultRetention = 5 * time.Minute
+)
+
+// Message is a sequenced message waiting for its acknowledgment.
+type Message struct {
+	Seq     uint64
+	Type    string
+	Payload json.RawMessage
+}
+
+// Buffer holds one player's unacknowledged messages. It is safe for
+// concurrent use.
+type Buffer struct {
+	capacity int
+
+	mu       sync.Mutex
+	seq      uint64    // Seq of the last message appended
+	messages []Message // Unacknowledged, oldest first
+	dropped  uint64    // Highest seq dropped unacknowledged because the buffer was full
+}
+
+// Append numbers a message and keeps it until acknowledged. When the buffer is
+// full, the oldest message is dropped and a later replay reports a gap.
+func (b *Buffer) Append(msgType string, payload json.RawMessage) Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	msg := Message{Seq: b.seq, Type: msgType, Payload: payload}
+	if len(b.messages) >= b.capacity {
+		b.dropped = b.messages[0].Seq
+		b.messages = b.messages[1:]
+	}
+	b.messages = append(b.messages, msg)
+	return msg
+}
+
+// Ack drops the messages up to and including seq.
+func (b *Buffer) Ack(seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := 0
+	for i < len(b.messages) && b.messages[i].Seq <= seq {
+		i++
+	}
+	b.messages = append(b.messages[:0:0], b.messages[i:]...)
+}
+
+// Since acknowledges seq and returns the messages after it. complete is false
+// if some of them were dropped.
+func (b *Buffer) Since(seq uint64) (messages []Message, complete bool) {
+	b.Ack(seq)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...), b.dropped <= seq
+}
+
+// Seq returns the seq of the last message appended.
+func (b *Buffer) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Pending returns the number of unacknowledged messages.
+func (b *Buffer) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.messages)
+}
+
+// Store keeps a Buffer per player while a session uses it and for a retention
+// window after the last session detaches. It is safe for concurrent use. A nil
+// Store offers no reliable delivery.
+type Store struct {
+	capacity  int
+	retention time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	buffers   map[string]*held
+	lastPrune time.Time
+}
+
+type held struct {
+	buffer     *Buffer
+	sessions   int       // Attached sessions
+	detachedAt time.Time // When sessions dropped to zero
+}
+
+// NewStore creates a Store whose buffers hold capacity messages and are kept
+// for retention after their player's last session ends.
+func NewStore(capacity int, retention time.Duration) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Store{capacity: capacity, retention: retention, now: time.Now, buffers: make(map[string]*held)}
+}
+
+// Attach returns playerID's buffer for a session, and whether it was kept from
+// an earlier session. A buffer whose retention ran out starts over from seq 1.
+func (s *Store) Attach(playerID string) (*Buffer, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	h, resumed := s.buffers[playerID]
+	if resumed && s.expiredLocked(h) {
+		resumed = false
+	}
+	if !resumed {
+		h = &held{buffer: &Buffer{capacity: s.capacity}}
+		s.buffers[playerID] = h
+	}
+	h.sessions++
+	return h.buffer, resumed
+}
+
+// Detach ends a session's use of playerID's buffer. The buffer is kept for the
+// retention window once no session uses it.
+func (s *Store) Detach(playerID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.buffers[playerID]
+	if !ok {
+		return
+	}
+	if h.sessions--; h.sessions <= 0 {
+		h.sessions, h.detachedAt = 0, s.now()
+	}
+	s.pruneLocked()
+}
+
+// Len returns the number of buffers kept.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buffers)
+}
+
+func (s *Store) expiredLocked(h *held) bool {
+	return h.sessions == 0 && s.now().Sub(h.detachedAt) >= s.retention
+}
+
+func (s *Store) pruneLocked() {
+	now := s.now()
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+	for playerID, h := range s.buffers {
+		if s.expiredLocked(h) {
+			delete(s.buffers, playerID)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func seqs(messages []Message) string {
+	var out []uint64
+	for _, m := range messages {
+		out = append(out, m.Seq)
+	}
+	return fmt.Sprint(out)
+}
+
+func TestBufferReplaysWhatWasNotAcknowledged(t *testing.T) {
+	b := &Buffer{capacity: 10}
+	for i := 0; i < 4; i++ {
+		b.Append("TRADE_UPDATE", []byte(`{}`))
+	}
+	b.Ack(2)
+	if b.Pending() != 2 {
+		t.Fatalf("%d pending after acking 2 of 4", b.Pending())
+	}
+	messages, complete := b.Since(3)
+	if got := seqs(messages); got != "[4]" || !complete {
+		t.Errorf("Since(3) = %s, %t; want [4], complete", got, complete)
+	}
+	// An older lastSeq than the acks replays what is left, without a gap.
+	if messages, complete := b.Since(1); seqs(messages) != "[4]" || !complete {
+		t.Errorf("Since(1) = %s, %t", seqs(messages), complete)
+	}
+}
+
+func TestFullBufferReportsAGap(t *testing.T) {
+	b := &Buffer{capacity: 2}
+	for i := 0; i < 5; i++ {
+		b.Append("TRADE_UPDATE", nil)
+	}
+	messages, complete := b.Since(1)
+	if seqs(messages) != "[4 5]" || complete {
+		t.Errorf("Since(1) = %s, %t; want [4 5] with a gap", seqs(messages), complete)
+	}
+	if _, complete := b.Since(3); !complete {
+		t.Error("a client that has seen the dropped messages has no gap")
+	}
+}
+
+func TestStoreKeepsBuffersForTheRetentionWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(0, 5*time.Minute)
+	s.now = func() time.Time { return now }
+
+	b, resumed := s.Attach("alice")
+	if resumed {
+		t.Fatal("a new player resumed a buffer")
+	}
+	b.Append("TRADE_UPDATE", nil)
+	s.Detach("alice")
+
+	now = now.Add(4 * time.Minute)
+	if again, resumed := s.Attach("alice"); !resumed || again != b {
+		t.Fatal("reconnecting within the window did not resume the buffer")
+	}
+	// A second session keeps the buffer alive after the first leaves.
+	s.Attach("alice")
+	s.Detach("alice")
+	now = now.Add(time.Hour)
+	if _, resumed := s.Attach("alice"); !resumed {
+		t.Fatal("the buffer expired while a session still used it")
+	}
+	s.Detach("alice")
+	s.Detach("alice")
+
+	now = now.Add(5 * time.Minute)
+	fresh, resumed := s.Attach("alice")
+	if resumed || fresh == b || fresh.Seq() != 0 {
+		t.Fatal("an expired buffer was resumed")
+	}
+	s.Detach("alice")
+
+	s.Attach("bob")
+	now = now.Add(10 * time.Minute)
+	s.Detach("bob")
+	now = now.Add(pruneInterval)
+	s.Attach("carol")
+	if s.Len() != 2 {
+		t.Errorf("%d buffers kept, want bob's and carol's", s.Len())
+	}
+}
+
+func TestNilStoreOffersNoBuffer(t *testing.T) {
+	var s *Store
+	if b, _ := s.Attach("alice"); b != nil {
+		t.Error("a nil store returned a buffer")
+	}
+	s.Detach("alice")
+}
@@ -0,0 +1,560 @@
+// Package diplomacy keeps the relations between guilds. Guild leaders propose
+// alliances, which the other guild's leader accepts or declines, and declare
+// rivalries on their own. An alliance is a group of guilds: a guild is in at
+// most one, and a guild that accepts the proposal of an alliance member joins
+// that alliance. Allied guilds share the alliance chat channel and the guild
+// events they open to the alliance, and territory treats them as friendly.
+package diplomacy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/model"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultMaxGuilds   = 5
+	DefaultProposalTTL = 72 * time.Hour
+)
+
+const maxAllianceName = 32
+
+var (
+	ErrNoGuild       = errors.New("you are not in a guild")
+	ErrNotLeader     = errors.New("only the guild leader can conduct diplomacy")
+	ErrUnknownGuild  = errors.New("no such guild")
+	ErrSelf          = errors.New("a guild cannot do that with itself")
+	ErrInvalid       = errors.New("invalid alliance")
+	ErrAllied        = errors.New("the guilds are allied")
+	ErrInAlliance    = errors.New("that guild is already in an alliance")
+	ErrRivals        = errors.New("the guilds are rivals")
+	ErrFull          = errors.New("the alliance is full")
+	ErrNoProposal    = errors.New("no such alliance proposal")
+	ErrNotInAlliance = errors.New("your guild is not in an alliance")
+	ErrNoRivalry     = errors.New("your guild has not declared that rivalry")
+)
+
+// Alliance is a group of allied guilds.
+type Alliance struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	GuildIDs []string  `json:"guildIds"` // In the order they joined
+	FormedAt time.Time `json:"formedAt"`
+}
+
+// Has reports whether guildID is in the alliance.
+func (a Alliance) Has(guildID string) bool {
+	for _, id := range a.GuildIDs {
+		if id == guildID {
+			return true
+		}
+	}
+	return false
+}
+
+// Proposal is an open offer of alliance from one guild to another.
+type Proposal struct {
+	FromGuildID string    `json:"fromGuildId"`
+	ToGuildID   string    `json:"toGuildId"`
+	ProposedBy  string    `json:"proposedBy"`
+	Name        string    `json:"name,omitempty"` // Name of the alliance it forms, if FromGuildID is in none yet
+	ProposedAt  time.Time `json:"proposedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Rivalry is a guild's declared hostility towards another.
+type Rivalry struct {
+	GuildID      string    `json:"guildId"` // Who declared it
+	RivalGuildID string    `json:"rivalGuildId"`
+	DeclaredBy   string    `json:"declaredBy"`
+	DeclaredAt   time.Time `json:"declaredAt"`
+}
+
+// Standing is a guild's diplomacy. Proposals are only shown to the guild's
+// own members.
+type Standing struct {
+	GuildID  string
+	Alliance *Alliance // Nil if the guild is in none
+	Rivals   []string  // Guilds it declared rivals, sorted
+	RivalOf  []string  // Guilds that declared it their rival, sorted
+	Incoming []Proposal
+	Outgoing []Proposal
+}
+
+// Options configures a Service.
+type Options struct {
+	MaxGuilds   int           // Most guilds in one alliance
+	ProposalTTL time.Duration // How long a proposal stays open
+}
+
+// Service keeps the relations between the guilds of a roster. It is safe for
+// concurrent use. A nil *Service knows no alliances.
+type Service struct {
+	store    Store
+	roster   *guilds.Roster
+	opts     Options
+	mail     *mail.Service         // Tells leaders about proposals and rivalries; nil sends none
+	onChange func(guilds []string) // Called with the guilds whose alliance changed
+	now      func() time.Time
+
+	mu    sync.Mutex
+	state State
+}
+
+// NewService creates a Service for the guilds of roster and loads its state.
+func NewService(store Store, roster *guilds.Roster, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load guild diplomacy: %w", err)
+	}
+	if opts.MaxGuilds < 2 {
+		opts.MaxGuilds = DefaultMaxGuilds
+	}
+	if opts.ProposalTTL <= 0 {
+		opts.ProposalTTL = DefaultProposalTTL
+	}
+	return &Service{store: store, roster: roster, opts: opts, now: time.Now, state: state}, nil
+}
+
+// UseMail mails guild leaders the proposals they receive, the answers to
+// theirs and the rivalries declared on their guild.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// OnChange has fn called, outside the service's lock, with the guilds that
+// joined or left an alliance, so that their online members can be moved to
+// the right alliance channel.
+func (s *Service) OnChange(fn func(guildIDs []string)) {
+	s.onChange = fn
+}
+
+// Propose offers an alliance from playerID's guild, which playerID must lead,
+// to guildID. If the proposing guild is in an alliance, guildID is invited
+// into it; otherwise the two form a new alliance called name.
+func (s *Service) Propose(playerID, guildID, name string) (Standing, error) {
+	from, err := s.leading(playerID)
+	if err != nil {
+		return Standing{}, err
+	}
+	to, err := s.other(from, guildID)
+	if err != nil {
+		return Standing{}, err
+	}
+	name = strings.TrimSpace(name)
+	if len(name) > maxAllianceName {
+		return Standing{}, fmt.Errorf("%w: a name has at most %d characters", ErrInvalid, maxAllianceName)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	s.prune(now)
+	if err := s.mayAlly(from.ID, to.ID); err != nil {
+		s.mu.Unlock()
+		return Standing{}, err
+	}
+	if alliance := s.allianceOf(from.ID); alliance != nil {
+		name = alliance.Name
+	} else if name == "" {
+		name = fmt.Sprintf("%s & %s", guildName(from), guildName(to))
+	}
+	s.state.Proposals = without(s.state.Proposals, from.ID, to.ID)
+	proposal := Proposal{
+		FromGuildID: from.ID,
+		ToGuildID:   to.ID,
+		ProposedBy:  playerID,
+		Name:        name,
+		ProposedAt:  now,
+		ExpiresAt:   now.Add(s.opts.ProposalTTL),
+	}
+	s.state.Proposals = append(s.state.Proposals, proposal)
+	s.save()
+	standing := s.standing(from.ID, true)
+	s.mu.Unlock()
+
+	utils.LogInfof("Diplomacy: %s proposed alliance %q of guild %s to guild %s.", playerID, name, from.ID, to.ID)
+	s.notify(to, from.ID, "guild.alliance_proposed", fmt.Sprintf("%s proposes an alliance", guildName(from)),
+		fmt.Sprintf("%s offers your guild a place in the alliance %q. The offer stands until %s.", guildName(from), name, proposal.ExpiresAt.Format(time.RFC1123)))
+	return standing, nil
+}
+
+// Respond accepts or declines the proposal guildID made to playerID's guild,
+// which playerID must lead.
+func (s *Service) Respond(playerID, guildID string, accept bool) (Standing, error) {
+	to, err := s.leading(playerID)
+	if err != nil {
+		return Standing{}, err
+	}
+	from, ok := s.roster.Guild(guildID)
+	if !ok {
+		return Standing{}, ErrUnknownGuild
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	s.prune(now)
+	var proposal *Proposal
+	for i := range s.state.Proposals {
+		if p := &s.state.Proposals[i]; p.FromGuildID == from.ID && p.ToGuildID == to.ID {
+			proposal = p
+		}
+	}
+	if proposal == nil {
+		s.mu.Unlock()
+		return Standing{}, ErrNoProposal
+	}
+	name := proposal.Name
+	if accept {
+		if err := s.mayAlly(from.ID, to.ID); err != nil {
+			s.mu.Unlock()
+			return Standing{}, err
+		}
+	}
+	s.state.Proposals = without(s.state.Proposals, from.ID, to.ID)
+	var changed []string
+	if accept {
+		if alliance := s.allianceOf(from.ID); alliance != nil {
+			alliance.GuildIDs = append(alliance.GuildIDs, to.ID)
+			name, changed = alliance.Name, []string{to.ID}
+		} else {
+			s.state.NextID++
+			s.state.Alliances = append(s.state.Alliances, Alliance{
+				ID:       "aln-" + strconv.FormatUint(s.state.NextID, 10),
+				Name:     name,
+				GuildIDs: []string{from.ID, to.ID},
+				FormedAt: now,
+			})
+			changed = []string{from.ID, to.ID}
+		}
+	}
+	s.save()
+	standing := s.standing(to.ID, true)
+	s.mu.Unlock()
+
+	if !accept {
+		utils.LogInfof("Diplomacy: %s declined the alliance proposal of guild %s to guild %s.", playerID, from.ID, to.ID)
+		s.notify(from, to.ID, "guild.alliance_declined", fmt.Sprintf("%s declined your alliance", guildName(to)),
+			fmt.Sprintf("%s declined to join the alliance %q.", guildName(to), name))
+		return standing, nil
+	}
+	utils.LogInfof("Diplomacy: %s accepted the alliance proposal of guild %s. Guild %s is in alliance %s (%s).", playerID, from.ID, to.ID, standing.Alliance.ID, name)
+	s.notify(from, to.ID, "guild.alliance_accepted", fmt.Sprintf("%s joined your alliance", guildName(to)),
+		fmt.Sprintf("%s accepted and joined the alliance %q.", guildName(to), name))
+	s.changed(changed)
+	return standing, nil
+}
+
+// Leave takes playerID's guild, which playerID must lead, out of its
+// alliance. An alliance left with a single guild is dissolved.
+func (s *Service) Leave(playerID string) (Standing, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Standing{}, err
+	}
+	s.mu.Lock()
+	alliance := s.allianceOf(guild.ID)
+	if alliance == nil {
+		s.mu.Unlock()
+		return Standing{}, ErrNotInAlliance
+	}
+	left := *alliance
+	kept := make([]string, 0, len(alliance.GuildIDs)-1)
+	for _, id := range alliance.GuildIDs {
+		if id != guild.ID {
+			kept = append(kept, id)
+		}
+	}
+	alliance.GuildIDs = kept
+	changed := []string{guild.ID}
+	if len(kept) < 2 {
+		changed = append(changed, kept...)
+		alliances := s.state.Alliances[:0]
+		for _, a := range s.state.Alliances {
+			if a.ID != left.ID {
+				alliances = append(alliances, a)
+			}
+		}
+		s.state.Alliances = alliances
+	}
+	s.save()
+	standing := s.standing(guild.ID, true)
+	s.mu.Unlock()
+
+	utils.LogInfof("Diplomacy: %s took guild %s out of alliance %s (%s).", playerID, guild.ID, left.ID, left.Name)
+	if len(changed) > 1 {
+		utils.LogInfof("Diplomacy: Alliance %s (%s) dissolved.", left.ID, left.Name)
+	}
+	s.changed(changed)
+	return standing, nil
+}
+
+// DeclareRivalry makes guildID a rival of playerID's guild, which playerID
+// must lead. Allies must part ways first; open proposals between the two are
+// withdrawn.
+func (s *Service) DeclareRivalry(playerID, guildID string) (Standing, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Standing{}, err
+	}
+	rival, err := s.other(guild, guildID)
+	if err != nil {
+		return Standing{}, err
+	}
+	s.mu.Lock()
+	if alliance := s.allianceOf(guild.ID); alliance != nil && alliance.Has(rival.ID) {
+		s.mu.Unlock()
+		return Standing{}, ErrAllied
+	}
+	for _, r := range s.state.Rivalries {
+		if r.GuildID == guild.ID && r.RivalGuildID == rival.ID {
+			standing := s.standing(guild.ID, true)
+			s.mu.Unlock()
+			return standing, nil
+		}
+	}
+	s.state.Rivalries = append(s.state.Rivalries, Rivalry{GuildID: guild.ID, RivalGuildID: rival.ID, DeclaredBy: playerID, DeclaredAt: s.now()})
+	s.state.Proposals = without(s.state.Proposals, guild.ID, rival.ID)
+	s.state.Proposals = without(s.state.Proposals, rival.ID, guild.ID)
+	s.save()
+	standing := s.standing(guild.ID, true)
+	s.mu.Unlock()
+
+	utils.LogInfof("Diplomacy: %s declared guild %s a rival of guild %s.", playerID, rival.ID, guild.ID)
+	s.notify(rival, guild.ID, "guild.rivalry_declared", fmt.Sprintf("%s declared a rivalry", guildName(guild)),
+		fmt.Sprintf("%s declared your guild its rival.", guildName(guild)))
+	return standing, nil
+}
+
+// EndRivalry withdraws the rivalry playerID's guild, which playerID must
+// lead, declared on guildID.
+func (s *Service) EndRivalry(playerID, guildID string) (Standing, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Standing{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.state.Rivalries[:0]
+	for _, r := range s.state.Rivalries {
+		if r.GuildID != guild.ID || r.RivalGuildID != guildID {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(s.state.Rivalries) {
+		return Standing{}, ErrNoRivalry
+	}
+	s.state.Rivalries = kept
+	s.save()
+	utils.LogInfof("Diplomacy: %s ended the rivalry of guild %s with guild %s.", playerID, guild.ID, guildID)
+	return s.standing(guild.ID, true), nil
+}
+
+// Standing returns the diplomacy of guildID as playerID sees it, or of
+// playerID's own guild if guildID is empty.
+func (s *Service) Standing(playerID, guildID string) (Standing, error) {
+	own, inGuild := s.roster.GuildOf(playerID)
+	if guildID == "" {
+		if !inGuild {
+			return Standing{}, ErrNoGuild
+		}
+		guildID = own.ID
+	}
+	if _, ok := s.roster.Guild(guildID); !ok {
+		return Standing{}, ErrUnknownGuild
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	return s.standing(guildID, inGuild && own.ID == guildID), nil
+}
+
+// AllianceOf returns the alliance guildID is in, if any.
+func (s *Service) AllianceOf(guildID string) (Alliance, bool) {
+	if s == nil || guildID == "" {
+		return Alliance{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if alliance := s.allianceOf(guildID); alliance != nil {
+		return copyAlliance(*alliance), true
+	}
+	return Alliance{}, false
+}
+
+// Allied reports whether two different guilds are in the same alliance.
+func (s *Service) Allied(guildA, guildB string) bool {
+	if s == nil || guildA == "" || guildA == guildB {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alliance := s.allianceOf(guildA)
+	return alliance != nil && alliance.Has(guildB)
+}
+
+// Rivals reports whether either guild declared the other its rival.
+func (s *Service) Rivals(guildA, guildB string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rivals(guildA, guildB)
+}
+
+// leading returns the guild playerID leads.
+func (s *Service) leading(playerID string) (model.Guild, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return model.Guild{}, ErrNoGuild
+	}
+	if guild.LeaderID != playerID {
+		return model.Guild{}, ErrNotLeader
+	}
+	return guild, nil
+}
+
+// other returns the guild guildID, which must not be guild.
+func (s *Service) other(guild model.Guild, guildID string) (model.Guild, error) {
+	if guildID == guild.ID {
+		return model.Guild{}, ErrSelf
+	}
+	other, ok := s.roster.Guild(guildID)
+	if !ok {
+		return model.Guild{}, ErrUnknownGuild
+	}
+	return other, nil
+}
+
+// mayAlly checks that guild to may join from's alliance, or form one with it.
+// The caller holds s.mu.
+func (s *Service) mayAlly(from, to string) error {
+	alliance := s.allianceOf(from)
+	switch {
+	case alliance != nil && alliance.Has(to):
+		return ErrAllied
+	case s.allianceOf(to) != nil:
+		return ErrInAlliance
+	case s.rivals(from, to):
+		return ErrRivals
+	case alliance != nil && len(alliance.GuildIDs) >= s.opts.MaxGuilds:
+		return fmt.Errorf("%w: at most %d guilds", ErrFull, s.opts.MaxGuilds)
+	}
+	return nil
+}
+
+// allianceOf returns guildID's alliance, or nil. The caller holds s.mu.
+func (s *Service) allianceOf(guildID string) *Alliance {
+	for i := range s.state.Alliances {
+		if s.state.Alliances[i].Has(guildID) {
+			return &s.state.Alliances[i]
+		}
+	}
+	return nil
+}
+
+// rivals reports whether either guild declared the other its rival. The
+// caller holds s.mu.
+func (s *Service) rivals(guildA, guildB string) bool {
+	for _, r := range s.state.Rivalries {
+		if r.GuildID == guildA && r.RivalGuildID == guildB || r.GuildID == guildB && r.RivalGuildID == guildA {
+			return true
+		}
+	}
+	return false
+}
+
+// standing returns guildID's diplomacy, with its proposals if own. The
+// caller holds s.mu.
+func (s *Service) standing(guildID string, own bool) Standing {
+	standing := Standing{GuildID: guildID, Rivals: []string{}, RivalOf: []string{}}
+	if alliance := s.allianceOf(guildID); alliance != nil {
+		a := copyAlliance(*alliance)
+		standing.Alliance = &a
+	}
+	for _, r := range s.state.Rivalries {
+		switch guildID {
+		case r.GuildID:
+			standing.Rivals = append(standing.Rivals, r.RivalGuildID)
+		case r.RivalGuildID:
+			standing.RivalOf = append(standing.RivalOf, r.GuildID)
+		}
+	}
+	sort.Strings(standing.Rivals)
+	sort.Strings(standing.RivalOf)
+	if own {
+		for _, p := range s.state.Proposals {
+			switch guildID {
+			case p.ToGuildID:
+				standing.Incoming = append(standing.Incoming, p)
+			case p.FromGuildID:
+				standing.Outgoing = append(standing.Outgoing, p)
+			}
+		}
+	}
+	return standing
+}
+
+// prune drops the proposals that expired by now. The caller holds s.mu.
+func (s *Service) prune(now time.Time) {
+	kept := s.state.Proposals[:0]
+	for _, p := range s.state.Proposals {
+		if now.Before(p.ExpiresAt) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) != len(s.state.Proposals) {
+		s.state.Proposals = kept
+		s.save()
+	}
+}
+
+// notify mails the leader of guild about the guild aboutID.
+func (s *Service) notify(guild model.Guild, aboutID, kind, subject, body string) {
+	s.mail.Send(mail.Message{To: guild.LeaderID, Kind: kind, Subject: subject, Body: body, Ref: aboutID})
+}
+
+func (s *Service) changed(guildIDs []string) {
+	if s.onChange != nil && len(guildIDs) > 0 {
+		s.onChange(guildIDs)
+	}
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Diplomacy: Failed to save guild diplomacy: %v", err)
+	}
+}
+
+// without returns proposals less the one from guild from to guild to.
+func without(proposals []Proposal, from, to string) []Proposal {
+	kept := proposals[:0]
+	for _, p := range proposals {
+		if p.FromGuildID != from || p.ToGuildID != to {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func copyAlliance(a Alliance) Alliance {
+	a.GuildIDs = append([]string(nil), a.GuildIDs...)
+	return a
+}
+
+func guildName(g model.Guild) string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return g.ID
+}
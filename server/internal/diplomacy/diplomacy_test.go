@@ -0,0 +1,140 @@
+package diplomacy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/model"
+)
+
+func newTestService(t *testing.T, store Store) (*Service, *time.Time, *[][]string) {
+	t.Helper()
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", Name: "Wolves", LeaderID: "ann", MemberIDs: []string{"ann", "amy"}},
+		{ID: "bears", Name: "Bears", LeaderID: "bob", MemberIDs: []string{"bob"}},
+		{ID: "crows", Name: "Crows", LeaderID: "cat", MemberIDs: []string{"cat"}},
+		{ID: "deer", LeaderID: "dan", MemberIDs: []string{"dan"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(store, roster, Options{MaxGuilds: 3, ProposalTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+	var changes [][]string
+	s.OnChange(func(guildIDs []string) { changes = append(changes, guildIDs) })
+	return s, &clock, &changes
+}
+
+func TestAlliancesFormGrowAndDissolve(t *testing.T) {
+	store := &MemoryStore{}
+	s, clock, changes := newTestService(t, store)
+
+	if _, err := s.Propose("amy", "bears", ""); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("proposal by a member: %v", err)
+	}
+	if _, err := s.Propose("ann", "wolves", ""); !errors.Is(err, ErrSelf) {
+		t.Fatalf("proposal to itself: %v", err)
+	}
+	standing, err := s.Propose("ann", "bears", "")
+	if err != nil || len(standing.Outgoing) != 1 || standing.Outgoing[0].Name != "Wolves & Bears" {
+		t.Fatalf("proposal = %+v, %v", standing, err)
+	}
+	if standing, _ := s.Standing("bob", ""); len(standing.Incoming) != 1 {
+		t.Fatalf("the bears see %+v", standing)
+	}
+	if standing, _ := s.Standing("cat", "wolves"); standing.Outgoing != nil {
+		t.Fatalf("other guilds see the wolves' proposals: %+v", standing)
+	}
+	standing, err = s.Respond("bob", "wolves", true)
+	if err != nil || standing.Alliance == nil || !reflect.DeepEqual(standing.Alliance.GuildIDs, []string{"wolves", "bears"}) {
+		t.Fatalf("accept = %+v, %v", standing, err)
+	}
+	if !s.Allied("wolves", "bears") || s.Allied("wolves", "crows") || s.Allied("wolves", "wolves") {
+		t.Fatal("Allied does not match the alliance")
+	}
+
+	// A member's proposal invites into the alliance, up to MaxGuilds.
+	if _, err := s.Propose("bob", "crows", "Ignored"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Propose("ann", "deer", ""); err != nil {
+		t.Fatal(err)
+	}
+	if standing, err := s.Respond("cat", "bears", true); err != nil || standing.Alliance.Name != "Wolves & Bears" {
+		t.Fatalf("crows accept = %+v, %v", standing, err)
+	}
+	if _, err := s.Respond("dan", "wolves", true); !errors.Is(err, ErrFull) {
+		t.Fatalf("accepting into a full alliance: %v", err)
+	}
+	if _, err := s.Respond("dan", "wolves", false); err != nil {
+		t.Fatalf("declining after the failed accept: %v", err)
+	}
+
+	// Leaving down to one guild dissolves the alliance.
+	if _, err := s.Leave("cat"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Leave("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.AllianceOf("wolves"); ok {
+		t.Fatal("a single guild is left in an alliance")
+	}
+	want := [][]string{{"wolves", "bears"}, {"crows"}, {"crows"}, {"bears", "wolves"}}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Fatalf("changes = %v, want %v", *changes, want)
+	}
+
+	// Proposals lapse.
+	s.Propose("ann", "deer", "")
+	*clock = clock.Add(2 * time.Hour)
+	if _, err := s.Respond("dan", "wolves", true); !errors.Is(err, ErrNoProposal) {
+		t.Fatalf("accepting an expired proposal: %v", err)
+	}
+
+	// The state survives a restart.
+	s.Propose("ann", "bears", "Pact")
+	s.Respond("bob", "wolves", true)
+	reloaded, _, _ := newTestService(t, store)
+	if alliance, ok := reloaded.AllianceOf("bears"); !ok || alliance.Name != "Pact" {
+		t.Fatalf("reloaded alliance = %+v, %v", alliance, ok)
+	}
+}
+
+func TestRivalsCannotAlly(t *testing.T) {
+	s, _, _ := newTestService(t, &MemoryStore{})
+	s.Propose("ann", "bears", "")
+	standing, err := s.DeclareRivalry("bob", "wolves")
+	if err != nil || !reflect.DeepEqual(standing.Rivals, []string{"wolves"}) || standing.Incoming != nil {
+		t.Fatalf("declare = %+v, %v; want the rivalry and the proposal withdrawn", standing, err)
+	}
+	if standing, _ := s.Standing("ann", ""); !reflect.DeepEqual(standing.RivalOf, []string{"bears"}) || len(standing.Rivals) != 0 {
+		t.Fatalf("the wolves see %+v", standing)
+	}
+	if _, err := s.Propose("ann", "bears", ""); !errors.Is(err, ErrRivals) || !s.Rivals("wolves", "bears") {
+		t.Fatalf("proposal to a rival: %v", err)
+	}
+	if _, err := s.EndRivalry("ann", "bears"); !errors.Is(err, ErrNoRivalry) {
+		t.Fatalf("ending a rivalry the other side declared: %v", err)
+	}
+	if _, err := s.EndRivalry("bob", "wolves"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Propose("ann", "crows", "")
+	s.Respond("cat", "wolves", true)
+	if _, err := s.DeclareRivalry("cat", "wolves"); !errors.Is(err, ErrAllied) {
+		t.Fatalf("rivalry with an ally: %v", err)
+	}
+	var none *Service
+	if none.Allied("wolves", "crows") || none.Rivals("wolves", "bears") {
+		t.Fatal("a nil service has relations")
+	}
+}
@@ -0,0 +1,20 @@
+package diplomacy
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted diplomacy of every guild.
+type State struct {
+	Alliances []Alliance `json:"alliances"`
+	Proposals []Proposal `json:"proposals,omitempty"` // Open alliance proposals
+	Rivalries []Rivalry  `json:"rivalries,omitempty"`
+	NextID    uint64     `json:"nextId"`
+}
+
+// Store persists the diplomacy state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the diplomacy state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the diplomacy state in a JSON file.
+type FileStore = jsonstore.File[State]
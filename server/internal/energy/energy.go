@@ -0,0 +1,322 @@
+// Package energy keeps the players' energy, the stamina that actions such as
+// crafting and arena queueing spend. Energy comes back one point at a time.
+// Only the point count and when regeneration was last counted are stored, so
+// a player who logs in after a while gets the energy they regained offline;
+// while they are online a ticker pushes each regained point to their session.
+package energy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Actions that spend energy, as named in balance energy.costs.
+const (
+	ActionCraft      = "craft"
+	ActionArenaQueue = "arenaQueue"
+)
+
+// DefaultTickInterval is how often online players' regained energy is pushed
+// when Options.TickInterval is zero.
+const DefaultTickInterval = 5 * time.Second
+
+// ErrNotEnoughEnergy is returned when an action costs more energy than the
+// player has.
+var ErrNotEnoughEnergy = errors.New("not enough energy")
+
+// State is a player's stored energy. Points regained since UpdatedAt are not
+// counted in Energy yet.
+type State struct {
+	Energy    int
+	UpdatedAt time.Time
+}
+
+// Status is a player's energy at a point in time.
+type Status struct {
+	Energy        int
+	Max           int
+	RegenInterval time.Duration
+	NextRegenAt   time.Time // When the next point comes back; zero when full
+}
+
+// Update tells a connected session that its player's energy changed.
+type Update struct {
+	Status Status
+}
+
+// Notifier delivers an *Update to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	TickInterval time.Duration // How often online players' regained energy is pushed
+}
+
+// online is a connected player. Their state is cached once loaded, since
+// a player is served by one instance at a time.
+type online struct {
+	notify Notifier
+	state  State
+	loaded bool
+	sent   int // Energy in the last Update
+}
+
+// Service spends and regenerates energy. It is safe for concurrent use; a
+// nil Service charges nothing.
+type Service struct {
+	store  Store
+	values func() balance.EnergyValues
+	opts   Options
+	now    func() time.Time
+
+	mu      sync.Mutex
+	players map[string]*online
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates a Service. values returns the current energy tunables,
+// usually from the balance service.
+func NewService(store Store, values func() balance.EnergyValues, opts Options) *Service {
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	return &Service{
+		store:   store,
+		values:  values,
+		opts:    opts,
+		now:     time.Now,
+		players: make(map[string]*online),
+	}
+}
+
+// Cost returns the energy action spends, or 0 if it is free or energy is off.
+func (s *Service) Cost(action string) int {
+	if s == nil {
+		return 0
+	}
+	values := s.values()
+	if values.Max <= 0 {
+		return 0
+	}
+	return values.Costs[action]
+}
+
+// Costs returns the energy each action spends, or nil if energy is off.
+func (s *Service) Costs() map[string]int {
+	if s == nil {
+		return nil
+	}
+	values := s.values()
+	if values.Max <= 0 {
+		return nil
+	}
+	costs := make(map[string]int, len(values.Costs))
+	for action, cost := range values.Costs {
+		costs[action] = cost
+	}
+	return costs
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players[playerID] = &online{notify: notify, sent: -1}
+}
+
+// Disconnect forgets a player who went offline. Their energy keeps
+// regenerating from the stored state.
+func (s *Service) Disconnect(playerID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.players, playerID)
+}
+
+// Get returns playerID's energy, with what they regained until now.
+func (s *Service) Get(ctx context.Context, playerID string) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, now := s.values(), s.now()
+	state, err := s.load(ctx, playerID, values, now)
+	if err != nil {
+		return Status{}, err
+	}
+	state = regenerate(state, values, now)
+	s.cache(playerID, state, false)
+	return status(state, values), nil
+}
+
+// Spend takes the energy of action from playerID. It returns
+// ErrNotEnoughEnergy, and takes nothing, if they have too little.
+func (s *Service) Spend(ctx context.Context, playerID, action string) error {
+	cost := s.Cost(action)
+	if cost <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, now := s.values(), s.now()
+	state, err := s.load(ctx, playerID, values, now)
+	if err != nil {
+		return err
+	}
+	state = regenerate(state, values, now)
+	if state.Energy < cost {
+		return fmt.Errorf("%w: %s needs %d, %d left", ErrNotEnoughEnergy, action, cost, state.Energy)
+	}
+	if state.Energy >= values.Max {
+		state.UpdatedAt = now // Regeneration starts with the first point spent
+	}
+	state.Energy -= cost
+	if err := s.store.Save(ctx, playerID, state); err != nil {
+		return err
+	}
+	s.cache(playerID, state, true)
+	return nil
+}
+
+// Refund gives back the energy of an action that failed after Spend.
+func (s *Service) Refund(ctx context.Context, playerID, action string) {
+	cost := s.Cost(action)
+	if cost <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, now := s.values(), s.now()
+	state, err := s.load(ctx, playerID, values, now)
+	if err == nil {
+		state = regenerate(state, values, now)
+		if state.Energy += cost; state.Energy >= values.Max {
+			state.Energy, state.UpdatedAt = values.Max, now
+		}
+		err = s.store.Save(ctx, playerID, state)
+	}
+	if err != nil {
+		utils.LogErrorf("Energy: Could not refund %d energy of %s to %s: %v", cost, action, playerID, err)
+		return
+	}
+	s.cache(playerID, state, true)
+}
+
+// Start pushes online players' regained energy in the background.
+func (s *Service) Start() {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Tick(s.now())
+			}
+		}
+	}()
+}
+
+// Stop stops the background pushes.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Tick pushes an Update to every online player who regained energy since
+// their last one. It uses the cached states and does not touch the store.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := s.values()
+	for _, p := range s.players {
+		if !p.loaded {
+			continue
+		}
+		p.state = regenerate(p.state, values, now)
+		if p.state.Energy != p.sent {
+			p.sent = p.state.Energy
+			p.notify(&Update{Status: status(p.state, values)})
+		}
+	}
+}
+
+// load returns playerID's stored state, from the cache when they are online.
+// Players without one start rested.
+func (s *Service) load(ctx context.Context, playerID string, values balance.EnergyValues, now time.Time) (State, error) {
+	if p, ok := s.players[playerID]; ok && p.loaded {
+		return p.state, nil
+	}
+	state, ok, err := s.store.Load(ctx, playerID)
+	if err != nil {
+		return State{}, err
+	}
+	if !ok {
+		return State{Energy: values.Max, UpdatedAt: now}, nil
+	}
+	return state, nil
+}
+
+// cache keeps an online player's state and, if notify is set, pushes it.
+// Callers hold s.mu.
+func (s *Service) cache(playerID string, state State, notify bool) {
+	p, ok := s.players[playerID]
+	if !ok {
+		return
+	}
+	p.state, p.loaded = state, true
+	if notify {
+		p.notify(&Update{Status: status(state, s.values())})
+	}
+	p.sent = state.Energy
+}
+
+// regenerate adds the points regained between state.UpdatedAt and now. A
+// partly regained point carries over in UpdatedAt.
+func regenerate(state State, values balance.EnergyValues, now time.Time) State {
+	if state.Energy >= values.Max {
+		state.Energy = values.Max
+		return state
+	}
+	interval := values.RegenInterval()
+	if interval <= 0 {
+		return state
+	}
+	points := int(now.Sub(state.UpdatedAt) / interval)
+	if points <= 0 {
+		return state
+	}
+	state.Energy += points
+	state.UpdatedAt = state.UpdatedAt.Add(time.Duration(points) * interval)
+	if state.Energy >= values.Max {
+		state.Energy, state.UpdatedAt = values.Max, now
+	}
+	return state
+}
+
+func status(state State, values balance.EnergyValues) Status {
+	st := Status{Energy: state.Energy, Max: values.Max, RegenInterval: values.RegenInterval()}
+	if state.Energy < values.Max {
+		st.NextRegenAt = state.UpdatedAt.Add(st.RegenInterval)
+	}
+	return st
+}
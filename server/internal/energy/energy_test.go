@@ -0,0 +1,90 @@
+package energy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+var testValues = balance.EnergyValues{Max: 10, RegenSeconds: 60, Costs: map[string]int{ActionCraft: 4}}
+
+func newTestService(store Store) (*Service, *time.Time) {
+	s := NewService(store, func() balance.EnergyValues { return testValues }, Options{})
+	now := time.Unix(1000, 0).UTC()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestEnergyRegeneratesOffline(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	s, now := newTestService(store)
+
+	for i := 0; i < 2; i++ {
+		if err := s.Spend(ctx, "alice", ActionCraft); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Spend(ctx, "alice", ActionCraft); !errors.Is(err, ErrNotEnoughEnergy) {
+		t.Fatalf("third craft with 2 energy: %v", err)
+	}
+	if err := s.Spend(ctx, "alice", "dance"); err != nil {
+		t.Errorf("free action: %v", err)
+	}
+
+	// Two and a half minutes later the player has regained two points, and the
+	// half point carries over.
+	*now = now.Add(150 * time.Second)
+	status, err := s.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Energy != 4 || !status.NextRegenAt.Equal(now.Add(30*time.Second)) {
+		t.Errorf("after 150s: %+v", status)
+	}
+	*now = now.Add(time.Hour)
+	if status, _ := s.Get(ctx, "alice"); status.Energy != 10 || !status.NextRegenAt.IsZero() {
+		t.Errorf("after an hour: %+v", status)
+	}
+	if status, _ := s.Get(ctx, "bob"); status.Energy != 10 {
+		t.Errorf("new player: %+v", status)
+	}
+
+	s.Refund(ctx, "alice", ActionCraft)
+	if state, _, _ := store.Load(ctx, "alice"); state.Energy != 10 {
+		t.Errorf("refund beyond max: %+v", state)
+	}
+}
+
+func TestOnlinePlayersAreToldAboutRegainedEnergy(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestService(NewMemoryStore())
+	var updates []Status
+	s.Connect("alice", func(note interface{}) { updates = append(updates, note.(*Update).Status) })
+
+	if _, err := s.Get(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Spend(ctx, "alice", ActionCraft); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now.Add(59 * time.Second))
+	s.Tick(now.Add(61 * time.Second))
+	s.Tick(now.Add(62 * time.Second))
+	if len(updates) != 2 || updates[0].Energy != 6 || updates[1].Energy != 7 {
+		t.Fatalf("updates = %+v", updates)
+	}
+
+	s.Disconnect("alice")
+	s.Tick(now.Add(time.Hour))
+	if len(updates) != 2 {
+		t.Errorf("offline player was updated: %+v", updates)
+	}
+	var nilService *Service
+	if err := nilService.Spend(ctx, "alice", ActionCraft); err != nil || nilService.Cost(ActionCraft) != 0 {
+		t.Errorf("nil service charged: %v", err)
+	}
+}
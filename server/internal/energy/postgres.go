@@ -0,0 +1,68 @@
+package energy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS player_energy (
+	player_id  TEXT PRIMARY KEY,
+	energy     INTEGER NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+`
+
+// PostgresStore keeps energy in the player_energy table.
+type PostgresStore struct {
+	DB      *sql.DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the player_energy table if it is missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create player_energy: %w", err)
+	}
+	return nil
+}
+
+// Load implements Store.
+func (s *PostgresStore) Load(ctx context.Context, playerID string) (State, bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var state State
+	err := s.DB.QueryRowContext(ctx, `SELECT energy, updated_at FROM player_energy WHERE player_id = $1`, playerID).
+		Scan(&state.Energy, &state.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, fmt.Errorf("load energy of %s: %w", playerID, err)
+	}
+	return state, true, nil
+}
+
+// Save implements Store.
+func (s *PostgresStore) Save(ctx context.Context, playerID string, state State) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO player_energy (player_id, energy, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (player_id) DO UPDATE SET energy = EXCLUDED.energy, updated_at = EXCLUDED.updated_at`,
+		playerID, state.Energy, state.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save energy of %s: %w", playerID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
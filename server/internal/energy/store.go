@@ -0,0 +1,41 @@
+package energy
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists the players' energy.
+type Store interface {
+	// Load returns playerID's state, if one was saved.
+	Load(ctx context.Context, playerID string) (State, bool, error)
+	Save(ctx context.Context, playerID string, state State) error
+}
+
+// MemoryStore keeps energy in memory. It is used when there is no database;
+// every player starts rested after a restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load(ctx context.Context, playerID string) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[playerID]
+	return state, ok, nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(ctx context.Context, playerID string, state State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[playerID] = state
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package events is an in-process publish/subscribe bus. Gameplay code
+// publishes what happened (a player logged in, a fight ended, an item sold) and
+// secondary systems such as achievements, quests, analytics and logging
+// subscribe to it, so core paths do not need to know about them.
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultSubscriberBuffer is the number of undelivered events a subscriber can
+// fall behind by before new events for it are dropped.
+const DefaultSubscriberBuffer = 256
+
+// Event is a published notification.
+type Event struct {
+	Topic   Topic
+	Time    time.Time
+	Payload interface{} // One of the payload types in topics.go
+}
+
+// Handler processes events for one subscription. Handlers run on the
+// subscription's own goroutine, one event at a time.
+type Handler func(Event)
+
+type subscription struct {
+	id      uint64
+	name    string
+	pattern string
+	events  chan Event
+	handler Handler
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// matches reports whether topic matches the subscription pattern: an exact
+// topic, a prefix such as "player.*", or "*" for everything.
+func (s *subscription) matches(topic Topic) bool {
+	if s.pattern == "*" || s.pattern == string(topic) {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(s.pattern, "*"); ok {
+		return strings.HasPrefix(string(topic), prefix)
+	}
+	return false
+}
+
+func (s *subscription) run() {
+	defer close(s.done)
+	for event := range s.events {
+		s.deliver(event)
+	}
+}
+
+func (s *subscription) deliver(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogErrorf("EventBus: Subscriber %s panicked handling %s: %v", s.name, event.Topic, r)
+		}
+	}()
+	s.handler(event)
+}
+
+// Bus delivers published events to matching subscribers. Publishing never
+// blocks: each subscriber has a buffered queue, and events are dropped (and
+// counted) for subscribers that fall too far behind.
+type Bus struct {
+	bufferSize int
+
+	mu     sync.RWMutex
+	subs   map[uint64]*subscription
+	nextID uint64
+	closed bool
+}
+
+// NewBus creates a Bus. A bufferSize of zero or less uses DefaultSubscriberBuffer.
+func NewBus(bufferSize int) *Bus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultSubscriberBuffer
+	}
+	return &Bus{bufferSize: bufferSize, subs: make(map[uint64]*subscription)}
+}
+
+// Subscribe registers handler for events whose topic matches pattern. The
+// name identifies the subscriber in logs and stats. The returned function
+// removes the subscription; events already queued are still delivered.
+func (b *Bus) Subscribe(pattern string, name string, handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return func() {}
+	}
+	b.nextID++
+	sub := &subscription{
+		id:      b.nextID,
+		name:    name,
+		pattern: pattern,
+		events:  make(chan Event, b.bufferSize),
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	b.subs[sub.id] = sub
+	go sub.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.remove(sub.id) })
+	}
+}
+
+// On subscribes to a topic with a handler that takes the topic's payload type.
+// Events whose payload is not a T are ignored.
+func On[T any](b *Bus, topic Topic, name string, handler func(T)) (unsubscribe func()) {
+	return b.Subscribe(string(topic), name, func(event Event) {
+		if payload, ok := event.Payload.(T); ok {
+			handler(payload)
+		}
+	})
+}
+
+// Publish sends an event to every matching subscriber. It is safe to call on a nil Bus.
+func (b *Bus) Publish(topic Topic, payload interface{}) {
+	if b == nil {
+		return
+	}
+	event := Event{Topic: topic, Time: time.Now(), Payload: payload}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if !sub.matches(topic) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			if sub.dropped.Add(1) == 1 {
+				utils.LogWarnf("EventBus: Subscriber %s is falling behind; dropping %s events.", sub.name, topic)
+			}
+		}
+	}
+}
+
+// SubscriberStats reports the backlog of one subscription.
+type SubscriberStats struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// Stats returns the current state of every subscription.
+func (b *Bus) Stats() []SubscriberStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stats := make([]SubscriberStats, 0, len(b.subs))
+	for _, sub := range b.subs {
+		stats = append(stats, SubscriberStats{Name: sub.name, Pattern: sub.pattern, Queued: len(sub.events), Dropped: sub.dropped.Load()})
+	}
+	return stats
+}
+
+// Close removes all subscriptions and waits for queued events to be delivered.
+// Later calls to Publish are ignored.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[uint64]*subscription)
+	b.mu.Unlock()
+	for _, sub := range subs {
+		close(sub.events)
+		<-sub.done
+	}
+}
+
+func (b *Bus) remove(id uint64) {
+	b.mu.Lock()
+	sub, ok := b.subs[id]
+	delete(b.subs, id)
+	b.mu.Unlock()
+	if ok {
+		close(sub.events)
+	}
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPublishMatchesPatterns(t *testing.T) {
+	bus := NewBus(0)
+	var mu sync.Mutex
+	got := map[string][]Topic{}
+	record := func(name string) Handler {
+		return func(e Event) {
+			mu.Lock()
+			got[name] = append(got[name], e.Topic)
+			mu.Unlock()
+		}
+	}
+	bus.Subscribe(string(TopicPlayerLogin), "exact", record("exact"))
+	bus.Subscribe("player.*", "prefix", record("prefix"))
+	bus.Subscribe("*", "all", record("all"))
+
+	var logins []string
+	On(bus, TopicPlayerLogin, "typed", func(p PlayerLogin) { logins = append(logins, p.PlayerID) })
+
+	bus.Publish(TopicPlayerLogin, PlayerLogin{PlayerID: "p1"})
+	bus.Publish(TopicPlayerLogout, PlayerLogout{PlayerID: "p1"})
+	bus.Publish(TopicCombatFinished, CombatFinished{WinnerID: "p1"})
+	bus.Close() // Waits for delivery
+
+	want := map[string]int{"exact": 1, "prefix": 2, "all": 3}
+	for name, n := range want {
+		if len(got[name]) != n {
+			t.Errorf("%s received %v, want %d events", name, got[name], n)
+		}
+	}
+	if len(logins) != 1 || logins[0] != "p1" {
+		t.Errorf("typed handler received %v", logins)
+	}
+}
+
+func TestSlowSubscriberDropsInsteadOfBlocking(t *testing.T) {
+	bus := NewBus(1)
+	release := make(chan struct{})
+	bus.Subscribe("*", "slow", func(Event) { <-release })
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(TopicRoomJoined, RoomJoined{PlayerID: "p1"})
+	}
+	stats := bus.Stats()
+	close(release)
+	bus.Close()
+
+	if len(stats) != 1 || stats[0].Dropped == 0 {
+		t.Fatalf("stats = %+v, want dropped events", stats)
+	}
+}
+
+func TestHandlerPanicDoesNotStopSubscription(t *testing.T) {
+	bus := NewBus(0)
+	calls := 0
+	bus.Subscribe("*", "flaky", func(Event) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+	})
+	bus.Publish(TopicPlayerLogin, PlayerLogin{})
+	bus.Publish(TopicPlayerLogin, PlayerLogin{})
+	bus.Close()
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+}
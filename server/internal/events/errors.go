@@ -0,0 +1,30 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrorReporter returns a function, for utils.SetErrorHook, that publishes
+// logged errors as server.error events. At most one event is published per
+// interval; errors in between are counted in the next event's Suppressed.
+func ErrorReporter(bus *Bus, interval time.Duration) func(message string) {
+	var (
+		mu         sync.Mutex
+		last       time.Time
+		suppressed int
+	)
+	return func(message string) {
+		mu.Lock()
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < interval {
+			suppressed++
+			mu.Unlock()
+			return
+		}
+		event := ServerError{Message: message, Suppressed: suppressed}
+		last, suppressed = now, 0
+		mu.Unlock()
+		bus.Publish(TopicServerError, event)
+	}
+}
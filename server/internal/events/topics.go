@@ -0,0 +1,152 @@
+package events
+
+import "time"
+
+// Topic names an event type. Topics are dot-separated, with the owning module first.
+type Topic string
+
+const (
+	TopicPlayerLogin           Topic = "player.login"              // PlayerLogin
+	TopicPlayerLogout          Topic = "player.logout"             // PlayerLogout
+	TopicRoomJoined            Topic = "room.joined"               // RoomJoined
+	TopicCombatFinished        Topic = "combat.finished"           // CombatFinished
+	TopicMarketSold            Topic = "market.sold"               // MarketSold
+	TopicTutorialStepCompleted Topic = "tutorial.step_completed"   // TutorialStepCompleted
+	TopicTerritoryClaimed      Topic = "territory.claimed"         // TerritoryClaimed
+	TopicSiegeScheduled        Topic = "territory.siege_scheduled" // TerritorySiege
+	TopicSiegeStarted          Topic = "territory.siege_started"   // TerritorySiege
+	TopicSiegeEnded            Topic = "territory.siege_ended"     // SiegeEnded
+	TopicItemMinted            Topic = "item.minted"               // ItemMinted
+	TopicEpochChanged          Topic = "chain.epoch_changed"       // EpochChanged
+	TopicTxCompleted           Topic = "chain.tx_completed"        // TxCompleted
+	TopicServerError           Topic = "server.error"              // ServerError
+	TopicCheatSuspected        Topic = "anticheat.suspected"       // CheatSuspected
+	TopicDeepLinkOpened        Topic = "deeplink.opened"           // DeepLinkOpened
+)
+
+// PlayerLogin is published when a player authenticates.
+type PlayerLogin struct {
+	PlayerID string
+}
+
+// PlayerLogout is published when an authenticated player's session ends.
+type PlayerLogout struct {
+	PlayerID string
+	Duration float64 // Session length in seconds
+}
+
+// RoomJoined is published when a player joins a room.
+type RoomJoined struct {
+	PlayerID string
+	RoomID   string
+}
+
+// CombatFinished is published when a fight ends with a defeated combatant.
+type CombatFinished struct {
+	WinnerID string
+	LoserID  string
+	Damage   int // Damage dealt by the final blow
+}
+
+// MarketSold is published when a marketplace purchase has executed on chain.
+type MarketSold struct {
+	ListingID string
+	NFTID     string
+	Seller    string
+	Buyer     string
+	Price     uint64
+	TxDigest  string
+}
+
+// TutorialStepCompleted is published when a player completes a tutorial step.
+type TutorialStepCompleted struct {
+	PlayerID string
+	StepID   string
+	Finished bool // The tutorial has no steps left
+}
+
+// TerritoryClaimed is published when a guild is granted a zone, by claim or by winning a siege.
+type TerritoryClaimed struct {
+	ZoneID  string
+	GuildID string
+}
+
+// TerritorySiege is published when a contested claim schedules a siege and when the siege begins.
+type TerritorySiege struct {
+	ZoneID          string
+	AttackerGuildID string
+	DefenderGuildID string
+	StartsAt        time.Time
+	EndsAt          time.Time
+}
+
+// SiegeEnded is published when a siege is resolved or runs out.
+type SiegeEnded struct {
+	ZoneID        string
+	WinnerGuildID string
+	Expired       bool // Nobody reported a result; the defender keeps the zone
+}
+
+// ItemMinted is published when the server has minted an item NFT on chain.
+type ItemMinted struct {
+	ItemType string
+	Owner    string
+	Source   string // What granted the item, e.g. "shop", "arena", "crafting" or "battlepass"
+	TxDigest string
+}
+
+// EpochChanged is published when the Sui chain moves to a new epoch, for
+// mechanics counted in epochs such as voting periods and listing expiry.
+type EpochChanged struct {
+	Epoch        uint64
+	Previous     uint64 // Epochs may be skipped if the chain was not reachable
+	StartedAt    time.Time
+	EstimatedEnd time.Time
+}
+
+// Statuses of a TxCompleted, as Sui reports transaction effects.
+const (
+	TxSuccess = "success"
+	TxFailure = "failure"
+)
+
+// TxCompleted is published when a transaction the server executed for players,
+// such as a mint or an escrow settlement, has completed, or when the server
+// gave up on it (Status TxFailure, possibly without a Digest).
+type TxCompleted struct {
+	PlayerIDs []string // The players it was for
+	Kind      string   // What it did, e.g. "crafting", "arena" or "trade"
+	Digest    string
+	Status    string // TxSuccess or TxFailure
+	Summary   string // One line for the players, e.g. "Iron Sword minted"
+}
+
+// ServerError is published for errors the server logs. Bursts are rate-limited
+// by the publisher, so subscribers see a sample rather than every line.
+type ServerError struct {
+	Message    string
+	Suppressed int // Errors dropped by the rate limit since the previous event
+}
+
+// CheatSuspected is published when the anticheat monitor reports a player for
+// repeated violations, or disconnects them.
+type CheatSuspected struct {
+	PlayerID   string
+	Check      string  // What was violated, e.g. "speed"
+	Action     string  // "flag" or "kick"
+	Violations int     // Recent violations, this one included
+	Observed   float64 // The measured value, e.g. units per second
+	Limit      float64 // The configured limit it exceeded
+	At         time.Time
+}
+
+// DeepLinkOpened is published when a player presents a valid deep link, so
+// the visit can be attributed to whoever promoted it.
+type DeepLinkOpened struct {
+	PlayerID string
+	LinkID   string
+	Kind     string // room, listing or guild
+	Target   string
+	Source   string // Who embedded the link
+	Campaign string
+}
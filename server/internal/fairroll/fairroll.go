@@ -0,0 +1,248 @@
+// Package fairroll rolls high-stakes loot so that players can check the
+// server did not pick the result. Before a roll, the server commits to a
+// random seed by publishing its SHA-256 hash; with the result it reveals the
+// seed, from which anyone can recompute the roll:
+//
+//	draw i = first 8 bytes of HMAC-SHA256(key: seed, message: i as a big-endian uint64),
+//	         as a big-endian uint64 shifted right by 11, divided by 2^53
+//
+// The draws replace math/rand in balance.LootValues.Roll, in the order the
+// table's drops are listed. Each seed is used for one roll.
+package fairroll
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// CommitmentKind is the outbox kind and on-chain event type of commitments
+// logged on chain.
+const CommitmentKind = "loot_commitment"
+
+// Commitment is the hash of the seed of a player's next high-stakes roll.
+type Commitment struct {
+	ID       string    `json:"id"`
+	PlayerID string    `json:"playerId"`
+	Hash     string    `json:"hash"` // Hex SHA-256 of the seed
+	At       time.Time `json:"at"`
+}
+
+// Reveal is a high-stakes roll together with everything needed to check it.
+type Reveal struct {
+	CommitmentID       string
+	PlayerID           string
+	Hash               string // As published in the commitment
+	Seed               string // Hex; its SHA-256 is Hash
+	Table              string
+	Drops              []balance.LootDrop // The table as it was rolled
+	DropRateMultiplier float64
+	Draws              int            // Number of draws the roll used
+	Loot               map[string]int // ItemID -> quantity
+	At                 time.Time
+}
+
+// Notifier delivers a *Commitment or *Reveal to a player's session.
+type Notifier func(note interface{})
+
+// Publisher records commitments somewhere players can see them before the
+// roll, such as an on-chain event log.
+type Publisher interface {
+	PublishCommitment(c Commitment) error
+}
+
+// Service holds a committed seed per connected player and rolls high-stakes
+// tables with it. It is safe for concurrent use.
+type Service struct {
+	tables    map[string]bool
+	publisher Publisher // Nil sends commitments to the player's session only
+	now       func() time.Time
+	random    func([]byte) (int, error)
+
+	mu        sync.Mutex
+	nextID    uint64
+	pending   map[string]*seed    // Committed, unrevealed seeds by player ID
+	notifiers map[string]Notifier // Connected sessions by player ID
+}
+
+type seed struct {
+	commitment Commitment
+	value      []byte
+}
+
+// NewService creates a Service that rolls tables with commitments.
+func NewService(tables []string) *Service {
+	highStakes := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		highStakes[table] = true
+	}
+	return &Service{
+		tables:    highStakes,
+		now:       time.Now,
+		random:    rand.Read,
+		pending:   make(map[string]*seed),
+		notifiers: make(map[string]Notifier),
+	}
+}
+
+// UsePublisher makes the service publish every commitment through p before
+// the seed is used.
+func (s *Service) UsePublisher(p Publisher) {
+	s.publisher = p
+}
+
+// HighStakes reports whether table is rolled with commitments.
+func (s *Service) HighStakes(table string) bool {
+	return s.tables[table]
+}
+
+// Connect registers a session for playerID and sends it the commitment for
+// the player's next roll.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	s.notifiers[playerID] = notify
+	next := s.pending[playerID]
+	s.mu.Unlock()
+	if next == nil {
+		next = s.commit(playerID)
+		s.hold(playerID, next)
+	}
+	notify(&next.commitment)
+}
+
+// Disconnect unregisters playerID's session and drops their unused
+// commitment; it is never revealed.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+	delete(s.pending, playerID)
+}
+
+// Roll rolls table for playerID with their committed seed if the table is
+// high-stakes, sends them the reveal and the commitment for their next roll,
+// and returns the loot. It returns false for other tables, which are rolled
+// as usual. A player without a commitment, such as one without a session,
+// gets nothing from a high-stakes table: a seed committed at roll time would
+// prove nothing.
+func (s *Service) Roll(playerID, table string, loot balance.LootValues) (map[string]int, bool) {
+	if !s.HighStakes(table) {
+		return nil, false
+	}
+	s.mu.Lock()
+	used := s.pending[playerID]
+	delete(s.pending, playerID)
+	notify := s.notifiers[playerID]
+	s.mu.Unlock()
+	if used == nil {
+		utils.LogWarnf("Fair roll of table %q for player %s refused: no commitment was published before the roll", table, playerID)
+		return nil, true
+	}
+
+	drops := append([]balance.LootDrop(nil), loot.Tables[table]...)
+	rng := &SeededRNG{Seed: used.value}
+	result := balance.LootValues{Tables: map[string][]balance.LootDrop{table: drops}, DropRateMultiplier: loot.DropRateMultiplier}.Roll(table, rng)
+	reveal := &Reveal{
+		CommitmentID:       used.commitment.ID,
+		PlayerID:           playerID,
+		Hash:               used.commitment.Hash,
+		Seed:               hex.EncodeToString(used.value),
+		Table:              table,
+		Drops:              drops,
+		DropRateMultiplier: loot.DropRateMultiplier,
+		Draws:              rng.Draws,
+		Loot:               result,
+		At:                 s.now().UTC(),
+	}
+	utils.LogInfof("Fair roll %s of table %q for player %s: seed %s, loot %v", reveal.CommitmentID, table, playerID, reveal.Seed, result)
+	if notify != nil {
+		next := s.commit(playerID)
+		s.hold(playerID, next)
+		notify(reveal)
+		notify(&next.commitment)
+	}
+	return result, true
+}
+
+// commit draws a seed for playerID and publishes its hash.
+func (s *Service) commit(playerID string) *seed {
+	value := make([]byte, 32)
+	if _, err := s.random(value); err != nil {
+		panic(fmt.Sprintf("fairroll: reading random seed: %v", err)) // crypto/rand does not fail on supported platforms
+	}
+	sum := sha256.Sum256(value)
+	s.mu.Lock()
+	s.nextID++
+	next := &seed{
+		commitment: Commitment{ID: fmt.Sprintf("fr-%d-%d", s.now().Unix(), s.nextID), PlayerID: playerID, Hash: hex.EncodeToString(sum[:]), At: s.now().UTC()},
+		value:      value,
+	}
+	s.mu.Unlock()
+	if s.publisher != nil {
+		if err := s.publisher.PublishCommitment(next.commitment); err != nil {
+			utils.LogWarnf("Fair roll commitment %s for player %s was not published: %v", next.commitment.ID, playerID, err)
+		}
+	}
+	return next
+}
+
+// hold keeps next for playerID's next roll while their session is connected.
+func (s *Service) hold(playerID string, next *seed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notifiers[playerID] != nil {
+		s.pending[playerID] = next
+	}
+}
+
+// SeededRNG is the balance.RNG of a revealed seed.
+type SeededRNG struct {
+	Seed  []byte
+	Draws int // Draws made so far
+}
+
+// Float64 returns the next draw, in [0, 1).
+func (r *SeededRNG) Float64() float64 {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(r.Draws))
+	r.Draws++
+	mac := hmac.New(sha256.New, r.Seed)
+	mac.Write(counter[:])
+	return float64(binary.BigEndian.Uint64(mac.Sum(nil))>>11) / (1 << 53)
+}
+
+// Verify checks that r's seed matches its commitment and that rolling its
+// table with the seed gives its loot.
+func Verify(r Reveal) error {
+	value, err := hex.DecodeString(r.Seed)
+	if err != nil {
+		return fmt.Errorf("seed is not hex: %v", err)
+	}
+	sum := sha256.Sum256(value)
+	if hex.EncodeToString(sum[:]) != r.Hash {
+		return errors.New("seed does not match the committed hash")
+	}
+	rng := &SeededRNG{Seed: value}
+	loot := balance.LootValues{Tables: map[string][]balance.LootDrop{r.Table: r.Drops}, DropRateMultiplier: r.DropRateMultiplier}.Roll(r.Table, rng)
+	if rng.Draws != r.Draws {
+		return fmt.Errorf("the roll takes %d draws, not %d", rng.Draws, r.Draws)
+	}
+	if len(loot) != len(r.Loot) {
+		return fmt.Errorf("the seed rolls %v, not %v", loot, r.Loot)
+	}
+	for item, quantity := range loot {
+		if r.Loot[item] != quantity {
+			return fmt.Errorf("the seed rolls %v, not %v", loot, r.Loot)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package fairroll
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+type recordingPublisher struct {
+	published []Commitment
+}
+
+func (p *recordingPublisher) PublishCommitment(c Commitment) error {
+	p.published = append(p.published, c)
+	return nil
+}
+
+func TestRollsRevealTheCommittedSeed(t *testing.T) {
+	publisher := &recordingPublisher{}
+	s := NewService([]string{"boss"})
+	s.UsePublisher(publisher)
+	var notes []interface{}
+	s.Connect("alice", func(note interface{}) { notes = append(notes, note) })
+
+	if len(notes) != 1 || len(publisher.published) != 1 {
+		t.Fatalf("connecting sent %v and published %v", notes, publisher.published)
+	}
+	committed := notes[0].(*Commitment)
+	if committed.PlayerID != "alice" || committed.Hash != publisher.published[0].Hash {
+		t.Fatalf("commitment = %+v, published %+v", committed, publisher.published[0])
+	}
+
+	loot := balance.LootValues{DropRateMultiplier: 1, Tables: map[string][]balance.LootDrop{
+		"boss":    {{ItemID: "dragon_scale", Chance: 0.5, Min: 1, Max: 3}, {ItemID: "gold", Chance: 1, Min: 10, Max: 20}},
+		"default": {{ItemID: "health_potion", Chance: 1, Min: 1, Max: 1}},
+	}}
+	if _, ok := s.Roll("alice", "default", loot); ok {
+		t.Fatal("the default table was rolled with a commitment")
+	}
+	got, ok := s.Roll("alice", "boss", loot)
+	if !ok || got["gold"] < 10 {
+		t.Fatalf("Roll = %v, %v", got, ok)
+	}
+	if len(notes) != 3 {
+		t.Fatalf("rolling sent %d notes, want a reveal and the next commitment", len(notes))
+	}
+	reveal := notes[1].(*Reveal)
+	if reveal.CommitmentID != committed.ID || reveal.Hash != committed.Hash || reveal.Draws < 3 {
+		t.Fatalf("reveal = %+v for commitment %+v", reveal, committed)
+	}
+	if err := Verify(*reveal); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if next := notes[2].(*Commitment); next.ID == committed.ID || next.Hash == committed.Hash || len(publisher.published) != 2 {
+		t.Fatalf("next commitment = %+v", next)
+	}
+
+	// A tampered result, table or seed does not verify.
+	tampered := *reveal
+	tampered.Loot = map[string]int{"gold": 999}
+	if Verify(tampered) == nil {
+		t.Error("Verify accepted different loot")
+	}
+	tampered = *reveal
+	tampered.Drops = []balance.LootDrop{{ItemID: "gold", Chance: 1, Min: 999, Max: 999}}
+	if Verify(tampered) == nil {
+		t.Error("Verify accepted a different table")
+	}
+	other := sha256.Sum256([]byte("other"))
+	tampered = *reveal
+	tampered.Seed = hex.EncodeToString(other[:])
+	if Verify(tampered) == nil {
+		t.Error("Verify accepted a seed that does not match the commitment")
+	}
+
+	// Players without a published commitment get nothing from the table.
+	s.Disconnect("alice")
+	for _, playerID := range []string{"alice", "npc-1"} {
+		if got, ok := s.Roll(playerID, "boss", loot); !ok || len(got) != 0 || len(publisher.published) != 2 {
+			t.Fatalf("rolling for %s without a commitment gave %v, %v and published %d commitments", playerID, got, ok, len(publisher.published))
+		}
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("seeds are still held for %d players without sessions", len(s.pending))
+	}
+}
+
+func TestSeededRNGIsDeterministic(t *testing.T) {
+	a, b := &SeededRNG{Seed: []byte("seed")}, &SeededRNG{Seed: []byte("seed")}
+	for i := 0; i < 100; i++ {
+		x, y := a.Float64(), b.Float64()
+		if x != y || x < 0 || x >= 1 {
+			t.Fatalf("draw %d: %v and %v", i, x, y)
+		}
+	}
+	if (&SeededRNG{Seed: []byte("other")}).Float64() == (&SeededRNG{Seed: []byte("seed")}).Float64() {
+		t.Error("different seeds gave the same first draw")
+	}
+}
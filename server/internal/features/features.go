@@ -0,0 +1,284 @@
+// Package features holds the feature flags that gate risky or
+// environment-specific subsystems. Flags start from configuration; admins can
+// override runtime flags while the server runs, and subsystems react through
+// change hooks. Overrides are persisted so they survive a restart.
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Flag names.
+const (
+	Marketplace        = "marketplace"        // Marketplace listings API backed by the marketplace contract
+	OnChainCombat      = "onchainCombat"      // Combat outcomes recorded on-chain by the combat engine
+	EscrowTrades       = "escrowTrades"       // New trades above the escrow threshold
+	Clustering         = "clustering"         // Multi-node clustering of the actor system
+	WebsocketTransport = "websocketTransport" // WebSocket listener next to the TCP one
+	ActorMessageAudit  = "actorMessageAudit"  // Debug checks of every message sent between actors
+)
+
+var (
+	ErrUnknownFlag     = errors.New("unknown feature flag")
+	ErrRestartRequired = errors.New("this flag cannot change while the server runs; set it in the config and restart")
+)
+
+// Flag describes a feature flag.
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Runtime     bool   `json:"runtime"`   // Can be overridden while running
+	Available   bool   `json:"available"` // Implemented in this build; unavailable flags stay off
+}
+
+// Known lists every flag the server understands.
+var Known = []Flag{
+	{Name: Marketplace, Description: "Marketplace listings API", Default: false, Runtime: true, Available: true},
+	{Name: OnChainCombat, Description: "Record combat outcomes on-chain", Default: false, Runtime: true, Available: true},
+	{Name: EscrowTrades, Description: "Accept new escrowed trades", Default: true, Runtime: true, Available: true},
+	{Name: Clustering, Description: "Multi-node actor clustering", Default: false, Runtime: false, Available: false},
+	{Name: WebsocketTransport, Description: "WebSocket client transport", Default: false, Runtime: false, Available: false},
+	{Name: ActorMessageAudit, Description: "Audit messages sent between actors", Default: false, Runtime: true, Available: true},
+}
+
+// Override is an admin's runtime setting of a flag.
+type Override struct {
+	Enabled bool      `json:"enabled"`
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+}
+
+// State is a flag's current value and where it comes from.
+type State struct {
+	Flag
+	Configured bool      `json:"configured"` // Value from the config, or the default
+	Override   *Override `json:"override,omitempty"`
+	Enabled    bool      `json:"enabled"`
+}
+
+// Store persists overrides.
+type Store interface {
+	LoadOverrides() (map[string]Override, error)
+	SaveOverrides(map[string]Override) error
+}
+
+// MemoryStore keeps overrides in memory.
+type MemoryStore struct {
+	mu        sync.Mutex
+	overrides map[string]Override
+}
+
+// LoadOverrides implements Store.
+func (m *MemoryStore) LoadOverrides() (map[string]Override, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copyOverrides(m.overrides), nil
+}
+
+// SaveOverrides implements Store.
+func (m *MemoryStore) SaveOverrides(overrides map[string]Override) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides = copyOverrides(overrides)
+	return nil
+}
+
+// FileStore keeps overrides in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+// LoadOverrides implements Store. A missing file means no overrides.
+func (f FileStore) LoadOverrides() (map[string]Override, error) {
+	overrides := make(map[string]Override)
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return overrides, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &overrides)
+	return overrides, err
+}
+
+// SaveOverrides implements Store.
+func (f FileStore) SaveOverrides(overrides map[string]Override) error {
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Registry answers whether a feature is on. It is safe for concurrent use.
+type Registry struct {
+	store Store
+	now   func() time.Time
+
+	mu         sync.Mutex
+	flags      map[string]Flag
+	configured map[string]bool
+	overrides  map[string]Override
+	hooks      map[string][]func(enabled bool)
+}
+
+// New creates a Registry from the configured flag values, applying the
+// overrides in store. Unknown names in configured are logged and ignored.
+func New(configured map[string]bool, store Store) (*Registry, error) {
+	overrides, err := store.LoadOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("loading feature overrides: %w", err)
+	}
+	r := &Registry{
+		store:      store,
+		now:        time.Now,
+		flags:      make(map[string]Flag),
+		configured: make(map[string]bool),
+		overrides:  make(map[string]Override),
+		hooks:      make(map[string][]func(bool)),
+	}
+	for _, flag := range Known {
+		r.flags[flag.Name] = flag
+		r.configured[flag.Name] = flag.Default
+	}
+	for name, enabled := range configured {
+		if _, ok := r.flags[name]; !ok {
+			utils.LogWarnf("Features: Ignoring unknown flag %q in the config.", name)
+			continue
+		}
+		r.configured[name] = enabled
+	}
+	for name, override := range overrides {
+		if flag, ok := r.flags[name]; ok && flag.Runtime {
+			r.overrides[name] = override
+		}
+	}
+	return r, nil
+}
+
+// Enabled reports whether the feature name is on.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enabledLocked(name)
+}
+
+// Known reports whether name is a flag the server understands.
+func (r *Registry) Known(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.flags[name]
+	return ok
+}
+
+// OnChange registers a hook called with the new value whenever an override
+// turns name on or off. Hooks run outside the registry lock, in the order added.
+func (r *Registry) OnChange(name string, hook func(enabled bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[name] = append(r.hooks[name], hook)
+}
+
+// Set overrides a runtime flag.
+func (r *Registry) Set(name string, enabled bool, by string) (State, error) {
+	return r.change(name, by, &Override{Enabled: enabled, By: by, At: r.now().UTC()})
+}
+
+// Clear removes a flag's override, returning it to the configured value.
+func (r *Registry) Clear(name, by string) (State, error) {
+	return r.change(name, by, nil)
+}
+
+// List returns every flag's state, sorted by name.
+func (r *Registry) List() []State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]State, 0, len(r.flags))
+	for name := range r.flags {
+		states = append(states, r.stateLocked(name))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+func (r *Registry) change(name, by string, override *Override) (State, error) {
+	r.mu.Lock()
+	flag, ok := r.flags[name]
+	if !ok {
+		r.mu.Unlock()
+		return State{}, ErrUnknownFlag
+	}
+	if !flag.Runtime {
+		r.mu.Unlock()
+		return State{}, ErrRestartRequired
+	}
+	before := r.enabledLocked(name)
+	previous, hadOverride := r.overrides[name]
+	if override != nil {
+		r.overrides[name] = *override
+	} else {
+		delete(r.overrides, name)
+	}
+	if err := r.store.SaveOverrides(r.overrides); err != nil {
+		if hadOverride {
+			r.overrides[name] = previous
+		} else {
+			delete(r.overrides, name)
+		}
+		r.mu.Unlock()
+		return State{}, fmt.Errorf("saving feature overrides: %w", err)
+	}
+	state := r.stateLocked(name)
+	var hooks []func(bool)
+	if state.Enabled != before {
+		hooks = append(hooks, r.hooks[name]...)
+	}
+	r.mu.Unlock()
+
+	utils.LogInfof("Features: %s set %s to %v.", by, name, state.Enabled)
+	for _, hook := range hooks {
+		hook(state.Enabled)
+	}
+	return state, nil
+}
+
+func (r *Registry) enabledLocked(name string) bool {
+	flag, ok := r.flags[name]
+	if !ok || !flag.Available {
+		return false
+	}
+	if override, ok := r.overrides[name]; ok {
+		return override.Enabled
+	}
+	return r.configured[name]
+}
+
+func (r *Registry) stateLocked(name string) State {
+	state := State{Flag: r.flags[name], Configured: r.configured[name], Enabled: r.enabledLocked(name)}
+	if override, ok := r.overrides[name]; ok {
+		state.Override = &override
+	}
+	return state
+}
+
+func copyOverrides(overrides map[string]Override) map[string]Override {
+	copied := make(map[string]Override, len(overrides))
+	for name, override := range overrides {
+		copied[name] = override
+	}
+	return copied
+}
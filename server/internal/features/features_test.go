@@ -0,0 +1,56 @@
+package features
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOverridesWinAndRunHooks(t *testing.T) {
+	store := &MemoryStore{}
+	r, err := New(map[string]bool{Marketplace: true}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Enabled(Marketplace) || !r.Enabled(EscrowTrades) || r.Enabled(OnChainCombat) {
+		t.Fatalf("initial flags = %+v", r.List())
+	}
+	var changes []bool
+	r.OnChange(Marketplace, func(enabled bool) { changes = append(changes, enabled) })
+
+	if _, err := r.Set(Marketplace, false, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Set(Marketplace, false, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Enabled(Marketplace) {
+		t.Fatal("override did not turn the marketplace off")
+	}
+	reloaded, _ := New(map[string]bool{Marketplace: true}, store)
+	if reloaded.Enabled(Marketplace) {
+		t.Fatal("override was not persisted")
+	}
+	state, err := r.Clear(Marketplace, "ops")
+	if err != nil || !state.Enabled || state.Override != nil {
+		t.Fatalf("clear = %+v, %v", state, err)
+	}
+	if len(changes) != 2 || changes[0] || !changes[1] {
+		t.Fatalf("hook calls = %v, want [false true]", changes)
+	}
+}
+
+func TestStartupOnlyAndUnavailableFlags(t *testing.T) {
+	r, err := New(map[string]bool{Clustering: true, "nope": true}, &MemoryStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Enabled(Clustering) {
+		t.Fatal("an unavailable flag is on")
+	}
+	if _, err := r.Set(WebsocketTransport, true, "ops"); !errors.Is(err, ErrRestartRequired) {
+		t.Fatalf("setting a startup-only flag: %v", err)
+	}
+	if _, err := r.Set("nope", true, "ops"); !errors.Is(err, ErrUnknownFlag) {
+		t.Fatalf("setting an unknown flag: %v", err)
+	}
+}
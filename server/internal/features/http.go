@@ -0,0 +1,81 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is recorded with every override.
+//
+//	GET  /admin/features         every flag with its configured value, override and effective value
+//	POST /admin/features/set     {"name": "marketplace", "enabled": true}
+//	POST /admin/features/clear   {"name": "marketplace"} returns the flag to its configured value
+func (r *Registry) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/features", adminhttp.Only(adminToken, func(w http.ResponseWriter, req *http.Request, operator string) {
+		if req.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"flags": r.List()})
+	}))
+	mux.HandleFunc("/admin/features/set", adminhttp.Only(adminToken, func(w http.ResponseWriter, req *http.Request, operator string) {
+		var body struct {
+			Name    string `json:"name"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if !decodePost(w, req, &body) {
+			return
+		}
+		if body.Enabled == nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("enabled is required"))
+			return
+		}
+		state, err := r.Set(body.Name, *body.Enabled, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, state)
+	}))
+	mux.HandleFunc("/admin/features/clear", adminhttp.Only(adminToken, func(w http.ResponseWriter, req *http.Request, operator string) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if !decodePost(w, req, &body) {
+			return
+		}
+		state, err := r.Clear(body.Name, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, state)
+	}))
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return false
+	}
+	return true
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownFlag):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRestartRequired):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
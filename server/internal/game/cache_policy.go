@@ -0,0 +1,346 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCacheUnavailable is returned (wrapped) when Redis is down and the cache
+// use case's policy is CacheFail.
+var ErrCacheUnavailable = errors.New("cache unavailable")
+
+// Cache use cases, each with its own fallback policy.
+const (
+	CachePlayerData  = "playerData"  // Cached player records
+	CachePlayerNames = "playerNames" // Display name index, used to keep names unique
+)
+
+// CachePolicy is what a cache use case does while Redis is down.
+type CachePolicy string
+
+const (
+	// CacheBypass treats Redis as a miss and goes to the database. Writes
+	// are replayed once Redis is back.
+	CacheBypass CachePolicy = "bypass"
+	// CacheServeStale serves the last value this server read or wrote, and
+	// goes to the database for anything it has not seen.
+	CacheServeStale CachePolicy = "stale"
+	// CacheFail returns ErrCacheUnavailable.
+	CacheFail CachePolicy = "fail"
+)
+
+// Cache fallback defaults.
+const (
+	DefaultBreakerFailures = 5
+	DefaultBreakerCooldown = 10 * time.Second
+	DefaultStaleEntries    = 10000
+)
+
+// defaultCachePolicies apply to use cases without a configured policy. The
+// name index lives only in Redis, so guessing a name is free could hand it
+// out twice.
+var defaultCachePolicies = map[string]CachePolicy{
+	CachePlayerData:  CacheBypass,
+	CachePlayerNames: CacheFail,
+}
+
+// CacheOptions configures how the cache behaves while Redis is down.
+type CacheOptions struct {
+	Policies        map[string]CachePolicy // By use case; missing use cases keep their default
+	BreakerFailures int                    // Consecutive Redis errors that open the breaker
+	BreakerCooldown time.Duration          // How long Redis is skipped before it is tried again
+	StaleEntries    int                    // Values kept per use case for CacheServeStale, and writes kept for replay
+}
+
+// ParseCachePolicy checks a policy name from the configuration.
+func ParseCachePolicy(name string) (CachePolicy, error) {
+	switch policy := CachePolicy(name); policy {
+	case CacheBypass, CacheServeStale, CacheFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown cache fallback policy %q (want bypass, stale or fail)", name)
+}
+
+// pendingWrite is a cache write to replay once Redis is back. A nil value
+// deletes the key.
+type pendingWrite struct {
+	value *string
+	ttl   time.Duration
+}
+
+// cacheGuard is a circuit breaker around Redis, with the stale copies and
+// pending writes of the fallback policies.
+type cacheGuard struct {
+	mu        sync.Mutex
+	opts      CacheOptions
+	failures  int                          // Consecutive Redis errors
+	openUntil time.Time                    // Redis is skipped until then; zero while closed
+	probing   bool                         // A call is testing whether Redis is back
+	stale     map[string]map[string]string // Use case -> key -> last value seen
+	pending   map[string]pendingWrite      // Key -> write to replay
+	dropped   int                          // Writes not kept for replay since Redis went down
+}
+
+func newCacheGuard() *cacheGuard {
+	return &cacheGuard{
+		opts: CacheOptions{
+			BreakerFailures: DefaultBreakerFailures,
+			BreakerCooldown: DefaultBreakerCooldown,
+			StaleEntries:    DefaultStaleEntries,
+		},
+		stale:   make(map[string]map[string]string),
+		pending: make(map[string]pendingWrite),
+	}
+}
+
+// UseCacheOptions sets the fallback policies and the breaker. Zero values
+// keep the defaults.
+func (dbcl *DBCacheLayer) UseCacheOptions(opts CacheOptions) {
+	g := dbcl.cache
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if opts.BreakerFailures <= 0 {
+		opts.BreakerFailures = DefaultBreakerFailures
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if opts.StaleEntries <= 0 {
+		opts.StaleEntries = DefaultStaleEntries
+	}
+	g.opts = opts
+}
+
+// policyFor returns the policy of useCase.
+func (g *cacheGuard) policyFor(useCase string) CachePolicy {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.policy(useCase)
+}
+
+// policy is policyFor with g.mu held.
+func (g *cacheGuard) policy(useCase string) CachePolicy {
+	if policy, ok := g.opts.Policies[useCase]; ok {
+		return policy
+	}
+	if policy, ok := defaultCachePolicies[useCase]; ok {
+		return policy
+	}
+	return CacheBypass
+}
+
+// allow reports whether a Redis call may be made. While the breaker is open
+// it lets one call through per cooldown to see whether Redis is back.
+func (g *cacheGuard) allow(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(g.openUntil) || g.probing {
+		return false
+	}
+	g.probing = true
+	return true
+}
+
+// done records the outcome of a Redis call. It returns the pending writes to
+// replay when the call closed the breaker.
+func (g *cacheGuard) done(err error, now time.Time) map[string]pendingWrite {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.probing = false
+	if err != nil && err != redis.Nil {
+		g.failures++
+		if g.failures >= g.opts.BreakerFailures {
+			if g.openUntil.IsZero() {
+				log.Printf("Redis failed %d times in a row (%v). Skipping it for %s.", g.failures, err, g.opts.BreakerCooldown)
+			}
+			g.openUntil = now.Add(g.opts.BreakerCooldown)
+		}
+		return nil
+	}
+	g.failures = 0
+	if g.openUntil.IsZero() {
+		return nil
+	}
+	g.openUntil = time.Time{}
+	replay := g.pending
+	g.pending = make(map[string]pendingWrite)
+	if g.dropped > 0 {
+		log.Printf("Redis is back. %d cache writes were not kept while it was down; those entries may be stale until they expire.", g.dropped)
+		g.dropped = 0
+	} else {
+		log.Println("Redis is back.")
+	}
+	return replay
+}
+
+// remember keeps value as the stale copy of key, if the use case serves stale values.
+func (g *cacheGuard) remember(useCase, key, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.policy(useCase) != CacheServeStale {
+		return
+	}
+	values := g.stale[useCase]
+	if values == nil {
+		values = make(map[string]string)
+		g.stale[useCase] = values
+	}
+	if _, ok := values[key]; !ok && len(values) >= g.opts.StaleEntries {
+		for evict := range values { // Any entry; the copy is best effort
+			delete(values, evict)
+			break
+		}
+	}
+	values[key] = value
+}
+
+func (g *cacheGuard) staleValue(useCase, key string) (string, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	value, ok := g.stale[useCase][key]
+	return value, ok
+}
+
+func (g *cacheGuard) forget(useCase, key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.stale[useCase], key)
+}
+
+// deferWrite keeps a write for replay, up to StaleEntries writes.
+func (g *cacheGuard) deferWrite(key string, write pendingWrite) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.pending[key]; !ok && len(g.pending) >= g.opts.StaleEntries {
+		g.dropped++
+		return
+	}
+	g.pending[key] = write
+}
+
+// cacheGet reads key for useCase. A miss, or an outage under CacheBypass, is
+// reported as redis.Nil so the caller goes to the database.
+func (dbcl *DBCacheLayer) cacheGet(ctx context.Context, useCase, key string) (string, error) {
+	g := dbcl.cache
+	now := time.Now()
+	err := errCacheSkipped
+	var value string
+	if g.allow(now) {
+		value, err = dbcl.redisClient.Get(ctx, key).Result()
+		dbcl.replay(ctx, g.done(err, now))
+		if err == nil {
+			g.remember(useCase, key, value)
+			return value, nil
+		}
+		if err == redis.Nil {
+			return "", redis.Nil
+		}
+	}
+	switch g.policyFor(useCase) {
+	case CacheFail:
+		return "", fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	case CacheServeStale:
+		if value, ok := g.staleValue(useCase, key); ok {
+			return value, nil
+		}
+	}
+	return "", redis.Nil
+}
+
+// cacheSet writes key for useCase. A write Redis cannot take is replayed
+// when it is back; only CacheFail reports it.
+func (dbcl *DBCacheLayer) cacheSet(ctx context.Context, useCase, key, value string, ttl time.Duration) error {
+	g := dbcl.cache
+	g.remember(useCase, key, value)
+	return dbcl.cacheWrite(ctx, useCase, key, pendingWrite{value: &value, ttl: ttl})
+}
+
+// cacheDel removes key for useCase, like cacheSet.
+func (dbcl *DBCacheLayer) cacheDel(ctx context.Context, useCase, key string) error {
+	dbcl.cache.forget(useCase, key)
+	return dbcl.cacheWrite(ctx, useCase, key, pendingWrite{})
+}
+
+func (dbcl *DBCacheLayer) cacheWrite(ctx context.Context, useCase, key string, write pendingWrite) error {
+	g := dbcl.cache
+	now := time.Now()
+	err := errCacheSkipped
+	if g.allow(now) {
+		err = dbcl.apply(ctx, key, write)
+		dbcl.replay(ctx, g.done(err, now))
+		if err == nil {
+			return nil
+		}
+	}
+	g.deferWrite(key, write)
+	if g.policyFor(useCase) == CacheFail {
+		return fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+	return nil
+}
+
+func (dbcl *DBCacheLayer) apply(ctx context.Context, key string, write pendingWrite) error {
+	if write.value == nil {
+		return dbcl.redisClient.Del(ctx, key).Err()
+	}
+	return dbcl.redisClient.Set(ctx, key, *write.value, write.ttl).Err()
+}
+
+// replay sends the writes made while Redis was down.
+func (dbcl *DBCacheLayer) replay(ctx context.Context, writes map[string]pendingWrite) {
+	if len(writes) == 0 {
+		return
+	}
+	failed := 0
+	for key, write := range writes {
+		if err := dbcl.apply(ctx, key, write); err != nil {
+			failed++
+			dbcl.cache.deferWrite(key, write)
+		}
+	}
+	log.Printf("Replayed %d cache writes made while Redis was down (%d failed).", len(writes), failed)
+}
+
+// errCacheSkipped stands for the Redis error while the breaker is open.
+var errCacheSkipped = errors.New("redis skipped while its circuit breaker is open")
+
+// CacheStats describe the Redis circuit breaker.
+type CacheStats struct {
+	BreakerOpen   bool                   `json:"breakerOpen"`
+	Failures      int                    `json:"failures"` // Consecutive Redis errors
+	PendingWrites int                    `json:"pendingWrites"`
+	StaleEntries  map[string]int         `json:"staleEntries,omitempty"` // By use case
+	Policies      map[string]CachePolicy `json:"policies"`
+}
+
+// CacheStats returns the state of the Redis circuit breaker.
+func (dbcl *DBCacheLayer) CacheStats() CacheStats {
+	g := dbcl.cache
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stats := CacheStats{
+		BreakerOpen:   !g.openUntil.IsZero(),
+		Failures:      g.failures,
+		PendingWrites: len(g.pending),
+		Policies:      make(map[string]CachePolicy),
+	}
+	for useCase := range defaultCachePolicies {
+		stats.Policies[useCase] = g.policy(useCase)
+	}
+	for useCase, values := range g.stale {
+		if stats.StaleEntries == nil {
+			stats.StaleEntries = make(map[string]int)
+		}
+		stats.StaleEntries[useCase] = len(values)
+	}
+	return stats
+}
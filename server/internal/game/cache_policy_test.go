@@ -0,0 +1,162 @@
+package game
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis answers GET, SET and DEL over RESP, enough for the cache layer.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	f := &fakeRedis{values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var n int
+		if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+			return
+		}
+		args := make([]string, n)
+		for i := range args {
+			var size int
+			if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+				return
+			}
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		f.mu.Lock()
+		switch strings.ToLower(args[0]) {
+		case "get":
+			if value, ok := f.values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "set":
+			f.values[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		case "del":
+			delete(f.values, args[1])
+			fmt.Fprint(conn, ":1\r\n")
+		default:
+			fmt.Fprint(conn, "+PONG\r\n")
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeRedis) get(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.values[key]
+}
+
+func TestCacheFallbackPolicies(t *testing.T) {
+	ctx := context.Background()
+	dbcl, err := NewDBCacheLayerFromURL("postgresql://user@localhost:1/primary", RedisConfig{Addr: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbcl.Stop()
+	dbcl.UseCacheOptions(CacheOptions{
+		Policies:        map[string]CachePolicy{"stale": CacheServeStale},
+		BreakerFailures: 2,
+		BreakerCooldown: time.Millisecond,
+	})
+
+	// Redis is down: writes are kept, and reads follow each use case's policy.
+	if err := dbcl.cacheSet(ctx, "stale", "k1", "v1", time.Hour); err != nil {
+		t.Fatalf("stale write: %v", err)
+	}
+	if err := dbcl.cacheSet(ctx, CachePlayerData, "k2", "v2", time.Hour); err != nil {
+		t.Fatalf("bypass write: %v", err)
+	}
+	if !dbcl.CacheStats().BreakerOpen {
+		t.Fatal("breaker still closed after two failures")
+	}
+	if value, err := dbcl.cacheGet(ctx, "stale", "k1"); err != nil || value != "v1" {
+		t.Fatalf("stale read = %q, %v", value, err)
+	}
+	if _, err := dbcl.cacheGet(ctx, CachePlayerData, "k2"); err != redis.Nil {
+		t.Fatalf("bypass read: err = %v, want a miss", err)
+	}
+	if _, err := dbcl.cacheGet(ctx, CachePlayerNames, "k3"); !errors.Is(err, ErrCacheUnavailable) {
+		t.Fatalf("fail read: err = %v", err)
+	}
+	if err := dbcl.cacheSet(ctx, CachePlayerNames, "k3", "v3", 0); !errors.Is(err, ErrCacheUnavailable) {
+		t.Fatalf("fail write: err = %v", err)
+	}
+
+	// Redis is back: the first call after the cooldown closes the breaker and
+	// replays the writes.
+	server, addr := startFakeRedis(t)
+	dbcl.redisClient.Close()
+	dbcl.redisClient = redis.NewClient(&redis.Options{Addr: addr})
+	time.Sleep(5 * time.Millisecond)
+	if _, err := dbcl.cacheGet(ctx, CachePlayerData, "missing"); err != redis.Nil {
+		t.Fatalf("read after recovery: err = %v", err)
+	}
+	if stats := dbcl.CacheStats(); stats.BreakerOpen || stats.PendingWrites != 0 {
+		t.Fatalf("stats after recovery = %+v", stats)
+	}
+	for key, want := range map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"} {
+		if got := server.get(key); got != want {
+			t.Errorf("%s = %q after replay, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRedisModes(t *testing.T) {
+	for _, cfg := range []RedisConfig{
+		{Mode: RedisSentinel, Addrs: []string{"127.0.0.1:26379"}},
+		{Mode: RedisCluster},
+		{Mode: RedisCluster, Addrs: []string{"127.0.0.1:7000"}, DB: 1},
+		{Mode: "replicated"},
+	} {
+		if _, err := newRedisClient(cfg); err == nil {
+			t.Errorf("%+v: no error", cfg)
+		}
+	}
+	client, err := newRedisClient(RedisConfig{Mode: RedisSentinel, MasterName: "mymaster", Addrs: []string{"127.0.0.1:26379"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+}
@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
 	"github.com/phuhao00/suigserver/server/internal/sui" // For interacting with Sui blockchain
 )
 
@@ -32,11 +34,14 @@ type CombatResult struct {
 	IsEvaded           bool
 	CombatLog          []string // Log of events during this combat turn/round
 	IsDefenderDefeated bool
+	XPAwarded          int            // Experience for the attacker when the defender is defeated
+	Loot               map[string]int // Items dropped by a defeated defender, ItemID -> quantity
 }
 
 // CombatEngine handles all combat calculations and logic.
 type CombatEngine struct {
 	suiCombatService *sui.CombatResultsSuiService // For recording combat results on-chain
+	eventBus         *events.Bus                  // Receives combat.finished; nil disables publishing
 	// dbCache *DBCacheLayer    // For fetching/updating combatant stats if not passed directly
 	baseHitChance       float64
 	baseCritChance      float64
@@ -47,6 +52,30 @@ type CombatEngine struct {
 	skillDefinitions  map[string]interface{} // Placeholder for skill data
 	statusEffectRules map[string]interface{} // Placeholder for status effect rules
 	elementalChart    map[string]interface{} // Placeholder for elemental advantages
+	damageModel       string                 // Default DamageModel name
+	modeDamageModels  map[string]string      // Game mode -> DamageModel name
+	balance           func() balance.Values  // Live tunables; when set, they replace the fields above
+	recordOnChain     func() bool            // Gate for on-chain recording; nil means always record
+	lootRoller        LootRoller             // Rolls the tables it takes instead of the engine; nil leaves all to the engine
+}
+
+// LootRoller rolls loot tables on the engine's behalf, such as a
+// fairroll.Service for high-stakes tables. Roll returns false for tables it
+// leaves to the engine.
+type LootRoller interface {
+	Roll(playerID, table string, loot balance.LootValues) (map[string]int, bool)
+}
+
+// TurnOptions select the damage model and add context to a combat turn.
+type TurnOptions struct {
+	Mode            string // Game mode, mapped to a model by CombatEngineConfig.ModeDamageModels
+	DamageModel     string // Explicit model, e.g. a room's setting; overrides Mode
+	Skill           string
+	AttackElement   string
+	DefenderElement string
+	LootTable       string  // Rolled when the defender is defeated; "default" if empty
+	XPMultiplier    float64 // Scales the kill XP, e.g. by a room's rules; 1 if 0
+	NoLoot          bool    // A defeat drops nothing
 }
 
 // NewCombatEngine creates a new CombatEngine.
@@ -61,7 +90,113 @@ func NewCombatEngine(suiCombatService *sui.CombatResultsSuiService /*, dbCache *
 		baseEvadeChance:     0.05, // 5% base chance to evade
 		critDamageBonus:     1.5,
 		minDamagePercentage: 0.1, // Ensure at least 10% of attack power as damage if hit
+		damageModel:         DefaultDamageModel,
+	}
+}
+
+// SetEventBus makes the engine publish combat.finished events to bus.
+func (ce *CombatEngine) SetEventBus(bus *events.Bus) {
+	ce.eventBus = bus
+}
+
+// UseBalance makes the engine read its combat constants, kill XP and loot
+// tables from values (usually balance.Service.Values) on every turn, so that
+// balance changes apply without a restart.
+func (ce *CombatEngine) UseBalance(values func() balance.Values) {
+	ce.balance = values
+}
+
+// RecordOnChainWhen makes on-chain recording of combat outcomes depend on
+// enabled (usually a feature flag), checked on every defeat so that it can be
+// switched off while the server runs.
+func (ce *CombatEngine) RecordOnChainWhen(enabled func() bool) {
+	ce.recordOnChain = enabled
+}
+
+// RollLootWith makes roller roll the loot of defeats first; the engine rolls
+// the tables roller does not take.
+func (ce *CombatEngine) RollLootWith(roller LootRoller) {
+	ce.lootRoller = roller
+}
+
+// combatSettings are the tunables in effect for one turn.
+type combatSettings struct {
+	params           DamageParams
+	damageModel      string
+	modeDamageModels map[string]string
+}
+
+func (ce *CombatEngine) settings() combatSettings {
+	if ce.balance == nil {
+		return combatSettings{
+			params: DamageParams{
+				HitChance:         ce.baseHitChance,
+				CritChance:        ce.baseCritChance,
+				EvadeChance:       ce.baseEvadeChance,
+				CritBonus:         ce.critDamageBonus,
+				MinDamageFraction: ce.minDamagePercentage,
+				ElementalChart:    ce.elementalChart,
+			},
+			damageModel:      ce.damageModel,
+			modeDamageModels: ce.modeDamageModels,
+		}
+	}
+	combat := ce.balance().Combat
+	return combatSettings{
+		params: DamageParams{
+			HitChance:         combat.HitChance,
+			CritChance:        combat.CritChance,
+			EvadeChance:       combat.EvadeChance,
+			CritBonus:         combat.CritBonus,
+			MinDamageFraction: combat.MinDamageFraction,
+			ElementalChart:    ce.elementalChart,
+		},
+		damageModel:      combat.DamageModel,
+		modeDamageModels: combat.ModeDamageModels,
+	}
+}
+
+// rewards returns the kill XP, scaled by opts, and a roll of the loot table
+// for attackerID unless opts rule out loot. Without balance values, a kill is
+// worth 100 XP and drops nothing.
+func (ce *CombatEngine) rewards(attackerID string, opts TurnOptions) (int, map[string]int) {
+	xp, loot := 100, map[string]int(nil)
+	if ce.balance != nil {
+		lootTable := opts.LootTable
+		if lootTable == "" {
+			lootTable = "default"
+		}
+		values := ce.balance()
+		xp = values.XP.KillXP
+		if !opts.NoLoot {
+			rolled := false
+			if ce.lootRoller != nil {
+				loot, rolled = ce.lootRoller.Roll(attackerID, lootTable, values.Loot)
+			}
+			if !rolled {
+				loot = values.Loot.Roll(lootTable, globalRNG{})
+			}
+		}
+	}
+	if opts.XPMultiplier > 0 {
+		xp = int(float64(xp) * opts.XPMultiplier)
+	}
+	return xp, loot
+}
+
+// ValidateBalance rejects balance values that name unregistered damage models.
+// It is meant as a balance.Validator.
+func ValidateBalance(values balance.Values) error {
+	names := []string{values.Combat.DamageModel}
+	for _, name := range values.Combat.ModeDamageModels {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if _, ok := LookupDamageModel(name); name != "" && !ok {
+			return fmt.Errorf("unknown damage model %q (registered: %v)", name, DamageModelNames())
+		}
 	}
+	return nil
 }
 
 // Start begins the combat engine operations.
@@ -97,12 +232,25 @@ func (ce *CombatEngine) Start(config *CombatEngineConfig) { // Assuming a config
 		if config.MinDamagePercentage > 0 {
 			ce.minDamagePercentage = config.MinDamagePercentage
 		}
+		if config.DamageModel != "" {
+			if _, ok := LookupDamageModel(config.DamageModel); ok {
+				ce.damageModel = config.DamageModel
+			} else {
+				log.Printf("Unknown damage model %q; using %q. Registered models: %v", config.DamageModel, ce.damageModel, DamageModelNames())
+			}
+		}
+		ce.modeDamageModels = config.ModeDamageModels
+		for mode, name := range config.ModeDamageModels {
+			if _, ok := LookupDamageModel(name); !ok {
+				log.Printf("Game mode %q uses unknown damage model %q; it will fall back to %q.", mode, name, ce.damageModel)
+			}
+		}
 	} else {
 		log.Println("Combat Engine started with default parameters (no config provided).")
 	}
 
-	log.Printf("Combat Parameters: HitChance=%.2f, CritChance=%.2f, EvadeChance=%.2f, CritBonus=%.2fx, MinDamageFactor=%.2f",
-		ce.baseHitChance, ce.baseCritChance, ce.baseEvadeChance, ce.critDamageBonus, ce.minDamagePercentage)
+	log.Printf("Combat Parameters: HitChance=%.2f, CritChance=%.2f, EvadeChance=%.2f, CritBonus=%.2fx, MinDamageFactor=%.2f, DamageModel=%s",
+		ce.baseHitChance, ce.baseCritChance, ce.baseEvadeChance, ce.critDamageBonus, ce.minDamagePercentage, ce.damageModel)
 	log.Println("Combat Engine started successfully.")
 }
 
@@ -117,6 +265,8 @@ type CombatEngineConfig struct {
 	BaseEvadeChance     float64                `json:"baseEvadeChance,omitempty"`
 	CritDamageBonus     float64                `json:"critDamageBonus,omitempty"`
 	MinDamagePercentage float64                `json:"minDamagePercentage,omitempty"`
+	DamageModel         string                 `json:"damageModel,omitempty"`      // Default model; "standard" if unset
+	ModeDamageModels    map[string]string      `json:"modeDamageModels,omitempty"` // Game mode -> model, e.g. {"arena": "mitigation"}
 }
 
 // Stop gracefully shuts down the combat engine.
@@ -124,11 +274,38 @@ func (ce *CombatEngine) Stop() {
 	log.Println("Combat Engine stopped.")
 }
 
-// SimulateCombatTurn simulates a single turn of combat between an attacker and a defender.
+// DamageModelFor returns the model for a turn: the explicit override if it is
+// registered, else the game mode's model, else the engine default.
+func (ce *CombatEngine) DamageModelFor(mode, override string) DamageModel {
+	return ce.damageModelFor(ce.settings(), mode, override)
+}
+
+func (ce *CombatEngine) damageModelFor(settings combatSettings, mode, override string) DamageModel {
+	for _, name := range []string{override, settings.modeDamageModels[mode], settings.damageModel} {
+		if name == "" {
+			continue
+		}
+		if model, ok := LookupDamageModel(name); ok {
+			return model
+		}
+	}
+	model, _ := LookupDamageModel(DefaultDamageModel)
+	return model
+}
+
+// SimulateCombatTurn simulates a single turn of combat between an attacker and a defender
+// with the engine's default damage model.
 // In a real game, you'd fetch full stats for attacker and defender.
 // For now, we'll pass simplified stats.
 func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *CombatResult {
-	log.Printf("Simulating combat turn: Attacker %s vs Defender %s", attacker.ID, defender.ID)
+	return ce.SimulateCombatTurnWith(attacker, defender, TurnOptions{})
+}
+
+// SimulateCombatTurnWith simulates a combat turn using the damage model selected by opts.
+func (ce *CombatEngine) SimulateCombatTurnWith(attacker, defender CombatantStats, opts TurnOptions) *CombatResult {
+	settings := ce.settings()
+	model := ce.damageModelFor(settings, opts.Mode, opts.DamageModel)
+	log.Printf("Simulating combat turn: Attacker %s vs Defender %s (damage model %s)", attacker.ID, defender.ID, model.Name())
 	result := &CombatResult{
 		AttackerID:     attacker.ID,
 		DefenderID:     defender.ID,
@@ -139,48 +316,32 @@ func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *C
 
 	result.CombatLog = append(result.CombatLog, time.Now().Format(time.RFC3339)+": "+attacker.ID+" prepares to attack "+defender.ID+".")
 
-	// 1. Check for evasion
-	if rand.Float64() < ce.baseEvadeChance { // Simplified evasion check
+	outcome := model.Compute(DamageInput{
+		Attacker:        attacker,
+		Defender:        defender,
+		Skill:           opts.Skill,
+		AttackElement:   opts.AttackElement,
+		DefenderElement: opts.DefenderElement,
+		Params:          settings.params,
+		RNG:             globalRNG{},
+	})
+	if outcome.Evaded {
 		result.IsEvaded = true
-		result.DamageDealt = 0
 		result.CombatLog = append(result.CombatLog, defender.ID+" evades the attack!")
 		log.Printf("Combat: %s evades %s's attack.", defender.ID, attacker.ID)
 		return result
 	}
-
-	// 2. Check for hit (using baseHitChance, can be modified by stats like accuracy/evasion)
-	if rand.Float64() > ce.baseHitChance {
-		result.DamageDealt = 0
+	if outcome.Missed {
 		result.CombatLog = append(result.CombatLog, attacker.ID+" misses "+defender.ID+".")
 		log.Printf("Combat: %s misses %s.", attacker.ID, defender.ID)
 		return result
 	}
-
-	// 3. Calculate base damage
-	// Simplified: damage = attacker's attack power - defender's defense
-	// Ensure damage is not negative and respects minDamagePercentage
-	baseDamage := attacker.AttackPower - defender.Defense
-	minDamage := int(float64(attacker.AttackPower) * ce.minDamagePercentage)
-	if minDamage < 1 {
-		minDamage = 1 // Always at least 1 damage if hit and not fully mitigated by minDamagePercentage rule.
-	}
-
-	if baseDamage < minDamage {
-		baseDamage = minDamage
-	}
-	if baseDamage <= 0 && minDamage <= 0 { // If defense is extremely high and minDamage is 0 or less
-		baseDamage = 0 // No damage dealt, but it was a hit.
-		result.CombatLog = append(result.CombatLog, attacker.ID+" hits "+defender.ID+" but deals no damage (fully mitigated).")
-	}
-
-	// 4. Check for critical hit
-	actualDamage := baseDamage
-	if rand.Float64() < ce.baseCritChance { // Simplified crit check
-		result.IsCriticalHit = true
-		actualDamage = int(float64(baseDamage) * ce.critDamageBonus)
-		result.CombatLog = append(result.CombatLog, "Critical Hit!")
+	result.CombatLog = append(result.CombatLog, outcome.Log...)
+	result.IsCriticalHit = outcome.Critical
+	if outcome.Critical {
 		log.Printf("Combat: %s lands a CRITICAL HIT on %s.", attacker.ID, defender.ID)
 	}
+	actualDamage := outcome.Damage
 
 	result.DamageDealt = actualDamage
 	result.DefenderHealth -= actualDamage
@@ -193,6 +354,7 @@ func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *C
 	result.CombatLog = append(result.CombatLog, fmt.Sprintf("%s's health is now %d/%d.", defender.ID, result.DefenderHealth, defender.MaxHealth))
 
 	if result.IsDefenderDefeated {
+		result.XPAwarded, result.Loot = ce.rewards(attacker.ID, opts)
 		result.CombatLog = append(result.CombatLog, defender.ID+" has been defeated!")
 		log.Printf("Combat: %s has defeated %s.", attacker.ID, defender.ID)
 	}
@@ -200,8 +362,16 @@ func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *C
 	log.Printf("Combat turn result for %s vs %s: Damage: %d, Defender HP: %d. Log: %v",
 		attacker.ID, defender.ID, result.DamageDealt, result.DefenderHealth, result.CombatLog)
 
+	if result.IsDefenderDefeated {
+		ce.eventBus.Publish(events.TopicCombatFinished, events.CombatFinished{
+			WinnerID: result.AttackerID,
+			LoserID:  result.DefenderID,
+			Damage:   result.DamageDealt,
+		})
+	}
+
 	// Record combat results on Sui blockchain if service is available
-	if ce.suiCombatService != nil && result.IsDefenderDefeated { // Example: Record only if someone is defeated
+	if ce.suiCombatService != nil && result.IsDefenderDefeated && (ce.recordOnChain == nil || ce.recordOnChain()) { // Example: Record only if someone is defeated
 		go func(combatOutcome CombatResult) {
 			// Prepare data for Sui. This needs to match CombatResultData in sui package
 			// and the expected arguments of the Move contract.
@@ -209,7 +379,7 @@ func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *C
 				CombatLogID:   fmt.Sprintf("%s_vs_%s_%d", combatOutcome.AttackerID, combatOutcome.DefenderID, time.Now().UnixNano()), // Generate a unique ID
 				WinnerAddress: combatOutcome.AttackerID,                                                                              // Assuming attacker wins if defender is defeated
 				LoserAddress:  combatOutcome.DefenderID,
-				Rewards:       map[string]interface{}{"xp_gained": 100, "items_dropped": "none"}, // Placeholder rewards
+				Rewards:       map[string]interface{}{"xp_gained": combatOutcome.XPAwarded, "items_dropped": combatOutcome.Loot},
 				AdditionalData: map[string]interface{}{
 					"damage_dealt":       combatOutcome.DamageDealt,
 					"final_health_c1":    combatOutcome.AttackerHealth, // This might be the attacker's health before this turn
@@ -229,8 +399,9 @@ func (ce *CombatEngine) SimulateCombatTurn(attacker, defender CombatantStats) *C
 				log.Printf("Error PREPARING transaction for combat result on Sui (%s vs %s): %v",
 					combatOutcome.AttackerID, combatOutcome.DefenderID, err)
 			} else {
-				log.Printf("Transaction for combat result (%s vs %s) PREPARED. TxBytes: %s. Digest (if any from prep): %s",
-					combatOutcome.AttackerID, combatOutcome.DefenderID, txBlockResponse.TxBytes, txBlockResponse.Digest)
+				// The digest is only known once the transaction has been executed.
+				log.Printf("Transaction for combat result (%s vs %s) PREPARED. TxBytes: %s.",
+					combatOutcome.AttackerID, combatOutcome.DefenderID, txBlockResponse.TxBytes)
 				// In a real system:
 				// 1. Get txBlockResponse.TxBytes
 				// 2. Sign these bytes with the appropriate private key (e.g., a server-held key for system transactions)
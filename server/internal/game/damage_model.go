@@ -0,0 +1,176 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// DefaultDamageModel is used when neither the room nor the game mode selects a model.
+const DefaultDamageModel = "standard"
+
+// RNG is the random source handed to damage models. *rand.Rand implements it;
+// tests can pass a fixed sequence.
+type RNG interface {
+	Float64() float64
+}
+
+// DamageParams are the engine's tunable chances and multipliers.
+type DamageParams struct {
+	HitChance         float64
+	CritChance        float64
+	EvadeChance       float64
+	CritBonus         float64                // Damage multiplier on a critical hit
+	MinDamageFraction float64                // Share of attack power dealt on any hit
+	ElementalChart    map[string]interface{} // Attack element -> defender element -> multiplier
+}
+
+// DamageInput is everything a damage model may use to resolve one attack.
+type DamageInput struct {
+	Attacker        CombatantStats
+	Defender        CombatantStats
+	Skill           string // Empty for a basic attack
+	AttackElement   string
+	DefenderElement string
+	Params          DamageParams
+	RNG             RNG
+}
+
+// DamageOutcome is a damage model's verdict on one attack.
+type DamageOutcome struct {
+	Evaded   bool
+	Missed   bool
+	Critical bool
+	Damage   int
+	Log      []string // Extra combat log lines, e.g. "Critical Hit!"
+}
+
+// DamageModel computes the damage of one attack. Game modes register their
+// own models with RegisterDamageModel and select them by name.
+type DamageModel interface {
+	Name() string
+	Compute(in DamageInput) DamageOutcome
+}
+
+var (
+	damageModelsMu sync.RWMutex
+	damageModels   = map[string]DamageModel{}
+)
+
+func init() {
+	RegisterDamageModel(StandardDamageModel{})
+	RegisterDamageModel(MitigationDamageModel{})
+}
+
+// RegisterDamageModel makes model selectable by its name, replacing any model
+// registered under the same name.
+func RegisterDamageModel(model DamageModel) {
+	damageModelsMu.Lock()
+	defer damageModelsMu.Unlock()
+	damageModels[model.Name()] = model
+}
+
+// LookupDamageModel returns the model registered as name.
+func LookupDamageModel(name string) (DamageModel, bool) {
+	damageModelsMu.RLock()
+	defer damageModelsMu.RUnlock()
+	model, ok := damageModels[name]
+	return model, ok
+}
+
+// DamageModelNames lists the registered models.
+func DamageModelNames() []string {
+	damageModelsMu.RLock()
+	defer damageModelsMu.RUnlock()
+	names := make([]string, 0, len(damageModels))
+	for name := range damageModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StandardDamageModel is the original formula: attack power minus defense,
+// floored at a share of attack power, with evasion, hit and critical rolls.
+type StandardDamageModel struct{}
+
+// Name implements DamageModel.
+func (StandardDamageModel) Name() string { return "standard" }
+
+// Compute implements DamageModel.
+func (StandardDamageModel) Compute(in DamageInput) DamageOutcome {
+	return rollAttack(in, func() int {
+		return in.Attacker.AttackPower - in.Defender.Defense
+	})
+}
+
+// MitigationDamageModel scales damage by 100/(100+defense), so defense never
+// fully blocks an attack and stacking it has diminishing returns. It suits PvP
+// modes where the standard formula lets tanks become immune.
+type MitigationDamageModel struct{}
+
+// Name implements DamageModel.
+func (MitigationDamageModel) Name() string { return "mitigation" }
+
+// Compute implements DamageModel.
+func (MitigationDamageModel) Compute(in DamageInput) DamageOutcome {
+	return rollAttack(in, func() int {
+		defense := in.Defender.Defense
+		if defense < 0 {
+			defense = 0
+		}
+		return in.Attacker.AttackPower * 100 / (100 + defense)
+	})
+}
+
+// rollAttack runs the shared evade/hit/crit rolls around a model's base damage
+// and applies the minimum damage and elemental multiplier.
+func rollAttack(in DamageInput, baseDamage func() int) DamageOutcome {
+	var out DamageOutcome
+	if in.RNG.Float64() < in.Params.EvadeChance {
+		out.Evaded = true
+		return out
+	}
+	if in.RNG.Float64() > in.Params.HitChance {
+		out.Missed = true
+		return out
+	}
+	damage := baseDamage()
+	minDamage := int(float64(in.Attacker.AttackPower) * in.Params.MinDamageFraction)
+	if minDamage < 1 {
+		minDamage = 1 // Always at least 1 damage on a hit
+	}
+	if damage < minDamage {
+		damage = minDamage
+	}
+	if multiplier, ok := elementalMultiplier(in.Params.ElementalChart, in.AttackElement, in.DefenderElement); ok {
+		damage = int(float64(damage) * multiplier)
+		out.Log = append(out.Log, fmt.Sprintf("%s against %s: x%.2f.", in.AttackElement, in.DefenderElement, multiplier))
+	}
+	if in.RNG.Float64() < in.Params.CritChance {
+		out.Critical = true
+		damage = int(float64(damage) * in.Params.CritBonus)
+		out.Log = append(out.Log, "Critical Hit!")
+	}
+	out.Damage = damage
+	return out
+}
+
+// elementalMultiplier reads chart[attack][defender] from the loosely typed chart in CombatEngineConfig.
+func elementalMultiplier(chart map[string]interface{}, attack, defender string) (float64, bool) {
+	if attack == "" || defender == "" {
+		return 0, false
+	}
+	row, ok := chart[attack].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	multiplier, ok := row[defender].(float64)
+	return multiplier, ok
+}
+
+// globalRNG uses the math/rand package source seeded by NewCombatEngine.
+type globalRNG struct{}
+
+func (globalRNG) Float64() float64 { return rand.Float64() }
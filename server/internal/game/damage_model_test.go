@@ -0,0 +1,90 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+// fixedRNG returns its values in order.
+type fixedRNG []float64
+
+func (r *fixedRNG) Float64() float64 {
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v
+}
+
+func hitInput(attack, defense int, rolls ...float64) DamageInput {
+	rng := fixedRNG(rolls)
+	return DamageInput{
+		Attacker: CombatantStats{ID: "a", AttackPower: attack},
+		Defender: CombatantStats{ID: "d", Defense: defense},
+		Params:   DamageParams{HitChance: 0.9, CritChance: 0.1, EvadeChance: 0.05, CritBonus: 1.5, MinDamageFraction: 0.1},
+		RNG:      &rng,
+	}
+}
+
+func TestBuiltInDamageModels(t *testing.T) {
+	standard, _ := LookupDamageModel("standard")
+	mitigation, _ := LookupDamageModel("mitigation")
+	// Rolls: no evade, hit, no crit.
+	if out := standard.Compute(hitInput(50, 20, 0.5, 0.5, 0.5)); out.Damage != 30 {
+		t.Fatalf("standard damage = %d, want 30", out.Damage)
+	}
+	if out := standard.Compute(hitInput(50, 100, 0.5, 0.5, 0.5)); out.Damage != 5 {
+		t.Fatalf("standard damage against high defense = %d, want the 10%% floor of 5", out.Damage)
+	}
+	if out := mitigation.Compute(hitInput(50, 100, 0.5, 0.5, 0.5)); out.Damage != 25 {
+		t.Fatalf("mitigation damage = %d, want 25", out.Damage)
+	}
+	if out := standard.Compute(hitInput(50, 20, 0.5, 0.5, 0.01)); !out.Critical || out.Damage != 45 {
+		t.Fatalf("critical outcome = %+v, want 45 damage", out)
+	}
+	if out := standard.Compute(hitInput(50, 20, 0.01)); !out.Evaded || out.Damage != 0 {
+		t.Fatalf("evade outcome = %+v", out)
+	}
+}
+
+func TestElementalMultiplier(t *testing.T) {
+	in := hitInput(50, 20, 0.5, 0.5, 0.5)
+	in.AttackElement, in.DefenderElement = "fire", "ice"
+	in.Params.ElementalChart = map[string]interface{}{"fire": map[string]interface{}{"ice": 2.0}}
+	if out := (StandardDamageModel{}).Compute(in); out.Damage != 60 {
+		t.Fatalf("fire vs ice damage = %d, want 60", out.Damage)
+	}
+}
+
+func TestDamageModelSelection(t *testing.T) {
+	ce := &CombatEngine{damageModel: DefaultDamageModel, modeDamageModels: map[string]string{"arena": "mitigation"}}
+	cases := []struct{ mode, override, want string }{
+		{"", "", "standard"},
+		{"arena", "", "mitigation"},
+		{"arena", "standard", "standard"},
+		{"arena", "missing", "mitigation"},
+	}
+	for _, c := range cases {
+		if got := ce.DamageModelFor(c.mode, c.override).Name(); got != c.want {
+			t.Errorf("DamageModelFor(%q, %q) = %s, want %s", c.mode, c.override, got, c.want)
+		}
+	}
+}
+
+func TestBalanceValuesReplaceEngineConstants(t *testing.T) {
+	values := balance.Defaults()
+	values.Combat.HitChance, values.Combat.EvadeChance = 0, 0
+	values.Combat.ModeDamageModels = map[string]string{"arena": "mitigation"}
+	ce := NewCombatEngine(nil)
+	ce.UseBalance(func() balance.Values { return values })
+	if got := ce.DamageModelFor("arena", "").Name(); got != "mitigation" {
+		t.Fatalf("arena model = %s, want mitigation from the balance values", got)
+	}
+	result := ce.SimulateCombatTurn(CombatantStats{ID: "a", AttackPower: 50}, CombatantStats{ID: "d", Health: 10, MaxHealth: 10})
+	if result.DamageDealt != 0 || result.IsEvaded {
+		t.Fatalf("result = %+v, want a miss with a hit chance of 0", result)
+	}
+	values.Combat.DamageModel = "missing"
+	if err := ValidateBalance(values); err == nil {
+		t.Fatal("ValidateBalance accepted an unknown damage model")
+	}
+}
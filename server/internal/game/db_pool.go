@@ -0,0 +1,135 @@
+package game
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PoolOptions sizes a PostgreSQL connection pool. Zero leaves the
+// database/sql default: unlimited open connections, 2 idle connections, and
+// connections kept forever.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (o PoolOptions) apply(db *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
+
+// ConfigurePool sizes the connection pools of the primary and, if there is
+// one, the read replica. Call it before the layer is used.
+func (dbcl *DBCacheLayer) ConfigurePool(opts PoolOptions) {
+	dbcl.pool = opts
+	opts.apply(dbcl.db)
+	if dbcl.replica != nil {
+		opts.apply(dbcl.replica)
+	}
+}
+
+// UseReadReplica sends read-heavy queries, such as chat history, to the
+// PostgreSQL replica at replicaURL. The replica gets the primary's pool
+// settings. Like the primary, it is connected lazily.
+func (dbcl *DBCacheLayer) UseReadReplica(replicaURL string) error {
+	replica, err := sql.Open("postgres", replicaURL)
+	if err != nil {
+		return fmt.Errorf("sql.Open replica failed: %w", err)
+	}
+	dbcl.pool.apply(replica)
+	dbcl.replica = replica
+	log.Println("PostgreSQL read replica configured.")
+	return nil
+}
+
+// ReadDB returns the read replica, or the primary if there is none. Reads
+// from it may lag behind writes, so use it only where that is acceptable.
+func (dbcl *DBCacheLayer) ReadDB() *sql.DB {
+	if dbcl.replica != nil {
+		return dbcl.replica
+	}
+	return dbcl.db
+}
+
+// PingReplica verifies that the read replica is reachable.
+func (dbcl *DBCacheLayer) PingReplica(ctx context.Context) error {
+	return dbcl.ReadDB().PingContext(ctx)
+}
+
+// HasReplica reports whether reads go to a separate replica.
+func (dbcl *DBCacheLayer) HasReplica() bool {
+	return dbcl.replica != nil
+}
+
+// SetQueryTimeout sets the bound that the stores sharing this layer's
+// handles put on each query. Zero leaves queries bounded only by their
+// caller's context.
+func (dbcl *DBCacheLayer) SetQueryTimeout(timeout time.Duration) {
+	dbcl.queryTimeout = timeout
+}
+
+// QueryTimeout returns the bound set with SetQueryTimeout.
+func (dbcl *DBCacheLayer) QueryTimeout() time.Duration {
+	return dbcl.queryTimeout
+}
+
+// PoolStats are the connection pool counters of one database handle.
+type PoolStats struct {
+	MaxOpenConnections int     `json:"maxOpenConnections"` // 0 is unlimited
+	OpenConnections    int     `json:"openConnections"`
+	InUse              int     `json:"inUse"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"waitCount"`         // Queries that waited for a free connection
+	WaitSeconds        float64 `json:"waitSeconds"`       // Total time spent waiting
+	MaxIdleClosed      int64   `json:"maxIdleClosed"`     // Closed because the idle pool was full
+	MaxIdleTimeClosed  int64   `json:"maxIdleTimeClosed"` // Closed after ConnMaxIdleTime
+	MaxLifetimeClosed  int64   `json:"maxLifetimeClosed"` // Closed after ConnMaxLifetime
+}
+
+// DatabaseStats are the pool counters of the primary and the read replica,
+// and the state of the Redis circuit breaker.
+type DatabaseStats struct {
+	Primary             PoolStats  `json:"primary"`
+	Replica             *PoolStats `json:"replica,omitempty"` // Nil without a replica
+	QueryTimeoutSeconds float64    `json:"queryTimeoutSeconds"`
+	Cache               CacheStats `json:"cache"` // Redis circuit breaker
+}
+
+// Stats returns the connection pool counters, for the metrics endpoint.
+func (dbcl *DBCacheLayer) Stats() DatabaseStats {
+	stats := DatabaseStats{Primary: poolStats(dbcl.db.Stats()), QueryTimeoutSeconds: dbcl.queryTimeout.Seconds(), Cache: dbcl.CacheStats()}
+	if dbcl.replica != nil {
+		replica := poolStats(dbcl.replica.Stats())
+		stats.Replica = &replica
+	}
+	return stats
+}
+
+func poolStats(s sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitSeconds:        s.WaitDuration.Seconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
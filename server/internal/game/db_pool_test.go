@@ -0,0 +1,34 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// sql.Open connects lazily, so pools can be configured without a database.
+func TestDBPoolAndReplica(t *testing.T) {
+	dbcl, err := NewDBCacheLayerFromURL("postgresql://user@localhost:1/primary", RedisConfig{Addr: "localhost:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbcl.Stop()
+	dbcl.ConfigurePool(PoolOptions{MaxOpenConns: 7, MaxIdleConns: 3, ConnMaxLifetime: time.Minute})
+	dbcl.SetQueryTimeout(2 * time.Second)
+
+	if dbcl.ReadDB() != dbcl.DB() || dbcl.HasReplica() {
+		t.Fatal("reads should go to the primary without a replica")
+	}
+	if stats := dbcl.Stats(); stats.Primary.MaxOpenConnections != 7 || stats.Replica != nil || stats.QueryTimeoutSeconds != 2 {
+		t.Fatalf("stats without a replica = %+v", stats)
+	}
+
+	if err := dbcl.UseReadReplica("postgresql://user@localhost:1/replica"); err != nil {
+		t.Fatal(err)
+	}
+	if dbcl.ReadDB() == dbcl.DB() {
+		t.Fatal("reads should go to the replica")
+	}
+	if stats := dbcl.Stats(); stats.Replica == nil || stats.Replica.MaxOpenConnections != 7 {
+		t.Fatalf("replica did not get the pool settings: %+v", stats)
+	}
+}
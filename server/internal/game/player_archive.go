@@ -0,0 +1,251 @@
+package game
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// playerArchiveSchema holds the hot player records (id, data) and the
+// archived ones, moved out while their players are inactive.
+const playerArchiveSchema = `
+CREATE TABLE IF NOT EXISTS players (
+	id   TEXT PRIMARY KEY,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS players_archive (
+	id          TEXT PRIMARY KEY,
+	data        JSONB NOT NULL,
+	last_login  TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ NOT NULL
+);
+`
+
+// PlayerArchive moves the PlayerData of players who have not logged in for a
+// while out of the players table and the cache, into players_archive. A
+// record is moved back the next time it is read or its player logs in, so
+// callers never see the difference. Tombstones of erased and transferred
+// players stay where they are. Archived players keep their display names.
+type PlayerArchive struct {
+	DB          *DBCacheLayer
+	InactiveFor time.Duration // Players whose last login is older are archived
+	BatchSize   int           // Most players archived per statement
+
+	archived atomic.Uint64
+	restored atomic.Uint64
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr string
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// PlayerArchiveStats are the archive counters since start.
+type PlayerArchiveStats struct {
+	Archived  uint64    `json:"archived"`
+	Restored  uint64    `json:"restored"`
+	LastRun   time.Time `json:"lastRun"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// EnsureSchema creates the players and players_archive tables if they are
+// missing, and makes DB restore archived players when it reads them.
+func (a *PlayerArchive) EnsureSchema(ctx context.Context) error {
+	if _, err := a.DB.db.ExecContext(ctx, playerArchiveSchema); err != nil {
+		return fmt.Errorf("create players_archive: %w", err)
+	}
+	a.DB.archive = a
+	return nil
+}
+
+// ArchiveInactive moves up to BatchSize players whose last login is before
+// now minus InactiveFor into the archive, and purges their cached records. It
+// returns the IDs it archived.
+func (a *PlayerArchive) ArchiveInactive(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	// One statement, so a record is never in both tables or in neither.
+	rows, err := a.DB.db.QueryContext(ctx, `
+		WITH moved AS (
+			DELETE FROM players WHERE id IN (
+				SELECT id FROM players
+				WHERE COALESCE((data->>'lastLogin')::timestamptz, 'epoch') < $1
+					AND data->>'deletedAt' IS NULL AND COALESCE(data->>'movedTo', '') = ''
+				ORDER BY id LIMIT $2
+				FOR UPDATE SKIP LOCKED)
+			RETURNING id, data)
+		INSERT INTO players_archive (id, data, last_login, archived_at)
+		SELECT id, data, (data->>'lastLogin')::timestamptz, $3::timestamptz FROM moved
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, last_login = EXCLUDED.last_login, archived_at = EXCLUDED.archived_at
+		RETURNING id`,
+		now.Add(-a.InactiveFor), a.batchSize(), now)
+	if err != nil {
+		return nil, fmt.Errorf("archive inactive players: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return ids, fmt.Errorf("scan archived player: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return ids, err
+	}
+	for _, id := range ids {
+		if err := a.DB.cacheDel(ctx, CachePlayerData, fmt.Sprintf("player:%s", id)); err != nil {
+			log.Printf("Error purging cached player data of archived player %s: %v", id, err)
+		}
+	}
+	a.archived.Add(uint64(len(ids)))
+	return ids, nil
+}
+
+// Restore moves an archived player back into the players table and returns
+// their record. It returns ErrPlayerNotFound (wrapped) if the player is not
+// archived, or if a newer record already took their place.
+func (a *PlayerArchive) Restore(ctx context.Context, playerID string) (*PlayerData, error) {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	var raw []byte
+	err := a.DB.db.QueryRowContext(ctx, `
+		WITH restored AS (DELETE FROM players_archive WHERE id = $1 RETURNING id, data)
+		INSERT INTO players (id, data) SELECT id, data FROM restored
+		ON CONFLICT (id) DO NOTHING
+		RETURNING data`, playerID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s is not archived", ErrPlayerNotFound, playerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore archived player %s: %w", playerID, err)
+	}
+	var data PlayerData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("restored player data of %s is corrupt: %w", playerID, err)
+	}
+	a.restored.Add(1)
+	log.Printf("Restored archived player data of %s (last login %s).", playerID, data.LastLogin.Format(time.RFC3339))
+	return &data, nil
+}
+
+// Purge deletes the player's archived record, if any, for erasure.
+func (a *PlayerArchive) Purge(ctx context.Context, playerID string) error {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	if _, err := a.DB.db.ExecContext(ctx, `DELETE FROM players_archive WHERE id = $1`, playerID); err != nil {
+		return fmt.Errorf("purge archived player %s: %w", playerID, err)
+	}
+	return nil
+}
+
+// RecordLogin restores the player if they are archived and sets their last
+// login to at, which keeps them out of the archive for another InactiveFor.
+func (a *PlayerArchive) RecordLogin(ctx context.Context, playerID string, at time.Time) error {
+	if _, err := a.Restore(ctx, playerID); err != nil && !errors.Is(err, ErrPlayerNotFound) {
+		return err
+	}
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	if _, err := a.DB.db.ExecContext(ctx,
+		`UPDATE players SET data = jsonb_set(data, '{lastLogin}', to_jsonb($2::text)) WHERE id = $1`,
+		playerID, at.UTC().Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("record login of %s: %w", playerID, err)
+	}
+	return a.DB.cacheDel(ctx, CachePlayerData, fmt.Sprintf("player:%s", playerID))
+}
+
+// Start archives inactive players every interval, in batches until none is
+// left, while leader reports true. A nil leader always runs.
+func (a *PlayerArchive) Start(interval time.Duration, leader func() bool) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	a.stop, a.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if leader == nil || leader() {
+				a.runOnce(context.Background())
+			}
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background archiving after the batch in progress.
+func (a *PlayerArchive) Stop() {
+	a.stopOnce.Do(func() {
+		if a.stop != nil {
+			close(a.stop)
+			<-a.done
+		}
+	})
+}
+
+// Stats returns the archive counters.
+func (a *PlayerArchive) Stats() PlayerArchiveStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return PlayerArchiveStats{Archived: a.archived.Load(), Restored: a.restored.Load(), LastRun: a.lastRun, LastError: a.lastErr}
+}
+
+func (a *PlayerArchive) runOnce(ctx context.Context) {
+	total := 0
+	var err error
+	for {
+		var ids []string
+		ids, err = a.ArchiveInactive(ctx, time.Now())
+		total += len(ids)
+		if err != nil || len(ids) < a.batchSize() {
+			break
+		}
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+	}
+	a.mu.Lock()
+	a.lastRun, a.lastErr = time.Now(), ""
+	if err != nil {
+		a.lastErr = err.Error()
+	}
+	a.mu.Unlock()
+	if err != nil {
+		log.Printf("Error archiving inactive players: %v", err)
+	}
+	if total > 0 {
+		log.Printf("Archived %d players inactive for %s.", total, a.InactiveFor)
+	}
+}
+
+func (a *PlayerArchive) batchSize() int {
+	if a.BatchSize <= 0 {
+		return 500
+	}
+	return a.BatchSize
+}
+
+func (a *PlayerArchive) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.DB.queryTimeout > 0 {
+		return context.WithTimeout(ctx, a.DB.queryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
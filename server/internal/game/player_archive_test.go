@@ -0,0 +1,37 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPlayerArchiveOutageDoesNotHideRecords(t *testing.T) {
+	dbcl, err := NewDBCacheLayerFromURL("postgresql://user@localhost:1/primary", RedisConfig{Addr: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbcl.Stop()
+	archive := &PlayerArchive{DB: dbcl, InactiveFor: 24 * time.Hour}
+	dbcl.archive = archive
+
+	if _, err := archive.Restore(context.Background(), "player123"); err == nil || errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("restore without a database: err = %v, want a query error", err)
+	}
+	// A read falls through to the hot table when the archive cannot be asked.
+	if data, err := dbcl.GetPlayerData("player123"); err != nil || data.ID != "player123" {
+		t.Fatalf("GetPlayerData = %+v, %v", data, err)
+	}
+
+	// A failed pass is reported, and Stop waits for the worker.
+	archive.Start(time.Hour, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for archive.Stats().LastRun.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	archive.Stop()
+	if stats := archive.Stats(); stats.LastError == "" || stats.Archived != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PlayerDataPrivacySource exposes PlayerData to privacy export and deletion
+// requests (it implements privacy.DataSource).
+type PlayerDataPrivacySource struct {
+	DB *DBCacheLayer
+}
+
+// Name implements privacy.DataSource.
+func (s PlayerDataPrivacySource) Name() string { return "playerData" }
+
+// Export implements privacy.DataSource.
+func (s PlayerDataPrivacySource) Export(_ context.Context, playerID string) (interface{}, error) {
+	return s.DB.GetPlayerData(playerID)
+}
+
+// Erase replaces the player's record with an anonymized tombstone and purges
+// the cached and archived copies. The tombstone keeps the ID so the account
+// cannot be reused.
+func (s PlayerDataPrivacySource) Erase(ctx context.Context, playerID string) error {
+	if s.DB.archive != nil {
+		if err := s.DB.archive.Purge(ctx, playerID); err != nil {
+			return err
+		}
+	}
+	now := time.Now().UTC()
+	tombstone := &PlayerData{
+		ID:          playerID,
+		DisplayName: "deleted-player",
+		DeletedAt:   &now,
+	}
+	if err := s.DB.SavePlayerData(playerID, tombstone); err != nil {
+		return err
+	}
+	if err := s.DB.redisClient.Del(ctx, fmt.Sprintf("player:%s", playerID)).Err(); err != nil {
+		return fmt.Errorf("purge cached player data for %s: %w", playerID, err)
+	}
+	log.Printf("Player data for %s anonymized and purged from cache.", playerID)
+	return nil
+}
@@ -0,0 +1,38 @@
+package game
+
+import (
+	"log"
+
+	"github.com/phuhao00/suigserver/server/internal/stats"
+)
+
+// PlayerProgress lets the DBCacheLayer keep each player's Experience and
+// Level for the stats service.
+type PlayerProgress struct {
+	DB *DBCacheLayer
+}
+
+// LoadProgress implements stats.ProgressStore.
+func (p PlayerProgress) LoadProgress(playerID string) (stats.Progress, error) {
+	data, err := p.DB.GetPlayerData(playerID)
+	if err != nil {
+		return stats.Progress{Level: 1}, nil
+	}
+	progress := stats.Progress{XP: data.Experience, Level: data.Level}
+	if progress.Level < 1 {
+		progress.Level = 1
+	}
+	return progress, nil
+}
+
+// SaveProgress implements stats.ProgressStore, creating the record for a player who has none yet.
+func (p PlayerProgress) SaveProgress(playerID string, progress stats.Progress) error {
+	data, err := p.DB.GetPlayerData(playerID)
+	if err != nil {
+		log.Printf("No player data for %s (%v), creating a record for their progress.", playerID, err)
+		data = &PlayerData{ID: playerID}
+	}
+	data.Experience = progress.XP
+	data.Level = progress.Level
+	return p.DB.SavePlayerData(playerID, data)
+}
@@ -0,0 +1,130 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func playerNameKey(displayName string) string {
+	return "playername:" + strings.ToLower(strings.TrimSpace(displayName))
+}
+
+// PlayerIDByName returns the player using displayName, ignoring case, or "" if
+// no player does. Names of erased and transferred players are free again.
+func (dbcl *DBCacheLayer) PlayerIDByName(displayName string) (string, error) {
+	playerID, err := dbcl.cacheGet(dbcl.ctx, CachePlayerNames, playerNameKey(displayName))
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("look up display name %q: %w", displayName, err)
+	}
+	// The index is not cleared on rename or erasure, so confirm against the record.
+	data, err := dbcl.GetPlayerData(playerID)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if data.DeletedAt != nil || data.MovedTo != "" || !strings.EqualFold(data.DisplayName, strings.TrimSpace(displayName)) {
+		return "", nil
+	}
+	return playerID, nil
+}
+
+// PlayerDataTransferSection moves PlayerData between shards (it implements
+// transfer.Section and transfer.Names).
+type PlayerDataTransferSection struct {
+	DB *DBCacheLayer
+}
+
+// Name implements transfer.Section.
+func (s PlayerDataTransferSection) Name() string { return "playerData" }
+
+// Export implements transfer.Section. Erased and already transferred players
+// cannot be exported.
+func (s PlayerDataTransferSection) Export(_ context.Context, playerID string) (json.RawMessage, error) {
+	data, err := s.DB.GetPlayerData(playerID)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data.DeletedAt != nil {
+		return nil, fmt.Errorf("player %s was erased", playerID)
+	}
+	if data.MovedTo != "" {
+		return nil, fmt.Errorf("player %s already moved to %s", playerID, data.MovedTo)
+	}
+	return json.Marshal(data)
+}
+
+// Exists implements transfer.Section.
+func (s PlayerDataTransferSection) Exists(_ context.Context, playerID string) (bool, error) {
+	_, err := s.DB.GetPlayerData(playerID)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Check implements transfer.Section. Player data never clashes beyond its ID
+// and name, which the transfer service resolves.
+func (s PlayerDataTransferSection) Check(context.Context, string, json.RawMessage) ([]string, error) {
+	return nil, nil
+}
+
+// Import implements transfer.Section. The record takes the player's ID and
+// display name on this shard.
+func (s PlayerDataTransferSection) Import(_ context.Context, playerID, displayName string, raw json.RawMessage) error {
+	var data PlayerData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("decode player data: %w", err)
+	}
+	data.ID = playerID
+	if displayName != "" {
+		data.DisplayName = displayName
+	}
+	return s.DB.SavePlayerData(playerID, &data)
+}
+
+// Retire implements transfer.Section. It replaces the player's record with a
+// tombstone naming the shard they moved to, so their coins and inventory
+// cannot be used here again.
+func (s PlayerDataTransferSection) Retire(_ context.Context, playerID, movedTo string) error {
+	tombstone := &PlayerData{
+		ID:          playerID,
+		DisplayName: "transferred-player",
+		MovedTo:     movedTo,
+	}
+	if err := s.DB.SavePlayerData(playerID, tombstone); err != nil {
+		return err
+	}
+	log.Printf("Player data for %s retired; the player moved to %s.", playerID, movedTo)
+	return nil
+}
+
+// DisplayName implements transfer.Names.
+func (s PlayerDataTransferSection) DisplayName(_ context.Context, playerID string) (string, error) {
+	data, err := s.DB.GetPlayerData(playerID)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return data.DisplayName, nil
+}
+
+// NameOwner implements transfer.Names.
+func (s PlayerDataTransferSection) NameOwner(_ context.Context, displayName string) (string, error) {
+	return s.DB.PlayerIDByName(displayName)
+}
@@ -0,0 +1,39 @@
+package game
+
+import (
+	"log"
+
+	"github.com/phuhao00/suigserver/server/internal/shop"
+)
+
+// ShopWallets lets the DBCacheLayer back the shop service with each player's
+// Coins and Inventory. Players without a record start with StartingCoins.
+type ShopWallets struct {
+	DB            *DBCacheLayer
+	StartingCoins int64
+}
+
+// LoadWallet implements shop.WalletStore.
+func (w ShopWallets) LoadWallet(playerID string) (shop.Wallet, error) {
+	data, err := w.DB.GetPlayerData(playerID)
+	if err != nil {
+		return shop.Wallet{Coins: w.StartingCoins, Inventory: make(map[string]int)}, nil
+	}
+	inventory := data.Inventory
+	if inventory == nil {
+		inventory = make(map[string]int)
+	}
+	return shop.Wallet{Coins: data.Coins, Inventory: inventory}, nil
+}
+
+// SaveWallet implements shop.WalletStore, creating the record for a player who has none yet.
+func (w ShopWallets) SaveWallet(playerID string, wallet shop.Wallet) error {
+	data, err := w.DB.GetPlayerData(playerID)
+	if err != nil {
+		log.Printf("No player data for %s (%v), creating a record for their wallet.", playerID, err)
+		data = &PlayerData{ID: playerID}
+	}
+	data.Coins = wallet.Coins
+	data.Inventory = wallet.Inventory
+	return w.DB.SavePlayerData(playerID, data)
+}
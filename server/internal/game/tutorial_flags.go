@@ -0,0 +1,28 @@
+package game
+
+import "log"
+
+// LoadTutorialFlags returns the player's completed tutorial steps. It lets the
+// DBCacheLayer back the onboarding service.
+func (dbcl *DBCacheLayer) LoadTutorialFlags(playerID string) (map[string]bool, error) {
+	data, err := dbcl.GetPlayerData(playerID)
+	if err != nil {
+		return nil, err
+	}
+	if data.TutorialFlags == nil {
+		return make(map[string]bool), nil
+	}
+	return data.TutorialFlags, nil
+}
+
+// SaveTutorialFlags stores the player's completed tutorial steps in their PlayerData,
+// creating the record for a player who has none yet.
+func (dbcl *DBCacheLayer) SaveTutorialFlags(playerID string, flags map[string]bool) error {
+	data, err := dbcl.GetPlayerData(playerID)
+	if err != nil {
+		log.Printf("No player data for %s (%v), creating a record for tutorial progress.", playerID, err)
+		data = &PlayerData{ID: playerID}
+	}
+	data.TutorialFlags = flags
+	return dbcl.SavePlayerData(playerID, data)
+}
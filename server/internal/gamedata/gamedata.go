@@ -0,0 +1,272 @@
+// Package gamedata loads the data designers edit — the maps room templates
+// are played on, the skills file's movement abilities and projectiles, and
+// the NPC file — and reloads it while the server runs. Each load is
+// validated as a whole and becomes a new immutable Set; rooms take the
+// current Set when they are created and keep it for their lifetime, so a
+// reload only reaches new rooms and their spawns. Player cooldowns carry over
+// from one Set to the next.
+package gamedata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/movement"
+	"github.com/phuhao00/suigserver/server/internal/npc"
+	"github.com/phuhao00/suigserver/server/internal/pathfinding"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Sources are the files the data is loaded from. Missing files mean no data
+// of their kind, like an empty maps directory.
+type Sources struct {
+	MapsDir     string  // Every *.json file is a map
+	SkillsFile  string  // Movement abilities and projectiles
+	NPCFile     string  // NPCs, checked against the maps
+	NavCellSize float64 // Cell size of the navigation grids built for the maps
+}
+
+// Set is one version of the data. It is never changed once loaded.
+type Set struct {
+	Version     int
+	LoadedAt    time.Time
+	Movement    *movement.Rules
+	NPCs        *npc.Roster
+	Navigation  *pathfinding.Library // Grids of this Set's maps only
+	Projectiles *projectile.Arsenal
+	Maps        int
+	Abilities   int
+}
+
+// Report is the outcome of a reload. With Errors the files were rejected
+// and Version is the one still in effect.
+type Report struct {
+	Version     int       `json:"version"`
+	LoadedAt    time.Time `json:"loadedAt"`
+	Changed     bool      `json:"changed"`
+	Maps        int       `json:"maps"`
+	Abilities   int       `json:"abilities"`
+	Projectiles int       `json:"projectiles"`
+	NPCs        int       `json:"npcs"`
+	CheckedAt   time.Time `json:"checkedAt"`
+	Errors      []string  `json:"errors,omitempty"`
+}
+
+// ErrInvalid is returned, wrapped, when a reload finds invalid files.
+var ErrInvalid = errors.New("invalid game data")
+
+// Watcher serves the current Set and reloads it when the files change. It is
+// safe for concurrent use. A nil *Watcher has no Set.
+type Watcher struct {
+	sources Sources
+	loot    *balance.Service
+
+	reloadMu sync.Mutex // Serializes reloads
+
+	mu      sync.RWMutex
+	current *Set
+	loaded  string // Fingerprint of the files current was loaded from
+	read    string // Fingerprint of the files last read, valid or not
+	last    Report
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWatcher loads the data from sources. If the files are invalid the
+// Watcher starts with an empty Set, version 0, and the returned report lists
+// why; fixing the files and reloading replaces it.
+func NewWatcher(sources Sources) (*Watcher, Report) {
+	w := &Watcher{
+		sources: sources,
+		current: &Set{Navigation: pathfinding.NewLibrary(sources.NavCellSize)},
+		done:    make(chan struct{}),
+	}
+	return w, w.Reload(true)
+}
+
+// UseLoot makes forced reloads also re-read the balance file, where the loot
+// tables live. Loot is not versioned per room: balance changes apply
+// everywhere at once.
+func (w *Watcher) UseLoot(loot *balance.Service) {
+	w.loot = loot
+}
+
+// Current returns the Set new rooms should use.
+func (w *Watcher) Current() *Set {
+	if w == nil {
+		return nil
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Last returns the report of the latest reload.
+func (w *Watcher) Last() Report {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.last
+}
+
+// Reload loads and validates the files and, if they are all valid and
+// changed since the current Set was loaded, makes them the current Set.
+// Without force, files that did not change since they were last read are not
+// read again and the last report is returned, unchanged.
+func (w *Watcher) Reload(force bool) Report {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	var errs []string
+	if force && w.loot != nil {
+		if _, err := w.loot.Reload(); err != nil {
+			errs = append(errs, fmt.Sprintf("loot: %v", err))
+		}
+	}
+	fingerprint, err := w.fingerprint()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	w.mu.RLock()
+	current, loaded, read := w.current, w.loaded, w.read
+	w.mu.RUnlock()
+	if err == nil && !force && fingerprint == read {
+		last := w.Last()
+		last.Changed = false
+		return last
+	}
+
+	now := time.Now()
+	report := Report{CheckedAt: now, Errors: errs}
+	if err == nil {
+		next, loadErrs := load(w.sources, current)
+		report.Errors = append(report.Errors, loadErrs...)
+		if len(loadErrs) == 0 && fingerprint != loaded {
+			next.Version, next.LoadedAt = current.Version+1, now
+			current, report.Changed = next, true
+		}
+	}
+	report.fill(current)
+
+	w.mu.Lock()
+	if err == nil {
+		w.read = fingerprint
+	}
+	if report.Changed {
+		w.current, w.loaded = current, fingerprint
+	}
+	w.last = report
+	w.mu.Unlock()
+	return report
+}
+
+// Err returns the report's errors as one error wrapping ErrInvalid, or nil.
+func (r Report) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(r.Errors, "; "))
+}
+
+// StartWatching reloads changed files every interval until Stop.
+func (w *Watcher) StartWatching(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				w.logReload(w.Reload(false))
+			}
+		}
+	}()
+}
+
+// Stop stops watching the files.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) logReload(report Report) {
+	switch {
+	case report.Err() != nil && report.Changed:
+		utils.LogWarnf("Game data: Reloaded as version %d, but %v", report.Version, report.Err())
+	case report.Err() != nil:
+		utils.LogErrorf("Game data: %v. Keeping version %d.", report.Err(), report.Version)
+	case report.Changed:
+		utils.LogInfof("Game data: Reloaded as version %d (%d maps, %d abilities, %d projectiles, %d NPCs). New rooms use it.",
+			report.Version, report.Maps, report.Abilities, report.Projectiles, report.NPCs)
+	}
+}
+
+// fingerprint describes the files the data is loaded from, so changes can be
+// noticed without reading them.
+func (w *Watcher) fingerprint() (string, error) {
+	paths, err := filepath.Glob(filepath.Join(w.sources.MapsDir, "*.json"))
+	if err != nil {
+		return "", fmt.Errorf("maps: %w", err)
+	}
+	sort.Strings(paths)
+	paths = append(paths, w.sources.SkillsFile, w.sources.NPCFile)
+	var b strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			fmt.Fprintf(&b, "%s:missing\n", path)
+		case err != nil:
+			return "", err
+		default:
+			fmt.Fprintf(&b, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String(), nil
+}
+
+// load reads every file, collecting all the errors rather than stopping at
+// the first. Cooldowns carry over from prev.
+func load(sources Sources, prev *Set) (*Set, []string) {
+	var errs []string
+	maps, err := geometry.LoadMaps(sources.MapsDir)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("maps: %v", err))
+	}
+	abilities, err := movement.LoadAbilities(sources.SkillsFile)
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Sprintf("movement abilities: %v", err))
+	}
+	projectiles, err := projectile.LoadDefinitions(sources.SkillsFile)
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Sprintf("projectiles: %v", err))
+	}
+	var roster *npc.Roster
+	if maps != nil { // NPCs cannot be checked against maps that failed to load
+		roster, err = npc.LoadRoster(sources.NPCFile, maps)
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Sprintf("NPCs: %v", err))
+		}
+	}
+	return &Set{
+		Movement:    prev.Movement.Reloaded(abilities, maps),
+		NPCs:        roster,
+		Navigation:  pathfinding.NewLibrary(sources.NavCellSize),
+		Projectiles: prev.Projectiles.Reloaded(projectiles),
+		Maps:        len(maps),
+		Abilities:   len(abilities),
+	}, errs
+}
+
+func (r *Report) fill(set *Set) {
+	r.Version, r.LoadedAt = set.Version, set.LoadedAt
+	r.Maps, r.Abilities, r.Projectiles, r.NPCs = set.Maps, set.Abilities, set.Projectiles.Len(), set.NPCs.Len()
+}
@@ -0,0 +1,107 @@
+package gamedata
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+const (
+	testMap    = `{"id":"plaza","bounds":{"minX":0,"minY":0,"maxX":10,"maxY":10},"spawn":{"x":1,"y":1}}`
+	testSkills = `{"movementAbilities":[{"id":"dash","kind":"dash","range":3,"cooldownMs":60000}],"projectiles":[{"id":"arrow","speed":10,"range":5}]}`
+)
+
+// writeFile writes content to path with a modification time of age ago, so
+// consecutive edits are told apart whatever the file system's resolution.
+func writeFile(t *testing.T, path, content string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(-age)
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testSources(t *testing.T) Sources {
+	dir := t.TempDir()
+	sources := Sources{MapsDir: filepath.Join(dir, "maps"), SkillsFile: filepath.Join(dir, "skills.json"), NPCFile: filepath.Join(dir, "npcs.json"), NavCellSize: 0.5}
+	if err := os.Mkdir(sources.MapsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(sources.MapsDir, "plaza.json"), testMap, time.Hour)
+	writeFile(t, sources.SkillsFile, testSkills, time.Hour)
+	writeFile(t, sources.NPCFile, `{"npcs":[{"id":"guard","mapId":"plaza","spawn":{"x":2,"y":2},"speed":1}]}`, time.Hour)
+	return sources
+}
+
+func TestReloadAppliesValidEditsToNewSets(t *testing.T) {
+	sources := testSources(t)
+	w, report := NewWatcher(sources)
+	if report.Err() != nil || report.Version != 1 || report.Maps != 1 || report.Abilities != 1 || report.Projectiles != 1 || report.NPCs != 1 {
+		t.Fatalf("initial report = %+v", report)
+	}
+	first := w.Current()
+	plaza, _ := first.Movement.Map("plaza")
+	if _, err := first.Movement.UseAbility("p1", "dash", plaza, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 3, Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if report := w.Reload(false); report.Changed || report.Version != 1 {
+		t.Errorf("reloading unchanged files: %+v", report)
+	}
+
+	writeFile(t, sources.NPCFile, `{"npcs":[{"id":"guard","mapId":"plaza","spawn":{"x":2,"y":2},"speed":1},{"id":"wolf","mapId":"plaza","spawn":{"x":5,"y":5},"speed":3}]}`, time.Minute)
+	if report := w.Reload(false); !report.Changed || report.Version != 2 || report.NPCs != 2 {
+		t.Fatalf("reloading an edited NPC file: %+v", report)
+	}
+	second := w.Current()
+	if len(first.NPCs.ForMap("plaza")) != 1 || len(second.NPCs.ForMap("plaza")) != 2 {
+		t.Error("the edit reached the Set already in use, or not the new one")
+	}
+	if second.Movement.CooldownRemaining("p1", "dash") <= 0 {
+		t.Error("the reload reset a cooldown")
+	}
+}
+
+func TestReloadRejectsInvalidFiles(t *testing.T) {
+	sources := testSources(t)
+	w, _ := NewWatcher(sources)
+	w.UseLoot(nil)
+	current := w.Current()
+
+	// An NPC off the map and a broken skills file are both reported.
+	writeFile(t, sources.NPCFile, `{"npcs":[{"id":"guard","mapId":"plaza","spawn":{"x":20,"y":2},"speed":1}]}`, time.Minute)
+	writeFile(t, sources.SkillsFile, `{"movementAbilities":[{"id":"dash","kind":"fly","range":3}]}`, time.Minute)
+	report := w.Reload(false)
+	if report.Changed || report.Version != 1 || len(report.Errors) != 2 || w.Current() != current {
+		t.Fatalf("reloading invalid files: %+v", report)
+	}
+
+	mux := http.NewServeMux()
+	w.RegisterHandlers(mux, "secret")
+	req := httptest.NewRequest(http.MethodPost, "/admin/gamedata/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Admin-User", "designer")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var forced Report
+	if err := json.NewDecoder(rec.Body).Decode(&forced); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || len(forced.Errors) != 2 || forced.Version != 1 {
+		t.Errorf("forced reload = %d %+v", rec.Code, forced)
+	}
+
+	writeFile(t, sources.NPCFile, `{"npcs":[]}`, 0)
+	writeFile(t, sources.SkillsFile, testSkills, 0)
+	if report := w.Reload(false); !report.Changed || report.Version != 2 || report.NPCs != 0 {
+		t.Errorf("reloading fixed files: %+v", report)
+	}
+}
@@ -0,0 +1,42 @@
+package gamedata
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator.
+//
+//	GET  /admin/gamedata         current version and the report of the latest reload
+//	POST /admin/gamedata/reload  reload every file now, and the balance file's loot tables
+//
+// A reload that finds invalid files answers 422 with the report listing them;
+// the current version stays in effect.
+func (w *Watcher) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/gamedata", adminhttp.Only(adminToken, func(rw http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(rw, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(rw, http.StatusOK, w.Last())
+	}))
+	mux.HandleFunc("/admin/gamedata/reload", adminhttp.Only(adminToken, func(rw http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(rw, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		utils.LogInfof("Game data: Reload forced by %q.", operator)
+		report := w.Reload(true)
+		w.logReload(report)
+		status := http.StatusOK
+		if len(report.Errors) > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		adminhttp.WriteJSON(rw, status, report)
+	}))
+}
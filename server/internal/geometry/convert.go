@@ -0,0 +1,113 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Layers a level editor puts shapes on.
+const (
+	LayerGround = "ground" // Walkable area
+	LayerWall   = "wall"   // Impassable obstacle
+	LayerLow    = "low"    // Obstacle players can jump over
+)
+
+// Export is the simple JSON export of a level editor: shapes on layers, seen
+// from above.
+type Export struct {
+	ID     string      `json:"id,omitempty"`
+	Bounds *[4]float64 `json:"bounds,omitempty"` // minX, minY, maxX, maxY; around the ground if unset
+	Spawn  *[2]float64 `json:"spawn,omitempty"`  // The centre of the first ground shape if unset
+	Shapes []Shape     `json:"shapes"`
+}
+
+// Shape is an exported rectangle or polygon.
+type Shape struct {
+	Name   string       `json:"name,omitempty"`
+	Layer  string       `json:"layer"`
+	Rect   *[4]float64  `json:"rect,omitempty"`   // minX, minY, maxX, maxY
+	Points [][2]float64 `json:"points,omitempty"` // Corners in order, for shapes that are not rectangles
+}
+
+// FromExport converts a level editor's JSON export into a Map.
+func FromExport(data []byte) (*Map, error) {
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	var b builder
+	for i, shape := range export.Shapes {
+		var pg Polygon
+		switch {
+		case shape.Rect != nil:
+			pg = Rect{MinX: shape.Rect[0], MinY: shape.Rect[1], MaxX: shape.Rect[2], MaxY: shape.Rect[3]}.Polygon()
+		case len(shape.Points) > 0:
+			for _, p := range shape.Points {
+				pg = append(pg, Vec{X: p[0], Y: p[1]})
+			}
+		default:
+			return nil, fmt.Errorf("shape %d (%s) has neither a rect nor points", i+1, shape.Name)
+		}
+		if err := b.add(shape.Layer, pg); err != nil {
+			return nil, fmt.Errorf("shape %d (%s): %w", i+1, shape.Name, err)
+		}
+	}
+	if export.Bounds != nil {
+		b.bounds = &Rect{MinX: export.Bounds[0], MinY: export.Bounds[1], MaxX: export.Bounds[2], MaxY: export.Bounds[3]}
+	}
+	if export.Spawn != nil {
+		b.spawn = &Vec{X: export.Spawn[0], Y: export.Spawn[1]}
+	}
+	return b.build(export.ID)
+}
+
+// builder collects the shapes of a converted map.
+type builder struct {
+	ground    []Polygon
+	obstacles []Obstacle
+	bounds    *Rect
+	spawn     *Vec
+}
+
+func (b *builder) add(layer string, pg Polygon) error {
+	switch layer {
+	case LayerGround:
+		b.ground = append(b.ground, pg)
+	case LayerWall, LayerLow:
+		b.obstacles = append(b.obstacles, Obstacle{Points: pg, Low: layer == LayerLow})
+	default:
+		return fmt.Errorf("unknown layer %q", layer)
+	}
+	return nil
+}
+
+// build returns the map. Without explicit bounds they are drawn around the
+// ground; without a spawn point players start in the middle of the first
+// ground area.
+func (b *builder) build(id string) (*Map, error) {
+	m := &Map{ID: id, Ground: b.ground, Obstacles: b.obstacles}
+	switch {
+	case b.bounds != nil:
+		m.Bounds = *b.bounds
+	case len(b.ground) > 0:
+		m.Bounds = b.ground[0].Bounds()
+		for _, area := range b.ground[1:] {
+			box := area.Bounds()
+			m.Bounds = m.Bounds.extend(Vec{X: box.MinX, Y: box.MinY}).extend(Vec{X: box.MaxX, Y: box.MaxY})
+		}
+	default:
+		return nil, fmt.Errorf("the export has no ground and no bounds")
+	}
+	switch {
+	case b.spawn != nil:
+		m.Spawn = *b.spawn
+	case len(b.ground) > 0:
+		m.Spawn = b.ground[0].Centroid()
+	default:
+		m.Spawn = Vec{X: (m.Bounds.MinX + m.Bounds.MaxX) / 2, Y: (m.Bounds.MinY + m.Bounds.MaxY) / 2}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,139 @@
+package geometry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestFromExport(t *testing.T) {
+	m, err := FromExport([]byte(`{
+		"id": "yard",
+		"shapes": [
+			{"name": "lawn", "layer": "ground", "rect": [0, 0, 20, 10]},
+			{"name": "shed", "layer": "wall", "points": [[12, 2], [16, 2], [16, 6], [12, 6]]},
+			{"name": "hedge", "layer": "low", "rect": [5, 0, 5.5, 10]}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "yard" || m.Bounds != (Rect{MaxX: 20, MaxY: 10}) || m.Spawn != (Vec{10, 5}) {
+		t.Errorf("map = %+v", m)
+	}
+	if m.BlockAt(Vec{14, 4}) != Wall || m.BlockAt(Vec{5.2, 1}) != Low || !m.Walkable(Vec{2, 2}) {
+		t.Error("the shapes did not end up as collision data")
+	}
+
+	for name, export := range map[string]string{
+		"layer":  `{"shapes": [{"layer": "lava", "rect": [0, 0, 1, 1]}]}`,
+		"ground": `{"shapes": [{"layer": "wall", "rect": [0, 0, 1, 1]}]}`,
+		"shape":  `{"shapes": [{"layer": "ground"}]}`,
+		"spawn":  `{"spawn": [30, 30], "shapes": [{"layer": "ground", "rect": [0, 0, 10, 10]}]}`,
+		"flat":   `{"shapes": [{"layer": "ground", "points": [[0, 0], [1, 1], [2, 2]]}]}`,
+		"json":   `{"shapes": [}`,
+	} {
+		if _, err := FromExport([]byte(export)); err == nil {
+			t.Errorf("%s: converted", name)
+		}
+	}
+}
+
+// testGLTF builds a glTF scene seen from above: a 10x10 ground quad, a box
+// wall translated to (6, 0, -6) whose top face covers 1x1, and a spawn node.
+func testGLTF(t *testing.T) (gltf []byte, bin []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	positions := [][3]float32{
+		// Ground quad at y=0, from x=0..10 and z=0..-10 (north).
+		{0, 0, 0}, {10, 0, 0}, {10, 0, -10}, {0, 0, -10},
+		// Wall: a unit box top face at y=2, plus one vertical side face.
+		{-0.5, 2, 0.5}, {0.5, 2, 0.5}, {0.5, 2, -0.5}, {-0.5, 2, -0.5},
+		{-0.5, 0, 0.5}, {0.5, 0, 0.5},
+	}
+	for _, p := range positions {
+		binary.Write(&buf, binary.LittleEndian, p)
+	}
+	indices := []uint16{0, 1, 2, 0, 2, 3, 0, 1, 2, 0, 2, 3, 4, 5, 1, 4, 1, 0}
+	binary.Write(&buf, binary.LittleEndian, indices)
+	bin = buf.Bytes()
+
+	gltf = []byte(fmt.Sprintf(`{
+		"asset": {"version": "2.0"},
+		"scene": 0,
+		"scenes": [{"nodes": [0, 3]}],
+		"nodes": [
+			{"name": "Level", "children": [1, 2]},
+			{"name": "Ground.001", "mesh": 0},
+			{"name": "Wall_crate", "mesh": 1, "translation": [6, 0, -6]},
+			{"name": "Spawn", "translation": [2, 0, -3]}
+		],
+		"meshes": [
+			{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]},
+			{"primitives": [{"attributes": {"POSITION": 2}, "indices": 3}]}
+		],
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3"},
+			{"bufferView": 1, "componentType": 5123, "count": 6, "type": "SCALAR"},
+			{"bufferView": 0, "byteOffset": 48, "componentType": 5126, "count": 6, "type": "VEC3"},
+			{"bufferView": 1, "byteOffset": 12, "componentType": 5123, "count": 12, "type": "SCALAR"}
+		],
+		"bufferViews": [
+			{"buffer": 0, "byteOffset": 0, "byteLength": 120},
+			{"buffer": 0, "byteOffset": 120, "byteLength": 36}
+		],
+		"buffers": [{"byteLength": %d, "uri": "data:application/octet-stream;base64,%s"}]
+	}`, len(bin), base64.StdEncoding.EncodeToString(bin)))
+	return gltf, bin
+}
+
+func TestFromGLTF(t *testing.T) {
+	gltf, _ := testGLTF(t)
+	m, err := FromGLTF(gltf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Bounds != (Rect{MaxX: 10, MaxY: 10}) || m.Spawn != (Vec{2, 3}) {
+		t.Errorf("bounds %+v, spawn %v", m.Bounds, m.Spawn)
+	}
+	if len(m.Ground) != 2 || len(m.Obstacles) != 2 {
+		t.Fatalf("%d ground triangles and %d obstacles, want 2 and 2 (the side face dropped)", len(m.Ground), len(m.Obstacles))
+	}
+	if m.BlockAt(Vec{6, 6}) != Wall || !m.Walkable(Vec{6, 7}) || !m.Walkable(Vec{9.9, 0.1}) {
+		t.Error("the wall is not where it was placed")
+	}
+}
+
+func TestFromGLB(t *testing.T) {
+	gltf, bin := testGLTF(t)
+	// The same scene, with its buffer in the GLB's binary chunk.
+	gltf = bytes.Replace(gltf, []byte(`, "uri": "data:application/octet-stream;base64,`+base64.StdEncoding.EncodeToString(bin)+`"`), nil, 1)
+	pad := func(b []byte, with byte) []byte {
+		for len(b)%4 != 0 {
+			b = append(b, with)
+		}
+		return b
+	}
+	jsonChunk, binChunk := pad(gltf, ' '), pad(append([]byte(nil), bin...), 0)
+	var glb bytes.Buffer
+	binary.Write(&glb, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(jsonChunk) + 8 + len(binChunk))})
+	binary.Write(&glb, binary.LittleEndian, []uint32{uint32(len(jsonChunk)), glbChunkJSON})
+	glb.Write(jsonChunk)
+	binary.Write(&glb, binary.LittleEndian, []uint32{uint32(len(binChunk)), glbChunkBIN})
+	glb.Write(binChunk)
+
+	m, err := FromGLTF(glb.Bytes(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.BlockAt(Vec{6, 6}) != Wall || math.Abs(m.Spawn.Y-3) > 1e-9 {
+		t.Errorf("map from GLB = %+v", m)
+	}
+
+	if _, err := FromGLTF(glb.Bytes()[:40], ""); err == nil {
+		t.Error("a truncated GLB converted")
+	}
+}
@@ -0,0 +1,123 @@
+// Package geometry is the collision data of the game's zones, kept in a
+// navmesh-lite form: a zone's map is the polygons players can stand on and the
+// obstacles placed on them. Maps are loaded from one JSON file per zone at
+// startup and answer point queries for movement validation and NPC pathing.
+// Level editor exports are converted into this format with cmd/mapconv.
+package geometry
+
+import "math"
+
+// Vec is a point on a map's ground plane.
+type Vec struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Dist returns the distance between v and o.
+func (v Vec) Dist(o Vec) float64 {
+	return math.Hypot(o.X-v.X, o.Y-v.Y)
+}
+
+// Finite reports whether both coordinates are real numbers.
+func (v Vec) Finite() bool {
+	return !math.IsNaN(v.X) && !math.IsNaN(v.Y) && !math.IsInf(v.X, 0) && !math.IsInf(v.Y, 0)
+}
+
+// Lerp returns the point a fraction t of the way from v to o.
+func (v Vec) Lerp(o Vec, t float64) Vec {
+	return Vec{X: v.X + (o.X-v.X)*t, Y: v.Y + (o.Y-v.Y)*t}
+}
+
+// Rect is an axis-aligned rectangle, edges included.
+type Rect struct {
+	MinX float64 `json:"minX"`
+	MinY float64 `json:"minY"`
+	MaxX float64 `json:"maxX"`
+	MaxY float64 `json:"maxY"`
+}
+
+// Contains reports whether p lies in r.
+func (r Rect) Contains(p Vec) bool {
+	return p.X >= r.MinX && p.X <= r.MaxX && p.Y >= r.MinY && p.Y <= r.MaxY
+}
+
+// Empty reports whether r has no area.
+func (r Rect) Empty() bool {
+	return r.MaxX <= r.MinX || r.MaxY <= r.MinY
+}
+
+// Polygon returns r's corners, counter-clockwise.
+func (r Rect) Polygon() Polygon {
+	return Polygon{{r.MinX, r.MinY}, {r.MaxX, r.MinY}, {r.MaxX, r.MaxY}, {r.MinX, r.MaxY}}
+}
+
+// Polygon is a simple polygon given by its corners in order, either way
+// round. Its edges belong to it.
+type Polygon []Vec
+
+// edgeTolerance is how close to an edge a point counts as on it.
+const edgeTolerance = 1e-9
+
+// Contains reports whether p lies in pg or on its edges.
+func (pg Polygon) Contains(p Vec) bool {
+	inside := false
+	for i, j := 0, len(pg)-1; i < len(pg); j, i = i, i+1 {
+		a, b := pg[j], pg[i]
+		if onSegment(p, a, b) {
+			return true
+		}
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Area returns pg's area.
+func (pg Polygon) Area() float64 {
+	sum := 0.0
+	for i, j := 0, len(pg)-1; i < len(pg); j, i = i, i+1 {
+		sum += pg[j].X*pg[i].Y - pg[i].X*pg[j].Y
+	}
+	return math.Abs(sum) / 2
+}
+
+// Bounds returns the smallest Rect around pg.
+func (pg Polygon) Bounds() Rect {
+	if len(pg) == 0 {
+		return Rect{}
+	}
+	r := Rect{MinX: pg[0].X, MinY: pg[0].Y, MaxX: pg[0].X, MaxY: pg[0].Y}
+	for _, p := range pg[1:] {
+		r = r.extend(p)
+	}
+	return r
+}
+
+// Centroid returns the average of pg's corners. It lies inside pg if pg is
+// convex.
+func (pg Polygon) Centroid() Vec {
+	var c Vec
+	for _, p := range pg {
+		c.X += p.X / float64(len(pg))
+		c.Y += p.Y / float64(len(pg))
+	}
+	return c
+}
+
+// extend returns r grown to include p.
+func (r Rect) extend(p Vec) Rect {
+	r.MinX, r.MaxX = math.Min(r.MinX, p.X), math.Max(r.MaxX, p.X)
+	r.MinY, r.MaxY = math.Min(r.MinY, p.Y), math.Max(r.MaxY, p.Y)
+	return r
+}
+
+// onSegment reports whether p lies on the segment from a to b.
+func onSegment(p, a, b Vec) bool {
+	cross := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+	if math.Abs(cross) > edgeTolerance*math.Max(1, a.Dist(b)) {
+		return false
+	}
+	return p.X >= math.Min(a.X, b.X)-edgeTolerance && p.X <= math.Max(a.X, b.X)+edgeTolerance &&
+		p.Y >= math.Min(a.Y, b.Y)-edgeTolerance && p.Y <= math.Max(a.Y, b.Y)+edgeTolerance
+}
@@ -0,0 +1,99 @@
+package geometry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolygonContains(t *testing.T) {
+	// An L shape: the square (0,0)-(4,4) without (2,2)-(4,4).
+	l := Polygon{{0, 0}, {4, 0}, {4, 2}, {2, 2}, {2, 4}, {0, 4}}
+	for _, tc := range []struct {
+		p    Vec
+		want bool
+	}{
+		{Vec{1, 1}, true},
+		{Vec{3, 1}, true},
+		{Vec{1, 3}, true},
+		{Vec{3, 3}, false}, // The cut-out corner
+		{Vec{4, 1}, true},  // On an edge
+		{Vec{2, 2}, true},  // On the inner corner
+		{Vec{5, 1}, false},
+		{Vec{-0.001, 1}, false},
+	} {
+		if got := l.Contains(tc.p); got != tc.want {
+			t.Errorf("Contains(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+	if area := l.Area(); area != 12 {
+		t.Errorf("Area = %v, want 12", area)
+	}
+}
+
+func TestMapGroundAndObstacles(t *testing.T) {
+	m := &Map{
+		Bounds: Rect{MaxX: 10, MaxY: 10},
+		Spawn:  Vec{1, 1},
+		// Two rooms joined by a corridor along y=4..6.
+		Ground: []Polygon{
+			Rect{MaxX: 4, MaxY: 10}.Polygon(),
+			Rect{MinX: 4, MinY: 4, MaxX: 6, MaxY: 6}.Polygon(),
+			Rect{MinX: 6, MaxX: 10, MaxY: 10}.Polygon(),
+		},
+		Obstacles: []Obstacle{
+			{Points: Polygon{{7, 7}, {9, 7}, {8, 9}}},
+			{Points: Rect{MinX: 1, MinY: 5, MaxX: 3, MaxY: 5.5}.Polygon(), Low: true},
+		},
+	}
+	for _, tc := range []struct {
+		p    Vec
+		want Block
+	}{
+		{Vec{2, 2}, Open},
+		{Vec{5, 5}, Open}, // In the corridor
+		{Vec{5, 2}, Wall}, // Beside the corridor, off the ground
+		{Vec{8, 8}, Wall}, // In the triangle
+		{Vec{2, 5.2}, Low},
+		{Vec{11, 5}, Wall}, // Out of bounds
+	} {
+		if got := m.BlockAt(tc.p); got != tc.want {
+			t.Errorf("BlockAt(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+	if err := m.Validate(); err != nil {
+		t.Error(err)
+	}
+	m.Spawn = Vec{5, 2}
+	if err := m.Validate(); err == nil {
+		t.Error("a spawn point off the ground validated")
+	}
+}
+
+func TestLoadMaps(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("arena.json", `{"bounds": {"maxX": 10, "maxY": 10}, "spawn": {"x": 1, "y": 1}}`)
+	write("notes.txt", `not a map`)
+	maps, err := LoadMaps(dir)
+	if err != nil || len(maps) != 1 || maps["arena"] == nil || maps["arena"].ID != "arena" {
+		t.Fatalf("LoadMaps = %v, %v; want the arena named after its file", maps, err)
+	}
+
+	write("copy.json", `{"id": "arena", "bounds": {"maxX": 10, "maxY": 10}, "spawn": {"x": 1, "y": 1}}`)
+	if _, err := LoadMaps(dir); err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Errorf("duplicate map id: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "copy.json"))
+
+	write("walled.json", `{"bounds": {"maxX": 10, "maxY": 10}, "obstacles": [{"points": [{"x": 0, "y": 0}, {"x": 2, "y": 0}, {"x": 2, "y": 2}]}]}`)
+	if _, err := LoadMaps(dir); err == nil || !strings.Contains(err.Error(), "spawn") {
+		t.Errorf("map spawning players in a wall: %v", err)
+	}
+}
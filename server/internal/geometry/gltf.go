@@ -0,0 +1,373 @@
+package geometry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// glTF constants used by the converter.
+const (
+	glbMagic          = 0x46546C67 // "glTF"
+	glbChunkJSON      = 0x4E4F534A
+	glbChunkBIN       = 0x004E4942
+	gltfUnsignedByte  = 5121
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+	gltfTriangles     = 4
+)
+
+// spawnNode is the name of the node marking a map's spawn point.
+const spawnNode = "spawn"
+
+// degenerateArea is the area below which a projected triangle is dropped.
+const degenerateArea = 1e-9
+
+type gltfDocument struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Name        string    `json:"name"`
+		Mesh        *int      `json:"mesh"`
+		Children    []int     `json:"children"`
+		Matrix      []float64 `json:"matrix"`
+		Translation []float64 `json:"translation"`
+		Rotation    []float64 `json:"rotation"`
+		Scale       []float64 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Indices    *int           `json:"indices"`
+			Mode       *int           `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		BufferView    *int   `json:"bufferView"`
+		ByteOffset    int    `json:"byteOffset"`
+		ComponentType int    `json:"componentType"`
+		Count         int    `json:"count"`
+		Type          string `json:"type"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Buffers []struct {
+		URI string `json:"uri"`
+	} `json:"buffers"`
+}
+
+// FromGLTF converts a glTF 2.0 scene, as .gltf JSON or binary .glb, into a
+// Map. External buffers are read relative to dir. Meshes are sorted onto
+// layers by the name of their node or its nearest named ancestor, which must
+// start with "ground", "wall" or "low"; other meshes are ignored. Every
+// triangle is projected onto the ground plane as seen from above, with +Y up
+// and -Z to the north, so walls need a top face. A node named "spawn" marks
+// the spawn point.
+func FromGLTF(data []byte, dir string) (*Map, error) {
+	doc, bin, err := parseGLTF(data)
+	if err != nil {
+		return nil, err
+	}
+	buffers := make([][]byte, len(doc.Buffers))
+	for i, buffer := range doc.Buffers {
+		switch {
+		case buffer.URI == "" && i == 0 && bin != nil:
+			buffers[i] = bin
+		case strings.HasPrefix(buffer.URI, "data:"):
+			comma := strings.IndexByte(buffer.URI, ',')
+			if comma < 0 || !strings.HasSuffix(buffer.URI[:comma], ";base64") {
+				return nil, fmt.Errorf("buffer %d: only base64 data URIs are supported", i)
+			}
+			if buffers[i], err = base64.StdEncoding.DecodeString(buffer.URI[comma+1:]); err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+		case buffer.URI != "":
+			name, err := url.PathUnescape(buffer.URI)
+			if err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+			if buffers[i], err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("buffer %d has no data", i)
+		}
+	}
+
+	c := &gltfConverter{doc: doc, buffers: buffers, visited: make(map[int]bool)}
+	roots := make([]int, 0, len(doc.Nodes))
+	if len(doc.Scenes) > 0 {
+		scene := 0
+		if doc.Scene != nil {
+			scene = *doc.Scene
+		}
+		if scene < 0 || scene >= len(doc.Scenes) {
+			return nil, fmt.Errorf("scene %d does not exist", scene)
+		}
+		roots = doc.Scenes[scene].Nodes
+	} else {
+		for i := range doc.Nodes {
+			roots = append(roots, i)
+		}
+	}
+	for _, node := range roots {
+		if err := c.visit(node, identity(), ""); err != nil {
+			return nil, err
+		}
+	}
+	return c.build("")
+}
+
+func parseGLTF(data []byte) (*gltfDocument, []byte, error) {
+	jsonChunk, bin := data, []byte(nil)
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		jsonChunk = nil
+		for rest := data[12:]; len(rest) >= 8; {
+			length, kind := binary.LittleEndian.Uint32(rest), binary.LittleEndian.Uint32(rest[4:])
+			if uint64(length) > uint64(len(rest)-8) {
+				return nil, nil, fmt.Errorf("truncated GLB chunk")
+			}
+			switch kind {
+			case glbChunkJSON:
+				jsonChunk = rest[8 : 8+length]
+			case glbChunkBIN:
+				bin = rest[8 : 8+length]
+			}
+			rest = rest[8+length:]
+		}
+		if jsonChunk == nil {
+			return nil, nil, fmt.Errorf("GLB file has no JSON chunk")
+		}
+	}
+	var doc gltfDocument
+	if err := json.Unmarshal(bytes.TrimRight(jsonChunk, " \x00"), &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid glTF: %w", err)
+	}
+	return &doc, bin, nil
+}
+
+type gltfConverter struct {
+	builder
+	doc     *gltfDocument
+	buffers [][]byte
+	visited map[int]bool
+}
+
+func (c *gltfConverter) visit(index int, parent matrix, layer string) error {
+	if index < 0 || index >= len(c.doc.Nodes) {
+		return fmt.Errorf("node %d does not exist", index)
+	}
+	if c.visited[index] {
+		return fmt.Errorf("node %d has more than one parent", index)
+	}
+	c.visited[index] = true
+	node := c.doc.Nodes[index]
+	world := parent.mul(localMatrix(node.Matrix, node.Translation, node.Rotation, node.Scale))
+	name := strings.ToLower(node.Name)
+	for _, l := range []string{LayerGround, LayerWall, LayerLow} {
+		if strings.HasPrefix(name, l) {
+			layer = l
+		}
+	}
+	if strings.HasPrefix(name, spawnNode) {
+		spawn := world.project([3]float64{})
+		c.spawn = &spawn
+	}
+	if node.Mesh != nil && layer != "" {
+		if err := c.addMesh(*node.Mesh, world, layer); err != nil {
+			return fmt.Errorf("node %d (%s): %w", index, node.Name, err)
+		}
+	}
+	for _, child := range node.Children {
+		if err := c.visit(child, world, layer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *gltfConverter) addMesh(index int, world matrix, layer string) error {
+	if index < 0 || index >= len(c.doc.Meshes) {
+		return fmt.Errorf("mesh %d does not exist", index)
+	}
+	for _, prim := range c.doc.Meshes[index].Primitives {
+		if prim.Mode != nil && *prim.Mode != gltfTriangles {
+			continue // Points and lines have no area
+		}
+		position, ok := prim.Attributes["POSITION"]
+		if !ok {
+			continue
+		}
+		vertices, err := c.positions(position)
+		if err != nil {
+			return err
+		}
+		indices := make([]int, len(vertices))
+		for i := range indices {
+			indices[i] = i
+		}
+		if prim.Indices != nil {
+			if indices, err = c.indices(*prim.Indices); err != nil {
+				return err
+			}
+		}
+		for i := 0; i+2 < len(indices); i += 3 {
+			var tri Polygon
+			for _, vi := range indices[i : i+3] {
+				if vi < 0 || vi >= len(vertices) {
+					return fmt.Errorf("index %d is out of range", vi)
+				}
+				tri = append(tri, world.project(vertices[vi]))
+			}
+			if tri.Area() < degenerateArea {
+				continue // Vertical faces, such as the sides of a wall
+			}
+			c.add(layer, tri)
+		}
+	}
+	return nil
+}
+
+// accessor returns the bytes of an accessor's elements and their stride.
+func (c *gltfConverter) accessor(index, size int) ([]byte, int, int, error) {
+	if index < 0 || index >= len(c.doc.Accessors) {
+		return nil, 0, 0, fmt.Errorf("accessor %d does not exist", index)
+	}
+	acc := c.doc.Accessors[index]
+	if acc.BufferView == nil || *acc.BufferView < 0 || *acc.BufferView >= len(c.doc.BufferViews) {
+		return nil, 0, 0, fmt.Errorf("accessor %d has no buffer view", index)
+	}
+	view := c.doc.BufferViews[*acc.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(c.buffers) {
+		return nil, 0, 0, fmt.Errorf("buffer view %d has no buffer", *acc.BufferView)
+	}
+	buffer := c.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset+view.ByteLength > len(buffer) {
+		return nil, 0, 0, fmt.Errorf("buffer view %d is out of range", *acc.BufferView)
+	}
+	data := buffer[view.ByteOffset : view.ByteOffset+view.ByteLength]
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = size
+	}
+	if acc.Count < 0 || acc.ByteOffset < 0 || acc.ByteOffset > len(data) || (acc.Count > 0 && acc.ByteOffset+(acc.Count-1)*stride+size > len(data)) {
+		return nil, 0, 0, fmt.Errorf("accessor %d is out of range", index)
+	}
+	return data[acc.ByteOffset:], stride, acc.Count, nil
+}
+
+func (c *gltfConverter) positions(index int) ([][3]float64, error) {
+	if index >= 0 && index < len(c.doc.Accessors) {
+		if acc := c.doc.Accessors[index]; acc.ComponentType != gltfFloat || acc.Type != "VEC3" {
+			return nil, fmt.Errorf("accessor %d: positions must be float VEC3", index)
+		}
+	}
+	data, stride, count, err := c.accessor(index, 12)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][3]float64, count)
+	for i := range out {
+		for k := 0; k < 3; k++ {
+			out[i][k] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*stride+4*k:])))
+		}
+	}
+	return out, nil
+}
+
+func (c *gltfConverter) indices(index int) ([]int, error) {
+	if index < 0 || index >= len(c.doc.Accessors) {
+		return nil, fmt.Errorf("accessor %d does not exist", index)
+	}
+	size := map[int]int{gltfUnsignedByte: 1, gltfUnsignedShort: 2, gltfUnsignedInt: 4}[c.doc.Accessors[index].ComponentType]
+	if size == 0 || c.doc.Accessors[index].Type != "SCALAR" {
+		return nil, fmt.Errorf("accessor %d: indices must be unsigned SCALAR", index)
+	}
+	data, stride, count, err := c.accessor(index, size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int, count)
+	for i := range out {
+		switch size {
+		case 1:
+			out[i] = int(data[i*stride])
+		case 2:
+			out[i] = int(binary.LittleEndian.Uint16(data[i*stride:]))
+		default:
+			out[i] = int(binary.LittleEndian.Uint32(data[i*stride:]))
+		}
+	}
+	return out, nil
+}
+
+// matrix is a column-major 4x4 transform, as glTF stores them.
+type matrix [16]float64
+
+func identity() matrix {
+	return matrix{0: 1, 5: 1, 10: 1, 15: 1}
+}
+
+func (a matrix) mul(b matrix) matrix {
+	var out matrix
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			for k := 0; k < 4; k++ {
+				out[col*4+row] += a[k*4+row] * b[col*4+k]
+			}
+		}
+	}
+	return out
+}
+
+// project transforms a point and returns where it lies on the ground plane.
+func (a matrix) project(p [3]float64) Vec {
+	x := a[0]*p[0] + a[4]*p[1] + a[8]*p[2] + a[12]
+	z := a[2]*p[0] + a[6]*p[1] + a[10]*p[2] + a[14]
+	return Vec{X: x, Y: -z}
+}
+
+// localMatrix returns a node's transform from its matrix or its translation,
+// rotation and scale.
+func localMatrix(m, t, r, s []float64) matrix {
+	if len(m) == 16 {
+		var out matrix
+		copy(out[:], m)
+		return out
+	}
+	out := identity()
+	if len(r) == 4 {
+		x, y, z, w := r[0], r[1], r[2], r[3]
+		out = matrix{
+			1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+			2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+			2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+			0, 0, 0, 1,
+		}
+	}
+	if len(s) == 3 {
+		for col := 0; col < 3; col++ {
+			for row := 0; row < 3; row++ {
+				out[col*4+row] *= s[col]
+			}
+		}
+	}
+	if len(t) == 3 {
+		out[12], out[13], out[14] = t[0], t[1], t[2]
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Block says what stands at a point of a map.
+type Block int
+
+const (
+	Open Block = iota // Walkable ground
+	Low               // A low obstacle, such as a fence or a ledge; players can jump over it
+	Wall              // Impassable, including everything off the map's ground
+)
+
+// Obstacle is an area of a map players cannot walk through.
+type Obstacle struct {
+	Points Polygon `json:"points"`
+	Low    bool    `json:"low,omitempty"` // Can be jumped over
+}
+
+// Map is the collision data of one zone: the ground players can stand on and
+// the obstacles on it.
+type Map struct {
+	ID        string     `json:"id"`
+	Bounds    Rect       `json:"bounds"`           // Nothing outside is walkable
+	Spawn     Vec        `json:"spawn"`            // Where players entering a room on this map start
+	Ground    []Polygon  `json:"ground,omitempty"` // Walkable areas; all of Bounds if empty
+	Obstacles []Obstacle `json:"obstacles,omitempty"`
+}
+
+// BlockAt returns what stands at p. A nil Map is open ground everywhere.
+func (m *Map) BlockAt(p Vec) Block {
+	if m == nil {
+		return Open
+	}
+	if !m.Bounds.Contains(p) || !m.onGround(p) {
+		return Wall
+	}
+	block := Open
+	for _, o := range m.Obstacles {
+		if !o.Points.Contains(p) {
+			continue
+		}
+		if !o.Low {
+			return Wall
+		}
+		block = Low
+	}
+	return block
+}
+
+// Walkable reports whether a player may stand at p.
+func (m *Map) Walkable(p Vec) bool {
+	return m.BlockAt(p) == Open
+}
+
+// SpawnPoint returns where players start on the map; the origin on a nil Map.
+func (m *Map) SpawnPoint() Vec {
+	if m == nil {
+		return Vec{}
+	}
+	return m.Spawn
+}
+
+func (m *Map) onGround(p Vec) bool {
+	if len(m.Ground) == 0 {
+		return true
+	}
+	for _, area := range m.Ground {
+		if area.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the map's shapes and that its spawn point is walkable.
+func (m *Map) Validate() error {
+	if m.Bounds.Empty() {
+		return fmt.Errorf("bounds are empty")
+	}
+	for i, area := range m.Ground {
+		if len(area) < 3 || area.Area() == 0 {
+			return fmt.Errorf("ground area %d has no area", i+1)
+		}
+	}
+	for i, o := range m.Obstacles {
+		if len(o.Points) < 3 || o.Points.Area() == 0 {
+			return fmt.Errorf("obstacle %d has no area", i+1)
+		}
+	}
+	if !m.Spawn.Finite() || !m.Walkable(m.Spawn) {
+		return fmt.Errorf("spawn point %v is not walkable", m.Spawn)
+	}
+	return nil
+}
+
+// LoadMap reads a map file. A map without an id is named after its file.
+func LoadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid map %s: %w", path, err)
+	}
+	if m.ID == "" {
+		m.ID = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid map %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// LoadMaps reads every *.json file in dir as a Map, one per zone.
+func LoadMaps(dir string) (map[string]*Map, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	maps := make(map[string]*Map, len(paths))
+	for _, path := range paths {
+		m, err := LoadMap(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := maps[m.ID]; dup {
+			return nil, fmt.Errorf("map %q is defined twice, the second time in %s", m.ID, path)
+		}
+		maps[m.ID] = m
+	}
+	return maps, nil
+}
@@ -0,0 +1,749 @@
+// Package gift runs player-to-player gifts of item NFTs. The server checks
+// that the sender owns the item and that the recipient has an address to
+// receive it, then hands the sender an unsigned transfer to sign. Gifts worth
+// more than a threshold wait for the recipient to accept. With a custody
+// address, such a gift is first transferred to the server, which releases it
+// to the recipient on acceptance and returns it to the sender otherwise.
+// Finished gifts are kept in both players' history.
+package gift
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Outbox message kinds for custody transfers. Their payload is a CustodyJob.
+const (
+	CustodyReleaseKind = "gift.custody_release"
+	CustodyReturnKind  = "gift.custody_return"
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultAcceptTTL    = 72 * time.Hour
+	DefaultTransferTTL  = 15 * time.Minute
+	DefaultHistoryLimit = 50
+	DefaultTickInterval = 10 * time.Second
+)
+
+var (
+	ErrUnknownGift        = errors.New("unknown gift")
+	ErrNotYourGift        = errors.New("that gift is not yours to answer")
+	ErrSelfGift           = errors.New("you cannot give yourself a gift")
+	ErrNoItem             = errors.New("a gift needs an item")
+	ErrNoRecipient        = errors.New("that player cannot receive items; they have no linked Sui address")
+	ErrNotOwner           = errors.New("you do not own that item")
+	ErrWrongState         = errors.New("the gift cannot do that now")
+	ErrTransferUnverified = errors.New("the transfer could not be verified")
+)
+
+// Chain reads and moves item NFTs. cmd/game adapts sui.SuiClient to it.
+type Chain interface {
+	// Owner returns the address owning objectID and the object's Move type.
+	Owner(ctx context.Context, objectID string) (owner, objectType string, err error)
+	// BuildTransfer returns unsigned transaction bytes moving objectID from sender to recipient.
+	BuildTransfer(ctx context.Context, sender, objectID, recipient string) (string, error)
+	// VerifyTransfer checks that txDigest succeeded, was sent by sender and
+	// left objectID with recipient.
+	VerifyTransfer(ctx context.Context, txDigest, sender, objectID, recipient string) error
+	// Transfer moves objectID from the custody address to recipient, signed by
+	// the server, and returns the transaction digest.
+	Transfer(ctx context.Context, objectID, recipient string) (string, error)
+}
+
+// AddressResolver returns a player's Sui address. accountlink.Service implements it.
+type AddressResolver interface {
+	Address(ctx context.Context, playerID string) (string, error)
+}
+
+// SpendLimit caps the MIST value players give away each day. parental.Service implements it.
+type SpendLimit interface {
+	SpendMist(playerID string, mist uint64) error
+	ReleaseMist(playerID string, mist uint64)
+}
+
+// CustodyJob is the payload of the custody outbox messages.
+type CustodyJob struct {
+	GiftID string `json:"giftId"`
+}
+
+// Update is sent to both players whenever a gift changes.
+type Update struct {
+	Gift Gift
+}
+
+// Notifier delivers an *Update to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	AcceptThreshold uint64            // Gifts worth more than this (MIST) need the recipient's acceptance
+	ItemValue       uint64            // Estimated MIST value of an item whose type is not in TypeValues
+	TypeValues      map[string]uint64 // MIST values by Move type, or by its "module::Name" suffix
+	AcceptTTL       time.Duration     // How long the recipient has to answer
+	TransferTTL     time.Duration     // How long the sender has to sign a transfer
+	HistoryLimit    int               // Finished gifts kept per player
+	TickInterval    time.Duration
+}
+
+// Service runs gifts. It is safe for concurrent use by session actors.
+type Service struct {
+	opts      Options
+	store     Store
+	chain     Chain
+	addresses AddressResolver
+	custody   string                // Address holding gifts that await acceptance; empty if off
+	jobs      *outbox.Outbox        // Custody transfers
+	items     *reservation.Registry // Holds gifted items until the gift finishes; nil reserves nothing
+	audit     *audit.Log            // Records gifts and status changes; nil records nothing
+	mail      *mail.Service         // Tells recipients about gifts while they are away; nil sends none
+	limit     SpendLimit            // Caps the value players give away; nil caps nothing
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	notifiers map[string]Notifier // Connected players
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService loads the gift state from store. Custody stays off until UseCustody.
+func NewService(opts Options, store Store, chain Chain, addresses AddressResolver) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading gift state: %w", err)
+	}
+	if state.Gifts == nil {
+		state.Gifts = make(map[string]Gift)
+	}
+	if state.History == nil {
+		state.History = make(map[string][]Gift)
+	}
+	if opts.AcceptTTL <= 0 {
+		opts.AcceptTTL = DefaultAcceptTTL
+	}
+	if opts.TransferTTL <= 0 {
+		opts.TransferTTL = DefaultTransferTTL
+	}
+	if opts.HistoryLimit <= 0 {
+		opts.HistoryLimit = DefaultHistoryLimit
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	return &Service{
+		opts:      opts,
+		store:     store,
+		chain:     chain,
+		addresses: addresses,
+		now:       time.Now,
+		state:     state,
+		notifiers: make(map[string]Notifier),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// NewServiceFromConfig creates a Service from configuration, keeping its state in NewFileStore(cfg.StateFile).
+func NewServiceFromConfig(cfg configs.GiftConfig, chain Chain, addresses AddressResolver) (*Service, error) {
+	return NewService(Options{
+		AcceptThreshold: cfg.AcceptThresholdMist,
+		ItemValue:       cfg.ItemValueMist,
+		TypeValues:      cfg.TypeValuesMist,
+		AcceptTTL:       time.Duration(cfg.AcceptTTLSeconds) * time.Second,
+		TransferTTL:     time.Duration(cfg.TransferTTLSeconds) * time.Second,
+		HistoryLimit:    cfg.HistoryLimit,
+	}, NewFileStore(cfg.StateFile), chain, addresses)
+}
+
+// UseCustody holds gifts that need acceptance at address until they are
+// answered. Its transfers are queued in jobs, so they survive restarts and
+// are retried.
+func (s *Service) UseCustody(address string, jobs *outbox.Outbox) {
+	s.custody, s.jobs = address, jobs
+	jobs.Handle(CustodyReleaseKind, s.releaseCustody)
+	jobs.Handle(CustodyReturnKind, s.returnCustody)
+}
+
+// UseReservations makes gifts reserve their item in items until they finish.
+// A gift is refused while another operation holds the item.
+func (s *Service) UseReservations(items *reservation.Registry) {
+	s.items = items
+}
+
+// UseSpendLimit holds the value of every gift against its sender's daily
+// limit when it is sent.
+func (s *Service) UseSpendLimit(limit SpendLimit) {
+	s.limit = limit
+}
+
+// spend holds mist against playerID's daily limit, if there is one.
+func (s *Service) spend(playerID string, mist uint64) error {
+	if s.limit == nil {
+		return nil
+	}
+	return s.limit.SpendMist(playerID, mist)
+}
+
+// unspend gives back mist held by spend, for a gift that failed.
+func (s *Service) unspend(playerID string, mist uint64) {
+	if s.limit != nil {
+		s.limit.ReleaseMist(playerID, mist)
+	}
+}
+
+// UseAuditLog records every gift and status change in log.
+func (s *Service) UseAuditLog(log *audit.Log) {
+	s.audit = log
+}
+
+// UseMail mails recipients when they are offered or receive a gift.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// Start expires gifts past their deadline in the background.
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.Tick(now)
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect forgets a player's notifier. Their gifts carry on.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Value estimates the MIST value of an item of itemType.
+func (s *Service) Value(itemType string) uint64 {
+	if value, ok := s.opts.TypeValues[itemType]; ok {
+		return value
+	}
+	for key, value := range s.opts.TypeValues {
+		if strings.HasSuffix(itemType, "::"+key) {
+			return value
+		}
+	}
+	return s.opts.ItemValue
+}
+
+// Send offers the item NFT itemID from one player to another. The sender must
+// own the item with their linked address and the recipient must have one.
+// The gift comes back with the unsigned transfer the sender is to sign, unless
+// it first waits for the recipient's acceptance.
+func (s *Service) Send(ctx context.Context, from, to, itemID, note string) (Gift, error) {
+	if from == to {
+		return Gift{}, ErrSelfGift
+	}
+	if itemID == "" {
+		return Gift{}, ErrNoItem
+	}
+	fromAddress, err := s.addresses.Address(ctx, from)
+	if err != nil {
+		return Gift{}, fmt.Errorf("your address: %w", err)
+	}
+	toAddress, err := s.addresses.Address(ctx, to)
+	if err != nil || toAddress == "" {
+		return Gift{}, ErrNoRecipient
+	}
+	owner, itemType, err := s.chain.Owner(ctx, itemID)
+	if err != nil {
+		return Gift{}, fmt.Errorf("could not look up item %s: %w", itemID, err)
+	}
+	if !strings.EqualFold(owner, fromAddress) {
+		return Gift{}, ErrNotOwner
+	}
+
+	now := s.now()
+	g := Gift{
+		ID:          newGiftID(),
+		From:        from,
+		To:          to,
+		FromAddress: fromAddress,
+		ToAddress:   toAddress,
+		ItemID:      itemID,
+		ItemType:    itemType,
+		Note:        note,
+		Value:       s.Value(itemType),
+		CreatedAt:   now,
+	}
+	g.NeedsAcceptance = g.Value > s.opts.AcceptThreshold
+	g.Custody = g.NeedsAcceptance && s.custody != ""
+	if err := s.spend(from, g.Value); err != nil {
+		return Gift{}, err
+	}
+	if err := s.items.Reserve(g.holder(), s.opts.AcceptTTL+s.opts.TransferTTL, itemID); err != nil {
+		s.unspend(from, g.Value)
+		return Gift{}, err
+	}
+	switch {
+	case g.Custody:
+		g.Status, g.Deadline = StatusAwaitingDeposit, now.Add(s.opts.TransferTTL)
+		g.TxBytes, err = s.chain.BuildTransfer(ctx, fromAddress, itemID, s.custody)
+	case g.NeedsAcceptance:
+		g.Status, g.Deadline = StatusPending, now.Add(s.opts.AcceptTTL)
+	default:
+		g.Status, g.Deadline = StatusAwaitingTransfer, now.Add(s.opts.TransferTTL)
+		g.TxBytes, err = s.chain.BuildTransfer(ctx, fromAddress, itemID, toAddress)
+	}
+	if err != nil {
+		s.items.Release(g.holder())
+		s.unspend(from, g.Value)
+		return Gift{}, fmt.Errorf("could not build the transfer: %w", err)
+	}
+
+	s.mu.Lock()
+	g = s.setStatusLocked(g, g.Status)
+	deliveries := s.notifyLocked(g)
+	s.mu.Unlock()
+	deliver(deliveries)
+	if g.Status == StatusPending {
+		s.mailOffer(g)
+	}
+	utils.LogInfof("Gift: %s offered %s to %s in %s (value %d MIST, %s).", from, itemID, to, g.ID, g.Value, g.Status)
+	return g, nil
+}
+
+// Respond accepts or declines a gift waiting for playerID's acceptance. An
+// accepted gift in custody is released to them; otherwise the sender gets the
+// transfer to sign.
+func (s *Service) Respond(ctx context.Context, playerID, giftID string, accept bool) (Gift, error) {
+	s.mu.Lock()
+	g, err := s.giftLocked(giftID)
+	if err == nil && g.To != playerID {
+		err = ErrNotYourGift
+	}
+	if err == nil && g.Status != StatusPending {
+		err = ErrWrongState
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Gift{}, err
+	}
+	if !accept || g.Custody {
+		defer s.mu.Unlock()
+		switch {
+		case !accept && g.Deposited:
+			g, err = s.custodyJobLocked(g, CustodyReturnKind, StatusReturning, "declined")
+		case !accept:
+			g = s.setStatusLocked(g, StatusDeclined)
+		default:
+			g, err = s.custodyJobLocked(g, CustodyReleaseKind, StatusReleasing, "")
+		}
+		if err != nil {
+			return Gift{}, err
+		}
+		deliver(s.notifyLocked(g))
+		utils.LogInfof("Gift: %s answered %s (%s).", playerID, g.ID, g.Status)
+		return g, nil
+	}
+	s.mu.Unlock()
+
+	txBytes, err := s.chain.BuildTransfer(ctx, g.FromAddress, g.ItemID, g.ToAddress)
+	if err != nil {
+		return Gift{}, fmt.Errorf("could not build the transfer: %w", err)
+	}
+	s.mu.Lock()
+	if g, err = s.giftLocked(giftID); err == nil && g.Status != StatusPending {
+		err = ErrWrongState // Cancelled or expired meanwhile
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Gift{}, err
+	}
+	g.TxBytes, g.Deadline = txBytes, s.now().Add(s.opts.TransferTTL)
+	g = s.setStatusLocked(g, StatusAwaitingTransfer)
+	deliveries := s.notifyLocked(g)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Gift: %s accepted %s; waiting for the transfer.", playerID, g.ID)
+	return g, nil
+}
+
+// Cancel withdraws a gift playerID sent, before it was delivered. An item
+// that may have reached custody is returned.
+func (s *Service) Cancel(playerID, giftID string) (Gift, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.giftLocked(giftID)
+	if err == nil && g.From != playerID {
+		err = ErrNotYourGift
+	}
+	if err == nil && g.Status != StatusAwaitingDeposit && g.Status != StatusPending && g.Status != StatusAwaitingTransfer {
+		err = ErrWrongState
+	}
+	if err != nil {
+		return Gift{}, err
+	}
+	if g.Custody {
+		g, err = s.custodyJobLocked(g, CustodyReturnKind, StatusReturning, "cancelled")
+		if err != nil {
+			return Gift{}, err
+		}
+	} else {
+		g = s.setStatusLocked(g, StatusCancelled)
+	}
+	deliver(s.notifyLocked(g))
+	utils.LogInfof("Gift: %s cancelled %s (%s).", playerID, g.ID, g.Status)
+	return g, nil
+}
+
+// Sent checks the transfer playerID signed for a gift, reported by its
+// digest. A deposit into custody puts the gift before the recipient; a
+// transfer to the recipient completes it.
+func (s *Service) Sent(ctx context.Context, playerID, giftID, txDigest string) (Gift, error) {
+	s.mu.Lock()
+	g, err := s.giftLocked(giftID)
+	if err == nil && g.From != playerID {
+		err = ErrNotYourGift
+	}
+	if err == nil && g.Status != StatusAwaitingDeposit && g.Status != StatusAwaitingTransfer {
+		err = ErrWrongState
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return Gift{}, err
+	}
+	recipient := g.ToAddress
+	if g.Status == StatusAwaitingDeposit {
+		recipient = s.custody
+	}
+	if err := s.chain.VerifyTransfer(ctx, txDigest, g.FromAddress, g.ItemID, recipient); err != nil {
+		return Gift{}, fmt.Errorf("%w: %v", ErrTransferUnverified, err)
+	}
+
+	s.mu.Lock()
+	status := g.Status
+	if g, err = s.giftLocked(giftID); err == nil && g.Status != status {
+		err = ErrWrongState
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Gift{}, err
+	}
+	g.TxBytes = ""
+	if status == StatusAwaitingDeposit {
+		g.Deposited, g.Deadline = true, s.now().Add(s.opts.AcceptTTL)
+		g = s.setStatusLocked(g, StatusPending)
+	} else {
+		g.TxDigest = txDigest
+		g = s.setStatusLocked(g, StatusCompleted)
+	}
+	deliveries := s.notifyLocked(g)
+	s.mu.Unlock()
+	deliver(deliveries)
+	if g.Status == StatusPending {
+		s.mailOffer(g)
+	} else {
+		s.mailReceived(g)
+	}
+	utils.LogInfof("Gift: Transfer %s of %s verified (%s).", txDigest, g.ID, g.Status)
+	return g, nil
+}
+
+// Gifts returns playerID's open gifts, sent and received.
+func (s *Service) Gifts(playerID string) []Gift {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var gifts []Gift
+	for _, g := range s.state.Gifts {
+		if g.Involves(playerID) {
+			gifts = append(gifts, g)
+		}
+	}
+	return gifts
+}
+
+// History returns playerID's finished gifts, sent and received, newest first.
+func (s *Service) History(playerID string) []Gift {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.state.History[playerID]
+	gifts := make([]Gift, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		gifts = append(gifts, history[i])
+	}
+	return gifts
+}
+
+// Tick times out gifts past their deadline. A gift that may have reached
+// custody is returned.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	var deliveries []func()
+	for id, g := range s.state.Gifts {
+		if now.Before(g.Deadline) {
+			continue
+		}
+		var err error
+		switch {
+		case g.Status == StatusAwaitingDeposit || (g.Status == StatusPending && g.Deposited):
+			g, err = s.custodyJobLocked(g, CustodyReturnKind, StatusReturning, "expired")
+		case g.Status == StatusPending || g.Status == StatusAwaitingTransfer:
+			g = s.setStatusLocked(g, StatusExpired)
+		default:
+			continue
+		}
+		if err != nil {
+			utils.LogErrorf("Gift: Could not queue the return of expired gift %s: %v", id, err)
+			continue
+		}
+		utils.LogInfof("Gift: %s timed out (%s).", id, g.Status)
+		deliveries = append(deliveries, s.notifyLocked(g)...)
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// Name implements privacy.DeletionGuard and transfer.Guard.
+func (s *Service) Name() string { return "gifts" }
+
+// CheckDeletion implements privacy.DeletionGuard: a player's data cannot be
+// deleted while custody may hold a gift of theirs.
+func (s *Service) CheckDeletion(ctx context.Context, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := 0
+	for _, g := range s.state.Gifts {
+		if g.Involves(playerID) && g.Custody && (g.Deposited || g.Status == StatusAwaitingDeposit || g.Status == StatusReturning) {
+			held++
+		}
+	}
+	if held > 0 {
+		return fmt.Errorf("%d gift(s) in custody", held)
+	}
+	return nil
+}
+
+// CheckTransfer implements transfer.Guard: a player cannot move to another
+// shard while custody may hold a gift of theirs.
+func (s *Service) CheckTransfer(ctx context.Context, playerID string) error {
+	return s.CheckDeletion(ctx, playerID)
+}
+
+// releaseCustody handles CustodyReleaseKind.
+func (s *Service) releaseCustody(ctx context.Context, payload json.RawMessage) error {
+	g, ok, err := s.jobGift(payload, StatusReleasing)
+	if !ok {
+		return err
+	}
+	digest, err := s.chain.Transfer(ctx, g.ItemID, g.ToAddress)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	g = s.state.Gifts[g.ID]
+	g.TxDigest = digest
+	g = s.setStatusLocked(g, StatusCompleted)
+	deliveries := s.notifyLocked(g)
+	s.mu.Unlock()
+	deliver(deliveries)
+	s.mailReceived(g)
+	utils.LogInfof("Gift: Custody released %s to %s in %s.", g.ID, g.To, digest)
+	return nil
+}
+
+// returnCustody handles CustodyReturnKind. The item goes back to the sender
+// if custody holds it; a gift whose item never arrived just ends.
+func (s *Service) returnCustody(ctx context.Context, payload json.RawMessage) error {
+	g, ok, err := s.jobGift(payload, StatusReturning)
+	if !ok {
+		return err
+	}
+	owner, _, err := s.chain.Owner(ctx, g.ItemID)
+	if err != nil {
+		return err
+	}
+	status, digest := reasonStatus(g.Reason), ""
+	if strings.EqualFold(owner, s.custody) {
+		if digest, err = s.chain.Transfer(ctx, g.ItemID, g.FromAddress); err != nil {
+			return err
+		}
+		status = StatusReturned
+	}
+	s.mu.Lock()
+	g = s.state.Gifts[g.ID]
+	g.TxDigest = digest
+	g = s.setStatusLocked(g, status)
+	deliveries := s.notifyLocked(g)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Gift: %s ended (%s, %s).", g.ID, g.Reason, status)
+	return nil
+}
+
+// jobGift decodes a custody job and returns its gift if it is still in
+// status. A job for a gift that moved on is done (ok is false, err nil).
+func (s *Service) jobGift(payload json.RawMessage, status Status) (Gift, bool, error) {
+	var job CustodyJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return Gift{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.state.Gifts[job.GiftID]
+	if !ok || g.Status != status {
+		utils.LogWarnf("Gift: Dropping custody job for %s, which is no longer %s.", job.GiftID, status)
+		return Gift{}, false, nil
+	}
+	return g, true, nil
+}
+
+// custodyJobLocked queues a custody transfer of kind and moves g to status.
+func (s *Service) custodyJobLocked(g Gift, kind string, status Status, reason string) (Gift, error) {
+	if s.jobs == nil {
+		return g, errors.New("gift custody is not enabled")
+	}
+	if _, err := s.jobs.Enqueue(kind+":"+g.ID, kind, CustodyJob{GiftID: g.ID}); err != nil {
+		return g, fmt.Errorf("could not queue the custody transfer: %w", err)
+	}
+	g.Reason, g.TxBytes = reason, ""
+	return s.setStatusLocked(g, status), nil
+}
+
+func (s *Service) giftLocked(giftID string) (Gift, error) {
+	g, ok := s.state.Gifts[giftID]
+	if !ok {
+		return Gift{}, ErrUnknownGift
+	}
+	return g, nil
+}
+
+// setStatusLocked stores g with status and saves the state. A finished gift
+// moves to both players' history.
+func (s *Service) setStatusLocked(g Gift, status Status) Gift {
+	g.Status = status
+	g.UpdatedAt = s.now()
+	if status.Final() {
+		g.TxBytes = ""
+		delete(s.state.Gifts, g.ID)
+		for _, playerID := range []string{g.From, g.To} {
+			history := append(s.state.History[playerID], g)
+			if excess := len(history) - s.opts.HistoryLimit; excess > 0 {
+				history = history[excess:]
+			}
+			s.state.History[playerID] = history
+		}
+		s.items.Release(g.holder())
+	} else {
+		s.state.Gifts[g.ID] = g
+	}
+	s.saveLocked()
+	s.auditLocked(g)
+	return g
+}
+
+// auditLocked records g's current status in the audit log.
+func (s *Service) auditLocked(g Gift) {
+	detail := fmt.Sprintf("item %s, value %d MIST", g.ItemID, g.Value)
+	if g.Custody {
+		detail += ", custody"
+	}
+	if g.TxDigest != "" {
+		detail += ", tx " + g.TxDigest
+	}
+	s.audit.Record(audit.Entry{Action: audit.ActionGift, PlayerID: g.From, Peer: g.To, Target: g.ID, Result: string(g.Status), Detail: detail})
+}
+
+// notifyLocked returns the notifications of g's players to deliver once the lock is released.
+func (s *Service) notifyLocked(g Gift) []func() {
+	var deliveries []func()
+	note := &Update{Gift: g}
+	for _, playerID := range []string{g.From, g.To} {
+		if notify := s.notifiers[playerID]; notify != nil {
+			deliveries = append(deliveries, func() { notify(note) })
+		}
+	}
+	return deliveries
+}
+
+// mailOffer tells the recipient a gift waits for their answer.
+func (s *Service) mailOffer(g Gift) {
+	s.mail.Send(mail.Message{
+		To:      g.To,
+		From:    g.From,
+		Kind:    "gift.offered",
+		Subject: "You have been offered a gift",
+		Body:    fmt.Sprintf("%s wants to give you item %s. Accept or decline it by %s.", g.From, g.ItemID, g.Deadline.UTC().Format("2006-01-02 15:04 MST")),
+		Ref:     g.ID,
+	})
+}
+
+// mailReceived tells the recipient a gift is theirs.
+func (s *Service) mailReceived(g Gift) {
+	s.mail.Send(mail.Message{
+		To:      g.To,
+		From:    g.From,
+		Kind:    "gift.received",
+		Subject: "You received a gift",
+		Body:    fmt.Sprintf("%s gave you item %s.", g.From, g.ItemID),
+		Ref:     g.ID,
+	})
+}
+
+func (s *Service) saveLocked() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Gift: Could not save gift state: %v", err)
+	}
+}
+
+// reasonStatus is the final status of a gift that ended for reason without
+// its item reaching custody.
+func reasonStatus(reason string) Status {
+	switch reason {
+	case "declined":
+		return StatusDeclined
+	case "cancelled":
+		return StatusCancelled
+	}
+	return StatusExpired
+}
+
+func deliver(deliveries []func()) {
+	for _, d := range deliveries {
+		d()
+	}
+}
+
+func newGiftID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "gift-" + hex.EncodeToString(b)
+}
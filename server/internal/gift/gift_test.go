@@ -0,0 +1,185 @@
+package gift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+)
+
+type addressBook map[string]string
+
+func (b addressBook) Address(ctx context.Context, playerID string) (string, error) {
+	if address, ok := b[playerID]; ok {
+		return address, nil
+	}
+	return "", errors.New("not linked")
+}
+
+// fakeChain owns objects by address and accepts any transfer it built.
+type fakeChain struct {
+	owners    map[string]string
+	built     map[string]string // txBytes -> recipient
+	transfers []string          // Custody transfers, as "object->recipient"
+}
+
+func (f *fakeChain) Owner(ctx context.Context, objectID string) (string, string, error) {
+	if objectID == "0xcrown" {
+		return f.owners[objectID], "0xpkg::item::Crown", nil
+	}
+	return f.owners[objectID], "0xpkg::item::Sword", nil
+}
+
+func (f *fakeChain) BuildTransfer(ctx context.Context, sender, objectID, recipient string) (string, error) {
+	txBytes := "tx:" + objectID + "->" + recipient
+	f.built[txBytes] = recipient
+	return txBytes, nil
+}
+
+func (f *fakeChain) VerifyTransfer(ctx context.Context, txDigest, sender, objectID, recipient string) error {
+	if f.built[txDigest] != recipient || f.owners[objectID] != sender {
+		return errors.New("no such transfer")
+	}
+	f.owners[objectID] = recipient
+	return nil
+}
+
+func (f *fakeChain) Transfer(ctx context.Context, objectID, recipient string) (string, error) {
+	f.owners[objectID] = recipient
+	f.transfers = append(f.transfers, objectID+"->"+recipient)
+	return "0xdigest", nil
+}
+
+// runJobs delivers every queued custody job as the outbox would.
+func runJobs(t *testing.T, s *Service, store *outbox.MemoryStore) {
+	t.Helper()
+	messages, err := store.Due(time.Now().Add(time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers := map[string]outbox.Handler{
+		CustodyReleaseKind: s.releaseCustody,
+		CustodyReturnKind:  s.returnCustody,
+	}
+	for _, msg := range messages {
+		if err := handlers[msg.Kind](context.Background(), msg.Payload); err != nil {
+			t.Fatalf("%s: %v", msg.Kind, err)
+		}
+		store.Delete(msg.ID)
+	}
+}
+
+func TestGiftFlows(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{
+		owners: map[string]string{"0xsword": "0xa", "0xcrown": "0xa"},
+		built:  make(map[string]string),
+	}
+	s, err := NewService(Options{AcceptThreshold: 1000, ItemValue: 100, TypeValues: map[string]uint64{"item::Crown": 5000}},
+		&MemoryStore{}, chain, addressBook{"alice": "0xa", "bob": "0xb", "carol": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, jobs := reservation.NewRegistry(), outbox.NewMemoryStore()
+	s.UseReservations(items)
+	s.UseCustody("0xcustody", outbox.New(jobs, outbox.Options{}))
+	var bobUpdates []Status
+	s.Connect("bob", func(note interface{}) { bobUpdates = append(bobUpdates, note.(*Update).Gift.Status) })
+
+	if _, err := s.Send(ctx, "bob", "alice", "0xsword", ""); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("gift of someone else's item: err = %v", err)
+	}
+	if _, err := s.Send(ctx, "alice", "carol", "0xsword", ""); !errors.Is(err, ErrNoRecipient) {
+		t.Fatalf("gift to a player without an address: err = %v", err)
+	}
+
+	// A cheap item goes straight to the recipient once the sender signs.
+	g, err := s.Send(ctx, "alice", "bob", "0xsword", "for you")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Status != StatusAwaitingTransfer || g.NeedsAcceptance || g.TxBytes != "tx:0xsword->0xb" {
+		t.Fatalf("cheap gift = %+v", g)
+	}
+	if _, err := s.Send(ctx, "alice", "bob", "0xsword", ""); !errors.Is(err, reservation.ErrReserved) {
+		t.Fatalf("second gift of the same item: err = %v", err)
+	}
+	if g, err = s.Sent(ctx, "alice", g.ID, g.TxBytes); err != nil || g.Status != StatusCompleted {
+		t.Fatalf("sent: %+v, %v", g, err)
+	}
+
+	// A valuable item waits in custody for the recipient, who declines it.
+	g, err = s.Send(ctx, "alice", "bob", "0xcrown", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Status != StatusAwaitingDeposit || !g.Custody || g.TxBytes != "tx:0xcrown->0xcustody" {
+		t.Fatalf("valuable gift = %+v", g)
+	}
+	if _, err := s.Respond(ctx, "bob", g.ID, true); !errors.Is(err, ErrWrongState) {
+		t.Fatalf("accepted before the deposit: err = %v", err)
+	}
+	if g, err = s.Sent(ctx, "alice", g.ID, g.TxBytes); err != nil || g.Status != StatusPending || !g.Deposited {
+		t.Fatalf("deposited: %+v, %v", g, err)
+	}
+	if g, err = s.Respond(ctx, "bob", g.ID, false); err != nil || g.Status != StatusReturning {
+		t.Fatalf("declined: %+v, %v", g, err)
+	}
+	runJobs(t, s, jobs)
+	if chain.owners["0xcrown"] != "0xa" {
+		t.Fatalf("crown is with %s after the decline, want the sender", chain.owners["0xcrown"])
+	}
+
+	// Sent again, it is accepted and released to the recipient.
+	if g, err = s.Send(ctx, "alice", "bob", "0xcrown", ""); err != nil {
+		t.Fatal(err)
+	}
+	s.Sent(ctx, "alice", g.ID, g.TxBytes)
+	if g, err = s.Respond(ctx, "bob", g.ID, true); err != nil || g.Status != StatusReleasing {
+		t.Fatalf("accepted: %+v, %v", g, err)
+	}
+	runJobs(t, s, jobs)
+	if chain.owners["0xcrown"] != "0xb" {
+		t.Fatalf("crown is with %s after the acceptance, want the recipient", chain.owners["0xcrown"])
+	}
+
+	if open := s.Gifts("alice"); len(open) != 0 {
+		t.Fatalf("open gifts = %+v", open)
+	}
+	var statuses []Status
+	for _, g := range s.History("bob") {
+		statuses = append(statuses, g.Status)
+	}
+	if len(statuses) != 3 || statuses[0] != StatusCompleted || statuses[1] != StatusReturned || statuses[2] != StatusCompleted {
+		t.Fatalf("history = %v", statuses)
+	}
+	if _, held := items.Holder("0xcrown"); held {
+		t.Fatal("crown still reserved after the gift finished")
+	}
+	if len(bobUpdates) == 0 {
+		t.Fatal("recipient got no updates")
+	}
+}
+
+func TestGiftExpiryReturnsUnansweredCustodyGift(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{owners: map[string]string{"0xcrown": "0xa"}, built: make(map[string]string)}
+	s, err := NewService(Options{AcceptThreshold: 10, ItemValue: 100}, &MemoryStore{}, chain, addressBook{"alice": "0xa", "bob": "0xb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := outbox.NewMemoryStore()
+	s.UseCustody("0xcustody", outbox.New(jobs, outbox.Options{}))
+
+	g, _ := s.Send(ctx, "alice", "bob", "0xcrown", "")
+	s.Sent(ctx, "alice", g.ID, g.TxBytes)
+	s.Tick(time.Now().Add(DefaultAcceptTTL + time.Minute))
+	runJobs(t, s, jobs)
+	history := s.History("alice")
+	if len(history) != 1 || history[0].Status != StatusReturned || history[0].Reason != "expired" || chain.owners["0xcrown"] != "0xa" {
+		t.Fatalf("history = %+v, crown with %s", history, chain.owners["0xcrown"])
+	}
+}
@@ -0,0 +1,108 @@
+package gift
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/jsonstore"
+)
+
+// Status is where a gift is in its flow.
+type Status string
+
+const (
+	StatusAwaitingDeposit  Status = "awaiting_deposit"  // The sender is to transfer the item into custody
+	StatusPending          Status = "pending"           // Waiting for the recipient to accept
+	StatusAwaitingTransfer Status = "awaiting_transfer" // The sender is to transfer the item to the recipient
+	StatusReleasing        Status = "releasing"         // Accepted; custody is transferring the item to the recipient
+	StatusReturning        Status = "returning"         // Declined, cancelled or expired; custody is returning the item
+	StatusCompleted        Status = "completed"         // The recipient has the item
+	StatusDeclined         Status = "declined"          // Refused by the recipient before any transfer
+	StatusCancelled        Status = "cancelled"         // Withdrawn by the sender before any transfer
+	StatusExpired          Status = "expired"           // Not transferred or answered in time
+	StatusReturned         Status = "returned"          // Custody gave the item back to the sender
+)
+
+// Final reports whether a gift in this status is over.
+func (s Status) Final() bool {
+	switch s {
+	case StatusCompleted, StatusDeclined, StatusCancelled, StatusExpired, StatusReturned:
+		return true
+	}
+	return false
+}
+
+// Gift is one item NFT given by one player to another.
+type Gift struct {
+	ID              string    `json:"id"`
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	FromAddress     string    `json:"fromAddress"`
+	ToAddress       string    `json:"toAddress"`
+	ItemID          string    `json:"itemId"`   // Object ID of the item NFT
+	ItemType        string    `json:"itemType"` // Its Move type
+	Note            string    `json:"note,omitempty"`
+	Value           uint64    `json:"value"`              // Estimated MIST value
+	NeedsAcceptance bool      `json:"needsAcceptance"`    // Worth more than the threshold
+	Custody         bool      `json:"custody"`            // Held by the server's custody address until accepted
+	Deposited       bool      `json:"deposited"`          // Custody holds the item
+	Reason          string    `json:"reason,omitempty"`   // Why a returning or returned gift ended: declined, cancelled or expired
+	TxBytes         string    `json:"txBytes,omitempty"`  // Unsigned transfer for the sender, while one is due
+	TxDigest        string    `json:"txDigest,omitempty"` // Transaction that delivered the item
+	Status          Status    `json:"status"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	Deadline        time.Time `json:"deadline"` // When the current step times out
+}
+
+// Involves reports whether playerID sent or receives the gift.
+func (g Gift) Involves(playerID string) bool {
+	return g.From == playerID || g.To == playerID
+}
+
+// holder is the reservation holder of the gift's item.
+func (g Gift) holder() string {
+	return "gift:" + g.ID
+}
+
+// State is the persisted gift state: open gifts, and every player's finished
+// gifts, newest last.
+type State struct {
+	Gifts   map[string]Gift   `json:"gifts"`
+	History map[string][]Gift `json:"history"`
+}
+
+// Store persists the gift state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the gift state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// NewFileStore keeps the gift state in a journal at path, each open gift and
+// each player's history its own record: a change to one gift in custody
+// appends that gift rather than rewriting every gift.
+func NewFileStore(path string) *jsonstore.Journal[State] {
+	return jsonstore.NewJournal[State](path, records{})
+}
+
+// records splits the gift state into its open gifts and histories.
+type records struct{}
+
+// Split implements jsonstore.Records.
+func (records) Split(state State) map[string]any {
+	r := make(map[string]any, len(state.Gifts)+len(state.History))
+	jsonstore.PutRecords(r, "gift/", state.Gifts)
+	jsonstore.PutRecords(r, "history/", state.History)
+	return r
+}
+
+// Join implements jsonstore.Records.
+func (records) Join(r map[string]json.RawMessage) (State, error) {
+	var state State
+	var err error
+	state.Gifts, err = jsonstore.TakeRecords[Gift](r, "gift/")
+	if err == nil {
+		state.History, err = jsonstore.TakeRecords[[]Gift](r, "history/")
+	}
+	return state, err
+}
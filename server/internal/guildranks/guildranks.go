@@ -0,0 +1,599 @@
+// Package guildranks keeps each guild's permission matrix. Every guild has
+// a leader, who may do anything, and officer and member ranks; leaders
+// redefine those two and add custom ranks with their own permissions: who may
+// invite and kick players, take from the guild bank and how much per day,
+// and schedule guild events. Gameplay flows ask the service before acting.
+//
+// The guild contract only knows officers and members, so each rank maps to
+// one of those. Changes that reach the chain come back as unsigned
+// transactions for the acting player to sign, as gifts do; the roster file is
+// updated by the chain mirror once they execute.
+package guildranks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/model"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultMaxCustomRanks        = 8
+	DefaultOfficerWithdrawPerDay = 10000
+)
+
+const maxRankName = 24
+
+var (
+	ErrNoGuild      = errors.New("you are not in a guild")
+	ErrNotLeader    = errors.New("only the guild leader can change ranks")
+	ErrNotPermitted = errors.New("your guild rank does not allow that")
+	ErrInvalid      = errors.New("invalid guild rank")
+	ErrNoRank       = errors.New("no such guild rank")
+	ErrTooMany      = errors.New("the guild has too many ranks")
+	ErrNotMember    = errors.New("that player is not in your guild")
+	ErrInGuild      = errors.New("that player is already in a guild")
+	ErrOverLimit    = errors.New("that is more than your rank may take from the guild bank today")
+	ErrNoWallet     = errors.New("link a wallet to make guild changes on chain")
+	ErrLeaderExempt = errors.New("the guild leader's rank cannot be changed")
+)
+
+// Permission is something a rank allows.
+type Permission string
+
+const (
+	PermInvite       Permission = "invite"       // Invite players into the guild
+	PermKick         Permission = "kick"         // Remove members below the leader
+	PermBankWithdraw Permission = "bankWithdraw" // Take game coin from the guild bank, up to the rank's daily limit
+	PermEvents       Permission = "events"       // Schedule and cancel guild events
+)
+
+// Permissions lists every permission.
+var Permissions = []Permission{PermInvite, PermKick, PermBankWithdraw, PermEvents}
+
+// Built-in ranks.
+const (
+	Leader  = "leader"
+	Officer = "officer"
+	Member  = "member"
+)
+
+// Ranks of the guild contract that ranks map to.
+const (
+	ChainOfficer = "officer"
+	ChainMember  = "member"
+)
+
+// Rank is a named set of permissions.
+type Rank struct {
+	Name           string       `json:"name"`
+	Permissions    []Permission `json:"permissions,omitempty"`
+	WithdrawPerDay uint64       `json:"withdrawPerDay,omitempty"` // Game coin a holder may take from the bank per UTC day with bankWithdraw; 0 is no limit
+	OnChainRank    string       `json:"onChainRank"`              // ChainOfficer or ChainMember
+}
+
+// Has reports whether r allows p.
+func (r Rank) Has(p Permission) bool {
+	for _, have := range r.Permissions {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Matrix is one guild's ranks and every member's rank.
+type Matrix struct {
+	GuildID string
+	Ranks   []Rank            // Leader, officer and member first, then custom ranks by name
+	Members map[string]string // Player ID -> rank name
+}
+
+// Action is a guild change a player was allowed to make.
+type Action struct {
+	Kind      string // "invite", "kick", "withdraw" or "rank"
+	GuildID   string
+	PlayerID  string // Who acts
+	TargetID  string // Player invited, kicked or ranked
+	Rank      string // New rank, for "rank"
+	Amount    uint64 // Game coin, for "withdraw"
+	Remaining uint64 // Game coin the player may still withdraw today, for "withdraw" under a limit
+	TxBytes   string // Unsigned transaction for PlayerID to sign; empty if nothing changes on chain
+}
+
+// Chain builds the unsigned guild contract transactions of actions.
+type Chain interface {
+	BuildAddMember(ctx context.Context, signer, guildObjectID, player string) (string, error)
+	BuildRemoveMember(ctx context.Context, signer, guildObjectID, player string) (string, error)
+	BuildSetRank(ctx context.Context, signer, guildObjectID, member, onChainRank string) (string, error)
+	BuildWithdraw(ctx context.Context, signer, guildObjectID string, amount uint64) (string, error)
+}
+
+// AddressResolver returns a player's Sui address. accountlink.Service implements it.
+type AddressResolver interface {
+	Address(ctx context.Context, playerID string) (string, error)
+}
+
+// Options configures a Service.
+type Options struct {
+	MaxCustomRanks        int    // Ranks a guild may add besides the built-in ones
+	OfficerWithdrawPerDay uint64 // Daily bank limit of the officer rank until a leader redefines it
+}
+
+// Service keeps the permission matrices. It is safe for concurrent use.
+type Service struct {
+	store     Store
+	roster    *guilds.Roster
+	opts      Options
+	chain     Chain           // Nil makes no changes on chain
+	addresses AddressResolver // Set with chain
+	now       func() time.Time
+
+	mu    sync.Mutex
+	state State
+}
+
+// NewService creates a Service for the guilds of roster and loads its state.
+func NewService(store Store, roster *guilds.Roster, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load guild ranks: %w", err)
+	}
+	if state.Guilds == nil {
+		state.Guilds = make(map[string]*GuildRanks)
+	}
+	if opts.MaxCustomRanks <= 0 {
+		opts.MaxCustomRanks = DefaultMaxCustomRanks
+	}
+	if opts.OfficerWithdrawPerDay == 0 {
+		opts.OfficerWithdrawPerDay = DefaultOfficerWithdrawPerDay
+	}
+	return &Service{store: store, roster: roster, opts: opts, now: time.Now, state: state}, nil
+}
+
+// UseChain has actions come with the guild contract transaction that makes
+// them, signed by the acting player's address from addresses.
+func (s *Service) UseChain(chain Chain, addresses AddressResolver) {
+	s.chain, s.addresses = chain, addresses
+}
+
+// Can reports whether playerID's rank in guildID allows p.
+func (s *Service) Can(guildID, playerID string, p Permission) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok || guild.ID != guildID {
+		return false
+	}
+	return s.rankOf(guild, playerID).Has(p)
+}
+
+// Matrix returns the ranks of playerID's guild.
+func (s *Service) Matrix(playerID string) (Matrix, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return Matrix{}, ErrNoGuild
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.matrix(guild), nil
+}
+
+// DefineRank adds or redefines a rank of playerID's guild; playerID must lead
+// it. The leader rank cannot be redefined.
+func (s *Service) DefineRank(playerID string, rank Rank) (Matrix, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Matrix{}, err
+	}
+	rank, err = s.validate(rank)
+	if err != nil {
+		return Matrix{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ranks := s.ranks(guild.ID)
+	custom := 0
+	replaced := false
+	for i, r := range ranks.Ranks {
+		if r.Name == rank.Name {
+			ranks.Ranks[i], replaced = rank, true
+		} else if r.Name != Officer && r.Name != Member {
+			custom++
+		}
+	}
+	if !replaced {
+		if rank.Name != Officer && rank.Name != Member && custom >= s.opts.MaxCustomRanks {
+			return Matrix{}, fmt.Errorf("%w: at most %d custom ranks", ErrTooMany, s.opts.MaxCustomRanks)
+		}
+		ranks.Ranks = append(ranks.Ranks, rank)
+	}
+	s.save()
+	utils.LogInfof("Guild ranks: %s defined rank %q of guild %s as %v (withdraw %d/day, on chain %s).", playerID, rank.Name, guild.ID, rank.Permissions, rank.WithdrawPerDay, rank.OnChainRank)
+	return s.matrix(guild), nil
+}
+
+// DeleteRank removes a custom rank of playerID's guild, whose holders go
+// back to their default rank, or restores the officer or member rank to the
+// server's definition. playerID must lead the guild.
+func (s *Service) DeleteRank(playerID, name string) (Matrix, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Matrix{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ranks := s.ranks(guild.ID)
+	kept := ranks.Ranks[:0]
+	for _, r := range ranks.Ranks {
+		if r.Name != name {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(ranks.Ranks) {
+		return Matrix{}, ErrNoRank
+	}
+	ranks.Ranks = kept
+	for memberID, rank := range ranks.Members {
+		if rank == name && name != Officer && name != Member {
+			delete(ranks.Members, memberID)
+		}
+	}
+	s.save()
+	utils.LogInfof("Guild ranks: %s deleted rank %q of guild %s.", playerID, name, guild.ID)
+	return s.matrix(guild), nil
+}
+
+// Assign gives memberID of playerID's guild the rank called name; playerID
+// must lead the guild. If the rank maps to another contract rank than the
+// member's last one, the action carries the promotion or demotion for the
+// leader to sign.
+func (s *Service) Assign(ctx context.Context, playerID, memberID, name string) (Action, error) {
+	guild, err := s.leading(playerID)
+	if err != nil {
+		return Action{}, err
+	}
+	if other, ok := s.roster.GuildOf(memberID); !ok || other.ID != guild.ID {
+		return Action{}, ErrNotMember
+	}
+	if memberID == guild.LeaderID || name == Leader {
+		return Action{}, ErrLeaderExempt
+	}
+	s.mu.Lock()
+	rank, ok := s.rank(guild.ID, name)
+	from := s.rankOf(guild, memberID)
+	s.mu.Unlock()
+	if !ok {
+		return Action{}, ErrNoRank
+	}
+
+	action := Action{Kind: "rank", GuildID: guild.ID, PlayerID: playerID, TargetID: memberID, Rank: rank.Name}
+	if rank.OnChainRank != from.OnChainRank {
+		action.TxBytes, err = s.build(ctx, playerID, guild, func(signer string) (string, error) {
+			target, err := s.address(ctx, memberID)
+			if err != nil {
+				return "", err
+			}
+			return s.chain.BuildSetRank(ctx, signer, guild.SuiObjectID, target, rank.OnChainRank)
+		})
+		if err != nil {
+			return Action{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rank(guild.ID, name); !ok {
+		return Action{}, ErrNoRank // Deleted while the transaction was built
+	}
+	ranks := s.ranks(guild.ID)
+	if ranks.Members == nil {
+		ranks.Members = make(map[string]string)
+	}
+	if name == s.defaultRank(guild, memberID) {
+		delete(ranks.Members, memberID)
+	} else {
+		ranks.Members[memberID] = name
+	}
+	s.save()
+	utils.LogInfof("Guild ranks: %s made %s of guild %s a %q (was %q).", playerID, memberID, guild.ID, rank.Name, from.Name)
+	return action, nil
+}
+
+// Invite lets playerID invite targetID, who is in no guild, into their guild.
+func (s *Service) Invite(ctx context.Context, playerID, targetID string) (Action, error) {
+	guild, err := s.allowed(playerID, PermInvite)
+	if err != nil {
+		return Action{}, err
+	}
+	if _, ok := s.roster.GuildOf(targetID); ok {
+		return Action{}, ErrInGuild
+	}
+	action := Action{Kind: "invite", GuildID: guild.ID, PlayerID: playerID, TargetID: targetID}
+	action.TxBytes, err = s.build(ctx, playerID, guild, func(signer string) (string, error) {
+		target, err := s.address(ctx, targetID)
+		if err != nil {
+			return "", err
+		}
+		return s.chain.BuildAddMember(ctx, signer, guild.SuiObjectID, target)
+	})
+	if err != nil {
+		return Action{}, err
+	}
+	utils.LogInfof("Guild ranks: %s invited %s into guild %s.", playerID, targetID, guild.ID)
+	return action, nil
+}
+
+// Kick lets playerID remove targetID from their guild. Nobody kicks the
+// leader, and only the leader kicks players whose rank may kick.
+func (s *Service) Kick(ctx context.Context, playerID, targetID string) (Action, error) {
+	guild, err := s.allowed(playerID, PermKick)
+	if err != nil {
+		return Action{}, err
+	}
+	if other, ok := s.roster.GuildOf(targetID); !ok || other.ID != guild.ID || targetID == playerID {
+		return Action{}, ErrNotMember
+	}
+	s.mu.Lock()
+	protected := targetID == guild.LeaderID || (playerID != guild.LeaderID && s.rankOf(guild, targetID).Has(PermKick))
+	s.mu.Unlock()
+	if protected {
+		return Action{}, ErrNotPermitted
+	}
+	action := Action{Kind: "kick", GuildID: guild.ID, PlayerID: playerID, TargetID: targetID}
+	action.TxBytes, err = s.build(ctx, playerID, guild, func(signer string) (string, error) {
+		target, err := s.address(ctx, targetID)
+		if err != nil {
+			return "", err
+		}
+		return s.chain.BuildRemoveMember(ctx, signer, guild.SuiObjectID, target)
+	})
+	if err != nil {
+		return Action{}, err
+	}
+	utils.LogInfof("Guild ranks: %s kicked %s from guild %s.", playerID, targetID, guild.ID)
+	return action, nil
+}
+
+// Withdraw lets playerID take amount game coin from their guild's bank,
+// within their rank's daily limit. The amount counts against the limit once
+// the transaction is handed out.
+func (s *Service) Withdraw(ctx context.Context, playerID string, amount uint64) (Action, error) {
+	if amount == 0 {
+		return Action{}, fmt.Errorf("%w: withdraw a positive amount", ErrInvalid)
+	}
+	guild, err := s.allowed(playerID, PermBankWithdraw)
+	if err != nil {
+		return Action{}, err
+	}
+
+	// Reserve the amount while the transaction is built, so that concurrent
+	// withdrawals cannot both fit the limit.
+	s.mu.Lock()
+	limit := s.rankOf(guild, playerID).WithdrawPerDay // The leader's is 0
+	day := s.now().UTC().Format("2006-01-02")
+	ranks := s.ranks(guild.ID)
+	taken := ranks.Withdrawn[playerID]
+	if taken.Day != day {
+		taken = Withdrawal{Day: day}
+	}
+	if limit > 0 && taken.Amount+amount > limit {
+		s.mu.Unlock()
+		return Action{}, fmt.Errorf("%w (%d of %d left)", ErrOverLimit, limit-taken.Amount, limit)
+	}
+	if ranks.Withdrawn == nil {
+		ranks.Withdrawn = make(map[string]Withdrawal)
+	}
+	ranks.Withdrawn[playerID] = Withdrawal{Day: day, Amount: taken.Amount + amount}
+	s.mu.Unlock()
+
+	action := Action{Kind: "withdraw", GuildID: guild.ID, PlayerID: playerID, Amount: amount}
+	action.TxBytes, err = s.build(ctx, playerID, guild, func(signer string) (string, error) {
+		return s.chain.BuildWithdraw(ctx, signer, guild.SuiObjectID, amount)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if w := ranks.Withdrawn[playerID]; w.Day == day && w.Amount >= amount {
+			ranks.Withdrawn[playerID] = Withdrawal{Day: day, Amount: w.Amount - amount}
+		}
+		return Action{}, err
+	}
+	if limit > 0 {
+		action.Remaining = limit - ranks.Withdrawn[playerID].Amount
+	}
+	s.save()
+	utils.LogInfof("Guild ranks: %s withdrew %d from the bank of guild %s.", playerID, amount, guild.ID)
+	return action, nil
+}
+
+// leading returns the guild playerID leads.
+func (s *Service) leading(playerID string) (model.Guild, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return model.Guild{}, ErrNoGuild
+	}
+	if guild.LeaderID != playerID {
+		return model.Guild{}, ErrNotLeader
+	}
+	return guild, nil
+}
+
+// allowed returns playerID's guild if their rank allows p.
+func (s *Service) allowed(playerID string, p Permission) (model.Guild, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return model.Guild{}, ErrNoGuild
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.rankOf(guild, playerID).Has(p) {
+		return model.Guild{}, ErrNotPermitted
+	}
+	return guild, nil
+}
+
+// build returns the transaction tx makes with the acting player's address,
+// or nothing if there is no chain.
+func (s *Service) build(ctx context.Context, playerID string, guild model.Guild, tx func(signer string) (string, error)) (string, error) {
+	if s.chain == nil {
+		return "", nil
+	}
+	if guild.SuiObjectID == "" {
+		return "", nil // Off-chain guild
+	}
+	signer, err := s.address(ctx, playerID)
+	if err != nil {
+		return "", err
+	}
+	return tx(signer)
+}
+
+func (s *Service) address(ctx context.Context, playerID string) (string, error) {
+	address, err := s.addresses.Address(ctx, playerID)
+	if err != nil || address == "" {
+		return "", fmt.Errorf("%w: no address for %s", ErrNoWallet, playerID)
+	}
+	return address, nil
+}
+
+// validate checks a rank a leader defines and fills in its defaults.
+func (s *Service) validate(rank Rank) (Rank, error) {
+	rank.Name = strings.TrimSpace(rank.Name)
+	switch {
+	case rank.Name == "" || len(rank.Name) > maxRankName:
+		return Rank{}, fmt.Errorf("%w: a rank needs a name of at most %d characters", ErrInvalid, maxRankName)
+	case strings.EqualFold(rank.Name, Leader):
+		return Rank{}, fmt.Errorf("%w: the leader rank cannot be redefined", ErrInvalid)
+	}
+	if rank.OnChainRank == "" {
+		rank.OnChainRank = ChainMember
+		if rank.Name == Officer {
+			rank.OnChainRank = ChainOfficer
+		}
+	}
+	if rank.OnChainRank != ChainOfficer && rank.OnChainRank != ChainMember {
+		return Rank{}, fmt.Errorf("%w: onChainRank must be %q or %q", ErrInvalid, ChainOfficer, ChainMember)
+	}
+	seen := make(map[Permission]bool, len(rank.Permissions))
+	perms := make([]Permission, 0, len(rank.Permissions))
+	for _, p := range rank.Permissions {
+		known := false
+		for _, q := range Permissions {
+			known = known || p == q
+		}
+		if !known {
+			return Rank{}, fmt.Errorf("%w: unknown permission %q", ErrInvalid, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	rank.Permissions = perms
+	return rank, nil
+}
+
+// builtIn returns the server's definition of the officer and member ranks.
+func (s *Service) builtIn(name string) Rank {
+	if name == Officer {
+		return Rank{Name: Officer, Permissions: []Permission{PermInvite, PermKick, PermBankWithdraw, PermEvents}, WithdrawPerDay: s.opts.OfficerWithdrawPerDay, OnChainRank: ChainOfficer}
+	}
+	return Rank{Name: Member, OnChainRank: ChainMember}
+}
+
+// ranks returns guildID's state, creating it. The caller holds s.mu.
+func (s *Service) ranks(guildID string) *GuildRanks {
+	ranks := s.state.Guilds[guildID]
+	if ranks == nil {
+		ranks = &GuildRanks{}
+		s.state.Guilds[guildID] = ranks
+	}
+	return ranks
+}
+
+// rank returns guildID's rank called name. The caller holds s.mu.
+func (s *Service) rank(guildID, name string) (Rank, bool) {
+	if ranks := s.state.Guilds[guildID]; ranks != nil {
+		for _, r := range ranks.Ranks {
+			if r.Name == name {
+				return r, true
+			}
+		}
+	}
+	if name == Officer || name == Member {
+		return s.builtIn(name), true
+	}
+	return Rank{}, false
+}
+
+// defaultRank is the rank of a member nobody assigned one: officer for the
+// roster's officers, member otherwise.
+func (s *Service) defaultRank(guild model.Guild, playerID string) string {
+	for _, officerID := range guild.OfficerIDs {
+		if officerID == playerID {
+			return Officer
+		}
+	}
+	return Member
+}
+
+// rankOf returns playerID's rank in guild. The caller holds s.mu.
+func (s *Service) rankOf(guild model.Guild, playerID string) Rank {
+	if playerID == guild.LeaderID {
+		return Rank{Name: Leader, Permissions: Permissions, OnChainRank: ChainOfficer}
+	}
+	if ranks := s.state.Guilds[guild.ID]; ranks != nil {
+		if name, ok := ranks.Members[playerID]; ok {
+			if rank, ok := s.rank(guild.ID, name); ok {
+				return rank
+			}
+		}
+	}
+	rank, _ := s.rank(guild.ID, s.defaultRank(guild, playerID))
+	return rank
+}
+
+// matrix returns guild's ranks. The caller holds s.mu.
+func (s *Service) matrix(guild model.Guild) Matrix {
+	officer, _ := s.rank(guild.ID, Officer)
+	member, _ := s.rank(guild.ID, Member)
+	m := Matrix{
+		GuildID: guild.ID,
+		Ranks:   []Rank{{Name: Leader, Permissions: Permissions, OnChainRank: ChainOfficer}, officer, member},
+		Members: make(map[string]string, len(guild.MemberIDs)),
+	}
+	var custom []Rank
+	if ranks := s.state.Guilds[guild.ID]; ranks != nil {
+		for _, r := range ranks.Ranks {
+			if r.Name != Officer && r.Name != Member {
+				custom = append(custom, r)
+			}
+		}
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+	m.Ranks = append(m.Ranks, custom...)
+	for _, memberID := range guild.MemberIDs {
+		m.Members[memberID] = s.rankOf(guild, memberID).Name
+	}
+	return m
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Guild ranks: Failed to save guild ranks: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package guildranks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/model"
+)
+
+type fakeChain struct {
+	calls []string
+}
+
+func (c *fakeChain) BuildAddMember(ctx context.Context, signer, guildObjectID, player string) (string, error) {
+	c.calls = append(c.calls, "add "+player+" by "+signer)
+	return "tx-add", nil
+}
+
+func (c *fakeChain) BuildRemoveMember(ctx context.Context, signer, guildObjectID, player string) (string, error) {
+	c.calls = append(c.calls, "remove "+player+" by "+signer)
+	return "tx-remove", nil
+}
+
+func (c *fakeChain) BuildSetRank(ctx context.Context, signer, guildObjectID, member, onChainRank string) (string, error) {
+	c.calls = append(c.calls, "rank "+member+" "+onChainRank+" by "+signer)
+	return "tx-rank", nil
+}
+
+func (c *fakeChain) BuildWithdraw(ctx context.Context, signer, guildObjectID string, amount uint64) (string, error) {
+	c.calls = append(c.calls, "withdraw by "+signer)
+	return "tx-withdraw", nil
+}
+
+type addressBook map[string]string
+
+func (b addressBook) Address(ctx context.Context, playerID string) (string, error) {
+	return b[playerID], nil
+}
+
+func newTestService(t *testing.T) (*Service, *fakeChain) {
+	t.Helper()
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", SuiObjectID: "0xwolves", LeaderID: "ann", MemberIDs: []string{"ann", "bob", "cat", "dot"}, OfficerIDs: []string{"bob"}},
+		{ID: "bears", LeaderID: "dan", MemberIDs: []string{"dan"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(&MemoryStore{}, roster, Options{OfficerWithdrawPerDay: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &fakeChain{}
+	s.UseChain(chain, addressBook{"ann": "0xann", "bob": "0xbob", "cat": "0xcat", "dot": "0xdot", "eve": "0xeve"})
+	return s, chain
+}
+
+func TestLeadersDefineAndAssignRanks(t *testing.T) {
+	s, chain := newTestService(t)
+	ctx := context.Background()
+	quartermaster := Rank{Name: "quartermaster", Permissions: []Permission{PermBankWithdraw, PermBankWithdraw}, WithdrawPerDay: 50}
+
+	if _, err := s.DefineRank("bob", quartermaster); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("officer DefineRank: %v", err)
+	}
+	if _, err := s.DefineRank("ann", Rank{Name: "Leader"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("redefining the leader: %v", err)
+	}
+	if _, err := s.DefineRank("ann", Rank{Name: "spy", Permissions: []Permission{"steal"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("unknown permission: %v", err)
+	}
+	matrix, err := s.DefineRank("ann", quartermaster)
+	if err != nil || len(matrix.Ranks) != 4 || matrix.Ranks[3].Name != "quartermaster" || len(matrix.Ranks[3].Permissions) != 1 || matrix.Ranks[3].OnChainRank != ChainMember {
+		t.Fatalf("DefineRank = %+v, %v", matrix, err)
+	}
+
+	// A member who becomes quartermaster stays a contract member.
+	action, err := s.Assign(ctx, "ann", "cat", "quartermaster")
+	if err != nil || action.TxBytes != "" || len(chain.calls) != 0 {
+		t.Fatalf("Assign = %+v, %v; chain calls %v", action, err, chain.calls)
+	}
+	if !s.Can("wolves", "cat", PermBankWithdraw) || s.Can("wolves", "cat", PermKick) {
+		t.Error("cat's permissions do not match the quartermaster rank")
+	}
+	// Demoting the officer comes with the contract demotion for the leader to sign.
+	if action, err = s.Assign(ctx, "ann", "bob", Member); err != nil || action.TxBytes != "tx-rank" || chain.calls[0] != "rank 0xbob member by 0xann" {
+		t.Fatalf("demotion = %+v, %v; chain calls %v", action, err, chain.calls)
+	}
+	if _, err := s.Assign(ctx, "ann", "ann", Officer); !errors.Is(err, ErrLeaderExempt) {
+		t.Fatalf("assigning the leader: %v", err)
+	}
+	if _, err := s.Assign(ctx, "ann", "dan", Officer); !errors.Is(err, ErrNotMember) {
+		t.Fatalf("assigning another guild's member: %v", err)
+	}
+
+	// Deleting the rank puts its holders back on their default rank.
+	if matrix, err = s.DeleteRank("ann", "quartermaster"); err != nil || matrix.Members["cat"] != Member || len(matrix.Ranks) != 3 {
+		t.Fatalf("DeleteRank = %+v, %v", matrix, err)
+	}
+	if _, err := s.DeleteRank("ann", "quartermaster"); !errors.Is(err, ErrNoRank) {
+		t.Fatalf("deleting twice: %v", err)
+	}
+}
+
+func TestActionsFollowTheRanks(t *testing.T) {
+	s, chain := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := s.Invite(ctx, "cat", "eve"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("member Invite: %v", err)
+	}
+	if _, err := s.Invite(ctx, "bob", "dan"); !errors.Is(err, ErrInGuild) {
+		t.Fatalf("inviting another guild's member: %v", err)
+	}
+	action, err := s.Invite(ctx, "bob", "eve")
+	if err != nil || action.TxBytes != "tx-add" || chain.calls[0] != "add 0xeve by 0xbob" {
+		t.Fatalf("Invite = %+v, %v", action, err)
+	}
+
+	// Officers cannot kick the leader or each other; the leader can.
+	if _, err := s.Kick(ctx, "bob", "ann"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("kicking the leader: %v", err)
+	}
+	if _, err := s.DefineRank("ann", Rank{Name: "warden", Permissions: []Permission{PermKick}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Assign(ctx, "ann", "dot", "warden"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Kick(ctx, "bob", "dot"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("kicking a rank that may kick: %v", err)
+	}
+	if action, err = s.Kick(ctx, "ann", "dot"); err != nil || action.TxBytes != "tx-remove" {
+		t.Fatalf("leader Kick = %+v, %v", action, err)
+	}
+	if action, err = s.Kick(ctx, "dot", "cat"); err != nil || action.TargetID != "cat" {
+		t.Fatalf("warden Kick = %+v, %v", action, err)
+	}
+
+	// Withdrawals stay within the rank's daily limit, which resets at midnight UTC.
+	now := time.Date(2026, 5, 1, 23, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	if action, err = s.Withdraw(ctx, "bob", 60); err != nil || action.Remaining != 40 || action.TxBytes != "tx-withdraw" {
+		t.Fatalf("Withdraw = %+v, %v", action, err)
+	}
+	if _, err := s.Withdraw(ctx, "bob", 41); !errors.Is(err, ErrOverLimit) {
+		t.Fatalf("over the limit: %v", err)
+	}
+	if _, err := s.Withdraw(ctx, "cat", 1); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("member Withdraw: %v", err)
+	}
+	if _, err := s.Withdraw(ctx, "ann", 100000); err != nil {
+		t.Fatalf("the leader has no limit: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if action, err = s.Withdraw(ctx, "bob", 100); err != nil || action.Remaining != 0 {
+		t.Fatalf("Withdraw the next day = %+v, %v", action, err)
+	}
+}
@@ -0,0 +1,30 @@
+package guildranks
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted rank matrix of every guild that changed its ranks.
+type State struct {
+	Guilds map[string]*GuildRanks `json:"guilds"` // By guild ID
+}
+
+// GuildRanks is one guild's ranks and who holds them.
+type GuildRanks struct {
+	Ranks     []Rank                `json:"ranks,omitempty"`     // Custom ranks, and the officer and member ranks if redefined
+	Members   map[string]string     `json:"members,omitempty"`   // Player ID -> rank, for members not on their default rank
+	Withdrawn map[string]Withdrawal `json:"withdrawn,omitempty"` // Bank withdrawals today, by player ID
+}
+
+// Withdrawal is what a player took from the guild bank on one UTC day.
+type Withdrawal struct {
+	Day    string `json:"day"` // 2006-01-02
+	Amount uint64 `json:"amount"`
+}
+
+// Store persists the rank state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the rank state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the rank state in a JSON file.
+type FileStore = jsonstore.File[State]
@@ -0,0 +1,121 @@
+// Package guilds keeps the off-chain guild roster: which players belong to
+// which guild. On-chain guilds are mirrored into the roster file; the server
+// reads it for guild chat and anything else that needs guild membership
+// without a chain query.
+package guilds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/phuhao00/suigserver/server/internal/model"
+)
+
+// File is the guild roster file.
+type File struct {
+	Guilds []model.Guild `json:"guilds"`
+}
+
+// Roster holds the guilds by ID and by member. It is not changed after it is
+// loaded, so it is safe for concurrent use. A nil *Roster has no guilds.
+type Roster struct {
+	byID     map[string]model.Guild
+	byPlayer map[string]string // Player ID -> guild ID
+}
+
+// LoadRoster reads a guild roster file.
+func LoadRoster(path string) (*Roster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid guild roster %s: %w", path, err)
+	}
+	r, err := NewRoster(file.Guilds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid guild roster %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// NewRoster creates a Roster of guilds. Every guild needs a unique ID and a
+// leader and officers among its members, and a player may be in one guild
+// only.
+func NewRoster(guilds []model.Guild) (*Roster, error) {
+	r := &Roster{byID: make(map[string]model.Guild, len(guilds)), byPlayer: make(map[string]string)}
+	for i, g := range guilds {
+		switch {
+		case g.ID == "":
+			return nil, fmt.Errorf("guild %d needs an id", i+1)
+		case r.byID[g.ID].ID != "":
+			return nil, fmt.Errorf("duplicate guild %q", g.ID)
+		}
+		leaderIsMember := g.LeaderID == ""
+		for _, playerID := range g.MemberIDs {
+			if other, ok := r.byPlayer[playerID]; ok {
+				return nil, fmt.Errorf("player %q is in guilds %q and %q", playerID, other, g.ID)
+			}
+			r.byPlayer[playerID] = g.ID
+			leaderIsMember = leaderIsMember || playerID == g.LeaderID
+		}
+		if !leaderIsMember {
+			return nil, fmt.Errorf("guild %q: leader %q is not a member", g.ID, g.LeaderID)
+		}
+		for _, officerID := range g.OfficerIDs {
+			if r.byPlayer[officerID] != g.ID {
+				return nil, fmt.Errorf("guild %q: officer %q is not a member", g.ID, officerID)
+			}
+		}
+		r.byID[g.ID] = g
+	}
+	return r, nil
+}
+
+// GuildOf returns the guild playerID belongs to, if any.
+func (r *Roster) GuildOf(playerID string) (model.Guild, bool) {
+	if r == nil {
+		return model.Guild{}, false
+	}
+	id, ok := r.byPlayer[playerID]
+	return r.byID[id], ok
+}
+
+// Guild returns the guild with id, if any.
+func (r *Roster) Guild(id string) (model.Guild, bool) {
+	if r == nil {
+		return model.Guild{}, false
+	}
+	g, ok := r.byID[id]
+	return g, ok
+}
+
+// IsOfficer reports whether playerID leads guildID or is one of its officers.
+func (r *Roster) IsOfficer(guildID, playerID string) bool {
+	if r == nil {
+		return false
+	}
+	g, ok := r.byID[guildID]
+	if !ok {
+		return false
+	}
+	if g.LeaderID == playerID {
+		return true
+	}
+	for _, officerID := range g.OfficerIDs {
+		if officerID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of guilds.
+func (r *Roster) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.byID)
+}
@@ -0,0 +1,52 @@
+package guilds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRoster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guilds.json")
+	load := func(content string) (*Roster, error) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadRoster(path)
+	}
+
+	r, err := load(`{"guilds": [{"id": "g1", "name": "Iron Wolves", "leaderId": "ann", "memberIds": ["ann", "bob", "cat"], "officerIds": ["bob"]}]}`)
+	if err != nil || r.Len() != 1 {
+		t.Fatalf("LoadRoster = %v, %v", r, err)
+	}
+	if g, ok := r.GuildOf("bob"); !ok || g.Name != "Iron Wolves" {
+		t.Errorf("GuildOf(bob) = %+v, %v", g, ok)
+	}
+	if _, ok := r.GuildOf("cid"); ok {
+		t.Error("cid is in a guild")
+	}
+	if !r.IsOfficer("g1", "ann") || !r.IsOfficer("g1", "bob") || r.IsOfficer("g1", "cat") || r.IsOfficer("g2", "bob") {
+		t.Error("IsOfficer does not match the leader and officers")
+	}
+
+	for want, content := range map[string]string{
+		"needs an id":      `{"guilds": [{"memberIds": ["ann"]}]}`,
+		"duplicate":        `{"guilds": [{"id": "g1"}, {"id": "g1"}]}`,
+		"is in guilds":     `{"guilds": [{"id": "g1", "memberIds": ["ann"]}, {"id": "g2", "memberIds": ["ann"]}]}`,
+		"is not a member":  `{"guilds": [{"id": "g1", "leaderId": "cid", "memberIds": ["ann"]}]}`,
+		"officer":          `{"guilds": [{"id": "g1", "memberIds": ["ann"], "officerIds": ["cid"]}]}`,
+		"cannot unmarshal": `{"guilds": {}}`,
+	} {
+		if _, err := load(content); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error about %s, got %v", want, err)
+		}
+	}
+}
+
+func TestNilRosterHasNoGuilds(t *testing.T) {
+	var r *Roster
+	if _, ok := r.GuildOf("ann"); ok || r.Len() != 0 {
+		t.Error("a nil roster has guilds")
+	}
+}
@@ -0,0 +1,301 @@
+// Package handlermetrics times how long actors take to handle each message.
+// Every handler gets a latency histogram, keyed by actor kind and message
+// type, and a handler call slower than the threshold is logged with its
+// actor, sender and whatever context the actor adds, to catch regressions
+// before players notice them.
+//
+// Like the quarantine, the metrics are registered with an actor system.
+// Receiver is receiver middleware that times each message an actor handles.
+// Actors that dispatch one message type to many handlers, like the player
+// session with client messages, time those handlers themselves with Observe.
+package handlermetrics
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/extensions"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultSlowThreshold is the threshold when Options leaves it at zero.
+const DefaultSlowThreshold = 50 * time.Millisecond
+
+// Log limits: a handler that is slow on every call is logged once per
+// interval, and the latest slow calls are kept for the debug endpoint.
+const (
+	slowLogInterval = 10 * time.Second
+	recentSlowCalls = 50
+)
+
+// BucketsMs are the upper bounds of the histogram buckets, in milliseconds.
+// Slower calls fall in one more bucket past the last bound.
+var BucketsMs = []float64{0.1, 0.5, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500}
+
+var extensionID = extensions.NextExtensionID()
+
+// Options configures a Service.
+type Options struct {
+	SlowThreshold time.Duration // Handler calls slower than this are logged; negative never logs
+}
+
+// SelfTimed is implemented by actors that time some of their messages with
+// Observe. Receiver leaves those messages to the actor.
+type SelfTimed interface {
+	TimesItself(message interface{}) bool
+}
+
+type handlerKey struct {
+	actor   string
+	message string
+}
+
+// histogram is the latency of one handler.
+type histogram struct {
+	count      uint64
+	slow       uint64
+	total      time.Duration
+	max        time.Duration
+	buckets    []uint64
+	lastLogged time.Time
+	suppressed int // Slow calls not logged since lastLogged
+}
+
+// SlowCall is one handler call over the threshold.
+type SlowCall struct {
+	Actor     string    `json:"actor"`
+	Message   string    `json:"message"`
+	PID       string    `json:"pid"`
+	Detail    string    `json:"detail,omitempty"`
+	ElapsedMs float64   `json:"elapsedMs"`
+	At        time.Time `json:"at"`
+}
+
+// Service holds the handler metrics of one actor system.
+type Service struct {
+	opts Options
+
+	mu       sync.Mutex
+	handlers map[handlerKey]*histogram
+	recent   []SlowCall // Oldest first
+}
+
+// New creates the metrics for system and registers them so Receiver and
+// Observe can find them.
+func New(system *actor.ActorSystem, opts Options) *Service {
+	if opts.SlowThreshold == 0 {
+		opts.SlowThreshold = DefaultSlowThreshold
+	}
+	s := &Service{opts: opts, handlers: make(map[handlerKey]*histogram)}
+	system.Extensions.Register(s)
+	return s
+}
+
+// ExtensionID implements extensions.Extension.
+func (s *Service) ExtensionID() extensions.ExtensionID { return extensionID }
+
+// fromSystem returns the metrics registered with system, if any.
+func fromSystem(system *actor.ActorSystem) *Service {
+	s, _ := system.Extensions.Get(extensionID).(*Service)
+	return s
+}
+
+// Receiver is receiver middleware that times each message the actor handles,
+// except those a SelfTimed actor times itself. Add it to actor props with
+// actor.WithReceiverMiddleware.
+func Receiver(next actor.ReceiverFunc) actor.ReceiverFunc {
+	return func(ctx actor.ReceiverContext, env *actor.MessageEnvelope) {
+		s := fromSystem(ctx.ActorSystem())
+		if s == nil {
+			next(ctx, env)
+			return
+		}
+		if timed, ok := ctx.Actor().(SelfTimed); ok && timed.TimesItself(env.Message) {
+			next(ctx, env)
+			return
+		}
+		started := time.Now()
+		next(ctx, env)
+		s.Record(actorKind(ctx.Actor()), typeName(env.Message), ctx.Self(), time.Since(started), func() string {
+			if env.Sender == nil {
+				return ""
+			}
+			return "sender " + env.Sender.String()
+		})
+	}
+}
+
+// Observe records a handler call the actor timed itself, under message, e.g.
+// the protocol type of a client message. detail, which may be nil, is called
+// for the log only if the call was slow.
+func Observe(ctx actor.Context, message string, elapsed time.Duration, detail func() string) {
+	if s := fromSystem(ctx.ActorSystem()); s != nil {
+		s.Record(actorKind(ctx.Actor()), message, ctx.Self(), elapsed, detail)
+	}
+}
+
+// Record adds one call of the handler of message in the actor pid, of kind
+// actorKind. detail, which may be nil, adds context to a slow call.
+func (s *Service) Record(actorKind, message string, pid *actor.PID, elapsed time.Duration, detail func() string) {
+	now := time.Now()
+	s.mu.Lock()
+	key := handlerKey{actorKind, message}
+	h := s.handlers[key]
+	if h == nil {
+		h = &histogram{buckets: make([]uint64, len(BucketsMs)+1)}
+		s.handlers[key] = h
+	}
+	h.count++
+	h.total += elapsed
+	if elapsed > h.max {
+		h.max = elapsed
+	}
+	ms := durationMs(elapsed)
+	h.buckets[sort.SearchFloat64s(BucketsMs, ms)]++
+	if s.opts.SlowThreshold < 0 || elapsed <= s.opts.SlowThreshold {
+		s.mu.Unlock()
+		return
+	}
+	h.slow++
+	call := SlowCall{Actor: actorKind, Message: message, PID: pidString(pid), ElapsedMs: ms, At: now}
+	if detail != nil {
+		call.Detail = detail()
+	}
+	s.recent = append(s.recent, call)
+	if len(s.recent) > recentSlowCalls {
+		s.recent = s.recent[len(s.recent)-recentSlowCalls:]
+	}
+	if now.Sub(h.lastLogged) < slowLogInterval {
+		h.suppressed++
+		s.mu.Unlock()
+		return
+	}
+	suppressed := h.suppressed
+	h.lastLogged, h.suppressed = now, 0
+	s.mu.Unlock()
+
+	context := call.PID
+	if call.Detail != "" {
+		context += ", " + call.Detail
+	}
+	if suppressed > 0 {
+		context += fmt.Sprintf("; %d more slow calls since the last warning", suppressed)
+	}
+	utils.LogWarnf("HandlerMetrics: %s took %s to handle %s, over the %s threshold (%s)", actorKind, elapsed.Round(time.Microsecond), message, s.opts.SlowThreshold, context)
+}
+
+// Handler is the latency of one handler.
+type Handler struct {
+	Actor   string   `json:"actor"`
+	Message string   `json:"message"`
+	Count   uint64   `json:"count"`
+	Slow    uint64   `json:"slow"` // Calls over the threshold
+	MeanMs  float64  `json:"meanMs"`
+	P50Ms   float64  `json:"p50Ms"` // Upper bound of the bucket holding the median
+	P99Ms   float64  `json:"p99Ms"`
+	MaxMs   float64  `json:"maxMs"`
+	Buckets []uint64 `json:"buckets"` // Calls per bucket of BucketsMs, then slower calls
+}
+
+// Stats are the handler metrics for the debug endpoint.
+type Stats struct {
+	SlowThresholdMs float64    `json:"slowThresholdMs"`
+	BucketsMs       []float64  `json:"bucketsMs"`
+	Handlers        []Handler  `json:"handlers"`   // Most total time first
+	RecentSlow      []SlowCall `json:"recentSlow"` // Newest first
+}
+
+// Stats returns the latency of every handler seen so far.
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{
+		SlowThresholdMs: durationMs(s.opts.SlowThreshold),
+		BucketsMs:       BucketsMs,
+		Handlers:        make([]Handler, 0, len(s.handlers)),
+		RecentSlow:      make([]SlowCall, 0, len(s.recent)),
+	}
+	totals := make(map[handlerKey]time.Duration, len(s.handlers))
+	for key, h := range s.handlers {
+		totals[key] = h.total
+		stats.Handlers = append(stats.Handlers, Handler{
+			Actor:   key.actor,
+			Message: key.message,
+			Count:   h.count,
+			Slow:    h.slow,
+			MeanMs:  durationMs(h.total) / float64(h.count),
+			P50Ms:   h.quantile(0.5),
+			P99Ms:   h.quantile(0.99),
+			MaxMs:   durationMs(h.max),
+			Buckets: append([]uint64(nil), h.buckets...),
+		})
+	}
+	sort.Slice(stats.Handlers, func(i, j int) bool {
+		a, b := stats.Handlers[i], stats.Handlers[j]
+		ta, tb := totals[handlerKey{a.Actor, a.Message}], totals[handlerKey{b.Actor, b.Message}]
+		if ta != tb {
+			return ta > tb
+		}
+		return a.Actor+a.Message < b.Actor+b.Message
+	})
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		stats.RecentSlow = append(stats.RecentSlow, s.recent[i])
+	}
+	return stats
+}
+
+// quantile estimates the q quantile as the upper bound of its bucket. Past
+// the last bound it is the slowest call.
+func (h *histogram) quantile(q float64) float64 {
+	rank := uint64(q*float64(h.count-1)) + 1
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank && i < len(BucketsMs) {
+			return BucketsMs[i]
+		} else if seen >= rank {
+			break
+		}
+	}
+	return durationMs(h.max)
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// typeNames caches the names of message and actor types, which are looked up
+// for every message.
+var typeNames sync.Map // reflect.Type -> string
+
+// typeName is the package-qualified type of v, e.g. "*messages.FindRoomRequest".
+func typeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return "<nil>"
+	}
+	if name, ok := typeNames.Load(t); ok {
+		return name.(string)
+	}
+	name := t.String()
+	typeNames.Store(t, name)
+	return name
+}
+
+// actorKind is the type name of an actor, e.g. "RoomActor".
+func actorKind(a actor.Actor) string {
+	kind := typeName(a)
+	return kind[strings.LastIndexByte(kind, '.')+1:]
+}
+
+func pidString(pid *actor.PID) string {
+	if pid == nil {
+		return ""
+	}
+	return pid.String()
+}
@@ -0,0 +1,83 @@
+package handlermetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+type fast struct{}
+
+type slow struct{}
+
+type command struct{ name string }
+
+// worker sleeps on slow messages and times commands itself, like the player
+// session does with client messages.
+type worker struct{ done chan struct{} }
+
+func (w *worker) TimesItself(message interface{}) bool {
+	_, ok := message.(*command)
+	return ok
+}
+
+func (w *worker) Receive(ctx actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *slow:
+		time.Sleep(20 * time.Millisecond)
+	case *command:
+		Observe(ctx, msg.name, 30*time.Millisecond, func() string { return "command " + msg.name })
+	case *fast:
+	default:
+		return
+	}
+	w.done <- struct{}{}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	metrics := New(system, Options{SlowThreshold: 10 * time.Millisecond})
+
+	done := make(chan struct{}, 8)
+	pid := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor { return &worker{done: done} }, actor.WithReceiverMiddleware(Receiver)))
+	for _, msg := range []interface{}{&fast{}, &fast{}, &slow{}, &command{name: "MOVE"}} {
+		system.Root.Send(pid, msg)
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("a message was not handled")
+		}
+	}
+	system.Root.StopFuture(pid).Wait()
+
+	stats := metrics.Stats()
+	handlers := make(map[string]Handler)
+	for _, h := range stats.Handlers {
+		if h.Actor != "worker" {
+			t.Errorf("handler %+v not attributed to the worker", h)
+		}
+		handlers[h.Message] = h
+	}
+	if h := handlers["*handlermetrics.fast"]; h.Count != 2 || h.Slow != 0 || h.P99Ms > 1 {
+		t.Errorf("fast = %+v", h)
+	}
+	if h := handlers["*handlermetrics.slow"]; h.Count != 1 || h.Slow != 1 || h.P50Ms != 25 || h.Buckets[len(h.Buckets)-1] != 0 {
+		t.Errorf("slow = %+v", h)
+	}
+	if h := handlers["MOVE"]; h.Count != 1 || h.MaxMs != 30 {
+		t.Errorf("MOVE = %+v", h)
+	}
+	if _, ok := handlers["*handlermetrics.command"]; ok {
+		t.Error("the middleware timed a message the actor times itself")
+	}
+	if len(stats.RecentSlow) != 2 || stats.RecentSlow[0].Message != "MOVE" || stats.RecentSlow[0].Detail != "command MOVE" {
+		t.Errorf("recent slow calls = %+v", stats.RecentSlow)
+	}
+	if stats.Handlers[0].Message != "MOVE" {
+		t.Errorf("handlers are not sorted by total time: %+v", stats.Handlers)
+	}
+}
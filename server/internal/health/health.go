@@ -0,0 +1,215 @@
+// Package health tracks liveness and readiness of the game server and exposes
+// them over HTTP as /healthz and /readyz for orchestrators.
+//
+// Liveness is driven by heartbeats: long-running loops (the TCP accept loop, the
+// actor system probe) call Beat periodically, and the server is reported dead
+// when any of them stops beating. Readiness additionally runs dependency checks
+// (DB, Redis, SUI RPC) whose results are cached for a short TTL so probes do not
+// hammer the dependencies.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Status is the outcome of a single check or of a whole report.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// DefaultCheckTimeout bounds a single readiness check when none is given.
+const DefaultCheckTimeout = 3 * time.Second
+
+// CheckFunc verifies that a dependency is reachable.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a heartbeat or readiness check.
+type CheckResult struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report is the JSON body returned by /healthz and /readyz.
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+	Load   interface{}            `json:"load,omitempty"` // From the function given to SetLoad
+}
+
+type heartbeat struct {
+	maxAge time.Duration
+	last   time.Time
+}
+
+type readinessCheck struct {
+	name  string
+	ttl   time.Duration
+	check CheckFunc
+
+	mu   sync.Mutex // Serializes runs so concurrent probes share one result
+	last CheckResult
+}
+
+// Monitor aggregates heartbeats and readiness checks.
+type Monitor struct {
+	mu           sync.RWMutex
+	heartbeats   map[string]*heartbeat
+	checks       []*readinessCheck
+	draining     bool
+	checkTimeout time.Duration
+	load         func() interface{}
+	now          func() time.Time
+}
+
+// NewMonitor creates an empty Monitor.
+func NewMonitor() *Monitor {
+	return &Monitor{
+		heartbeats:   make(map[string]*heartbeat),
+		checkTimeout: DefaultCheckTimeout,
+		now:          time.Now,
+	}
+}
+
+// ExpectHeartbeat registers a component that must call Beat at least every maxAge.
+// The registration itself counts as the first beat, giving the component a grace period.
+func (m *Monitor) ExpectHeartbeat(component string, maxAge time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.heartbeats[component] = &heartbeat{maxAge: maxAge, last: m.now()}
+}
+
+// Beat records that component is still making progress. Unregistered components are ignored.
+func (m *Monitor) Beat(component string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hb, ok := m.heartbeats[component]; ok {
+		hb.last = m.now()
+	}
+}
+
+// AddReadinessCheck registers a dependency check whose result is reused for ttl.
+func (m *Monitor) AddReadinessCheck(name string, ttl time.Duration, check CheckFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, &readinessCheck{name: name, ttl: ttl, check: check})
+}
+
+// SetDraining marks the server as shutting down so /readyz fails while
+// in-flight sessions finish, letting load balancers stop routing traffic.
+func (m *Monitor) SetDraining(draining bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.draining = draining
+}
+
+// SetLoad adds load() to every report, for orchestrators that scale on the
+// game's load rather than raw CPU. load should return quickly.
+func (m *Monitor) SetLoad(load func() interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.load = load
+}
+
+// Liveness reports whether every registered heartbeat is fresh.
+func (m *Monitor) Liveness() Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(m.heartbeats))}
+	now := m.now()
+	for component, hb := range m.heartbeats {
+		result := CheckResult{Status: StatusOK, CheckedAt: hb.last}
+		if age := now.Sub(hb.last); age > hb.maxAge {
+			result.Status = StatusFail
+			result.Error = "no heartbeat for " + age.Round(time.Second).String()
+			report.Status = StatusFail
+		}
+		report.Checks["heartbeat:"+component] = result
+	}
+	if m.load != nil {
+		report.Load = m.load()
+	}
+	return report
+}
+
+// Readiness reports whether the server can accept traffic: all heartbeats are
+// fresh, the server is not draining, and every dependency check passes.
+func (m *Monitor) Readiness(ctx context.Context) Report {
+	report := m.Liveness()
+
+	m.mu.RLock()
+	draining := m.draining
+	checks := make([]*readinessCheck, len(m.checks))
+	copy(checks, m.checks)
+	m.mu.RUnlock()
+
+	if draining {
+		report.Status = StatusFail
+		report.Checks["draining"] = CheckResult{Status: StatusFail, Error: "server is shutting down", CheckedAt: m.now()}
+	}
+
+	for _, rc := range checks {
+		result := rc.run(ctx, m.checkTimeout, m.now)
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+		report.Checks[rc.name] = result
+	}
+	return report
+}
+
+func (rc *readinessCheck) run(ctx context.Context, timeout time.Duration, now func() time.Time) CheckResult {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !rc.last.CheckedAt.IsZero() && now().Sub(rc.last.CheckedAt) < rc.ttl {
+		return rc.last
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := CheckResult{Status: StatusOK, CheckedAt: now()}
+	if err := rc.check(checkCtx); err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		if rc.last.Status != StatusFail {
+			utils.LogWarnf("Health: readiness check '%s' failed: %v", rc.name, err)
+		}
+	} else if rc.last.Status == StatusFail {
+		utils.LogInfof("Health: readiness check '%s' recovered.", rc.name)
+	}
+	rc.last = result
+	return result
+}
+
+// RegisterHandlers installs /healthz and /readyz on mux.
+func (m *Monitor) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, m.Liveness())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, m.Readiness(r.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		utils.LogErrorf("Health: failed to write report: %v", err)
+	}
+}
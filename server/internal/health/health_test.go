@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestMonitor() (*Monitor, *time.Time) {
+	m := NewMonitor()
+	clock := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+	return m, &clock
+}
+
+func TestHeartbeatsExpire(t *testing.T) {
+	m, clock := newTestMonitor()
+	m.ExpectHeartbeat("accept", 10*time.Second)
+	m.Beat("unregistered")
+
+	*clock = clock.Add(9 * time.Second)
+	if report := m.Liveness(); report.Status != StatusOK || len(report.Checks) != 1 {
+		t.Fatalf("within the grace period: %+v", report)
+	}
+	*clock = clock.Add(2 * time.Second)
+	report := m.Liveness()
+	if report.Status != StatusFail || report.Checks["heartbeat:accept"].Error != "no heartbeat for 11s" {
+		t.Fatalf("after a missed heartbeat: %+v", report)
+	}
+	if m.Readiness(context.Background()).Status != StatusFail {
+		t.Error("a dead server is ready")
+	}
+	m.Beat("accept")
+	if report := m.Liveness(); report.Status != StatusOK {
+		t.Fatalf("after a beat: %+v", report)
+	}
+}
+
+func TestDrainingFlipsReadiness(t *testing.T) {
+	m, _ := newTestMonitor()
+	m.ExpectHeartbeat("accept", time.Minute)
+	mux := http.NewServeMux()
+	m.RegisterHandlers(mux)
+	probe := func(path string) (int, Report) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, report
+	}
+
+	if code, _ := probe("/readyz"); code != http.StatusOK {
+		t.Fatalf("ready before draining: %d", code)
+	}
+	m.SetDraining(true)
+	if code, report := probe("/readyz"); code != http.StatusServiceUnavailable || report.Checks["draining"].Status != StatusFail {
+		t.Fatalf("draining: %d %+v", code, report)
+	}
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("a draining server is not alive: %d", code)
+	}
+	m.SetDraining(false)
+	if code, _ := probe("/readyz"); code != http.StatusOK {
+		t.Fatalf("ready after draining: %d", code)
+	}
+}
+
+func TestReadinessChecksAreCachedForTheirTTL(t *testing.T) {
+	m, clock := newTestMonitor()
+	runs := 0
+	var failure error
+	m.AddReadinessCheck("db", 5*time.Second, func(ctx context.Context) error {
+		runs++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("the check has no timeout")
+		}
+		return failure
+	})
+
+	ctx := context.Background()
+	if report := m.Readiness(ctx); report.Status != StatusOK || runs != 1 {
+		t.Fatalf("first probe: %+v after %d runs", report, runs)
+	}
+	failure = errors.New("connection refused")
+	*clock = clock.Add(4 * time.Second)
+	if report := m.Readiness(ctx); report.Status != StatusOK || runs != 1 {
+		t.Fatalf("within the TTL: %+v after %d runs", report, runs)
+	}
+	*clock = clock.Add(time.Second)
+	report := m.Readiness(ctx)
+	if report.Status != StatusFail || report.Checks["db"].Error != "connection refused" || runs != 2 {
+		t.Fatalf("after the TTL: %+v after %d runs", report, runs)
+	}
+	if m.Readiness(ctx).Status != StatusFail || runs != 2 {
+		t.Fatalf("the failure was not cached: %d runs", runs)
+	}
+}
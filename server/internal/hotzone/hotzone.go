@@ -0,0 +1,387 @@
+// Package hotzone watches how crowded the maps are. Rooms report where their
+// members stand, and the Monitor counts them in the cells of a grid laid over
+// each map. When a cell of a map stays crowded, the Monitor raises the map's
+// NPC spawn density and has its crowded rooms open overflow instances; once
+// the crowd has thinned out, it takes those changes back step by step.
+//
+// Every change is made to the balance values, so it shows in the balance
+// history with what caused it and operators can roll it back like any other
+// version.
+package hotzone
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults used when Options leaves a value unset.
+const (
+	DefaultCellSize      = 10
+	DefaultHotPlayers    = 12
+	DefaultSustain       = 3
+	DefaultNPCStep       = 0.25
+	DefaultMaxNPCDensity = 2
+	DefaultInterval      = 10 * time.Second
+)
+
+// adjustedBy names the monitor in the balance history.
+const adjustedBy = "hotzones"
+
+// staleReports is how many intervals a room's report counts without a new one.
+const staleReports = 3
+
+// Options configure a Monitor.
+type Options struct {
+	CellSize      float64       // Side of a grid cell, in map units
+	HotPlayers    int           // Players in one cell that make it hot
+	CoolPlayers   int           // A map cools down once no cell has more players than this; half of HotPlayers if 0
+	Sustain       int           // Evaluations in a row a map must be hot, or cool, before it is adjusted
+	NPCStep       float64       // NPC density added or taken back per adjustment
+	MaxNPCDensity float64       // Most NPC density the monitor raises a map to
+	Interval      time.Duration // Between evaluations; rooms report this often
+}
+
+// Sample is where the members of a room stand.
+type Sample struct {
+	Room      string // Unique among the rooms reporting
+	Map       string
+	Positions []geometry.Vec
+}
+
+// Cell is a grid cell and the players in it, in every room on its map.
+type Cell struct {
+	X       int `json:"x"`
+	Y       int `json:"y"`
+	Players int `json:"players"`
+}
+
+// MapView is how crowded a map was at the latest evaluation.
+type MapView struct {
+	Map        string  `json:"map"`
+	Rooms      int     `json:"rooms"`
+	Players    int     `json:"players"`
+	Peak       int     `json:"peak"`               // Players in its most crowded cell
+	HotCells   []Cell  `json:"hotCells,omitempty"` // Most crowded first
+	Hot        bool    `json:"hot"`
+	Streak     int     `json:"streak"` // Evaluations in a row it was hot, or cool; it is adjusted at Sustain
+	NPCDensity float64 `json:"npcDensity"`
+	Overflow   bool    `json:"overflow"`
+	Adjusted   bool    `json:"adjusted"` // The monitor raised its density and has not taken all of it back
+}
+
+type roomReport struct {
+	mapID string
+	cells map[[2]int]int
+	at    time.Time
+}
+
+type mapState struct {
+	hot      bool // Of the current streak: hot or cool
+	streak   int
+	adjusted bool
+}
+
+// Monitor collects the rooms' reports and adjusts the maps' density. Its
+// methods are safe for concurrent use, and the reporting ones do nothing on a
+// nil Monitor, so rooms can report without checking whether it is on.
+type Monitor struct {
+	balance *balance.Service
+	opts    Options
+	now     func() time.Time
+
+	mu    sync.Mutex
+	rooms map[string]roomReport
+	maps  map[string]*mapState
+	views []MapView
+	stop  chan struct{}
+}
+
+// NewMonitor creates a Monitor that adjusts the density values of values.
+func NewMonitor(values *balance.Service, opts Options) *Monitor {
+	if opts.CellSize <= 0 {
+		opts.CellSize = DefaultCellSize
+	}
+	if opts.HotPlayers <= 0 {
+		opts.HotPlayers = DefaultHotPlayers
+	}
+	if opts.CoolPlayers <= 0 || opts.CoolPlayers >= opts.HotPlayers {
+		opts.CoolPlayers = opts.HotPlayers / 2
+	}
+	if opts.Sustain <= 0 {
+		opts.Sustain = DefaultSustain
+	}
+	if opts.NPCStep <= 0 {
+		opts.NPCStep = DefaultNPCStep
+	}
+	if opts.MaxNPCDensity < 1 {
+		opts.MaxNPCDensity = DefaultMaxNPCDensity
+	}
+	if opts.MaxNPCDensity > balance.MaxNPCMultiplier {
+		opts.MaxNPCDensity = balance.MaxNPCMultiplier
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	return &Monitor{balance: values, opts: opts, now: time.Now, rooms: make(map[string]roomReport), maps: make(map[string]*mapState)}
+}
+
+// Interval is how often rooms should report.
+func (m *Monitor) Interval() time.Duration {
+	if m == nil {
+		return 0
+	}
+	return m.opts.Interval
+}
+
+// Density returns the density values in effect.
+func (m *Monitor) Density() balance.DensityValues {
+	if m == nil {
+		return balance.DensityValues{}
+	}
+	return m.balance.Values().Density
+}
+
+// Report records where the members of a room stand now.
+func (m *Monitor) Report(sample Sample) {
+	if m == nil || sample.Map == "" {
+		return
+	}
+	cells := make(map[[2]int]int, len(sample.Positions))
+	for _, p := range sample.Positions {
+		cells[m.cell(p)]++
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rooms[sample.Room] = roomReport{mapID: sample.Map, cells: cells, at: m.now()}
+}
+
+// Forget drops a room that emptied or stopped.
+func (m *Monitor) Forget(room string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rooms, room)
+}
+
+// Maps returns the maps as of the latest evaluation, by ID.
+func (m *Monitor) Maps() []MapView {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MapView(nil), m.views...)
+}
+
+// Evaluate counts the players in each cell of every map and adjusts the maps
+// that stayed hot or cool for Sustain evaluations. Start does so every
+// Interval.
+func (m *Monitor) Evaluate() []MapView {
+	density := m.balance.Values().Density
+	now := m.now()
+
+	m.mu.Lock()
+	totals := make(map[string]map[[2]int]int)
+	rooms := make(map[string]int)
+	for id, report := range m.rooms {
+		if now.Sub(report.at) > staleReports*m.opts.Interval {
+			delete(m.rooms, id) // The room stopped without saying so
+			continue
+		}
+		cells := totals[report.mapID]
+		if cells == nil {
+			cells = make(map[[2]int]int)
+			totals[report.mapID] = cells
+		}
+		for cell, n := range report.cells {
+			cells[cell] += n
+		}
+		rooms[report.mapID]++
+	}
+	for mapID := range totals {
+		if m.maps[mapID] == nil {
+			m.maps[mapID] = &mapState{}
+		}
+	}
+
+	views := make([]MapView, 0, len(m.maps))
+	var raise, lower []string
+	for mapID, state := range m.maps {
+		view := MapView{Map: mapID, Rooms: rooms[mapID], NPCDensity: density.NPCMultiplier(mapID), Overflow: density.Overflows(mapID)}
+		for cell, n := range totals[mapID] {
+			view.Players += n
+			if n > view.Peak {
+				view.Peak = n
+			}
+			if n >= m.opts.HotPlayers {
+				view.HotCells = append(view.HotCells, Cell{X: cell[0], Y: cell[1], Players: n})
+			}
+		}
+		sort.Slice(view.HotCells, func(i, j int) bool {
+			a, b := view.HotCells[i], view.HotCells[j]
+			if a.Players != b.Players {
+				return a.Players > b.Players
+			}
+			return a.X < b.X || a.X == b.X && a.Y < b.Y
+		})
+		view.Hot = view.Peak >= m.opts.HotPlayers
+		cool := view.Peak <= m.opts.CoolPlayers
+		switch {
+		case view.Hot || cool:
+			if state.hot != view.Hot {
+				state.hot, state.streak = view.Hot, 0
+			}
+			state.streak++
+		default:
+			state.streak = 0
+		}
+		switch {
+		case view.Hot && state.streak >= m.opts.Sustain:
+			state.streak = 0
+			if view.NPCDensity < m.opts.MaxNPCDensity || !view.Overflow {
+				raise = append(raise, mapID)
+			}
+		case cool && state.streak >= m.opts.Sustain && state.adjusted:
+			state.streak = 0
+			lower = append(lower, mapID)
+		case cool && !state.adjusted && rooms[mapID] == 0:
+			delete(m.maps, mapID) // Nothing left to watch or take back
+			continue
+		}
+		view.Streak, view.Adjusted = state.streak, state.adjusted
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Map < views[j].Map })
+	m.views = views
+	m.mu.Unlock()
+
+	if len(raise) > 0 || len(lower) > 0 {
+		m.adjust(views, raise, lower)
+	}
+	return m.Maps()
+}
+
+// adjust raises the density of the maps in raise and lowers it on those in
+// lower, as one balance version.
+func (m *Monitor) adjust(views []MapView, raise, lower []string) {
+	byMap := make(map[string]MapView, len(views))
+	for _, view := range views {
+		byMap[view.Map] = view
+	}
+	sort.Strings(raise)
+	sort.Strings(lower)
+	var changes []string
+	snapshot, err := m.balance.Adjust(adjustedBy, func(v *balance.Values) string {
+		d := &v.Density
+		for _, mapID := range raise {
+			from := d.NPCMultiplier(mapID)
+			to := math.Min(from+m.opts.NPCStep, m.opts.MaxNPCDensity)
+			setNPCMultiplier(d, mapID, to)
+			change := fmt.Sprintf("%s is hot (%d players in cell %d,%d): NPC density %g -> %g", mapID, byMap[mapID].Peak, byMap[mapID].HotCells[0].X, byMap[mapID].HotCells[0].Y, from, to)
+			if !d.Overflows(mapID) {
+				d.OverflowMaps = append(d.OverflowMaps, mapID)
+				change += ", overflow instances on"
+			}
+			changes = append(changes, change)
+		}
+		for _, mapID := range lower {
+			from := d.NPCMultiplier(mapID)
+			to := math.Max(from-m.opts.NPCStep, 1)
+			setNPCMultiplier(d, mapID, to)
+			change := fmt.Sprintf("%s cooled down (at most %d players in a cell): NPC density %g -> %g", mapID, byMap[mapID].Peak, from, to)
+			if to == 1 && d.Overflows(mapID) {
+				d.OverflowMaps = without(d.OverflowMaps, mapID)
+				change += ", overflow instances off"
+			}
+			changes = append(changes, change)
+		}
+		return strings.Join(changes, "; ")
+	})
+	if err != nil {
+		utils.LogErrorf("Hot zones: Could not adjust map density: %v", err)
+		return
+	}
+	utils.LogInfof("Hot zones: Balance version %d: %s.", snapshot.Version, snapshot.Note)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	density := snapshot.Values.Density
+	for _, mapID := range append(raise, lower...) {
+		if state := m.maps[mapID]; state != nil {
+			state.adjusted = density.NPCMultiplier(mapID) > 1 || density.Overflows(mapID)
+		}
+	}
+	for i := range m.views {
+		m.views[i].NPCDensity = density.NPCMultiplier(m.views[i].Map)
+		m.views[i].Overflow = density.Overflows(m.views[i].Map)
+		if state := m.maps[m.views[i].Map]; state != nil {
+			m.views[i].Adjusted = state.adjusted
+		}
+	}
+}
+
+// Start evaluates every Interval until Stop.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Evaluate()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the evaluations.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+func (m *Monitor) cell(p geometry.Vec) [2]int {
+	return [2]int{int(math.Floor(p.X / m.opts.CellSize)), int(math.Floor(p.Y / m.opts.CellSize))}
+}
+
+func setNPCMultiplier(d *balance.DensityValues, mapID string, multiplier float64) {
+	if multiplier == 1 {
+		delete(d.NPCMultipliers, mapID)
+		return
+	}
+	if d.NPCMultipliers == nil {
+		d.NPCMultipliers = make(map[string]float64)
+	}
+	d.NPCMultipliers[mapID] = multiplier
+}
+
+func without(ids []string, id string) []string {
+	kept := ids[:0:0]
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,82 @@
+package hotzone
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+func crowd(n int, at geometry.Vec) []geometry.Vec {
+	positions := make([]geometry.Vec, n)
+	for i := range positions {
+		positions[i] = at
+	}
+	return positions
+}
+
+func TestMonitorRaisesAndTakesBackDensity(t *testing.T) {
+	values, err := balance.Open("", &balance.MemoryHistory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMonitor(values, Options{CellSize: 10, HotPlayers: 4, CoolPlayers: 1, Sustain: 2, NPCStep: 0.5, MaxNPCDensity: 1.5, Interval: time.Second})
+	clock := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+
+	// Two rooms on the same map share their cells.
+	m.Report(Sample{Room: "a", Map: "forest", Positions: crowd(2, geometry.Vec{X: 31, Y: 42})})
+	m.Report(Sample{Room: "b", Map: "forest", Positions: append(crowd(2, geometry.Vec{X: 39, Y: 48}), geometry.Vec{X: 1, Y: 1})})
+	m.Report(Sample{Room: "c", Map: "cave", Positions: crowd(3, geometry.Vec{})})
+	views := m.Evaluate()
+	if len(views) != 2 || views[1].Map != "forest" || !views[1].Hot || views[1].Players != 5 || views[1].HotCells[0] != (Cell{X: 3, Y: 4, Players: 4}) {
+		t.Fatalf("first evaluation = %+v", views)
+	}
+	if values.Current().Version != 1 {
+		t.Fatal("adjusted before the map stayed hot for Sustain evaluations")
+	}
+	m.Evaluate()
+	snapshot := values.Current()
+	if d := snapshot.Values.Density; d.NPCMultiplier("forest") != 1.5 || !d.Overflows("forest") || d.Overflows("cave") {
+		t.Fatalf("density after a sustained hot zone = %+v", d)
+	}
+	if snapshot.Source != balance.SourceAuto || snapshot.By != "hotzones" || !strings.Contains(snapshot.Note, "forest is hot (4 players in cell 3,4)") {
+		t.Fatalf("version = %+v", snapshot)
+	}
+	m.Evaluate()
+	m.Evaluate()
+	if values.Current().Version != snapshot.Version {
+		t.Fatal("adjusted a map already at its most density")
+	}
+
+	// Room b empties and room a stops reporting; the map cools down.
+	m.Forget("b")
+	clock = clock.Add(4 * time.Second)
+	m.Evaluate()
+	m.Evaluate()
+	if d := values.Values().Density; d.NPCMultiplier("forest") != 1 || d.Overflows("forest") {
+		t.Fatalf("density after cooling down = %+v", d)
+	}
+	if views := m.Maps(); len(views) != 1 || views[0].Map != "forest" || views[0].Adjusted {
+		t.Fatalf("maps after cooling down = %+v", views)
+	}
+	m.Evaluate()
+	if views := m.Maps(); len(views) != 0 {
+		t.Fatalf("still watching %+v", views)
+	}
+
+	// The changes are balance versions like any other.
+	if _, err := values.Rollback(snapshot.Version, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if !values.Values().Density.Overflows("forest") {
+		t.Fatal("rollback did not restore the monitor's change")
+	}
+	var none *Monitor
+	none.Report(Sample{Room: "a", Map: "forest"})
+	if none.Density().NPCMultiplier("forest") != 1 {
+		t.Fatal("a nil monitor changes density")
+	}
+}
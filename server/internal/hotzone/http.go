@@ -0,0 +1,27 @@
+package hotzone
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the admin endpoint to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator. The monitor's changes are in the balance history, where they can
+// be rolled back.
+//
+//	GET /admin/hotzones   every watched map as of the latest evaluation
+func (m *Monitor) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/hotzones", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"maps":    m.Maps(),
+			"density": m.Density(),
+		})
+	}))
+}
@@ -0,0 +1,122 @@
+// Package idempotency remembers the results of mutating commands that
+// clients sent with an idempotency key, so a command retried after a network
+// failure is answered with its original result instead of running twice. Keys
+// are kept per player, for a while and up to a limit, and survive a reconnect.
+package idempotency
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Defaults used when Options leaves a field zero.
+const (
+	DefaultTTL        = 10 * time.Minute
+	DefaultPendingTTL = time.Minute
+	DefaultMaxKeys    = 64
+)
+
+// Result is the reply a command was answered with. A command answered with
+// nothing, such as a trade response whose outcome arrives as an update, has
+// an empty Type.
+type Result struct {
+	Type    string
+	Payload json.RawMessage
+}
+
+// Options configures a Cache.
+type Options struct {
+	TTL        time.Duration // How long a result is kept
+	PendingTTL time.Duration // How long a command may run before a retry is let through
+	MaxKeys    int           // Keys kept per player; the oldest is dropped first
+}
+
+type entry struct {
+	key       string
+	expiresAt time.Time
+	done      bool
+	result    Result
+}
+
+// Cache holds recently used keys and their results. It is safe for
+// concurrent use.
+type Cache struct {
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	players   map[string][]*entry // By player ID, oldest first
+	lastSweep time.Time
+}
+
+// NewCache creates a Cache.
+func NewCache(opts Options) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.PendingTTL <= 0 {
+		opts.PendingTTL = DefaultPendingTTL
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = DefaultMaxKeys
+	}
+	return &Cache{opts: opts, now: time.Now, players: make(map[string][]*entry), lastSweep: time.Now()}
+}
+
+// Begin claims key for a command of playerID. If the key completed before,
+// it returns its result and done; if a command with the key is still
+// running, it returns pending. Otherwise the caller runs the command and
+// calls Complete.
+func (c *Cache) Begin(playerID, key string) (result Result, done, pending bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.lastSweep) >= c.opts.TTL {
+		// Now and then, drop the keys of players who have not been back.
+		c.lastSweep = now
+		for id := range c.players {
+			c.prune(id, now)
+		}
+	}
+	entries := c.prune(playerID, now)
+	for _, e := range entries {
+		if e.key == key {
+			return e.result, e.done, !e.done
+		}
+	}
+	if len(entries) >= c.opts.MaxKeys {
+		entries = entries[len(entries)-c.opts.MaxKeys+1:]
+	}
+	c.players[playerID] = append(entries, &entry{key: key, expiresAt: now.Add(c.opts.PendingTTL)})
+	return Result{}, false, false
+}
+
+// Complete records the result of the command claimed with key.
+func (c *Cache) Complete(playerID, key string, result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.players[playerID] {
+		if e.key == key {
+			e.done, e.result, e.expiresAt = true, result, c.now().Add(c.opts.TTL)
+			return
+		}
+	}
+}
+
+// prune drops playerID's expired keys and returns the rest. Callers hold c.mu.
+func (c *Cache) prune(playerID string, now time.Time) []*entry {
+	entries := c.players[playerID]
+	kept := entries[:0]
+	for _, e := range entries {
+		if now.Before(e.expiresAt) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		delete(c.players, playerID)
+		return nil
+	}
+	c.players[playerID] = kept
+	return kept
+}
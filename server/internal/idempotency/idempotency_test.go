@@ -0,0 +1,54 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeysArePendingThenDoneThenExpire(t *testing.T) {
+	c := NewCache(Options{TTL: time.Minute, PendingTTL: 10 * time.Second, MaxKeys: 2})
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	if _, done, pending := c.Begin("alice", "k1"); done || pending {
+		t.Fatal("a new key was already known")
+	}
+	if _, done, pending := c.Begin("alice", "k1"); done || !pending {
+		t.Fatal("a running command's key was not pending")
+	}
+	if _, done, pending := c.Begin("bob", "k1"); done || pending {
+		t.Fatal("keys are shared between players")
+	}
+	c.Complete("alice", "k1", Result{Type: "SHOP_TRANSACTION_RESULT", Payload: []byte(`{"success":true}`)})
+	if result, done, _ := c.Begin("alice", "k1"); !done || result.Type != "SHOP_TRANSACTION_RESULT" || string(result.Payload) != `{"success":true}` {
+		t.Fatalf("completed key = %+v, %t", result, done)
+	}
+
+	// A command that never finishes lets retries through after PendingTTL.
+	c.Begin("bob", "k2")
+	now = now.Add(11 * time.Second)
+	if _, _, pending := c.Begin("bob", "k2"); pending {
+		t.Error("a stuck command's key is still pending")
+	}
+	if _, done, _ := c.Begin("alice", "k1"); !done {
+		t.Error("a result was dropped before its TTL")
+	}
+	now = now.Add(time.Minute)
+	if _, done, _ := c.Begin("alice", "k1"); done {
+		t.Error("a result outlived its TTL")
+	}
+}
+
+func TestOldestKeysAreDroppedAtTheLimit(t *testing.T) {
+	c := NewCache(Options{MaxKeys: 2})
+	for _, key := range []string{"k1", "k2", "k3"} {
+		c.Begin("alice", key)
+		c.Complete("alice", key, Result{})
+	}
+	if _, done, _ := c.Begin("alice", "k3"); !done {
+		t.Error("the newest key was dropped")
+	}
+	if _, done, _ := c.Begin("alice", "k1"); done {
+		t.Error("the oldest key was kept beyond the limit")
+	}
+}
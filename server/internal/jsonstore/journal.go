@@ -0,0 +1,266 @@
+package jsonstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// compactSlack is how many superseded entries a journal holds, beyond one per
+// live record, before a save compacts it.
+const compactSlack = 1024
+
+// Records splits a state into records that are saved on their own, and joins
+// them back. A record keeps its key for as long as it exists, such as an
+// order under "order/" and its ID.
+type Records[T any] interface {
+	Split(state T) map[string]any
+	Join(records map[string]json.RawMessage) (T, error)
+}
+
+// Journal keeps a state as records in an append-only file. A save appends
+// the records that changed since the last one and the removal of those that
+// are gone, and syncs the file before it returns: it writes what changed
+// rather than the whole state, and a crash loses no saved change. Loading
+// replays the file and compacts it to one entry per record, as does a save
+// once superseded entries outnumber live ones.
+type Journal[T any] struct {
+	path    string
+	records Records[T]
+
+	mu      sync.Mutex
+	file    *os.File            // Open for appending; nil until loaded, and after a failed write
+	saved   map[string][32]byte // Hash of each saved record's encoding
+	entries int                 // In the file, live or not
+}
+
+// entry is one line of a journal: a record, or its removal when Value is empty.
+type entry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// NewJournal creates a Journal keeping the records of a state in the file at path.
+func NewJournal[T any](path string, records Records[T]) *Journal[T] {
+	return &Journal[T]{path: path, records: records}
+}
+
+// LoadState implements Store. A missing file is an empty state, and a file
+// saved by File is read as one and rewritten as a journal.
+func (j *Journal[T]) LoadState() (T, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var state T
+	data, err := os.ReadFile(j.path)
+	if err != nil && !os.IsNotExist(err) {
+		return state, err
+	}
+	if bytes.HasPrefix(data, []byte("{\n")) {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return state, err
+		}
+		encoded, err := encodeRecords(j.records.Split(state))
+		if err != nil {
+			return state, err
+		}
+		return state, j.compact(encoded)
+	}
+	records, err := replay(data)
+	if err != nil {
+		return state, fmt.Errorf("%s: %w", j.path, err)
+	}
+	if state, err = j.records.Join(records); err != nil {
+		return state, fmt.Errorf("%s: %w", j.path, err)
+	}
+	return state, j.compact(records)
+}
+
+// SaveState implements Store.
+func (j *Journal[T]) SaveState(state T) error {
+	encoded, err := encodeRecords(j.records.Split(state))
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return j.compact(encoded)
+	}
+
+	var buf bytes.Buffer
+	changed := make(map[string][32]byte)
+	for _, key := range sortedKeys(encoded) {
+		hash := sha256.Sum256(encoded[key])
+		if saved, ok := j.saved[key]; ok && saved == hash {
+			continue
+		}
+		changed[key] = hash
+		writeEntry(&buf, entry{Key: key, Value: encoded[key]})
+	}
+	var removed []string
+	for _, key := range sortedKeys(j.saved) {
+		if _, ok := encoded[key]; !ok {
+			removed = append(removed, key)
+			writeEntry(&buf, entry{Key: key})
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	if _, err = j.file.Write(buf.Bytes()); err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		// The next save rewrites the file, dropping any entry written in part.
+		j.file.Close()
+		j.file = nil
+		return err
+	}
+	for key, hash := range changed {
+		j.saved[key] = hash
+	}
+	for _, key := range removed {
+		delete(j.saved, key)
+	}
+	j.entries += len(changed) + len(removed)
+	if j.entries > 2*len(j.saved)+compactSlack {
+		return j.compact(encoded)
+	}
+	return nil
+}
+
+// compact replaces the file with one entry per record and opens it for
+// appending.
+func (j *Journal[T]) compact(records map[string]json.RawMessage) error {
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	var buf bytes.Buffer
+	saved := make(map[string][32]byte, len(records))
+	for _, key := range sortedKeys(records) {
+		writeEntry(&buf, entry{Key: key, Value: records[key]})
+		saved[key] = sha256.Sum256(records[key])
+	}
+	if err := syncFile(j.path+".tmp", buf.Bytes()); err != nil {
+		return err
+	}
+	if err := os.Rename(j.path+".tmp", j.path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	j.file, j.saved, j.entries = file, saved, len(records)
+	return nil
+}
+
+// replay applies the entries of a journal in order. A last entry cut short
+// by a crash is ignored: its save never returned.
+func replay(data []byte) (map[string]json.RawMessage, error) {
+	records := make(map[string]json.RawMessage)
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if len(e.Value) == 0 {
+			delete(records, e.Key)
+		} else {
+			records[e.Key] = e.Value
+		}
+	}
+	return records, nil
+}
+
+func encodeRecords(records map[string]any) (map[string]json.RawMessage, error) {
+	encoded := make(map[string]json.RawMessage, len(records))
+	for key, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %s: %w", key, err)
+		}
+		encoded[key] = data
+	}
+	return encoded, nil
+}
+
+func writeEntry(buf *bytes.Buffer, e entry) {
+	data, _ := json.Marshal(e) // A key and valid JSON always encode
+	buf.Write(data)
+	buf.WriteByte('\n')
+}
+
+// syncFile writes data to path and syncs it to disk.
+func syncFile(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PutRecords adds the values of m to records, each under prefix and its key in m.
+func PutRecords[V any](records map[string]any, prefix string, m map[string]V) {
+	for key, value := range m {
+		records[prefix+key] = value
+	}
+}
+
+// TakeRecords decodes the records under prefix into a map by the rest of
+// their keys.
+func TakeRecords[V any](records map[string]json.RawMessage, prefix string) (map[string]V, error) {
+	m := make(map[string]V)
+	for key, data := range records {
+		id, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		var value V
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("record %s: %w", key, err)
+		}
+		m[id] = value
+	}
+	return m, nil
+}
+
+// TakeRecord decodes the record under key into value, if there is one.
+func TakeRecord(records map[string]json.RawMessage, key string, value any) error {
+	data, ok := records[key]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("record %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package jsonstore persists the state of a service as JSON. Services that
+// keep their whole state in memory and save it after every change load and
+// save it through a Store: Memory for tests and servers without a state
+// file, File to rewrite one JSON file, and Journal to append only the records
+// that changed, for state that holds players' assets in custody.
+package jsonstore
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Store persists a state of type T.
+type Store[T any] interface {
+	LoadState() (T, error)
+	SaveState(T) error
+}
+
+// Memory keeps a state in memory. It is kept encoded, as File would keep it,
+// so a loaded state shares no maps or slices with the saved one.
+type Memory[T any] struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// LoadState implements Store. Nothing saved is an empty state.
+func (m *Memory[T]) LoadState() (T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var state T
+	if m.data == nil {
+		return state, nil
+	}
+	err := json.Unmarshal(m.data, &state)
+	return state, err
+}
+
+// SaveState implements Store.
+func (m *Memory[T]) SaveState(state T) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = data
+	return nil
+}
+
+// File keeps a state in a JSON file, rewritten on every save.
+type File[T any] struct {
+	Path string
+}
+
+// LoadState implements Store. A missing file is an empty state.
+func (f File[T]) LoadState() (T, error) {
+	var state T
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// SaveState implements Store.
+func (f File[T]) SaveState(state T) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(f.Path, data)
+}
+
+// replaceFile writes data to path through a temporary file, so a crash
+// leaves either the old content or the new.
+func replaceFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,126 @@
+package jsonstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type book struct {
+	Orders map[string]int `json:"orders"`
+	Next   int            `json:"next"`
+}
+
+type bookRecords struct{}
+
+func (bookRecords) Split(b book) map[string]any {
+	r := map[string]any{"next": b.Next}
+	PutRecords(r, "order/", b.Orders)
+	return r
+}
+
+func (bookRecords) Join(r map[string]json.RawMessage) (book, error) {
+	var b book
+	err := TakeRecord(r, "next", &b.Next)
+	if err == nil {
+		b.Orders, err = TakeRecords[int](r, "order/")
+	}
+	return b, err
+}
+
+func TestMemorySharesNothingWithTheSavedState(t *testing.T) {
+	var store Memory[book]
+	state := book{Orders: map[string]int{"a": 1}}
+	store.SaveState(state)
+	state.Orders["a"] = 2
+	loaded, err := store.LoadState()
+	if err != nil || loaded.Orders["a"] != 1 {
+		t.Fatalf("loaded %+v, %v after changing the saved map", loaded, err)
+	}
+}
+
+func TestJournalAppendsWhatChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.json")
+	journal := NewJournal[book](path, bookRecords{})
+	state, err := journal.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Orders = map[string]int{"a": 1, "b": 2}
+	if err := journal.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	before := size(t, path)
+
+	// Changing one order appends that order alone.
+	state.Orders["a"] = 3
+	delete(state.Orders, "b")
+	if err := journal.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	appended, _ := os.ReadFile(path)
+	appended = appended[before:]
+	if want := "{\"key\":\"order/a\",\"value\":3}\n{\"key\":\"order/b\"}\n"; string(appended) != want {
+		t.Fatalf("appended %q, want %q", appended, want)
+	}
+	if err := journal.SaveState(state); err != nil || size(t, path) != before+int64(len(appended)) {
+		t.Fatalf("saving an unchanged state wrote to the file: %v", err)
+	}
+
+	// An entry cut short by a crash is ignored on restart.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"key":"order/c","val`)
+	f.Close()
+	loaded, err := NewJournal[book](path, bookRecords{}).LoadState()
+	if err != nil || len(loaded.Orders) != 1 || loaded.Orders["a"] != 3 {
+		t.Fatalf("restarted with %+v, %v", loaded, err)
+	}
+}
+
+func TestJournalCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.json")
+	journal := NewJournal[book](path, bookRecords{})
+	state := book{Orders: map[string]int{"a": 0}}
+	for i := 0; i < 3*compactSlack; i++ {
+		state.Next = i
+		if err := journal.SaveState(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines > compactSlack+2 {
+		t.Fatalf("the journal kept %d entries for 2 records", lines)
+	}
+	loaded, err := NewJournal[book](path, bookRecords{}).LoadState()
+	if err != nil || loaded.Next != 3*compactSlack-1 {
+		t.Fatalf("loaded %+v, %v", loaded, err)
+	}
+}
+
+func TestJournalReadsAFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.json")
+	if err := (File[book]{Path: path}).SaveState(book{Orders: map[string]int{"a": 1}, Next: 2}); err != nil {
+		t.Fatal(err)
+	}
+	journal := NewJournal[book](path, bookRecords{})
+	loaded, err := journal.LoadState()
+	if err != nil || loaded.Orders["a"] != 1 || loaded.Next != 2 {
+		t.Fatalf("loaded %+v, %v", loaded, err)
+	}
+	// It is a journal from then on.
+	data, _ := os.ReadFile(path)
+	if want := "{\"key\":\"next\",\"value\":2}\n{\"key\":\"order/a\",\"value\":1}\n"; string(data) != want {
+		t.Fatalf("rewritten as %q", data)
+	}
+}
+
+func size(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
@@ -0,0 +1,172 @@
+package keys
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	keyFileVersion = 1
+	keyFileKDF     = "pbkdf2-sha256"
+	// DefaultKDFIterations follows current OWASP guidance for PBKDF2-HMAC-SHA256.
+	DefaultKDFIterations = 600000
+	keyFileSaltSize      = 16
+)
+
+// keyFile is the on-disk format of an encrypted key file.
+type keyFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// EncryptedFileProvider reads an AES-256-GCM encrypted key file whose key is
+// derived from a passphrase held in an environment variable.
+type EncryptedFileProvider struct {
+	Path             string
+	PassphraseEnvVar string
+}
+
+// Name implements Provider.
+func (p *EncryptedFileProvider) Name() string { return "file:" + p.Path }
+
+// LoadKey implements Provider.
+func (p *EncryptedFileProvider) LoadKey(ctx context.Context) (string, error) {
+	passphrase := os.Getenv(p.PassphraseEnvVar)
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase environment variable %s is not set", p.PassphraseEnvVar)
+	}
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file %s: %w", p.Path, err)
+	}
+	key, err := decryptKey(data, passphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt key file %s: %w", p.Path, err)
+	}
+	return normalizeKey(key)
+}
+
+// WriteEncryptedKeyFile encrypts privateKey with passphrase and writes it to path
+// with owner-only permissions.
+func WriteEncryptedKeyFile(path, privateKey, passphrase string) error {
+	data, err := encryptKey(privateKey, passphrase, DefaultKDFIterations)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func encryptKey(privateKey, passphrase string, iterations int) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	if _, err := normalizeKey(privateKey); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, keyFileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newKeyFileCipher(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file := keyFile{
+		Version:    keyFileVersion,
+		KDF:        keyFileKDF,
+		Iterations: iterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(privateKey), nil)),
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+func decryptKey(data []byte, passphrase string) (string, error) {
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("invalid key file format: %w", err)
+	}
+	if file.Version != keyFileVersion || file.KDF != keyFileKDF || file.Iterations <= 0 {
+		return "", fmt.Errorf("unsupported key file (version %d, kdf %q)", file.Version, file.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(file.Salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid salt: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(file.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(file.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	gcm, err := newKeyFileCipher(passphrase, salt, file.Iterations)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("invalid nonce size %d", len(nonce))
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase or corrupted key file")
+	}
+	return string(plaintext), nil
+}
+
+func newKeyFileCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 8018) with HMAC-SHA256.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	derived := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	var blockIndex [4]byte
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(blockIndex[:], uint32(block))
+		prf.Write(blockIndex[:])
+		derived = prf.Sum(derived)
+		t := derived[len(derived)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return derived[:keyLen]
+}
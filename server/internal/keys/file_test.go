@@ -0,0 +1,43 @@
+package keys
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncryptedKeyRoundTrip(t *testing.T) {
+	const privateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+	data, err := encryptKey(privateKey, "correct horse", 1000)
+	if err != nil {
+		t.Fatalf("encryptKey failed: %v", err)
+	}
+
+	got, err := decryptKey(data, "correct horse")
+	if err != nil {
+		t.Fatalf("decryptKey failed: %v", err)
+	}
+	if got != privateKey {
+		t.Errorf("Expected decrypted key %q, got %q", privateKey, got)
+	}
+
+	if _, err := decryptKey(data, "wrong passphrase"); err == nil {
+		t.Error("Expected decryption with the wrong passphrase to fail")
+	}
+}
+
+func TestEncryptKeyRejectsPlaceholder(t *testing.T) {
+	if _, err := encryptKey(PlaceholderKey, "passphrase", 1000); err == nil {
+		t.Error("Expected the placeholder key to be rejected")
+	}
+}
+
+// TestPBKDF2SHA256 checks the implementation against the RFC 7914 section 11 test vector.
+func TestPBKDF2SHA256(t *testing.T) {
+	got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("Unexpected PBKDF2 output:\n got  %s\n want %s", got, want)
+	}
+}
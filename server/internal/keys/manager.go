@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// keyLoadTimeout bounds a single provider load.
+const keyLoadTimeout = 15 * time.Second
+
+// Manager holds the current signing key and rotates it by reloading from its Provider.
+// A failed reload keeps the previous key so signing is never interrupted by a
+// transient backend outage.
+type Manager struct {
+	provider Provider
+
+	mu          sync.RWMutex
+	key         string
+	fingerprint string
+	loadedAt    time.Time
+	stop        chan struct{}
+}
+
+// NewManager creates a Manager for provider. Call Reload to load the first key.
+func NewManager(provider Provider) *Manager {
+	return &Manager{provider: provider}
+}
+
+// Reload fetches the key from the provider, replacing the current key if it changed.
+func (m *Manager) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), keyLoadTimeout)
+	defer cancel()
+
+	key, err := m.provider.LoadKey(ctx)
+	if err != nil {
+		utils.LogWarnf("KeyManager: Failed to load signing key from %s: %v", m.provider.Name(), err)
+		return err
+	}
+	fingerprint := Fingerprint(key)
+
+	m.mu.Lock()
+	previous := m.fingerprint
+	m.key = key
+	m.fingerprint = fingerprint
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+
+	switch {
+	case previous == "":
+		utils.LogInfof("KeyManager: Signing key loaded from %s (fingerprint %s).", m.provider.Name(), fingerprint)
+	case previous != fingerprint:
+		utils.LogInfof("KeyManager: Signing key rotated from %s to %s (source %s).", previous, fingerprint, m.provider.Name())
+	}
+	return nil
+}
+
+// PrivateKey returns the current key, or ErrNoKey if none has been loaded.
+func (m *Manager) PrivateKey() (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.key == "" {
+		return "", ErrNoKey
+	}
+	return m.key, nil
+}
+
+// Fingerprint returns the fingerprint of the current key, or "" if none is loaded.
+func (m *Manager) Fingerprint() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fingerprint
+}
+
+// ProviderName describes where keys are loaded from.
+func (m *Manager) ProviderName() string {
+	return m.provider.Name()
+}
+
+// StartAutoReload reloads the key every interval so rotations in the backend are
+// picked up without a restart. It does nothing if interval is not positive.
+func (m *Manager) StartAutoReload(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.Reload() // Failures are logged and the previous key is kept
+			}
+		}
+	}()
+}
+
+// Stop ends automatic reloading.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
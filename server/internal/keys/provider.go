@@ -0,0 +1,136 @@
+// Package keys loads the server's SUI signing key from a pluggable backend
+// (config file, environment variable, encrypted key file, Vault, or an external
+// command such as a cloud KMS/secret manager CLI) and supports rotating it at
+// runtime without redeploying the configuration.
+package keys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/configs"
+)
+
+// PlaceholderKey is the value written to example configs; it is never a usable key.
+const PlaceholderKey = "YOUR_SUI_PRIVATE_KEY_HEX_HERE"
+
+// ErrNoKey is returned when a provider has no key configured.
+var ErrNoKey = errors.New("no signing key configured")
+
+// Provider loads the server's private signing key from a backend.
+type Provider interface {
+	// Name describes the backend for logs, without revealing secrets.
+	Name() string
+	// LoadKey returns the private key (hex or suiprivkey format).
+	LoadKey(ctx context.Context) (string, error)
+}
+
+// StaticProvider serves a key held in memory, e.g. the legacy sui.privateKey config value.
+type StaticProvider struct {
+	Key string
+}
+
+// Name implements Provider.
+func (p *StaticProvider) Name() string { return "config" }
+
+// LoadKey implements Provider.
+func (p *StaticProvider) LoadKey(ctx context.Context) (string, error) {
+	return normalizeKey(p.Key)
+}
+
+// EnvProvider reads the key from an environment variable.
+type EnvProvider struct {
+	VarName string
+}
+
+// Name implements Provider.
+func (p *EnvProvider) Name() string { return "env:" + p.VarName }
+
+// LoadKey implements Provider.
+func (p *EnvProvider) LoadKey(ctx context.Context) (string, error) {
+	return normalizeKey(os.Getenv(p.VarName))
+}
+
+// CommandProvider runs an external command and uses its trimmed stdout as the key.
+// This integrates secret managers that ship a CLI, e.g.
+// ["aws", "secretsmanager", "get-secret-value", "--secret-id", "sui-key", "--query", "SecretString", "--output", "text"].
+type CommandProvider struct {
+	Command []string
+}
+
+// Name implements Provider.
+func (p *CommandProvider) Name() string {
+	if len(p.Command) == 0 {
+		return "command"
+	}
+	return "command:" + p.Command[0]
+}
+
+// LoadKey implements Provider.
+func (p *CommandProvider) LoadKey(ctx context.Context) (string, error) {
+	if len(p.Command) == 0 {
+		return "", fmt.Errorf("key command is empty")
+	}
+	output, err := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("key command %s failed: %w", p.Command[0], err)
+	}
+	return normalizeKey(string(output))
+}
+
+// normalizeKey trims whitespace and rejects empty or placeholder keys.
+func normalizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || key == PlaceholderKey {
+		return "", ErrNoKey
+	}
+	return key, nil
+}
+
+// Fingerprint returns a short, non-reversible identifier of key for logs.
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// NewProviderFromConfig builds the provider selected by cfg.Type. legacyKey is the
+// sui.privateKey config value, used by the default "config" type.
+func NewProviderFromConfig(cfg configs.KeySourceConfig, legacyKey string) (Provider, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "", "config":
+		return &StaticProvider{Key: legacyKey}, nil
+	case "env":
+		if cfg.EnvVar == "" {
+			return nil, fmt.Errorf("key source 'env' requires envVar")
+		}
+		return &EnvProvider{VarName: cfg.EnvVar}, nil
+	case "file":
+		if cfg.FilePath == "" || cfg.PassphraseEnvVar == "" {
+			return nil, fmt.Errorf("key source 'file' requires filePath and passphraseEnvVar")
+		}
+		return &EncryptedFileProvider{Path: cfg.FilePath, PassphraseEnvVar: cfg.PassphraseEnvVar}, nil
+	case "vault":
+		if cfg.VaultAddress == "" || cfg.VaultSecretPath == "" {
+			return nil, fmt.Errorf("key source 'vault' requires vaultAddress and vaultSecretPath")
+		}
+		return &VaultProvider{
+			Address:     cfg.VaultAddress,
+			TokenEnvVar: cfg.VaultTokenEnvVar,
+			SecretPath:  cfg.VaultSecretPath,
+			Field:       cfg.VaultField,
+		}, nil
+	case "command":
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("key source 'command' requires command")
+		}
+		return &CommandProvider{Command: cfg.Command}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source type %q", cfg.Type)
+	}
+}
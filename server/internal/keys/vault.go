@@ -0,0 +1,79 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultVaultTokenEnvVar is the environment variable the Vault CLI also uses.
+const DefaultVaultTokenEnvVar = "VAULT_TOKEN"
+
+// VaultProvider reads the key from a HashiCorp Vault KV secret (v1 or v2).
+type VaultProvider struct {
+	Address     string // e.g. "https://vault.internal:8200"
+	TokenEnvVar string // Environment variable holding the Vault token; defaults to VAULT_TOKEN
+	SecretPath  string // e.g. "secret/data/suigserver" for KV v2
+	Field       string // Field within the secret; defaults to "privateKey"
+	HTTPClient  *http.Client
+}
+
+// Name implements Provider.
+func (p *VaultProvider) Name() string { return "vault:" + p.SecretPath }
+
+// LoadKey implements Provider.
+func (p *VaultProvider) LoadKey(ctx context.Context) (string, error) {
+	tokenEnvVar := p.TokenEnvVar
+	if tokenEnvVar == "" {
+		tokenEnvVar = DefaultVaultTokenEnvVar
+	}
+	token := os.Getenv(tokenEnvVar)
+	if token == "" {
+		return "", fmt.Errorf("vault token environment variable %s is not set", tokenEnvVar)
+	}
+	field := p.Field
+	if field == "" {
+		field = "privateKey"
+	}
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	url := strings.TrimRight(p.Address, "/") + "/v1/" + strings.TrimLeft(p.SecretPath, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, p.SecretPath)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	data := body.Data
+	// KV v2 nests the secret under data.data; KV v1 puts it directly under data.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %q", p.SecretPath, field)
+	}
+	return normalizeKey(value)
+}
@@ -0,0 +1,288 @@
+// Package leader elects one server instance to run each singleton background
+// worker, such as the sweep that removes expired listings on-chain. Each
+// worker has a role; instances campaign for every role by holding a lease in
+// a shared store (a Redis key or a Postgres advisory lock), renewing it while
+// they run. When the leader stops or loses the store, its lease runs out and
+// another instance takes over.
+//
+// Workers keep running on every instance and ask their role before each run,
+// so failover needs no restart: the new leader's next tick does the work.
+// Leadership can briefly overlap around a failover, so the work must tolerate
+// running twice, as outbox handlers already do.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/health"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultLeaseTTL is the lease duration when Options leaves it at zero.
+const DefaultLeaseTTL = 15 * time.Second
+
+// CampaignHeartbeat is the health heartbeat name of the campaign loop.
+const CampaignHeartbeat = "leader-election"
+
+// Lease is the store instances compete in.
+type Lease interface {
+	// Acquire takes the lease on name for holder, or renews it if holder
+	// already has it, for ttl. It reports whether holder has the lease.
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease on name if holder has it.
+	Release(ctx context.Context, name, holder string) error
+}
+
+// Options configures an Elector.
+type Options struct {
+	Holder   string        // This instance's name in the leases; defaults to hostname-pid
+	LeaseTTL time.Duration // How long a lease outlives its last renewal; renewed every third of it
+}
+
+// Role is one singleton worker's leadership. A nil Role always leads, so
+// workers run everywhere when no elector is configured.
+type Role struct {
+	name string
+
+	mu          sync.Mutex
+	leader      bool
+	since       time.Time // Of the last change
+	lastRenewed time.Time
+	lastError   string
+	changes     int
+	onChange    []func(leader bool)
+}
+
+// Name returns the role's name.
+func (r *Role) Name() string { return r.name }
+
+// IsLeader reports whether this instance runs the role's worker now.
+func (r *Role) IsLeader() bool {
+	if r == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+// OnChange registers fn to be called, on the elector's goroutine, when this
+// instance gains or loses the role.
+func (r *Role) OnChange(fn func(leader bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// set records the outcome of a campaign and returns the callbacks to run if
+// leadership changed.
+func (r *Role) set(leader bool, err error, now time.Time) []func(bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.lastError = err.Error()
+	} else {
+		r.lastError = ""
+		if leader {
+			r.lastRenewed = now
+		}
+	}
+	if leader == r.leader {
+		return nil
+	}
+	r.leader, r.since = leader, now
+	r.changes++
+	return append([]func(bool){}, r.onChange...)
+}
+
+// RoleStatus is a role's leadership, for the health and debug endpoints.
+type RoleStatus struct {
+	Role        string    `json:"role"`
+	Leader      bool      `json:"leader"`
+	Since       time.Time `json:"since"`               // Of the last change
+	LastRenewed time.Time `json:"lastRenewed"`         // Of the lease, while leading
+	LastError   string    `json:"lastError,omitempty"` // Of the latest campaign
+	Changes     int       `json:"changes"`             // Times leadership was gained or lost
+}
+
+// Status is this instance's leadership of every role.
+type Status struct {
+	Holder          string       `json:"holder"`
+	LeaseTTLSeconds float64      `json:"leaseTtlSeconds"`
+	Roles           []RoleStatus `json:"roles"`
+}
+
+// Elector campaigns for roles on behalf of this instance.
+type Elector struct {
+	lease Lease
+	opts  Options
+
+	mu    sync.Mutex
+	roles map[string]*Role
+
+	healthMonitor *health.Monitor
+
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// New creates an elector that campaigns in lease.
+func New(lease Lease, opts Options) *Elector {
+	if opts.Holder == "" {
+		host, _ := os.Hostname()
+		opts.Holder = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = DefaultLeaseTTL
+	}
+	return &Elector{
+		lease: lease,
+		opts:  opts,
+		roles: make(map[string]*Role),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Holder returns this instance's name in the leases.
+func (e *Elector) Holder() string { return e.opts.Holder }
+
+// Role returns the role named name, campaigning for it from the next round.
+// A nil Elector returns a nil Role, which always leads.
+func (e *Elector) Role(name string) *Role {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	role, ok := e.roles[name]
+	if !ok {
+		role = &Role{name: name}
+		e.roles[name] = role
+	}
+	return role
+}
+
+// SetHealthMonitor reports the campaign loop as a heartbeat, so an instance
+// whose loop is stuck, and that may lead on a stale view, is reported dead.
+// Call it before Start.
+func (e *Elector) SetHealthMonitor(monitor *health.Monitor) {
+	e.healthMonitor = monitor
+	monitor.ExpectHeartbeat(CampaignHeartbeat, 3*e.opts.LeaseTTL)
+}
+
+// Start campaigns in the background until Stop.
+func (e *Elector) Start() {
+	e.startOnce.Do(func() { go e.run() })
+}
+
+// Stop stops campaigning and releases the roles this instance leads, so
+// another instance can take them over at once instead of after the lease.
+func (e *Elector) Stop() {
+	if e == nil {
+		return
+	}
+	e.stopOnce.Do(func() {
+		close(e.stop)
+		e.startOnce.Do(func() { close(e.done) }) // Never started
+		<-e.done
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, role := range e.sortedRoles() {
+			if !role.IsLeader() {
+				continue
+			}
+			if err := e.lease.Release(ctx, role.name, e.opts.Holder); err != nil {
+				utils.LogWarnf("Leader: Could not release %s: %v", role.name, err)
+			}
+			e.apply(role, role.set(false, nil, time.Now()))
+		}
+	})
+}
+
+func (e *Elector) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		e.Campaign(time.Now())
+		if e.healthMonitor != nil {
+			e.healthMonitor.Beat(CampaignHeartbeat)
+		}
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Campaign takes or renews the lease of every role once. Start calls it
+// every third of the lease TTL.
+func (e *Elector) Campaign(now time.Time) {
+	for _, role := range e.sortedRoles() {
+		ctx, cancel := context.WithTimeout(context.Background(), e.opts.LeaseTTL/3)
+		leader, err := e.lease.Acquire(ctx, role.name, e.opts.Holder, e.opts.LeaseTTL)
+		cancel()
+		if err != nil {
+			// The store could not be reached. A leader keeps the role only while
+			// its last renewal surely outlasts the next round; past that, another
+			// instance may already hold the lease.
+			role.mu.Lock()
+			leader = role.leader && now.Sub(role.lastRenewed) < e.opts.LeaseTTL*2/3
+			role.mu.Unlock()
+		}
+		e.apply(role, role.set(leader, err, now))
+	}
+}
+
+func (e *Elector) apply(role *Role, callbacks []func(bool)) {
+	if callbacks == nil {
+		return
+	}
+	leader := role.IsLeader()
+	if leader {
+		utils.LogInfof("Leader: %s now leads %s.", e.opts.Holder, role.name)
+	} else {
+		utils.LogInfof("Leader: %s no longer leads %s.", e.opts.Holder, role.name)
+	}
+	for _, fn := range callbacks {
+		fn(leader)
+	}
+}
+
+func (e *Elector) sortedRoles() []*Role {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	roles := make([]*Role, 0, len(e.roles))
+	for _, role := range e.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].name < roles[j].name })
+	return roles
+}
+
+// Status returns this instance's leadership of every role.
+func (e *Elector) Status() Status {
+	status := Status{Holder: e.opts.Holder, LeaseTTLSeconds: e.opts.LeaseTTL.Seconds(), Roles: []RoleStatus{}}
+	for _, role := range e.sortedRoles() {
+		role.mu.Lock()
+		status.Roles = append(status.Roles, RoleStatus{
+			Role:        role.name,
+			Leader:      role.leader,
+			Since:       role.since,
+			LastRenewed: role.lastRenewed,
+			LastError:   role.lastError,
+			Changes:     role.changes,
+		})
+		role.mu.Unlock()
+	}
+	return status
+}
@@ -0,0 +1,94 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	lease := NewMemoryLease()
+	now := time.Now()
+	lease.now = func() time.Time { return now }
+	a := New(lease, Options{Holder: "a", LeaseTTL: time.Minute})
+	b := New(lease, Options{Holder: "b", LeaseTTL: time.Minute})
+	roleA, roleB := a.Role("sweep"), b.Role("sweep")
+	var changes []bool
+	roleB.OnChange(func(leader bool) { changes = append(changes, leader) })
+
+	a.Campaign(now)
+	b.Campaign(now)
+	if !roleA.IsLeader() || roleB.IsLeader() {
+		t.Fatalf("a leads = %v, b leads = %v", roleA.IsLeader(), roleB.IsLeader())
+	}
+
+	// a stops renewing; b takes over once the lease runs out.
+	now = now.Add(59 * time.Second)
+	b.Campaign(now)
+	if roleB.IsLeader() {
+		t.Fatal("b took over before the lease ran out")
+	}
+	now = now.Add(2 * time.Second)
+	b.Campaign(now)
+	if !roleB.IsLeader() {
+		t.Fatal("b did not take over an expired lease")
+	}
+	a.Campaign(now)
+	if roleA.IsLeader() {
+		t.Fatal("a still leads after losing the lease")
+	}
+
+	// Stopping releases the lease at once.
+	b.Stop()
+	a.Campaign(now)
+	if !roleA.IsLeader() || roleB.IsLeader() {
+		t.Fatalf("after b stopped: a leads = %v, b leads = %v", roleA.IsLeader(), roleB.IsLeader())
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("b's changes = %v", changes)
+	}
+	if status := a.Status(); len(status.Roles) != 1 || !status.Roles[0].Leader || status.Roles[0].Changes != 3 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+type failingLease struct{ err error }
+
+func (f *failingLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	return true, nil
+}
+
+func (f *failingLease) Release(ctx context.Context, name, holder string) error { return nil }
+
+func TestLeaderKeepsRoleThroughBriefOutage(t *testing.T) {
+	lease := &failingLease{}
+	e := New(lease, Options{Holder: "a", LeaseTTL: 30 * time.Second})
+	role := e.Role("sweep")
+	now := time.Now()
+	e.Campaign(now)
+
+	lease.err = errors.New("connection refused")
+	e.Campaign(now.Add(10 * time.Second))
+	if !role.IsLeader() {
+		t.Fatal("lost the role within the lease")
+	}
+	if e.Status().Roles[0].LastError == "" {
+		t.Error("the failed renewal was not reported")
+	}
+	e.Campaign(now.Add(20 * time.Second))
+	if role.IsLeader() {
+		t.Fatal("kept the role when another instance may hold the lease")
+	}
+}
+
+func TestNilRoleLeads(t *testing.T) {
+	var e *Elector
+	if !e.Role("sweep").IsLeader() {
+		t.Error("a nil role does not lead")
+	}
+	e.Stop()
+}
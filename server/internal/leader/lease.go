@@ -0,0 +1,162 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// MemoryLease keeps leases in this process. It is for tests, and for a lone
+// instance, which always wins.
+type MemoryLease struct {
+	mu     sync.Mutex
+	leases map[string]memoryHold
+	now    func() time.Time
+}
+
+type memoryHold struct {
+	holder  string
+	expires time.Time
+}
+
+// NewMemoryLease creates an empty in-process lease store.
+func NewMemoryLease() *MemoryLease {
+	return &MemoryLease{leases: make(map[string]memoryHold), now: time.Now}
+}
+
+// Acquire implements Lease.
+func (m *MemoryLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if hold, ok := m.leases[name]; ok && hold.holder != holder && now.Before(hold.expires) {
+		return false, nil
+	}
+	m.leases[name] = memoryHold{holder: holder, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Release implements Lease.
+func (m *MemoryLease) Release(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leases[name].holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
+
+// redisAcquire sets the lease key if it is free, or extends it if the holder
+// already has it, in one step so no other instance can slip in between.
+var redisAcquire = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+// redisRelease deletes the lease key only if the holder has it.
+var redisRelease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisLease keeps each lease in a Redis key holding the holder's name, which
+// expires unless renewed.
+type RedisLease struct {
+	Client redis.UniversalClient
+	Prefix string // Prepended to role names; defaults to "leader:"
+}
+
+func (r RedisLease) key(name string) string {
+	if r.Prefix == "" {
+		return "leader:" + name
+	}
+	return r.Prefix + name
+}
+
+// Acquire implements Lease.
+func (r RedisLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	held, err := redisAcquire.Run(ctx, r.Client, []string{r.key(name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis lease %s: %w", name, err)
+	}
+	return held == 1, nil
+}
+
+// Release implements Lease.
+func (r RedisLease) Release(ctx context.Context, name, holder string) error {
+	return redisRelease.Run(ctx, r.Client, []string{r.key(name)}, holder).Err()
+}
+
+// PostgresLease holds a session advisory lock per role, on a connection kept
+// out of the pool while the lock is held. Postgres frees the lock when that
+// connection closes, so a leader that dies or loses the database stops
+// leading without waiting for a TTL; the TTL only bounds each renewal.
+type PostgresLease struct {
+	DB *sql.DB
+
+	mu    sync.Mutex
+	conns map[string]*sql.Conn // Role -> connection holding its lock
+}
+
+// NewPostgresLease creates a lease store on db.
+func NewPostgresLease(db *sql.DB) *PostgresLease {
+	return &PostgresLease{DB: db, conns: make(map[string]*sql.Conn)}
+}
+
+// Acquire implements Lease. A held lock is renewed by checking that its
+// connection is still alive.
+func (p *PostgresLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[name]; ok {
+		err := conn.PingContext(ctx)
+		if err == nil {
+			return true, nil
+		}
+		// The lock went with the session, so leadership is lost for certain.
+		utils.LogWarnf("Leader: Lost the Postgres session holding %s: %v", name, err)
+		conn.Close()
+		delete(p.conns, name)
+		return false, nil
+	}
+	conn, err := p.DB.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("postgres lease %s: %w", name, err)
+	}
+	var held bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, "leader:"+name).Scan(&held); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("postgres lease %s: %w", name, err)
+	}
+	if !held {
+		conn.Close()
+		return false, nil
+	}
+	p.conns[name] = conn
+	return true, nil
+}
+
+// Release implements Lease.
+func (p *PostgresLease) Release(ctx context.Context, name, holder string) error {
+	p.mu.Lock()
+	conn, ok := p.conns[name]
+	delete(p.conns, name)
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	defer conn.Close()
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, "leader:"+name)
+	return err
+}
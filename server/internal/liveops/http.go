@@ -0,0 +1,114 @@
+package liveops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/features"
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is recorded with every action.
+//
+//	GET  /admin/liveops                players online by world and zone, error rate, live events, today's marketplace sales and the outbox backlog
+//	POST /admin/liveops/flag           {"name": "marketplace", "enabled": false}
+//	POST /admin/liveops/broadcast      {"text": "Servers restart in 10 minutes."} sends an ANNOUNCEMENT to every online player
+//	POST /admin/liveops/events/start   {"name": "Harvest weekend", "minutes": 2880, "feature": "escrowTrades", "announcement": "..."}
+//	POST /admin/liveops/events/end     {"id": "event-3"}
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/liveops", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.Dashboard())
+	}))
+	mux.HandleFunc("/admin/liveops/flag", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var body struct {
+			Name    string `json:"name"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if !decodePost(w, r, &body) {
+			return
+		}
+		if body.Enabled == nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("enabled is required"))
+			return
+		}
+		state, err := s.SetFlag(body.Name, *body.Enabled, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, state)
+	}))
+	mux.HandleFunc("/admin/liveops/broadcast", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if !decodePost(w, r, &body) {
+			return
+		}
+		recipients, err := s.Broadcast(body.Text, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]int{"recipients": recipients})
+	}))
+	mux.HandleFunc("/admin/liveops/events/start", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var req StartRequest
+		if !decodePost(w, r, &req) {
+			return
+		}
+		event, err := s.StartEvent(req, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, event)
+	}))
+	mux.HandleFunc("/admin/liveops/events/end", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var body struct {
+			ID string `json:"id"`
+		}
+		if !decodePost(w, r, &body) {
+			return
+		}
+		event, err := s.EndEvent(body.ID, operator)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, event)
+	}))
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return false
+	}
+	return true
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidEvent), errors.Is(err, ErrInvalidText):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownEvent), errors.Is(err, features.ErrUnknownFlag):
+		return http.StatusNotFound
+	case errors.Is(err, features.ErrRestartRequired):
+		return http.StatusConflict
+	case errors.Is(err, ErrNoFlags), errors.Is(err, ErrBroadcastDisabled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,390 @@
+// Package liveops backs the live-ops dashboard. One admin request shows what
+// the server is doing right now: players online by world and zone, the
+// recent error rate, the live events running, today's marketplace sales and
+// the backlog of on-chain side effects. The quick actions operators take from
+// the dashboard are here too: toggling a feature flag, broadcasting an
+// announcement to every online player and starting or ending a live event.
+package liveops
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+	"github.com/phuhao00/suigserver/server/internal/worlds"
+)
+
+// errorWindow is how many minutes of error counts are kept.
+const errorWindow = 60
+
+// MaxAnnouncement is the longest announcement, in characters.
+const MaxAnnouncement = 500
+
+// MaxEventDuration is the longest a live event can run.
+const MaxEventDuration = 14 * 24 * time.Hour
+
+// DefaultTickInterval is how often ended events are closed when
+// Options.TickInterval is zero.
+const DefaultTickInterval = 10 * time.Second
+
+var (
+	ErrInvalidEvent      = errors.New("invalid live event")
+	ErrUnknownEvent      = errors.New("unknown live event")
+	ErrInvalidText       = errors.New("announcement must be 1 to 500 characters")
+	ErrNoFlags           = errors.New("feature flags are not available")
+	ErrBroadcastDisabled = errors.New("broadcasts are not available")
+)
+
+// Event is a live event: a named period, such as a holiday weekend, with an
+// optional feature flag that is on while it runs.
+type Event struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Feature   string    `json:"feature,omitempty"` // Flag turned on at the start and returned to its configured value at the end
+	StartedAt time.Time `json:"startedAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	StartedBy string    `json:"startedBy"`
+}
+
+// StartRequest describes a live event to start.
+type StartRequest struct {
+	Name         string `json:"name"`
+	Minutes      int    `json:"minutes"`
+	Feature      string `json:"feature,omitempty"`
+	Announcement string `json:"announcement,omitempty"` // Broadcast when the event starts
+}
+
+// MarketDay is the marketplace's sales on one UTC day.
+type MarketDay struct {
+	Date   string `json:"date"` // YYYY-MM-DD
+	Sales  int    `json:"sales"`
+	Volume uint64 `json:"volume"` // MIST paid by buyers
+}
+
+// ErrorRate counts the errors the server logged recently.
+type ErrorRate struct {
+	LastMinute   int       `json:"lastMinute"`
+	Last5Minutes int       `json:"last5Minutes"`
+	LastHour     int       `json:"lastHour"`
+	PerMinute    []int     `json:"perMinute"` // The last hour, oldest first
+	LastMessage  string    `json:"lastMessage,omitempty"`
+	LastAt       time.Time `json:"lastAt,omitempty"`
+}
+
+// Dashboard is the live-ops dashboard.
+type Dashboard struct {
+	Time        time.Time      `json:"time"`
+	Online      int            `json:"online"` // Sessions across every world
+	Worlds      []worlds.Stats `json:"worlds"`
+	Errors      ErrorRate      `json:"errors"`
+	Events      []Event        `json:"events"`
+	Marketplace MarketDay      `json:"marketplace"`
+	Outbox      *outbox.Stats  `json:"outbox,omitempty"`
+}
+
+// Options are where the dashboard's figures come from and how its actions
+// reach the game.
+type Options struct {
+	Worlds       func() []worlds.Stats // Per-world counters; nil shows none
+	Outbox       func() outbox.Stats   // Side-effect backlog; nil leaves it out
+	Flags        *features.Registry    // Flags toggled by the dashboard and by events
+	Announce     func(text string) int // Sends text to every online player and returns how many there were
+	TickInterval time.Duration         // How often ended events are closed
+}
+
+// Service keeps the dashboard's counters and runs live events. It is safe
+// for concurrent use.
+type Service struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	mu     sync.Mutex
+	state  State
+	errors errorCounts
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates a Service with the state in store.
+func New(store Store, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading live-ops state: %w", err)
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	return &Service{store: store, opts: opts, now: time.Now, state: state}, nil
+}
+
+// Subscribe counts the errors the server logs and the marketplace's sales.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicServerError, "liveops", func(e events.ServerError) {
+		s.recordError(e.Message, 1+e.Suppressed)
+	})
+	events.On(bus, events.TopicMarketSold, "liveops", func(e events.MarketSold) {
+		s.recordSale(e.Price)
+	})
+}
+
+// Start closes events as they end, until Stop.
+func (s *Service) Start() {
+	s.Tick(s.now())
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Tick(s.now())
+			}
+		}
+	}()
+}
+
+// Stop stops closing events.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Dashboard gathers the dashboard. Asking the worlds for their counters may
+// take a moment.
+func (s *Service) Dashboard() Dashboard {
+	now := s.now().UTC()
+	dashboard := Dashboard{Time: now, Worlds: []worlds.Stats{}}
+	if s.opts.Worlds != nil {
+		dashboard.Worlds = s.opts.Worlds()
+	}
+	for _, world := range dashboard.Worlds {
+		dashboard.Online += world.Sessions
+	}
+	if s.opts.Outbox != nil {
+		stats := s.opts.Outbox()
+		dashboard.Outbox = &stats
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dashboard.Errors = s.errors.rate(now)
+	dashboard.Events = append([]Event{}, s.state.Events...)
+	dashboard.Marketplace = s.state.Market
+	if dashboard.Marketplace.Date != day(now) {
+		dashboard.Marketplace = MarketDay{Date: day(now)}
+	}
+	return dashboard
+}
+
+// SetFlag turns a runtime feature flag on or off.
+func (s *Service) SetFlag(name string, enabled bool, operator string) (features.State, error) {
+	if s.opts.Flags == nil {
+		return features.State{}, ErrNoFlags
+	}
+	state, err := s.opts.Flags.Set(name, enabled, operator)
+	if err == nil {
+		utils.LogInfof("Live ops: %s set feature %s to %v.", operator, name, enabled)
+	}
+	return state, err
+}
+
+// Broadcast sends text to every online player and returns how many there
+// were.
+func (s *Service) Broadcast(text, operator string) (int, error) {
+	if s.opts.Announce == nil {
+		return 0, ErrBroadcastDisabled
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > MaxAnnouncement {
+		return 0, ErrInvalidText
+	}
+	recipients := s.opts.Announce(text)
+	utils.LogInfof("Live ops: %s broadcast to %d players: %q", operator, recipients, text)
+	return recipients, nil
+}
+
+// StartEvent starts a live event now. Its feature flag, if it names one, is
+// turned on, and its announcement, if any, is broadcast.
+func (s *Service) StartEvent(req StartRequest, operator string) (Event, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	duration := time.Duration(req.Minutes) * time.Minute
+	switch {
+	case req.Name == "":
+		return Event{}, fmt.Errorf("%w: name is required", ErrInvalidEvent)
+	case req.Minutes <= 0 || duration > MaxEventDuration:
+		return Event{}, fmt.Errorf("%w: minutes must be between 1 and %d", ErrInvalidEvent, int(MaxEventDuration/time.Minute))
+	case req.Announcement != "" && s.opts.Announce == nil:
+		return Event{}, ErrBroadcastDisabled
+	case utf8.RuneCountInString(strings.TrimSpace(req.Announcement)) > MaxAnnouncement:
+		return Event{}, ErrInvalidText
+	}
+	if req.Feature != "" {
+		if s.opts.Flags == nil {
+			return Event{}, ErrNoFlags
+		}
+		if _, err := s.opts.Flags.Set(req.Feature, true, operator); err != nil {
+			return Event{}, err
+		}
+	}
+
+	s.mu.Lock()
+	now := s.now().UTC()
+	s.state.NextID++
+	event := Event{
+		ID:        fmt.Sprintf("event-%d", s.state.NextID),
+		Name:      req.Name,
+		Feature:   req.Feature,
+		StartedAt: now,
+		EndsAt:    now.Add(duration),
+		StartedBy: operator,
+	}
+	s.state.Events = append(s.state.Events, event)
+	s.saveLocked()
+	s.mu.Unlock()
+
+	utils.LogInfof("Live ops: %s started event %s (%s) until %s.", operator, event.ID, event.Name, event.EndsAt.Format(time.RFC3339))
+	if strings.TrimSpace(req.Announcement) != "" {
+		s.Broadcast(req.Announcement, operator)
+	}
+	return event, nil
+}
+
+// EndEvent ends a running event early.
+func (s *Service) EndEvent(id, operator string) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, event := range s.state.Events {
+		if event.ID == id {
+			s.endLocked(i, operator)
+			s.saveLocked()
+			return event, nil
+		}
+	}
+	return Event{}, fmt.Errorf("%w %q", ErrUnknownEvent, id)
+}
+
+// Tick ends the events that are over at now.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ended := false
+	for i := len(s.state.Events) - 1; i >= 0; i-- {
+		if !now.Before(s.state.Events[i].EndsAt) {
+			s.endLocked(i, "liveops")
+			ended = true
+		}
+	}
+	if ended {
+		s.saveLocked()
+	}
+}
+
+// endLocked removes the event at i and returns its flag to the configured
+// value, unless another running event still needs it on.
+func (s *Service) endLocked(i int, operator string) {
+	event := s.state.Events[i]
+	s.state.Events = append(s.state.Events[:i:i], s.state.Events[i+1:]...)
+	utils.LogInfof("Live ops: Event %s (%s) ended by %s.", event.ID, event.Name, operator)
+	if event.Feature == "" || s.opts.Flags == nil {
+		return
+	}
+	for _, other := range s.state.Events {
+		if other.Feature == event.Feature {
+			return
+		}
+	}
+	if _, err := s.opts.Flags.Clear(event.Feature, operator); err != nil {
+		utils.LogErrorf("Live ops: Could not clear feature %s after event %s: %v", event.Feature, event.ID, err)
+	}
+}
+
+func (s *Service) recordError(message string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors.add(s.now(), message, count)
+}
+
+func (s *Service) recordSale(price uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	today := day(s.now())
+	if s.state.Market.Date != today {
+		s.state.Market = MarketDay{Date: today}
+	}
+	s.state.Market.Sales++
+	s.state.Market.Volume += price
+	s.saveLocked()
+}
+
+func (s *Service) saveLocked() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Live ops: Could not save the state: %v", err)
+	}
+}
+
+// day is t's UTC date.
+func day(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// errorCounts is errors per minute over the last errorWindow minutes.
+type errorCounts struct {
+	minute      int64            // Unix minute of counts' last bucket
+	counts      [errorWindow]int // Oldest first
+	lastMessage string
+	lastAt      time.Time
+}
+
+func (c *errorCounts) add(now time.Time, message string, count int) {
+	c.advance(now)
+	c.counts[errorWindow-1] += count
+	c.lastMessage, c.lastAt = message, now.UTC()
+}
+
+// advance moves the window so its last bucket is now's minute.
+func (c *errorCounts) advance(now time.Time) {
+	minute := now.Unix() / 60
+	shift := minute - c.minute
+	if shift <= 0 {
+		return
+	}
+	if shift >= errorWindow {
+		c.counts = [errorWindow]int{}
+	} else {
+		copy(c.counts[:], c.counts[shift:])
+		for i := errorWindow - int(shift); i < errorWindow; i++ {
+			c.counts[i] = 0
+		}
+	}
+	c.minute = minute
+}
+
+func (c *errorCounts) rate(now time.Time) ErrorRate {
+	c.advance(now)
+	rate := ErrorRate{PerMinute: append([]int(nil), c.counts[:]...), LastMessage: c.lastMessage, LastAt: c.lastAt}
+	for i, n := range c.counts {
+		rate.LastHour += n
+		if i >= errorWindow-5 {
+			rate.Last5Minutes += n
+		}
+	}
+	rate.LastMinute = c.counts[errorWindow-1]
+	return rate
+}
@@ -0,0 +1,90 @@
+package liveops
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/worlds"
+)
+
+func TestEventsTurnTheirFlagOnUntilTheyEnd(t *testing.T) {
+	flags, err := features.New(nil, &features.MemoryStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var announced []string
+	store := &MemoryStore{}
+	s, _ := New(store, Options{Flags: flags, Announce: func(text string) int {
+		announced = append(announced, text)
+		return 3
+	}})
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.StartEvent(StartRequest{Name: "Harvest", Minutes: 0}, "ops"); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("event without a duration: %v", err)
+	}
+	long, err := s.StartEvent(StartRequest{Name: "Harvest", Minutes: 60, Feature: features.Marketplace, Announcement: "Harvest has begun!"}, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, _ := s.StartEvent(StartRequest{Name: "Flash sale", Minutes: 10, Feature: features.Marketplace}, "ops")
+	if !flags.Enabled(features.Marketplace) || len(announced) != 1 {
+		t.Fatalf("marketplace on = %v, announced = %v", flags.Enabled(features.Marketplace), announced)
+	}
+
+	// The flash sale ends first; the harvest still needs the marketplace.
+	now = now.Add(10 * time.Minute)
+	s.Tick(now)
+	if events := s.Dashboard().Events; len(events) != 1 || events[0].ID != long.ID || !flags.Enabled(features.Marketplace) {
+		t.Fatalf("after %s ended: events = %+v, marketplace on = %v", short.ID, events, flags.Enabled(features.Marketplace))
+	}
+	if _, err := s.EndEvent(long.ID, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if flags.Enabled(features.Marketplace) {
+		t.Error("marketplace stayed on after the last event ended")
+	}
+	if state, _ := store.LoadState(); len(state.Events) != 0 || state.NextID != 2 {
+		t.Errorf("stored state = %+v", state)
+	}
+	if _, err := s.EndEvent(long.ID, "ops"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("ending twice: %v", err)
+	}
+}
+
+func TestDashboardCounts(t *testing.T) {
+	s, _ := New(&MemoryStore{}, Options{Worlds: func() []worlds.Stats {
+		return []worlds.Stats{{ID: "eu", Sessions: 4, PlayersByZone: map[string]int{"plaza": 3}}, {ID: "us", Sessions: 2}}
+	}})
+	now := time.Date(2026, 10, 16, 23, 58, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.recordError("db down", 3)
+	now = now.Add(time.Minute)
+	s.recordError("db down again", 1)
+	s.recordSale(100)
+	s.recordSale(250)
+	dashboard := s.Dashboard()
+	if dashboard.Online != 6 || len(dashboard.Worlds) != 2 {
+		t.Errorf("online = %d in %d worlds", dashboard.Online, len(dashboard.Worlds))
+	}
+	if e := dashboard.Errors; e.LastMinute != 1 || e.Last5Minutes != 4 || e.LastHour != 4 || e.LastMessage != "db down again" {
+		t.Errorf("errors = %+v", e)
+	}
+	if m := dashboard.Marketplace; m.Date != "2026-10-16" || m.Sales != 2 || m.Volume != 350 {
+		t.Errorf("marketplace = %+v", m)
+	}
+
+	// A new day starts the sales over; errors age out of the window.
+	now = now.Add(time.Hour)
+	dashboard = s.Dashboard()
+	if m := dashboard.Marketplace; m.Date != "2026-10-17" || m.Sales != 0 {
+		t.Errorf("marketplace the next day = %+v", m)
+	}
+	if e := dashboard.Errors; e.LastHour != 0 || len(e.PerMinute) != errorWindow {
+		t.Errorf("errors an hour later = %+v", e)
+	}
+}
@@ -0,0 +1,19 @@
+package liveops
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is what is persisted: the running events and today's sales.
+type State struct {
+	Events []Event   `json:"events"`
+	NextID int       `json:"nextId"`
+	Market MarketDay `json:"market"`
+}
+
+// Store persists the state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the state in a JSON file.
+type FileStore = jsonstore.File[State]
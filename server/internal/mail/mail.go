@@ -0,0 +1,204 @@
+// Package mail keeps players' mailboxes: messages the server sends them, such
+// as a notice that their marketplace listing expired, which wait until read
+// whether or not the player was online. Sessions that connect are told about
+// new mail as it arrives.
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultMaxPerPlayer is the mailbox size when Options leaves it at zero.
+const DefaultMaxPerPlayer = 100
+
+// ErrNoRecipient is returned by Send for mail without a To.
+var ErrNoRecipient = errors.New("mail has no recipient")
+
+// SystemSender is the From of mail sent by the server itself.
+const SystemSender = "system"
+
+// Message is one piece of mail.
+type Message struct {
+	ID      string    `json:"id"`
+	To      string    `json:"to"`
+	From    string    `json:"from"`          // SystemSender or a player ID
+	Kind    string    `json:"kind"`          // What the mail is about, e.g. "market.listing_expired"
+	Subject string    `json:"subject"`       // One line
+	Body    string    `json:"body"`          // Free text; may span lines
+	Ref     string    `json:"ref,omitempty"` // ID of the object the mail is about, e.g. a listing
+	SentAt  time.Time `json:"sentAt"`
+	Read    bool      `json:"read"`
+}
+
+// Notice tells a connected session that new mail arrived.
+type Notice struct {
+	Message Message
+}
+
+// Notifier delivers a *Notice to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	MaxPerPlayer int // Messages kept per mailbox; the oldest are dropped beyond it
+}
+
+// Service keeps the mailboxes. It is safe for concurrent use. A nil Service
+// drops what is sent to it, so senders need not check whether mail is enabled.
+type Service struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	notifiers map[string]Notifier // Connected sessions by player ID
+}
+
+// NewService creates a Service and loads its state.
+func NewService(store Store, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load mail state: %w", err)
+	}
+	if state.Mailboxes == nil {
+		state.Mailboxes = make(map[string][]Message)
+	}
+	if opts.MaxPerPlayer <= 0 {
+		opts.MaxPerPlayer = DefaultMaxPerPlayer
+	}
+	return &Service{
+		store:     store,
+		opts:      opts,
+		now:       time.Now,
+		state:     state,
+		notifiers: make(map[string]Notifier),
+	}, nil
+}
+
+// Connect registers a session to be told about playerID's new mail.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect unregisters playerID's session.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Send delivers msg to msg.To's mailbox, filling in its ID, sender and time,
+// and returns it as delivered. A full mailbox drops its oldest message.
+func (s *Service) Send(msg Message) (Message, error) {
+	if s == nil {
+		return Message{}, nil
+	}
+	if msg.To == "" {
+		return Message{}, ErrNoRecipient
+	}
+	s.mu.Lock()
+	s.state.NextID++
+	msg.ID = strconv.FormatUint(s.state.NextID, 10)
+	if msg.From == "" {
+		msg.From = SystemSender
+	}
+	msg.SentAt = s.now().UTC()
+	msg.Read = false
+	box := append(s.state.Mailboxes[msg.To], msg)
+	if excess := len(box) - s.opts.MaxPerPlayer; excess > 0 {
+		box = box[excess:]
+	}
+	s.state.Mailboxes[msg.To] = box
+	s.save()
+	notify := s.notifiers[msg.To]
+	s.mu.Unlock()
+
+	utils.LogInfof("Mail: Sent %s mail %s to %s.", msg.Kind, msg.ID, msg.To)
+	if notify != nil {
+		notify(&Notice{Message: msg})
+	}
+	return msg, nil
+}
+
+// Inbox returns playerID's mail, newest first, and how much of it is unread.
+func (s *Service) Inbox(playerID string) ([]Message, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	box := s.state.Mailboxes[playerID]
+	messages := make([]Message, 0, len(box))
+	unread := 0
+	for i := len(box) - 1; i >= 0; i-- {
+		messages = append(messages, box[i])
+		if !box[i].Read {
+			unread++
+		}
+	}
+	return messages, unread
+}
+
+// MarkRead marks the given messages of playerID's as read and returns how many changed.
+func (s *Service) MarkRead(playerID string, ids []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wanted := idSet(ids)
+	box := s.state.Mailboxes[playerID]
+	changed := 0
+	for i := range box {
+		if wanted[box[i].ID] && !box[i].Read {
+			box[i].Read = true
+			changed++
+		}
+	}
+	if changed > 0 {
+		s.save()
+	}
+	return changed
+}
+
+// Delete removes the given messages from playerID's mailbox and returns how many it removed.
+func (s *Service) Delete(playerID string, ids []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wanted := idSet(ids)
+	box := s.state.Mailboxes[playerID]
+	kept := box[:0]
+	for _, msg := range box {
+		if !wanted[msg.ID] {
+			kept = append(kept, msg)
+		}
+	}
+	removed := len(box) - len(kept)
+	if removed == 0 {
+		return 0
+	}
+	if len(kept) == 0 {
+		delete(s.state.Mailboxes, playerID)
+	} else {
+		s.state.Mailboxes[playerID] = kept
+	}
+	s.save()
+	return removed
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Mail: Failed to save mail state: %v", err)
+	}
+}
+
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
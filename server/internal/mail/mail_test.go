@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestMailboxKeepsNewestMessages(t *testing.T) {
+	s, err := NewService(&MemoryStore{}, Options{MaxPerPlayer: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notices []*Notice
+	s.Connect("alice", func(note interface{}) { notices = append(notices, note.(*Notice)) })
+	for _, subject := range []string{"one", "two", "three"} {
+		if _, err := s.Send(Message{To: "alice", Kind: "test", Subject: subject}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notices) != 3 || notices[2].Message.From != SystemSender {
+		t.Fatalf("notices = %+v", notices)
+	}
+
+	messages, unread := s.Inbox("alice")
+	if len(messages) != 2 || unread != 2 || messages[0].Subject != "three" || messages[1].Subject != "two" {
+		t.Fatalf("inbox = %+v, %d unread", messages, unread)
+	}
+	if n := s.MarkRead("alice", []string{messages[1].ID, "missing"}); n != 1 {
+		t.Fatalf("marked %d read", n)
+	}
+	if n := s.Delete("alice", []string{messages[0].ID}); n != 1 {
+		t.Fatalf("deleted %d", n)
+	}
+	messages, unread = s.Inbox("alice")
+	if len(messages) != 1 || unread != 0 || !messages[0].Read {
+		t.Fatalf("inbox after read and delete = %+v, %d unread", messages, unread)
+	}
+
+	s.Disconnect("alice")
+	s.Send(Message{To: "alice", Kind: "test"})
+	if len(notices) != 3 {
+		t.Fatal("a disconnected session was notified")
+	}
+	var none *Service
+	if _, err := none.Send(Message{To: "alice"}); err != nil {
+		t.Fatalf("send to a nil service = %v", err)
+	}
+}
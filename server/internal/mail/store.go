@@ -0,0 +1,18 @@
+package mail
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted mail: every player's mailbox, oldest message first.
+type State struct {
+	Mailboxes map[string][]Message `json:"mailboxes"`
+	NextID    uint64               `json:"nextId"`
+}
+
+// Store persists the mail state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the mail state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the mail state in a JSON file.
+type FileStore = jsonstore.File[State]
@@ -0,0 +1,99 @@
+package metrichistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Query limits of the admin endpoint.
+const (
+	defaultRange = 24 * time.Hour
+	maxBuckets   = 2000
+	queryTimeout = 10 * time.Second
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header.
+//
+//	GET /admin/metrics/history                                          the recorded metrics, interval and retention
+//	GET /admin/metrics/history?metric=NAME&from=&to=&step=&instance=    the metric per instance, summarized per step
+//
+// from and to are RFC 3339 times, the last 24 hours by default; step is a
+// duration such as "15m", by default the one giving about 200 buckets.
+func (r *Recorder) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/metrics/history", adminhttp.Only(adminToken, func(w http.ResponseWriter, req *http.Request, _ string) {
+		if req.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		params := req.URL.Query()
+		if params.Get("metric") == "" {
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"instance":         r.opts.Instance,
+				"intervalSeconds":  r.opts.Interval.Seconds(),
+				"retentionSeconds": r.opts.Retention.Seconds(),
+				"metrics":          r.Metrics(),
+			})
+			return
+		}
+		q, err := r.parseQuery(params.Get("metric"), params.Get("instance"), params.Get("from"), params.Get("to"), params.Get("step"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(req.Context(), queryTimeout)
+		defer cancel()
+		series, err := r.History(ctx, q)
+		switch {
+		case errors.Is(err, ErrUnknownMetric):
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+		case err != nil:
+			utils.LogErrorf("Metric history: %v", err)
+			adminhttp.WriteError(w, http.StatusInternalServerError, errors.New("could not read the metric history"))
+		default:
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"metric":      q.Metric,
+				"from":        q.From,
+				"to":          q.To,
+				"stepSeconds": q.Step.Seconds(),
+				"series":      series,
+			})
+		}
+	}))
+}
+
+// parseQuery reads the query parameters, filling in the defaults.
+func (r *Recorder) parseQuery(metric, instance, from, to, step string) (Query, error) {
+	q := Query{Metric: metric, Instance: instance, To: r.now().UTC()}
+	var err error
+	if to != "" {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	q.From = q.To.Add(-defaultRange)
+	if from != "" {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if !q.From.Before(q.To) {
+		return q, errors.New("from must be before to")
+	}
+	span := q.To.Sub(q.From)
+	if step == "" {
+		q.Step = max(span/200, r.opts.Interval).Truncate(time.Second)
+	} else if q.Step, err = time.ParseDuration(step); err != nil || q.Step < time.Second {
+		return q, errors.New("step must be a duration of at least 1s, such as 15m")
+	}
+	if span/q.Step > maxBuckets {
+		return q, fmt.Errorf("from and to span more than %d steps of %s", maxBuckets, q.Step)
+	}
+	return q, nil
+}
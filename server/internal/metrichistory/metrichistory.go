@@ -0,0 +1,259 @@
+// Package metrichistory keeps a history of the server's key metrics for trend
+// analysis and capacity planning. A Recorder samples the metrics registered
+// with it at a fixed interval, such as the players online, the rate of signed
+// transactions and handler latency, and stores the samples, in Postgres when
+// there is a database. Samples older than the retention are deleted. The
+// admin API returns a metric's samples averaged over steps of any size.
+package metrichistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults used when Options leaves a field at zero.
+const (
+	DefaultInterval  = time.Minute
+	DefaultRetention = 30 * 24 * time.Hour
+)
+
+// pruneEvery is how often samples past the retention are deleted.
+const pruneEvery = time.Hour
+
+// recordTimeout bounds storing one round of samples.
+const recordTimeout = 10 * time.Second
+
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// Sample is one reading of a metric on one server instance.
+type Sample struct {
+	Metric   string
+	Instance string
+	Time     time.Time
+	Value    float64
+}
+
+// Bucket summarizes the samples of one step.
+type Bucket struct {
+	Time    time.Time `json:"time"` // Start of the step
+	Avg     float64   `json:"avg"`
+	Min     float64   `json:"min"`
+	Max     float64   `json:"max"`
+	Samples int       `json:"samples"`
+}
+
+// Series is a metric's buckets on one instance, oldest first.
+type Series struct {
+	Instance string   `json:"instance"`
+	Buckets  []Bucket `json:"buckets"`
+}
+
+// Query selects the samples of Metric taken in [From, To), on Instance or
+// on every instance if it is empty, summarized per Step.
+type Query struct {
+	Metric   string
+	Instance string
+	From     time.Time
+	To       time.Time
+	Step     time.Duration
+}
+
+// Options configures a Recorder.
+type Options struct {
+	Instance  string        // Names this server's samples; defaults to the hostname
+	Interval  time.Duration // Between samples
+	Retention time.Duration // How long samples are kept
+}
+
+// metric is one registered metric. sample returns false to skip a round,
+// such as the first reading of a counter.
+type metric struct {
+	name        string
+	description string
+	sample      func(now time.Time) (float64, bool)
+}
+
+// MetricInfo describes a registered metric.
+type MetricInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Recorder samples the registered metrics and stores them. Metrics are
+// registered before Start.
+type Recorder struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	mu      sync.Mutex // Guards metrics
+	metrics []metric
+
+	recording sync.Mutex // Serializes rounds, so counters see one reading at a time
+	lastPrune time.Time
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRecorder creates a Recorder that stores samples in store.
+func NewRecorder(store Store, opts Options) *Recorder {
+	if opts.Instance == "" {
+		opts.Instance, _ = os.Hostname()
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	return &Recorder{store: store, opts: opts, now: time.Now}
+}
+
+// Gauge registers a metric read as it is, such as the players online.
+func (r *Recorder) Gauge(name, description string, read func() float64) {
+	r.add(name, description, func(time.Time) (float64, bool) { return read(), true })
+}
+
+// Counter registers a metric that only grows, such as transactions signed.
+// Its rate per second over each interval is recorded.
+func (r *Recorder) Counter(name, description string, read func() uint64) {
+	var (
+		last     uint64
+		lastTime time.Time
+	)
+	r.add(name, description, func(now time.Time) (float64, bool) {
+		value := read()
+		previous, previousTime := last, lastTime
+		last, lastTime = value, now
+		if previousTime.IsZero() || value < previous || !now.After(previousTime) {
+			return 0, false // The first reading, or the counter was reset
+		}
+		return float64(value-previous) / now.Sub(previousTime).Seconds(), true
+	})
+}
+
+// Mean registers a metric read as a running count and total, such as handler
+// calls and the time they took. The mean over each interval is recorded;
+// intervals without calls are skipped.
+func (r *Recorder) Mean(name, description string, read func() (count uint64, total float64)) {
+	var (
+		lastCount uint64
+		lastTotal float64
+		started   bool
+	)
+	r.add(name, description, func(time.Time) (float64, bool) {
+		count, total := read()
+		previousCount, previousTotal, seen := lastCount, lastTotal, started
+		lastCount, lastTotal, started = count, total, true
+		if !seen || count <= previousCount {
+			return 0, false
+		}
+		return (total - previousTotal) / float64(count-previousCount), true
+	})
+}
+
+func (r *Recorder) add(name, description string, sample func(time.Time) (float64, bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, metric{name: name, description: description, sample: sample})
+}
+
+// Metrics lists the registered metrics by name.
+func (r *Recorder) Metrics() []MetricInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := make([]MetricInfo, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		infos = append(infos, MetricInfo{Name: m.name, Description: m.description})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Start samples the metrics every interval until Stop.
+func (r *Recorder) Start() {
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.opts.Interval)
+		defer ticker.Stop()
+		r.Record(r.now())
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.Record(r.now())
+			}
+		}
+	}()
+}
+
+// Stop stops sampling.
+func (r *Recorder) Stop() {
+	r.stopOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+			<-r.done
+		}
+	})
+}
+
+// Record samples every metric at now and stores the samples, deleting those
+// past the retention once an hour.
+func (r *Recorder) Record(now time.Time) {
+	r.recording.Lock()
+	defer r.recording.Unlock()
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	now = now.UTC().Truncate(time.Second)
+	samples := make([]Sample, 0, len(metrics))
+	for _, m := range metrics {
+		if value, ok := m.sample(now); ok {
+			samples = append(samples, Sample{Metric: m.name, Instance: r.opts.Instance, Time: now, Value: value})
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err := r.store.Append(ctx, samples); err != nil {
+		utils.LogWarnf("Metric history: Could not store %d samples: %v", len(samples), err)
+	}
+	if now.Sub(r.lastPrune) < pruneEvery {
+		return
+	}
+	r.lastPrune = now
+	deleted, err := r.store.Prune(ctx, now.Add(-r.opts.Retention))
+	if err != nil {
+		utils.LogWarnf("Metric history: Could not delete old samples: %v", err)
+	} else if deleted > 0 {
+		utils.LogInfof("Metric history: Deleted %d samples older than %s.", deleted, r.opts.Retention)
+	}
+}
+
+// History returns the series q selects. The metric must be registered.
+func (r *Recorder) History(ctx context.Context, q Query) ([]Series, error) {
+	known := false
+	for _, m := range r.Metrics() {
+		known = known || m.Name == q.Metric
+	}
+	if !known {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMetric, q.Metric)
+	}
+	return r.store.Query(ctx, q)
+}
+
+// Options returns the recorder's options, with the defaults filled in.
+func (r *Recorder) Options() Options {
+	return r.opts
+}
@@ -0,0 +1,79 @@
+package metrichistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecorderSamplesGaugesRatesAndMeans(t *testing.T) {
+	store := &MemoryStore{}
+	r := NewRecorder(store, Options{Instance: "eu-1", Interval: time.Minute, Retention: 2 * time.Hour})
+	var (
+		online        float64
+		signed        uint64
+		calls         uint64
+		handlerTimeMs float64
+		start         = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		ctx           = context.Background()
+	)
+	r.Gauge("players.online", "", func() float64 { return online })
+	r.Counter("sui.signed_per_second", "", func() uint64 { return signed })
+	r.Mean("handler.mean_ms", "", func() (uint64, float64) { return calls, handlerTimeMs })
+
+	online = 10
+	r.Record(start)
+	online, signed, calls, handlerTimeMs = 20, 120, 4, 8
+	r.Record(start.Add(time.Minute))
+	online, signed = 30, 240 // No handler calls this minute
+	r.Record(start.Add(2 * time.Minute))
+
+	q := Query{From: start, To: start.Add(time.Hour), Step: time.Hour}
+	for metric, want := range map[string]Bucket{
+		"players.online":        {Time: start, Avg: 20, Min: 10, Max: 30, Samples: 3},
+		"sui.signed_per_second": {Time: start, Avg: 2, Min: 2, Max: 2, Samples: 2},
+		"handler.mean_ms":       {Time: start, Avg: 2, Min: 2, Max: 2, Samples: 1},
+	} {
+		q.Metric = metric
+		series, err := r.History(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(series) != 1 || series[0].Instance != "eu-1" || len(series[0].Buckets) != 1 || series[0].Buckets[0] != want {
+			t.Errorf("%s = %+v, want %+v", metric, series, want)
+		}
+	}
+	q.Metric = "nope"
+	if _, err := r.History(ctx, q); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("unknown metric: %v", err)
+	}
+
+	// Two hours on, the first round is past the retention.
+	r.lastPrune = start
+	r.Record(start.Add(2*time.Hour + time.Minute))
+	q.Metric, q.To = "players.online", start.Add(3*time.Hour)
+	series, _ := r.History(ctx, q)
+	if total := series[0].Buckets[0].Samples + series[0].Buckets[1].Samples; total != 3 {
+		t.Errorf("samples after pruning = %d, want 3", total)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	r := NewRecorder(&MemoryStore{}, Options{Interval: time.Minute})
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	q, err := r.parseQuery("players.online", "", "", "", "")
+	if err != nil || !q.To.Equal(now) || !q.From.Equal(now.Add(-24*time.Hour)) || q.Step != 432*time.Second {
+		t.Errorf("defaults = %+v, %v", q, err)
+	}
+	if q, err := r.parseQuery("players.online", "", "2026-10-16T11:00:00Z", "", ""); err != nil || q.Step != time.Minute {
+		t.Errorf("an hour at the default step = %+v, %v", q, err)
+	}
+	for _, bad := range [][2]string{{"2026-10-16T13:00:00Z", ""}, {"", "1ms"}, {"2026-01-01T00:00:00Z", "1s"}} {
+		if _, err := r.parseQuery("players.online", "", bad[0], "", bad[1]); err == nil {
+			t.Errorf("from %q step %q was accepted", bad[0], bad[1])
+		}
+	}
+}
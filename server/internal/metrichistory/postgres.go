@@ -0,0 +1,120 @@
+package metrichistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS metric_samples (
+	metric      TEXT NOT NULL,
+	instance    TEXT NOT NULL,
+	recorded_at TIMESTAMPTZ NOT NULL,
+	value       DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (metric, instance, recorded_at)
+);
+CREATE INDEX IF NOT EXISTS metric_samples_recorded_at ON metric_samples (recorded_at);
+`
+
+// PostgresStore keeps samples in the metric_samples table, one row per
+// metric, instance and time.
+type PostgresStore struct {
+	DB      *sql.DB
+	Timeout time.Duration // Bound on each query; 0 leaves it to the caller's context
+}
+
+// EnsureSchema creates the metric_samples table if it is missing.
+func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create metric_samples: %w", err)
+	}
+	return nil
+}
+
+// Append implements Store. The samples are inserted in one statement.
+func (s *PostgresStore) Append(ctx context.Context, samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var query strings.Builder
+	query.WriteString(`INSERT INTO metric_samples (metric, instance, recorded_at, value) VALUES `)
+	args := make([]interface{}, 0, 4*len(samples))
+	for i, sample := range samples {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d)", 4*i+1, 4*i+2, 4*i+3, 4*i+4)
+		args = append(args, sample.Metric, sample.Instance, sample.Time, sample.Value)
+	}
+	query.WriteString(` ON CONFLICT DO NOTHING`)
+	if _, err := s.DB.ExecContext(ctx, query.String(), args...); err != nil {
+		return fmt.Errorf("append metric samples: %w", err)
+	}
+	return nil
+}
+
+// Query implements Store. Samples are grouped in steps counted from the Unix
+// epoch, as MemoryStore does.
+func (s *PostgresStore) Query(ctx context.Context, q Query) ([]Series, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	step := int64(q.Step / time.Second)
+	if step <= 0 {
+		step = 1
+	}
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT instance, floor(extract(epoch FROM recorded_at) / $4::bigint)::bigint * $4::bigint AS bucket,
+		       avg(value), min(value), max(value), count(*)
+		FROM metric_samples
+		WHERE metric = $1 AND recorded_at >= $2 AND recorded_at < $3 AND ($5::text = '' OR instance = $5::text)
+		GROUP BY instance, bucket
+		ORDER BY instance, bucket`, q.Metric, q.From, q.To, step, q.Instance)
+	if err != nil {
+		return nil, fmt.Errorf("query %s history: %w", q.Metric, err)
+	}
+	defer rows.Close()
+	series := []Series{}
+	for rows.Next() {
+		var (
+			instance string
+			start    int64
+			b        Bucket
+		)
+		if err := rows.Scan(&instance, &start, &b.Avg, &b.Min, &b.Max, &b.Samples); err != nil {
+			return nil, fmt.Errorf("query %s history: %w", q.Metric, err)
+		}
+		b.Time = time.Unix(start, 0).UTC()
+		if len(series) == 0 || series[len(series)-1].Instance != instance {
+			series = append(series, Series{Instance: instance})
+		}
+		last := &series[len(series)-1]
+		last.Buckets = append(last.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query %s history: %w", q.Metric, err)
+	}
+	return series, nil
+}
+
+// Prune implements Store.
+func (s *PostgresStore) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM metric_samples WHERE recorded_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("prune metric samples: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
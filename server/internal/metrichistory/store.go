@@ -0,0 +1,96 @@
+package metrichistory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store keeps samples.
+type Store interface {
+	Append(ctx context.Context, samples []Sample) error
+	// Query summarizes the samples q selects per instance and step.
+	Query(ctx context.Context, q Query) ([]Series, error)
+	// Prune deletes the samples taken before cutoff and returns how many.
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// MemoryStore keeps samples in memory. It is used when there is no database.
+type MemoryStore struct {
+	mu      sync.Mutex
+	samples []Sample
+}
+
+// Append implements Store.
+func (m *MemoryStore) Append(ctx context.Context, samples []Sample) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, samples...)
+	return nil
+}
+
+// Query implements Store.
+func (m *MemoryStore) Query(ctx context.Context, q Query) ([]Series, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byInstance := make(map[string]map[int64]*Bucket)
+	for _, sample := range m.samples {
+		if sample.Metric != q.Metric || q.Instance != "" && sample.Instance != q.Instance ||
+			sample.Time.Before(q.From) || !sample.Time.Before(q.To) {
+			continue
+		}
+		buckets := byInstance[sample.Instance]
+		if buckets == nil {
+			buckets = make(map[int64]*Bucket)
+			byInstance[sample.Instance] = buckets
+		}
+		start := bucketStart(sample.Time, q.Step)
+		b := buckets[start.Unix()]
+		if b == nil {
+			b = &Bucket{Time: start, Min: sample.Value, Max: sample.Value}
+			buckets[start.Unix()] = b
+		}
+		b.Avg += sample.Value // The sum until every sample is in
+		b.Min = min(b.Min, sample.Value)
+		b.Max = max(b.Max, sample.Value)
+		b.Samples++
+	}
+	series := make([]Series, 0, len(byInstance))
+	for instance, buckets := range byInstance {
+		s := Series{Instance: instance, Buckets: make([]Bucket, 0, len(buckets))}
+		for _, b := range buckets {
+			b.Avg /= float64(b.Samples)
+			s.Buckets = append(s.Buckets, *b)
+		}
+		sort.Slice(s.Buckets, func(i, j int) bool { return s.Buckets[i].Time.Before(s.Buckets[j].Time) })
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Instance < series[j].Instance })
+	return series, nil
+}
+
+// Prune implements Store.
+func (m *MemoryStore) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.samples[:0]
+	for _, sample := range m.samples {
+		if !sample.Time.Before(cutoff) {
+			kept = append(kept, sample)
+		}
+	}
+	deleted := int64(len(m.samples) - len(kept))
+	m.samples = kept
+	return deleted, nil
+}
+
+// bucketStart is the start of the step t falls in, steps being counted from
+// the Unix epoch.
+func bucketStart(t time.Time, step time.Duration) time.Time {
+	seconds := int64(step / time.Second)
+	if seconds <= 0 {
+		seconds = 1
+	}
+	return time.Unix(t.Unix()/seconds*seconds, 0).UTC()
+}
@@ -63,6 +63,7 @@ type Guild struct {
 	Name        string    `json:"name"`
 	LeaderID    string    `json:"leaderId"`    // Player ID of the guild leader
 	MemberIDs   []string  `json:"memberIds"`   // List of Player IDs
+	OfficerIDs  []string  `json:"officerIds,omitempty"` // Members who may schedule guild events, besides the leader
 	CreatedAt   time.Time `json:"createdAt"`
 	MOTD        string    `json:"motd"`        // Message of the day
 }
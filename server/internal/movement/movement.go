@@ -0,0 +1,258 @@
+// Package movement validates where players go. Plain moves must follow a clear
+// path over walkable ground. Movement abilities, defined in skill data, move a
+// player further in one go: a dash stops at the first obstacle, a jump clears
+// low obstacles, and a teleport only needs a walkable destination. Abilities
+// have a range and a per-player cooldown. The collision data is the geometry
+// map each room is played on; rooms without one are open ground.
+package movement
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// Kinds of movement ability.
+const (
+	KindDash     = "dash"     // Straight line; stops short at the first obstacle
+	KindJump     = "jump"     // Straight line over low obstacles; must land on walkable ground
+	KindTeleport = "teleport" // Ignores the path; must land on walkable ground
+)
+
+// sampleStep is the spacing of the points checked along a path. Obstacles
+// thinner than this can be slipped through.
+const sampleStep = 0.25
+
+// rangeTolerance absorbs float rounding in client-computed targets.
+const rangeTolerance = 0.01
+
+// sweepThreshold is the number of cooldown entries above which expired ones
+// are swept.
+const sweepThreshold = 4096
+
+var (
+	ErrUnknownAbility = errors.New("unknown movement ability")
+	ErrOutOfRange     = errors.New("target is out of range")
+	ErrBlocked        = errors.New("the way is blocked")
+)
+
+// CooldownError is returned for an ability used before its cooldown is over.
+type CooldownError struct {
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("ability is on cooldown for another %s", e.Remaining.Round(100*time.Millisecond))
+}
+
+// Ability is a movement ability from skill data.
+type Ability struct {
+	ID         string  `json:"id"`
+	Kind       string  `json:"kind"`       // KindDash, KindJump or KindTeleport
+	Range      float64 `json:"range"`      // Furthest target from the player's position
+	CooldownMs int     `json:"cooldownMs"` // Time before the player may use it again
+}
+
+// Cooldown returns the ability's cooldown.
+func (a Ability) Cooldown() time.Duration {
+	return time.Duration(a.CooldownMs) * time.Millisecond
+}
+
+// SkillData is the skills file. Only movement abilities are read for now.
+type SkillData struct {
+	MovementAbilities []Ability `json:"movementAbilities"`
+}
+
+// LoadAbilities reads the movement abilities of a skills file.
+func LoadAbilities(path string) ([]Ability, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var skills SkillData
+	if err := json.Unmarshal(data, &skills); err != nil {
+		return nil, fmt.Errorf("invalid skill data %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(skills.MovementAbilities))
+	for i, a := range skills.MovementAbilities {
+		switch {
+		case a.ID == "":
+			return nil, fmt.Errorf("invalid skill data %s: movement ability %d needs an id", path, i+1)
+		case seen[a.ID]:
+			return nil, fmt.Errorf("invalid skill data %s: duplicate movement ability %q", path, a.ID)
+		case a.Kind != KindDash && a.Kind != KindJump && a.Kind != KindTeleport:
+			return nil, fmt.Errorf("invalid skill data %s: movement ability %q has unknown kind %q", path, a.ID, a.Kind)
+		case a.Range <= 0 || a.CooldownMs < 0:
+			return nil, fmt.Errorf("invalid skill data %s: movement ability %q needs a positive range and a cooldown of 0 or more", path, a.ID)
+		}
+		seen[a.ID] = true
+	}
+	return skills.MovementAbilities, nil
+}
+
+// Rules holds the movement abilities, the maps rooms are played on and every
+// player's cooldowns, which outlast rooms. It is safe for concurrent use. A
+// nil *Rules has no abilities and no maps.
+type Rules struct {
+	abilities map[string]Ability
+	maps      map[string]*geometry.Map
+	now       func() time.Time
+
+	mu      *sync.Mutex // Shared with the Rules reloaded from these
+	readyAt map[cooldownKey]time.Time
+}
+
+type cooldownKey struct {
+	playerID  string
+	abilityID string
+}
+
+// NewRules creates Rules for abilities and maps.
+func NewRules(abilities []Ability, maps map[string]*geometry.Map) *Rules {
+	byID := make(map[string]Ability, len(abilities))
+	for _, a := range abilities {
+		byID[a.ID] = a
+	}
+	if maps == nil {
+		maps = make(map[string]*geometry.Map)
+	}
+	return &Rules{abilities: byID, maps: maps, now: time.Now, mu: new(sync.Mutex), readyAt: make(map[cooldownKey]time.Time)}
+}
+
+// Reloaded returns Rules for new abilities and maps that share r's
+// cooldowns, so reloading game data does not reset them.
+func (r *Rules) Reloaded(abilities []Ability, maps map[string]*geometry.Map) *Rules {
+	next := NewRules(abilities, maps)
+	if r != nil {
+		next.now, next.mu, next.readyAt = r.now, r.mu, r.readyAt
+	}
+	return next
+}
+
+// Map returns the map with id, if there is one.
+func (r *Rules) Map(id string) (*geometry.Map, bool) {
+	if r == nil {
+		return nil, false
+	}
+	m, ok := r.maps[id]
+	return m, ok
+}
+
+// Ability returns the ability with id, if there is one.
+func (r *Rules) Ability(id string) (Ability, bool) {
+	if r == nil {
+		return Ability{}, false
+	}
+	a, ok := r.abilities[id]
+	return a, ok
+}
+
+// Move checks a plain move from from to to on m: the path must be clear
+// walkable ground. A nil m is open ground.
+func Move(m *geometry.Map, from, to geometry.Vec) error {
+	if !to.Finite() || !pathClear(m, from, to, geometry.Open) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// UseAbility moves playerID from from towards target with the ability
+// abilityID on m, and returns where the player lands. A failed attempt does
+// not start the cooldown.
+func (r *Rules) UseAbility(playerID, abilityID string, m *geometry.Map, from, target geometry.Vec) (geometry.Vec, error) {
+	ability, ok := r.Ability(abilityID)
+	if !ok {
+		return from, ErrUnknownAbility
+	}
+	if !target.Finite() || from.Dist(target) > ability.Range+rangeTolerance {
+		return from, ErrOutOfRange
+	}
+	key := cooldownKey{playerID, abilityID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if ready := r.readyAt[key]; now.Before(ready) {
+		return from, &CooldownError{Remaining: ready.Sub(now)}
+	}
+
+	landing := target
+	switch ability.Kind {
+	case KindDash:
+		landing = furthestClear(m, from, target)
+		if landing == from {
+			return from, ErrBlocked
+		}
+	case KindJump:
+		if !pathClear(m, from, target, geometry.Low) || !m.Walkable(target) {
+			return from, ErrBlocked
+		}
+	case KindTeleport:
+		if !m.Walkable(target) {
+			return from, ErrBlocked
+		}
+	}
+	if len(r.readyAt) >= sweepThreshold {
+		for k, ready := range r.readyAt {
+			if !now.Before(ready) {
+				delete(r.readyAt, k)
+			}
+		}
+	}
+	r.readyAt[key] = now.Add(ability.Cooldown())
+	return landing, nil
+}
+
+// CooldownRemaining returns how long playerID must wait to use abilityID again.
+func (r *Rules) CooldownRemaining(playerID, abilityID string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if remaining := r.readyAt[cooldownKey{playerID, abilityID}].Sub(r.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// samples returns the number of steps a path from from to to is checked in.
+func samples(from, to geometry.Vec) int {
+	return int(math.Ceil(from.Dist(to) / sampleStep))
+}
+
+// pathClear reports whether every point from from to to is at most worst.
+func pathClear(m *geometry.Map, from, to geometry.Vec, worst geometry.Block) bool {
+	if m == nil {
+		return true // Open ground; nothing to sample
+	}
+	n := samples(from, to)
+	for i := 1; i <= n; i++ {
+		if m.BlockAt(from.Lerp(to, float64(i)/float64(n))) > worst {
+			return false
+		}
+	}
+	return m.BlockAt(to) <= worst
+}
+
+// furthestClear returns the last walkable point on the way from from to to.
+func furthestClear(m *geometry.Map, from, to geometry.Vec) geometry.Vec {
+	if m == nil {
+		return to
+	}
+	n := samples(from, to)
+	last := from
+	for i := 1; i <= n; i++ {
+		p := from.Lerp(to, float64(i)/float64(n))
+		if !m.Walkable(p) {
+			break
+		}
+		last = p
+	}
+	return last
+}
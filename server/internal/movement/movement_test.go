@@ -0,0 +1,142 @@
+package movement
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// testMap is a 20x10 field with a wall at x=10..11 over y=0..6 and a low
+// fence at x=4..4.5 over the full height.
+var testMap = &geometry.Map{
+	ID:     "field",
+	Bounds: geometry.Rect{MaxX: 20, MaxY: 10},
+	Spawn:  geometry.Vec{X: 1, Y: 1},
+	Obstacles: []geometry.Obstacle{
+		{Points: geometry.Rect{MinX: 10, MaxX: 11, MaxY: 6}.Polygon()},
+		{Points: geometry.Rect{MinX: 4, MaxX: 4.5, MaxY: 10}.Polygon(), Low: true},
+	},
+}
+
+func newTestRules(now *time.Time) *Rules {
+	r := NewRules([]Ability{
+		{ID: "dash", Kind: KindDash, Range: 6, CooldownMs: 4000},
+		{ID: "leap", Kind: KindJump, Range: 5, CooldownMs: 1000},
+		{ID: "blink", Kind: KindTeleport, Range: 12},
+	}, map[string]*geometry.Map{testMap.ID: testMap})
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestMoveNeedsAClearPath(t *testing.T) {
+	if err := Move(testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 3, Y: 8}); err != nil {
+		t.Errorf("open move: %v", err)
+	}
+	if err := Move(testMap, geometry.Vec{X: 3, Y: 1}, geometry.Vec{X: 6, Y: 1}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("walking through the fence: %v", err)
+	}
+	if err := Move(testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: -1, Y: 1}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("walking off the map: %v", err)
+	}
+	if err := Move(nil, geometry.Vec{}, geometry.Vec{X: 1e12, Y: 1e12}); err != nil {
+		t.Errorf("a room without a map is open ground: %v", err)
+	}
+}
+
+func TestDashStopsAtTheFirstObstacle(t *testing.T) {
+	now := time.Now()
+	r := newTestRules(&now)
+
+	landing, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 6, Y: 2}, geometry.Vec{X: 12, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if landing.X >= 10 || landing.X < 9.5 || landing.Y != 2 {
+		t.Errorf("dash into the wall landed at %v, want just short of x=10", landing)
+	}
+
+	now = now.Add(4 * time.Second)
+	if _, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 9.9, Y: 2}, geometry.Vec{X: 12, Y: 2}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("dash against the wall: %v", err)
+	}
+	if r.CooldownRemaining("p1", "dash") != 0 {
+		t.Error("a blocked dash started the cooldown")
+	}
+}
+
+func TestJumpClearsLowObstaclesOnly(t *testing.T) {
+	now := time.Now()
+	r := newTestRules(&now)
+
+	landing, err := r.UseAbility("p1", "leap", testMap, geometry.Vec{X: 2, Y: 5}, geometry.Vec{X: 6, Y: 5})
+	if err != nil || landing != (geometry.Vec{X: 6, Y: 5}) {
+		t.Fatalf("jump over the fence: %v, %v", landing, err)
+	}
+	if _, err := r.UseAbility("p2", "leap", testMap, geometry.Vec{X: 8, Y: 2}, geometry.Vec{X: 12.5, Y: 2}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("jump over the wall: %v", err)
+	}
+	if _, err := r.UseAbility("p3", "leap", testMap, geometry.Vec{X: 2, Y: 5}, geometry.Vec{X: 4.2, Y: 5}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("jump onto the fence: %v", err)
+	}
+}
+
+func TestTeleportOnlyNeedsAWalkableDestination(t *testing.T) {
+	now := time.Now()
+	r := newTestRules(&now)
+
+	if landing, err := r.UseAbility("p1", "blink", testMap, geometry.Vec{X: 8, Y: 2}, geometry.Vec{X: 15, Y: 2}); err != nil || landing != (geometry.Vec{X: 15, Y: 2}) {
+		t.Errorf("teleport past the wall: %v, %v", landing, err)
+	}
+	if _, err := r.UseAbility("p1", "blink", testMap, geometry.Vec{X: 8, Y: 2}, geometry.Vec{X: 10.5, Y: 2}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("teleport into the wall: %v", err)
+	}
+}
+
+func TestAbilitiesCheckRangeAndCooldown(t *testing.T) {
+	now := time.Now()
+	r := newTestRules(&now)
+
+	if _, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 1, Y: 8}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("dash beyond its range: %v", err)
+	}
+	if _, err := r.UseAbility("p1", "fly", testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 1, Y: 2}); !errors.Is(err, ErrUnknownAbility) {
+		t.Errorf("unknown ability: %v", err)
+	}
+
+	if _, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 1, Y: 6}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Second)
+	var cooldown *CooldownError
+	if _, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 1, Y: 6}, geometry.Vec{X: 1, Y: 2}); !errors.As(err, &cooldown) || cooldown.Remaining != 3*time.Second {
+		t.Errorf("dash on cooldown: %v", err)
+	}
+	if _, err := r.UseAbility("p2", "dash", testMap, geometry.Vec{X: 1, Y: 1}, geometry.Vec{X: 1, Y: 6}); err != nil {
+		t.Errorf("cooldowns are per player: %v", err)
+	}
+	now = now.Add(3 * time.Second)
+	if _, err := r.UseAbility("p1", "dash", testMap, geometry.Vec{X: 1, Y: 6}, geometry.Vec{X: 1, Y: 2}); err != nil {
+		t.Errorf("dash after the cooldown: %v", err)
+	}
+}
+
+func TestLoadAbilitiesRejectsInvalidSkillData(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"kind":      `{"movementAbilities": [{"id": "fly", "kind": "flight", "range": 5}]}`,
+		"range":     `{"movementAbilities": [{"id": "dash", "kind": "dash"}]}`,
+		"duplicate": `{"movementAbilities": [{"id": "dash", "kind": "dash", "range": 5}, {"id": "dash", "kind": "jump", "range": 5}]}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAbilities(path); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
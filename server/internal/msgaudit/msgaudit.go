@@ -0,0 +1,361 @@
+// Package msgaudit is a debug mode that checks the messages actors send each
+// other. Every send is compared against a registry of allowed message types,
+// and its size is estimated. Message types that are unregistered, too large,
+// or that carry state unsafe to share between actors (maps, channels, funcs,
+// locks) are reported once in the log and counted for the debug endpoint.
+// Messages are still delivered: the audit only reports.
+//
+// The audit is registered with an actor system like the quarantine. Sender is
+// sender middleware that does nothing until the audit is enabled, so it can
+// stay in every actor's props and be switched on per environment.
+package msgaudit
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/extensions"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Finding kinds.
+const (
+	KindUnregistered = "unregistered" // The message type is not in the registry
+	KindOversized    = "oversized"    // The message is estimated larger than Options.MaxBytes
+	KindUnsafe       = "unsafe"       // The message type carries state that is unsafe to share
+)
+
+// DefaultMaxBytes is the size limit when Options leaves it at zero.
+const DefaultMaxBytes = 64 << 10
+
+// modulePath prefixes the packages whose types are inspected field by field.
+const modulePath = "github.com/phuhao00/suigserver/"
+
+var extensionID = extensions.NextExtensionID()
+
+// Registry lists the message types actors may send each other.
+type Registry struct {
+	mu       sync.RWMutex
+	types    map[reflect.Type]bool
+	packages map[string]bool
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[reflect.Type]bool), packages: make(map[string]bool)}
+}
+
+// Allow registers the types of messages, e.g. Allow(&trade.Update{}).
+func (r *Registry) Allow(messages ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range messages {
+		r.types[messageType(msg)] = true
+	}
+}
+
+// AllowPackageOf registers every type declared in the package of each of
+// messages, e.g. a package that holds nothing but actor messages.
+func (r *Registry) AllowPackageOf(messages ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range messages {
+		r.packages[messageType(msg).PkgPath()] = true
+	}
+}
+
+// Allowed reports whether messages of type t may be sent.
+func (r *Registry) Allowed(t reflect.Type) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.types[t] || r.packages[t.PkgPath()]
+}
+
+// Options configures a Service.
+type Options struct {
+	MaxBytes int // Estimated message size above which a send is reported
+}
+
+// Finding is a problem seen with one message type.
+type Finding struct {
+	Kind        string    `json:"kind"`
+	MessageType string    `json:"messageType"`
+	Detail      string    `json:"detail"`
+	Target      string    `json:"target"` // PID of the latest send
+	Sender      string    `json:"sender,omitempty"`
+	Count       uint64    `json:"count"` // Sends with this problem
+	FirstAt     time.Time `json:"firstAt"`
+	LastAt      time.Time `json:"lastAt"`
+}
+
+type findingKey struct {
+	kind        string
+	messageType string
+}
+
+// Service audits the sends of one actor system.
+type Service struct {
+	registry *Registry
+	opts     Options
+	enabled  atomic.Bool
+	checked  atomic.Uint64
+
+	mu       sync.Mutex
+	findings map[findingKey]*Finding
+	unsafe   map[reflect.Type]string // Why a type is unsafe, or "" if it is not; filled as types are seen
+}
+
+// New creates the audit for system, disabled, and registers it so Sender can
+// find it.
+func New(system *actor.ActorSystem, registry *Registry, opts Options) *Service {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	s := &Service{
+		registry: registry,
+		opts:     opts,
+		findings: make(map[findingKey]*Finding),
+		unsafe:   make(map[reflect.Type]string),
+	}
+	system.Extensions.Register(s)
+	return s
+}
+
+// ExtensionID implements extensions.Extension.
+func (s *Service) ExtensionID() extensions.ExtensionID { return extensionID }
+
+// fromSystem returns the audit registered with system, if any.
+func fromSystem(system *actor.ActorSystem) *Service {
+	s, _ := system.Extensions.Get(extensionID).(*Service)
+	return s
+}
+
+// SetEnabled turns the audit on or off.
+func (s *Service) SetEnabled(enabled bool) {
+	if s.enabled.Swap(enabled) != enabled {
+		utils.LogInfof("MsgAudit: Actor message audit %s.", map[bool]string{true: "enabled", false: "disabled"}[enabled])
+	}
+}
+
+// Enabled reports whether sends are being audited.
+func (s *Service) Enabled() bool {
+	return s.enabled.Load()
+}
+
+// Sender is sender middleware that audits each message while the audit of
+// the sender's actor system is enabled. Add it to actor props with
+// actor.WithSenderMiddleware, and to the root context.
+func Sender(next actor.SenderFunc) actor.SenderFunc {
+	return func(ctx actor.SenderContext, target *actor.PID, env *actor.MessageEnvelope) {
+		if s := fromSystem(ctx.ActorSystem()); s != nil && s.Enabled() {
+			sender := ""
+			if self := ctx.Self(); self != nil {
+				sender = self.Id
+			}
+			s.Check(target, sender, env.Message)
+		}
+		next(ctx, target, env)
+	}
+}
+
+// Check audits one message sent to target.
+func (s *Service) Check(target *actor.PID, sender string, message interface{}) {
+	if message == nil {
+		return
+	}
+	s.checked.Add(1)
+	t := reflect.TypeOf(message)
+	name := t.String()
+	if !s.registry.Allowed(messageType(message)) {
+		s.report(KindUnregistered, name, "not in the actor message registry", target, sender)
+	}
+	if reason := s.unsafeReason(t); reason != "" {
+		s.report(KindUnsafe, name, reason, target, sender)
+	}
+	if size := estimateSize(reflect.ValueOf(message), 2*s.opts.MaxBytes); size > s.opts.MaxBytes {
+		s.report(KindOversized, name, fmt.Sprintf("about %d bytes, over the %d byte limit", size, s.opts.MaxBytes), target, sender)
+	}
+}
+
+// Stats is what the audit has seen.
+type Stats struct {
+	Enabled  bool      `json:"enabled"`
+	MaxBytes int       `json:"maxBytes"`
+	Checked  uint64    `json:"checked"` // Sends audited since startup
+	Findings []Finding `json:"findings"`
+}
+
+// Stats returns the findings, most frequent first.
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	findings := make([]Finding, 0, len(s.findings))
+	for _, f := range s.findings {
+		findings = append(findings, *f)
+	}
+	s.mu.Unlock()
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Count != findings[j].Count {
+			return findings[i].Count > findings[j].Count
+		}
+		return findings[i].MessageType < findings[j].MessageType
+	})
+	return Stats{Enabled: s.Enabled(), MaxBytes: s.opts.MaxBytes, Checked: s.checked.Load(), Findings: findings}
+}
+
+// report counts a finding and logs the first of each kind and message type.
+func (s *Service) report(kind, messageType, detail string, target *actor.PID, sender string) {
+	now := time.Now()
+	s.mu.Lock()
+	key := findingKey{kind: kind, messageType: messageType}
+	f, seen := s.findings[key]
+	if !seen {
+		f = &Finding{Kind: kind, MessageType: messageType, FirstAt: now}
+		s.findings[key] = f
+	}
+	f.Detail, f.Target, f.Sender = detail, target.String(), sender
+	f.Count++
+	f.LastAt = now
+	s.mu.Unlock()
+	if !seen {
+		utils.LogWarnf("MsgAudit: %s message %s sent to %s: %s.", kind, messageType, target.String(), detail)
+	}
+}
+
+// unsafeReason returns why values of t are unsafe to share between actors,
+// or "" if they are not. The answer is cached per type.
+func (s *Service) unsafeReason(t reflect.Type) string {
+	s.mu.Lock()
+	reason, ok := s.unsafe[t]
+	s.mu.Unlock()
+	if ok {
+		return reason
+	}
+	reason = unsafeReason(t, "", make(map[reflect.Type]bool))
+	s.mu.Lock()
+	s.unsafe[t] = reason
+	s.mu.Unlock()
+	return reason
+}
+
+// unsafeReason walks t's fields. Types outside this module are not entered,
+// except to recognise locks.
+func unsafeReason(t reflect.Type, path string, seen map[reflect.Type]bool) string {
+	switch t.Kind() {
+	case reflect.Map:
+		return fieldName(path) + " is a map, which both actors could touch at once"
+	case reflect.Chan:
+		return fieldName(path) + " is a channel"
+	case reflect.Func:
+		return fieldName(path) + " is a func, which may capture the sender's state"
+	case reflect.UnsafePointer:
+		return fieldName(path) + " is an unsafe.Pointer"
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return unsafeReason(t.Elem(), path, seen)
+	case reflect.Struct:
+		if pkg := t.PkgPath(); pkg == "sync" || pkg == "sync/atomic" {
+			return fieldName(path) + " is a " + t.String() + ", which must not be copied or shared"
+		}
+		if t.Name() != "" && !strings.HasPrefix(t.PkgPath(), modulePath) {
+			return ""
+		}
+		if seen[t] {
+			return ""
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if reason := unsafeReason(field.Type, joinPath(path, field.Name), seen); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// estimateSize approximates the memory a value holds, following pointers,
+// slices, maps and interfaces. It stops counting past limit.
+func estimateSize(v reflect.Value, limit int) int {
+	size := 0
+	var walk func(v reflect.Value, depth int)
+	visited := make(map[uintptr]bool)
+	walk = func(v reflect.Value, depth int) {
+		if !v.IsValid() || size > limit || depth > 32 {
+			return
+		}
+		switch v.Kind() {
+		case reflect.Pointer:
+			if v.IsNil() || visited[v.Pointer()] {
+				return
+			}
+			visited[v.Pointer()] = true
+			size += int(v.Type().Elem().Size())
+			walk(v.Elem(), depth+1)
+		case reflect.Interface:
+			if !v.IsNil() {
+				size += int(v.Elem().Type().Size())
+				walk(v.Elem(), depth+1)
+			}
+		case reflect.String:
+			size += v.Len()
+		case reflect.Slice:
+			if v.IsNil() || visited[v.Pointer()] {
+				return
+			}
+			visited[v.Pointer()] = true
+			size += v.Len() * int(v.Type().Elem().Size())
+			for i := 0; i < v.Len() && size <= limit; i++ {
+				walk(v.Index(i), depth+1)
+			}
+		case reflect.Array:
+			for i := 0; i < v.Len() && size <= limit; i++ {
+				walk(v.Index(i), depth+1)
+			}
+		case reflect.Map:
+			if v.IsNil() {
+				return
+			}
+			size += v.Len() * int(v.Type().Key().Size()+v.Type().Elem().Size())
+			iter := v.MapRange()
+			for iter.Next() && size <= limit {
+				walk(iter.Key(), depth+1)
+				walk(iter.Value(), depth+1)
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField() && size <= limit; i++ {
+				walk(v.Field(i), depth+1)
+			}
+		}
+	}
+	size += int(v.Type().Size())
+	walk(v, 0)
+	return size
+}
+
+// messageType is the named type of a message, without the pointer.
+func messageType(msg interface{}) reflect.Type {
+	t := reflect.TypeOf(msg)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func fieldName(path string) string {
+	if path == "" {
+		return "the message"
+	}
+	return "field " + path
+}
@@ -0,0 +1,80 @@
+package msgaudit
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+type hello struct{ Text string }
+
+type shared struct{ Positions map[string]int }
+
+type locked struct {
+	mu    sync.Mutex
+	Count int
+}
+
+type stray struct{}
+
+func TestAuditReportsUnregisteredUnsafeAndOversizedMessages(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	registry := NewRegistry()
+	registry.Allow(&hello{}, &shared{}, &locked{})
+	audit := New(system, registry, Options{MaxBytes: 1024})
+	system.Root.WithSenderMiddleware(Sender)
+
+	received := make(chan interface{}, 8)
+	pid := system.Root.Spawn(actor.PropsFromFunc(func(ctx actor.Context) {
+		if _, ok := ctx.Message().(actor.SystemMessage); !ok {
+			received <- ctx.Message()
+		}
+	}))
+	system.Root.Send(pid, &stray{})
+	if len(audit.Stats().Findings) != 0 {
+		t.Fatal("the audit reported while disabled")
+	}
+
+	audit.SetEnabled(true)
+	system.Root.Send(pid, &hello{Text: "hi"})
+	system.Root.Send(pid, &stray{})
+	system.Root.Send(pid, &stray{})
+	system.Root.Send(pid, &shared{Positions: map[string]int{"alice": 1}})
+	system.Root.Send(pid, &locked{})
+	system.Root.Send(pid, &hello{Text: strings.Repeat("x", 4096)})
+	for i := 0; i < 7; i++ {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatal("a message was not delivered")
+		}
+	}
+
+	stats := audit.Stats()
+	if stats.Checked != 6 {
+		t.Errorf("checked %d sends, want 6", stats.Checked)
+	}
+	found := make(map[string]Finding)
+	for _, f := range stats.Findings {
+		found[f.Kind+" "+f.MessageType] = f
+	}
+	if f := found[KindUnregistered+" *msgaudit.stray"]; f.Count != 2 {
+		t.Errorf("unregistered stray = %+v, want 2 sends", f)
+	}
+	if f := found[KindUnsafe+" *msgaudit.shared"]; !strings.Contains(f.Detail, "field Positions is a map") {
+		t.Errorf("unsafe shared = %+v", f)
+	}
+	if f := found[KindUnsafe+" *msgaudit.locked"]; !strings.Contains(f.Detail, "sync.Mutex") {
+		t.Errorf("unsafe locked = %+v", f)
+	}
+	if f := found[KindOversized+" *msgaudit.hello"]; f.Count != 1 {
+		t.Errorf("oversized hello = %+v, want only the long one", f)
+	}
+	if len(stats.Findings) != 4 {
+		t.Errorf("findings = %+v", stats.Findings)
+	}
+}
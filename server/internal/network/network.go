@@ -1,6 +1,8 @@
 package network
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	// "log" // Replaced by utils.LogX
 	"net"
@@ -8,11 +10,12 @@ import (
 	"sync"
 	"time"
 
-	"encoding/binary"
-
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
 	sessionactor "github.com/phuhao00/suigserver/server/internal/actor" // Alias for the actor package
 	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/chaos"
+	"github.com/phuhao00/suigserver/server/internal/health"
 	"github.com/phuhao00/suigserver/server/internal/sui"   // For sui.SuiClient
 	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
 )
@@ -24,6 +27,11 @@ const (
 	// LengthPrefixSize is the size in bytes of the message length prefix.
 	// Using uint32 for length, so 4 bytes.
 	LengthPrefixSize = 4
+	// acceptHeartbeatInterval bounds how long Accept blocks so the accept loop can
+	// report liveness even when no clients are connecting.
+	acceptHeartbeatInterval = 5 * time.Second
+	// AcceptLoopHeartbeat is the health heartbeat name of the accept loop.
+	AcceptLoopHeartbeat = "tcp-accept-loop"
 )
 
 // TCPServer manages TCP client connections and interfaces with the actor system.
@@ -36,6 +44,13 @@ type TCPServer struct {
 	roomManagerPID  *actor.PID     // PID of the RoomManagerActor
 	worldManagerPID *actor.PID     // PID of the WorldManagerActor
 	suiClient       *sui.SuiClient // SUI client instance
+	healthMonitor   *health.Monitor
+	chaos           *chaos.Service // Delays and drops client messages in chaos builds; nil injects nothing
+	sessionServices sessionactor.SessionServices
+	// Sessions of open connections, told to disconnect by Stop
+	sessionsMu         sync.Mutex
+	sessions           map[*actor.PID]struct{}
+	shutdownRetryAfter time.Duration
 	// Auth Configs
 	enableDummyAuth bool
 	dummyToken      string
@@ -70,6 +85,7 @@ func NewTCPServer(
 		port:            port,
 		actorSystem:     system,
 		shutdown:        make(chan struct{}),
+		sessions:        make(map[*actor.PID]struct{}),
 		roomManagerPID:  roomManagerPID,
 		worldManagerPID: worldManagerPID,
 		suiClient:       suiClient,
@@ -79,6 +95,31 @@ func NewTCPServer(
 	}
 }
 
+// SetSessionServices attaches shared game services to every player session.
+// It must be called before Start.
+func (s *TCPServer) SetSessionServices(services sessionactor.SessionServices) {
+	s.sessionServices = services
+}
+
+// SetChaos makes client messages go through the fault injector on their way
+// to sessions. It must be called before Start.
+func (s *TCPServer) SetChaos(faults *chaos.Service) {
+	s.chaos = faults
+}
+
+// SetShutdownRetryAfter sets how long clients are told to wait before
+// reconnecting when Stop disconnects them. It must be called before Start.
+func (s *TCPServer) SetShutdownRetryAfter(d time.Duration) {
+	s.shutdownRetryAfter = d
+}
+
+// SetHealthMonitor makes the accept loop report heartbeats to monitor.
+// It must be called before Start.
+func (s *TCPServer) SetHealthMonitor(monitor *health.Monitor) {
+	s.healthMonitor = monitor
+	monitor.ExpectHeartbeat(AcceptLoopHeartbeat, 6*acceptHeartbeatInterval)
+}
+
 // Start begins listening for TCP connections.
 func (s *TCPServer) Start() error {
 	listenAddr := ":" + strconv.Itoa(s.port)
@@ -96,12 +137,31 @@ func (s *TCPServer) Start() error {
 	return nil
 }
 
+// Addr returns the address the server is listening on, or nil before Start.
+// With port 0 this is where to find the port the system picked.
+func (s *TCPServer) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
 func (s *TCPServer) acceptConnections() {
 	defer s.wg.Done()
 	utils.LogInfo("TCP accept loop started.")
+	tcpListener, canSetDeadline := s.listener.(*net.TCPListener)
 	for {
+		if s.healthMonitor != nil {
+			s.healthMonitor.Beat(AcceptLoopHeartbeat)
+			if canSetDeadline {
+				tcpListener.SetDeadline(time.Now().Add(acceptHeartbeatInterval))
+			}
+		}
 		conn, err := s.listener.Accept()
 		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue // Accept deadline expired; loop to beat again
+			}
 			select {
 			case <-s.shutdown:
 				utils.LogInfo("TCP accept loop shutting down.")
@@ -150,7 +210,7 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	}
 
 	// PlayerSessionActor now requires worldManagerPID, suiClient, and auth configs.
-	playerSessionProps := sessionactor.PropsForPlayerSession(
+	playerSessionProps := sessionactor.PropsForPlayerSessionWithServices(
 		s.actorSystem,
 		s.roomManagerPID,
 		s.worldManagerPID,
@@ -158,9 +218,18 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 		s.enableDummyAuth,
 		s.dummyToken,
 		s.dummyPlayerID,
+		s.sessionServices,
 	)
 	playerSessionPID := s.actorSystem.Root.Spawn(playerSessionProps)
 	utils.LogInfof("[%s] Spawned PlayerSessionActor with PID: %s", clientAddr, playerSessionPID.String())
+	s.sessionsMu.Lock()
+	s.sessions[playerSessionPID] = struct{}{}
+	s.sessionsMu.Unlock()
+	defer func() {
+		s.sessionsMu.Lock()
+		delete(s.sessions, playerSessionPID)
+		s.sessionsMu.Unlock()
+	}()
 
 	// Send ClientConnected message to the PlayerSessionActor
 	// This message includes the net.Conn so the actor can use it.
@@ -173,43 +242,50 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	// Goroutine for reading from the client and forwarding messages to PlayerSessionActor
 	// reader := bufio.NewReader(conn) // Replaced by direct read for length-prefixing
 	for {
-		// Implement proper message framing: Length-Prefixing
-		// 1. Read the length prefix (e.g., 4 bytes for uint32)
-		lenBuf := make([]byte, LengthPrefixSize)
-		_, err := io.ReadFull(conn, lenBuf)
+		// Read one frame: a legacy length-prefixed envelope, or a version 2 frame
+		// with flags and a message type ID (see protocol.ReadFrame).
+		frame, err := protocol.ReadFrame(conn, MaxMessageSize)
+		if errors.Is(err, protocol.ErrFrameTooLarge) {
+			utils.LogWarnf("[%s] %v. Closing connection.", clientAddr, err)
+			// The session says why and closes the connection.
+			s.actorSystem.Root.Send(playerSessionPID, &messages.TerminateSession{
+				Code:   protocol.DisconnectProtocolViolation,
+				Reason: fmt.Sprintf("Message over the %d byte limit.", MaxMessageSize),
+			})
+			return
+		}
 		if err != nil {
-			s.handleReadError(conn, playerSessionPID, err, "reading length prefix")
+			s.handleReadError(conn, playerSessionPID, err, "reading frame")
 			return
 		}
 
-		messageLength := binary.BigEndian.Uint32(lenBuf)
-
-		// 2. Validate message length
-		if messageLength == 0 {
+		// Validate message length
+		if len(frame.Body) == 0 {
 			utils.LogWarnf("[%s] Received message with zero length. Ignoring.", clientAddr)
 			continue // Or treat as an error/disconnect
 		}
-		if messageLength > MaxMessageSize {
-			utils.LogWarnf("[%s] Message length %d exceeds MaxMessageSize %d. Closing connection.",
-				clientAddr, messageLength, MaxMessageSize)
-			s.actorSystem.Root.Send(playerSessionPID, &messages.ClientDisconnected{Reason: "Message too large"})
-			conn.Close()
-			return
-		}
 
-		// 3. Read the message payload
-		payloadBuf := make([]byte, messageLength)
-		_, err = io.ReadFull(conn, payloadBuf)
-		if err != nil {
-			s.handleReadError(conn, playerSessionPID, err, "reading payload") // handleReadError uses utils.Log
-			return
+		if utils.DebugEnabled() {
+			utils.LogDebugf("[%s] Received v%d frame (type ID %d, flags %d), %d bytes.",
+				clientAddr, frame.Version, frame.TypeID, frame.Flags, len(frame.Body))
 		}
 
-		utils.LogDebugf("[%s] Received %d bytes. Payload: '%s'", clientAddr, messageLength, string(payloadBuf))
+		if delay, drop := s.chaos.ClientMessage(); drop {
+			utils.LogDebugf("[%s] Chaos: dropped a client frame.", clientAddr)
+			continue
+		} else if delay > 0 {
+			time.Sleep(delay)
+		}
 
 		if playerSessionPID != nil {
-			// The payloadBuf is what PlayerSessionActor expects (e.g., JSON string)
-			s.actorSystem.Root.Send(playerSessionPID, &messages.ClientMessage{Payload: payloadBuf})
+			s.actorSystem.Root.Send(playerSessionPID, &messages.ClientMessage{
+				Payload:      frame.Body,
+				FrameVersion: frame.Version,
+				TypeID:       frame.TypeID,
+				Flags:        frame.Flags,
+				WireSize:     frame.Size,
+				ReceivedAt:   time.Now(),
+			})
 		} else {
 			// This case should ideally not be reached if PIDs are managed correctly
 			utils.LogWarnf("[%s] Warning: No PlayerSessionPID. Cannot process message.", clientAddr)
@@ -220,11 +296,7 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 		select {
 		case <-s.shutdown:
 			utils.LogInfof("[%s] Server shutting down, closing connection handler.", clientAddr)
-			if playerSessionPID != nil {
-				s.actorSystem.Root.Send(playerSessionPID, &messages.ClientDisconnected{Reason: "Server shutdown"})
-			}
-			conn.Close()
-			return
+			return // Stop has told the session, which closes the connection
 		default:
 			// continue reading
 		}
@@ -266,6 +338,19 @@ func (s *TCPServer) Stop() {
 		utils.LogInfo("TCP listener was not active or already closed.")
 	}
 
+	// Each session tells its client the server is going away and closes the
+	// connection, which ends its connection handler.
+	s.sessionsMu.Lock()
+	for pid := range s.sessions {
+		s.actorSystem.Root.Send(pid, &messages.TerminateSession{
+			Code:       protocol.DisconnectMaintenance,
+			Reason:     "The server is shutting down.",
+			Retryable:  true,
+			RetryAfter: s.shutdownRetryAfter,
+		})
+	}
+	s.sessionsMu.Unlock()
+
 	// Wait for all connection handlers and the accept loop goroutine to finish.
 	// A timeout can be added here to prevent hanging indefinitely if a goroutine is stuck.
 	shutdownCompleted := make(chan struct{})
@@ -1,10 +1,10 @@
 package network
 
 // This file now only imports the protocol package
-// All protocol-related types have been moved to server/internal/protocol
+// All protocol-related types have been moved to pkg/protocol
 
 import (
-	"github.com/phuhao00/suigserver/server/internal/protocol"
+	"github.com/phuhao00/suigserver/pkg/protocol"
 )
 
 // Re-export commonly used types for backward compatibility
@@ -0,0 +1,244 @@
+// Package npc defines the non-player characters that roam rooms and how they
+// decide where to go. An NPC patrols its waypoints, chases the nearest player
+// who comes within its aggro range, and gives up once the chase leads too far
+// from home. Paths come from the room's pathfinding Planner; a Brain only asks
+// for them and walks them.
+package npc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+const (
+	// reachDistance is how close an NPC must get to a waypoint to count it reached.
+	reachDistance = 0.1
+	// engageDistance is how close an NPC chasing a player stops.
+	engageDistance = 1.0
+	// repathDistance is how far a chased player may move before the NPC asks
+	// for a new path.
+	repathDistance = 1.5
+	// retryDelay is how long an NPC waits after a failed path request.
+	retryDelay = 2 * time.Second
+)
+
+// Definition is an NPC from the NPC file.
+type Definition struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	MapID      string         `json:"mapId"`                // Rooms on this map spawn the NPC
+	Spawn      geometry.Vec   `json:"spawn"`                // Where it starts and returns to
+	Patrol     []geometry.Vec `json:"patrol,omitempty"`     // Waypoints walked in a loop; it stands at Spawn without them
+	Speed      float64        `json:"speed"`                // Units per second
+	AggroRange float64        `json:"aggroRange,omitempty"` // Chases players this close; 0 never chases
+	LeashRange float64        `json:"leashRange,omitempty"` // Gives up chasing this far from Spawn; 0 never does
+	Health     int            `json:"health,omitempty"`     // Hit points; 0 cannot be damaged
+	Defense    int            `json:"defense,omitempty"`
+}
+
+// File is the NPC file.
+type File struct {
+	NPCs []Definition `json:"npcs"`
+}
+
+// Roster holds the NPC definitions by map. A nil *Roster has no NPCs.
+type Roster struct {
+	byMap map[string][]Definition
+}
+
+// LoadRoster reads an NPC file, checking every NPC's spawn point and patrol
+// waypoints against maps.
+func LoadRoster(path string, maps map[string]*geometry.Map) (*Roster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid NPC file %s: %w", path, err)
+	}
+	r := &Roster{byMap: make(map[string][]Definition)}
+	seen := make(map[string]bool, len(file.NPCs))
+	for i, def := range file.NPCs {
+		if err := def.validate(maps); err != nil {
+			return nil, fmt.Errorf("invalid NPC file %s: NPC %d (%s): %w", path, i+1, def.ID, err)
+		}
+		if seen[def.ID] {
+			return nil, fmt.Errorf("invalid NPC file %s: duplicate NPC %q", path, def.ID)
+		}
+		seen[def.ID] = true
+		r.byMap[def.MapID] = append(r.byMap[def.MapID], def)
+	}
+	return r, nil
+}
+
+func (d Definition) validate(maps map[string]*geometry.Map) error {
+	if d.ID == "" {
+		return fmt.Errorf("needs an id")
+	}
+	if d.Speed <= 0 || d.AggroRange < 0 || d.LeashRange < 0 {
+		return fmt.Errorf("needs a positive speed and ranges of 0 or more")
+	}
+	if d.Health < 0 || d.Defense < 0 {
+		return fmt.Errorf("needs a health and defense of 0 or more")
+	}
+	m, ok := maps[d.MapID]
+	if !ok {
+		return fmt.Errorf("unknown map %q", d.MapID)
+	}
+	for _, p := range append([]geometry.Vec{d.Spawn}, d.Patrol...) {
+		if !m.Walkable(p) {
+			return fmt.Errorf("%v is not walkable", p)
+		}
+	}
+	return nil
+}
+
+// ForMap returns the NPCs of rooms on the map with id.
+func (r *Roster) ForMap(id string) []Definition {
+	if r == nil {
+		return nil
+	}
+	return r.byMap[id]
+}
+
+// Len returns the number of NPCs.
+func (r *Roster) Len() int {
+	if r == nil {
+		return 0
+	}
+	n := 0
+	for _, defs := range r.byMap {
+		n += len(defs)
+	}
+	return n
+}
+
+// Brain is the state of one NPC: where it is, what it is after and the path
+// it is walking.
+type Brain struct {
+	def      Definition
+	pos      geometry.Vec
+	waypoint int            // Next patrol waypoint
+	target   string         // Player being chased
+	goal     geometry.Vec   // Where the current or requested path leads
+	path     []geometry.Vec // Remaining waypoints
+	pending  bool           // A path has been requested
+	waitFor  time.Duration  // Until the next path request after a failed one
+}
+
+// NewBrain creates the Brain of an NPC standing at its spawn point.
+func NewBrain(def Definition) *Brain {
+	return &Brain{def: def, pos: def.Spawn, goal: def.Spawn}
+}
+
+// Position returns where the NPC is.
+func (b *Brain) Position() geometry.Vec {
+	return b.pos
+}
+
+// Target returns the player the NPC is chasing, if any.
+func (b *Brain) Target() string {
+	return b.target
+}
+
+// Tick advances the NPC by dt, given where the players are. It returns a goal
+// to request a path to, if the NPC needs one, and whether the NPC moved. The
+// NPC keeps walking its old path while it waits for the new one.
+func (b *Brain) Tick(players map[string]geometry.Vec, dt time.Duration) (goal geometry.Vec, needPath bool, moved bool) {
+	if b.waitFor > 0 {
+		b.waitFor -= dt
+	}
+	wanted, chasing := b.decide(players)
+	if !b.pending && b.waitFor <= 0 && b.needsPath(wanted, chasing) {
+		b.goal, b.pending = wanted, true
+		goal, needPath = wanted, true
+	}
+	return goal, needPath, b.walk(dt, wanted, chasing)
+}
+
+// decide picks what the NPC is after: the nearest player in aggro range who
+// is within the leash, or else its patrol.
+func (b *Brain) decide(players map[string]geometry.Vec) (geometry.Vec, bool) {
+	leashed := func(p geometry.Vec) bool {
+		return b.def.LeashRange == 0 || p.Dist(b.def.Spawn) <= b.def.LeashRange
+	}
+	previous := b.target
+	if p, ok := players[b.target]; !ok || !leashed(p) {
+		b.target = ""
+	}
+	if b.target == "" && b.def.AggroRange > 0 {
+		best := b.def.AggroRange
+		for id, p := range players {
+			if d := p.Dist(b.pos); d <= best && leashed(p) {
+				b.target, best = id, d
+			}
+		}
+	}
+	if b.target != previous {
+		b.path = nil // Heading somewhere else now
+	}
+	if b.target != "" {
+		return players[b.target], true
+	}
+	if len(b.def.Patrol) == 0 {
+		return b.def.Spawn, false
+	}
+	if b.pos.Dist(b.def.Patrol[b.waypoint]) <= reachDistance {
+		b.waypoint = (b.waypoint + 1) % len(b.def.Patrol)
+	}
+	return b.def.Patrol[b.waypoint], false
+}
+
+// needsPath reports whether the NPC must ask for a new path to wanted: a
+// chased player has moved too far from where the path leads, the patrol has
+// moved on, or the path ran out short of the goal.
+func (b *Brain) needsPath(wanted geometry.Vec, chasing bool) bool {
+	if chasing && wanted.Dist(b.goal) > repathDistance || !chasing && wanted != b.goal {
+		return true
+	}
+	return len(b.path) == 0 && !b.near(wanted, chasing)
+}
+
+// near reports whether the NPC is close enough to goal to stop.
+func (b *Brain) near(goal geometry.Vec, chasing bool) bool {
+	if chasing {
+		return b.pos.Dist(goal) <= engageDistance
+	}
+	return b.pos.Dist(goal) <= reachDistance
+}
+
+// walk moves the NPC along its path at its speed, stopping short of a chased
+// player, and reports whether it moved.
+func (b *Brain) walk(dt time.Duration, wanted geometry.Vec, chasing bool) bool {
+	step := b.def.Speed * dt.Seconds()
+	start := b.pos
+	for step > 0 && len(b.path) > 0 && !(chasing && b.near(wanted, true)) {
+		next := b.path[0]
+		d := b.pos.Dist(next)
+		if d <= step {
+			b.pos, step, b.path = next, step-d, b.path[1:]
+			continue
+		}
+		b.pos, step = b.pos.Lerp(next, step/d), 0
+	}
+	return b.pos != start
+}
+
+// SetPath hands the NPC the path it asked for. If none was found it tries
+// again a little later; an unreachable patrol waypoint is skipped.
+func (b *Brain) SetPath(path []geometry.Vec, found bool) {
+	b.pending = false
+	if found {
+		b.path = path
+		return
+	}
+	b.path, b.waitFor = nil, retryDelay
+	if b.target == "" && len(b.def.Patrol) > 0 {
+		b.waypoint = (b.waypoint + 1) % len(b.def.Patrol)
+	}
+}
@@ -0,0 +1,118 @@
+package npc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+const tick = 100 * time.Millisecond
+
+// follow ticks b until it asks for a path, and hands it a straight one.
+func follow(t *testing.T, b *Brain, players map[string]geometry.Vec) geometry.Vec {
+	t.Helper()
+	goal, needPath, _ := b.Tick(players, tick)
+	if !needPath {
+		t.Fatal("the NPC did not ask for a path")
+	}
+	b.SetPath([]geometry.Vec{goal}, true)
+	return goal
+}
+
+func TestBrainPatrols(t *testing.T) {
+	b := NewBrain(Definition{
+		ID:     "guard",
+		Spawn:  geometry.Vec{X: 0, Y: 0},
+		Patrol: []geometry.Vec{{X: 1, Y: 0}, {X: 1, Y: 1}},
+		Speed:  5,
+	})
+	if goal := follow(t, b, nil); goal != (geometry.Vec{X: 1, Y: 0}) {
+		t.Fatalf("first goal %v, want the first waypoint", goal)
+	}
+	for i := 0; i < 2; i++ {
+		b.Tick(nil, tick) // 0.5 per tick
+	}
+	if b.Position() != (geometry.Vec{X: 1, Y: 0}) {
+		t.Fatalf("at %v after walking, want at the first waypoint", b.Position())
+	}
+	if goal := follow(t, b, nil); goal != (geometry.Vec{X: 1, Y: 1}) {
+		t.Errorf("next goal %v, want the second waypoint", goal)
+	}
+}
+
+func TestBrainChasesWithinItsLeash(t *testing.T) {
+	b := NewBrain(Definition{ID: "wolf", Spawn: geometry.Vec{}, Speed: 10, AggroRange: 5, LeashRange: 8})
+
+	if _, needPath, _ := b.Tick(map[string]geometry.Vec{"far": {X: 6, Y: 0}}, tick); needPath || b.Target() != "" {
+		t.Fatal("chased a player out of aggro range")
+	}
+	players := map[string]geometry.Vec{"far": {X: 6, Y: 0}, "near": {X: 4, Y: 0}}
+	if goal := follow(t, b, players); goal != players["near"] || b.Target() != "near" {
+		t.Fatalf("chasing %q towards %v", b.Target(), goal)
+	}
+	for i := 0; i < 10; i++ {
+		b.Tick(players, tick)
+	}
+	if d := b.Position().Dist(players["near"]); d > engageDistance+1e-9 || d < engageDistance-0.5 {
+		t.Errorf("stopped %v from the player, want about %v", d, engageDistance)
+	}
+
+	// The player runs off past the leash: the wolf goes home.
+	players["near"] = geometry.Vec{X: 9, Y: 0}
+	delete(players, "far")
+	if goal := follow(t, b, players); goal != (geometry.Vec{}) || b.Target() != "" {
+		t.Errorf("after the player left the leash: chasing %q towards %v", b.Target(), goal)
+	}
+}
+
+func TestBrainRetriesAfterAFailedPath(t *testing.T) {
+	b := NewBrain(Definition{ID: "wolf", Speed: 1, AggroRange: 5})
+	players := map[string]geometry.Vec{"p": {X: 3, Y: 0}}
+	if _, needPath, _ := b.Tick(players, tick); !needPath {
+		t.Fatal("no path requested")
+	}
+	b.SetPath(nil, false)
+	for waited := time.Duration(0); waited < retryDelay-tick; waited += tick {
+		if _, needPath, _ := b.Tick(players, tick); needPath {
+			t.Fatalf("asked again after %v", waited)
+		}
+	}
+	if _, needPath, _ := b.Tick(players, tick); !needPath {
+		t.Error("did not ask again after the retry delay")
+	}
+}
+
+func TestLoadRosterChecksTheMaps(t *testing.T) {
+	maps := map[string]*geometry.Map{"yard": {
+		Bounds:    geometry.Rect{MaxX: 10, MaxY: 10},
+		Obstacles: []geometry.Obstacle{{Points: geometry.Rect{MinX: 4, MinY: 4, MaxX: 6, MaxY: 6}.Polygon()}},
+	}}
+	path := filepath.Join(t.TempDir(), "npcs.json")
+	load := func(content string) (*Roster, error) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadRoster(path, maps)
+	}
+
+	roster, err := load(`{"npcs": [{"id": "cat", "mapId": "yard", "spawn": {"x": 1, "y": 1}, "speed": 1}]}`)
+	if err != nil || len(roster.ForMap("yard")) != 1 || roster.Len() != 1 {
+		t.Fatalf("LoadRoster = %v, %v", roster, err)
+	}
+	for want, content := range map[string]string{
+		"walkable": `{"npcs": [{"id": "cat", "mapId": "yard", "spawn": {"x": 1, "y": 1}, "patrol": [{"x": 5, "y": 5}], "speed": 1}]}`,
+		"map":      `{"npcs": [{"id": "cat", "mapId": "moon", "speed": 1}]}`,
+		"speed":    `{"npcs": [{"id": "cat", "mapId": "yard", "spawn": {"x": 1, "y": 1}}]}`,
+		"health":   `{"npcs": [{"id": "cat", "mapId": "yard", "spawn": {"x": 1, "y": 1}, "speed": 1, "health": -5}]}`,
+		"duplicate": `{"npcs": [{"id": "cat", "mapId": "yard", "spawn": {"x": 1, "y": 1}, "speed": 1},
+		                        {"id": "cat", "mapId": "yard", "spawn": {"x": 2, "y": 2}, "speed": 1}]}`,
+	} {
+		if _, err := load(content); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error about %s, got %v", want, err)
+		}
+	}
+}
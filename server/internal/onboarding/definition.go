@@ -0,0 +1,98 @@
+// Package onboarding runs the server-driven tutorial: it tracks which steps
+// each player has completed, gates actions until their prerequisite steps are
+// done, and produces the step prompts pushed to the client.
+package onboarding
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// EventRoomJoined is recorded when the player has joined a room. Other events
+// are the client message types, with PLAYER_ACTION events qualified by their
+// action type (see ClientEvent).
+const EventRoomJoined = "ROOM_JOINED"
+
+// ClientEvent returns the event name for a client message.
+func ClientEvent(msgType, actionType string) string {
+	if msgType == "PLAYER_ACTION" && actionType != "" {
+		return msgType + ":" + actionType
+	}
+	return msgType
+}
+
+// Step is one tutorial step. A step completes when the player triggers its
+// CompleteOn event after all of its required steps are complete.
+type Step struct {
+	ID         string   `json:"id"`
+	Prompt     string   `json:"prompt"`
+	Hint       string   `json:"hint,omitempty"`
+	CompleteOn string   `json:"completeOn"`         // Event name, e.g. "ROOM_JOINED" or "PLAYER_ACTION:MOVE"
+	Requires   []string `json:"requires,omitempty"` // Defaults to the previous step
+}
+
+// Definition is the tutorial loaded from a data file.
+type Definition struct {
+	Steps []Step `json:"steps"`
+	// Gates maps an event to the step that must be complete before the player may trigger it.
+	Gates map[string]string `json:"gates,omitempty"`
+}
+
+// LoadDefinition reads and validates a tutorial definition from a JSON file.
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid tutorial definition %s: %w", path, err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tutorial definition %s: %w", path, err)
+	}
+	return &def, nil
+}
+
+// Validate checks step IDs and references, and fills in the default prerequisites.
+// Steps may only require steps listed before them, which rules out cycles.
+func (d *Definition) Validate() error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("no steps defined")
+	}
+	seen := make(map[string]bool, len(d.Steps))
+	for i := range d.Steps {
+		step := &d.Steps[i]
+		if step.ID == "" || step.CompleteOn == "" {
+			return fmt.Errorf("step %d needs an id and completeOn", i+1)
+		}
+		if seen[step.ID] {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		if step.Requires == nil && i > 0 {
+			step.Requires = []string{d.Steps[i-1].ID}
+		}
+		for _, required := range step.Requires {
+			if !seen[required] {
+				return fmt.Errorf("step %q requires %q, which is not an earlier step", step.ID, required)
+			}
+		}
+		seen[step.ID] = true
+	}
+	for event, stepID := range d.Gates {
+		if !seen[stepID] {
+			return fmt.Errorf("gate for %q refers to unknown step %q", event, stepID)
+		}
+	}
+	return nil
+}
+
+func (d *Definition) step(id string) (Step, int, bool) {
+	for i, step := range d.Steps {
+		if step.ID == id {
+			return step, i, true
+		}
+	}
+	return Step{}, 0, false
+}
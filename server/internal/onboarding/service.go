@@ -0,0 +1,160 @@
+package onboarding
+
+import (
+	"sync"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Store persists tutorial flags (completed step IDs). game.DBCacheLayer
+// implements it on top of PlayerData.
+type Store interface {
+	LoadTutorialFlags(playerID string) (map[string]bool, error)
+	SaveTutorialFlags(playerID string, flags map[string]bool) error
+}
+
+// MemoryStore keeps tutorial flags in memory, for servers without a database.
+type MemoryStore struct {
+	mu    sync.Mutex
+	flags map[string]map[string]bool
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{flags: make(map[string]map[string]bool)}
+}
+
+// LoadTutorialFlags implements Store.
+func (m *MemoryStore) LoadTutorialFlags(playerID string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copyFlags(m.flags[playerID]), nil
+}
+
+// SaveTutorialFlags implements Store.
+func (m *MemoryStore) SaveTutorialFlags(playerID string, flags map[string]bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flags[playerID] = copyFlags(flags)
+	return nil
+}
+
+func copyFlags(flags map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(flags))
+	for id, done := range flags {
+		copied[id] = done
+	}
+	return copied
+}
+
+// Prompt describes the step a player should work on next.
+type Prompt struct {
+	StepID string
+	Prompt string
+	Hint   string
+	Index  int // Zero-based position of the step
+	Total  int
+}
+
+// Service tracks tutorial progress for connected players. It is safe for
+// concurrent use by session actors.
+type Service struct {
+	def   *Definition
+	store Store
+
+	mu       sync.Mutex
+	progress map[string]map[string]bool // PlayerID -> completed step IDs, for players in session
+}
+
+// NewService creates a Service for a validated definition.
+func NewService(def *Definition, store Store) *Service {
+	return &Service{def: def, store: store, progress: make(map[string]map[string]bool)}
+}
+
+// Begin loads a player's progress when their session starts and returns the
+// current step, or nil if the tutorial is already complete.
+func (s *Service) Begin(playerID string) *Prompt {
+	flags, err := s.store.LoadTutorialFlags(playerID)
+	if err != nil {
+		utils.LogWarnf("Onboarding: Could not load tutorial progress for %s, starting fresh: %v", playerID, err)
+		flags = make(map[string]bool)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress[playerID] = flags
+	return s.currentLocked(flags)
+}
+
+// End forgets a player's in-memory progress when their session ends.
+func (s *Service) End(playerID string) {
+	s.mu.Lock()
+	delete(s.progress, playerID)
+	s.mu.Unlock()
+}
+
+// Allowed reports whether the player may trigger event. If not, it returns the
+// prompt for the step the gate waits on.
+func (s *Service) Allowed(playerID, event string) (bool, *Prompt) {
+	stepID, gated := s.def.Gates[event]
+	if !gated {
+		return true, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flags := s.progress[playerID]
+	if flags[stepID] {
+		return true, nil
+	}
+	step, index, _ := s.def.step(stepID)
+	return false, &Prompt{StepID: step.ID, Prompt: step.Prompt, Hint: step.Hint, Index: index, Total: len(s.def.Steps)}
+}
+
+// Record applies event to the player's progress. If it completes a step, the
+// new progress is saved and the IDs of the completed steps are returned along
+// with the next step (nil once the tutorial is finished).
+func (s *Service) Record(playerID, event string) (completed []string, next *Prompt) {
+	s.mu.Lock()
+	flags, ok := s.progress[playerID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	for _, step := range s.def.Steps {
+		if step.CompleteOn == event && !flags[step.ID] && requirementsMet(step, flags) {
+			flags[step.ID] = true
+			completed = append(completed, step.ID)
+		}
+	}
+	if len(completed) == 0 {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	next = s.currentLocked(flags)
+	snapshot := copyFlags(flags)
+	s.mu.Unlock()
+
+	if err := s.store.SaveTutorialFlags(playerID, snapshot); err != nil {
+		utils.LogWarnf("Onboarding: Failed to save tutorial progress for %s: %v", playerID, err)
+	}
+	utils.LogInfof("Onboarding: Player %s completed tutorial step(s) %v.", playerID, completed)
+	return completed, next
+}
+
+// currentLocked returns the first incomplete step whose prerequisites are met.
+func (s *Service) currentLocked(flags map[string]bool) *Prompt {
+	for i, step := range s.def.Steps {
+		if !flags[step.ID] && requirementsMet(step, flags) {
+			return &Prompt{StepID: step.ID, Prompt: step.Prompt, Hint: step.Hint, Index: i, Total: len(s.def.Steps)}
+		}
+	}
+	return nil
+}
+
+func requirementsMet(step Step, flags map[string]bool) bool {
+	for _, required := range step.Requires {
+		if !flags[required] {
+			return false
+		}
+	}
+	return true
+}
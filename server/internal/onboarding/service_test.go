@@ -0,0 +1,60 @@
+package onboarding
+
+import "testing"
+
+func testDefinition(t *testing.T) *Definition {
+	t.Helper()
+	def := &Definition{
+		Steps: []Step{
+			{ID: "join", Prompt: "Join a room", CompleteOn: EventRoomJoined},
+			{ID: "chat", Prompt: "Say hello", CompleteOn: "SEND_CHAT"},
+		},
+		Gates: map[string]string{"PLAYER_ACTION:TRADE": "chat"},
+	}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return def
+}
+
+func TestServiceProgression(t *testing.T) {
+	store := NewMemoryStore()
+	svc := NewService(testDefinition(t), store)
+
+	if prompt := svc.Begin("p1"); prompt == nil || prompt.StepID != "join" {
+		t.Fatalf("first prompt = %+v, want join", prompt)
+	}
+	// Chatting before the prerequisite step is complete does not count.
+	if completed, _ := svc.Record("p1", "SEND_CHAT"); len(completed) != 0 {
+		t.Fatalf("SEND_CHAT completed %v before join", completed)
+	}
+	if ok, required := svc.Allowed("p1", ClientEvent("PLAYER_ACTION", "TRADE")); ok || required.StepID != "chat" {
+		t.Fatalf("trade allowed=%t required=%+v, want gated on chat", ok, required)
+	}
+
+	if completed, next := svc.Record("p1", EventRoomJoined); len(completed) != 1 || next == nil || next.StepID != "chat" {
+		t.Fatalf("after join: completed=%v next=%+v", completed, next)
+	}
+	if completed, next := svc.Record("p1", "SEND_CHAT"); len(completed) != 1 || next != nil {
+		t.Fatalf("after chat: completed=%v next=%+v, want finished", completed, next)
+	}
+	if ok, _ := svc.Allowed("p1", "PLAYER_ACTION:TRADE"); !ok {
+		t.Fatal("trade still gated after tutorial finished")
+	}
+
+	// Progress is persisted and restored on the next session.
+	svc.End("p1")
+	if prompt := svc.Begin("p1"); prompt != nil {
+		t.Fatalf("prompt after reload = %+v, want nil", prompt)
+	}
+}
+
+func TestValidateRejectsForwardReferences(t *testing.T) {
+	def := &Definition{Steps: []Step{
+		{ID: "a", CompleteOn: "X", Requires: []string{"b"}},
+		{ID: "b", CompleteOn: "Y"},
+	}}
+	if err := def.Validate(); err == nil {
+		t.Fatal("expected an error for a step requiring a later step")
+	}
+}
@@ -0,0 +1,824 @@
+// Package orderbook runs an off-chain order book for the game's fungible
+// token, traded for SUI. A player places a limit order and pays its deposit,
+// the tokens to sell or the MIST to buy with, into the server's custody
+// address. Funded orders match by price, then time: a match fills as much as
+// both orders have left at the resting order's price, and custody settles it
+// with one programmable transaction paying the tokens to the buyer and the
+// MIST to the seller. Whatever an order leaves unspent when it is filled,
+// cancelled or expires is refunded.
+package orderbook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Outbox message kinds for custody payouts.
+const (
+	SettleKind = "orderbook.settle" // Payload is a FillJob
+	RefundKind = "orderbook.refund" // Payload is an OrderJob
+)
+
+// SuiCoinType is the coin orders are priced in.
+const SuiCoinType = "0x2::sui::SUI"
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultDepositTTL    = 15 * time.Minute
+	DefaultOrderTTL      = 24 * time.Hour
+	DefaultMaxTTL        = 7 * 24 * time.Hour
+	DefaultMaxOpenOrders = 20
+	DefaultDepthLevels   = 20
+	DefaultTickInterval  = 10 * time.Second
+)
+
+// finishedRetention is how long finished orders are kept for ORDER_UPDATE
+// replays and for deposits that arrive after their deadline.
+const finishedRetention = 24 * time.Hour
+
+// chainClockSkew is how far checkpoint timestamps may trail the server clock.
+// A deposit must have been checkpointed after its order was placed, less this.
+const chainClockSkew = 5 * time.Minute
+
+var (
+	ErrUnknownOrder      = errors.New("unknown order")
+	ErrNotYourOrder      = errors.New("that order is not yours")
+	ErrInvalidOrder      = errors.New("an order needs a side of buy or sell, a price, a quantity and a TTL within the limit")
+	ErrTooManyOrders     = errors.New("you have too many open orders")
+	ErrWrongState        = errors.New("the order cannot do that now")
+	ErrDepositUsed       = errors.New("that transaction already funded an order")
+	ErrDepositUnverified = errors.New("the deposit could not be verified")
+	ErrPayoutDropped     = errors.New("the payout can never execute")
+)
+
+// Chain moves coins in and out of custody. cmd/game adapts sui.SuiClient to it.
+type Chain interface {
+	// BuildDeposit returns unsigned transaction bytes paying amount of
+	// coinType from sender to the custody address.
+	BuildDeposit(ctx context.Context, sender, coinType string, amount uint64) (string, error)
+	// VerifyDeposit checks that txDigest succeeded, was sent by sender,
+	// paid the custody address at least amount of coinType and was
+	// checkpointed no earlier than since.
+	VerifyDeposit(ctx context.Context, txDigest, sender, coinType string, amount uint64, since time.Time) error
+	// SignPayout builds one programmable transaction making every payout
+	// from custody and signs it with the server key, without executing it.
+	SignPayout(ctx context.Context, payouts []Payout) (SignedPayout, error)
+	// SubmitPayout executes a signed payout unless the chain already has it,
+	// so submitting one again never pays twice. An error wrapping
+	// ErrPayoutDropped means it can never execute, and another may be signed
+	// in its place; after any other error it is submitted again.
+	SubmitPayout(ctx context.Context, payout SignedPayout) error
+}
+
+// Payout is one coin payment from custody.
+type Payout struct {
+	Recipient string
+	CoinType  string
+	Amount    uint64
+}
+
+// AddressResolver returns a player's Sui address. accountlink.Service implements it.
+type AddressResolver interface {
+	Address(ctx context.Context, playerID string) (string, error)
+}
+
+// SpendLimit caps the MIST players put into buy orders each day. parental.Service implements it.
+type SpendLimit interface {
+	SpendMist(playerID string, mist uint64) error
+	ReleaseMist(playerID string, mist uint64)
+}
+
+// FillJob is the payload of SettleKind.
+type FillJob struct {
+	FillID string `json:"fillId"`
+}
+
+// OrderJob is the payload of RefundKind.
+type OrderJob struct {
+	OrderID string `json:"orderId"`
+}
+
+// Update is sent to a player whenever one of their orders changes. Fill is
+// set when the change is a fill, or the fill's settlement.
+type Update struct {
+	Order Order
+	Fill  *Fill
+}
+
+// Notifier delivers an *Update to a player's session.
+type Notifier func(note interface{})
+
+// Level is the open quantity at one price.
+type Level struct {
+	Price    uint64
+	Quantity uint64
+	Orders   int
+}
+
+// Depth is the top of the book: bids best (highest) first, asks best (lowest) first.
+type Depth struct {
+	Bids      []Level
+	Asks      []Level
+	LastPrice uint64 // Of the latest fill; 0 before any
+}
+
+// Options configures a Service.
+type Options struct {
+	TokenType     string        // Coin type of the game token
+	DepositTTL    time.Duration // How long a player has to pay an order's deposit
+	DefaultTTL    time.Duration // How long an order stays on the book if the player names no TTL
+	MaxTTL        time.Duration
+	MaxOpenOrders int // Unfinished orders per player
+	DepthLevels   int // Most price levels per side in Depth
+	TickInterval  time.Duration
+}
+
+// Service runs the order book. It is safe for concurrent use by session actors.
+type Service struct {
+	opts      Options
+	store     Store
+	chain     Chain
+	addresses AddressResolver
+	jobs      *outbox.Outbox // Settlements and refunds
+	limit     SpendLimit     // Caps buy order deposits; nil caps nothing
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	pending   map[string]bool     // Deposit transactions being verified
+	notifiers map[string]Notifier // Connected players
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService loads the order book from store. Settlements and refunds are
+// queued in jobs, so they survive restarts and are retried.
+func NewService(opts Options, store Store, chain Chain, addresses AddressResolver, jobs *outbox.Outbox) (*Service, error) {
+	if opts.TokenType == "" {
+		return nil, errors.New("the order book needs the token's coin type")
+	}
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading order book: %w", err)
+	}
+	if state.Orders == nil {
+		state.Orders = make(map[string]Order)
+	}
+	if state.Fills == nil {
+		state.Fills = make(map[string]Fill)
+	}
+	if state.Deposits == nil {
+		state.Deposits = make(map[string]Deposit)
+	}
+	for digest, d := range state.Deposits {
+		if d.At.IsZero() {
+			// Saved before deposits were timed; kept a full retention from now.
+			d.At = time.Now()
+			state.Deposits[digest] = d
+		}
+	}
+	if opts.DepositTTL <= 0 {
+		opts.DepositTTL = DefaultDepositTTL
+	}
+	if opts.DefaultTTL <= 0 {
+		opts.DefaultTTL = DefaultOrderTTL
+	}
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = DefaultMaxTTL
+	}
+	if opts.MaxOpenOrders <= 0 {
+		opts.MaxOpenOrders = DefaultMaxOpenOrders
+	}
+	if opts.DepthLevels <= 0 {
+		opts.DepthLevels = DefaultDepthLevels
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	s := &Service{
+		opts:      opts,
+		store:     store,
+		chain:     chain,
+		addresses: addresses,
+		jobs:      jobs,
+		now:       time.Now,
+		state:     state,
+		pending:   make(map[string]bool),
+		notifiers: make(map[string]Notifier),
+		stop:      make(chan struct{}),
+	}
+	jobs.Handle(SettleKind, s.settle)
+	jobs.Handle(RefundKind, s.refund)
+	return s, nil
+}
+
+// UseSpendLimit holds the deposit of every buy order against its player's
+// daily limit when the order is placed.
+func (s *Service) UseSpendLimit(limit SpendLimit) {
+	s.limit = limit
+}
+
+// spend holds mist against playerID's daily limit, if there is one.
+func (s *Service) spend(playerID string, mist uint64) error {
+	if s.limit == nil {
+		return nil
+	}
+	return s.limit.SpendMist(playerID, mist)
+}
+
+// unspend gives back mist held by spend, for an operation that failed.
+func (s *Service) unspend(playerID string, mist uint64) {
+	if s.limit != nil {
+		s.limit.ReleaseMist(playerID, mist)
+	}
+}
+
+// NewServiceFromConfig creates a Service from configuration, keeping its state in NewFileStore(cfg.StateFile).
+func NewServiceFromConfig(cfg configs.OrderBookConfig, chain Chain, addresses AddressResolver, jobs *outbox.Outbox) (*Service, error) {
+	return NewService(Options{
+		TokenType:     cfg.TokenType,
+		DepositTTL:    time.Duration(cfg.DepositTTLSeconds) * time.Second,
+		DefaultTTL:    time.Duration(cfg.DefaultTTLSeconds) * time.Second,
+		MaxTTL:        time.Duration(cfg.MaxTTLSeconds) * time.Second,
+		MaxOpenOrders: cfg.MaxOpenOrders,
+		DepthLevels:   cfg.DepthLevels,
+	}, NewFileStore(cfg.StateFile), chain, addresses, jobs)
+}
+
+// Start expires orders past their deadline in the background.
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.Tick(now)
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect forgets a player's notifier. Their orders stay on the book.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Place opens an order to buy or sell quantity tokens at price MIST each,
+// which expires ttl after it is placed (the default TTL if zero). The order
+// comes back with the unsigned deposit the player is to sign; it is on the
+// book once Funded confirms the deposit.
+func (s *Service) Place(ctx context.Context, playerID string, side Side, price, quantity uint64, ttl time.Duration) (Order, error) {
+	if ttl == 0 {
+		ttl = s.opts.DefaultTTL
+	}
+	if side != Buy && side != Sell || price == 0 || quantity == 0 || ttl < 0 || ttl > s.opts.MaxTTL {
+		return Order{}, ErrInvalidOrder
+	}
+	deposit := quantity
+	if side == Buy {
+		hi, lo := bits.Mul64(price, quantity)
+		if hi != 0 {
+			return Order{}, ErrInvalidOrder
+		}
+		deposit = lo
+	}
+	address, err := s.addresses.Address(ctx, playerID)
+	if err != nil {
+		return Order{}, fmt.Errorf("your address: %w", err)
+	}
+
+	// Refuse early, before building a deposit; the limit is enforced below,
+	// where the order is inserted under the same lock.
+	s.mu.Lock()
+	full := s.openOrdersLocked(playerID) >= s.opts.MaxOpenOrders
+	s.mu.Unlock()
+	if full {
+		return Order{}, ErrTooManyOrders
+	}
+	var spent uint64 // MIST a buy order pays in
+	if side == Buy {
+		spent = deposit
+	}
+	if err := s.spend(playerID, spent); err != nil {
+		return Order{}, err
+	}
+	txBytes, err := s.chain.BuildDeposit(ctx, address, s.CoinType(side), deposit)
+	if err != nil {
+		s.unspend(playerID, spent)
+		return Order{}, fmt.Errorf("could not build the deposit: %w", err)
+	}
+
+	now := s.now()
+	o := Order{
+		ID:        newOrderID(),
+		PlayerID:  playerID,
+		Address:   address,
+		Side:      side,
+		Price:     price,
+		Quantity:  quantity,
+		Deposit:   deposit,
+		TxBytes:   txBytes,
+		Status:    StatusAwaitingDeposit,
+		PlacedAt:  now,
+		UpdatedAt: now,
+		Deadline:  now.Add(s.opts.DepositTTL),
+		ExpiresAt: now.Add(ttl),
+	}
+	s.mu.Lock()
+	if s.openOrdersLocked(playerID) >= s.opts.MaxOpenOrders {
+		s.mu.Unlock()
+		s.unspend(playerID, spent)
+		return Order{}, ErrTooManyOrders
+	}
+	s.state.Orders[o.ID] = o
+	s.saveLocked()
+	deliveries := s.notifyLocked(o, nil)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("OrderBook: %s placed %s, %s %d at %d MIST; waiting for a deposit of %d.", playerID, o.ID, side, quantity, price, deposit)
+	return o, nil
+}
+
+// Funded checks the deposit playerID paid for an order, reported by its
+// digest, and puts the order on the book, where it matches every resting
+// order it crosses. A deposit for an order that was cancelled or expired
+// meanwhile is refunded.
+func (s *Service) Funded(ctx context.Context, playerID, orderID, txDigest string) (Order, error) {
+	s.mu.Lock()
+	o, err := s.orderLocked(playerID, orderID)
+	if err == nil && o.Deposited {
+		err = ErrWrongState
+	}
+	if err == nil && (s.pending[txDigest] || s.state.Deposits[txDigest].OrderID != "") {
+		err = ErrDepositUsed
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Order{}, err
+	}
+	s.pending[txDigest] = true
+	s.mu.Unlock()
+
+	verifyErr := s.chain.VerifyDeposit(ctx, txDigest, o.Address, s.CoinType(o.Side), o.Deposit, o.PlacedAt.Add(-chainClockSkew))
+
+	s.mu.Lock()
+	delete(s.pending, txDigest)
+	if verifyErr != nil {
+		s.mu.Unlock()
+		return Order{}, fmt.Errorf("%w: %v", ErrDepositUnverified, verifyErr)
+	}
+	if o, err = s.orderLocked(playerID, orderID); err == nil && o.Deposited {
+		err = ErrWrongState
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Order{}, err
+	}
+	s.state.Deposits[txDigest] = Deposit{OrderID: o.ID, At: s.now()}
+	o.Deposited, o.TxBytes = true, ""
+	var deliveries []func()
+	if o.Status == StatusAwaitingDeposit {
+		s.state.NextSeq++
+		o.Seq = s.state.NextSeq
+		o = s.setStatusLocked(o, StatusOpen)
+		deliveries = append(s.notifyLocked(o, nil), s.matchLocked(o.ID)...)
+		o = s.state.Orders[o.ID]
+	} else {
+		o = s.refundLocked(s.setStatusLocked(o, o.Status))
+		deliveries = s.notifyLocked(o, nil)
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("OrderBook: Deposit %s for %s verified (%s, %d of %d filled).", txDigest, o.ID, o.Status, o.Filled, o.Quantity)
+	return o, nil
+}
+
+// Cancel takes one of playerID's orders off the book and refunds what its
+// deposit has left.
+func (s *Service) Cancel(playerID, orderID string) (Order, error) {
+	s.mu.Lock()
+	o, err := s.orderLocked(playerID, orderID)
+	if err == nil && o.Status.Final() {
+		err = ErrWrongState
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Order{}, err
+	}
+	o.TxBytes = ""
+	o = s.refundLocked(s.setStatusLocked(o, StatusCancelled))
+	deliveries := s.notifyLocked(o, nil)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("OrderBook: %s cancelled %s with %d of %d filled.", playerID, o.ID, o.Filled, o.Quantity)
+	return o, nil
+}
+
+// Orders returns playerID's unfinished orders, oldest first.
+func (s *Service) Orders(playerID string) []Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var orders []Order
+	for _, o := range s.state.Orders {
+		if o.PlayerID == playerID && !o.Status.Final() {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].PlacedAt.Before(orders[j].PlacedAt) })
+	return orders
+}
+
+// Depth returns up to levels price levels on each side of the book, at most
+// the configured depth; the configured depth if levels is zero.
+func (s *Service) Depth(levels int) Depth {
+	if levels <= 0 || levels > s.opts.DepthLevels {
+		levels = s.opts.DepthLevels
+	}
+	s.mu.Lock()
+	bids, asks := make(map[uint64]*Level), make(map[uint64]*Level)
+	for _, o := range s.state.Orders {
+		if o.Status != StatusOpen {
+			continue
+		}
+		side := bids
+		if o.Side == Sell {
+			side = asks
+		}
+		level := side[o.Price]
+		if level == nil {
+			level = &Level{Price: o.Price}
+			side[o.Price] = level
+		}
+		level.Quantity += o.Remaining()
+		level.Orders++
+	}
+	depth := Depth{LastPrice: s.state.LastPrice}
+	s.mu.Unlock()
+
+	depth.Bids = topLevels(bids, levels, func(a, b uint64) bool { return a > b })
+	depth.Asks = topLevels(asks, levels, func(a, b uint64) bool { return a < b })
+	return depth
+}
+
+// Tick expires orders past their deadline, refunding their deposits, and
+// forgets finished orders and deposits past the retention.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	var deliveries []func()
+	changed := false
+	for id, o := range s.state.Orders {
+		switch {
+		case o.Status == StatusAwaitingDeposit && !now.Before(o.Deadline),
+			o.Status == StatusOpen && !now.Before(o.ExpiresAt):
+			o.TxBytes = ""
+			o = s.refundLocked(s.setStatusLocked(o, StatusExpired))
+		case o.Status.Final() && o.Unspent() == 0 && now.Sub(o.UpdatedAt) > finishedRetention:
+			delete(s.state.Orders, id)
+			changed = true
+			continue
+		default:
+			continue
+		}
+		utils.LogInfof("OrderBook: %s expired with %d of %d filled.", id, o.Filled, o.Quantity)
+		deliveries = append(deliveries, s.notifyLocked(o, nil)...)
+	}
+	// An order a deposit could be presented for again was placed before it
+	// was checkpointed, so it is gone by the time the deposit is this old;
+	// a newer order rejects it in VerifyDeposit.
+	depositRetention := s.opts.DepositTTL + chainClockSkew + finishedRetention + s.opts.TickInterval
+	for digest, d := range s.state.Deposits {
+		if now.Sub(d.At) > depositRetention {
+			delete(s.state.Deposits, digest)
+			changed = true
+		}
+	}
+	if changed {
+		s.saveLocked()
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// matchLocked fills the open order orderID against the best resting orders
+// it crosses, until it is filled or none is left. An order never fills
+// against another of the same player's.
+func (s *Service) matchLocked(orderID string) []func() {
+	var deliveries []func()
+	for {
+		taker := s.state.Orders[orderID]
+		if taker.Status != StatusOpen || taker.Remaining() == 0 {
+			return deliveries
+		}
+		maker, ok := s.bestLocked(taker)
+		if !ok {
+			return deliveries
+		}
+		buy, sell := taker, maker
+		if taker.Side == Sell {
+			buy, sell = maker, taker
+		}
+		quantity := min(buy.Remaining(), sell.Remaining())
+		fill := Fill{
+			ID:          newID("fill-"),
+			BuyOrderID:  buy.ID,
+			SellOrderID: sell.ID,
+			Buyer:       buy.PlayerID,
+			Seller:      sell.PlayerID,
+			Price:       maker.Price,
+			Quantity:    quantity,
+			BuyerAddr:   buy.Address,
+			SellerAddr:  sell.Address,
+			At:          s.now(),
+		}
+		buy.Filled += quantity
+		buy.Spent += fill.Price * quantity
+		sell.Filled += quantity
+		sell.Spent += quantity
+		s.state.Fills[fill.ID] = fill
+		s.state.LastPrice = fill.Price
+		if _, err := s.jobs.Enqueue(SettleKind+":"+fill.ID, SettleKind, FillJob{FillID: fill.ID}); err != nil {
+			utils.LogErrorf("OrderBook: Could not queue the settlement of %s: %v", fill.ID, err)
+		}
+		for _, o := range []Order{buy, sell} {
+			if o.Remaining() == 0 {
+				o = s.refundLocked(s.setStatusLocked(o, StatusFilled))
+			} else {
+				o = s.setStatusLocked(o, StatusOpen)
+			}
+			deliveries = append(deliveries, s.notifyLocked(o, &fill)...)
+		}
+		utils.LogInfof("OrderBook: %s filled %d at %d MIST between %s (buy %s) and %s (sell %s).", fill.ID, quantity, fill.Price, fill.Buyer, fill.BuyOrderID, fill.Seller, fill.SellOrderID)
+	}
+}
+
+// bestLocked returns the resting order taker fills against first: the best
+// price it crosses, then the oldest.
+func (s *Service) bestLocked(taker Order) (Order, bool) {
+	var best Order
+	found := false
+	for _, o := range s.state.Orders {
+		if o.Status != StatusOpen || o.Side == taker.Side || o.PlayerID == taker.PlayerID || o.Remaining() == 0 {
+			continue
+		}
+		crosses, better := o.Price <= taker.Price, o.Price < best.Price
+		if taker.Side == Sell {
+			crosses, better = o.Price >= taker.Price, o.Price > best.Price
+		}
+		if crosses && (!found || better || o.Price == best.Price && o.Seq < best.Seq) {
+			best, found = o, true
+		}
+	}
+	return best, found
+}
+
+// refundLocked queues the refund of what o's deposit has left, if anything.
+func (s *Service) refundLocked(o Order) Order {
+	if o.Unspent() == 0 {
+		return o
+	}
+	if _, err := s.jobs.Enqueue(RefundKind+":"+o.ID, RefundKind, OrderJob{OrderID: o.ID}); err != nil {
+		utils.LogErrorf("OrderBook: Could not queue the refund of %s: %v", o.ID, err)
+	}
+	return o
+}
+
+// settle handles SettleKind: custody pays the tokens to the buyer and the
+// MIST to the seller in one transaction. The transaction is signed and saved
+// with the fill before it is submitted, so a retry after a crash or an
+// unanswered submit submits the same one, which the chain runs at most once.
+func (s *Service) settle(ctx context.Context, payload json.RawMessage) error {
+	var job FillJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	fill, ok := s.state.Fills[job.FillID]
+	s.mu.Unlock()
+	if !ok {
+		utils.LogWarnf("OrderBook: Dropping settlement of %s, which is already settled.", job.FillID)
+		return nil
+	}
+	if fill.Payout == nil {
+		payout, err := s.chain.SignPayout(ctx, []Payout{
+			{Recipient: fill.BuyerAddr, CoinType: s.opts.TokenType, Amount: fill.Quantity},
+			{Recipient: fill.SellerAddr, CoinType: SuiCoinType, Amount: fill.Price * fill.Quantity},
+		})
+		if err != nil {
+			return err
+		}
+		fill.Payout = &payout
+		if err := s.saveFill(fill); err != nil {
+			return err
+		}
+	}
+	if err := s.chain.SubmitPayout(ctx, *fill.Payout); err != nil {
+		if errors.Is(err, ErrPayoutDropped) {
+			// It never paid, so the retry signs a new one.
+			fill.Payout = nil
+			s.saveFill(fill)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.state.Fills, fill.ID)
+	s.saveLocked()
+	fill.TxDigest = fill.Payout.Digest
+	var deliveries []func()
+	for _, id := range []string{fill.BuyOrderID, fill.SellOrderID} {
+		if o, ok := s.state.Orders[id]; ok {
+			deliveries = append(deliveries, s.notifyLocked(o, &fill)...)
+		}
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("OrderBook: %s settled in %s.", fill.ID, fill.TxDigest)
+	return nil
+}
+
+// refund handles RefundKind: custody returns what an order's deposit has
+// left. Like a settlement, the refund is signed and saved before it is
+// submitted.
+func (s *Service) refund(ctx context.Context, payload json.RawMessage) error {
+	var job OrderJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	o, ok := s.state.Orders[job.OrderID]
+	s.mu.Unlock()
+	if !ok || o.Unspent() == 0 {
+		utils.LogWarnf("OrderBook: Dropping refund of %s, which has nothing left to refund.", job.OrderID)
+		return nil
+	}
+	if o.RefundPayout == nil {
+		amount := o.Unspent()
+		payout, err := s.chain.SignPayout(ctx, []Payout{{Recipient: o.Address, CoinType: s.CoinType(o.Side), Amount: amount}})
+		if err != nil {
+			return err
+		}
+		if o, err = s.saveRefund(o.ID, amount, &payout); err != nil {
+			return err
+		}
+	}
+	if err := s.chain.SubmitPayout(ctx, *o.RefundPayout); err != nil {
+		if errors.Is(err, ErrPayoutDropped) {
+			s.saveRefund(o.ID, 0, nil)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	o = s.state.Orders[o.ID]
+	amount, digest := o.Refunding, o.RefundPayout.Digest
+	o.Refunded += amount
+	o.RefundTx = digest
+	o.Refunding, o.RefundPayout = 0, nil
+	o = s.setStatusLocked(o, o.Status)
+	deliveries := s.notifyLocked(o, nil)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("OrderBook: Refunded %d of %s's deposit to %s in %s.", amount, o.ID, o.PlayerID, digest)
+	return nil
+}
+
+// saveFill stores fill and saves the book. A payout is only submitted once
+// it is saved.
+func (s *Service) saveFill(fill Fill) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Fills[fill.ID] = fill
+	if err := s.store.SaveState(s.state); err != nil {
+		return fmt.Errorf("saving the payout of %s: %w", fill.ID, err)
+	}
+	return nil
+}
+
+// saveRefund records the signed refund of amount of orderID's deposit, or
+// clears it when payout is nil, and saves the book.
+func (s *Service) saveRefund(orderID string, amount uint64, payout *SignedPayout) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.state.Orders[orderID]
+	o.Refunding, o.RefundPayout = amount, payout
+	s.state.Orders[orderID] = o
+	if err := s.store.SaveState(s.state); err != nil {
+		return o, fmt.Errorf("saving the refund of %s: %w", orderID, err)
+	}
+	return o, nil
+}
+
+// openOrdersLocked counts playerID's unfinished orders.
+func (s *Service) openOrdersLocked(playerID string) int {
+	open := 0
+	for _, o := range s.state.Orders {
+		if o.PlayerID == playerID && !o.Status.Final() {
+			open++
+		}
+	}
+	return open
+}
+
+func (s *Service) orderLocked(playerID, orderID string) (Order, error) {
+	o, ok := s.state.Orders[orderID]
+	if !ok {
+		return Order{}, ErrUnknownOrder
+	}
+	if o.PlayerID != playerID {
+		return Order{}, ErrNotYourOrder
+	}
+	return o, nil
+}
+
+// setStatusLocked stores o with status and saves the state.
+func (s *Service) setStatusLocked(o Order, status Status) Order {
+	o.Status = status
+	o.UpdatedAt = s.now()
+	s.state.Orders[o.ID] = o
+	s.saveLocked()
+	return o
+}
+
+// notifyLocked returns the notification of o's player to deliver once the lock is released.
+func (s *Service) notifyLocked(o Order, fill *Fill) []func() {
+	notify := s.notifiers[o.PlayerID]
+	if notify == nil {
+		return nil
+	}
+	note := &Update{Order: o, Fill: fill}
+	return []func(){func() { notify(note) }}
+}
+
+// CoinType is what an order on side deposits: the token to sell, or SUI to
+// buy with.
+func (s *Service) CoinType(side Side) string {
+	if side == Sell {
+		return s.opts.TokenType
+	}
+	return SuiCoinType
+}
+
+func (s *Service) saveLocked() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("OrderBook: Could not save the order book: %v", err)
+	}
+}
+
+// topLevels returns the first n levels in the order of before.
+func topLevels(levels map[uint64]*Level, n int, before func(a, b uint64) bool) []Level {
+	sorted := make([]Level, 0, len(levels))
+	for _, level := range levels {
+		sorted = append(sorted, *level)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return before(sorted[i].Price, sorted[j].Price) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func deliver(deliveries []func()) {
+	for _, d := range deliveries {
+		d()
+	}
+}
+
+func newOrderID() string {
+	return newID("ord-")
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
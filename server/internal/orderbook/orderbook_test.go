@@ -0,0 +1,443 @@
+package orderbook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+)
+
+const token = "0xpkg::game_coin::GAME_COIN"
+
+type addressBook map[string]string
+
+func (b addressBook) Address(ctx context.Context, playerID string) (string, error) {
+	if address, ok := b[playerID]; ok {
+		return address, nil
+	}
+	return "", errors.New("not linked")
+}
+
+// fakeChain accepts any deposit it built and records custody payouts. A
+// signed payout runs once, however often it is submitted.
+type fakeChain struct {
+	payouts    []string                 // As "recipient amount coin"
+	signed     int                      // Payouts signed
+	landed     map[string]bool          // Digests of the payouts that ran
+	building   func()                   // Called while a deposit is built, if set
+	depositAt  time.Time                // When deposits were checkpointed, if set
+	submitting func(SignedPayout) error // Called before a payout runs, if set; an error fails the submit
+	lost       bool                     // Payouts run, but the submit does not hear back
+}
+
+func (f *fakeChain) BuildDeposit(ctx context.Context, sender, coinType string, amount uint64) (string, error) {
+	if f.building != nil {
+		f.building()
+	}
+	return fmt.Sprintf("%s %d %s", sender, amount, coinType), nil
+}
+
+func (f *fakeChain) VerifyDeposit(ctx context.Context, txDigest, sender, coinType string, amount uint64, since time.Time) error {
+	if txDigest != fmt.Sprintf("%s %d %s", sender, amount, coinType) {
+		return errors.New("no such deposit")
+	}
+	if !f.depositAt.IsZero() && f.depositAt.Before(since) {
+		return errors.New("deposit predates the order")
+	}
+	return nil
+}
+
+func (f *fakeChain) SignPayout(ctx context.Context, payouts []Payout) (SignedPayout, error) {
+	var described []string
+	for _, p := range payouts {
+		coin := "SUI"
+		if p.CoinType == token {
+			coin = "GAME"
+		}
+		described = append(described, fmt.Sprintf("%s %d %s", p.Recipient, p.Amount, coin))
+	}
+	f.signed++
+	return SignedPayout{TxBytes: strings.Join(described, ";"), Signature: "sig", Digest: fmt.Sprintf("0xtx%d", f.signed)}, nil
+}
+
+func (f *fakeChain) SubmitPayout(ctx context.Context, payout SignedPayout) error {
+	if f.landed[payout.Digest] {
+		return nil
+	}
+	if f.submitting != nil {
+		if err := f.submitting(payout); err != nil {
+			return err
+		}
+	}
+	if f.landed == nil {
+		f.landed = make(map[string]bool)
+	}
+	f.landed[payout.Digest] = true
+	f.payouts = append(f.payouts, strings.Split(payout.TxBytes, ";")...)
+	if f.lost {
+		return errors.New("context deadline exceeded")
+	}
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *fakeChain, *outbox.MemoryStore) {
+	t.Helper()
+	chain, jobs := &fakeChain{}, outbox.NewMemoryStore()
+	s, err := NewService(Options{TokenType: token, DepositTTL: time.Minute, MaxOpenOrders: 2},
+		&MemoryStore{}, chain, addressBook{"alice": "0xa", "bob": "0xb", "carol": "0xc"}, outbox.New(jobs, outbox.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, chain, jobs
+}
+
+// place places an order and funds it with the deposit it asks for.
+func place(t *testing.T, s *Service, playerID string, side Side, price, quantity uint64) Order {
+	t.Helper()
+	o, err := s.Place(context.Background(), playerID, side, price, quantity, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, err = s.Funded(context.Background(), playerID, o.ID, o.TxBytes); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// runJobs delivers every queued settlement and refund as the outbox would.
+func runJobs(t *testing.T, s *Service, store *outbox.MemoryStore) {
+	t.Helper()
+	messages, err := store.Due(time.Now().Add(time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers := map[string]outbox.Handler{SettleKind: s.settle, RefundKind: s.refund}
+	for _, msg := range messages {
+		if err := handlers[msg.Kind](context.Background(), msg.Payload); err != nil {
+			t.Fatalf("%s: %v", msg.Kind, err)
+		}
+		store.Delete(msg.ID)
+	}
+}
+
+func TestOrdersMatchByPriceThenTime(t *testing.T) {
+	s, chain, jobs := newTestService(t)
+	var bobFills []uint64
+	s.Connect("bob", func(note interface{}) {
+		if fill := note.(*Update).Fill; fill != nil && fill.TxDigest == "" {
+			bobFills = append(bobFills, fill.Quantity)
+		}
+	})
+
+	sell := place(t, s, "alice", Sell, 5, 100)
+	if sell.Status != StatusOpen || sell.Deposit != 100 {
+		t.Fatalf("sell = %+v", sell)
+	}
+	if own := place(t, s, "alice", Buy, 6, 10); own.Filled != 0 {
+		t.Fatalf("alice's buy filled against her own sell: %+v", own)
+	}
+	// Bob's buy at 7 fills at alice's price; the 2 MIST per token he offered above it come back.
+	buy := place(t, s, "bob", Buy, 7, 60)
+	if buy.Status != StatusFilled || buy.Spent != 300 || buy.Unspent() != 120 {
+		t.Fatalf("bob's buy = %+v", buy)
+	}
+	// Carol's buy takes the rest of alice's sell and rests with what is left.
+	carol := place(t, s, "carol", Buy, 6, 50)
+	if carol.Status != StatusOpen || carol.Filled != 40 {
+		t.Fatalf("carol's buy = %+v", carol)
+	}
+	if !reflect.DeepEqual(bobFills, []uint64{60}) {
+		t.Fatalf("bob was told of fills %v", bobFills)
+	}
+	depth := s.Depth(0)
+	wantBids := []Level{{Price: 6, Quantity: 10 + 10, Orders: 2}}
+	if !reflect.DeepEqual(depth.Bids, wantBids) || len(depth.Asks) != 0 || depth.LastPrice != 5 {
+		t.Fatalf("depth = %+v", depth)
+	}
+
+	runJobs(t, s, jobs)
+	want := map[string]bool{
+		"0xb 60 GAME": true, "0xa 300 SUI": true, // Bob's fill
+		"0xb 120 SUI": true,                      // Bob's refund
+		"0xc 40 GAME": true, "0xa 200 SUI": true, // Carol's fill
+	}
+	got := make(map[string]bool)
+	for _, p := range chain.payouts {
+		got[p] = true
+	}
+	if !reflect.DeepEqual(got, want) || len(chain.payouts) != len(want) {
+		t.Fatalf("payouts = %v", chain.payouts)
+	}
+	if len(s.state.Fills) != 0 {
+		t.Fatalf("unsettled fills left: %v", s.state.Fills)
+	}
+
+	// A deposit funds one order only.
+	o, _ := s.Place(context.Background(), "bob", Sell, 9, 1, 0)
+	if _, err := s.Funded(context.Background(), "bob", o.ID, "0xa 300 SUI"); !errors.Is(err, ErrDepositUnverified) {
+		t.Fatalf("funding with another deposit: %v", err)
+	}
+	if _, err := s.Funded(context.Background(), "bob", o.ID, o.TxBytes); err != nil {
+		t.Fatal(err)
+	}
+	o2, _ := s.Place(context.Background(), "bob", Sell, 9, 1, 0)
+	if _, err := s.Funded(context.Background(), "bob", o2.ID, o.TxBytes); !errors.Is(err, ErrDepositUsed) {
+		t.Fatalf("funding twice with one deposit: %v", err)
+	}
+}
+
+func TestOrdersExpireAndAreRefunded(t *testing.T) {
+	s, chain, jobs := newTestService(t)
+	ctx := context.Background()
+	clock := time.Now()
+	s.now = func() time.Time { return clock }
+
+	for _, bad := range []struct {
+		side            Side
+		price, quantity uint64
+		ttl             time.Duration
+	}{{"hold", 1, 1, 0}, {Buy, 0, 1, 0}, {Sell, 1, 0, 0}, {Buy, 1, 1, 30 * 24 * time.Hour}, {Buy, 1 << 40, 1 << 40, 0}} {
+		if _, err := s.Place(ctx, "alice", bad.side, bad.price, bad.quantity, bad.ttl); !errors.Is(err, ErrInvalidOrder) {
+			t.Fatalf("placing %+v: %v", bad, err)
+		}
+	}
+	if _, err := s.Place(ctx, "dave", Buy, 1, 1, 0); err == nil {
+		t.Fatal("a player without an address placed an order")
+	}
+
+	unfunded, _ := s.Place(ctx, "alice", Sell, 5, 10, time.Hour)
+	open := place(t, s, "alice", Buy, 3, 10)
+	if _, err := s.Place(ctx, "alice", Buy, 3, 10, 0); !errors.Is(err, ErrTooManyOrders) {
+		t.Fatalf("a third order: %v", err)
+	}
+	if _, err := s.Cancel("bob", open.ID); !errors.Is(err, ErrNotYourOrder) {
+		t.Fatalf("cancelling another's order: %v", err)
+	}
+
+	// The unfunded order lapses after the deposit TTL; its late deposit is returned.
+	clock = clock.Add(2 * time.Minute)
+	s.Tick(clock)
+	if o := s.state.Orders[unfunded.ID]; o.Status != StatusExpired {
+		t.Fatalf("unfunded order = %+v", o)
+	}
+	if o, err := s.Funded(ctx, "alice", unfunded.ID, unfunded.TxBytes); err != nil || o.Status != StatusExpired || o.Unspent() != 10 {
+		t.Fatalf("late deposit = %+v, %v", o, err)
+	}
+	// The funded one lasts its TTL, then its whole deposit is refunded.
+	s.Tick(clock.Add(23 * time.Hour))
+	if o := s.state.Orders[open.ID]; o.Status != StatusOpen {
+		t.Fatalf("open order expired early: %+v", o)
+	}
+	s.Tick(clock.Add(24 * time.Hour))
+	runJobs(t, s, jobs)
+	if want := []string{"0xa 10 GAME", "0xa 30 SUI"}; !reflect.DeepEqual(chain.payouts, want) {
+		t.Fatalf("payouts = %v, want %v", chain.payouts, want)
+	}
+	if o := s.state.Orders[open.ID]; o.Status != StatusExpired || o.Unspent() != 0 || o.RefundTx == "" {
+		t.Fatalf("expired order = %+v", o)
+	}
+	if orders := s.Orders("alice"); len(orders) != 0 {
+		t.Fatalf("alice still has orders %+v", orders)
+	}
+
+	// Finished orders are forgotten after the retention.
+	s.Tick(clock.Add(72 * time.Hour))
+	if len(s.state.Orders) != 0 {
+		t.Fatalf("orders left: %v", s.state.Orders)
+	}
+}
+
+func TestOpenOrderLimitHoldsForConcurrentPlaces(t *testing.T) {
+	s, chain, _ := newTestService(t)
+	// Every Place passes the early check before any of them inserts its order.
+	const places = 3
+	var building sync.WaitGroup
+	building.Add(places)
+	chain.building = func() {
+		building.Done()
+		building.Wait()
+	}
+
+	errs := make(chan error, places)
+	for i := 0; i < places; i++ {
+		go func() {
+			_, err := s.Place(context.Background(), "alice", Buy, 1, 1, 0)
+			errs <- err
+		}()
+	}
+	placed := 0
+	for i := 0; i < places; i++ {
+		switch err := <-errs; {
+		case err == nil:
+			placed++
+		case !errors.Is(err, ErrTooManyOrders):
+			t.Fatal(err)
+		}
+	}
+	if placed != 2 || len(s.Orders("alice")) != 2 {
+		t.Fatalf("placed %d orders over a limit of 2: %+v", placed, s.Orders("alice"))
+	}
+}
+
+func TestPayoutsRunOnceAcrossRetriesAndRestarts(t *testing.T) {
+	chain, jobs := &fakeChain{}, outbox.NewMemoryStore()
+	store := NewFileStore(filepath.Join(t.TempDir(), "orderbook.json"))
+	open := func() (*Service, map[string]outbox.Handler) {
+		s, err := NewService(Options{TokenType: token, MaxOpenOrders: 2},
+			store, chain, addressBook{"alice": "0xa", "bob": "0xb"}, outbox.New(jobs, outbox.Options{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, map[string]outbox.Handler{SettleKind: s.settle, RefundKind: s.refund}
+	}
+	s, handlers := open()
+	// Bob's buy at 7 fills alice's sell at 5, which queues a settlement and a
+	// refund of his 2 MIST per token over the price.
+	place(t, s, "alice", Sell, 5, 10)
+	buy := place(t, s, "bob", Buy, 7, 10)
+	messages, err := jobs.Due(time.Now().Add(time.Hour), 100)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("queued %d jobs: %v", len(messages), err)
+	}
+
+	// Every payout is saved before it is submitted. Both run, but the
+	// submits do not hear back.
+	chain.lost = true
+	chain.submitting = func(payout SignedPayout) error {
+		saved, err := store.LoadState()
+		if err != nil {
+			return err
+		}
+		savedPayouts := make(map[string]bool)
+		for _, fill := range saved.Fills {
+			if fill.Payout != nil {
+				savedPayouts[fill.Payout.Digest] = true
+			}
+		}
+		if refund := saved.Orders[buy.ID].RefundPayout; refund != nil {
+			savedPayouts[refund.Digest] = true
+		}
+		if !savedPayouts[payout.Digest] {
+			t.Errorf("submitting %s before saving it", payout.Digest)
+		}
+		return nil
+	}
+	for _, msg := range messages {
+		if err := handlers[msg.Kind](context.Background(), msg.Payload); err == nil || errors.Is(err, ErrPayoutDropped) {
+			t.Fatalf("%s without an answer: %v", msg.Kind, err)
+		}
+	}
+
+	// After a restart, the retries find both payouts on chain instead of
+	// paying again.
+	chain.lost, chain.submitting = false, nil
+	s, handlers = open()
+	for _, msg := range messages {
+		if err := handlers[msg.Kind](context.Background(), msg.Payload); err != nil {
+			t.Fatalf("%s after a restart: %v", msg.Kind, err)
+		}
+		jobs.Delete(msg.ID)
+	}
+	if want := []string{"0xb 10 GAME", "0xa 50 SUI", "0xb 20 SUI"}; !reflect.DeepEqual(chain.payouts, want) || chain.signed != 2 {
+		t.Fatalf("payouts = %v from %d signed, want %v", chain.payouts, chain.signed, want)
+	}
+	saved, _ := store.LoadState()
+	if o := saved.Orders[buy.ID]; o.Unspent() != 0 || o.RefundTx != "0xtx2" || o.RefundPayout != nil || len(saved.Fills) != 0 {
+		t.Fatalf("saved after paying: %+v, fills %v", o, saved.Fills)
+	}
+
+	// A payout that can never run is replaced by a new one.
+	chain.submitting = func(SignedPayout) error {
+		chain.submitting = nil
+		return fmt.Errorf("%w: the gas coin moved on", ErrPayoutDropped)
+	}
+	place(t, s, "alice", Sell, 5, 1)
+	place(t, s, "bob", Buy, 5, 1)
+	messages, _ = jobs.Due(time.Now().Add(time.Hour), 100)
+	if err := s.settle(context.Background(), messages[0].Payload); !errors.Is(err, ErrPayoutDropped) {
+		t.Fatalf("settling with a dropped payout: %v", err)
+	}
+	runJobs(t, s, jobs)
+	if want := []string{"0xb 1 GAME", "0xa 5 SUI"}; !reflect.DeepEqual(chain.payouts[3:], want) || chain.signed != 4 {
+		t.Fatalf("payouts = %v from %d signed, want %v more", chain.payouts, chain.signed, want)
+	}
+}
+
+func TestDepositsAreForgottenOnceNoOrderCanTakeThem(t *testing.T) {
+	s, chain, _ := newTestService(t)
+	ctx := context.Background()
+	clock := time.Now()
+	s.now = func() time.Time { return clock }
+	chain.depositAt = clock
+
+	// The fake's deposit digest is its bytes, so a second order for the same
+	// amount is offered the first one's transaction.
+	place(t, s, "alice", Sell, 5, 10)
+	again, _ := s.Place(ctx, "alice", Sell, 5, 10, 0)
+	if _, err := s.Funded(ctx, "alice", again.ID, again.TxBytes); !errors.Is(err, ErrDepositUsed) {
+		t.Fatalf("funding a second order with one deposit: %v", err)
+	}
+	s.Tick(clock.Add(24 * time.Hour))
+	if len(s.state.Deposits) != 1 {
+		t.Fatalf("deposits = %v while an order could take one again", s.state.Deposits)
+	}
+
+	// Once it is forgotten, a newer order still refuses it for its age.
+	clock = clock.Add(2 * 24 * time.Hour)
+	s.Tick(clock)
+	if len(s.state.Deposits) != 0 {
+		t.Fatalf("deposits = %v past their retention", s.state.Deposits)
+	}
+	later, _ := s.Place(ctx, "alice", Sell, 5, 10, 0)
+	if _, err := s.Funded(ctx, "alice", later.ID, again.TxBytes); !errors.Is(err, ErrDepositUnverified) {
+		t.Fatalf("funding a new order with a forgotten deposit: %v", err)
+	}
+
+	// Deposits saved as bare order IDs load with a verification time.
+	store := &MemoryStore{}
+	var state State
+	if err := json.Unmarshal([]byte(`{"deposits": {"0xd": "o1"}}`), &state); err != nil {
+		t.Fatal(err)
+	}
+	store.SaveState(state)
+	old, err := NewService(Options{TokenType: token}, store, chain, addressBook{}, outbox.New(outbox.NewMemoryStore(), outbox.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := old.state.Deposits["0xd"]; d.OrderID != "o1" || d.At.IsZero() {
+		t.Fatalf("old deposit loaded as %+v", d)
+	}
+}
+
+func TestCancelNotifiesOutsideTheLock(t *testing.T) {
+	s, _, _ := newTestService(t)
+	o := place(t, s, "alice", Buy, 3, 10)
+	// A session answering an update asks the book for more, as ORDER_BOOK_REQUEST does.
+	var open []Order
+	s.Connect("alice", func(note interface{}) { open = s.Orders("alice") })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Cancel("alice", o.ID)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancel deadlocked notifying the player")
+	}
+	if len(open) != 0 {
+		t.Fatalf("open orders after cancelling = %+v", open)
+	}
+}
@@ -0,0 +1,175 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/jsonstore"
+)
+
+// Side is whether an order buys or sells the token.
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Status is where an order is in its flow.
+type Status string
+
+const (
+	StatusAwaitingDeposit Status = "awaiting_deposit" // The player is to pay the deposit into custody
+	StatusOpen            Status = "open"             // On the book
+	StatusFilled          Status = "filled"           // Bought or sold in full
+	StatusCancelled       Status = "cancelled"        // Withdrawn by the player
+	StatusExpired         Status = "expired"          // Not deposited or filled in time
+)
+
+// Final reports whether an order in this status is off the book for good.
+func (s Status) Final() bool {
+	return s == StatusFilled || s == StatusCancelled || s == StatusExpired
+}
+
+// Order is one limit order for the game token. Prices are MIST per token
+// unit, quantities token units.
+type Order struct {
+	ID           string        `json:"id"`
+	PlayerID     string        `json:"playerId"`
+	Address      string        `json:"address"` // The player's Sui address; pays the deposit and receives fills and refunds
+	Side         Side          `json:"side"`
+	Price        uint64        `json:"price"`
+	Quantity     uint64        `json:"quantity"`
+	Filled       uint64        `json:"filled"`
+	Deposit      uint64        `json:"deposit"`   // Tokens to sell, or MIST to buy Quantity at Price
+	Spent        uint64        `json:"spent"`     // Part of the deposit paid out for fills
+	Deposited    bool          `json:"deposited"` // Custody holds the deposit
+	Refunded     uint64        `json:"refunded,omitempty"`
+	RefundTx     string        `json:"refundTx,omitempty"`     // Transaction returning the unspent deposit
+	Refunding    uint64        `json:"refunding,omitempty"`    // Being refunded by RefundPayout
+	RefundPayout *SignedPayout `json:"refundPayout,omitempty"` // Signed refund, until it executes
+	TxBytes      string        `json:"txBytes,omitempty"`      // Unsigned deposit for the player, while one is due
+	Status       Status        `json:"status"`
+	Seq          uint64        `json:"seq"` // Time priority among orders at one price
+	PlacedAt     time.Time     `json:"placedAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	Deadline     time.Time     `json:"deadline"`  // For the deposit
+	ExpiresAt    time.Time     `json:"expiresAt"` // The order leaves the book unfilled
+}
+
+// Remaining is the quantity still to fill.
+func (o Order) Remaining() uint64 {
+	return o.Quantity - o.Filled
+}
+
+// Unspent is the part of a deposit custody holds for the player.
+func (o Order) Unspent() uint64 {
+	if !o.Deposited {
+		return 0
+	}
+	return o.Deposit - o.Spent - o.Refunded
+}
+
+// Fill is one match between a buy and a sell order, settled on-chain by a
+// single transaction paying both sides from custody.
+type Fill struct {
+	ID          string        `json:"id"`
+	BuyOrderID  string        `json:"buyOrderId"`
+	SellOrderID string        `json:"sellOrderId"`
+	Buyer       string        `json:"buyer"`
+	Seller      string        `json:"seller"`
+	Price       uint64        `json:"price"` // The resting order's price
+	Quantity    uint64        `json:"quantity"`
+	BuyerAddr   string        `json:"buyerAddr"`  // Receives the tokens
+	SellerAddr  string        `json:"sellerAddr"` // Receives the MIST
+	At          time.Time     `json:"at"`
+	TxDigest    string        `json:"txDigest,omitempty"` // Settlement transaction, once executed
+	Payout      *SignedPayout `json:"payout,omitempty"`   // Signed settlement, until it executes
+}
+
+// SignedPayout is a custody transaction that is signed but not yet known to
+// have executed. It is saved before it is submitted, and its digest is known
+// before then, so a retry looks it up and submits the same one again rather
+// than paying twice.
+type SignedPayout struct {
+	TxBytes   string `json:"txBytes"`
+	Signature string `json:"signature"`
+	Digest    string `json:"digest"`
+}
+
+// Deposit is the order a deposit transaction funded. It is kept until no
+// order it could be presented for again is left.
+type Deposit struct {
+	OrderID string    `json:"orderId"`
+	At      time.Time `json:"at"` // When it was verified
+}
+
+// UnmarshalJSON also reads the bare order IDs that deposits were saved as
+// before they recorded when they were verified.
+func (d *Deposit) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.OrderID); err == nil {
+		return nil
+	}
+	type deposit Deposit
+	return json.Unmarshal(data, (*deposit)(d))
+}
+
+// State is the persisted order book: open and recently finished orders, and
+// fills awaiting settlement.
+type State struct {
+	Orders    map[string]Order   `json:"orders"`
+	Fills     map[string]Fill    `json:"fills"`
+	Deposits  map[string]Deposit `json:"deposits"` // By transaction digest, so no deposit pays twice
+	NextSeq   uint64             `json:"nextSeq"`
+	LastPrice uint64             `json:"lastPrice"` // Of the latest fill
+}
+
+// Store persists the order book.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the order book in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// NewFileStore keeps the order book in a journal at path, each order, fill
+// and deposit its own record: a change to one order appends that order
+// rather than rewriting the book.
+func NewFileStore(path string) *jsonstore.Journal[State] {
+	return jsonstore.NewJournal[State](path, records{})
+}
+
+// records splits the book into its orders, fills and deposits, and a record
+// of its counters.
+type records struct{}
+
+// counters is the record of the book's own fields.
+type counters struct {
+	NextSeq   uint64 `json:"nextSeq"`
+	LastPrice uint64 `json:"lastPrice"`
+}
+
+// Split implements jsonstore.Records.
+func (records) Split(state State) map[string]any {
+	r := map[string]any{"book": counters{NextSeq: state.NextSeq, LastPrice: state.LastPrice}}
+	jsonstore.PutRecords(r, "order/", state.Orders)
+	jsonstore.PutRecords(r, "fill/", state.Fills)
+	jsonstore.PutRecords(r, "deposit/", state.Deposits)
+	return r
+}
+
+// Join implements jsonstore.Records.
+func (records) Join(r map[string]json.RawMessage) (State, error) {
+	var state State
+	var c counters
+	err := jsonstore.TakeRecord(r, "book", &c)
+	state.NextSeq, state.LastPrice = c.NextSeq, c.LastPrice
+	if err == nil {
+		state.Orders, err = jsonstore.TakeRecords[Order](r, "order/")
+	}
+	if err == nil {
+		state.Fills, err = jsonstore.TakeRecords[Fill](r, "fill/")
+	}
+	if err == nil {
+		state.Deposits, err = jsonstore.TakeRecords[Deposit](r, "deposit/")
+	}
+	return state, err
+}
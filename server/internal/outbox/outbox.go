@@ -0,0 +1,261 @@
+// Package outbox delivers side effects, such as on-chain mints, at least once.
+// Callers enqueue a message under an idempotency key; a worker runs the
+// handler registered for its kind and retries failures with backoff until the
+// message is delivered or has used up its attempts.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Message is one queued side effect.
+type Message struct {
+	ID            string          `json:"id"` // Idempotency key; enqueueing an existing ID is a no-op
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	LastError     string          `json:"lastError,omitempty"`
+	Dead          bool            `json:"dead,omitempty"` // Out of attempts; kept for inspection
+}
+
+// Handler delivers a message payload. Handlers must tolerate being called more
+// than once for the same message.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// GiveUpHandler is told about a message that ran out of attempts, with the
+// last error.
+type GiveUpHandler func(payload json.RawMessage, err error)
+
+// Options configures an Outbox. Zero values use the defaults.
+type Options struct {
+	PollInterval time.Duration // Default 2s
+	MaxAttempts  int           // Default 8
+	BaseBackoff  time.Duration // Delay after the first failure, doubled per attempt. Default 5s
+	MaxBackoff   time.Duration // Default 10m
+	BatchSize    int           // Messages per poll. Default 20
+}
+
+// Stats are the outbox counters.
+type Stats struct {
+	Pending   int    `json:"pending"`
+	Dead      int    `json:"dead"`
+	Delivered uint64 `json:"delivered"`
+	Failures  uint64 `json:"failures"`
+}
+
+// Outbox runs registered handlers for stored messages.
+type Outbox struct {
+	store Store
+	opts  Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	giveUps  map[string]GiveUpHandler
+	leader   func() bool // Whether this instance delivers; nil always does
+
+	delivered atomic.Uint64
+	failures  atomic.Uint64
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// New creates an Outbox on store.
+func New(store Store, opts Options) *Outbox {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 5 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	return &Outbox{
+		store:    store,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		giveUps:  make(map[string]GiveUpHandler),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Handle registers the handler for kind.
+func (o *Outbox) Handle(kind string, handler Handler) {
+	o.mu.Lock()
+	o.handlers[kind] = handler
+	o.mu.Unlock()
+}
+
+// OnGiveUp registers the handler called when a message of kind is marked
+// dead, e.g. to tell a player their reward could not be minted.
+func (o *Outbox) OnGiveUp(kind string, handler GiveUpHandler) {
+	o.mu.Lock()
+	o.giveUps[kind] = handler
+	o.mu.Unlock()
+}
+
+// UseLeader makes the worker deliver only while leader reports true, for an
+// outbox shared by several instances. Messages are enqueued either way.
+func (o *Outbox) UseLeader(leader func() bool) {
+	o.mu.Lock()
+	o.leader = leader
+	o.mu.Unlock()
+}
+
+// Enqueue stores a message to be delivered. It reports whether the message was
+// new; a pending or dead message with the same ID is left untouched. Delivered
+// messages are removed, so callers must not re-enqueue completed work.
+func (o *Outbox) Enqueue(id, kind string, payload interface{}) (bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("outbox: encoding %s payload: %w", kind, err)
+	}
+	now := time.Now()
+	return o.store.Add(Message{ID: id, Kind: kind, Payload: data, CreatedAt: now, NextAttemptAt: now})
+}
+
+// Start runs the delivery worker.
+func (o *Outbox) Start() {
+	o.startOnce.Do(func() { go o.run() })
+}
+
+// Stop stops the worker after the delivery in progress, if any.
+func (o *Outbox) Stop() {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+		o.startOnce.Do(func() { close(o.done) }) // Never started
+		<-o.done
+	})
+}
+
+// Stats returns the current counters.
+func (o *Outbox) Stats() Stats {
+	stats := Stats{Delivered: o.delivered.Load(), Failures: o.failures.Load()}
+	messages, _ := o.store.List()
+	for _, msg := range messages {
+		if msg.Dead {
+			stats.Dead++
+		} else {
+			stats.Pending++
+		}
+	}
+	return stats
+}
+
+// List returns the stored messages of kind, pending and dead, oldest first.
+func (o *Outbox) List(kind string) ([]Message, error) {
+	messages, err := o.store.List()
+	if err != nil {
+		return nil, err
+	}
+	matching := messages[:0]
+	for _, msg := range messages {
+		if msg.Kind == kind {
+			matching = append(matching, msg)
+		}
+	}
+	return matching, nil
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(o.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		o.deliverDue()
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue attempts every message that is due.
+func (o *Outbox) deliverDue() {
+	o.mu.RLock()
+	leader := o.leader
+	o.mu.RUnlock()
+	if leader != nil && !leader() {
+		return
+	}
+	due, err := o.store.Due(time.Now(), o.opts.BatchSize)
+	if err != nil {
+		utils.LogErrorf("Outbox: Could not read due messages: %v", err)
+		return
+	}
+	for _, msg := range due {
+		select {
+		case <-o.stop:
+			return
+		default:
+		}
+		o.deliver(msg)
+	}
+}
+
+func (o *Outbox) deliver(msg Message) {
+	o.mu.RLock()
+	handler, ok := o.handlers[msg.Kind]
+	o.mu.RUnlock()
+	if !ok {
+		return // Left pending until a handler is registered
+	}
+	err := handler(context.Background(), msg.Payload)
+	if err == nil {
+		o.delivered.Add(1)
+		if err := o.store.Delete(msg.ID); err != nil {
+			utils.LogErrorf("Outbox: Delivered %s but could not remove it: %v", msg.ID, err)
+		}
+		return
+	}
+	o.failures.Add(1)
+	msg.Attempts++
+	msg.LastError = err.Error()
+	if msg.Attempts >= o.opts.MaxAttempts {
+		msg.Dead = true
+		utils.LogErrorf("Outbox: Giving up on %s (%s) after %d attempts: %v", msg.ID, msg.Kind, msg.Attempts, err)
+		o.mu.RLock()
+		giveUp := o.giveUps[msg.Kind]
+		o.mu.RUnlock()
+		if giveUp != nil {
+			defer giveUp(msg.Payload, err) // After the message is saved dead
+		}
+	} else {
+		msg.NextAttemptAt = time.Now().Add(o.backoff(msg.Attempts))
+		utils.LogWarnf("Outbox: Delivery of %s (%s) failed (attempt %d), retrying at %s: %v",
+			msg.ID, msg.Kind, msg.Attempts, msg.NextAttemptAt.Format(time.RFC3339), err)
+	}
+	if err := o.store.Update(msg); err != nil {
+		utils.LogErrorf("Outbox: Could not save attempt for %s: %v", msg.ID, err)
+	}
+}
+
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.opts.BaseBackoff
+	for i := 1; i < attempts && delay < o.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.opts.MaxBackoff {
+		delay = o.opts.MaxBackoff
+	}
+	return delay
+}
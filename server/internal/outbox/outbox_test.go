@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnqueueIsIdempotent(t *testing.T) {
+	o := New(NewMemoryStore(), Options{})
+	if added, err := o.Enqueue("reward:1", "mint", map[string]string{"to": "a"}); !added || err != nil {
+		t.Fatalf("first enqueue = %v, %v", added, err)
+	}
+	if added, _ := o.Enqueue("reward:1", "mint", map[string]string{"to": "b"}); added {
+		t.Fatal("second enqueue with the same id should be ignored")
+	}
+	if stats := o.Stats(); stats.Pending != 1 {
+		t.Fatalf("pending = %d, want 1", stats.Pending)
+	}
+}
+
+func TestDeliveryRetriesThenGivesUp(t *testing.T) {
+	o := New(NewMemoryStore(), Options{MaxAttempts: 2, BaseBackoff: time.Nanosecond})
+	calls := 0
+	o.Handle("mint", func(ctx context.Context, payload json.RawMessage) error {
+		calls++
+		return errors.New("rpc down")
+	})
+	var gaveUp []string
+	o.OnGiveUp("mint", func(payload json.RawMessage, err error) {
+		gaveUp = append(gaveUp, string(payload)+": "+err.Error())
+	})
+	o.Enqueue("reward:1", "mint", "p1")
+	o.deliverDue()
+	time.Sleep(time.Millisecond)
+	o.deliverDue()
+	o.deliverDue() // Dead messages are not retried
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	if stats := o.Stats(); stats.Dead != 1 || stats.Failures != 2 {
+		t.Fatalf("stats = %+v, want one dead message after two failures", stats)
+	}
+	if len(gaveUp) != 1 || gaveUp[0] != `"p1": rpc down` {
+		t.Fatalf("give-up handler calls = %q", gaveUp)
+	}
+}
+
+func TestFileStoreKeepsPendingMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := New(store, Options{})
+	o.Enqueue("reward:1", "mint", nil)
+	o.Enqueue("reward:2", "mint", nil)
+	o.Handle("mint", func(ctx context.Context, payload json.RawMessage) error { return nil })
+	o.deliver(mustDue(t, store)[0])
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages, _ := reopened.List(); len(messages) != 1 || messages[0].ID != "reward:2" {
+		t.Fatalf("reopened store = %+v, want only reward:2", messages)
+	}
+}
+
+func mustDue(t *testing.T, store Store) []Message {
+	t.Helper()
+	due, err := store.Due(time.Now(), 0)
+	if err != nil || len(due) == 0 {
+		t.Fatalf("due = %v, %v", due, err)
+	}
+	return due
+}
@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists outbox messages.
+type Store interface {
+	// Add stores msg unless a message with the same ID exists; it reports whether msg was added.
+	Add(msg Message) (bool, error)
+	// Due returns up to limit live messages whose next attempt is at or before now, oldest first.
+	Due(now time.Time, limit int) ([]Message, error)
+	Update(msg Message) error
+	Delete(id string) error
+	List() ([]Message, error)
+}
+
+// MemoryStore keeps messages in memory. Pending messages are lost on restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	messages map[string]Message
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{messages: make(map[string]Message)}
+}
+
+// Add implements Store.
+func (m *MemoryStore) Add(msg Message) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.messages[msg.ID]; exists {
+		return false, nil
+	}
+	m.messages[msg.ID] = msg
+	return true, nil
+}
+
+// Due implements Store.
+func (m *MemoryStore) Due(now time.Time, limit int) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []Message
+	for _, msg := range m.messages {
+		if !msg.Dead && !msg.NextAttemptAt.After(now) {
+			due = append(due, msg)
+		}
+	}
+	sortByCreation(due)
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Update implements Store.
+func (m *MemoryStore) Update(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages[msg.ID] = msg
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.messages, id)
+	return nil
+}
+
+// List implements Store.
+func (m *MemoryStore) List() ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Message, 0, len(m.messages))
+	for _, msg := range m.messages {
+		list = append(list, msg)
+	}
+	sortByCreation(list)
+	return list, nil
+}
+
+func sortByCreation(messages []Message) {
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+}
+
+// FileStore is a MemoryStore that rewrites a JSON file after every change, so
+// pending messages survive a restart. It suits low-volume outboxes.
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// OpenFileStore loads the messages saved at path, if any.
+func OpenFileStore(path string) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		store.messages[msg.ID] = msg
+	}
+	return store, nil
+}
+
+// Add implements Store.
+func (f *FileStore) Add(msg Message) (bool, error) {
+	added, _ := f.MemoryStore.Add(msg)
+	if !added {
+		return false, nil
+	}
+	return true, f.save()
+}
+
+// Update implements Store.
+func (f *FileStore) Update(msg Message) error {
+	f.MemoryStore.Update(msg)
+	return f.save()
+}
+
+// Delete implements Store.
+func (f *FileStore) Delete(id string) error {
+	f.MemoryStore.Delete(id)
+	return f.save()
+}
+
+// save writes the messages to a temporary file and renames it over the old one.
+func (f *FileStore) save() error {
+	messages, _ := f.MemoryStore.List()
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
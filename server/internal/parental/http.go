@@ -0,0 +1,106 @@
+package parental
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// StatusView is an account's controls and standing as the admin API shows
+// them. Negative values mean no limit.
+type StatusView struct {
+	PlayerID       string   `json:"playerId"`
+	Restricted     bool     `json:"restricted"`
+	Controls       Controls `json:"controls"`
+	PlaytimeUsed   int64    `json:"playtimeUsedSeconds"`
+	PlaytimeLeft   int64    `json:"playtimeLeftSeconds"`
+	InCurfew       bool     `json:"inCurfew"`
+	CurfewStartsIn int64    `json:"curfewStartsInSeconds"`
+	SpendLeft      int64    `json:"spendLeft"`
+	TradeLeftMist  int64    `json:"tradeLeftMist"`
+}
+
+// View returns the StatusView of playerID.
+func (s *Service) View(playerID string) StatusView {
+	st := s.Status(playerID)
+	return StatusView{
+		PlayerID:       playerID,
+		Restricted:     st.Restricted,
+		Controls:       st.Controls,
+		PlaytimeUsed:   int64(st.PlaytimeUsed.Seconds()),
+		PlaytimeLeft:   seconds(st.PlaytimeLeft),
+		InCurfew:       st.InCurfew,
+		CurfewStartsIn: seconds(st.CurfewIn),
+		SpendLeft:      st.SpendLeft,
+		TradeLeftMist:  st.TradeLeft,
+	}
+}
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is recorded with the controls.
+//
+//	GET  /admin/parental?playerId=   the player's controls and what is left of them today
+//	POST /admin/parental             {"playerId": "...", "controls": {...}} replaces the player's controls
+//	POST /admin/parental/clear       {"playerId": "..."} removes them
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/parental", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method == http.MethodGet {
+			playerID := r.URL.Query().Get("playerId")
+			if playerID == "" {
+				adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId is required"))
+				return
+			}
+			adminhttp.WriteJSON(w, http.StatusOK, s.View(playerID))
+			return
+		}
+		var body struct {
+			PlayerID string   `json:"playerId"`
+			Controls Controls `json:"controls"`
+		}
+		if !decodePost(w, r, &body) {
+			return
+		}
+		if _, err := s.Set(body.PlayerID, body.Controls, operator); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.View(body.PlayerID))
+	}))
+	mux.HandleFunc("/admin/parental/clear", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var body struct {
+			PlayerID string `json:"playerId"`
+		}
+		if !decodePost(w, r, &body) {
+			return
+		}
+		if !s.Clear(body.PlayerID, operator) {
+			adminhttp.WriteError(w, http.StatusNotFound, errors.New("the player has no parental controls"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.View(body.PlayerID))
+	}))
+}
+
+// seconds is d in whole seconds, or -1 for no limit.
+func seconds(d time.Duration) int64 {
+	if d < 0 {
+		return -1
+	}
+	return int64(d.Seconds())
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return false
+	}
+	return true
+}
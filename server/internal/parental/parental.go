@@ -0,0 +1,406 @@
+// Package parental keeps account-level restrictions that a parent or guardian
+// asks operators to set: a daily playtime limit, a nightly curfew, a
+// restricted chat mode and a daily cap on coins spent in shops. Sessions ask
+// the service when the player signs in, check it periodically while they
+// play, and warn the client before a limit is reached.
+//
+// Days and curfews follow the account's time zone, so a limit resets at the
+// player's local midnight.
+package parental
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultCheckInterval = time.Minute
+)
+
+// DefaultWarnBefore are the warnings sent when Options.WarnBefore is empty.
+var DefaultWarnBefore = []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute}
+
+var (
+	ErrInvalid        = errors.New("invalid parental controls")
+	ErrCurfew         = errors.New("parental controls do not allow playing at this time")
+	ErrPlaytimeUsed   = errors.New("today's playtime allowed by parental controls is used up")
+	ErrChatRestricted = errors.New("chat is restricted by parental controls")
+	ErrSpendLimit     = errors.New("that is more than parental controls allow spending today")
+)
+
+// Limits a session can run into.
+const (
+	LimitPlaytime = "playtime"
+	LimitCurfew   = "curfew"
+)
+
+// Controls are the restrictions of one account. Zero values restrict nothing.
+type Controls struct {
+	DailyPlaytimeMinutes int       `json:"dailyPlaytimeMinutes,omitempty"`
+	Curfew               *Curfew   `json:"curfew,omitempty"`
+	TimeZone             string    `json:"timeZone,omitempty"` // IANA name, e.g. Europe/Berlin; UTC if empty
+	ChatRestricted       bool      `json:"chatRestricted,omitempty"`
+	DailySpendLimit      int64     `json:"dailySpendLimit,omitempty"`     // Coins per day in shops
+	DailyTradeLimitMist  int64     `json:"dailyTradeLimitMist,omitempty"` // MIST per day in buy orders, trades and gifts
+	UpdatedBy            string    `json:"updatedBy,omitempty"`
+	UpdatedAt            time.Time `json:"updatedAt,omitempty"`
+}
+
+// Curfew is a daily window in which the player may not play. End may be
+// earlier than Start for a window over midnight.
+type Curfew struct {
+	Start string `json:"start"` // 15:04, local time
+	End   string `json:"end"`
+}
+
+// Status is how an account stands against its controls right now.
+type Status struct {
+	Restricted   bool // The account has controls
+	Controls     Controls
+	PlaytimeUsed time.Duration
+	PlaytimeLeft time.Duration // -1 without a limit
+	InCurfew     bool
+	CurfewIn     time.Duration // Until the next curfew starts; -1 without one
+	SpendLeft    int64         // -1 without a limit
+	TradeLeft    int64         // MIST; -1 without a limit
+}
+
+// TimeLeft is how long the player may keep playing and the limit that ends
+// it, or -1 and "" if nothing does.
+func (st Status) TimeLeft() (time.Duration, string) {
+	if st.InCurfew {
+		return 0, LimitCurfew
+	}
+	left, limit := time.Duration(-1), ""
+	if st.PlaytimeLeft >= 0 {
+		left, limit = st.PlaytimeLeft, LimitPlaytime
+	}
+	if st.CurfewIn >= 0 && (left < 0 || st.CurfewIn < left) {
+		left, limit = st.CurfewIn, LimitCurfew
+	}
+	return left, limit
+}
+
+// Options configures the Service.
+type Options struct {
+	CheckInterval time.Duration   // How often sessions count playtime and check the limits
+	WarnBefore    []time.Duration // Warnings sent as the time left falls below each
+}
+
+// Service keeps the controls. It is safe for concurrent use.
+type Service struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	mu    sync.Mutex
+	state State
+}
+
+// NewService loads the controls from store.
+func NewService(store Store, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load parental controls: %w", err)
+	}
+	if state.Accounts == nil {
+		state.Accounts = make(map[string]*Account)
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultCheckInterval
+	}
+	if len(opts.WarnBefore) == 0 {
+		opts.WarnBefore = DefaultWarnBefore
+	}
+	opts.WarnBefore = append([]time.Duration(nil), opts.WarnBefore...)
+	sort.Slice(opts.WarnBefore, func(i, j int) bool { return opts.WarnBefore[i] > opts.WarnBefore[j] })
+	return &Service{store: store, opts: opts, now: time.Now, state: state}, nil
+}
+
+// CheckInterval is how often sessions should call AddPlaytime and Status.
+func (s *Service) CheckInterval() time.Duration {
+	return s.opts.CheckInterval
+}
+
+// Warning returns the smallest warning threshold at or above left, or 0 if
+// left is above all of them.
+func (s *Service) Warning(left time.Duration) time.Duration {
+	var threshold time.Duration
+	for _, before := range s.opts.WarnBefore {
+		if left <= before {
+			threshold = before
+		}
+	}
+	return threshold
+}
+
+// Set replaces the controls of playerID. operator is recorded with them.
+func (s *Service) Set(playerID string, controls Controls, operator string) (Controls, error) {
+	if playerID == "" {
+		return Controls{}, fmt.Errorf("%w: a player ID is required", ErrInvalid)
+	}
+	if err := controls.validate(); err != nil {
+		return Controls{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	controls.UpdatedBy, controls.UpdatedAt = operator, s.now().UTC()
+	account := s.state.Accounts[playerID]
+	if account == nil {
+		account = &Account{}
+		s.state.Accounts[playerID] = account
+	}
+	account.Controls = controls
+	s.save()
+	utils.LogInfof("Parental controls: %s set the controls of %s.", operator, playerID)
+	return controls, nil
+}
+
+// Clear removes the controls of playerID, and reports whether there were any.
+func (s *Service) Clear(playerID, operator string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.state.Accounts[playerID]; !ok {
+		return false
+	}
+	delete(s.state.Accounts, playerID)
+	s.save()
+	utils.LogInfof("Parental controls: %s cleared the controls of %s.", operator, playerID)
+	return true
+}
+
+// Status returns how playerID stands against their controls.
+func (s *Service) Status(playerID string) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked(playerID, s.now())
+}
+
+// Admit checks whether playerID may sign in now.
+func (s *Service) Admit(playerID string) error {
+	if s == nil {
+		return nil
+	}
+	st := s.Status(playerID)
+	if st.InCurfew {
+		return ErrCurfew
+	}
+	if st.PlaytimeLeft == 0 {
+		return ErrPlaytimeUsed
+	}
+	return nil
+}
+
+// AddPlaytime counts d of play by a restricted playerID towards today.
+func (s *Service) AddPlaytime(playerID string, d time.Duration) {
+	if s == nil || d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.state.Accounts[playerID]
+	if !ok {
+		return
+	}
+	s.usageLocked(account, s.now()).PlaytimeMs += d.Milliseconds()
+	s.save()
+}
+
+// MayChat reports whether playerID may send chat, whispers and voice.
+func (s *Service) MayChat(playerID string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.state.Accounts[playerID]
+	return !ok || !account.Controls.ChatRestricted
+}
+
+// Spend reserves coins against playerID's daily limit. Release them if the
+// purchase fails.
+func (s *Service) Spend(playerID string, coins int64) error {
+	return s.reserve(playerID, coins, spentCoins)
+}
+
+// Release gives back coins reserved by Spend.
+func (s *Service) Release(playerID string, coins int64) {
+	s.release(playerID, coins, spentCoins)
+}
+
+// SpendMist reserves MIST against playerID's daily trade limit: the deposit
+// of a buy order, or the value a player gives in a trade or a gift. Release
+// it if the order, trade or gift fails.
+func (s *Service) SpendMist(playerID string, mist uint64) error {
+	if mist > math.MaxInt64 {
+		return s.reserve(playerID, math.MaxInt64, spentMist)
+	}
+	return s.reserve(playerID, int64(mist), spentMist)
+}
+
+// ReleaseMist gives back MIST reserved by SpendMist.
+func (s *Service) ReleaseMist(playerID string, mist uint64) {
+	if mist > math.MaxInt64 {
+		mist = math.MaxInt64
+	}
+	s.release(playerID, int64(mist), spentMist)
+}
+
+// spentCoins and spentMist pick a daily limit and what was used of it.
+func spentCoins(c Controls, u *Usage) (int64, *int64) { return c.DailySpendLimit, &u.Spent }
+func spentMist(c Controls, u *Usage) (int64, *int64)  { return c.DailyTradeLimitMist, &u.SpentMist }
+
+func (s *Service) reserve(playerID string, amount int64, spent func(Controls, *Usage) (int64, *int64)) error {
+	if s == nil || amount <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.state.Accounts[playerID]
+	if !ok {
+		return nil
+	}
+	limit, used := spent(account.Controls, s.usageLocked(account, s.now()))
+	if limit <= 0 {
+		return nil
+	}
+	if amount > limit-*used {
+		return ErrSpendLimit
+	}
+	*used += amount
+	s.save()
+	return nil
+}
+
+func (s *Service) release(playerID string, amount int64, spent func(Controls, *Usage) (int64, *int64)) {
+	if s == nil || amount <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.state.Accounts[playerID]
+	if !ok {
+		return
+	}
+	_, used := spent(account.Controls, s.usageLocked(account, s.now()))
+	if *used -= amount; *used < 0 {
+		*used = 0
+	}
+	s.save()
+}
+
+func (s *Service) statusLocked(playerID string, now time.Time) Status {
+	st := Status{PlaytimeLeft: -1, CurfewIn: -1, SpendLeft: -1, TradeLeft: -1}
+	account, ok := s.state.Accounts[playerID]
+	if !ok {
+		return st
+	}
+	c := account.Controls
+	st.Restricted, st.Controls = true, c
+	usage := s.usageLocked(account, now)
+	st.PlaytimeUsed = time.Duration(usage.PlaytimeMs) * time.Millisecond
+	if c.DailyPlaytimeMinutes > 0 {
+		st.PlaytimeLeft = time.Duration(c.DailyPlaytimeMinutes)*time.Minute - st.PlaytimeUsed
+		if st.PlaytimeLeft < 0 {
+			st.PlaytimeLeft = 0
+		}
+	}
+	if c.Curfew != nil {
+		st.InCurfew, st.CurfewIn = c.Curfew.check(now.In(c.location()))
+	}
+	if c.DailySpendLimit > 0 {
+		st.SpendLeft = c.DailySpendLimit - usage.Spent
+		if st.SpendLeft < 0 {
+			st.SpendLeft = 0
+		}
+	}
+	if c.DailyTradeLimitMist > 0 {
+		st.TradeLeft = c.DailyTradeLimitMist - usage.SpentMist
+		if st.TradeLeft < 0 {
+			st.TradeLeft = 0
+		}
+	}
+	return st
+}
+
+// usageLocked returns the account's usage for the day of now, starting a new
+// day when the last one is over.
+func (s *Service) usageLocked(account *Account, now time.Time) *Usage {
+	day := now.In(account.Controls.location()).Format("2006-01-02")
+	if account.Usage.Day != day {
+		account.Usage = Usage{Day: day}
+	}
+	return &account.Usage
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Parental controls: Failed to save parental controls: %v", err)
+	}
+}
+
+func (c Controls) validate() error {
+	if c.DailyPlaytimeMinutes < 0 || c.DailyPlaytimeMinutes > 24*60 {
+		return fmt.Errorf("%w: dailyPlaytimeMinutes must be between 0 and 1440", ErrInvalid)
+	}
+	if c.DailySpendLimit < 0 || c.DailyTradeLimitMist < 0 {
+		return fmt.Errorf("%w: spending limits cannot be negative", ErrInvalid)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalid, c.TimeZone)
+		}
+	}
+	if c.Curfew != nil {
+		start, err1 := parseClock(c.Curfew.Start)
+		end, err2 := parseClock(c.Curfew.End)
+		if err1 != nil || err2 != nil || start == end {
+			return fmt.Errorf("%w: curfew needs a different start and end as HH:MM", ErrInvalid)
+		}
+	}
+	return nil
+}
+
+func (c Controls) location() *time.Location {
+	if c.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// check reports whether local is within the curfew and, if not, how long
+// until it starts.
+func (cf *Curfew) check(local time.Time) (bool, time.Duration) {
+	start, _ := parseClock(cf.Start)
+	end, _ := parseClock(cf.End)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	now := local.Sub(midnight)
+	if start < end && now >= start && now < end || start > end && (now >= start || now < end) {
+		return true, 0
+	}
+	next := midnight.Add(start)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location()).Add(start)
+	}
+	return false, next.Sub(local)
+}
+
+// parseClock reads a 15:04 time of day as the time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,139 @@
+package parental
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T, now time.Time) (*Service, *time.Time) {
+	t.Helper()
+	s, err := NewService(&MemoryStore{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := now
+	s.now = func() time.Time { return clock }
+	return s, &clock
+}
+
+func TestPlaytimeLimitResetsAtLocalMidnight(t *testing.T) {
+	s, clock := newTestService(t, time.Date(2026, 3, 2, 20, 0, 0, 0, time.UTC))
+	if _, err := s.Set("kid", Controls{DailyPlaytimeMinutes: 30, TimeZone: "Asia/Tokyo"}, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Admit("kid"); err != nil {
+		t.Fatalf("admit = %v", err)
+	}
+	s.AddPlaytime("kid", 20*time.Minute)
+	if left, limit := s.Status("kid").TimeLeft(); left != 10*time.Minute || limit != LimitPlaytime {
+		t.Fatalf("time left = %v (%s)", left, limit)
+	}
+	if w := s.Warning(10 * time.Minute); w != 15*time.Minute {
+		t.Fatalf("warning = %v", w)
+	}
+	s.AddPlaytime("kid", 15*time.Minute)
+	if err := s.Admit("kid"); !errors.Is(err, ErrPlaytimeUsed) {
+		t.Fatalf("admit after the limit = %v", err)
+	}
+
+	// 20:00 UTC is 05:00 in Tokyo; by 16:00 UTC it is the next day there.
+	*clock = time.Date(2026, 3, 3, 16, 0, 0, 0, time.UTC)
+	if err := s.Admit("kid"); err != nil {
+		t.Fatalf("admit the next day = %v", err)
+	}
+	if st := s.Status("free"); st.Restricted || st.PlaytimeLeft != -1 {
+		t.Fatalf("unrestricted status = %+v", st)
+	}
+}
+
+func TestCurfewOverMidnight(t *testing.T) {
+	s, clock := newTestService(t, time.Date(2026, 3, 2, 20, 30, 0, 0, time.UTC))
+	if _, err := s.Set("kid", Controls{Curfew: &Curfew{Start: "21:00", End: "07:00"}}, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if left, limit := s.Status("kid").TimeLeft(); left != 30*time.Minute || limit != LimitCurfew {
+		t.Fatalf("time left = %v (%s)", left, limit)
+	}
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 3, 6, 59, 0, 0, time.UTC),
+	} {
+		*clock = at
+		if err := s.Admit("kid"); !errors.Is(err, ErrCurfew) {
+			t.Fatalf("admit at %v = %v", at, err)
+		}
+	}
+	*clock = time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC)
+	if err := s.Admit("kid"); err != nil {
+		t.Fatalf("admit after the curfew = %v", err)
+	}
+	if st := s.Status("kid"); st.CurfewIn != 14*time.Hour {
+		t.Fatalf("curfew in %v", st.CurfewIn)
+	}
+}
+
+func TestSpendLimitAndChat(t *testing.T) {
+	s, _ := newTestService(t, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	if _, err := s.Set("kid", Controls{DailySpendLimit: 100, ChatRestricted: true}, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if s.MayChat("kid") || !s.MayChat("free") {
+		t.Fatal("chat restriction not applied to the right player")
+	}
+	if err := s.Spend("kid", 80); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Spend("kid", 30); !errors.Is(err, ErrSpendLimit) {
+		t.Fatalf("spend over the limit = %v", err)
+	}
+	s.Release("kid", 80)
+	if err := s.Spend("kid", 100); err != nil {
+		t.Fatalf("spend after release = %v", err)
+	}
+	if st := s.Status("kid"); st.SpendLeft != 0 || st.TradeLeft != -1 {
+		t.Fatalf("spend left = %d, trade left = %d", st.SpendLeft, st.TradeLeft)
+	}
+	// MIST has its own limit, apart from coins.
+	if err := s.SpendMist("kid", 1<<63); err != nil {
+		t.Fatalf("MIST spent without a limit = %v", err)
+	}
+	s.Set("kid", Controls{DailySpendLimit: 100, DailyTradeLimitMist: 1000, ChatRestricted: true}, "ops")
+	if err := s.SpendMist("kid", 600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SpendMist("kid", 1<<63); !errors.Is(err, ErrSpendLimit) {
+		t.Fatalf("MIST over the limit = %v", err)
+	}
+	s.ReleaseMist("kid", 200)
+	if st := s.Status("kid"); st.TradeLeft != 600 || st.SpendLeft != 0 {
+		t.Fatalf("trade left = %d, spend left = %d", st.TradeLeft, st.SpendLeft)
+	}
+
+	if !s.Clear("kid", "ops") || s.Clear("kid", "ops") {
+		t.Fatal("clear did not report whether there were controls")
+	}
+	var none *Service
+	if none.Admit("kid") != nil || !none.MayChat("kid") || none.Spend("kid", 1) != nil {
+		t.Fatal("a nil service restricts players")
+	}
+}
+
+func TestSetValidatesControls(t *testing.T) {
+	s, _ := newTestService(t, time.Now())
+	for _, c := range []Controls{
+		{DailyPlaytimeMinutes: -1},
+		{DailyPlaytimeMinutes: 24*60 + 1},
+		{DailySpendLimit: -5},
+		{TimeZone: "Mars/Olympus"},
+		{Curfew: &Curfew{Start: "21:00", End: "21:00"}},
+		{Curfew: &Curfew{Start: "9pm", End: "07:00"}},
+	} {
+		if _, err := s.Set("kid", c, "ops"); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("set %+v = %v", c, err)
+		}
+	}
+	if _, err := s.Set("", Controls{}, "ops"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("set without a player = %v", err)
+	}
+}
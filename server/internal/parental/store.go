@@ -0,0 +1,31 @@
+package parental
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted controls and usage of every restricted account.
+type State struct {
+	Accounts map[string]*Account `json:"accounts"` // By player ID
+}
+
+// Account is one player's controls and what they used of them today.
+type Account struct {
+	Controls Controls `json:"controls"`
+	Usage    Usage    `json:"usage"`
+}
+
+// Usage is what a player played and spent on one day of their time zone.
+type Usage struct {
+	Day        string `json:"day"` // 2006-01-02
+	PlaytimeMs int64  `json:"playtimeMs"`
+	Spent      int64  `json:"spent"`               // Coins
+	SpentMist  int64  `json:"spentMist,omitempty"` // In buy orders, trades and gifts
+}
+
+// Store persists the parental controls.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the parental controls in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the parental controls in a JSON file.
+type FileStore = jsonstore.File[State]
@@ -0,0 +1,186 @@
+// Package pathfinding finds paths for NPCs across a zone's map. A map is
+// rasterised once into a navigation grid, searched with A*, and the cell path
+// is smoothed into straight legs between the corners an NPC must turn at.
+// Searches run on a Planner with a per-tick budget, so that NPCs chasing
+// players across a large map cannot starve the room's tick loop, and their
+// results are cached.
+package pathfinding
+
+import (
+	"math"
+	"sync"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// maxCells caps the size of a grid; finer grids of large maps get coarser
+// cells instead.
+const maxCells = 1 << 20
+
+// Grid is the navigation graph of a map: square cells that are walkable if
+// their centre is, connected to their eight neighbours. Diagonal steps may not
+// cut corners. A Grid is read-only and safe for concurrent use.
+type Grid struct {
+	m        *geometry.Map
+	origin   geometry.Vec
+	cell     float64
+	w, h     int
+	walkable []bool
+}
+
+// NewGrid rasterises m into cells of cellSize.
+func NewGrid(m *geometry.Map, cellSize float64) *Grid {
+	width, height := m.Bounds.MaxX-m.Bounds.MinX, m.Bounds.MaxY-m.Bounds.MinY
+	if cellSize <= 0 {
+		cellSize = 1
+	}
+	if cells := (width / cellSize) * (height / cellSize); cells > maxCells {
+		cellSize *= math.Sqrt(cells / maxCells)
+	}
+	g := &Grid{
+		m:      m,
+		origin: geometry.Vec{X: m.Bounds.MinX, Y: m.Bounds.MinY},
+		cell:   cellSize,
+		w:      int(math.Ceil(width / cellSize)),
+		h:      int(math.Ceil(height / cellSize)),
+	}
+	g.walkable = make([]bool, g.w*g.h)
+	for i := range g.walkable {
+		g.walkable[i] = m.Walkable(g.center(i))
+	}
+	return g
+}
+
+// CellSize returns the width of the grid's cells.
+func (g *Grid) CellSize() float64 {
+	return g.cell
+}
+
+// Walkable reports whether p is walkable on the grid's map.
+func (g *Grid) Walkable(p geometry.Vec) bool {
+	return g.m.Walkable(p)
+}
+
+// center returns the middle of cell i.
+func (g *Grid) center(i int) geometry.Vec {
+	return geometry.Vec{
+		X: g.origin.X + (float64(i%g.w)+0.5)*g.cell,
+		Y: g.origin.Y + (float64(i/g.w)+0.5)*g.cell,
+	}
+}
+
+// cellOf returns the cell p is in.
+func (g *Grid) cellOf(p geometry.Vec) (int, bool) {
+	x, y := int(math.Floor((p.X-g.origin.X)/g.cell)), int(math.Floor((p.Y-g.origin.Y)/g.cell))
+	if x < 0 || y < 0 || x >= g.w || y >= g.h {
+		return 0, false
+	}
+	return y*g.w + x, true
+}
+
+// nearestWalkable returns the walkable cell closest to p, searching rings of
+// cells up to radius away from p's own cell.
+func (g *Grid) nearestWalkable(p geometry.Vec, radius int) (int, bool) {
+	x, y := int(math.Floor((p.X-g.origin.X)/g.cell)), int(math.Floor((p.Y-g.origin.Y)/g.cell))
+	for r := 0; r <= radius; r++ {
+		best, bestDist := -1, math.Inf(1)
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if max(abs(dx), abs(dy)) != r {
+					continue // Inner rings were searched already
+				}
+				cx, cy := x+dx, y+dy
+				if cx < 0 || cy < 0 || cx >= g.w || cy >= g.h || !g.walkable[cy*g.w+cx] {
+					continue
+				}
+				if d := g.center(cy*g.w + cx).Dist(p); d < bestDist {
+					best, bestDist = cy*g.w+cx, d
+				}
+			}
+		}
+		if best >= 0 {
+			return best, true
+		}
+	}
+	return 0, false
+}
+
+// neighbours calls f with each cell reachable in one step from i and the cost
+// of the step in cells.
+func (g *Grid) neighbours(i int, f func(n int, cost float64)) {
+	x, y := i%g.w, i/g.w
+	open := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < g.w && y < g.h && g.walkable[y*g.w+x]
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if (dx == 0 && dy == 0) || !open(x+dx, y+dy) {
+				continue
+			}
+			if dx != 0 && dy != 0 {
+				if !open(x+dx, y) || !open(x, y+dy) {
+					continue // Cutting a corner
+				}
+				f((y+dy)*g.w+x+dx, math.Sqrt2)
+				continue
+			}
+			f((y+dy)*g.w+x+dx, 1)
+		}
+	}
+}
+
+// heuristic is the octile distance between cells a and b, which never
+// overestimates the cost on an eight-connected grid.
+func (g *Grid) heuristic(a, b int) float64 {
+	dx, dy := float64(abs(a%g.w-b%g.w)), float64(abs(a/g.w-b/g.w))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
+
+// clear reports whether the straight line from a to b stays on walkable
+// ground of the map itself, not just of the grid.
+func (g *Grid) clear(a, b geometry.Vec) bool {
+	n := int(math.Ceil(a.Dist(b) / (g.cell / 2)))
+	for i := 1; i <= n; i++ {
+		if !g.m.Walkable(a.Lerp(b, float64(i)/float64(n))) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Library builds the grids of maps once and shares them between rooms. It is
+// safe for concurrent use.
+type Library struct {
+	cellSize float64
+
+	mu    sync.Mutex
+	grids map[*geometry.Map]*Grid
+}
+
+// NewLibrary creates a Library building grids with cells of cellSize.
+func NewLibrary(cellSize float64) *Library {
+	return &Library{cellSize: cellSize, grids: make(map[*geometry.Map]*Grid)}
+}
+
+// Grid returns m's grid, building it on first use. It returns nil for a nil
+// m, which Planners treat as open ground, or on a nil Library.
+func (l *Library) Grid(m *geometry.Map) *Grid {
+	if l == nil || m == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	g, ok := l.grids[m]
+	if !ok {
+		g = NewGrid(m, l.cellSize)
+		l.grids[m] = g
+	}
+	return g
+}
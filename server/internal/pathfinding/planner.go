@@ -0,0 +1,246 @@
+package pathfinding
+
+import (
+	"container/heap"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// DefaultBudget is the number of cells a Planner expands per Step if it is
+// not given a budget.
+const DefaultBudget = 2000
+
+// minSlice is the least a search expands when it gets its turn in a Step, so
+// that many pending searches still make progress.
+const minSlice = 64
+
+// cacheSize is the number of cell paths a Planner keeps.
+const cacheSize = 256
+
+// snapRadius is how many cells around an endpoint in a wall are searched for
+// a walkable cell to start or end at.
+const snapRadius = 3
+
+// Result is the outcome of a path request.
+type Result struct {
+	ID    string
+	Path  []geometry.Vec // Waypoints after the start, ending at the goal
+	Found bool
+}
+
+// Planner runs path searches on a grid within a budget per Step. It belongs
+// to a single goroutine, such as a room actor's. A nil grid is open ground,
+// where every path is a straight line.
+type Planner struct {
+	grid   *Grid
+	budget int
+	queue  []*search          // Pending searches, served round-robin
+	ready  []Result           // Answered without a search, returned by the next Step
+	cache  map[[2]int][]int   // Cell paths by start and goal cell
+	order  [][2]int           // Cache keys, oldest first
+	byID   map[string]*search // Pending searches by requester
+}
+
+// NewPlanner creates a Planner expanding at most budget cells per Step.
+func NewPlanner(grid *Grid, budget int) *Planner {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+	return &Planner{grid: grid, budget: budget, cache: make(map[[2]int][]int), byID: make(map[string]*search)}
+}
+
+// Request asks for a path for id from from to to, replacing id's pending
+// request if it has one. The result comes from a later Step.
+func (p *Planner) Request(id string, from, to geometry.Vec) {
+	p.Cancel(id)
+	if p.grid == nil || p.grid.clear(from, to) {
+		p.ready = append(p.ready, Result{ID: id, Path: []geometry.Vec{to}, Found: true})
+		return
+	}
+	start, okStart := p.grid.nearestWalkable(from, snapRadius)
+	goal, okGoal := p.grid.nearestWalkable(to, snapRadius)
+	if !okStart || !okGoal {
+		p.ready = append(p.ready, Result{ID: id})
+		return
+	}
+	if cells, ok := p.cache[[2]int{start, goal}]; ok {
+		p.ready = append(p.ready, Result{ID: id, Path: p.smooth(from, to, cells), Found: true})
+		return
+	}
+	s := newSearch(p.grid, id, from, to, start, goal)
+	p.queue = append(p.queue, s)
+	p.byID[id] = s
+}
+
+// Cancel drops id's pending request.
+func (p *Planner) Cancel(id string) {
+	s, ok := p.byID[id]
+	if !ok {
+		return
+	}
+	delete(p.byID, id)
+	for i, queued := range p.queue {
+		if queued == s {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			break
+		}
+	}
+}
+
+// Pending returns the number of searches still running.
+func (p *Planner) Pending() int {
+	return len(p.queue)
+}
+
+// Step advances the pending searches by up to the budget's worth of cells and
+// returns the requests that are answered.
+func (p *Planner) Step() []Result {
+	results := p.ready
+	p.ready = nil
+	budget := p.budget
+	for budget > 0 && len(p.queue) > 0 {
+		s := p.queue[0]
+		p.queue = p.queue[1:]
+		slice := max(budget/(len(p.queue)+1), minSlice)
+		used, done := s.run(p.grid, min(slice, budget))
+		budget -= used
+		if !done {
+			p.queue = append(p.queue, s)
+			continue
+		}
+		delete(p.byID, s.id)
+		result := Result{ID: s.id}
+		if cells := s.cells(); cells != nil {
+			p.remember(s.start, s.goal, cells)
+			result.Path, result.Found = p.smooth(s.from, s.to, cells), true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (p *Planner) remember(start, goal int, cells []int) {
+	key := [2]int{start, goal}
+	if _, ok := p.cache[key]; ok {
+		return
+	}
+	if len(p.order) >= cacheSize {
+		delete(p.cache, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.cache[key] = cells
+	p.order = append(p.order, key)
+}
+
+// smooth turns a cell path into waypoints, keeping only the cells where the
+// straight line from the previous waypoint would leave walkable ground. The
+// path ends at to if it is walkable, and at the goal cell otherwise.
+func (p *Planner) smooth(from, to geometry.Vec, cells []int) []geometry.Vec {
+	points := make([]geometry.Vec, 0, len(cells)+1)
+	for _, c := range cells {
+		points = append(points, p.grid.center(c))
+	}
+	if p.grid.Walkable(to) {
+		points = append(points, to)
+	}
+	path := make([]geometry.Vec, 0, 4)
+	anchor := from
+	for i := 1; i < len(points); i++ {
+		if !p.grid.clear(anchor, points[i]) {
+			anchor = points[i-1]
+			path = append(path, anchor)
+		}
+	}
+	return append(path, points[len(points)-1])
+}
+
+// search is the state of one A* search, kept between Steps.
+type search struct {
+	id       string
+	from, to geometry.Vec
+	start    int
+	goal     int
+	open     openSet
+	g        map[int]float64
+	parent   map[int]int
+	closed   map[int]bool
+	found    bool
+}
+
+func newSearch(grid *Grid, id string, from, to geometry.Vec, start, goal int) *search {
+	s := &search{
+		id: id, from: from, to: to, start: start, goal: goal,
+		g: map[int]float64{start: 0}, parent: make(map[int]int), closed: make(map[int]bool),
+	}
+	heap.Push(&s.open, node{cell: start, f: grid.heuristic(start, goal)})
+	return s
+}
+
+// run expands up to limit cells. It returns how many it expanded and whether
+// the search is over.
+func (s *search) run(grid *Grid, limit int) (int, bool) {
+	expanded := 0
+	for expanded < limit {
+		if s.open.Len() == 0 {
+			return expanded, true // Unreachable
+		}
+		current := heap.Pop(&s.open).(node).cell
+		if s.closed[current] {
+			continue
+		}
+		if current == s.goal {
+			s.found = true
+			return expanded, true
+		}
+		s.closed[current] = true
+		expanded++
+		grid.neighbours(current, func(n int, cost float64) {
+			if s.closed[n] {
+				return
+			}
+			g := s.g[current] + cost
+			if known, ok := s.g[n]; ok && known <= g {
+				return
+			}
+			s.g[n], s.parent[n] = g, current
+			heap.Push(&s.open, node{cell: n, f: g + grid.heuristic(n, s.goal)})
+		})
+	}
+	return expanded, false
+}
+
+// cells returns the found path from the start cell to the goal, or nil.
+func (s *search) cells() []int {
+	if !s.found {
+		return nil
+	}
+	var reversed []int
+	for c := s.goal; c != s.start; c = s.parent[c] {
+		reversed = append(reversed, c)
+	}
+	cells := make([]int, 0, len(reversed)+1)
+	cells = append(cells, s.start)
+	for i := len(reversed) - 1; i >= 0; i-- {
+		cells = append(cells, reversed[i])
+	}
+	return cells
+}
+
+type node struct {
+	cell int
+	f    float64
+}
+
+// openSet is a min-heap of nodes by f.
+type openSet []node
+
+func (o openSet) Len() int            { return len(o) }
+func (o openSet) Less(i, j int) bool  { return o[i].f < o[j].f }
+func (o openSet) Swap(i, j int)       { o[i], o[j] = o[j], o[i] }
+func (o *openSet) Push(x interface{}) { *o = append(*o, x.(node)) }
+func (o *openSet) Pop() interface{} {
+	old := *o
+	n := old[len(old)-1]
+	*o = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,101 @@
+package pathfinding
+
+import (
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+// walled is a 30x20 map split by a wall at x=14..16 with a gap at the top,
+// y=17..20.
+var walled = &geometry.Map{
+	ID:        "walled",
+	Bounds:    geometry.Rect{MaxX: 30, MaxY: 20},
+	Spawn:     geometry.Vec{X: 1, Y: 1},
+	Obstacles: []geometry.Obstacle{{Points: geometry.Rect{MinX: 14, MaxX: 16, MaxY: 17}.Polygon()}},
+}
+
+// run steps p until it answers, returning the result and the steps it took.
+func run(t *testing.T, p *Planner) (Result, int) {
+	t.Helper()
+	for steps := 1; steps <= 1000; steps++ {
+		if results := p.Step(); len(results) > 0 {
+			return results[0], steps
+		}
+	}
+	t.Fatal("no result after 1000 steps")
+	return Result{}, 0
+}
+
+func TestPathGoesAroundTheWall(t *testing.T) {
+	p := NewPlanner(NewGrid(walled, 0.5), 0)
+	from, to := geometry.Vec{X: 5, Y: 5}, geometry.Vec{X: 25, Y: 5}
+	p.Request("npc", from, to)
+	result, _ := run(t, p)
+	if !result.Found || result.Path[len(result.Path)-1] != to {
+		t.Fatalf("result = %+v", result)
+	}
+	// Smoothing leaves the corners of the gap, not a waypoint per cell.
+	if len(result.Path) > 4 {
+		t.Errorf("path has %d waypoints: %v", len(result.Path), result.Path)
+	}
+	prev := from
+	for _, p := range result.Path {
+		if !NewGrid(walled, 0.5).clear(prev, p) {
+			t.Errorf("leg %v -> %v crosses the wall", prev, p)
+		}
+		prev = p
+	}
+}
+
+func TestSearchesStayWithinTheBudget(t *testing.T) {
+	grid := NewGrid(walled, 0.25)
+	p := NewPlanner(grid, minSlice)
+	p.Request("npc", geometry.Vec{X: 5, Y: 5}, geometry.Vec{X: 25, Y: 5})
+	result, steps := run(t, p)
+	if !result.Found || steps < 2 {
+		t.Fatalf("found %v in %d steps; want a search spread over several", result.Found, steps)
+	}
+
+	// The same cells again come from the cache, without a search.
+	p.Request("other", geometry.Vec{X: 5.05, Y: 5.05}, geometry.Vec{X: 25, Y: 5})
+	if p.Pending() != 0 {
+		t.Error("a cached path was searched again")
+	}
+	if result, steps := run(t, p); !result.Found || result.ID != "other" || steps != 1 {
+		t.Errorf("cached result %+v after %d steps", result, steps)
+	}
+}
+
+func TestUnreachableGoal(t *testing.T) {
+	sealed := &geometry.Map{
+		Bounds: geometry.Rect{MaxX: 20, MaxY: 20},
+		Spawn:  geometry.Vec{X: 1, Y: 1},
+		Obstacles: []geometry.Obstacle{
+			{Points: geometry.Polygon{{X: 10, Y: 0}, {X: 11, Y: 0}, {X: 11, Y: 20}, {X: 10, Y: 20}}},
+		},
+	}
+	p := NewPlanner(NewGrid(sealed, 1), 0)
+	p.Request("npc", geometry.Vec{X: 5, Y: 5}, geometry.Vec{X: 15, Y: 5})
+	if result, _ := run(t, p); result.Found {
+		t.Errorf("found a path through a sealed wall: %v", result.Path)
+	}
+}
+
+func TestRequestReplacesThePendingOne(t *testing.T) {
+	p := NewPlanner(NewGrid(walled, 0.25), minSlice)
+	p.Request("npc", geometry.Vec{X: 5, Y: 5}, geometry.Vec{X: 25, Y: 5})
+	p.Step()
+	p.Request("npc", geometry.Vec{X: 5, Y: 5}, geometry.Vec{X: 8, Y: 5})
+	if result, _ := run(t, p); result.Path[len(result.Path)-1] != (geometry.Vec{X: 8, Y: 5}) || p.Pending() != 0 {
+		t.Errorf("result = %+v with %d pending; want only the new request", result, p.Pending())
+	}
+}
+
+func TestOpenGroundIsAStraightLine(t *testing.T) {
+	p := NewPlanner(nil, 0)
+	p.Request("npc", geometry.Vec{}, geometry.Vec{X: 100, Y: 100})
+	if result, steps := run(t, p); !result.Found || len(result.Path) != 1 || steps != 1 {
+		t.Errorf("result = %+v after %d steps", result, steps)
+	}
+}
@@ -0,0 +1,68 @@
+package plugins
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the plugins' admin endpoints to mux, and an index of
+// the enabled plugins with the message types and endpoints each one serves.
+// Requests must carry "Authorization: Bearer <adminToken>" and an
+// X-Admin-User header naming the operator.
+//
+//	GET /admin/plugins          the enabled plugins
+//	... /admin/plugins/<name>/  whatever each plugin registered
+//
+// Endpoints plugins registered with HandleAdminRoutes are added as well.
+func (m *Manager) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/plugins", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, m.describe())
+	}))
+	for pattern, handler := range m.admin {
+		mux.HandleFunc(pattern, adminhttp.Only(adminToken, handler))
+	}
+	for _, register := range m.routes {
+		register(mux, adminToken)
+	}
+}
+
+// RegisterDebugHandlers adds the plugins' debug endpoints to mux.
+func (m *Manager) RegisterDebugHandlers(mux *http.ServeMux) {
+	for pattern, handler := range m.debug {
+		mux.HandleFunc(pattern, handler)
+	}
+}
+
+// pluginInfo describes one enabled plugin for GET /admin/plugins.
+type pluginInfo struct {
+	Name      string   `json:"name"`
+	Messages  []string `json:"messages,omitempty"`
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+func (m *Manager) describe() []pluginInfo {
+	infos := make([]pluginInfo, len(m.plugins))
+	for i, p := range m.plugins {
+		infos[i].Name = p.Name
+		for msgType, owner := range m.owners {
+			if owner == p.Name {
+				infos[i].Messages = append(infos[i].Messages, msgType)
+			}
+		}
+		for pattern, owner := range m.endpoints {
+			if owner == p.Name {
+				infos[i].Endpoints = append(infos[i].Endpoints, pattern)
+			}
+		}
+		sort.Strings(infos[i].Messages)
+		sort.Strings(infos[i].Endpoints)
+	}
+	return infos
+}
@@ -0,0 +1,315 @@
+// Package plugins lets optional subsystems, such as analytics, join the game
+// server without main wiring each of them. A plugin package calls Register
+// from its init function and is compiled in by a blank import in
+// server/cmd/game, so a deployment composes its server by which plugin
+// packages it imports, and turns compiled-in plugins off with
+// plugins.disabled.
+//
+// A plugin has hooks at three points of the server's life. Init runs while
+// the core services are wired; there the plugin subscribes to what it needs
+// and registers client message handlers and admin and debug endpoints. Start
+// runs once every plugin is initialized, and Stop at shutdown, after the
+// event bus has delivered its last events. Plugins run in name order, except
+// that a plugin runs after those it names in After.
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/features"
+	"github.com/phuhao00/suigserver/server/internal/health"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Plugin is an optional subsystem. Every hook may be nil.
+type Plugin struct {
+	Name  string   // Unique; names the plugin in the config and its endpoints
+	After []string // Plugins whose Init and Start run first, and Stop last, if they are enabled
+	Init  func(h *Host) error
+	Start func() error
+	Stop  func()
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Plugin)
+)
+
+// Register makes p available to the server. It is meant to be called from
+// init functions, and panics if p has no name or its name is taken.
+func Register(p Plugin) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if p.Name == "" {
+		panic("plugins: Register of a plugin without a name")
+	}
+	if _, ok := registry[p.Name]; ok {
+		panic("plugins: Register called twice for plugin " + p.Name)
+	}
+	registry[p.Name] = p
+}
+
+// Registered returns the plugins compiled in, by name.
+func Registered() []Plugin {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	plugins := make([]Plugin, 0, len(registry))
+	for _, p := range registry {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// Session is the player session a plugin message arrived on. Its methods
+// queue the message on the session, so they may be called from any goroutine.
+type Session interface {
+	PlayerID() string
+	Send(msgType string, payload interface{})
+	SendError(code, message string)
+}
+
+// MessageHandler answers one client message type for authenticated players.
+// It runs on the session's actor, so it must not block; slow work belongs in
+// a goroutine that answers through s.
+type MessageHandler func(s Session, payload json.RawMessage)
+
+// AdminHandler serves an admin endpoint once the request has passed the
+// admin token check. operator is the X-Admin-User header.
+type AdminHandler = adminhttp.Handler
+
+// Host is what a plugin's Init is given: the core services, and the
+// registration of its handlers.
+type Host struct {
+	Config   *configs.Config
+	Settings json.RawMessage // The plugin's entry in plugins.settings; nil if it has none
+	Events   *events.Bus
+	Jobs     *outbox.Outbox // On-chain side effects; register job kinds before the server starts it
+	Features *features.Registry
+	Sui      *sui.SuiClient
+	Health   *health.Monitor // For readiness checks and heartbeats
+
+	plugin  string
+	manager *Manager
+	errs    []string
+}
+
+// HandleMessage routes client messages of msgType to handler. A type the
+// protocol or another plugin already uses fails the plugin's Init. Plugin
+// messages are not in the protocol registry, so clients send them in JSON
+// envelopes.
+func (h *Host) HandleMessage(msgType string, handler MessageHandler) {
+	if _, ok := protocol.LookupMessage(msgType); ok {
+		h.errs = append(h.errs, fmt.Sprintf("message type %s is part of the protocol", msgType))
+		return
+	}
+	if owner, ok := h.manager.owners[msgType]; ok {
+		h.errs = append(h.errs, fmt.Sprintf("message type %s is handled by plugin %s", msgType, owner))
+		return
+	}
+	h.manager.owners[msgType] = h.plugin
+	h.manager.handlers[msgType] = handler
+}
+
+// HandleAdmin serves handler at /admin/plugins/<name><path>, behind the
+// admin token like every other admin endpoint.
+func (h *Host) HandleAdmin(path string, handler AdminHandler) {
+	if pattern, ok := h.endpoint("/admin/plugins/", path); ok {
+		h.manager.admin[pattern] = handler
+	}
+}
+
+// HandleAdminRoutes has register add admin endpoints to the admin mux at
+// paths of their own, behind the admin token it is given, for subsystems
+// whose endpoints predate them being plugins. They are listed in no index.
+func (h *Host) HandleAdminRoutes(register func(mux *http.ServeMux, adminToken string)) {
+	h.manager.routes = append(h.manager.routes, register)
+}
+
+// HandleDebug serves handler at /debug/<name><path>.
+func (h *Host) HandleDebug(path string, handler http.HandlerFunc) {
+	if pattern, ok := h.endpoint("/debug/", path); ok {
+		h.manager.debug[pattern] = handler
+	}
+}
+
+// endpoint claims the plugin's path under prefix. path is empty or starts
+// with a slash.
+func (h *Host) endpoint(prefix, path string) (string, bool) {
+	pattern := prefix + h.plugin + path
+	if path != "" && !strings.HasPrefix(path, "/") {
+		h.errs = append(h.errs, fmt.Sprintf("endpoint path %q does not start with /", path))
+		return "", false
+	}
+	if _, ok := h.manager.endpoints[pattern]; ok {
+		h.errs = append(h.errs, fmt.Sprintf("endpoint %s is registered twice", pattern))
+		return "", false
+	}
+	h.manager.endpoints[pattern] = h.plugin
+	return pattern, true
+}
+
+// Manager runs the enabled plugins and routes to their handlers.
+type Manager struct {
+	plugins  []Plugin // Enabled, in start order
+	settings map[string]json.RawMessage
+	started  int // Plugins started so far, from the first
+
+	owners    map[string]string // Message type -> plugin handling it
+	handlers  map[string]MessageHandler
+	endpoints map[string]string // Admin or debug endpoint -> plugin serving it
+	admin     map[string]AdminHandler
+	routes    []func(mux *http.ServeMux, adminToken string)
+	debug     map[string]http.HandlerFunc
+}
+
+// New picks the plugins of available that cfg does not disable, and orders
+// them. Disabled names that are not available are only logged, since a build
+// may leave plugins out. It panics if two plugins share a name or wait on
+// each other through After.
+func New(cfg configs.PluginsConfig, available []Plugin) *Manager {
+	m := &Manager{
+		settings:  cfg.Settings,
+		owners:    make(map[string]string),
+		handlers:  make(map[string]MessageHandler),
+		endpoints: make(map[string]string),
+		admin:     make(map[string]AdminHandler),
+		debug:     make(map[string]http.HandlerFunc),
+	}
+	disabled := make(map[string]bool)
+	for _, name := range cfg.Disabled {
+		disabled[name] = true
+	}
+	for _, p := range available {
+		if disabled[p.Name] {
+			delete(disabled, p.Name)
+			continue
+		}
+		m.plugins = append(m.plugins, p)
+	}
+	for name := range disabled {
+		utils.LogWarnf("Plugins: %s is disabled but not compiled in.", name)
+	}
+	m.plugins = inOrder(m.plugins)
+	return m
+}
+
+// inOrder sorts plugins by name, then moves each after the plugins it names
+// in After.
+func inOrder(plugins []Plugin) []Plugin {
+	sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	pending := make(map[string]bool, len(plugins))
+	for _, p := range plugins {
+		if pending[p.Name] {
+			panic("plugins: two plugins named " + p.Name)
+		}
+		pending[p.Name] = true
+	}
+	ordered := make([]Plugin, 0, len(plugins))
+	for len(ordered) < len(plugins) {
+		placed := false
+		for _, p := range plugins {
+			if !pending[p.Name] || waiting(p, pending) {
+				continue
+			}
+			ordered = append(ordered, p)
+			delete(pending, p.Name)
+			placed = true
+			break
+		}
+		if !placed {
+			panic(fmt.Sprintf("plugins: %v wait on each other", sortedKeys(pending)))
+		}
+	}
+	return ordered
+}
+
+// waiting reports whether p names a plugin in After that is not placed yet.
+func waiting(p Plugin, pending map[string]bool) bool {
+	for _, name := range p.After {
+		if pending[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Names lists the enabled plugins.
+func (m *Manager) Names() []string {
+	names := make([]string, len(m.plugins))
+	for i, p := range m.plugins {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Init runs each plugin's Init with host, stopping at the first that fails.
+func (m *Manager) Init(host Host) error {
+	for _, p := range m.plugins {
+		if p.Init == nil {
+			continue
+		}
+		h := host
+		h.Settings, h.plugin, h.manager, h.errs = m.settings[p.Name], p.Name, m, nil
+		if err := p.Init(&h); err != nil {
+			return fmt.Errorf("plugin %s: %w", p.Name, err)
+		}
+		if len(h.errs) > 0 {
+			return fmt.Errorf("plugin %s: %s", p.Name, strings.Join(h.errs, "; "))
+		}
+	}
+	return nil
+}
+
+// Start runs each plugin's Start in order. If one fails, the plugins already
+// started are stopped again.
+func (m *Manager) Start() error {
+	for _, p := range m.plugins {
+		if p.Start != nil {
+			if err := p.Start(); err != nil {
+				m.Stop()
+				return fmt.Errorf("plugin %s: %w", p.Name, err)
+			}
+		}
+		m.started++
+	}
+	return nil
+}
+
+// Stop runs the Stop of each started plugin, in reverse order.
+func (m *Manager) Stop() {
+	for ; m.started > 0; m.started-- {
+		if stop := m.plugins[m.started-1].Stop; stop != nil {
+			stop()
+		}
+	}
+}
+
+// Handler returns the plugin handler of msgType. A nil *Manager has none.
+func (m *Manager) Handler(msgType string) (MessageHandler, bool) {
+	if m == nil {
+		return nil, false
+	}
+	handler, ok := m.handlers[msgType]
+	return handler, ok
+}
@@ -0,0 +1,169 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// recorder builds plugins that log their hooks to one list.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) plugin(name string, startErr error) Plugin {
+	return Plugin{
+		Name: name,
+		Init: func(h *Host) error {
+			r.calls = append(r.calls, "init "+name+" "+string(h.Settings))
+			return nil
+		},
+		Start: func() error {
+			r.calls = append(r.calls, "start "+name)
+			return startErr
+		},
+		Stop: func() { r.calls = append(r.calls, "stop "+name) },
+	}
+}
+
+func TestPluginsRunInOrderAndStopInReverse(t *testing.T) {
+	r := &recorder{}
+	cfg := configs.PluginsConfig{
+		Disabled: []string{"seasons", "missing"},
+		Settings: map[string]json.RawMessage{"anticheat": json.RawMessage(`{"strict":true}`)},
+	}
+	m := New(cfg, []Plugin{r.plugin("analytics", nil), r.plugin("anticheat", nil), r.plugin("seasons", nil), {Name: "bare"}})
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"analytics", "anticheat", "bare"}) {
+		t.Fatalf("enabled plugins = %v", names)
+	}
+	if err := m.Init(Host{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.Stop()
+	m.Stop() // A second Stop does nothing
+	want := []string{
+		"init analytics ", `init anticheat {"strict":true}`,
+		"start analytics", "start anticheat",
+		"stop anticheat", "stop analytics",
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("hooks ran as %q, want %q", r.calls, want)
+	}
+
+	// A failed Start stops the plugins started before it.
+	r.calls = nil
+	m = New(configs.PluginsConfig{}, []Plugin{r.plugin("a", nil), r.plugin("b", errors.New("no sink")), r.plugin("c", nil)})
+	if err := m.Start(); err == nil || !strings.Contains(err.Error(), "plugin b") {
+		t.Fatalf("Start = %v, want b's failure", err)
+	}
+	if want := []string{"start a", "start b", "stop a"}; !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("hooks ran as %q, want %q", r.calls, want)
+	}
+}
+
+func TestPluginsRegisterHandlersWithoutConflicts(t *testing.T) {
+	handle := func(msgType string) Plugin {
+		return Plugin{Name: strings.ToLower(msgType), Init: func(h *Host) error {
+			h.HandleMessage(msgType, func(s Session, payload json.RawMessage) {})
+			return nil
+		}}
+	}
+	if err := New(configs.PluginsConfig{}, []Plugin{handle(protocol.MsgTypeSendChat)}).Init(Host{}); err == nil {
+		t.Fatal("a plugin took over a protocol message type")
+	}
+	twice := Plugin{Name: "twice", Init: func(h *Host) error {
+		h.HandleMessage("SEASON_INFO", func(s Session, payload json.RawMessage) {})
+		return nil
+	}}
+	if err := New(configs.PluginsConfig{}, []Plugin{handle("SEASON_INFO"), twice}).Init(Host{}); err == nil {
+		t.Fatal("two plugins handle SEASON_INFO")
+	}
+
+	m := New(configs.PluginsConfig{}, []Plugin{handle("SEASON_INFO"), {Name: "seasons-admin", Init: func(h *Host) error {
+		h.HandleAdmin("/reset", func(w http.ResponseWriter, r *http.Request, operator string) {
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]string{"resetBy": operator})
+		})
+		return nil
+	}}})
+	if err := m.Init(Host{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Handler("SEASON_INFO"); !ok {
+		t.Fatal("SEASON_INFO has no handler")
+	}
+	if _, ok := (*Manager)(nil).Handler("SEASON_INFO"); ok {
+		t.Fatal("a nil manager has a handler")
+	}
+
+	mux := http.NewServeMux()
+	m.RegisterHandlers(mux, "secret")
+	call := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Admin-User", "ops")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := call("/admin/plugins/seasons-admin/reset", "guess"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reset without the admin token = %d", rec.Code)
+	}
+	if rec := call("/admin/plugins/seasons-admin/reset", "secret"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"resetBy":"ops"`) {
+		t.Fatalf("reset = %d %s", rec.Code, rec.Body)
+	}
+	var index []pluginInfo
+	rec := call("/admin/plugins", "secret")
+	if err := json.NewDecoder(rec.Body).Decode(&index); err != nil {
+		t.Fatal(err)
+	}
+	want := []pluginInfo{
+		{Name: "season_info", Messages: []string{"SEASON_INFO"}},
+		{Name: "seasons-admin", Endpoints: []string{"/admin/plugins/seasons-admin/reset"}},
+	}
+	if !reflect.DeepEqual(index, want) {
+		t.Fatalf("GET /admin/plugins = %+v, want %+v", index, want)
+	}
+}
+
+func TestPluginsRunAfterThoseTheyNeed(t *testing.T) {
+	r := &recorder{}
+	after := func(name string, needs ...string) Plugin {
+		p := r.plugin(name, nil)
+		p.After = needs
+		return p
+	}
+	// archive needs leader; orders need parental, and seasons, which is off.
+	m := New(configs.PluginsConfig{Disabled: []string{"seasons"}}, []Plugin{
+		after("orders", "parental", "seasons"), after("archive", "leader"), after("parental"), after("leader"), after("seasons"),
+	})
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"leader", "archive", "parental", "orders"}) {
+		t.Fatalf("plugins in order %v", names)
+	}
+	m = New(configs.PluginsConfig{}, []Plugin{after("orders", "parental"), after("parental")})
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"parental", "orders"}) {
+		t.Fatalf("plugins in order %v", names)
+	}
+	m.Start()
+	m.Stop()
+	if want := []string{"start parental", "start orders", "stop orders", "stop parental"}; !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("hooks ran as %q, want %q", r.calls, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("plugins waiting on each other were ordered")
+		}
+	}()
+	New(configs.PluginsConfig{}, []Plugin{after("a", "b"), after("b", "a")})
+}
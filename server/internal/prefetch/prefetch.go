@@ -0,0 +1,359 @@
+// Package prefetch reads what a player holds on chain when they log in: their
+// coin balances, their profile object and the item NFTs they own. The three
+// reads run at once, so a login costs one round of chain requests instead of
+// many lazy ones later, and their results warm the caches of the services
+// that need them. Login waits a short while for the reads and tells the client
+// which parts are ready.
+package prefetch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults used when Options leaves a field zero.
+const (
+	DefaultWait     = time.Second
+	DefaultTimeout  = 10 * time.Second
+	DefaultCacheTTL = 30 * time.Second
+)
+
+// Parts of a prefetch, as named in Summary.
+const (
+	PartBalances = "balances"
+	PartProfile  = "profile"
+	PartItems    = "items"
+)
+
+// Balance is how much of one coin type an address holds.
+type Balance struct {
+	CoinType string
+	Total    string // Decimal, as the chain reports it
+}
+
+// Profile is the player's profile object.
+type Profile struct {
+	ObjectID string
+	Version  string
+	Fields   map[string]interface{}
+}
+
+// Snapshot is what an address held when it was prefetched. A part that could
+// not be read is left empty and listed in Failed.
+type Snapshot struct {
+	PlayerID  string
+	Address   string
+	Balances  []Balance
+	Profile   *Profile // Nil if the address has none
+	Items     []string // Item types of the owned item NFTs
+	Failed    []string
+	FetchedAt time.Time
+}
+
+// HasFailed reports whether part could not be read.
+func (s Snapshot) HasFailed(part string) bool {
+	for _, name := range s.Failed {
+		if name == part {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary tells the client what was ready when login stopped waiting.
+type Summary struct {
+	Linked   bool     // The player has a primary linked address; nothing is read otherwise
+	Ready    []string // Parts read in time
+	Pending  []string // Parts still being read; their caches warm when they finish
+	Failed   []string
+	Snapshot Snapshot
+}
+
+// Fetchers read the parts of a prefetch. A nil fetcher skips its part.
+type Fetchers struct {
+	Address  func(ctx context.Context, playerID string) (string, error) // Primary linked address; "" if none
+	Balances func(ctx context.Context, address string) ([]Balance, error)
+	Profile  func(ctx context.Context, address string) (*Profile, error)
+	Items    func(ctx context.Context, address string) ([]string, error)
+}
+
+// Options configures a Service.
+type Options struct {
+	Wait     time.Duration // How long Prefetch waits for the reads
+	Timeout  time.Duration // Bound on the reads, which carry on after Wait
+	CacheTTL time.Duration // A player's snapshot is reused for this long, e.g. across a quick reconnect
+}
+
+// Service prefetches players' chain state and caches the snapshots. It is
+// safe for concurrent use.
+type Service struct {
+	fetch   Fetchers
+	opts    Options
+	now     func() time.Time
+	warmers []func(Snapshot)
+
+	mu       sync.Mutex
+	cache    map[string]Snapshot      // By player ID
+	inFlight map[string]chan struct{} // Player ID -> closed when their prefetch finishes
+}
+
+// NewService creates a Service reading through fetch.
+func NewService(fetch Fetchers, opts Options) *Service {
+	if opts.Wait <= 0 {
+		opts.Wait = DefaultWait
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		fetch:    fetch,
+		opts:     opts,
+		now:      time.Now,
+		inFlight: make(map[string]chan struct{}),
+		cache:    make(map[string]Snapshot),
+	}
+}
+
+// OnFetched registers warm to be called with every finished snapshot, e.g.
+// to prime the cache of the NFTs an address owns. Register warmers before
+// the first Prefetch.
+func (s *Service) OnFetched(warm func(Snapshot)) {
+	s.warmers = append(s.warmers, warm)
+}
+
+// Subscribe forgets the snapshots of players whose transactions complete,
+// as their balances and NFTs have changed.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicTxCompleted, "prefetch", func(e events.TxCompleted) {
+		for _, playerID := range e.PlayerIDs {
+			s.Forget(playerID)
+		}
+	})
+}
+
+// Cached returns playerID's snapshot while it is fresh.
+func (s *Service) Cached(playerID string) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.cache[playerID]
+	if !ok || s.now().Sub(snapshot.FetchedAt) >= s.opts.CacheTTL {
+		return Snapshot{}, false
+	}
+	return snapshot, true
+}
+
+// Forget drops playerID's snapshot, e.g. after a transaction changed it.
+func (s *Service) Forget(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, playerID)
+}
+
+// Prefetch reads playerID's chain state, or reuses a fresh snapshot, and
+// waits up to Options.Wait or until ctx ends for it. Parts not read by then
+// are Pending and are cached when they finish. A second Prefetch of a player
+// whose reads are running waits on those reads.
+func (s *Service) Prefetch(ctx context.Context, playerID string) Summary {
+	if snapshot, ok := s.Cached(playerID); ok {
+		return s.summarize(snapshot, nil)
+	}
+	s.mu.Lock()
+	done, running := s.inFlight[playerID]
+	if !running {
+		done = make(chan struct{})
+		s.inFlight[playerID] = done
+	}
+	s.mu.Unlock()
+
+	progress := &progress{done: make(map[string]bool)}
+	if !running {
+		go s.run(playerID, progress, done)
+	}
+	wait := time.NewTimer(s.opts.Wait)
+	defer wait.Stop()
+	select {
+	case <-done:
+		if !running {
+			snapshot, _, _ := progress.state()
+			return s.summarize(snapshot, nil)
+		}
+		if snapshot, ok := s.Cached(playerID); ok {
+			return s.summarize(snapshot, nil)
+		}
+	case <-wait.C:
+	case <-ctx.Done():
+	}
+	if running {
+		return Summary{Linked: true, Pending: s.parts(nil)}
+	}
+	snapshot, started, finished := progress.state()
+	if !started {
+		// Still looking up the address, which is unlikely to be missing.
+		return Summary{Linked: true, Pending: s.parts(nil)}
+	}
+	pending := make(map[string]bool)
+	for _, name := range s.parts(finished) {
+		pending[name] = true
+	}
+	return s.summarize(snapshot, pending)
+}
+
+// parts returns the parts the fetchers read, leaving out those in done.
+func (s *Service) parts(done map[string]bool) []string {
+	var names []string
+	for _, part := range []struct {
+		name       string
+		configured bool
+	}{{PartBalances, s.fetch.Balances != nil}, {PartProfile, s.fetch.Profile != nil}, {PartItems, s.fetch.Items != nil}} {
+		if part.configured && !done[part.name] {
+			names = append(names, part.name)
+		}
+	}
+	return names
+}
+
+// run reads every part at once, caches the snapshot and warms the caches.
+func (s *Service) run(playerID string, progress *progress, done chan struct{}) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inFlight, playerID)
+		s.mu.Unlock()
+		close(done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+
+	address := ""
+	if s.fetch.Address != nil {
+		var err error
+		if address, err = s.fetch.Address(ctx, playerID); err != nil {
+			utils.LogWarnf("Prefetch: Could not look up %s's address: %v", playerID, err)
+			progress.set(func(snapshot *Snapshot) { snapshot.Failed = s.parts(nil) })
+		}
+	}
+	progress.start(playerID, address, s.now())
+	if address != "" {
+		var wg sync.WaitGroup
+		part := func(name string, read func() error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := read()
+				if err != nil {
+					utils.LogWarnf("Prefetch: Could not read the %s of %s: %v", name, address, err)
+				}
+				progress.finish(name, err)
+			}()
+		}
+		if s.fetch.Balances != nil {
+			part(PartBalances, func() error {
+				balances, err := s.fetch.Balances(ctx, address)
+				progress.set(func(snapshot *Snapshot) { snapshot.Balances = balances })
+				return err
+			})
+		}
+		if s.fetch.Profile != nil {
+			part(PartProfile, func() error {
+				profile, err := s.fetch.Profile(ctx, address)
+				progress.set(func(snapshot *Snapshot) { snapshot.Profile = profile })
+				return err
+			})
+		}
+		if s.fetch.Items != nil {
+			part(PartItems, func() error {
+				items, err := s.fetch.Items(ctx, address)
+				progress.set(func(snapshot *Snapshot) { snapshot.Items = items })
+				return err
+			})
+		}
+		wg.Wait()
+	}
+
+	snapshot, _, _ := progress.state()
+	if len(snapshot.Failed) == 0 {
+		// Failed reads are retried at the next login rather than reused.
+		s.mu.Lock()
+		s.cache[playerID] = snapshot
+		s.mu.Unlock()
+	}
+	if address == "" {
+		return
+	}
+	for _, warm := range s.warmers {
+		warm(snapshot)
+	}
+}
+
+// summarize sorts the parts of snapshot into ready, pending and failed.
+func (s *Service) summarize(snapshot Snapshot, pending map[string]bool) Summary {
+	summary := Summary{Linked: snapshot.Address != "" || len(snapshot.Failed) > 0, Snapshot: snapshot}
+	if !summary.Linked {
+		return summary
+	}
+	failed := make(map[string]bool, len(snapshot.Failed))
+	for _, name := range snapshot.Failed {
+		failed[name] = true
+	}
+	for _, name := range s.parts(nil) {
+		switch {
+		case pending[name]:
+			summary.Pending = append(summary.Pending, name)
+		case failed[name]:
+			summary.Failed = append(summary.Failed, name)
+		default:
+			summary.Ready = append(summary.Ready, name)
+		}
+	}
+	return summary
+}
+
+// progress collects the parts of a running prefetch.
+type progress struct {
+	mu      sync.Mutex
+	started bool
+	current Snapshot
+	done    map[string]bool
+}
+
+func (p *progress) start(playerID, address string, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = true
+	p.current.PlayerID, p.current.Address, p.current.FetchedAt = playerID, address, at
+}
+
+func (p *progress) set(update func(*Snapshot)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	update(&p.current)
+}
+
+func (p *progress) finish(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[name] = true
+	if err != nil {
+		p.current.Failed = append(p.current.Failed, name)
+	}
+}
+
+// state returns a copy of the parts read so far, whether the address has
+// been looked up and which parts have finished.
+func (p *progress) state() (Snapshot, bool, map[string]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snapshot := p.current
+	snapshot.Failed = append([]string(nil), p.current.Failed...)
+	done := make(map[string]bool, len(p.done))
+	for name := range p.done {
+		done[name] = true
+	}
+	return snapshot, p.started, done
+}
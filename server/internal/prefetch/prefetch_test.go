@@ -0,0 +1,96 @@
+package prefetch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetchReadsEveryPartAtOnceAndWarmsCaches(t *testing.T) {
+	var started sync.WaitGroup
+	started.Add(3)
+	var reads int32
+	// Each read waits for the other two, so the test hangs unless they run at once.
+	together := func() {
+		atomic.AddInt32(&reads, 1)
+		started.Done()
+		started.Wait()
+	}
+	s := NewService(Fetchers{
+		Address: func(ctx context.Context, playerID string) (string, error) { return "0xa11ce", nil },
+		Balances: func(ctx context.Context, address string) ([]Balance, error) {
+			together()
+			return []Balance{{CoinType: "0x2::sui::SUI", Total: "42"}}, nil
+		},
+		Profile: func(ctx context.Context, address string) (*Profile, error) {
+			together()
+			return &Profile{ObjectID: "0xp1", Version: "3"}, nil
+		},
+		Items: func(ctx context.Context, address string) ([]string, error) {
+			together()
+			return []string{"iron_sword", "red_hat"}, nil
+		},
+	}, Options{Wait: 5 * time.Second})
+	var warmed []Snapshot
+	s.OnFetched(func(snapshot Snapshot) { warmed = append(warmed, snapshot) })
+
+	summary := s.Prefetch(context.Background(), "alice")
+	if !summary.Linked || !reflect.DeepEqual(summary.Ready, []string{PartBalances, PartProfile, PartItems}) || len(summary.Pending)+len(summary.Failed) != 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if summary.Snapshot.Address != "0xa11ce" || summary.Snapshot.Profile.ObjectID != "0xp1" || len(summary.Snapshot.Items) != 2 || summary.Snapshot.Balances[0].Total != "42" {
+		t.Errorf("snapshot = %+v", summary.Snapshot)
+	}
+	if len(warmed) != 1 || warmed[0].Address != "0xa11ce" {
+		t.Errorf("warmed with %+v", warmed)
+	}
+
+	// A reconnect reuses the snapshot.
+	if again := s.Prefetch(context.Background(), "alice"); len(again.Ready) != 3 || atomic.LoadInt32(&reads) != 3 {
+		t.Errorf("second prefetch = %+v after %d reads", again, reads)
+	}
+	if _, ok := s.Cached("alice"); !ok {
+		t.Error("alice's snapshot is not cached")
+	}
+}
+
+func TestSlowAndFailedParts(t *testing.T) {
+	release := make(chan struct{})
+	s := NewService(Fetchers{
+		Address: func(ctx context.Context, playerID string) (string, error) {
+			if playerID == "nobody" {
+				return "", nil
+			}
+			return "0xb0b", nil
+		},
+		Balances: func(ctx context.Context, address string) ([]Balance, error) {
+			return nil, errors.New("rpc down")
+		},
+		Items: func(ctx context.Context, address string) ([]string, error) {
+			<-release
+			return []string{"red_hat"}, nil
+		},
+	}, Options{Wait: 20 * time.Millisecond})
+	warmed := make(chan Snapshot, 1)
+	s.OnFetched(func(snapshot Snapshot) { warmed <- snapshot })
+
+	summary := s.Prefetch(context.Background(), "bob")
+	if !reflect.DeepEqual(summary.Pending, []string{PartItems}) || !reflect.DeepEqual(summary.Failed, []string{PartBalances}) || len(summary.Ready) != 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	close(release)
+	if snapshot := <-warmed; len(snapshot.Items) != 1 {
+		t.Errorf("warmed with %+v", snapshot)
+	}
+	if _, ok := s.Cached("bob"); ok {
+		t.Error("a snapshot with a failed read was cached")
+	}
+
+	if summary := s.Prefetch(context.Background(), "nobody"); summary.Linked || len(summary.Ready)+len(summary.Pending) != 0 {
+		t.Errorf("summary of a player without an address = %+v", summary)
+	}
+}
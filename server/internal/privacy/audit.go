@@ -0,0 +1,67 @@
+package privacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit actions.
+const (
+	ActionExport = "export"
+	ActionDelete = "delete"
+)
+
+// AuditEntry records one privacy request.
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	PlayerID    string    `json:"playerId"`
+	RequestedBy string    `json:"requestedBy"`
+	Reason      string    `json:"reason,omitempty"`
+	Result      string    `json:"result"` // "ok", "partial", "started" or "blocked"
+	Detail      string    `json:"detail,omitempty"`
+}
+
+// AuditLog stores the audit trail. Requests fail if their entry cannot be written.
+type AuditLog interface {
+	Append(entry AuditEntry) error
+}
+
+// FileAuditLog appends entries as JSON lines to a file.
+type FileAuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileAuditLog opens (or creates) the audit log at path for appending.
+func OpenFileAuditLog(path string) (*FileAuditLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open privacy audit log %s: %w", path, err)
+	}
+	return &FileAuditLog{file: file}, nil
+}
+
+// Append implements AuditLog. Entries are synced to disk before it returns.
+func (l *FileAuditLog) Append(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close closes the underlying file.
+func (l *FileAuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,54 @@
+package privacy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which goes into the audit log.
+//
+//	POST /admin/privacy/export?playerId=ID
+//	POST /admin/privacy/delete?playerId=ID   body: {"reason": "...", "confirmPlayerId": "ID"}
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/privacy/export", adminhttp.Method(http.MethodPost, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		playerID := r.URL.Query().Get("playerId")
+		bundle, err := s.Export(r.Context(), playerID, operator)
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="player-export.json"`)
+		adminhttp.WriteJSON(w, http.StatusOK, bundle)
+	})))
+	mux.HandleFunc("/admin/privacy/delete", adminhttp.Method(http.MethodPost, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		playerID := r.URL.Query().Get("playerId")
+		var body struct {
+			Reason          string `json:"reason"`
+			ConfirmPlayerID string `json:"confirmPlayerId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("body must be JSON with reason and confirmPlayerId"))
+			return
+		}
+		// Deletion cannot be undone, so the player ID must be given twice.
+		if playerID == "" || body.ConfirmPlayerID != playerID || body.Reason == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId, a matching confirmPlayerId and a reason are required"))
+			return
+		}
+		report, err := s.Delete(r.Context(), playerID, operator, body.Reason)
+		if errors.Is(err, ErrDeletionBlocked) {
+			adminhttp.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, report)
+	})))
+}
@@ -0,0 +1,159 @@
+// Package privacy implements admin-triggered data export and deletion for a
+// player (GDPR access and erasure requests). Each store of personal data
+// registers a DataSource; deletion is refused while any DeletionGuard objects,
+// e.g. while the player has value held in on-chain escrow. Every request is
+// written to an audit log.
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// ErrDeletionBlocked is returned (wrapped) when a DeletionGuard refuses a deletion.
+var ErrDeletionBlocked = errors.New("deletion blocked")
+
+// DataSource is one store of player data.
+type DataSource interface {
+	Name() string
+	// Export returns the player's data in a JSON-serializable form, or nil if there is none.
+	Export(ctx context.Context, playerID string) (interface{}, error)
+	// Erase deletes or anonymizes the player's data and purges any cached copies.
+	Erase(ctx context.Context, playerID string) error
+}
+
+// DeletionGuard can veto a deletion, e.g. while the player has pending
+// on-chain escrow that would be orphaned.
+type DeletionGuard interface {
+	Name() string
+	CheckDeletion(ctx context.Context, playerID string) error
+}
+
+// ExportBundle is the result of a data export.
+type ExportBundle struct {
+	PlayerID    string                 `json:"playerId"`
+	GeneratedAt time.Time              `json:"generatedAt"`
+	Sections    map[string]interface{} `json:"sections"`         // DataSource name -> exported data
+	Errors      map[string]string      `json:"errors,omitempty"` // DataSource name -> export failure
+}
+
+// DeletionReport is the result of a deletion.
+type DeletionReport struct {
+	PlayerID  string            `json:"playerId"`
+	DeletedAt time.Time         `json:"deletedAt"`
+	Erased    []string          `json:"erased"`           // DataSources that were erased
+	Failed    map[string]string `json:"failed,omitempty"` // DataSource name -> erase failure
+}
+
+// Service runs export and deletion requests across all registered data sources.
+// Register sources and guards before serving requests.
+type Service struct {
+	sources []DataSource
+	guards  []DeletionGuard
+	audit   AuditLog
+}
+
+// NewService creates a Service that records requests in audit.
+func NewService(audit AuditLog) *Service {
+	return &Service{audit: audit}
+}
+
+// AddSource registers a store of player data.
+func (s *Service) AddSource(source DataSource) {
+	s.sources = append(s.sources, source)
+}
+
+// AddGuard registers a deletion safeguard.
+func (s *Service) AddGuard(guard DeletionGuard) {
+	s.guards = append(s.guards, guard)
+}
+
+// Export collects the player's data from every source. A failing source is
+// reported in the bundle rather than failing the whole export.
+func (s *Service) Export(ctx context.Context, playerID, requestedBy string) (*ExportBundle, error) {
+	if playerID == "" {
+		return nil, fmt.Errorf("player ID is required")
+	}
+	bundle := &ExportBundle{
+		PlayerID:    playerID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]interface{}),
+	}
+	for _, source := range s.sources {
+		data, err := source.Export(ctx, playerID)
+		if err != nil {
+			if bundle.Errors == nil {
+				bundle.Errors = make(map[string]string)
+			}
+			bundle.Errors[source.Name()] = err.Error()
+			continue
+		}
+		if data != nil {
+			bundle.Sections[source.Name()] = data
+		}
+	}
+	result := "ok"
+	if len(bundle.Errors) > 0 {
+		result = "partial"
+	}
+	if err := s.record(AuditEntry{Action: ActionExport, PlayerID: playerID, RequestedBy: requestedBy, Result: result}); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// Delete erases the player's data from every source, after all guards allow it.
+// Sources are all attempted even if one fails; failures are in the report and
+// the request can be retried.
+func (s *Service) Delete(ctx context.Context, playerID, requestedBy, reason string) (*DeletionReport, error) {
+	if playerID == "" {
+		return nil, fmt.Errorf("player ID is required")
+	}
+	for _, guard := range s.guards {
+		if err := guard.CheckDeletion(ctx, playerID); err != nil {
+			blocked := fmt.Errorf("%w by %s: %v", ErrDeletionBlocked, guard.Name(), err)
+			if auditErr := s.record(AuditEntry{Action: ActionDelete, PlayerID: playerID, RequestedBy: requestedBy, Reason: reason, Result: "blocked", Detail: blocked.Error()}); auditErr != nil {
+				return nil, auditErr
+			}
+			return nil, blocked
+		}
+	}
+	// Record the request before erasing anything, so there is a trail even if the server dies midway.
+	if err := s.record(AuditEntry{Action: ActionDelete, PlayerID: playerID, RequestedBy: requestedBy, Reason: reason, Result: "started"}); err != nil {
+		return nil, err
+	}
+
+	report := &DeletionReport{PlayerID: playerID, DeletedAt: time.Now().UTC()}
+	for _, source := range s.sources {
+		if err := source.Erase(ctx, playerID); err != nil {
+			if report.Failed == nil {
+				report.Failed = make(map[string]string)
+			}
+			report.Failed[source.Name()] = err.Error()
+			utils.LogErrorf("Privacy: Failed to erase %s data for player %s: %v", source.Name(), playerID, err)
+			continue
+		}
+		report.Erased = append(report.Erased, source.Name())
+	}
+	result, detail := "ok", ""
+	if len(report.Failed) > 0 {
+		result, detail = "partial", fmt.Sprintf("failed sources: %v", report.Failed)
+	}
+	if err := s.record(AuditEntry{Action: ActionDelete, PlayerID: playerID, RequestedBy: requestedBy, Reason: reason, Result: result, Detail: detail}); err != nil {
+		return report, err
+	}
+	utils.LogInfof("Privacy: Player %s deleted by %s (%s).", playerID, requestedBy, result)
+	return report, nil
+}
+
+func (s *Service) record(entry AuditEntry) error {
+	entry.Time = time.Now().UTC()
+	if err := s.audit.Append(entry); err != nil {
+		return fmt.Errorf("write privacy audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type memoryAudit struct{ entries []AuditEntry }
+
+func (a *memoryAudit) Append(entry AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+type fakeSource struct {
+	name     string
+	data     map[string]interface{}
+	eraseErr error
+}
+
+func (s *fakeSource) Name() string { return s.name }
+func (s *fakeSource) Export(_ context.Context, playerID string) (interface{}, error) {
+	return s.data[playerID], nil
+}
+func (s *fakeSource) Erase(_ context.Context, playerID string) error {
+	if s.eraseErr != nil {
+		return s.eraseErr
+	}
+	delete(s.data, playerID)
+	return nil
+}
+
+type guardFunc func(string) error
+
+func (g guardFunc) Name() string                                           { return "escrow" }
+func (g guardFunc) CheckDeletion(_ context.Context, playerID string) error { return g(playerID) }
+
+func TestExportAndDelete(t *testing.T) {
+	audit := &memoryAudit{}
+	profile := &fakeSource{name: "profile", data: map[string]interface{}{"p1": "alice"}}
+	chat := &fakeSource{name: "chat", data: map[string]interface{}{}, eraseErr: errors.New("db down")}
+	svc := NewService(audit)
+	svc.AddSource(profile)
+	svc.AddSource(chat)
+
+	bundle, err := svc.Export(context.Background(), "p1", "ops")
+	if err != nil || bundle.Sections["profile"] != "alice" || len(bundle.Sections) != 1 {
+		t.Fatalf("export = %+v, %v", bundle, err)
+	}
+
+	report, err := svc.Delete(context.Background(), "p1", "ops", "user request")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(report.Erased) != 1 || report.Failed["chat"] == "" {
+		t.Fatalf("report = %+v, want profile erased and chat failed", report)
+	}
+	if _, ok := profile.data["p1"]; ok {
+		t.Fatal("profile data not erased")
+	}
+	results := []string{}
+	for _, entry := range audit.entries {
+		results = append(results, entry.Action+":"+entry.Result)
+	}
+	want := []string{"export:ok", "delete:started", "delete:partial"}
+	if len(results) != len(want) {
+		t.Fatalf("audit = %v, want %v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Fatalf("audit = %v, want %v", results, want)
+		}
+	}
+}
+
+func TestDeleteBlockedByGuard(t *testing.T) {
+	audit := &memoryAudit{}
+	profile := &fakeSource{name: "profile", data: map[string]interface{}{"p1": "alice"}}
+	svc := NewService(audit)
+	svc.AddSource(profile)
+	svc.AddGuard(guardFunc(func(string) error { return errors.New("2 trades in escrow") }))
+
+	_, err := svc.Delete(context.Background(), "p1", "ops", "user request")
+	if !errors.Is(err, ErrDeletionBlocked) {
+		t.Fatalf("err = %v, want ErrDeletionBlocked", err)
+	}
+	if _, ok := profile.data["p1"]; !ok {
+		t.Fatal("data erased despite the guard")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Result != "blocked" {
+		t.Fatalf("audit = %+v, want one blocked entry", audit.entries)
+	}
+}
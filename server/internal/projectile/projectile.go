@@ -0,0 +1,343 @@
+// Package projectile simulates ranged attacks. A projectile leaves its owner
+// at launch height and flies in a straight line at its speed; with gravity it
+// is lobbed so that it comes back down to launch height at the aimed point.
+// Each tick its path is sampled against the room's map and the hitboxes of
+// players and NPCs, and it ends on the first thing it touches: a target, a
+// wall, the ground, or the end of its range. Projectiles are defined in the
+// skill data next to the movement abilities.
+package projectile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+const (
+	// LaunchHeight is the height projectiles leave their owner at.
+	LaunchHeight = 1.2
+	// LowHeight is the height of low obstacles; projectiles below it hit them.
+	LowHeight = 0.8
+	// HitboxRadius and HitboxHeight size the cylinder players and NPCs are hit in.
+	HitboxRadius = 0.5
+	HitboxHeight = 2.0
+)
+
+// Reasons a projectile ends.
+const (
+	ReasonHit    = "hit"    // It hit a player or NPC
+	ReasonRange  = "range"  // It flew its full range
+	ReasonWall   = "wall"   // It hit an obstacle or left the map
+	ReasonGround = "ground" // It came down
+)
+
+// sampleStep is the spacing of the points checked along a projectile's path.
+// It is below HitboxRadius, so projectiles cannot pass through a target
+// between two samples.
+const sampleStep = 0.25
+
+// maxInFlight caps the projectiles of one Simulation.
+const maxInFlight = 512
+
+// sweepThreshold is the number of cooldown entries above which expired ones
+// are swept.
+const sweepThreshold = 4096
+
+var (
+	ErrUnknownProjectile = errors.New("unknown projectile")
+	ErrOnCooldown        = errors.New("projectile is on cooldown")
+	ErrNoAim             = errors.New("aim at a point away from yourself")
+	ErrTooMany           = errors.New("too many projectiles in flight")
+)
+
+// Definition is a projectile from skill data.
+type Definition struct {
+	ID          string  `json:"id"`
+	Speed       float64 `json:"speed"`             // Horizontal units per second
+	Range       float64 `json:"range"`             // Horizontal distance flown before it expires
+	Gravity     float64 `json:"gravity,omitempty"` // Downward pull in units per second squared; 0 flies level
+	Radius      float64 `json:"radius,omitempty"`  // Added to the hitboxes it is tested against
+	AttackPower int     `json:"attackPower"`       // Attacker stat handed to the combat engine
+	Element     string  `json:"element,omitempty"`
+	CooldownMs  int     `json:"cooldownMs"` // Time before the owner may fire it again
+}
+
+// Cooldown returns the projectile's cooldown.
+func (d Definition) Cooldown() time.Duration {
+	return time.Duration(d.CooldownMs) * time.Millisecond
+}
+
+// skillData is the part of the skills file with the projectiles.
+type skillData struct {
+	Projectiles []Definition `json:"projectiles"`
+}
+
+// LoadDefinitions reads the projectiles of a skills file.
+func LoadDefinitions(path string) ([]Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var skills skillData
+	if err := json.Unmarshal(data, &skills); err != nil {
+		return nil, fmt.Errorf("invalid skill data %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(skills.Projectiles))
+	for i, d := range skills.Projectiles {
+		switch {
+		case d.ID == "":
+			return nil, fmt.Errorf("invalid skill data %s: projectile %d needs an id", path, i+1)
+		case seen[d.ID]:
+			return nil, fmt.Errorf("invalid skill data %s: duplicate projectile %q", path, d.ID)
+		case d.Speed <= 0 || d.Range <= 0:
+			return nil, fmt.Errorf("invalid skill data %s: projectile %q needs a positive speed and range", path, d.ID)
+		case d.Gravity < 0 || d.Radius < 0 || d.AttackPower < 0 || d.CooldownMs < 0:
+			return nil, fmt.Errorf("invalid skill data %s: projectile %q needs a gravity, radius, attack power and cooldown of 0 or more", path, d.ID)
+		}
+		seen[d.ID] = true
+	}
+	return skills.Projectiles, nil
+}
+
+// Arsenal holds the projectile definitions and every player's cooldowns,
+// which outlast rooms. It is safe for concurrent use. A nil *Arsenal has no
+// projectiles.
+type Arsenal struct {
+	defs map[string]Definition
+	now  func() time.Time
+
+	mu      *sync.Mutex // Shared with the Arsenals reloaded from this one
+	readyAt map[cooldownKey]time.Time
+}
+
+type cooldownKey struct {
+	playerID     string
+	projectileID string
+}
+
+// NewArsenal creates an Arsenal of defs.
+func NewArsenal(defs []Definition) *Arsenal {
+	byID := make(map[string]Definition, len(defs))
+	for _, d := range defs {
+		byID[d.ID] = d
+	}
+	return &Arsenal{defs: byID, now: time.Now, mu: new(sync.Mutex), readyAt: make(map[cooldownKey]time.Time)}
+}
+
+// Reloaded returns an Arsenal of new defs that shares a's cooldowns, so
+// reloading game data does not reset them.
+func (a *Arsenal) Reloaded(defs []Definition) *Arsenal {
+	next := NewArsenal(defs)
+	if a != nil {
+		next.now, next.mu, next.readyAt = a.now, a.mu, a.readyAt
+	}
+	return next
+}
+
+// Len returns the number of projectiles.
+func (a *Arsenal) Len() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.defs)
+}
+
+// Ready returns the definition of projectileID if playerID may fire it now.
+func (a *Arsenal) Ready(playerID, projectileID string) (Definition, error) {
+	if a == nil {
+		return Definition{}, ErrUnknownProjectile
+	}
+	def, ok := a.defs[projectileID]
+	if !ok {
+		return Definition{}, ErrUnknownProjectile
+	}
+	if remaining := a.CooldownRemaining(playerID, projectileID); remaining > 0 {
+		return def, fmt.Errorf("%w for another %s", ErrOnCooldown, remaining.Round(100*time.Millisecond))
+	}
+	return def, nil
+}
+
+// Fired starts playerID's cooldown of projectileID.
+func (a *Arsenal) Fired(playerID, projectileID string) {
+	if a == nil {
+		return
+	}
+	def, ok := a.defs[projectileID]
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if len(a.readyAt) >= sweepThreshold {
+		for k, ready := range a.readyAt {
+			if !now.Before(ready) {
+				delete(a.readyAt, k)
+			}
+		}
+	}
+	a.readyAt[cooldownKey{playerID, projectileID}] = now.Add(def.Cooldown())
+}
+
+// CooldownRemaining returns how long playerID must wait to fire projectileID again.
+func (a *Arsenal) CooldownRemaining(playerID, projectileID string) time.Duration {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if remaining := a.readyAt[cooldownKey{playerID, projectileID}].Sub(a.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Projectile is a projectile in flight. Its path follows from the fields
+// alone, so clients can draw it from the spawn event.
+type Projectile struct {
+	ID      string
+	OwnerID string
+	Def     Definition
+	Origin  geometry.Vec // Where it was fired from
+	Dir     geometry.Vec // Unit direction of flight
+	Lift    float64      // Initial upward speed; 0 unless it has gravity
+	elapsed float64      // Seconds in flight
+}
+
+// At returns where the projectile is, and how high, t seconds after it was
+// fired.
+func (p *Projectile) At(t float64) (geometry.Vec, float64) {
+	d := p.Def.Speed * t
+	pos := geometry.Vec{X: p.Origin.X + p.Dir.X*d, Y: p.Origin.Y + p.Dir.Y*d}
+	return pos, LaunchHeight + p.Lift*t - p.Def.Gravity*t*t/2
+}
+
+// Target is a hitbox a projectile can hit.
+type Target struct {
+	ID  string
+	Pos geometry.Vec
+	NPC bool
+}
+
+// End is how a projectile's flight ended.
+type End struct {
+	Projectile Projectile
+	Reason     string       // ReasonHit, ReasonRange, ReasonWall or ReasonGround
+	Pos        geometry.Vec // Where it ended
+	Height     float64
+	Target     Target // The target hit, for ReasonHit
+}
+
+// Simulation is the projectiles in flight in one room. It belongs to a single
+// goroutine, such as a room actor's. A nil map is open ground.
+type Simulation struct {
+	m      *geometry.Map
+	next   int
+	flying []*Projectile
+}
+
+// NewSimulation creates a Simulation on m.
+func NewSimulation(m *geometry.Map) *Simulation {
+	return &Simulation{m: m}
+}
+
+// Len returns the number of projectiles in flight.
+func (s *Simulation) Len() int {
+	return len(s.flying)
+}
+
+// Clear drops every projectile in flight.
+func (s *Simulation) Clear() {
+	s.flying = nil
+}
+
+// Fire launches def for ownerID from from towards aim. Aim beyond the range
+// is fine; a lobbed projectile then comes down at the end of its range.
+func (s *Simulation) Fire(ownerID string, def Definition, from, aim geometry.Vec) (Projectile, error) {
+	d := from.Dist(aim)
+	if !from.Finite() || !aim.Finite() || d == 0 {
+		return Projectile{}, ErrNoAim
+	}
+	if len(s.flying) >= maxInFlight {
+		return Projectile{}, ErrTooMany
+	}
+	s.next++
+	p := &Projectile{
+		ID:      fmt.Sprintf("p%d", s.next),
+		OwnerID: ownerID,
+		Def:     def,
+		Origin:  from,
+		Dir:     geometry.Vec{X: (aim.X - from.X) / d, Y: (aim.Y - from.Y) / d},
+	}
+	if def.Gravity > 0 {
+		// Back at launch height after the flight time to the aimed point.
+		p.Lift = def.Gravity * math.Min(d, def.Range) / def.Speed / 2
+	}
+	s.flying = append(s.flying, p)
+	return *p, nil
+}
+
+// Step advances every projectile by dt and returns those that ended. A
+// projectile cannot hit its owner.
+func (s *Simulation) Step(dt time.Duration, targets []Target) []End {
+	var ended []End
+	flying := s.flying[:0]
+	for _, p := range s.flying {
+		if end, done := s.advance(p, dt.Seconds(), targets); done {
+			ended = append(ended, end)
+			continue
+		}
+		flying = append(flying, p)
+	}
+	for i := len(flying); i < len(s.flying); i++ {
+		s.flying[i] = nil
+	}
+	s.flying = flying
+	return ended
+}
+
+// advance moves p by dt seconds, sampling its path, and reports whether it
+// ended.
+func (s *Simulation) advance(p *Projectile, dt float64, targets []Target) (End, bool) {
+	from, rangeTime := p.elapsed, p.Def.Range/p.Def.Speed
+	to := math.Min(from+dt, rangeTime)
+	n := max(int(math.Ceil(p.Def.Speed*(to-from)/sampleStep)), 1)
+	reach := HitboxRadius + p.Def.Radius
+	for i := 1; i <= n; i++ {
+		t := from + (to-from)*float64(i)/float64(n)
+		pos, height := p.At(t)
+		end := End{Projectile: *p, Pos: pos, Height: height}
+		if height <= 0 {
+			end.Reason = ReasonGround
+			return end, true
+		}
+		if block := s.m.BlockAt(pos); block == geometry.Wall || block == geometry.Low && height < LowHeight {
+			end.Reason = ReasonWall
+			return end, true
+		}
+		if height <= HitboxHeight {
+			best := reach
+			for _, target := range targets {
+				if d := target.Pos.Dist(pos); d <= best && target.ID != p.OwnerID {
+					end.Target, best = target, d
+				}
+			}
+			if end.Target.ID != "" {
+				end.Reason = ReasonHit
+				return end, true
+			}
+		}
+	}
+	p.elapsed = to
+	if to >= rangeTime {
+		pos, height := p.At(to)
+		return End{Projectile: *p, Reason: ReasonRange, Pos: pos, Height: height}, true
+	}
+	return End{}, false
+}
@@ -0,0 +1,140 @@
+package projectile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+)
+
+const tick = 100 * time.Millisecond
+
+var arrow = Definition{ID: "arrow", Speed: 20, Range: 15, AttackPower: 10}
+
+// fly steps s until a projectile ends, or fails after a few seconds.
+func fly(t *testing.T, s *Simulation, targets []Target) End {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		if ended := s.Step(tick, targets); len(ended) > 0 {
+			return ended[0]
+		}
+	}
+	t.Fatal("the projectile never ended")
+	return End{}
+}
+
+func TestProjectileHitsTheFirstTargetOnItsPath(t *testing.T) {
+	s := NewSimulation(nil)
+	if _, err := s.Fire("archer", arrow, geometry.Vec{}, geometry.Vec{X: 1}); err != nil {
+		t.Fatal(err)
+	}
+	targets := []Target{
+		{ID: "archer", Pos: geometry.Vec{}},
+		{ID: "far", Pos: geometry.Vec{X: 8, Y: 0.2}},
+		{ID: "wolf", Pos: geometry.Vec{X: 5, Y: -0.3}, NPC: true},
+		{ID: "aside", Pos: geometry.Vec{X: 3, Y: 2}},
+	}
+	end := fly(t, s, targets)
+	if end.Reason != ReasonHit || end.Target.ID != "wolf" || !end.Target.NPC {
+		t.Fatalf("end = %+v, want a hit on the wolf", end)
+	}
+	if s.Len() != 0 {
+		t.Errorf("%d projectiles still flying after the hit", s.Len())
+	}
+}
+
+func TestProjectileExpiresAtItsRange(t *testing.T) {
+	s := NewSimulation(nil)
+	s.Fire("archer", arrow, geometry.Vec{}, geometry.Vec{Y: 1})
+	end := fly(t, s, nil)
+	if end.Reason != ReasonRange || end.Pos.Dist(geometry.Vec{Y: 15}) > 1e-9 {
+		t.Errorf("end = %+v, want it to expire 15 units away", end)
+	}
+}
+
+func TestProjectileStopsAtWallsButClearsLowObstacles(t *testing.T) {
+	m := &geometry.Map{
+		Bounds: geometry.Rect{MinX: -20, MinY: -20, MaxX: 20, MaxY: 20},
+		Obstacles: []geometry.Obstacle{
+			{Points: geometry.Rect{MinX: 3, MinY: -1, MaxX: 4, MaxY: 1}.Polygon(), Low: true},
+			{Points: geometry.Rect{MinX: 8, MinY: -1, MaxX: 9, MaxY: 1}.Polygon()},
+		},
+	}
+	s := NewSimulation(m)
+	s.Fire("archer", arrow, geometry.Vec{}, geometry.Vec{X: 1})
+	if end := fly(t, s, nil); end.Reason != ReasonWall || end.Pos.X < 8 || end.Pos.X > 8.25 {
+		t.Errorf("end = %+v, want it stopped by the wall at x=8", end)
+	}
+}
+
+func TestLobbedProjectileComesDownAtTheAimedPoint(t *testing.T) {
+	s := NewSimulation(nil)
+	grenade := Definition{ID: "grenade", Speed: 8, Range: 12, Gravity: 20}
+	p, _ := s.Fire("thrower", grenade, geometry.Vec{}, geometry.Vec{X: 6})
+	if _, height := p.At(6.0 / 8); height < LaunchHeight-1e-9 || height > LaunchHeight+1e-9 {
+		t.Fatalf("at the aimed point the grenade is %v high, want %v", height, LaunchHeight)
+	}
+	// It flies over a target halfway, at the top of its arc.
+	end := fly(t, s, []Target{{ID: "under", Pos: geometry.Vec{X: 3}}})
+	if end.Reason != ReasonGround || end.Pos.X < 6 || end.Pos.X > 7.5 {
+		t.Errorf("end = %+v, want it to land just past x=6", end)
+	}
+}
+
+func TestFireNeedsAnAim(t *testing.T) {
+	s := NewSimulation(nil)
+	if _, err := s.Fire("archer", arrow, geometry.Vec{X: 1}, geometry.Vec{X: 1}); !errors.Is(err, ErrNoAim) {
+		t.Errorf("Fire at yourself = %v, want ErrNoAim", err)
+	}
+}
+
+func TestArsenalCooldowns(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a := NewArsenal([]Definition{{ID: "arrow", Speed: 1, Range: 1, CooldownMs: 1500}})
+	a.now = func() time.Time { return now }
+
+	if _, err := a.Ready("p1", "fireball"); !errors.Is(err, ErrUnknownProjectile) {
+		t.Errorf("Ready(fireball) = %v", err)
+	}
+	if _, err := a.Ready("p1", "arrow"); err != nil {
+		t.Fatal(err)
+	}
+	a.Fired("p1", "arrow")
+	if _, err := a.Ready("p1", "arrow"); !errors.Is(err, ErrOnCooldown) {
+		t.Errorf("Ready right after firing = %v, want ErrOnCooldown", err)
+	}
+	if _, err := a.Ready("p2", "arrow"); err != nil {
+		t.Errorf("another player's cooldown applied: %v", err)
+	}
+	now = now.Add(1500 * time.Millisecond)
+	if _, err := a.Ready("p1", "arrow"); err != nil {
+		t.Errorf("Ready after the cooldown = %v", err)
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.json")
+	load := func(content string) ([]Definition, error) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadDefinitions(path)
+	}
+	defs, err := load(`{"movementAbilities": [{"id": "dash"}], "projectiles": [{"id": "arrow", "speed": 20, "range": 15}]}`)
+	if err != nil || len(defs) != 1 || defs[0].ID != "arrow" {
+		t.Fatalf("LoadDefinitions = %v, %v", defs, err)
+	}
+	for want, content := range map[string]string{
+		"positive speed": `{"projectiles": [{"id": "arrow", "range": 15}]}`,
+		"duplicate":      `{"projectiles": [{"id": "arrow", "speed": 1, "range": 1}, {"id": "arrow", "speed": 1, "range": 1}]}`,
+		"gravity":        `{"projectiles": [{"id": "arrow", "speed": 1, "range": 1, "gravity": -1}]}`,
+	} {
+		if _, err := load(content); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error about %s, got %v", want, err)
+		}
+	}
+}
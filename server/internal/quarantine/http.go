@@ -0,0 +1,108 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is logged with every change.
+//
+//	GET  /admin/quarantine?kind=...   entries (newest first), blocked message types and counts
+//	POST /admin/quarantine/replay     {"id": 1, "target": "optional PID id"} sends the message again
+//	POST /admin/quarantine/release    {"actorKind": "...", "messageType": "..."} delivers a blocked type again
+//	POST /admin/quarantine/discard    {"id": 1}
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/quarantine", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"entries": s.Entries(r.URL.Query().Get("kind")),
+			"blocked": s.Blocked(),
+			"counts":  s.Counts(),
+		})
+	}))
+	mux.HandleFunc("/admin/quarantine/replay", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var req struct {
+			ID     uint64 `json:"id"`
+			Target string `json:"target"`
+		}
+		if !decodePost(w, r, &req) {
+			return
+		}
+		var target *actor.PID
+		if req.Target != "" {
+			target = actor.NewPID(s.system.Address(), req.Target)
+		}
+		entry, err := s.Replay(req.ID, target)
+		if err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		utils.LogInfof("Quarantine: %s replayed entry %d (%s) to %s.", operator, entry.ID, entry.MessageType, targetName(target, entry))
+		adminhttp.WriteJSON(w, http.StatusOK, entry)
+	}))
+	mux.HandleFunc("/admin/quarantine/release", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var req struct {
+			ActorKind   string `json:"actorKind"`
+			MessageType string `json:"messageType"`
+		}
+		if !decodePost(w, r, &req) {
+			return
+		}
+		if !s.Release(req.ActorKind, req.MessageType) {
+			adminhttp.WriteError(w, http.StatusNotFound, errors.New("message type is not blocked"))
+			return
+		}
+		utils.LogInfof("Quarantine: %s released %s for %s.", operator, req.MessageType, req.ActorKind)
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]bool{"released": true})
+	}))
+	mux.HandleFunc("/admin/quarantine/discard", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if !decodePost(w, r, &req) {
+			return
+		}
+		if err := s.Discard(req.ID); err != nil {
+			adminhttp.WriteError(w, statusFor(err), err)
+			return
+		}
+		utils.LogInfof("Quarantine: %s discarded entry %d.", operator, req.ID)
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]bool{"discarded": true})
+	}))
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return false
+	}
+	return true
+}
+
+func statusFor(err error) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func targetName(target *actor.PID, entry Entry) string {
+	if target != nil {
+		return target.Id
+	}
+	return entry.Actor
+}
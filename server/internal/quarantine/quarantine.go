@@ -0,0 +1,398 @@
+// Package quarantine keeps actor messages that could not be processed: dead
+// letters, messages whose handler panicked, and messages an actor has no
+// handler for. Entries keep the original message so an operator can replay it
+// after a fix. A message type that keeps crashing one kind of actor is poison:
+// further messages of that type are held in quarantine instead of being
+// delivered, until an operator releases the type.
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/eventstream"
+	"github.com/asynkron/protoactor-go/extensions"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Entry kinds.
+const (
+	KindDeadLetter     = "dead_letter"     // Sent to an actor that no longer exists
+	KindHandlerFailure = "handler_failure" // The actor's handler panicked
+	KindUnhandled      = "unhandled"       // The actor has no handler for the message type
+	KindHeld           = "held"            // Not delivered, because its type is blocked as poison
+)
+
+// ErrNotFound is returned for an entry ID that is not in the quarantine.
+var ErrNotFound = errors.New("quarantine entry not found")
+
+var extensionID = extensions.NextExtensionID()
+
+// Entry is one quarantined message.
+type Entry struct {
+	ID          uint64          `json:"id"`
+	Kind        string          `json:"kind"`
+	ActorKind   string          `json:"actorKind,omitempty"` // Actor type, e.g. "WorldManagerActor"
+	Actor       string          `json:"actor"`               // PID the message was sent to
+	Sender      string          `json:"sender,omitempty"`
+	MessageType string          `json:"messageType"`
+	Payload     json.RawMessage `json:"payload"` // The message as JSON, or its %+v text if it does not encode
+	Error       string          `json:"error,omitempty"`
+	Stack       string          `json:"stack,omitempty"`
+	At          time.Time       `json:"at"`
+	Replays     int             `json:"replays"`
+	LastReplay  time.Time       `json:"lastReplay"`
+
+	message interface{}
+	target  *actor.PID
+}
+
+// BlockedType is a message type that is held back from one kind of actor.
+type BlockedType struct {
+	ActorKind   string    `json:"actorKind"`
+	MessageType string    `json:"messageType"`
+	Since       time.Time `json:"since"`
+	Held        int       `json:"held"` // Messages held since the type was blocked
+}
+
+// Options configures a Service. Zero values use the defaults.
+type Options struct {
+	MaxEntries       int           // Entries kept; the oldest are dropped. Default 1000
+	FailureThreshold int           // Panics on one message type within FailureWindow that block it. Default 3; negative never blocks
+	FailureWindow    time.Duration // Default 10m
+}
+
+type typeKey struct {
+	actorKind   string
+	messageType string
+}
+
+// Service is the quarantine of one actor system.
+type Service struct {
+	system *actor.ActorSystem
+	opts   Options
+	sub    *eventstream.Subscription
+
+	mu       sync.Mutex
+	entries  []*Entry // Oldest first
+	nextID   uint64
+	counts   map[string]uint64 // Entries added per kind since startup
+	failures map[typeKey][]time.Time
+	blocked  map[typeKey]*BlockedType
+}
+
+// New creates the quarantine for system. It subscribes to the system's dead
+// letters and registers itself so Guard and ReportUnhandled can find it.
+func New(system *actor.ActorSystem, opts Options) *Service {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = 10 * time.Minute
+	}
+	s := &Service{
+		system:   system,
+		opts:     opts,
+		counts:   make(map[string]uint64),
+		failures: make(map[typeKey][]time.Time),
+		blocked:  make(map[typeKey]*BlockedType),
+	}
+	system.Extensions.Register(s)
+	s.sub = system.EventStream.Subscribe(func(evt interface{}) {
+		if deadLetter, ok := evt.(*actor.DeadLetterEvent); ok {
+			s.onDeadLetter(deadLetter)
+		}
+	})
+	return s
+}
+
+// ExtensionID implements extensions.Extension.
+func (s *Service) ExtensionID() extensions.ExtensionID { return extensionID }
+
+// Close stops capturing dead letters.
+func (s *Service) Close() {
+	s.system.EventStream.Unsubscribe(s.sub)
+}
+
+// fromSystem returns the quarantine registered with system, if any.
+func fromSystem(system *actor.ActorSystem) *Service {
+	s, _ := system.Extensions.Get(extensionID).(*Service)
+	return s
+}
+
+// Guard is receiver middleware that quarantines messages whose handler panics
+// (the panic still reaches the actor's supervisor) and holds back messages of
+// blocked types. Add it to actor props with actor.WithReceiverMiddleware.
+func Guard(next actor.ReceiverFunc) actor.ReceiverFunc {
+	return func(ctx actor.ReceiverContext, env *actor.MessageEnvelope) {
+		s := fromSystem(ctx.ActorSystem())
+		if s == nil {
+			next(ctx, env)
+			return
+		}
+		kind := actorKind(ctx.Actor())
+		if !isLifecycle(env.Message) && s.holdBack(kind, ctx.Self(), env) {
+			return
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				s.onFailure(kind, ctx.Self(), env, r, string(debug.Stack()))
+				panic(r)
+			}
+		}()
+		next(ctx, env)
+	}
+}
+
+// ReportUnhandled quarantines a message the actor has no handler for. Actors
+// call it from the default case of their Receive. Lifecycle messages an actor
+// ignores are not reported.
+func ReportUnhandled(ctx actor.Context, message interface{}) {
+	s := fromSystem(ctx.ActorSystem())
+	if s == nil || isLifecycle(message) {
+		return
+	}
+	s.add(&Entry{
+		Kind:      KindUnhandled,
+		ActorKind: actorKind(ctx.Actor()),
+		Actor:     ctx.Self().String(),
+		Sender:    pidString(ctx.Sender()),
+		message:   message,
+		target:    ctx.Self(),
+	})
+}
+
+func (s *Service) onDeadLetter(evt *actor.DeadLetterEvent) {
+	// Lifecycle messages and timer ticks reach stopped actors all the time.
+	if isLifecycle(evt.Message) {
+		return
+	}
+	if _, ok := evt.Message.(actor.NotInfluenceReceiveTimeout); ok {
+		return
+	}
+	s.add(&Entry{
+		Kind:    KindDeadLetter,
+		Actor:   pidString(evt.PID),
+		Sender:  pidString(evt.Sender),
+		message: evt.Message,
+		target:  evt.PID,
+	})
+}
+
+func (s *Service) onFailure(kind string, self *actor.PID, env *actor.MessageEnvelope, reason interface{}, stack string) {
+	entry := &Entry{
+		Kind:      KindHandlerFailure,
+		ActorKind: kind,
+		Actor:     self.String(),
+		Sender:    pidString(env.Sender),
+		Error:     fmt.Sprint(reason),
+		Stack:     stack,
+		message:   env.Message,
+		target:    self,
+	}
+	s.add(entry)
+	utils.LogErrorf("Quarantine: %s %s panicked on %s: %v (entry %d)", kind, self.Id, entry.MessageType, reason, entry.ID)
+
+	if s.opts.FailureThreshold < 0 || isLifecycle(env.Message) {
+		return
+	}
+	key := typeKey{actorKind: kind, messageType: entry.MessageType}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recent := s.failures[key][:0]
+	for _, at := range s.failures[key] {
+		if now.Sub(at) < s.opts.FailureWindow {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	s.failures[key] = recent
+	if len(recent) >= s.opts.FailureThreshold && s.blocked[key] == nil {
+		s.blocked[key] = &BlockedType{ActorKind: kind, MessageType: entry.MessageType, Since: now}
+		utils.LogErrorf("Quarantine: %s failed %d times on %s. Holding back further %s messages until released.",
+			kind, len(recent), entry.MessageType, entry.MessageType)
+	}
+}
+
+// holdBack quarantines the message instead of delivering it if its type is blocked for kind.
+func (s *Service) holdBack(kind string, self *actor.PID, env *actor.MessageEnvelope) bool {
+	key := typeKey{actorKind: kind, messageType: messageType(env.Message)}
+	s.mu.Lock()
+	blocked := s.blocked[key]
+	if blocked != nil {
+		blocked.Held++
+	}
+	s.mu.Unlock()
+	if blocked == nil {
+		return false
+	}
+	s.add(&Entry{
+		Kind:      KindHeld,
+		ActorKind: kind,
+		Actor:     self.String(),
+		Sender:    pidString(env.Sender),
+		message:   env.Message,
+		target:    self,
+	})
+	return true
+}
+
+func (s *Service) add(entry *Entry) {
+	entry.MessageType = messageType(entry.message)
+	entry.Payload = encodePayload(entry.message)
+	entry.At = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	entry.ID = s.nextID
+	s.counts[entry.Kind]++
+	s.entries = append(s.entries, entry)
+	if len(s.entries) > s.opts.MaxEntries {
+		s.entries = s.entries[len(s.entries)-s.opts.MaxEntries:]
+	}
+}
+
+// Entries returns the quarantined messages of kind, or of every kind if kind
+// is empty, newest first.
+func (s *Service) Entries(kind string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Entry, 0, len(s.entries))
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if kind == "" || s.entries[i].Kind == kind {
+			list = append(list, *s.entries[i])
+		}
+	}
+	return list
+}
+
+// Counts returns the number of entries added per kind since startup, including dropped ones.
+func (s *Service) Counts() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]uint64, len(s.counts))
+	for kind, n := range s.counts {
+		counts[kind] = n
+	}
+	return counts
+}
+
+// Blocked returns the message types currently held back.
+func (s *Service) Blocked() []BlockedType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]BlockedType, 0, len(s.blocked))
+	for _, blocked := range s.blocked {
+		list = append(list, *blocked)
+	}
+	return list
+}
+
+// Release delivers messageType to actorKind again and forgets its failures.
+// Held messages stay in the quarantine for replay. It reports whether the type was blocked.
+func (s *Service) Release(actorKind, messageType string) bool {
+	key := typeKey{actorKind: actorKind, messageType: messageType}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blocked[key]
+	delete(s.blocked, key)
+	delete(s.failures, key)
+	return ok
+}
+
+// Replay sends an entry's original message again, to target or, if target is
+// nil, to the actor it was first sent to. The entry stays in the quarantine.
+func (s *Service) Replay(id uint64, target *actor.PID) (Entry, error) {
+	s.mu.Lock()
+	entry := s.find(id)
+	if entry == nil {
+		s.mu.Unlock()
+		return Entry{}, ErrNotFound
+	}
+	if target == nil {
+		target = entry.target
+	}
+	if target == nil {
+		s.mu.Unlock()
+		return Entry{}, fmt.Errorf("entry %d has no target actor", id)
+	}
+	entry.Replays++
+	entry.LastReplay = time.Now()
+	replayed := *entry
+	s.mu.Unlock()
+	s.system.Root.Send(target, replayed.message)
+	return replayed, nil
+}
+
+// Discard removes an entry.
+func (s *Service) Discard(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, entry := range s.entries {
+		if entry.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *Service) find(id uint64) *Entry {
+	for _, entry := range s.entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// isLifecycle reports messages the actor system itself sends an actor.
+func isLifecycle(message interface{}) bool {
+	switch message.(type) {
+	case actor.SystemMessage, actor.AutoReceiveMessage, *actor.ReceiveTimeout:
+		return true
+	}
+	return false
+}
+
+// actorKind is the type name of an actor, e.g. "RoomActor".
+func actorKind(a actor.Actor) string {
+	t := reflect.TypeOf(a)
+	if t == nil {
+		return ""
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// messageType is the package-qualified type of a message, e.g. "*messages.FindRoomRequest".
+func messageType(message interface{}) string {
+	return fmt.Sprintf("%T", message)
+}
+
+func encodePayload(message interface{}) json.RawMessage {
+	if data, err := json.Marshal(message); err == nil {
+		return data
+	}
+	data, _ := json.Marshal(fmt.Sprintf("%+v", message))
+	return data
+}
+
+func pidString(pid *actor.PID) string {
+	if pid == nil {
+		return ""
+	}
+	return pid.String()
+}
@@ -0,0 +1,104 @@
+package quarantine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+type crash struct{ N int }
+
+type ping struct{}
+
+type flaky struct{ pings chan struct{} }
+
+func (f *flaky) Receive(ctx actor.Context) {
+	switch ctx.Message().(type) {
+	case *actor.Started:
+	case *crash:
+		panic("boom")
+	case *ping:
+		f.pings <- struct{}{}
+	default:
+		ReportUnhandled(ctx, ctx.Message())
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoisonMessagesAreHeldUntilReleased(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	q := New(system, Options{FailureThreshold: 2})
+	defer q.Close()
+
+	pings := make(chan struct{}, 1)
+	pid := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor { return &flaky{pings: pings} }, actor.WithReceiverMiddleware(Guard)))
+	system.Root.Send(pid, &crash{N: 1})
+	system.Root.Send(pid, &crash{N: 2})
+	system.Root.Send(pid, &crash{N: 3})
+	system.Root.Send(pid, "unexpected")
+	waitFor(t, "entries", func() bool { return len(q.Entries("")) == 4 })
+
+	if n := len(q.Entries(KindHandlerFailure)); n != 2 {
+		t.Errorf("%d handler failures, want 2", n)
+	}
+	held := q.Entries(KindHeld)
+	if len(held) != 1 || string(held[0].Payload) != `{"N":3}` || held[0].ActorKind != "flaky" {
+		t.Fatalf("held = %+v", held)
+	}
+	if n := len(q.Entries(KindUnhandled)); n != 1 {
+		t.Errorf("%d unhandled entries, want 1", n)
+	}
+	if blocked := q.Blocked(); len(blocked) != 1 || blocked[0].MessageType != "*quarantine.crash" {
+		t.Errorf("blocked = %+v", blocked)
+	}
+
+	// Other message types are still delivered while crash is held back.
+	system.Root.Send(pid, &ping{})
+	select {
+	case <-pings:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ping was not delivered")
+	}
+
+	if !q.Release("flaky", "*quarantine.crash") {
+		t.Fatal("Release reported the type as not blocked")
+	}
+	if _, err := q.Replay(held[0].ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "replayed failure", func() bool { return len(q.Entries(KindHandlerFailure)) == 3 })
+}
+
+func TestDeadLettersAreCaptured(t *testing.T) {
+	system := actor.NewActorSystem()
+	defer system.Shutdown()
+	q := New(system, Options{})
+	defer q.Close()
+
+	pid := system.Root.Spawn(actor.PropsFromFunc(func(actor.Context) {}))
+	if err := system.Root.StopFuture(pid).Wait(); err != nil {
+		t.Fatal(err)
+	}
+	system.Root.Send(pid, &ping{})
+	waitFor(t, "dead letter", func() bool { return len(q.Entries(KindDeadLetter)) == 1 })
+
+	entry := q.Entries(KindDeadLetter)[0]
+	if entry.Actor != pid.String() || entry.MessageType != "*quarantine.ping" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if err := q.Discard(entry.ID); err != nil || len(q.Entries("")) != 0 {
+		t.Errorf("Discard: %v, %d entries left", err, len(q.Entries("")))
+	}
+}
@@ -0,0 +1,50 @@
+// Package ratelimit throttles senders with token buckets: a sender may send a
+// burst at once and then keeps a steady rate.
+package ratelimit
+
+import "time"
+
+// Limit is how fast a sender may send.
+type Limit struct {
+	PerSecond float64 // Sustained rate; 0 is unlimited
+	Burst     int     // Sent at once before the rate applies; at least 1
+}
+
+// PerMinute returns a Limit of n per minute after a burst of burst.
+func PerMinute(n, burst int) Limit {
+	return Limit{PerSecond: float64(n) / 60, Burst: burst}
+}
+
+// Unlimited reports whether l lets everything through.
+func (l Limit) Unlimited() bool {
+	return l.PerSecond <= 0
+}
+
+// Bucket throttles one sender. It starts full. It belongs to a single
+// goroutine, such as an actor's.
+type Bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full Bucket for limit.
+func NewBucket(limit Limit) *Bucket {
+	return &Bucket{limit: limit, tokens: float64(max(limit.Burst, 1))}
+}
+
+// Allow reports whether the sender may send at now, and takes a token if so.
+func (b *Bucket) Allow(now time.Time) bool {
+	if b.limit.Unlimited() {
+		return true
+	}
+	if !b.last.IsZero() {
+		b.tokens = min(float64(max(b.limit.Burst, 1)), b.tokens+now.Sub(b.last).Seconds()*b.limit.PerSecond)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketAllowsABurstThenTheRate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBucket(Limit{PerSecond: 2, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if !b.Allow(now) {
+			t.Fatalf("message %d of the burst refused", i+1)
+		}
+	}
+	if b.Allow(now) {
+		t.Fatal("a fourth message at once was allowed")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !b.Allow(now) || b.Allow(now) {
+		t.Error("want one message after half a second at 2 per second")
+	}
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		b.Allow(now)
+	}
+	if b.Allow(now) {
+		t.Error("the bucket filled past its burst")
+	}
+}
+
+func TestUnlimitedBucket(t *testing.T) {
+	b := NewBucket(Limit{})
+	for i := 0; i < 100; i++ {
+		if !b.Allow(time.Unix(1000, 0)) {
+			t.Fatal("an unlimited bucket refused a message")
+		}
+	}
+	if l := PerMinute(30, 5); l.PerSecond != 0.5 || l.Burst != 5 {
+		t.Errorf("PerMinute(30, 5) = %+v", l)
+	}
+}
@@ -0,0 +1,102 @@
+// Package receipts tells players when transactions the server executed for
+// them have completed, such as the mint of a crafted item or the settlement
+// of an escrowed trade. Receipts come from chain.tx_completed events. Online
+// players get them on their session; offline players find them in their mail.
+package receipts
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+)
+
+// MailKind is the Kind of receipts mailed to offline players.
+const MailKind = "chain.tx_receipt"
+
+// Receipt is the outcome of one transaction for one player.
+type Receipt struct {
+	PlayerID string
+	Kind     string // What the transaction did, e.g. "crafting" or "trade"
+	Digest   string // Empty if the server gave up before a transaction executed
+	Status   string // events.TxSuccess or events.TxFailure
+	Summary  string
+	At       time.Time
+}
+
+// Notifier delivers a *Receipt to a player's session.
+type Notifier func(note interface{})
+
+// Service routes receipts to sessions or mail. It is safe for concurrent use.
+type Service struct {
+	mail *mail.Service // Nil drops the receipts of offline players
+	now  func() time.Time
+
+	mu        sync.Mutex
+	notifiers map[string]Notifier // Connected sessions by player ID
+}
+
+// NewService creates a Service that mails receipts through mailService.
+func NewService(mailService *mail.Service) *Service {
+	return &Service{mail: mailService, now: time.Now, notifiers: make(map[string]Notifier)}
+}
+
+// Connect registers a session to be sent playerID's receipts.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+}
+
+// Disconnect unregisters playerID's session. Later receipts are mailed.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+}
+
+// Subscribe delivers a receipt to every player of each chain.tx_completed
+// event on bus.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, events.TopicTxCompleted, "receipts", func(e events.TxCompleted) {
+		for _, playerID := range e.PlayerIDs {
+			s.Deliver(Receipt{PlayerID: playerID, Kind: e.Kind, Digest: e.Digest, Status: e.Status, Summary: e.Summary})
+		}
+	})
+}
+
+// Deliver sends r to its player's session, or mails it if they are offline.
+func (s *Service) Deliver(r Receipt) {
+	if r.PlayerID == "" {
+		return
+	}
+	if r.At.IsZero() {
+		r.At = s.now().UTC()
+	}
+	s.mu.Lock()
+	notify := s.notifiers[r.PlayerID]
+	s.mu.Unlock()
+	if notify != nil {
+		notify(&r)
+		return
+	}
+	s.mail.Send(mail.Message{
+		To:      r.PlayerID,
+		Kind:    MailKind,
+		Subject: r.Summary,
+		Body:    mailBody(r),
+		Ref:     r.Digest,
+	})
+}
+
+func mailBody(r Receipt) string {
+	switch {
+	case r.Status == events.TxSuccess:
+		return fmt.Sprintf("%s. Transaction %s completed on chain.", r.Summary, r.Digest)
+	case r.Digest != "":
+		return fmt.Sprintf("%s. Transaction %s failed on chain.", r.Summary, r.Digest)
+	}
+	return r.Summary + ". Nothing was recorded on chain; support can look into it."
+}
@@ -0,0 +1,46 @@
+package receipts
+
+import (
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+)
+
+func TestOnlinePlayersGetReceiptsAndOfflinePlayersMail(t *testing.T) {
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(mailService)
+	var sent []*Receipt
+	s.Connect("alice", func(note interface{}) { sent = append(sent, note.(*Receipt)) })
+
+	bus := events.NewBus(0)
+	s.Subscribe(bus)
+	bus.Publish(events.TopicTxCompleted, events.TxCompleted{
+		PlayerIDs: []string{"alice", "bob"},
+		Kind:      "trade",
+		Digest:    "D1",
+		Status:    events.TxSuccess,
+		Summary:   "Trade t-1 completed",
+	})
+	bus.Close()
+
+	if len(sent) != 1 || sent[0].Digest != "D1" || sent[0].Status != events.TxSuccess || sent[0].At.IsZero() {
+		t.Fatalf("alice's session got %+v", sent)
+	}
+	if inbox, _ := mailService.Inbox("alice"); len(inbox) != 0 {
+		t.Errorf("alice was mailed too: %+v", inbox)
+	}
+	inbox, unread := mailService.Inbox("bob")
+	if unread != 1 || inbox[0].Kind != MailKind || inbox[0].Ref != "D1" || inbox[0].Subject != "Trade t-1 completed" {
+		t.Fatalf("bob's inbox = %+v", inbox)
+	}
+
+	s.Disconnect("alice")
+	s.Deliver(Receipt{PlayerID: "alice", Kind: "crafting", Status: events.TxFailure, Summary: "Iron Sword could not be minted"})
+	if inbox, _ := mailService.Inbox("alice"); len(inbox) != 1 || inbox[0].Body != "Iron Sword could not be minted. Nothing was recorded on chain; support can look into it." {
+		t.Errorf("alice's inbox after disconnecting = %+v", inbox)
+	}
+}
@@ -0,0 +1,569 @@
+// Package recruitment keeps the guild recruitment board. Guild officers post
+// a listing saying what their guild is like and whom it looks for; players
+// search the board and apply with a short message, and officers accept or
+// reject each application. Both sides hear about it by mail.
+//
+// Accepting an application does not change the roster, which mirrors the
+// guild contract: the guild still invites the player on chain, and the
+// player is a member once the chain mirror sees the invite execute.
+package recruitment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/model"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultListingTTL          = 30 * 24 * time.Hour
+	DefaultApplicationTTL      = 7 * 24 * time.Hour
+	DefaultMaxOpenApplications = 5
+	DefaultMaxPendingPerGuild  = 50
+)
+
+// Limits of listings and applications.
+const (
+	maxDescription  = 500
+	maxRequirements = 200
+	maxTags         = 5
+	maxTag          = 16
+	maxMessage      = 300
+	maxNote         = 200
+	maxSearch       = 50
+)
+
+// Application statuses.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+var (
+	ErrNoGuild       = errors.New("you are not in a guild")
+	ErrNotPermitted  = errors.New("your guild rank does not allow recruiting")
+	ErrInvalid       = errors.New("invalid recruitment listing")
+	ErrUnknownGuild  = errors.New("no such guild")
+	ErrNotRecruiting = errors.New("that guild is not recruiting")
+	ErrInGuild       = errors.New("the player is already in a guild")
+	ErrApplied       = errors.New("you already applied to that guild")
+	ErrTooMany       = errors.New("too many open applications")
+	ErrNoApplication = errors.New("no such application")
+	ErrDecided       = errors.New("that application was already decided")
+)
+
+// Listing is a guild's post on the recruitment board.
+type Listing struct {
+	GuildID      string    `json:"guildId"`
+	Description  string    `json:"description"`
+	Requirements string    `json:"requirements,omitempty"` // What the guild expects of applicants, in its own words
+	Tags         []string  `json:"tags,omitempty"`         // Lower case, e.g. "pvp", "casual", "eu"
+	PostedBy     string    `json:"postedBy"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+
+	GuildName string `json:"-"` // From the roster, filled in on the way out
+	Members   int    `json:"-"`
+}
+
+// Application is a player's request to join a guild.
+type Application struct {
+	ID        string    `json:"id"`
+	GuildID   string    `json:"guildId"`
+	PlayerID  string    `json:"playerId"`
+	Message   string    `json:"message,omitempty"`
+	Status    string    `json:"status"`
+	AppliedAt time.Time `json:"appliedAt"`
+	DecidedBy string    `json:"decidedBy,omitempty"`
+	DecidedAt time.Time `json:"decidedAt"`
+	Note      string    `json:"note,omitempty"` // The reviewer's word to the applicant
+	ExpiresAt time.Time `json:"expiresAt"`      // Pending: when it lapses undecided. Decided: when it is forgotten
+}
+
+// Board is what a guild's recruiters see: its listing, if it has one, and
+// the applications waiting for a decision, oldest first.
+type Board struct {
+	GuildID      string
+	Listing      *Listing
+	Applications []Application
+}
+
+// Options configures a Service.
+type Options struct {
+	ListingTTL          time.Duration // How long a listing stays up unless posted again
+	ApplicationTTL      time.Duration // How long an application waits for a decision, and is kept after one
+	MaxOpenApplications int           // Pending applications per player
+	MaxPendingPerGuild  int           // Pending applications per guild
+}
+
+// Service keeps the recruitment board of the guilds of a roster. It is safe
+// for concurrent use.
+type Service struct {
+	store  Store
+	roster *guilds.Roster
+	opts   Options
+	ranks  *guildranks.Service // Decides who recruits; the roster's officers if nil
+	mail   *mail.Service       // Tells recruiters and applicants about applications; nil sends none
+	now    func() time.Time
+
+	mu    sync.Mutex
+	state State
+}
+
+// NewService creates a Service for the guilds of roster and loads its state.
+func NewService(store Store, roster *guilds.Roster, opts Options) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load guild recruitment: %w", err)
+	}
+	if opts.ListingTTL <= 0 {
+		opts.ListingTTL = DefaultListingTTL
+	}
+	if opts.ApplicationTTL <= 0 {
+		opts.ApplicationTTL = DefaultApplicationTTL
+	}
+	if opts.MaxOpenApplications <= 0 {
+		opts.MaxOpenApplications = DefaultMaxOpenApplications
+	}
+	if opts.MaxPendingPerGuild <= 0 {
+		opts.MaxPendingPerGuild = DefaultMaxPendingPerGuild
+	}
+	return &Service{store: store, roster: roster, opts: opts, now: time.Now, state: state}, nil
+}
+
+// UseRanks lets the ranks the guild's permission matrix gives the invite
+// permission recruit, instead of the roster's officers.
+func (s *Service) UseRanks(ranks *guildranks.Service) {
+	s.ranks = ranks
+}
+
+// UseMail mails recruiters the applications their guild receives, and
+// applicants the decisions on theirs.
+func (s *Service) UseMail(mailService *mail.Service) {
+	s.mail = mailService
+}
+
+// Post puts up or replaces the listing of playerID's guild; playerID must be
+// allowed to recruit.
+func (s *Service) Post(playerID string, listing Listing) (Board, error) {
+	guild, err := s.recruiting(playerID)
+	if err != nil {
+		return Board{}, err
+	}
+	listing, err = validate(listing)
+	if err != nil {
+		return Board{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.prune(now)
+	listing.GuildID, listing.PostedBy = guild.ID, playerID
+	listing.UpdatedAt, listing.ExpiresAt = now, now.Add(s.opts.ListingTTL)
+	if existing := s.listing(guild.ID); existing != nil {
+		*existing = listing
+	} else {
+		s.state.Listings = append(s.state.Listings, listing)
+	}
+	s.save()
+	utils.LogInfof("Recruitment: %s posted the listing of guild %s (tags %v).", playerID, guild.ID, listing.Tags)
+	return s.board(guild), nil
+}
+
+// Close takes down the listing of playerID's guild. Applications already
+// made stay up for review.
+func (s *Service) Close(playerID string) (Board, error) {
+	guild, err := s.recruiting(playerID)
+	if err != nil {
+		return Board{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	kept := s.state.Listings[:0]
+	for _, l := range s.state.Listings {
+		if l.GuildID != guild.ID {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == len(s.state.Listings) {
+		return Board{}, ErrNotRecruiting
+	}
+	s.state.Listings = kept
+	s.save()
+	utils.LogInfof("Recruitment: %s closed the listing of guild %s.", playerID, guild.ID)
+	return s.board(guild), nil
+}
+
+// Board returns the listing and pending applications of playerID's guild;
+// playerID must be allowed to recruit.
+func (s *Service) Board(playerID string) (Board, error) {
+	guild, err := s.recruiting(playerID)
+	if err != nil {
+		return Board{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	return s.board(guild), nil
+}
+
+// Search returns the listings whose guild name, description or requirements
+// contain query and that carry tag, each if not empty, most recently posted
+// first. At most limit come back, 20 if limit is not positive.
+func (s *Service) Search(query, tag string, limit int) []Listing {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > maxSearch {
+		limit = maxSearch
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	s.mu.Lock()
+	s.prune(s.now())
+	var found []Listing
+	for _, l := range s.state.Listings {
+		l = s.fill(l)
+		if tag != "" && !hasTag(l, tag) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(l.GuildName+"\n"+l.Description+"\n"+l.Requirements), query) {
+			continue
+		}
+		found = append(found, l)
+	}
+	s.mu.Unlock()
+	sort.SliceStable(found, func(i, j int) bool { return found[i].UpdatedAt.After(found[j].UpdatedAt) })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// Apply asks to join guildID on behalf of playerID, who must be in no guild,
+// and returns playerID's applications.
+func (s *Service) Apply(playerID, guildID, message string) ([]Application, error) {
+	if _, ok := s.roster.GuildOf(playerID); ok {
+		return nil, ErrInGuild
+	}
+	guild, ok := s.roster.Guild(guildID)
+	if !ok {
+		return nil, ErrUnknownGuild
+	}
+	message = strings.TrimSpace(message)
+	if len(message) > maxMessage {
+		return nil, fmt.Errorf("%w: a message has at most %d characters", ErrInvalid, maxMessage)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	s.prune(now)
+	if s.listing(guild.ID) == nil {
+		s.mu.Unlock()
+		return nil, ErrNotRecruiting
+	}
+	open, waiting := 0, 0
+	for _, a := range s.state.Applications {
+		if a.Status != StatusPending {
+			continue
+		}
+		if a.PlayerID == playerID {
+			if a.GuildID == guild.ID {
+				s.mu.Unlock()
+				return nil, ErrApplied
+			}
+			open++
+		}
+		if a.GuildID == guild.ID {
+			waiting++
+		}
+	}
+	switch {
+	case open >= s.opts.MaxOpenApplications:
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: at most %d at a time", ErrTooMany, s.opts.MaxOpenApplications)
+	case waiting >= s.opts.MaxPendingPerGuild:
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: the guild has %d applications to review", ErrTooMany, waiting)
+	}
+	s.state.NextID++
+	application := Application{
+		ID:        "app-" + strconv.FormatUint(s.state.NextID, 10),
+		GuildID:   guild.ID,
+		PlayerID:  playerID,
+		Message:   message,
+		Status:    StatusPending,
+		AppliedAt: now,
+		ExpiresAt: now.Add(s.opts.ApplicationTTL),
+	}
+	s.state.Applications = append(s.state.Applications, application)
+	s.save()
+	applications := s.applications(playerID)
+	s.mu.Unlock()
+
+	utils.LogInfof("Recruitment: %s applied to guild %s (%s).", playerID, guild.ID, application.ID)
+	body := fmt.Sprintf("%s asks to join %s.", playerID, guildName(guild))
+	if message != "" {
+		body += "\n\n" + message
+	}
+	for _, recruiterID := range s.recruiters(guild) {
+		s.mail.Send(mail.Message{To: recruiterID, Kind: "guild.application_received", Subject: fmt.Sprintf("%s applied to %s", playerID, guildName(guild)), Body: body, Ref: application.ID})
+	}
+	return applications, nil
+}
+
+// Withdraw takes back playerID's pending application applicationID.
+func (s *Service) Withdraw(playerID, applicationID string) ([]Application, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	i := s.find(applicationID)
+	if i < 0 || s.state.Applications[i].PlayerID != playerID {
+		return nil, ErrNoApplication
+	}
+	if s.state.Applications[i].Status != StatusPending {
+		return nil, ErrDecided
+	}
+	guildID := s.state.Applications[i].GuildID
+	s.state.Applications = append(s.state.Applications[:i], s.state.Applications[i+1:]...)
+	s.save()
+	utils.LogInfof("Recruitment: %s withdrew application %s to guild %s.", playerID, applicationID, guildID)
+	return s.applications(playerID), nil
+}
+
+// Applications returns playerID's applications, newest first.
+func (s *Service) Applications(playerID string) []Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	return s.applications(playerID)
+}
+
+// Review accepts or rejects applicationID to playerID's guild; playerID must
+// be allowed to recruit. An applicant who joined another guild meanwhile
+// cannot be accepted. It returns the guild's board and the decided
+// application.
+func (s *Service) Review(playerID, applicationID string, accept bool, note string) (Board, Application, error) {
+	guild, err := s.recruiting(playerID)
+	if err != nil {
+		return Board{}, Application{}, err
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > maxNote {
+		return Board{}, Application{}, fmt.Errorf("%w: a note has at most %d characters", ErrInvalid, maxNote)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	s.prune(now)
+	i := s.find(applicationID)
+	if i < 0 || s.state.Applications[i].GuildID != guild.ID {
+		s.mu.Unlock()
+		return Board{}, Application{}, ErrNoApplication
+	}
+	application := &s.state.Applications[i]
+	if application.Status != StatusPending {
+		s.mu.Unlock()
+		return Board{}, Application{}, ErrDecided
+	}
+	if _, ok := s.roster.GuildOf(application.PlayerID); ok && accept {
+		s.mu.Unlock()
+		return Board{}, Application{}, ErrInGuild
+	}
+	application.Status = StatusRejected
+	if accept {
+		application.Status = StatusAccepted
+	}
+	application.DecidedBy, application.DecidedAt, application.Note = playerID, now, note
+	application.ExpiresAt = now.Add(s.opts.ApplicationTTL)
+	decided := *application
+	s.save()
+	board := s.board(guild)
+	s.mu.Unlock()
+
+	utils.LogInfof("Recruitment: %s %s application %s of %s to guild %s.", playerID, decided.Status, decided.ID, decided.PlayerID, guild.ID)
+	subject := fmt.Sprintf("%s turned down your application", guildName(guild))
+	body := fmt.Sprintf("%s decided not to take you in this time.", guildName(guild))
+	if accept {
+		subject = fmt.Sprintf("%s accepted your application", guildName(guild))
+		body = fmt.Sprintf("Welcome! %s accepted you. Sign the guild's invite when it arrives to become a member.", guildName(guild))
+	}
+	if note != "" {
+		body += "\n\n" + note
+	}
+	s.mail.Send(mail.Message{To: decided.PlayerID, Kind: "guild.application_" + decided.Status, Subject: subject, Body: body, Ref: decided.ID})
+	return board, decided, nil
+}
+
+// recruiting returns playerID's guild if playerID may recruit for it.
+func (s *Service) recruiting(playerID string) (model.Guild, error) {
+	guild, ok := s.roster.GuildOf(playerID)
+	if !ok {
+		return model.Guild{}, ErrNoGuild
+	}
+	if !s.mayRecruit(guild.ID, playerID) {
+		return model.Guild{}, ErrNotPermitted
+	}
+	return guild, nil
+}
+
+// mayRecruit reports whether playerID may post for and review applications
+// to guildID.
+func (s *Service) mayRecruit(guildID, playerID string) bool {
+	if s.ranks != nil {
+		return s.ranks.Can(guildID, playerID, guildranks.PermInvite)
+	}
+	return s.roster.IsOfficer(guildID, playerID)
+}
+
+// recruiters returns the members of guild who may review its applications.
+func (s *Service) recruiters(guild model.Guild) []string {
+	var ids []string
+	for _, memberID := range guild.MemberIDs {
+		if s.mayRecruit(guild.ID, memberID) {
+			ids = append(ids, memberID)
+		}
+	}
+	return ids
+}
+
+// listing returns guildID's listing, or nil. The caller holds s.mu.
+func (s *Service) listing(guildID string) *Listing {
+	for i := range s.state.Listings {
+		if s.state.Listings[i].GuildID == guildID {
+			return &s.state.Listings[i]
+		}
+	}
+	return nil
+}
+
+// find returns the index of applicationID, or -1. The caller holds s.mu.
+func (s *Service) find(applicationID string) int {
+	for i, a := range s.state.Applications {
+		if a.ID == applicationID {
+			return i
+		}
+	}
+	return -1
+}
+
+// board returns guild's board. The caller holds s.mu.
+func (s *Service) board(guild model.Guild) Board {
+	board := Board{GuildID: guild.ID}
+	if l := s.listing(guild.ID); l != nil {
+		listing := s.fill(*l)
+		board.Listing = &listing
+	}
+	for _, a := range s.state.Applications {
+		if a.GuildID == guild.ID && a.Status == StatusPending {
+			board.Applications = append(board.Applications, a)
+		}
+	}
+	return board
+}
+
+// applications returns playerID's applications, newest first. The caller
+// holds s.mu.
+func (s *Service) applications(playerID string) []Application {
+	applications := []Application{}
+	for i := len(s.state.Applications) - 1; i >= 0; i-- { // Applications are kept in the order they were made
+		if a := s.state.Applications[i]; a.PlayerID == playerID {
+			applications = append(applications, a)
+		}
+	}
+	return applications
+}
+
+// fill copies l with its guild's name and size from the roster.
+func (s *Service) fill(l Listing) Listing {
+	l.Tags = append([]string(nil), l.Tags...)
+	if guild, ok := s.roster.Guild(l.GuildID); ok {
+		l.GuildName, l.Members = guildName(guild), len(guild.MemberIDs)
+	}
+	return l
+}
+
+// prune drops the listings and applications that expired by now, and the
+// listings of guilds no longer on the roster. The caller holds s.mu.
+func (s *Service) prune(now time.Time) {
+	listings := s.state.Listings[:0]
+	for _, l := range s.state.Listings {
+		if _, ok := s.roster.Guild(l.GuildID); ok && now.Before(l.ExpiresAt) {
+			listings = append(listings, l)
+		}
+	}
+	applications := s.state.Applications[:0]
+	for _, a := range s.state.Applications {
+		if now.Before(a.ExpiresAt) {
+			applications = append(applications, a)
+		}
+	}
+	if len(listings) != len(s.state.Listings) || len(applications) != len(s.state.Applications) {
+		s.state.Listings, s.state.Applications = listings, applications
+		s.save()
+	}
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Recruitment: Failed to save guild recruitment: %v", err)
+	}
+}
+
+// validate trims listing and checks its lengths, lower casing its tags.
+func validate(listing Listing) (Listing, error) {
+	listing.Description = strings.TrimSpace(listing.Description)
+	listing.Requirements = strings.TrimSpace(listing.Requirements)
+	switch {
+	case listing.Description == "":
+		return Listing{}, fmt.Errorf("%w: a listing needs a description", ErrInvalid)
+	case len(listing.Description) > maxDescription:
+		return Listing{}, fmt.Errorf("%w: a description has at most %d characters", ErrInvalid, maxDescription)
+	case len(listing.Requirements) > maxRequirements:
+		return Listing{}, fmt.Errorf("%w: requirements have at most %d characters", ErrInvalid, maxRequirements)
+	case len(listing.Tags) > maxTags:
+		return Listing{}, fmt.Errorf("%w: at most %d tags", ErrInvalid, maxTags)
+	}
+	tags := make([]string, 0, len(listing.Tags))
+	seen := make(map[string]bool)
+	for _, tag := range listing.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTag || strings.ContainsAny(tag, " \t\n") {
+			return Listing{}, fmt.Errorf("%w: tag %q is not one word of at most %d characters", ErrInvalid, tag, maxTag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	listing.Tags = tags
+	return listing, nil
+}
+
+func hasTag(l Listing, tag string) bool {
+	for _, t := range l.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func guildName(g model.Guild) string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return g.ID
+}
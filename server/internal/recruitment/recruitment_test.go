@@ -0,0 +1,153 @@
+package recruitment
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/model"
+)
+
+func newTestService(t *testing.T, store Store) (*Service, *mail.Service, *time.Time) {
+	t.Helper()
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", Name: "Wolves", LeaderID: "ann", MemberIDs: []string{"ann", "amy", "abe"}, OfficerIDs: []string{"amy"}},
+		{ID: "bears", Name: "Bears", LeaderID: "bob", MemberIDs: []string{"bob"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(store, roster, Options{ApplicationTTL: time.Hour, MaxOpenApplications: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UseMail(mailService)
+	clock := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+	return s, mailService, &clock
+}
+
+func TestListingsAreSearchable(t *testing.T) {
+	s, _, clock := newTestService(t, &MemoryStore{})
+
+	if _, err := s.Post("abe", Listing{Description: "Raiders"}); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("post by a member: %v", err)
+	}
+	if _, err := s.Post("cat", Listing{Description: "Raiders"}); !errors.Is(err, ErrNoGuild) {
+		t.Fatalf("post without a guild: %v", err)
+	}
+	if _, err := s.Post("ann", Listing{Description: "Raiders", Tags: []string{"two words"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("post with a bad tag: %v", err)
+	}
+	board, err := s.Post("amy", Listing{Description: " Weekly raids, EU evenings. ", Requirements: "Voice chat", Tags: []string{"PvE", "eu", "pve"}})
+	if err != nil || board.Listing == nil || board.Listing.Description != "Weekly raids, EU evenings." || !reflect.DeepEqual(board.Listing.Tags, []string{"pve", "eu"}) {
+		t.Fatalf("post = %+v, %v", board, err)
+	}
+	*clock = clock.Add(time.Minute)
+	if _, err := s.Post("bob", Listing{Description: "Casual crafting guild", Tags: []string{"casual"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	found := s.Search("", "", 0)
+	if len(found) != 2 || found[0].GuildID != "bears" || found[1].GuildName != "Wolves" || found[1].Members != 3 {
+		t.Fatalf("search all = %+v", found)
+	}
+	if found := s.Search("RAIDS", "", 0); len(found) != 1 || found[0].GuildID != "wolves" {
+		t.Fatalf("search by text = %+v", found)
+	}
+	if found := s.Search("bears", "casual", 0); len(found) != 1 || found[0].GuildID != "bears" {
+		t.Fatalf("search by name and tag = %+v", found)
+	}
+	if found := s.Search("", "pvp", 0); len(found) != 0 {
+		t.Fatalf("search by a tag nobody has = %+v", found)
+	}
+
+	if _, err := s.Close("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Close("bob"); !errors.Is(err, ErrNotRecruiting) {
+		t.Fatalf("second close: %v", err)
+	}
+	*clock = clock.Add(DefaultListingTTL)
+	if found := s.Search("", "", 0); len(found) != 0 {
+		t.Fatalf("expired listings are found: %+v", found)
+	}
+}
+
+func TestApplicationsAreReviewedAndMailed(t *testing.T) {
+	store := &MemoryStore{}
+	s, mailService, clock := newTestService(t, store)
+	if _, err := s.Post("ann", Listing{Description: "Wolves recruit"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Post("bob", Listing{Description: "Bears recruit"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Apply("abe", "bears", ""); !errors.Is(err, ErrInGuild) {
+		t.Fatalf("application by a guild member: %v", err)
+	}
+	applications, err := s.Apply("cat", "wolves", "I tank.")
+	if err != nil || len(applications) != 1 || applications[0].Status != StatusPending {
+		t.Fatalf("apply = %+v, %v", applications, err)
+	}
+	if _, err := s.Apply("cat", "wolves", ""); !errors.Is(err, ErrApplied) {
+		t.Fatalf("second application to the wolves: %v", err)
+	}
+	if _, err := s.Apply("cat", "bears", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Apply("dan", "nowhere", ""); !errors.Is(err, ErrUnknownGuild) {
+		t.Fatalf("application to a missing guild: %v", err)
+	}
+	for _, recruiter := range []string{"ann", "amy"} {
+		if inbox, _ := mailService.Inbox(recruiter); len(inbox) != 1 || inbox[0].Kind != "guild.application_received" || inbox[0].Ref != applications[0].ID {
+			t.Fatalf("%s's inbox = %+v", recruiter, inbox)
+		}
+	}
+	if inbox, _ := mailService.Inbox("abe"); len(inbox) != 0 {
+		t.Fatalf("a member who may not recruit got %+v", inbox)
+	}
+
+	id := applications[0].ID
+	if _, _, err := s.Review("abe", id, true, ""); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("review by a member: %v", err)
+	}
+	if _, _, err := s.Review("bob", id, true, ""); !errors.Is(err, ErrNoApplication) {
+		t.Fatalf("review by another guild: %v", err)
+	}
+	board, decided, err := s.Review("amy", id, true, "See you at the raid.")
+	if err != nil || decided.Status != StatusAccepted || decided.DecidedBy != "amy" || len(board.Applications) != 0 {
+		t.Fatalf("accept = %+v %+v, %v", board, decided, err)
+	}
+	if _, _, err := s.Review("ann", id, false, ""); !errors.Is(err, ErrDecided) {
+		t.Fatalf("second review: %v", err)
+	}
+	if inbox, _ := mailService.Inbox("cat"); len(inbox) != 1 || inbox[0].Kind != "guild.application_accepted" {
+		t.Fatalf("cat's inbox = %+v", inbox)
+	}
+	if _, err := s.Withdraw("cat", id); !errors.Is(err, ErrDecided) {
+		t.Fatalf("withdraw of an accepted application: %v", err)
+	}
+
+	// State survives a restart; applications lapse after their TTL.
+	s, err = NewService(store, s.roster, s.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *clock }
+	if applications := s.Applications("cat"); len(applications) != 2 || applications[1].Status != StatusAccepted {
+		t.Fatalf("cat's applications after a restart = %+v", applications)
+	}
+	*clock = clock.Add(time.Hour)
+	if applications := s.Applications("cat"); len(applications) != 0 {
+		t.Fatalf("applications outlived their TTL: %+v", applications)
+	}
+}
@@ -0,0 +1,19 @@
+package recruitment
+
+import "github.com/phuhao00/suigserver/server/internal/jsonstore"
+
+// State is the persisted recruitment board.
+type State struct {
+	Listings     []Listing     `json:"listings"`               // At most one per guild
+	Applications []Application `json:"applications,omitempty"` // Pending, and decided ones until they expire
+	NextID       uint64        `json:"nextId"`
+}
+
+// Store persists the recruitment state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the recruitment state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the recruitment state in a JSON file.
+type FileStore = jsonstore.File[State]
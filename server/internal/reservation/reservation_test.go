@@ -0,0 +1,49 @@
+package reservation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReserveConflictsAndExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry()
+	r.now = func() time.Time { return now }
+
+	if err := r.Reserve("trade:1", time.Minute, "0xsword", "0xshield"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reserve("marketplace:list:alice", time.Minute, "0xhelm", "0xsword"); !errors.Is(err, ErrReserved) {
+		t.Fatalf("Reserve of a held item: got %v, want ErrReserved", err)
+	}
+	if _, ok := r.Holder("0xhelm"); ok {
+		t.Error("a failed Reserve kept part of its objects")
+	}
+	if err := r.Reserve("trade:1", 2*time.Minute, "0xsword"); err != nil {
+		t.Errorf("holder renewing its own reservation: %v", err)
+	}
+
+	r.Release("marketplace:list:alice", "0xshield")
+	if res, ok := r.Holder("0xshield"); !ok || res.Holder != "trade:1" {
+		t.Error("Release freed another holder's object")
+	}
+	r.Release("trade:1", "0xshield")
+	if err := r.Reserve("marketplace:list:alice", time.Minute, "0xshield"); err != nil {
+		t.Errorf("reserving a released object: %v", err)
+	}
+
+	now = now.Add(90 * time.Second)
+	if err := r.Reserve("marketplace:list:alice", time.Minute, "0xsword"); !errors.Is(err, ErrReserved) {
+		t.Errorf("renewed reservation expired early: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := r.Reserve("marketplace:list:alice", time.Minute, "0xsword"); err != nil {
+		t.Errorf("reserving an expired reservation: %v", err)
+	}
+
+	r.Release("marketplace:list:alice")
+	if list := r.List(); len(list) != 0 {
+		t.Errorf("after releasing everything: %v", list)
+	}
+}
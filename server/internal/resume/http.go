@@ -0,0 +1,34 @@
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// RegisterHandlers adds the admin endpoint to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator, which is logged.
+//
+//	POST /admin/players/resume-tokens/revoke   {"playerId": "..."} invalidates every resume token of a compromised account
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/players/resume-tokens/revoke", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		var req struct {
+			PlayerID string `json:"playerId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId is required"))
+			return
+		}
+		revoked := s.RevokeAll(req.PlayerID)
+		utils.LogInfof("Resume: %s revoked %d resume token(s) of player %s.", operator, revoked, req.PlayerID)
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]int{"revoked": revoked})
+	}))
+}
@@ -0,0 +1,226 @@
+// Package resume issues the tokens clients reconnect with instead of their
+// credentials. A token is handed out at auth and is good for one resume: the
+// session it resumes gets a new one. Tokens are bound to the account and,
+// optionally, to the client's IP and device, and stay valid while their
+// session is connected and for a sliding window after it drops. A chain of
+// resumes ends at a maximum age, after which the player signs in again.
+//
+// Only hashes of tokens are kept. A token presented twice was copied, so its
+// reuse revokes every token of the account.
+package resume
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults used when Options leaves a field zero.
+const (
+	DefaultWindow = 10 * time.Minute
+	DefaultMaxAge = 24 * time.Hour
+)
+
+// Errors returned by Redeem.
+var (
+	ErrInvalid  = errors.New("unknown or expired resume token")
+	ErrReused   = errors.New("resume token was already used; all of the account's tokens are revoked")
+	ErrMismatch = errors.New("resume token was issued to another IP or device")
+)
+
+// Binding is what a token is tied to besides the account. An empty Device
+// matches nothing but an empty Device.
+type Binding struct {
+	IP     string
+	Device string // Fingerprint or ID the client sent
+}
+
+// Options configures a Service.
+type Options struct {
+	Window time.Duration // How long a token outlives its session's disconnect
+	MaxAge time.Duration // How long resumes may go on after a sign-in with credentials
+	BindIP bool          // Tokens are only redeemed from the IP they were issued to
+}
+
+// Grant is a token handed to a client.
+type Grant struct {
+	Token    string
+	Window   time.Duration // Validity after the session drops
+	NotAfter time.Time     // End of the resume chain
+}
+
+type token struct {
+	playerID  string
+	binding   Binding
+	signedIn  time.Time // Start of the resume chain
+	attached  bool      // Its session is connected
+	expiresAt time.Time // When detached
+}
+
+// used remembers a redeemed token, to spot its reuse.
+type used struct {
+	playerID  string
+	expiresAt time.Time
+}
+
+// Service holds the live tokens. It is safe for concurrent use; a nil
+// Service issues nothing and redeems nothing.
+type Service struct {
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	tokens    map[string]*token // By hash
+	used      map[string]used   // By hash
+	lastSweep time.Time
+}
+
+// NewService creates a Service.
+func NewService(opts Options) *Service {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	return &Service{
+		opts:      opts,
+		now:       time.Now,
+		tokens:    make(map[string]*token),
+		used:      make(map[string]used),
+		lastSweep: time.Now(),
+	}
+}
+
+// Issue starts a resume chain for a player who signed in with credentials
+// and returns its first token, attached to the new session.
+func (s *Service) Issue(playerID string, binding Binding) (Grant, error) {
+	if s == nil {
+		return Grant{}, errors.New("session resumption is disabled")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	return s.issue(&token{playerID: playerID, binding: binding, signedIn: now})
+}
+
+// Redeem uses up raw and returns its player with the token that replaces it,
+// attached to the resuming session. A token that does not match binding is
+// used up all the same.
+func (s *Service) Redeem(raw string, binding Binding) (string, Grant, error) {
+	if s == nil {
+		return "", Grant{}, errors.New("session resumption is disabled")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	hash := hashToken(raw)
+	t, ok := s.tokens[hash]
+	if !ok {
+		if u, ok := s.used[hash]; ok {
+			s.revokeAll(u.playerID)
+			return "", Grant{}, ErrReused
+		}
+		return "", Grant{}, ErrInvalid
+	}
+	delete(s.tokens, hash)
+	if s.expired(t, now) {
+		return "", Grant{}, ErrInvalid
+	}
+	s.used[hash] = used{playerID: t.playerID, expiresAt: t.signedIn.Add(s.opts.MaxAge)}
+	if binding.Device != t.binding.Device || (s.opts.BindIP && binding.IP != t.binding.IP) {
+		return "", Grant{}, ErrMismatch
+	}
+	grant, err := s.issue(&token{playerID: t.playerID, binding: binding, signedIn: t.signedIn})
+	return t.playerID, grant, err
+}
+
+// Release starts the window of a token whose session disconnected.
+func (s *Service) Release(raw string) {
+	if s == nil || raw == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tokens[hashToken(raw)]; ok {
+		t.attached, t.expiresAt = false, s.now().Add(s.opts.Window)
+	}
+}
+
+// Revoke invalidates raw, as when its session logs out.
+func (s *Service) Revoke(raw string) {
+	if s == nil || raw == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, hashToken(raw))
+}
+
+// RevokeAll invalidates every token of playerID and returns how many there
+// were.
+func (s *Service) RevokeAll(playerID string) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revokeAll(playerID)
+}
+
+// issue stores t under a new random token. Callers hold s.mu.
+func (s *Service) issue(t *token) (Grant, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return Grant{}, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b[:])
+	t.attached = true
+	s.tokens[hashToken(raw)] = t
+	return Grant{Token: raw, Window: s.opts.Window, NotAfter: t.signedIn.Add(s.opts.MaxAge)}, nil
+}
+
+// revokeAll is RevokeAll for callers that hold s.mu.
+func (s *Service) revokeAll(playerID string) int {
+	revoked := 0
+	for hash, t := range s.tokens {
+		if t.playerID == playerID {
+			delete(s.tokens, hash)
+			revoked++
+		}
+	}
+	return revoked
+}
+
+func (s *Service) expired(t *token, now time.Time) bool {
+	return !now.Before(t.signedIn.Add(s.opts.MaxAge)) || (!t.attached && !now.Before(t.expiresAt))
+}
+
+// sweep now and then drops expired tokens. Callers hold s.mu.
+func (s *Service) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.opts.Window {
+		return
+	}
+	s.lastSweep = now
+	for hash, t := range s.tokens {
+		if s.expired(t, now) {
+			delete(s.tokens, hash)
+		}
+	}
+	for hash, u := range s.used {
+		if !now.Before(u.expiresAt) {
+			delete(s.used, hash)
+		}
+	}
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,80 @@
+package resume
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokensAreSingleUseAndRotate(t *testing.T) {
+	s := NewService(Options{})
+	phone := Binding{IP: "10.0.0.1", Device: "phone"}
+	first, err := s.Issue("alice", phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playerID, second, err := s.Redeem(first.Token, phone)
+	if err != nil || playerID != "alice" || second.Token == first.Token || !second.NotAfter.Equal(first.NotAfter) {
+		t.Fatalf("Redeem = %q, %+v, %v", playerID, second, err)
+	}
+	// Presenting the first token again gives the copy away: every token goes.
+	if _, _, err := s.Redeem(first.Token, phone); !errors.Is(err, ErrReused) {
+		t.Fatalf("reused token: %v", err)
+	}
+	if _, _, err := s.Redeem(second.Token, phone); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("token after reuse: %v", err)
+	}
+
+	third, _ := s.Issue("alice", phone)
+	if _, _, err := s.Redeem(third.Token, Binding{IP: "10.0.0.1", Device: "laptop"}); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("other device: %v", err)
+	}
+	fourth, _ := s.Issue("alice", phone)
+	if _, _, err := s.Redeem(fourth.Token, Binding{IP: "10.0.0.2", Device: "phone"}); err != nil {
+		t.Fatalf("other IP without BindIP: %v", err)
+	}
+}
+
+func TestTokensExpireAfterTheirSessionDrops(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewService(Options{Window: time.Minute, MaxAge: time.Hour, BindIP: true})
+	s.now = func() time.Time { return now }
+	home := Binding{IP: "10.0.0.1"}
+
+	connected, _ := s.Issue("alice", home)
+	now = now.Add(30 * time.Minute) // Connected the whole time
+	if _, _, err := s.Redeem(connected.Token, Binding{IP: "10.0.0.2"}); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("other IP with BindIP: %v", err)
+	}
+
+	dropped, _ := s.Issue("alice", home)
+	s.Release(dropped.Token)
+	now = now.Add(2 * time.Minute)
+	if _, _, err := s.Redeem(dropped.Token, home); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("token after its window: %v", err)
+	}
+
+	old, _ := s.Issue("alice", home)
+	now = now.Add(50 * time.Minute)
+	_, renewed, err := s.Redeem(old.Token, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(15 * time.Minute) // Past the chain's maximum age
+	if _, _, err := s.Redeem(renewed.Token, home); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("token past the maximum age: %v", err)
+	}
+
+	a, _ := s.Issue("alice", home)
+	b, _ := s.Issue("alice", home)
+	s.Revoke(a.Token)
+	if _, _, err := s.Redeem(a.Token, home); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("revoked token: %v", err)
+	}
+	if n := s.RevokeAll("alice"); n != 1 {
+		t.Fatalf("RevokeAll = %d", n)
+	}
+	if _, _, err := s.Redeem(b.Token, home); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("token after RevokeAll: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package ruleset holds the modifiers a room is created with, such as
+// friendly fire or an XP multiplier. A room's rules are fixed for its
+// lifetime; the room consults them when it resolves projectile hits and
+// their rewards, and room listings show them so players can choose.
+package ruleset
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultMaxXPMultiplier is the largest XP multiplier a room may have when
+// Limits.MaxXPMultiplier is zero.
+const DefaultMaxXPMultiplier = 2.0
+
+// ErrInvalid is returned (wrapped) for rules a room cannot be created with.
+var ErrInvalid = errors.New("invalid room rules")
+
+// Ruleset is a room's modifiers.
+type Ruleset struct {
+	FriendlyFire bool    // Members' projectiles hit other members; otherwise only NPCs
+	XPMultiplier float64 // Scales the XP of defeats; 1 leaves it as is
+	NoLoot       bool    // Defeats drop nothing
+	Hardcore     bool    // Defeated members are out until they leave, instead of respawning
+}
+
+// Default is the rules of rooms created without any.
+var Default = Ruleset{FriendlyFire: true, XPMultiplier: 1}
+
+// Limits are what the server lets players choose.
+type Limits struct {
+	MaxXPMultiplier float64 // DefaultMaxXPMultiplier if 0
+}
+
+// Validate rejects rules outside limits.
+func (r Ruleset) Validate(limits Limits) error {
+	maxXP := limits.MaxXPMultiplier
+	if maxXP <= 0 {
+		maxXP = DefaultMaxXPMultiplier
+	}
+	if math.IsNaN(r.XPMultiplier) || r.XPMultiplier <= 0 || r.XPMultiplier > maxXP {
+		return fmt.Errorf("%w: xpMultiplier must be above 0 and at most %g", ErrInvalid, maxXP)
+	}
+	return nil
+}
+
+// Modifiers names the ways r differs from Default, for logs.
+func (r Ruleset) Modifiers() []string {
+	var names []string
+	if !r.FriendlyFire {
+		names = append(names, "no friendly fire")
+	}
+	if r.XPMultiplier != Default.XPMultiplier {
+		names = append(names, fmt.Sprintf("%gx XP", r.XPMultiplier))
+	}
+	if r.NoLoot {
+		names = append(names, "no loot")
+	}
+	if r.Hardcore {
+		names = append(names, "hardcore")
+	}
+	return names
+}
@@ -0,0 +1,39 @@
+package ruleset
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		rules  Ruleset
+		limits Limits
+		ok     bool
+	}{
+		{Default, Limits{}, true},
+		{Ruleset{XPMultiplier: 2}, Limits{}, true},
+		{Ruleset{XPMultiplier: 2.5}, Limits{}, false},
+		{Ruleset{XPMultiplier: 2.5}, Limits{MaxXPMultiplier: 3}, true},
+		{Ruleset{XPMultiplier: -1}, Limits{}, false},
+		{Ruleset{FriendlyFire: true}, Limits{}, false},
+		{Ruleset{XPMultiplier: math.NaN()}, Limits{}, false},
+	} {
+		err := tc.rules.Validate(tc.limits)
+		if tc.ok != (err == nil) || (err != nil && !errors.Is(err, ErrInvalid)) {
+			t.Errorf("%+v within %+v: %v", tc.rules, tc.limits, err)
+		}
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	rules := Ruleset{XPMultiplier: 1.5, NoLoot: true, Hardcore: true}
+	if got, want := rules.Modifiers(), []string{"no friendly fire", "1.5x XP", "no loot", "hardcore"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Modifiers() = %q", got)
+	}
+	if got := Default.Modifiers(); len(got) != 0 {
+		t.Errorf("default Modifiers() = %q", got)
+	}
+}
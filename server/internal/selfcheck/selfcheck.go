@@ -0,0 +1,168 @@
+// Package selfcheck runs a list of deployment checks, such as configuration
+// completeness and dependency reachability, and reports the results.
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn" // Works, but probably not as intended in production
+	StatusFail Status = "fail"
+)
+
+// DefaultTimeout bounds each check.
+const DefaultTimeout = 5 * time.Second
+
+// CheckFunc performs a check. It returns a short description of what it found;
+// an error fails the check, unless it was made with Warnf.
+type CheckFunc func(ctx context.Context) (detail string, err error)
+
+type warning struct{ msg string }
+
+func (w *warning) Error() string { return w.msg }
+
+// Warnf returns an error that marks a check as a warning rather than a failure.
+func Warnf(format string, args ...interface{}) error {
+	return &warning{msg: fmt.Sprintf(format, args...)}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report is the outcome of a Suite run.
+type Report struct {
+	OK       bool      `json:"ok"` // No check failed; warnings are allowed
+	Failures int       `json:"failures"`
+	Warnings int       `json:"warnings"`
+	Checks   []Result  `json:"checks"` // In the order they were added
+	RanAt    time.Time `json:"ranAt"`
+}
+
+type check struct {
+	category string
+	name     string
+	fn       CheckFunc
+}
+
+// Suite is a list of checks.
+type Suite struct {
+	Timeout time.Duration // Per check; DefaultTimeout if zero
+	checks  []check
+}
+
+// Add appends a check. Category groups checks in the report, e.g. "config".
+func (s *Suite) Add(category, name string, fn CheckFunc) {
+	s.checks = append(s.checks, check{category: category, name: name, fn: fn})
+}
+
+// Run runs every check concurrently and waits for them all.
+func (s *Suite) Run(ctx context.Context) Report {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	report := Report{Checks: make([]Result, len(s.checks)), RanAt: time.Now()}
+	var wg sync.WaitGroup
+	for i, c := range s.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		switch result.Status {
+		case StatusFail:
+			report.Failures++
+		case StatusWarn:
+			report.Warnings++
+		}
+	}
+	report.OK = report.Failures == 0
+	return report
+}
+
+func run(ctx context.Context, c check, timeout time.Duration) (result Result) {
+	result = Result{Category: c.category, Name: c.name}
+	start := time.Now()
+	defer func() { result.DurationMs = time.Since(start).Milliseconds() }()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("check panicked: %v", r)}
+			}
+		}()
+		detail, err := c.fn(ctx)
+		done <- outcome{detail, err}
+	}()
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		out.err = fmt.Errorf("no answer within %s", timeout)
+	}
+
+	var warn *warning
+	switch {
+	case out.err == nil:
+		result.Status, result.Detail = StatusOK, out.detail
+	case errors.As(out.err, &warn):
+		result.Status, result.Detail = StatusWarn, out.err.Error()
+	default:
+		result.Status, result.Detail = StatusFail, out.err.Error()
+	}
+	return result
+}
+
+// WriteText writes the report as an aligned table followed by a summary line.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, result := range r.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", statusLabel(result.Status), result.Category, result.Name, result.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verdict := "PASSED"
+	if !r.OK {
+		verdict = "FAILED"
+	}
+	_, err := fmt.Fprintf(w, "\nSelf-check %s: %d checks, %d failed, %d warnings\n", verdict, len(r.Checks), r.Failures, r.Warnings)
+	return err
+}
+
+func statusLabel(status Status) string {
+	switch status {
+	case StatusOK:
+		return "[ OK ]"
+	case StatusWarn:
+		return "[WARN]"
+	}
+	return "[FAIL]"
+}
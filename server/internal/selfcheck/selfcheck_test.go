@@ -0,0 +1,53 @@
+package selfcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunClassifiesChecks(t *testing.T) {
+	suite := &Suite{Timeout: 50 * time.Millisecond}
+	suite.Add("config", "ok", func(context.Context) (string, error) { return "fine", nil })
+	suite.Add("config", "warn", func(context.Context) (string, error) { return "", Warnf("placeholder %s", "id") })
+	suite.Add("connectivity", "fail", func(context.Context) (string, error) { return "", errors.New("refused") })
+	suite.Add("connectivity", "slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return "late", nil
+	})
+	suite.Add("ports", "panics", func(context.Context) (string, error) { panic("boom") })
+
+	report := suite.Run(context.Background())
+	want := []Status{StatusOK, StatusWarn, StatusFail, StatusFail, StatusFail}
+	for i, result := range report.Checks {
+		if result.Status != want[i] {
+			t.Errorf("%s: status %s, want %s (%s)", result.Name, result.Status, want[i], result.Detail)
+		}
+	}
+	if report.OK || report.Failures != 3 || report.Warnings != 1 {
+		t.Errorf("report = ok %v, %d failures, %d warnings", report.OK, report.Failures, report.Warnings)
+	}
+	if report.Checks[1].Detail != "placeholder id" {
+		t.Errorf("warning detail = %q", report.Checks[1].Detail)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "[WARN]  config        warn") || !strings.Contains(out.String(), "Self-check FAILED: 5 checks, 3 failed, 1 warnings") {
+		t.Errorf("unexpected text report:\n%s", out.String())
+	}
+}
+
+func TestWarningsDoNotFailTheReport(t *testing.T) {
+	suite := &Suite{}
+	suite.Add("config", "auth", func(context.Context) (string, error) { return "", Warnf("dummy auth") })
+	if report := suite.Run(context.Background()); !report.OK {
+		t.Errorf("report with only warnings is not OK: %+v", report)
+	}
+}
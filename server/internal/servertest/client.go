@@ -0,0 +1,188 @@
+package servertest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// DefaultTimeout is how long a Client waits for an expected message.
+const DefaultTimeout = 5 * time.Second
+
+// maxFrameSize bounds the frames a Client accepts.
+const maxFrameSize = 1 << 20
+
+// Message is a message received from the server, with its payload undecoded.
+type Message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	Seq     uint64          `json:"seq,omitempty"` // Set on reliable messages
+}
+
+// Decode unmarshals the payload into v.
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// ServerError is returned by Expect when the server answers with an ERROR, or
+// ends the session with a DISCONNECT.
+type ServerError struct {
+	protocol.ErrorResponsePayload
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error %s: %s", e.Code, e.Message)
+}
+
+// Client is a player connection speaking the legacy framing: length-prefixed
+// JSON envelopes, which the server answers in kind. A Client is not safe for
+// concurrent use.
+type Client struct {
+	conn     net.Conn
+	PlayerID string // Set by a successful Auth
+	Timeout  time.Duration
+}
+
+// Dial connects a client to addr.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, Timeout: DefaultTimeout}, nil
+}
+
+// Close closes the connection. The server sees the player disconnect.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Send sends a message of msgType with payload.
+func (c *Client) Send(msgType string, payload interface{}) error {
+	return c.SendWithKey(msgType, "", payload)
+}
+
+// SendWithKey sends a message with an idempotency key, so the server answers
+// a resend with the original result.
+func (c *Client) SendWithKey(msgType, idempotencyKey string, payload interface{}) error {
+	msg, err := protocol.NewMessage(msgType, payload)
+	if err != nil {
+		return err
+	}
+	msg.IdempotencyKey = idempotencyKey
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	frame, err := protocol.EncodeFrame(protocol.FrameVersionLegacy, 0, protocol.EnvelopeTypeID, body)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.Timeout))
+	_, err = c.conn.Write(frame)
+	return err
+}
+
+// Receive returns the next message from the server.
+func (c *Client) Receive() (Message, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.Timeout))
+	frame, err := protocol.ReadFrame(c.conn, maxFrameSize)
+	if err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if err := json.Unmarshal(frame.Body, &msg); err != nil {
+		return Message{}, fmt.Errorf("decode %q: %w", frame.Body, err)
+	}
+	return msg, nil
+}
+
+// Expect reads messages until one of msgType arrives and decodes its payload
+// into v, if v is not nil. Messages of other types are discarded, except an
+// ERROR or a DISCONNECT, which is returned as a *ServerError.
+func (c *Client) Expect(msgType string, v interface{}) error {
+	deadline := time.Now().Add(c.Timeout)
+	for time.Now().Before(deadline) {
+		msg, err := c.Receive()
+		if err != nil {
+			return fmt.Errorf("waiting for %s: %w", msgType, err)
+		}
+		if msg.Type == msgType {
+			if v == nil {
+				return nil
+			}
+			return msg.Decode(v)
+		}
+		if msg.Type == protocol.MsgTypeError {
+			serverErr := &ServerError{}
+			msg.Decode(&serverErr.ErrorResponsePayload)
+			return serverErr
+		}
+		if msg.Type == protocol.MsgTypeDisconnect {
+			var payload protocol.DisconnectPayload
+			msg.Decode(&payload)
+			return &ServerError{protocol.ErrorResponsePayload{Code: payload.Code, Message: payload.Reason}}
+		}
+	}
+	return fmt.Errorf("no %s within %s", msgType, c.Timeout)
+}
+
+// Request sends a message and expects a reply of replyType.
+func (c *Client) Request(msgType string, payload interface{}, replyType string, reply interface{}) error {
+	if err := c.Send(msgType, payload); err != nil {
+		return err
+	}
+	return c.Expect(replyType, reply)
+}
+
+// Auth authenticates with token. A rejected token is an error.
+func (c *Client) Auth(token string) (protocol.AuthResponsePayload, error) {
+	return c.AuthWith(protocol.AuthRequestPayload{Token: token})
+}
+
+// AuthWith authenticates with a full AUTH payload, e.g. to ask for reliable
+// delivery. A rejected token is an error.
+func (c *Client) AuthWith(req protocol.AuthRequestPayload) (protocol.AuthResponsePayload, error) {
+	var resp protocol.AuthResponsePayload
+	if err := c.Request(protocol.MsgTypeAuthRequest, req, protocol.MsgTypeAuthResponse, &resp); err != nil {
+		return resp, err
+	}
+	if !resp.Success {
+		return resp, errors.New("authentication failed: " + resp.Message)
+	}
+	c.PlayerID = resp.PlayerID
+	return resp, nil
+}
+
+// CreateRoom creates a room, which the client joins, and returns its ID.
+func (c *Client) CreateRoom(payload protocol.CreateRoomRequestPayload) (string, error) {
+	var resp protocol.CreateRoomResponsePayload
+	if err := c.Request(protocol.MsgTypeCreateRoomRequest, payload, protocol.MsgTypeCreateRoomResponse, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		return "", errors.New("create room failed: " + resp.Message)
+	}
+	return resp.RoomID, nil
+}
+
+// JoinRoom joins the room matching criteria, e.g. a room ID, and returns its ID.
+func (c *Client) JoinRoom(criteria string) (string, error) {
+	var resp protocol.JoinRoomResponsePayload
+	if err := c.Request(protocol.MsgTypeJoinRoomRequest, protocol.JoinRoomRequestPayload{Criteria: criteria}, protocol.MsgTypeJoinRoomResponse, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		return "", errors.New("join room failed: " + resp.Message)
+	}
+	return resp.RoomID, nil
+}
+
+// Chat says text in the client's room.
+func (c *Client) Chat(text string) error {
+	return c.Send(protocol.MsgTypeSendChat, protocol.ChatMessagePayload{Text: text})
+}
@@ -0,0 +1,197 @@
+// Package servertest boots the game server in-process for end-to-end tests.
+// A Server runs the real actors and TCP server on a random local port, with a
+// SuiStub in place of the chain, and Clients drive it over the real wire
+// protocol. Nothing leaves the machine, so the tests run in CI without a node.
+package servertest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/configs"
+	internalActor "github.com/phuhao00/suigserver/server/internal/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/network"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+)
+
+// requestTimeout bounds the harness's own requests to the server's actors.
+const requestTimeout = 5 * time.Second
+
+// Combat results are recorded through this package, module, sender and gas
+// coin on the stub chain.
+const (
+	CombatPackageID = "0xcombat"
+	CombatModule    = "combat_results"
+	ServerAddress   = "0xserver"
+	ServerGasCoin   = "0xgas"
+)
+
+// Options configures a Server.
+type Options struct {
+	Players  map[string]string             // AUTH token -> player ID
+	Sui      *SuiStub                      // The chain; a new, empty stub if nil
+	Services internalActor.SessionServices // Session services; the harness fills in Auth and Events if unset
+	World    internalActor.WorldServices   // World manager services; the harness fills in Events if unset
+	Rooms    internalActor.RoomServices    // Room manager services
+}
+
+// Server is a running game server.
+type Server struct {
+	System       *actor.ActorSystem
+	RoomManager  *actor.PID
+	WorldManager *actor.PID
+	Sui          *SuiStub
+	SuiClient    *sui.SuiClient // Talks to Sui
+	Events       *events.Bus
+
+	tcp *network.TCPServer
+
+	mu      sync.Mutex
+	clients []*Client
+	closers []func()
+}
+
+// Start boots a server listening on a random port.
+func Start(opts Options) (*Server, error) {
+	stub := opts.Sui
+	if stub == nil {
+		stub = NewSuiStub()
+	}
+	s := &Server{
+		System:    actor.NewActorSystem(),
+		Sui:       stub,
+		SuiClient: sui.NewSuiClientWithAPI("stub", stub),
+		Events:    events.NewBus(events.DefaultSubscriberBuffer),
+	}
+	services := opts.Services
+	if services.Auth == nil {
+		services.Auth = tokenTable(opts.Players)
+	}
+	if services.Events == nil {
+		services.Events = s.Events
+	}
+	world := opts.World
+	if world.Events == nil {
+		world.Events = s.Events
+	}
+
+	s.RoomManager = s.System.Root.Spawn(internalActor.PropsForRoomManagerWithServices(s.System, opts.Rooms))
+	s.WorldManager = s.System.Root.Spawn(internalActor.PropsForWorldManagerWithServices(s.System, world))
+	s.tcp = network.NewTCPServer(0, s.System, s.RoomManager, s.WorldManager, s.SuiClient, false, "", "")
+	s.tcp.SetSessionServices(services)
+	if err := s.tcp.Start(); err != nil {
+		s.System.Shutdown()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Addr returns the loopback address clients connect to.
+func (s *Server) Addr() string {
+	port := s.tcp.Addr().(*net.TCPAddr).Port
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// Dial connects a client that has not authenticated yet.
+func (s *Server) Dial() (*Client, error) {
+	c, err := Dial(s.Addr())
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.clients = append(s.clients, c)
+	s.mu.Unlock()
+	return c, nil
+}
+
+// Login connects a client and authenticates it with token.
+func (s *Server) Login(token string) (*Client, error) {
+	c, err := s.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Auth(token); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// SessionOf returns the session actor of an online player.
+func (s *Server) SessionOf(playerID string) (*actor.PID, error) {
+	result, err := s.System.Root.RequestFuture(internalActor.PlayerRegistryPID(s.WorldManager, playerID), &messages.GetPlayerSession{PlayerID: playerID}, requestTimeout).Result()
+	if err != nil {
+		return nil, err
+	}
+	location, ok := result.(*messages.PlayerSessionLocation)
+	if !ok || location.SessionPID == nil {
+		return nil, fmt.Errorf("player %s is not online", playerID)
+	}
+	return location.SessionPID, nil
+}
+
+// StartCombat starts a turn-based fight. Participants whose Stats.ID is an
+// online player are bound to that player's session; the others are NPCs.
+// Defeats are recorded through CombatPackageID on the stub chain.
+func (s *Server) StartCombat(cfg internalActor.CombatSessionConfig) *actor.PID {
+	participants := make([]internalActor.CombatParticipant, len(cfg.Participants))
+	for i, p := range cfg.Participants {
+		if p.SessionPID == nil {
+			p.SessionPID, _ = s.SessionOf(p.Stats.ID)
+		}
+		participants[i] = p
+	}
+	cfg.Participants = participants
+	engine := game.NewCombatEngine(sui.NewCombatResultsSuiService(s.SuiClient, CombatPackageID, CombatModule, ServerAddress, ServerGasCoin))
+	engine.SetEventBus(s.Events)
+	return s.System.Root.Spawn(internalActor.PropsForCombatSession(engine, cfg))
+}
+
+// Marketplace starts a marketplace service manager on the stub chain. It is
+// closed with the server.
+func (s *Server) Marketplace(config *configs.MarketplaceConfig) (*sui.MarketplaceServiceManager, error) {
+	manager, err := sui.NewMarketplaceServiceManagerWithClient(config, s.SuiClient)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.closers = append(s.closers, func() { manager.Close() })
+	s.mu.Unlock()
+	return manager, nil
+}
+
+// Close disconnects every client and stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	clients, closers := s.clients, s.closers
+	s.clients, s.closers = nil, nil
+	s.mu.Unlock()
+	for _, c := range clients {
+		c.Close()
+	}
+	for _, closer := range closers {
+		closer()
+	}
+	s.tcp.Stop()
+	s.System.Shutdown()
+	s.Events.Close()
+}
+
+// tokenTable authenticates the tokens of Options.Players.
+type tokenTable map[string]string
+
+func (t tokenTable) Authenticate(token string) (string, error) {
+	playerID, ok := t[token]
+	if !ok {
+		return "", errors.New("unknown token")
+	}
+	return playerID, nil
+}
@@ -0,0 +1,2059 @@
+package servertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/configs"
+	internalActor "github.com/phuhao00/suigserver/server/internal/actor"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+	"github.com/phuhao00/suigserver/server/internal/anticheat"
+	"github.com/phuhao00/suigserver/server/internal/arena"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/battlepass"
+	"github.com/phuhao00/suigserver/server/internal/calendar"
+	"github.com/phuhao00/suigserver/server/internal/chatchannels"
+	"github.com/phuhao00/suigserver/server/internal/clientversion"
+	"github.com/phuhao00/suigserver/server/internal/cosmetics"
+	"github.com/phuhao00/suigserver/server/internal/crafting"
+	"github.com/phuhao00/suigserver/server/internal/deeplink"
+	"github.com/phuhao00/suigserver/server/internal/delivery"
+	"github.com/phuhao00/suigserver/server/internal/diplomacy"
+	"github.com/phuhao00/suigserver/server/internal/energy"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/fairroll"
+	"github.com/phuhao00/suigserver/server/internal/game"
+	"github.com/phuhao00/suigserver/server/internal/geometry"
+	"github.com/phuhao00/suigserver/server/internal/guildranks"
+	"github.com/phuhao00/suigserver/server/internal/guilds"
+	"github.com/phuhao00/suigserver/server/internal/hotzone"
+	"github.com/phuhao00/suigserver/server/internal/idempotency"
+	"github.com/phuhao00/suigserver/server/internal/mail"
+	"github.com/phuhao00/suigserver/server/internal/model"
+	"github.com/phuhao00/suigserver/server/internal/movement"
+	"github.com/phuhao00/suigserver/server/internal/npc"
+	"github.com/phuhao00/suigserver/server/internal/orderbook"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/parental"
+	"github.com/phuhao00/suigserver/server/internal/pathfinding"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/prefetch"
+	"github.com/phuhao00/suigserver/server/internal/projectile"
+	"github.com/phuhao00/suigserver/server/internal/ratelimit"
+	"github.com/phuhao00/suigserver/server/internal/receipts"
+	"github.com/phuhao00/suigserver/server/internal/recruitment"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/resume"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/stats"
+	"github.com/phuhao00/suigserver/server/internal/sui"
+	"github.com/phuhao00/suigserver/server/internal/trade"
+	"github.com/phuhao00/suigserver/server/internal/worlds"
+)
+
+func startServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	if opts.Players == nil {
+		opts.Players = map[string]string{"alice-token": "alice", "bob-token": "bob"}
+	}
+	srv, err := Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func login(t *testing.T, srv *Server, token string) *Client {
+	t.Helper()
+	c, err := srv.Login(token)
+	if err != nil {
+		t.Fatalf("login with %s: %v", token, err)
+	}
+	return c
+}
+
+// eventually polls cond until it holds or timeout passes.
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); !cond(); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestAuthRoomsAndChat(t *testing.T) {
+	srv := startServer(t, Options{})
+
+	stranger, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stranger.Auth("forged-token"); err == nil {
+		t.Fatal("a forged token authenticated")
+	}
+
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	if alice.PlayerID != "alice" || bob.PlayerID != "bob" {
+		t.Fatalf("player IDs = %q, %q", alice.PlayerID, bob.PlayerID)
+	}
+
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "harness", Visibility: protocol.RoomVisibilityPrivate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined, err := bob.JoinRoom(roomID); err != nil || joined != roomID {
+		t.Fatalf("bob joined %q, %v; want room %s", joined, err, roomID)
+	}
+
+	if err := alice.Chat("hello bob"); err != nil {
+		t.Fatal(err)
+	}
+	var chat protocol.ChatMessagePayload
+	if err := bob.Expect(protocol.MsgTypeNewChatMessage, &chat); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Text != "hello bob" || chat.SenderName != "alice" {
+		t.Errorf("bob got %+v", chat)
+	}
+
+	var serverErr *ServerError
+	if err := stranger.Request(protocol.MsgTypeSendChat, protocol.ChatMessagePayload{Text: "hi"}, protocol.MsgTypeNewChatMessage, nil); !errors.As(err, &serverErr) {
+		t.Errorf("chat before auth: got %v, want a server error", err)
+	}
+}
+
+func TestCombatRecordsTheDefeatOnChain(t *testing.T) {
+	srv := startServer(t, Options{})
+	alice := login(t, srv, "alice-token")
+
+	srv.StartCombat(internalActor.CombatSessionConfig{
+		CombatID: "harness-fight",
+		Participants: []internalActor.CombatParticipant{
+			{Stats: game.CombatantStats{ID: "alice", Health: 500, MaxHealth: 500, AttackPower: 50, Defense: 5, Speed: 20}, Team: 0},
+			{Stats: game.CombatantStats{ID: "goblin", Health: 10, MaxHealth: 10, AttackPower: 5, Defense: 0, Speed: 1}, Team: 1},
+		},
+	})
+
+	var ended protocol.CombatEndedPayload
+	for done := false; !done; {
+		msg, err := alice.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case protocol.MsgTypeCombatTurn:
+			var turn protocol.CombatTurnPayload
+			msg.Decode(&turn)
+			if err := alice.Send(protocol.MsgTypeCombatAction, protocol.CombatActionPayload{
+				CombatID: turn.CombatID, Action: protocol.CombatActionAttack, TargetID: "goblin",
+			}); err != nil {
+				t.Fatal(err)
+			}
+		case protocol.MsgTypeCombatEnded:
+			msg.Decode(&ended)
+			done = true
+		}
+	}
+	if ended.CombatID != "harness-fight" || ended.WinnerTeam != 0 {
+		t.Fatalf("fight ended %+v, want alice's team to win", ended)
+	}
+
+	eventually(t, DefaultTimeout, "the combat outcome transaction", func() bool {
+		for _, call := range srv.Sui.MoveCalls() {
+			if call.PackageObjectId == CombatPackageID && call.Function == "record_combat_outcome" {
+				return call.Arguments[1] == "alice" && call.Arguments[2] == "goblin"
+			}
+		}
+		return false
+	})
+}
+
+func TestMarketplaceListingFollowsTheChain(t *testing.T) {
+	const (
+		pkg    = "0xmarket"
+		seller = "0xseller"
+		nftID  = "0xsword"
+	)
+	listingCreated := pkg + "::marketplace::ListingCreated"
+	srv := startServer(t, Options{})
+	srv.Sui.PutObject(nftID, seller, pkg+"::items::Sword", map[string]interface{}{"name": "Sword"})
+	gas := srv.Sui.AddCoin(seller, "", 1_000_000_000)
+	srv.Sui.OnExecute("list_nft", func(stub *SuiStub, call models.MoveCallRequest) error {
+		if call.Arguments[1] != nftID {
+			return fmt.Errorf("unexpected NFT %v", call.Arguments[1])
+		}
+		stub.PutObject("0xlisting", call.Signer, pkg+"::marketplace::Listing", nil)
+		stub.EmitEvent(listingCreated, call.Signer, map[string]interface{}{
+			"listing_id": "0xlisting",
+			"seller":     call.Signer,
+			"nft_id":     nftID,
+			"price":      call.Arguments[2],
+		})
+		return nil
+	})
+
+	config := configs.DefaultMarketplaceConfig()
+	config.PackageID = pkg
+	config.MarketplaceObjectID = "0xmarketplace"
+	config.EventPollInterval = 1
+	manager, err := srv.Marketplace(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := reservation.NewRegistry()
+	manager.UseReservations(items)
+	// Event sync starts from the newest event it finds, so let it look first.
+	eventually(t, DefaultTimeout, "event sync to start", func() bool { return srv.Sui.Calls("SuiXQueryEvents") > 0 })
+
+	tx, err := manager.PrepareListNFTForSale(seller, nftID, pkg+"::items::Sword", 250, SuiCoinType, "a sword", nil, gas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, held := items.Holder(nftID); !held {
+		t.Fatal("the NFT is not reserved while its listing is prepared")
+	}
+	result, err := srv.SuiClient.ExecuteTransactionBlock(tx.TxBytes, []string{"seller-signature"})
+	if err != nil || result.Effects.Status.Status != "success" {
+		t.Fatalf("executing the listing: %+v, %v", result.Effects.Status, err)
+	}
+
+	eventually(t, 5*time.Second, "the ListingCreated event to end the reservation", func() bool {
+		_, held := items.Holder(nftID)
+		return !held
+	})
+	listings, _, err := manager.GetListings(listingCreated, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listings) != 1 || listings[0].ID != "0xlisting" || listings[0].Price != 250 {
+		t.Errorf("listings = %+v", listings)
+	}
+}
+
+func TestReliableMessagesAreReplayedAfterReconnecting(t *testing.T) {
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Delivery: delivery.NewStore(0, 0)}})
+	alice, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := alice.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", Reliable: true}); err != nil || !resp.Reliable {
+		t.Fatalf("reliable auth = %+v, %v", resp, err)
+	}
+
+	// Alice wins a one-sided fight, then drops before acknowledging the result.
+	srv.StartCombat(internalActor.CombatSessionConfig{
+		CombatID: "replayed-fight",
+		Participants: []internalActor.CombatParticipant{
+			{Stats: game.CombatantStats{ID: "alice", Health: 500, MaxHealth: 500, AttackPower: 50, Defense: 5, Speed: 20}, Team: 0},
+			{Stats: game.CombatantStats{ID: "goblin", Health: 10, MaxHealth: 10, AttackPower: 5, Defense: 0, Speed: 1}, Team: 1},
+		},
+	})
+	var ended Message
+	for ended.Type != protocol.MsgTypeCombatEnded {
+		if ended, err = alice.Receive(); err != nil {
+			t.Fatal(err)
+		}
+		if ended.Type == protocol.MsgTypeCombatTurn {
+			alice.Send(protocol.MsgTypeCombatAction, protocol.CombatActionPayload{
+				CombatID: "replayed-fight", Action: protocol.CombatActionAttack, TargetID: "goblin",
+			})
+		}
+	}
+	if ended.Seq != 1 {
+		t.Fatalf("COMBAT_ENDED has seq %d, want 1", ended.Seq)
+	}
+	alice.Close()
+
+	again, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := again.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", Reliable: true})
+	if err != nil || resp.Replayed != 1 || resp.Gap {
+		t.Fatalf("resumed auth = %+v, %v; want one replayed message", resp, err)
+	}
+	replayed, err := again.Receive()
+	if err != nil || replayed.Type != protocol.MsgTypeCombatEnded || replayed.Seq != 1 {
+		t.Fatalf("replayed %+v, %v", replayed, err)
+	}
+
+	// Once acknowledged, it is not replayed again.
+	if err := again.Send(protocol.MsgTypeAck, protocol.AckPayload{Seq: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := again.Request(protocol.MsgTypePing, protocol.PingPongPayload{}, protocol.MsgTypePong, nil); err != nil {
+		t.Fatal(err) // The session has handled the ACK
+	}
+	again.Close()
+	third, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := third.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", Reliable: true}); err != nil || resp.Replayed != 0 || resp.Gap {
+		t.Fatalf("auth after the ack = %+v, %v; want nothing to replay", resp, err)
+	}
+}
+
+func TestMovementIsValidatedAgainstTheMap(t *testing.T) {
+	field := &geometry.Map{
+		ID:        "field",
+		Bounds:    geometry.Rect{MaxX: 20, MaxY: 10},
+		Spawn:     geometry.Vec{X: 1, Y: 1},
+		Obstacles: []geometry.Obstacle{{Points: geometry.Rect{MinX: 10, MaxX: 11, MaxY: 10}.Polygon()}},
+	}
+	rules := movement.NewRules([]movement.Ability{{ID: "dash", Kind: movement.KindDash, Range: 6, CooldownMs: 60000}}, map[string]*geometry.Map{"field": field})
+	srv := startServer(t, Options{Rooms: internalActor.RoomServices{Movement: rules}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+
+	if _, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "nowhere", MapID: "atlantis"}); err == nil {
+		t.Fatal("created a room on an unknown map")
+	}
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "field", MapID: "field"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	expectPosition := func(c *Client, match func(protocol.PositionUpdatePayload) bool) protocol.PositionUpdatePayload {
+		t.Helper()
+		for {
+			var update protocol.PositionUpdatePayload
+			if err := c.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+				t.Fatal(err)
+			}
+			if match(update) {
+				return update
+			}
+		}
+	}
+
+	// Walking through the wall snaps alice back to where she was.
+	if err := alice.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 12, Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	corrected := expectPosition(alice, func(u protocol.PositionUpdatePayload) bool { return u.Corrected })
+	if corrected.PlayerID != "alice" || corrected.X != 1 || corrected.Y != 1 {
+		t.Errorf("correction = %+v, want alice back at the spawn point", corrected)
+	}
+
+	var result protocol.MovementAbilityResultPayload
+	if err := alice.Request(protocol.MsgTypeUseMovementAbility, protocol.UseMovementAbilityPayload{AbilityID: "dash", X: 7, Y: 1}, protocol.MsgTypeMovementAbilityResult, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.X != 7 || result.CooldownMs == 0 {
+		t.Fatalf("dash result = %+v", result)
+	}
+	seen := expectPosition(bob, func(u protocol.PositionUpdatePayload) bool { return u.AbilityID == "dash" })
+	if seen.PlayerID != "alice" || seen.X != 7 {
+		t.Errorf("bob saw %+v", seen)
+	}
+
+	if err := alice.Request(protocol.MsgTypeUseMovementAbility, protocol.UseMovementAbilityPayload{AbilityID: "dash", X: 8, Y: 1}, protocol.MsgTypeMovementAbilityResult, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.X != 7 || result.CooldownMs == 0 {
+		t.Errorf("dash on cooldown = %+v", result)
+	}
+}
+
+func TestNPCsChasePlayersAroundWalls(t *testing.T) {
+	yard := &geometry.Map{
+		ID:        "yard",
+		Bounds:    geometry.Rect{MaxX: 20, MaxY: 10},
+		Spawn:     geometry.Vec{X: 2, Y: 2},
+		Obstacles: []geometry.Obstacle{{Points: geometry.Rect{MinX: 8, MaxX: 9, MaxY: 7}.Polygon()}},
+	}
+	maps := map[string]*geometry.Map{"yard": yard}
+	dir := t.TempDir()
+	npcFile := filepath.Join(dir, "npcs.json")
+	os.WriteFile(npcFile, []byte(`{"npcs": [{"id": "wolf", "mapId": "yard", "spawn": {"x": 15, "y": 2}, "speed": 20, "aggroRange": 30}]}`), 0600)
+	roster, err := npc.LoadRoster(npcFile, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Rooms: internalActor.RoomServices{
+		Movement:   movement.NewRules(nil, maps),
+		NPCs:       roster,
+		Navigation: pathfinding.NewLibrary(0.5),
+		Tick:       20 * time.Millisecond,
+	}})
+	alice := login(t, srv, "alice-token")
+	if _, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "yard", MapID: "yard"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wolf comes around the wall and stops next to alice.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if !update.NPC {
+			continue
+		}
+		at := geometry.Vec{X: update.X, Y: update.Y}
+		if !yard.Walkable(at) {
+			t.Fatalf("the wolf walked through the wall at %v", at)
+		}
+		if at.Dist(yard.Spawn) <= 1.01 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the wolf got no closer than %v", at)
+		}
+	}
+}
+
+func TestHotZonesAddNPCsAndOpenOverflowInstances(t *testing.T) {
+	yard := &geometry.Map{ID: "yard", Bounds: geometry.Rect{MaxX: 20, MaxY: 10}, Spawn: geometry.Vec{X: 2, Y: 2}}
+	maps := map[string]*geometry.Map{"yard": yard}
+	npcFile := filepath.Join(t.TempDir(), "npcs.json")
+	os.WriteFile(npcFile, []byte(`{"npcs": [{"id": "wolf", "mapId": "yard", "spawn": {"x": 15, "y": 2}, "speed": 1}]}`), 0600)
+	roster, err := npc.LoadRoster(npcFile, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := balance.Open("", &balance.MemoryHistory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	density := values.Values()
+	density.Density.OverflowPlayers = 2
+	if _, err := values.Update(density, "ops", ""); err != nil {
+		t.Fatal(err)
+	}
+	monitor := hotzone.NewMonitor(values, hotzone.Options{HotPlayers: 2, Sustain: 1, NPCStep: 1, MaxNPCDensity: 2, Interval: 50 * time.Millisecond})
+	srv := startServer(t, Options{
+		Players: map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Rooms:   internalActor.RoomServices{Movement: movement.NewRules(nil, maps), NPCs: roster, Density: monitor},
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "yard", MapID: "yard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both stand at the spawn point, which makes its cell hot.
+	eventually(t, 2*time.Second, "the yard to get hot", func() bool {
+		monitor.Evaluate()
+		return values.Values().Density.NPCMultiplier("yard") == 2
+	})
+	if snapshot := values.Current(); snapshot.Source != balance.SourceAuto || !snapshot.Values.Density.Overflows("yard") {
+		t.Fatalf("balance version = %+v", snapshot)
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.NPC && update.PlayerID == "wolf~2" {
+			break
+		}
+	}
+	carol := login(t, srv, "carol-token")
+	if joined, err := carol.JoinRoom(roomID); err != nil || joined != roomID+"-overflow-1" {
+		t.Fatalf("joining the crowded room = %q, %v", joined, err)
+	}
+}
+
+func TestProjectilesHitPlayersAndExpireAtWalls(t *testing.T) {
+	field := &geometry.Map{
+		ID:        "field",
+		Bounds:    geometry.Rect{MaxX: 20, MaxY: 10},
+		Spawn:     geometry.Vec{X: 1, Y: 1},
+		Obstacles: []geometry.Obstacle{{Points: geometry.Rect{MinX: 10, MaxX: 11, MaxY: 10}.Polygon()}},
+	}
+	srv := startServer(t, Options{Rooms: internalActor.RoomServices{
+		Movement: movement.NewRules(nil, map[string]*geometry.Map{"field": field}),
+		Tick:     20 * time.Millisecond,
+		Projectiles: projectile.NewArsenal([]projectile.Definition{
+			{ID: "arrow", Speed: 30, Range: 20, AttackPower: 20, CooldownMs: 60000},
+			{ID: "bolt", Speed: 30, Range: 20, AttackPower: 20},
+		}),
+		Combat:      game.NewCombatEngine(nil),
+		PlayerStats: game.CombatantStats{Health: 100, MaxHealth: 100},
+	}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "field", MapID: "field"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 8, Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.PlayerID == "bob" && update.X == 8 {
+			break
+		}
+	}
+
+	if err := alice.Send(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "arrow", X: 20, Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var spawned protocol.ProjectileSpawnedPayload
+	if err := bob.Expect(protocol.MsgTypeProjectileSpawned, &spawned); err != nil {
+		t.Fatal(err)
+	}
+	if spawned.OwnerID != "alice" || spawned.X != 1 || spawned.DirX != 1 || spawned.Speed != 30 {
+		t.Fatalf("spawned = %+v", spawned)
+	}
+	var hit protocol.ProjectileHitPayload
+	if err := bob.Expect(protocol.MsgTypeProjectileHit, &hit); err != nil {
+		t.Fatal(err)
+	}
+	if hit.ID != spawned.ID || hit.TargetID != "bob" || hit.NPC || hit.Health != 100-hit.Damage {
+		t.Errorf("hit = %+v", hit)
+	}
+	if err := alice.Expect(protocol.MsgTypeProjectileHit, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The arrow is on cooldown; a bolt flies off the top of the map.
+	err = alice.Request(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "arrow", X: 20, Y: 1}, protocol.MsgTypeProjectileSpawned, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != "PROJECTILE_REFUSED" {
+		t.Fatalf("second arrow: %v, want it refused", err)
+	}
+	if err := alice.Send(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "bolt", X: 1, Y: 9}); err != nil {
+		t.Fatal(err)
+	}
+	var expired protocol.ProjectileExpiredPayload
+	if err := alice.Expect(protocol.MsgTypeProjectileExpired, &expired); err != nil {
+		t.Fatal(err)
+	}
+	if expired.Reason != projectile.ReasonWall || expired.Y < 10 {
+		t.Errorf("expired = %+v, want it stopped at the edge of the map", expired)
+	}
+}
+
+func TestRoomRulesAreListedAndApplied(t *testing.T) {
+	// Every shot lands and none is a crit, so the kill is certain.
+	values := balance.Defaults()
+	values.Combat.HitChance, values.Combat.CritChance, values.Combat.EvadeChance = 1, 0, 0
+	combat := game.NewCombatEngine(nil)
+	combat.UseBalance(func() balance.Values { return values })
+	srv := startServer(t, Options{Rooms: internalActor.RoomServices{
+		Tick:        20 * time.Millisecond,
+		Projectiles: projectile.NewArsenal([]projectile.Definition{{ID: "bolt", Speed: 30, Range: 20, AttackPower: 500}}),
+		Combat:      combat,
+		PlayerStats: game.CombatantStats{Health: 100, MaxHealth: 100},
+	}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	tooMuch := 5.0
+	if _, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "greedy", Rules: &protocol.RoomRulesPayload{XPMultiplier: &tooMuch}}); err == nil {
+		t.Fatal("a room with a 5x XP multiplier was created")
+	}
+	xp := 1.5
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "arena", Rules: &protocol.RoomRulesPayload{XPMultiplier: &xp, Hardcore: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Expect(protocol.MsgTypeJoinRoomResponse, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var list protocol.RoomListPayload
+	if err := bob.Request(protocol.MsgTypeListRoomsRequest, nil, protocol.MsgTypeRoomList, &list); err != nil {
+		t.Fatal(err)
+	}
+	var listed *protocol.RoomRulesPayload
+	for _, room := range list.Rooms {
+		if room.RoomID == roomID {
+			listed = room.Rules
+		}
+	}
+	if listed == nil || !listed.Hardcore || listed.NoLoot || *listed.XPMultiplier != 1.5 || !*listed.FriendlyFire {
+		t.Fatalf("rooms = %+v", list.Rooms)
+	}
+
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 8, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.PlayerID == "bob" && update.X == 8 {
+			break
+		}
+	}
+	if err := alice.Send(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "bolt", X: 20, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	var hit protocol.ProjectileHitPayload
+	if err := bob.Expect(protocol.MsgTypeProjectileHit, &hit); err != nil {
+		t.Fatal(err)
+	}
+	if hit.TargetID != "bob" || !hit.Defeated || hit.XP != 150 {
+		t.Fatalf("hit = %+v, want bob defeated for 1.5x XP", hit)
+	}
+
+	// Bob is out of the hardcore room: no respawn, and no more shots.
+	err = bob.Request(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "bolt", X: 0, Y: 0}, protocol.MsgTypeProjectileSpawned, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != "PROJECTILE_REFUSED" {
+		t.Fatalf("bob's shot after elimination: %v, want it refused", err)
+	}
+}
+
+func TestStatDeltasReachThePlayerAndTheirRoom(t *testing.T) {
+	statsService := stats.NewService(stats.Options{TickInterval: 10 * time.Millisecond})
+	statsService.Start()
+	t.Cleanup(statsService.Stop)
+	srv := startServer(t, Options{
+		Rooms: internalActor.RoomServices{
+			Tick:        20 * time.Millisecond,
+			Projectiles: projectile.NewArsenal([]projectile.Definition{{ID: "bolt", Speed: 30, Range: 20, AttackPower: 20}}),
+			Combat:      game.NewCombatEngine(nil),
+			PlayerStats: game.CombatantStats{Health: 100, MaxHealth: 100},
+			Stats:       statsService,
+		},
+		Services: internalActor.SessionServices{Stats: statsService},
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "yard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 8, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.PlayerID == "bob" && update.X == 8 {
+			break
+		}
+	}
+	if err := alice.Send(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "bolt", X: 20, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	var hit protocol.ProjectileHitPayload
+	if err := bob.Expect(protocol.MsgTypeProjectileHit, &hit); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Client{"bob": bob, "alice": alice} {
+		var delta protocol.StatDeltaPayload
+		if err := c.Expect(protocol.MsgTypeStatDelta, &delta); err != nil {
+			t.Fatal(err)
+		}
+		if len(delta.Changes) != 1 || delta.Changes[0].PlayerID != "bob" || delta.Changes[0].Stats[stats.HP] != int64(hit.Health) {
+			t.Errorf("%s got %+v after a hit leaving bob at %d", name, delta, hit.Health)
+		}
+	}
+}
+
+func TestRetriedCommandsAreAnsweredWithTheOriginalResult(t *testing.T) {
+	catalog := &shop.Catalog{Shops: []shop.Shop{{ID: "general", Items: []shop.Item{{ItemID: "potion", BuyPrice: 10}}}}}
+	wallets := &shop.MemoryWallets{StartingCoins: 100}
+	shops, err := shop.NewService(catalog, wallets, &shop.MemoryStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Services: internalActor.SessionServices{
+		Shop:        shops,
+		Idempotency: idempotency.NewCache(idempotency.Options{}),
+	}})
+	alice := login(t, srv, "alice-token")
+	buy := protocol.ShopTransactionRequestPayload{ShopID: "general", ItemID: "potion", Action: protocol.ShopActionBuy}
+
+	var first, retried protocol.ShopTransactionResultPayload
+	for _, result := range []*protocol.ShopTransactionResultPayload{&first, &retried} {
+		if err := alice.SendWithKey(protocol.MsgTypeShopTransaction, "buy-1", buy); err != nil {
+			t.Fatal(err)
+		}
+		if err := alice.Expect(protocol.MsgTypeShopTransactionResult, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !first.Success || first.Coins != 90 || retried != first {
+		t.Fatalf("first = %+v, retried = %+v", first, retried)
+	}
+	if wallet, _ := wallets.LoadWallet("alice"); wallet.Coins != 90 || wallet.Inventory["potion"] != 1 {
+		t.Errorf("wallet after a retried purchase = %+v", wallet)
+	}
+
+	// The key survives a reconnect; a new key buys again.
+	alice.Close()
+	alice = login(t, srv, "alice-token")
+	if err := alice.SendWithKey(protocol.MsgTypeShopTransaction, "buy-1", buy); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Expect(protocol.MsgTypeShopTransactionResult, &retried); err != nil || retried != first {
+		t.Fatalf("retry after reconnecting = %+v, %v", retried, err)
+	}
+	if err := alice.SendWithKey(protocol.MsgTypeShopTransaction, "buy-2", buy); err != nil {
+		t.Fatal(err)
+	}
+	var second protocol.ShopTransactionResultPayload
+	if err := alice.Expect(protocol.MsgTypeShopTransactionResult, &second); err != nil || second.Coins != 80 {
+		t.Fatalf("second purchase = %+v, %v", second, err)
+	}
+}
+
+func TestChatChannels(t *testing.T) {
+	roster, err := guilds.NewRoster([]model.Guild{{ID: "wolves", LeaderID: "alice", MemberIDs: []string{"alice", "carol"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{
+		Players: map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		World: internalActor.WorldServices{Guilds: roster, ChatChannels: chatchannels.Policies{
+			chatchannels.Global: {AutoJoin: true, MessagesPerMinute: 1, Burst: 1},
+			chatchannels.Trade:  {},
+			chatchannels.Zone:   {AutoJoin: true},
+			chatchannels.Guild:  {AutoJoin: true},
+		}},
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	carol := login(t, srv, "carol-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "plaza"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Expect(protocol.MsgTypeJoinRoomResponse, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zone chat reaches bob in the room, guild chat reaches carol outside it.
+	alice.Send(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Zone, Text: "anyone here?"})
+	var got protocol.ChannelMessagePayload
+	if err := bob.Expect(protocol.MsgTypeChannelMessage, &got); err != nil || got.Scope != roomID || got.SenderID != "alice" {
+		t.Fatalf("bob got %+v, %v; want alice's zone message", got, err)
+	}
+	alice.Send(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Guild, Text: "wolves, assemble"})
+	if err := carol.Expect(protocol.MsgTypeChannelMessage, &got); err != nil || got.Channel != chatchannels.Guild || got.Scope != "wolves" {
+		t.Fatalf("carol got %+v, %v; want alice's guild message first", got, err)
+	}
+
+	var serverErr *ServerError
+	err = bob.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Guild, Text: "hi"}, protocol.MsgTypeChannelMessage, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "CHANNEL_UNAVAILABLE" {
+		t.Errorf("guild chat without a guild: %v", err)
+	}
+	err = bob.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Trade, Text: "WTS sword"}, protocol.MsgTypeChannelMessage, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "NOT_IN_CHANNEL" {
+		t.Errorf("trade chat before joining: %v", err)
+	}
+	var list protocol.ChannelListPayload
+	if err := bob.Request(protocol.MsgTypeChannelJoin, protocol.ChannelRequestPayload{Channel: chatchannels.Trade}, protocol.MsgTypeChannelList, &list); err != nil {
+		t.Fatal(err)
+	}
+	want := []protocol.ChannelInfo{
+		{Channel: chatchannels.Global, Joined: true, Members: 3, AutoJoin: true, MessagesPerMinute: 1},
+		{Channel: chatchannels.Trade, Joined: true, Members: 1},
+		{Channel: chatchannels.Zone, Scope: roomID, Joined: true, Members: 2, AutoJoin: true},
+	}
+	if fmt.Sprint(list.Channels) != fmt.Sprint(want) {
+		t.Errorf("bob's channels = %+v\nwant %+v", list.Channels, want)
+	}
+
+	// Global chat allows one message a minute.
+	if err := carol.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Global, Text: "hello world"}, protocol.MsgTypeChannelMessage, &got); err != nil || got.Text != "hello world" {
+		t.Fatalf("carol's global message: %+v, %v", got, err)
+	}
+	err = carol.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Global, Text: "hello again"}, protocol.MsgTypeChannelMessage, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "CHANNEL_THROTTLED" {
+		t.Errorf("second global message: %v, want it throttled", err)
+	}
+}
+
+func TestAlliancesShareAChatChannel(t *testing.T) {
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", LeaderID: "alice", MemberIDs: []string{"alice", "carol"}},
+		{ID: "bears", LeaderID: "bob", MemberIDs: []string{"bob"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guildDiplomacy, err := diplomacy.NewService(&diplomacy.MemoryStore{}, roster, diplomacy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{
+		Players:  map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Services: internalActor.SessionServices{Diplomacy: guildDiplomacy},
+		World: internalActor.WorldServices{Guilds: roster, Diplomacy: guildDiplomacy, ChatChannels: chatchannels.Policies{
+			chatchannels.Guild:    {AutoJoin: true},
+			chatchannels.Alliance: {AutoJoin: true},
+		}},
+	})
+	guildDiplomacy.OnChange(func(guildIDs []string) {
+		srv.System.Root.Send(srv.WorldManager, &messages.AllianceChanged{GuildIDs: guildIDs})
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	carol := login(t, srv, "carol-token")
+
+	var serverErr *ServerError
+	err = bob.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Alliance, Text: "hi"}, protocol.MsgTypeChannelMessage, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "CHANNEL_UNAVAILABLE" {
+		t.Fatalf("alliance chat before allying: %v", err)
+	}
+	var standing protocol.DiplomacyPayload
+	if err := alice.Request(protocol.MsgTypeAlliancePropose, protocol.AllianceProposeRequestPayload{GuildID: "bears", Name: "Northern Pact"}, protocol.MsgTypeDiplomacy, &standing); err != nil || len(standing.Outgoing) != 1 {
+		t.Fatalf("proposal = %+v, %v", standing, err)
+	}
+	err = carol.Request(protocol.MsgTypeAllianceRespond, protocol.AllianceRespondRequestPayload{GuildID: "wolves", Accept: true}, protocol.MsgTypeDiplomacy, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "NOT_GUILD_LEADER" {
+		t.Fatalf("answer by a member: %v", err)
+	}
+	var accepted protocol.DiplomacyPayload
+	if err := bob.Request(protocol.MsgTypeAllianceRespond, protocol.AllianceRespondRequestPayload{GuildID: "wolves", Accept: true}, protocol.MsgTypeDiplomacy, &accepted); err != nil || accepted.Alliance == nil || accepted.Alliance.Name != "Northern Pact" {
+		t.Fatalf("accept = %+v, %v", accepted, err)
+	}
+
+	// Both guilds' online members were moved into the alliance channel.
+	bob.Send(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Alliance, Text: "well met"})
+	var got protocol.ChannelMessagePayload
+	if err := carol.Expect(protocol.MsgTypeChannelMessage, &got); err != nil || got.Channel != chatchannels.Alliance || got.Scope != accepted.Alliance.AllianceID || got.SenderID != "bob" {
+		t.Fatalf("carol got %+v, %v; want bob's alliance message", got, err)
+	}
+	if err := alice.Expect(protocol.MsgTypeChannelMessage, &got); err != nil || got.SenderID != "bob" {
+		t.Fatalf("alice got %+v, %v; want bob's alliance message", got, err)
+	}
+	var bears protocol.DiplomacyPayload
+	if err := carol.Request(protocol.MsgTypeDiplomacyRequest, protocol.DiplomacyRequestPayload{GuildID: "bears"}, protocol.MsgTypeDiplomacy, &bears); err != nil || bears.Alliance == nil || len(bears.Alliance.GuildIDs) != 2 || bears.Incoming != nil {
+		t.Fatalf("the bears' standing = %+v, %v", bears, err)
+	}
+
+	var left protocol.DiplomacyPayload
+	if err := bob.Request(protocol.MsgTypeAllianceLeave, protocol.AllianceLeaveRequestPayload{}, protocol.MsgTypeDiplomacy, &left); err != nil || left.Alliance != nil {
+		t.Fatalf("leave = %+v, %v", left, err)
+	}
+	err = alice.Request(protocol.MsgTypeChannelSend, protocol.ChannelSendPayload{Channel: chatchannels.Alliance, Text: "still there?"}, protocol.MsgTypeChannelMessage, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "CHANNEL_UNAVAILABLE" {
+		t.Errorf("alliance chat after the alliance dissolved: %v", err)
+	}
+}
+
+func TestGuildRecruitmentFromListingToAcceptance(t *testing.T) {
+	roster, err := guilds.NewRoster([]model.Guild{
+		{ID: "wolves", Name: "Wolves", LeaderID: "alice", MemberIDs: []string{"alice", "carol"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailService, err := mail.NewService(&mail.MemoryStore{}, mail.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	board, err := recruitment.NewService(&recruitment.MemoryStore{}, roster, recruitment.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.UseMail(mailService)
+	srv := startServer(t, Options{
+		Players:  map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Services: internalActor.SessionServices{Recruitment: board, Mail: mailService},
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	carol := login(t, srv, "carol-token")
+
+	var serverErr *ServerError
+	err = carol.Request(protocol.MsgTypeGuildRecruitmentPost, protocol.GuildRecruitmentPostRequestPayload{Description: "Raiders"}, protocol.MsgTypeGuildRecruitment, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "GUILD_RANK_FORBIDS" {
+		t.Fatalf("post by a member: %v", err)
+	}
+	var recruiting protocol.GuildRecruitmentPayload
+	post := protocol.GuildRecruitmentPostRequestPayload{Description: "Weekly raids", Requirements: "Voice chat", Tags: []string{"pve"}}
+	if err := alice.Request(protocol.MsgTypeGuildRecruitmentPost, post, protocol.MsgTypeGuildRecruitment, &recruiting); err != nil || recruiting.Listing == nil {
+		t.Fatalf("post = %+v, %v", recruiting, err)
+	}
+
+	var found protocol.GuildRecruitmentListingsPayload
+	if err := bob.Request(protocol.MsgTypeGuildRecruitmentSearch, protocol.GuildRecruitmentSearchRequestPayload{Tag: "pve"}, protocol.MsgTypeGuildRecruitmentListings, &found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Listings) != 1 || found.Listings[0].GuildName != "Wolves" || found.Listings[0].Members != 2 || found.Listings[0].Requirements != "Voice chat" {
+		t.Fatalf("GUILD_RECRUITMENT_LISTINGS = %+v", found)
+	}
+	var applications protocol.GuildApplicationsPayload
+	if err := bob.Request(protocol.MsgTypeGuildApply, protocol.GuildApplyRequestPayload{GuildID: "wolves", Message: "I heal."}, protocol.MsgTypeGuildApplications, &applications); err != nil {
+		t.Fatal(err)
+	}
+	if len(applications.Applications) != 1 || applications.Applications[0].Status != recruitment.StatusPending {
+		t.Fatalf("GUILD_APPLICATIONS = %+v", applications)
+	}
+	err = carol.Request(protocol.MsgTypeGuildApply, protocol.GuildApplyRequestPayload{GuildID: "wolves"}, protocol.MsgTypeGuildApplications, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "ALREADY_IN_GUILD" {
+		t.Fatalf("application by a member: %v", err)
+	}
+
+	// The leader is mailed the application and accepts it; the applicant is mailed the decision.
+	var notice protocol.MailMessagePayload
+	if err := alice.Expect(protocol.MsgTypeMailNew, &notice); err != nil || notice.Kind != "guild.application_received" {
+		t.Fatalf("leader's mail = %+v, %v", notice, err)
+	}
+	id := applications.Applications[0].ApplicationID
+	if err := alice.Request(protocol.MsgTypeGuildRecruitmentRequest, protocol.GuildRecruitmentRequestPayload{}, protocol.MsgTypeGuildRecruitment, &recruiting); err != nil || len(recruiting.Applications) != 1 || recruiting.Applications[0].ApplicationID != id {
+		t.Fatalf("board = %+v, %v", recruiting, err)
+	}
+	review := protocol.GuildApplicationReviewRequestPayload{ApplicationID: id, Accept: true, Note: "Welcome aboard."}
+	if err := alice.Request(protocol.MsgTypeGuildApplicationReview, review, protocol.MsgTypeGuildRecruitment, &recruiting); err != nil || len(recruiting.Applications) != 0 {
+		t.Fatalf("review = %+v, %v", recruiting, err)
+	}
+	if err := bob.Expect(protocol.MsgTypeMailNew, &notice); err != nil || notice.Kind != "guild.application_accepted" || notice.Ref != id {
+		t.Fatalf("applicant's mail = %+v, %v", notice, err)
+	}
+	if err := bob.Request(protocol.MsgTypeGuildApplicationsRequest, protocol.GuildApplicationsRequestPayload{}, protocol.MsgTypeGuildApplications, &applications); err != nil {
+		t.Fatal(err)
+	}
+	if got := applications.Applications; len(got) != 1 || got[0].Status != recruitment.StatusAccepted || got[0].DecidedBy != "alice" || got[0].Note != "Welcome aboard." {
+		t.Fatalf("GUILD_APPLICATIONS after the review = %+v", got)
+	}
+	err = bob.Request(protocol.MsgTypeGuildApplicationWithdraw, protocol.GuildApplicationWithdrawRequestPayload{ApplicationID: id}, protocol.MsgTypeGuildApplications, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "APPLICATION_DECIDED" {
+		t.Fatalf("withdraw after the review: %v", err)
+	}
+}
+
+// orderWallets stands in for linked wallets in order book tests.
+type orderWallets map[string]string
+
+func (w orderWallets) Address(ctx context.Context, playerID string) (string, error) {
+	if address, ok := w[playerID]; ok {
+		return address, nil
+	}
+	return "", errors.New("no linked wallet")
+}
+
+// orderChain builds deposits whose digest is their own bytes and pays out
+// nothing.
+type orderChain struct{}
+
+func (orderChain) BuildDeposit(ctx context.Context, sender, coinType string, amount uint64) (string, error) {
+	return fmt.Sprintf("deposit %s %d %s", sender, amount, coinType), nil
+}
+
+func (orderChain) VerifyDeposit(ctx context.Context, txDigest, sender, coinType string, amount uint64, since time.Time) error {
+	if txDigest != fmt.Sprintf("deposit %s %d %s", sender, amount, coinType) {
+		return errors.New("no such deposit")
+	}
+	return nil
+}
+
+func (orderChain) SignPayout(ctx context.Context, payouts []orderbook.Payout) (orderbook.SignedPayout, error) {
+	return orderbook.SignedPayout{Digest: "0xsettled"}, nil
+}
+
+func (orderChain) SubmitPayout(ctx context.Context, payout orderbook.SignedPayout) error {
+	return nil
+}
+
+func TestOrderBookMatchesFundedOrders(t *testing.T) {
+	book, err := orderbook.NewService(orderbook.Options{TokenType: "0xpkg::game_coin::GAME_COIN"}, &orderbook.MemoryStore{},
+		orderChain{}, orderWallets{"alice": "0xa", "bob": "0xb"}, outbox.New(outbox.NewMemoryStore(), outbox.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Services: internalActor.SessionServices{OrderBook: book}})
+	alice, bob := login(t, srv, "alice-token"), login(t, srv, "bob-token")
+
+	var serverErr *ServerError
+	err = alice.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideSell, Price: 0, Quantity: 10}, protocol.MsgTypeOrderUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "INVALID_ORDER" {
+		t.Fatalf("order without a price: %v", err)
+	}
+	var placed protocol.OrderUpdatePayload
+	if err := alice.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideSell, Price: 5, Quantity: 10}, protocol.MsgTypeOrderUpdate, &placed); err != nil || placed.Order.Status != "awaiting_deposit" || placed.Order.TxBytes == "" {
+		t.Fatalf("ORDER_PLACE = %+v, %v", placed, err)
+	}
+	err = alice.Request(protocol.MsgTypeOrderFunded, protocol.OrderFundedRequestPayload{OrderID: placed.Order.OrderID, TxDigest: "0xforged"}, protocol.MsgTypeOrderUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "ORDER_DEPOSIT_UNVERIFIED" {
+		t.Fatalf("funding with a forged deposit: %v", err)
+	}
+	var funded protocol.OrderUpdatePayload
+	if err := alice.Request(protocol.MsgTypeOrderFunded, protocol.OrderFundedRequestPayload{OrderID: placed.Order.OrderID, TxDigest: placed.Order.TxBytes}, protocol.MsgTypeOrderUpdate, &funded); err != nil || funded.Order.Status != "open" {
+		t.Fatalf("ORDER_FUNDED = %+v, %v", funded, err)
+	}
+
+	var buy protocol.OrderUpdatePayload
+	if err := bob.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideBuy, Price: 6, Quantity: 4}, protocol.MsgTypeOrderUpdate, &buy); err != nil || buy.Order.Deposit != 24 {
+		t.Fatalf("bob's ORDER_PLACE = %+v, %v", buy, err)
+	}
+	bob.Send(protocol.MsgTypeOrderFunded, protocol.OrderFundedRequestPayload{OrderID: buy.Order.OrderID, TxDigest: buy.Order.TxBytes})
+	var opened, filled protocol.OrderUpdatePayload
+	if err := bob.Expect(protocol.MsgTypeOrderUpdate, &opened); err != nil || opened.Order.Status != "open" {
+		t.Fatalf("bob's funded order = %+v, %v", opened, err)
+	}
+	if err := bob.Expect(protocol.MsgTypeOrderUpdate, &filled); err != nil || filled.Order.Status != "filled" || filled.Fill == nil || filled.Fill.Price != 5 {
+		t.Fatalf("bob's fill = %+v, %v", filled, err)
+	}
+	var sold protocol.OrderUpdatePayload
+	if err := alice.Expect(protocol.MsgTypeOrderUpdate, &sold); err != nil || sold.Order.Filled != 4 || sold.Fill == nil || sold.Fill.Quantity != 4 {
+		t.Fatalf("alice's fill = %+v, %v", sold, err)
+	}
+
+	var depth protocol.OrderBookPayload
+	if err := bob.Request(protocol.MsgTypeOrderBookRequest, protocol.OrderBookRequestPayload{}, protocol.MsgTypeOrderBook, &depth); err != nil {
+		t.Fatal(err)
+	}
+	if len(depth.Asks) != 1 || depth.Asks[0].Quantity != 6 || len(depth.Bids) != 0 || depth.LastPrice != 5 || len(depth.Orders) != 0 {
+		t.Fatalf("ORDER_BOOK = %+v", depth)
+	}
+	var cancelled protocol.OrderUpdatePayload
+	if err := alice.Request(protocol.MsgTypeOrderCancel, protocol.OrderCancelRequestPayload{OrderID: placed.Order.OrderID}, protocol.MsgTypeOrderUpdate, &cancelled); err != nil || cancelled.Order.Status != "cancelled" {
+		t.Fatalf("ORDER_CANCEL = %+v, %v", cancelled, err)
+	}
+	err = bob.Request(protocol.MsgTypeOrderCancel, protocol.OrderCancelRequestPayload{OrderID: placed.Order.OrderID}, protocol.MsgTypeOrderUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "UNKNOWN_ORDER" {
+		t.Fatalf("cancelling another's order: %v", err)
+	}
+}
+
+func TestParentalSpendCapCoversOrdersAndTrades(t *testing.T) {
+	controls, err := parental.NewService(&parental.MemoryStore{}, parental.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls.Set("alice", parental.Controls{DailyTradeLimitMist: 100}, "ops")
+	controls.Set("bob", parental.Controls{DailyTradeLimitMist: 50}, "ops")
+	wallets := orderWallets{"alice": "0xa", "bob": "0xb"}
+	book, err := orderbook.NewService(orderbook.Options{TokenType: "0xpkg::game_coin::GAME_COIN"}, &orderbook.MemoryStore{},
+		orderChain{}, wallets, outbox.New(outbox.NewMemoryStore(), outbox.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.UseSpendLimit(controls)
+	trades, err := trade.NewService(trade.Options{EscrowThreshold: 1 << 40}, &trade.MemoryStore{}, wallets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades.UseSpendLimit(controls)
+	srv := startServer(t, Options{Services: internalActor.SessionServices{OrderBook: book, Trades: trades, Parental: controls}})
+	alice, bob := login(t, srv, "alice-token"), login(t, srv, "bob-token")
+
+	// A buy order's deposit counts against the cap; selling tokens does not.
+	var serverErr *ServerError
+	if err := alice.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideBuy, Price: 6, Quantity: 10}, protocol.MsgTypeOrderUpdate, nil); err != nil {
+		t.Fatalf("a buy order within the cap: %v", err)
+	}
+	err = alice.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideBuy, Price: 5, Quantity: 10}, protocol.MsgTypeOrderUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "SPEND_LIMIT_REACHED" {
+		t.Fatalf("a buy order over the cap: %v", err)
+	}
+	if err := alice.Request(protocol.MsgTypeOrderPlace, protocol.OrderPlaceRequestPayload{Side: protocol.OrderSideSell, Price: 5, Quantity: 10}, protocol.MsgTypeOrderUpdate, nil); err != nil {
+		t.Fatalf("a sell order: %v", err)
+	}
+
+	// What a player gives in a trade counts too: the proposer's side when it
+	// is proposed and the counterparty's when it is accepted.
+	var proposed protocol.TradeUpdatePayload
+	err = alice.Request(protocol.MsgTypeTradePropose, protocol.TradeProposeRequestPayload{PlayerID: "bob",
+		Give: protocol.TradeOfferPayload{Coins: 41}, Want: protocol.TradeOfferPayload{Coins: 60}}, protocol.MsgTypeTradeUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "SPEND_LIMIT_REACHED" {
+		t.Fatalf("a trade over the cap: %v", err)
+	}
+	if err := alice.Request(protocol.MsgTypeTradePropose, protocol.TradeProposeRequestPayload{PlayerID: "bob",
+		Give: protocol.TradeOfferPayload{Coins: 40}, Want: protocol.TradeOfferPayload{Coins: 60}}, protocol.MsgTypeTradeUpdate, &proposed); err != nil {
+		t.Fatalf("a trade within the cap: %v", err)
+	}
+	if err := bob.Expect(protocol.MsgTypeTradeUpdate, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = bob.Request(protocol.MsgTypeTradeRespond, protocol.TradeRespondRequestPayload{TradeID: proposed.TradeID, Action: protocol.TradeActionAccept}, protocol.MsgTypeTradeUpdate, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "SPEND_LIMIT_REACHED" {
+		t.Fatalf("accepting a trade over the cap: %v", err)
+	}
+
+	if left := controls.Status("alice").TradeLeft; left != 0 {
+		t.Fatalf("alice has %d MIST left, want 0", left)
+	}
+	if left := controls.Status("bob").TradeLeft; left != 50 {
+		t.Fatalf("bob has %d MIST left after a refused accept, want 50", left)
+	}
+}
+
+func TestPluginsAnswerTheirOwnMessages(t *testing.T) {
+	seasons := plugins.Plugin{Name: "seasons", Init: func(h *plugins.Host) error {
+		h.HandleMessage("SEASON_INFO", func(s plugins.Session, payload json.RawMessage) {
+			var req struct {
+				Season int `json:"season"`
+			}
+			if err := json.Unmarshal(payload, &req); err != nil || req.Season == 0 {
+				s.SendError("INVALID_SEASON", "Name a season.")
+				return
+			}
+			go s.Send("SEASON", map[string]interface{}{"season": req.Season, "player": s.PlayerID()})
+		})
+		return nil
+	}}
+	manager := plugins.New(configs.PluginsConfig{}, []plugins.Plugin{seasons})
+	if err := manager.Init(plugins.Host{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Plugins: manager}})
+	alice := login(t, srv, "alice-token")
+
+	var season struct {
+		Season int    `json:"season"`
+		Player string `json:"player"`
+	}
+	if err := alice.Request("SEASON_INFO", map[string]int{"season": 3}, "SEASON", &season); err != nil || season.Season != 3 || season.Player != "alice" {
+		t.Fatalf("SEASON_INFO = %+v, %v", season, err)
+	}
+	var serverErr *ServerError
+	if err := alice.Request("SEASON_INFO", map[string]int{}, "SEASON", nil); !errors.As(err, &serverErr) || serverErr.Code != "INVALID_SEASON" {
+		t.Fatalf("SEASON_INFO without a season: %v", err)
+	}
+	if err := alice.Request("SEASON_RESET", nil, "SEASON", nil); !errors.As(err, &serverErr) || serverErr.Code != "UNKNOWN_COMMAND" {
+		t.Fatalf("a type no plugin handles: %v", err)
+	}
+}
+
+func TestSocialActionsAreBroadcastAndThrottled(t *testing.T) {
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Social: ratelimit.Limit{PerSecond: 0.01, Burst: 2}}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "plaza"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+
+	alice.Send(protocol.MsgTypeSocialAction, protocol.SocialActionPayload{Kind: protocol.SocialEmote, Name: "wave", X: 3})
+	var social protocol.SocialPayload
+	if err := bob.Expect(protocol.MsgTypeSocial, &social); err != nil {
+		t.Fatal(err)
+	}
+	if want := (protocol.SocialPayload{PlayerID: "alice", Kind: protocol.SocialEmote, Name: "wave"}); social != want {
+		t.Errorf("bob got %+v, want %+v", social, want)
+	}
+	if err := alice.Request(protocol.MsgTypeSocialAction, protocol.SocialActionPayload{Kind: protocol.SocialPing, Name: "danger", X: 4, Y: 5}, protocol.MsgTypeSocial, &social); err != nil {
+		t.Fatal(err)
+	}
+	if social.Name != "wave" {
+		t.Errorf("alice's own emote came back as %+v", social)
+	}
+	if err := alice.Expect(protocol.MsgTypeSocial, &social); err != nil || social.Kind != protocol.SocialPing || social.X != 4 {
+		t.Errorf("alice's ping came back as %+v, %v", social, err)
+	}
+
+	var serverErr *ServerError
+	err = alice.Request(protocol.MsgTypeSocialAction, protocol.SocialActionPayload{Kind: protocol.SocialQuickReply, Name: "Thanks!"}, protocol.MsgTypeSocial, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "INVALID_SOCIAL_PAYLOAD" {
+		t.Errorf("badly named quick reply: %v", err)
+	}
+	err = alice.Request(protocol.MsgTypeSocialAction, protocol.SocialActionPayload{Kind: protocol.SocialQuickReply, Name: "thanks"}, protocol.MsgTypeSocial, &social)
+	if !errors.As(err, &serverErr) || serverErr.Code != "SOCIAL_THROTTLED" {
+		t.Errorf("third action in a burst of 2: %v, %+v", err, social)
+	}
+}
+
+func TestOutdatedClientsMustUpgrade(t *testing.T) {
+	versions, err := clientversion.New(clientversion.Options{Min: "1.4", UpgradeURL: "https://example.com/download"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Versions: versions}})
+
+	old, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serverErr *ServerError
+	_, err = old.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", ClientVersion: "1.3.2"})
+	if !errors.As(err, &serverErr) || serverErr.Code != protocol.ErrCodeUpgradeRequired || serverErr.UpgradeURL != "https://example.com/download" {
+		t.Fatalf("auth with an outdated client: %v", err)
+	}
+	current, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := current.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", ClientVersion: "1.4.1"}); err != nil {
+		t.Fatal(err)
+	}
+	stats := versions.Stats().Versions
+	if len(stats) != 2 || stats[0] != (clientversion.Count{Version: "1.4.1", Allowed: 1, Online: 1}) || stats[1].Refused != 1 {
+		t.Errorf("version stats = %+v", stats)
+	}
+	current.Close()
+	eventually(t, 2*time.Second, "the session to stop counting as online", func() bool {
+		return versions.Stats().Versions[0].Online == 0
+	})
+}
+
+func TestSpeedhacksAreCorrectedThenDisconnected(t *testing.T) {
+	monitor := anticheat.New(anticheat.Options{MaxSpeed: 5, FlagAfter: 1, KickAfter: 2})
+	srv := startServer(t, Options{Rooms: internalActor.RoomServices{Anticheat: monitor}})
+	alice := login(t, srv, "alice-token")
+	if _, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "track"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, move := range []protocol.MovePayload{{X: 3, Y: 0}, {X: 90, Y: 0}} {
+		if err := alice.Send(protocol.MsgTypeMove, move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.Corrected {
+			if update.X != 3 {
+				t.Errorf("correction = %+v, want alice back where her last allowed move took her", update)
+			}
+			break
+		}
+	}
+
+	if err := alice.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 90, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	// Expect skips the correction; nothing but the error should follow it.
+	var serverErr *ServerError
+	if err := alice.Expect(protocol.MsgTypeAuthResponse, nil); !errors.As(err, &serverErr) || serverErr.Code != protocol.ErrCodeCheatDetected {
+		t.Fatalf("after the second violation: %v, want %s", err, protocol.ErrCodeCheatDetected)
+	}
+	if stats := monitor.Stats(time.Now()); stats.Corrected != 2 || stats.Flagged != 1 || stats.Kicked != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCraftsFinishAndArePushed(t *testing.T) {
+	recipes := []crafting.Recipe{{ID: "potion", Name: "Potion", ItemType: "potion", DurationSeconds: 60}}
+	crafts := crafting.NewService(recipes, crafting.NewMemoryStore(), &shop.MemoryWallets{}, crafting.Options{MaxQueued: 1})
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Crafting: crafts}})
+	alice := login(t, srv, "alice-token")
+
+	var queue protocol.CraftQueuePayload
+	if err := alice.Request(protocol.MsgTypeCraftStart, protocol.CraftStartRequestPayload{RecipeID: "potion"}, protocol.MsgTypeCraftQueue, &queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.Crafts) != 1 || queue.Crafts[0].Stage != "crafting" || len(queue.Recipes) != 1 || queue.MaxQueued != 1 {
+		t.Fatalf("queue = %+v", queue)
+	}
+	var serverErr *ServerError
+	err := alice.Request(protocol.MsgTypeCraftStart, protocol.CraftStartRequestPayload{RecipeID: "potion"}, protocol.MsgTypeCraftQueue, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "CRAFT_QUEUE_FULL" {
+		t.Errorf("second craft: %v", err)
+	}
+
+	if n, err := crafts.Tick(context.Background(), time.Now().Add(time.Minute)); n != 1 || err != nil {
+		t.Fatalf("Tick = %d, %v", n, err)
+	}
+	var completed protocol.CraftPayload
+	if err := alice.Expect(protocol.MsgTypeCraftCompleted, &completed); err != nil {
+		t.Fatal(err)
+	}
+	if completed.CraftID != queue.Crafts[0].CraftID || completed.Stage != "ready" {
+		t.Errorf("completed = %+v", completed)
+	}
+}
+
+func TestCraftingSpendsEnergy(t *testing.T) {
+	values := balance.EnergyValues{Max: 5, RegenSeconds: 3600, Costs: map[string]int{energy.ActionCraft: 3}}
+	energyService := energy.NewService(energy.NewMemoryStore(), func() balance.EnergyValues { return values }, energy.Options{})
+	recipes := []crafting.Recipe{{ID: "potion", Name: "Potion", ItemType: "potion", DurationSeconds: 60}}
+	crafts := crafting.NewService(recipes, crafting.NewMemoryStore(), &shop.MemoryWallets{}, crafting.Options{})
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Crafting: crafts, Energy: energyService}})
+	alice := login(t, srv, "alice-token")
+
+	var status protocol.EnergyPayload
+	if err := alice.Expect(protocol.MsgTypeEnergy, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Energy != 5 || status.Max != 5 || status.Costs[energy.ActionCraft] != 3 {
+		t.Errorf("energy on login = %+v", status)
+	}
+	if err := alice.Request(protocol.MsgTypeCraftStart, protocol.CraftStartRequestPayload{RecipeID: "potion"}, protocol.MsgTypeEnergy, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Energy != 2 || status.NextRegenAt == 0 {
+		t.Errorf("energy after crafting = %+v", status)
+	}
+	if err := alice.Expect(protocol.MsgTypeCraftQueue, nil); err != nil {
+		t.Fatal(err)
+	}
+	var serverErr *ServerError
+	err := alice.Request(protocol.MsgTypeCraftStart, protocol.CraftStartRequestPayload{RecipeID: "potion"}, protocol.MsgTypeCraftQueue, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "NOT_ENOUGH_ENERGY" {
+		t.Errorf("craft without energy: %v", err)
+	}
+}
+
+func TestBattlePassRewardsAreClaimed(t *testing.T) {
+	now := time.Now().UTC()
+	def := battlepass.Definition{
+		Seasons: []battlepass.Season{{
+			ID:       "s1",
+			Name:     "Season 1",
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.AddDate(0, 1, 0),
+			Tiers:    []battlepass.Tier{{XP: 50, Free: &battlepass.Reward{Coins: 25}, Premium: &battlepass.Reward{Cosmetic: "cape"}}},
+		}},
+		XPSources: map[string]int{battlepass.SourceCombatWin: 50},
+	}
+	wallets := &shop.MemoryWallets{}
+	pass, err := battlepass.NewService(def, &battlepass.MemoryStore{}, wallets, battlepass.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass.Start()
+	t.Cleanup(pass.Stop)
+	srv := startServer(t, Options{Services: internalActor.SessionServices{BattlePass: pass}})
+	alice := login(t, srv, "alice-token")
+
+	var view protocol.BattlePassPayload
+	if err := alice.Expect(protocol.MsgTypeBattlePass, &view); err != nil {
+		t.Fatal(err)
+	}
+	if !view.Active || view.SeasonID != "s1" || len(view.Tiers) != 1 || view.Tier != 0 {
+		t.Fatalf("battle pass on login = %+v", view)
+	}
+	var serverErr *ServerError
+	err = alice.Request(protocol.MsgTypeBattlePassClaim, protocol.BattlePassClaimRequestPayload{Tier: 1, Track: battlepass.TrackFree}, protocol.MsgTypeBattlePass, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "BATTLEPASS_TIER_LOCKED" {
+		t.Errorf("claim before reaching the tier: %v", err)
+	}
+
+	pass.AddXP("alice", battlepass.SourceCombatWin)
+	if err := alice.Expect(protocol.MsgTypeBattlePass, &view); err != nil {
+		t.Fatal(err)
+	}
+	if view.XP != 50 || view.Tier != 1 {
+		t.Fatalf("battle pass after a win = %+v", view)
+	}
+	if err := alice.Request(protocol.MsgTypeBattlePassClaim, protocol.BattlePassClaimRequestPayload{Tier: 1, Track: battlepass.TrackFree}, protocol.MsgTypeBattlePass, &view); err != nil {
+		t.Fatal(err)
+	}
+	if !view.Tiers[0].Free.Claimed || view.Tiers[0].Premium.Claimed {
+		t.Errorf("tiers after claiming = %+v", view.Tiers[0])
+	}
+	if wallet, _ := wallets.LoadWallet("alice"); wallet.Coins != 25 {
+		t.Errorf("wallet = %+v", wallet)
+	}
+	err = alice.Request(protocol.MsgTypeBattlePassClaim, protocol.BattlePassClaimRequestPayload{Tier: 1, Track: battlepass.TrackPremium}, protocol.MsgTypeBattlePass, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "BATTLEPASS_PREMIUM_REQUIRED" {
+		t.Errorf("premium claim without the pass: %v", err)
+	}
+}
+
+func TestAppearanceIsShownToTheRoom(t *testing.T) {
+	catalog := cosmetics.Catalog{Slots: []string{"back"}, Cosmetics: []cosmetics.Cosmetic{{ID: "ember_cape", Slot: "back"}}}
+	address := func(ctx context.Context, playerID string) (string, error) { return "0x" + playerID, nil }
+	ownedBy := func(ctx context.Context, address string) ([]string, error) {
+		if address == "0xalice" {
+			return []string{"ember_cape"}, nil
+		}
+		return nil, nil
+	}
+	wardrobe := cosmetics.NewService(catalog, cosmetics.NewMemoryStore(), address, ownedBy, cosmetics.Options{})
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Cosmetics: wardrobe}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	if err := alice.Expect(protocol.MsgTypeAppearance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Expect(protocol.MsgTypeAppearance, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var appearance protocol.AppearancePayload
+	if err := alice.Request(protocol.MsgTypeAppearanceEquip, protocol.AppearanceEquipRequestPayload{Slot: "back", CosmeticID: "ember_cape"}, protocol.MsgTypeAppearance, &appearance); err != nil {
+		t.Fatal(err)
+	}
+	if appearance.PlayerID != "alice" || appearance.Equipped["back"] != "ember_cape" {
+		t.Errorf("alice's appearance = %+v", appearance)
+	}
+	var serverErr *ServerError
+	err := bob.Request(protocol.MsgTypeAppearanceEquip, protocol.AppearanceEquipRequestPayload{Slot: "back", CosmeticID: "ember_cape"}, protocol.MsgTypeAppearance, nil)
+	if !errors.As(err, &serverErr) || serverErr.Code != "COSMETIC_NOT_OWNED" {
+		t.Errorf("bob equipping alice's cape: %v", err)
+	}
+
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "plaza"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Expect(protocol.MsgTypeAppearance, &appearance); err != nil {
+		t.Fatal(err)
+	}
+	if appearance.PlayerID != "alice" || appearance.Equipped["back"] != "ember_cape" {
+		t.Errorf("bob saw alice on joining as %+v", appearance)
+	}
+	alice.Send(protocol.MsgTypeAppearanceEquip, protocol.AppearanceEquipRequestPayload{Slot: "back"})
+	appearance = protocol.AppearancePayload{}
+	if err := bob.Expect(protocol.MsgTypeAppearance, &appearance); err != nil {
+		t.Fatal(err)
+	}
+	if appearance.PlayerID != "alice" || len(appearance.Equipped) != 0 {
+		t.Errorf("bob saw alice take off her cape as %+v", appearance)
+	}
+}
+
+func TestAnnouncementsReachEveryPlayer(t *testing.T) {
+	srv := startServer(t, Options{})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	for _, playerID := range []string{"alice", "bob"} {
+		if _, err := srv.SessionOf(playerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.System.Root.Send(srv.WorldManager, &messages.Announcement{Text: "Servers restart in 10 minutes.", SentAt: time.Now()})
+	for _, client := range []*Client{alice, bob} {
+		var announcement protocol.AnnouncementPayload
+		if err := client.Expect(protocol.MsgTypeAnnouncement, &announcement); err != nil {
+			t.Fatal(err)
+		}
+		if announcement.Text != "Servers restart in 10 minutes." || announcement.SentAt == 0 {
+			t.Errorf("announcement = %+v", announcement)
+		}
+	}
+}
+
+func TestTxReceiptsReachOnlinePlayers(t *testing.T) {
+	txReceipts := receipts.NewService(nil)
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Receipts: txReceipts}})
+	txReceipts.Subscribe(srv.Events)
+	alice := login(t, srv, "alice-token")
+
+	srv.Events.Publish(events.TopicTxCompleted, events.TxCompleted{
+		PlayerIDs: []string{"alice"},
+		Kind:      "trade",
+		Digest:    "D1",
+		Status:    events.TxSuccess,
+		Summary:   "Trade t-1 completed",
+	})
+	var receipt protocol.TxReceiptPayload
+	if err := alice.Expect(protocol.MsgTypeTxReceipt, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Kind != "trade" || receipt.Digest != "D1" || receipt.Status != events.TxSuccess || receipt.At == 0 {
+		t.Errorf("receipt = %+v", receipt)
+	}
+}
+
+func TestLoginPrefetchesChainState(t *testing.T) {
+	const profileType, itemType = "0xgame::player::Profile", "0xgame::item::Item"
+	stub := NewSuiStub()
+	stub.AddCoin("0xa11ce", SuiCoinType, 700)
+	stub.AddCoin("0xa11ce", SuiCoinType, 42)
+	stub.PutObject("0xprofile", "0xa11ce", profileType, map[string]interface{}{"level": "3"})
+	stub.PutObject("0xsword", "0xa11ce", itemType, map[string]interface{}{"name": "iron_sword"})
+	client := sui.NewSuiClientWithAPI("stub", stub)
+	loginPrefetch := prefetch.NewService(prefetch.Fetchers{
+		Address: func(ctx context.Context, playerID string) (string, error) { return "0xa11ce", nil },
+		Balances: func(ctx context.Context, address string) ([]prefetch.Balance, error) {
+			all, err := client.AllBalances(ctx, address)
+			var balances []prefetch.Balance
+			for _, balance := range all {
+				balances = append(balances, prefetch.Balance{CoinType: balance.CoinType, Total: balance.TotalBalance})
+			}
+			return balances, err
+		},
+		Profile: func(ctx context.Context, address string) (*prefetch.Profile, error) {
+			object, err := client.FirstOwnedObject(ctx, address, profileType)
+			if err != nil || object == nil {
+				return nil, err
+			}
+			return &prefetch.Profile{ObjectID: object.ObjectId, Version: object.Version}, nil
+		},
+		Items: func(ctx context.Context, address string) ([]string, error) {
+			return client.OwnedItemNames(ctx, address, itemType)
+		},
+	}, prefetch.Options{Wait: 5 * time.Second})
+	srv := startServer(t, Options{Sui: stub, Services: internalActor.SessionServices{Prefetch: loginPrefetch}})
+
+	c, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Auth("alice-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := resp.Ready
+	if ready == nil || ready.Address != "0xa11ce" || len(ready.Ready) != 3 || len(ready.Pending)+len(ready.Failed) != 0 {
+		t.Fatalf("ready = %+v", ready)
+	}
+	if len(ready.Balances) != 1 || ready.Balances[0].Total != "742" || ready.ProfileID != "0xprofile" || len(ready.Items) != 1 || ready.Items[0] != "iron_sword" {
+		t.Errorf("ready = %+v", ready)
+	}
+	if calls := stub.Calls("SuiXGetAllBalance"); calls != 1 {
+		t.Errorf("balance reads = %d", calls)
+	}
+}
+
+func TestResumeTokensAreSingleUseAndRevokedOnLogout(t *testing.T) {
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Resume: resume.NewService(resume.Options{})}})
+	resumeWith := func(token, deviceID string) (protocol.AuthResponsePayload, error) {
+		c, err := srv.Dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c.AuthWith(protocol.AuthRequestPayload{ResumeToken: token, DeviceID: deviceID})
+	}
+
+	alice, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := alice.AuthWith(protocol.AuthRequestPayload{Token: "alice-token", DeviceID: "phone"})
+	if err != nil || first.ResumeToken == "" || first.ResumeWindow != 600 || first.ResumeNotAfter == 0 {
+		t.Fatalf("auth = %+v, %v; want a resume token", first, err)
+	}
+	alice.Close()
+
+	second, err := resumeWith(first.ResumeToken, "phone")
+	if err != nil || second.PlayerID != "alice" || second.ResumeToken == "" || second.ResumeToken == first.ResumeToken {
+		t.Fatalf("resumed auth = %+v, %v; want alice with a new token", second, err)
+	}
+	if _, err := resumeWith(first.ResumeToken, "phone"); err == nil {
+		t.Fatal("a used resume token was accepted again")
+	}
+	if _, err := resumeWith(second.ResumeToken, "phone"); err == nil {
+		t.Fatal("a token survived the reuse of an earlier one")
+	}
+
+	bob, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	onPhone, err := bob.AuthWith(protocol.AuthRequestPayload{Token: "bob-token", DeviceID: "phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumeWith(onPhone.ResumeToken, "laptop"); err == nil {
+		t.Fatal("a resume token was accepted from another device")
+	}
+	bob.Close()
+
+	// Logging out closes the connection and revokes the session's token.
+	bob, err = srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedIn, err := bob.AuthWith(protocol.AuthRequestPayload{Token: "bob-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgTypeLogout, protocol.LogoutPayload{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Expect(protocol.MsgTypePong, nil); err == nil {
+		t.Fatal("the connection stayed open after LOGOUT")
+	}
+	if _, err := resumeWith(signedIn.ResumeToken, ""); err == nil {
+		t.Fatal("a resume token was accepted after its session logged out")
+	}
+}
+
+func TestLateJoinersGetTheRoomState(t *testing.T) {
+	srv := startServer(t, Options{Players: map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"}})
+	alice, bob := login(t, srv, "alice-token"), login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "tavern", Rules: &protocol.RoomRulesPayload{NoLoot: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Chat("first round is on me"); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Expect(protocol.MsgTypeNewChatMessage, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var joined protocol.JoinRoomResponsePayload
+	if err := bob.Request(protocol.MsgTypeJoinRoomRequest, protocol.JoinRoomRequestPayload{Criteria: roomID}, protocol.MsgTypeJoinRoomResponse, &joined); err != nil || !joined.Success {
+		t.Fatalf("JOIN_ROOM_RESPONSE = %+v, %v", joined, err)
+	}
+	state := joined.State
+	if state == nil || state.RoomID != roomID || len(state.Members) != 2 || state.Members[0].PlayerID != "alice" || state.Members[1].PlayerID != "bob" {
+		t.Fatalf("join state = %+v", state)
+	}
+	if len(state.Chat) != 1 || state.Chat[0].SenderID != "alice" || state.Chat[0].Text != "first round is on me" || state.Chat[0].At == 0 {
+		t.Fatalf("join state chat = %+v", state.Chat)
+	}
+	if !state.Rules.NoLoot || state.Tick.IntervalMs <= 0 {
+		t.Fatalf("join state rules and tick = %+v, %+v", state.Rules, state.Tick)
+	}
+
+	var requested protocol.RoomStatePayload
+	if err := alice.Request(protocol.MsgTypeRoomStateRequest, protocol.RoomStateRequestPayload{}, protocol.MsgTypeRoomState, &requested); err != nil {
+		t.Fatal(err)
+	}
+	if requested.RoomID != roomID || len(requested.Members) != 2 || len(requested.Chat) != 1 {
+		t.Fatalf("ROOM_STATE = %+v", requested)
+	}
+	carol := login(t, srv, "carol-token")
+	var serverErr *ServerError
+	if err := carol.Request(protocol.MsgTypeRoomStateRequest, protocol.RoomStateRequestPayload{}, protocol.MsgTypeRoomState, nil); !errors.As(err, &serverErr) || serverErr.Code != "NOT_IN_A_ROOM" {
+		t.Fatalf("ROOM_STATE_REQUEST outside a room: %v, want it refused", err)
+	}
+}
+
+func TestGuildEventsOpenARoomForTheirAttendees(t *testing.T) {
+	roster, err := guilds.NewRoster([]model.Guild{{ID: "wolves", LeaderID: "alice", MemberIDs: []string{"alice", "bob", "carol"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guildCalendar, err := calendar.NewService(&calendar.MemoryStore{}, roster, calendar.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{
+		Players:  map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Services: internalActor.SessionServices{Calendar: guildCalendar},
+	})
+	guildCalendar.UseRooms(internalActor.GuildEventRooms(srv.System, srv.RoomManager))
+	alice, bob, carol := login(t, srv, "alice-token"), login(t, srv, "bob-token"), login(t, srv, "carol-token")
+
+	startsAt := time.Now().Add(time.Hour)
+	create := protocol.GuildEventCreateRequestPayload{Kind: "raid", Title: "Dragon raid", StartsAt: startsAt.UnixMilli(), Capacity: 5}
+	err = bob.Request(protocol.MsgTypeGuildEventCreate, create, protocol.MsgTypeGuildEvent, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != "NOT_GUILD_OFFICER" {
+		t.Fatalf("member's GUILD_EVENT_CREATE: %v, want it refused", err)
+	}
+	var event protocol.GuildEventPayload
+	if err := alice.Request(protocol.MsgTypeGuildEventCreate, create, protocol.MsgTypeGuildEvent, &event); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Request(protocol.MsgTypeGuildEventRSVP, protocol.GuildEventRSVPRequestPayload{EventID: event.EventID, Going: true}, protocol.MsgTypeGuildEvent, &event); err != nil {
+		t.Fatal(err)
+	}
+	var listed protocol.GuildEventsPayload
+	if err := carol.Request(protocol.MsgTypeGuildEventsRequest, protocol.GuildEventsRequestPayload{}, protocol.MsgTypeGuildEvents, &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Events) != 1 || len(listed.Events[0].Attendees) != 2 || listed.Events[0].Status != "scheduled" {
+		t.Fatalf("GUILD_EVENTS = %+v", listed)
+	}
+
+	guildCalendar.Tick(startsAt)
+	var started protocol.GuildEventPayload
+	if err := bob.Expect(protocol.MsgTypeGuildEventStarted, &started); err != nil || started.RoomID == "" {
+		t.Fatalf("GUILD_EVENT_STARTED = %+v, %v", started, err)
+	}
+	if _, err := bob.JoinRoom(started.RoomID); err != nil {
+		t.Fatalf("attendee could not join the event room: %v", err)
+	}
+	if _, err := carol.JoinRoom(started.RoomID); err == nil {
+		t.Fatal("a player who did not RSVP joined the event room")
+	}
+}
+
+func TestGuildRanksGateGuildActions(t *testing.T) {
+	roster, err := guilds.NewRoster([]model.Guild{{ID: "wolves", LeaderID: "alice", MemberIDs: []string{"alice", "bob", "carol"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranks, err := guildranks.NewService(&guildranks.MemoryStore{}, roster, guildranks.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{
+		Players:  map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Services: internalActor.SessionServices{GuildRanks: ranks},
+	})
+	alice, bob, carol := login(t, srv, "alice-token"), login(t, srv, "bob-token"), login(t, srv, "carol-token")
+
+	banker := protocol.GuildRankPayload{Name: "banker", Permissions: []string{"bankWithdraw"}, WithdrawPerDay: 50}
+	var serverErr *ServerError
+	if err := bob.Request(protocol.MsgTypeGuildRankDefine, banker, protocol.MsgTypeGuildRanks, nil); !errors.As(err, &serverErr) || serverErr.Code != "NOT_GUILD_LEADER" {
+		t.Fatalf("member's GUILD_RANK_DEFINE: %v, want it refused", err)
+	}
+	var matrix protocol.GuildRanksPayload
+	if err := alice.Request(protocol.MsgTypeGuildRankDefine, banker, protocol.MsgTypeGuildRanks, &matrix); err != nil {
+		t.Fatal(err)
+	}
+	if len(matrix.Ranks) != 4 || matrix.Members["bob"] != "member" {
+		t.Fatalf("GUILD_RANKS = %+v", matrix)
+	}
+	var action protocol.GuildActionPayload
+	if err := alice.Request(protocol.MsgTypeGuildRankAssign, protocol.GuildRankAssignRequestPayload{PlayerID: "bob", Rank: "banker"}, protocol.MsgTypeGuildAction, &action); err != nil || action.Rank != "banker" {
+		t.Fatalf("GUILD_RANK_ASSIGN = %+v, %v", action, err)
+	}
+
+	if err := bob.Request(protocol.MsgTypeGuildBankWithdraw, protocol.GuildBankWithdrawRequestPayload{Amount: 30}, protocol.MsgTypeGuildAction, &action); err != nil || action.Remaining != 20 {
+		t.Fatalf("GUILD_BANK_WITHDRAW = %+v, %v", action, err)
+	}
+	if err := bob.Request(protocol.MsgTypeGuildBankWithdraw, protocol.GuildBankWithdrawRequestPayload{Amount: 30}, protocol.MsgTypeGuildAction, nil); !errors.As(err, &serverErr) || serverErr.Code != "GUILD_BANK_LIMIT" {
+		t.Fatalf("withdrawal over the limit: %v, want it refused", err)
+	}
+	if err := carol.Request(protocol.MsgTypeGuildInvite, protocol.GuildMemberRequestPayload{PlayerID: "dave"}, protocol.MsgTypeGuildAction, nil); !errors.As(err, &serverErr) || serverErr.Code != "GUILD_RANK_FORBIDS" {
+		t.Fatalf("member's GUILD_INVITE: %v, want it refused", err)
+	}
+}
+
+func TestDeepLinksRouteAndAttributeVisits(t *testing.T) {
+	deepLinks, err := deeplink.NewService([]byte("0123456789abcdef0123456789abcdef"), deeplink.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{Services: internalActor.SessionServices{DeepLinks: deepLinks}})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "showcase"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, link, err := deepLinks.Mint(deeplink.Link{Kind: deeplink.KindRoom, Target: roomID, Source: "partner-blog", Campaign: "launch"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bob.Request(protocol.MsgTypeDeepLinkOpen, protocol.DeepLinkOpenPayload{Token: token + "x"}, protocol.MsgTypeDeepLinkRoute, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != "INVALID_DEEP_LINK" {
+		t.Fatalf("tampered DEEP_LINK_OPEN: %v, want it refused", err)
+	}
+	var route protocol.DeepLinkRoutePayload
+	if err := bob.Request(protocol.MsgTypeDeepLinkOpen, protocol.DeepLinkOpenPayload{Token: token}, protocol.MsgTypeDeepLinkRoute, &route); err != nil {
+		t.Fatal(err)
+	}
+	if route.LinkID != link.ID || route.Kind != "room" || route.Target != roomID {
+		t.Fatalf("DEEP_LINK_ROUTE = %+v", route)
+	}
+	var joined protocol.JoinRoomResponsePayload
+	if err := bob.Expect(protocol.MsgTypeJoinRoomResponse, &joined); err != nil || !joined.Success || joined.RoomID != roomID {
+		t.Fatalf("join after the deep link = %+v, %v", joined, err)
+	}
+	if got := deepLinks.Attributions(); len(got) != 1 || got[0].Link.Source != "partner-blog" || got[0].Players != 1 {
+		t.Errorf("Attributions = %+v", got)
+	}
+}
+
+func TestTimeSyncAndClockHints(t *testing.T) {
+	srv := startServer(t, Options{Services: internalActor.SessionServices{
+		ClockHints: internalActor.ClockHints{Interval: 50 * time.Millisecond, Tolerance: 20 * time.Millisecond},
+	}})
+	alice := login(t, srv, "alice-token")
+
+	before := time.Now().UnixMilli()
+	var sync protocol.TimeSyncResponsePayload
+	if err := alice.Request(protocol.MsgTypeTimeSync, protocol.TimeSyncRequestPayload{T0: 12345}, protocol.MsgTypeTimeSyncResponse, &sync); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().UnixMilli()
+	if sync.T0 != 12345 || sync.ReceivedAt < before || sync.ReceivedAt > sync.SentAt || sync.SentAt > after {
+		t.Fatalf("TIME_SYNC_RESPONSE = %+v, sent between %d and %d", sync, before, after)
+	}
+
+	var hint protocol.ClockHintPayload
+	if err := alice.Expect(protocol.MsgTypeClockHint, &hint); err != nil {
+		t.Fatal(err)
+	}
+	if hint.Tolerance != 20 || hint.ServerTime < sync.SentAt {
+		t.Errorf("CLOCK_HINT = %+v", hint)
+	}
+}
+
+func TestArenaMatchesByMeasuredLatency(t *testing.T) {
+	arenaService, err := arena.NewService(arena.Options{
+		KFactor:      32,
+		SeasonLength: time.Hour,
+		BaseWindow:   100,
+		Latency:      arena.LatencyRules{Window: time.Second},
+		MaxLatency:   100 * time.Millisecond,
+		Regions:      []string{"eu", "us"},
+	}, &arena.MemoryStore{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, Options{
+		Players: map[string]string{"alice-token": "alice", "bob-token": "bob", "carol-token": "carol"},
+		Services: internalActor.SessionServices{
+			Arena:      arenaService,
+			ClockHints: internalActor.ClockHints{Interval: 400 * time.Millisecond},
+		},
+	})
+	// Each client answers its first CLOCK_HINT after a delay, which the server
+	// measures as the round trip.
+	answerHint := func(c *Client, delay time.Duration) {
+		t.Helper()
+		var hint protocol.ClockHintPayload
+		if err := c.Expect(protocol.MsgTypeClockHint, &hint); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(delay)
+		if err := c.Request(protocol.MsgTypePing, protocol.PingPongPayload{HintTime: hint.ServerTime}, protocol.MsgTypePong, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	carol := login(t, srv, "carol-token")
+	answerHint(alice, 20*time.Millisecond)
+	answerHint(bob, 40*time.Millisecond)
+	answerHint(carol, 150*time.Millisecond)
+
+	err = carol.Request(protocol.MsgTypeArenaQueue, protocol.ArenaQueueRequestPayload{Mode: protocol.ArenaMode1v1}, protocol.MsgTypeArenaQueueStatus, nil)
+	if serverErr, ok := err.(*ServerError); !ok || serverErr.Code != "ARENA_LATENCY_TOO_HIGH" {
+		t.Fatalf("queueing on a slow connection: %v", err)
+	}
+	err = alice.Request(protocol.MsgTypeArenaQueue, protocol.ArenaQueueRequestPayload{Mode: protocol.ArenaMode1v1, Regions: []string{"mars"}}, protocol.MsgTypeArenaQueueStatus, nil)
+	if serverErr, ok := err.(*ServerError); !ok || serverErr.Code != "INVALID_ARENA_PAYLOAD" {
+		t.Fatalf("queueing for an unknown region: %v", err)
+	}
+	for _, c := range []*Client{alice, bob} {
+		if err := c.Request(protocol.MsgTypeArenaQueue, protocol.ArenaQueueRequestPayload{Mode: protocol.ArenaMode1v1, Regions: []string{"us", "eu"}}, protocol.MsgTypeArenaQueueStatus, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	arenaService.Tick(time.Now())
+
+	var found protocol.ArenaMatchFoundPayload
+	if err := alice.Expect(protocol.MsgTypeArenaMatchFound, &found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Region != "us" || found.LatencyMs < 20 || found.ExpectedLatencyMs < 40 || found.ExpectedLatencyMs >= 100 {
+		t.Errorf("ARENA_MATCH_FOUND = %+v", found)
+	}
+}
+
+func TestParentalControlsRestrictChatAndEndPlaytime(t *testing.T) {
+	controls, err := parental.NewService(&parental.MemoryStore{}, parental.Options{CheckInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := controls.Set("alice", parental.Controls{DailyPlaytimeMinutes: 1, ChatRestricted: true}, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	controls.AddPlaytime("alice", time.Minute-500*time.Millisecond)
+	srv := startServer(t, Options{Services: internalActor.SessionServices{Parental: controls}})
+
+	alice := login(t, srv, "alice-token")
+	var status protocol.ParentalStatusPayload
+	if err := alice.Expect(protocol.MsgTypeParentalStatus, &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Restricted || !status.ChatRestricted || status.Warning != protocol.ParentalLimitPlaytime || status.TimeLeft > 1 {
+		t.Fatalf("PARENTAL_STATUS = %+v", status)
+	}
+	login(t, srv, "bob-token")
+	err = alice.Request(protocol.MsgTypeSendWhisper, protocol.WhisperRequestPayload{ToPlayerID: "bob", Text: "hi"}, protocol.MsgTypeNewWhisper, nil)
+	if serverErr, ok := err.(*ServerError); !ok || serverErr.Code != "CHAT_RESTRICTED" {
+		t.Fatalf("whisper with restricted chat: %v", err)
+	}
+
+	// The rest of the day's playtime runs out and the session ends.
+	err = alice.Expect(protocol.MsgTypePong, nil)
+	if serverErr, ok := err.(*ServerError); !ok || serverErr.Code != "PLAYTIME_LIMIT_REACHED" {
+		t.Fatalf("session past the playtime limit: %v", err)
+	}
+	_, err = srv.Login("alice-token")
+	if serverErr, ok := err.(*ServerError); !ok || serverErr.Code != "PLAYTIME_LIMIT_REACHED" {
+		t.Fatalf("login past the playtime limit: %v", err)
+	}
+	if used := controls.Status("alice").PlaytimeUsed; used < time.Minute {
+		t.Errorf("playtime used = %v", used)
+	}
+}
+
+func TestTransfersMovePlayersBetweenRooms(t *testing.T) {
+	srv := startServer(t, Options{})
+	directory := worlds.NewDirectory()
+	if err := directory.Add(&worlds.World{ID: worlds.DefaultID, RoomManagerPID: srv.RoomManager, WorldManagerPID: srv.WorldManager}); err != nil {
+		t.Fatal(err)
+	}
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	vault, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "vault", Visibility: protocol.RoomVisibilityPrivate, Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Expect(protocol.MsgTypeJoinRoomResponse, nil); err != nil {
+		t.Fatal(err)
+	}
+	hall, err := bob.CreateRoom(protocol.CreateRoomRequestPayload{Name: "hall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Expect(protocol.MsgTypeJoinRoomResponse, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A refused transfer leaves the player where they were.
+	result, err := directory.TransferPlayer(srv.System.Root, "bob", "no-such-room", "portal", time.Second)
+	if err != nil || result.Success || result.FromRoomID != hall {
+		t.Fatalf("transfer to a missing room = %+v, %v", result, err)
+	}
+	err = bob.Expect(protocol.MsgTypeRoomTransfer, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != "ROOM_TRANSFER_FAILED" {
+		t.Fatalf("after a refused transfer: %v, want ROOM_TRANSFER_FAILED", err)
+	}
+
+	// The server moves past the password, and the client gets the new room at once.
+	result, err = directory.TransferPlayer(srv.System.Root, "bob", vault, "portal", time.Second)
+	if err != nil || !result.Success || result.FromRoomID != hall || result.RoomID != vault {
+		t.Fatalf("transfer = %+v, %v", result, err)
+	}
+	var moved protocol.RoomTransferPayload
+	if err := bob.Expect(protocol.MsgTypeRoomTransfer, &moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved.FromRoomID != hall || moved.RoomID != vault || moved.Reason != "portal" || len(moved.Players) != 2 || moved.ServerTime == 0 {
+		t.Fatalf("ROOM_TRANSFER = %+v", moved)
+	}
+	diagnostics, err := directory.PlayerDiagnostics(srv.System.Root, "bob", time.Second)
+	if err != nil || diagnostics.RoomID != vault {
+		t.Fatalf("bob's room after the transfer = %+v, %v", diagnostics, err)
+	}
+	if result, err := directory.TransferPlayer(srv.System.Root, "bob", vault, "portal", time.Second); err != nil || result.Success {
+		t.Fatalf("transfer into the current room = %+v, %v", result, err)
+	}
+	if _, err := directory.TransferPlayer(srv.System.Root, "carol", hall, "admin", time.Second); !errors.Is(err, worlds.ErrPlayerOffline) {
+		t.Fatalf("transfer of an offline player: %v", err)
+	}
+}
+
+func TestKickedPlayersAreToldWhyBeforeTheConnectionCloses(t *testing.T) {
+	srv := startServer(t, Options{})
+	directory := worlds.NewDirectory()
+	if err := directory.Add(&worlds.World{ID: worlds.DefaultID, RoomManagerPID: srv.RoomManager, WorldManagerPID: srv.WorldManager}); err != nil {
+		t.Fatal(err)
+	}
+	alice := login(t, srv, "alice-token")
+	if err := directory.KickPlayer(srv.System.Root, "alice", "Account under review.", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var disconnect protocol.DisconnectPayload
+	if err := alice.Expect(protocol.MsgTypeDisconnect, &disconnect); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.DisconnectPayload{Code: protocol.DisconnectKicked, Reason: "Account under review."}
+	if disconnect != want {
+		t.Fatalf("DISCONNECT = %+v, want %+v", disconnect, want)
+	}
+	if msg, err := alice.Receive(); err == nil {
+		t.Fatalf("connection still open after DISCONNECT, got %s", msg.Type)
+	}
+	if err := directory.KickPlayer(srv.System.Root, "carol", "", time.Second); !errors.Is(err, worlds.ErrPlayerOffline) {
+		t.Fatalf("kick of an offline player: %v", err)
+	}
+}
+
+func TestHighStakesLootIsRevealedWithItsCommittedSeed(t *testing.T) {
+	values := balance.Defaults()
+	values.Combat.HitChance, values.Combat.CritChance, values.Combat.EvadeChance = 1, 0, 0
+	values.Loot.Tables["default"] = []balance.LootDrop{{ItemID: "dragon_scale", Chance: 1, Min: 1, Max: 3}, {ItemID: "gold", Chance: 0.5, Min: 5, Max: 9}}
+	fairRolls := fairroll.NewService([]string{"default"})
+	combat := game.NewCombatEngine(nil)
+	combat.UseBalance(func() balance.Values { return values })
+	combat.RollLootWith(fairRolls)
+	srv := startServer(t, Options{
+		Services: internalActor.SessionServices{FairRolls: fairRolls},
+		Rooms: internalActor.RoomServices{
+			Tick:        20 * time.Millisecond,
+			Projectiles: projectile.NewArsenal([]projectile.Definition{{ID: "bolt", Speed: 30, Range: 20, AttackPower: 500}}),
+			Combat:      combat,
+			PlayerStats: game.CombatantStats{Health: 100, MaxHealth: 100},
+		},
+	})
+	alice := login(t, srv, "alice-token")
+	bob := login(t, srv, "bob-token")
+	var committed protocol.LootCommitmentPayload
+	if err := alice.Expect(protocol.MsgTypeLootCommitment, &committed); err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := alice.CreateRoom(protocol.CreateRoomRequestPayload{Name: "lair"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.JoinRoom(roomID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgTypeMove, protocol.MovePayload{X: 8, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var update protocol.PositionUpdatePayload
+		if err := alice.Expect(protocol.MsgTypePositionUpdate, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.PlayerID == "bob" && update.X == 8 {
+			break
+		}
+	}
+	if err := alice.Send(protocol.MsgTypeFireProjectile, protocol.FireProjectilePayload{ProjectileID: "bolt", X: 20, Y: 0}); err != nil {
+		t.Fatal(err)
+	}
+	// The reveal and the next commitment come from the roll, and the hit from
+	// the room, in either order.
+	var hit protocol.ProjectileHitPayload
+	var reveal protocol.LootRevealPayload
+	var next protocol.LootCommitmentPayload
+	for hit.ID == "" || reveal.Seed == "" || next.Hash == "" {
+		msg, err := alice.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case protocol.MsgTypeProjectileHit:
+			msg.Decode(&hit)
+		case protocol.MsgTypeLootReveal:
+			msg.Decode(&reveal)
+		case protocol.MsgTypeLootCommitment:
+			msg.Decode(&next)
+		}
+	}
+	if !hit.Defeated || hit.Loot["dragon_scale"] == 0 || reveal.CommitmentID != committed.CommitmentID || reveal.Hash != committed.Hash {
+		t.Fatalf("hit = %+v, reveal = %+v for commitment %+v", hit, reveal, committed)
+	}
+
+	// The client can check the roll from the reveal alone.
+	checked := fairroll.Reveal{Hash: reveal.Hash, Seed: reveal.Seed, Table: reveal.Table, DropRateMultiplier: reveal.DropRateMultiplier, Draws: reveal.Draws, Loot: hit.Loot}
+	for _, drop := range reveal.Drops {
+		checked.Drops = append(checked.Drops, balance.LootDrop{ItemID: drop.ItemID, Chance: drop.Chance, Min: drop.Min, Max: drop.Max})
+	}
+	if err := fairroll.Verify(checked); err != nil {
+		t.Fatalf("Verify(%+v): %v", reveal, err)
+	}
+	if next.CommitmentID == committed.CommitmentID || next.Hash == committed.Hash {
+		t.Errorf("next commitment = %+v, want a new seed", next)
+	}
+}
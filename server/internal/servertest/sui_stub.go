@@ -0,0 +1,497 @@
+package servertest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// SuiCoinType is the coin type used when a coin query names none.
+const SuiCoinType = "0x2::sui::SUI"
+
+// ExecuteFunc runs when a transaction calling its Move function is executed.
+// It can change the stub, e.g. emit the events the contract would, and
+// returns an error to make the transaction fail on-chain.
+type ExecuteFunc func(stub *SuiStub, call models.MoveCallRequest) error
+
+// SuiStub is an in-memory sui.ISuiAPI holding canned objects, coins and
+// events. MoveCall prepares a transaction without checking it, and executing
+// the transaction records it and runs the ExecuteFunc registered for its
+// function, if any. Signatures are not checked. It is safe for concurrent use.
+type SuiStub struct {
+	sui.ISuiAPI // Calls the server never makes; they panic on the nil interface
+
+	mu         sync.Mutex
+	objects    map[string]models.SuiObjectData
+	owners     map[string]string            // Object ID -> owner address
+	coins      map[string][]models.CoinData // Owner address -> coins
+	events     []models.SuiEventResponse    // Oldest first
+	onExecute  map[string]ExecuteFunc       // Move function -> effect
+	moveCalls  []models.MoveCallRequest
+	prepared   map[string][]models.MoveCallRequest // TxBytes -> calls, one unless batched
+	executed   []models.MoveCallRequest
+	txs        map[string]models.SuiTransactionBlockResponse // Digest -> executed transaction
+	calls      map[string]int                                // ISuiAPI method -> times called
+	seq        int
+	checkpoint uint64
+}
+
+// NewSuiStub creates an empty chain.
+func NewSuiStub() *SuiStub {
+	return &SuiStub{
+		objects:   make(map[string]models.SuiObjectData),
+		owners:    make(map[string]string),
+		coins:     make(map[string][]models.CoinData),
+		onExecute: make(map[string]ExecuteFunc),
+		prepared:  make(map[string][]models.MoveCallRequest),
+		txs:       make(map[string]models.SuiTransactionBlockResponse),
+		calls:     make(map[string]int),
+	}
+}
+
+// PutObject adds or replaces a Move object owned by owner.
+func (s *SuiStub) PutObject(objectID, owner, objectType string, fields map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version := 1
+	if old, ok := s.objects[objectID]; ok {
+		v, _ := strconv.Atoi(old.Version)
+		version = v + 1
+	}
+	s.objects[objectID] = models.SuiObjectData{
+		ObjectId: objectID,
+		Version:  strconv.Itoa(version),
+		Digest:   s.nextID("objdigest"),
+		Type:     objectType,
+		Owner:    map[string]interface{}{"AddressOwner": owner},
+		Content: &models.SuiParsedData{
+			DataType:      "moveObject",
+			SuiMoveObject: models.SuiMoveObject{Type: objectType, Fields: fields, HasPublicTransfer: true},
+		},
+	}
+	s.owners[objectID] = owner
+}
+
+// DeleteObject removes an object, e.g. one burned by a transaction.
+func (s *SuiStub) DeleteObject(objectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, objectID)
+	delete(s.owners, objectID)
+}
+
+// AddCoin gives owner a coin of coinType and returns the coin's object ID.
+// An empty coinType is SuiCoinType.
+func (s *SuiStub) AddCoin(owner, coinType string, balance uint64) string {
+	if coinType == "" {
+		coinType = SuiCoinType
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coin := models.CoinData{
+		CoinType:     coinType,
+		CoinObjectId: s.nextID("0xcoin"),
+		Version:      "1",
+		Digest:       s.nextID("coindigest"),
+		Balance:      strconv.FormatUint(balance, 10),
+	}
+	s.coins[owner] = append(s.coins[owner], coin)
+	return coin.CoinObjectId
+}
+
+// EmitEvent appends a Move event of eventType ("<package>::<module>::<Name>")
+// as if a transaction by sender had emitted it, and returns its ID.
+func (s *SuiStub) EmitEvent(eventType, sender string, fields map[string]interface{}) models.EventId {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.emitLocked(s.nextID("eventtx"), eventType, sender, fields)
+}
+
+func (s *SuiStub) emitLocked(txDigest, eventType, sender string, fields map[string]interface{}) models.EventId {
+	id := models.EventId{TxDigest: txDigest, EventSeq: strconv.Itoa(len(s.events))}
+	parts := strings.SplitN(eventType, "::", 3)
+	event := models.SuiEventResponse{
+		Id:          id,
+		Type:        eventType,
+		Sender:      sender,
+		ParsedJson:  fields,
+		TimestampMs: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}
+	if len(parts) == 3 {
+		event.PackageId, event.TransactionModule = parts[0], parts[1]
+	}
+	s.events = append(s.events, event)
+	s.checkpoint++
+	return id
+}
+
+// OnExecute registers fn to run whenever a transaction calling the Move
+// function is executed. It replaces any earlier registration.
+func (s *SuiStub) OnExecute(function string, fn ExecuteFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onExecute[function] = fn
+}
+
+// MoveCalls returns every call prepared so far, in order. A batched
+// transaction contributes each of its calls.
+func (s *SuiStub) MoveCalls() []models.MoveCallRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.MoveCallRequest(nil), s.moveCalls...)
+}
+
+// Executed returns the calls of every transaction executed so far, in order,
+// including those that failed.
+func (s *SuiStub) Executed() []models.MoveCallRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.MoveCallRequest(nil), s.executed...)
+}
+
+// Calls returns how many times the ISuiAPI method, e.g. "SuiXQueryEvents",
+// has been called.
+func (s *SuiStub) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// nextID returns a unique ID starting with prefix. The caller holds s.mu.
+func (s *SuiStub) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%d", prefix, s.seq)
+}
+
+// object returns a Move object, or a coin added with AddCoin, as a node would.
+func (s *SuiStub) object(objectID string) models.SuiObjectResponse {
+	if data, ok := s.objects[objectID]; ok {
+		return models.SuiObjectResponse{Data: &data}
+	}
+	for owner, coins := range s.coins {
+		for _, coin := range coins {
+			if coin.CoinObjectId == objectID {
+				return models.SuiObjectResponse{Data: &models.SuiObjectData{
+					ObjectId: objectID,
+					Version:  coin.Version,
+					Digest:   coin.Digest,
+					Type:     "0x2::coin::Coin<" + coin.CoinType + ">",
+					Owner:    map[string]interface{}{"AddressOwner": owner},
+				}}
+			}
+		}
+	}
+	return models.SuiObjectResponse{Error: &models.SuiObjectResponseError{Code: "notExists", ObjectId: objectID}}
+}
+
+// SuiGetObject implements sui.ISuiAPI. Missing objects get a notExists error
+// in the response, as from a real node.
+func (s *SuiStub) SuiGetObject(ctx context.Context, req models.SuiGetObjectRequest) (models.SuiObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiGetObject"]++
+	return s.object(req.ObjectId), nil
+}
+
+// SuiMultiGetObjects implements sui.ISuiAPI.
+func (s *SuiStub) SuiMultiGetObjects(ctx context.Context, req models.SuiMultiGetObjectsRequest) ([]*models.SuiObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiMultiGetObjects"]++
+	responses := make([]*models.SuiObjectResponse, len(req.ObjectIds))
+	for i, id := range req.ObjectIds {
+		response := s.object(id)
+		responses[i] = &response
+	}
+	return responses, nil
+}
+
+// SuiXGetOwnedObjects implements sui.ISuiAPI. A StructType filter is
+// honoured; other filters are ignored. Everything fits on one page.
+func (s *SuiStub) SuiXGetOwnedObjects(ctx context.Context, req models.SuiXGetOwnedObjectsRequest) (models.PaginatedObjectsResponse, error) {
+	structType := ""
+	if filter, ok := filterMap(req.Query.Filter); ok {
+		structType, _ = filter["StructType"].(string)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiXGetOwnedObjects"]++
+	var ids []string
+	for id, owner := range s.owners {
+		if owner == req.Address && (structType == "" || s.objects[id].Type == structType) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var page models.PaginatedObjectsResponse
+	for _, id := range ids {
+		page.Data = append(page.Data, s.object(id))
+	}
+	return page, nil
+}
+
+// SuiXGetCoins implements sui.ISuiAPI. Everything fits on one page.
+func (s *SuiStub) SuiXGetCoins(ctx context.Context, req models.SuiXGetCoinsRequest) (models.PaginatedCoinsResponse, error) {
+	coinType := req.CoinType
+	if coinType == "" {
+		coinType = SuiCoinType
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiXGetCoins"]++
+	var page models.PaginatedCoinsResponse
+	for _, coin := range s.coins[req.Owner] {
+		if coin.CoinType == coinType {
+			page.Data = append(page.Data, coin)
+		}
+	}
+	return page, nil
+}
+
+// SuiXGetBalance implements sui.ISuiAPI.
+func (s *SuiStub) SuiXGetBalance(ctx context.Context, req models.SuiXGetBalanceRequest) (models.CoinBalanceResponse, error) {
+	s.mu.Lock()
+	s.calls["SuiXGetBalance"]++
+	s.mu.Unlock()
+	coins, err := s.SuiXGetCoins(ctx, models.SuiXGetCoinsRequest{Owner: req.Owner, CoinType: req.CoinType})
+	if err != nil {
+		return models.CoinBalanceResponse{}, err
+	}
+	var total uint64
+	for _, coin := range coins.Data {
+		balance, _ := strconv.ParseUint(coin.Balance, 10, 64)
+		total += balance
+	}
+	coinType := req.CoinType
+	if coinType == "" {
+		coinType = SuiCoinType
+	}
+	return models.CoinBalanceResponse{
+		CoinType:        coinType,
+		CoinObjectCount: len(coins.Data),
+		TotalBalance:    strconv.FormatUint(total, 10),
+	}, nil
+}
+
+// SuiXGetAllBalance implements sui.ISuiAPI, listing coin types in order.
+func (s *SuiStub) SuiXGetAllBalance(ctx context.Context, req models.SuiXGetAllBalanceRequest) (models.CoinAllBalanceResponse, error) {
+	s.mu.Lock()
+	s.calls["SuiXGetAllBalance"]++
+	var coinTypes []string
+	seen := make(map[string]bool)
+	for _, coin := range s.coins[req.Owner] {
+		if !seen[coin.CoinType] {
+			seen[coin.CoinType] = true
+			coinTypes = append(coinTypes, coin.CoinType)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(coinTypes)
+	balances := models.CoinAllBalanceResponse{}
+	for _, coinType := range coinTypes {
+		balance, err := s.SuiXGetBalance(ctx, models.SuiXGetBalanceRequest{Owner: req.Owner, CoinType: coinType})
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
+
+// SuiXQueryEvents implements sui.ISuiAPI for MoveEventType, MoveEventModule,
+// MoveModule and Sender filters; other filters match every event.
+func (s *SuiStub) SuiXQueryEvents(ctx context.Context, req models.SuiXQueryEventsRequest) (models.PaginatedEventsResponse, error) {
+	filter, _ := filterMap(req.SuiEventFilter)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiXQueryEvents"]++
+
+	// Events are numbered by position, so the cursor's EventSeq is an index.
+	start, step := 0, 1
+	if req.DescendingOrder {
+		start, step = len(s.events)-1, -1
+	}
+	if cursor, ok := req.Cursor.(*models.EventId); ok && cursor != nil {
+		seq, err := strconv.Atoi(cursor.EventSeq)
+		if err != nil {
+			return models.PaginatedEventsResponse{}, fmt.Errorf("invalid cursor %s:%s", cursor.TxDigest, cursor.EventSeq)
+		}
+		start = seq + step
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var page models.PaginatedEventsResponse
+	for i := start; i >= 0 && i < len(s.events); i += step {
+		event := s.events[i]
+		if !eventMatches(filter, event) {
+			continue
+		}
+		if len(page.Data) == limit {
+			page.HasNextPage = true
+			break
+		}
+		page.Data = append(page.Data, event)
+		page.NextCursor = event.Id
+	}
+	return page, nil
+}
+
+func eventMatches(filter map[string]interface{}, event models.SuiEventResponse) bool {
+	if eventType, ok := filter["MoveEventType"].(string); ok {
+		return event.Type == eventType
+	}
+	for _, key := range []string{"MoveEventModule", "MoveModule"} {
+		if module, ok := filterMap(filter[key]); ok {
+			return strings.HasPrefix(event.Type, fmt.Sprintf("%s::%s::", module["package"], module["module"]))
+		}
+	}
+	if sender, ok := filter["Sender"].(string); ok {
+		return event.Sender == sender
+	}
+	return true
+}
+
+// filterMap reads a filter given as any map type, e.g. models.SuiEventFilter.
+func filterMap(filter interface{}) (map[string]interface{}, bool) {
+	if filter == nil {
+		return nil, false
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return nil, false
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// SuiGetLatestCheckpointSequenceNumber implements sui.ISuiAPI. The checkpoint
+// advances with every event and executed transaction.
+func (s *SuiStub) SuiGetLatestCheckpointSequenceNumber(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiGetLatestCheckpointSequenceNumber"]++
+	return s.checkpoint, nil
+}
+
+// MoveCall implements sui.ISuiAPI. It records the call and returns opaque
+// transaction bytes for it.
+func (s *SuiStub) MoveCall(ctx context.Context, req models.MoveCallRequest) (models.TxnMetaData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["MoveCall"]++
+	txBytes := base64.StdEncoding.EncodeToString([]byte(s.nextID("stubtx")))
+	s.moveCalls = append(s.moveCalls, req)
+	s.prepared[txBytes] = []models.MoveCallRequest{req}
+	return models.TxnMetaData{TxBytes: txBytes}, nil
+}
+
+// BatchTransaction implements sui.ISuiAPI. It prepares one transaction
+// running every Move call in order; transfers are not supported.
+func (s *SuiStub) BatchTransaction(ctx context.Context, req models.BatchTransactionRequest) (models.BatchTransactionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["BatchTransaction"]++
+	calls := make([]models.MoveCallRequest, 0, len(req.RPCTransactionRequestParams))
+	for _, params := range req.RPCTransactionRequestParams {
+		if params.MoveCallRequestParams == nil {
+			return models.BatchTransactionResponse{}, fmt.Errorf("the stub only batches Move calls")
+		}
+		call := *params.MoveCallRequestParams
+		call.Signer, call.Gas, call.GasBudget = req.Signer, req.Gas, req.GasBudget
+		calls = append(calls, call)
+	}
+	txBytes := base64.StdEncoding.EncodeToString([]byte(s.nextID("stubtx")))
+	s.moveCalls = append(s.moveCalls, calls...)
+	s.prepared[txBytes] = calls
+	return models.BatchTransactionResponse{TxBytes: txBytes}, nil
+}
+
+// SuiDryRunTransactionBlock implements sui.ISuiAPI. Every prepared
+// transaction dry-runs successfully; ExecuteFuncs do not run.
+func (s *SuiStub) SuiDryRunTransactionBlock(ctx context.Context, req models.SuiDryRunTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiDryRunTransactionBlock"]++
+	if _, ok := s.prepared[req.TxBytes]; !ok {
+		return models.SuiTransactionBlockResponse{}, fmt.Errorf("unknown transaction bytes")
+	}
+	return models.SuiTransactionBlockResponse{Effects: models.SuiEffects{Status: models.ExecutionStatus{Status: "success"}}}, nil
+}
+
+// SuiExecuteTransactionBlock implements sui.ISuiAPI. The transaction's
+// ExecuteFunc runs first; events it emits are returned with the transaction.
+func (s *SuiStub) SuiExecuteTransactionBlock(ctx context.Context, req models.SuiExecuteTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	s.mu.Lock()
+	s.calls["SuiExecuteTransactionBlock"]++
+	calls, ok := s.prepared[req.TxBytes]
+	if !ok {
+		s.mu.Unlock()
+		return models.SuiTransactionBlockResponse{}, fmt.Errorf("unknown transaction bytes")
+	}
+	delete(s.prepared, req.TxBytes)
+	digest := s.nextID("stubdigest")
+	fns := make([]ExecuteFunc, len(calls))
+	for i, call := range calls {
+		fns[i] = s.onExecute[call.Function]
+	}
+	firstEvent := len(s.events)
+	s.mu.Unlock()
+
+	// The calls of a batch run in order until one fails. Effects of the
+	// calls before it are not undone.
+	status := models.ExecutionStatus{Status: "success"}
+	for i, fn := range fns {
+		if fn == nil {
+			continue
+		}
+		if err := fn(s, calls[i]); err != nil {
+			status = models.ExecutionStatus{Status: "failure", Error: err.Error()}
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response := models.SuiTransactionBlockResponse{
+		Digest:      digest,
+		Effects:     models.SuiEffects{Status: status, TransactionDigest: digest},
+		TimestampMs: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}
+	for i := firstEvent; i < len(s.events); i++ {
+		// Events emitted by fn belong to this transaction.
+		s.events[i].Id.TxDigest = digest
+		response.Events = append(response.Events, s.events[i])
+	}
+	s.checkpoint++
+	response.Checkpoint = strconv.FormatUint(s.checkpoint, 10)
+	s.executed = append(s.executed, calls...)
+	s.txs[digest] = response
+	return response, nil
+}
+
+// SuiGetTransactionBlock implements sui.ISuiAPI for executed transactions.
+func (s *SuiStub) SuiGetTransactionBlock(ctx context.Context, req models.SuiGetTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["SuiGetTransactionBlock"]++
+	response, ok := s.txs[req.Digest]
+	if !ok {
+		return models.SuiTransactionBlockResponse{}, fmt.Errorf("transaction %s not found", req.Digest)
+	}
+	return response, nil
+}
@@ -0,0 +1,130 @@
+// Package shop runs NPC vendors: shops defined in a data file that buy and
+// sell items for soft currency, with limited stock that restocks over time,
+// per-player purchase limits, and premium items paid for on-chain.
+package shop
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultCoinType is the coin premium prices are in unless an item says otherwise.
+const DefaultCoinType = "0x2::sui::SUI"
+
+// Premium marks an item that is paid for on-chain and minted as an item NFT.
+type Premium struct {
+	Price    uint64 `json:"price"`              // In the coin's smallest unit, e.g. MIST for SUI
+	CoinType string `json:"coinType,omitempty"` // Defaults to DefaultCoinType
+}
+
+// Item is one entry of a shop's price table.
+type Item struct {
+	ItemID    string `json:"itemId"`
+	Name      string `json:"name"`
+	BuyPrice  int64  `json:"buyPrice,omitempty"`  // Soft currency the player pays; 0 if the shop does not sell it for coins
+	SellPrice int64  `json:"sellPrice,omitempty"` // Soft currency the shop pays; 0 if it does not buy the item
+	Stock     int    `json:"stock,omitempty"`     // 0 is unlimited
+	// RestockSeconds is how often RestockAmount units come back, up to Stock. 0 never restocks.
+	RestockSeconds int `json:"restockSeconds,omitempty"`
+	RestockAmount  int `json:"restockAmount,omitempty"` // Defaults to a full restock
+	// PlayerLimit caps how many units one player may buy per LimitWindowSeconds
+	// (for good if the window is 0). 0 is no limit.
+	PlayerLimit        int      `json:"playerLimit,omitempty"`
+	LimitWindowSeconds int      `json:"limitWindowSeconds,omitempty"`
+	Premium            *Premium `json:"premium,omitempty"`
+}
+
+func (i Item) restockInterval() time.Duration { return time.Duration(i.RestockSeconds) * time.Second }
+func (i Item) limitWindow() time.Duration     { return time.Duration(i.LimitWindowSeconds) * time.Second }
+
+// Shop is one NPC vendor.
+type Shop struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+func (s *Shop) item(itemID string) (Item, bool) {
+	for _, item := range s.Items {
+		if item.ItemID == itemID {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// Catalog is every shop, loaded from a data file.
+type Catalog struct {
+	Shops []Shop `json:"shops"`
+}
+
+// LoadCatalog reads and validates a shop catalog from a JSON file.
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid shop catalog %s: %w", path, err)
+	}
+	if err := catalog.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shop catalog %s: %w", path, err)
+	}
+	return &catalog, nil
+}
+
+// Validate checks shop and item IDs and prices, and fills in defaults.
+func (c *Catalog) Validate() error {
+	shops := make(map[string]bool, len(c.Shops))
+	for si := range c.Shops {
+		shop := &c.Shops[si]
+		if shop.ID == "" {
+			return fmt.Errorf("shop %d needs an id", si+1)
+		}
+		if shops[shop.ID] {
+			return fmt.Errorf("duplicate shop id %q", shop.ID)
+		}
+		shops[shop.ID] = true
+		items := make(map[string]bool, len(shop.Items))
+		for ii := range shop.Items {
+			item := &shop.Items[ii]
+			if item.ItemID == "" {
+				return fmt.Errorf("shop %q: item %d needs an itemId", shop.ID, ii+1)
+			}
+			if items[item.ItemID] {
+				return fmt.Errorf("shop %q: duplicate item %q", shop.ID, item.ItemID)
+			}
+			items[item.ItemID] = true
+			if item.BuyPrice < 0 || item.SellPrice < 0 || item.Stock < 0 || item.PlayerLimit < 0 {
+				return fmt.Errorf("shop %q: item %q has a negative price, stock or limit", shop.ID, item.ItemID)
+			}
+			if item.Premium != nil {
+				if item.Premium.Price == 0 {
+					return fmt.Errorf("shop %q: premium item %q needs a price", shop.ID, item.ItemID)
+				}
+				if item.BuyPrice > 0 || item.SellPrice > 0 {
+					return fmt.Errorf("shop %q: premium item %q cannot also have coin prices", shop.ID, item.ItemID)
+				}
+				if item.Premium.CoinType == "" {
+					item.Premium.CoinType = DefaultCoinType
+				}
+			}
+			if item.Stock > 0 && item.RestockSeconds > 0 && item.RestockAmount <= 0 {
+				item.RestockAmount = item.Stock
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) shop(id string) (*Shop, bool) {
+	for i := range c.Shops {
+		if c.Shops[i].ID == id {
+			return &c.Shops[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,395 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// PremiumMintKind is the outbox message kind for premium purchases. Its payload is a PremiumPurchase.
+const PremiumMintKind = "shop.premium_mint"
+
+var (
+	ErrUnknownShop       = errors.New("unknown shop")
+	ErrUnknownItem       = errors.New("the shop does not stock that item")
+	ErrInvalidQuantity   = errors.New("quantity must be at least 1")
+	ErrNotForSale        = errors.New("the shop does not sell that item for coins")
+	ErrNotBuying         = errors.New("the shop does not buy that item")
+	ErrPremiumItem       = errors.New("that item is bought with an on-chain payment")
+	ErrNotPremium        = errors.New("that item is not a premium item")
+	ErrPremiumDisabled   = errors.New("premium purchases are not enabled")
+	ErrOutOfStock        = errors.New("out of stock")
+	ErrLimitReached      = errors.New("purchase limit reached")
+	ErrInsufficientFunds = errors.New("not enough coins")
+	ErrNotEnoughItems    = errors.New("not enough items to sell")
+	ErrPaymentUsed       = errors.New("that payment has already been used")
+	ErrPaymentInvalid    = errors.New("payment could not be verified")
+)
+
+// PaymentVerifier checks that txDigest is a successful on-chain payment of at
+// least amount of coinType from payer to recipient. sui.SuiClient implements it.
+type PaymentVerifier interface {
+	VerifyPayment(txDigest, payer, recipient, coinType string, amount uint64) error
+}
+
+// PremiumPurchase is the payload of a PremiumMintKind outbox message.
+type PremiumPurchase struct {
+	PlayerID string `json:"playerId"`
+	Address  string `json:"address,omitempty"` // Sui address that paid and receives the item
+	ShopID   string `json:"shopId"`
+	ItemID   string `json:"itemId"`
+	Name     string `json:"name"`
+	TxDigest string `json:"txDigest"`
+}
+
+// ItemView is an item as shown to one player.
+type ItemView struct {
+	Item
+	Remaining     int       // -1 for unlimited stock
+	NextRestock   time.Time // Zero unless units are due back
+	PurchasesLeft int       // -1 for no limit
+	LimitResetsAt time.Time // Zero unless the limit is reached and resets
+}
+
+// View is a shop as shown to one player.
+type View struct {
+	ShopID           string
+	Name             string
+	Coins            int64
+	Items            []ItemView
+	PremiumRecipient string // Address premium payments go to
+}
+
+// Receipt describes a completed purchase or sale.
+type Receipt struct {
+	ShopID   string
+	ItemID   string
+	Quantity int
+	Price    int64 // Coins paid (buy) or received (sell)
+	Coins    int64 // Balance afterwards
+	Premium  bool  // The item will be minted on-chain
+}
+
+// Service runs the shops. It is safe for concurrent use by session actors.
+// Stock restocks lazily: it is brought up to date whenever an item is looked at.
+type Service struct {
+	catalog   *Catalog
+	wallets   WalletStore
+	store     Store
+	verifier  PaymentVerifier
+	mints     *outbox.Outbox
+	recipient string
+	now       func() time.Time
+
+	mu      sync.Mutex
+	state   State
+	pending map[string]bool // Payment digests being verified
+}
+
+// NewService creates a Service for a validated catalog and loads its state.
+func NewService(catalog *Catalog, wallets WalletStore, store Store) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("could not load shop state: %w", err)
+	}
+	if state.Stock == nil {
+		state.Stock = make(map[string]StockLevel)
+	}
+	if state.Purchases == nil {
+		state.Purchases = make(map[string]PurchaseCount)
+	}
+	if state.Payments == nil {
+		state.Payments = make(map[string]string)
+	}
+	return &Service{
+		catalog: catalog,
+		wallets: wallets,
+		store:   store,
+		now:     time.Now,
+		state:   state,
+		pending: make(map[string]bool),
+	}, nil
+}
+
+// EnablePremium turns on premium purchases: payments to recipient are checked
+// with verifier and the items are queued for minting in mints.
+func (s *Service) EnablePremium(verifier PaymentVerifier, mints *outbox.Outbox, recipient string) {
+	s.verifier, s.mints, s.recipient = verifier, mints, recipient
+}
+
+// Browse returns a shop's items with the stock and limits that apply to playerID.
+func (s *Service) Browse(playerID, shopID string) (View, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shop, ok := s.catalog.shop(shopID)
+	if !ok {
+		return View{}, ErrUnknownShop
+	}
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err != nil {
+		return View{}, err
+	}
+	now := s.now()
+	view := View{ShopID: shop.ID, Name: shop.Name, Coins: wallet.Coins}
+	if s.verifier != nil {
+		view.PremiumRecipient = s.recipient
+	}
+	for _, item := range shop.Items {
+		if item.Premium != nil && s.verifier == nil {
+			continue
+		}
+		itemView := ItemView{Item: item, Remaining: -1, PurchasesLeft: -1}
+		if level, limited := s.stock(shop.ID, item, now); limited {
+			itemView.Remaining = level.Remaining
+			if level.Remaining < item.Stock && item.RestockSeconds > 0 {
+				itemView.NextRestock = level.RestockedAt.Add(item.restockInterval())
+			}
+		}
+		if item.PlayerLimit > 0 {
+			count := s.purchases(playerID, shop.ID, item, now)
+			itemView.PurchasesLeft = item.PlayerLimit - count.Count
+			if itemView.PurchasesLeft <= 0 && item.LimitWindowSeconds > 0 {
+				itemView.LimitResetsAt = count.WindowStart.Add(item.limitWindow())
+			}
+		}
+		view.Items = append(view.Items, itemView)
+	}
+	return view, nil
+}
+
+// Buy sells quantity units of an item to playerID for soft currency.
+func (s *Service) Buy(playerID, shopID, itemID string, quantity int) (Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, err := s.lookup(shopID, itemID, quantity)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if item.Premium != nil {
+		return Receipt{}, ErrPremiumItem
+	}
+	if item.BuyPrice == 0 {
+		return Receipt{}, ErrNotForSale
+	}
+	now := s.now()
+	if err := s.checkAvailable(playerID, shopID, item, quantity, now); err != nil {
+		return Receipt{}, err
+	}
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err != nil {
+		return Receipt{}, err
+	}
+	cost := item.BuyPrice * int64(quantity)
+	if wallet.Coins < cost {
+		return Receipt{}, ErrInsufficientFunds
+	}
+	wallet.Coins -= cost
+	if wallet.Inventory == nil {
+		wallet.Inventory = make(map[string]int)
+	}
+	wallet.Inventory[itemID] += quantity
+	if err := s.wallets.SaveWallet(playerID, wallet); err != nil {
+		return Receipt{}, err
+	}
+	s.take(playerID, shopID, item, quantity, now)
+	s.save()
+	utils.LogInfof("Shop: %s bought %d x %s from %s for %d coins.", playerID, quantity, itemID, shopID, cost)
+	return Receipt{ShopID: shopID, ItemID: itemID, Quantity: quantity, Price: cost, Coins: wallet.Coins}, nil
+}
+
+// Quote returns what quantity units of an item cost in soft currency.
+func (s *Service) Quote(shopID, itemID string, quantity int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, err := s.lookup(shopID, itemID, quantity)
+	if err != nil {
+		return 0, err
+	}
+	return item.BuyPrice * int64(quantity), nil
+}
+
+// Sell buys quantity units of an item back from playerID.
+func (s *Service) Sell(playerID, shopID, itemID string, quantity int) (Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, err := s.lookup(shopID, itemID, quantity)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if item.SellPrice == 0 {
+		return Receipt{}, ErrNotBuying
+	}
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if wallet.Inventory[itemID] < quantity {
+		return Receipt{}, ErrNotEnoughItems
+	}
+	wallet.Inventory[itemID] -= quantity
+	if wallet.Inventory[itemID] == 0 {
+		delete(wallet.Inventory, itemID)
+	}
+	proceeds := item.SellPrice * int64(quantity)
+	wallet.Coins += proceeds
+	if err := s.wallets.SaveWallet(playerID, wallet); err != nil {
+		return Receipt{}, err
+	}
+	utils.LogInfof("Shop: %s sold %d x %s to %s for %d coins.", playerID, quantity, itemID, shopID, proceeds)
+	return Receipt{ShopID: shopID, ItemID: itemID, Quantity: quantity, Price: proceeds, Coins: wallet.Coins}, nil
+}
+
+// BuyPremium grants one premium item for the on-chain payment txDigest, which
+// must be sent from the player's Sui address. The stock and the player's limit
+// are reserved while the payment is verified, and the item is queued for
+// minting to address once it is. It makes RPC calls, so callers should not run
+// it on an actor's goroutine.
+func (s *Service) BuyPremium(playerID, address, shopID, itemID, txDigest string) (Receipt, error) {
+	if s.verifier == nil || s.mints == nil {
+		return Receipt{}, ErrPremiumDisabled
+	}
+	s.mu.Lock()
+	item, err := s.lookup(shopID, itemID, 1)
+	if err == nil && item.Premium == nil {
+		err = ErrNotPremium
+	}
+	if err == nil && (txDigest == "" || s.pending[txDigest] || s.state.Payments[txDigest] != "") {
+		err = ErrPaymentUsed
+	}
+	now := s.now()
+	if err == nil {
+		err = s.checkAvailable(playerID, shopID, item, 1, now)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Receipt{}, err
+	}
+	s.take(playerID, shopID, item, 1, now)
+	s.pending[txDigest] = true
+	s.mu.Unlock()
+
+	verifyErr := s.verifier.VerifyPayment(txDigest, address, s.recipient, item.Premium.CoinType, item.Premium.Price)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, txDigest)
+	if verifyErr != nil {
+		s.giveBack(playerID, shopID, item)
+		return Receipt{}, fmt.Errorf("%w: %v", ErrPaymentInvalid, verifyErr)
+	}
+	purchase := PremiumPurchase{PlayerID: playerID, Address: address, ShopID: shopID, ItemID: itemID, Name: item.Name, TxDigest: txDigest}
+	if _, err := s.mints.Enqueue("shop:premium:"+txDigest, PremiumMintKind, purchase); err != nil {
+		s.giveBack(playerID, shopID, item)
+		return Receipt{}, fmt.Errorf("could not queue the item for minting: %w", err)
+	}
+	s.state.Payments[txDigest] = playerID
+	s.save()
+	utils.LogInfof("Shop: %s bought premium item %s from %s (tx %s). Queued for minting.", playerID, itemID, shopID, txDigest)
+	return Receipt{ShopID: shopID, ItemID: itemID, Quantity: 1, Premium: true}, nil
+}
+
+func (s *Service) lookup(shopID, itemID string, quantity int) (Item, error) {
+	shop, ok := s.catalog.shop(shopID)
+	if !ok {
+		return Item{}, ErrUnknownShop
+	}
+	item, ok := shop.item(itemID)
+	if !ok {
+		return Item{}, ErrUnknownItem
+	}
+	if quantity < 1 {
+		return Item{}, ErrInvalidQuantity
+	}
+	return item, nil
+}
+
+// checkAvailable reports whether playerID may buy quantity units now.
+func (s *Service) checkAvailable(playerID, shopID string, item Item, quantity int, now time.Time) error {
+	if level, limited := s.stock(shopID, item, now); limited && level.Remaining < quantity {
+		return ErrOutOfStock
+	}
+	if item.PlayerLimit > 0 && s.purchases(playerID, shopID, item, now).Count+quantity > item.PlayerLimit {
+		return ErrLimitReached
+	}
+	return nil
+}
+
+// take removes quantity units from stock and counts them against the player's limit.
+func (s *Service) take(playerID, shopID string, item Item, quantity int, now time.Time) {
+	if level, limited := s.stock(shopID, item, now); limited {
+		if level.Remaining == item.Stock {
+			level.RestockedAt = now // The restock timer starts with the first sale from full stock
+		}
+		level.Remaining -= quantity
+		s.state.Stock[stockKey(shopID, item.ItemID)] = level
+	}
+	if item.PlayerLimit > 0 {
+		count := s.purchases(playerID, shopID, item, now)
+		if count.Count == 0 {
+			count.WindowStart = now
+		}
+		count.Count += quantity
+		s.state.Purchases[purchaseKey(playerID, shopID, item.ItemID)] = count
+	}
+}
+
+// giveBack undoes take for one unit.
+func (s *Service) giveBack(playerID, shopID string, item Item) {
+	if level, ok := s.state.Stock[stockKey(shopID, item.ItemID)]; ok && level.Remaining < item.Stock {
+		level.Remaining++
+		s.state.Stock[stockKey(shopID, item.ItemID)] = level
+	}
+	key := purchaseKey(playerID, shopID, item.ItemID)
+	if count, ok := s.state.Purchases[key]; ok && count.Count > 0 {
+		count.Count--
+		s.state.Purchases[key] = count
+	}
+}
+
+// stock returns an item's current stock level, applying any restocks that are
+// due. It reports false for items with unlimited stock.
+func (s *Service) stock(shopID string, item Item, now time.Time) (StockLevel, bool) {
+	if item.Stock == 0 {
+		return StockLevel{}, false
+	}
+	level, ok := s.state.Stock[stockKey(shopID, item.ItemID)]
+	if !ok || level.Remaining > item.Stock {
+		return StockLevel{Remaining: item.Stock, RestockedAt: now}, true
+	}
+	interval := item.restockInterval()
+	if interval > 0 && level.Remaining < item.Stock {
+		if periods := int(now.Sub(level.RestockedAt) / interval); periods > 0 {
+			level.Remaining += periods * item.RestockAmount
+			if level.Remaining > item.Stock {
+				level.Remaining = item.Stock
+			}
+			level.RestockedAt = level.RestockedAt.Add(time.Duration(periods) * interval)
+		}
+	}
+	return level, true
+}
+
+// purchases returns the player's count for the current limit window.
+func (s *Service) purchases(playerID, shopID string, item Item, now time.Time) PurchaseCount {
+	count := s.state.Purchases[purchaseKey(playerID, shopID, item.ItemID)]
+	if window := item.limitWindow(); window > 0 && count.Count > 0 && !now.Before(count.WindowStart.Add(window)) {
+		return PurchaseCount{}
+	}
+	return count
+}
+
+func (s *Service) save() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Shop: Failed to save shop state: %v", err)
+	}
+}
+
+func stockKey(shopID, itemID string) string { return shopID + "/" + itemID }
+
+func purchaseKey(playerID, shopID, itemID string) string {
+	return playerID + "/" + shopID + "/" + itemID
+}
@@ -0,0 +1,120 @@
+package shop
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+)
+
+type fakeVerifier struct{ err error }
+
+func (f fakeVerifier) VerifyPayment(txDigest, payer, recipient, coinType string, amount uint64) error {
+	return f.err
+}
+
+func newTestService(t *testing.T, coins int64) (*Service, *time.Time) {
+	t.Helper()
+	catalog := &Catalog{Shops: []Shop{{
+		ID: "smith",
+		Items: []Item{
+			{ItemID: "sword", BuyPrice: 100, SellPrice: 40, Stock: 2, RestockSeconds: 60, RestockAmount: 1},
+			{ItemID: "potion", BuyPrice: 10, PlayerLimit: 3, LimitWindowSeconds: 3600},
+			{ItemID: "crown", Stock: 1, Premium: &Premium{Price: 1000}},
+		},
+	}}}
+	if err := catalog.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(catalog, &MemoryWallets{StartingCoins: coins}, &MemoryStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestBuyChargesCoinsAndRestocks(t *testing.T) {
+	s, now := newTestService(t, 1000)
+	for i := 0; i < 2; i++ {
+		if _, err := s.Buy("p1", "smith", "sword", 1); err != nil {
+			t.Fatalf("buy %d: %v", i, err)
+		}
+	}
+	if _, err := s.Buy("p1", "smith", "sword", 1); !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("third buy err = %v, want ErrOutOfStock", err)
+	}
+	*now = now.Add(61 * time.Second)
+	receipt, err := s.Buy("p1", "smith", "sword", 1)
+	if err != nil {
+		t.Fatalf("buy after restock: %v", err)
+	}
+	if receipt.Coins != 700 {
+		t.Fatalf("coins = %d, want 700", receipt.Coins)
+	}
+	view, _ := s.Browse("p1", "smith")
+	if sword := view.Items[0]; sword.Remaining != 0 || sword.NextRestock.IsZero() {
+		t.Fatalf("sword view = %+v, want empty with a restock time", sword)
+	}
+}
+
+func TestPlayerLimitResetsAfterWindow(t *testing.T) {
+	s, now := newTestService(t, 1000)
+	if _, err := s.Buy("p1", "smith", "potion", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Buy("p1", "smith", "potion", 1); !errors.Is(err, ErrLimitReached) {
+		t.Fatalf("err = %v, want ErrLimitReached", err)
+	}
+	if _, err := s.Buy("p2", "smith", "potion", 1); err != nil {
+		t.Fatalf("other player: %v", err)
+	}
+	*now = now.Add(time.Hour)
+	if _, err := s.Buy("p1", "smith", "potion", 1); err != nil {
+		t.Fatalf("after window: %v", err)
+	}
+}
+
+func TestSellAndInsufficientFunds(t *testing.T) {
+	s, _ := newTestService(t, 150)
+	if _, err := s.Sell("p1", "smith", "sword", 1); !errors.Is(err, ErrNotEnoughItems) {
+		t.Fatalf("sell err = %v, want ErrNotEnoughItems", err)
+	}
+	if _, err := s.Buy("p1", "smith", "sword", 2); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("buy err = %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := s.Buy("p1", "smith", "sword", 1); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := s.Sell("p1", "smith", "sword", 1)
+	if err != nil || receipt.Coins != 90 {
+		t.Fatalf("sell = %+v, %v; want 90 coins", receipt, err)
+	}
+	if _, err := s.Sell("p1", "smith", "potion", 1); !errors.Is(err, ErrNotBuying) {
+		t.Fatalf("err = %v, want ErrNotBuying", err)
+	}
+}
+
+func TestPremiumPurchaseQueuesMintOncePerPayment(t *testing.T) {
+	s, _ := newTestService(t, 0)
+	if _, err := s.Buy("p1", "smith", "crown", 1); !errors.Is(err, ErrPremiumItem) {
+		t.Fatalf("coin buy err = %v, want ErrPremiumItem", err)
+	}
+	mints := outbox.New(outbox.NewMemoryStore(), outbox.Options{})
+	s.EnablePremium(fakeVerifier{err: errors.New("no transfer")}, mints, "0xtreasury")
+	if _, err := s.BuyPremium("p1", "0x1", "smith", "crown", "tx1"); !errors.Is(err, ErrPaymentInvalid) {
+		t.Fatalf("err = %v, want ErrPaymentInvalid", err)
+	}
+	s.verifier = fakeVerifier{}
+	if _, err := s.BuyPremium("p1", "0x1", "smith", "crown", "tx2"); err != nil {
+		t.Fatalf("premium buy after failed verification: %v", err)
+	}
+	if _, err := s.BuyPremium("p2", "0x2", "smith", "crown", "tx2"); !errors.Is(err, ErrPaymentUsed) {
+		t.Fatalf("reused payment err = %v, want ErrPaymentUsed", err)
+	}
+	if stats := mints.Stats(); stats.Pending != 1 {
+		t.Fatalf("outbox stats = %+v, want 1 pending mint", stats)
+	}
+}
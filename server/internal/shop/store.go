@@ -0,0 +1,88 @@
+package shop
+
+import (
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/jsonstore"
+)
+
+// Wallet is a player's soft currency and item inventory.
+type Wallet struct {
+	Coins     int64
+	Inventory map[string]int // ItemID -> quantity
+}
+
+// WalletStore persists wallets. game.DBCacheLayer implements it on top of PlayerData.
+type WalletStore interface {
+	LoadWallet(playerID string) (Wallet, error)
+	SaveWallet(playerID string, wallet Wallet) error
+}
+
+// MemoryWallets keeps wallets in memory, for servers without a database.
+// New players start with StartingCoins.
+type MemoryWallets struct {
+	StartingCoins int64
+
+	mu      sync.Mutex
+	wallets map[string]Wallet
+}
+
+// LoadWallet implements WalletStore.
+func (m *MemoryWallets) LoadWallet(playerID string) (Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wallet, ok := m.wallets[playerID]
+	if !ok {
+		return Wallet{Coins: m.StartingCoins, Inventory: make(map[string]int)}, nil
+	}
+	return copyWallet(wallet), nil
+}
+
+// SaveWallet implements WalletStore.
+func (m *MemoryWallets) SaveWallet(playerID string, wallet Wallet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.wallets == nil {
+		m.wallets = make(map[string]Wallet)
+	}
+	m.wallets[playerID] = copyWallet(wallet)
+	return nil
+}
+
+func copyWallet(wallet Wallet) Wallet {
+	inventory := make(map[string]int, len(wallet.Inventory))
+	for id, qty := range wallet.Inventory {
+		inventory[id] = qty
+	}
+	return Wallet{Coins: wallet.Coins, Inventory: inventory}
+}
+
+// StockLevel is the remaining stock of a limited item.
+type StockLevel struct {
+	Remaining   int       `json:"remaining"`
+	RestockedAt time.Time `json:"restockedAt"` // Start of the current restock period
+}
+
+// PurchaseCount is how many units of an item a player bought in the current limit window.
+type PurchaseCount struct {
+	Count       int       `json:"count"`
+	WindowStart time.Time `json:"windowStart"`
+}
+
+// State is the persisted shop state. Stock is keyed by "shopID/itemID",
+// purchases by "playerID/shopID/itemID".
+type State struct {
+	Stock     map[string]StockLevel    `json:"stock"`
+	Purchases map[string]PurchaseCount `json:"purchases"`
+	Payments  map[string]string        `json:"payments"` // Premium payment tx digest -> player ID; a digest buys one item
+}
+
+// Store persists the shop state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the shop state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// FileStore keeps the shop state in a JSON file.
+type FileStore = jsonstore.File[State]
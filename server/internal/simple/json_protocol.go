@@ -0,0 +1,261 @@
+package simple
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// frameFormat is how messages are delimited on a connection.
+type frameFormat int
+
+const (
+	// frameNewline terminates every message with '\n' (text mode and line-based JSON clients).
+	frameNewline frameFormat = iota
+	// frameLengthPrefixed prefixes every message with a 4-byte big-endian length, like the actor server.
+	frameLengthPrefixed
+)
+
+const (
+	// maxJSONMessageSize mirrors network.MaxMessageSize of the actor-based server.
+	maxJSONMessageSize = 1 * 1024 * 1024
+	// framingDetectTimeout is how long a JSON client has to send its first bytes
+	// before the server assumes length-prefixed framing and sends the welcome.
+	framingDetectTimeout = 2 * time.Second
+)
+
+// detectFraming peeks at the first byte sent by a JSON client. A length prefix
+// for any message under 16MB starts with a zero byte, while a line-based JSON
+// client starts with '{' or whitespace. Clients that stay silent get the actor
+// server's length-prefixed framing.
+func detectFraming(conn net.Conn, reader *bufio.Reader) frameFormat {
+	conn.SetReadDeadline(time.Now().Add(framingDetectTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	first, err := reader.Peek(1)
+	if err != nil || first[0] == 0 {
+		return frameLengthPrefixed
+	}
+	return frameNewline
+}
+
+// writeFrame writes a single message using the client's framing.
+func (c *Client) writeFrame(message string) error {
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if c.framing == frameLengthPrefixed {
+		buffer := make([]byte, 4+len(message))
+		binary.BigEndian.PutUint32(buffer[0:4], uint32(len(message)))
+		copy(buffer[4:], message)
+		_, err := c.conn.Write(buffer)
+		return err
+	}
+	_, err := c.conn.Write([]byte(message + "\n"))
+	return err
+}
+
+// readFrame reads the next JSON message from the client, skipping blank lines.
+// The returned slice is only valid until the next call.
+func (c *Client) readFrame() ([]byte, error) {
+	if c.framing == frameLengthPrefixed {
+		lenBuf := make([]byte, 4)
+		if _, err := io.ReadFull(c.reader, lenBuf); err != nil {
+			return nil, err
+		}
+		messageLength := binary.BigEndian.Uint32(lenBuf)
+		if messageLength > maxJSONMessageSize {
+			return nil, fmt.Errorf("message length %d exceeds maximum %d", messageLength, maxJSONMessageSize)
+		}
+		payload := make([]byte, messageLength)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+
+	if c.lines == nil {
+		// A Scanner stops at maxJSONMessageSize, where ReadBytes would keep
+		// buffering a line that never ends.
+		c.lines = bufio.NewScanner(c.reader)
+		c.lines.Buffer(make([]byte, 0, 4096), maxJSONMessageSize+1)
+	}
+	for c.lines.Scan() {
+		if line := bytes.TrimSpace(c.lines.Bytes()); len(line) > 0 {
+			return line, nil
+		}
+	}
+	if err := c.lines.Err(); err == bufio.ErrTooLong {
+		return nil, fmt.Errorf("message exceeds maximum %d", maxJSONMessageSize)
+	} else if err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// readJSONFrames is the readPump loop for JSON protocol mode.
+func (c *Client) readJSONFrames() {
+	for {
+		payload, err := c.readFrame()
+		if err != nil {
+			log.Printf("Error reading from client %s: %v", c.id, err)
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		if len(payload) == 0 {
+			continue
+		}
+		c.handleJSONMessage(payload)
+	}
+}
+
+// sendJSON wraps a payload in a ClientServerMessage and queues it for the client.
+func (c *Client) sendJSON(msgType string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling %s for client %s: %v", msgType, c.id, err)
+		return
+	}
+	c.send(string(protocol.AppendEnvelope(nil, msgType, body, 0)))
+}
+
+// sendJSONError sends an ERROR message with the same codes the actor server uses.
+func (c *Client) sendJSONError(code, message string) {
+	c.sendJSON(protocol.MsgTypeError, protocol.ErrorResponsePayload{Code: code, Message: message})
+}
+
+// notify sends an informational text to the client in its protocol's format.
+func (c *Client) notify(message string) {
+	if c.server.protocol == ProtocolJSON {
+		c.sendJSON(protocol.MsgTypeSimpleMessage, protocol.SimpleMessagePayload{Message: message})
+		return
+	}
+	c.send(message)
+}
+
+// handleJSONMessage processes a ClientServerMessage. It mirrors the behaviour of
+// the actor server's PlayerSessionActor without any SUI or actor dependencies;
+// the AUTH token is used directly as the player name.
+func (c *Client) handleJSONMessage(data []byte) {
+	var msg protocol.ClientServerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Client %s sent invalid JSON: %v", c.id, err)
+		c.sendJSONError("INVALID_JSON", "Message is not valid JSON.")
+		return
+	}
+	log.Printf("Client %s sent message type: %s", c.id, msg.Type)
+
+	switch msg.Type {
+	case protocol.MsgTypeAuthRequest:
+		if c.name != "" {
+			c.sendJSONError("ALREADY_AUTHENTICATED", "You are already authenticated.")
+			return
+		}
+		var authReq protocol.AuthRequestPayload
+		if err := msg.DecodePayload(&authReq); err != nil {
+			c.sendJSONError("INVALID_AUTH_PAYLOAD", "Auth payload is malformed.")
+			return
+		}
+		if authReq.Token == "" {
+			c.sendJSON(protocol.MsgTypeAuthResponse, protocol.AuthResponsePayload{
+				Success: false,
+				Message: "Authentication failed. Token cannot be empty.",
+			})
+			return
+		}
+		c.name = authReq.Token
+		c.sendJSON(protocol.MsgTypeAuthResponse, protocol.AuthResponsePayload{
+			PlayerID: c.name,
+			Success:  true,
+			Message:  "Authentication successful.",
+		})
+
+	case protocol.MsgTypeJoinRoomRequest:
+		if c.name == "" {
+			c.sendJSONError("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		var joinReq protocol.JoinRoomRequestPayload
+		if err := msg.DecodePayload(&joinReq); err != nil {
+			c.sendJSONError("INVALID_JOIN_PAYLOAD", "Join room payload is malformed.")
+			return
+		}
+		if joinReq.Criteria == "" {
+			c.sendJSONError("INVALID_JOIN_CRITERIA", "Join room criteria cannot be empty.")
+			return
+		}
+		c.joinRoom(joinReq.Criteria)
+
+	case protocol.MsgTypeSendChat:
+		if c.name == "" {
+			c.sendJSONError("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		if c.room == nil {
+			c.sendJSONError("NOT_IN_A_ROOM", "You are not in a room. Join a room first.")
+			return
+		}
+		var chatReq protocol.ChatMessagePayload
+		if err := msg.DecodePayload(&chatReq); err != nil {
+			c.sendJSONError("INVALID_CHAT_PAYLOAD", "Chat payload is malformed.")
+			return
+		}
+		if chatReq.Text == "" {
+			c.sendJSONError("EMPTY_CHAT_MESSAGE", "Chat message cannot be empty.")
+			return
+		}
+		// Like the actor server's RoomActor, chat is echoed back to the sender too.
+		c.room.broadcastJSON(protocol.MsgTypeNewChatMessage, protocol.ChatMessagePayload{
+			SenderName: c.name,
+			Text:       chatReq.Text,
+		})
+
+	case protocol.MsgTypePing:
+		var ping protocol.PingPongPayload
+		if msg.Payload != nil {
+			if err := msg.DecodePayload(&ping); err != nil {
+				log.Printf("Client %s sent malformed PING payload: %v", c.id, err)
+			}
+		}
+		if ping.Timestamp == 0 {
+			ping.Timestamp = time.Now().UnixMilli()
+		}
+		c.sendJSON(protocol.MsgTypePong, ping)
+
+	case protocol.MsgTypePlayerAction:
+		if c.name == "" {
+			c.sendJSONError("NOT_AUTHENTICATED", "Please authenticate first.")
+			return
+		}
+		var action protocol.PlayerActionPayload
+		if err := msg.DecodePayload(&action); err != nil || action.ActionType == "" {
+			c.sendJSONError("INVALID_ACTION_PAYLOAD", "Player action payload is malformed.")
+			return
+		}
+		c.sendJSON(protocol.MsgTypePlayerActionResponse, protocol.PlayerActionResponsePayload{
+			ActionType: action.ActionType,
+			Status:     "SUCCESS",
+			Message:    "Action accepted by simple server stub (no game logic applied).",
+			Data:       action.Data,
+		})
+
+	default:
+		c.sendJSONError("UNKNOWN_COMMAND", fmt.Sprintf("Unknown command type: %s", msg.Type))
+	}
+}
+
+// broadcastJSON sends a protocol message to every client in the room, including the sender.
+func (r *Room) broadcastJSON(msgType string, payload interface{}) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, client := range r.clients {
+		client.sendJSON(msgType, payload)
+	}
+}
@@ -0,0 +1,251 @@
+package simple
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// startJSONServer starts a JSON mode server on a free port. It is stopped once
+// the test's clients are gone, since stopping closes the clients' sessions.
+func startJSONServer(t *testing.T) *SimpleServer {
+	t.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	s := NewSimpleServer(0, ProtocolJSON)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			s.mu.RLock()
+			connected := len(s.clients)
+			s.mu.RUnlock()
+			if connected == 0 {
+				break
+			}
+		}
+		s.Stop()
+		log.SetOutput(out)
+	})
+	return s
+}
+
+// testClient speaks the JSON protocol with one of the two framings.
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	framed bool // Length-prefixed rather than newline framing
+}
+
+func dial(t *testing.T, s *SimpleServer, framed bool) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn), framed: framed}
+}
+
+func (c *testClient) send(msgType string, payload interface{}) {
+	c.t.Helper()
+	msg, err := protocol.NewMessage(msgType, payload)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.write(body)
+}
+
+func (c *testClient) write(body []byte) {
+	c.t.Helper()
+	if c.framed {
+		body = append(binary.BigEndian.AppendUint32(nil, uint32(len(body))), body...)
+	} else {
+		body = append(body, '\n')
+	}
+	if _, err := c.conn.Write(body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive returns the next message, skipping keepalive blank lines.
+func (c *testClient) receive() protocol.ClientServerMessage {
+	c.t.Helper()
+	var body []byte
+	if c.framed {
+		var length [4]byte
+		if _, err := io.ReadFull(c.reader, length[:]); err != nil {
+			c.t.Fatal(err)
+		}
+		body = make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(c.reader, body); err != nil {
+			c.t.Fatal(err)
+		}
+	} else {
+		for len(body) == 0 {
+			line, err := c.reader.ReadBytes('\n')
+			if err != nil {
+				c.t.Fatal(err)
+			}
+			body = bytes.TrimSpace(line)
+		}
+	}
+	var msg protocol.ClientServerMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("%q: %v", body, err)
+	}
+	return msg
+}
+
+func (c *testClient) expect(msgType string, payload interface{}) {
+	c.t.Helper()
+	msg := c.receive()
+	if msg.Type != msgType {
+		c.t.Fatalf("got %s %s, want %s", msg.Type, msg.Payload, msgType)
+	}
+	if payload != nil {
+		if err := msg.DecodePayload(payload); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// closed reports whether the server hung up on the client, discarding
+// whatever it sent before.
+func (c *testClient) closed() bool {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := io.Copy(io.Discard, c.reader)
+	return err == nil // io.Copy returns nil at EOF
+}
+
+func TestFramingDetection(t *testing.T) {
+	s := startJSONServer(t)
+	for _, tc := range []struct {
+		name   string
+		framed bool
+		silent bool
+	}{
+		{"newline", false, false},
+		{"length-prefixed", true, false},
+		{"silent", true, true},
+	} {
+		c := dial(t, s, tc.framed)
+		if !tc.silent {
+			c.send(protocol.MsgTypePing, protocol.PingPongPayload{Timestamp: 42})
+		}
+		var welcome protocol.SimpleMessagePayload
+		c.expect(protocol.MsgTypeSimpleMessage, &welcome)
+		if !strings.HasPrefix(welcome.Message, "Welcome!") {
+			t.Errorf("%s: welcome = %q", tc.name, welcome.Message)
+		}
+		if tc.silent {
+			c.send(protocol.MsgTypePing, protocol.PingPongPayload{Timestamp: 42})
+		}
+		var pong protocol.PingPongPayload
+		c.expect(protocol.MsgTypePong, &pong)
+		if pong.Timestamp != 42 {
+			t.Errorf("%s: pong = %+v", tc.name, pong)
+		}
+		c.conn.Close()
+	}
+}
+
+func TestAuthJoinChatAndPing(t *testing.T) {
+	s := startJSONServer(t)
+	alice, bob := dial(t, s, false), dial(t, s, true)
+	for _, c := range []*testClient{alice, bob} {
+		c.send(protocol.MsgTypePing, nil)
+		c.expect(protocol.MsgTypeSimpleMessage, nil)
+		var pong protocol.PingPongPayload
+		if c.expect(protocol.MsgTypePong, &pong); pong.Timestamp == 0 {
+			t.Error("a PING without a timestamp was answered without one")
+		}
+	}
+
+	var failed protocol.ErrorResponsePayload
+	alice.send(protocol.MsgTypeJoinRoomRequest, protocol.JoinRoomRequestPayload{Criteria: "arena"})
+	if alice.expect(protocol.MsgTypeError, &failed); failed.Code != "NOT_AUTHENTICATED" {
+		t.Fatalf("join before auth = %+v", failed)
+	}
+	var auth protocol.AuthResponsePayload
+	alice.send(protocol.MsgTypeAuthRequest, protocol.AuthRequestPayload{Token: ""})
+	if alice.expect(protocol.MsgTypeAuthResponse, &auth); auth.Success {
+		t.Fatalf("empty token = %+v", auth)
+	}
+	alice.send(protocol.MsgTypeAuthRequest, protocol.AuthRequestPayload{Token: "alice"})
+	if alice.expect(protocol.MsgTypeAuthResponse, &auth); !auth.Success || auth.PlayerID != "alice" {
+		t.Fatalf("auth = %+v", auth)
+	}
+	alice.send(protocol.MsgTypeSendChat, protocol.ChatMessagePayload{Text: "hi"})
+	if alice.expect(protocol.MsgTypeError, &failed); failed.Code != "NOT_IN_A_ROOM" {
+		t.Fatalf("chat outside a room = %+v", failed)
+	}
+	bob.send(protocol.MsgTypeAuthRequest, protocol.AuthRequestPayload{Token: "bob"})
+	bob.expect(protocol.MsgTypeAuthResponse, nil)
+
+	var joined protocol.JoinRoomResponsePayload
+	alice.send(protocol.MsgTypeJoinRoomRequest, protocol.JoinRoomRequestPayload{Criteria: "arena"})
+	if alice.expect(protocol.MsgTypeJoinRoomResponse, &joined); !joined.Success || joined.RoomID != "arena" {
+		t.Fatalf("alice joined %+v", joined)
+	}
+	bob.send(protocol.MsgTypeJoinRoomRequest, protocol.JoinRoomRequestPayload{Criteria: "arena"})
+	bob.expect(protocol.MsgTypeJoinRoomResponse, nil)
+	var notice protocol.SimpleMessagePayload
+	if alice.expect(protocol.MsgTypeSimpleMessage, &notice); notice.Message != "bob joined the room" {
+		t.Fatalf("alice was told %q", notice.Message)
+	}
+
+	// Chat reaches the whole room, the sender included.
+	bob.send(protocol.MsgTypeSendChat, protocol.ChatMessagePayload{Text: "hello"})
+	for _, c := range []*testClient{alice, bob} {
+		var chat protocol.ChatMessagePayload
+		if c.expect(protocol.MsgTypeNewChatMessage, &chat); chat.SenderName != "bob" || chat.Text != "hello" {
+			t.Fatalf("chat = %+v", chat)
+		}
+	}
+	alice.conn.Close()
+	bob.conn.Close()
+}
+
+func TestOversizedFramesAreRejected(t *testing.T) {
+	s := startJSONServer(t)
+
+	framed := dial(t, s, true)
+	if _, err := framed.conn.Write(binary.BigEndian.AppendUint32(nil, maxJSONMessageSize+1)); err != nil {
+		t.Fatal(err)
+	}
+	if !framed.closed() {
+		t.Error("an oversized length prefix did not close the connection")
+	}
+
+	// A line that never ends is cut off at the limit rather than buffered.
+	line := dial(t, s, false)
+	go line.conn.Write(append([]byte("{"), bytes.Repeat([]byte("a"), maxJSONMessageSize+1)...))
+	if !line.closed() {
+		t.Error("an oversized line did not close the connection")
+	}
+
+	// A line just under the limit is still read.
+	fits := dial(t, s, false)
+	ping, _ := json.Marshal(protocol.ClientServerMessage{Type: protocol.MsgTypePing})
+	fits.write(append(ping, bytes.Repeat([]byte(" "), maxJSONMessageSize-len(ping))...))
+	fits.expect(protocol.MsgTypeSimpleMessage, nil)
+	fits.expect(protocol.MsgTypePong, nil)
+	fits.conn.Close()
+}
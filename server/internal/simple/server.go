@@ -9,11 +9,36 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/phuhao00/suigserver/pkg/protocol"
+)
+
+// ProtocolMode selects the wire format spoken by a SimpleServer.
+type ProtocolMode string
+
+const (
+	// ProtocolText is the line-based slash command protocol (/auth, /join, ...).
+	ProtocolText ProtocolMode = "text"
+	// ProtocolJSON accepts the same ClientServerMessage JSON as the actor-based server.
+	ProtocolJSON ProtocolMode = "json"
 )
 
+// ParseProtocolMode validates a protocol mode given on the command line.
+func ParseProtocolMode(mode string) (ProtocolMode, error) {
+	switch ProtocolMode(strings.ToLower(mode)) {
+	case ProtocolText:
+		return ProtocolText, nil
+	case ProtocolJSON:
+		return ProtocolJSON, nil
+	default:
+		return "", fmt.Errorf("unknown protocol mode %q (expected %q or %q)", mode, ProtocolText, ProtocolJSON)
+	}
+}
+
 // SimpleServer is a basic game server without external dependencies
 type SimpleServer struct {
 	port     int
+	protocol ProtocolMode
 	listener net.Listener
 	clients  map[string]*Client
 	rooms    map[string]*Room
@@ -23,13 +48,17 @@ type SimpleServer struct {
 
 // Client represents a connected player
 type Client struct {
-	conn   net.Conn
-	id     string
-	name   string
-	room   *Room
-	server *SimpleServer
-	sendCh chan string
-	quitCh chan struct{}
+	conn    net.Conn
+	reader  *bufio.Reader
+	lines   *bufio.Scanner // Messages of newline framing, read from reader
+	framing frameFormat
+	id      string
+	name    string
+	room    *Room
+	server  *SimpleServer
+	sendCh  chan string
+	quitCh  chan struct{}
+	closing sync.Once // Closes quitCh, which the read and write pumps and Stop may all do
 }
 
 // Room represents a game room
@@ -40,13 +69,17 @@ type Room struct {
 	mu      sync.RWMutex
 }
 
-// NewSimpleServer creates a new simple server
-func NewSimpleServer(port int) *SimpleServer {
+// NewSimpleServer creates a new simple server speaking the given protocol
+func NewSimpleServer(port int, protocol ProtocolMode) *SimpleServer {
+	if protocol == "" {
+		protocol = ProtocolText
+	}
 	return &SimpleServer{
-		port:    port,
-		clients: make(map[string]*Client),
-		rooms:   make(map[string]*Room),
-		quit:    make(chan struct{}),
+		port:     port,
+		protocol: protocol,
+		clients:  make(map[string]*Client),
+		rooms:    make(map[string]*Room),
+		quit:     make(chan struct{}),
 	}
 }
 
@@ -58,7 +91,7 @@ func (s *SimpleServer) Start() error {
 		return fmt.Errorf("failed to start server: %v", err)
 	}
 
-	log.Printf("Simple Game Server started on port %d", s.port)
+	log.Printf("Simple Game Server started on port %d (protocol: %s)", s.port, s.protocol)
 
 	// Create a default room
 	defaultRoom := &Room{
@@ -94,12 +127,18 @@ func (s *SimpleServer) handleConnection(conn net.Conn) {
 	clientID := fmt.Sprintf("client_%d", time.Now().UnixNano())
 	client := &Client{
 		conn:   conn,
+		reader: bufio.NewReader(conn),
 		id:     clientID,
 		server: s,
 		sendCh: make(chan string, 10),
 		quitCh: make(chan struct{}),
 	}
 
+	if s.protocol == ProtocolJSON {
+		// Framing must be known before the pumps start, as both of them depend on it.
+		client.framing = detectFraming(conn, client.reader)
+	}
+
 	s.mu.Lock()
 	s.clients[clientID] = client
 	s.mu.Unlock()
@@ -111,8 +150,12 @@ func (s *SimpleServer) handleConnection(conn net.Conn) {
 	go client.readPump()
 
 	// Send welcome message
-	client.send("Welcome to the Simple Game Server!")
-	client.send("Commands: /auth <name>, /join <room>, /say <message>, /quit")
+	if s.protocol == ProtocolJSON {
+		client.notify("Welcome! Please authenticate. Send JSON: {\"type\":\"AUTH\",\"payload\":{\"token\":\"your_name\"}}")
+	} else {
+		client.send("Welcome to the Simple Game Server!")
+		client.send("Commands: /auth <name>, /join <room>, /say <message>, /quit")
+	}
 
 	// Wait for client to disconnect
 	<-client.quitCh
@@ -140,6 +183,11 @@ func (c *Client) send(message string) {
 	}
 }
 
+// quit ends the client's session. It may be called more than once.
+func (c *Client) quit() {
+	c.closing.Do(func() { close(c.quitCh) })
+}
+
 // writePump sends messages to the client
 func (c *Client) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
@@ -148,16 +196,18 @@ func (c *Client) writePump() {
 	for {
 		select {
 		case message := <-c.sendCh:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if _, err := c.conn.Write([]byte(message + "\n")); err != nil {
+			if err := c.writeFrame(message); err != nil {
 				log.Printf("Error writing to client %s: %v", c.id, err)
-				close(c.quitCh)
+				c.quit()
 				return
 			}
 		case <-ticker.C:
+			if c.framing == frameLengthPrefixed {
+				continue // Blank-line keepalives are only meaningful for line-based framing
+			}
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if _, err := c.conn.Write([]byte("\n")); err != nil {
-				close(c.quitCh)
+				c.quit()
 				return
 			}
 		case <-c.quitCh:
@@ -168,13 +218,16 @@ func (c *Client) writePump() {
 
 // readPump reads messages from the client
 func (c *Client) readPump() {
-	defer close(c.quitCh)
+	defer c.quit()
 
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	reader := bufio.NewReader(c.conn)
+	if c.server.protocol == ProtocolJSON {
+		c.readJSONFrames()
+		return
+	}
 
 	for {
-		line, err := reader.ReadString('\n')
+		line, err := c.reader.ReadString('\n')
 		if err != nil {
 			log.Printf("Error reading from client %s: %v", c.id, err)
 			return
@@ -239,7 +292,7 @@ func (c *Client) handleMessage(message string) {
 
 	case "/quit":
 		c.send("Goodbye!")
-		close(c.quitCh)
+		c.quit()
 
 	default:
 		c.send("Unknown command. Available commands: /auth, /join, /say, /quit")
@@ -272,7 +325,15 @@ func (c *Client) joinRoom(roomID string) {
 	room.mu.Unlock()
 
 	c.room = room
-	c.send(fmt.Sprintf("Joined room: %s", roomID))
+	if c.server.protocol == ProtocolJSON {
+		c.sendJSON(protocol.MsgTypeJoinRoomResponse, protocol.JoinRoomResponsePayload{
+			Success: true,
+			RoomID:  roomID,
+			Message: "Successfully joined room: " + roomID,
+		})
+	} else {
+		c.send(fmt.Sprintf("Joined room: %s", roomID))
+	}
 	room.broadcast(c, fmt.Sprintf("%s joined the room", c.name))
 }
 
@@ -300,14 +361,14 @@ func (c *Client) leaveRoom() {
 	c.room = nil
 }
 
-// broadcast sends a message to all clients in the room
+// broadcast sends a text notice to all clients in the room
 func (r *Room) broadcast(sender *Client, message string) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	for _, client := range r.clients {
 		if client != sender {
-			client.send(message)
+			client.notify(message)
 		}
 	}
 }
@@ -321,7 +382,7 @@ func (s *SimpleServer) Stop() {
 
 	s.mu.Lock()
 	for _, client := range s.clients {
-		close(client.quitCh)
+		client.quit()
 	}
 	s.mu.Unlock()
 
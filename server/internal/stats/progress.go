@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+// ErrNoProgress is returned by AddXP when the Service keeps no experience.
+var ErrNoProgress = errors.New("experience is not kept")
+
+// Progress is a player's total experience and the level it reached.
+type Progress struct {
+	XP    int
+	Level int
+}
+
+// ProgressStore persists the players' progress. game.PlayerProgress
+// implements it on top of PlayerData.
+type ProgressStore interface {
+	LoadProgress(playerID string) (Progress, error) // Level 1 and no XP for new players
+	SaveProgress(playerID string, progress Progress) error
+}
+
+// MemoryProgress keeps progress in memory, for servers without a database.
+type MemoryProgress struct {
+	mu       sync.Mutex
+	progress map[string]Progress
+}
+
+// LoadProgress implements ProgressStore.
+func (m *MemoryProgress) LoadProgress(playerID string) (Progress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	progress, ok := m.progress[playerID]
+	if !ok {
+		return Progress{Level: 1}, nil
+	}
+	return progress, nil
+}
+
+// SaveProgress implements ProgressStore.
+func (m *MemoryProgress) SaveProgress(playerID string, progress Progress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.progress == nil {
+		m.progress = make(map[string]Progress)
+	}
+	m.progress[playerID] = progress
+	return nil
+}
+
+// UseProgress makes AddXP credit experience kept in store. curve returns
+// the current XP tunables, usually from the balance service.
+func (s *Service) UseProgress(store ProgressStore, curve func() balance.XPValues) {
+	s.progress, s.curve = store, curve
+}
+
+// AddXP credits xp to playerID, works out the level they reached and
+// reports both.
+func (s *Service) AddXP(playerID string, xp int) (Progress, error) {
+	if s == nil || s.progress == nil {
+		return Progress{}, ErrNoProgress
+	}
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	progress, err := s.progress.LoadProgress(playerID)
+	if err != nil {
+		return Progress{}, err
+	}
+	progress.XP += xp
+	progress.Level = s.curve().LevelForXP(progress.XP)
+	if err := s.progress.SaveProgress(playerID, progress); err != nil {
+		return Progress{}, err
+	}
+	s.Set(playerID, XP, int64(progress.XP))
+	s.Set(playerID, Level, int64(progress.Level))
+	return progress, nil
+}
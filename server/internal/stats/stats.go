@@ -0,0 +1,198 @@
+// Package stats pushes changes of the players' authoritative stats, such as
+// health, level, XP and coins, to their sessions. Whatever changes a stat
+// reports the new value with Set; changes are collected and sent once per
+// tick as a compact Delta, so a burst of hits or purchases costs one message.
+// Health is also shown to the player's party, such as the members of their
+// room.
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+)
+
+// Stats reported in a Delta.
+const (
+	HP    = "hp"
+	Level = "level"
+	XP    = "xp"
+	Coins = "coins"
+)
+
+// DefaultTickInterval is how often changes are pushed when
+// Options.TickInterval is zero.
+const DefaultTickInterval = 100 * time.Millisecond
+
+// Change is the new values of one player's stats.
+type Change struct {
+	PlayerID string
+	Stats    map[string]int64
+}
+
+// Delta tells a connected session the stats that changed since the last
+// tick, its player's own and their party's.
+type Delta struct {
+	Changes []Change
+}
+
+// Notifier delivers a *Delta to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	TickInterval time.Duration // How often collected changes are pushed
+}
+
+// online is a connected player and the changes waiting for their next tick.
+type online struct {
+	notify  Notifier
+	pending map[string]map[string]int64 // Player ID -> stat -> value
+	order   []string                    // Player IDs in pending, in the order they changed
+	sent    map[string]int64            // Own stats as last pushed
+}
+
+// Service collects stat changes and pushes them to online players. It is
+// safe for concurrent use; a nil Service ignores changes.
+type Service struct {
+	opts Options
+
+	progressMu sync.Mutex // Serializes AddXP's load and save
+	progress   ProgressStore
+	curve      func() balance.XPValues
+
+	mu      sync.Mutex
+	players map[string]*online
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates a Service.
+func NewService(opts Options) *Service {
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = DefaultTickInterval
+	}
+	return &Service{opts: opts, players: make(map[string]*online)}
+}
+
+// Connect registers the session notifier of a player who came online.
+func (s *Service) Connect(playerID string, notify Notifier) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players[playerID] = &online{notify: notify, sent: make(map[string]int64)}
+}
+
+// Disconnect forgets a player who went offline, with their pending changes.
+func (s *Service) Disconnect(playerID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.players, playerID)
+}
+
+// Set records that playerID's stat is now value. The change goes to
+// playerID and, for HP, to party at the next tick; offline players miss it
+// and see their stats when they next ask.
+func (s *Service) Set(playerID, stat string, value int64, party ...string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue(playerID, playerID, stat, value)
+	if stat != HP {
+		return
+	}
+	for _, memberID := range party {
+		if memberID != playerID {
+			s.queue(memberID, playerID, stat, value)
+		}
+	}
+}
+
+// queue adds a change of subjectID's stat to recipientID's next Delta. A
+// later change of the same stat replaces it. Callers hold s.mu.
+func (s *Service) queue(recipientID, subjectID, stat string, value int64) {
+	p, ok := s.players[recipientID]
+	if !ok {
+		return
+	}
+	if p.pending == nil {
+		p.pending = make(map[string]map[string]int64)
+	}
+	changed, ok := p.pending[subjectID]
+	if !ok {
+		changed = make(map[string]int64)
+		p.pending[subjectID] = changed
+		p.order = append(p.order, subjectID)
+	}
+	changed[stat] = value
+}
+
+// Start pushes the collected changes every tick in the background.
+func (s *Service) Start() {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Tick()
+			}
+		}
+	}()
+}
+
+// Stop stops the background pushes.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// Tick sends every online player with pending changes one Delta. The
+// player's own stats are left out if they are what was last pushed.
+func (s *Service) Tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for playerID, p := range s.players {
+		if len(p.order) == 0 {
+			continue
+		}
+		delta := &Delta{}
+		for _, subjectID := range p.order {
+			changed := p.pending[subjectID]
+			if subjectID == playerID {
+				for stat, value := range changed {
+					if last, ok := p.sent[stat]; ok && last == value {
+						delete(changed, stat)
+					} else {
+						p.sent[stat] = value
+					}
+				}
+			}
+			if len(changed) > 0 {
+				delta.Changes = append(delta.Changes, Change{PlayerID: subjectID, Stats: changed})
+			}
+		}
+		p.pending, p.order = nil, nil
+		if len(delta.Changes) > 0 {
+			p.notify(delta)
+		}
+	}
+}
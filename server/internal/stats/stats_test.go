@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+)
+
+func TestChangesAreBatchedPerTick(t *testing.T) {
+	s := NewService(Options{})
+	sent := map[string][]*Delta{}
+	for _, id := range []string{"alice", "bob"} {
+		id := id
+		s.Connect(id, func(note interface{}) { sent[id] = append(sent[id], note.(*Delta)) })
+	}
+
+	s.Set("alice", HP, 90, "alice", "bob", "carol")
+	s.Set("alice", HP, 70, "alice", "bob", "carol")
+	s.Set("alice", Coins, 500, "bob")
+	s.Tick()
+	want := []Change{{PlayerID: "alice", Stats: map[string]int64{HP: 70, Coins: 500}}}
+	if len(sent["alice"]) != 1 || !reflect.DeepEqual(sent["alice"][0].Changes, want) {
+		t.Fatalf("alice got %+v", sent["alice"])
+	}
+	want = []Change{{PlayerID: "alice", Stats: map[string]int64{HP: 70}}}
+	if len(sent["bob"]) != 1 || !reflect.DeepEqual(sent["bob"][0].Changes, want) {
+		t.Fatalf("bob got %+v, want only alice's health", sent["bob"])
+	}
+
+	// Nothing changed, or only back to what was last pushed: no message.
+	s.Set("alice", Coins, 500)
+	s.Tick()
+	s.Tick()
+	if len(sent["alice"]) != 1 {
+		t.Errorf("alice got %+v after setting the same coins", sent["alice"][1:])
+	}
+
+	s.Disconnect("bob")
+	s.Set("alice", HP, 60, "bob")
+	s.Tick()
+	if len(sent["bob"]) != 1 {
+		t.Errorf("bob got %+v after disconnecting", sent["bob"][1:])
+	}
+}
+
+func TestXPAndCoins(t *testing.T) {
+	s := NewService(Options{})
+	var deltas []*Delta
+	s.Connect("alice", func(note interface{}) { deltas = append(deltas, note.(*Delta)) })
+	if _, err := s.AddXP("alice", 10); err != ErrNoProgress {
+		t.Fatalf("AddXP without progress: %v", err)
+	}
+	s.UseProgress(&MemoryProgress{}, func() balance.XPValues {
+		return balance.XPValues{BaseXP: 100, Growth: 2, MaxLevel: 10}
+	})
+	if _, err := s.AddXP("alice", 80); err != nil {
+		t.Fatal(err)
+	}
+	progress, err := s.AddXP("alice", 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != (Progress{XP: 230, Level: 2}) {
+		t.Errorf("progress = %+v", progress)
+	}
+
+	wallets := Wallets{WalletStore: &shop.MemoryWallets{StartingCoins: 100}, Stats: s}
+	if err := wallets.SaveWallet("alice", shop.Wallet{Coins: 40}); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	want := []Change{{PlayerID: "alice", Stats: map[string]int64{XP: 230, Level: 2, Coins: 40}}}
+	if len(deltas) != 1 || !reflect.DeepEqual(deltas[0].Changes, want) {
+		t.Errorf("deltas = %+v", deltas)
+	}
+}
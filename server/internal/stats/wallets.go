@@ -0,0 +1,19 @@
+package stats
+
+import "github.com/phuhao00/suigserver/server/internal/shop"
+
+// Wallets reports the coins of every wallet saved through the embedded
+// store, whether by the shop, crafting, the battle pass or trade fees.
+type Wallets struct {
+	shop.WalletStore
+	Stats *Service
+}
+
+// SaveWallet implements shop.WalletStore.
+func (w Wallets) SaveWallet(playerID string, wallet shop.Wallet) error {
+	if err := w.WalletStore.SaveWallet(playerID, wallet); err != nil {
+		return err
+	}
+	w.Stats.Set(playerID, Coins, wallet.Coins)
+	return nil
+}
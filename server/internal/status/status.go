@@ -0,0 +1,185 @@
+// Package status serves the public /status endpoint that game launchers and the
+// website poll: the server's name, version, region, population, queue and
+// maintenance windows. The response holds nothing private and is built at
+// most once per cache period, so it can sit behind a CDN.
+package status
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DefaultMaxAge is how long a report is cached when Options leaves it unset.
+const DefaultMaxAge = 15 * time.Second
+
+// States.
+const (
+	StateOnline      = "online"
+	StateMaintenance = "maintenance" // A maintenance window is in progress
+)
+
+// Window is a scheduled maintenance window.
+type Window struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Message string    `json:"message,omitempty"`
+}
+
+// World is one game world in the report.
+type World struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Region  string `json:"region,omitempty"`
+	Players int    `json:"players"`
+}
+
+// Report is the JSON body of /status.
+type Report struct {
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	Region        string    `json:"region,omitempty"`
+	State         string    `json:"state"` // StateOnline or StateMaintenance
+	Players       int       `json:"players"`
+	Queue         int       `json:"queue"` // Players waiting for a match
+	StartedAt     time.Time `json:"startedAt"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	Worlds        []World   `json:"worlds,omitempty"`
+	Maintenance   []Window  `json:"maintenance,omitempty"` // The current window, if any, and upcoming ones, soonest first
+	GeneratedAt   time.Time `json:"generatedAt"`
+}
+
+// Options configures a Service. The counting functions are optional.
+type Options struct {
+	Name        string
+	Version     string
+	Region      string
+	MaxAge      time.Duration // Cache period for the service and for HTTP caches
+	Maintenance []Window
+	Players     func() int     // Online players
+	Queue       func() int     // Players waiting for a match
+	Worlds      func() []World // Per-world population
+}
+
+// Service builds status reports. It is safe for concurrent use.
+type Service struct {
+	opts      Options
+	startedAt time.Time
+	now       func() time.Time
+
+	mu     sync.Mutex
+	report Report
+	body   []byte
+	etag   string
+}
+
+// New creates a Service for a server starting now.
+func New(opts Options) *Service {
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	return &Service{opts: opts, startedAt: time.Now(), now: time.Now}
+}
+
+// Report returns the current report, rebuilding it if the cached one is older
+// than the cache period.
+func (s *Service) Report() Report {
+	report, _, _ := s.current()
+	return report
+}
+
+func (s *Service) current() (Report, []byte, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.body != nil && now.Sub(s.report.GeneratedAt) < s.opts.MaxAge {
+		return s.report, s.body, s.etag
+	}
+	report := s.build(now)
+	body, err := json.Marshal(report)
+	if err != nil {
+		utils.LogErrorf("Status: failed to encode report: %v", err)
+		return report, nil, ""
+	}
+	// The ETag ignores the timestamps, so an unchanged server revalidates.
+	stable := report
+	stable.UptimeSeconds, stable.GeneratedAt = 0, time.Time{}
+	stableBody, _ := json.Marshal(stable)
+	sum := sha256.Sum256(stableBody)
+	s.report, s.body, s.etag = report, body, `"`+hex.EncodeToString(sum[:8])+`"`
+	return s.report, s.body, s.etag
+}
+
+func (s *Service) build(now time.Time) Report {
+	report := Report{
+		Name:          s.opts.Name,
+		Version:       s.opts.Version,
+		Region:        s.opts.Region,
+		State:         StateOnline,
+		StartedAt:     s.startedAt.UTC(),
+		UptimeSeconds: int64(now.Sub(s.startedAt) / time.Second),
+		GeneratedAt:   now.UTC(),
+	}
+	if s.opts.Players != nil {
+		report.Players = s.opts.Players()
+	}
+	if s.opts.Queue != nil {
+		report.Queue = s.opts.Queue()
+	}
+	if s.opts.Worlds != nil {
+		report.Worlds = s.opts.Worlds()
+	}
+	for _, w := range s.opts.Maintenance {
+		if !w.End.After(now) {
+			continue // Over
+		}
+		if !w.Start.After(now) {
+			report.State = StateMaintenance
+		}
+		report.Maintenance = append(report.Maintenance, w)
+	}
+	sort.Slice(report.Maintenance, func(i, j int) bool {
+		return report.Maintenance[i].Start.Before(report.Maintenance[j].Start)
+	})
+	return report
+}
+
+// RegisterHandlers installs GET /status on mux. It needs no credentials and
+// may be called from any origin.
+func (s *Service) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "use GET", http.StatusMethodNotAllowed)
+			return
+		}
+		_, body, etag := s.current()
+		if body == nil {
+			http.Error(w, "status unavailable", http.StatusInternalServerError)
+			return
+		}
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", "*")
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.opts.MaxAge/time.Second)))
+		header.Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		header.Set("Content-Type", "application/json")
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		if _, err := w.Write(body); err != nil {
+			utils.LogDebugf("Status: failed to write report: %v", err)
+		}
+	})
+}
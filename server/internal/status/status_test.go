@@ -0,0 +1,91 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReportShowsMaintenanceWindows(t *testing.T) {
+	start := time.Date(2026, 1, 6, 5, 0, 0, 0, time.UTC)
+	now := start
+	s := New(Options{
+		Name:    "EU",
+		Version: "v1",
+		Maintenance: []Window{
+			{Start: start.Add(48 * time.Hour), End: start.Add(50 * time.Hour), Message: "later"},
+			{Start: start.Add(time.Hour), End: start.Add(3 * time.Hour), Message: "weekly"},
+			{Start: start.Add(-3 * time.Hour), End: start.Add(-time.Hour), Message: "over"},
+		},
+		Players: func() int { return 42 },
+	})
+	s.startedAt, s.now = start, func() time.Time { return now }
+
+	report := s.Report()
+	if report.State != StateOnline || report.Players != 42 || len(report.Maintenance) != 2 || report.Maintenance[0].Message != "weekly" {
+		t.Fatalf("before the window: %+v", report)
+	}
+
+	now = start.Add(90 * time.Minute)
+	report = s.Report()
+	if report.State != StateMaintenance || report.UptimeSeconds != 5400 {
+		t.Fatalf("during the window: %+v", report)
+	}
+}
+
+func TestReportIsCachedForMaxAge(t *testing.T) {
+	players := 1
+	now := time.Now()
+	s := New(Options{MaxAge: 10 * time.Second, Players: func() int { return players }})
+	s.now = func() time.Time { return now }
+
+	s.Report()
+	players = 2
+	now = now.Add(9 * time.Second)
+	if got := s.Report().Players; got != 1 {
+		t.Errorf("players = %d within the cache period, want the cached 1", got)
+	}
+	now = now.Add(time.Second)
+	if got := s.Report().Players; got != 2 {
+		t.Errorf("players = %d after the cache period, want 2", got)
+	}
+}
+
+func TestHandlerIsCacheable(t *testing.T) {
+	now := time.Now()
+	s := New(Options{Name: "EU", MaxAge: 30 * time.Second})
+	s.now = func() time.Time { return now }
+	mux := http.NewServeMux()
+	s.RegisterHandlers(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK || report.Name != "EU" {
+		t.Fatalf("GET /status: %d %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("launcher pages on other origins cannot read the status")
+	}
+
+	// A rebuilt report with only new timestamps keeps its ETag.
+	now = now.Add(time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidating an unchanged status: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /status: %d", rec.Code)
+	}
+}
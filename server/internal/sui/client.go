@@ -7,49 +7,97 @@ import (
 	// "log" // Replaced by utils.LogX
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/block-vision/sui-go-sdk/models"
 	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/audit"
 	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
 	// "github.com/tidwall/gjson" // No longer needed if adaptToGJSON is removed
 )
 
-// SuiClient represents a client for interacting with Sui blockchain using sui-go-sdk
+// SuiClient represents a client for interacting with Sui blockchain using sui-go-sdk.
+// It can be configured with fallback RPC nodes; see StartHealthMonitor.
 type SuiClient struct {
-	sdkClient sui.ISuiAPI
-	nodeURL   string
+	nodeURL string // Primary RPC node URL
+
+	mu          sync.RWMutex
+	endpoints   []*rpcEndpoint // In priority order, primary first
+	active      int            // Index into endpoints of the node serving requests
+	monitorStop chan struct{}
+
+	versions *objectVersionCache // Latest object versions seen; see ExecuteWithRebuild
+	objects  *objectFactsCache   // Owners and types of objects clients supplied, for VerifyObjects
+	audit    *audit.Log          // Records executed transactions; nil records nothing
+	faults   FaultInjector       // Fails calls on purpose in chaos tests; nil fails none
+	signers  *SignerPool         // Signs server transactions when set; see UseSignerPool
 }
 
 // NewSuiClient creates a new Sui client using sui-go-sdk
 func NewSuiClient(nodeURL string) *SuiClient {
+	return NewSuiClientWithFallbacks(nodeURL, nil)
+}
+
+// NewSuiClientWithFallbacks creates a Sui client that fails over from the primary
+// RPC node to the fallback nodes (in order) when the health monitor finds the
+// primary unhealthy. Duplicate and empty fallback URLs are ignored.
+func NewSuiClientWithFallbacks(nodeURL string, fallbackURLs []string) *SuiClient {
 	if nodeURL == "" {
 		nodeURL = "https://fullnode.testnet.sui.io:443" // Default to testnet if not specified
 	}
-	// Initialize the sui-go-sdk client
+	// Initialize the sui-go-sdk clients
 	// The sdk does not require explicit timeout setting in the same way as resty here.
 	// It uses default http client settings which can be customized if needed by creating a custom http.Client.
-	cli := sui.NewSuiClient(nodeURL)
+	endpoints := []*rpcEndpoint{newRPCEndpoint(nodeURL)}
+	seen := map[string]bool{nodeURL: true}
+	for _, url := range fallbackURLs {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		endpoints = append(endpoints, newRPCEndpoint(url))
+	}
 
 	return &SuiClient{
-		sdkClient: cli,
 		nodeURL:   nodeURL,
+		endpoints: endpoints,
+		versions:  newObjectVersionCache(maxCachedObjectVersions),
+		objects:   newObjectFactsCache(),
+	}
+}
+
+// NewSuiClientWithAPI creates a Sui client that sends every call to api, such
+// as an in-memory stub in tests. label stands in for the node URL in logs and
+// endpoint status.
+func NewSuiClientWithAPI(label string, api sui.ISuiAPI) *SuiClient {
+	return &SuiClient{
+		nodeURL:   label,
+		endpoints: []*rpcEndpoint{{url: label, api: api, healthy: true}},
+		versions:  newObjectVersionCache(maxCachedObjectVersions),
+		objects:   newObjectFactsCache(),
 	}
 }
 
 // GetObject retrieves an object from Sui
 func (c *SuiClient) GetObject(objectID string) (models.SuiObjectResponse, error) {
-	return c.sdkClient.SuiGetObject(context.Background(), models.SuiGetObjectRequest{
-		ObjectId: objectID,
-		Options: models.SuiObjectDataOptions{
-			ShowType:                true,
-			ShowOwner:               true,
-			ShowPreviousTransaction: true,
-			ShowDisplay:             false,
-			ShowContent:             true,
-			ShowBcs:                 false,
-			ShowStorageRebate:       true,
-		},
+	response, err := callActive(c, func(api sui.ISuiAPI) (models.SuiObjectResponse, error) {
+		return api.SuiGetObject(context.Background(), models.SuiGetObjectRequest{
+			ObjectId: objectID,
+			Options: models.SuiObjectDataOptions{
+				ShowType:                true,
+				ShowOwner:               true,
+				ShowPreviousTransaction: true,
+				ShowDisplay:             false,
+				ShowContent:             true,
+				ShowBcs:                 false,
+				ShowStorageRebate:       true,
+			},
+		})
 	})
+	if err == nil {
+		c.versions.recordObject(response.Data)
+	}
+	return response, err
 }
 
 // GetOwnedObjects retrieves objects owned by an address
@@ -59,23 +107,88 @@ func (c *SuiClient) GetOwnedObjects(address string, objectType *string) (models.
 		filter = map[string]interface{}{"StructType": *objectType}
 	}
 
-	return c.sdkClient.SuiXGetOwnedObjects(context.Background(), models.SuiXGetOwnedObjectsRequest{
-		Address: address,
-		Query: models.SuiObjectResponseQuery{
-			Filter: filter,
-			Options: models.SuiObjectDataOptions{
-				ShowType:                true,
-				ShowOwner:               true,
-				ShowPreviousTransaction: true,
-				ShowDisplay:             false,
-				ShowContent:             true,
-				ShowBcs:                 false,
-				ShowStorageRebate:       true,
+	return callActive(c, func(api sui.ISuiAPI) (models.PaginatedObjectsResponse, error) {
+		return api.SuiXGetOwnedObjects(context.Background(), models.SuiXGetOwnedObjectsRequest{
+			Address: address,
+			Query: models.SuiObjectResponseQuery{
+				Filter: filter,
+				Options: models.SuiObjectDataOptions{
+					ShowType:                true,
+					ShowOwner:               true,
+					ShowPreviousTransaction: true,
+					ShowDisplay:             false,
+					ShowContent:             true,
+					ShowBcs:                 false,
+					ShowStorageRebate:       true,
+				},
 			},
-		},
+		})
 	})
 }
 
+// OwnedObjectIDs returns the IDs of every object address owns, across all pages.
+func (c *SuiClient) OwnedObjectIDs(address string) ([]string, error) {
+	var ids []string
+	var cursor interface{}
+	for {
+		page, err := callActive(c, func(api sui.ISuiAPI) (models.PaginatedObjectsResponse, error) {
+			return api.SuiXGetOwnedObjects(context.Background(), models.SuiXGetOwnedObjectsRequest{
+				Address: address,
+				Cursor:  cursor,
+				Limit:   50,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Data {
+			if object.Data != nil {
+				ids = append(ids, object.Data.ObjectId)
+			}
+		}
+		if !page.HasNextPage || page.NextCursor == "" {
+			return ids, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// OwnedItemNames returns the name field of every object of structType that
+// address owns, across all pages. Item NFTs carry the item type they were
+// minted as in their name.
+func (c *SuiClient) OwnedItemNames(ctx context.Context, address, structType string) ([]string, error) {
+	var names []string
+	var cursor interface{}
+	for {
+		page, err := callActive(c, func(api sui.ISuiAPI) (models.PaginatedObjectsResponse, error) {
+			return api.SuiXGetOwnedObjects(ctx, models.SuiXGetOwnedObjectsRequest{
+				Address: address,
+				Query: models.SuiObjectResponseQuery{
+					Filter:  map[string]interface{}{"StructType": structType},
+					Options: models.SuiObjectDataOptions{ShowContent: true},
+				},
+				Cursor: cursor,
+				Limit:  50,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Data {
+			if object.Data == nil || object.Data.Content == nil {
+				continue
+			}
+			if name, ok := object.Data.Content.Fields["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+		if !page.HasNextPage || page.NextCursor == "" {
+			return names, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
 // MoveCall prepares a transaction block for a Move function call.
 // Note: sui-go-sdk's MoveCall is part of building a transaction block.
 // This function will now return a models.TxnMetaData which contains transaction metadata.
@@ -90,33 +203,105 @@ func (c *SuiClient) MoveCall(sender, packageID, module, function string, typeArg
 		typeArgs[i] = arg
 	}
 
-	return c.sdkClient.MoveCall(context.Background(), models.MoveCallRequest{
+	request := models.MoveCallRequest{
 		Signer:          sender,
 		PackageObjectId: packageID,
 		Module:          module,
 		Function:        function,
 		TypeArguments:   typeArgs,
 		Arguments:       arguments,
-		Gas:             &gas,
 		GasBudget:       gasBudgetStr,
 		// GasPrice:      gasPriceStr, // GasPrice is often fetched dynamically or set globally
+	}
+	if gas != "" { // Otherwise the node picks one of the sender's coins
+		request.Gas = &gas
+	}
+	txMeta, err := callActive(c, func(api sui.ISuiAPI) (models.TxnMetaData, error) {
+		return api.MoveCall(context.Background(), request)
+	})
+	if err == nil {
+		c.versions.recordTransaction(txMeta)
+	}
+	return txMeta, err
+}
+
+// BatchMoveCall prepares one programmable transaction block running calls in
+// order, paid by sender's gas object. The signer, gas and budget of each call
+// are ignored.
+func (c *SuiClient) BatchMoveCall(sender string, calls []models.MoveCallRequest, gas string, gasBudget uint64) (models.TxnMetaData, error) {
+	params := make([]models.RPCTransactionRequestParams, len(calls))
+	for i := range calls {
+		call := calls[i]
+		params[i] = models.RPCTransactionRequestParams{MoveCallRequestParams: &call}
+	}
+	batch, err := callActive(c, func(api sui.ISuiAPI) (models.BatchTransactionResponse, error) {
+		return api.BatchTransaction(context.Background(), models.BatchTransactionRequest{
+			Signer:                         sender,
+			RPCTransactionRequestParams:    params,
+			Gas:                            &gas,
+			GasBudget:                      strconv.FormatUint(gasBudget, 10),
+			SuiTransactionBlockBuilderMode: "Commit",
+		})
 	})
+	if err != nil {
+		return models.TxnMetaData{}, err
+	}
+	txMeta := models.TxnMetaData{Gas: batch.Gas, InputObjects: batch.InputObjects, TxBytes: batch.TxBytes}
+	c.versions.recordTransaction(txMeta)
+	return txMeta, nil
+}
+
+// SetAuditLog records every transaction the client executes in log.
+func (c *SuiClient) SetAuditLog(log *audit.Log) {
+	c.audit = log
 }
 
 // ExecuteTransactionBlock executes a transaction block
 func (c *SuiClient) ExecuteTransactionBlock(txBytes string, signatures []string) (models.SuiTransactionBlockResponse, error) {
-	return c.sdkClient.SuiExecuteTransactionBlock(context.Background(), models.SuiExecuteTransactionBlockRequest{
-		TxBytes:   txBytes,
-		Signature: signatures,
-		Options: models.SuiTransactionBlockOptions{
-			ShowInput:          true,
-			ShowRawInput:       false,
-			ShowEffects:        true,
-			ShowEvents:         true,
-			ShowObjectChanges:  true,
-			ShowBalanceChanges: true,
-		},
-		RequestType: "WaitForLocalExecution",
+	resp, err := callActive(c, func(api sui.ISuiAPI) (models.SuiTransactionBlockResponse, error) {
+		return api.SuiExecuteTransactionBlock(context.Background(), models.SuiExecuteTransactionBlockRequest{
+			TxBytes:   txBytes,
+			Signature: signatures,
+			Options: models.SuiTransactionBlockOptions{
+				ShowInput:          true,
+				ShowRawInput:       false,
+				ShowEffects:        true,
+				ShowEvents:         true,
+				ShowObjectChanges:  true,
+				ShowBalanceChanges: true,
+			},
+			RequestType: "WaitForLocalExecution",
+		})
+	})
+	c.auditTransaction(resp, err)
+	return resp, err
+}
+
+// auditTransaction records an executed transaction, or the failure to submit one.
+func (c *SuiClient) auditTransaction(resp models.SuiTransactionBlockResponse, err error) {
+	entry := audit.Entry{Action: audit.ActionChainTx, Actor: resp.Transaction.Data.Sender, Target: resp.Digest, Result: resp.Effects.Status.Status}
+	switch {
+	case err != nil:
+		entry.Result, entry.Detail = audit.ResultFailed, err.Error()
+	case entry.Result != "success":
+		entry.Detail = resp.Effects.Status.Error
+	}
+	c.audit.Record(entry)
+}
+
+// GetTransactionBlock fetches an executed transaction with its effects, events and object changes.
+func (c *SuiClient) GetTransactionBlock(digest string) (models.SuiTransactionBlockResponse, error) {
+	return callActive(c, func(api sui.ISuiAPI) (models.SuiTransactionBlockResponse, error) {
+		return api.SuiGetTransactionBlock(context.Background(), models.SuiGetTransactionBlockRequest{
+			Digest: digest,
+			Options: models.SuiTransactionBlockOptions{
+				ShowInput:          true,
+				ShowEffects:        true,
+				ShowEvents:         true,
+				ShowObjectChanges:  true,
+				ShowBalanceChanges: true,
+			},
+		})
 	})
 }
 
@@ -146,27 +331,92 @@ func (c *SuiClient) QueryEvents(query models.SuiEventFilter, cursor *string, lim
 		}
 	}
 
-	return c.sdkClient.SuiXQueryEvents(context.Background(), models.SuiXQueryEventsRequest{
-		SuiEventFilter:  query,
-		Cursor:          actualCursor,
-		Limit:           actualLimit,
-		DescendingOrder: descendingOrder,
+	return callActive(c, func(api sui.ISuiAPI) (models.PaginatedEventsResponse, error) {
+		return api.SuiXQueryEvents(context.Background(), models.SuiXQueryEventsRequest{
+			SuiEventFilter:  query,
+			Cursor:          actualCursor,
+			Limit:           actualLimit,
+			DescendingOrder: descendingOrder,
+		})
 	})
 }
 
 // GetCoins retrieves coins owned by an address
 func (c *SuiClient) GetCoins(address, coinType string) (models.PaginatedCoinsResponse, error) {
-	return c.sdkClient.SuiXGetCoins(context.Background(), models.SuiXGetCoinsRequest{
-		Owner:    address,
-		CoinType: coinType,
+	return callActive(c, func(api sui.ISuiAPI) (models.PaginatedCoinsResponse, error) {
+		return api.SuiXGetCoins(context.Background(), models.SuiXGetCoinsRequest{
+			Owner:    address,
+			CoinType: coinType,
+		})
 	})
 }
 
 // GetBalance gets the balance for a specific coin type
 func (c *SuiClient) GetBalance(address, coinType string) (models.CoinBalanceResponse, error) {
-	return c.sdkClient.SuiXGetBalance(context.Background(), models.SuiXGetBalanceRequest{
-		Owner:    address,
-		CoinType: coinType,
+	return callActive(c, func(api sui.ISuiAPI) (models.CoinBalanceResponse, error) {
+		return api.SuiXGetBalance(context.Background(), models.SuiXGetBalanceRequest{
+			Owner:    address,
+			CoinType: coinType,
+		})
+	})
+}
+
+// AllBalances returns address's balance of every coin type it holds, in one call.
+func (c *SuiClient) AllBalances(ctx context.Context, address string) (models.CoinAllBalanceResponse, error) {
+	return callActive(c, func(api sui.ISuiAPI) (models.CoinAllBalanceResponse, error) {
+		return api.SuiXGetAllBalance(ctx, models.SuiXGetAllBalanceRequest{Owner: address})
+	})
+}
+
+// FirstOwnedObject returns the first object of structType that address owns,
+// with its content, or nil if it owns none.
+func (c *SuiClient) FirstOwnedObject(ctx context.Context, address, structType string) (*models.SuiObjectData, error) {
+	page, err := callActive(c, func(api sui.ISuiAPI) (models.PaginatedObjectsResponse, error) {
+		return api.SuiXGetOwnedObjects(ctx, models.SuiXGetOwnedObjectsRequest{
+			Address: address,
+			Query: models.SuiObjectResponseQuery{
+				Filter:  map[string]interface{}{"StructType": structType},
+				Options: models.SuiObjectDataOptions{ShowType: true, ShowContent: true},
+			},
+			Limit: 1,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range page.Data {
+		if object.Data != nil {
+			c.versions.recordObject(object.Data)
+			return object.Data, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetLatestCheckpointSequenceNumber returns the latest checkpoint known to the RPC node.
+// It is a cheap call, which makes it suitable for connectivity and health checks.
+func (c *SuiClient) GetLatestCheckpointSequenceNumber(ctx context.Context) (uint64, error) {
+	return callActive(c, func(api sui.ISuiAPI) (uint64, error) {
+		return api.SuiGetLatestCheckpointSequenceNumber(ctx)
+	})
+}
+
+// GetLatestSystemState returns the Sui system state, which names the current
+// epoch, when it started and how long epochs last.
+func (c *SuiClient) GetLatestSystemState(ctx context.Context) (models.SuiSystemStateSummary, error) {
+	return callActive(c, func(api sui.ISuiAPI) (models.SuiSystemStateSummary, error) {
+		return api.SuiXGetLatestSuiSystemState(ctx)
+	})
+}
+
+// GetDynamicFieldObject returns the dynamic field of parentID named by a value
+// of nameType, such as an entry of a Table whose ID is parentID.
+func (c *SuiClient) GetDynamicFieldObject(parentID, nameType string, nameValue interface{}) (models.SuiObjectResponse, error) {
+	return callActive(c, func(api sui.ISuiAPI) (models.SuiObjectResponse, error) {
+		return api.SuiXGetDynamicFieldObject(context.Background(), models.SuiXGetDynamicFieldObjectRequest{
+			ObjectId:         parentID,
+			DynamicFieldName: models.DynamicFieldObjectName{Type: nameType, Value: nameValue},
+		})
 	})
 }
 
@@ -0,0 +1,110 @@
+package sui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// CoinPayment is one payment of amount of coinType to recipient.
+type CoinPayment struct {
+	Recipient string
+	CoinType  string
+	Amount    uint64
+}
+
+// BuildCoinPayment prepares a transaction paying amount of coinType from
+// sender to recipient out of one of sender's coins, for sender to sign. The
+// coin must hold the whole amount; gasObjectID, if set, is never spent.
+func (c *SuiClient) BuildCoinPayment(sender, coinType string, amount uint64, recipient, gasObjectID string, gasBudget uint64) (models.TxnMetaData, error) {
+	coins, err := c.pickCoins(sender, []CoinPayment{{Recipient: recipient, CoinType: coinType, Amount: amount}}, gasObjectID)
+	if err != nil {
+		return models.TxnMetaData{}, err
+	}
+	return c.MoveCall(sender, "0x2", "pay", "split_and_transfer", []string{coinType},
+		[]interface{}{coins[0], strconv.FormatUint(amount, 10), recipient}, gasObjectID, gasBudget)
+}
+
+// PayCoinsWithServerKey makes every payment from sender, whose hex key is
+// privateKey, in one programmable transaction paid for by gasObjectID, and
+// returns the executed transaction. If sender is in the signer pool, the pool
+// signs it and picks the gas coin instead.
+func (c *SuiClient) PayCoinsWithServerKey(sender string, payments []CoinPayment, gasObjectID string, gasBudget uint64, privateKey string) (models.SuiTransactionBlockResponse, error) {
+	resp, err := c.executeAsServer(sender, gasObjectID, gasBudget, privateKey, c.coinPayments(payments, gasBudget))
+	if err != nil {
+		return resp, err
+	}
+	utils.LogInfof("SuiClient: Made %d coin payment(s) from %s (tx %s).", len(payments), sender, resp.Digest)
+	return resp, nil
+}
+
+// SignCoinPayments is PayCoinsWithServerKey without executing: it returns the
+// signed transaction, to be saved and then submitted with SubmitSigned as
+// often as needed. It is always signed with privateKey and paid for by
+// gasObjectID, even if sender is in the signer pool.
+func (c *SuiClient) SignCoinPayments(sender string, payments []CoinPayment, gasObjectID string, gasBudget uint64, privateKey string) (SignedTransaction, error) {
+	return c.signAsServer(sender, gasObjectID, gasBudget, privateKey, c.coinPayments(payments, gasBudget))
+}
+
+// coinPayments builds one programmable transaction making every payment.
+func (c *SuiClient) coinPayments(payments []CoinPayment, gasBudget uint64) TxBuilder {
+	return func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		coins, err := c.pickCoins(sender, payments, gasObjectID)
+		if err != nil {
+			return models.TxnMetaData{}, err
+		}
+		calls := make([]models.MoveCallRequest, len(payments))
+		for i, p := range payments {
+			calls[i] = models.MoveCallRequest{
+				PackageObjectId: "0x2",
+				Module:          "pay",
+				Function:        "split_and_transfer",
+				TypeArguments:   []interface{}{p.CoinType},
+				Arguments:       []interface{}{coins[i], strconv.FormatUint(p.Amount, 10), p.Recipient},
+			}
+		}
+		return c.BatchMoveCall(sender, calls, gasObjectID, gasBudget)
+	}
+}
+
+// pickCoins returns, for each payment, one of owner's coins of its type that
+// covers it after the payments before it. gasObjectID is left out.
+func (c *SuiClient) pickCoins(owner string, payments []CoinPayment, gasObjectID string) ([]string, error) {
+	balances := make(map[string]uint64) // Coin object ID -> what is left of it
+	byType := make(map[string][]string) // Coin type -> coin object IDs
+	for _, p := range payments {
+		if _, ok := byType[p.CoinType]; ok {
+			continue
+		}
+		resp, err := c.GetCoins(owner, p.CoinType)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s coins of %s: %w", p.CoinType, owner, err)
+		}
+		byType[p.CoinType] = []string{}
+		for _, coin := range resp.Data {
+			balance, err := strconv.ParseUint(coin.Balance, 10, 64)
+			if err != nil || strings.EqualFold(coin.CoinObjectId, gasObjectID) {
+				continue
+			}
+			balances[coin.CoinObjectId] = balance
+			byType[p.CoinType] = append(byType[p.CoinType], coin.CoinObjectId)
+		}
+	}
+	picked := make([]string, len(payments))
+	for i, p := range payments {
+		for _, id := range byType[p.CoinType] {
+			if balances[id] >= p.Amount {
+				picked[i] = id
+				balances[id] -= p.Amount
+				break
+			}
+		}
+		if picked[i] == "" {
+			return nil, fmt.Errorf("%s has no %s coin holding %d", owner, p.CoinType, p.Amount)
+		}
+	}
+	return picked, nil
+}
@@ -0,0 +1,159 @@
+package sui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// gasBudgetMarginPercent is added on top of the dry-run gas cost when suggesting a budget,
+// since object state (and so storage cost) can change between the dry run and execution.
+const gasBudgetMarginPercent = 20
+
+// DryRunTransactionBlock simulates a transaction without signing or committing it.
+func (c *SuiClient) DryRunTransactionBlock(txBytes string) (models.SuiTransactionBlockResponse, error) {
+	return callActive(c, func(api sui.ISuiAPI) (models.SuiTransactionBlockResponse, error) {
+		return api.SuiDryRunTransactionBlock(context.Background(), models.SuiDryRunTransactionBlockRequest{
+			TxBytes: txBytes,
+		})
+	})
+}
+
+// GasEstimate is the gas cost reported by a dry run, in MIST.
+type GasEstimate struct {
+	ComputationCost uint64
+	StorageCost     uint64
+	StorageRebate   uint64
+	// NetCost is what the sender actually pays: computation + storage - rebate (never negative).
+	NetCost uint64
+	// SuggestedBudget covers computation and storage (the rebate is only credited afterwards) plus a safety margin.
+	SuggestedBudget uint64
+}
+
+// TransactionFailedError reports a transaction that the network rejected or would reject.
+type TransactionFailedError struct {
+	Stage         string // "dry-run" or "execute"
+	Raw           string // Error string reported by the node
+	PlayerMessage string // Human-readable reason that is safe to show to players
+}
+
+func (e *TransactionFailedError) Error() string {
+	return fmt.Sprintf("transaction failed during %s: %s (%s)", e.Stage, e.PlayerMessage, e.Raw)
+}
+
+var moveAbortCodePattern = regexp.MustCompile(`MoveAbort\(.*,\s*(\d+)\)`)
+
+// executionErrorReasons maps fragments of Sui execution errors to player-facing text.
+// They are checked in order, so more specific fragments come first.
+var executionErrorReasons = []struct {
+	fragment string
+	message  string
+}{
+	{"InsufficientGas", "The transaction ran out of gas. Please try again."},
+	{"GasBalanceTooLow", "Insufficient payment coin: the gas coin balance is too low."},
+	{"insufficient payment coin", "Insufficient payment coin: the gas coin balance is too low."},
+	{"InsufficientCoinBalance", "Not enough coins to complete this transaction."},
+	{"GasBudgetTooLow", "The gas budget is too low for this transaction."},
+	{"GasBudgetTooHigh", "The gas budget exceeds the payment coin balance."},
+	{"ObjectVersionUnavailableForConsumption", "An item involved was changed by another transaction. Please try again."},
+	{"ObjectNotFound", "An item involved in this transaction no longer exists."},
+	{"InputObjectDeleted", "An item involved in this transaction no longer exists."},
+	{"InvalidOwnership", "You do not own an item required by this transaction."},
+	{"CoinBalanceOverflow", "The resulting coin balance would be too large."},
+}
+
+// ExplainExecutionError turns a raw Sui execution error into a message suitable for players.
+func ExplainExecutionError(raw string) string {
+	if match := moveAbortCodePattern.FindStringSubmatch(raw); match != nil {
+		return fmt.Sprintf("The game contract rejected this action (code %s).", match[1])
+	}
+	lowered := strings.ToLower(raw)
+	for _, reason := range executionErrorReasons {
+		if strings.Contains(lowered, strings.ToLower(reason.fragment)) {
+			return reason.message
+		}
+	}
+	return "The transaction could not be completed."
+}
+
+// EstimateGas extracts the gas cost from a transaction response's effects.
+func EstimateGas(response models.SuiTransactionBlockResponse) (GasEstimate, error) {
+	gasUsed := response.Effects.GasUsed
+	var estimate GasEstimate
+	var err error
+	if estimate.ComputationCost, err = parseMist(gasUsed.ComputationCost); err != nil {
+		return GasEstimate{}, fmt.Errorf("invalid computation cost: %w", err)
+	}
+	if estimate.StorageCost, err = parseMist(gasUsed.StorageCost); err != nil {
+		return GasEstimate{}, fmt.Errorf("invalid storage cost: %w", err)
+	}
+	if estimate.StorageRebate, err = parseMist(gasUsed.StorageRebate); err != nil {
+		return GasEstimate{}, fmt.Errorf("invalid storage rebate: %w", err)
+	}
+
+	gross := estimate.ComputationCost + estimate.StorageCost
+	if gross > estimate.StorageRebate {
+		estimate.NetCost = gross - estimate.StorageRebate
+	}
+	estimate.SuggestedBudget = gross + gross*gasBudgetMarginPercent/100
+	return estimate, nil
+}
+
+func parseMist(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// PreflightTransaction dry-runs txBytes and returns its gas estimate. If the
+// simulation fails, the error is a *TransactionFailedError carrying a
+// player-facing reason, so callers can reject the action before signing.
+func (c *SuiClient) PreflightTransaction(txBytes string) (GasEstimate, error) {
+	response, err := c.DryRunTransactionBlock(txBytes)
+	if err != nil {
+		return GasEstimate{}, &TransactionFailedError{Stage: "dry-run", Raw: err.Error(), PlayerMessage: ExplainExecutionError(err.Error())}
+	}
+	if status := response.Effects.Status; status.Status != "success" {
+		utils.LogWarnf("SUI Client: Dry run failed with status %q: %s", status.Status, status.Error)
+		return GasEstimate{}, &TransactionFailedError{Stage: "dry-run", Raw: status.Error, PlayerMessage: ExplainExecutionError(status.Error)}
+	}
+	estimate, err := EstimateGas(response)
+	if err != nil {
+		return GasEstimate{}, err
+	}
+	utils.LogDebugf("SUI Client: Dry run succeeded. Net gas %d MIST, suggested budget %d MIST.", estimate.NetCost, estimate.SuggestedBudget)
+	return estimate, nil
+}
+
+// preflightWithin is PreflightTransaction for a transaction built with
+// gasBudget. A budget below the suggested one is rejected with a
+// *TransactionFailedError: the transaction could run out of gas on chain and
+// still be charged.
+func (c *SuiClient) preflightWithin(txBytes string, gasBudget uint64) error {
+	estimate, err := c.PreflightTransaction(txBytes)
+	if err != nil {
+		return err
+	}
+	if estimate.SuggestedBudget > gasBudget {
+		raw := fmt.Sprintf("GasBudgetTooLow: budget %d is below the suggested %d", gasBudget, estimate.SuggestedBudget)
+		utils.LogWarnf("SUI Client: Rejecting transaction before signing: %s", raw)
+		return &TransactionFailedError{Stage: "dry-run", Raw: raw, PlayerMessage: ExplainExecutionError(raw)}
+	}
+	return nil
+}
+
+// checkExecutionEffects converts a failed execution status into a *TransactionFailedError.
+func checkExecutionEffects(response models.SuiTransactionBlockResponse) error {
+	status := response.Effects.Status
+	if status.Status == "" || status.Status == "success" {
+		return nil
+	}
+	return &TransactionFailedError{Stage: "execute", Raw: status.Error, PlayerMessage: ExplainExecutionError(status.Error)}
+}
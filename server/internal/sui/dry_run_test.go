@@ -0,0 +1,79 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+func TestExplainExecutionError(t *testing.T) {
+	cases := map[string]string{
+		"InsufficientCoinBalance in command 0":                                          "Not enough coins to complete this transaction.",
+		"Balance of gas object 0x12 is lower than the needed amount: GasBalanceTooLow":  "Insufficient payment coin: the gas coin balance is too low.",
+		"MoveAbort(MoveLocation { module: ModuleId { name: market } }, 3) in command 1": "The game contract rejected this action (code 3).",
+		"something new": "The transaction could not be completed.",
+	}
+	for raw, want := range cases {
+		if got := ExplainExecutionError(raw); got != want {
+			t.Errorf("ExplainExecutionError(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	var response models.SuiTransactionBlockResponse
+	response.Effects.GasUsed.ComputationCost = "1000"
+	response.Effects.GasUsed.StorageCost = "4000"
+	response.Effects.GasUsed.StorageRebate = "3000"
+
+	estimate, err := EstimateGas(response)
+	if err != nil {
+		t.Fatalf("EstimateGas failed: %v", err)
+	}
+	if estimate.NetCost != 2000 {
+		t.Errorf("Expected net cost 2000, got %d", estimate.NetCost)
+	}
+	if estimate.SuggestedBudget != 6000 {
+		t.Errorf("Expected suggested budget 6000, got %d", estimate.SuggestedBudget)
+	}
+}
+
+// costlyAPI builds mints whose dry run costs 5000 MIST, and counts executions.
+type costlyAPI struct {
+	sui.ISuiAPI
+	executed int
+}
+
+func (a *costlyAPI) MoveCall(ctx context.Context, req models.MoveCallRequest) (models.TxnMetaData, error) {
+	return models.TxnMetaData{TxBytes: "bWludA=="}, nil
+}
+
+func (a *costlyAPI) SuiDryRunTransactionBlock(ctx context.Context, req models.SuiDryRunTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	var resp models.SuiTransactionBlockResponse
+	resp.Effects.Status.Status = "success"
+	resp.Effects.GasUsed.ComputationCost = "1000"
+	resp.Effects.GasUsed.StorageCost = "4000"
+	return resp, nil
+}
+
+func (a *costlyAPI) SuiExecuteTransactionBlock(ctx context.Context, req models.SuiExecuteTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	a.executed++
+	return models.SuiTransactionBlockResponse{Digest: "tx"}, nil
+}
+
+func TestServerTransactionsBelowTheSuggestedBudgetAreRejected(t *testing.T) {
+	api := &costlyAPI{}
+	items := NewItemNFTService(NewSuiClientWithAPI("stub", api), "0x5", "item", "0xminter", "0xgas")
+
+	_, err := items.MintItemNFTAndExecute("sword", nil, "0xa1", 5000, "0x1")
+	var failed *TransactionFailedError
+	if !errors.As(err, &failed) || failed.PlayerMessage != "The gas budget is too low for this transaction." {
+		t.Fatalf("Expected a budget below the suggested 6000 to be rejected, got %v", err)
+	}
+	if api.executed != 0 {
+		t.Errorf("Expected nothing to be executed, got %d executions", api.executed)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/block-vision/sui-go-sdk/models"
 	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
@@ -213,3 +215,46 @@ func (s *EconomySuiService) BurnGameTokens(burnerAddress string, tokenObjectIDs
 		tokenObjectIDs, burnerAddress, txBlockResponse.TxBytes)
 	return txBlockResponse, nil
 }
+
+// VerifyPayment checks that txDigest is a successful transaction sent by payer
+// that paid recipient at least amount of coinType. It lets shops accept
+// on-chain payments for premium items.
+func (c *SuiClient) VerifyPayment(txDigest, payer, recipient, coinType string, amount uint64) error {
+	return c.VerifyPaymentSince(txDigest, payer, recipient, coinType, amount, time.Time{})
+}
+
+// VerifyPaymentSince is VerifyPayment for a payment that must also have been
+// checkpointed no earlier than since, unless since is zero. Callers that
+// forget old digests use it so an old payment cannot be presented again.
+func (c *SuiClient) VerifyPaymentSince(txDigest, payer, recipient, coinType string, amount uint64, since time.Time) error {
+	tx, err := c.GetTransactionBlock(txDigest)
+	if err != nil {
+		return fmt.Errorf("could not fetch payment transaction %s: %w", txDigest, err)
+	}
+	if !since.IsZero() {
+		ms, err := strconv.ParseInt(tx.TimestampMs, 10, 64)
+		if err != nil {
+			return fmt.Errorf("payment transaction %s is not checkpointed yet", txDigest)
+		}
+		if time.UnixMilli(ms).Before(since) {
+			return fmt.Errorf("payment transaction %s predates %s", txDigest, since.Format(time.RFC3339))
+		}
+	}
+	if tx.Effects.Status.Status != "success" {
+		return fmt.Errorf("payment transaction %s did not succeed (status %q)", txDigest, tx.Effects.Status.Status)
+	}
+	if !strings.EqualFold(tx.Transaction.Data.Sender, payer) {
+		return fmt.Errorf("payment transaction %s was not sent by %s", txDigest, payer)
+	}
+	for _, change := range tx.BalanceChanges {
+		if change.CoinType != coinType || !strings.EqualFold(change.GetBalanceChangeOwner(), recipient) {
+			continue
+		}
+		received, err := strconv.ParseInt(change.Amount, 10, 64)
+		if err == nil && received >= 0 && uint64(received) >= amount {
+			utils.LogInfof("SuiClient: Verified payment of %d %s from %s to %s (tx %s).", received, coinType, payer, recipient, txDigest)
+			return nil
+		}
+	}
+	return fmt.Errorf("transaction %s does not pay %d %s to %s", txDigest, amount, coinType, recipient)
+}
@@ -0,0 +1,182 @@
+package sui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+const (
+	// DefaultEpochPollInterval is how often the epoch is checked when no interval is configured.
+	DefaultEpochPollInterval = 30 * time.Second
+	// epochRecheckInterval is how often the epoch is checked once its estimated
+	// end has passed, until the chain moves on.
+	epochRecheckInterval = 5 * time.Second
+	// epochPollTimeout bounds a single poll.
+	epochPollTimeout = 10 * time.Second
+)
+
+// Epoch is the chain's current epoch.
+type Epoch struct {
+	Number     uint64        `json:"number"`
+	StartedAt  time.Time     `json:"startedAt"`
+	Duration   time.Duration `json:"duration"`   // Target length; the epoch ends at a checkpoint after it
+	Checkpoint uint64        `json:"checkpoint"` // Latest checkpoint seen
+}
+
+// EstimatedEnd is when the epoch is expected to end.
+func (e Epoch) EstimatedEnd() time.Time {
+	return e.StartedAt.Add(e.Duration)
+}
+
+// EpochTracker follows the chain's epoch and checkpoint by polling, so game
+// logic counted in epochs can read the current one and be told when it
+// changes. A change is published on the event bus as events.EpochChanged.
+// The first poll only finds the epoch to start from.
+type EpochTracker struct {
+	interval   time.Duration
+	state      func(ctx context.Context) (models.SuiSystemStateSummary, error)
+	checkpoint func(ctx context.Context) (uint64, error)
+	bus        *events.Bus
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	current Epoch
+	known   bool // A poll has succeeded
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewEpochTracker creates a tracker polling client every interval, or
+// DefaultEpochPollInterval if interval is not positive, and publishing epoch
+// changes on bus.
+func NewEpochTracker(client *SuiClient, bus *events.Bus, interval time.Duration) *EpochTracker {
+	if interval <= 0 {
+		interval = DefaultEpochPollInterval
+	}
+	return &EpochTracker{
+		interval:   interval,
+		state:      client.GetLatestSystemState,
+		checkpoint: client.GetLatestCheckpointSequenceNumber,
+		bus:        bus,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Current returns the current epoch, and false until the first successful poll.
+func (t *EpochTracker) Current() (Epoch, bool) {
+	if t == nil {
+		return Epoch{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.current, t.known
+}
+
+// Start polls in the background until Stop. Near the estimated end of an
+// epoch it polls sooner, so the change is noticed promptly.
+func (t *EpochTracker) Start() {
+	go func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-timer.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), epochPollTimeout)
+			if err := t.Poll(ctx); err != nil {
+				utils.LogWarnf("EpochTracker: Polling the epoch failed: %v", err)
+			}
+			cancel()
+			timer.Reset(t.nextPoll())
+		}
+	}()
+}
+
+// Stop stops the background polling.
+func (t *EpochTracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// nextPoll is how long to wait before the next poll.
+func (t *EpochTracker) nextPoll() time.Duration {
+	epoch, known := t.Current()
+	if !known {
+		return t.interval
+	}
+	wait := epoch.EstimatedEnd().Sub(t.now())
+	if wait <= 0 {
+		wait = epochRecheckInterval
+	}
+	if wait > t.interval {
+		wait = t.interval
+	}
+	return wait
+}
+
+// Poll reads the current epoch and checkpoint, and publishes the change if the
+// epoch moved on since the last poll.
+func (t *EpochTracker) Poll(ctx context.Context) error {
+	state, err := t.state(ctx)
+	if err != nil {
+		return err
+	}
+	epoch, err := epochFromState(state)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := t.checkpoint(ctx)
+	if err != nil {
+		utils.LogWarnf("EpochTracker: Reading the latest checkpoint failed: %v", err)
+	}
+
+	t.mu.Lock()
+	previous, known := t.current, t.known
+	if err != nil {
+		checkpoint = previous.Checkpoint
+	}
+	epoch.Checkpoint = checkpoint
+	t.current, t.known = epoch, true
+	t.mu.Unlock()
+
+	if known && epoch.Number != previous.Number {
+		utils.LogInfof("EpochTracker: Sui epoch %d started (was %d), estimated to end at %s.", epoch.Number, previous.Number, epoch.EstimatedEnd().Format(time.RFC3339))
+		t.bus.Publish(events.TopicEpochChanged, events.EpochChanged{
+			Epoch:        epoch.Number,
+			Previous:     previous.Number,
+			StartedAt:    epoch.StartedAt,
+			EstimatedEnd: epoch.EstimatedEnd(),
+		})
+	}
+	return nil
+}
+
+func epochFromState(state models.SuiSystemStateSummary) (Epoch, error) {
+	number, err := strconv.ParseUint(state.Epoch, 10, 64)
+	if err != nil {
+		return Epoch{}, fmt.Errorf("invalid epoch %q: %w", state.Epoch, err)
+	}
+	startMs, err := strconv.ParseInt(state.EpochStartTimestampMs, 10, 64)
+	if err != nil {
+		return Epoch{}, fmt.Errorf("invalid epoch start %q: %w", state.EpochStartTimestampMs, err)
+	}
+	durationMs, err := strconv.ParseInt(state.EpochDurationMs, 10, 64)
+	if err != nil {
+		return Epoch{}, fmt.Errorf("invalid epoch duration %q: %w", state.EpochDurationMs, err)
+	}
+	return Epoch{
+		Number:    number,
+		StartedAt: time.UnixMilli(startMs).UTC(),
+		Duration:  time.Duration(durationMs) * time.Millisecond,
+	}, nil
+}
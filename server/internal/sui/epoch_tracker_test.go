@@ -0,0 +1,65 @@
+package sui
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/events"
+)
+
+func TestEpochTrackerPublishesEpochChanges(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	epoch, checkpoint := uint64(40), uint64(1000)
+	bus := events.NewBus(0)
+	changes := make(chan events.EpochChanged, 4)
+	bus.Subscribe(string(events.TopicEpochChanged), "test", func(e events.Event) {
+		changes <- e.Payload.(events.EpochChanged)
+	})
+	tracker := &EpochTracker{
+		interval: time.Minute,
+		state: func(context.Context) (models.SuiSystemStateSummary, error) {
+			return models.SuiSystemStateSummary{
+				Epoch:                 strconv.FormatUint(epoch, 10),
+				EpochStartTimestampMs: strconv.FormatInt(start.Add(time.Duration(epoch-40)*24*time.Hour).UnixMilli(), 10),
+				EpochDurationMs:       strconv.FormatInt((24 * time.Hour).Milliseconds(), 10),
+			}, nil
+		},
+		checkpoint: func(context.Context) (uint64, error) { return checkpoint, nil },
+		bus:        bus,
+		now:        func() time.Time { return start.Add(23*time.Hour + 59*time.Minute + 30*time.Second) },
+	}
+	if _, known := tracker.Current(); known {
+		t.Fatal("epoch known before the first poll")
+	}
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := tracker.Current()
+	if current.Number != 40 || current.Checkpoint != 1000 || !current.EstimatedEnd().Equal(start.Add(24*time.Hour)) {
+		t.Fatalf("current = %+v", current)
+	}
+	if wait := tracker.nextPoll(); wait != 30*time.Second {
+		t.Fatalf("next poll in %v, want the 30s left in the epoch", wait)
+	}
+
+	epoch, checkpoint = 41, 1200
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case change := <-changes:
+		if change.Epoch != 41 || change.Previous != 40 || !change.StartedAt.Equal(start.Add(24*time.Hour)) {
+			t.Fatalf("change = %+v", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no epoch change published")
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("extra change published: %+v", change)
+	default:
+	}
+}
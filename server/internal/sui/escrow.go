@@ -0,0 +1,218 @@
+package sui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// suiClockObjectID is the shared Clock object the escrow refund reads.
+const suiClockObjectID = "0x6"
+
+// EscrowTerms are the terms of a two-party escrow: what each side gives.
+type EscrowTerms struct {
+	TradeID   string
+	PartyA    string   // Sui address of the proposer
+	PartyB    string   // Sui address of the counterparty
+	ItemsA    []string // Item object IDs party A deposits
+	ItemsB    []string
+	CoinsA    uint64 // MIST party A deposits
+	CoinsB    uint64
+	FeeA      uint64    // MIST party A deposits on top of CoinsA, paid to Treasury on release
+	FeeB      uint64    // The same for party B
+	Treasury  string    // Receives the fees; required if there are any
+	ExpiresAt time.Time // After this either party may claim a refund without the arbiter
+}
+
+// EscrowInfo is the deposit state of an escrow object.
+type EscrowInfo struct {
+	ID              string
+	TradeID         string
+	PartyA, PartyB  string
+	ExpectedItemsA  int
+	ExpectedItemsB  int
+	ExpectedCoinsA  uint64
+	ExpectedCoinsB  uint64
+	DepositedItemsA int
+	DepositedItemsB int
+	DepositedCoinsA uint64
+	DepositedCoinsB uint64
+	ExpiresAtMs     uint64
+}
+
+// Complete reports whether both sides have deposited everything agreed.
+func (e EscrowInfo) Complete() bool {
+	return e.DepositedItemsA == e.ExpectedItemsA && e.DepositedItemsB == e.ExpectedItemsB &&
+		e.DepositedCoinsA == e.ExpectedCoinsA && e.DepositedCoinsB == e.ExpectedCoinsB
+}
+
+// EscrowSuiService drives the escrow Move module. The server is the arbiter of
+// every escrow it creates: only it can release one early.
+type EscrowSuiService struct {
+	suiClient      *SuiClient
+	packageID      string // Package containing the escrow module
+	moduleName     string // e.g. "escrow"
+	itemType       string // Full type of the traded item NFTs, the module's type argument
+	arbiterAddress string // Server address that creates, releases and refunds escrows
+	gasObjectID    string // Gas coin owned by arbiterAddress
+	gasBudget      uint64
+}
+
+// NewEscrowSuiService creates a new EscrowSuiService.
+func NewEscrowSuiService(suiClient *SuiClient, packageID, moduleName, itemType, arbiterAddress, gasObjectID string, gasBudget uint64) *EscrowSuiService {
+	utils.LogInfo("Initializing Escrow Sui Service...")
+	if suiClient == nil {
+		log.Panic("EscrowSuiService: SuiClient cannot be nil")
+	}
+	if packageID == "" || moduleName == "" || itemType == "" || arbiterAddress == "" || gasObjectID == "" {
+		log.Panic("EscrowSuiService: packageID, moduleName, itemType, arbiterAddress and gasObjectID must be provided.")
+	}
+	return &EscrowSuiService{
+		suiClient:      suiClient,
+		packageID:      packageID,
+		moduleName:     moduleName,
+		itemType:       itemType,
+		arbiterAddress: arbiterAddress,
+		gasObjectID:    gasObjectID,
+		gasBudget:      gasBudget,
+	}
+}
+
+// CreateEscrow creates and shares an escrow with terms and returns its object ID.
+func (s *EscrowSuiService) CreateEscrow(terms EscrowTerms, serverPrivateKeyHex string) (string, error) {
+	utils.LogInfof("EscrowSuiService: Creating escrow for trade %s between %s and %s.", terms.TradeID, terms.PartyA, terms.PartyB)
+	itemsA, itemsB := terms.ItemsA, terms.ItemsB
+	if itemsA == nil {
+		itemsA = []string{}
+	}
+	if itemsB == nil {
+		itemsB = []string{}
+	}
+	callArgs := []interface{}{
+		terms.TradeID,
+		terms.PartyA,
+		terms.PartyB,
+		itemsA,
+		itemsB,
+		strconv.FormatUint(terms.CoinsA, 10),
+		strconv.FormatUint(terms.CoinsB, 10),
+	}
+	// An escrow with fees holds them with the deposits, and the module's
+	// release pays them to the treasury in the same transaction.
+	function := "create"
+	if terms.FeeA != 0 || terms.FeeB != 0 {
+		if terms.Treasury == "" {
+			return "", fmt.Errorf("create escrow for trade %s: fees need a treasury address", terms.TradeID)
+		}
+		function = "create_with_fees"
+		callArgs = append(callArgs, strconv.FormatUint(terms.FeeA, 10), strconv.FormatUint(terms.FeeB, 10), terms.Treasury)
+	}
+	callArgs = append(callArgs, strconv.FormatInt(terms.ExpiresAt.UnixMilli(), 10))
+	resp, err := s.execute(function, callArgs, serverPrivateKeyHex)
+	if err != nil {
+		utils.LogErrorf("EscrowSuiService: Failed to create escrow for trade %s: %v", terms.TradeID, err)
+		return "", fmt.Errorf("create escrow for trade %s: %w", terms.TradeID, err)
+	}
+	for _, change := range resp.ObjectChanges {
+		if change.Type == "created" && strings.Contains(change.ObjectType, "::"+s.moduleName+"::Escrow<") {
+			utils.LogInfof("EscrowSuiService: Escrow %s created for trade %s. Digest: %s", change.ObjectId, terms.TradeID, resp.Digest)
+			return change.ObjectId, nil
+		}
+	}
+	return "", fmt.Errorf("create escrow for trade %s: transaction %s created no escrow object", terms.TradeID, resp.Digest)
+}
+
+// GetEscrow reads the deposit state of an escrow.
+func (s *EscrowSuiService) GetEscrow(escrowID string) (EscrowInfo, error) {
+	objectResponse, err := s.suiClient.GetObject(escrowID)
+	if err != nil {
+		utils.LogErrorf("EscrowSuiService: Failed to get escrow object %s: %v", escrowID, err)
+		return EscrowInfo{}, fmt.Errorf("failed to get escrow object %s: %w", escrowID, err)
+	}
+	if objectResponse.Data == nil || objectResponse.Data.Content == nil || len(objectResponse.Data.Content.Fields) == 0 {
+		return EscrowInfo{}, fmt.Errorf("escrow object %s not found or has no content", escrowID)
+	}
+	return parseEscrowFields(escrowID, objectResponse.Data.Content.Fields), nil
+}
+
+// ReleaseEscrow sends each side's deposits to the other party and returns the
+// transaction digest. The escrow must be complete.
+func (s *EscrowSuiService) ReleaseEscrow(escrowID, serverPrivateKeyHex string) (string, error) {
+	utils.LogInfof("EscrowSuiService: Releasing escrow %s.", escrowID)
+	resp, err := s.execute("release", []interface{}{escrowID}, serverPrivateKeyHex)
+	if err != nil {
+		utils.LogErrorf("EscrowSuiService: Failed to release escrow %s: %v", escrowID, err)
+		return "", fmt.Errorf("release escrow %s: %w", escrowID, err)
+	}
+	return resp.Digest, nil
+}
+
+// RefundEscrow returns every deposit to its depositor and returns the
+// transaction digest.
+func (s *EscrowSuiService) RefundEscrow(escrowID, serverPrivateKeyHex string) (string, error) {
+	utils.LogInfof("EscrowSuiService: Refunding escrow %s.", escrowID)
+	resp, err := s.execute("refund", []interface{}{escrowID, suiClockObjectID}, serverPrivateKeyHex)
+	if err != nil {
+		utils.LogErrorf("EscrowSuiService: Failed to refund escrow %s: %v", escrowID, err)
+		return "", fmt.Errorf("refund escrow %s: %w", escrowID, err)
+	}
+	return resp.Digest, nil
+}
+
+// execute prepares, dry-runs, signs and executes an arbiter call, rebuilding it
+// once on an object version conflict. If the arbiter is in the signer pool,
+// the pool signs it.
+func (s *EscrowSuiService) execute(function string, callArgs []interface{}, serverPrivateKeyHex string) (models.SuiTransactionBlockResponse, error) {
+	return s.suiClient.executeAsServer(s.arbiterAddress, s.gasObjectID, s.gasBudget, serverPrivateKeyHex, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		txBlockResponse, err := s.suiClient.MoveCall(sender, s.packageID, s.moduleName, function, []string{s.itemType}, callArgs, gasObjectID, s.gasBudget)
+		if err != nil {
+			return txBlockResponse, fmt.Errorf("MoveCall failed for %s: %w", function, err)
+		}
+		return txBlockResponse, nil
+	})
+}
+
+// parseEscrowFields reads an Escrow object's fields. Numbers come back as
+// strings; a Balance is either its value or a struct holding it.
+func parseEscrowFields(escrowID string, fields map[string]interface{}) EscrowInfo {
+	info := EscrowInfo{ID: escrowID}
+	info.TradeID, _ = fields["trade_id"].(string)
+	info.PartyA, _ = fields["party_a"].(string)
+	info.PartyB, _ = fields["party_b"].(string)
+	info.ExpectedItemsA = fieldLength(fields["expected_items_a"])
+	info.ExpectedItemsB = fieldLength(fields["expected_items_b"])
+	info.ExpectedCoinsA = fieldUint(fields["expected_coins_a"])
+	info.ExpectedCoinsB = fieldUint(fields["expected_coins_b"])
+	info.DepositedItemsA = fieldLength(fields["items_a"])
+	info.DepositedItemsB = fieldLength(fields["items_b"])
+	info.DepositedCoinsA = fieldUint(fields["coins_a"])
+	info.DepositedCoinsB = fieldUint(fields["coins_b"])
+	info.ExpiresAtMs = fieldUint(fields["expires_at_ms"])
+	return info
+}
+
+func fieldLength(value interface{}) int {
+	items, _ := value.([]interface{})
+	return len(items)
+}
+
+func fieldUint(value interface{}) uint64 {
+	switch v := value.(type) {
+	case string:
+		n, _ := strconv.ParseUint(v, 10, 64)
+		return n
+	case float64:
+		return uint64(v)
+	case map[string]interface{}:
+		if nested, ok := v["fields"].(map[string]interface{}); ok {
+			return fieldUint(nested["value"])
+		}
+		return fieldUint(v["value"])
+	}
+	return 0
+}
@@ -54,11 +54,16 @@ func NewEventLogSuiService(suiClient *SuiClient, packageID, moduleName, senderAd
 // Returns TxnMetaData for subsequent signing and execution.
 // This uses the service's configured senderAddress and gasObjectID.
 func (s *EventLogSuiService) LogGameEventViaCall(event GameEventData, gasBudget uint64) (models.TxnMetaData, error) {
+	return s.logGameEventFrom(s.senderAddress, s.gasObjectID, event, gasBudget)
+}
+
+// logGameEventFrom prepares the event call with sender paying from gasObjectID.
+func (s *EventLogSuiService) logGameEventFrom(sender, gasObjectID string, event GameEventData, gasBudget uint64) (models.TxnMetaData, error) {
 	functionName := "log_custom_event" // Example Move function name
 	utils.LogInfof("EventLogSuiService: Preparing to log game event via Move call: Type '%s', Creator: %s. GasBudget: %d",
 		event.EventType, event.EventCreator, gasBudget)
 
-	if s.packageID == "" || s.moduleName == "" || s.senderAddress == "" || s.gasObjectID == "" {
+	if s.packageID == "" || s.moduleName == "" || sender == "" || gasObjectID == "" {
 		errMsg := "missing packageID, moduleName, senderAddress, or gasObjectID for LogGameEventViaCall in EventLogSuiService config"
 		utils.LogError("EventLogSuiService: " + errMsg)
 		return models.TxnMetaData{}, fmt.Errorf(errMsg)
@@ -85,13 +90,13 @@ func (s *EventLogSuiService) LogGameEventViaCall(event GameEventData, gasBudget
 	typeArgs := []string{}
 
 	txBlockResponse, err := s.suiClient.MoveCall(
-		s.senderAddress,
+		sender,
 		s.packageID,
 		s.moduleName,
 		functionName,
 		typeArgs,
 		callArgs,
-		s.gasObjectID,
+		gasObjectID,
 		gasBudget,
 	)
 
@@ -105,6 +110,22 @@ func (s *EventLogSuiService) LogGameEventViaCall(event GameEventData, gasBudget
 	return txBlockResponse, nil
 }
 
+// LogGameEventAndExecute records event on chain: it prepares the call with
+// LogGameEventViaCall, dry-runs it, signs it with privateKey (the hex key of
+// the service's sender) and executes it. With a signer pool, any free pool
+// signer sends it instead. A transaction built on stale object versions is
+// rebuilt once.
+func (s *EventLogSuiService) LogGameEventAndExecute(event GameEventData, gasBudget uint64, privateKey string) (models.SuiTransactionBlockResponse, error) {
+	resp, err := s.suiClient.executeFromAnyServer(s.senderAddress, s.gasObjectID, gasBudget, privateKey, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return s.logGameEventFrom(sender, gasObjectID, event, gasBudget)
+	})
+	if err != nil {
+		return resp, err
+	}
+	utils.LogInfof("EventLogSuiService: Logged game event %s (tx %s).", event.EventType, resp.Digest)
+	return resp, nil
+}
+
 // QueryGameEvents retrieves past game events using Sui's event querying capabilities.
 // eventTypeFilter should be the fully qualified event type string, e.g., "0xPACKAGE::MODULE::EventName"
 func (s *EventLogSuiService) QueryGameEvents(
@@ -0,0 +1,131 @@
+package sui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// eventPageSize is how many events one poll asks for at a time.
+const eventPageSize = 50
+
+// EventHandler receives one event. It runs on the subscriber's goroutine and
+// must not call the subscriber.
+type EventHandler func(event models.SuiEventResponse)
+
+// EventSubscriber follows the events a Move module emits by polling
+// suix_queryEvents from a cursor, and hands each new event, in order, to the
+// handlers registered for its name. It starts at the newest event: history
+// from before Start is not replayed.
+type EventSubscriber struct {
+	filter   models.SuiEventFilter
+	interval time.Duration
+	query    func(filter models.SuiEventFilter, cursor *string, limit *uint64, descending bool) (models.PaginatedEventsResponse, error)
+
+	mu       sync.Mutex
+	handlers map[string][]EventHandler // Event name, e.g. "ListingCreated" -> handlers
+	cursor   *string                   // Last event seen, "txDigest:eventSeq"
+	primed   bool                      // cursor has been placed at the newest event
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewModuleEventSubscriber creates a subscriber for the events defined in
+// packageID::module, polled every interval.
+func NewModuleEventSubscriber(client *SuiClient, packageID, module string, interval time.Duration) *EventSubscriber {
+	return &EventSubscriber{
+		filter:   models.SuiEventFilter{"MoveEventModule": map[string]interface{}{"package": packageID, "module": module}},
+		interval: interval,
+		query:    client.QueryEvents,
+		handlers: make(map[string][]EventHandler),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Handle registers handler for the events named name (the struct name,
+// without package, module or type arguments).
+func (s *EventSubscriber) Handle(name string, handler EventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = append(s.handlers[name], handler)
+}
+
+// Start polls in the background until Stop.
+func (s *EventSubscriber) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Poll(); err != nil {
+				utils.LogWarnf("EventSubscriber: Polling %v failed: %v", s.filter, err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background polling.
+func (s *EventSubscriber) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Poll delivers every event emitted since the last poll. The first poll only
+// finds the newest event to start from.
+func (s *EventSubscriber) Poll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.primed {
+		limit := uint64(1)
+		resp, err := s.query(s.filter, nil, &limit, true)
+		if err != nil {
+			return err
+		}
+		if len(resp.Data) > 0 {
+			s.cursor = eventCursor(resp.Data[0].Id)
+		}
+		s.primed = true
+		return nil
+	}
+	for {
+		limit := uint64(eventPageSize)
+		resp, err := s.query(s.filter, s.cursor, &limit, false)
+		if err != nil {
+			return err
+		}
+		for _, event := range resp.Data {
+			for _, handler := range s.handlers[eventName(event.Type)] {
+				handler(event)
+			}
+			s.cursor = eventCursor(event.Id)
+		}
+		if !resp.HasNextPage || len(resp.Data) == 0 {
+			return nil
+		}
+	}
+}
+
+func eventCursor(id models.EventId) *string {
+	cursor := fmt.Sprintf("%s:%s", id.TxDigest, id.EventSeq)
+	return &cursor
+}
+
+// eventName returns the struct name of a full event type such as
+// "0x2::marketplace::ListingCreated<0x2::sui::SUI>".
+func eventName(eventType string) string {
+	if i := strings.IndexByte(eventType, '<'); i >= 0 {
+		eventType = eventType[:i]
+	}
+	if i := strings.LastIndex(eventType, "::"); i >= 0 {
+		eventType = eventType[i+2:]
+	}
+	return eventType
+}
@@ -0,0 +1,60 @@
+package sui
+
+import (
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+)
+
+func TestEventSubscriberDeliversNewEventsInOrder(t *testing.T) {
+	history := []models.SuiEventResponse{
+		{Id: models.EventId{TxDigest: "a", EventSeq: "0"}, Type: "0x1::marketplace::ListingCreated"},
+	}
+	var cursors []string
+	s := &EventSubscriber{
+		handlers: make(map[string][]EventHandler),
+		query: func(filter models.SuiEventFilter, cursor *string, limit *uint64, descending bool) (models.PaginatedEventsResponse, error) {
+			if descending {
+				return models.PaginatedEventsResponse{Data: history[len(history)-1:]}, nil
+			}
+			cursors = append(cursors, *cursor)
+			start := 0
+			for i, event := range history {
+				if *eventCursor(event.Id) == *cursor {
+					start = i + 1
+				}
+			}
+			end := start + int(*limit)
+			if end > len(history) {
+				end = len(history)
+			}
+			return models.PaginatedEventsResponse{Data: history[start:end], HasNextPage: end < len(history)}, nil
+		},
+	}
+	var seen []string
+	s.Handle("ListingCreated", func(event models.SuiEventResponse) { seen = append(seen, event.Id.TxDigest) })
+	s.Handle("NFTPurchased", func(event models.SuiEventResponse) { seen = append(seen, "bought "+event.Id.TxDigest) })
+
+	if err := s.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 0 {
+		t.Fatalf("history was replayed: %v", seen)
+	}
+	for _, digest := range []string{"b", "c"} {
+		history = append(history, models.SuiEventResponse{Id: models.EventId{TxDigest: digest, EventSeq: "0"}, Type: "0x1::marketplace::ListingCreated"})
+	}
+	history = append(history, models.SuiEventResponse{Id: models.EventId{TxDigest: "d", EventSeq: "1"}, Type: "0x1::marketplace::NFTPurchased<0x2::sui::SUI>"})
+	if err := s.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 3 || seen[0] != "b" || seen[1] != "c" || seen[2] != "bought d" {
+		t.Fatalf("delivered %v", seen)
+	}
+	if last := cursors[len(cursors)-1]; last != "d:1" {
+		t.Fatalf("last cursor = %s, want d:1", last)
+	}
+}
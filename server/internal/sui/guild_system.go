@@ -101,15 +101,15 @@ func (s *GuildSystemSuiService) AddMember(
 	requesterAddress string, // Signer of the transaction (e.g., guild officer)
 	guildObjectID string, // The ID of the guild object
 	playerAddress string, // The address of the player to be added
-	requesterGasObjectID string,
+	requesterGasObjectID string, // Empty lets the node pick one of the requester's coins
 	gasBudget uint64,
 ) (models.TxnMetaData, error) {
 	functionName := "add_member"
 	utils.LogInfof("GuildSystemSuiService: User %s preparing to add player %s to guild %s. GasObject: %s, GasBudget: %d",
 		requesterAddress, playerAddress, guildObjectID, requesterGasObjectID, gasBudget)
 
-	if requesterAddress == "" || guildObjectID == "" || playerAddress == "" || requesterGasObjectID == "" {
-		errMsg := "requesterAddress, guildObjectID, playerAddress, and requesterGasObjectID must be provided for AddMember"
+	if requesterAddress == "" || guildObjectID == "" || playerAddress == "" {
+		errMsg := "requesterAddress, guildObjectID and playerAddress must be provided for AddMember"
 		utils.LogError("GuildSystemSuiService: " + errMsg)
 		return models.TxnMetaData{}, fmt.Errorf(errMsg)
 	}
@@ -145,15 +145,15 @@ func (s *GuildSystemSuiService) RemoveMember(
 	requesterAddress string, // Signer (e.g., guild officer)
 	guildObjectID string,
 	playerAddress string, // Player to remove
-	requesterGasObjectID string,
+	requesterGasObjectID string, // Empty lets the node pick one of the requester's coins
 	gasBudget uint64,
 ) (models.TxnMetaData, error) {
 	functionName := "remove_member"
 	utils.LogInfof("GuildSystemSuiService: User %s preparing to remove player %s from guild %s. GasObject: %s, GasBudget: %d",
 		requesterAddress, playerAddress, guildObjectID, requesterGasObjectID, gasBudget)
 
-	if requesterAddress == "" || guildObjectID == "" || playerAddress == "" || requesterGasObjectID == "" {
-		errMsg := "requesterAddress, guildObjectID, playerAddress, and requesterGasObjectID must be provided for RemoveMember"
+	if requesterAddress == "" || guildObjectID == "" || playerAddress == "" {
+		errMsg := "requesterAddress, guildObjectID and playerAddress must be provided for RemoveMember"
 		utils.LogError("GuildSystemSuiService: " + errMsg)
 		return models.TxnMetaData{}, fmt.Errorf(errMsg)
 	}
@@ -330,3 +330,57 @@ func (s *GuildSystemSuiService) ManageGuildBank(
 	// TODO: Add error handling for MoveCall
 	return s.suiClient.MoveCall(officerAddress, s.packageID, s.moduleName, functionName, typeArgs, callArgs, officerGasObjectID, gasBudget)
 }
+
+// ClaimTerritory prepares a transaction in which a guild leader or officer claims
+// a world zone. The chain only records the claim (a TerritoryClaimed event); the
+// game server decides whether it is granted or contested once it has executed.
+func (s *GuildSystemSuiService) ClaimTerritory(
+	claimantAddress string, // Leader or officer of the guild (signer)
+	guildObjectID string,
+	zoneID string,
+	claimantGasObjectID string,
+	gasBudget uint64,
+) (models.TxnMetaData, error) {
+	functionName := "claim_territory"
+	utils.LogInfof("GuildSystemSuiService: %s preparing to claim zone '%s' for guild %s. GasObject: %s, GasBudget: %d",
+		claimantAddress, zoneID, guildObjectID, claimantGasObjectID, gasBudget)
+
+	if claimantAddress == "" || guildObjectID == "" || zoneID == "" || claimantGasObjectID == "" {
+		errMsg := "claimantAddress, guildObjectID, zoneID, and claimantGasObjectID must be provided for ClaimTerritory"
+		utils.LogError("GuildSystemSuiService: " + errMsg)
+		return models.TxnMetaData{}, fmt.Errorf(errMsg)
+	}
+
+	callArgs := []interface{}{guildObjectID, zoneID}
+	txBlockResponse, err := s.suiClient.MoveCall(claimantAddress, s.packageID, s.moduleName, functionName, []string{}, callArgs, claimantGasObjectID, gasBudget)
+	if err != nil {
+		utils.LogErrorf("GuildSystemSuiService: Error preparing ClaimTerritory transaction for zone '%s': %v", zoneID, err)
+		return models.TxnMetaData{}, fmt.Errorf("MoveCall failed for ClaimTerritory ('%s'): %w", zoneID, err)
+	}
+	return txBlockResponse, nil
+}
+
+// VerifyTerritoryClaim checks that txDigest is a successful claim_territory
+// transaction for zoneID from this package and returns the claiming guild's ID.
+func (s *GuildSystemSuiService) VerifyTerritoryClaim(txDigest, zoneID string) (string, error) {
+	tx, err := s.suiClient.GetTransactionBlock(txDigest)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch claim transaction %s: %w", txDigest, err)
+	}
+	if tx.Effects.Status.Status != "success" {
+		return "", fmt.Errorf("claim transaction %s did not succeed (status %q)", txDigest, tx.Effects.Status.Status)
+	}
+	eventType := fmt.Sprintf("%s::%s::TerritoryClaimed", s.packageID, s.moduleName)
+	for _, event := range tx.Events {
+		if event.Type != eventType {
+			continue
+		}
+		claimedZone, _ := event.ParsedJson["zone_id"].(string)
+		guildID, _ := event.ParsedJson["guild_id"].(string)
+		if claimedZone == zoneID && guildID != "" {
+			utils.LogInfof("GuildSystemSuiService: Verified claim of zone '%s' by guild %s (tx %s).", zoneID, guildID, txDigest)
+			return guildID, nil
+		}
+	}
+	return "", fmt.Errorf("transaction %s has no TerritoryClaimed event for zone '%s'", txDigest, zoneID)
+}
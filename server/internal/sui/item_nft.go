@@ -42,8 +42,13 @@ func NewItemNFTService(suiClient *SuiClient, packageID, moduleName, adminAddress
 // MintItemNFT prepares a transaction to mint a new Item NFT.
 // Returns TransactionBlockResponse for subsequent signing and execution by the admin/minter.
 func (s *ItemNFTService) MintItemNFT(itemType string, metadata map[string]interface{}, ownerAddress string, gasBudget uint64) (models.TxnMetaData, error) {
+	return s.mintItemNFTFrom(s.adminAddress, s.adminGasObjID, itemType, metadata, ownerAddress, gasBudget)
+}
+
+// mintItemNFTFrom prepares the mint with sender paying from gasObjectID.
+func (s *ItemNFTService) mintItemNFTFrom(sender, gasObjectID, itemType string, metadata map[string]interface{}, ownerAddress string, gasBudget uint64) (models.TxnMetaData, error) {
 	functionName := "mint_item_nft" // Assumed Move function name
-	utils.LogInfof("ItemNFTService: Preparing to mint Item NFT of type %s for %s by admin %s.", itemType, ownerAddress, s.adminAddress)
+	utils.LogInfof("ItemNFTService: Preparing to mint Item NFT of type %s for %s by admin %s.", itemType, ownerAddress, sender)
 
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
@@ -58,19 +63,19 @@ func (s *ItemNFTService) MintItemNFT(itemType string, metadata map[string]interf
 	}
 	typeArgs := []string{} // If mint function is generic
 
-	if s.adminAddress == "" || s.adminGasObjID == "" {
+	if sender == "" || gasObjectID == "" {
 		utils.LogError("ItemNFTService: adminAddress and adminGasObjID must be configured for minting")
 		return models.TxnMetaData{}, fmt.Errorf("adminAddress and adminGasObjID must be configured for minting")
 	}
 
 	txBlockResponse, err := s.suiClient.MoveCall(
-		s.adminAddress, // Minter address
+		sender, // Minter address
 		s.packageID,
 		s.moduleName,
 		functionName,
 		typeArgs,
 		callArgs,
-		s.adminGasObjID, // Gas object owned by the minter
+		gasObjectID, // Gas object owned by the minter
 		gasBudget,
 	)
 
@@ -82,12 +87,13 @@ func (s *ItemNFTService) MintItemNFT(itemType string, metadata map[string]interf
 	return txBlockResponse, nil
 }
 
-// MintItemNFTAndExecute is an EXAMPLE function demonstrating the full flow:
-// 1. Prepare Transaction (using MintItemNFT)
-// 2. Sign Transaction (using conceptual server-side SignTransactionBytesWithServerKey)
-// 3. Execute Transaction
-// It requires serverPrivateKeyHex to be configured.
-// WARNING: This is for demonstration. Private key management needs to be secure in production.
+// MintItemNFTAndExecute mints an Item NFT on chain: the transaction is
+// prepared, dry-run to catch failures (e.g. an insufficient payment coin)
+// before signing, signed and executed. If an object changed version between
+// preparation and execution, the transaction is rebuilt once.
+// It is signed with serverPrivateKeyHex, the admin's key, unless the client has
+// a signer pool; then any free pool signer mints it.
+// WARNING: Private key management needs to be secure in production.
 func (s *ItemNFTService) MintItemNFTAndExecute(
 	itemType string,
 	metadata map[string]interface{},
@@ -97,40 +103,17 @@ func (s *ItemNFTService) MintItemNFTAndExecute(
 ) (models.SuiTransactionBlockResponse, error) {
 	utils.LogInfof("ItemNFTService: Attempting to mint and execute Item NFT of type %s for %s", itemType, ownerAddress)
 
-	// 1. Prepare the transaction
-	txBlockResponse, err := s.MintItemNFT(itemType, metadata, ownerAddress, gasBudget)
+	executeResponse, err := s.suiClient.executeFromAnyServer(s.adminAddress, s.adminGasObjID, gasBudget, serverPrivateKeyHex, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return s.mintItemNFTFrom(sender, gasObjectID, itemType, metadata, ownerAddress, gasBudget)
+	})
 	if err != nil {
-		// MintItemNFT already logs the specific error
-		return models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to prepare MintItemNFT transaction: %w", err)
-	}
-
-	if txBlockResponse.TxBytes == "" {
-		utils.LogError("ItemNFTService: Prepared transaction resulted in empty TxBytes.")
-		return models.SuiTransactionBlockResponse{}, fmt.Errorf("prepared transaction resulted in empty TxBytes")
+		if IsVersionConflict(err) {
+			utils.LogErrorf("ItemNFTService: MintItemNFT (Type: %s) kept hitting object version conflicts: %v", itemType, err)
+		} else {
+			utils.LogErrorf("ItemNFTService: MintItemNFT transaction failed (Type: %s for %s): %v", itemType, ownerAddress, err)
+		}
+		return executeResponse, err
 	}
-	utils.LogDebugf("ItemNFTService: TxBytes for signing: %s", txBlockResponse.TxBytes)
-
-	// 2. Sign the transaction (using conceptual server-side signing)
-	//    In a real scenario, ensure serverPrivateKeyHex is securely managed.
-	signature, err := SignTransactionBytesWithServerKey(txBlockResponse.TxBytes, serverPrivateKeyHex)
-	if err != nil {
-		utils.LogErrorf("ItemNFTService: Failed to sign transaction for MintItemNFT (Type: %s): %v", itemType, err)
-		return models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to sign transaction: %w", err)
-	}
-	utils.LogDebugf("ItemNFTService: Conceptual signature obtained: %s", signature)
-
-	// 3. Execute the transaction
-	utils.LogInfo("ItemNFTService: Executing transaction...")
-	executeResponse, err := s.suiClient.ExecuteTransactionBlock(txBlockResponse.TxBytes, []string{signature})
-	if err != nil {
-		utils.LogErrorf("ItemNFTService: Failed to execute MintItemNFT transaction (Type: %s): %v", itemType, err)
-		// Log more details from executeResponse if available, e.g., executeResponse.Error
-		return models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to execute transaction: %w", err)
-	}
-
-	// Check for errors in execution effects
-	// The sui-go-sdk's ExecuteTransactionBlock already returns an error if the RPC call itself failed.
-	// TODO: Fix effects structure access based on actual sui-go-sdk models
 
 	utils.LogInfof("ItemNFTService: MintItemNFT transaction executed successfully (Type: %s for %s). Digest: %s",
 		itemType, ownerAddress, executeResponse.Digest)
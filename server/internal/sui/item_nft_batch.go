@@ -0,0 +1,68 @@
+package sui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// ItemMint is one item NFT minted by MintItemNFTBatch.
+type ItemMint struct {
+	ItemType string
+	Metadata map[string]interface{}
+	Owner    string // Recipient of the new NFT
+}
+
+// MintItemNFTBatch prepares one programmable transaction that mints every
+// item, in order, with the admin address and gas object.
+func (s *ItemNFTService) MintItemNFTBatch(mints []ItemMint, gasBudget uint64) (models.TxnMetaData, error) {
+	return s.mintItemNFTBatchFrom(s.adminAddress, s.adminGasObjID, mints, gasBudget)
+}
+
+// mintItemNFTBatchFrom prepares the batch with sender paying from gasObjectID.
+func (s *ItemNFTService) mintItemNFTBatchFrom(sender, gasObjectID string, mints []ItemMint, gasBudget uint64) (models.TxnMetaData, error) {
+	if len(mints) == 0 {
+		return models.TxnMetaData{}, errors.New("no items to mint")
+	}
+	if sender == "" || gasObjectID == "" {
+		return models.TxnMetaData{}, fmt.Errorf("adminAddress and adminGasObjID must be configured for minting")
+	}
+	calls := make([]models.MoveCallRequest, len(mints))
+	for i, mint := range mints {
+		metadataJSON, err := json.Marshal(mint.Metadata)
+		if err != nil {
+			return models.TxnMetaData{}, fmt.Errorf("failed to marshal metadata of item %d to JSON: %w", i, err)
+		}
+		calls[i] = models.MoveCallRequest{
+			PackageObjectId: s.packageID,
+			Module:          s.moduleName,
+			Function:        "mint_item_nft",
+			TypeArguments:   []interface{}{},
+			Arguments:       []interface{}{mint.ItemType, string(metadataJSON), mint.Owner},
+		}
+	}
+	txMeta, err := s.suiClient.BatchMoveCall(sender, calls, gasObjectID, gasBudget)
+	if err != nil {
+		return models.TxnMetaData{}, fmt.Errorf("BatchTransaction failed for %d mints: %w", len(mints), err)
+	}
+	return txMeta, nil
+}
+
+// MintItemNFTBatchAndExecute mints every item in one transaction: it is
+// prepared, dry-run, signed with serverPrivateKeyHex (or by a free signer of
+// the client's signer pool) and executed, and rebuilt once on an object
+// version conflict. Either every item is minted or none is.
+func (s *ItemNFTService) MintItemNFTBatchAndExecute(mints []ItemMint, gasBudget uint64, serverPrivateKeyHex string) (models.SuiTransactionBlockResponse, error) {
+	response, err := s.suiClient.executeFromAnyServer(s.adminAddress, s.adminGasObjID, gasBudget, serverPrivateKeyHex, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return s.mintItemNFTBatchFrom(sender, gasObjectID, mints, gasBudget)
+	})
+	if err != nil {
+		utils.LogErrorf("ItemNFTService: Batch of %d mints failed: %v", len(mints), err)
+		return response, err
+	}
+	utils.LogInfof("ItemNFTService: Minted %d items in one transaction. Digest: %s", len(mints), response.Digest)
+	return response, nil
+}
@@ -0,0 +1,43 @@
+package sui
+
+import (
+	"context"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// batchAPI records the batched transaction it is asked to build.
+type batchAPI struct {
+	sui.ISuiAPI
+	request models.BatchTransactionRequest
+}
+
+func (a *batchAPI) BatchTransaction(ctx context.Context, req models.BatchTransactionRequest) (models.BatchTransactionResponse, error) {
+	a.request = req
+	return models.BatchTransactionResponse{TxBytes: "batch"}, nil
+}
+
+func TestMintItemNFTBatch(t *testing.T) {
+	api := &batchAPI{}
+	items := NewItemNFTService(NewSuiClientWithAPI("stub", api), "0x5", "item", "0xminter", "0xgas")
+	meta, err := items.MintItemNFTBatch([]ItemMint{
+		{ItemType: "badge", Owner: "0xa1"},
+		{ItemType: "cape", Owner: "0xb2", Metadata: map[string]interface{}{"event": "launch"}},
+	}, 1000)
+	if err != nil || meta.TxBytes != "batch" {
+		t.Fatalf("MintItemNFTBatch = %+v, %v", meta, err)
+	}
+	req := api.request
+	if req.Signer != "0xminter" || *req.Gas != "0xgas" || req.GasBudget != "1000" || len(req.RPCTransactionRequestParams) != 2 {
+		t.Fatalf("request = %+v", req)
+	}
+	call := req.RPCTransactionRequestParams[1].MoveCallRequestParams
+	if call.Function != "mint_item_nft" || call.Arguments[0] != "cape" || call.Arguments[1] != `{"event":"launch"}` || call.Arguments[2] != "0xb2" {
+		t.Errorf("second call = %+v", call)
+	}
+	if _, err := items.MintItemNFTBatch(nil, 1000); err == nil {
+		t.Error("an empty batch was prepared")
+	}
+}
@@ -2,9 +2,11 @@ package sui
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
+	"sync"
 
 	// "github.com/tidwall/gjson" // Will be removed
 	"github.com/block-vision/sui-go-sdk/models"
@@ -50,10 +52,16 @@ type PurchaseResult struct {
 	PurchaseTime      uint64 `json:"purchase_time"`
 }
 
+// ErrListingNotActive is returned for an NFT the marketplace holds no listing of.
+var ErrListingNotActive = errors.New("no active listing for that NFT")
+
 // MarketSuiService interacts with the Marketplace contract on the Sui blockchain
 type MarketSuiService struct {
 	client *SuiClient
 	config MarketplaceConfig
+
+	tableMu sync.Mutex
+	tableID string // The marketplace's active_listings table, once read
 }
 
 // NewMarketSuiService creates a new MarketSuiService
@@ -210,6 +218,49 @@ func (s *MarketSuiService) PurchaseNFT(
 	return txBlockResponse, nil
 }
 
+// PurchaseNFTWithFee prepares a purchase like PurchaseNFT in which the buyer
+// also pays the server's fee to treasury: payment must cover the price and
+// fee, and the module's purchase_nft_with_fee sends fee MIST of it to treasury.
+func (s *MarketSuiService) PurchaseNFTWithFee(
+	buyerAddress string,
+	listingObjectID string,
+	paymentCoinID string,
+	nftType string,
+	coinType string,
+	fee uint64,
+	treasury string,
+	gasObjectID string,
+	gasBudget uint64,
+) (models.TxnMetaData, error) {
+	utils.LogInfof("MarketSuiService: Player %s purchasing from listing %s with coin %s and a fee of %d to %s.",
+		buyerAddress, listingObjectID, paymentCoinID, fee, treasury)
+	if gasObjectID == "" || paymentCoinID == "" || listingObjectID == "" || treasury == "" {
+		return models.TxnMetaData{}, fmt.Errorf("gasObjectID, paymentCoinID, listingObjectID and treasury must be provided for PurchaseNFTWithFee")
+	}
+	arguments := []interface{}{
+		s.config.MarketplaceObjectID,
+		listingObjectID,
+		paymentCoinID,
+		strconv.FormatUint(fee, 10),
+		treasury,
+	}
+	txBlockResponse, err := s.client.MoveCall(
+		buyerAddress,
+		s.config.PackageID,
+		s.config.Module,
+		"purchase_nft_with_fee",
+		[]string{nftType, coinType},
+		arguments,
+		gasObjectID,
+		gasBudget,
+	)
+	if err != nil {
+		utils.LogErrorf("MarketSuiService: MoveCall for PurchaseNFTWithFee failed for listing %s by %s: %v", listingObjectID, buyerAddress, err)
+		return models.TxnMetaData{}, fmt.Errorf("MoveCall failed for PurchaseNFTWithFee (listing: %s): %w", listingObjectID, err)
+	}
+	return txBlockResponse, nil
+}
+
 // CancelListing prepares a transaction to cancel an NFT listing.
 // Returns transaction bytes. Requires seller's gas object.
 // nftType and coinType are the generic types used when the item was listed.
@@ -292,45 +343,17 @@ func (s *MarketSuiService) GetListings(eventType string, limit int, cursor *stri
 		// For this example, we assume ParsedJson is a map[string]interface{} after JSON unmarshal.
 		parsedJSON := event.ParsedJson
 		if parsedJSON == nil {
-			utils.LogWarnf("MarketSuiService: Could not parse event JSON for event ID %s:%d", event.Id.TxDigest, event.Id.EventSeq)
+			utils.LogWarnf("MarketSuiService: Could not parse event JSON for event ID %s:%s", event.Id.TxDigest, event.Id.EventSeq)
 			continue
 		}
-
-		var listing ListingInfo
-		if id, ok := parsedJSON["listing_id"].(string); ok {
-			listing.ID = id
-		}
-		if seller, ok := parsedJSON["seller"].(string); ok {
-			listing.Seller = seller
-		}
-		if nftID, ok := parsedJSON["nft_id"].(string); ok {
-			listing.NFTID = nftID
-		}
-		if nftType, ok := parsedJSON["nft_type"].(string); ok { // Assuming nft_type is part of event
-			listing.NFTType = nftType
-		}
-		if priceStr, ok := parsedJSON["price"].(string); ok { // Price might be string in event
-			if p, err := strconv.ParseUint(priceStr, 10, 64); err == nil {
-				listing.Price = p
-			}
-		}
-		if currency, ok := parsedJSON["currency"].(string); ok { // Assuming currency is part of event
-			listing.Currency = currency
-		}
-		if createdAtStr, ok := parsedJSON["created_at"].(string); ok { // Timestamp might be string
-			if ts, err := strconv.ParseUint(createdAtStr, 10, 64); err == nil {
-				listing.CreatedAt = ts
-			}
-		}
-		// Description and ExpiresAt might not be in simple ListingCreatedEvent, could be on Listing object itself.
-		listings = append(listings, listing)
+		listings = append(listings, listingFromEvent(parsedJSON))
 	}
 
 	var nextCursorStr *string
 	if sdkResponse.HasNextPage && sdkResponse.NextCursor.TxDigest != "" {
 		// Construct string cursor for the next call, if our client.QueryEvents expects string
 		// Or pass sdkResponse.NextCursor directly if client.QueryEvents is updated
-		strCursor := fmt.Sprintf("%s:%d", sdkResponse.NextCursor.TxDigest, sdkResponse.NextCursor.EventSeq)
+		strCursor := fmt.Sprintf("%s:%s", sdkResponse.NextCursor.TxDigest, sdkResponse.NextCursor.EventSeq)
 		nextCursorStr = &strCursor
 	}
 
@@ -338,6 +361,31 @@ func (s *MarketSuiService) GetListings(eventType string, limit int, cursor *stri
 	return listings, nextCursorStr, nil
 }
 
+// listingFromEvent reads a ListingCreated event's parsed JSON. Numbers come
+// back as strings.
+func listingFromEvent(parsedJSON map[string]interface{}) ListingInfo {
+	var listing ListingInfo
+	listing.ID, _ = parsedJSON["listing_id"].(string)
+	listing.Seller, _ = parsedJSON["seller"].(string)
+	listing.NFTID, _ = parsedJSON["nft_id"].(string)
+	listing.NFTType, _ = parsedJSON["nft_type"].(string)
+	listing.Currency, _ = parsedJSON["currency"].(string)
+	if priceStr, ok := parsedJSON["price"].(string); ok {
+		if p, err := strconv.ParseUint(priceStr, 10, 64); err == nil {
+			listing.Price = p
+		}
+	}
+	createdAtStr, ok := parsedJSON["created_at"].(string)
+	if !ok {
+		createdAtStr, _ = parsedJSON["timestamp"].(string) // The field the marketplace module's event uses
+	}
+	if ts, err := strconv.ParseUint(createdAtStr, 10, 64); err == nil {
+		listing.CreatedAt = ts
+	}
+	// Description and ExpiresAt are not in the event; they are on the Listing object itself.
+	return listing
+}
+
 // GetListingInfo retrieves detailed information about a specific listing object.
 func (s *MarketSuiService) GetListingInfo(listingObjectID string) (*ListingInfo, error) {
 	utils.LogInfof("MarketSuiService: Fetching listing info for object ID %s", listingObjectID)
@@ -386,11 +434,135 @@ func (s *MarketSuiService) GetListingInfo(listingObjectID string) (*ListingInfo,
 			listing.CreatedAt = ts
 		}
 	}
-	// Populate ExpiresAt and Description if they exist in fields
+	listing.ExpiresAt = fieldOptionalUint(fields["expires_at"])
+	listing.Description, _ = fields["description"].(string)
 
 	return listing, nil
 }
 
+// GetActiveListing reads the listing of nftID from the marketplace's
+// active_listings table, which is keyed by NFT ID. Unlike the ListingCreated
+// event, the entry carries the listing's expiry and description. It returns
+// ErrListingNotActive once the listing is sold, cancelled or removed.
+func (s *MarketSuiService) GetActiveListing(nftID string) (*ListingInfo, error) {
+	tableID, err := s.activeListingsTable()
+	if err != nil {
+		return nil, err
+	}
+	objectResponse, err := s.client.GetDynamicFieldObject(tableID, "0x2::object::ID", nftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the listing of NFT %s: %w", nftID, err)
+	}
+	if objectResponse.Data == nil || objectResponse.Data.Content == nil {
+		return nil, ErrListingNotActive
+	}
+	value, _ := objectResponse.Data.Content.Fields["value"].(map[string]interface{})
+	fields, _ := value["fields"].(map[string]interface{})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("could not parse the listing of NFT %s", nftID)
+	}
+	listing := listingFromFields(fields)
+	return &listing, nil
+}
+
+// activeListingsTable returns the ID of the marketplace's active_listings table.
+func (s *MarketSuiService) activeListingsTable() (string, error) {
+	s.tableMu.Lock()
+	defer s.tableMu.Unlock()
+	if s.tableID != "" {
+		return s.tableID, nil
+	}
+	objectResponse, err := s.client.GetObject(s.config.MarketplaceObjectID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get marketplace object %s: %w", s.config.MarketplaceObjectID, err)
+	}
+	if objectResponse.Data == nil || objectResponse.Data.Content == nil {
+		return "", fmt.Errorf("marketplace object %s not found or has no content", s.config.MarketplaceObjectID)
+	}
+	table, _ := objectResponse.Data.Content.Fields["active_listings"].(map[string]interface{})
+	tableFields, _ := table["fields"].(map[string]interface{})
+	uid, _ := tableFields["id"].(map[string]interface{})
+	tableID, _ := uid["id"].(string)
+	if tableID == "" {
+		return "", fmt.Errorf("marketplace object %s has no active_listings table", s.config.MarketplaceObjectID)
+	}
+	s.tableID = tableID
+	return tableID, nil
+}
+
+// listingFromFields reads a Listing struct's fields as the RPC returns them.
+func listingFromFields(fields map[string]interface{}) ListingInfo {
+	var listing ListingInfo
+	if uid, ok := fields["id"].(map[string]interface{}); ok {
+		listing.ID, _ = uid["id"].(string)
+	}
+	listing.Seller, _ = fields["seller"].(string)
+	listing.NFTID, _ = fields["nft_id"].(string)
+	listing.NFTType, _ = fields["nft_type"].(string)
+	listing.Price = fieldUint(fields["price"])
+	listing.Currency, _ = fields["currency"].(string)
+	listing.CreatedAt = fieldUint(fields["created_at"])
+	listing.ExpiresAt = fieldOptionalUint(fields["expires_at"])
+	listing.Description, _ = fields["description"].(string)
+	return listing
+}
+
+// fieldOptionalUint reads an Option<u64> field, which is null when empty.
+func fieldOptionalUint(value interface{}) *uint64 {
+	if value == nil {
+		return nil
+	}
+	n := fieldUint(value)
+	return &n
+}
+
+// ListedNFTType returns the Move type of the NFT a listing holds. A listing's
+// nft_type is what the seller wrote; this is the type the chain stored.
+func (s *MarketSuiService) ListedNFTType(listingObjectID string) (string, error) {
+	objectResponse, err := s.client.GetDynamicFieldObject(listingObjectID, "vector<u8>", []int{'n', 'f', 't'}) // Not []byte, which marshals as base64
+	if err != nil {
+		return "", fmt.Errorf("failed to read the NFT of listing %s: %w", listingObjectID, err)
+	}
+	if objectResponse.Data == nil || objectResponse.Data.Content == nil {
+		return "", fmt.Errorf("listing %s holds no NFT", listingObjectID)
+	}
+	value, _ := objectResponse.Data.Content.Fields["value"].(map[string]interface{})
+	nftType, _ := value["type"].(string)
+	if nftType == "" {
+		return "", fmt.Errorf("could not read the NFT type of listing %s", listingObjectID)
+	}
+	return nftType, nil
+}
+
+// RemoveExpiredListing prepares a transaction returning an expired listing's
+// NFT to its seller. Anyone may send it once the chain's epoch is past the
+// listing's expires_at. nftType is the NFT's Move type (see ListedNFTType).
+func (s *MarketSuiService) RemoveExpiredListing(
+	senderAddress string,
+	nftID string,
+	nftType string,
+	gasObjectID string,
+	gasBudget uint64,
+) (models.TxnMetaData, error) {
+	if gasObjectID == "" || nftID == "" || nftType == "" {
+		return models.TxnMetaData{}, fmt.Errorf("gasObjectID, nftID and nftType must be provided for RemoveExpiredListing")
+	}
+	txBlockResponse, err := s.client.MoveCall(
+		senderAddress,
+		s.config.PackageID,
+		s.config.Module,
+		"remove_expired_listing",
+		[]string{nftType},
+		[]interface{}{s.config.MarketplaceObjectID, nftID},
+		gasObjectID,
+		gasBudget,
+	)
+	if err != nil {
+		return models.TxnMetaData{}, fmt.Errorf("MoveCall failed for RemoveExpiredListing (NFT: %s): %w", nftID, err)
+	}
+	return txBlockResponse, nil
+}
+
 // GetMarketplaceInfo retrieves marketplace statistics from the shared marketplace object.
 func (s *MarketSuiService) GetMarketplaceInfo() (*MarketplaceInfo, error) {
 	utils.LogInfo("MarketSuiService: Fetching marketplace information")
@@ -562,7 +734,7 @@ func (s *MarketSuiService) GetMarketplaceEvents(eventTypeFilter string, limit in
 	for _, eventData := range sdkResponse.Data {
 		parsedEvent, err := s.parseMarketplaceEvent(eventData)
 		if err != nil {
-			utils.LogWarnf("MarketSuiService: Could not parse event data for event ID %s:%d: %v", eventData.Id.TxDigest, eventData.Id.EventSeq, err)
+			utils.LogWarnf("MarketSuiService: Could not parse event data for event ID %s:%s: %v", eventData.Id.TxDigest, eventData.Id.EventSeq, err)
 			continue // Skip this event
 		}
 		parsedEvents = append(parsedEvents, parsedEvent)
@@ -570,7 +742,7 @@ func (s *MarketSuiService) GetMarketplaceEvents(eventTypeFilter string, limit in
 
 	var nextCursorStr *string
 	if sdkResponse.HasNextPage && sdkResponse.NextCursor.TxDigest != "" {
-		strCursor := fmt.Sprintf("%s:%d", sdkResponse.NextCursor.TxDigest, sdkResponse.NextCursor.EventSeq)
+		strCursor := fmt.Sprintf("%s:%s", sdkResponse.NextCursor.TxDigest, sdkResponse.NextCursor.EventSeq)
 		nextCursorStr = &strCursor
 		utils.LogDebugf("MarketSuiService: Next cursor for events: %s", strCursor)
 	}
@@ -0,0 +1,266 @@
+package sui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// expiryBackfillEvents bounds how many past ListingCreated events are read
+// for listings created before the expiry worker started.
+const expiryBackfillEvents = 500
+
+// ListingExpiry is how a MarketplaceServiceManager enforces listing durations.
+//
+// The contract stores a listing's expiry as its creation epoch plus
+// durationHours*3600 and compares it with the current epoch, so the chain
+// only lets a listing be removed long after its duration. The manager
+// therefore hides a listing once its duration has passed in real time,
+// measured from the ListingCreated event, and removes it on-chain once the
+// epoch is past its expires_at.
+type ListingExpiry struct {
+	Epochs    *EpochTracker        // Current epoch, for on-chain removal; nil never removes
+	OnExpired func(ExpiredListing) // Told once per listing when it is hidden, e.g. to mail the seller; may be nil
+	Cleanup   *CleanupSigner       // Sends remove_expired_listing; nil only hides listings
+	Leader    func() bool          // Whether this instance calls OnExpired and removes listings; every instance hides them. Nil always does
+}
+
+// CleanupSigner is the server address that sends remove_expired_listing. Any
+// address may; the NFT goes back to the seller.
+type CleanupSigner struct {
+	Address     string
+	GasObjectID string
+	GasBudget   uint64
+	PrivateKey  func() (string, error) // Hex key of Address, read for each transaction
+}
+
+// ExpiredListing is a listing whose duration has passed.
+type ExpiredListing struct {
+	Listing   ListingInfo
+	ExpiredAt time.Time // When its duration ended
+}
+
+// expiringListing is an open listing with an expiry.
+type expiringListing struct {
+	listing    ListingInfo // ExpiresAt and CreatedAt as the chain counts them
+	listedAt   time.Time   // Wall-clock time of the ListingCreated event
+	hidden     bool        // Expired and kept out of queries
+	triedEpoch uint64      // Epoch of the last removal attempt
+}
+
+// deadline is when the listing's duration ends in real time.
+func (l *expiringListing) deadline() time.Time {
+	seconds := *l.listing.ExpiresAt - l.listing.CreatedAt // durationHours*3600, whatever the unit of CreatedAt
+	return l.listedAt.Add(time.Duration(seconds) * time.Second)
+}
+
+// removable reports whether the chain accepts remove_expired_listing at epoch.
+func (l *expiringListing) removable(epoch uint64) bool {
+	return epoch > *l.listing.ExpiresAt
+}
+
+// expiryState is the manager's part for listing expiry.
+type expiryState struct {
+	mu       sync.Mutex
+	config   ListingExpiry
+	started  bool
+	listings map[string]*expiringListing // By listing ID
+}
+
+// UseExpiry starts enforcing listing durations as expiry describes, sweeping
+// every expiry_sweep_interval_seconds. It needs event sync, which reports new
+// listings; listings created before it are found among recent ListingCreated
+// events.
+func (m *MarketplaceServiceManager) UseExpiry(expiry ListingExpiry) {
+	if m.config.ExpirySweepInterval <= 0 {
+		utils.LogInfo("MarketplaceManager: Listing expiry is off (expiry_sweep_interval_seconds is 0).")
+		return
+	}
+	if m.events == nil {
+		utils.LogWarn("MarketplaceManager: Listing expiry needs caching and event sync. Expired listings will stay listed.")
+		return
+	}
+	m.expiry.mu.Lock()
+	defer m.expiry.mu.Unlock()
+	m.expiry.config = expiry
+	if m.expiry.started {
+		return
+	}
+	m.expiry.started = true
+	m.expiry.listings = make(map[string]*expiringListing)
+	m.events.Handle(eventListingCreated, m.onListingCreatedExpiry)
+	go m.expiryRoutine(time.Duration(m.config.ExpirySweepInterval) * time.Second)
+}
+
+// expiryRoutine finds the listings created before it started, then sweeps
+// every interval until Close.
+func (m *MarketplaceServiceManager) expiryRoutine(interval time.Duration) {
+	if err := m.backfillExpiry(); err != nil {
+		utils.LogWarnf("MarketplaceManager: Could not read past listings for expiry: %v", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.sweepExpired(time.Now())
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backfillExpiry tracks the still-open listings among recent ListingCreated events.
+func (m *MarketplaceServiceManager) backfillExpiry() error {
+	eventType := fmt.Sprintf("%s::%s::%s", m.config.PackageID, m.config.Module, eventListingCreated)
+	var cursor *string
+	for read := 0; read < expiryBackfillEvents; {
+		limit := uint64(50)
+		resp, err := m.client.QueryEvents(models.SuiEventFilter{"MoveEventType": eventType}, cursor, &limit, true)
+		if err != nil {
+			return err
+		}
+		for _, event := range resp.Data {
+			m.onListingCreatedExpiry(event)
+		}
+		read += len(resp.Data)
+		if !resp.HasNextPage || len(resp.Data) == 0 {
+			return nil
+		}
+		cursor = eventCursor(resp.NextCursor)
+	}
+	return nil
+}
+
+// onListingCreatedExpiry reads a new listing's expiry from the chain and
+// tracks it if it has one.
+func (m *MarketplaceServiceManager) onListingCreatedExpiry(event models.SuiEventResponse) {
+	created := listingFromEvent(event.ParsedJson)
+	if created.ID == "" || created.NFTID == "" {
+		return
+	}
+	listing, err := m.activeListing(created.NFTID)
+	if errors.Is(err, ErrListingNotActive) {
+		return
+	}
+	if err != nil {
+		utils.LogWarnf("MarketplaceManager: Could not read the expiry of listing %s: %v", created.ID, err)
+		return
+	}
+	if listing.ID != created.ID || listing.ExpiresAt == nil {
+		return // Relisted since, or listed without a duration
+	}
+	listedAt := time.Now()
+	if ms, err := strconv.ParseInt(event.TimestampMs, 10, 64); err == nil {
+		listedAt = time.UnixMilli(ms)
+	}
+	m.expiry.mu.Lock()
+	defer m.expiry.mu.Unlock()
+	if _, ok := m.expiry.listings[listing.ID]; !ok {
+		m.expiry.listings[listing.ID] = &expiringListing{listing: *listing, listedAt: listedAt}
+	}
+}
+
+// untrackExpiry stops tracking a closed listing and returns it, if it was tracked.
+func (m *MarketplaceServiceManager) untrackExpiry(listingID string) (ListingInfo, bool) {
+	m.expiry.mu.Lock()
+	defer m.expiry.mu.Unlock()
+	tracked, ok := m.expiry.listings[listingID]
+	if !ok {
+		return ListingInfo{}, false
+	}
+	delete(m.expiry.listings, listingID)
+	return tracked.listing, true
+}
+
+// sweepExpired hides the listings whose duration has passed by now and sends
+// the removal of those the chain lets go.
+func (m *MarketplaceServiceManager) sweepExpired(now time.Time) {
+	m.expiry.mu.Lock()
+	config := m.expiry.config
+	var due []*expiringListing
+	for _, tracked := range m.expiry.listings {
+		if !tracked.hidden && !now.Before(tracked.deadline()) {
+			due = append(due, tracked)
+		}
+	}
+	m.expiry.mu.Unlock()
+
+	// With several instances, each hides listings in its own cache, and only
+	// the leader tells sellers and removes listings on-chain.
+	leads := config.Leader == nil || config.Leader()
+
+	// A listing is re-read before it is hidden: it may have been extended,
+	// which emits no event.
+	for _, tracked := range due {
+		listing, err := m.activeListing(tracked.listing.NFTID)
+		if errors.Is(err, ErrListingNotActive) || (err == nil && listing.ID != tracked.listing.ID) {
+			m.untrackExpiry(tracked.listing.ID)
+			continue
+		}
+		if err != nil {
+			utils.LogWarnf("MarketplaceManager: Could not re-read expired listing %s: %v", tracked.listing.ID, err)
+			continue
+		}
+		m.expiry.mu.Lock()
+		if listing.ExpiresAt != nil {
+			tracked.listing.ExpiresAt = listing.ExpiresAt
+		}
+		expiredAt := tracked.deadline()
+		hide := listing.ExpiresAt != nil && !now.Before(expiredAt)
+		tracked.hidden = hide
+		m.expiry.mu.Unlock()
+		if !hide {
+			continue
+		}
+		m.closeListing(tracked.listing.ID, tracked.listing.Seller, "", "expired")
+		utils.LogInfof("MarketplaceManager: Listing %s of %s expired at %s and is hidden.", tracked.listing.ID, tracked.listing.Seller, expiredAt.Format(time.RFC3339))
+		if config.OnExpired != nil && leads {
+			config.OnExpired(ExpiredListing{Listing: tracked.listing, ExpiredAt: expiredAt})
+		}
+	}
+
+	epoch, known := config.Epochs.Current()
+	if config.Cleanup == nil || !known || !leads {
+		return
+	}
+	m.expiry.mu.Lock()
+	var removable []ListingInfo
+	for _, tracked := range m.expiry.listings {
+		if tracked.hidden && tracked.removable(epoch.Number) && tracked.triedEpoch != epoch.Number {
+			tracked.triedEpoch = epoch.Number // Once per epoch; the ListingExpired event untracks it
+			removable = append(removable, tracked.listing)
+		}
+	}
+	m.expiry.mu.Unlock()
+	for _, listing := range removable {
+		if err := m.removeExpired(config.Cleanup, listing); err != nil {
+			utils.LogWarnf("MarketplaceManager: Could not remove expired listing %s: %v", listing.ID, err)
+		}
+	}
+}
+
+// removeExpired sends remove_expired_listing for listing, returning its NFT to the seller.
+func (m *MarketplaceServiceManager) removeExpired(signer *CleanupSigner, listing ListingInfo) error {
+	nftType, err := m.marketService.ListedNFTType(listing.ID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := signer.PrivateKey()
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.executeFromAnyServer(signer.Address, signer.GasObjectID, signer.GasBudget, privateKey, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return m.marketService.RemoveExpiredListing(sender, listing.NFTID, nftType, gasObjectID, signer.GasBudget)
+	})
+	if err != nil {
+		return err
+	}
+	utils.LogInfof("MarketplaceManager: Removed expired listing %s in %s; its NFT is back with %s.", listing.ID, resp.Digest, listing.Seller)
+	return nil
+}
@@ -0,0 +1,88 @@
+package sui
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/configs"
+)
+
+func TestExpiredListingsAreHiddenAndReported(t *testing.T) {
+	manager, err := NewMarketplaceServiceManager(&configs.MarketplaceConfig{
+		SuiNodeURL:          "https://fullnode.testnet.sui.io:443",
+		PackageID:           "0x1",
+		MarketplaceObjectID: "0x2",
+		Module:              "marketplace",
+		DefaultGasBudget:    1000000,
+		EnableCaching:       true,
+		CacheExpiration:     300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	listedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := uint64(500 + 24*3600) // Listed in epoch 500 for 24 hours
+	onChain := map[string]*ListingInfo{
+		"0xnft": {ID: "0xa", Seller: "0xseller", NFTID: "0xnft", CreatedAt: 500, ExpiresAt: &expiresAt},
+	}
+	manager.activeListing = func(nftID string) (*ListingInfo, error) {
+		if listing, ok := onChain[nftID]; ok {
+			copied := *listing
+			return &copied, nil
+		}
+		return nil, ErrListingNotActive
+	}
+	var expired []ExpiredListing
+	manager.expiry.listings = make(map[string]*expiringListing)
+	manager.expiry.config = ListingExpiry{OnExpired: func(e ExpiredListing) { expired = append(expired, e) }}
+	manager.setCache("listings_first", cachedListings{firstPage: true, Listings: []ListingInfo{{ID: "0xa"}, {ID: "0xb"}}})
+
+	created := models.SuiEventResponse{
+		ParsedJson:  map[string]interface{}{"listing_id": "0xa", "seller": "0xseller", "nft_id": "0xnft"},
+		TimestampMs: strconv.FormatInt(listedAt.UnixMilli(), 10),
+	}
+	manager.onListingCreatedExpiry(created)
+	manager.sweepExpired(listedAt.Add(23 * time.Hour))
+	if len(expired) != 0 {
+		t.Fatalf("a listing expired an hour early: %+v", expired)
+	}
+
+	// Extended on-chain by an hour, which no event reports.
+	extended := expiresAt + 3600
+	onChain["0xnft"].ExpiresAt = &extended
+	manager.sweepExpired(listedAt.Add(24 * time.Hour))
+	if len(expired) != 0 {
+		t.Fatal("an extended listing expired at its old deadline")
+	}
+	manager.sweepExpired(listedAt.Add(25 * time.Hour))
+	manager.sweepExpired(listedAt.Add(26 * time.Hour))
+	if len(expired) != 1 || expired[0].Listing.ID != "0xa" || !expired[0].ExpiredAt.Equal(listedAt.Add(25*time.Hour)) {
+		t.Fatalf("expired = %+v, want 0xa reported once at its extended deadline", expired)
+	}
+	first, _ := manager.getFromCache("listings_first")
+	if listings := first.(cachedListings).Listings; len(listings) != 1 || listings[0].ID != "0xb" {
+		t.Fatalf("cached page = %+v, want the expired listing hidden", listings)
+	}
+	if refetched := manager.withoutClosed([]ListingInfo{{ID: "0xa"}}); len(refetched) != 0 {
+		t.Fatal("a fetched page still shows the expired listing")
+	}
+
+	// The on-chain removal's event ends the tracking and names the seller.
+	manager.onListingClosed(models.SuiEventResponse{Type: "0x1::marketplace::ListingExpired", ParsedJson: map[string]interface{}{"listing_id": "0xa"}})
+	if _, tracked := manager.untrackExpiry("0xa"); tracked {
+		t.Fatal("a removed listing is still tracked")
+	}
+
+	// A listing sold before it expired is dropped without a report.
+	onChain["0xnft2"] = &ListingInfo{ID: "0xc", NFTID: "0xnft2", CreatedAt: 500, ExpiresAt: &expiresAt}
+	created.ParsedJson = map[string]interface{}{"listing_id": "0xc", "nft_id": "0xnft2"}
+	manager.onListingCreatedExpiry(created)
+	delete(onChain, "0xnft2")
+	manager.sweepExpired(listedAt.Add(48 * time.Hour))
+	if len(expired) != 1 || len(manager.expiry.listings) != 0 {
+		t.Fatalf("expired = %+v, tracked = %d; want the sold listing dropped", expired, len(manager.expiry.listings))
+	}
+}
@@ -1,6 +1,7 @@
 package sui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,6 +9,10 @@ import (
 
 	"github.com/block-vision/sui-go-sdk/models" // Added for TransactionBlockResponse
 	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
 	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
 )
 
@@ -20,13 +25,30 @@ type MarketplaceServiceManager struct {
 	config        *configs.MarketplaceConfig
 
 	// Caching
-	cache       map[string]interface{}
-	cacheMutex  sync.RWMutex
-	cacheExpiry map[string]time.Time
+	cache          map[string]interface{}
+	cacheMutex     sync.RWMutex
+	cacheExpiry    map[string]time.Time
+	closedListings map[string]struct{}   // Listings seen sold, cancelled or expired; filtered out of fetched pages
+	closedOrder    []string              // closedListings oldest first, to bound it
+	events         *EventSubscriber      // Keeps cached entries in step with the marketplace; nil without event sync
+	items          *reservation.Registry // NFTs and listings with a prepared transaction; nil reserves nothing
+
+	// Listing expiry
+	expiry        expiryState
+	activeListing func(nftID string) (*ListingInfo, error) // Reads a listing's entry in the marketplace
+
+	// Fees
+	feeRate         func() balance.FeeRate // Server fee on purchases; nil charges none
+	treasuryAddress string                 // Receives the fees
+	ledger          *treasury.Ledger       // Records the fees of reported sales; nil records nothing
+	bus             *events.Bus            // Receives market.sold for reported sales; nil publishes nothing
 
 	// Rate limiting
 	rateLimiter map[string][]time.Time
 	rateMutex   sync.RWMutex
+
+	done      chan struct{} // Closed by Close to stop the cache cleanup routine
+	closeOnce sync.Once
 }
 
 // NewMarketplaceServiceManager creates a new marketplace service manager
@@ -34,9 +56,15 @@ func NewMarketplaceServiceManager(config *configs.MarketplaceConfig) (*Marketpla
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
+	return NewMarketplaceServiceManagerWithClient(config, NewSuiClient(config.SuiNodeURL))
+}
 
-	// Create Sui client
-	client := NewSuiClient(config.SuiNodeURL)
+// NewMarketplaceServiceManagerWithClient creates a marketplace service manager
+// that talks to the chain through client instead of config.SuiNodeURL.
+func NewMarketplaceServiceManagerWithClient(config *configs.MarketplaceConfig, client *SuiClient) (*MarketplaceServiceManager, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
 
 	// Create marketplace service config
 	marketConfig := MarketplaceConfig{
@@ -55,12 +83,20 @@ func NewMarketplaceServiceManager(config *configs.MarketplaceConfig) (*Marketpla
 		cache:         make(map[string]interface{}),
 		cacheExpiry:   make(map[string]time.Time),
 		rateLimiter:   make(map[string][]time.Time),
+		done:          make(chan struct{}),
+
+		closedListings: make(map[string]struct{}),
+		activeListing:  marketService.GetActiveListing,
 	}
 
 	// Start cache cleanup routine
 	if config.EnableCaching {
 		go manager.cacheCleanupRoutine()
 	}
+	if config.EnableCaching && config.EventSyncEnabled {
+		manager.events = manager.subscribe(NewModuleEventSubscriber(client, config.PackageID, config.Module, time.Duration(config.EventPollInterval)*time.Second))
+		manager.events.Start()
+	}
 
 	utils.LogInfo("Marketplace Service Manager initialized successfully")
 	return manager, nil
@@ -71,8 +107,13 @@ func (m *MarketplaceServiceManager) cacheCleanupRoutine() {
 	ticker := time.NewTicker(time.Minute * 5) // Clean every 5 minutes
 	defer ticker.Stop()
 
-	for range ticker.C {
-		m.cleanExpiredCache()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.cleanExpiredCache()
+		}
 	}
 }
 
@@ -111,6 +152,11 @@ func (m *MarketplaceServiceManager) getFromCache(key string) (interface{}, bool)
 
 // setCache stores data in cache
 func (m *MarketplaceServiceManager) setCache(key string, value interface{}) {
+	m.setCacheFor(key, value, time.Second*time.Duration(m.config.CacheExpiration))
+}
+
+// setCacheFor stores data in cache for ttl.
+func (m *MarketplaceServiceManager) setCacheFor(key string, value interface{}, ttl time.Duration) {
 	if !m.config.EnableCaching {
 		return
 	}
@@ -119,7 +165,61 @@ func (m *MarketplaceServiceManager) setCache(key string, value interface{}) {
 	defer m.cacheMutex.Unlock()
 
 	m.cache[key] = value
-	m.cacheExpiry[key] = time.Now().Add(time.Second * time.Duration(m.config.CacheExpiration))
+	m.cacheExpiry[key] = time.Now().Add(ttl)
+}
+
+// UseReservations makes the Prepare methods reserve the NFT or listing they
+// build a transaction for in items, shared with the other services that
+// prepare transactions for player items. A transaction is refused while its
+// object is reserved by another operation. Reservations end when the
+// marketplace reports the outcome, with event sync on, or expire after
+// reservation_ttl_seconds.
+func (m *MarketplaceServiceManager) UseReservations(items *reservation.Registry) {
+	m.items = items
+}
+
+// UseFees charges purchases a server fee at the rate rate returns, read for
+// every purchase so that it follows balance changes. The buyer pays it to
+// treasuryAddress on top of the price in the purchase transaction. Fees are
+// recorded in ledger when the marketplace reports the sale, with event sync on.
+func (m *MarketplaceServiceManager) UseFees(rate func() balance.FeeRate, treasuryAddress string, ledger *treasury.Ledger) {
+	m.feeRate, m.treasuryAddress, m.ledger = rate, treasuryAddress, ledger
+}
+
+// UseEvents publishes a market.sold event on bus for every sale the
+// marketplace reports, with event sync on.
+func (m *MarketplaceServiceManager) UseEvents(bus *events.Bus) {
+	m.bus = bus
+}
+
+// purchaseFee returns the server fee on buying listingObjectID.
+func (m *MarketplaceServiceManager) purchaseFee(listingObjectID string) (uint64, error) {
+	if m.feeRate == nil || m.treasuryAddress == "" {
+		return 0, nil
+	}
+	rate := m.feeRate()
+	if rate.Zero() {
+		return 0, nil
+	}
+	listing, err := m.marketService.GetListingInfo(listingObjectID)
+	if err != nil {
+		return 0, fmt.Errorf("could not price the fee on listing %s: %w", listingObjectID, err)
+	}
+	return rate.On(listing.Price), nil
+}
+
+func (m *MarketplaceServiceManager) reserve(holder, objectID string) error {
+	return m.items.Reserve(holder, time.Duration(m.config.ReservationTTL)*time.Second, objectID)
+}
+
+// listHolder and closeHolder name a player's marketplace operations in the
+// reservation registry: listing an NFT, and buying or cancelling a listing.
+func listHolder(address string) string {
+	return "marketplace:list:" + address
+}
+
+func closeHolder(address string) string {
+	return "marketplace:close:" + address
 }
 
 // checkRateLimit checks if the operation is rate limited for a user
@@ -164,7 +264,8 @@ func (m *MarketplaceServiceManager) checkRateLimit(userID string) bool {
 // This manager method handles rate limiting, validation, and then calls the underlying service.
 // It returns the TransactionBlockResponse which contains TxBytes for signing.
 // The actual signing and execution must be handled by the caller or a subsequent step.
-// gasObjectID must be for a gas coin owned by sellerAddress.
+// gasObjectID must be for a gas coin owned by sellerAddress. The NFT and the gas
+// coin are checked on-chain first; a failed check is an *ObjectClaimError.
 func (m *MarketplaceServiceManager) PrepareListNFTForSale(
 	sellerAddress string,
 	nftID string,
@@ -185,6 +286,16 @@ func (m *MarketplaceServiceManager) PrepareListNFTForSale(
 	if gasObjectID == "" {
 		return models.TxnMetaData{}, fmt.Errorf("gasObjectID is required for PrepareListNFTForSale")
 	}
+	if err := m.client.VerifyObjects(context.Background(),
+		ObjectClaim{ObjectID: nftID, Owner: sellerAddress, Type: nftType, Role: "NFT"},
+		ObjectClaim{ObjectID: gasObjectID, Owner: sellerAddress, Type: CoinObjectType(SuiCoinType), Role: "gas coin"},
+	); err != nil {
+		return models.TxnMetaData{}, err
+	}
+	// The NFT stays reserved until the listing shows up or the reservation expires.
+	if err := m.reserve(listHolder(sellerAddress), nftID); err != nil {
+		return models.TxnMetaData{}, err
+	}
 
 	// Call marketplace service - note the new signature
 	txBlockResp, err := m.marketService.ListNFTForSale(
@@ -192,6 +303,7 @@ func (m *MarketplaceServiceManager) PrepareListNFTForSale(
 		gasObjectID, m.config.DefaultGasBudget, // Using default gas budget from config
 	)
 	if err != nil {
+		m.items.Release(listHolder(sellerAddress), nftID)
 		return models.TxnMetaData{}, err // Error already logged by service
 	}
 
@@ -207,7 +319,8 @@ func (m *MarketplaceServiceManager) PrepareListNFTForSale(
 // PreparePurchaseNFT prepares a transaction to purchase an NFT.
 // Returns TransactionBlockResponse containing TxBytes for signing by the buyer.
 // buyerGasObjectID must be for a gas coin owned by buyerAddress.
-// paymentCoinID is the specific Coin object used for payment.
+// paymentCoinID is the specific Coin object used for payment. Both coins are
+// checked on-chain first; a failed check is an *ObjectClaimError.
 func (m *MarketplaceServiceManager) PreparePurchaseNFT(
 	buyerAddress string,
 	listingObjectID string,
@@ -222,13 +335,38 @@ func (m *MarketplaceServiceManager) PreparePurchaseNFT(
 	if buyerGasObjectID == "" || paymentCoinID == "" || listingObjectID == "" {
 		return models.TxnMetaData{}, fmt.Errorf("buyerGasObjectID, paymentCoinID, and listingObjectID are required")
 	}
+	if err := m.client.VerifyObjects(context.Background(),
+		ObjectClaim{ObjectID: paymentCoinID, Owner: buyerAddress, Type: CoinObjectType(coinType), Role: "payment coin"},
+		ObjectClaim{ObjectID: buyerGasObjectID, Owner: buyerAddress, Type: CoinObjectType(SuiCoinType), Role: "gas coin"},
+	); err != nil {
+		return models.TxnMetaData{}, err
+	}
+	// A purchase and a cancellation of one listing cannot both be prepared.
+	if err := m.reserve(closeHolder(buyerAddress), listingObjectID); err != nil {
+		return models.TxnMetaData{}, err
+	}
 
-	txBlockResp, err := m.marketService.PurchaseNFT(
-		buyerAddress, listingObjectID, paymentCoinID,
-		nftType, coinType, // Pass NFT and Coin types for generics
-		buyerGasObjectID, m.config.DefaultGasBudget,
-	)
+	fee, err := m.purchaseFee(listingObjectID)
 	if err != nil {
+		m.items.Release(closeHolder(buyerAddress), listingObjectID)
+		return models.TxnMetaData{}, err
+	}
+	var txBlockResp models.TxnMetaData
+	if fee == 0 {
+		txBlockResp, err = m.marketService.PurchaseNFT(
+			buyerAddress, listingObjectID, paymentCoinID,
+			nftType, coinType, // Pass NFT and Coin types for generics
+			buyerGasObjectID, m.config.DefaultGasBudget,
+		)
+	} else {
+		txBlockResp, err = m.marketService.PurchaseNFTWithFee(
+			buyerAddress, listingObjectID, paymentCoinID,
+			nftType, coinType, fee, m.treasuryAddress,
+			buyerGasObjectID, m.config.DefaultGasBudget,
+		)
+	}
+	if err != nil {
+		m.items.Release(closeHolder(buyerAddress), listingObjectID)
 		return models.TxnMetaData{}, err
 	}
 
@@ -241,7 +379,8 @@ func (m *MarketplaceServiceManager) PreparePurchaseNFT(
 
 // PrepareCancelListing prepares a transaction to cancel an NFT listing.
 // Returns TransactionBlockResponse containing TxBytes for signing by the seller.
-// sellerGasObjectID must be for a gas coin owned by sellerAddress.
+// sellerGasObjectID must be for a gas coin owned by sellerAddress, which is
+// checked on-chain first.
 func (m *MarketplaceServiceManager) PrepareCancelListing(
 	sellerAddress string,
 	listingObjectID string,
@@ -255,6 +394,14 @@ func (m *MarketplaceServiceManager) PrepareCancelListing(
 	if sellerGasObjectID == "" || listingObjectID == "" {
 		return models.TxnMetaData{}, fmt.Errorf("sellerGasObjectID and listingObjectID are required")
 	}
+	if err := m.client.VerifyObjects(context.Background(),
+		ObjectClaim{ObjectID: sellerGasObjectID, Owner: sellerAddress, Type: CoinObjectType(SuiCoinType), Role: "gas coin"},
+	); err != nil {
+		return models.TxnMetaData{}, err
+	}
+	if err := m.reserve(closeHolder(sellerAddress), listingObjectID); err != nil {
+		return models.TxnMetaData{}, err
+	}
 
 	txBlockResp, err := m.marketService.CancelListing(
 		sellerAddress, listingObjectID,
@@ -262,6 +409,7 @@ func (m *MarketplaceServiceManager) PrepareCancelListing(
 		sellerGasObjectID, m.config.DefaultGasBudget,
 	)
 	if err != nil {
+		m.items.Release(closeHolder(sellerAddress), listingObjectID)
 		return models.TxnMetaData{}, err
 	}
 
@@ -276,14 +424,15 @@ func (m *MarketplaceServiceManager) PrepareCancelListing(
 // type of the event that creates listings (e.g., "0xPKG::market::ListingCreated").
 func (m *MarketplaceServiceManager) GetListings(eventType string, limit int, cursor *string) ([]ListingInfo, *string, error) {
 	// Note: Caching key might need to include eventType if it can vary for "listings"
-	cacheKey := fmt.Sprintf("listings_%s_%d_%v", eventType, limit, cursor)
+	cursorKey := ""
+	if cursor != nil {
+		cursorKey = *cursor
+	}
+	cacheKey := fmt.Sprintf("listings_%s_%d_%s", eventType, limit, cursorKey)
 
 	// Try cache first
 	if cached, found := m.getFromCache(cacheKey); found {
-		if result, ok := cached.(struct {
-			Listings   []ListingInfo
-			NextCursor *string
-		}); ok {
+		if result, ok := cached.(cachedListings); ok {
 			return result.Listings, result.NextCursor, nil
 		}
 	}
@@ -295,14 +444,18 @@ func (m *MarketplaceServiceManager) GetListings(eventType string, limit int, cur
 		return nil, nil, err
 	}
 
-	// Cache the result
-	m.setCache(cacheKey, struct {
-		Listings   []ListingInfo
-		NextCursor *string
-	}{
+	// Drop listings already closed, and cache the result. With event sync the
+	// entry is kept up to date, so it can live longer.
+	listings = m.withoutClosed(listings)
+	ttl := time.Second * time.Duration(m.config.CacheExpiration)
+	if m.events != nil {
+		ttl = time.Second * time.Duration(m.config.SyncedCacheExpiration)
+	}
+	m.setCacheFor(cacheKey, cachedListings{
+		firstPage:  cursor == nil,
 		Listings:   listings,
 		NextCursor: nextCursor,
-	})
+	}, ttl)
 
 	return listings, nextCursor, nil
 }
@@ -417,6 +570,7 @@ func (m *MarketplaceServiceManager) GetStats() map[string]interface{} {
 	return map[string]interface{}{
 		"cache_enabled":         m.config.EnableCaching,
 		"cache_size":            cacheSize,
+		"event_sync":            m.events != nil,
 		"rate_limit_enabled":    m.config.RateLimitEnabled,
 		"rate_limit_entries":    rateLimitEntries,
 		"sui_node_url":          m.config.SuiNodeURL,
@@ -428,6 +582,11 @@ func (m *MarketplaceServiceManager) GetStats() map[string]interface{} {
 // Close gracefully shuts down the service manager
 func (m *MarketplaceServiceManager) Close() error {
 	utils.LogInfo("Shutting down Marketplace Service Manager...")
+	m.closeOnce.Do(func() { close(m.done) })
+
+	if m.events != nil {
+		m.events.Stop()
+	}
 
 	// Clear caches
 	m.cacheMutex.Lock()
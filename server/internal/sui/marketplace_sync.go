@@ -0,0 +1,177 @@
+package sui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// maxClosedListings bounds how many closed listing IDs the manager remembers.
+// Older ones have long left the cached listing pages.
+const maxClosedListings = 10000
+
+// Marketplace module events that change cached data.
+const (
+	eventListingCreated  = "ListingCreated"
+	eventNFTPurchased    = "NFTPurchased"
+	eventListingCanceled = "ListingCanceled"
+	eventListingExpired  = "ListingExpired"
+)
+
+// cachedListings is a cached GetListings page.
+type cachedListings struct {
+	firstPage  bool // Requested without a cursor, so new listings belong at its top
+	Listings   []ListingInfo
+	NextCursor *string
+}
+
+// subscribe registers the cache updates for the marketplace events with events.
+func (m *MarketplaceServiceManager) subscribe(events *EventSubscriber) *EventSubscriber {
+	events.Handle(eventListingCreated, m.onListingCreated)
+	events.Handle(eventNFTPurchased, m.onNFTPurchased)
+	events.Handle(eventListingCanceled, m.onListingClosed)
+	events.Handle(eventListingExpired, m.onListingClosed)
+	return events
+}
+
+// onListingCreated adds a new listing to the top of every cached first page
+// and drops the seller's NFTs and the marketplace totals. The listed NFT's
+// reservation ends: it now sits in the marketplace.
+func (m *MarketplaceServiceManager) onListingCreated(event models.SuiEventResponse) {
+	listing := listingFromEvent(event.ParsedJson)
+	if listing.ID == "" {
+		return
+	}
+	m.items.Release(listHolder(listing.Seller), listing.NFTID)
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	for key, value := range m.cache {
+		page, ok := value.(cachedListings)
+		if !ok || !page.firstPage || containsListing(page.Listings, listing.ID) {
+			continue
+		}
+		page.Listings = append([]ListingInfo{listing}, page.Listings...)
+		m.cache[key] = page
+	}
+	m.invalidateLocked("marketplace_info", "player_nfts_"+listing.Seller)
+	utils.LogDebugf("MarketplaceManager: Listing %s added to cached pages.", listing.ID)
+}
+
+// onNFTPurchased closes the sold listing, publishes the sale and records the
+// server fee the buyer paid, if any.
+func (m *MarketplaceServiceManager) onNFTPurchased(event models.SuiEventResponse) {
+	m.onListingClosed(event)
+	if m.bus != nil {
+		sale := events.MarketSold{TxDigest: event.Id.TxDigest}
+		sale.ListingID, _ = event.ParsedJson["listing_id"].(string)
+		sale.NFTID, _ = event.ParsedJson["nft_id"].(string)
+		sale.Seller, _ = event.ParsedJson["seller"].(string)
+		sale.Buyer, _ = event.ParsedJson["buyer"].(string)
+		sale.Price = fieldUint(event.ParsedJson["price_paid"])
+		m.bus.Publish(events.TopicMarketSold, sale)
+	}
+	feeStr, _ := event.ParsedJson["server_fee"].(string)
+	fee, err := strconv.ParseUint(feeStr, 10, 64)
+	if err != nil || fee == 0 {
+		return
+	}
+	buyer, _ := event.ParsedJson["buyer"].(string)
+	m.ledger.Record(treasury.Entry{
+		Source:   treasury.SourceMarketplace,
+		Currency: treasury.CurrencyMIST,
+		Amount:   fee,
+		Payer:    buyer,
+		Ref:      event.Id.TxDigest,
+	})
+}
+
+// onListingClosed removes a sold, cancelled or expired listing from every
+// cached page, drops the entries whose owners or totals it changed and ends
+// the listing's reservation.
+func (m *MarketplaceServiceManager) onListingClosed(event models.SuiEventResponse) {
+	listingID, _ := event.ParsedJson["listing_id"].(string)
+	if listingID == "" {
+		return
+	}
+	seller, _ := event.ParsedJson["seller"].(string)
+	buyer, _ := event.ParsedJson["buyer"].(string)
+	if listing, ok := m.untrackExpiry(listingID); ok && seller == "" {
+		seller = listing.Seller // ListingExpired does not name the seller
+	}
+	m.closeListing(listingID, seller, buyer, eventName(event.Type))
+}
+
+// closeListing drops a listing from every cached page and keeps it out of
+// fetched ones. reason names what closed it, for the log.
+func (m *MarketplaceServiceManager) closeListing(listingID, seller, buyer, reason string) {
+	m.items.Release(closeHolder(seller), listingID)
+	m.items.Release(closeHolder(buyer), listingID)
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	m.markClosedLocked(listingID)
+	for key, value := range m.cache {
+		page, ok := value.(cachedListings)
+		if !ok || !containsListing(page.Listings, listingID) {
+			continue
+		}
+		page.Listings = m.withoutClosedLocked(page.Listings)
+		m.cache[key] = page
+	}
+	m.invalidateLocked("marketplace_info", fmt.Sprintf("listing_info_%s", listingID))
+	for _, owner := range []string{seller, buyer} {
+		if owner != "" {
+			m.invalidateLocked("player_nfts_" + owner)
+		}
+	}
+	utils.LogDebugf("MarketplaceManager: Listing %s closed (%s).", listingID, reason)
+}
+
+// withoutClosed filters listings already known to be closed out of a page fetched from the chain.
+func (m *MarketplaceServiceManager) withoutClosed(listings []ListingInfo) []ListingInfo {
+	m.cacheMutex.RLock()
+	defer m.cacheMutex.RUnlock()
+	return m.withoutClosedLocked(listings)
+}
+
+func (m *MarketplaceServiceManager) withoutClosedLocked(listings []ListingInfo) []ListingInfo {
+	open := make([]ListingInfo, 0, len(listings))
+	for _, listing := range listings {
+		if _, closed := m.closedListings[listing.ID]; !closed {
+			open = append(open, listing)
+		}
+	}
+	return open
+}
+
+func (m *MarketplaceServiceManager) markClosedLocked(listingID string) {
+	if _, ok := m.closedListings[listingID]; ok {
+		return
+	}
+	if len(m.closedOrder) == maxClosedListings {
+		delete(m.closedListings, m.closedOrder[0])
+		m.closedOrder = m.closedOrder[1:]
+	}
+	m.closedListings[listingID] = struct{}{}
+	m.closedOrder = append(m.closedOrder, listingID)
+}
+
+func (m *MarketplaceServiceManager) invalidateLocked(keys ...string) {
+	for _, key := range keys {
+		delete(m.cache, key)
+		delete(m.cacheExpiry, key)
+	}
+}
+
+func containsListing(listings []ListingInfo, listingID string) bool {
+	for _, listing := range listings {
+		if strings.EqualFold(listing.ID, listingID) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/block-vision/sui-go-sdk/models"
 	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
 )
 
 func TestMarketplaceServiceManager(t *testing.T) {
@@ -188,3 +192,109 @@ func BenchmarkCache(b *testing.B) {
 		manager.getFromCache(fmt.Sprintf("key_%d", i%1000))
 	}
 }
+
+func TestListingsCacheFollowsMarketplaceEvents(t *testing.T) {
+	manager, err := NewMarketplaceServiceManager(&configs.MarketplaceConfig{
+		SuiNodeURL:          "https://fullnode.testnet.sui.io:443",
+		PackageID:           "0x1",
+		MarketplaceObjectID: "0x2",
+		Module:              "marketplace",
+		DefaultGasBudget:    1000000,
+		EnableCaching:       true,
+		CacheExpiration:     300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	manager.setCache("listings_first", cachedListings{firstPage: true, Listings: []ListingInfo{{ID: "0xa"}}})
+	manager.setCache("listings_second", cachedListings{Listings: []ListingInfo{{ID: "0xold"}}})
+	manager.setCache("marketplace_info", &MarketplaceInfo{})
+	manager.setCache("player_nfts_0xbuyer", []map[string]interface{}{})
+
+	manager.onListingCreated(models.SuiEventResponse{ParsedJson: map[string]interface{}{"listing_id": "0xb", "seller": "0xseller", "price": "5"}})
+	first, _ := manager.getFromCache("listings_first")
+	if listings := first.(cachedListings).Listings; len(listings) != 2 || listings[0].ID != "0xb" || listings[0].Price != 5 {
+		t.Fatalf("first page after ListingCreated = %+v", listings)
+	}
+	second, _ := manager.getFromCache("listings_second")
+	if len(second.(cachedListings).Listings) != 1 {
+		t.Fatal("a new listing was added to a later page")
+	}
+	if _, found := manager.getFromCache("marketplace_info"); found {
+		t.Fatal("marketplace totals survived a new listing")
+	}
+
+	manager.onListingClosed(models.SuiEventResponse{ParsedJson: map[string]interface{}{"listing_id": "0xa", "buyer": "0xbuyer"}})
+	first, _ = manager.getFromCache("listings_first")
+	if listings := first.(cachedListings).Listings; len(listings) != 1 || listings[0].ID != "0xb" {
+		t.Fatalf("first page after NFTPurchased = %+v", listings)
+	}
+	if _, found := manager.getFromCache("player_nfts_0xbuyer"); found {
+		t.Fatal("the buyer's NFTs survived a purchase")
+	}
+	if refetched := manager.withoutClosed([]ListingInfo{{ID: "0xa"}, {ID: "0xc"}}); len(refetched) != 1 || refetched[0].ID != "0xc" {
+		t.Fatalf("fetched page = %+v, want the closed listing dropped", refetched)
+	}
+}
+
+func TestReportedSalesRecordTheirServerFee(t *testing.T) {
+	manager, err := NewMarketplaceServiceManager(&configs.MarketplaceConfig{
+		SuiNodeURL:          "https://fullnode.testnet.sui.io:443",
+		PackageID:           "0x1",
+		MarketplaceObjectID: "0x2",
+		DefaultGasBudget:    1000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	ledger, _ := treasury.Open(&treasury.MemoryStore{}, 0)
+	manager.UseFees(func() balance.FeeRate { return balance.FeeRate{BasisPoints: 100} }, "0xtreasury", ledger)
+
+	sale := models.SuiEventResponse{ParsedJson: map[string]interface{}{"listing_id": "0xa", "buyer": "0xbuyer", "server_fee": "25"}}
+	sale.Id.TxDigest = "digest"
+	manager.onNFTPurchased(sale)
+	manager.onNFTPurchased(models.SuiEventResponse{ParsedJson: map[string]interface{}{"listing_id": "0xb", "buyer": "0xbuyer"}})
+
+	report := ledger.Report("")
+	if len(report.Totals) != 1 || report.Totals[0].Amount != 25 || report.Totals[0].Source != treasury.SourceMarketplace {
+		t.Fatalf("treasury totals = %+v, want 25 MIST from one marketplace sale", report.Totals)
+	}
+	if entry := report.Recent[0]; entry.Payer != "0xbuyer" || entry.Ref != "digest" {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestReportedSalesArePublished(t *testing.T) {
+	manager, err := NewMarketplaceServiceManager(&configs.MarketplaceConfig{
+		SuiNodeURL:          "https://fullnode.testnet.sui.io:443",
+		PackageID:           "0x1",
+		MarketplaceObjectID: "0x2",
+		DefaultGasBudget:    1000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	bus := events.NewBus(0)
+	sold := make(chan events.MarketSold, 1)
+	events.On(bus, events.TopicMarketSold, "test", func(e events.MarketSold) { sold <- e })
+	manager.UseEvents(bus)
+
+	sale := models.SuiEventResponse{ParsedJson: map[string]interface{}{
+		"listing_id": "0xa", "nft_id": "0xnft", "seller": "0xseller", "buyer": "0xbuyer", "price_paid": "1500",
+	}}
+	sale.Id.TxDigest = "digest"
+	manager.onNFTPurchased(sale)
+
+	select {
+	case e := <-sold:
+		want := events.MarketSold{ListingID: "0xa", NFTID: "0xnft", Seller: "0xseller", Buyer: "0xbuyer", Price: 1500, TxDigest: "digest"}
+		if e != want {
+			t.Errorf("market.sold = %+v, want %+v", e, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no market.sold event")
+	}
+}
@@ -0,0 +1,213 @@
+package sui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// Codes of ObjectClaimError, sent to clients as error codes.
+const (
+	ObjectNotFound     = "OBJECT_NOT_FOUND"     // No such object, or it was deleted
+	ObjectWrongType    = "OBJECT_WRONG_TYPE"    // The object has another Move type
+	ObjectNotOwned     = "OBJECT_NOT_OWNED"     // Another address, an object, or no one (shared) owns it
+	ObjectLookupFailed = "OBJECT_LOOKUP_FAILED" // The node could not be asked; the claim may be fine
+)
+
+// objectFactsTTL is how long an object's owner and type are trusted after a
+// lookup. Ownership can change on-chain at any time, so this only saves
+// repeated lookups while a player retries; the transaction itself remains
+// the final check.
+const objectFactsTTL = 10 * time.Second
+
+// SuiCoinType is the Move type of SUI, the gas coin.
+const SuiCoinType = "0x2::sui::SUI"
+
+// CoinObjectType returns the Move type of a coin object of coinType.
+func CoinObjectType(coinType string) string {
+	return "0x2::coin::Coin<" + coinType + ">"
+}
+
+// ObjectClaim is what a client implies about an object ID it supplied.
+type ObjectClaim struct {
+	ObjectID string
+	Owner    string // Address that must own the object
+	Type     string // Move type it must have; "" accepts any. A type without type arguments accepts any instantiation
+	Role     string // What the client supplied it as, e.g. "payment coin", for messages
+}
+
+// ObjectClaimError reports a claim that does not hold.
+type ObjectClaimError struct {
+	Code   string // One of the Object constants
+	Claim  ObjectClaim
+	Detail string // What was found instead, or why the lookup failed
+}
+
+func (e *ObjectClaimError) Error() string {
+	role := e.Claim.Role
+	if role == "" {
+		role = "object"
+	}
+	switch e.Code {
+	case ObjectNotFound:
+		return fmt.Sprintf("%s %s does not exist", role, e.Claim.ObjectID)
+	case ObjectWrongType:
+		return fmt.Sprintf("%s %s is a %s, not a %s", role, e.Claim.ObjectID, e.Detail, e.Claim.Type)
+	case ObjectNotOwned:
+		return fmt.Sprintf("%s %s is not owned by %s (%s)", role, e.Claim.ObjectID, e.Claim.Owner, e.Detail)
+	default:
+		return fmt.Sprintf("could not check %s %s: %s", role, e.Claim.ObjectID, e.Detail)
+	}
+}
+
+// objectFacts is an object's owner and type as last looked up.
+type objectFacts struct {
+	owner     string // Owning address; "" if an object owns it or it is shared or immutable
+	ownerKind string // "address", "object", "shared" or "immutable"
+	objType   string
+	fetchedAt time.Time
+}
+
+// objectFactsCache remembers the owners and types of objects that exist.
+type objectFactsCache struct {
+	mu    sync.Mutex
+	facts map[string]objectFacts
+}
+
+func newObjectFactsCache() *objectFactsCache {
+	return &objectFactsCache{facts: make(map[string]objectFacts)}
+}
+
+func (c *objectFactsCache) get(objectID string, now time.Time) (objectFacts, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	facts, ok := c.facts[objectID]
+	if ok && now.Sub(facts.fetchedAt) > objectFactsTTL {
+		delete(c.facts, objectID)
+		return objectFacts{}, false
+	}
+	return facts, ok
+}
+
+func (c *objectFactsCache) put(objectID string, facts objectFacts) {
+	c.mu.Lock()
+	c.facts[objectID] = facts
+	c.mu.Unlock()
+}
+
+func (c *objectFactsCache) forget(objectIDs []string) {
+	c.mu.Lock()
+	for _, id := range objectIDs {
+		delete(c.facts, id)
+	}
+	c.mu.Unlock()
+}
+
+// VerifyObjects checks, before the server prepares a transaction around
+// object IDs a client supplied, that each object exists, has the claimed type
+// and is owned by the claimed address. It returns an *ObjectClaimError for
+// the first claim that fails. Objects not looked up in the last few seconds
+// are fetched in one call.
+func (c *SuiClient) VerifyObjects(ctx context.Context, claims ...ObjectClaim) error {
+	now := time.Now()
+	found := make(map[string]objectFacts, len(claims))
+	var missing []string
+	for _, claim := range claims {
+		if facts, ok := c.objects.get(claim.ObjectID, now); ok {
+			found[claim.ObjectID] = facts
+		} else if _, listed := found[claim.ObjectID]; !listed {
+			missing = append(missing, claim.ObjectID)
+		}
+	}
+	if len(missing) > 0 {
+		responses, err := callActive(c, func(api sui.ISuiAPI) ([]*models.SuiObjectResponse, error) {
+			return api.SuiMultiGetObjects(ctx, models.SuiMultiGetObjectsRequest{
+				ObjectIds: missing,
+				Options:   models.SuiObjectDataOptions{ShowType: true, ShowOwner: true},
+			})
+		})
+		if err != nil {
+			for _, claim := range claims {
+				if _, ok := found[claim.ObjectID]; !ok {
+					return &ObjectClaimError{Code: ObjectLookupFailed, Claim: claim, Detail: err.Error()}
+				}
+			}
+		}
+		for _, response := range responses {
+			if response == nil || response.Data == nil {
+				continue
+			}
+			facts := factsOf(response.Data, now)
+			c.objects.put(response.Data.ObjectId, facts)
+			c.versions.recordObject(response.Data)
+			found[response.Data.ObjectId] = facts
+		}
+	}
+
+	for _, claim := range claims {
+		facts, ok := found[claim.ObjectID]
+		switch {
+		case !ok:
+			return &ObjectClaimError{Code: ObjectNotFound, Claim: claim}
+		case claim.Type != "" && !moveTypeMatches(facts.objType, claim.Type):
+			return &ObjectClaimError{Code: ObjectWrongType, Claim: claim, Detail: facts.objType}
+		case facts.ownerKind != "address":
+			return &ObjectClaimError{Code: ObjectNotOwned, Claim: claim, Detail: facts.ownerKind + " object"}
+		case normalizeAddress(facts.owner) != normalizeAddress(claim.Owner):
+			return &ObjectClaimError{Code: ObjectNotOwned, Claim: claim, Detail: "owned by " + facts.owner}
+		}
+	}
+	return nil
+}
+
+func factsOf(data *models.SuiObjectData, now time.Time) objectFacts {
+	facts := objectFacts{objType: data.Type, fetchedAt: now, ownerKind: "immutable"}
+	if owner := addressOwner(data.Owner); owner != "" {
+		facts.owner, facts.ownerKind = owner, "address"
+		return facts
+	}
+	switch owner := data.Owner.(type) {
+	case map[string]interface{}:
+		if _, ok := owner["ObjectOwner"]; ok {
+			facts.ownerKind = "object"
+		} else if _, ok := owner["Shared"]; ok {
+			facts.ownerKind = "shared"
+		}
+	case models.ObjectOwner:
+		if owner.ObjectOwner != "" {
+			facts.ownerKind = "object"
+		} else {
+			facts.ownerKind = "shared"
+		}
+	}
+	return facts
+}
+
+var moveAddressPattern = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+
+// normalizeAddress pads an address to 32 bytes, so 0x2 and 0x00…02 compare equal.
+func normalizeAddress(address string) string {
+	hex := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if len(hex) < 64 {
+		hex = strings.Repeat("0", 64-len(hex)) + hex
+	}
+	return "0x" + hex
+}
+
+// moveTypeMatches reports whether actual is the Move type want. Addresses are
+// compared in full, and a want without type arguments matches any
+// instantiation of its struct.
+func moveTypeMatches(actual, want string) bool {
+	actual = moveAddressPattern.ReplaceAllStringFunc(actual, normalizeAddress)
+	want = moveAddressPattern.ReplaceAllStringFunc(want, normalizeAddress)
+	if !strings.Contains(want, "<") {
+		actual, _, _ = strings.Cut(actual, "<")
+	}
+	return strings.ReplaceAll(actual, " ", "") == strings.ReplaceAll(want, " ", "")
+}
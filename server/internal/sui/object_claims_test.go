@@ -0,0 +1,77 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// objectsAPI serves SuiMultiGetObjects from a fixed set of objects.
+type objectsAPI struct {
+	sui.ISuiAPI
+	objects map[string]*models.SuiObjectData
+	calls   int
+}
+
+func (a *objectsAPI) SuiMultiGetObjects(ctx context.Context, req models.SuiMultiGetObjectsRequest) ([]*models.SuiObjectResponse, error) {
+	a.calls++
+	responses := make([]*models.SuiObjectResponse, len(req.ObjectIds))
+	for i, id := range req.ObjectIds {
+		if data, ok := a.objects[id]; ok {
+			responses[i] = &models.SuiObjectResponse{Data: data}
+		} else {
+			responses[i] = &models.SuiObjectResponse{Error: &models.SuiObjectResponseError{Code: "notExists", ObjectId: id}}
+		}
+	}
+	return responses, nil
+}
+
+func TestVerifyObjects(t *testing.T) {
+	const seller = "0x00000000000000000000000000000000000000000000000000000000000000a1"
+	api := &objectsAPI{objects: map[string]*models.SuiObjectData{
+		"0xnft":  {ObjectId: "0xnft", Type: "0x5::item::Sword", Owner: map[string]interface{}{"AddressOwner": seller}},
+		"0xgas":  {ObjectId: "0xgas", Type: "0x0000000000000000000000000000000000000000000000000000000000000002::coin::Coin<0x2::sui::SUI>", Owner: map[string]interface{}{"AddressOwner": seller}},
+		"0xpool": {ObjectId: "0xpool", Type: "0x5::market::Pool<0x2::sui::SUI>", Owner: map[string]interface{}{"Shared": map[string]interface{}{"initial_shared_version": 1}}},
+	}}
+	client := NewSuiClientWithAPI("stub", api)
+	ctx := context.Background()
+
+	// Short and padded forms of an address name the same owner and type.
+	err := client.VerifyObjects(ctx,
+		ObjectClaim{ObjectID: "0xnft", Owner: "0xa1", Type: "0x5::item::Sword"},
+		ObjectClaim{ObjectID: "0xgas", Owner: seller, Type: CoinObjectType(SuiCoinType)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.VerifyObjects(ctx, ObjectClaim{ObjectID: "0xnft", Owner: seller}); err != nil || api.calls != 1 {
+		t.Fatalf("cached lookup: err = %v after %d calls", err, api.calls)
+	}
+
+	for _, tc := range []struct {
+		claim ObjectClaim
+		code  string
+	}{
+		{ObjectClaim{ObjectID: "0xgone", Owner: seller}, ObjectNotFound},
+		{ObjectClaim{ObjectID: "0xnft", Owner: "0xb2"}, ObjectNotOwned},
+		{ObjectClaim{ObjectID: "0xgas", Owner: seller, Type: CoinObjectType("0x9::gold::GOLD")}, ObjectWrongType},
+		{ObjectClaim{ObjectID: "0xnft", Owner: seller, Type: "0x5::item::Shield"}, ObjectWrongType},
+		{ObjectClaim{ObjectID: "0xpool", Owner: seller, Type: "0x5::market::Pool"}, ObjectNotOwned},
+	} {
+		var claimErr *ObjectClaimError
+		if err := client.VerifyObjects(ctx, tc.claim); !errors.As(err, &claimErr) || claimErr.Code != tc.code {
+			t.Errorf("%+v: err = %v, want %s", tc.claim, err, tc.code)
+		}
+	}
+
+	// Executing a transaction with an object forgets its owner.
+	client.ForgetObjects("0xnft")
+	calls := api.calls
+	client.VerifyObjects(ctx, ObjectClaim{ObjectID: "0xnft", Owner: seller})
+	if api.calls != calls+1 {
+		t.Error("the invalidated object was not looked up again")
+	}
+}
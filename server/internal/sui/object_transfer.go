@@ -0,0 +1,106 @@
+package sui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/utils" // For logging
+)
+
+// ObjectOwner returns the address owning objectID and the object's Move type.
+// An object that is shared, wrapped or owned by another object has no owner
+// address and is reported with an error.
+func (c *SuiClient) ObjectOwner(objectID string) (owner, objectType string, err error) {
+	response, err := c.GetObject(objectID)
+	if err != nil {
+		return "", "", err
+	}
+	if response.Data == nil {
+		if response.Error != nil {
+			return "", "", fmt.Errorf("object %s: %s", objectID, response.Error.Error)
+		}
+		return "", "", fmt.Errorf("object %s not found", objectID)
+	}
+	owner = addressOwner(response.Data.Owner)
+	if owner == "" {
+		return "", response.Data.Type, fmt.Errorf("object %s is not owned by an address", objectID)
+	}
+	return owner, response.Data.Type, nil
+}
+
+// BuildObjectTransfer returns an unsigned transaction moving objectID from
+// sender to recipient, for sender to sign. Gas is picked from sender's coins
+// when gasObjectID is empty.
+func (c *SuiClient) BuildObjectTransfer(sender, objectID, recipient, gasObjectID string, gasBudget uint64) (models.TxnMetaData, error) {
+	request := models.TransferObjectRequest{
+		Signer:    sender,
+		ObjectId:  objectID,
+		GasBudget: strconv.FormatUint(gasBudget, 10),
+		Recipient: recipient,
+	}
+	if gasObjectID != "" {
+		request.Gas = &gasObjectID
+	}
+	txMeta, err := callActive(c, func(api sui.ISuiAPI) (models.TxnMetaData, error) {
+		return api.TransferObject(context.Background(), request)
+	})
+	if err == nil {
+		c.versions.recordTransaction(txMeta)
+	}
+	return txMeta, err
+}
+
+// TransferObjectWithServerKey moves objectID from sender, whose hex key is
+// privateKey, to recipient and returns the executed transaction. If sender is
+// in the signer pool, the pool signs and pays for it instead.
+func (c *SuiClient) TransferObjectWithServerKey(sender, objectID, recipient, gasObjectID string, gasBudget uint64, privateKey string) (models.SuiTransactionBlockResponse, error) {
+	resp, err := c.executeAsServer(sender, gasObjectID, gasBudget, privateKey, func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return c.BuildObjectTransfer(sender, objectID, recipient, gasObjectID, gasBudget)
+	})
+	if err != nil {
+		return resp, err
+	}
+	utils.LogInfof("SuiClient: Transferred %s from %s to %s (tx %s).", objectID, sender, recipient, resp.Digest)
+	return resp, nil
+}
+
+// VerifyObjectTransfer checks that txDigest is a successful transaction sent
+// by sender that left objectID owned by recipient. It lets the server accept
+// transfers that players signed themselves.
+func (c *SuiClient) VerifyObjectTransfer(txDigest, sender, objectID, recipient string) error {
+	tx, err := c.GetTransactionBlock(txDigest)
+	if err != nil {
+		return fmt.Errorf("could not fetch transfer transaction %s: %w", txDigest, err)
+	}
+	if tx.Effects.Status.Status != "success" {
+		return fmt.Errorf("transfer transaction %s did not succeed (status %q)", txDigest, tx.Effects.Status.Status)
+	}
+	if !strings.EqualFold(tx.Transaction.Data.Sender, sender) {
+		return fmt.Errorf("transfer transaction %s was not sent by %s", txDigest, sender)
+	}
+	for _, change := range tx.ObjectChanges {
+		if change.ObjectId == objectID && strings.EqualFold(change.GetObjectChangeAddressOwner(), recipient) {
+			return nil
+		}
+	}
+	return fmt.Errorf("transaction %s does not transfer %s to %s", txDigest, objectID, recipient)
+}
+
+// addressOwner returns the address in an object's owner field, or "" if no
+// address owns it.
+func addressOwner(owner interface{}) string {
+	raw, err := json.Marshal(owner)
+	if err != nil {
+		return ""
+	}
+	var parsed models.ObjectOwner
+	if json.Unmarshal(raw, &parsed) != nil {
+		return ""
+	}
+	return parsed.AddressOwner
+}
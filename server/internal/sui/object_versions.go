@@ -0,0 +1,330 @@
+package sui
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// maxCachedObjectVersions bounds the object version cache. The objects seen
+// least recently are dropped first.
+const maxCachedObjectVersions = 10000
+
+// ObjectRef is the version and digest of an object as last seen by the server.
+type ObjectRef struct {
+	ObjectID  string
+	Version   uint64
+	Digest    string
+	FetchedAt time.Time
+}
+
+// objectVersionCache remembers the latest object versions seen while fetching
+// objects, preparing transactions and executing them, so a version conflict
+// can be traced to the objects that moved on.
+type objectVersionCache struct {
+	mu    sync.Mutex
+	max   int
+	refs  map[string]*list.Element // Of ObjectRef
+	order *list.List               // Most recently seen first
+}
+
+func newObjectVersionCache(max int) *objectVersionCache {
+	return &objectVersionCache{max: max, refs: make(map[string]*list.Element), order: list.New()}
+}
+
+// record keeps ref unless a newer version of the object is already known.
+func (c *objectVersionCache) record(ref ObjectRef) {
+	if ref.ObjectID == "" || ref.Version == 0 {
+		return
+	}
+	ref.FetchedAt = time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.refs[ref.ObjectID]; ok {
+		if e.Value.(ObjectRef).Version <= ref.Version {
+			e.Value = ref
+		}
+		c.order.MoveToFront(e)
+		return
+	}
+	c.refs[ref.ObjectID] = c.order.PushFront(ref)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.refs, oldest.Value.(ObjectRef).ObjectID)
+	}
+}
+
+func (c *objectVersionCache) recordObject(data *models.SuiObjectData) {
+	if data == nil {
+		return
+	}
+	version, _ := strconv.ParseUint(data.Version, 10, 64)
+	c.record(ObjectRef{ObjectID: data.ObjectId, Version: version, Digest: data.Digest})
+}
+
+// recordTransaction keeps the gas and owned input versions a transaction was prepared with.
+func (c *objectVersionCache) recordTransaction(meta models.TxnMetaData) {
+	for _, ref := range preparedRefs(meta) {
+		c.record(ref)
+	}
+}
+
+// recordEffects keeps the versions an executed transaction left its objects at.
+func (c *objectVersionCache) recordEffects(response models.SuiTransactionBlockResponse) {
+	effects := response.Effects
+	for _, changed := range append(append([]models.OwnedObjectRef{effects.GasObject}, effects.Mutated...), effects.Created...) {
+		ref := changed.Reference
+		c.record(ObjectRef{ObjectID: ref.ObjectId, Version: ref.Version, Digest: ref.Digest})
+	}
+	for _, deleted := range effects.Deleted {
+		c.invalidate([]string{deleted.ObjectId})
+	}
+}
+
+func (c *objectVersionCache) get(objectID string) (ObjectRef, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.refs[objectID]
+	if !ok {
+		return ObjectRef{}, false
+	}
+	return e.Value.(ObjectRef), true
+}
+
+func (c *objectVersionCache) invalidate(objectIDs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range objectIDs {
+		if e, ok := c.refs[id]; ok {
+			c.order.Remove(e)
+			delete(c.refs, id)
+		}
+	}
+}
+
+// preparedRefs returns the versions of the gas and owned input objects of a
+// prepared transaction, by object ID.
+func preparedRefs(meta models.TxnMetaData) map[string]ObjectRef {
+	refs := make(map[string]ObjectRef)
+	for _, gas := range meta.Gas {
+		refs[gas.ObjectId] = ObjectRef{ObjectID: gas.ObjectId, Version: gas.Version, Digest: gas.Digest}
+	}
+	for _, input := range meta.InputObjects {
+		if ref, ok := ownedInputRef(input); ok {
+			refs[ref.ObjectID] = ref
+		}
+	}
+	return refs
+}
+
+// ownedInputRef extracts the object ref from an input object entry of the form
+// {"ImmOrOwnedMoveObject": {"objectId": ..., "version": ..., "digest": ...}}.
+func ownedInputRef(input interface{}) (ObjectRef, bool) {
+	kind, ok := input.(map[string]interface{})
+	if !ok {
+		return ObjectRef{}, false
+	}
+	fields, ok := kind["ImmOrOwnedMoveObject"].(map[string]interface{})
+	if !ok {
+		return ObjectRef{}, false
+	}
+	ref := ObjectRef{}
+	ref.ObjectID, _ = fields["objectId"].(string)
+	ref.Digest, _ = fields["digest"].(string)
+	switch version := fields["version"].(type) {
+	case string:
+		ref.Version, _ = strconv.ParseUint(version, 10, 64)
+	case float64:
+		ref.Version = uint64(version)
+	}
+	return ref, ref.ObjectID != ""
+}
+
+// CachedObjectVersion returns the latest version of an object the client has seen.
+func (c *SuiClient) CachedObjectVersion(objectID string) (ObjectRef, bool) {
+	return c.versions.get(objectID)
+}
+
+// refreshObjectVersions fetches the current versions of objectIDs into the
+// cache. Objects that no longer exist are dropped from it.
+func (c *SuiClient) refreshObjectVersions(objectIDs []string) error {
+	if len(objectIDs) == 0 {
+		return nil
+	}
+	responses, err := callActive(c, func(api sui.ISuiAPI) ([]*models.SuiObjectResponse, error) {
+		return api.SuiMultiGetObjects(context.Background(), models.SuiMultiGetObjectsRequest{ObjectIds: objectIDs})
+	})
+	c.versions.invalidate(objectIDs)
+	if err != nil {
+		return fmt.Errorf("failed to refresh object versions: %w", err)
+	}
+	for _, response := range responses {
+		if response != nil {
+			c.versions.recordObject(response.Data)
+		}
+	}
+	return nil
+}
+
+// ForgetObjects drops the owners and types VerifyObjects saw for objectIDs,
+// e.g. after an object is known to have changed.
+func (c *SuiClient) ForgetObjects(objectIDs ...string) {
+	c.objects.forget(objectIDs)
+}
+
+// versionConflictFragments identify execution errors caused by stale object references.
+var versionConflictFragments = []string{
+	"ObjectVersionUnavailableForConsumption",
+	"is not available for consumption",
+	"needs to be rebuilt",
+}
+
+var objectIDPattern = regexp.MustCompile(`0x[0-9a-fA-F]{40,64}`)
+
+// IsVersionConflict reports whether err was caused by an object changing version
+// between preparation and execution.
+func IsVersionConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	var conflict *VersionConflictError
+	if errors.As(err, &conflict) {
+		return true
+	}
+	msg := err.Error()
+	for _, fragment := range versionConflictFragments {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionConflictError reports a version conflict that persisted after the transaction was rebuilt.
+type VersionConflictError struct {
+	ObjectIDs []string // Objects named by the node as stale, if any
+	Err       error    // Error from the final attempt
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("object version conflict persisted after rebuild (objects %v): %v", e.ObjectIDs, e.Err)
+}
+
+func (e *VersionConflictError) Unwrap() error { return e.Err }
+
+// TxAttempt prepares, signs and executes a transaction once. It returns the
+// prepared metadata even on failure so the objects involved can be named.
+type TxAttempt func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error)
+
+// ExecuteWithRebuild runs attempt and, if it fails with an object version
+// conflict, rebuilds the transaction once. The node resolves object versions
+// when a transaction is built, so the rebuilt one uses the current versions.
+// A conflict on the rebuilt transaction is returned as *VersionConflictError,
+// naming the objects that moved on since it was prepared.
+func (c *SuiClient) ExecuteWithRebuild(attempt TxAttempt) (models.SuiTransactionBlockResponse, error) {
+	meta, response, err := attempt()
+	if !IsVersionConflict(err) {
+		c.recordExecuted(meta, response, err)
+		return response, err
+	}
+
+	stale := c.staleObjects(err, meta)
+	utils.LogWarnf("SUI Client: Object version conflict on %v. Rebuilding transaction: %v", stale, err)
+	c.ForgetObjects(stale...)
+
+	meta, response, err = attempt()
+	if IsVersionConflict(err) {
+		stale = c.staleObjects(err, meta)
+		utils.LogErrorf("SUI Client: Object version conflict persisted after rebuild on %v", stale)
+		return response, &VersionConflictError{ObjectIDs: stale, Err: err}
+	}
+	c.recordExecuted(meta, response, err)
+	return response, err
+}
+
+// recordExecuted keeps the versions an executed transaction left its objects
+// at, and drops the cached owners of the objects it consumed, since execution
+// may have moved them.
+func (c *SuiClient) recordExecuted(meta models.TxnMetaData, response models.SuiTransactionBlockResponse, err error) {
+	if err != nil {
+		return
+	}
+	for id := range preparedRefs(meta) {
+		c.ForgetObjects(id)
+	}
+	c.versions.recordEffects(response)
+}
+
+// staleObjects names the objects behind a version conflict: those the
+// transaction was prepared with at an older version than the current one.
+// Objects the cache already knows a newer version of need no lookup; the
+// other candidates are fetched again, which replaces their cached versions.
+// If none can be shown to have moved, every candidate is named.
+func (c *SuiClient) staleObjects(err error, meta models.TxnMetaData) []string {
+	candidates := c.conflictingObjects(err, meta)
+	prepared := preparedRefs(meta)
+	moved := func(id string) bool {
+		cached, ok := c.versions.get(id)
+		return ok && prepared[id].Version != 0 && cached.Version > prepared[id].Version
+	}
+	var stale, unknown []string
+	for _, id := range candidates {
+		if moved(id) {
+			stale = append(stale, id)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	if refreshErr := c.refreshObjectVersions(unknown); refreshErr != nil {
+		utils.LogWarnf("SUI Client: %v", refreshErr)
+	}
+	for _, id := range unknown {
+		if moved(id) {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		return candidates
+	}
+	return stale
+}
+
+// conflictingObjects lists the objects named in a conflict error, falling back to
+// every object the transaction referenced when the error names none.
+func (c *SuiClient) conflictingObjects(err error, meta models.TxnMetaData) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range objectIDPattern.FindAllString(err.Error(), -1) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		return ids
+	}
+	for _, gas := range meta.Gas {
+		if !seen[gas.ObjectId] {
+			seen[gas.ObjectId] = true
+			ids = append(ids, gas.ObjectId)
+		}
+	}
+	for _, input := range meta.InputObjects {
+		if ref, ok := ownedInputRef(input); ok && !seen[ref.ObjectID] {
+			seen[ref.ObjectID] = true
+			ids = append(ids, ref.ObjectID)
+		}
+	}
+	return ids
+}
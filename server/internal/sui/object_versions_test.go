@@ -0,0 +1,116 @@
+package sui
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+)
+
+func TestConflictingObjects(t *testing.T) {
+	const staleID = "0x5f1c0b6c3a2b84e3c9a51d07e4f6e83a29b3d0b0c11f0a5e2d4c6b8a9e7f1d23"
+	client := NewSuiClient("")
+
+	err := errors.New("Object (" + staleID + ", SequenceNumber(5), o#abc) is not available for consumption, its current version: SequenceNumber(6)")
+	if !IsVersionConflict(err) {
+		t.Fatal("Expected a version conflict to be detected")
+	}
+	if ids := client.conflictingObjects(err, models.TxnMetaData{}); len(ids) != 1 || ids[0] != staleID {
+		t.Errorf("Expected stale object %s, got %v", staleID, ids)
+	}
+
+	// Without IDs in the error, every object the transaction used is named.
+	meta := models.TxnMetaData{InputObjects: []interface{}{
+		map[string]interface{}{"ImmOrOwnedMoveObject": map[string]interface{}{"objectId": "0x1", "version": float64(7), "digest": "d"}},
+	}}
+	if ids := client.conflictingObjects(errors.New("ObjectVersionUnavailableForConsumption"), meta); len(ids) != 1 || ids[0] != "0x1" {
+		t.Errorf("Expected input object 0x1, got %v", ids)
+	}
+
+	if IsVersionConflict(errors.New("InsufficientGas")) {
+		t.Error("Expected InsufficientGas not to be treated as a version conflict")
+	}
+}
+
+func TestExecuteWithRebuildRebuildsOnce(t *testing.T) {
+	client := NewSuiClient("")
+	conflict := errors.New("Object (0x1, SequenceNumber(5), o#abc) is not available for consumption")
+
+	attempts := 0
+	_, err := client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		attempts++
+		if attempts == 1 {
+			return models.TxnMetaData{}, models.SuiTransactionBlockResponse{}, conflict
+		}
+		return models.TxnMetaData{}, models.SuiTransactionBlockResponse{Digest: "tx"}, nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Expected the rebuilt transaction to succeed, got %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	_, err = client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		attempts++
+		return models.TxnMetaData{}, models.SuiTransactionBlockResponse{}, conflict
+	})
+	var persisted *VersionConflictError
+	if !errors.As(err, &persisted) || attempts != 2 {
+		t.Errorf("Expected a VersionConflictError after one rebuild, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestObjectVersionCacheKeepsTheNewestAndIsBounded(t *testing.T) {
+	cache := newObjectVersionCache(2)
+	cache.record(ObjectRef{ObjectID: "0x1", Version: 5})
+	cache.record(ObjectRef{ObjectID: "0x1", Version: 4}) // Seen late, e.g. in an old prepared transaction
+	if ref, _ := cache.get("0x1"); ref.Version != 5 {
+		t.Fatalf("Expected version 5 to stay cached, got %d", ref.Version)
+	}
+	cache.record(ObjectRef{ObjectID: "0x2", Version: 1})
+	cache.record(ObjectRef{ObjectID: "0x1", Version: 6})
+	cache.record(ObjectRef{ObjectID: "0x3", Version: 1})
+	if _, ok := cache.get("0x2"); ok {
+		t.Error("Expected the object seen least recently to be dropped")
+	}
+	if _, ok := cache.get("0x1"); !ok {
+		t.Error("Expected a recently seen object to stay cached")
+	}
+}
+
+func TestPersistentConflictNamesTheObjectsThatMoved(t *testing.T) {
+	api := &objectsAPI{objects: map[string]*models.SuiObjectData{
+		"0x1": {ObjectId: "0x1", Version: "8"},
+		"0x2": {ObjectId: "0x2", Version: "3"},
+	}}
+	client := NewSuiClientWithAPI("stub", api)
+	meta := models.TxnMetaData{InputObjects: []interface{}{
+		map[string]interface{}{"ImmOrOwnedMoveObject": map[string]interface{}{"objectId": "0x1", "version": float64(7), "digest": "d1"}},
+		map[string]interface{}{"ImmOrOwnedMoveObject": map[string]interface{}{"objectId": "0x2", "version": "3", "digest": "d2"}},
+	}}
+	conflict := errors.New("ObjectVersionUnavailableForConsumption")
+
+	// The error names no object; the cache, refreshed on the conflict, tells
+	// that only 0x1 moved on since the transaction was prepared.
+	_, err := client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		return meta, models.SuiTransactionBlockResponse{}, conflict
+	})
+	var persisted *VersionConflictError
+	if !errors.As(err, &persisted) || len(persisted.ObjectIDs) != 1 || persisted.ObjectIDs[0] != "0x1" {
+		t.Fatalf("Expected a conflict on 0x1 alone, got %v", err)
+	}
+	if ref, ok := client.CachedObjectVersion("0x1"); !ok || ref.Version != 8 {
+		t.Fatalf("Expected 0x1 cached at version 8, got %+v", ref)
+	}
+
+	// Versions a transaction leaves its objects at are cached, so a later
+	// conflict on them needs no lookup.
+	var executed models.SuiTransactionBlockResponse
+	executed.Effects.Mutated = []models.OwnedObjectRef{{Reference: models.SuiObjectRef{ObjectId: "0x2", Version: 4}}}
+	client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		return meta, executed, nil
+	})
+	calls := api.calls
+	if stale := client.staleObjects(conflict, meta); len(stale) != 2 || api.calls != calls {
+		t.Errorf("Expected both objects named from the cache, got %v after %d lookups", stale, api.calls-calls)
+	}
+}
@@ -0,0 +1,262 @@
+package sui
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+const (
+	// DefaultRPCHealthCheckInterval is how often endpoints are probed when no interval is configured.
+	DefaultRPCHealthCheckInterval = 15 * time.Second
+	// rpcProbeTimeout bounds a single health probe.
+	rpcProbeTimeout = 5 * time.Second
+	// rpcFailureThreshold is the number of consecutive failed probes before an endpoint is marked unhealthy.
+	rpcFailureThreshold = 3
+	// rpcLatencySmoothing is the weight of the newest sample in the latency moving average.
+	rpcLatencySmoothing = 0.2
+)
+
+// rpcEndpoint is one configured RPC node with its running statistics.
+type rpcEndpoint struct {
+	url string
+	api sui.ISuiAPI
+
+	mu                  sync.Mutex
+	healthy             bool
+	avgLatency          time.Duration
+	consecutiveFailures int
+	totalRequests       uint64
+	totalErrors         uint64
+	lastError           string
+	lastChecked         time.Time
+}
+
+// EndpointStatus is a snapshot of an RPC endpoint's health, for diagnostics.
+type EndpointStatus struct {
+	URL                 string        `json:"url"`
+	Active              bool          `json:"active"`
+	Healthy             bool          `json:"healthy"`
+	AvgLatency          time.Duration `json:"avgLatency"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	TotalRequests       uint64        `json:"totalRequests"`
+	TotalErrors         uint64        `json:"totalErrors"`
+	ErrorRate           float64       `json:"errorRate"`
+	LastError           string        `json:"lastError,omitempty"`
+	LastChecked         time.Time     `json:"lastChecked"`
+}
+
+func newRPCEndpoint(url string) *rpcEndpoint {
+	return &rpcEndpoint{
+		url:     url,
+		api:     sui.NewSuiClient(url),
+		healthy: true, // Assume healthy until probes say otherwise
+	}
+}
+
+// recordCall updates request/error counters and the latency average.
+func (e *rpcEndpoint) recordCall(latency time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.totalRequests++
+	if err != nil {
+		e.totalErrors++
+		e.lastError = err.Error()
+		return
+	}
+	if e.avgLatency == 0 {
+		e.avgLatency = latency
+	} else {
+		e.avgLatency = time.Duration(rpcLatencySmoothing*float64(latency) + (1-rpcLatencySmoothing)*float64(e.avgLatency))
+	}
+}
+
+// recordProbe updates health based on a probe result and reports whether health changed.
+func (e *rpcEndpoint) recordProbe(latency time.Duration, err error) (changed bool) {
+	e.recordCall(latency, err)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastChecked = time.Now()
+	wasHealthy := e.healthy
+	if err != nil {
+		e.consecutiveFailures++
+		if e.consecutiveFailures >= rpcFailureThreshold {
+			e.healthy = false
+		}
+	} else {
+		e.consecutiveFailures = 0
+		e.healthy = true
+	}
+	return wasHealthy != e.healthy
+}
+
+func (e *rpcEndpoint) isHealthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy
+}
+
+func (e *rpcEndpoint) status(active bool) EndpointStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := EndpointStatus{
+		URL:                 e.url,
+		Active:              active,
+		Healthy:             e.healthy,
+		AvgLatency:          e.avgLatency,
+		ConsecutiveFailures: e.consecutiveFailures,
+		TotalRequests:       e.totalRequests,
+		TotalErrors:         e.totalErrors,
+		LastError:           e.lastError,
+		LastChecked:         e.lastChecked,
+	}
+	if e.totalRequests > 0 {
+		status.ErrorRate = float64(e.totalErrors) / float64(e.totalRequests)
+	}
+	return status
+}
+
+// activeEndpoint returns the endpoint currently serving requests.
+func (c *SuiClient) activeEndpoint() *rpcEndpoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.endpoints[c.active]
+}
+
+// FaultInjector fails RPC calls on purpose, for chaos testing. SuiCall runs
+// before each call; an error fails the call as if the node had returned it.
+type FaultInjector interface {
+	SuiCall(ctx context.Context) error
+}
+
+// SetFaultInjector makes every RPC call go through faults first.
+func (c *SuiClient) SetFaultInjector(faults FaultInjector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = faults
+}
+
+// callActive runs fn against the active endpoint and records the outcome in its statistics.
+func callActive[T any](c *SuiClient, fn func(api sui.ISuiAPI) (T, error)) (T, error) {
+	c.mu.RLock()
+	endpoint, faults := c.endpoints[c.active], c.faults
+	c.mu.RUnlock()
+	start := time.Now()
+	if faults != nil {
+		if err := faults.SuiCall(context.Background()); err != nil {
+			endpoint.recordCall(time.Since(start), err)
+			var zero T
+			return zero, err
+		}
+	}
+	result, err := fn(endpoint.api)
+	endpoint.recordCall(time.Since(start), err)
+	return result, err
+}
+
+// ActiveEndpointURL returns the URL of the RPC node currently serving requests.
+func (c *SuiClient) ActiveEndpointURL() string {
+	return c.activeEndpoint().url
+}
+
+// EndpointStatuses returns a snapshot of every configured RPC endpoint, in priority order.
+func (c *SuiClient) EndpointStatuses() []EndpointStatus {
+	c.mu.RLock()
+	active := c.active
+	endpoints := c.endpoints
+	c.mu.RUnlock()
+
+	statuses := make([]EndpointStatus, len(endpoints))
+	for i, endpoint := range endpoints {
+		statuses[i] = endpoint.status(i == active)
+	}
+	return statuses
+}
+
+// StartHealthMonitor probes every configured endpoint in the background and
+// fails over to the highest-priority healthy one. The primary is preferred again
+// as soon as it recovers. Calling it more than once has no effect.
+func (c *SuiClient) StartHealthMonitor(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRPCHealthCheckInterval
+	}
+	c.mu.Lock()
+	if c.monitorStop != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.monitorStop = make(chan struct{})
+	stop := c.monitorStop
+	c.mu.Unlock()
+
+	utils.LogInfof("SUI Client: Starting RPC health monitor for %d endpoint(s), interval %s", len(c.endpoints), interval)
+	go func() {
+		c.probeEndpoints()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				c.probeEndpoints()
+			}
+		}
+	}()
+}
+
+// StopHealthMonitor stops the background monitor started by StartHealthMonitor.
+func (c *SuiClient) StopHealthMonitor() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.monitorStop != nil {
+		close(c.monitorStop)
+		c.monitorStop = nil
+	}
+}
+
+// probeEndpoints checks every endpoint concurrently and then re-selects the active one.
+func (c *SuiClient) probeEndpoints() {
+	var wg sync.WaitGroup
+	for _, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func(endpoint *rpcEndpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), rpcProbeTimeout)
+			defer cancel()
+			start := time.Now()
+			_, err := endpoint.api.SuiGetLatestCheckpointSequenceNumber(ctx)
+			if endpoint.recordProbe(time.Since(start), err) {
+				if err != nil {
+					utils.LogWarnf("SUI Client: RPC endpoint %s marked unhealthy after %d failed probes: %v", endpoint.url, rpcFailureThreshold, err)
+				} else {
+					utils.LogInfof("SUI Client: RPC endpoint %s recovered.", endpoint.url)
+				}
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	c.selectActiveEndpoint()
+}
+
+// selectActiveEndpoint switches to the first healthy endpoint in priority order.
+// If none are healthy the current endpoint is kept.
+func (c *SuiClient) selectActiveEndpoint() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, endpoint := range c.endpoints {
+		if !endpoint.isHealthy() {
+			continue
+		}
+		if i != c.active {
+			utils.LogWarnf("SUI Client: Failing over RPC endpoint from %s to %s", c.endpoints[c.active].url, endpoint.url)
+			c.active = i
+		}
+		return
+	}
+	utils.LogErrorf("SUI Client: No healthy RPC endpoints; staying on %s", c.endpoints[c.active].url)
+}
@@ -0,0 +1,77 @@
+package sui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/block-vision/sui-go-sdk/models"
+)
+
+// ErrTransactionDropped reports that a signed transaction can never execute
+// successfully: it ran and failed, or an object it uses has moved on to a
+// newer version. A replacement may be signed without risk of both running.
+var ErrTransactionDropped = errors.New("the signed transaction can no longer execute")
+
+// SignedTransaction is a server transaction that is signed but not yet known
+// to have executed. Its digest is known before it is submitted, so it can be
+// saved first and looked up on chain after a crash or an unanswered submit.
+type SignedTransaction struct {
+	TxBytes   string `json:"txBytes"`
+	Signature string `json:"signature"`
+	Digest    string `json:"digest"`
+}
+
+// SubmitSigned executes tx unless the chain already has it, and returns its
+// response. Submitting the same transaction again never runs it twice: it
+// is looked up by digest first, and the node returns the effects of bytes it
+// already executed. An error wrapping ErrTransactionDropped means tx can never
+// run; any other error leaves it unknown, and tx should be submitted again.
+func (c *SuiClient) SubmitSigned(tx SignedTransaction) (models.SuiTransactionBlockResponse, error) {
+	if resp, err := c.GetTransactionBlock(tx.Digest); err == nil && resp.Digest != "" {
+		return resp, executedResult(resp)
+	}
+	resp, err := c.ExecuteTransactionBlock(tx.TxBytes, []string{tx.Signature})
+	if err == nil {
+		return resp, executedResult(resp)
+	}
+	if !IsVersionConflict(err) {
+		return resp, fmt.Errorf("failed to execute transaction %s: %w", tx.Digest, err)
+	}
+	// A stale object may also mean tx itself consumed it.
+	if found, lookupErr := c.GetTransactionBlock(tx.Digest); lookupErr == nil && found.Digest != "" {
+		return found, executedResult(found)
+	}
+	return resp, fmt.Errorf("%w: transaction %s: %w", ErrTransactionDropped, tx.Digest, err)
+}
+
+// signAsServer builds a transaction from sender paying with gasObjectID,
+// dry-runs it against gasBudget and signs it with privateKey, without
+// executing it. The signer pool is not used: a signed transaction may wait
+// for a retry, and would hold a pool gas coin meanwhile.
+func (c *SuiClient) signAsServer(sender, gasObjectID string, gasBudget uint64, privateKey string, build TxBuilder) (SignedTransaction, error) {
+	tx, err := build(sender, gasObjectID)
+	if err != nil {
+		return SignedTransaction{}, err
+	}
+	if err := c.preflightWithin(tx.TxBytes, gasBudget); err != nil {
+		return SignedTransaction{}, err
+	}
+	signature, err := SignTransactionBytesWithServerKey(tx.TxBytes, privateKey)
+	if err != nil {
+		return SignedTransaction{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	digest, err := TransactionDigest(tx.TxBytes)
+	if err != nil {
+		return SignedTransaction{}, err
+	}
+	return SignedTransaction{TxBytes: tx.TxBytes, Signature: signature, Digest: digest}, nil
+}
+
+// executedResult is nil for a transaction that executed successfully. One
+// that executed and failed is dropped: its objects have moved on.
+func executedResult(resp models.SuiTransactionBlockResponse) error {
+	if err := checkExecutionEffects(resp); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionDropped, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// ledgerAPI remembers the transactions it executed. Unknown digests are not
+// found, as on a real node.
+type ledgerAPI struct {
+	sui.ISuiAPI
+	executed   map[string]string // Digest -> effects status
+	executes   int
+	executeErr error  // Returned by execute calls instead of running them
+	failure    string // Execution error of transactions that run, if any
+}
+
+func (a *ledgerAPI) SuiExecuteTransactionBlock(ctx context.Context, req models.SuiExecuteTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	a.executes++
+	if a.executeErr != nil {
+		return models.SuiTransactionBlockResponse{}, a.executeErr
+	}
+	digest, _ := TransactionDigest(req.TxBytes)
+	a.executed[digest] = "success"
+	if a.failure != "" {
+		a.executed[digest] = "failure"
+	}
+	return a.SuiGetTransactionBlock(ctx, models.SuiGetTransactionBlockRequest{Digest: digest})
+}
+
+func (a *ledgerAPI) SuiGetTransactionBlock(ctx context.Context, req models.SuiGetTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	status, ok := a.executed[req.Digest]
+	if !ok {
+		return models.SuiTransactionBlockResponse{}, errors.New("Could not find the referenced transaction")
+	}
+	var resp models.SuiTransactionBlockResponse
+	resp.Digest = req.Digest
+	resp.Effects.Status.Status = status
+	if status != "success" {
+		resp.Effects.Status.Error = "InsufficientCoinBalance"
+	}
+	return resp, nil
+}
+
+func TestSubmitSignedRunsATransactionOnce(t *testing.T) {
+	api := &ledgerAPI{executed: make(map[string]string)}
+	client := NewSuiClientWithAPI("stub", api)
+	digest, _ := TransactionDigest("cGF5b3V0")
+	tx := SignedTransaction{TxBytes: "cGF5b3V0", Signature: "sig", Digest: digest}
+
+	// An unanswered submit leaves the transaction unknown, to be submitted again.
+	api.executeErr = errors.New("context deadline exceeded")
+	if _, err := client.SubmitSigned(tx); err == nil || errors.Is(err, ErrTransactionDropped) {
+		t.Fatalf("Expected an unknown outcome, got %v", err)
+	}
+	api.executeErr = nil
+	if resp, err := client.SubmitSigned(tx); err != nil || resp.Digest != digest {
+		t.Fatalf("SubmitSigned = %s, %v", resp.Digest, err)
+	}
+	// Once the chain has it, it is found rather than submitted again.
+	if _, err := client.SubmitSigned(tx); err != nil || api.executes != 2 {
+		t.Fatalf("Expected the second submit to find the transaction, got %v after %d executes", err, api.executes)
+	}
+
+	// A stale object the transaction did not consume itself drops it.
+	stale, _ := TransactionDigest("c3RhbGU=")
+	api.executeErr = errors.New("Transaction needs to be rebuilt because object 0x5 version 0x2 is unavailable for consumption")
+	if _, err := client.SubmitSigned(SignedTransaction{TxBytes: "c3RhbGU=", Signature: "sig", Digest: stale}); !errors.Is(err, ErrTransactionDropped) {
+		t.Fatalf("Expected a stale transaction to be dropped, got %v", err)
+	}
+
+	// A transaction that ran and failed is dropped too.
+	api.executeErr, api.failure = nil, "InsufficientCoinBalance"
+	failed, _ := TransactionDigest("ZmFpbA==")
+	_, err := client.SubmitSigned(SignedTransaction{TxBytes: "ZmFpbA==", Signature: "sig", Digest: failed})
+	var txErr *TransactionFailedError
+	if !errors.Is(err, ErrTransactionDropped) || !errors.As(err, &txErr) {
+		t.Fatalf("Expected a failed transaction to be dropped, got %v", err)
+	}
+}
@@ -0,0 +1,545 @@
+package sui
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/mr-tron/base58"
+	"github.com/phuhao00/suigserver/server/internal/keys"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+	"golang.org/x/crypto/blake2b"
+)
+
+// SignerSelection is the strategy a SignerPool uses to pick a signer.
+type SignerSelection string
+
+const (
+	// SelectRoundRobin rotates through signers in order.
+	SelectRoundRobin SignerSelection = "round_robin"
+	// SelectLeastBusy picks the signer with the fewest in-flight transactions.
+	SelectLeastBusy SignerSelection = "least_busy"
+)
+
+// quarantineRetryInterval is how often the pool checks whether the transaction
+// holding a quarantined gas coin has executed.
+const quarantineRetryInterval = 10 * time.Second
+
+// PoolSignerConfig describes one server address in a SignerPool.
+type PoolSignerConfig struct {
+	Address    string        // Sui address the key controls
+	GasCoinIDs []string      // Gas coins owned by Address; each backs at most one in-flight transaction
+	Keys       *keys.Manager // Source of the address's private key
+}
+
+// poolSigner is a signer's runtime state. All fields are guarded by SignerPool.mu.
+type poolSigner struct {
+	address  string
+	keys     *keys.Manager
+	gasCoins []string
+	gasInUse map[string]bool
+	inFlight int
+	nonce    uint64 // Monotonic per-signer sequence number assigned to each lease
+	signed   uint64
+}
+
+func (s *poolSigner) freeGasCoin() string {
+	for _, coin := range s.gasCoins {
+		if !s.gasInUse[coin] {
+			return coin
+		}
+	}
+	return ""
+}
+
+// SignerPool spreads server transactions over several addresses so on-chain
+// writes are not serialized on a single address's gas coin.
+//
+// Equivocation protection: Sui locks an owned object (such as a gas coin) for
+// the rest of the epoch if two different transactions using the same object
+// version are signed. The pool therefore leases every gas coin to at most one
+// transaction at a time, and a lease refuses to sign a second, different
+// transaction. When the node does not answer an execute call, the transaction
+// may still run, so its gas coin is quarantined until the transaction is found
+// on chain instead of going back to the pool.
+type SignerPool struct {
+	strategy        SignerSelection
+	quarantineRetry time.Duration
+
+	mu          sync.Mutex
+	signers     []*poolSigner
+	next        int                         // Round-robin cursor
+	released    chan struct{}               // Closed and replaced whenever a gas coin is freed
+	quarantined map[string]*quarantinedCoin // Gas coin ID -> its unconfirmed transaction
+}
+
+// quarantinedCoin is a gas coin whose transaction was submitted without the
+// node confirming it.
+type quarantinedCoin struct {
+	signer    *poolSigner
+	client    *SuiClient
+	digest    string // "" when the transaction bytes could not be decoded
+	txBytes   string
+	signature string
+	checkedAt time.Time
+}
+
+// NewSignerPool validates the signer configuration and creates a pool.
+func NewSignerPool(strategy SignerSelection, signers []PoolSignerConfig) (*SignerPool, error) {
+	if strategy == "" {
+		strategy = SelectRoundRobin
+	}
+	if strategy != SelectRoundRobin && strategy != SelectLeastBusy {
+		return nil, fmt.Errorf("unknown signer selection strategy %q", strategy)
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("signer pool requires at least one signer")
+	}
+
+	pool := &SignerPool{
+		strategy:        strategy,
+		quarantineRetry: quarantineRetryInterval,
+		released:        make(chan struct{}),
+		quarantined:     make(map[string]*quarantinedCoin),
+	}
+	seenAddresses := make(map[string]bool)
+	seenCoins := make(map[string]bool)
+	for _, cfg := range signers {
+		if cfg.Address == "" || cfg.Keys == nil {
+			return nil, fmt.Errorf("signer pool entry requires an address and a key manager")
+		}
+		if seenAddresses[cfg.Address] {
+			return nil, fmt.Errorf("signer %s is configured more than once", cfg.Address)
+		}
+		seenAddresses[cfg.Address] = true
+		if len(cfg.GasCoinIDs) == 0 {
+			return nil, fmt.Errorf("signer %s has no gas coins", cfg.Address)
+		}
+		for _, coin := range cfg.GasCoinIDs {
+			if seenCoins[coin] {
+				return nil, fmt.Errorf("gas coin %s is assigned to more than one signer", coin)
+			}
+			seenCoins[coin] = true
+		}
+		pool.signers = append(pool.signers, &poolSigner{
+			address:  cfg.Address,
+			keys:     cfg.Keys,
+			gasCoins: append([]string(nil), cfg.GasCoinIDs...),
+			gasInUse: make(map[string]bool),
+		})
+	}
+	utils.LogInfof("SignerPool: Initialized with %d signer(s), %d gas coin(s), strategy %s", len(pool.signers), len(seenCoins), strategy)
+	return pool, nil
+}
+
+// SignerLease grants exclusive use of one signer's gas coin for a single transaction.
+// It must be released exactly once.
+type SignerLease struct {
+	pool   *SignerPool
+	signer *poolSigner
+
+	Address   string // Sender address for the transaction
+	GasCoinID string // Gas coin to pay with
+	Nonce     uint64 // Per-signer sequence number, for logs and correlation
+
+	mu          sync.Mutex
+	signedBytes string
+	signature   string
+	released    bool
+}
+
+// Has reports whether address is one of the pool's signers.
+func (p *SignerPool) Has(address string) bool {
+	return p.signer(address) != nil
+}
+
+func (p *SignerPool) signer(address string) *poolSigner {
+	for _, signer := range p.signers {
+		if signer.address == address {
+			return signer
+		}
+	}
+	return nil
+}
+
+// Acquire waits until a signer with a free gas coin is available, or ctx is done.
+func (p *SignerPool) Acquire(ctx context.Context) (*SignerLease, error) {
+	return p.acquire(ctx, nil)
+}
+
+// AcquireAs is Acquire for a transaction only sender may sign: it waits for a
+// free gas coin of that signer.
+func (p *SignerPool) AcquireAs(ctx context.Context, sender string) (*SignerLease, error) {
+	signer := p.signer(sender)
+	if signer == nil {
+		return nil, fmt.Errorf("%s is not a signer in the pool", sender)
+	}
+	return p.acquire(ctx, signer)
+}
+
+func (p *SignerPool) acquire(ctx context.Context, only *poolSigner) (*SignerLease, error) {
+	for {
+		p.settleQuarantined()
+		p.mu.Lock()
+		if lease := p.tryAcquireLocked(only); lease != nil {
+			p.mu.Unlock()
+			return lease, nil
+		}
+		released := p.released
+		var retry <-chan time.Time
+		if len(p.quarantined) > 0 {
+			retry = time.After(p.quarantineRetry)
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no signer available: %w", ctx.Err())
+		case <-released:
+		case <-retry:
+		}
+	}
+}
+
+// tryAcquireLocked leases a free gas coin of only, or of the signer the
+// strategy picks when only is nil.
+func (p *SignerPool) tryAcquireLocked(only *poolSigner) *SignerLease {
+	var chosen *poolSigner
+	var coin string
+	switch {
+	case only != nil:
+		if free := only.freeGasCoin(); free != "" {
+			chosen, coin = only, free
+		}
+	case p.strategy == SelectLeastBusy:
+		for _, signer := range p.signers {
+			if free := signer.freeGasCoin(); free != "" && (chosen == nil || signer.inFlight < chosen.inFlight) {
+				chosen, coin = signer, free
+			}
+		}
+	default:
+		for i := 0; i < len(p.signers); i++ {
+			idx := (p.next + i) % len(p.signers)
+			if free := p.signers[idx].freeGasCoin(); free != "" {
+				chosen, coin = p.signers[idx], free
+				p.next = (idx + 1) % len(p.signers)
+				break
+			}
+		}
+	}
+	if chosen == nil {
+		return nil
+	}
+
+	chosen.gasInUse[coin] = true
+	chosen.inFlight++
+	chosen.nonce++
+	return &SignerLease{
+		pool:      p,
+		signer:    chosen,
+		Address:   chosen.address,
+		GasCoinID: coin,
+		Nonce:     chosen.nonce,
+	}
+}
+
+// Sign signs txBytes with the leased signer's key. Signing the same bytes again
+// returns the same signature (safe for retries); signing different bytes under
+// the same lease is refused to prevent equivocation on the gas coin.
+func (l *SignerLease) Sign(txBytes string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return "", fmt.Errorf("signer lease for %s (nonce %d) has already been released", l.Address, l.Nonce)
+	}
+	if l.signedBytes != "" {
+		if l.signedBytes != txBytes {
+			utils.LogErrorf("SignerPool: Refusing to sign a second transaction with gas coin %s of %s (nonce %d)", l.GasCoinID, l.Address, l.Nonce)
+			return "", fmt.Errorf("lease for gas coin %s already signed a different transaction", l.GasCoinID)
+		}
+		return l.signature, nil
+	}
+
+	privateKey, err := l.signer.keys.PrivateKey()
+	if err != nil {
+		return "", fmt.Errorf("no key for signer %s: %w", l.Address, err)
+	}
+	signature, err := SignTransactionBytesWithServerKey(txBytes, privateKey)
+	if err != nil {
+		return "", err
+	}
+	l.signedBytes = txBytes
+	l.signature = signature
+
+	l.pool.mu.Lock()
+	l.signer.signed++
+	l.pool.mu.Unlock()
+	return signature, nil
+}
+
+// Release returns the gas coin to the pool. Extra calls are ignored.
+func (l *SignerLease) Release() {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return
+	}
+	l.released = true
+	l.mu.Unlock()
+
+	p := l.pool
+	p.mu.Lock()
+	delete(l.signer.gasInUse, l.GasCoinID)
+	l.signer.inFlight--
+	p.signalReleasedLocked()
+	p.mu.Unlock()
+}
+
+func (p *SignerPool) signalReleasedLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// quarantine ends the lease without freeing its gas coin, because the
+// transaction signed under it may have executed. The coin stays out of the
+// pool until settleQuarantined finds the transaction on chain.
+func (l *SignerLease) quarantine(client *SuiClient) {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return
+	}
+	l.released = true
+	txBytes, signature := l.signedBytes, l.signature
+	l.mu.Unlock()
+
+	digest, err := TransactionDigest(txBytes)
+	if err != nil {
+		utils.LogWarnf("SignerPool: %v", err)
+	}
+	p := l.pool
+	p.mu.Lock()
+	l.signer.inFlight--
+	p.quarantined[l.GasCoinID] = &quarantinedCoin{
+		signer:    l.signer,
+		client:    client,
+		digest:    digest,
+		txBytes:   txBytes,
+		signature: signature,
+		checkedAt: time.Now(),
+	}
+	p.mu.Unlock()
+	utils.LogWarnf("SignerPool: Gas coin %s of %s is quarantined until transaction %s (nonce %d) is known to have executed", l.GasCoinID, l.Address, digest, l.Nonce)
+}
+
+// settleQuarantined frees quarantined gas coins whose transactions have
+// executed. Each coin is checked at most once per quarantineRetry.
+func (p *SignerPool) settleQuarantined() {
+	p.mu.Lock()
+	if len(p.quarantined) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	due := make(map[string]*quarantinedCoin)
+	now := time.Now()
+	for coin, q := range p.quarantined {
+		if now.Sub(q.checkedAt) >= p.quarantineRetry {
+			q.checkedAt = now
+			due[coin] = q
+		}
+	}
+	p.mu.Unlock()
+
+	for coin, q := range due {
+		digest, ok := q.settle()
+		if !ok {
+			continue
+		}
+		q.client.ForgetObjects(coin)
+		p.mu.Lock()
+		delete(p.quarantined, coin)
+		delete(q.signer.gasInUse, coin)
+		p.signalReleasedLocked()
+		p.mu.Unlock()
+		utils.LogInfof("SignerPool: Transaction %s executed; gas coin %s of %s is back in the pool", digest, coin, q.signer.address)
+	}
+}
+
+// settle reports whether the quarantined transaction has executed. It looks
+// the transaction up by digest and, if the node does not know it, submits
+// the same signed bytes again: executing them twice cannot equivocate, and
+// either runs the transaction or returns its existing effects.
+func (q *quarantinedCoin) settle() (string, bool) {
+	if q.digest != "" {
+		if resp, err := q.client.GetTransactionBlock(q.digest); err == nil && resp.Digest != "" {
+			return resp.Digest, true
+		}
+	}
+	resp, err := q.client.ExecuteTransactionBlock(q.txBytes, []string{q.signature})
+	if err != nil || resp.Digest == "" {
+		utils.LogWarnf("SignerPool: Transaction %s on quarantined gas coin is still unconfirmed: %v", q.digest, err)
+		return "", false
+	}
+	return resp.Digest, true
+}
+
+// TransactionDigest returns the digest Sui gives the transaction in txBytes
+// (base64 BCS TransactionData): the base58 Blake2b-256 hash of the bytes,
+// prefixed with "TransactionData::".
+func TransactionDigest(txBytes string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		return "", fmt.Errorf("transaction bytes are not base64: %w", err)
+	}
+	sum := blake2b.Sum256(append([]byte("TransactionData::"), raw...))
+	return base58.Encode(sum[:]), nil
+}
+
+// TxBuilder prepares a transaction for the given sender and gas coin, typically via SuiClient.MoveCall.
+type TxBuilder func(sender, gasCoinID string) (models.TxnMetaData, error)
+
+// Execute leases a signer, builds the transaction with it, dry-runs, signs and
+// executes it, and releases the signer afterwards. Failures reported by the
+// network are returned as *TransactionFailedError. If an object changed version
+// since the transaction was built, it is rebuilt once under a fresh lease.
+// build must use gasBudget; a dry run suggesting more rejects the transaction
+// before it is signed.
+func (p *SignerPool) Execute(ctx context.Context, client *SuiClient, gasBudget uint64, build TxBuilder) (models.SuiTransactionBlockResponse, error) {
+	return client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		return p.executeOnce(ctx, client, nil, gasBudget, build)
+	})
+}
+
+// ExecuteAs is Execute for a transaction only sender may sign, such as one
+// moving objects sender holds in custody. sender must be in the pool.
+func (p *SignerPool) ExecuteAs(ctx context.Context, client *SuiClient, sender string, gasBudget uint64, build TxBuilder) (models.SuiTransactionBlockResponse, error) {
+	signer := p.signer(sender)
+	if signer == nil {
+		return models.SuiTransactionBlockResponse{}, fmt.Errorf("%s is not a signer in the pool", sender)
+	}
+	return client.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		return p.executeOnce(ctx, client, signer, gasBudget, build)
+	})
+}
+
+// UseSignerPool has the client sign server transactions with pool. Those any
+// server address may send are spread over all of its signers; those only one
+// address may sign go through the pool when that address is one of its
+// signers. The rest are still signed with the key their caller passes.
+func (c *SuiClient) UseSignerPool(pool *SignerPool) {
+	c.signers = pool
+}
+
+// executeFromAnyServer executes a server transaction that does not depend on
+// which address sends it. Without a signer pool it is sent by sender, paying
+// with gasObjectID and signed with privateKey.
+func (c *SuiClient) executeFromAnyServer(sender, gasObjectID string, gasBudget uint64, privateKey string, build TxBuilder) (models.SuiTransactionBlockResponse, error) {
+	if c.signers != nil {
+		return c.signers.Execute(context.Background(), c, gasBudget, build)
+	}
+	return c.executeWithKey(sender, gasObjectID, gasBudget, privateKey, build)
+}
+
+// executeAsServer executes a server transaction only sender may sign, through
+// the signer pool if sender is one of its signers and with privateKey and
+// gasObjectID otherwise.
+func (c *SuiClient) executeAsServer(sender, gasObjectID string, gasBudget uint64, privateKey string, build TxBuilder) (models.SuiTransactionBlockResponse, error) {
+	if c.signers != nil && c.signers.Has(sender) {
+		return c.signers.ExecuteAs(context.Background(), c, sender, gasBudget, build)
+	}
+	return c.executeWithKey(sender, gasObjectID, gasBudget, privateKey, build)
+}
+
+// executeWithKey builds a transaction from sender paying with gasObjectID,
+// dry-runs it against gasBudget, signs it with privateKey and executes it,
+// rebuilding it once on an object version conflict.
+func (c *SuiClient) executeWithKey(sender, gasObjectID string, gasBudget uint64, privateKey string, build TxBuilder) (models.SuiTransactionBlockResponse, error) {
+	return c.ExecuteWithRebuild(func() (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+		tx, err := build(sender, gasObjectID)
+		if err != nil {
+			return tx, models.SuiTransactionBlockResponse{}, err
+		}
+		if err := c.preflightWithin(tx.TxBytes, gasBudget); err != nil {
+			return tx, models.SuiTransactionBlockResponse{}, err
+		}
+		signature, err := SignTransactionBytesWithServerKey(tx.TxBytes, privateKey)
+		if err != nil {
+			return tx, models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to sign transaction: %w", err)
+		}
+		executeResponse, err := c.ExecuteTransactionBlock(tx.TxBytes, []string{signature})
+		if err != nil {
+			return tx, models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to execute transaction: %w", err)
+		}
+		return tx, executeResponse, checkExecutionEffects(executeResponse)
+	})
+}
+
+func (p *SignerPool) executeOnce(ctx context.Context, client *SuiClient, only *poolSigner, gasBudget uint64, build TxBuilder) (models.TxnMetaData, models.SuiTransactionBlockResponse, error) {
+	lease, err := p.acquire(ctx, only)
+	if err != nil {
+		return models.TxnMetaData{}, models.SuiTransactionBlockResponse{}, err
+	}
+	defer lease.Release()
+
+	txMeta, err := build(lease.Address, lease.GasCoinID)
+	if err != nil {
+		return txMeta, models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to build transaction for signer %s: %w", lease.Address, err)
+	}
+	// Simulate first so doomed transactions are rejected before they are signed.
+	if err := client.preflightWithin(txMeta.TxBytes, gasBudget); err != nil {
+		return txMeta, models.SuiTransactionBlockResponse{}, err
+	}
+	signature, err := lease.Sign(txMeta.TxBytes)
+	if err != nil {
+		return txMeta, models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to sign transaction with signer %s: %w", lease.Address, err)
+	}
+	utils.LogDebugf("SignerPool: Executing transaction from %s (nonce %d, gas %s)", lease.Address, lease.Nonce, lease.GasCoinID)
+	response, err := client.ExecuteTransactionBlock(txMeta.TxBytes, []string{signature})
+	if err != nil {
+		// The node may have executed the transaction anyway, and a different
+		// transaction on the same gas coin version would equivocate.
+		lease.quarantine(client)
+		return txMeta, models.SuiTransactionBlockResponse{}, fmt.Errorf("failed to execute transaction from signer %s: %w", lease.Address, err)
+	}
+	if err := checkExecutionEffects(response); err != nil {
+		return txMeta, response, err
+	}
+	return txMeta, response, nil
+}
+
+// SignerStats is a snapshot of one signer's usage.
+type SignerStats struct {
+	Address      string `json:"address"`
+	InFlight     int    `json:"inFlight"`
+	FreeGasCoins int    `json:"freeGasCoins"`
+	Quarantined  int    `json:"quarantined"` // Gas coins held by unconfirmed transactions
+	Signed       uint64 `json:"signed"`
+	Nonce        uint64 `json:"nonce"`
+}
+
+// Stats returns per-signer usage, in configuration order.
+func (p *SignerPool) Stats() []SignerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]SignerStats, len(p.signers))
+	for i, signer := range p.signers {
+		quarantined := 0
+		for _, q := range p.quarantined {
+			if q.signer == signer {
+				quarantined++
+			}
+		}
+		stats[i] = SignerStats{
+			Address:      signer.address,
+			InFlight:     signer.inFlight,
+			FreeGasCoins: len(signer.gasCoins) - len(signer.gasInUse),
+			Quarantined:  quarantined,
+			Signed:       signer.signed,
+			Nonce:        signer.nonce,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,191 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/phuhao00/suigserver/server/internal/keys"
+)
+
+func newTestSignerPool(t *testing.T, strategy SignerSelection) *SignerPool {
+	t.Helper()
+	var signers []PoolSignerConfig
+	for _, addr := range []string{"0xa", "0xb"} {
+		manager := keys.NewManager(&keys.StaticProvider{Key: "0x" + addr[2:] + "1"})
+		if err := manager.Reload(); err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+		signers = append(signers, PoolSignerConfig{Address: addr, GasCoinIDs: []string{addr + "-gas"}, Keys: manager})
+	}
+	pool, err := NewSignerPool(strategy, signers)
+	if err != nil {
+		t.Fatalf("NewSignerPool failed: %v", err)
+	}
+	return pool
+}
+
+func TestSignerPoolLeasesEachGasCoinOnce(t *testing.T) {
+	pool := newTestSignerPool(t, SelectRoundRobin)
+
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	second, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if first.Address == second.Address {
+		t.Fatalf("Expected round robin to use both signers, got %s twice", first.Address)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); err == nil {
+		t.Fatal("Expected Acquire to block while every gas coin is leased")
+	}
+
+	first.Release()
+	third, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	if third.GasCoinID != first.GasCoinID || third.Nonce != first.Nonce+1 {
+		t.Errorf("Expected released coin %s with nonce %d, got %s with nonce %d", first.GasCoinID, first.Nonce+1, third.GasCoinID, third.Nonce)
+	}
+}
+
+func TestSignerLeaseRefusesEquivocation(t *testing.T) {
+	pool := newTestSignerPool(t, SelectLeastBusy)
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer lease.Release()
+
+	if _, err := lease.Sign("tx-one"); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if _, err := lease.Sign("tx-one"); err != nil {
+		t.Errorf("Expected re-signing the same bytes to succeed, got %v", err)
+	}
+	if _, err := lease.Sign("tx-two"); err == nil {
+		t.Error("Expected signing a different transaction with the same lease to fail")
+	}
+}
+
+// chainAPI builds object transfers and executes them. While down, execute
+// calls fail without saying whether the transaction ran, and lookups fail.
+type chainAPI struct {
+	sui.ISuiAPI
+	mu        sync.Mutex
+	down      bool
+	transfers []models.TransferObjectRequest
+	lookups   []string // Digests asked for
+}
+
+func (a *chainAPI) TransferObject(ctx context.Context, req models.TransferObjectRequest) (models.TxnMetaData, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.transfers = append(a.transfers, req)
+	return models.TxnMetaData{TxBytes: "dHJhbnNmZXI="}, nil
+}
+
+func (a *chainAPI) SuiDryRunTransactionBlock(ctx context.Context, req models.SuiDryRunTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	var resp models.SuiTransactionBlockResponse
+	resp.Effects.Status.Status = "success"
+	return resp, nil
+}
+
+func (a *chainAPI) SuiExecuteTransactionBlock(ctx context.Context, req models.SuiExecuteTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.down {
+		return models.SuiTransactionBlockResponse{}, errors.New("context deadline exceeded")
+	}
+	digest, _ := TransactionDigest(req.TxBytes)
+	return models.SuiTransactionBlockResponse{Digest: digest}, nil
+}
+
+func (a *chainAPI) SuiGetTransactionBlock(ctx context.Context, req models.SuiGetTransactionBlockRequest) (models.SuiTransactionBlockResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lookups = append(a.lookups, req.Digest)
+	if a.down {
+		return models.SuiTransactionBlockResponse{}, errors.New("connection refused")
+	}
+	return models.SuiTransactionBlockResponse{Digest: req.Digest}, nil
+}
+
+func TestServerTransactionsUseTheSignerPool(t *testing.T) {
+	pool := newTestSignerPool(t, SelectRoundRobin)
+	api := &chainAPI{}
+	client := NewSuiClientWithAPI("stub", api)
+	client.UseSignerPool(pool)
+
+	// Custody held by a pool signer is moved by that signer, with its gas coin.
+	if _, err := client.TransferObjectWithServerKey("0xb", "0xnft", "0xc", "0xconfigured-gas", 1000, ""); err != nil {
+		t.Fatalf("TransferObjectWithServerKey failed: %v", err)
+	}
+	if req := api.transfers[0]; req.Signer != "0xb" || req.Gas == nil || *req.Gas != "0xb-gas" {
+		t.Errorf("Expected the transfer to be sent by 0xb with 0xb-gas, got %+v", req)
+	}
+	if stats := pool.Stats(); stats[1].Signed != 1 || stats[1].InFlight != 0 {
+		t.Errorf("Expected 0xb to have signed once and be idle, got %+v", stats[1])
+	}
+
+	// Custody held by another address is still signed with the caller's key.
+	if _, err := client.TransferObjectWithServerKey("0xd", "0xnft", "0xc", "0xconfigured-gas", 1000, ""); err == nil {
+		t.Error("Expected a transfer from outside the pool to need the caller's key")
+	}
+	if _, err := pool.ExecuteAs(context.Background(), client, "0xd", 1000, nil); err == nil {
+		t.Error("Expected ExecuteAs to refuse a sender outside the pool")
+	}
+}
+
+func TestSignerPoolQuarantinesUnconfirmedGasCoin(t *testing.T) {
+	pool := newTestSignerPool(t, SelectRoundRobin)
+	pool.quarantineRetry = time.Hour
+	api := &chainAPI{down: true}
+	client := NewSuiClientWithAPI("stub", api)
+	build := func(sender, gasObjectID string) (models.TxnMetaData, error) {
+		return models.TxnMetaData{TxBytes: "bWludA=="}, nil
+	}
+
+	if _, err := pool.ExecuteAs(context.Background(), client, "0xa", 1000, build); err == nil {
+		t.Fatal("Expected the execute call to fail")
+	}
+	if stats := pool.Stats(); stats[0].Quarantined != 1 || stats[0].FreeGasCoins != 0 || stats[0].InFlight != 0 {
+		t.Fatalf("Expected 0xa's gas coin to be quarantined, got %+v", stats[0])
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.AcquireAs(ctx, "0xa"); err == nil {
+		t.Fatal("Expected a quarantined gas coin not to be leased")
+	}
+
+	// Once the transaction is found on chain, the coin goes back to the pool.
+	api.mu.Lock()
+	api.down = false
+	api.mu.Unlock()
+	pool.mu.Lock()
+	pool.quarantineRetry = time.Millisecond
+	pool.mu.Unlock()
+	lease, err := pool.AcquireAs(context.Background(), "0xa")
+	if err != nil {
+		t.Fatalf("AcquireAs after the transaction was found failed: %v", err)
+	}
+	defer lease.Release()
+	digest, _ := TransactionDigest("bWludA==")
+	if lease.GasCoinID != "0xa-gas" || len(api.lookups) == 0 || api.lookups[len(api.lookups)-1] != digest {
+		t.Errorf("Expected 0xa-gas back after looking up %s, got %s after %v", digest, lease.GasCoinID, api.lookups)
+	}
+	if stats := pool.Stats(); stats[0].Quarantined != 0 {
+		t.Errorf("Expected no quarantined coins, got %+v", stats[0])
+	}
+}
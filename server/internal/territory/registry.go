@@ -0,0 +1,210 @@
+package territory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrUnknownZone is returned for zone IDs that are not in the zone list.
+	ErrUnknownZone = errors.New("unknown zone")
+	// ErrAlreadyOwner is returned when a guild claims a zone it already holds.
+	ErrAlreadyOwner = errors.New("guild already holds this zone")
+	// ErrSiegePending is returned when a zone is claimed while a siege over it is scheduled or running.
+	ErrSiegePending = errors.New("a siege over this zone is already scheduled")
+	// ErrNoSiege is returned when resolving a zone that is not under siege.
+	ErrNoSiege = errors.New("zone is not under siege")
+	// ErrAllied is returned when a guild claims a zone held by an allied guild.
+	ErrAllied = errors.New("an allied guild holds this zone")
+)
+
+// ClaimVerifier checks an executed claim_territory transaction and returns the
+// claiming guild. sui.GuildSystemSuiService implements it.
+type ClaimVerifier interface {
+	VerifyTerritoryClaim(txDigest, zoneID string) (guildID string, err error)
+}
+
+// Alliances tells which guilds are allied. diplomacy.Service implements it.
+type Alliances interface {
+	Allied(guildA, guildB string) bool
+}
+
+// Claim records a guild's hold on a zone.
+type Claim struct {
+	ZoneID    string
+	GuildID   string
+	TxDigest  string // claim_territory transaction
+	ClaimedAt time.Time
+}
+
+// Siege is a contested claim: the attacker takes the zone if it wins between
+// StartsAt and EndsAt, otherwise the defender keeps it.
+type Siege struct {
+	ZoneID          string
+	AttackerGuildID string
+	DefenderGuildID string
+	TxDigest        string // The attacker's claim_territory transaction
+	StartsAt        time.Time
+	EndsAt          time.Time
+}
+
+// ClaimResult is the outcome of a claim: either the zone was granted or a siege
+// was scheduled.
+type ClaimResult struct {
+	Granted bool
+	Claim   Claim  // Set when Granted
+	Siege   *Siege // Set when the zone was held by another guild
+}
+
+// Benefits are what a guild gets from a zone.
+type Benefits struct {
+	ZoneID       string
+	OwnerGuildID string             // Empty if the zone is unclaimed
+	Buffs        map[string]float64 // Only for members of the owning guild
+	TaxRate      float64            // Charged to other guilds while the zone is owned; allies pay none
+	Allied       bool               // The owner is allied with the guild asking
+}
+
+// Registry holds zone claims and sieges. It is not safe for concurrent use;
+// the WorldManagerActor owns it.
+type Registry struct {
+	zones         map[string]Zone
+	claims        map[string]Claim
+	sieges        map[string]Siege
+	siegeDelay    time.Duration
+	siegeDuration time.Duration
+	alliances     Alliances // Allies neither siege nor tax each other; no guild is allied if nil
+}
+
+// NewRegistry creates a Registry for zones. A contested claim schedules a siege
+// siegeDelay after the claim, lasting siegeDuration.
+func NewRegistry(zones []Zone, siegeDelay, siegeDuration time.Duration) *Registry {
+	r := &Registry{
+		zones:         make(map[string]Zone, len(zones)),
+		claims:        make(map[string]Claim),
+		sieges:        make(map[string]Siege),
+		siegeDelay:    siegeDelay,
+		siegeDuration: siegeDuration,
+	}
+	for _, zone := range zones {
+		r.zones[zone.ID] = zone
+	}
+	return r
+}
+
+// UseAlliances has allied guilds treated as friendly: they cannot contest
+// each other's zones and pay no tax in them.
+func (r *Registry) UseAlliances(alliances Alliances) {
+	r.alliances = alliances
+}
+
+// Claim applies a verified on-chain claim. An unclaimed zone is granted
+// immediately; a zone held by another guild gets a siege, unless the guilds
+// are allied.
+func (r *Registry) Claim(zoneID, guildID, txDigest string, now time.Time) (ClaimResult, error) {
+	if _, ok := r.zones[zoneID]; !ok {
+		return ClaimResult{}, fmt.Errorf("%w: %s", ErrUnknownZone, zoneID)
+	}
+	if _, pending := r.sieges[zoneID]; pending {
+		return ClaimResult{}, ErrSiegePending
+	}
+	current, held := r.claims[zoneID]
+	if !held {
+		claim := Claim{ZoneID: zoneID, GuildID: guildID, TxDigest: txDigest, ClaimedAt: now}
+		r.claims[zoneID] = claim
+		return ClaimResult{Granted: true, Claim: claim}, nil
+	}
+	if current.GuildID == guildID {
+		return ClaimResult{}, ErrAlreadyOwner
+	}
+	if r.allied(current.GuildID, guildID) {
+		return ClaimResult{}, ErrAllied
+	}
+	startsAt := now.Add(r.siegeDelay)
+	siege := Siege{
+		ZoneID:          zoneID,
+		AttackerGuildID: guildID,
+		DefenderGuildID: current.GuildID,
+		TxDigest:        txDigest,
+		StartsAt:        startsAt,
+		EndsAt:          startsAt.Add(r.siegeDuration),
+	}
+	r.sieges[zoneID] = siege
+	return ClaimResult{Siege: &siege}, nil
+}
+
+// ResolveSiege ends the siege over zoneID with winnerGuildID, which must be the
+// attacker or the defender, and returns the resulting claim.
+func (r *Registry) ResolveSiege(zoneID, winnerGuildID string, now time.Time) (Claim, error) {
+	siege, ok := r.sieges[zoneID]
+	if !ok {
+		return Claim{}, ErrNoSiege
+	}
+	switch winnerGuildID {
+	case siege.DefenderGuildID:
+	case siege.AttackerGuildID:
+		r.claims[zoneID] = Claim{ZoneID: zoneID, GuildID: siege.AttackerGuildID, TxDigest: siege.TxDigest, ClaimedAt: now}
+	default:
+		return Claim{}, fmt.Errorf("guild %s is not part of the siege over %s", winnerGuildID, zoneID)
+	}
+	delete(r.sieges, zoneID)
+	return r.claims[zoneID], nil
+}
+
+// ExpireSiege resolves the siege over zoneID in the defender's favour if it has
+// ended without a result. It reports whether a siege was expired.
+func (r *Registry) ExpireSiege(zoneID string, now time.Time) (Siege, bool) {
+	siege, ok := r.sieges[zoneID]
+	if !ok || now.Before(siege.EndsAt) {
+		return Siege{}, false
+	}
+	delete(r.sieges, zoneID)
+	return siege, true
+}
+
+// Siege returns the siege scheduled or running over zoneID, if any.
+func (r *Registry) Siege(zoneID string) (Siege, bool) {
+	siege, ok := r.sieges[zoneID]
+	return siege, ok
+}
+
+// Benefits returns what guildID gets from zoneID: the zone's buffs if it owns
+// the zone, otherwise the tax it pays there, which is none for the owner's
+// allies.
+func (r *Registry) Benefits(zoneID, guildID string) (Benefits, error) {
+	zone, ok := r.zones[zoneID]
+	if !ok {
+		return Benefits{}, fmt.Errorf("%w: %s", ErrUnknownZone, zoneID)
+	}
+	claim, held := r.claims[zoneID]
+	benefits := Benefits{ZoneID: zoneID, OwnerGuildID: claim.GuildID}
+	switch {
+	case !held:
+	case guildID != "" && claim.GuildID == guildID:
+		benefits.Buffs = make(map[string]float64, len(zone.Buffs))
+		for stat, bonus := range zone.Buffs {
+			benefits.Buffs[stat] = bonus
+		}
+	case r.allied(claim.GuildID, guildID):
+		benefits.Allied = true
+	default:
+		benefits.TaxRate = zone.TaxRate
+	}
+	return benefits, nil
+}
+
+// Claims returns all current claims ordered by zone ID.
+func (r *Registry) Claims() []Claim {
+	claims := make([]Claim, 0, len(r.claims))
+	for _, claim := range r.claims {
+		claims = append(claims, claim)
+	}
+	sort.Slice(claims, func(i, j int) bool { return claims[i].ZoneID < claims[j].ZoneID })
+	return claims
+}
+
+func (r *Registry) allied(guildA, guildB string) bool {
+	return r.alliances != nil && r.alliances.Allied(guildA, guildB)
+}
@@ -0,0 +1,108 @@
+package territory
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testRegistry() *Registry {
+	zones := []Zone{{ID: "north", Buffs: map[string]float64{"gatherSpeed": 0.1}, TaxRate: 0.05}}
+	return NewRegistry(zones, time.Hour, 30*time.Minute)
+}
+
+func TestClaimUnclaimedZoneIsGranted(t *testing.T) {
+	r := testRegistry()
+	now := time.Now()
+	result, err := r.Claim("north", "guildA", "tx1", now)
+	if err != nil || !result.Granted || result.Claim.GuildID != "guildA" {
+		t.Fatalf("claim = %+v, %v; want granted to guildA", result, err)
+	}
+	if _, err := r.Claim("north", "guildA", "tx2", now); !errors.Is(err, ErrAlreadyOwner) {
+		t.Fatalf("repeat claim error = %v, want ErrAlreadyOwner", err)
+	}
+	if _, err := r.Claim("south", "guildA", "tx3", now); !errors.Is(err, ErrUnknownZone) {
+		t.Fatalf("unknown zone error = %v, want ErrUnknownZone", err)
+	}
+}
+
+func TestContestedClaimSchedulesSiege(t *testing.T) {
+	r := testRegistry()
+	now := time.Now()
+	r.Claim("north", "guildA", "tx1", now)
+	result, err := r.Claim("north", "guildB", "tx2", now)
+	if err != nil || result.Granted || result.Siege == nil {
+		t.Fatalf("contested claim = %+v, %v; want a siege", result, err)
+	}
+	if !result.Siege.StartsAt.Equal(now.Add(time.Hour)) || !result.Siege.EndsAt.Equal(now.Add(90*time.Minute)) {
+		t.Fatalf("siege window = %v-%v", result.Siege.StartsAt, result.Siege.EndsAt)
+	}
+	if _, err := r.Claim("north", "guildC", "tx3", now); !errors.Is(err, ErrSiegePending) {
+		t.Fatalf("claim during siege error = %v, want ErrSiegePending", err)
+	}
+	if _, err := r.ResolveSiege("north", "guildC", now); err == nil {
+		t.Fatal("resolving with an outside guild should fail")
+	}
+	claim, err := r.ResolveSiege("north", "guildB", now)
+	if err != nil || claim.GuildID != "guildB" || claim.TxDigest != "tx2" {
+		t.Fatalf("resolve = %+v, %v; want guildB", claim, err)
+	}
+	if _, ok := r.Siege("north"); ok {
+		t.Fatal("siege should be cleared after resolution")
+	}
+}
+
+func TestExpiredSiegeKeepsDefender(t *testing.T) {
+	r := testRegistry()
+	now := time.Now()
+	r.Claim("north", "guildA", "tx1", now)
+	r.Claim("north", "guildB", "tx2", now)
+	if _, expired := r.ExpireSiege("north", now.Add(time.Hour)); expired {
+		t.Fatal("siege expired before it ended")
+	}
+	if _, expired := r.ExpireSiege("north", now.Add(2*time.Hour)); !expired {
+		t.Fatal("siege did not expire after it ended")
+	}
+	if claims := r.Claims(); len(claims) != 1 || claims[0].GuildID != "guildA" {
+		t.Fatalf("claims = %+v, want guildA to keep north", claims)
+	}
+}
+
+func TestBenefits(t *testing.T) {
+	r := testRegistry()
+	if b, _ := r.Benefits("north", "guildA"); b.OwnerGuildID != "" || b.Buffs != nil || b.TaxRate != 0 {
+		t.Fatalf("unclaimed benefits = %+v, want none", b)
+	}
+	r.Claim("north", "guildA", "tx1", time.Now())
+	if b, _ := r.Benefits("north", "guildA"); b.Buffs["gatherSpeed"] != 0.1 || b.TaxRate != 0 {
+		t.Fatalf("owner benefits = %+v, want buffs and no tax", b)
+	}
+	if b, _ := r.Benefits("north", "guildB"); b.Buffs != nil || b.TaxRate != 0.05 {
+		t.Fatalf("visitor benefits = %+v, want tax only", b)
+	}
+}
+
+type allies map[string]string // Guild -> alliance
+
+func (a allies) Allied(guildA, guildB string) bool {
+	return guildA != guildB && a[guildA] != "" && a[guildA] == a[guildB]
+}
+
+func TestAlliedGuildsNeitherSiegeNorTax(t *testing.T) {
+	r := testRegistry()
+	r.UseAlliances(allies{"guildA": "aln-1", "guildB": "aln-1"})
+	now := time.Now()
+	r.Claim("north", "guildA", "tx1", now)
+	if _, err := r.Claim("north", "guildB", "tx2", now); !errors.Is(err, ErrAllied) {
+		t.Fatalf("claim by an ally = %v, want ErrAllied", err)
+	}
+	if b, _ := r.Benefits("north", "guildB"); b.TaxRate != 0 || !b.Allied || b.Buffs != nil {
+		t.Fatalf("ally's benefits = %+v", b)
+	}
+	if b, _ := r.Benefits("north", "guildC"); b.TaxRate != 0.05 || b.Allied {
+		t.Fatalf("outsider's benefits = %+v", b)
+	}
+	if result, err := r.Claim("north", "guildC", "tx3", now); err != nil || result.Siege == nil {
+		t.Fatalf("claim by an outsider = %+v, %v; want a siege", result, err)
+	}
+}
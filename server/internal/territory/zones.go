@@ -0,0 +1,47 @@
+// Package territory tracks which guild holds each world zone, the buffs and
+// taxes a claim grants, and the sieges that decide contested claims.
+package territory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Zone is a claimable area of the world.
+type Zone struct {
+	ID      string             `json:"id"`
+	Name    string             `json:"name"`
+	Buffs   map[string]float64 `json:"buffs,omitempty"` // Stat -> multiplier bonus for the owning guild, e.g. "gatherSpeed": 0.1
+	TaxRate float64            `json:"taxRate"`         // Fraction of other guilds' trades in the zone paid to the owner (0-1)
+}
+
+type zoneFile struct {
+	Zones []Zone `json:"zones"`
+}
+
+// LoadZones reads and validates the zone list from a JSON file.
+func LoadZones(path string) ([]Zone, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file zoneFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid zone file %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(file.Zones))
+	for i, zone := range file.Zones {
+		if zone.ID == "" {
+			return nil, fmt.Errorf("invalid zone file %s: zone %d has no id", path, i+1)
+		}
+		if seen[zone.ID] {
+			return nil, fmt.Errorf("invalid zone file %s: duplicate zone id %q", path, zone.ID)
+		}
+		if zone.TaxRate < 0 || zone.TaxRate > 1 {
+			return nil, fmt.Errorf("invalid zone file %s: zone %q tax rate %.2f is outside 0-1", path, zone.ID, zone.TaxRate)
+		}
+		seen[zone.ID] = true
+	}
+	return file.Zones, nil
+}
@@ -0,0 +1,165 @@
+// Package timers schedules delayed and periodic work for actors. A timer
+// never touches actor state: when it fires it sends a message to the actor's
+// PID, and the actor does the work in its Receive like for any other message.
+package timers
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Sender delivers timer messages. *actor.RootContext is one; an actor.Context
+// is not, since timers fire on their own goroutines.
+type Sender interface {
+	Send(pid *actor.PID, message interface{})
+}
+
+// Clock is the time source of a Scheduler.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f on its own goroutine once d has passed.
+	AfterFunc(d time.Duration, f func()) Stopper
+}
+
+// Stopper cancels a pending AfterFunc. *time.Timer is one.
+type Stopper interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Stopper { return time.AfterFunc(d, f) }
+
+// Scheduler creates timers that send messages to actors. A nil Scheduler
+// uses real time.
+type Scheduler struct {
+	clock Clock
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New creates a Scheduler on clock, or on real time if clock is nil.
+func New(clock Clock) *Scheduler {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Scheduler{clock: clock, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Now returns the scheduler's current time. Actors comparing timestamps with
+// their timer messages should use it rather than time.Now, so that tests on a
+// virtual clock see consistent times.
+func (s *Scheduler) Now() time.Time {
+	if s == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// After sends msg to pid once delay has passed.
+func (s *Scheduler) After(sender Sender, pid *actor.PID, delay time.Duration, msg interface{}) *Timer {
+	t := &Timer{}
+	t.arm(s.clockOrReal(), delay, func() {
+		if t.fire(false) {
+			sender.Send(pid, msg)
+		}
+	})
+	return t
+}
+
+// Every sends msg to pid every interval until the timer is stopped. Each wait
+// is lengthened by a random amount below jitter, so that actors started
+// together, such as sessions after a restart, spread their ticks out.
+func (s *Scheduler) Every(sender Sender, pid *actor.PID, interval, jitter time.Duration, msg interface{}) *Timer {
+	t := &Timer{}
+	var tick func()
+	tick = func() {
+		if !t.fire(true) {
+			return
+		}
+		sender.Send(pid, msg)
+		t.arm(s.clockOrReal(), interval+s.jitter(jitter), tick)
+	}
+	t.arm(s.clockOrReal(), interval+s.jitter(jitter), tick)
+	return t
+}
+
+func (s *Scheduler) clockOrReal() Clock {
+	if s == nil {
+		return realClock{}
+	}
+	return s.clock
+}
+
+func (s *Scheduler) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	if s == nil {
+		return time.Duration(rand.Int63n(int64(max)))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(s.rng.Int63n(int64(max)))
+}
+
+// Timer is a pending After or Every. A message it already sent is still
+// delivered after Stop, so actors that must ignore late ticks should check
+// Stopped, or tag their messages, when handling them.
+type Timer struct {
+	mu      sync.Mutex
+	pending Stopper
+	stopped bool
+}
+
+// Stop cancels the timer. It is safe to call more than once and on nil.
+func (t *Timer) Stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.pending != nil {
+		t.pending.Stop()
+		t.pending = nil
+	}
+}
+
+// Stopped reports whether Stop was called, or an After timer has fired.
+func (t *Timer) Stopped() bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped
+}
+
+func (t *Timer) arm(clock Clock, d time.Duration, f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.stopped {
+		t.pending = clock.AfterFunc(d, f)
+	}
+}
+
+// fire reports whether the timer may send; an After timer is spent by it.
+func (t *Timer) fire(periodic bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.pending = nil
+	if !periodic {
+		t.stopped = true
+	}
+	return true
+}
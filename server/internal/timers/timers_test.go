@@ -0,0 +1,81 @@
+package timers
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+type tick struct{}
+
+// recorder is a Sender that records when each message was sent.
+type recorder struct {
+	clock *VirtualClock
+	mu    sync.Mutex
+	sent  []time.Time
+}
+
+func (r *recorder) Send(pid *actor.PID, message interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, r.clock.Now())
+}
+
+func TestAfterAndEveryOnVirtualTime(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewVirtualClock(start)
+	s := New(clock)
+	pid := actor.NewPID("local", "session")
+
+	once := &recorder{clock: clock}
+	timer := s.After(once, pid, 5*time.Second, &tick{})
+	clock.Advance(4 * time.Second)
+	if len(once.sent) != 0 {
+		t.Fatalf("After fired early: %v", once.sent)
+	}
+	clock.Advance(10 * time.Second)
+	if len(once.sent) != 1 || !once.sent[0].Equal(start.Add(5*time.Second)) {
+		t.Fatalf("After sent %v, want once at +5s", once.sent)
+	}
+	if !timer.Stopped() {
+		t.Error("After timer not spent after firing")
+	}
+
+	periodic := &recorder{clock: clock}
+	every := s.Every(periodic, pid, 10*time.Second, 2*time.Second, &tick{})
+	clock.Advance(time.Minute)
+	if n := len(periodic.sent); n < 5 || n > 6 {
+		t.Fatalf("Every sent %d ticks in a minute, want 5 or 6", n)
+	}
+	for i := 1; i < len(periodic.sent); i++ {
+		if gap := periodic.sent[i].Sub(periodic.sent[i-1]); gap < 10*time.Second || gap >= 12*time.Second {
+			t.Errorf("tick %d came %s after the last, want [10s, 12s)", i, gap)
+		}
+	}
+
+	every.Stop()
+	sent := len(periodic.sent)
+	clock.Advance(time.Minute)
+	if len(periodic.sent) != sent || clock.Pending() != 0 {
+		t.Errorf("stopped timer still ticking: %d more sends, %d pending", len(periodic.sent)-sent, clock.Pending())
+	}
+}
+
+func TestNilSchedulerUsesRealTime(t *testing.T) {
+	var s *Scheduler
+	pid := actor.NewPID("local", "session")
+	done := make(chan struct{})
+	timer := s.After(senderFunc(func(*actor.PID, interface{}) { close(done) }), pid, time.Millisecond, &tick{})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("real-time timer did not fire")
+	}
+	timer.Stop()
+}
+
+type senderFunc func(pid *actor.PID, message interface{})
+
+func (f senderFunc) Send(pid *actor.PID, message interface{}) { f(pid, message) }
@@ -0,0 +1,93 @@
+package timers
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// VirtualClock is a Clock for tests. Time stands still until Advance, which
+// runs the callbacks that came due, in order, on the caller's goroutine.
+type VirtualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	seq     int
+	pending []*virtualTimer
+}
+
+type virtualTimer struct {
+	clock *VirtualClock
+	at    time.Time
+	seq   int // Orders timers due at the same time by creation
+	f     func()
+}
+
+// NewVirtualClock creates a VirtualClock reading start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the virtual time.
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc schedules f for d after the virtual time.
+func (c *VirtualClock) AfterFunc(d time.Duration, f func()) Stopper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &virtualTimer{clock: c, at: c.now.Add(d), seq: c.seq, f: f}
+	c.pending = append(c.pending, t)
+	return t
+}
+
+// Advance moves the virtual time forward by d. Each callback due by then runs
+// with the clock set to its due time, so callbacks that schedule more work
+// within d see it run too.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		sort.Slice(c.pending, func(i, j int) bool {
+			if c.pending[i].at.Equal(c.pending[j].at) {
+				return c.pending[i].seq < c.pending[j].seq
+			}
+			return c.pending[i].at.Before(c.pending[j].at)
+		})
+		if len(c.pending) == 0 || c.pending[0].at.After(end) {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		next := c.pending[0]
+		c.pending = c.pending[1:]
+		c.now = next.at
+		c.mu.Unlock()
+		next.f()
+	}
+}
+
+// Pending returns how many callbacks are scheduled.
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+func (t *virtualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.pending {
+		if pending == t {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
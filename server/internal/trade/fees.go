@@ -0,0 +1,100 @@
+package trade
+
+import (
+	"fmt"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// FeeSchedule returns the fees in effect in a region. cmd/game reads them from
+// the balance values, so they follow balance changes.
+type FeeSchedule func(region string) balance.Fees
+
+// UseFees makes trades pay fees to the treasury, at the rates in effect in the
+// proposer's region when the trade is proposed. An escrowed trade's fee is a
+// share of its value, split between the players and deposited into the escrow
+// with their side; the escrow pays it out when released. A direct trade costs
+// each player a flat fee in coins from wallets when it is accepted. Collected
+// fees are recorded in ledger.
+func (s *Service) UseFees(schedule FeeSchedule, wallets shop.WalletStore, ledger *treasury.Ledger) {
+	s.fees, s.wallets, s.treasury = schedule, wallets, ledger
+}
+
+// setFees prices t's fees for both players.
+func (s *Service) setFees(t *Trade) {
+	if s.fees == nil {
+		return
+	}
+	fees := s.fees(t.Region)
+	if t.Escrowed {
+		total := fees.Trade.On(t.Value)
+		t.CounterpartyFee = total / 2
+		t.ProposerFee = total - t.CounterpartyFee
+		return
+	}
+	if s.wallets != nil && fees.DirectTrade > 0 {
+		t.ProposerFee, t.CounterpartyFee = uint64(fees.DirectTrade), uint64(fees.DirectTrade)
+	}
+}
+
+// chargeDirect takes a direct trade's fees from both players' wallets. Neither
+// pays unless both can.
+func (s *Service) chargeDirect(t Trade) error {
+	if t.ProposerFee == 0 && t.CounterpartyFee == 0 {
+		return nil
+	}
+	payers := []string{t.Proposer, t.Counterparty}
+	wallets := make([]shop.Wallet, len(payers))
+	for i, playerID := range payers {
+		wallet, err := s.wallets.LoadWallet(playerID)
+		if err != nil {
+			return fmt.Errorf("could not load %s's wallet: %w", playerID, err)
+		}
+		fee := int64(t.FeeOf(playerID))
+		if wallet.Coins < fee {
+			return ErrFeeUnpaid
+		}
+		wallet.Coins -= fee
+		wallets[i] = wallet
+	}
+	for i, playerID := range payers {
+		if err := s.wallets.SaveWallet(playerID, wallets[i]); err != nil {
+			if i > 0 {
+				s.refundDirect(t, payers[0])
+			}
+			return fmt.Errorf("could not charge %s's trade fee: %w", playerID, err)
+		}
+	}
+	s.recordFees(t, treasury.SourceDirectTrade, treasury.CurrencyCoins)
+	return nil
+}
+
+// refundDirect gives playerID back their fee for t after the other player's
+// could not be taken.
+func (s *Service) refundDirect(t Trade, playerID string) {
+	wallet, err := s.wallets.LoadWallet(playerID)
+	if err == nil {
+		wallet.Coins += int64(t.FeeOf(playerID))
+		err = s.wallets.SaveWallet(playerID, wallet)
+	}
+	if err != nil {
+		utils.LogErrorf("Trade: Could not refund %s's fee of %d coins for %s: %v", playerID, t.FeeOf(playerID), t.ID, err)
+	}
+}
+
+// recordFees records both players' fees for t in the treasury ledger.
+func (s *Service) recordFees(t Trade, source, currency string) {
+	for _, playerID := range []string{t.Proposer, t.Counterparty} {
+		s.treasury.Record(treasury.Entry{
+			Source:   source,
+			Currency: currency,
+			Amount:   t.FeeOf(playerID),
+			Region:   t.Region,
+			Payer:    playerID,
+			Ref:      t.ID,
+		})
+	}
+}
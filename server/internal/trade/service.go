@@ -0,0 +1,789 @@
+// Package trade runs player-to-player trades of item NFTs and SUI. Trades worth
+// more than a configurable threshold go through an on-chain escrow the server
+// arbitrates: both players deposit their side, and the server releases both
+// sides at once or refunds them.
+package trade
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/audit"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Outbox message kinds for escrow transactions. Their payload is an EscrowJob.
+const (
+	EscrowCreateKind  = "trade.escrow_create"
+	EscrowReleaseKind = "trade.escrow_release"
+	EscrowRefundKind  = "trade.escrow_refund"
+)
+
+// Defaults for Options fields left at zero.
+const (
+	DefaultProposalTTL = 2 * time.Minute
+	DefaultDepositTTL  = 15 * time.Minute
+	DefaultMaxItems    = 16
+)
+
+// selfRefundDelay is how long after the deposit deadline players can reclaim
+// their deposits on-chain themselves, should the server never refund them.
+const selfRefundDelay = time.Hour
+
+// finishedRetention is how long finished trades are kept for TRADE_UPDATE replays and debugging.
+const finishedRetention = 24 * time.Hour
+
+var (
+	ErrUnknownTrade       = errors.New("unknown trade")
+	ErrNotYourTrade       = errors.New("you are not part of that trade")
+	ErrSelfTrade          = errors.New("you cannot trade with yourself")
+	ErrEmptyTrade         = errors.New("a trade needs something on at least one side")
+	ErrTooManyItems       = errors.New("too many items in one trade")
+	ErrDuplicateItem      = errors.New("an item is listed twice")
+	ErrWrongState         = errors.New("the trade cannot do that now")
+	ErrEscrowUnavailable  = errors.New("trades of this value need escrow, which is not enabled")
+	ErrEscrowPaused       = errors.New("escrowed trades are paused; try a smaller trade or try again later")
+	ErrDepositsIncomplete = errors.New("the escrow does not hold every deposit yet")
+	ErrFeeUnpaid          = errors.New("both players need the coins for the trade fee")
+)
+
+// Escrow creates and settles on-chain escrows. cmd/game adapts sui.EscrowSuiService to it.
+type Escrow interface {
+	// Create opens an escrow for an accepted trade and returns its object ID.
+	// Either player may refund it themselves after expiresAt.
+	Create(ctx context.Context, t Trade, expiresAt time.Time) (string, error)
+	// Complete reports whether both players have deposited their side.
+	Complete(ctx context.Context, escrowID string) (bool, error)
+	// Release and Refund settle the escrow and return the transaction digest.
+	Release(ctx context.Context, escrowID string) (string, error)
+	Refund(ctx context.Context, escrowID string) (string, error)
+}
+
+// OwnershipCheck confirms that owner holds every item in itemIDs on-chain.
+type OwnershipCheck func(ctx context.Context, owner string, itemIDs []string) error
+
+// SpendLimit caps the MIST value players give in trades each day. parental.Service implements it.
+type SpendLimit interface {
+	SpendMist(playerID string, mist uint64) error
+	ReleaseMist(playerID string, mist uint64)
+}
+
+// AddressResolver returns a player's Sui address. accountlink.Service implements it.
+type AddressResolver interface {
+	Address(ctx context.Context, playerID string) (string, error)
+}
+
+// EscrowJob is the payload of the escrow outbox messages.
+type EscrowJob struct {
+	TradeID string `json:"tradeId"`
+}
+
+// Update is sent to both players whenever a trade changes.
+type Update struct {
+	Trade Trade
+}
+
+// Notifier delivers an *Update to a player's session.
+type Notifier func(note interface{})
+
+// Options configures a Service.
+type Options struct {
+	EscrowThreshold uint64        // Trades worth more than this (MIST) use escrow
+	ItemValue       uint64        // Estimated MIST value of one item when valuing a trade
+	MaxItems        int           // Items per side
+	ProposalTTL     time.Duration // How long the counterparty has to answer
+	DepositTTL      time.Duration // How long both players have to deposit into escrow
+	TickInterval    time.Duration
+}
+
+// Service runs trades. It is safe for concurrent use by session actors.
+type Service struct {
+	opts      Options
+	store     Store
+	addresses AddressResolver
+	escrow    Escrow
+	jobs      *outbox.Outbox
+	items     *reservation.Registry // Holds traded items until the trade finishes; nil reserves nothing
+	audit     *audit.Log            // Records proposals and status changes; nil records nothing
+	fees      FeeSchedule           // Fees charged on trades; nil charges none
+	wallets   shop.WalletStore      // Pays the fees of direct trades
+	treasury  *treasury.Ledger      // Records the fees collected; nil records nothing
+	events    *events.Bus           // Receives chain.tx_completed for settled escrows; nil publishes nothing
+	ownership OwnershipCheck        // Checks escrowed items before their escrow is prepared; nil checks none
+	limit     SpendLimit            // Caps what players give in trades; nil caps nothing
+	now       func() time.Time
+
+	mu           sync.Mutex
+	state        State
+	notifiers    map[string]Notifier // Connected players
+	regions      map[string]string   // Region of each connected player's world
+	escrowPaused bool                // New escrowed trades refused; open escrows still settle
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService loads the trade state from store. Escrow stays off until EnableEscrow.
+func NewService(opts Options, store Store, addresses AddressResolver) (*Service, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading trade state: %w", err)
+	}
+	if state.Trades == nil {
+		state.Trades = make(map[string]Trade)
+	}
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = DefaultMaxItems
+	}
+	if opts.ProposalTTL <= 0 {
+		opts.ProposalTTL = DefaultProposalTTL
+	}
+	if opts.DepositTTL <= 0 {
+		opts.DepositTTL = DefaultDepositTTL
+	}
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = 5 * time.Second
+	}
+	return &Service{
+		opts:      opts,
+		store:     store,
+		addresses: addresses,
+		now:       time.Now,
+		state:     state,
+		notifiers: make(map[string]Notifier),
+		regions:   make(map[string]string),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// NewServiceFromConfig creates a Service from configuration, keeping its state in NewFileStore(cfg.StateFile).
+func NewServiceFromConfig(cfg configs.TradeConfig, addresses AddressResolver) (*Service, error) {
+	return NewService(Options{
+		EscrowThreshold: cfg.EscrowThresholdMist,
+		ItemValue:       cfg.ItemValueMist,
+		MaxItems:        cfg.MaxItems,
+		ProposalTTL:     time.Duration(cfg.ProposalTTLSeconds) * time.Second,
+		DepositTTL:      time.Duration(cfg.DepositTTLSeconds) * time.Second,
+	}, NewFileStore(cfg.StateFile), addresses)
+}
+
+// EnableEscrow turns on escrow for high-value trades. Escrow transactions are
+// queued in jobs, so they survive restarts and are retried.
+func (s *Service) EnableEscrow(escrow Escrow, jobs *outbox.Outbox) {
+	s.escrow, s.jobs = escrow, jobs
+	jobs.Handle(EscrowCreateKind, s.createEscrow)
+	jobs.Handle(EscrowReleaseKind, s.releaseEscrow)
+	jobs.Handle(EscrowRefundKind, s.refundEscrow)
+	jobs.OnGiveUp(EscrowReleaseKind, func(payload json.RawMessage, err error) { s.settlementFailed(payload, "release") })
+	jobs.OnGiveUp(EscrowRefundKind, func(payload json.RawMessage, err error) { s.settlementFailed(payload, "refund") })
+}
+
+// UseEvents publishes chain.tx_completed on bus for both players when an
+// escrow is settled, or when the server gives up settling it.
+func (s *Service) UseEvents(bus *events.Bus) {
+	s.events = bus
+}
+
+// UseReservations makes trades reserve their items in items: the proposer's
+// when proposing, the counterparty's when accepting. A trade is refused while
+// another operation holds one of its items, and releases them once finished.
+func (s *Service) UseReservations(items *reservation.Registry) {
+	s.items = items
+}
+
+// UseOwnershipCheck makes escrowed trades check, when proposed, that each
+// player owns the items on their side, so no escrow is prepared around items
+// a player named but does not have.
+func (s *Service) UseOwnershipCheck(check OwnershipCheck) {
+	s.ownership = check
+}
+
+// UseAuditLog records every proposal and status change in log.
+func (s *Service) UseAuditLog(log *audit.Log) {
+	s.audit = log
+}
+
+// UseSpendLimit holds the value of what each player gives in a trade against
+// their daily limit: the proposer's side when proposing, the counterparty's
+// when accepting.
+func (s *Service) UseSpendLimit(limit SpendLimit) {
+	s.limit = limit
+}
+
+// spend holds mist against playerID's daily limit, if there is one.
+func (s *Service) spend(playerID string, mist uint64) error {
+	if s.limit == nil {
+		return nil
+	}
+	return s.limit.SpendMist(playerID, mist)
+}
+
+// unspend gives back mist held by spend, for an operation that failed.
+func (s *Service) unspend(playerID string, mist uint64) {
+	if s.limit != nil {
+		s.limit.ReleaseMist(playerID, mist)
+	}
+}
+
+// SetEscrowPaused stops or resumes new escrowed trades. While paused, trades
+// above the threshold cannot be proposed or accepted, but escrows already
+// created are still released or refunded.
+func (s *Service) SetEscrowPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.escrowPaused = paused
+	utils.LogInfof("Trade: Escrowed trades paused: %v.", paused)
+}
+
+// Start expires unanswered proposals and unfunded escrows in the background.
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.opts.TickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.Tick(now)
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Connect registers the session notifier of a player who came online in a
+// world of region. The region picks the fees of the trades they propose.
+func (s *Service) Connect(playerID, region string, notify Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[playerID] = notify
+	s.regions[playerID] = region
+}
+
+// Disconnect forgets a player's notifier. Their trades carry on.
+func (s *Service) Disconnect(playerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notifiers, playerID)
+	delete(s.regions, playerID)
+}
+
+// Value estimates the MIST value of a trade: the coins on both sides plus ItemValue per item.
+func (s *Service) Value(give, want Offer) uint64 {
+	return s.offerValue(give) + s.offerValue(want)
+}
+
+// offerValue estimates the MIST value of one side of a trade.
+func (s *Service) offerValue(o Offer) uint64 {
+	return o.Coins + uint64(len(o.ItemIDs))*s.opts.ItemValue
+}
+
+// Propose offers counterparty a trade of give for want. Trades that need escrow
+// require both players to have a linked Sui address.
+func (s *Service) Propose(ctx context.Context, proposer, counterparty string, give, want Offer) (Trade, error) {
+	if proposer == counterparty {
+		return Trade{}, ErrSelfTrade
+	}
+	if err := s.validate(give, want); err != nil {
+		return Trade{}, err
+	}
+	value := s.Value(give, want)
+	now := s.now()
+	t := Trade{
+		ID:           newTradeID(),
+		Proposer:     proposer,
+		Counterparty: counterparty,
+		Give:         give,
+		Want:         want,
+		Value:        value,
+		Escrowed:     value > s.opts.EscrowThreshold,
+		Status:       StatusProposed,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Deadline:     now.Add(s.opts.ProposalTTL),
+	}
+	s.mu.Lock()
+	t.Region = s.regions[proposer]
+	paused := s.escrowPaused
+	s.mu.Unlock()
+	s.setFees(&t)
+	if t.Escrowed {
+		if s.escrow == nil {
+			return Trade{}, ErrEscrowUnavailable
+		}
+		if paused {
+			return Trade{}, ErrEscrowPaused
+		}
+		var err error
+		if t.ProposerAddress, err = s.addresses.Address(ctx, proposer); err != nil {
+			return Trade{}, fmt.Errorf("your address: %w", err)
+		}
+		if t.CounterpartyAddress, err = s.addresses.Address(ctx, counterparty); err != nil {
+			return Trade{}, fmt.Errorf("%s's address: %w", counterparty, err)
+		}
+		if err := s.checkOwnership(ctx, t); err != nil {
+			return Trade{}, err
+		}
+	}
+	if err := s.spend(proposer, s.offerValue(give)); err != nil {
+		return Trade{}, err
+	}
+	if err := s.items.Reserve(t.holder(), s.opts.ProposalTTL, give.ItemIDs...); err != nil {
+		s.unspend(proposer, s.offerValue(give))
+		return Trade{}, err
+	}
+	s.mu.Lock()
+	s.state.Trades[t.ID] = t
+	s.saveLocked()
+	s.auditLocked(t)
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Trade: %s proposed %s to %s (value %d MIST, escrow: %v).", proposer, t.ID, counterparty, value, t.Escrowed)
+	return t, nil
+}
+
+// Accept accepts a proposal addressed to playerID. A direct trade is then
+// settled by the players; an escrowed one moves on to creating its escrow.
+func (s *Service) Accept(playerID, tradeID string) (Trade, error) {
+	s.mu.Lock()
+	t, err := s.tradeLocked(playerID, tradeID)
+	if err == nil && (t.Status != StatusProposed || t.Counterparty != playerID) {
+		err = ErrWrongState
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Trade{}, err
+	}
+	given := s.offerValue(t.Want)
+	if err := s.spend(playerID, given); err != nil {
+		s.mu.Unlock()
+		return Trade{}, err
+	}
+	// Until the escrow holds the deposits, or the players have swapped the
+	// items themselves, nothing else may use them.
+	reserveFor := s.opts.ProposalTTL
+	if t.Escrowed {
+		reserveFor = s.opts.DepositTTL
+	}
+	if err := s.items.Reserve(t.holder(), reserveFor, t.itemIDs()...); err != nil {
+		s.unspend(playerID, given)
+		s.mu.Unlock()
+		return Trade{}, err
+	}
+	if t.Escrowed {
+		err := ErrEscrowUnavailable
+		switch {
+		case s.jobs == nil:
+		case s.escrowPaused:
+			err = ErrEscrowPaused
+		default:
+			if _, err = s.jobs.Enqueue("trade:create:"+t.ID, EscrowCreateKind, EscrowJob{TradeID: t.ID}); err != nil {
+				err = fmt.Errorf("could not queue the escrow: %w", err)
+			}
+		}
+		if err != nil {
+			s.items.Release(t.holder(), t.Want.ItemIDs...) // The proposal stands; only the proposer's items stay held
+			s.unspend(playerID, given)
+			s.mu.Unlock()
+			return Trade{}, err
+		}
+		t = s.setStatusLocked(t, StatusEscrowCreating)
+	} else {
+		if err := s.chargeDirect(t); err != nil {
+			s.items.Release(t.holder(), t.Want.ItemIDs...)
+			s.unspend(playerID, given)
+			s.mu.Unlock()
+			return Trade{}, err
+		}
+		t = s.setStatusLocked(t, StatusAccepted)
+	}
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Trade: %s accepted %s.", playerID, t.ID)
+	return t, nil
+}
+
+// Cancel backs playerID out of a trade: the counterparty declines or the
+// proposer withdraws a proposal, and either player can call off an escrow
+// that is still waiting for deposits, which refunds it.
+func (s *Service) Cancel(playerID, tradeID string) (Trade, error) {
+	s.mu.Lock()
+	t, err := s.tradeLocked(playerID, tradeID)
+	if err != nil {
+		s.mu.Unlock()
+		return Trade{}, err
+	}
+	switch {
+	case t.Status == StatusProposed && playerID == t.Counterparty:
+		t = s.setStatusLocked(t, StatusDeclined)
+	case t.Status == StatusProposed:
+		t = s.setStatusLocked(t, StatusCancelled)
+	case t.Status == StatusAwaitingDeposits:
+		if t, err = s.refundLocked(t); err != nil {
+			s.mu.Unlock()
+			return Trade{}, err
+		}
+	default:
+		s.mu.Unlock()
+		return Trade{}, ErrWrongState
+	}
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Trade: %s backed out of %s (%s).", playerID, t.ID, t.Status)
+	return t, nil
+}
+
+// Deposited checks the escrow after playerID reports a deposit. Once both
+// sides are in, the escrow is released. It returns ErrDepositsIncomplete
+// while deposits are missing.
+func (s *Service) Deposited(ctx context.Context, playerID, tradeID string) (Trade, error) {
+	s.mu.Lock()
+	t, err := s.tradeLocked(playerID, tradeID)
+	if err == nil && t.Status != StatusAwaitingDeposits {
+		err = ErrWrongState
+	}
+	s.mu.Unlock()
+	if err == nil && s.escrow == nil {
+		err = ErrEscrowUnavailable
+	}
+	if err != nil {
+		return Trade{}, err
+	}
+	complete, err := s.escrow.Complete(ctx, t.EscrowID)
+	if err != nil {
+		return Trade{}, fmt.Errorf("could not read escrow %s: %w", t.EscrowID, err)
+	}
+	if !complete {
+		return t, ErrDepositsIncomplete
+	}
+
+	s.mu.Lock()
+	t = s.state.Trades[tradeID]
+	if t.Status != StatusAwaitingDeposits {
+		s.mu.Unlock()
+		return t, nil // Released or cancelled meanwhile
+	}
+	if _, err := s.jobs.Enqueue("trade:release:"+t.ID, EscrowReleaseKind, EscrowJob{TradeID: t.ID}); err != nil {
+		s.mu.Unlock()
+		return Trade{}, fmt.Errorf("could not queue the release: %w", err)
+	}
+	t = s.setStatusLocked(t, StatusReleasing)
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Trade: Escrow %s of %s is fully deposited. Releasing.", t.EscrowID, t.ID)
+	return t, nil
+}
+
+// Trades returns playerID's unfinished trades.
+func (s *Service) Trades(playerID string) []Trade {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var trades []Trade
+	for _, t := range s.state.Trades {
+		if t.Involves(playerID) && !t.Status.Final() {
+			trades = append(trades, t)
+		}
+	}
+	return trades
+}
+
+// Tick expires proposals and escrows past their deadline and forgets trades
+// that finished long ago.
+func (s *Service) Tick(now time.Time) {
+	s.mu.Lock()
+	var deliveries []func()
+	changed := false
+	for id, t := range s.state.Trades {
+		switch {
+		case t.Status == StatusProposed && !now.Before(t.Deadline):
+			t = s.setStatusLocked(t, StatusExpired)
+		case t.Status == StatusAwaitingDeposits && !now.Before(t.Deadline):
+			var err error
+			if t, err = s.refundLocked(t); err != nil {
+				utils.LogErrorf("Trade: Could not queue the refund of expired trade %s: %v", id, err)
+				continue
+			}
+		case t.Status.Final() && now.Sub(t.UpdatedAt) > finishedRetention:
+			delete(s.state.Trades, id)
+			changed = true
+			continue
+		default:
+			continue
+		}
+		utils.LogInfof("Trade: %s timed out (%s).", id, t.Status)
+		deliveries = append(deliveries, s.notifyLocked(t)...)
+	}
+	if changed {
+		s.saveLocked()
+	}
+	s.mu.Unlock()
+	deliver(deliveries)
+}
+
+// Name implements privacy.DeletionGuard and transfer.Guard.
+func (s *Service) Name() string { return "trades" }
+
+// CheckDeletion implements privacy.DeletionGuard: a player's data cannot be
+// deleted while their assets may be in escrow.
+func (s *Service) CheckDeletion(ctx context.Context, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	escrowed := 0
+	for _, t := range s.state.Trades {
+		if t.Involves(playerID) && t.Status.InEscrow() {
+			escrowed++
+		}
+	}
+	if escrowed > 0 {
+		return fmt.Errorf("%d trade(s) in escrow", escrowed)
+	}
+	return nil
+}
+
+// CheckTransfer implements transfer.Guard: a player cannot move to another
+// shard while their assets may be in escrow here.
+func (s *Service) CheckTransfer(ctx context.Context, playerID string) error {
+	return s.CheckDeletion(ctx, playerID)
+}
+
+// createEscrow handles EscrowCreateKind.
+func (s *Service) createEscrow(ctx context.Context, payload json.RawMessage) error {
+	t, ok, err := s.jobTrade(payload, StatusEscrowCreating)
+	if !ok {
+		return err
+	}
+	deadline := s.now().Add(s.opts.DepositTTL)
+	escrowID, err := s.escrow.Create(ctx, t, deadline.Add(selfRefundDelay))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	t = s.state.Trades[t.ID]
+	t.EscrowID = escrowID
+	t.Deadline = deadline
+	t = s.setStatusLocked(t, StatusAwaitingDeposits)
+	// Creating the escrow took part of the reservation; hold the items until the deposits are due.
+	if err := s.items.Reserve(t.holder(), s.opts.DepositTTL, t.itemIDs()...); err != nil {
+		utils.LogWarnf("Trade: Items of %s are no longer reserved: %v", t.ID, err)
+	}
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	utils.LogInfof("Trade: Escrow %s opened for %s. Deposits are due by %s.", escrowID, t.ID, deadline.Format(time.RFC3339))
+	return nil
+}
+
+// releaseEscrow handles EscrowReleaseKind.
+func (s *Service) releaseEscrow(ctx context.Context, payload json.RawMessage) error {
+	return s.settleEscrow(ctx, payload, StatusReleasing, StatusCompleted, s.escrow.Release)
+}
+
+// refundEscrow handles EscrowRefundKind.
+func (s *Service) refundEscrow(ctx context.Context, payload json.RawMessage) error {
+	return s.settleEscrow(ctx, payload, StatusRefunding, StatusRefunded, s.escrow.Refund)
+}
+
+func (s *Service) settleEscrow(ctx context.Context, payload json.RawMessage, from, to Status, settle func(context.Context, string) (string, error)) error {
+	t, ok, err := s.jobTrade(payload, from)
+	if !ok {
+		return err
+	}
+	digest, err := settle(ctx, t.EscrowID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	t = s.setStatusLocked(s.state.Trades[t.ID], to)
+	deliveries := s.notifyLocked(t)
+	s.mu.Unlock()
+	deliver(deliveries)
+	if to == StatusCompleted {
+		s.recordFees(t, treasury.SourceTrade, treasury.CurrencyMIST)
+	}
+	utils.LogInfof("Trade: Escrow %s of %s settled (%s).", t.EscrowID, t.ID, to)
+	summary := fmt.Sprintf("Trade %s completed", t.ID)
+	if to == StatusRefunded {
+		summary = fmt.Sprintf("Trade %s refunded", t.ID)
+	}
+	s.publishTx(t, digest, events.TxSuccess, summary)
+	return nil
+}
+
+// settlementFailed tells both players that the server gave up settling
+// their escrow. Support must settle it; either player may refund it after
+// its deadline.
+func (s *Service) settlementFailed(payload json.RawMessage, action string) {
+	var job EscrowJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return
+	}
+	s.mu.Lock()
+	t, ok := s.state.Trades[job.TradeID]
+	s.mu.Unlock()
+	if ok {
+		s.publishTx(t, "", events.TxFailure, fmt.Sprintf("The escrow %s of trade %s failed", action, t.ID))
+	}
+}
+
+func (s *Service) publishTx(t Trade, digest, status, summary string) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.TopicTxCompleted, events.TxCompleted{
+		PlayerIDs: []string{t.Proposer, t.Counterparty},
+		Kind:      "trade",
+		Digest:    digest,
+		Status:    status,
+		Summary:   summary,
+	})
+}
+
+// jobTrade decodes an escrow job and returns its trade if it is still in
+// status. A job for a trade that moved on is done (ok is false, err nil).
+func (s *Service) jobTrade(payload json.RawMessage, status Status) (Trade, bool, error) {
+	var job EscrowJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return Trade{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.state.Trades[job.TradeID]
+	if !ok || t.Status != status {
+		utils.LogWarnf("Trade: Dropping escrow job for %s, which is no longer %s.", job.TradeID, status)
+		return Trade{}, false, nil
+	}
+	return t, true, nil
+}
+
+func (s *Service) checkOwnership(ctx context.Context, t Trade) error {
+	if s.ownership == nil {
+		return nil
+	}
+	if len(t.Give.ItemIDs) > 0 {
+		if err := s.ownership(ctx, t.ProposerAddress, t.Give.ItemIDs); err != nil {
+			return err
+		}
+	}
+	if len(t.Want.ItemIDs) > 0 {
+		if err := s.ownership(ctx, t.CounterpartyAddress, t.Want.ItemIDs); err != nil {
+			return fmt.Errorf("%s's items: %w", t.Counterparty, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) validate(give, want Offer) error {
+	if len(give.ItemIDs) == 0 && give.Coins == 0 && len(want.ItemIDs) == 0 && want.Coins == 0 {
+		return ErrEmptyTrade
+	}
+	seen := make(map[string]bool)
+	for _, items := range [][]string{give.ItemIDs, want.ItemIDs} {
+		if len(items) > s.opts.MaxItems {
+			return fmt.Errorf("%w: at most %d per side", ErrTooManyItems, s.opts.MaxItems)
+		}
+		for _, id := range items {
+			if seen[id] {
+				return ErrDuplicateItem
+			}
+			seen[id] = true
+		}
+	}
+	return nil
+}
+
+func (s *Service) tradeLocked(playerID, tradeID string) (Trade, error) {
+	t, ok := s.state.Trades[tradeID]
+	if !ok {
+		return Trade{}, ErrUnknownTrade
+	}
+	if !t.Involves(playerID) {
+		return Trade{}, ErrNotYourTrade
+	}
+	return t, nil
+}
+
+func (s *Service) refundLocked(t Trade) (Trade, error) {
+	if s.jobs == nil {
+		return t, ErrEscrowUnavailable
+	}
+	if _, err := s.jobs.Enqueue("trade:refund:"+t.ID, EscrowRefundKind, EscrowJob{TradeID: t.ID}); err != nil {
+		return t, fmt.Errorf("could not queue the refund: %w", err)
+	}
+	return s.setStatusLocked(t, StatusRefunding), nil
+}
+
+// setStatusLocked stores t with status and saves the state.
+func (s *Service) setStatusLocked(t Trade, status Status) Trade {
+	t.Status = status
+	t.UpdatedAt = s.now()
+	s.state.Trades[t.ID] = t
+	s.saveLocked()
+	s.auditLocked(t)
+	if status.Final() {
+		s.items.Release(t.holder())
+	}
+	return t
+}
+
+// auditLocked records t's current status in the audit log.
+func (s *Service) auditLocked(t Trade) {
+	detail := fmt.Sprintf("value %d MIST, give %d items + %d MIST, want %d items + %d MIST", t.Value, len(t.Give.ItemIDs), t.Give.Coins, len(t.Want.ItemIDs), t.Want.Coins)
+	if t.ProposerFee != 0 || t.CounterpartyFee != 0 {
+		detail += fmt.Sprintf(", fees %d + %d", t.ProposerFee, t.CounterpartyFee)
+	}
+	if t.EscrowID != "" {
+		detail += ", escrow " + t.EscrowID
+	}
+	s.audit.Record(audit.Entry{Action: audit.ActionTrade, PlayerID: t.Proposer, Peer: t.Counterparty, Target: t.ID, Result: string(t.Status), Detail: detail})
+}
+
+// notifyLocked returns the notifications of t's players to deliver once the lock is released.
+func (s *Service) notifyLocked(t Trade) []func() {
+	var deliveries []func()
+	note := &Update{Trade: t}
+	for _, playerID := range []string{t.Proposer, t.Counterparty} {
+		if notify := s.notifiers[playerID]; notify != nil {
+			deliveries = append(deliveries, func() { notify(note) })
+		}
+	}
+	return deliveries
+}
+
+func (s *Service) saveLocked() {
+	if err := s.store.SaveState(s.state); err != nil {
+		utils.LogErrorf("Trade: Could not save trade state: %v", err)
+	}
+}
+
+func deliver(deliveries []func()) {
+	for _, d := range deliveries {
+		d()
+	}
+}
+
+func newTradeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "trade-" + hex.EncodeToString(b)
+}
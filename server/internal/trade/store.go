@@ -0,0 +1,130 @@
+package trade
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/jsonstore"
+)
+
+// Status is where a trade is in its lifecycle.
+type Status string
+
+const (
+	StatusProposed         Status = "proposed"          // Waiting for the counterparty
+	StatusAccepted         Status = "accepted"          // Direct trade agreed; the players transfer the assets themselves
+	StatusDeclined         Status = "declined"          // Refused by the counterparty
+	StatusCancelled        Status = "cancelled"         // Withdrawn by the proposer before acceptance
+	StatusExpired          Status = "expired"           // Not answered in time
+	StatusEscrowCreating   Status = "escrow_creating"   // Accepted; the escrow is being created on-chain
+	StatusAwaitingDeposits Status = "awaiting_deposits" // Escrow open for both sides' deposits
+	StatusReleasing        Status = "releasing"         // Fully deposited; the escrow is being released
+	StatusCompleted        Status = "completed"         // Escrow released to both players
+	StatusRefunding        Status = "refunding"         // Cancelled or timed out; deposits are being returned
+	StatusRefunded         Status = "refunded"          // Deposits returned
+)
+
+// InEscrow reports whether the trade's escrow holds, or may hold, player assets.
+func (s Status) InEscrow() bool {
+	switch s {
+	case StatusEscrowCreating, StatusAwaitingDeposits, StatusReleasing, StatusRefunding:
+		return true
+	}
+	return false
+}
+
+// Final reports whether the trade can no longer change.
+func (s Status) Final() bool {
+	switch s {
+	case StatusAccepted, StatusDeclined, StatusCancelled, StatusExpired, StatusCompleted, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Offer is what one side of a trade gives.
+type Offer struct {
+	ItemIDs []string `json:"itemIds"` // Item NFT object IDs
+	Coins   uint64   `json:"coins"`   // MIST
+}
+
+// Trade is a trade between two players. The proposer gives Give and receives Want.
+type Trade struct {
+	ID                  string    `json:"id"`
+	Proposer            string    `json:"proposer"`
+	Counterparty        string    `json:"counterparty"`
+	Give                Offer     `json:"give"`
+	Want                Offer     `json:"want"`
+	Value               uint64    `json:"value"` // Estimated MIST value of both sides
+	Escrowed            bool      `json:"escrowed"`
+	Status              Status    `json:"status"`
+	ProposerAddress     string    `json:"proposerAddress,omitempty"` // Escrowed trades only
+	CounterpartyAddress string    `json:"counterpartyAddress,omitempty"`
+	EscrowID            string    `json:"escrowId,omitempty"`
+	Region              string    `json:"region,omitempty"`          // Of the proposer's world; picks the fees
+	ProposerFee         uint64    `json:"proposerFee,omitempty"`     // MIST deposited on top of Give if escrowed, coins otherwise
+	CounterpartyFee     uint64    `json:"counterpartyFee,omitempty"` // The same for Want
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+	Deadline            time.Time `json:"deadline,omitempty"` // For the answer, or for the deposits once in escrow
+}
+
+// Involves reports whether playerID is one of the trade's players.
+func (t Trade) Involves(playerID string) bool {
+	return t.Proposer == playerID || t.Counterparty == playerID
+}
+
+// FeeOf returns what playerID pays to the treasury for the trade.
+func (t Trade) FeeOf(playerID string) uint64 {
+	switch playerID {
+	case t.Proposer:
+		return t.ProposerFee
+	case t.Counterparty:
+		return t.CounterpartyFee
+	}
+	return 0
+}
+
+// itemIDs returns the items of both sides.
+func (t Trade) itemIDs() []string {
+	return append(append([]string{}, t.Give.ItemIDs...), t.Want.ItemIDs...)
+}
+
+// holder names the trade in the item reservation registry.
+func (t Trade) holder() string {
+	return "trade:" + t.ID
+}
+
+// State is the persisted trade state.
+type State struct {
+	Trades map[string]Trade `json:"trades"`
+}
+
+// Store persists the trade state.
+type Store = jsonstore.Store[State]
+
+// MemoryStore keeps the trade state in memory.
+type MemoryStore = jsonstore.Memory[State]
+
+// NewFileStore keeps the trade state in a journal at path, each trade its
+// own record: a change to one escrow appends that trade rather than
+// rewriting every trade.
+func NewFileStore(path string) *jsonstore.Journal[State] {
+	return jsonstore.NewJournal[State](path, records{})
+}
+
+// records splits the trade state into its trades.
+type records struct{}
+
+// Split implements jsonstore.Records.
+func (records) Split(state State) map[string]any {
+	r := make(map[string]any, len(state.Trades))
+	jsonstore.PutRecords(r, "trade/", state.Trades)
+	return r
+}
+
+// Join implements jsonstore.Records.
+func (records) Join(r map[string]json.RawMessage) (State, error) {
+	trades, err := jsonstore.TakeRecords[Trade](r, "trade/")
+	return State{Trades: trades}, err
+}
@@ -0,0 +1,340 @@
+package trade
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/balance"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/reservation"
+	"github.com/phuhao00/suigserver/server/internal/shop"
+	"github.com/phuhao00/suigserver/server/internal/treasury"
+)
+
+type addressBook map[string]string
+
+func (b addressBook) Address(ctx context.Context, playerID string) (string, error) {
+	if address, ok := b[playerID]; ok {
+		return address, nil
+	}
+	return "", errors.New("not linked")
+}
+
+type fakeEscrow struct {
+	created   []Trade
+	complete  bool
+	released  []string
+	refunded  []string
+	expiresAt time.Time
+}
+
+func (f *fakeEscrow) Create(ctx context.Context, t Trade, expiresAt time.Time) (string, error) {
+	f.created = append(f.created, t)
+	f.expiresAt = expiresAt
+	return "0xescrow", nil
+}
+
+func (f *fakeEscrow) Complete(ctx context.Context, escrowID string) (bool, error) {
+	return f.complete, nil
+}
+
+func (f *fakeEscrow) Release(ctx context.Context, escrowID string) (string, error) {
+	f.released = append(f.released, escrowID)
+	return "release-digest", nil
+}
+
+func (f *fakeEscrow) Refund(ctx context.Context, escrowID string) (string, error) {
+	f.refunded = append(f.refunded, escrowID)
+	return "refund-digest", nil
+}
+
+// runJobs delivers every queued escrow job as the outbox would.
+func runJobs(t *testing.T, s *Service, store *outbox.MemoryStore) {
+	t.Helper()
+	messages, err := store.Due(time.Now().Add(time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers := map[string]outbox.Handler{
+		EscrowCreateKind:  s.createEscrow,
+		EscrowReleaseKind: s.releaseEscrow,
+		EscrowRefundKind:  s.refundEscrow,
+	}
+	for _, msg := range messages {
+		if err := handlers[msg.Kind](context.Background(), msg.Payload); err != nil {
+			t.Fatalf("%s: %v", msg.Kind, err)
+		}
+		store.Delete(msg.ID)
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *fakeEscrow, *outbox.MemoryStore) {
+	t.Helper()
+	s, err := NewService(Options{EscrowThreshold: 1000, ItemValue: 400}, &MemoryStore{}, addressBook{"alice": "0xa", "bob": "0xb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	escrow, jobs := &fakeEscrow{}, outbox.NewMemoryStore()
+	s.EnableEscrow(escrow, outbox.New(jobs, outbox.Options{}))
+	return s, escrow, jobs
+}
+
+func TestSmallTradeSkipsEscrow(t *testing.T) {
+	s, escrow, jobs := newTestService(t)
+	var updates []Status
+	s.Connect("bob", "", func(note interface{}) { updates = append(updates, note.(*Update).Trade.Status) })
+
+	proposed, err := s.Propose(context.Background(), "alice", "bob", Offer{ItemIDs: []string{"0x1"}}, Offer{Coins: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proposed.Escrowed || proposed.Value != 900 {
+		t.Fatalf("trade = %+v, want a direct trade worth 900", proposed)
+	}
+	if _, err := s.Accept("alice", proposed.ID); !errors.Is(err, ErrWrongState) {
+		t.Fatalf("proposer accepting: %v", err)
+	}
+	accepted, err := s.Accept("bob", proposed.ID)
+	if err != nil || accepted.Status != StatusAccepted {
+		t.Fatalf("accept = %+v, %v", accepted, err)
+	}
+	runJobs(t, s, jobs)
+	if len(escrow.created) != 0 {
+		t.Fatal("a direct trade created an escrow")
+	}
+	if len(updates) != 2 || updates[1] != StatusAccepted {
+		t.Fatalf("bob's updates = %v", updates)
+	}
+}
+
+func TestEscrowedTradeReleasesOnceFullyDeposited(t *testing.T) {
+	s, escrow, jobs := newTestService(t)
+	ctx := context.Background()
+	proposed, err := s.Propose(ctx, "alice", "bob", Offer{ItemIDs: []string{"0x1", "0x2"}}, Offer{Coins: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proposed.Escrowed || proposed.ProposerAddress != "0xa" || proposed.CounterpartyAddress != "0xb" {
+		t.Fatalf("trade = %+v, want an escrowed trade between 0xa and 0xb", proposed)
+	}
+	if _, err := s.Accept("bob", proposed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Deposited(ctx, "alice", proposed.ID); !errors.Is(err, ErrWrongState) {
+		t.Fatalf("deposit before the escrow exists: %v", err)
+	}
+	runJobs(t, s, jobs)
+	if len(escrow.created) != 1 || escrow.expiresAt.IsZero() {
+		t.Fatalf("escrow created %d times", len(escrow.created))
+	}
+	if _, err := s.Deposited(ctx, "alice", proposed.ID); !errors.Is(err, ErrDepositsIncomplete) {
+		t.Fatalf("deposit check with deposits missing: %v", err)
+	}
+	if err := s.CheckDeletion(ctx, "bob"); err == nil {
+		t.Fatal("deletion allowed while the trade is in escrow")
+	}
+
+	bus := events.NewBus(0)
+	var receipts []events.TxCompleted
+	events.On(bus, events.TopicTxCompleted, "test", func(e events.TxCompleted) { receipts = append(receipts, e) })
+	s.UseEvents(bus)
+	escrow.complete = true
+	releasing, err := s.Deposited(ctx, "bob", proposed.ID)
+	if err != nil || releasing.Status != StatusReleasing {
+		t.Fatalf("deposited = %+v, %v", releasing, err)
+	}
+	runJobs(t, s, jobs)
+	bus.Close()
+	if len(escrow.released) != 1 || len(escrow.refunded) != 0 {
+		t.Fatalf("released %v, refunded %v", escrow.released, escrow.refunded)
+	}
+	if len(receipts) != 1 || receipts[0].Digest != "release-digest" || receipts[0].Status != events.TxSuccess || len(receipts[0].PlayerIDs) != 2 {
+		t.Fatalf("receipts = %+v", receipts)
+	}
+	if got := s.state.Trades[proposed.ID].Status; got != StatusCompleted {
+		t.Fatalf("status = %s, want %s", got, StatusCompleted)
+	}
+	if err := s.CheckDeletion(ctx, "bob"); err != nil {
+		t.Fatalf("deletion after the trade completed: %v", err)
+	}
+}
+
+func TestUnfundedEscrowIsRefundedAtDeadline(t *testing.T) {
+	s, escrow, jobs := newTestService(t)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	proposed, err := s.Propose(context.Background(), "alice", "bob", Offer{Coins: 2000}, Offer{ItemIDs: []string{"0x3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Accept("bob", proposed.ID)
+	runJobs(t, s, jobs)
+
+	s.Tick(now.Add(DefaultDepositTTL - time.Second))
+	if got := s.state.Trades[proposed.ID].Status; got != StatusAwaitingDeposits {
+		t.Fatalf("status before the deadline = %s", got)
+	}
+	s.Tick(now.Add(DefaultDepositTTL))
+	runJobs(t, s, jobs)
+	if len(escrow.refunded) != 1 {
+		t.Fatalf("refunded %v", escrow.refunded)
+	}
+	if got := s.state.Trades[proposed.ID].Status; got != StatusRefunded {
+		t.Fatalf("status = %s, want %s", got, StatusRefunded)
+	}
+}
+
+func TestEscrowedTradeNeedsLinkedAddresses(t *testing.T) {
+	s, _, _ := newTestService(t)
+	if _, err := s.Propose(context.Background(), "alice", "carol", Offer{Coins: 5000}, Offer{}); err == nil {
+		t.Fatal("escrowed trade proposed to a player without an address")
+	}
+	withoutEscrow, _ := NewService(Options{EscrowThreshold: 1000}, &MemoryStore{}, addressBook{})
+	if _, err := withoutEscrow.Propose(context.Background(), "alice", "bob", Offer{Coins: 5000}, Offer{}); !errors.Is(err, ErrEscrowUnavailable) {
+		t.Fatalf("escrowed trade without escrow: %v", err)
+	}
+}
+
+func TestEscrowedTradeChecksItemOwnership(t *testing.T) {
+	s, _, _ := newTestService(t)
+	owners := map[string]string{"0x1": "0xa", "0x2": "0xa", "0x3": "0xb"}
+	errNotOwned := errors.New("not owned")
+	s.UseOwnershipCheck(func(ctx context.Context, owner string, itemIDs []string) error {
+		for _, id := range itemIDs {
+			if owners[id] != owner {
+				return errNotOwned
+			}
+		}
+		return nil
+	})
+	ctx := context.Background()
+	if _, err := s.Propose(ctx, "alice", "bob", Offer{ItemIDs: []string{"0x1", "0x3"}}, Offer{Coins: 5000}); !errors.Is(err, errNotOwned) {
+		t.Fatalf("offering bob's item: %v", err)
+	}
+	if _, err := s.Propose(ctx, "alice", "bob", Offer{ItemIDs: []string{"0x1"}}, Offer{ItemIDs: []string{"0x2"}, Coins: 5000}); !errors.Is(err, errNotOwned) {
+		t.Fatalf("asking bob for alice's item: %v", err)
+	}
+	if _, err := s.Propose(ctx, "alice", "bob", Offer{ItemIDs: []string{"0x1", "0x2"}}, Offer{ItemIDs: []string{"0x3"}, Coins: 5000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPausedEscrowRefusesNewEscrowedTrades(t *testing.T) {
+	s, _, jobs := newTestService(t)
+	ctx := context.Background()
+	open, err := s.Propose(ctx, "alice", "bob", Offer{Coins: 5000}, Offer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEscrowPaused(true)
+	if _, err := s.Propose(ctx, "alice", "bob", Offer{Coins: 5000}, Offer{}); !errors.Is(err, ErrEscrowPaused) {
+		t.Fatalf("escrowed proposal while paused: %v", err)
+	}
+	if _, err := s.Accept("bob", open.ID); !errors.Is(err, ErrEscrowPaused) {
+		t.Fatalf("accepting an escrowed trade while paused: %v", err)
+	}
+	if _, err := s.Propose(ctx, "alice", "bob", Offer{Coins: 500}, Offer{}); err != nil {
+		t.Fatalf("direct trade while escrow is paused: %v", err)
+	}
+	s.SetEscrowPaused(false)
+	if _, err := s.Accept("bob", open.ID); err != nil {
+		t.Fatal(err)
+	}
+	runJobs(t, s, jobs)
+}
+
+func TestTradedItemsAreReservedUntilTheTradeEnds(t *testing.T) {
+	s, _, _ := newTestService(t)
+	items := reservation.NewRegistry()
+	s.UseReservations(items)
+	ctx := context.Background()
+
+	if err := items.Reserve("marketplace:list:0xb", time.Minute, "0xbow"); err != nil {
+		t.Fatal(err)
+	}
+	proposed, err := s.Propose(ctx, "alice", "bob", Offer{ItemIDs: []string{"0xsword"}}, Offer{ItemIDs: []string{"0xbow"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Propose(ctx, "alice", "carol", Offer{ItemIDs: []string{"0xsword"}}, Offer{Coins: 100}); !errors.Is(err, reservation.ErrReserved) {
+		t.Fatalf("offering an item already in a trade: %v", err)
+	}
+	if _, err := s.Accept("bob", proposed.ID); !errors.Is(err, reservation.ErrReserved) {
+		t.Fatalf("accepting with an item being listed: %v", err)
+	}
+
+	items.Release("marketplace:list:0xb")
+	if _, err := s.Accept("bob", proposed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if held := items.List(); len(held) != 0 {
+		t.Errorf("items still reserved after the trade was settled: %v", held)
+	}
+}
+
+func TestTradeFeesArePaidToTheTreasury(t *testing.T) {
+	s, escrow, jobs := newTestService(t)
+	ctx := context.Background()
+	wallets := &shop.MemoryWallets{StartingCoins: 30}
+	wallets.SaveWallet("carol", shop.Wallet{Coins: 5})
+	ledger, err := treasury.Open(&treasury.MemoryStore{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fees := balance.FeeValues{
+		Trade:       balance.FeeRate{BasisPoints: 100},
+		DirectTrade: 10,
+		Overrides:   []balance.FeeOverride{{Region: "eu", Trade: &balance.FeeRate{BasisPoints: 300}}},
+	}
+	s.UseFees(func(region string) balance.Fees { return fees.In(region) }, wallets, ledger)
+	s.Connect("alice", "eu", func(interface{}) {})
+
+	escrowed, err := s.Propose(ctx, "alice", "bob", Offer{Coins: 5100}, Offer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if escrowed.Region != "eu" || escrowed.ProposerFee != 77 || escrowed.CounterpartyFee != 76 {
+		t.Fatalf("escrowed trade fees = %s %d + %d, want eu 77 + 76", escrowed.Region, escrowed.ProposerFee, escrowed.CounterpartyFee)
+	}
+	s.Accept("bob", escrowed.ID)
+	runJobs(t, s, jobs)
+	if created := escrow.created[0]; created.ProposerFee != 77 {
+		t.Fatalf("escrow created without the fees: %+v", created)
+	}
+	if report := ledger.Report(""); len(report.Totals) != 0 {
+		t.Fatalf("fees recorded before the escrow was released: %+v", report.Totals)
+	}
+	escrow.complete = true
+	s.Deposited(ctx, "alice", escrowed.ID)
+	runJobs(t, s, jobs)
+
+	direct, err := s.Propose(ctx, "bob", "alice", Offer{Coins: 100}, Offer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Accept("alice", direct.ID); err != nil {
+		t.Fatal(err)
+	}
+	if alice, _ := wallets.LoadWallet("alice"); alice.Coins != 20 {
+		t.Errorf("alice has %d coins after a direct trade fee of 10, want 20", alice.Coins)
+	}
+	poor, _ := s.Propose(ctx, "bob", "carol", Offer{Coins: 100}, Offer{})
+	if _, err := s.Accept("carol", poor.ID); !errors.Is(err, ErrFeeUnpaid) {
+		t.Fatalf("accepting without the coins for the fee: %v", err)
+	}
+	if bob, _ := wallets.LoadWallet("bob"); bob.Coins != 20 {
+		t.Errorf("bob has %d coins, want 20: he paid a fee for a trade that was refused", bob.Coins)
+	}
+
+	report := ledger.Report("")
+	want := []treasury.Total{
+		{Source: treasury.SourceDirectTrade, Currency: treasury.CurrencyCoins, Amount: 20, Count: 2},
+		{Source: treasury.SourceTrade, Currency: treasury.CurrencyMIST, Region: "eu", Amount: 153, Count: 2},
+	}
+	if len(report.Totals) != len(want) || report.Totals[0] != want[0] || report.Totals[1] != want[1] {
+		t.Errorf("treasury totals = %+v, want %+v", report.Totals, want)
+	}
+}
@@ -0,0 +1,96 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// maxBundleSize bounds an uploaded bundle.
+const maxBundleSize = 16 << 20
+
+// RegisterHandlers adds the transfer workflow to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header naming the
+// operator.
+//
+//	POST /admin/transfer/export?playerId=ID                             the bundle, on the source
+//	POST /admin/transfer/import?onConflict=fail|rename|overwrite&dryRun=true&force=true   body: bundle, on the target
+//	POST /admin/transfer/retire?playerId=ID   body: {"movedTo": "shard", "confirmPlayerId": "ID"}, on the source
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/transfer/export", adminhttp.Method(http.MethodPost, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		bundle, err := s.Export(r.Context(), r.URL.Query().Get("playerId"))
+		if errors.Is(err, ErrBlocked) {
+			adminhttp.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="player-transfer.json"`)
+		adminhttp.WriteJSON(w, http.StatusOK, bundle)
+	})))
+	mux.HandleFunc("/admin/transfer/import", adminhttp.Method(http.MethodPost, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		query := r.URL.Query()
+		policy, err := ParsePolicy(query.Get("onConflict"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts := ImportOptions{OnConflict: policy}
+		for name, flag := range map[string]*bool{"dryRun": &opts.DryRun, "force": &opts.Force} {
+			if v := query.Get(name); v != "" {
+				if *flag, err = strconv.ParseBool(v); err != nil {
+					adminhttp.WriteError(w, http.StatusBadRequest, errors.New(name+" must be true or false"))
+					return
+				}
+			}
+		}
+		var bundle Bundle
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBundleSize)).Decode(&bundle); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("body must be a bundle from /admin/transfer/export"))
+			return
+		}
+		report, err := s.Import(r.Context(), &bundle, opts)
+		switch {
+		case errors.Is(err, ErrConflict), errors.Is(err, ErrAssetsMoved):
+			adminhttp.WriteJSON(w, http.StatusConflict, struct {
+				Error  string  `json:"error"`
+				Report *Report `json:"report"`
+			}{err.Error(), report})
+		case err != nil:
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+		default:
+			adminhttp.WriteJSON(w, http.StatusOK, report)
+		}
+	})))
+	mux.HandleFunc("/admin/transfer/retire", adminhttp.Method(http.MethodPost, adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, operator string) {
+		playerID := r.URL.Query().Get("playerId")
+		var body struct {
+			MovedTo         string `json:"movedTo"`
+			ConfirmPlayerID string `json:"confirmPlayerId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("body must be JSON with movedTo and confirmPlayerId"))
+			return
+		}
+		// Retiring removes the player's state here, so the player ID must be given twice.
+		if playerID == "" || body.ConfirmPlayerID != playerID || body.MovedTo == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId, a matching confirmPlayerId and movedTo are required"))
+			return
+		}
+		retired, failed, err := s.Retire(r.Context(), playerID, body.MovedTo)
+		if errors.Is(err, ErrBlocked) {
+			adminhttp.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, map[string]interface{}{"playerId": playerID, "movedTo": body.MovedTo, "retired": retired, "failed": failed})
+	})))
+}
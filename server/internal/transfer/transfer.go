@@ -0,0 +1,442 @@
+// Package transfer moves a player's server-side state from one shard to
+// another, e.g. when shards merge. An operator exports the player on the
+// source server, which also records the on-chain objects the player's linked
+// addresses own; imports the bundle on the target, which checks that those
+// objects are still owned by the same addresses and resolves clashing player
+// IDs and display names; and then retires the player on the source so their
+// inventory cannot be played in two places.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// BundleVersion is the bundle format this server writes and reads.
+const BundleVersion = 1
+
+// maxRenames bounds the suffixes tried when renaming a clashing ID or name.
+const maxRenames = 100
+
+var (
+	// ErrBlocked is returned (wrapped) when a Guard refuses a transfer.
+	ErrBlocked = errors.New("transfer blocked")
+	// ErrConflict is returned (wrapped) when the bundle clashes with the
+	// target's data and the conflict policy does not resolve it.
+	ErrConflict = errors.New("transfer conflict")
+	// ErrAssetsMoved is returned when on-chain objects recorded at export are
+	// no longer owned by the same address.
+	ErrAssetsMoved = errors.New("on-chain assets changed owner since export")
+)
+
+// Policy says what an import does when the player's ID or display name is
+// already taken on the target.
+type Policy string
+
+// Conflict policies.
+const (
+	PolicyFail      Policy = "fail"      // Refuse the import
+	PolicyRename    Policy = "rename"    // Import under a new ID or name, suffixed with the source shard
+	PolicyOverwrite Policy = "overwrite" // Replace the target's data for the ID; clashing names are still renamed
+)
+
+// ParsePolicy parses a conflict policy; "" is PolicyFail.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case "":
+		return PolicyFail, nil
+	case PolicyFail, PolicyRename, PolicyOverwrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q: use fail, rename or overwrite", s)
+}
+
+// Section is one store of a player's server-side state.
+type Section interface {
+	Name() string
+	// Export returns the player's data, or nil if there is none.
+	Export(ctx context.Context, playerID string) (json.RawMessage, error)
+	// Exists reports whether this server holds data for playerID.
+	Exists(ctx context.Context, playerID string) (bool, error)
+	// Check returns the clashes importing data as playerID would cause that no
+	// policy can resolve, e.g. an address linked to another player.
+	Check(ctx context.Context, playerID string, data json.RawMessage) ([]string, error)
+	// Import writes data as playerID's, replacing anything held for playerID.
+	Import(ctx context.Context, playerID, displayName string, data json.RawMessage) error
+	// Retire removes the player's data after they moved to another shard,
+	// leaving a marker where the store keeps one.
+	Retire(ctx context.Context, playerID, movedTo string) error
+}
+
+// Guard can veto a transfer, e.g. while the player is online or has assets in
+// escrow.
+type Guard interface {
+	Name() string
+	CheckTransfer(ctx context.Context, playerID string) error
+}
+
+// Names looks up display names on this server.
+type Names interface {
+	// DisplayName returns the player's display name, or "" if they have none.
+	DisplayName(ctx context.Context, playerID string) (string, error)
+	// NameOwner returns the player using displayName, or "" if it is free.
+	NameOwner(ctx context.Context, displayName string) (string, error)
+}
+
+// Chain lists the objects an address owns on-chain. *sui.SuiClient implements it.
+type Chain interface {
+	OwnedObjectIDs(address string) ([]string, error)
+}
+
+// Bundle is an exported player.
+type Bundle struct {
+	Version     int                        `json:"version"`
+	PlayerID    string                     `json:"playerId"`
+	DisplayName string                     `json:"displayName,omitempty"`
+	Source      string                     `json:"source"` // Shard the player was exported from
+	ExportedAt  time.Time                  `json:"exportedAt"`
+	Sections    map[string]json.RawMessage `json:"sections"` // Section name -> exported data
+	Holdings    []Holding                  `json:"holdings,omitempty"`
+}
+
+// Holding is what one of the player's linked addresses owned at export.
+type Holding struct {
+	Address string   `json:"address"`
+	Objects []string `json:"objects"` // Object IDs, sorted
+}
+
+// AssetCheck is the import-time check of one Holding.
+type AssetCheck struct {
+	Address string   `json:"address"`
+	Objects int      `json:"objects"`           // Recorded at export
+	Missing []string `json:"missing,omitempty"` // No longer owned by the address
+}
+
+// ImportOptions control an import.
+type ImportOptions struct {
+	OnConflict Policy
+	DryRun     bool // Check and resolve everything but write nothing
+	Force      bool // Import even if on-chain objects changed owner
+}
+
+// Report is the result of an import.
+type Report struct {
+	PlayerID    string            `json:"playerId"`              // ID in the bundle
+	ImportedAs  string            `json:"importedAs"`            // ID on this server
+	DisplayName string            `json:"displayName,omitempty"` // Name on this server
+	Source      string            `json:"source"`
+	DryRun      bool              `json:"dryRun,omitempty"`
+	Resolved    []string          `json:"resolved,omitempty"`  // Clashes the policy resolved, and how
+	Conflicts   []string          `json:"conflicts,omitempty"` // Clashes that refused the import
+	Assets      []AssetCheck      `json:"assets,omitempty"`
+	Imported    []string          `json:"imported,omitempty"` // Sections written
+	Failed      map[string]string `json:"failed,omitempty"`   // Section name -> import failure
+}
+
+// Service exports, imports and retires players. Register sections and guards
+// before serving requests.
+type Service struct {
+	shard    string
+	links    accountlink.Store
+	chain    Chain
+	names    Names
+	sections []Section
+	guards   []Guard
+	now      func() time.Time
+}
+
+// NewService creates a Service for the shard named shard. links and chain
+// record and verify on-chain holdings; names finds clashing display names and
+// may be nil, in which case names are not checked.
+func NewService(shard string, links accountlink.Store, chain Chain, names Names) *Service {
+	return &Service{shard: shard, links: links, chain: chain, names: names, now: time.Now}
+}
+
+// AddSection registers a store of player state.
+func (s *Service) AddSection(section Section) {
+	s.sections = append(s.sections, section)
+}
+
+// AddGuard registers a transfer safeguard.
+func (s *Service) AddGuard(guard Guard) {
+	s.guards = append(s.guards, guard)
+}
+
+// Export bundles the player's state from every section and records what
+// their linked addresses own on-chain. It changes nothing; Retire the player
+// once the bundle is imported.
+func (s *Service) Export(ctx context.Context, playerID string) (*Bundle, error) {
+	if playerID == "" {
+		return nil, fmt.Errorf("player ID is required")
+	}
+	if err := s.checkGuards(ctx, playerID); err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{
+		Version:    BundleVersion,
+		PlayerID:   playerID,
+		Source:     s.shard,
+		ExportedAt: s.now().UTC(),
+		Sections:   make(map[string]json.RawMessage),
+	}
+	for _, section := range s.sections {
+		data, err := section.Export(ctx, playerID)
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", section.Name(), err)
+		}
+		if data != nil {
+			bundle.Sections[section.Name()] = data
+		}
+	}
+	if len(bundle.Sections) == 0 {
+		return nil, fmt.Errorf("player %s has no data on this server", playerID)
+	}
+	if s.names != nil {
+		name, err := s.names.DisplayName(ctx, playerID)
+		if err != nil {
+			return nil, fmt.Errorf("reading display name: %w", err)
+		}
+		bundle.DisplayName = name
+	}
+	links, err := s.links.Links(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("reading linked addresses: %w", err)
+	}
+	for _, link := range links {
+		objects, err := s.chain.OwnedObjectIDs(link.Address)
+		if err != nil {
+			return nil, fmt.Errorf("listing objects owned by %s: %w", link.Address, err)
+		}
+		sort.Strings(objects)
+		bundle.Holdings = append(bundle.Holdings, Holding{Address: link.Address, Objects: objects})
+	}
+	utils.LogInfof("Transfer: Exported player %s from %s (%d sections, %d addresses).", playerID, s.shard, len(bundle.Sections), len(bundle.Holdings))
+	return bundle, nil
+}
+
+// Import writes the bundle's state to every section it has data for. The
+// player keeps their ID and name unless they clash, which opts.OnConflict
+// resolves. Clashes no policy resolves, and on-chain objects that changed
+// owner since the export unless opts.Force is set, refuse the import before
+// anything is written; the report says why. Sections are all attempted even
+// if one fails; failures are in the report and the import can be retried
+// with PolicyOverwrite.
+func (s *Service) Import(ctx context.Context, bundle *Bundle, opts ImportOptions) (*Report, error) {
+	if bundle == nil || bundle.PlayerID == "" {
+		return nil, fmt.Errorf("bundle has no player")
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is not supported; want %d", bundle.Version, BundleVersion)
+	}
+	if opts.OnConflict == "" {
+		opts.OnConflict = PolicyFail
+	}
+	report := &Report{PlayerID: bundle.PlayerID, ImportedAs: bundle.PlayerID, DisplayName: bundle.DisplayName, Source: bundle.Source, DryRun: opts.DryRun}
+
+	if err := s.resolveID(ctx, bundle, opts.OnConflict, report); err != nil {
+		return report, err
+	}
+	if err := s.resolveName(ctx, bundle, opts.OnConflict, report); err != nil {
+		return report, err
+	}
+	for _, section := range s.sections {
+		data, ok := bundle.Sections[section.Name()]
+		if !ok {
+			continue
+		}
+		clashes, err := section.Check(ctx, report.ImportedAs, data)
+		if err != nil {
+			return report, fmt.Errorf("checking %s: %w", section.Name(), err)
+		}
+		report.Conflicts = append(report.Conflicts, clashes...)
+	}
+	if len(report.Conflicts) > 0 {
+		return report, fmt.Errorf("%w: %s", ErrConflict, strings.Join(report.Conflicts, "; "))
+	}
+	moved, err := s.verifyHoldings(bundle, report)
+	if err != nil {
+		return report, err
+	}
+	if moved > 0 && !opts.Force {
+		return report, fmt.Errorf("%w: %d object(s)", ErrAssetsMoved, moved)
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, section := range s.sections {
+		data, ok := bundle.Sections[section.Name()]
+		if !ok {
+			continue
+		}
+		if err := section.Import(ctx, report.ImportedAs, report.DisplayName, data); err != nil {
+			if report.Failed == nil {
+				report.Failed = make(map[string]string)
+			}
+			report.Failed[section.Name()] = err.Error()
+			utils.LogErrorf("Transfer: Failed to import %s data for player %s: %v", section.Name(), report.ImportedAs, err)
+			continue
+		}
+		report.Imported = append(report.Imported, section.Name())
+	}
+	utils.LogInfof("Transfer: Imported player %s from %s as %s (%d sections, %d failed).", bundle.PlayerID, bundle.Source, report.ImportedAs, len(report.Imported), len(report.Failed))
+	return report, nil
+}
+
+// Retire removes the player's state from every section after they were
+// imported on movedTo. Sections are all attempted even if one fails; the
+// failed ones are returned and the request can be retried.
+func (s *Service) Retire(ctx context.Context, playerID, movedTo string) (retired []string, failed map[string]string, err error) {
+	if playerID == "" || movedTo == "" {
+		return nil, nil, fmt.Errorf("player ID and destination are required")
+	}
+	if err := s.checkGuards(ctx, playerID); err != nil {
+		return nil, nil, err
+	}
+	for _, section := range s.sections {
+		if err := section.Retire(ctx, playerID, movedTo); err != nil {
+			if failed == nil {
+				failed = make(map[string]string)
+			}
+			failed[section.Name()] = err.Error()
+			utils.LogErrorf("Transfer: Failed to retire %s data for player %s: %v", section.Name(), playerID, err)
+			continue
+		}
+		retired = append(retired, section.Name())
+	}
+	utils.LogInfof("Transfer: Retired player %s, moved to %s.", playerID, movedTo)
+	return retired, failed, nil
+}
+
+func (s *Service) checkGuards(ctx context.Context, playerID string) error {
+	for _, guard := range s.guards {
+		if err := guard.CheckTransfer(ctx, playerID); err != nil {
+			return fmt.Errorf("%w by %s: %v", ErrBlocked, guard.Name(), err)
+		}
+	}
+	return nil
+}
+
+// resolveID picks the ID the player is imported as.
+func (s *Service) resolveID(ctx context.Context, bundle *Bundle, policy Policy, report *Report) error {
+	taken, err := s.exists(ctx, bundle.PlayerID)
+	if err != nil || !taken {
+		return err
+	}
+	switch policy {
+	case PolicyOverwrite:
+		report.Resolved = append(report.Resolved, fmt.Sprintf("player ID %s exists and is overwritten", bundle.PlayerID))
+		return nil
+	case PolicyRename:
+		for i := 1; i <= maxRenames; i++ {
+			id := withSuffix(bundle.PlayerID, bundle.Source, i)
+			taken, err := s.exists(ctx, id)
+			if err != nil {
+				return err
+			}
+			if !taken {
+				report.ImportedAs = id
+				report.Resolved = append(report.Resolved, fmt.Sprintf("player ID %s exists; imported as %s", bundle.PlayerID, id))
+				return nil
+			}
+		}
+		report.Conflicts = append(report.Conflicts, fmt.Sprintf("no free ID for %s", bundle.PlayerID))
+	default:
+		report.Conflicts = append(report.Conflicts, fmt.Sprintf("player ID %s exists", bundle.PlayerID))
+	}
+	return fmt.Errorf("%w: %s", ErrConflict, report.Conflicts[len(report.Conflicts)-1])
+}
+
+// resolveName picks the display name the player is imported with. A name
+// used by another player is never overwritten.
+func (s *Service) resolveName(ctx context.Context, bundle *Bundle, policy Policy, report *Report) error {
+	if s.names == nil || bundle.DisplayName == "" {
+		return nil
+	}
+	taken := func(name string) (bool, error) {
+		owner, err := s.names.NameOwner(ctx, name)
+		return owner != "" && owner != report.ImportedAs, err
+	}
+	clash, err := taken(bundle.DisplayName)
+	if err != nil || !clash {
+		return err
+	}
+	if policy != PolicyFail {
+		for i := 1; i <= maxRenames; i++ {
+			name := withSuffix(bundle.DisplayName, bundle.Source, i)
+			clash, err := taken(name)
+			if err != nil {
+				return err
+			}
+			if !clash {
+				report.DisplayName = name
+				report.Resolved = append(report.Resolved, fmt.Sprintf("display name %q is taken; renamed to %q", bundle.DisplayName, name))
+				return nil
+			}
+		}
+	}
+	report.Conflicts = append(report.Conflicts, fmt.Sprintf("display name %q is taken", bundle.DisplayName))
+	return fmt.Errorf("%w: display name %q is taken", ErrConflict, bundle.DisplayName)
+}
+
+func (s *Service) exists(ctx context.Context, playerID string) (bool, error) {
+	for _, section := range s.sections {
+		found, err := section.Exists(ctx, playerID)
+		if err != nil {
+			return false, fmt.Errorf("looking up %s in %s: %w", playerID, section.Name(), err)
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyHoldings checks that every object recorded at export is still owned by
+// the same address, and returns how many are not.
+func (s *Service) verifyHoldings(bundle *Bundle, report *Report) (int, error) {
+	moved := 0
+	for _, holding := range bundle.Holdings {
+		owned, err := s.chain.OwnedObjectIDs(holding.Address)
+		if err != nil {
+			return 0, fmt.Errorf("listing objects owned by %s: %w", holding.Address, err)
+		}
+		still := make(map[string]bool, len(owned))
+		for _, id := range owned {
+			still[id] = true
+		}
+		check := AssetCheck{Address: holding.Address, Objects: len(holding.Objects)}
+		for _, id := range holding.Objects {
+			if !still[id] {
+				check.Missing = append(check.Missing, id)
+			}
+		}
+		moved += len(check.Missing)
+		report.Assets = append(report.Assets, check)
+	}
+	return moved, nil
+}
+
+var nonSuffix = regexp.MustCompile(`[^a-z0-9]+`)
+
+// withSuffix appends the source shard to s, and a number after the first try.
+func withSuffix(s, source string, try int) string {
+	suffix := strings.Trim(nonSuffix.ReplaceAllString(strings.ToLower(source), "-"), "-")
+	if suffix == "" {
+		suffix = "transfer"
+	}
+	if try > 1 {
+		suffix += strconv.Itoa(try)
+	}
+	return s + "-" + suffix
+}
@@ -0,0 +1,160 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/accountlink"
+)
+
+const addr = "0x00000000000000000000000000000000000000000000000000000000000000a1"
+
+// profiles is a Section and Names keeping display names by player.
+type profiles map[string]string
+
+func (p profiles) Name() string { return "profile" }
+func (p profiles) Export(_ context.Context, playerID string) (json.RawMessage, error) {
+	if name, ok := p[playerID]; ok {
+		return json.Marshal(name)
+	}
+	return nil, nil
+}
+func (p profiles) Exists(_ context.Context, playerID string) (bool, error) {
+	_, ok := p[playerID]
+	return ok, nil
+}
+func (p profiles) Check(context.Context, string, json.RawMessage) ([]string, error) { return nil, nil }
+func (p profiles) Import(_ context.Context, playerID, displayName string, _ json.RawMessage) error {
+	p[playerID] = displayName
+	return nil
+}
+func (p profiles) Retire(_ context.Context, playerID, _ string) error {
+	delete(p, playerID)
+	return nil
+}
+func (p profiles) DisplayName(_ context.Context, playerID string) (string, error) {
+	return p[playerID], nil
+}
+func (p profiles) NameOwner(_ context.Context, displayName string) (string, error) {
+	for id, name := range p {
+		if strings.EqualFold(name, displayName) {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// chain maps addresses to the objects they own.
+type chain map[string][]string
+
+func (c chain) OwnedObjectIDs(address string) ([]string, error) { return c[address], nil }
+
+type shard struct {
+	service  *Service
+	profiles profiles
+	links    *accountlink.MemoryStore
+}
+
+func newShard(name string, c chain) shard {
+	s := shard{profiles: profiles{}, links: accountlink.NewMemoryStore()}
+	s.service = NewService(name, s.links, c, s.profiles)
+	s.service.AddSection(s.profiles)
+	s.service.AddSection(accountlink.TransferSection{Store: s.links})
+	return s
+}
+
+func TestTransferResolvesClashingIDsAndNames(t *testing.T) {
+	ctx := context.Background()
+	objects := chain{addr: {"0xsword", "0xshield"}}
+	source, target := newShard("eu-west-a", objects), newShard("eu-west-b", objects)
+	source.profiles["p1"] = "Alice"
+	source.links.Save(ctx, accountlink.Link{PlayerID: "p1", Address: addr, Primary: true, LinkedAt: time.Now()})
+	target.profiles["p1"] = "Bob"
+	target.profiles["p2"] = "alice"
+
+	bundle, err := source.service.Export(ctx, "p1")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if bundle.DisplayName != "Alice" || len(bundle.Holdings) != 1 || len(bundle.Holdings[0].Objects) != 2 {
+		t.Fatalf("bundle = %+v", bundle)
+	}
+
+	report, err := target.service.Import(ctx, bundle, ImportOptions{})
+	if !errors.Is(err, ErrConflict) || len(report.Conflicts) != 1 {
+		t.Fatalf("import with the fail policy = %+v, %v; want an ID conflict", report, err)
+	}
+	report, err = target.service.Import(ctx, bundle, ImportOptions{OnConflict: PolicyRename, DryRun: true})
+	if err != nil || report.ImportedAs != "p1-eu-west-a" || report.DisplayName != "Alice-eu-west-a" || len(report.Imported) != 0 {
+		t.Fatalf("dry run = %+v, %v", report, err)
+	}
+	if _, ok := target.profiles["p1-eu-west-a"]; ok {
+		t.Fatal("a dry run imported the player")
+	}
+
+	report, err = target.service.Import(ctx, bundle, ImportOptions{OnConflict: PolicyRename})
+	if err != nil || len(report.Imported) != 2 {
+		t.Fatalf("import = %+v, %v", report, err)
+	}
+	if target.profiles["p1-eu-west-a"] != "Alice-eu-west-a" || target.profiles["p1"] != "Bob" {
+		t.Fatalf("target profiles = %v", target.profiles)
+	}
+	if owner, _ := target.links.Owner(ctx, addr); owner != "p1-eu-west-a" {
+		t.Fatalf("address linked to %q on the target", owner)
+	}
+
+	if _, _, err := source.service.Retire(ctx, "p1", "eu-west-b"); err != nil {
+		t.Fatalf("retire: %v", err)
+	}
+	if links, _ := source.links.Links(ctx, "p1"); len(links) != 0 || source.profiles["p1"] != "" {
+		t.Fatal("the source still has the player")
+	}
+}
+
+func TestImportVerifiesOnChainOwnership(t *testing.T) {
+	ctx := context.Background()
+	objects := chain{addr: {"0xsword", "0xshield"}}
+	source, target := newShard("a", objects), newShard("b", objects)
+	source.profiles["p1"] = "Alice"
+	source.links.Save(ctx, accountlink.Link{PlayerID: "p1", Address: addr, Primary: true})
+	bundle, err := source.service.Export(ctx, "p1")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	objects[addr] = []string{"0xshield"}
+	report, err := target.service.Import(ctx, bundle, ImportOptions{})
+	if !errors.Is(err, ErrAssetsMoved) || len(report.Assets) != 1 || len(report.Assets[0].Missing) != 1 || report.Assets[0].Missing[0] != "0xsword" {
+		t.Fatalf("import = %+v, %v; want 0xsword reported missing", report, err)
+	}
+
+	target.links.Save(ctx, accountlink.Link{PlayerID: "p9", Address: addr})
+	if _, err := target.service.Import(ctx, bundle, ImportOptions{Force: true}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("import of an address linked to another player = %v, want a conflict", err)
+	}
+	target.links.DeletePlayer(ctx, "p9")
+	if report, err := target.service.Import(ctx, bundle, ImportOptions{Force: true}); err != nil || len(report.Imported) != 2 {
+		t.Fatalf("forced import = %+v, %v", report, err)
+	}
+}
+
+func TestGuardsBlockTransfers(t *testing.T) {
+	s := newShard("a", chain{})
+	s.profiles["p1"] = "Alice"
+	s.service.AddGuard(guardFunc(func(string) error { return errors.New("player is online") }))
+	if _, err := s.service.Export(context.Background(), "p1"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("export = %v, want blocked", err)
+	}
+	if _, _, err := s.service.Retire(context.Background(), "p1", "b"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("retire = %v, want blocked", err)
+	}
+}
+
+type guardFunc func(string) error
+
+func (g guardFunc) Name() string                                           { return "sessions" }
+func (g guardFunc) CheckTransfer(_ context.Context, playerID string) error { return g(playerID) }
@@ -0,0 +1,214 @@
+// Package treasury keeps the ledger of the fees the server collects, in soft
+// currency and on-chain, and reports the totals to operators.
+package treasury
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// Fee sources.
+const (
+	SourceTrade       = "trade"        // Escrowed trades, paid on-chain when the escrow is released
+	SourceDirectTrade = "direct_trade" // Direct trades, paid in coins when accepted
+	SourceMarketplace = "marketplace"  // Marketplace purchases, paid on-chain by the buyer
+)
+
+// Currencies.
+const (
+	CurrencyCoins = "coins" // Soft currency
+	CurrencyMIST  = "mist"
+)
+
+// DefaultRecent is how many entries a ledger keeps when not told otherwise.
+const DefaultRecent = 200
+
+// Entry is one collected fee.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Currency string    `json:"currency"`
+	Amount   uint64    `json:"amount"`
+	Region   string    `json:"region,omitempty"`
+	Payer    string    `json:"payer,omitempty"` // Player ID or Sui address
+	Ref      string    `json:"ref,omitempty"`   // Trade ID or transaction digest
+}
+
+// Total is what one source collected in one currency and region.
+type Total struct {
+	Source   string `json:"source"`
+	Currency string `json:"currency"`
+	Region   string `json:"region,omitempty"`
+	Amount   uint64 `json:"amount"`
+	Count    int    `json:"count"`
+}
+
+// Report is the treasury report: every total, and the latest entries newest first.
+type Report struct {
+	Totals []Total   `json:"totals"`
+	Recent []Entry   `json:"recent"`
+	Since  time.Time `json:"since,omitempty"` // First fee recorded
+}
+
+// State is the persisted ledger.
+type State struct {
+	Totals []Total   `json:"totals"`
+	Recent []Entry   `json:"recent"` // Oldest first
+	Since  time.Time `json:"since,omitempty"`
+}
+
+// Store persists the ledger.
+type Store interface {
+	LoadState() (State, error)
+	SaveState(State) error
+}
+
+// MemoryStore keeps the ledger in memory.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state State
+}
+
+// LoadState implements Store.
+func (m *MemoryStore) LoadState() (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, nil
+}
+
+// SaveState implements Store.
+func (m *MemoryStore) SaveState(state State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	return nil
+}
+
+// FileStore keeps the ledger in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+// LoadState implements Store. A missing file is an empty ledger.
+func (f FileStore) LoadState() (State, error) {
+	var state State
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// SaveState implements Store.
+func (f FileStore) SaveState(state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Ledger records collected fees. It is safe for concurrent use, and a nil
+// Ledger records nothing.
+type Ledger struct {
+	store  Store
+	recent int
+	now    func() time.Time
+
+	mu    sync.Mutex
+	state State
+}
+
+// Open loads the ledger from store. It keeps the latest recent entries, or
+// DefaultRecent if recent is not positive; totals cover every fee.
+func Open(store Store, recent int) (*Ledger, error) {
+	state, err := store.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("loading treasury ledger: %w", err)
+	}
+	if recent <= 0 {
+		recent = DefaultRecent
+	}
+	return &Ledger{store: store, recent: recent, now: time.Now, state: state}, nil
+}
+
+// Record adds a fee to the ledger. Fees of zero are not recorded.
+func (l *Ledger) Record(entry Entry) {
+	if l == nil || entry.Amount == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry.Time.IsZero() {
+		entry.Time = l.now().UTC()
+	}
+	if l.state.Since.IsZero() {
+		l.state.Since = entry.Time
+	}
+	found := false
+	for i, total := range l.state.Totals {
+		if total.Source == entry.Source && total.Currency == entry.Currency && total.Region == entry.Region {
+			l.state.Totals[i].Amount += entry.Amount
+			l.state.Totals[i].Count++
+			found = true
+			break
+		}
+	}
+	if !found {
+		l.state.Totals = append(l.state.Totals, Total{Source: entry.Source, Currency: entry.Currency, Region: entry.Region, Amount: entry.Amount, Count: 1})
+	}
+	l.state.Recent = append(l.state.Recent, entry)
+	if extra := len(l.state.Recent) - l.recent; extra > 0 {
+		l.state.Recent = append([]Entry(nil), l.state.Recent[extra:]...)
+	}
+	if err := l.store.SaveState(l.state); err != nil {
+		utils.LogErrorf("Treasury: Could not save the ledger: %v", err)
+	}
+}
+
+// Report returns the totals, sorted by source, currency and region, and the
+// latest entries. A non-empty region limits both to that region.
+func (l *Ledger) Report(region string) Report {
+	report := Report{Totals: []Total{}, Recent: []Entry{}}
+	if l == nil {
+		return report
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report.Since = l.state.Since
+	for _, total := range l.state.Totals {
+		if region == "" || total.Region == region {
+			report.Totals = append(report.Totals, total)
+		}
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		a, b := report.Totals[i], report.Totals[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Region < b.Region
+	})
+	for i := len(l.state.Recent) - 1; i >= 0; i-- {
+		if entry := l.state.Recent[i]; region == "" || entry.Region == region {
+			report.Recent = append(report.Recent, entry)
+		}
+	}
+	return report
+}
@@ -0,0 +1,42 @@
+package treasury
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLedgerTotalsSurviveReopening(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "ledger.json")}
+	ledger, err := Open(store, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger.Record(Entry{Source: SourceTrade, Currency: CurrencyMIST, Amount: 40, Region: "eu", Ref: "t1"})
+	ledger.Record(Entry{Source: SourceTrade, Currency: CurrencyMIST, Amount: 60, Region: "eu", Ref: "t2"})
+	ledger.Record(Entry{Source: SourceDirectTrade, Currency: CurrencyCoins, Amount: 5, Region: "us", Ref: "t3"})
+	ledger.Record(Entry{Source: SourceDirectTrade, Currency: CurrencyCoins, Amount: 0, Ref: "free"})
+
+	reopened, err := Open(store, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := reopened.Report("")
+	want := []Total{
+		{Source: SourceDirectTrade, Currency: CurrencyCoins, Region: "us", Amount: 5, Count: 1},
+		{Source: SourceTrade, Currency: CurrencyMIST, Region: "eu", Amount: 100, Count: 2},
+	}
+	if len(report.Totals) != 2 || report.Totals[0] != want[0] || report.Totals[1] != want[1] {
+		t.Fatalf("totals = %+v, want %+v", report.Totals, want)
+	}
+	if len(report.Recent) != 2 || report.Recent[0].Ref != "t3" || report.Recent[1].Ref != "t2" {
+		t.Fatalf("recent = %+v, want t3 then t2", report.Recent)
+	}
+	if eu := reopened.Report("eu"); len(eu.Totals) != 1 || len(eu.Recent) != 1 {
+		t.Errorf("eu report = %+v", eu)
+	}
+	var none *Ledger
+	none.Record(Entry{Amount: 1})
+	if report := none.Report(""); len(report.Totals) != 0 {
+		t.Errorf("nil ledger report = %+v", report)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +24,9 @@ var (
 	logger                   = log.New(os.Stdout, "", 0) // Use a custom logger to control prefix and flags
 )
 
+// errorHook is called for error and fatal messages; see SetErrorHook.
+var errorHook atomic.Pointer[func(message string)]
+
 func logLevelToString(level LogLevel) string {
 	switch level {
 	case LevelDebug:
@@ -60,7 +64,24 @@ func SetLogLevel(levelString string) {
 	LogInfof("Log level set to %s", logLevelToString(currentLogLevel))
 }
 
+// SetErrorHook registers a function that is called with every message logged at
+// error level or above, whatever the log level, e.g. to report errors to another
+// system. The hook runs on the logging goroutine, so it must not block. A nil hook
+// removes it.
+func SetErrorHook(hook func(message string)) {
+	if hook == nil {
+		errorHook.Store(nil)
+		return
+	}
+	errorHook.Store(&hook)
+}
+
 func logInternal(level LogLevel, message string) {
+	if level >= LevelError {
+		if hook := errorHook.Load(); hook != nil {
+			(*hook)(message)
+		}
+	}
 	if level >= currentLogLevel {
 		timestamp := time.Now().Format("2006-01-02 15:04:05.000")
 		// Using standard log package's Println to ensure atomic write for the whole line
@@ -70,12 +91,22 @@ func logInternal(level LogLevel, message string) {
 	}
 }
 
+// DebugEnabled reports whether debug messages are logged, so hot paths can
+// skip building them.
+func DebugEnabled() bool {
+	return currentLogLevel <= LevelDebug
+}
+
 func LogDebug(args ...interface{}) {
-	logInternal(LevelDebug, fmt.Sprint(args...))
+	if DebugEnabled() {
+		logInternal(LevelDebug, fmt.Sprint(args...))
+	}
 }
 
 func LogDebugf(format string, args ...interface{}) {
-	logInternal(LevelDebug, fmt.Sprintf(format, args...))
+	if DebugEnabled() {
+		logInternal(LevelDebug, fmt.Sprintf(format, args...))
+	}
 }
 
 func LogInfo(args ...interface{}) {
@@ -0,0 +1,168 @@
+// Package webhooks posts selected events from the event bus to outside
+// services, such as Discord or Slack channels or an operations endpoint.
+// Deliveries go through the outbox, so they are retried with backoff and
+// survive restarts.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phuhao00/suigserver/server/internal/events"
+)
+
+// Body formats an endpoint can receive.
+const (
+	FormatJSON    = "json"    // The Notification as JSON
+	FormatDiscord = "discord" // A Discord webhook message: {"content": ...}
+	FormatSlack   = "slack"   // A Slack incoming webhook message: {"text": ...}
+)
+
+// Request headers sent with every delivery.
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// DefaultTimeout bounds one delivery request.
+const DefaultTimeout = 10 * time.Second
+
+// chatMessageLimit keeps Discord and Slack messages under Discord's 2000 character limit.
+const chatMessageLimit = 1900
+
+// Endpoint is one webhook receiver.
+type Endpoint struct {
+	Name         string
+	URL          string
+	Secret       string   // HMAC-SHA256 key for the signature header; deliveries are unsigned if empty
+	Format       string   // FormatJSON (default), FormatDiscord or FormatSlack
+	Events       []string // Topic patterns, as for events.Bus.Subscribe: "market.sold", "territory.*", "*"
+	MinSalePrice uint64   // market.sold: only sales at or above this price
+	ItemTypes    []string // item.minted: only these item types; empty sends every mint
+	Timeout      time.Duration
+}
+
+func (e Endpoint) validate() error {
+	if e.Name == "" {
+		return errors.New("webhook endpoint needs a name")
+	}
+	parsed, err := url.Parse(e.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("webhook %s: url must be an http(s) URL", e.Name)
+	}
+	switch e.Format {
+	case "", FormatJSON, FormatDiscord, FormatSlack:
+	default:
+		return fmt.Errorf("webhook %s: unknown format %q", e.Name, e.Format)
+	}
+	if len(e.Events) == 0 {
+		return fmt.Errorf("webhook %s: no events selected", e.Name)
+	}
+	return nil
+}
+
+// Wants reports whether event should be sent to the endpoint: its topic matches
+// one of the endpoint's patterns and it passes the endpoint's filters.
+func (e Endpoint) Wants(event events.Event) bool {
+	matched := false
+	for _, pattern := range e.Events {
+		if topicMatches(pattern, event.Topic) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	switch payload := event.Payload.(type) {
+	case events.MarketSold:
+		return payload.Price >= e.MinSalePrice
+	case events.ItemMinted:
+		if len(e.ItemTypes) == 0 {
+			return true
+		}
+		for _, itemType := range e.ItemTypes {
+			if itemType == payload.ItemType {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// topicMatches applies a subscription pattern: an exact topic, a prefix such as
+// "player.*", or "*" for everything.
+func topicMatches(pattern string, topic events.Topic) bool {
+	if pattern == "*" || pattern == string(topic) {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(string(topic), prefix)
+	}
+	return false
+}
+
+// host is the endpoint URL without its path and query, which for Discord and
+// Slack contain the webhook's secret token.
+func (e Endpoint) host() string {
+	parsed, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// Notification is the body posted to FormatJSON endpoints.
+type Notification struct {
+	ID    string          `json:"id"` // Unique per delivery; repeated on retries
+	Event string          `json:"event"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"` // The event payload
+}
+
+// body encodes a notification in the endpoint's format.
+func (e Endpoint) body(n Notification) ([]byte, error) {
+	switch e.Format {
+	case FormatDiscord:
+		return json.Marshal(map[string]string{"content": summary(n)})
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": summary(n)})
+	}
+	return json.Marshal(n)
+}
+
+// summary is a one-message rendering of a notification for chat services.
+func summary(n Notification) string {
+	text := fmt.Sprintf("%s: %s", n.Event, n.Data)
+	if len(text) > chatMessageLimit {
+		cut := chatMessageLimit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…"
+	}
+	return text
+}
+
+// Sign returns the signature header value for a delivery body sent at
+// timestamp: "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers should recompute it with the shared secret and reject stale timestamps.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,43 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+)
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header.
+//
+//	GET  /admin/webhooks        endpoint delivery status, recent attempts and queued deliveries
+//	POST /admin/webhooks/test   {"endpoint": "..."} queues a webhook.test notification
+func (s *Service) RegisterHandlers(mux *http.ServeMux, adminToken string) {
+	mux.HandleFunc("/admin/webhooks", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, s.Report())
+	}))
+	mux.HandleFunc("/admin/webhooks/test", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		var req struct {
+			Endpoint string `json:"endpoint"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+			return
+		}
+		id, err := s.Test(req.Endpoint, r.Header.Get("X-Admin-User"))
+		if err != nil {
+			adminhttp.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusAccepted, map[string]string{"id": id})
+	}))
+}
@@ -0,0 +1,365 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// DeliveryKind is the outbox message kind of webhook deliveries.
+const DeliveryKind = "webhook.deliver"
+
+// TopicTest is the topic of notifications sent from the admin API to check an endpoint.
+const TopicTest events.Topic = "webhook.test"
+
+// recentAttempts is how many delivery attempts the admin API shows.
+const recentAttempts = 100
+
+// delivery is the outbox payload: one notification for one endpoint.
+type delivery struct {
+	Endpoint     string       `json:"endpoint"`
+	Notification Notification `json:"notification"`
+}
+
+// EndpointStatus is the delivery record of one endpoint.
+type EndpointStatus struct {
+	Name           string    `json:"name"`
+	Host           string    `json:"host"`
+	Format         string    `json:"format"`
+	Events         []string  `json:"events"`
+	Signed         bool      `json:"signed"`
+	Queued         uint64    `json:"queued"` // Notifications handed to the outbox since startup
+	Delivered      uint64    `json:"delivered"`
+	FailedAttempts uint64    `json:"failedAttempts"`
+	Pending        int       `json:"pending"` // Waiting in the outbox, including retries
+	Dead           int       `json:"dead"`    // Out of attempts; see /debug/outbox
+	LastStatusCode int       `json:"lastStatusCode,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	LastSuccessAt  time.Time `json:"lastSuccessAt"`
+	LastFailureAt  time.Time `json:"lastFailureAt"`
+}
+
+// Attempt is one delivery attempt.
+type Attempt struct {
+	ID         string    `json:"id"`
+	Endpoint   string    `json:"endpoint"`
+	Event      string    `json:"event"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Report is the webhook state shown in the admin API.
+type Report struct {
+	Endpoints []EndpointStatus `json:"endpoints"`
+	Recent    []Attempt        `json:"recent"` // Newest first
+	Queue     []QueuedDelivery `json:"queue"`  // Undelivered notifications, oldest first
+}
+
+// QueuedDelivery is a notification waiting in the outbox or given up on.
+type QueuedDelivery struct {
+	ID            string    `json:"id"`
+	Endpoint      string    `json:"endpoint"`
+	Event         string    `json:"event"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	LastError     string    `json:"lastError,omitempty"`
+	Dead          bool      `json:"dead,omitempty"`
+}
+
+// Service turns bus events into webhook deliveries.
+type Service struct {
+	box       *outbox.Outbox
+	client    *http.Client
+	endpoints map[string]Endpoint
+	order     []string // Endpoint names in configuration order
+
+	mu     sync.Mutex
+	status map[string]*EndpointStatus
+	recent []Attempt // Ring of the last recentAttempts attempts
+	next   int
+}
+
+// NewService creates a Service for endpoints and registers its delivery handler
+// with box.
+func NewService(endpoints []Endpoint, box *outbox.Outbox) (*Service, error) {
+	s := &Service{
+		box:       box,
+		client:    &http.Client{},
+		endpoints: make(map[string]Endpoint, len(endpoints)),
+		status:    make(map[string]*EndpointStatus, len(endpoints)),
+	}
+	for _, endpoint := range endpoints {
+		if err := endpoint.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := s.endpoints[endpoint.Name]; ok {
+			return nil, fmt.Errorf("webhook %s is configured twice", endpoint.Name)
+		}
+		if endpoint.Format == "" {
+			endpoint.Format = FormatJSON
+		}
+		if endpoint.Timeout <= 0 {
+			endpoint.Timeout = DefaultTimeout
+		}
+		s.endpoints[endpoint.Name] = endpoint
+		s.order = append(s.order, endpoint.Name)
+		s.status[endpoint.Name] = &EndpointStatus{
+			Name:   endpoint.Name,
+			Host:   endpoint.host(),
+			Format: endpoint.Format,
+			Events: endpoint.Events,
+			Signed: endpoint.Secret != "",
+		}
+	}
+	box.Handle(DeliveryKind, s.deliver)
+	return s, nil
+}
+
+// NewServiceFromConfig creates a Service for the configured endpoints, reading
+// URLs and secrets from their environment variables. It returns nil if no
+// endpoints are configured.
+func NewServiceFromConfig(cfg configs.WebhooksConfig, box *outbox.Outbox) (*Service, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, nil
+	}
+	endpoints := make([]Endpoint, 0, len(cfg.Endpoints))
+	for _, endpointCfg := range cfg.Endpoints {
+		endpoint := Endpoint{
+			Name:         endpointCfg.Name,
+			URL:          endpointCfg.URL,
+			Format:       endpointCfg.Format,
+			Events:       endpointCfg.Events,
+			MinSalePrice: endpointCfg.MinSalePrice,
+			ItemTypes:    endpointCfg.ItemTypes,
+			Timeout:      time.Duration(endpointCfg.TimeoutSeconds) * time.Second,
+		}
+		if endpointCfg.URLEnvVar != "" {
+			endpoint.URL = os.Getenv(endpointCfg.URLEnvVar)
+			if endpoint.URL == "" {
+				return nil, fmt.Errorf("webhook %s: %s is not set", endpoint.Name, endpointCfg.URLEnvVar)
+			}
+		}
+		if endpointCfg.SecretEnvVar != "" {
+			endpoint.Secret = os.Getenv(endpointCfg.SecretEnvVar)
+			if endpoint.Secret == "" {
+				return nil, fmt.Errorf("webhook %s: %s is not set", endpoint.Name, endpointCfg.SecretEnvVar)
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return NewService(endpoints, box)
+}
+
+// Subscribe queues a delivery for every event on bus that an endpoint wants.
+func (s *Service) Subscribe(bus *events.Bus) (unsubscribe func()) {
+	return bus.Subscribe("*", "webhooks", s.handleEvent)
+}
+
+func (s *Service) handleEvent(event events.Event) {
+	if serverErr, ok := event.Payload.(events.ServerError); ok && strings.Contains(serverErr.Message, DeliveryKind) {
+		return // A failing webhook must not report its own failures to itself
+	}
+	var data json.RawMessage
+	for _, name := range s.order {
+		if !s.endpoints[name].Wants(event) {
+			continue
+		}
+		if data == nil {
+			encoded, err := json.Marshal(event.Payload)
+			if err != nil {
+				utils.LogWarnf("Webhooks: Could not encode %s event: %v", event.Topic, err)
+				return
+			}
+			data = encoded
+		}
+		s.enqueue(name, Notification{ID: newID(), Event: string(event.Topic), Time: event.Time, Data: data})
+	}
+}
+
+// Test queues a webhook.test notification for the named endpoint and returns its ID.
+func (s *Service) Test(name, requestedBy string) (string, error) {
+	if _, ok := s.endpoints[name]; !ok {
+		return "", fmt.Errorf("unknown webhook %q", name)
+	}
+	data, _ := json.Marshal(map[string]string{"requestedBy": requestedBy})
+	n := Notification{ID: newID(), Event: string(TopicTest), Time: time.Now(), Data: data}
+	if err := s.enqueue(name, n); err != nil {
+		return "", err
+	}
+	return n.ID, nil
+}
+
+func (s *Service) enqueue(name string, n Notification) error {
+	// Logged as a warning: errors are themselves published as events.
+	if _, err := s.box.Enqueue("webhook:"+name+":"+n.ID, DeliveryKind, delivery{Endpoint: name, Notification: n}); err != nil {
+		utils.LogWarnf("Webhooks: Could not queue %s for %s: %v", n.Event, name, err)
+		return err
+	}
+	s.mu.Lock()
+	s.status[name].Queued++
+	s.mu.Unlock()
+	return nil
+}
+
+// deliver is the outbox handler: it posts one notification.
+func (s *Service) deliver(ctx context.Context, payload json.RawMessage) error {
+	var d delivery
+	if err := json.Unmarshal(payload, &d); err != nil {
+		return err
+	}
+	endpoint, ok := s.endpoints[d.Endpoint]
+	if !ok {
+		utils.LogWarnf("Webhooks: Dropping %s for %s, which is no longer configured.", d.Notification.ID, d.Endpoint)
+		return nil
+	}
+	start := time.Now()
+	status, err := s.post(ctx, endpoint, d.Notification)
+	s.record(Attempt{
+		ID:         d.Notification.ID,
+		Endpoint:   d.Endpoint,
+		Event:      d.Notification.Event,
+		At:         start,
+		StatusCode: status,
+		Error:      errorString(err),
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	return err
+}
+
+func (s *Service) post(ctx context.Context, endpoint Endpoint, n Notification) (int, error) {
+	body, err := endpoint.body(n)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, endpoint.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, n.ID)
+	req.Header.Set(HeaderEvent, n.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	if endpoint.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(endpoint.Secret, now, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Client errors include the URL, which may hold the webhook token.
+		return 0, fmt.Errorf("posting to %s failed: %w", endpoint.host(), unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s returned HTTP %d", endpoint.host(), resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *Service) record(attempt Attempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status[attempt.Endpoint]
+	status.LastStatusCode = attempt.StatusCode
+	if attempt.Error == "" {
+		status.Delivered++
+		status.LastSuccessAt = attempt.At
+	} else {
+		status.FailedAttempts++
+		status.LastError = attempt.Error
+		status.LastFailureAt = attempt.At
+	}
+	if len(s.recent) < recentAttempts {
+		s.recent = append(s.recent, attempt)
+	} else {
+		s.recent[s.next] = attempt
+	}
+	s.next = (s.next + 1) % recentAttempts
+}
+
+// Report returns each endpoint's delivery record, the latest attempts and the
+// notifications still in the outbox.
+func (s *Service) Report() Report {
+	queued, err := s.box.List(DeliveryKind)
+	if err != nil {
+		utils.LogWarnf("Webhooks: Could not list queued deliveries: %v", err)
+	}
+	report := Report{Queue: make([]QueuedDelivery, 0, len(queued))}
+	pending := make(map[string]int)
+	dead := make(map[string]int)
+	for _, msg := range queued {
+		var d delivery
+		if err := json.Unmarshal(msg.Payload, &d); err != nil {
+			continue
+		}
+		if msg.Dead {
+			dead[d.Endpoint]++
+		} else {
+			pending[d.Endpoint]++
+		}
+		report.Queue = append(report.Queue, QueuedDelivery{
+			ID:            d.Notification.ID,
+			Endpoint:      d.Endpoint,
+			Event:         d.Notification.Event,
+			Attempts:      msg.Attempts,
+			NextAttemptAt: msg.NextAttemptAt,
+			LastError:     msg.LastError,
+			Dead:          msg.Dead,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range s.order {
+		status := *s.status[name]
+		status.Pending, status.Dead = pending[name], dead[name]
+		report.Endpoints = append(report.Endpoints, status)
+	}
+	report.Recent = make([]Attempt, 0, len(s.recent))
+	for i := 1; i <= len(s.recent); i++ {
+		report.Recent = append(report.Recent, s.recent[(s.next-i+recentAttempts)%recentAttempts])
+	}
+	return report
+}
+
+func newID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/suigserver/server/configs"
+	"github.com/phuhao00/suigserver/server/internal/events"
+	"github.com/phuhao00/suigserver/server/internal/outbox"
+)
+
+func TestEndpointFilters(t *testing.T) {
+	endpoint := Endpoint{
+		Events:       []string{"market.sold", "item.*"},
+		MinSalePrice: 1000,
+		ItemTypes:    []string{"arena_champion"},
+	}
+	cases := []struct {
+		event events.Event
+		want  bool
+	}{
+		{events.Event{Topic: events.TopicMarketSold, Payload: events.MarketSold{Price: 1000}}, true},
+		{events.Event{Topic: events.TopicMarketSold, Payload: events.MarketSold{Price: 999}}, false},
+		{events.Event{Topic: events.TopicItemMinted, Payload: events.ItemMinted{ItemType: "arena_champion"}}, true},
+		{events.Event{Topic: events.TopicItemMinted, Payload: events.ItemMinted{ItemType: "iron_sword"}}, false},
+		{events.Event{Topic: events.TopicPlayerLogin, Payload: events.PlayerLogin{PlayerID: "p1"}}, false},
+	}
+	for _, c := range cases {
+		if got := endpoint.Wants(c.event); got != c.want {
+			t.Errorf("Wants(%s %+v) = %v, want %v", c.event.Topic, c.event.Payload, got, c.want)
+		}
+	}
+}
+
+func TestURLEnvVarWinsOverURL(t *testing.T) {
+	t.Setenv("OPS_WEBHOOK_URL", "https://hooks.example/from-env")
+	box := outbox.New(outbox.NewMemoryStore(), outbox.Options{})
+	service, err := NewServiceFromConfig(configs.WebhooksConfig{Endpoints: []configs.WebhookEndpointConfig{
+		{Name: "ops", URL: "https://hooks.example/from-file", URLEnvVar: "OPS_WEBHOOK_URL", Events: []string{"*"}},
+		{Name: "audit", URL: "https://hooks.example/audit", Events: []string{"*"}},
+	}}, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := service.endpoints["ops"].URL; got != "https://hooks.example/from-env" {
+		t.Errorf("ops URL = %q", got)
+	}
+	if got := service.endpoints["audit"].URL; got != "https://hooks.example/audit" {
+		t.Errorf("audit URL = %q", got)
+	}
+
+	_, err = NewServiceFromConfig(configs.WebhooksConfig{Endpoints: []configs.WebhookEndpointConfig{
+		{Name: "ops", URL: "https://hooks.example/from-file", URLEnvVar: "UNSET_WEBHOOK_URL", Events: []string{"*"}},
+	}}, box)
+	if err == nil {
+		t.Fatal("an unset urlEnvVar fell back to url")
+	}
+}
+
+func TestDeliverySignedAndRecorded(t *testing.T) {
+	var gotBody []byte
+	var gotHeaders http.Header
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	box := outbox.New(outbox.NewMemoryStore(), outbox.Options{})
+	service, err := NewService([]Endpoint{{Name: "ops", URL: server.URL + "/hook", Secret: "s3cret", Events: []string{"territory.*"}}}, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.handleEvent(events.Event{Topic: events.TopicTerritoryClaimed, Time: time.Now(), Payload: events.TerritoryClaimed{ZoneID: "z1", GuildID: "g1"}})
+	service.handleEvent(events.Event{Topic: events.TopicPlayerLogin, Time: time.Now(), Payload: events.PlayerLogin{PlayerID: "p1"}})
+
+	queued, _ := box.List(DeliveryKind)
+	if len(queued) != 1 {
+		t.Fatalf("queued %d deliveries, want 1", len(queued))
+	}
+	if err := service.deliver(context.Background(), queued[0].Payload); err == nil {
+		t.Fatal("expected an error for HTTP 502")
+	}
+	fail = false
+	if err := service.deliver(context.Background(), queued[0].Payload); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp, _ := strconv.ParseInt(gotHeaders.Get(HeaderTimestamp), 10, 64)
+	if want := Sign("s3cret", time.Unix(timestamp, 0), gotBody); gotHeaders.Get(HeaderSignature) != want {
+		t.Errorf("signature = %q, want %q", gotHeaders.Get(HeaderSignature), want)
+	}
+	var n Notification
+	if err := json.Unmarshal(gotBody, &n); err != nil || n.Event != string(events.TopicTerritoryClaimed) || gotHeaders.Get(HeaderID) != n.ID {
+		t.Errorf("body = %s (%v), id header %q", gotBody, err, gotHeaders.Get(HeaderID))
+	}
+
+	report := service.Report()
+	status := report.Endpoints[0]
+	if status.Queued != 1 || status.Delivered != 1 || status.FailedAttempts != 1 || status.Host != server.URL {
+		t.Errorf("status = %+v", status)
+	}
+	if len(report.Recent) != 2 || report.Recent[0].StatusCode != http.StatusOK || report.Recent[1].StatusCode != http.StatusBadGateway {
+		t.Errorf("recent = %+v, want newest first", report.Recent)
+	}
+}
+
+func TestChatFormats(t *testing.T) {
+	n := Notification{Event: "market.sold", Data: json.RawMessage(`{"Price":5}`)}
+	body, _ := Endpoint{Format: FormatDiscord}.body(n)
+	if string(body) != `{"content":"market.sold: {\"Price\":5}"}` {
+		t.Errorf("discord body = %s", body)
+	}
+	body, _ = Endpoint{Format: FormatSlack}.body(n)
+	if string(body) != `{"text":"market.sold: {\"Price\":5}"}` {
+		t.Errorf("slack body = %s", body)
+	}
+}
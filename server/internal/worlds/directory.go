@@ -0,0 +1,205 @@
+// Package worlds lets one process serve several game worlds (shards), such as
+// regional realms or a test realm. Each world has its own RoomManagerActor and
+// WorldManagerActor; players pick one when they authenticate.
+package worlds
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/pkg/protocol"
+	"github.com/phuhao00/suigserver/server/internal/actor/messages"
+)
+
+// DefaultID is the ID of the world used when none are configured.
+const DefaultID = "default"
+
+var (
+	ErrUnknownWorld  = errors.New("unknown world") // A world ID that is not served here
+	ErrPlayerOffline = errors.New("player is not online on this server")
+)
+
+// World is one game world and its top-level actors.
+type World struct {
+	ID              string
+	Name            string
+	Region          string
+	RoomManagerPID  *actor.PID
+	WorldManagerPID *actor.PID
+
+	sessions int64 // Authenticated sessions in this world
+}
+
+// Joined counts a session that authenticated into the world.
+func (w *World) Joined() { atomic.AddInt64(&w.sessions, 1) }
+
+// Left counts a session that ended.
+func (w *World) Left() { atomic.AddInt64(&w.sessions, -1) }
+
+// Sessions returns the number of sessions in the world.
+func (w *World) Sessions() int { return int(atomic.LoadInt64(&w.sessions)) }
+
+// Stats describe one world, for the admin API.
+type Stats struct {
+	ID            string         `json:"id"`
+	Name          string         `json:"name"`
+	Region        string         `json:"region,omitempty"`
+	Sessions      int            `json:"sessions"`
+	ActivePlayers int            `json:"activePlayers"`
+	Rooms         int            `json:"rooms"`
+	PlayersInRoom int            `json:"playersInRooms"`
+	ClaimedZones  int            `json:"claimedZones"`
+	PlayersByZone map[string]int `json:"playersByZone,omitempty"` // Online players in each zone
+	Error         string         `json:"error,omitempty"`         // Set if a manager did not answer
+}
+
+// Directory is the set of worlds served by this process. Worlds are added at
+// startup; after that it is safe for concurrent use.
+type Directory struct {
+	worlds []*World
+	byID   map[string]*World
+}
+
+// NewDirectory creates an empty Directory.
+func NewDirectory() *Directory {
+	return &Directory{byID: make(map[string]*World)}
+}
+
+// Add registers a world. The first world added is the default.
+func (d *Directory) Add(world *World) error {
+	if world.ID == "" {
+		return errors.New("world needs an id")
+	}
+	if _, exists := d.byID[world.ID]; exists {
+		return fmt.Errorf("duplicate world id %q", world.ID)
+	}
+	d.worlds = append(d.worlds, world)
+	d.byID[world.ID] = world
+	return nil
+}
+
+// Lookup returns the world with id, or the default world if id is empty.
+func (d *Directory) Lookup(id string) (*World, error) {
+	if id == "" {
+		if len(d.worlds) == 0 {
+			return nil, ErrUnknownWorld
+		}
+		return d.worlds[0], nil
+	}
+	world, ok := d.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownWorld, id)
+	}
+	return world, nil
+}
+
+// Worlds returns every world, default first.
+func (d *Directory) Worlds() []*World {
+	return append([]*World(nil), d.worlds...)
+}
+
+// Sessions returns the number of sessions across all worlds.
+func (d *Directory) Sessions() int {
+	total := 0
+	for _, world := range d.worlds {
+		total += world.Sessions()
+	}
+	return total
+}
+
+// Stats asks each world's managers for their counters, waiting up to timeout for each.
+func (d *Directory) Stats(root *actor.RootContext, timeout time.Duration) []Stats {
+	stats := make([]Stats, 0, len(d.worlds))
+	for _, world := range d.worlds {
+		s := Stats{ID: world.ID, Name: world.Name, Region: world.Region, Sessions: world.Sessions()}
+		if result, err := root.RequestFuture(world.WorldManagerPID, &messages.GetWorldStats{}, timeout).Result(); err != nil {
+			s.Error = "world manager: " + err.Error()
+		} else if worldStats, ok := result.(*messages.WorldStats); ok {
+			s.ActivePlayers, s.ClaimedZones, s.PlayersByZone = worldStats.ActivePlayers, worldStats.ClaimedZones, worldStats.PlayersByZone
+		}
+		if result, err := root.RequestFuture(world.RoomManagerPID, &messages.GetRoomStats{}, timeout).Result(); err != nil {
+			s.Error = "room manager: " + err.Error()
+		} else if roomStats, ok := result.(*messages.RoomStats); ok {
+			s.Rooms, s.PlayersInRoom = roomStats.Rooms, roomStats.Players
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// Announce sends text to every player online in any world and returns how
+// many sessions there were to receive it.
+func (d *Directory) Announce(root *actor.RootContext, text string) int {
+	announcement := &messages.Announcement{Text: text, SentAt: time.Now()}
+	for _, world := range d.worlds {
+		root.Send(world.WorldManagerPID, announcement)
+	}
+	return d.Sessions()
+}
+
+// PlayerDiagnostics finds playerID's session in any world and asks it for its
+// state and traffic counters, waiting up to timeout for each actor. It returns
+// ErrPlayerOffline if no world has the player.
+func (d *Directory) PlayerDiagnostics(root *actor.RootContext, playerID string, timeout time.Duration) (*messages.SessionDiagnostics, error) {
+	sessionPID, err := d.findSession(root, playerID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	result, err := root.RequestFuture(sessionPID, &messages.GetSessionDiagnostics{}, timeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", sessionPID.Id, err)
+	}
+	if diagnostics, ok := result.(*messages.SessionDiagnostics); ok {
+		return diagnostics, nil
+	}
+	return nil, fmt.Errorf("session %s answered with %T", sessionPID.Id, result)
+}
+
+// TransferPlayer moves playerID to the room matching roomID in their world,
+// as TransferSession does, and waits up to timeout for each actor. The move
+// was refused if the result is not a success; the player then stays where
+// they were. It returns ErrPlayerOffline if no world has the player.
+func (d *Directory) TransferPlayer(root *actor.RootContext, playerID, roomID, reason string, timeout time.Duration) (*messages.TransferResult, error) {
+	sessionPID, err := d.findSession(root, playerID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	// The session asks the room manager and then the room before it answers.
+	result, err := root.RequestFuture(sessionPID, &messages.TransferSession{RoomID: roomID, Reason: reason}, 3*timeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", sessionPID.Id, err)
+	}
+	if transfer, ok := result.(*messages.TransferResult); ok {
+		return transfer, nil
+	}
+	return nil, fmt.Errorf("session %s answered with %T", sessionPID.Id, result)
+}
+
+// KickPlayer ends playerID's session. The client is told it was kicked, with
+// reason, and that it should not reconnect on its own. It returns
+// ErrPlayerOffline if no world has the player.
+func (d *Directory) KickPlayer(root *actor.RootContext, playerID, reason string, timeout time.Duration) error {
+	sessionPID, err := d.findSession(root, playerID, timeout)
+	if err != nil {
+		return err
+	}
+	root.Send(sessionPID, &messages.TerminateSession{Code: protocol.DisconnectKicked, Reason: reason})
+	return nil
+}
+
+// findSession returns playerID's session in any world, or ErrPlayerOffline.
+func (d *Directory) findSession(root *actor.RootContext, playerID string, timeout time.Duration) (*actor.PID, error) {
+	for _, world := range d.worlds {
+		result, err := root.RequestFuture(world.WorldManagerPID, &messages.GetPlayerSession{PlayerID: playerID}, timeout).Result()
+		if err != nil {
+			return nil, fmt.Errorf("world %s manager: %w", world.ID, err)
+		}
+		if location, ok := result.(*messages.PlayerSessionLocation); ok && location.SessionPID != nil {
+			return location.SessionPID, nil
+		}
+	}
+	return nil, ErrPlayerOffline
+}
@@ -0,0 +1,32 @@
+package worlds
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDirectoryLookup(t *testing.T) {
+	directory := NewDirectory()
+	if _, err := directory.Lookup(""); !errors.Is(err, ErrUnknownWorld) {
+		t.Fatalf("empty directory lookup: got %v, want ErrUnknownWorld", err)
+	}
+	if err := directory.Add(&World{ID: "eu-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := directory.Add(&World{ID: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := directory.Add(&World{ID: "test"}); err == nil {
+		t.Fatal("duplicate world id was accepted")
+	}
+
+	if world, err := directory.Lookup(""); err != nil || world.ID != "eu-1" {
+		t.Fatalf("default world: got %v, %v; want eu-1", world, err)
+	}
+	if world, err := directory.Lookup("test"); err != nil || world.ID != "test" {
+		t.Fatalf("lookup test: got %v, %v", world, err)
+	}
+	if _, err := directory.Lookup("us-1"); !errors.Is(err, ErrUnknownWorld) {
+		t.Fatalf("unknown world: got %v, want ErrUnknownWorld", err)
+	}
+}
@@ -0,0 +1,112 @@
+package worlds
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/phuhao00/suigserver/server/internal/adminhttp"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+// statsTimeout bounds how long the admin API waits for each manager actor.
+const statsTimeout = 2 * time.Second
+
+// RegisterHandlers adds the admin endpoints to mux. Requests must carry
+// "Authorization: Bearer <adminToken>" and an X-Admin-User header.
+//
+//	GET  /admin/worlds                            per-world sessions, players, rooms and claimed zones
+//	GET  /admin/players/diagnostics?playerId=ID   an online player's connection, traffic, room and recent errors
+//	POST /admin/players/transfer                  {"playerId": "...", "roomId": "...", "reason": "..."} moves an online player to another room
+//	POST /admin/players/kick                      {"playerId": "...", "reason": "..."} disconnects an online player
+func (d *Directory) RegisterHandlers(mux *http.ServeMux, root *actor.RootContext, adminToken string) {
+	mux.HandleFunc("/admin/worlds", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		adminhttp.WriteJSON(w, http.StatusOK, d.Stats(root, statsTimeout))
+	}))
+	mux.HandleFunc("/admin/players/diagnostics", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodGet {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		playerID := r.URL.Query().Get("playerId")
+		if playerID == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId is required"))
+			return
+		}
+		diagnostics, err := d.PlayerDiagnostics(root, playerID, statsTimeout)
+		switch {
+		case errors.Is(err, ErrPlayerOffline):
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+		case err != nil:
+			utils.LogErrorf("Worlds: diagnostics for player %s failed: %v", playerID, err)
+			adminhttp.WriteError(w, http.StatusGatewayTimeout, err)
+		default:
+			adminhttp.WriteJSON(w, http.StatusOK, diagnostics)
+		}
+	}))
+	mux.HandleFunc("/admin/players/transfer", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		var req struct {
+			PlayerID string `json:"playerId"`
+			RoomID   string `json:"roomId"`
+			Reason   string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" || req.RoomID == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId and roomId are required"))
+			return
+		}
+		if req.Reason == "" {
+			req.Reason = "admin"
+		}
+		result, err := d.TransferPlayer(root, req.PlayerID, req.RoomID, req.Reason, statsTimeout)
+		switch {
+		case errors.Is(err, ErrPlayerOffline):
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+		case err != nil:
+			utils.LogErrorf("Worlds: transfer of player %s failed: %v", req.PlayerID, err)
+			adminhttp.WriteError(w, http.StatusGatewayTimeout, err)
+		case !result.Success:
+			adminhttp.WriteJSON(w, http.StatusConflict, result)
+		default:
+			utils.LogInfof("Worlds: %s moved player %s from room %q to %s (%s).", r.Header.Get("X-Admin-User"), req.PlayerID, result.FromRoomID, result.RoomID, req.Reason)
+			adminhttp.WriteJSON(w, http.StatusOK, result)
+		}
+	}))
+	mux.HandleFunc("/admin/players/kick", adminhttp.Only(adminToken, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodPost {
+			adminhttp.WriteError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		var req struct {
+			PlayerID string `json:"playerId"`
+			Reason   string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+			adminhttp.WriteError(w, http.StatusBadRequest, errors.New("playerId is required"))
+			return
+		}
+		if req.Reason == "" {
+			req.Reason = "Disconnected by an operator."
+		}
+		err := d.KickPlayer(root, req.PlayerID, req.Reason, statsTimeout)
+		switch {
+		case errors.Is(err, ErrPlayerOffline):
+			adminhttp.WriteError(w, http.StatusNotFound, err)
+		case err != nil:
+			utils.LogErrorf("Worlds: kick of player %s failed: %v", req.PlayerID, err)
+			adminhttp.WriteError(w, http.StatusGatewayTimeout, err)
+		default:
+			utils.LogInfof("Worlds: %s kicked player %s (%s).", r.Header.Get("X-Admin-User"), req.PlayerID, req.Reason)
+			adminhttp.WriteJSON(w, http.StatusOK, map[string]string{"playerId": req.PlayerID})
+		}
+	}))
+}
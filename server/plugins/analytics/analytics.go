@@ -0,0 +1,42 @@
+// Package analytics is the plugin that sends gameplay records to business
+// dashboards through the analytics pipeline, when analytics.enabled is set.
+// It reports its counters at /debug/analytics.
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+
+	internalAnalytics "github.com/phuhao00/suigserver/server/internal/analytics"
+	"github.com/phuhao00/suigserver/server/internal/plugins"
+	"github.com/phuhao00/suigserver/server/internal/utils" // Logger
+)
+
+func init() {
+	var pipeline *internalAnalytics.Pipeline
+	plugins.Register(plugins.Plugin{
+		Name: "analytics",
+		Init: func(h *plugins.Host) error {
+			if !h.Config.Analytics.Enabled {
+				return nil
+			}
+			p, err := internalAnalytics.NewPipelineFromConfig(h.Config.Analytics)
+			if err != nil {
+				return err
+			}
+			p.Subscribe(h.Events)
+			h.HandleDebug("", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(p.Stats())
+			})
+			pipeline = p
+			utils.LogInfof("Analytics enabled with %d sink(s).", len(h.Config.Analytics.Sinks))
+			return nil
+		},
+		Stop: func() {
+			if pipeline != nil {
+				pipeline.Close() // Flushes the last batch
+			}
+		},
+	})
+}
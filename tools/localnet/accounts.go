@@ -0,0 +1,153 @@
+package localnet
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/models"
+	"github.com/block-vision/sui-go-sdk/signer"
+)
+
+// Account is an ed25519 key pair on the localnet.
+type Account struct {
+	*signer.Signer
+	seed []byte
+}
+
+// NewAccount creates an account with a random key. It holds nothing until
+// funded.
+func NewAccount() (*Account, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return &Account{Signer: signer.NewSigner(seed), seed: seed}, nil
+}
+
+// PrivateKeyHex returns the key in the form of the server's sui.privateKey.
+func (a *Account) PrivateKeyHex() string {
+	return hex.EncodeToString(a.seed)
+}
+
+// keystoreEntry returns the key as the sui CLI keystore stores it: the scheme
+// flag followed by the private key, base64 encoded.
+func (a *Account) keystoreEntry() string {
+	return base64.StdEncoding.EncodeToString(append([]byte{signer.SigntureFlagEd25519}, a.seed...))
+}
+
+// NewFundedAccount creates an account and funds it from the faucet.
+func (n *Network) NewFundedAccount(ctx context.Context) (*Account, error) {
+	account, err := NewAccount()
+	if err != nil {
+		return nil, err
+	}
+	if err := n.Fund(ctx, account.Address); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Fund asks the faucet for gas for address and waits until it arrives. Failed
+// requests are retried until ctx is done.
+func (n *Network) Fund(ctx context.Context, address string) error {
+	for {
+		err := n.requestGas(ctx, address)
+		if err == nil {
+			return n.waitForBalance(ctx, address)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("localnet: faucet %s: %w (last error: %v)", n.FaucetURL, ctx.Err(), err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// requestGas makes one faucet request, on the v2 path first and then on the
+// path older faucets serve.
+func (n *Network) requestGas(ctx context.Context, address string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"FixedAmountRequest": map[string]string{"recipient": address},
+	})
+	var err error
+	for _, path := range []string{"/v2/gas", "/gas"} {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, n.FaucetURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		var resp *http.Response
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			continue
+		}
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		err = fmt.Errorf("%s: %s: %s", path, resp.Status, bytes.TrimSpace(detail))
+	}
+	return err
+}
+
+// waitForBalance polls until address holds some SUI.
+func (n *Network) waitForBalance(ctx context.Context, address string) error {
+	for {
+		balance, err := n.Client.SuiXGetBalance(ctx, models.SuiXGetBalanceRequest{Owner: address})
+		if err == nil {
+			if total, _ := strconv.ParseUint(balance.TotalBalance, 10, 64); total > 0 {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("localnet: gas for %s never arrived: %w", address, ctx.Err())
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// GasCoin returns one of address's SUI coin objects, for gas payment.
+func (n *Network) GasCoin(ctx context.Context, address string) (string, error) {
+	coins, err := n.Client.SuiXGetCoins(ctx, models.SuiXGetCoinsRequest{Owner: address, CoinType: "0x2::sui::SUI", Limit: 1})
+	if err != nil {
+		return "", err
+	}
+	if len(coins.Data) == 0 {
+		return "", fmt.Errorf("localnet: %s has no gas coins", address)
+	}
+	return coins.Data[0].CoinObjectId, nil
+}
+
+// Execute signs txBytes, e.g. from a MoveCall, as account and executes it. A
+// transaction that runs but aborts is an error too.
+func (n *Network) Execute(ctx context.Context, account *Account, txBytes string) (models.SuiTransactionBlockResponse, error) {
+	signed, err := account.SignTransaction(txBytes)
+	if err != nil {
+		return models.SuiTransactionBlockResponse{}, err
+	}
+	resp, err := n.Client.SuiExecuteTransactionBlock(ctx, models.SuiExecuteTransactionBlockRequest{
+		TxBytes:     txBytes,
+		Signature:   []string{signed.Signature},
+		Options:     models.SuiTransactionBlockOptions{ShowEffects: true, ShowEvents: true, ShowObjectChanges: true},
+		RequestType: "WaitForLocalExecution",
+	})
+	if err != nil {
+		return resp, err
+	}
+	if status := resp.Effects.Status; status.Status != "success" {
+		return resp, fmt.Errorf("localnet: transaction %s failed: %s", resp.Digest, status.Error)
+	}
+	return resp, nil
+}
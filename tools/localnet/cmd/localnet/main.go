@@ -0,0 +1,87 @@
+// Command localnet starts a Sui localnet (or connects to one), publishes the
+// game's Move packages, funds addresses and writes a server config pointing
+// at the result. A network it started keeps running until interrupted.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/phuhao00/suigserver/tools/localnet"
+)
+
+func main() {
+	rpc := flag.String("rpc", "", "RPC URL of a running localnet; one is started if empty")
+	faucet := flag.String("faucet", "", "Faucet URL of the localnet (default "+localnet.DefaultFaucetURL+")")
+	contracts := flag.String("contracts", "", "Directory with the Move packages (default: contracts/ of this repository)")
+	out := flag.String("out", "localnet", "Directory the server and marketplace configs are written to")
+	base := flag.String("base", "", "Config the server config is based on (default: config.example.json)")
+	fund := flag.String("fund", "", "Comma-separated addresses to fund from the faucet")
+	keep := flag.Bool("keep", false, "Keep the scratch directory with the node log and client config")
+	flag.Parse()
+
+	ctx := context.Background()
+	network, err := localnet.Start(ctx, localnet.Options{
+		RPCURL:       *rpc,
+		FaucetURL:    *faucet,
+		ContractsDir: *contracts,
+		KeepDir:      *keep,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer network.Close()
+
+	files, err := network.WriteConfig(ctx, *out, *base)
+	if err != nil {
+		network.Close()
+		log.Fatal(err)
+	}
+	for _, address := range strings.Split(*fund, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		if err := network.Fund(ctx, address); err != nil {
+			network.Close()
+			log.Fatal(err)
+		}
+		fmt.Printf("Funded %s\n", address)
+	}
+
+	fmt.Printf("RPC:      %s\nFaucet:   %s\nDeployer: %s\n", network.RPCURL, network.FaucetURL, network.Deployer.Address)
+	names, _ := network.Packages()
+	for _, name := range names {
+		if pkg, ok := network.Published(name); ok {
+			fmt.Printf("%-20s %s\n", name, pkg.ID)
+			for typ, id := range pkg.Objects {
+				fmt.Printf("  %-40s %s\n", typ, id)
+			}
+		}
+	}
+	fmt.Printf("Server config:      %s\nMarketplace config: %s\n", files.Server, files.Marketplace)
+	if *keep {
+		fmt.Printf("Scratch directory:  %s\n", network.Dir)
+	}
+
+	if !network.Started() {
+		return
+	}
+	fmt.Println("Localnet running; press Ctrl-C to stop it.")
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	stopped := make(chan struct{})
+	go func() {
+		network.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-interrupted:
+	case <-stopped:
+		fmt.Fprintln(os.Stderr, "sui start exited")
+	}
+}
@@ -0,0 +1,112 @@
+package localnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phuhao00/suigserver/server/configs"
+)
+
+// ConfigFiles are the files WriteConfig writes.
+type ConfigFiles struct {
+	Server      string // Server config, e.g. for configs.LoadConfig
+	Marketplace string // Marketplace config the server config points at
+}
+
+// WriteConfig writes a server config and a marketplace config into dir that
+// point the server at this network: its RPC, the deployer as the server key,
+// arbiter and gas owner, and the IDs of the published packages. Settings not
+// about the chain are copied from base, config.example.json of the repository
+// if empty. Packages are published first if they have not been.
+func (n *Network) WriteConfig(ctx context.Context, dir, base string) (ConfigFiles, error) {
+	files := ConfigFiles{
+		Server:      filepath.Join(dir, "config.json"),
+		Marketplace: filepath.Join(dir, "marketplace.json"),
+	}
+	packages, err := n.PublishAll(ctx)
+	if err != nil {
+		return files, err
+	}
+	gas, err := n.GasCoin(ctx, n.Deployer.Address)
+	if err != nil {
+		return files, err
+	}
+	if base == "" {
+		if base, err = findUp("config.example.json"); err != nil {
+			return files, fmt.Errorf("localnet: no base config: %w", err)
+		}
+	}
+	data, err := os.ReadFile(base)
+	if err != nil {
+		return files, err
+	}
+	config := make(map[string]interface{})
+	if err := json.Unmarshal(data, &config); err != nil {
+		return files, fmt.Errorf("localnet: %s: %w", base, err)
+	}
+
+	id := func(name string) string {
+		if pkg := packages[name]; pkg != nil {
+			return pkg.ID
+		}
+		return ""
+	}
+	set(config, "sui.rpcUrl", n.RPCURL)
+	set(config, "sui.fallbackRpcUrls", []string{})
+	set(config, "sui.websocketUrl", "")
+	set(config, "sui.privateKey", n.Deployer.PrivateKeyHex())
+	set(config, "sui.keySource", map[string]interface{}{"type": "config"})
+	set(config, "sui.gameLogicPackageId", id("game_world"))
+	set(config, "sui.playerRegistryPackageId", id("player_system"))
+	set(config, "sui.playerObjectPackageId", id("player_system"))
+	set(config, "sui.playerObjectModule", "player")
+	set(config, "sui.itemSystemPackageId", id("items_system"))
+	set(config, "sui.guildPackageId", id("guild_system"))
+	set(config, "sui.guildModule", "guild")
+	set(config, "trade.escrowPackageId", id("marketplace_system"))
+	set(config, "trade.escrowModule", "escrow")
+	set(config, "trade.arbiterAddress", n.Deployer.Address)
+	set(config, "trade.arbiterGasObjectId", gas)
+	if items := id("items_system"); items != "" {
+		set(config, "trade.itemType", items+"::item::ItemNFT")
+	}
+	set(config, "features.marketplaceConfigFile", files.Marketplace)
+
+	marketplace := configs.DefaultMarketplaceConfig()
+	marketplace.SuiNodeURL = n.RPCURL
+	if pkg := packages["marketplace_system"]; pkg != nil {
+		marketplace.PackageID = pkg.ID
+		marketplace.MarketplaceObjectID = pkg.Object("marketplace::Marketplace")
+		marketplace.AdminCapID = pkg.Object("marketplace::AdminCap")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return files, err
+	}
+	if err := marketplace.SaveToFile(files.Marketplace); err != nil {
+		return files, err
+	}
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return files, err
+	}
+	return files, os.WriteFile(files.Server, append(out, '\n'), 0o600)
+}
+
+// set sets a dotted path in a decoded JSON object, creating objects on the way.
+func set(config map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := config[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			config[key] = next
+		}
+		config = next
+	}
+	config[keys[len(keys)-1]] = value
+}
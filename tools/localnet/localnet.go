@@ -0,0 +1,266 @@
+// Package localnet runs the game's Move packages on a local Sui network for
+// end-to-end chain tests. Start boots `sui start` (or connects to a running
+// localnet), creates and funds a deployer, and Publish deploys the contracts/
+// packages with the sui CLI. The resulting IDs can be written into a server
+// config with WriteConfig, and test accounts created with NewFundedAccount
+// sign and execute the transactions the server prepares.
+package localnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block-vision/sui-go-sdk/sui"
+)
+
+// Endpoints of the network `sui start --with-faucet` runs.
+const (
+	DefaultRPCURL    = "http://127.0.0.1:9000"
+	DefaultFaucetURL = "http://127.0.0.1:9123"
+)
+
+// Environment variables that point tests at an existing localnet instead of
+// starting one.
+const (
+	EnvRPCURL    = "SUI_LOCALNET_RPC"
+	EnvFaucetURL = "SUI_LOCALNET_FAUCET"
+)
+
+// DefaultStartTimeout bounds how long Start waits for a new network to serve
+// RPC and faucet requests.
+const DefaultStartTimeout = 2 * time.Minute
+
+// Options configures a Network.
+type Options struct {
+	RPCURL        string        // Connect to this localnet instead of starting one; defaults to $SUI_LOCALNET_RPC
+	FaucetURL     string        // Faucet of RPCURL; defaults to $SUI_LOCALNET_FAUCET, then DefaultFaucetURL
+	SuiBinary     string        // The sui CLI; "sui" on PATH if empty
+	ContractsDir  string        // Directory holding the Move packages; found above the working directory if empty
+	SuiDependency string        // Move.toml line replacing each package's Sui dependency; DefaultSuiDependency if empty
+	GasBudget     uint64        // Gas budget of each publish; DefaultGasBudget if zero
+	StartTimeout  time.Duration // DefaultStartTimeout if zero
+	KeepDir       bool          // Keep the scratch directory (node log, client config, package copies) on Close
+}
+
+// Network is a running localnet with a funded deployer.
+type Network struct {
+	RPCURL    string
+	FaucetURL string
+	Client    sui.ISuiAPI
+	Deployer  *Account // Publishes the packages and owns their admin objects
+	Dir       string   // Scratch directory of this Network
+
+	opts Options
+	node *exec.Cmd // Nil when connected to an existing network
+	done chan struct{}
+
+	mu       sync.Mutex
+	packages map[string]*Package
+}
+
+// Start starts a localnet, or connects to opts.RPCURL, and funds a new
+// deployer account. Close stops a network that Start started.
+func Start(ctx context.Context, opts Options) (*Network, error) {
+	if opts.RPCURL == "" {
+		opts.RPCURL = os.Getenv(EnvRPCURL)
+	}
+	if opts.FaucetURL == "" {
+		opts.FaucetURL = os.Getenv(EnvFaucetURL)
+	}
+	if opts.FaucetURL == "" {
+		opts.FaucetURL = DefaultFaucetURL
+	}
+	if opts.SuiBinary == "" {
+		opts.SuiBinary = "sui"
+	}
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = DefaultStartTimeout
+	}
+
+	dir, err := os.MkdirTemp("", "suigserver-localnet-")
+	if err != nil {
+		return nil, err
+	}
+	n := &Network{
+		RPCURL:    opts.RPCURL,
+		FaucetURL: strings.TrimRight(opts.FaucetURL, "/"),
+		Dir:       dir,
+		opts:      opts,
+		packages:  make(map[string]*Package),
+	}
+	if n.RPCURL == "" {
+		n.RPCURL = DefaultRPCURL
+		if err := n.startNode(); err != nil {
+			n.Close()
+			return nil, err
+		}
+	}
+	n.Client = sui.NewSuiClient(n.RPCURL)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.StartTimeout)
+	defer cancel()
+	if err := n.waitForRPC(ctx); err != nil {
+		n.Close()
+		return nil, err
+	}
+	if n.Deployer, err = NewAccount(); err != nil {
+		n.Close()
+		return nil, err
+	}
+	if err := n.writeClientConfig(); err != nil {
+		n.Close()
+		return nil, err
+	}
+	// The faucet comes up after the RPC, so the first requests may fail.
+	if err := n.Fund(ctx, n.Deployer.Address); err != nil {
+		n.Close()
+		return nil, fmt.Errorf("funding the deployer: %w", err)
+	}
+	return n, nil
+}
+
+// startNode runs `sui start` with a fresh genesis in the scratch directory.
+func (n *Network) startNode() error {
+	if _, err := exec.LookPath(n.opts.SuiBinary); err != nil {
+		return fmt.Errorf("localnet: no sui binary: %w", err)
+	}
+	log, err := os.Create(filepath.Join(n.Dir, "node.log"))
+	if err != nil {
+		return err
+	}
+	n.node = exec.Command(n.opts.SuiBinary, "start", "--with-faucet", "--force-regenesis")
+	n.node.Dir = n.Dir
+	n.node.Stdout, n.node.Stderr = log, log
+	if err := n.node.Start(); err != nil {
+		log.Close()
+		n.node = nil
+		return fmt.Errorf("localnet: starting sui: %w", err)
+	}
+	n.done = make(chan struct{})
+	go func() {
+		n.node.Wait()
+		log.Close()
+		close(n.done)
+	}()
+	return nil
+}
+
+// waitForRPC polls the RPC endpoint until it answers.
+func (n *Network) waitForRPC(ctx context.Context) error {
+	for {
+		if _, err := n.Client.SuiGetLatestCheckpointSequenceNumber(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("localnet: %s did not come up: %w%s", n.RPCURL, ctx.Err(), n.logTail())
+		case <-n.done:
+			return fmt.Errorf("localnet: sui start exited%s", n.logTail())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// logTail returns the end of the node log, for errors.
+func (n *Network) logTail() string {
+	if n.node == nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(n.Dir, "node.log"))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	if len(data) > 2048 {
+		data = data[len(data)-2048:]
+	}
+	return "\n" + string(bytes.TrimSpace(data))
+}
+
+// writeClientConfig writes a sui CLI config whose only key is the deployer's,
+// so publishing never touches the user's ~/.sui.
+func (n *Network) writeClientConfig() error {
+	keystore := filepath.Join(n.Dir, "sui.keystore")
+	if err := os.WriteFile(keystore, []byte(fmt.Sprintf("[\n  %q\n]\n", n.Deployer.keystoreEntry())), 0o600); err != nil {
+		return err
+	}
+	config := fmt.Sprintf(`---
+keystore:
+  File: %q
+envs:
+  - alias: localnet
+    rpc: %q
+    ws: ~
+    basic_auth: ~
+active_env: localnet
+active_address: %q
+`, keystore, n.RPCURL, n.Deployer.Address)
+	return os.WriteFile(n.clientConfig(), []byte(config), 0o600)
+}
+
+func (n *Network) clientConfig() string {
+	return filepath.Join(n.Dir, "client.yaml")
+}
+
+// Started reports whether Start started the network, rather than connecting
+// to a running one.
+func (n *Network) Started() bool {
+	return n.node != nil
+}
+
+// Wait blocks until a network Start started exits. It returns at once for a
+// network it connected to.
+func (n *Network) Wait() {
+	if n.done != nil {
+		<-n.done
+	}
+}
+
+// Close stops the network if Start started it and removes the scratch
+// directory unless Options.KeepDir is set.
+func (n *Network) Close() error {
+	var err error
+	if n.node != nil && n.node.Process != nil {
+		n.node.Process.Signal(os.Interrupt)
+		select {
+		case <-n.done:
+		case <-time.After(10 * time.Second):
+			n.node.Process.Kill()
+			<-n.done
+		}
+	}
+	if !n.opts.KeepDir {
+		err = os.RemoveAll(n.Dir)
+	}
+	return err
+}
+
+// errNoContracts is returned when Options.ContractsDir is empty and no
+// contracts directory is found above the working directory.
+var errNoContracts = errors.New("localnet: contracts directory not found; set Options.ContractsDir")
+
+// findUp looks for name in the working directory and its parents.
+func findUp(name string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,137 @@
+package localnet
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/models"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	StopShared()
+	os.Exit(code)
+}
+
+func TestRewriteSuiDependency(t *testing.T) {
+	toml := "[dependencies]\nSui = { local = \"C:\\\\sui\" }\n# Sui = { git = \"old\" }\n\n[addresses]\nmmo_game = \"0x0\"\n"
+	got := string(rewriteSuiDependency([]byte(toml), DefaultSuiDependency))
+	if strings.Contains(got, "C:") || !strings.Contains(got, "\n"+DefaultSuiDependency+"\n") {
+		t.Errorf("rewritten Move.toml:\n%s", got)
+	}
+	if !strings.Contains(got, "# Sui = { git = \"old\" }") || !strings.Contains(got, "mmo_game = \"0x0\"") {
+		t.Errorf("other lines changed:\n%s", got)
+	}
+}
+
+func TestParsePublishOutput(t *testing.T) {
+	out := `BUILDING marketplace_system
+{
+  "digest": "DiGeSt",
+  "effects": {"status": {"status": "success"}},
+  "objectChanges": [
+    {"type": "mutated", "objectType": "0x2::coin::Coin<0x2::sui::SUI>", "objectId": "0xgas"},
+    {"type": "created", "objectType": "0xabc::marketplace::Marketplace", "objectId": "0xm"},
+    {"type": "created", "objectType": "0xabc::marketplace::AdminCap", "objectId": "0xcap"},
+    {"type": "created", "objectType": "0x2::package::UpgradeCap", "objectId": "0xup"},
+    {"type": "published", "packageId": "0xabc", "modules": ["escrow", "marketplace"]}
+  ]
+}`
+	pkg, err := parsePublishOutput("marketplace_system", []byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.ID != "0xabc" || pkg.Digest != "DiGeSt" || len(pkg.Modules) != 2 {
+		t.Errorf("package = %+v", pkg)
+	}
+	for typ, want := range map[string]string{
+		"marketplace::Marketplace": "0xm",
+		"marketplace::AdminCap":    "0xcap",
+		"0x2::package::UpgradeCap": "0xup",
+	} {
+		if got := pkg.Object(typ); got != want {
+			t.Errorf("Object(%q) = %q, want %q", typ, got, want)
+		}
+	}
+	if len(pkg.Objects) != 3 {
+		t.Errorf("objects = %v; mutated objects are not created at publish", pkg.Objects)
+	}
+
+	failed := `{"effects": {"status": {"status": "failure", "error": "InsufficientGas"}}, "objectChanges": []}`
+	if _, err := parsePublishOutput("x", []byte(failed)); err == nil || !strings.Contains(err.Error(), "InsufficientGas") {
+		t.Errorf("failed publish: err = %v", err)
+	}
+}
+
+func TestSetCreatesObjects(t *testing.T) {
+	config := map[string]interface{}{"sui": map[string]interface{}{"rpcUrl": "old", "gasBudget": 5.0}}
+	set(config, "sui.rpcUrl", "new")
+	set(config, "trade.escrowModule", "escrow")
+	sui := config["sui"].(map[string]interface{})
+	if sui["rpcUrl"] != "new" || sui["gasBudget"] != 5.0 {
+		t.Errorf("sui = %v", sui)
+	}
+	if config["trade"].(map[string]interface{})["escrowModule"] != "escrow" {
+		t.Errorf("trade = %v", config["trade"])
+	}
+}
+
+// TestCreatePlayerOnLocalnet runs a MoveCall against the published player
+// package. It needs a localnet; see ForTest.
+func TestCreatePlayerOnLocalnet(t *testing.T) {
+	network := ForTest(t)
+	ctx := context.Background()
+	player, ok := network.Published("player_system")
+	if !ok {
+		t.Fatal("player_system was not published")
+	}
+	registry := player.Object("player::PlayerRegistry")
+	if registry == "" {
+		t.Fatalf("no PlayerRegistry among %v", player.Objects)
+	}
+
+	alice := network.Account(t)
+	tx, err := network.Client.MoveCall(ctx, models.MoveCallRequest{
+		Signer:          alice.Address,
+		PackageObjectId: player.ID,
+		Module:          "player",
+		Function:        "create_player",
+		TypeArguments:   []interface{}{},
+		Arguments:       []interface{}{registry, "alice", 1},
+		GasBudget:       "100000000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := network.Execute(ctx, alice, tx.TxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range resp.ObjectChanges {
+		if change.Type == "created" && change.ObjectType == player.ID+"::player::PlayerNFT" {
+			return
+		}
+	}
+	t.Errorf("no PlayerNFT created: %+v", resp.ObjectChanges)
+}
+
+func TestWriteConfigOnLocalnet(t *testing.T) {
+	network := ForTest(t)
+	dir := t.TempDir()
+	files, err := network.WriteConfig(context.Background(), dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(files.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marketplace, _ := network.Published("marketplace_system")
+	for _, want := range []string{network.RPCURL, network.Deployer.Address, marketplace.ID} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("server config lacks %s", want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package localnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/block-vision/sui-go-sdk/models"
+)
+
+// DefaultSuiDependency replaces the Sui dependency of the packages' Move.toml,
+// which points at a framework checkout on a developer machine.
+const DefaultSuiDependency = `Sui = { git = "https://github.com/MystenLabs/sui.git", subdir = "crates/sui-framework/packages/sui-framework", rev = "framework/testnet" }`
+
+// DefaultGasBudget is the gas budget of each publish, in MIST.
+const DefaultGasBudget = 1_000_000_000
+
+// Package is a published Move package.
+type Package struct {
+	Name    string            // Directory name under contracts/, e.g. "marketplace_system"
+	ID      string            // Package ID
+	Modules []string          // Module names
+	Objects map[string]string // Objects created at publish by type, e.g. "marketplace::Marketplace"; types outside the package keep their full name
+	Digest  string            // Digest of the publish transaction
+}
+
+// Object returns the ID of the object of typ created at publish, or "".
+func (p *Package) Object(typ string) string {
+	return p.Objects[typ]
+}
+
+// Packages returns the names of the Move packages in the contracts directory.
+func (n *Network) Packages() ([]string, error) {
+	dir, err := n.contractsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "Move.toml")); entry.IsDir() && err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// PublishAll publishes every package in the contracts directory.
+func (n *Network) PublishAll(ctx context.Context) (map[string]*Package, error) {
+	names, err := n.Packages()
+	if err != nil {
+		return nil, err
+	}
+	published := make(map[string]*Package, len(names))
+	for _, name := range names {
+		pkg, err := n.Publish(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		published[name] = pkg
+	}
+	return published, nil
+}
+
+// Publish publishes the named package as the deployer. A package is
+// published once per Network; later calls return the first result.
+func (n *Network) Publish(ctx context.Context, name string) (*Package, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if pkg, ok := n.packages[name]; ok {
+		return pkg, nil
+	}
+	contracts, err := n.contractsDir()
+	if err != nil {
+		return nil, err
+	}
+	dependency := n.opts.SuiDependency
+	if dependency == "" {
+		dependency = DefaultSuiDependency
+	}
+	dir := filepath.Join(n.Dir, "packages", name)
+	if err := copyPackage(filepath.Join(contracts, name), dir, dependency); err != nil {
+		return nil, fmt.Errorf("localnet: copying %s: %w", name, err)
+	}
+
+	budget := n.opts.GasBudget
+	if budget == 0 {
+		budget = DefaultGasBudget
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, n.opts.SuiBinary, "client", "--client.config", n.clientConfig(),
+		"publish", dir, "--json", "--gas-budget", strconv.FormatUint(budget, 10), "--skip-dependency-verification")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("localnet: publishing %s: %w\n%s", name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	pkg, err := parsePublishOutput(name, stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	n.packages[name] = pkg
+	return pkg, nil
+}
+
+// Published returns the named package if it has been published.
+func (n *Network) Published(name string) (*Package, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	pkg, ok := n.packages[name]
+	return pkg, ok
+}
+
+// contractsDir returns Options.ContractsDir, or the contracts directory of the
+// repository the working directory is in.
+func (n *Network) contractsDir() (string, error) {
+	if n.opts.ContractsDir != "" {
+		return n.opts.ContractsDir, nil
+	}
+	toml, err := findUp(filepath.Join("contracts", "marketplace_system", "Move.toml"))
+	if err != nil {
+		return "", errNoContracts
+	}
+	return filepath.Dir(filepath.Dir(toml)), nil
+}
+
+// copyPackage copies a Move package to dst without its build output and lock
+// file, pointing its Sui dependency at dependency.
+func copyPackage(src, dst, dependency string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if d.IsDir() {
+			if rel == "build" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		if rel == "Move.lock" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if rel == "Move.toml" {
+			data = rewriteSuiDependency(data, dependency)
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+}
+
+// rewriteSuiDependency replaces the active `Sui = ...` line of a Move.toml.
+func rewriteSuiDependency(toml []byte, dependency string) []byte {
+	lines := strings.Split(string(toml), "\n")
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "Sui ") || strings.HasPrefix(trimmed, "Sui=") {
+			lines[i] = dependency
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// publishOutput is the part of `sui client publish --json` output Publish
+// reads.
+type publishOutput struct {
+	Digest  string `json:"digest"`
+	Effects struct {
+		Status models.ExecutionStatus `json:"status"`
+	} `json:"effects"`
+	ObjectChanges []models.ObjectChange `json:"objectChanges"`
+}
+
+// parsePublishOutput reads the package and the objects its init functions
+// created from the CLI's JSON output, which may follow build messages.
+func parsePublishOutput(name string, out []byte) (*Package, error) {
+	start := bytes.IndexByte(out, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("localnet: publishing %s: no JSON in output %q", name, out)
+	}
+	var result publishOutput
+	if err := json.Unmarshal(out[start:], &result); err != nil {
+		return nil, fmt.Errorf("localnet: publishing %s: %w", name, err)
+	}
+	if result.Effects.Status.Status != "success" {
+		return nil, fmt.Errorf("localnet: publishing %s failed: %s", name, result.Effects.Status.Error)
+	}
+	pkg := &Package{Name: name, Digest: result.Digest, Objects: make(map[string]string)}
+	for _, change := range result.ObjectChanges {
+		if change.Type == "published" {
+			pkg.ID, pkg.Modules = change.PackageId, change.Modules
+		}
+	}
+	if pkg.ID == "" {
+		return nil, errors.New("localnet: publishing " + name + ": no package in the output")
+	}
+	for _, change := range result.ObjectChanges {
+		if change.Type == "created" {
+			pkg.Objects[strings.TrimPrefix(change.ObjectType, pkg.ID+"::")] = change.ObjectId
+		}
+	}
+	return pkg, nil
+}
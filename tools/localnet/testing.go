@@ -0,0 +1,67 @@
+package localnet
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+// EnvEnable set to a non-empty value lets ForTest start a localnet with the
+// sui binary. Without it, or EnvRPCURL, chain tests are skipped.
+const EnvEnable = "SUI_LOCALNET"
+
+var shared struct {
+	once    sync.Once
+	network *Network
+	err     error
+}
+
+// ForTest returns a localnet with every package published, shared by the
+// tests of the binary. It skips t unless $SUI_LOCALNET_RPC names a running
+// localnet, or $SUI_LOCALNET is set and a sui binary is on PATH, so chain
+// tests stay out of runs without a Sui toolchain. Call StopShared from
+// TestMain to stop the network once the tests are done.
+func ForTest(t testing.TB) *Network {
+	t.Helper()
+	if os.Getenv(EnvRPCURL) == "" {
+		if os.Getenv(EnvEnable) == "" {
+			t.Skipf("set %s=1 or %s to run chain tests", EnvEnable, EnvRPCURL)
+		}
+		if _, err := exec.LookPath("sui"); err != nil {
+			t.Skip("no sui binary on PATH")
+		}
+	}
+	shared.once.Do(func() {
+		ctx := context.Background()
+		if shared.network, shared.err = Start(ctx, Options{}); shared.err != nil {
+			return
+		}
+		if _, shared.err = shared.network.PublishAll(ctx); shared.err != nil {
+			shared.network.Close()
+			shared.network = nil
+		}
+	})
+	if shared.err != nil {
+		t.Fatal(shared.err)
+	}
+	return shared.network
+}
+
+// Account returns a new funded account for a test.
+func (n *Network) Account(t testing.TB) *Account {
+	t.Helper()
+	account, err := n.NewFundedAccount(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+// StopShared stops the network ForTest started, if any.
+func StopShared() {
+	if shared.network != nil {
+		shared.network.Close()
+	}
+}